@@ -0,0 +1,153 @@
+package prefab
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+)
+
+// Default host for the admin listener, which should not be reachable from
+// outside the machine unless explicitly configured.
+const defaultAdminHost = "127.0.0.1"
+
+// AdminOptionProvider can be implemented by plugins to register handlers and
+// services that should only be reachable via the admin listener, such as debug
+// endpoints. Options should be created with WithAdminHTTPHandler,
+// WithAdminHTTPHandlerFunc, and WithAdminGRPCService.
+//
+// When no admin listener is configured, admin handlers and services are not
+// served, unless WithAdminOnPublic is used.
+type AdminOptionProvider interface {
+	AdminOptions() []ServerOption
+}
+
+// WithAdminAddress configures a separate listener for admin handlers and
+// services. A port of 0 disables the admin listener.
+//
+// Config keys: `server.admin.host`, `server.admin.port`.
+func WithAdminAddress(host string, port int) ServerOption {
+	return func(b *builder) {
+		b.adminHost = host
+		b.adminPort = port
+	}
+}
+
+// WithAdminListener configures the admin listener to accept connections from
+// ln, instead of listening on the configured admin host and port.
+func WithAdminListener(ln net.Listener) ServerOption {
+	return func(b *builder) {
+		b.adminListener = ln
+	}
+}
+
+// WithAdminOnPublic serves admin handlers and services on the public server
+// when no admin listener is configured. Without it they are skipped, with a
+// warning. Only use this when every admin handler is protected some other way,
+// for example with authz middleware.
+//
+// Config key: `server.admin.public`.
+func WithAdminOnPublic() ServerOption {
+	return func(b *builder) {
+		b.adminOnPublic = true
+	}
+}
+
+// WithAdminHTTPHandler adds an HTTP handler to the admin listener.
+func WithAdminHTTPHandler(prefix string, h http.Handler) ServerOption {
+	return func(b *builder) {
+		b.adminHandlers = append(b.adminHandlers, handler{
+			prefix:      prefix,
+			httpHandler: h,
+		})
+	}
+}
+
+// WithAdminHTTPHandlerFunc adds an HTTP handler function to the admin listener.
+func WithAdminHTTPHandlerFunc(prefix string, h func(http.ResponseWriter, *http.Request)) ServerOption {
+	return WithAdminHTTPHandler(prefix, http.HandlerFunc(h))
+}
+
+// WithAdminGRPCService registers a GRPC service on the admin listener.
+func WithAdminGRPCService(desc *grpc.ServiceDesc, impl any) ServerOption {
+	return func(b *builder) {
+		b.serverBuilders = append(b.serverBuilders, func(s *Server) {
+			s.AdminServiceRegistrar().RegisterService(desc, impl)
+		})
+	}
+}
+
+func (b *builder) hasAdminListener() bool {
+	return b.adminListener != nil || b.adminPort != 0
+}
+
+// HasAdminListener returns whether the server has a separate admin listener.
+func (s *Server) HasAdminListener() bool {
+	return s.adminMux != nil
+}
+
+// AdminServiceRegistrar returns the GRPC Service Registrar for admin services.
+// If no admin listener is configured, this is the public server when
+// WithAdminOnPublic is used, and otherwise a registrar which skips services.
+func (s *Server) AdminServiceRegistrar() grpc.ServiceRegistrar {
+	if s.adminGRPCServer != nil {
+		return s.adminGRPCServer
+	}
+	if s.adminOnPublic {
+		return s.grpcServer
+	}
+	return skipRegistrar{ctx: s.baseContext}
+}
+
+// skipRegistrar drops admin services when there is nowhere private to serve
+// them.
+type skipRegistrar struct {
+	ctx context.Context
+}
+
+func (r skipRegistrar) RegisterService(desc *grpc.ServiceDesc, _ any) {
+	logging.Warnw(r.ctx, "prefab: admin service not registered, there is no admin listener",
+		"service", desc.ServiceName)
+}
+
+// startAdmin starts serving the admin listener in the background, if one is
+// configured.
+func (s *Server) startAdmin(ctx context.Context) error {
+	if s.adminMux == nil {
+		return nil
+	}
+	addr := net.JoinHostPort(s.adminHost, strconv.Itoa(s.adminPort))
+	ln := s.adminListener
+	if ln == nil {
+		var listenCfg net.ListenConfig
+		var err error
+		ln, err = listenCfg.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on admin address: %w", err)
+		}
+	} else {
+		addr = ln.Addr().String()
+	}
+
+	s.adminHTTPServer = &http.Server{
+		Addr:              addr,
+		ReadHeaderTimeout: readHeaderTimeout,
+		BaseContext: func(listener net.Listener) context.Context {
+			return ctx
+		},
+	}
+	handler := grpcOrHTTPHandler(s.adminGRPCServer, s.adminMux)
+	go func() {
+		defer ln.Close()
+		logging.Infof(s.baseContext, "🔧  Listening for admin traffic on %s\n", addr)
+		if err := s.serve(s.adminHTTPServer, ln, handler); !errors.Is(err, http.ErrServerClosed) {
+			logging.Errorw(s.baseContext, "❌ Admin listener error", "error", err)
+		}
+	}()
+	return nil
+}
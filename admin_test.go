@@ -0,0 +1,80 @@
+package prefab
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type adminPlugin struct{}
+
+func (p *adminPlugin) Name() string { return "admin_test" }
+
+func (p *adminPlugin) AdminOptions() []ServerOption {
+	return []ServerOption{
+		WithAdminHTTPHandlerFunc("/debug/test", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("admin"))
+		}),
+	}
+}
+
+func serveStatus(h http.Handler, path string) int {
+	ctx := logging.With(context.Background(), logging.NewDevLogger())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestAdminListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := New(WithAdminListener(ln), WithPlugin(&adminPlugin{}))
+	require.NotNil(t, s.adminMux)
+	assert.Same(t, s.adminGRPCServer, s.AdminServiceRegistrar())
+
+	assert.Equal(t, http.StatusOK, serveStatus(s.adminMux, "/debug/test"))
+	assert.Equal(t, http.StatusNotFound, serveStatus(s.httpMux, "/debug/test"))
+}
+
+func TestAdminListener_Disabled(t *testing.T) {
+	s := New(WithPlugin(&adminPlugin{}))
+	assert.Nil(t, s.adminMux)
+	assert.False(t, s.HasAdminListener())
+	assert.IsType(t, skipRegistrar{}, s.AdminServiceRegistrar())
+
+	// Admin handlers are not served when there is no admin listener.
+	assert.Equal(t, http.StatusNotFound, serveStatus(s.httpMux, "/debug/test"))
+}
+
+func TestAdminOnPublic(t *testing.T) {
+	s := New(WithAdminOnPublic(), WithPlugin(&adminPlugin{}))
+	assert.Nil(t, s.adminMux)
+	assert.Same(t, s.grpcServer, s.AdminServiceRegistrar())
+
+	assert.Equal(t, http.StatusOK, serveStatus(s.httpMux, "/debug/test"))
+}
+
+func TestAdminListener_Start(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := New(WithAdminListener(ln), WithPlugin(&adminPlugin{}))
+	s.httpServer = &http.Server{}
+	require.NoError(t, s.startAdmin(s.baseContext))
+	defer func() { require.NoError(t, s.Shutdown()) }()
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+ln.Addr().String()+"/debug/test", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,1195 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: adminservice.proto
+
+package prefab
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Empty request object.
+type DescribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DescribeRequest) Reset() {
+	*x = DescribeRequest{}
+	mi := &file_adminservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DescribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DescribeRequest) ProtoMessage() {}
+
+func (x *DescribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DescribeRequest.ProtoReflect.Descriptor instead.
+func (*DescribeRequest) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{0}
+}
+
+// Summary of a running server, see Server.Describe.
+type ServerDescription struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Address the server listens on.
+	Address string `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	// Address of the admin listener, if there is one.
+	AdminAddress string `protobuf:"bytes,2,opt,name=admin_address,json=adminAddress,proto3" json:"admin_address,omitempty"`
+	// Whether connections are served over TLS.
+	Tls bool `protobuf:"varint,3,opt,name=tls,proto3" json:"tls,omitempty"`
+	// Whether client certificates are requested, see WithClientCertificates.
+	ClientCertificates bool `protobuf:"varint,4,opt,name=client_certificates,json=clientCertificates,proto3" json:"client_certificates,omitempty"`
+	// Registered plugins, in registration order.
+	Plugins []*PluginDescription `protobuf:"bytes,5,rep,name=plugins,proto3" json:"plugins,omitempty"`
+	// HTTP routes, excluding the GRPC Gateway.
+	Routes []*RouteDescription `protobuf:"bytes,6,rep,name=routes,proto3" json:"routes,omitempty"`
+	// GRPC services, including admin services.
+	Services []*ServiceDescription `protobuf:"bytes,7,rep,name=services,proto3" json:"services,omitempty"`
+	// Paths of Server-Sent Event endpoints.
+	SseEndpoints []string `protobuf:"bytes,8,rep,name=sse_endpoints,json=sseEndpoints,proto3" json:"sse_endpoints,omitempty"`
+	// Version of Go the server was built with.
+	GoVersion     string `protobuf:"bytes,9,opt,name=go_version,json=goVersion,proto3" json:"go_version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerDescription) Reset() {
+	*x = ServerDescription{}
+	mi := &file_adminservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerDescription) ProtoMessage() {}
+
+func (x *ServerDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerDescription.ProtoReflect.Descriptor instead.
+func (*ServerDescription) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ServerDescription) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *ServerDescription) GetAdminAddress() string {
+	if x != nil {
+		return x.AdminAddress
+	}
+	return ""
+}
+
+func (x *ServerDescription) GetTls() bool {
+	if x != nil {
+		return x.Tls
+	}
+	return false
+}
+
+func (x *ServerDescription) GetClientCertificates() bool {
+	if x != nil {
+		return x.ClientCertificates
+	}
+	return false
+}
+
+func (x *ServerDescription) GetPlugins() []*PluginDescription {
+	if x != nil {
+		return x.Plugins
+	}
+	return nil
+}
+
+func (x *ServerDescription) GetRoutes() []*RouteDescription {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *ServerDescription) GetServices() []*ServiceDescription {
+	if x != nil {
+		return x.Services
+	}
+	return nil
+}
+
+func (x *ServerDescription) GetSseEndpoints() []string {
+	if x != nil {
+		return x.SseEndpoints
+	}
+	return nil
+}
+
+func (x *ServerDescription) GetGoVersion() string {
+	if x != nil {
+		return x.GoVersion
+	}
+	return ""
+}
+
+type PluginDescription struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Plugin name.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Registry key, which includes the instance ID for named instances.
+	Key string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// Plugin version, or the version of the module which provides it.
+	Version       string `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PluginDescription) Reset() {
+	*x = PluginDescription{}
+	mi := &file_adminservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PluginDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PluginDescription) ProtoMessage() {}
+
+func (x *PluginDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PluginDescription.ProtoReflect.Descriptor instead.
+func (*PluginDescription) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PluginDescription) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PluginDescription) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *PluginDescription) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type RouteDescription struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pattern       string                 `protobuf:"bytes,1,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	Method        string                 `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Middleware    int32                  `protobuf:"varint,3,opt,name=middleware,proto3" json:"middleware,omitempty"`
+	Admin         bool                   `protobuf:"varint,4,opt,name=admin,proto3" json:"admin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteDescription) Reset() {
+	*x = RouteDescription{}
+	mi := &file_adminservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteDescription) ProtoMessage() {}
+
+func (x *RouteDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteDescription.ProtoReflect.Descriptor instead.
+func (*RouteDescription) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RouteDescription) GetPattern() string {
+	if x != nil {
+		return x.Pattern
+	}
+	return ""
+}
+
+func (x *RouteDescription) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *RouteDescription) GetMiddleware() int32 {
+	if x != nil {
+		return x.Middleware
+	}
+	return 0
+}
+
+func (x *RouteDescription) GetAdmin() bool {
+	if x != nil {
+		return x.Admin
+	}
+	return false
+}
+
+type ServiceDescription struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Name    string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Methods []*MethodDescription   `protobuf:"bytes,2,rep,name=methods,proto3" json:"methods,omitempty"`
+	// Whether the service is served on the admin listener.
+	Admin         bool `protobuf:"varint,3,opt,name=admin,proto3" json:"admin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServiceDescription) Reset() {
+	*x = ServiceDescription{}
+	mi := &file_adminservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServiceDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServiceDescription) ProtoMessage() {}
+
+func (x *ServiceDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServiceDescription.ProtoReflect.Descriptor instead.
+func (*ServiceDescription) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ServiceDescription) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ServiceDescription) GetMethods() []*MethodDescription {
+	if x != nil {
+		return x.Methods
+	}
+	return nil
+}
+
+func (x *ServiceDescription) GetAdmin() bool {
+	if x != nil {
+		return x.Admin
+	}
+	return false
+}
+
+type MethodDescription struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	ClientStreaming bool                   `protobuf:"varint,2,opt,name=client_streaming,json=clientStreaming,proto3" json:"client_streaming,omitempty"`
+	ServerStreaming bool                   `protobuf:"varint,3,opt,name=server_streaming,json=serverStreaming,proto3" json:"server_streaming,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *MethodDescription) Reset() {
+	*x = MethodDescription{}
+	mi := &file_adminservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MethodDescription) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MethodDescription) ProtoMessage() {}
+
+func (x *MethodDescription) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MethodDescription.ProtoReflect.Descriptor instead.
+func (*MethodDescription) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *MethodDescription) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MethodDescription) GetClientStreaming() bool {
+	if x != nil {
+		return x.ClientStreaming
+	}
+	return false
+}
+
+func (x *MethodDescription) GetServerStreaming() bool {
+	if x != nil {
+		return x.ServerStreaming
+	}
+	return false
+}
+
+// Empty request object.
+type GetLogSettingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLogSettingsRequest) Reset() {
+	*x = GetLogSettingsRequest{}
+	mi := &file_adminservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLogSettingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLogSettingsRequest) ProtoMessage() {}
+
+func (x *GetLogSettingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLogSettingsRequest.ProtoReflect.Descriptor instead.
+func (*GetLogSettingsRequest) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{6}
+}
+
+type SetLogLevelRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Prefix of the logger names to change, e.g. "/pkg.Service/". Loggers are
+	// named after the GRPC method or HTTP path of the request. Empty changes all
+	// loggers.
+	Logger string `protobuf:"bytes,1,opt,name=logger,proto3" json:"logger,omitempty"`
+	// One of debug, info, warn, or error. Empty removes the override.
+	Level string `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	// How long until the override is reverted. Unset overrides don't expire.
+	Duration      *durationpb.Duration `protobuf:"bytes,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetLogLevelRequest) Reset() {
+	*x = SetLogLevelRequest{}
+	mi := &file_adminservice_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetLogLevelRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetLogLevelRequest) ProtoMessage() {}
+
+func (x *SetLogLevelRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetLogLevelRequest.ProtoReflect.Descriptor instead.
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *SetLogLevelRequest) GetLogger() string {
+	if x != nil {
+		return x.Logger
+	}
+	return ""
+}
+
+func (x *SetLogLevelRequest) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *SetLogLevelRequest) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+type SetVerboseLoggingRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full GRPC method name, e.g. "/pkg.Service/Method". Empty matches any method.
+	Method string `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	// Subject of the caller's identity. Empty matches any caller.
+	Subject string `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	// Whether to enable or disable the rule.
+	Enabled bool `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	// How long until the rule expires, defaults to 15 minutes.
+	Duration      *durationpb.Duration `protobuf:"bytes,4,opt,name=duration,proto3" json:"duration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetVerboseLoggingRequest) Reset() {
+	*x = SetVerboseLoggingRequest{}
+	mi := &file_adminservice_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetVerboseLoggingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetVerboseLoggingRequest) ProtoMessage() {}
+
+func (x *SetVerboseLoggingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetVerboseLoggingRequest.ProtoReflect.Descriptor instead.
+func (*SetVerboseLoggingRequest) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *SetVerboseLoggingRequest) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *SetVerboseLoggingRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *SetVerboseLoggingRequest) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *SetVerboseLoggingRequest) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+type LogSettings struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Levels        []*LogLevelOverride    `protobuf:"bytes,1,rep,name=levels,proto3" json:"levels,omitempty"`
+	Verbose       []*VerboseLoggingRule  `protobuf:"bytes,2,rep,name=verbose,proto3" json:"verbose,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogSettings) Reset() {
+	*x = LogSettings{}
+	mi := &file_adminservice_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogSettings) ProtoMessage() {}
+
+func (x *LogSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogSettings.ProtoReflect.Descriptor instead.
+func (*LogSettings) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *LogSettings) GetLevels() []*LogLevelOverride {
+	if x != nil {
+		return x.Levels
+	}
+	return nil
+}
+
+func (x *LogSettings) GetVerbose() []*VerboseLoggingRule {
+	if x != nil {
+		return x.Verbose
+	}
+	return nil
+}
+
+type LogLevelOverride struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Logger        string                 `protobuf:"bytes,1,opt,name=logger,proto3" json:"logger,omitempty"`
+	Level         string                 `protobuf:"bytes,2,opt,name=level,proto3" json:"level,omitempty"`
+	ExpireTime    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expire_time,json=expireTime,proto3" json:"expire_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LogLevelOverride) Reset() {
+	*x = LogLevelOverride{}
+	mi := &file_adminservice_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LogLevelOverride) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LogLevelOverride) ProtoMessage() {}
+
+func (x *LogLevelOverride) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LogLevelOverride.ProtoReflect.Descriptor instead.
+func (*LogLevelOverride) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LogLevelOverride) GetLogger() string {
+	if x != nil {
+		return x.Logger
+	}
+	return ""
+}
+
+func (x *LogLevelOverride) GetLevel() string {
+	if x != nil {
+		return x.Level
+	}
+	return ""
+}
+
+func (x *LogLevelOverride) GetExpireTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpireTime
+	}
+	return nil
+}
+
+type VerboseLoggingRule struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Method        string                 `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
+	Subject       string                 `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	ExpireTime    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expire_time,json=expireTime,proto3" json:"expire_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *VerboseLoggingRule) Reset() {
+	*x = VerboseLoggingRule{}
+	mi := &file_adminservice_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *VerboseLoggingRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*VerboseLoggingRule) ProtoMessage() {}
+
+func (x *VerboseLoggingRule) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use VerboseLoggingRule.ProtoReflect.Descriptor instead.
+func (*VerboseLoggingRule) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *VerboseLoggingRule) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *VerboseLoggingRule) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *VerboseLoggingRule) GetExpireTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpireTime
+	}
+	return nil
+}
+
+// Empty request object.
+type GetStreamStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStreamStatsRequest) Reset() {
+	*x = GetStreamStatsRequest{}
+	mi := &file_adminservice_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStreamStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStreamStatsRequest) ProtoMessage() {}
+
+func (x *GetStreamStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStreamStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStreamStatsRequest) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{12}
+}
+
+// Open streams and limits, see WithStreamLimits.
+type StreamStats struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Streams open across the server.
+	Open int32 `protobuf:"varint,1,opt,name=open,proto3" json:"open,omitempty"`
+	// Limit on streams open across the server, 0 if unlimited.
+	MaxOpen int32 `protobuf:"varint,2,opt,name=max_open,json=maxOpen,proto3" json:"max_open,omitempty"`
+	// Limit on streams open for each caller, 0 if unlimited.
+	MaxPerIdentity int32 `protobuf:"varint,3,opt,name=max_per_identity,json=maxPerIdentity,proto3" json:"max_per_identity,omitempty"`
+	// Callers with open streams, by subject, most streams first.
+	Identities []*IdentityStreams `protobuf:"bytes,4,rep,name=identities,proto3" json:"identities,omitempty"`
+	// Stats for each SSE endpoint, see SSEStreamStats.
+	Endpoints     []*EndpointStreams `protobuf:"bytes,5,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamStats) Reset() {
+	*x = StreamStats{}
+	mi := &file_adminservice_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamStats) ProtoMessage() {}
+
+func (x *StreamStats) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamStats.ProtoReflect.Descriptor instead.
+func (*StreamStats) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *StreamStats) GetOpen() int32 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *StreamStats) GetMaxOpen() int32 {
+	if x != nil {
+		return x.MaxOpen
+	}
+	return 0
+}
+
+func (x *StreamStats) GetMaxPerIdentity() int32 {
+	if x != nil {
+		return x.MaxPerIdentity
+	}
+	return 0
+}
+
+func (x *StreamStats) GetIdentities() []*IdentityStreams {
+	if x != nil {
+		return x.Identities
+	}
+	return nil
+}
+
+func (x *StreamStats) GetEndpoints() []*EndpointStreams {
+	if x != nil {
+		return x.Endpoints
+	}
+	return nil
+}
+
+type IdentityStreams struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Open          int32                  `protobuf:"varint,2,opt,name=open,proto3" json:"open,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IdentityStreams) Reset() {
+	*x = IdentityStreams{}
+	mi := &file_adminservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IdentityStreams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IdentityStreams) ProtoMessage() {}
+
+func (x *IdentityStreams) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IdentityStreams.ProtoReflect.Descriptor instead.
+func (*IdentityStreams) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *IdentityStreams) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *IdentityStreams) GetOpen() int32 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+type EndpointStreams struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Path            string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Open            int64                  `protobuf:"varint,2,opt,name=open,proto3" json:"open,omitempty"`
+	Dropped         int64                  `protobuf:"varint,3,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	SlowDisconnects int64                  `protobuf:"varint,4,opt,name=slow_disconnects,json=slowDisconnects,proto3" json:"slow_disconnects,omitempty"`
+	Rejected        int64                  `protobuf:"varint,5,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EndpointStreams) Reset() {
+	*x = EndpointStreams{}
+	mi := &file_adminservice_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndpointStreams) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndpointStreams) ProtoMessage() {}
+
+func (x *EndpointStreams) ProtoReflect() protoreflect.Message {
+	mi := &file_adminservice_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndpointStreams.ProtoReflect.Descriptor instead.
+func (*EndpointStreams) Descriptor() ([]byte, []int) {
+	return file_adminservice_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *EndpointStreams) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *EndpointStreams) GetOpen() int64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *EndpointStreams) GetDropped() int64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+func (x *EndpointStreams) GetSlowDisconnects() int64 {
+	if x != nil {
+		return x.SlowDisconnects
+	}
+	return 0
+}
+
+func (x *EndpointStreams) GetRejected() int64 {
+	if x != nil {
+		return x.Rejected
+	}
+	return 0
+}
+
+var File_adminservice_proto protoreflect.FileDescriptor
+
+const file_adminservice_proto_rawDesc = "" +
+	"\n" +
+	"\x12adminservice.proto\x12\x06prefab\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x11\n" +
+	"\x0fDescribeRequest\"\xf8\x02\n" +
+	"\x11ServerDescription\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12#\n" +
+	"\radmin_address\x18\x02 \x01(\tR\fadminAddress\x12\x10\n" +
+	"\x03tls\x18\x03 \x01(\bR\x03tls\x12/\n" +
+	"\x13client_certificates\x18\x04 \x01(\bR\x12clientCertificates\x123\n" +
+	"\aplugins\x18\x05 \x03(\v2\x19.prefab.PluginDescriptionR\aplugins\x120\n" +
+	"\x06routes\x18\x06 \x03(\v2\x18.prefab.RouteDescriptionR\x06routes\x126\n" +
+	"\bservices\x18\a \x03(\v2\x1a.prefab.ServiceDescriptionR\bservices\x12#\n" +
+	"\rsse_endpoints\x18\b \x03(\tR\fsseEndpoints\x12\x1d\n" +
+	"\n" +
+	"go_version\x18\t \x01(\tR\tgoVersion\"S\n" +
+	"\x11PluginDescription\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x10\n" +
+	"\x03key\x18\x02 \x01(\tR\x03key\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\"z\n" +
+	"\x10RouteDescription\x12\x18\n" +
+	"\apattern\x18\x01 \x01(\tR\apattern\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x1e\n" +
+	"\n" +
+	"middleware\x18\x03 \x01(\x05R\n" +
+	"middleware\x12\x14\n" +
+	"\x05admin\x18\x04 \x01(\bR\x05admin\"s\n" +
+	"\x12ServiceDescription\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x123\n" +
+	"\amethods\x18\x02 \x03(\v2\x19.prefab.MethodDescriptionR\amethods\x12\x14\n" +
+	"\x05admin\x18\x03 \x01(\bR\x05admin\"}\n" +
+	"\x11MethodDescription\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12)\n" +
+	"\x10client_streaming\x18\x02 \x01(\bR\x0fclientStreaming\x12)\n" +
+	"\x10server_streaming\x18\x03 \x01(\bR\x0fserverStreaming\"\x17\n" +
+	"\x15GetLogSettingsRequest\"y\n" +
+	"\x12SetLogLevelRequest\x12\x16\n" +
+	"\x06logger\x18\x01 \x01(\tR\x06logger\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x125\n" +
+	"\bduration\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\bduration\"\x9d\x01\n" +
+	"\x18SetVerboseLoggingRequest\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x18\n" +
+	"\aenabled\x18\x03 \x01(\bR\aenabled\x125\n" +
+	"\bduration\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\bduration\"u\n" +
+	"\vLogSettings\x120\n" +
+	"\x06levels\x18\x01 \x03(\v2\x18.prefab.LogLevelOverrideR\x06levels\x124\n" +
+	"\averbose\x18\x02 \x03(\v2\x1a.prefab.VerboseLoggingRuleR\averbose\"}\n" +
+	"\x10LogLevelOverride\x12\x16\n" +
+	"\x06logger\x18\x01 \x01(\tR\x06logger\x12\x14\n" +
+	"\x05level\x18\x02 \x01(\tR\x05level\x12;\n" +
+	"\vexpire_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expireTime\"\x83\x01\n" +
+	"\x12VerboseLoggingRule\x12\x16\n" +
+	"\x06method\x18\x01 \x01(\tR\x06method\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12;\n" +
+	"\vexpire_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expireTime\"\x17\n" +
+	"\x15GetStreamStatsRequest\"\xd6\x01\n" +
+	"\vStreamStats\x12\x12\n" +
+	"\x04open\x18\x01 \x01(\x05R\x04open\x12\x19\n" +
+	"\bmax_open\x18\x02 \x01(\x05R\amaxOpen\x12(\n" +
+	"\x10max_per_identity\x18\x03 \x01(\x05R\x0emaxPerIdentity\x127\n" +
+	"\n" +
+	"identities\x18\x04 \x03(\v2\x17.prefab.IdentityStreamsR\n" +
+	"identities\x125\n" +
+	"\tendpoints\x18\x05 \x03(\v2\x17.prefab.EndpointStreamsR\tendpoints\"?\n" +
+	"\x0fIdentityStreams\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x12\n" +
+	"\x04open\x18\x02 \x01(\x05R\x04open\"\x9a\x01\n" +
+	"\x0fEndpointStreams\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x12\n" +
+	"\x04open\x18\x02 \x01(\x03R\x04open\x12\x18\n" +
+	"\adropped\x18\x03 \x01(\x03R\adropped\x12)\n" +
+	"\x10slow_disconnects\x18\x04 \x01(\x03R\x0fslowDisconnects\x12\x1a\n" +
+	"\brejected\x18\x05 \x01(\x03R\brejected2\xe6\x02\n" +
+	"\fAdminService\x12>\n" +
+	"\bDescribe\x12\x17.prefab.DescribeRequest\x1a\x19.prefab.ServerDescription\x12D\n" +
+	"\x0eGetLogSettings\x12\x1d.prefab.GetLogSettingsRequest\x1a\x13.prefab.LogSettings\x12>\n" +
+	"\vSetLogLevel\x12\x1a.prefab.SetLogLevelRequest\x1a\x13.prefab.LogSettings\x12J\n" +
+	"\x11SetVerboseLogging\x12 .prefab.SetVerboseLoggingRequest\x1a\x13.prefab.LogSettings\x12D\n" +
+	"\x0eGetStreamStats\x12\x1d.prefab.GetStreamStatsRequest\x1a\x13.prefab.StreamStatsB\x18Z\x16github.com/dpup/prefabb\x06proto3"
+
+var (
+	file_adminservice_proto_rawDescOnce sync.Once
+	file_adminservice_proto_rawDescData []byte
+)
+
+func file_adminservice_proto_rawDescGZIP() []byte {
+	file_adminservice_proto_rawDescOnce.Do(func() {
+		file_adminservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_adminservice_proto_rawDesc), len(file_adminservice_proto_rawDesc)))
+	})
+	return file_adminservice_proto_rawDescData
+}
+
+var file_adminservice_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_adminservice_proto_goTypes = []any{
+	(*DescribeRequest)(nil),          // 0: prefab.DescribeRequest
+	(*ServerDescription)(nil),        // 1: prefab.ServerDescription
+	(*PluginDescription)(nil),        // 2: prefab.PluginDescription
+	(*RouteDescription)(nil),         // 3: prefab.RouteDescription
+	(*ServiceDescription)(nil),       // 4: prefab.ServiceDescription
+	(*MethodDescription)(nil),        // 5: prefab.MethodDescription
+	(*GetLogSettingsRequest)(nil),    // 6: prefab.GetLogSettingsRequest
+	(*SetLogLevelRequest)(nil),       // 7: prefab.SetLogLevelRequest
+	(*SetVerboseLoggingRequest)(nil), // 8: prefab.SetVerboseLoggingRequest
+	(*LogSettings)(nil),              // 9: prefab.LogSettings
+	(*LogLevelOverride)(nil),         // 10: prefab.LogLevelOverride
+	(*VerboseLoggingRule)(nil),       // 11: prefab.VerboseLoggingRule
+	(*GetStreamStatsRequest)(nil),    // 12: prefab.GetStreamStatsRequest
+	(*StreamStats)(nil),              // 13: prefab.StreamStats
+	(*IdentityStreams)(nil),          // 14: prefab.IdentityStreams
+	(*EndpointStreams)(nil),          // 15: prefab.EndpointStreams
+	(*durationpb.Duration)(nil),      // 16: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),    // 17: google.protobuf.Timestamp
+}
+var file_adminservice_proto_depIdxs = []int32{
+	2,  // 0: prefab.ServerDescription.plugins:type_name -> prefab.PluginDescription
+	3,  // 1: prefab.ServerDescription.routes:type_name -> prefab.RouteDescription
+	4,  // 2: prefab.ServerDescription.services:type_name -> prefab.ServiceDescription
+	5,  // 3: prefab.ServiceDescription.methods:type_name -> prefab.MethodDescription
+	16, // 4: prefab.SetLogLevelRequest.duration:type_name -> google.protobuf.Duration
+	16, // 5: prefab.SetVerboseLoggingRequest.duration:type_name -> google.protobuf.Duration
+	10, // 6: prefab.LogSettings.levels:type_name -> prefab.LogLevelOverride
+	11, // 7: prefab.LogSettings.verbose:type_name -> prefab.VerboseLoggingRule
+	17, // 8: prefab.LogLevelOverride.expire_time:type_name -> google.protobuf.Timestamp
+	17, // 9: prefab.VerboseLoggingRule.expire_time:type_name -> google.protobuf.Timestamp
+	14, // 10: prefab.StreamStats.identities:type_name -> prefab.IdentityStreams
+	15, // 11: prefab.StreamStats.endpoints:type_name -> prefab.EndpointStreams
+	0,  // 12: prefab.AdminService.Describe:input_type -> prefab.DescribeRequest
+	6,  // 13: prefab.AdminService.GetLogSettings:input_type -> prefab.GetLogSettingsRequest
+	7,  // 14: prefab.AdminService.SetLogLevel:input_type -> prefab.SetLogLevelRequest
+	8,  // 15: prefab.AdminService.SetVerboseLogging:input_type -> prefab.SetVerboseLoggingRequest
+	12, // 16: prefab.AdminService.GetStreamStats:input_type -> prefab.GetStreamStatsRequest
+	1,  // 17: prefab.AdminService.Describe:output_type -> prefab.ServerDescription
+	9,  // 18: prefab.AdminService.GetLogSettings:output_type -> prefab.LogSettings
+	9,  // 19: prefab.AdminService.SetLogLevel:output_type -> prefab.LogSettings
+	9,  // 20: prefab.AdminService.SetVerboseLogging:output_type -> prefab.LogSettings
+	13, // 21: prefab.AdminService.GetStreamStats:output_type -> prefab.StreamStats
+	17, // [17:22] is the sub-list for method output_type
+	12, // [12:17] is the sub-list for method input_type
+	12, // [12:12] is the sub-list for extension type_name
+	12, // [12:12] is the sub-list for extension extendee
+	0,  // [0:12] is the sub-list for field type_name
+}
+
+func init() { file_adminservice_proto_init() }
+func file_adminservice_proto_init() {
+	if File_adminservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_adminservice_proto_rawDesc), len(file_adminservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   16,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_adminservice_proto_goTypes,
+		DependencyIndexes: file_adminservice_proto_depIdxs,
+		MessageInfos:      file_adminservice_proto_msgTypes,
+	}.Build()
+	File_adminservice_proto = out.File
+	file_adminservice_proto_goTypes = nil
+	file_adminservice_proto_depIdxs = nil
+}
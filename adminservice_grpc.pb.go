@@ -0,0 +1,299 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: adminservice.proto
+
+package prefab
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AdminService_Describe_FullMethodName          = "/prefab.AdminService/Describe"
+	AdminService_GetLogSettings_FullMethodName    = "/prefab.AdminService/GetLogSettings"
+	AdminService_SetLogLevel_FullMethodName       = "/prefab.AdminService/SetLogLevel"
+	AdminService_SetVerboseLogging_FullMethodName = "/prefab.AdminService/SetVerboseLogging"
+	AdminService_GetStreamStats_FullMethodName    = "/prefab.AdminService/GetStreamStats"
+)
+
+// AdminServiceClient is the client API for AdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AdminService is served on the admin listener, and is not registered when
+// there is no separate admin listener.
+type AdminServiceClient interface {
+	// Describe returns a summary of what the server is running, so deployment
+	// tooling can verify the effective configuration.
+	Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*ServerDescription, error)
+	// GetLogSettings returns the active log level overrides and verbose logging
+	// rules.
+	GetLogSettings(ctx context.Context, in *GetLogSettingsRequest, opts ...grpc.CallOption) (*LogSettings, error)
+	// SetLogLevel changes the log level of all loggers, or of loggers whose name
+	// starts with a prefix, without restarting the server.
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogSettings, error)
+	// SetVerboseLogging enables or disables logging of request and response
+	// payloads for a method, a subject, or both. Rules expire automatically.
+	SetVerboseLogging(ctx context.Context, in *SetVerboseLoggingRequest, opts ...grpc.CallOption) (*LogSettings, error)
+	// GetStreamStats returns the number of open SSE streams, overall, for each
+	// caller, and for each endpoint, along with the configured limits.
+	GetStreamStats(ctx context.Context, in *GetStreamStatsRequest, opts ...grpc.CallOption) (*StreamStats, error)
+}
+
+type adminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAdminServiceClient(cc grpc.ClientConnInterface) AdminServiceClient {
+	return &adminServiceClient{cc}
+}
+
+func (c *adminServiceClient) Describe(ctx context.Context, in *DescribeRequest, opts ...grpc.CallOption) (*ServerDescription, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServerDescription)
+	err := c.cc.Invoke(ctx, AdminService_Describe_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetLogSettings(ctx context.Context, in *GetLogSettingsRequest, opts ...grpc.CallOption) (*LogSettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogSettings)
+	err := c.cc.Invoke(ctx, AdminService_GetLogSettings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*LogSettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogSettings)
+	err := c.cc.Invoke(ctx, AdminService_SetLogLevel_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetVerboseLogging(ctx context.Context, in *SetVerboseLoggingRequest, opts ...grpc.CallOption) (*LogSettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LogSettings)
+	err := c.cc.Invoke(ctx, AdminService_SetVerboseLogging_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetStreamStats(ctx context.Context, in *GetStreamStatsRequest, opts ...grpc.CallOption) (*StreamStats, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StreamStats)
+	err := c.cc.Invoke(ctx, AdminService_GetStreamStats_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AdminServiceServer is the server API for AdminService service.
+// All implementations must embed UnimplementedAdminServiceServer
+// for forward compatibility.
+//
+// AdminService is served on the admin listener, and is not registered when
+// there is no separate admin listener.
+type AdminServiceServer interface {
+	// Describe returns a summary of what the server is running, so deployment
+	// tooling can verify the effective configuration.
+	Describe(context.Context, *DescribeRequest) (*ServerDescription, error)
+	// GetLogSettings returns the active log level overrides and verbose logging
+	// rules.
+	GetLogSettings(context.Context, *GetLogSettingsRequest) (*LogSettings, error)
+	// SetLogLevel changes the log level of all loggers, or of loggers whose name
+	// starts with a prefix, without restarting the server.
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*LogSettings, error)
+	// SetVerboseLogging enables or disables logging of request and response
+	// payloads for a method, a subject, or both. Rules expire automatically.
+	SetVerboseLogging(context.Context, *SetVerboseLoggingRequest) (*LogSettings, error)
+	// GetStreamStats returns the number of open SSE streams, overall, for each
+	// caller, and for each endpoint, along with the configured limits.
+	GetStreamStats(context.Context, *GetStreamStatsRequest) (*StreamStats, error)
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+// UnimplementedAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAdminServiceServer struct{}
+
+func (UnimplementedAdminServiceServer) Describe(context.Context, *DescribeRequest) (*ServerDescription, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Describe not implemented")
+}
+func (UnimplementedAdminServiceServer) GetLogSettings(context.Context, *GetLogSettingsRequest) (*LogSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLogSettings not implemented")
+}
+func (UnimplementedAdminServiceServer) SetLogLevel(context.Context, *SetLogLevelRequest) (*LogSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLogLevel not implemented")
+}
+func (UnimplementedAdminServiceServer) SetVerboseLogging(context.Context, *SetVerboseLoggingRequest) (*LogSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetVerboseLogging not implemented")
+}
+func (UnimplementedAdminServiceServer) GetStreamStats(context.Context, *GetStreamStatsRequest) (*StreamStats, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStreamStats not implemented")
+}
+func (UnimplementedAdminServiceServer) mustEmbedUnimplementedAdminServiceServer() {}
+func (UnimplementedAdminServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AdminServiceServer will
+// result in compilation errors.
+type UnsafeAdminServiceServer interface {
+	mustEmbedUnimplementedAdminServiceServer()
+}
+
+func RegisterAdminServiceServer(s grpc.ServiceRegistrar, srv AdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AdminService_ServiceDesc, srv)
+}
+
+func _AdminService_Describe_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DescribeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).Describe(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_Describe_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).Describe(ctx, req.(*DescribeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetLogSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLogSettingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetLogSettings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetLogSettings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetLogSettings(ctx, req.(*GetLogSettingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetLogLevel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetVerboseLogging_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetVerboseLoggingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetVerboseLogging(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_SetVerboseLogging_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetVerboseLogging(ctx, req.(*SetVerboseLoggingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetStreamStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStreamStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetStreamStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AdminService_GetStreamStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetStreamStats(ctx, req.(*GetStreamStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AdminService_ServiceDesc is the grpc.ServiceDesc for AdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "prefab.AdminService",
+	HandlerType: (*AdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Describe",
+			Handler:    _AdminService_Describe_Handler,
+		},
+		{
+			MethodName: "GetLogSettings",
+			Handler:    _AdminService_GetLogSettings_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "SetVerboseLogging",
+			Handler:    _AdminService_SetVerboseLogging_Handler,
+		},
+		{
+			MethodName: "GetStreamStats",
+			Handler:    _AdminService_GetStreamStats_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "adminservice.proto",
+}
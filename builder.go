@@ -6,9 +6,11 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/dpup/prefab/internal/config"
 	"github.com/dpup/prefab/logging"
@@ -19,9 +21,11 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 // ServerOptions customize the configuration and operation of the GRPC server.
@@ -31,9 +35,12 @@ type handler struct {
 	prefix      string
 	httpHandler http.Handler
 	jsonHandler JSONHandler
+	middleware  []Middleware
 }
 
-// Options passed to runtime.JSONPb when building the server.
+// JSONMarshalOptions are the default options used to encode GRPC Gateway and
+// JSON handler responses. Use WithJSONMarshalOptions to configure a single
+// server, or method options to configure individual RPCs.
 var JSONMarshalOptions = protojson.MarshalOptions{
 	Multiline:       true,
 	Indent:          "  ",
@@ -54,13 +61,16 @@ func New(opts ...ServerOption) *Server {
 	}
 
 	b := &builder{
-		host:            Config.String("server.host"),
-		port:            Config.Int("server.port"),
-		incomingHeaders: Config.Strings("server.incomingHeaders"),
-		certFile:        Config.String("server.tls.certFile"),
-		keyFile:         Config.String("server.tls.keyFile"),
-		maxMsgSizeBytes: Config.Int("server.maxMsgSizeBytes"),
-		csrfSigningKey:  resolveCSRFSigningKey(),
+		host:              Config.String("server.host"),
+		port:              Config.Int("server.port"),
+		incomingHeaders:   Config.Strings("server.incomingHeaders"),
+		certFile:          Config.String("server.tls.certFile"),
+		keyFile:           Config.String("server.tls.keyFile"),
+		clientCAFile:      Config.String("server.tls.clientCAFile"),
+		requireClientCert: Config.Bool("server.tls.requireClientCert"),
+		maxMsgSizeBytes:   Config.Int("server.maxMsgSizeBytes"),
+		maxSendMsgBytes:   Config.Int("server.maxSendMsgSizeBytes"),
+		csrfSigningKey:    resolveCSRFSigningKey(),
 		securityHeaders: &SecurityHeaders{
 			XFramesOptions:        XFramesOptions(Config.String("server.security.xFramesOptions")),
 			HSTSExpiration:        Config.Duration("server.security.hstsExpiration"),
@@ -73,9 +83,50 @@ func New(opts ...ServerOption) *Server {
 			CORSAllowCredentials:  Config.Bool("server.security.corsAllowCredentials"),
 			CORSMaxAge:            Config.Duration("server.security.corsMaxAge"),
 		},
+		corsOverrides: corsOverridesFromConfig(),
+		adminHost:     Config.String("server.admin.host"),
+		adminPort:     Config.Int("server.admin.port"),
+		adminOnPublic: Config.Bool("server.admin.public"),
+		keepalive: keepalive.ServerParameters{
+			MaxConnectionIdle:     Config.Duration("server.grpc.maxConnectionIdle"),
+			MaxConnectionAge:      Config.Duration("server.grpc.maxConnectionAge"),
+			MaxConnectionAgeGrace: Config.Duration("server.grpc.maxConnectionAgeGrace"),
+			Time:                  Config.Duration("server.grpc.keepalive.time"),
+			Timeout:               Config.Duration("server.grpc.keepalive.timeout"),
+		},
+		keepalivePolicy: keepalive.EnforcementPolicy{
+			MinTime:             Config.Duration("server.grpc.keepalive.minTime"),
+			PermitWithoutStream: Config.Bool("server.grpc.keepalive.permitWithoutStream"),
+		},
+		maxConcurrentStreams:  Config.Int("server.grpc.maxConcurrentStreams"),
+		initialWindowSize:     Config.Int("server.grpc.initialWindowSize"),
+		initialConnWindowSize: Config.Int("server.grpc.initialConnWindowSize"),
+		requestTimeout:        Config.Duration("server.requestTimeout"),
+		maxRequestTimeout:     Config.Duration("server.maxRequestTimeout"),
+		streamLimits: StreamLimits{
+			MaxOpen:        Config.Int("server.streams.maxOpen"),
+			MaxPerIdentity: Config.Int("server.streams.maxPerIdentity"),
+			RetryAfter:     Config.Duration("server.streams.retryAfter"),
+		},
+		enforceSunset:     Config.Bool("server.enforceSunset"),
+		defaultAPIVersion: Config.String("server.versions.default"),
+		locality: serverutil.Locality{
+			Region:   Config.String("server.locality.region"),
+			Zone:     Config.String("server.locality.zone"),
+			Instance: Config.String("server.locality.instance"),
+		},
+		localityHeaders:    Config.Bool("server.locality.headers"),
+		trustedProxies:     Config.Strings("server.proxy.trusted"),
+		proxyProtocol:      Config.Bool("server.proxy.protocol"),
+		forwardedAddress:   Config.Bool("server.proxy.forwardedAddress"),
+		backgroundWarmup:   Config.Bool("server.backgroundWarmup"),
+		watchConfig:        Config.Bool("server.watchConfig"),
+		jsonMarshalOptions: JSONMarshalOptions,
+		pooledJSONBuffers:  Config.Bool("server.pooledJSONBuffers"),
 
 		plugins: &Registry{},
 	}
+	virtualHostsFromConfig(b)
 	for _, opt := range opts {
 		opt(b)
 	}
@@ -85,44 +136,116 @@ func New(opts ...ServerOption) *Server {
 
 	// Add headers from CORS allow-list to propagate to the gRPC server. (Dupes don't matter)
 	b.incomingHeaders = append(b.incomingHeaders, b.securityHeaders.CORSAllowHeaders...)
+	for _, o := range b.corsOverrides {
+		b.incomingHeaders = append(b.incomingHeaders, o.AllowHeaders...)
+	}
+	for _, vh := range b.virtualHosts {
+		if vh.securityHeaders != nil {
+			b.incomingHeaders = append(b.incomingHeaders, vh.securityHeaders.CORSAllowHeaders...)
+		}
+	}
 
 	return b.build()
 }
 
 type builder struct {
-	baseContext     context.Context
-	logger          logging.Logger
-	host            string
-	port            int
-	incomingHeaders []string
-	certFile        string
-	keyFile         string
-	maxMsgSizeBytes int
-	csrfSigningKey  []byte
-	securityHeaders *SecurityHeaders
+	baseContext       context.Context
+	logger            logging.Logger
+	host              string
+	port              int
+	incomingHeaders   []string
+	certFile          string
+	keyFile           string
+	clientCAFile      string
+	requireClientCert bool
+	maxMsgSizeBytes   int
+	maxSendMsgBytes   int
+	csrfSigningKey    []byte
+	securityHeaders   *SecurityHeaders
+	corsOverrides     map[string]CORSOverride
+	listener          net.Listener
+
+	adminHost     string
+	adminPort     int
+	adminListener net.Listener
+	adminOnPublic bool
+	adminHandlers []handler
+
+	keepalive             keepalive.ServerParameters
+	keepalivePolicy       keepalive.EnforcementPolicy
+	maxConcurrentStreams  int
+	initialWindowSize     int
+	initialConnWindowSize int
+
+	requestTimeout    time.Duration
+	maxRequestTimeout time.Duration
+	routeTimeouts     map[string]time.Duration
+
+	streamLimits StreamLimits
+
+	enforceSunset bool
+
+	apiVersions       []*apiVersion
+	defaultAPIVersion string
+
+	locality        serverutil.Locality
+	localityHeaders bool
+
+	trustedProxies   []string
+	proxyProtocol    bool
+	forwardedAddress bool
+
+	virtualHosts []*virtualHost
+
+	backgroundWarmup bool
+	watchConfig      bool
+
+	jsonMarshalOptions   protojson.MarshalOptions
+	jsonUnmarshalOptions protojson.UnmarshalOptions
+	pooledJSONBuffers    bool
 
 	plugins *Registry
 
-	handlers        []handler
-	interceptors    []grpc.UnaryServerInterceptor
-	serverBuilders  []func(s *Server)
-	configInjectors []ConfigInjector
-	clientConfigs   map[string]string
+	handlers          []handler
+	interceptors      []interceptor
+	serverBuilders    []func(s *Server)
+	configInjectors   []ConfigInjector
+	clientConfigs     map[string]*structpb.Value
+	clientConfigFuncs []ClientConfigFunc
 }
 
 func (b *builder) build() *Server {
 	if b.baseContext == nil {
 		b.baseContext = context.Background()
 	}
+	applyLogConfig(b.baseContext)
+
+	trustedProxies, err := serverutil.ParseTrustedProxies(b.trustedProxies)
+	if err != nil {
+		panic(err)
+	}
+	if b.proxyProtocol && len(trustedProxies) == 0 {
+		panic("prefab: the PROXY protocol requires trusted proxies, see WithTrustedProxies")
+	}
+	if len(trustedProxies) > 0 {
+		serverutil.SetTrustedProxies(trustedProxies)
+		// Runs before plugin injectors, so that they see the resolved address.
+		b.configInjectors = append([]ConfigInjector{forwardedInjector(b.forwardedAddress)}, b.configInjectors...)
+	} else {
+		serverutil.SetTrustedProxies(nil)
+	}
 
 	gatewayOpts := b.buildGatewayOpts()
+	jsonMarshaler := newJSONMarshaler(b.jsonMarshalOptions, b.jsonUnmarshalOptions)
+	jsonMarshaler.pooled = b.pooledJSONBuffers
 	gateway := runtime.NewServeMux(
 		// Override default JSON marshaler so that 0, false, and "" are emitted as
 		// actual values rather than undefined. This allows for better handling of
 		// PB wrapper types that allow for true, false, null.
-		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
-			MarshalOptions: JSONMarshalOptions,
-		}),
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, jsonMarshaler),
+
+		// Apply JSON options declared with method options.
+		runtime.WithForwardResponseRewriter(jsonMarshaler.rewriteResponse),
 
 		// Map CSRF query param to metadata.
 		runtime.WithMetadata(csrfMetadataAnnotator),
@@ -130,6 +253,22 @@ func (b *builder) build() *Server {
 		// Map request fields to metadata.
 		runtime.WithMetadata(serverutil.HttpMetadataAnnotator),
 
+		// Map field mask query params to metadata.
+		runtime.WithMetadata(fieldMaskMetadataAnnotator),
+
+		// Map the negotiated API version to metadata.
+		runtime.WithMetadata(apiVersionMetadataAnnotator),
+
+		// Map the scheme reported by trusted proxies to metadata.
+		runtime.WithMetadata(forwardedMetadataAnnotator),
+
+		// Remove fields that weren't requested with a field mask.
+		runtime.WithForwardResponseOption(fieldMaskForwarder),
+
+		// Set headers declared with method options. Must run before the status
+		// code is written.
+		runtime.WithForwardResponseOption(responseHeaderForwarder),
+
 		// Forward custom HTTP status codes for GRPC responses.
 		runtime.WithForwardResponseOption(statusCodeForwarder),
 
@@ -137,7 +276,7 @@ func (b *builder) build() *Server {
 		runtime.WithErrorHandler(gatewayErrorHandler),
 
 		// Support form encoded payloads.
-		runtime.WithMarshalerOption("application/x-www-form-urlencoded", &formDecoder{}),
+		runtime.WithMarshalerOption("application/x-www-form-urlencoded", &formDecoder{Marshaler: jsonMarshaler}),
 
 		// Support for standard headers plus propriety application headers.
 		runtime.WithIncomingHeaderMatcher(serverutil.HeaderMatcher(b.incomingHeaders)),
@@ -154,6 +293,10 @@ func (b *builder) build() *Server {
 	} else {
 		ctx = logging.EnsureLogger(ctx)
 	}
+	serverutil.SetLocality(b.locality)
+	if !b.locality.IsZero() {
+		ctx = logging.With(ctx, withLocalityLogger(logging.FromContext(ctx), b.locality))
+	}
 
 	// Check for unknown config keys and warn about potential typos
 	if warnings := config.ValidateConfigKeys(Config); len(warnings) > 0 {
@@ -166,63 +309,200 @@ func (b *builder) build() *Server {
 		port:        b.port,
 		certFile:    b.certFile,
 		keyFile:     b.keyFile,
+		clientAuth:  b.clientAuth(),
+		listener:    b.listener,
 		httpMux:     http.NewServeMux(),
 		grpcServer:  grpc.NewServer(b.buildGRPCOpts()...),
 		gatewayOpts: gatewayOpts,
 		grpcGateway: gateway,
 		plugins:     b.plugins,
+		streams:     newStreamTracker(b.streamLimits),
+		versions:    newAPIVersions(b.apiVersions, b.defaultAPIVersion, b.enforceSunset),
+
+		backgroundWarmup: b.backgroundWarmup,
+		watchConfig:      b.watchConfig,
+	}
+	if b.localityHeaders {
+		s.localityHeaders = b.locality
+	}
+	if len(trustedProxies) > 0 {
+		s.trustedProxies = trustedProxies
+		s.proxyProtocol = b.proxyProtocol
+	}
+	s.hostCertificates = b.hostCertificates()
+	if b.hasAdminListener() {
+		s.adminHost = b.adminHost
+		if s.adminHost == "" {
+			s.adminHost = defaultAdminHost
+		}
+		s.adminPort = b.adminPort
+		s.adminListener = b.adminListener
+		s.adminMux = http.NewServeMux()
+		s.adminGRPCServer = grpc.NewServer(b.buildGRPCOpts()...)
+	} else {
+		s.adminOnPublic = b.adminOnPublic
 	}
 
 	for _, fn := range b.serverBuilders {
 		fn(s)
 	}
 
-	s.httpMux.Handle("/api/", securityMiddleware(conditionalResponse(http.Handler(gateway)), b.securityHeaders))
-	for _, h := range b.handlers {
+	security := newSecurityPolicy(b.securityHeaders, b.corsOverrides)
+	api := b.gatewayDeadlineMiddleware(conditionalResponse(gateway))
+	if !b.hasGatewayHosts() {
+		s.httpMux.Handle("/api/", securityMiddleware(s.versions.middleware(api), security))
+	}
+	mount := func(mux *http.ServeMux, h handler, admin bool, host string, security *securityPolicy) {
 		var handler http.Handler
 		if h.jsonHandler != nil {
-			handler = wrapJSONHandler(h.jsonHandler)
+			handler = wrapJSONHandler(h.jsonHandler, b.jsonMarshalOptions)
 		} else {
 			handler = h.httpHandler
 		}
+		handler = chainMiddleware(handler, h.middleware)
+		handler = withRouteParams(handler)
+		if !admin {
+			handler = deadlineMiddleware(handler, b.timeoutForRoute(h.prefix), b.maxRequestTimeout)
+		}
 		handler = httpContextMiddleware(handler, b.configInjectors, gateway)
-		handler = securityMiddleware(handler, b.securityHeaders)
-		s.httpMux.Handle(h.prefix, handler)
+		handler = securityMiddleware(handler, security)
+		if host != "" {
+			mux.Handle(hostPattern(host, h.prefix), handler)
+		} else {
+			mux.Handle(h.prefix, handler)
+		}
+		s.routes = append(s.routes, Route{
+			Pattern:    h.prefix,
+			Method:     routeMethod(h.prefix),
+			Host:       host,
+			Middleware: len(h.middleware),
+			Admin:      admin && s.adminMux != nil,
+		})
+	}
+	for _, h := range b.handlers {
+		mount(s.httpMux, h, false, "", security)
+	}
+	for _, vh := range b.virtualHosts {
+		hostSecurity := security
+		if vh.securityHeaders != nil {
+			hostSecurity = newSecurityPolicy(vh.securityHeaders, b.corsOverrides)
+		}
+		if vh.gateway {
+			s.httpMux.Handle(vh.host+"/api/", securityMiddleware(s.versions.middleware(api), hostSecurity))
+		}
+		for _, h := range vh.handlers {
+			mount(s.httpMux, h, false, vh.host, hostSecurity)
+		}
+	}
+	// Without an admin listener, admin handlers are only served publicly if
+	// explicitly allowed. The readiness probe is always served.
+	adminMux := s.httpMux
+	if s.adminMux == nil && !b.adminOnPublic {
+		for _, h := range b.adminHandlers {
+			logging.Warnw(ctx, "prefab: admin handler not registered, there is no admin listener", "pattern", h.prefix)
+		}
+		b.adminHandlers = nil
+	}
+	b.adminHandlers = append(b.adminHandlers, handler{
+		prefix:      "GET /readyz",
+		httpHandler: http.HandlerFunc(s.readyHandler),
+	})
+	if s.adminMux != nil {
+		adminMux = s.adminMux
+		b.adminHandlers = append(b.adminHandlers, handler{
+			prefix:      "GET /debug/routes",
+			jsonHandler: func(*http.Request) (any, error) { return s.Routes(), nil },
+		}, handler{
+			prefix:      "GET /debug/interceptors",
+			jsonHandler: func(*http.Request) (any, error) { return s.Interceptors(), nil },
+		})
+		s.adminGRPCServer.RegisterService(&AdminService_ServiceDesc, &adminService{s: s})
+	}
+	for _, h := range b.adminHandlers {
+		mount(adminMux, h, true, "", security)
 	}
 
 	// Register the metaservice last so that it can see all the client configs.
-	m := &meta{configs: b.clientConfigs, csrfSigningKey: b.csrfSigningKey}
+	if _, err := nestClientConfig(b.clientConfigs); err != nil {
+		panic(err)
+	}
+	m := &meta{configs: b.clientConfigs, configFuncs: b.clientConfigFuncs, csrfSigningKey: b.csrfSigningKey, versions: s.versions}
 	s.ServiceRegistrar().RegisterService(&MetaService_ServiceDesc, m)
 	_ = RegisterMetaServiceHandlerFromEndpoint(s.GatewayArgs())
 
+	// Scoped interceptors are resolved last, once all services are registered.
+	s.interceptors = b.resolveInterceptors(s)
+
 	return s
 }
 func (b *builder) buildGRPCOpts() []grpc.ServerOption {
-	interceptors := append(
-		[]grpc.UnaryServerInterceptor{
-			configInterceptor(b.configInjectors),
-			logging.Interceptor(),
-			csrfInterceptor(b.csrfSigningKey),
-		},
-		b.interceptors...)
-	opts := []grpc.ServerOption{grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(interceptors...))}
+	chain := b.interceptorChain()
+	interceptors := make([]grpc.UnaryServerInterceptor, len(chain))
+	for i, ic := range chain {
+		interceptors[i] = ic.fn
+	}
+	unary := grpc_middleware.ChainUnaryServer(interceptors...)
+	opts := []grpc.ServerOption{
+		grpc.UnaryInterceptor(unary),
+		grpc.StreamInterceptor(streamInterceptor(unary)),
+	}
 	if b.isSecure() {
-		opts = append(opts, grpc.Creds(serverTLSFromFile(b.certFile, b.keyFile)))
+		opts = append(opts, grpc.Creds(serverTLSFromFile(b.certFile, b.keyFile, b.clientAuth())))
 	}
 	if b.maxMsgSizeBytes > 0 {
 		opts = append(opts, grpc.MaxRecvMsgSize(b.maxMsgSizeBytes))
 	}
+	if b.maxSendMsgBytes > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(b.maxSendMsgBytes))
+	}
+	if b.keepalive != (keepalive.ServerParameters{}) {
+		opts = append(opts, grpc.KeepaliveParams(b.keepalive))
+	}
+	if b.keepalivePolicy != (keepalive.EnforcementPolicy{}) {
+		opts = append(opts, grpc.KeepaliveEnforcementPolicy(b.keepalivePolicy))
+	}
+	// Sizes are validated to be positive and within range by ValidateConfig.
+	if b.maxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(b.maxConcurrentStreams))) //nolint:gosec // Range checked.
+	}
+	if b.initialWindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(int32(b.initialWindowSize))) //nolint:gosec // Range checked.
+	}
+	if b.initialConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(int32(b.initialConnWindowSize))) //nolint:gosec // Range checked.
+	}
 	return opts
 }
 
 func (b *builder) buildGatewayOpts() []grpc.DialOption {
 	opts := []grpc.DialOption{}
 	if b.isSecure() {
-		opts = append(opts, grpc.WithTransportCredentials(clientTLSFromFile(b.certFile)))
+		// When client certificates are required, the gateway presents the
+		// server's own certificate.
+		var clientKey string
+		if b.requireClientCert {
+			clientKey = b.keyFile
+		}
+		opts = append(opts, grpc.WithTransportCredentials(clientTLSFromFile(b.certFile, clientKey)))
 	} else {
 		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
-	// TODO: Allow a local connection for testing.
+	if d, ok := b.listener.(listenerDialer); ok {
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return d.DialContext(ctx)
+		}))
+	}
+	// Let the gateway send and receive messages as large as the server allows.
+	var callOpts []grpc.CallOption
+	if b.maxMsgSizeBytes > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(b.maxMsgSizeBytes))
+	}
+	if b.maxSendMsgBytes > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(b.maxSendMsgBytes))
+	}
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
 	return opts
 }
 
@@ -230,6 +510,19 @@ func (b *builder) isSecure() bool {
 	return b.certFile != "" && b.keyFile != ""
 }
 
+// clientAuth returns the client certificate policy, or nil if client
+// certificates are not configured.
+func (b *builder) clientAuth() *clientAuth {
+	if b.clientCAFile == "" {
+		return nil
+	}
+	ca := &clientAuth{pool: certPoolFromFile(b.clientCAFile), mode: tls.VerifyClientCertIfGiven}
+	if b.requireClientCert {
+		ca.mode = tls.RequireAndVerifyClientCert
+	}
+	return ca
+}
+
 // WithContext sets the base context for the server. This context will be used
 // for all requests and can be used to inject values into the context.
 func WithContext(ctx context.Context) ServerOption {
@@ -271,6 +564,42 @@ func WithPort(port int) ServerOption {
 	}
 }
 
+// WithListener configures the server to accept connections from ln, instead of
+// listening on the configured host and port. If ln has a
+// `DialContext(context.Context) (net.Conn, error)` method, such as a
+// bufconn.Listener, the GRPC Gateway uses it to connect to the GRPC server,
+// which allows tests to run a full server without touching the network.
+func WithListener(ln net.Listener) ServerOption {
+	return func(b *builder) {
+		b.listener = ln
+	}
+}
+
+// listenerDialer is implemented by in-memory listeners, such as bufconn.
+type listenerDialer interface {
+	DialContext(ctx context.Context) (net.Conn, error)
+}
+
+// WithClientCertificates configures the server to verify client certificates
+// against the CAs in caFile, enabling mutual TLS. If required is false,
+// certificates are verified when presented but clients without one are still
+// accepted, allowing browsers and services to share a listener. Requires
+// WithTLS.
+//
+// When required, the server's certificate is also used as the client
+// certificate for gateway requests, so must be issued by a CA in caFile and be
+// valid for client authentication.
+//
+// Verified certificates can be mapped to identities with the auth/mtls plugin.
+//
+// Config keys: `server.tls.clientCAFile`, `server.tls.requireClientCert`.
+func WithClientCertificates(caFile string, required bool) ServerOption {
+	return func(b *builder) {
+		b.clientCAFile = caFile
+		b.requireClientCert = required
+	}
+}
+
 // WithIncomingHeaders specifies a safe-list of headers that can be forwarded
 // via gRPC metadata with the `prefab` prefix. Headers that are allowed by
 // the CORS security config are automatically added to this list,
@@ -303,6 +632,61 @@ func WithMaxRecvMsgSize(maxMsgSizeBytes int) ServerOption {
 	}
 }
 
+// WithMaxSendMsgSize sets the maximum GRPC message size the server will send.
+// Default is unlimited.
+//
+// Config key: `server.maxSendMsgSizeBytes`.
+func WithMaxSendMsgSize(maxMsgSizeBytes int) ServerOption {
+	return func(b *builder) {
+		b.maxSendMsgBytes = maxMsgSizeBytes
+	}
+}
+
+// WithKeepalive sets keepalive and connection age parameters for GRPC
+// connections. Zero values use the GRPC defaults.
+//
+// Config keys: `server.grpc.keepalive.time`, `server.grpc.keepalive.timeout`,
+// `server.grpc.maxConnectionIdle`, `server.grpc.maxConnectionAge`,
+// `server.grpc.maxConnectionAgeGrace`.
+func WithKeepalive(params keepalive.ServerParameters) ServerOption {
+	return func(b *builder) {
+		b.keepalive = params
+	}
+}
+
+// WithKeepaliveEnforcementPolicy sets how often clients may send keepalive
+// pings. Clients that ping more often are disconnected.
+//
+// Config keys: `server.grpc.keepalive.minTime`,
+// `server.grpc.keepalive.permitWithoutStream`.
+func WithKeepaliveEnforcementPolicy(policy keepalive.EnforcementPolicy) ServerOption {
+	return func(b *builder) {
+		b.keepalivePolicy = policy
+	}
+}
+
+// WithMaxConcurrentStreams limits the number of concurrent streams, including
+// unary calls, per GRPC connection.
+//
+// Config key: `server.grpc.maxConcurrentStreams`.
+func WithMaxConcurrentStreams(n uint32) ServerOption {
+	return func(b *builder) {
+		b.maxConcurrentStreams = int(n)
+	}
+}
+
+// WithInitialWindowSize sets the HTTP/2 flow control window sizes for GRPC
+// streams and connections. Values below 64KB are ignored by GRPC.
+//
+// Config keys: `server.grpc.initialWindowSize`,
+// `server.grpc.initialConnWindowSize`.
+func WithInitialWindowSize(stream, conn int32) ServerOption {
+	return func(b *builder) {
+		b.initialWindowSize = int(stream)
+		b.initialConnWindowSize = int(conn)
+	}
+}
+
 // WithCRSFSigningKey sets the key used to sign CSRF tokens.
 //
 // Config key: `server.csrfSigningKey`.
@@ -332,7 +716,7 @@ func resolveCSRFSigningKey() []byte {
 }
 
 // WithSecurityHeaders sets the security headers that should be set on HTTP
-// responses.
+// responses. See WithCORSOverride to configure CORS for specific paths.
 //
 // Config keys:
 // - `server.security.xFramesOptions`
@@ -351,6 +735,51 @@ func WithSecurityHeaders(headers *SecurityHeaders) ServerOption {
 	}
 }
 
+// WithCORSOverride replaces the server-wide CORS configuration for requests
+// whose path starts with prefix. This can be used to open up a public API to
+// any origin, or to lock down a sensitive endpoint. Overrides apply to both
+// gateway routes and HTTP handlers, and the longest matching prefix wins.
+// Other security headers are inherited from the server-wide configuration.
+//
+// Example:
+//
+//	prefab.WithCORSOverride("/api/embed/", prefab.CORSOverride{
+//	    OriginValidator: func(origin string) bool {
+//	        return customers.IsKnownDomain(origin)
+//	    },
+//	})
+//
+// Config key: `server.security.corsOverrides`, a map of prefix to override
+// with keys `origins`, `allowMethods`, `allowHeaders`, `exposeHeaders`,
+// `allowCredentials`, and `maxAge`.
+func WithCORSOverride(prefix string, o CORSOverride) ServerOption {
+	return func(b *builder) {
+		if b.corsOverrides == nil {
+			b.corsOverrides = map[string]CORSOverride{}
+		}
+		b.corsOverrides[prefix] = o
+	}
+}
+
+// corsOverridesFromConfig reads per-prefix CORS overrides from
+// `server.security.corsOverrides`.
+func corsOverridesFromConfig() map[string]CORSOverride {
+	overrides := map[string]CORSOverride{}
+	c := Config.Cut("server.security.corsOverrides")
+	for _, prefix := range c.MapKeys("") {
+		o := c.Cut(prefix)
+		overrides[prefix] = CORSOverride{
+			Origins:          o.Strings("origins"),
+			AllowMethods:     o.Strings("allowMethods"),
+			AllowHeaders:     o.Strings("allowHeaders"),
+			ExposeHeaders:    o.Strings("exposeHeaders"),
+			AllowCredentials: o.Bool("allowCredentials"),
+			MaxAge:           o.Duration("maxAge"),
+		}
+	}
+	return overrides
+}
+
 // WithStaticFileServer configures the server to serve static files from disk
 // for HTTP requests that match the given prefix.
 func WithStaticFiles(prefix, dir string) ServerOption {
@@ -393,11 +822,20 @@ func WithJSONHandler(prefix string, h JSONHandler) ServerOption {
 	}
 }
 
-// WithGRPCInterceptor configures GRPC Unary Interceptors. They will be executed
-// in the order they were added.
-func WithGRPCInterceptor(interceptor grpc.UnaryServerInterceptor) ServerOption {
+// WithGRPCInterceptor configures GRPC Unary Interceptors. They are executed in
+// order of phase and priority, see InterceptorPhase, and otherwise in the order
+// they were added. Without options, interceptors run in PhaseApp. Interceptors
+// also run for streaming methods, see IsStreaming.
+func WithGRPCInterceptor(fn grpc.UnaryServerInterceptor, opts ...InterceptorOption) ServerOption {
 	return func(b *builder) {
-		b.interceptors = append(b.interceptors, interceptor)
+		ic := interceptor{fn: fn, phase: PhaseApp}
+		for _, opt := range opts {
+			opt(&ic)
+		}
+		if ic.name == "" {
+			ic.name = funcName(fn)
+		}
+		b.interceptors = append(b.interceptors, ic)
 	}
 }
 
@@ -437,7 +875,8 @@ func WithGRPCGateway(fn func(ctx context.Context, mux *runtime.ServeMux, endpoin
 
 // WithPlugin registers a plugin with the server's registry. Plugins will be
 // initialized at server start. If the Plugin implements `OptionProvider` then
-// additional server options can be configured for the server.
+// additional server options can be configured for the server, and if it
+// implements `AdminOptionProvider` then admin handlers can be registered.
 func WithPlugin(p Plugin) ServerOption {
 	return func(b *builder) {
 		if so, ok := p.(OptionProvider); ok {
@@ -445,18 +884,67 @@ func WithPlugin(p Plugin) ServerOption {
 				opt(b)
 			}
 		}
+		if ao, ok := p.(AdminOptionProvider); ok {
+			for _, opt := range ao.AdminOptions() {
+				opt(b)
+			}
+		}
 		b.plugins.Register(p)
 	}
 }
 
+// WithConditionalPlugin registers a plugin only if the boolean config key is
+// true, e.g. `plugins.oauth.enabled`, allowing plugins compiled into a binary
+// to be toggled per environment. When disabled, the plugin's options are not
+// applied and plugins which require it fail to start with an error naming the
+// key.
+func WithConditionalPlugin(key string, p Plugin) ServerOption {
+	return func(b *builder) {
+		RegisterConfigKey(ConfigKeyInfo{
+			Key:         key,
+			Description: "Enables the " + p.Name() + " plugin",
+			Type:        "bool",
+		})
+		if Config.Bool(key) {
+			WithPlugin(p)(b)
+		} else {
+			b.plugins.Disable(PluginKey(p), key)
+		}
+	}
+}
+
 // WithClientConfig adds a key value pair which will be made available to the
 // client via the metaservice.
 func WithClientConfig(key, value string) ServerOption {
+	return WithClientConfigValue(key, value)
+}
+
+// WithClientConfigValue adds a typed value which will be made available to the
+// client via the metaservice. Values can be anything that encodes to JSON, such
+// as bools, numbers, slices and maps.
+//
+// Dotted keys are namespaces: `features.search` is served in the response's
+// `values` as `{"features": {"search": ...}}`.
+func WithClientConfigValue(key string, value any) ServerOption {
+	v, err := clientConfigValue(key, value)
+	if err != nil {
+		panic(err)
+	}
 	return func(b *builder) {
 		if b.clientConfigs == nil {
-			b.clientConfigs = map[string]string{}
+			b.clientConfigs = map[string]*structpb.Value{}
 		}
-		b.clientConfigs[key] = value
+		b.clientConfigs[key] = v
+	}
+}
+
+// WithClientConfigFunc adds a function which computes client config values for
+// each request to the metaservice, for example feature flags which depend on
+// the user. Values override those added with WithClientConfig and
+// WithClientConfigValue.
+func WithClientConfigFunc(fn ClientConfigFunc) ServerOption {
+	return func(b *builder) {
+		b.clientConfigFuncs = append(b.clientConfigFuncs, fn)
 	}
 }
 
@@ -469,21 +957,50 @@ func WithRequestConfig(injector ConfigInjector) ServerOption {
 	}
 }
 
+// clientAuth holds the policy for verifying client certificates.
+type clientAuth struct {
+	pool *x509.CertPool
+	mode tls.ClientAuthType
+}
+
+func (ca *clientAuth) apply(c *tls.Config) {
+	if ca == nil {
+		return
+	}
+	c.ClientCAs = ca.pool
+	c.ClientAuth = ca.mode
+}
+
 // Creates credentials from a cert and key file.
 // Based on credentials.NewServerTLSFromFile.
-func serverTLSFromFile(cert, key string) credentials.TransportCredentials {
+func serverTLSFromFile(cert, key string, ca *clientAuth) credentials.TransportCredentials {
 	c, err := tls.LoadX509KeyPair(cert, key)
 	if err != nil {
 		panic(err)
 	}
 	tlsConfig := safeTLSConfig()
 	tlsConfig.Certificates = []tls.Certificate{c}
+	ca.apply(tlsConfig)
 	return credentials.NewTLS(tlsConfig)
 }
 
-// Based on credentials.NewClientTLSFromFile.
-func clientTLSFromFile(cert string) credentials.TransportCredentials {
-	b, err := os.ReadFile(cert)
+// Based on credentials.NewClientTLSFromFile. If key is provided, cert is also
+// presented as the client certificate.
+func clientTLSFromFile(cert, key string) credentials.TransportCredentials {
+	tlsConfig := safeTLSConfig()
+	tlsConfig.RootCAs = certPoolFromFile(cert)
+	if key != "" {
+		c, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			panic(err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{c}
+	}
+	return credentials.NewTLS(tlsConfig)
+}
+
+func certPoolFromFile(file string) *x509.CertPool {
+	b, err := os.ReadFile(file)
 	if err != nil {
 		panic(err)
 	}
@@ -491,9 +1008,7 @@ func clientTLSFromFile(cert string) credentials.TransportCredentials {
 	if !cp.AppendCertsFromPEM(b) {
 		panic("Failed to append credentials")
 	}
-	tlsConfig := safeTLSConfig()
-	tlsConfig.RootCAs = cp
-	return credentials.NewTLS(tlsConfig)
+	return cp
 }
 
 // TLS1.2 min and support for HTTP2.
@@ -0,0 +1,54 @@
+package prefab
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/internal/config"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/keepalive"
+)
+
+func TestBuildGRPCOpts_Tuning(t *testing.T) {
+	b := &builder{}
+	base := len(b.buildGRPCOpts())
+
+	for _, opt := range []ServerOption{
+		WithMaxRecvMsgSize(8 << 20),
+		WithMaxSendMsgSize(8 << 20),
+		WithKeepalive(keepalive.ServerParameters{Time: time.Minute, MaxConnectionAge: time.Hour}),
+		WithKeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{MinTime: 10 * time.Second}),
+		WithMaxConcurrentStreams(100),
+		WithInitialWindowSize(1<<20, 1<<22),
+	} {
+		opt(b)
+	}
+	assert.Len(t, b.buildGRPCOpts(), base+7)
+	assert.Len(t, b.buildGatewayOpts(), 2, "expected transport credentials and default call options")
+}
+
+func TestWithConditionalPlugin(t *testing.T) {
+	originalConfig := Config
+	defer func() { Config = originalConfig }()
+
+	Config = koanf.New(".")
+	require.NoError(t, Config.Load(confmap.Provider(map[string]interface{}{
+		"plugins.a.enabled": true,
+		"plugins.b.enabled": false,
+	}, "."), nil))
+
+	b := &builder{plugins: &Registry{}}
+	WithConditionalPlugin("plugins.a.enabled", &adminPlugin{})(b)
+	WithConditionalPlugin("plugins.b.enabled", &TestPlugin{name: "B"})(b)
+	WithConditionalPlugin("plugins.c.enabled", &TestPlugin{name: "C"})(b)
+
+	assert.NotNil(t, b.plugins.Get("admin_test"))
+	assert.Len(t, b.adminHandlers, 1, "options should be applied for enabled plugins")
+	assert.Nil(t, b.plugins.Get("B"))
+	assert.True(t, b.plugins.Disabled("B"))
+	assert.True(t, b.plugins.Disabled("C"), "unset keys disable the plugin")
+	assert.True(t, config.IsRegisteredKey("plugins.c.enabled"))
+}
@@ -0,0 +1,170 @@
+package prefab
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
+)
+
+// CheckPlugin is implemented by plugins which can verify their external
+// dependencies, such as a database connection, as part of Validate. Check is
+// called after Init and must not modify external state.
+type CheckPlugin interface {
+	Plugin
+
+	// Check returns an error describing why the plugin isn't ready to serve.
+	Check(ctx context.Context) error
+}
+
+type dryRunKey struct{}
+
+// IsDryRun returns whether plugins are being initialized by Validate, rather
+// than to serve traffic. Plugins should avoid side effects, such as creating
+// tables or starting background workers, when it returns true.
+func IsDryRun(ctx context.Context) bool {
+	v, _ := ctx.Value(dryRunKey{}).(bool)
+	return v
+}
+
+// CheckResult is the outcome of one step of Validate.
+type CheckResult struct {
+	Name string
+	Err  error
+}
+
+// CheckReport is returned by Validate.
+type CheckReport struct {
+	Results []CheckResult
+}
+
+// OK returns whether every check passed.
+func (r *CheckReport) OK() bool {
+	for _, res := range r.Results {
+		if res.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the report for display in a terminal or CI log.
+func (r *CheckReport) String() string {
+	var sb strings.Builder
+	for _, res := range r.Results {
+		if res.Err != nil {
+			fmt.Fprintf(&sb, "❌ %s: %v\n", res.Name, res.Err)
+		} else {
+			fmt.Fprintf(&sb, "✅ %s\n", res.Name)
+		}
+	}
+	if r.OK() {
+		sb.WriteString("\nAll checks passed.\n")
+	} else {
+		sb.WriteString("\nChecks failed.\n")
+	}
+	return sb.String()
+}
+
+func (r *CheckReport) add(name string, err error) {
+	r.Results = append(r.Results, CheckResult{Name: name, Err: err})
+}
+
+// Validate verifies that a server could start with the given options, without
+// binding any ports, so it can be used as a pre-deploy gate. It validates
+// config, builds the server, initializes plugins in dry-run mode, see
+// IsDryRun, and runs the checks of plugins implementing CheckPlugin, such as
+// storage connectivity and pending migrations. Plugins are shut down before
+// returning.
+//
+// Example:
+//
+//	if report := prefab.Validate(opts...); !report.OK() {
+//		log.Fatal(report)
+//	}
+func Validate(opts ...ServerOption) *CheckReport {
+	report := &CheckReport{}
+
+	config.EnsureDefaultsLoaded(Config)
+	if errs := ValidateConfig(); len(errs) > 0 {
+		for _, e := range errs {
+			report.add("config "+e.Key, errors.New(e.Message))
+		}
+		return report
+	}
+	report.add("config", nil)
+
+	s, err := buildForCheck(opts)
+	if err != nil {
+		report.add("build", err)
+		return report
+	}
+	report.add("build", nil)
+
+	ctx := context.WithValue(s.baseContext, ctxKey{}, s)
+	ctx = context.WithValue(ctx, dryRunKey{}, true)
+	if err := s.plugins.Init(ctx); err != nil {
+		report.add("plugins", err)
+		return report
+	}
+	report.add(fmt.Sprintf("plugins (%d initialized)", len(s.plugins.initOrder)), nil)
+
+	for _, key := range s.plugins.initOrder {
+		if p, ok := s.plugins.plugins[key].(CheckPlugin); ok {
+			report.add(key, p.Check(ctx))
+		}
+	}
+
+	if err := s.plugins.Shutdown(ctx); err != nil {
+		report.add("shutdown", err)
+	}
+	return report
+}
+
+// buildForCheck builds the server, converting panics from misconfigured
+// options into errors.
+func buildForCheck(opts []ServerOption) (s *Server, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+	return New(opts...), nil
+}
+
+// HandleCheckFlag runs Validate and exits if the program was started with
+// `--check`, printing the report and exiting non-zero if any check failed.
+// Otherwise it returns without doing anything. Call it at the start of main,
+// with the same options passed to New.
+//
+// Example:
+//
+//	func main() {
+//		opts := []prefab.ServerOption{prefab.WithPlugin(storage.Plugin(db))}
+//		prefab.HandleCheckFlag(opts...)
+//		s := prefab.New(opts...)
+//		...
+//	}
+func HandleCheckFlag(opts ...ServerOption) {
+	if !hasCheckFlag(os.Args[1:]) {
+		return
+	}
+	report := Validate(opts...)
+	fmt.Print(report)
+	if !report.OK() {
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func hasCheckFlag(args []string) bool {
+	for _, a := range args {
+		if a == "--check" || a == "-check" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package prefab
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type checkPlugin struct {
+	err      error
+	dryRun   bool
+	shutdown bool
+}
+
+func (p *checkPlugin) Name() string { return "checker" }
+
+func (p *checkPlugin) Init(ctx context.Context, _ *Registry) error {
+	p.dryRun = IsDryRun(ctx)
+	return nil
+}
+
+func (p *checkPlugin) Check(context.Context) error { return p.err }
+
+func (p *checkPlugin) Shutdown(context.Context) error {
+	p.shutdown = true
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	p := &checkPlugin{}
+	report := Validate(WithPlugin(p))
+	assert.True(t, report.OK(), report.String())
+	assert.True(t, p.dryRun, "plugins are initialized in dry-run mode")
+	assert.True(t, p.shutdown)
+	assert.Contains(t, report.String(), "✅ checker")
+	assert.Contains(t, report.String(), "All checks passed.")
+
+	p = &checkPlugin{err: errors.New("database unreachable")}
+	report = Validate(WithPlugin(p))
+	assert.False(t, report.OK())
+	assert.Contains(t, report.String(), "❌ checker: database unreachable")
+	assert.Contains(t, report.String(), "Checks failed.")
+}
+
+func TestValidate_InvalidConfig(t *testing.T) {
+	port := Config.Get("server.port")
+	t.Cleanup(func() { _ = Config.Set("server.port", port) })
+	require.NoError(t, Config.Set("server.port", 99999))
+
+	p := &checkPlugin{}
+	report := Validate(WithPlugin(p))
+	assert.False(t, report.OK())
+	assert.Contains(t, report.String(), "❌ config server.port")
+	assert.False(t, p.dryRun, "plugins aren't initialized with invalid config")
+}
+
+func TestValidate_BuildPanic(t *testing.T) {
+	report := Validate(func(*builder) { panic("bad option") })
+	assert.False(t, report.OK())
+	assert.Contains(t, report.String(), "❌ build: bad option")
+}
+
+func TestHasCheckFlag(t *testing.T) {
+	assert.True(t, hasCheckFlag([]string{"--check"}))
+	assert.True(t, hasCheckFlag([]string{"-v", "-check"}))
+	assert.False(t, hasCheckFlag([]string{"check"}))
+	assert.False(t, hasCheckFlag(nil))
+}
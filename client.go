@@ -0,0 +1,268 @@
+package prefab
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
+	"github.com/dpup/prefab/resilience"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// DialOption customizes a client connection created with Dial.
+type DialOption func(*dialConfig)
+
+type dialConfig struct {
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	insecure     bool
+	tlsConfig    *tls.Config
+	propagate    bool
+	grpcOpts     []grpc.DialOption
+
+	hedge          *hedgePolicy
+	deadlineMargin time.Duration
+}
+
+// WithCallTimeout sets the deadline applied to calls whose context doesn't
+// already have one. Zero disables the default deadline.
+//
+// Config key: `client.timeout`.
+func WithCallTimeout(d time.Duration) DialOption {
+	return func(c *dialConfig) {
+		c.timeout = d
+	}
+}
+
+// WithRetries configures how many times unary calls are retried when the
+// server returns Unavailable, and the initial backoff between attempts, which
+// doubles after each attempt.
+//
+// Config keys: `client.maxRetries`, `client.retryBackoff`.
+func WithRetries(maxRetries int, backoff time.Duration) DialOption {
+	return func(c *dialConfig) {
+		c.maxRetries = maxRetries
+		c.retryBackoff = backoff
+	}
+}
+
+// WithTLSConfig overrides the default TLS configuration, which uses the system
+// roots and requires TLS 1.2.
+func WithTLSConfig(cfg *tls.Config) DialOption {
+	return func(c *dialConfig) {
+		c.tlsConfig = cfg
+		c.insecure = false
+	}
+}
+
+// WithInsecure dials without transport security. Only use this for local
+// development or when TLS is terminated by a sidecar.
+//
+// Config key: `client.insecure`.
+func WithInsecure() DialOption {
+	return func(c *dialConfig) {
+		c.insecure = true
+	}
+}
+
+// WithoutCredentialPropagation stops the caller's credentials from being
+// forwarded on outgoing calls, for example when calling a third-party service.
+func WithoutCredentialPropagation() DialOption {
+	return func(c *dialConfig) {
+		c.propagate = false
+	}
+}
+
+// WithResilience applies a circuit breaker, retries, and bulkhead to calls on
+// the connection. If the policy retries, the built-in retries configured by
+// WithRetries are disabled.
+//
+// Example:
+//
+//	prefab.Dial(ctx, "billing.internal:443", prefab.WithResilience(resilience.Policy{
+//		Breaker: resilience.NewBreaker("billing"),
+//		Retry:   &resilience.RetryPolicy{MaxAttempts: 4},
+//	}))
+func WithResilience(p resilience.Policy) DialOption {
+	return func(c *dialConfig) {
+		if p.Retry != nil {
+			c.maxRetries = 0
+		}
+		c.grpcOpts = append(c.grpcOpts,
+			grpc.WithChainUnaryInterceptor(resilience.UnaryClientInterceptor(p)),
+			grpc.WithChainStreamInterceptor(resilience.StreamClientInterceptor(p)),
+		)
+	}
+}
+
+// WithDialOptions appends raw GRPC dial options.
+func WithDialOptions(opts ...grpc.DialOption) DialOption {
+	return func(c *dialConfig) {
+		c.grpcOpts = append(c.grpcOpts, opts...)
+	}
+}
+
+// Dial creates a GRPC client connection for calling other Prefab servers. The
+// connection is configured with interceptors that keep cross-service
+// conventions consistent:
+//
+//   - The caller's credentials, including delegated identities, are forwarded
+//     when the call is made with a request context.
+//   - The X-Request-ID of the incoming request is forwarded, or a new ID is
+//     generated.
+//   - Unary calls that fail with Unavailable are retried with backoff.
+//   - Calls without a deadline are given the `client.timeout` deadline, and
+//     calls made within a request never outlive the request's deadline.
+//
+// Example:
+//
+//	conn, err := prefab.Dial(ctx, "notes.internal:443")
+//	client := notes.NewNotesServiceClient(conn)
+//	resp, err := client.GetNote(ctx, req) // ctx is the incoming request context
+func Dial(_ context.Context, target string, opts ...DialOption) (*grpc.ClientConn, error) {
+	config.EnsureDefaultsLoaded(Config)
+
+	c := &dialConfig{
+		timeout:      ConfigDuration("client.timeout"),
+		maxRetries:   ConfigInt("client.maxRetries"),
+		retryBackoff: ConfigDuration("client.retryBackoff"),
+		insecure:     ConfigBool("client.insecure"),
+		propagate:    true,
+
+		deadlineMargin: ConfigDuration("client.deadlineMargin"),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	var creds credentials.TransportCredentials
+	if c.insecure {
+		creds = insecure.NewCredentials()
+	} else {
+		tlsConfig := c.tlsConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	grpcOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithChainUnaryInterceptor(c.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(c.streamInterceptor),
+	}
+	grpcOpts = append(grpcOpts, c.grpcOpts...)
+
+	conn, err := grpc.NewClient(target, grpcOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return conn, nil
+}
+
+func (c *dialConfig) unaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, cancel, err := c.budgetContext(c.outgoingContext(ctx))
+	defer cancel()
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok && c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	if c.hedge.applies(method) {
+		return c.hedge.invoke(ctx, method, req, reply, cc, invoker, opts...)
+	}
+
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || status.Code(err) != codes.Unavailable || attempt >= c.maxRetries {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *dialConfig) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	// Streams are long lived, so the default deadline is not applied. The
+	// margin is, since the stream must still end before the request does.
+	ctx, cancel, err := c.budgetContext(c.outgoingContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	s, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelStream{ClientStream: s, cancel: cancel}, nil
+}
+
+// outgoingContext adds the request ID and caller credentials to the outgoing
+// metadata, unless they have been set explicitly.
+func (c *dialConfig) outgoingContext(ctx context.Context) context.Context {
+	out, _ := metadata.FromOutgoingContext(ctx)
+
+	if len(out.Get(serverutil.RequestIDHeader)) == 0 {
+		id := serverutil.RequestID(ctx)
+		if id == "" {
+			id = uuid.NewString()
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, serverutil.RequestIDHeader, id)
+	}
+
+	if c.propagate && len(out.Get("authorization")) == 0 {
+		if authz := outgoingCredentials(ctx); authz != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authz)
+		}
+	}
+	return ctx
+}
+
+// CredentialsFunc returns the authorization value that should be forwarded on
+// outgoing calls made on behalf of the current request.
+type CredentialsFunc func(ctx context.Context) string
+
+type credentialsKey struct{}
+
+// WithOutgoingCredentials configures how Dial'd connections derive credentials
+// from the request context. Plugins, such as auth, use this to forward
+// identities that arrived by other means than the authorization header, e.g.
+// cookies. By default the incoming authorization header is forwarded.
+func WithOutgoingCredentials(ctx context.Context, fn CredentialsFunc) context.Context {
+	return context.WithValue(ctx, credentialsKey{}, fn)
+}
+
+func outgoingCredentials(ctx context.Context) string {
+	if fn, ok := ctx.Value(credentialsKey{}).(CredentialsFunc); ok {
+		return fn(ctx)
+	}
+	return incomingAuthorization(ctx)
+}
+
+func incomingAuthorization(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("authorization"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,238 @@
+package prefab
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type flakyHealth struct {
+	healthpb.UnimplementedHealthServer
+	failures int
+	calls    int
+	md       metadata.MD
+	deadline bool
+}
+
+func (h *flakyHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.calls++
+	h.md, _ = metadata.FromIncomingContext(ctx)
+	_, h.deadline = ctx.Deadline()
+	if h.calls <= h.failures {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func dialTest(t *testing.T, svc *flakyHealth, opts ...DialOption) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, svc)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	opts = append([]DialOption{
+		WithInsecure(),
+		WithRetries(2, time.Millisecond),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+	}, opts...)
+	conn, err := Dial(t.Context(), "passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestDial_Propagation(t *testing.T) {
+	svc := &flakyHealth{}
+	client := dialTest(t, svc)
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+		"authorization", "Bearer abc",
+		"x-request-id", "req-1",
+	))
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer abc"}, svc.md.Get("authorization"))
+	assert.Equal(t, []string{"req-1"}, svc.md.Get("x-request-id"))
+	assert.True(t, svc.deadline, "default deadline should be applied")
+}
+
+func TestDial_CustomCredentials(t *testing.T) {
+	svc := &flakyHealth{}
+	client := dialTest(t, svc)
+
+	ctx := WithOutgoingCredentials(t.Context(), func(context.Context) string { return "Bearer from-cookie" })
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer from-cookie"}, svc.md.Get("authorization"))
+	assert.Len(t, svc.md.Get("x-request-id"), 1, "request id should be generated")
+}
+
+func TestDial_WithoutCredentialPropagation(t *testing.T) {
+	svc := &flakyHealth{}
+	client := dialTest(t, svc, WithoutCredentialPropagation(), WithCallTimeout(0))
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer abc"))
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, svc.md.Get("authorization"))
+	assert.False(t, svc.deadline)
+}
+
+func TestDial_Retries(t *testing.T) {
+	t.Run("RecoversWithinLimit", func(t *testing.T) {
+		svc := &flakyHealth{failures: 2}
+		client := dialTest(t, svc)
+		_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, svc.calls)
+	})
+
+	t.Run("GivesUp", func(t *testing.T) {
+		svc := &flakyHealth{failures: 5}
+		client := dialTest(t, svc)
+		_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.Equal(t, 3, svc.calls)
+	})
+}
+
+func TestDial_Resilience(t *testing.T) {
+	svc := &flakyHealth{failures: 10}
+	client := dialTest(t, svc, WithResilience(resilience.Policy{
+		Breaker: resilience.NewBreaker("client_test", resilience.WithFailureThreshold(2)),
+		Retry:   &resilience.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+	}))
+
+	// The breaker opens after two failures, which stops the retries.
+	_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+	assert.ErrorIs(t, err, resilience.ErrOpen)
+	assert.Equal(t, 2, svc.calls, "built-in retries are disabled")
+}
+
+// slowHealth blocks the first call until it is cancelled.
+type slowHealth struct {
+	healthpb.UnimplementedHealthServer
+	mu        sync.Mutex
+	calls     int
+	cancelled bool
+	remaining time.Duration
+}
+
+func (h *slowHealth) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	h.mu.Lock()
+	h.calls++
+	attempt := strconv.Itoa(h.calls)
+	first := h.calls == 1
+	if deadline, ok := ctx.Deadline(); ok {
+		h.remaining = time.Until(deadline)
+	}
+	h.mu.Unlock()
+	_ = grpc.SendHeader(ctx, metadata.Pairs("attempt", attempt))
+	_ = grpc.SetTrailer(ctx, metadata.Pairs("attempt", attempt))
+	if first {
+		<-ctx.Done()
+		h.mu.Lock()
+		h.cancelled = true
+		h.mu.Unlock()
+		return nil, ctx.Err()
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func dialSlow(t *testing.T, svc *slowHealth, opts ...DialOption) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	healthpb.RegisterHealthServer(s, svc)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	opts = append([]DialOption{
+		WithInsecure(),
+		WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})),
+	}, opts...)
+	conn, err := Dial(t.Context(), "passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestDial_Hedging(t *testing.T) {
+	svc := &slowHealth{}
+	client := dialSlow(t, svc, WithHedging(10*time.Millisecond, 3, "/grpc.health.v1.Health/Check"))
+
+	resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+	assert.Eventually(t, func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		return svc.cancelled
+	}, time.Second, time.Millisecond, "the slow attempt is cancelled")
+	svc.mu.Lock()
+	assert.Equal(t, 2, svc.calls)
+	svc.mu.Unlock()
+}
+
+func TestDial_HedgingMetadata(t *testing.T) {
+	svc := &slowHealth{}
+	client := dialSlow(t, svc, WithHedging(10*time.Millisecond, 3, "/grpc.health.v1.Health/Check"))
+
+	var header, trailer metadata.MD
+	var p peer.Peer
+	_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{}, grpc.Header(&header), grpc.Trailer(&trailer), grpc.Peer(&p))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2"}, header.Get("attempt"), "the winning attempt's header is returned")
+	assert.Equal(t, []string{"2"}, trailer.Get("attempt"))
+	assert.NotNil(t, p.Addr)
+
+	// The losing attempt doesn't overwrite the winner's metadata once it ends.
+	assert.Eventually(t, func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		return svc.cancelled
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, []string{"2"}, header.Get("attempt"))
+}
+
+func TestDial_HedgingOnlyIdempotent(t *testing.T) {
+	h := &hedgePolicy{delay: time.Millisecond, maxAttempts: 2}
+	assert.False(t, h.applies("/grpc.health.v1.Health/Check"), "not declared idempotent")
+	assert.False(t, h.applies("/unknown.Service/Method"))
+}
+
+func TestDial_DeadlineMargin(t *testing.T) {
+	svc := &slowHealth{calls: 1}
+	client := dialSlow(t, svc, WithDeadlineMargin(200*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Second)
+	defer cancel()
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.LessOrEqual(t, svc.remaining, 800*time.Millisecond)
+
+	ctx, cancel = context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	_, err = client.Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 2, svc.calls, "call isn't sent once the budget is exhausted")
+}
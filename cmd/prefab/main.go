@@ -0,0 +1,95 @@
+// Command prefab scaffolds new prefab services.
+//
+// Usage:
+//
+//	go run github.com/dpup/prefab/cmd/prefab@latest new [flags] <name>
+//
+// The generated service has a proto definition with HTTP and authz
+// annotations, a plugin which registers the GRPC service and gateway, storage
+// and auth wiring, a config file, Makefile targets for generating code,
+// running, testing, and pre-deploy checks, and example tests.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage: prefab <command> [flags]
+
+Commands:
+  new    Scaffold a new prefab service
+
+Run "prefab <command> -h" for details.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "new":
+		return runNew(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "prefab: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
+
+func runNew(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("new", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	module := fs.String("module", "", "Go module path (default: the service name)")
+	dir := fs.String("dir", "", "Output directory (default: ./<name>)")
+	resource := fs.String("resource", "", "Name of the resource the service manages (default: the singular of the service name)")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, "Usage: prefab new [flags] <name>\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	// Allow flags after the name, e.g. `prefab new notes -module=...`.
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return 2
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	p, err := newProject(positional[0], *module, *resource)
+	if err != nil {
+		fmt.Fprintf(stderr, "prefab: %v\n", err)
+		return 1
+	}
+	if *dir == "" {
+		*dir = p.Name
+	}
+	if err := scaffold(*dir, p); err != nil {
+		fmt.Fprintf(stderr, "prefab: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "✅ Created %s in %s\n\nNext steps:\n\n", p.Service, *dir)
+	fmt.Fprintf(stdout, "  cd %s\n", *dir)
+	fmt.Fprint(stdout, "  make setup   # fetch prefab, install protoc plugins, generate code\n")
+	fmt.Fprint(stdout, "  make test\n")
+	fmt.Fprint(stdout, "  make run\n")
+	return 0
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"embed"
+	"encoding/hex"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"github.com/dpup/prefab/errors"
+	pluralize "github.com/gertd/go-pluralize"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// files maps templates to their destination, which may itself be a template.
+var files = []struct {
+	tmpl, path string
+}{
+	{"go.mod.tmpl", "go.mod"},
+	{"main.go.tmpl", "main.go"},
+	{"prefab.yaml.tmpl", "prefab.yaml"},
+	{"Makefile.tmpl", "Makefile"},
+	{"gitignore.tmpl", ".gitignore"},
+	{"README.md.tmpl", "README.md"},
+	{"service.proto.tmpl", "proto/{{.Name}}/{{.Name}}.proto"},
+	{"plugin.go.tmpl", "{{.Name}}/plugin.go"},
+	{"service.go.tmpl", "{{.Name}}/service.go"},
+	{"service_test.go.tmpl", "{{.Name}}/service_test.go"},
+}
+
+var nameRe = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// project holds the values templates are rendered with. For a service named
+// "notes", Name and PluralLower are "notes", Service is "NotesService",
+// Resource is "Note", and Plural is "Notes".
+type project struct {
+	Name          string
+	Module        string
+	Service       string
+	Resource      string
+	ResourceLower string
+	Plural        string
+	PluralLower   string
+
+	PrefabVersion  string
+	SigningKey     string
+	CSRFSigningKey string
+}
+
+func newProject(name, module, resource string) (project, error) {
+	if !nameRe.MatchString(name) {
+		return project{}, errors.Errorf("invalid name %q, must be lowercase letters and digits, starting with a letter", name)
+	}
+	if module == "" {
+		module = name
+	}
+	pl := pluralize.NewClient()
+	if resource == "" {
+		resource = pl.Singular(name)
+	}
+	if !nameRe.MatchString(resource) {
+		return project{}, errors.Errorf("invalid resource %q, must be lowercase letters and digits, starting with a letter", resource)
+	}
+	plural := pl.Plural(resource)
+	return project{
+		Name:           name,
+		Module:         module,
+		Service:        exported(name) + "Service",
+		Resource:       exported(resource),
+		ResourceLower:  resource,
+		Plural:         exported(plural),
+		PluralLower:    plural,
+		PrefabVersion:  prefabVersion(),
+		SigningKey:     randomKey(),
+		CSRFSigningKey: randomKey(),
+	}, nil
+}
+
+// scaffold renders the templates into dir, which must not exist or be empty.
+func scaffold(dir string, p project) error {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return errors.Errorf("%s already exists and is not empty", dir)
+	}
+	for _, f := range files {
+		path, err := render(f.path, f.path, p)
+		if err != nil {
+			return err
+		}
+		b, err := templates.ReadFile("templates/" + f.tmpl)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		out, err := render(f.tmpl, string(b), p)
+		if err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".go") {
+			formatted, err := format.Source([]byte(out))
+			if err != nil {
+				return errors.Errorf("formatting %s: %v", path, err)
+			}
+			out = string(formatted)
+		}
+		dest := filepath.Join(dir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if err := os.WriteFile(dest, []byte(out), 0o644); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+func render(name, text string, p project) (string, error) {
+	t, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, p); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return buf.String(), nil
+}
+
+func exported(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// prefabVersion returns the version of prefab the generator was built from, so
+// that `go run github.com/dpup/prefab/cmd/prefab@v0.7.0` scaffolds a service
+// using v0.7.0. Development builds fall back to the latest release.
+func prefabVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && strings.HasPrefix(info.Main.Version, "v") {
+		return info.Main.Version
+	}
+	return "latest"
+}
+
+func randomKey() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProject(t *testing.T) {
+	p, err := newProject("notes", "github.com/acme/notes", "")
+	require.NoError(t, err)
+	assert.Equal(t, "NotesService", p.Service)
+	assert.Equal(t, "Note", p.Resource)
+	assert.Equal(t, "note", p.ResourceLower)
+	assert.Equal(t, "Notes", p.Plural)
+	assert.Equal(t, "notes", p.PluralLower)
+	assert.Len(t, p.SigningKey, 48)
+	assert.NotEqual(t, p.SigningKey, p.CSRFSigningKey)
+
+	p, err = newProject("inventory", "", "item")
+	require.NoError(t, err)
+	assert.Equal(t, "inventory", p.Module)
+	assert.Equal(t, "Item", p.Resource)
+	assert.Equal(t, "Items", p.Plural)
+
+	_, err = newProject("My-Service", "", "")
+	require.Error(t, err)
+	_, err = newProject("notes", "", "Note")
+	require.Error(t, err)
+}
+
+func TestScaffold(t *testing.T) {
+	dir := t.TempDir()
+	p, err := newProject("notes", "github.com/acme/notes", "")
+	require.NoError(t, err)
+	require.NoError(t, scaffold(dir, p))
+
+	for _, f := range []string{
+		"go.mod", "main.go", "prefab.yaml", "Makefile", ".gitignore", "README.md",
+		"proto/notes/notes.proto", "notes/plugin.go", "notes/service.go", "notes/service_test.go",
+	} {
+		assert.FileExists(t, filepath.Join(dir, f))
+	}
+
+	gomod := read(t, dir, "go.mod")
+	assert.True(t, strings.HasPrefix(gomod, "module github.com/acme/notes\n"))
+
+	proto := read(t, dir, "proto/notes/notes.proto")
+	assert.Contains(t, proto, `option go_package = "github.com/acme/notes/notes";`)
+	assert.Contains(t, proto, `option (prefab.authz.action) = "notes.view";`)
+	assert.Contains(t, proto, `string id = 1 [(prefab.authz.id) = true];`)
+	assert.Contains(t, proto, `get: "/api/notes/{id}"`)
+
+	makefile := read(t, dir, "Makefile")
+	assert.Contains(t, makefile, `go list -m -f '{{.Dir}}' github.com/dpup/prefab`)
+	assert.Contains(t, makefile, "proto/notes/notes.proto")
+
+	assert.Contains(t, read(t, dir, "prefab.yaml"), "signingKey: "+p.SigningKey)
+	assert.Contains(t, read(t, dir, "main.go"), `"github.com/acme/notes/notes"`)
+
+	fset := token.NewFileSet()
+	for _, f := range []string{"main.go", "notes/plugin.go", "notes/service.go", "notes/service_test.go"} {
+		_, err := parser.ParseFile(fset, f, read(t, dir, f), parser.AllErrors)
+		assert.NoError(t, err, f)
+	}
+}
+
+func TestScaffold_NonEmptyDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o644))
+	p, err := newProject("notes", "", "")
+	require.NoError(t, err)
+	require.ErrorContains(t, scaffold(dir, p), "not empty")
+	assert.Equal(t, "package main", read(t, dir, "main.go"), "existing files aren't overwritten")
+}
+
+func TestRun(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "svc")
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"new", "notes", "-module=example.com/notes", "-dir", dir}, &stdout, &stderr)
+	require.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "Created NotesService in "+dir)
+	assert.FileExists(t, filepath.Join(dir, "notes", "service.go"))
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"new"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: prefab new")
+
+	stderr.Reset()
+	assert.Equal(t, 2, run([]string{"bogus"}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown command "bogus"`)
+
+	assert.Equal(t, 1, run([]string{"new", "-dir", dir, "notes"}, &stdout, &stderr), "refuses to overwrite")
+}
+
+func read(t *testing.T, dir, name string) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(b)
+}
@@ -0,0 +1,647 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dpup/prefab/internal/protoplugin"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// runtimeSource is written to runtimeFile and imported by the generated files.
+//
+//go:embed prefab.ts
+var runtimeSource string
+
+const runtimeFile = "prefab.ts"
+
+// options are the plugin's parameters.
+type options struct {
+	useProtoNames bool
+}
+
+// wellKnownTypes maps well-known types to the TypeScript type of their JSON
+// encoding.
+var wellKnownTypes = map[protoreflect.FullName]string{
+	"google.protobuf.Any":         `{ "@type": string; [key: string]: unknown }`,
+	"google.protobuf.BoolValue":   "boolean",
+	"google.protobuf.BytesValue":  "string",
+	"google.protobuf.DoubleValue": "number",
+	"google.protobuf.Duration":    "string",
+	"google.protobuf.Empty":       "Record<string, never>",
+	"google.protobuf.FieldMask":   "string",
+	"google.protobuf.FloatValue":  "number",
+	"google.protobuf.Int32Value":  "number",
+	"google.protobuf.Int64Value":  "string",
+	"google.protobuf.ListValue":   "unknown[]",
+	"google.protobuf.StringValue": "string",
+	"google.protobuf.Struct":      "Record<string, unknown>",
+	"google.protobuf.Timestamp":   "string",
+	"google.protobuf.UInt32Value": "number",
+	"google.protobuf.UInt64Value": "string",
+	"google.protobuf.Value":       "unknown",
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func generate(gen *protogen.Plugin, opts options) error {
+	generated := false
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		g := &fileGenerator{opts: opts, file: f, imports: map[string]string{}, runtime: map[string]bool{}}
+		content, err := g.generate()
+		if err != nil {
+			return err
+		}
+		if content == "" {
+			continue
+		}
+		out := gen.NewGeneratedFile(tsFile(f.Desc.Path()), "")
+		if _, err := out.Write([]byte(content)); err != nil {
+			return err
+		}
+		generated = true
+	}
+	if generated {
+		out := gen.NewGeneratedFile(runtimeFile, "")
+		out.P("// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.")
+		out.P()
+		if _, err := out.Write([]byte(runtimeSource)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileGenerator generates the TypeScript for a single proto file.
+type fileGenerator struct {
+	opts options
+	file *protogen.File
+	buf  strings.Builder
+
+	// Generated files which are imported, keyed by alias.
+	imports map[string]string
+
+	// Names imported from the runtime, and whether they're only used as types.
+	runtime map[string]bool
+}
+
+func (g *fileGenerator) p(parts ...string) {
+	for _, s := range parts {
+		g.buf.WriteString(s)
+	}
+	g.buf.WriteByte('\n')
+}
+
+func (g *fileGenerator) generate() (string, error) {
+	for _, e := range g.file.Enums {
+		g.enum(e)
+	}
+	for _, m := range g.file.Messages {
+		g.message(m)
+	}
+	for _, s := range g.file.Services {
+		if err := g.service(s); err != nil {
+			return "", err
+		}
+	}
+	if g.buf.Len() == 0 {
+		return "", nil
+	}
+
+	var out strings.Builder
+	out.WriteString("// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.\n")
+	out.WriteString("// source: " + g.file.Desc.Path() + "\n\n")
+	if len(g.runtime) > 0 {
+		var values, types []string
+		for name, typeOnly := range g.runtime {
+			if typeOnly {
+				types = append(types, name)
+			} else {
+				values = append(values, name)
+			}
+		}
+		sort.Strings(values)
+		sort.Strings(types)
+		runtime := importPath(g.file.Desc.Path(), runtimeFile)
+		if len(values) == 0 {
+			fmt.Fprintf(&out, "import type { %s } from %q;\n", strings.Join(types, ", "), runtime)
+		} else {
+			for i, t := range types {
+				types[i] = "type " + t
+			}
+			fmt.Fprintf(&out, "import { %s } from %q;\n", strings.Join(append(values, types...), ", "), runtime)
+		}
+	}
+	aliases := make([]string, 0, len(g.imports))
+	for alias := range g.imports {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		fmt.Fprintf(&out, "import type * as %s from %q;\n", alias, g.imports[alias])
+	}
+	if len(g.runtime) > 0 || len(aliases) > 0 {
+		out.WriteString("\n")
+	}
+	out.WriteString(strings.TrimSuffix(g.buf.String(), "\n"))
+	return out.String(), nil
+}
+
+func (g *fileGenerator) enum(e *protogen.Enum) {
+	g.comment("", e.Comments.Leading)
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = strconv.Quote(string(v.Desc.Name()))
+	}
+	line := "export type " + tsName(e.Desc) + " = " + strings.Join(values, " | ") + ";"
+	if len(line) <= 80 {
+		g.p(line)
+	} else {
+		g.p("export type ", tsName(e.Desc), " =")
+		for i, v := range values {
+			if i == len(values)-1 {
+				v += ";"
+			}
+			g.p("  | ", v)
+		}
+	}
+	g.p()
+}
+
+func (g *fileGenerator) message(m *protogen.Message) {
+	if m.Desc.IsMapEntry() {
+		return
+	}
+	g.comment("", m.Comments.Leading)
+	if len(m.Fields) == 0 {
+		g.p("export interface ", tsName(m.Desc), " {}")
+	} else {
+		g.p("export interface ", tsName(m.Desc), " {")
+		for _, f := range m.Fields {
+			g.comment("  ", f.Comments.Leading)
+			name := g.fieldName(f.Desc)
+			if !identifier.MatchString(name) {
+				name = strconv.Quote(name)
+			}
+			g.p("  ", name, "?: ", g.fieldType(f.Desc), ";")
+		}
+		g.p("}")
+	}
+	g.p()
+	for _, e := range m.Enums {
+		g.enum(e)
+	}
+	for _, nested := range m.Messages {
+		g.message(nested)
+	}
+}
+
+// method is the generated code for a service method.
+type method struct {
+	doc       string
+	signature string
+	body      []string
+}
+
+func (g *fileGenerator) service(s *protogen.Service) error {
+	var methods []method
+	for _, m := range s.Methods {
+		ssePath, err := protoplugin.SSEPath(m)
+		if err != nil {
+			return err
+		}
+		var call *httpCall
+		switch {
+		case ssePath != "":
+			call, err = g.sseCall(m, ssePath)
+		case m.Desc.IsStreamingClient() || m.Desc.IsStreamingServer():
+			// Only unary methods, and streams served as SSE, are supported.
+		default:
+			call, err = g.unaryCall(m)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", m.Desc.FullName(), err)
+		}
+		if call == nil {
+			continue
+		}
+		if v, ok := protoplugin.BoolOption(m.Desc.Options(), protoplugin.JSONUseProtoNamesField); ok && v != g.opts.useProtoNames {
+			fmt.Fprintf(os.Stderr, "protoc-gen-prefab-ts: warning: %s overrides json_use_proto_names, its response type won't match\n", m.Desc.FullName())
+		}
+		methods = append(methods, g.method(m, call))
+	}
+	if len(methods) == 0 {
+		return nil
+	}
+
+	g.use("Transport", true)
+	g.use("CallOptions", true)
+	g.comment("", s.Comments.Leading)
+	g.p("export class ", s.GoName, "Client {")
+	g.p("  private readonly transport: Transport;")
+	g.p()
+	g.p("  constructor(transport: Transport) {")
+	g.p("    this.transport = transport;")
+	g.p("  }")
+	for _, m := range methods {
+		g.p()
+		if m.doc != "" {
+			g.buf.WriteString(m.doc)
+		}
+		g.p("  ", m.signature, " {")
+		for _, line := range m.body {
+			g.p("    ", line)
+		}
+		g.p("  }")
+	}
+	g.p("}")
+	g.p()
+	return nil
+}
+
+// httpCall describes how a method is called over HTTP.
+type httpCall struct {
+	stream    bool
+	verb      string
+	path      string // TypeScript expression
+	query     string // TypeScript expression
+	body      string // TypeScript expression
+	queryMaps []string
+	result    string // TypeScript type
+}
+
+func (g *fileGenerator) method(m *protogen.Method, call *httpCall) method {
+	name := strings.ToLower(m.GoName[:1]) + m.GoName[1:]
+	req := g.typeRef(m.Input.Desc)
+	var gm method
+	gm.doc = jsdoc("  ", m.Comments.Leading)
+	fields := []string{"method: " + strconv.Quote(call.verb), "path: " + call.path}
+	if call.query != "" {
+		fields = append(fields, "query: "+call.query)
+	}
+	if call.body != "" {
+		fields = append(fields, "body: "+call.body)
+	}
+	if len(call.queryMaps) > 0 {
+		quoted := make([]string, len(call.queryMaps))
+		for i, p := range call.queryMaps {
+			quoted[i] = strconv.Quote(p)
+		}
+		fields = append(fields, "queryMaps: ["+strings.Join(quoted, ", ")+"]")
+	}
+	if call.stream {
+		gm.signature = fmt.Sprintf("%s(req: %s = {}, opts?: CallOptions): AsyncGenerator<%s>", name, req, call.result)
+		gm.body = append(gm.body, fmt.Sprintf("return this.transport.stream<%s>(", call.result))
+	} else {
+		gm.signature = fmt.Sprintf("%s(req: %s = {}, opts?: CallOptions): Promise<%s>", name, req, call.result)
+		gm.body = append(gm.body, fmt.Sprintf("return this.transport.unary<%s>(", call.result))
+	}
+	gm.body = append(gm.body, "  {")
+	for _, f := range fields {
+		gm.body = append(gm.body, "    "+f+",")
+	}
+	gm.body = append(gm.body, "  },", "  opts,", ");")
+	return gm
+}
+
+// unaryCall returns the call for a method's `google.api.http` rule, or nil if
+// it doesn't have one. If the rule has no body, but an additional binding does,
+// the additional binding is used so that fields such as credentials aren't sent
+// in the URL.
+func (g *fileGenerator) unaryCall(m *protogen.Method) (*httpCall, error) {
+	if !proto.HasExtension(m.Desc.Options(), annotations.E_Http) {
+		return nil, nil
+	}
+	rule, _ := proto.GetExtension(m.Desc.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if rule.GetBody() == "" {
+		for _, b := range rule.GetAdditionalBindings() {
+			if b.GetBody() != "" {
+				rule = b
+				break
+			}
+		}
+	}
+	call := &httpCall{}
+	var template string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		call.verb, template = "GET", p.Get
+	case *annotations.HttpRule_Put:
+		call.verb, template = "PUT", p.Put
+	case *annotations.HttpRule_Post:
+		call.verb, template = "POST", p.Post
+	case *annotations.HttpRule_Delete:
+		call.verb, template = "DELETE", p.Delete
+	case *annotations.HttpRule_Patch:
+		call.verb, template = "PATCH", p.Patch
+	case *annotations.HttpRule_Custom:
+		call.verb, template = strings.ToUpper(p.Custom.GetKind()), p.Custom.GetPath()
+	default:
+		return nil, nil
+	}
+
+	input := m.Input.Desc
+	pathExpr, consumed, err := g.pathExpr(template, input)
+	if err != nil {
+		return nil, err
+	}
+	call.path = pathExpr
+
+	switch body := rule.GetBody(); body {
+	case "*":
+		call.body = g.omit(consumed)
+	case "":
+		call.query, call.queryMaps = g.omit(consumed), g.queryMaps(input, consumed)
+	default:
+		fd := input.Fields().ByName(protoreflect.Name(body))
+		if fd == nil {
+			return nil, fmt.Errorf("body %q isn't a field of %s", body, input.FullName())
+		}
+		call.body = "req." + g.fieldName(fd)
+		if !slices.Contains(consumed, g.fieldName(fd)) {
+			consumed = append(consumed, g.fieldName(fd))
+		}
+		call.query, call.queryMaps = g.omit(consumed), g.queryMaps(input, consumed)
+	}
+
+	call.result = g.typeRef(m.Output.Desc)
+	if rb := rule.GetResponseBody(); rb != "" {
+		fd := m.Output.Desc.Fields().ByName(protoreflect.Name(rb))
+		if fd == nil {
+			return nil, fmt.Errorf("response_body %q isn't a field of %s", rb, m.Output.Desc.FullName())
+		}
+		call.result = g.fieldType(fd)
+	}
+	return call, nil
+}
+
+// sseCall returns the call for a method annotated with `(prefab.sse_path)`.
+func (g *fileGenerator) sseCall(m *protogen.Method, ssePath string) (*httpCall, error) {
+	input := m.Input.Desc
+	pathExpr, consumed, err := g.pathExpr(ssePath, input)
+	if err != nil {
+		return nil, err
+	}
+	return &httpCall{
+		stream:    true,
+		verb:      "GET",
+		path:      pathExpr,
+		query:     g.omit(consumed),
+		queryMaps: g.queryMaps(input, consumed),
+		result:    g.typeRef(m.Output.Desc),
+	}, nil
+}
+
+// pathExpr converts a path template, such as "/v1/{name=shelves/*}/books", to
+// a TypeScript template literal. It returns the names of the top-level request
+// fields which are sent in the path.
+func (g *fileGenerator) pathExpr(template string, input protoreflect.MessageDescriptor) (string, []string, error) {
+	var expr strings.Builder
+	var consumed []string
+	expr.WriteString("`")
+	for template != "" {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			expr.WriteString(escapeTemplate(template))
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("path %q has an unterminated parameter", template)
+		}
+		expr.WriteString(escapeTemplate(template[:start]))
+		param, pattern, _ := strings.Cut(template[start+1:start+end], "=")
+		template = template[start+end+1:]
+
+		access, top := "req", ""
+		msg := input
+		names := strings.Split(param, ".")
+		for i, name := range names {
+			var fd protoreflect.FieldDescriptor
+			if msg != nil {
+				fd = msg.Fields().ByName(protoreflect.Name(name))
+			}
+			if fd == nil {
+				return "", nil, fmt.Errorf("path parameter {%s} isn't a field of %s", param, input.FullName())
+			}
+			if i == 0 {
+				top = g.fieldName(fd)
+				access += "." + top
+			} else {
+				access += "?." + g.fieldName(fd)
+			}
+			msg = fd.Message()
+		}
+		// Nested fields are left in the request, the server ignores them in the
+		// query and overwrites them in the body.
+		if len(names) == 1 && !slices.Contains(consumed, top) {
+			consumed = append(consumed, top)
+		}
+		g.use("pathParam", false)
+		if pattern != "" && pattern != "*" {
+			fmt.Fprintf(&expr, "${pathParam(%s, %q, true)}", access, param)
+		} else {
+			fmt.Fprintf(&expr, "${pathParam(%s, %q)}", access, param)
+		}
+	}
+	expr.WriteString("`")
+	return expr.String(), consumed, nil
+}
+
+// omit returns an expression for the request without the given fields.
+func (g *fileGenerator) omit(fields []string) string {
+	if len(fields) == 0 {
+		return "req"
+	}
+	g.use("omit", false)
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = strconv.Quote(f)
+	}
+	return "omit(req, [" + strings.Join(quoted, ", ") + "])"
+}
+
+// queryMaps returns the paths of map fields which can be sent in the query, as
+// the query parameter parser expects them to be encoded as `field[key]`.
+func (g *fileGenerator) queryMaps(input protoreflect.MessageDescriptor, exclude []string) []string {
+	var paths []string
+	var walk func(msg protoreflect.MessageDescriptor, prefix string, seen map[protoreflect.FullName]bool)
+	walk = func(msg protoreflect.MessageDescriptor, prefix string, seen map[protoreflect.FullName]bool) {
+		fields := msg.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			name := g.fieldName(fd)
+			if prefix == "" && slices.Contains(exclude, name) {
+				continue
+			}
+			switch {
+			case fd.IsMap():
+				paths = append(paths, prefix+name)
+			case fd.IsList() || fd.Message() == nil:
+			case wellKnownTypes[fd.Message().FullName()] != "" || seen[fd.Message().FullName()]:
+			default:
+				seen[fd.Message().FullName()] = true
+				walk(fd.Message(), prefix+name+".", seen)
+				delete(seen, fd.Message().FullName())
+			}
+		}
+	}
+	walk(input, "", map[protoreflect.FullName]bool{input.FullName(): true})
+	return paths
+}
+
+func (g *fileGenerator) fieldName(fd protoreflect.FieldDescriptor) string {
+	if g.opts.useProtoNames {
+		return string(fd.Name())
+	}
+	return fd.JSONName()
+}
+
+func (g *fileGenerator) fieldType(fd protoreflect.FieldDescriptor) string {
+	if fd.IsMap() {
+		return "Record<string, " + g.singularType(fd.MapValue()) + ">"
+	}
+	t := g.singularType(fd)
+	if fd.IsList() {
+		if strings.Contains(t, "|") {
+			return "(" + t + ")[]"
+		}
+		return t + "[]"
+	}
+	if fd.Message() != nil {
+		return t + " | null"
+	}
+	return t
+}
+
+// singularType returns the type of the JSON encoding of a field's values.
+func (g *fileGenerator) singularType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return "boolean"
+	case protoreflect.StringKind, protoreflect.BytesKind:
+		return "string"
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return "string"
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return "null"
+		}
+		return g.typeRef(fd.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if t, ok := wellKnownTypes[fd.Message().FullName()]; ok {
+			return t
+		}
+		return g.typeRef(fd.Message())
+	default:
+		return "number"
+	}
+}
+
+// typeRef returns a reference to the type generated for a message or enum,
+// importing the file it's generated in if necessary.
+func (g *fileGenerator) typeRef(d protoreflect.Descriptor) string {
+	if md, ok := d.(protoreflect.MessageDescriptor); ok {
+		if t, ok := wellKnownTypes[md.FullName()]; ok {
+			return t
+		}
+	}
+	file := d.ParentFile().Path()
+	if file == g.file.Desc.Path() {
+		return tsName(d)
+	}
+	alias := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.TrimSuffix(file, ".proto"))
+	g.imports[alias] = importPath(g.file.Desc.Path(), tsFile(file))
+	return alias + "." + tsName(d)
+}
+
+// use records that a name is imported from the runtime.
+func (g *fileGenerator) use(name string, typeOnly bool) {
+	if prev, ok := g.runtime[name]; ok && !prev {
+		return
+	}
+	g.runtime[name] = typeOnly
+}
+
+func (g *fileGenerator) comment(indent string, c protogen.Comments) {
+	g.buf.WriteString(jsdoc(indent, c))
+}
+
+// jsdoc converts a proto comment to a JSDoc comment.
+func jsdoc(indent string, c protogen.Comments) string {
+	text := strings.TrimSpace(string(c))
+	if text == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(indent + "/**\n")
+	for _, line := range strings.Split(strings.TrimRight(string(c), "\n"), "\n") {
+		line = strings.TrimRight(strings.TrimPrefix(line, " "), " ")
+		line = strings.ReplaceAll(line, "*/", "*\\/")
+		if line == "" {
+			b.WriteString(indent + " *\n")
+		} else {
+			b.WriteString(indent + " * " + line + "\n")
+		}
+	}
+	b.WriteString(indent + " */\n")
+	return b.String()
+}
+
+// tsName returns the name of the type generated for a message or enum, nested
+// types are prefixed with their parent's name, e.g. Outer_Inner.
+func tsName(d protoreflect.Descriptor) string {
+	name := string(d.FullName())
+	if pkg := string(d.ParentFile().Package()); pkg != "" {
+		name = strings.TrimPrefix(name, pkg+".")
+	}
+	return strings.ReplaceAll(name, ".", "_")
+}
+
+// tsFile returns the name of the file generated for a proto file.
+func tsFile(protoPath string) string {
+	return strings.TrimSuffix(protoPath, ".proto") + ".prefab.ts"
+}
+
+// importPath returns the module specifier used to import target from the file
+// generated for protoPath.
+func importPath(protoPath, target string) string {
+	from := strings.Split(path.Dir(protoPath), "/")
+	if from[0] == "." {
+		from = nil
+	}
+	to := strings.Split(strings.TrimSuffix(target, ".ts"), "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	rel := strings.Repeat("../", len(from)-i) + strings.Join(to[i:], "/")
+	if !strings.HasPrefix(rel, "../") {
+		rel = "./" + rel
+	}
+	return rel
+}
+
+// escapeTemplate escapes text for use in a template literal.
+func escapeTemplate(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "`", "\\`", "${", "\\${").Replace(s)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/examples/ssestream/counterservice"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata")
+
+// run invokes the plugin for the given files, which may import any registered
+// file.
+func run(t *testing.T, opts options, files ...*descriptorpb.FileDescriptorProto) (map[string]string, error) {
+	t.Helper()
+	req := &pluginpb.CodeGeneratorRequest{}
+	seen := map[string]bool{}
+	var addDeps func(fd protoreflect.FileDescriptor)
+	addDeps = func(fd protoreflect.FileDescriptor) {
+		for i := 0; i < fd.Imports().Len(); i++ {
+			dep := fd.Imports().Get(i).FileDescriptor
+			if !seen[dep.Path()] {
+				seen[dep.Path()] = true
+				addDeps(dep)
+				req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(dep))
+			}
+		}
+	}
+	for _, f := range files {
+		fd, err := protodesc.NewFile(f, protoregistry.GlobalFiles)
+		require.NoError(t, err)
+		addDeps(fd)
+		req.ProtoFile = append(req.ProtoFile, f)
+		req.FileToGenerate = append(req.FileToGenerate, f.GetName())
+	}
+
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+	if err := generate(gen, opts); err != nil {
+		return nil, err
+	}
+	resp := gen.Response()
+	require.Empty(t, resp.GetError())
+	out := map[string]string{}
+	for _, f := range resp.GetFile() {
+		out[f.GetName()] = f.GetContent()
+	}
+	return out, nil
+}
+
+func TestGenerate_Golden(t *testing.T) {
+	out, err := run(t, options{},
+		protodesc.ToFileDescriptorProto(prefab.File_metaservice_proto),
+		protodesc.ToFileDescriptorProto(auth.File_plugins_auth_authservice_proto),
+		protodesc.ToFileDescriptorProto(counterservice.File_examples_ssestream_counterservice_counterservice_proto),
+	)
+	require.NoError(t, err)
+	require.Len(t, out, 4)
+	assert.Equal(t, "// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.\n\n"+runtimeSource, out["prefab.ts"])
+
+	for _, name := range []string{
+		"metaservice.prefab.ts",
+		"plugins/auth/authservice.prefab.ts",
+		"examples/ssestream/counterservice/counterservice.prefab.ts",
+	} {
+		golden := filepath.Join("testdata", name)
+		if *update {
+			require.NoError(t, os.MkdirAll(filepath.Dir(golden), 0o755))
+			require.NoError(t, os.WriteFile(golden, []byte(out[name]), 0o644))
+		}
+		want, err := os.ReadFile(golden)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), out[name], "%s is up to date, run with -update", name)
+	}
+}
+
+// testFile returns a library service, with methods which exercise the
+// different ways a request can be mapped to HTTP.
+func testFile(rules map[string]*annotations.HttpRule) *descriptorpb.FileDescriptorProto {
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	i64 := descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+	msg := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	enum := descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	var methods []*descriptorpb.MethodDescriptorProto
+	for _, name := range []string{"GetBook", "UpdateBook", "ListBooks", "WatchBooks"} {
+		m := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".library.BookRequest"),
+			OutputType: proto.String(".library.Book"),
+		}
+		if name == "WatchBooks" {
+			m.ServerStreaming = proto.Bool(true)
+		}
+		if rule, ok := rules[name]; ok {
+			m.Options = &descriptorpb.MethodOptions{}
+			proto.SetExtension(m.Options, annotations.E_Http, rule)
+		}
+		methods = append(methods, m)
+	}
+
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("library/library.proto"),
+		Package:    proto.String("library"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/library")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Book"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("name")},
+					{Name: proto.String("page_count"), Number: proto.Int32(2), Type: i64, Label: optional, JsonName: proto.String("pageCount")},
+					{Name: proto.String("published_at"), Number: proto.Int32(3), Type: msg, Label: optional, TypeName: proto.String(".google.protobuf.Timestamp"), JsonName: proto.String("publishedAt")},
+					{Name: proto.String("format"), Number: proto.Int32(4), Type: enum, Label: optional, TypeName: proto.String(".library.Book.Format"), JsonName: proto.String("format")},
+					{Name: proto.String("authors"), Number: proto.Int32(5), Type: msg, Label: repeated, TypeName: proto.String(".library.Author"), JsonName: proto.String("authors")},
+				},
+				EnumType: []*descriptorpb.EnumDescriptorProto{{
+					Name: proto.String("Format"),
+					Value: []*descriptorpb.EnumValueDescriptorProto{
+						{Name: proto.String("FORMAT_UNSPECIFIED"), Number: proto.Int32(0)},
+						{Name: proto.String("HARDCOVER"), Number: proto.Int32(1)},
+					},
+				}},
+			},
+			{
+				Name: proto.String("Author"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("display_name"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("displayName")},
+				},
+			},
+			{
+				Name: proto.String("BookRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{Name: proto.String("name"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("name")},
+					{Name: proto.String("book"), Number: proto.Int32(2), Type: msg, Label: optional, TypeName: proto.String(".library.Book"), JsonName: proto.String("book")},
+					{Name: proto.String("labels"), Number: proto.Int32(3), Type: msg, Label: repeated, TypeName: proto.String(".library.BookRequest.LabelsEntry"), JsonName: proto.String("labels")},
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name: proto.String("LabelsEntry"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						{Name: proto.String("key"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("key")},
+						{Name: proto.String("value"), Number: proto.Int32(2), Type: str, Label: optional, JsonName: proto.String("value")},
+					},
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+				}},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("Library"),
+			Method: methods,
+		}},
+	}
+}
+
+func TestGenerate_Messages(t *testing.T) {
+	out, err := run(t, options{}, testFile(nil))
+	require.NoError(t, err)
+	got := out["library/library.prefab.ts"]
+
+	assert.Contains(t, got, "export interface Book {\n"+
+		"  name?: string;\n"+
+		"  pageCount?: string;\n"+
+		"  publishedAt?: string | null;\n"+
+		"  format?: Book_Format;\n"+
+		"  authors?: Author[];\n"+
+		"}\n")
+	assert.Contains(t, got, `export type Book_Format = "FORMAT_UNSPECIFIED" | "HARDCOVER";`)
+	assert.Contains(t, got, "  labels?: Record<string, string>;\n")
+	assert.NotContains(t, got, "LabelsEntry")
+	assert.NotContains(t, got, "class LibraryClient", "methods without http rules are skipped")
+	assert.NotContains(t, got, "import", "nothing from the runtime is used")
+}
+
+func TestGenerate_UseProtoNames(t *testing.T) {
+	out, err := run(t, options{useProtoNames: true}, testFile(nil))
+	require.NoError(t, err)
+	assert.Contains(t, out["library/library.prefab.ts"], "  page_count?: string;\n")
+}
+
+func TestGenerate_HTTPRules(t *testing.T) {
+	out, err := run(t, options{}, testFile(map[string]*annotations.HttpRule{
+		"GetBook": {
+			Pattern:      &annotations.HttpRule_Get{Get: "/v1/{name=shelves/*/books/*}"},
+			ResponseBody: "authors",
+		},
+		"UpdateBook": {
+			Pattern: &annotations.HttpRule_Patch{Patch: "/v1/books/{book.name}"},
+			Body:    "book",
+		},
+		"ListBooks": {
+			Pattern: &annotations.HttpRule_Post{Post: "/v1/books:search"},
+			Body:    "*",
+		},
+		"WatchBooks": {
+			Pattern: &annotations.HttpRule_Get{Get: "/v1/books:watch"},
+		},
+	}))
+	require.NoError(t, err)
+	got := out["library/library.prefab.ts"]
+
+	assert.Contains(t, got, `import { omit, pathParam, type CallOptions, type Transport } from "../prefab";`)
+	assert.Contains(t, got, "  getBook(req: BookRequest = {}, opts?: CallOptions): Promise<Author[]> {\n"+
+		"    return this.transport.unary<Author[]>(\n"+
+		"      {\n"+
+		"        method: \"GET\",\n"+
+		"        path: `/v1/${pathParam(req.name, \"name\", true)}`,\n"+
+		"        query: omit(req, [\"name\"]),\n"+
+		"        queryMaps: [\"labels\"],\n"+
+		"      },\n")
+	assert.Contains(t, got, "path: `/v1/books/${pathParam(req.book?.name, \"book.name\")}`,\n"+
+		"        query: omit(req, [\"book\"]),\n"+
+		"        body: req.book,\n")
+	assert.Contains(t, got, "path: `/v1/books:search`,\n"+
+		"        body: req,\n")
+	assert.NotContains(t, got, "watchBooks", "streams are only generated for sse_path")
+}
+
+func TestGenerate_SSE(t *testing.T) {
+	f := testFile(nil)
+	f.Dependency = append(f.Dependency, "server.proto")
+	watch := f.Service[0].Method[3]
+	watch.Options = &descriptorpb.MethodOptions{}
+	proto.SetExtension(watch.Options, prefab.E_SsePath, "/books/{name}/watch")
+
+	out, err := run(t, options{}, f)
+	require.NoError(t, err)
+	assert.Contains(t, out["library/library.prefab.ts"],
+		"  watchBooks(req: BookRequest = {}, opts?: CallOptions): AsyncGenerator<Book> {\n"+
+			"    return this.transport.stream<Book>(\n"+
+			"      {\n"+
+			"        method: \"GET\",\n"+
+			"        path: `/books/${pathParam(req.name, \"name\")}/watch`,\n"+
+			"        query: omit(req, [\"name\"]),\n"+
+			"        queryMaps: [\"labels\"],\n"+
+			"      },\n")
+}
+
+func TestGenerate_InvalidPathParam(t *testing.T) {
+	_, err := run(t, options{}, testFile(map[string]*annotations.HttpRule{
+		"GetBook": {Pattern: &annotations.HttpRule_Get{Get: "/v1/books/{id}"}},
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "library.Library.GetBook: path parameter {id} isn't a field of library.BookRequest")
+}
+
+func TestImportPath(t *testing.T) {
+	assert.Equal(t, "./prefab", importPath("notes.proto", "prefab.ts"))
+	assert.Equal(t, "../../prefab", importPath("plugins/auth/authservice.proto", "prefab.ts"))
+	assert.Equal(t, "./other.prefab", importPath("a/b.proto", "a/other.prefab.ts"))
+	assert.Equal(t, "../c/d.prefab", importPath("a/b.proto", "c/d.prefab.ts"))
+}
+
+func TestJSDoc(t *testing.T) {
+	assert.Empty(t, jsdoc("", protogen.Comments("")))
+	assert.Equal(t, "  /**\n   * Gets a book.\n   *\n   * Ends with *\\/ escaped.\n   */\n",
+		jsdoc("  ", protogen.Comments(" Gets a book.\n\n Ends with */ escaped.\n")))
+}
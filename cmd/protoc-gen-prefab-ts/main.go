@@ -0,0 +1,47 @@
+// Command protoc-gen-prefab-ts is a protoc plugin which generates TypeScript
+// clients for prefab services.
+//
+// For each proto file it generates `<file>.prefab.ts`, containing interfaces
+// for the JSON encoding of messages and enums, and a client class for each
+// service. Methods with a `google.api.http` rule call the GRPC Gateway, and
+// server-streaming methods annotated with `(prefab.sse_path)` are consumed as
+// Server-Sent Events:
+//
+//	const transport = new Transport({ baseUrl: "https://api.example.com" });
+//	const notes = new NotesServiceClient(transport);
+//	const { note } = await notes.getNote({ id: "123" });
+//	for await (const update of notes.streamUpdates({ id: "123" })) { ... }
+//
+// The generated files import `prefab.ts`, a small runtime which is written to
+// the root of the output directory. It handles prefab's conventions: requests
+// send the CSRF header, are authenticated with the identity cookie or a bearer
+// token, and errors are thrown as a PrefabError, with the code name and the
+// user presentable message from the error response.
+//
+// By default fields use lowerCamelCase JSON names. If the server is configured
+// to use proto names, see prefab.WithJSONMarshalOptions, pass
+// `use_proto_names=true`.
+//
+// Usage:
+//
+//	go install github.com/dpup/prefab/cmd/protoc-gen-prefab-ts
+//	protoc --prefab-ts_out=web/src/api notes.proto
+package main
+
+import (
+	"flag"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	var flags flag.FlagSet
+	opts := options{}
+	flags.BoolVar(&opts.useProtoNames, "use_proto_names", false, "use proto field names rather than lowerCamelCase")
+
+	protogen.Options{ParamFunc: flags.Set}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		return generate(gen, opts)
+	})
+}
@@ -0,0 +1,36 @@
+// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.
+// source: examples/ssestream/counterservice/counterservice.proto
+
+import { omit, pathParam, type CallOptions, type Transport } from "../../../prefab";
+
+export interface CountRequest {
+  name?: string;
+  start?: number;
+  limit?: number;
+  intervalMs?: number;
+}
+
+export interface CountResponse {
+  name?: string;
+  count?: number;
+  timestamp?: string | null;
+}
+
+export class CounterServiceClient {
+  private readonly transport: Transport;
+
+  constructor(transport: Transport) {
+    this.transport = transport;
+  }
+
+  count(req: CountRequest = {}, opts?: CallOptions): AsyncGenerator<CountResponse> {
+    return this.transport.stream<CountResponse>(
+      {
+        method: "GET",
+        path: `/counter/${pathParam(req.name, "name")}`,
+        query: omit(req, ["name"]),
+      },
+      opts,
+    );
+  }
+}
@@ -0,0 +1,64 @@
+// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.
+// source: metaservice.proto
+
+import type { CallOptions, Transport } from "./prefab";
+
+export interface ClientConfigRequest {}
+
+export interface ClientConfigResponse {
+  configs?: Record<string, string>;
+  csrfToken?: string;
+  values?: Record<string, unknown> | null;
+  apiVersion?: string;
+  apiVersions?: ApiVersion[];
+}
+
+export interface ApiVersion {
+  name?: string;
+  default?: boolean;
+  sunset?: string;
+}
+
+export interface ErrorCatalogRequest {}
+
+export interface ErrorCatalogResponse {
+  errors?: CatalogError[];
+}
+
+export interface CatalogError {
+  domain?: string;
+  reason?: string;
+  code?: string;
+  httpStatus?: number;
+  message?: string;
+}
+
+export class MetaServiceClient {
+  private readonly transport: Transport;
+
+  constructor(transport: Transport) {
+    this.transport = transport;
+  }
+
+  clientConfig(req: ClientConfigRequest = {}, opts?: CallOptions): Promise<ClientConfigResponse> {
+    return this.transport.unary<ClientConfigResponse>(
+      {
+        method: "GET",
+        path: `/api/meta/config`,
+        query: req,
+      },
+      opts,
+    );
+  }
+
+  errorCatalog(req: ErrorCatalogRequest = {}, opts?: CallOptions): Promise<ErrorCatalogResponse> {
+    return this.transport.unary<ErrorCatalogResponse>(
+      {
+        method: "GET",
+        path: `/api/meta/errors`,
+        query: req,
+      },
+      opts,
+    );
+  }
+}
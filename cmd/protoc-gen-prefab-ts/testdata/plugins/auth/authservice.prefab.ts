@@ -0,0 +1,188 @@
+// Code generated by protoc-gen-prefab-ts. DO NOT EDIT.
+// source: plugins/auth/authservice.proto
+
+import type { CallOptions, Transport } from "../../prefab";
+
+export interface LoginRequest {
+  provider?: string;
+  creds?: Record<string, string>;
+  issueToken?: boolean;
+  redirectUri?: string;
+  rememberMe?: boolean;
+}
+
+export interface LoginResponse {
+  issued?: boolean;
+  token?: string;
+  redirectUri?: string;
+}
+
+export interface LogoutRequest {
+  redirectUri?: string;
+  endSession?: boolean;
+}
+
+export interface LogoutResponse {
+  redirectUri?: string;
+}
+
+export interface ListProvidersRequest {}
+
+export interface ListProvidersResponse {
+  providers?: ProviderInfo[];
+}
+
+export interface ProviderInfo {
+  name?: string;
+  displayName?: string;
+  icon?: string;
+  redirect?: boolean;
+  credentials?: string[];
+}
+
+export interface ConfigRequest {}
+
+export interface ConfigResponse {
+  csrfToken?: string;
+  configs?: Record<string, string>;
+}
+
+export interface IdentityRequest {}
+
+export interface IdentityResponse {
+  provider?: string;
+  subject?: string;
+  email?: string;
+  emailVerified?: boolean;
+  name?: string;
+  delegation?: DelegationInfo | null;
+  rememberMe?: boolean;
+  mfa?: boolean;
+  groups?: string[];
+}
+
+export interface DelegationInfo {
+  delegatorSub?: string;
+  delegatorProvider?: string;
+  delegatorSessionId?: string;
+  reason?: string;
+  delegatedAt?: string;
+}
+
+export interface AssumeIdentityRequest {
+  provider?: string;
+  subject?: string;
+  reason?: string;
+}
+
+export interface AssumeIdentityResponse {
+  token?: string;
+}
+
+export interface LinkAccountRequest {
+  token?: string;
+}
+
+export interface LinkAccountResponse {
+  accountId?: string;
+  linkedIdentities?: LinkedIdentity[];
+}
+
+export interface UnlinkAccountRequest {
+  provider?: string;
+  subject?: string;
+}
+
+export interface UnlinkAccountResponse {
+  linkedIdentities?: LinkedIdentity[];
+}
+
+export interface LinkedIdentity {
+  provider?: string;
+  subject?: string;
+}
+
+export class AuthServiceClient {
+  private readonly transport: Transport;
+
+  constructor(transport: Transport) {
+    this.transport = transport;
+  }
+
+  login(req: LoginRequest = {}, opts?: CallOptions): Promise<LoginResponse> {
+    return this.transport.unary<LoginResponse>(
+      {
+        method: "POST",
+        path: `/api/auth/login`,
+        body: req,
+      },
+      opts,
+    );
+  }
+
+  logout(req: LogoutRequest = {}, opts?: CallOptions): Promise<LogoutResponse> {
+    return this.transport.unary<LogoutResponse>(
+      {
+        method: "POST",
+        path: `/api/auth/logout`,
+        body: req,
+      },
+      opts,
+    );
+  }
+
+  listProviders(req: ListProvidersRequest = {}, opts?: CallOptions): Promise<ListProvidersResponse> {
+    return this.transport.unary<ListProvidersResponse>(
+      {
+        method: "GET",
+        path: `/api/auth/providers`,
+        query: req,
+      },
+      opts,
+    );
+  }
+
+  identity(req: IdentityRequest = {}, opts?: CallOptions): Promise<IdentityResponse> {
+    return this.transport.unary<IdentityResponse>(
+      {
+        method: "GET",
+        path: `/api/auth/me`,
+        query: req,
+      },
+      opts,
+    );
+  }
+
+  assumeIdentity(req: AssumeIdentityRequest = {}, opts?: CallOptions): Promise<AssumeIdentityResponse> {
+    return this.transport.unary<AssumeIdentityResponse>(
+      {
+        method: "POST",
+        path: `/api/auth/assume`,
+        body: req,
+      },
+      opts,
+    );
+  }
+
+  linkAccount(req: LinkAccountRequest = {}, opts?: CallOptions): Promise<LinkAccountResponse> {
+    return this.transport.unary<LinkAccountResponse>(
+      {
+        method: "POST",
+        path: `/api/auth/link`,
+        body: req,
+      },
+      opts,
+    );
+  }
+
+  unlinkAccount(req: UnlinkAccountRequest = {}, opts?: CallOptions): Promise<UnlinkAccountResponse> {
+    return this.transport.unary<UnlinkAccountResponse>(
+      {
+        method: "POST",
+        path: `/api/auth/unlink`,
+        body: req,
+      },
+      opts,
+    );
+  }
+}
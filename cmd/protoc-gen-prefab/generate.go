@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/dpup/prefab/internal/protoplugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	contextPackage = protogen.GoImportPath("context")
+	grpcPackage    = protogen.GoImportPath("google.golang.org/grpc")
+	codesPackage   = protogen.GoImportPath("google.golang.org/grpc/codes")
+	errorsPackage  = protogen.GoImportPath("github.com/dpup/prefab/errors")
+	prefabPackage  = protogen.GoImportPath("github.com/dpup/prefab")
+)
+
+// sseMethod is a method annotated with `(prefab.sse_path)`.
+type sseMethod struct {
+	service *protogen.Service
+	method  *protogen.Method
+	path    string
+}
+
+// optionName returns the name of the generated server option.
+func (m sseMethod) optionName() string {
+	return "With" + m.method.GoName + "SSE"
+}
+
+func generate(gen *protogen.Plugin) error {
+	for _, f := range gen.Files {
+		if !f.Generate {
+			continue
+		}
+		methods, err := sseMethods(f)
+		if err != nil {
+			return err
+		}
+		catalogs, err := errorCatalogs(f)
+		if err != nil {
+			return err
+		}
+		if len(methods) == 0 && len(catalogs) == 0 {
+			continue
+		}
+		generateFile(gen, f, methods, catalogs)
+	}
+	return nil
+}
+
+// sseMethods returns the annotated methods in the file, verifying that they're
+// server-streaming and that path parameters refer to fields of the request.
+func sseMethods(f *protogen.File) ([]sseMethod, error) {
+	var methods []sseMethod
+	names := map[string]protoreflect.FullName{}
+	for _, svc := range f.Services {
+		for _, m := range svc.Methods {
+			path, err := protoplugin.SSEPath(m)
+			if err != nil {
+				return nil, err
+			}
+			if path == "" {
+				continue
+			}
+			sm := sseMethod{service: svc, method: m, path: path}
+			if other, ok := names[sm.optionName()]; ok {
+				return nil, fmt.Errorf("%s: %s is already generated for %s", m.Desc.FullName(), sm.optionName(), other)
+			}
+			names[sm.optionName()] = m.Desc.FullName()
+			methods = append(methods, sm)
+		}
+	}
+	return methods, nil
+}
+
+// errorCatalog is an enum with values annotated with `(prefab.error)`.
+type errorCatalog struct {
+	enum   *protogen.Enum
+	values []catalogValue
+}
+
+type catalogValue struct {
+	value *protogen.EnumValue
+	spec  protoplugin.ErrorSpec
+}
+
+// errorCatalogs returns the enums in the file, including nested enums, which
+// declare errors.
+func errorCatalogs(f *protogen.File) ([]errorCatalog, error) {
+	enums := f.Enums
+	var walk func(msgs []*protogen.Message)
+	walk = func(msgs []*protogen.Message) {
+		for _, m := range msgs {
+			enums = append(enums, m.Enums...)
+			walk(m.Messages)
+		}
+	}
+	walk(f.Messages)
+
+	var catalogs []errorCatalog
+	for _, e := range enums {
+		c := errorCatalog{enum: e}
+		for _, v := range e.Values {
+			spec, ok := protoplugin.ErrorSpecOption(v)
+			if !ok {
+				continue
+			}
+			if spec.Code <= 0 || spec.Code > int32(codes.Unauthenticated) {
+				return nil, fmt.Errorf("%s: error requires a code other than OK", v.Desc.FullName())
+			}
+			if spec.Message == "" {
+				return nil, fmt.Errorf("%s: error requires a message", v.Desc.FullName())
+			}
+			c.values = append(c.values, catalogValue{value: v, spec: spec})
+		}
+		if len(c.values) > 0 {
+			catalogs = append(catalogs, c)
+		}
+	}
+	return catalogs, nil
+}
+
+func generateFile(gen *protogen.Plugin, f *protogen.File, methods []sseMethod, catalogs []errorCatalog) {
+	g := gen.NewGeneratedFile(f.GeneratedFilenamePrefix+"_prefab.pb.go", f.GoImportPath)
+	g.P("// Code generated by protoc-gen-prefab. DO NOT EDIT.")
+	g.P("// source: ", f.Desc.Path())
+	g.P()
+	g.P("package ", f.GoPackageName)
+	g.P()
+
+	for _, m := range methods {
+		svc, method := m.service, m.method
+		g.P("// ", m.optionName(), " returns a server option which serves ", svc.GoName, ".", method.GoName)
+		g.P("// as Server-Sent Events at ", fmt.Sprintf("%q", m.path), ". The request is populated")
+		g.P("// from path and query parameters, see prefab.PopulateSSERequest. The service")
+		g.P("// must also be registered with the server. Options can add guards, see")
+		g.P("// prefab.WithSSEGuard.")
+		g.P("func ", m.optionName(), "(opts ...", prefabPackage.Ident("SSEOption"), ") ", prefabPackage.Ident("ServerOption"), " {")
+		g.P("return ", prefabPackage.Ident("WithSSEStream"), "(", fmt.Sprintf("%q", m.path), ", func(ctx ",
+			contextPackage.Ident("Context"), ", params map[string]string, cc ", grpcPackage.Ident("ClientConnInterface"),
+			") (", prefabPackage.Ident("ClientStream"), "[*", method.Output.GoIdent, "], error) {")
+		g.P("req := &", method.Input.GoIdent, "{}")
+		g.P("if err := ", prefabPackage.Ident("PopulateSSERequest"), "(req, params); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("return ", f.GoImportPath.Ident("New"+svc.GoName+"Client"), "(cc).", method.GoName, "(ctx, req)")
+		g.P("}, opts...)")
+		g.P("}")
+		g.P()
+	}
+
+	if len(catalogs) == 0 {
+		return
+	}
+	g.P("func init() {")
+	g.P(errorsPackage.Ident("RegisterCatalog"), "(")
+	for _, c := range catalogs {
+		for _, v := range c.values {
+			status := ""
+			if v.spec.HTTPStatus != 0 {
+				status = fmt.Sprintf(" HTTPStatus: %d,", v.spec.HTTPStatus)
+			}
+			g.P(errorsPackage.Ident("CatalogEntry"), "{Domain: ", fmt.Sprintf("%q", c.enum.Desc.FullName()),
+				", Reason: ", fmt.Sprintf("%q", v.value.Desc.Name()), ", Number: ", v.value.Desc.Number(),
+				", Code: ", codesPackage.Ident(codes.Code(v.spec.Code).String()), ",", status,
+				" Message: ", fmt.Sprintf("%q", v.spec.Message), "},") //nolint:gosec // Range checked.
+		}
+	}
+	g.P(")")
+	g.P("}")
+	g.P()
+	for _, c := range catalogs {
+		g.P("// Err returns the error declared by x in the error catalog, with a message")
+		g.P("// formatted with args. See errors.FromCatalog.")
+		g.P("func (x ", c.enum.GoIdent, ") Err(args ...any) *", errorsPackage.Ident("Error"), " {")
+		g.P("return ", errorsPackage.Ident("FromCatalogSkip"), "(x, 1, args...)")
+		g.P("}")
+		g.P()
+	}
+}
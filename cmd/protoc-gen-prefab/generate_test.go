@@ -0,0 +1,172 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/examples/ssestream/counterservice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// run invokes the plugin for the last file, with the others as dependencies.
+func run(t *testing.T, files ...*descriptorpb.FileDescriptorProto) (*pluginpb.CodeGeneratorResponse, error) {
+	t.Helper()
+	deps := []protoreflect.FileDescriptor{
+		descriptorpb.File_google_protobuf_descriptor_proto,
+		anypb.File_google_protobuf_any_proto,
+		timestamppb.File_google_protobuf_timestamp_proto,
+		annotations.File_google_api_http_proto,
+		annotations.File_google_api_annotations_proto,
+		code.File_google_rpc_code_proto,
+		errors.File_errors_errors_proto,
+		prefab.File_server_proto,
+	}
+	req := &pluginpb.CodeGeneratorRequest{FileToGenerate: []string{files[len(files)-1].GetName()}}
+	for _, fd := range deps {
+		req.ProtoFile = append(req.ProtoFile, protodesc.ToFileDescriptorProto(fd))
+	}
+	req.ProtoFile = append(req.ProtoFile, files...)
+
+	gen, err := protogen.Options{}.New(req)
+	require.NoError(t, err)
+	if err := generate(gen); err != nil {
+		return nil, err
+	}
+	return gen.Response(), nil
+}
+
+func TestGenerate_Golden(t *testing.T) {
+	resp, err := run(t, protodesc.ToFileDescriptorProto(counterservice.File_examples_ssestream_counterservice_counterservice_proto))
+	require.NoError(t, err)
+	require.Len(t, resp.GetFile(), 1)
+	assert.Equal(t, "github.com/dpup/prefab/examples/ssestream/counterservice/counterservice_prefab.pb.go", resp.GetFile()[0].GetName())
+
+	want, err := os.ReadFile("../../examples/ssestream/counterservice/counterservice_prefab.pb.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(want), resp.GetFile()[0].GetContent(), "generated code is up to date")
+}
+
+// testFile returns a file with a Watch method annotated with path.
+func testFile(path string, serverStreaming bool) *descriptorpb.FileDescriptorProto {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, prefab.E_SsePath, path)
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+	msg := descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("watch.proto"),
+		Package:    proto.String("watch"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"server.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/watch")},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Ref"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("id")},
+			}},
+			{Name: proto.String("WatchRequest"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: str, Label: optional, JsonName: proto.String("id")},
+				{Name: proto.String("ref"), Number: proto.Int32(2), Type: msg, TypeName: proto.String(".watch.Ref"), Label: optional, JsonName: proto.String("ref")},
+				{Name: proto.String("tags"), Number: proto.Int32(3), Type: str, Label: repeated, JsonName: proto.String("tags")},
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("WatchService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:            proto.String("Watch"),
+				InputType:       proto.String(".watch.WatchRequest"),
+				OutputType:      proto.String(".watch.Ref"),
+				ServerStreaming: proto.Bool(serverStreaming),
+				Options:         opts,
+			}},
+		}},
+	}
+}
+
+func TestGenerate_Paths(t *testing.T) {
+	resp, err := run(t, testFile("/watch/{id}/refs/{ref.id}", true))
+	require.NoError(t, err)
+	require.Len(t, resp.GetFile(), 1)
+	assert.Contains(t, resp.GetFile()[0].GetContent(), "func WithWatchSSE(opts ...prefab.SSEOption) prefab.ServerOption {")
+	assert.Contains(t, resp.GetFile()[0].GetContent(), `prefab.WithSSEStream("/watch/{id}/refs/{ref.id}"`)
+
+	for path, msg := range map[string]string{
+		"watch/{id}":        "must start with /",
+		"/watch/{missing}":  "{missing} isn't a field of watch.WatchRequest",
+		"/watch/{ref.name}": "{ref.name} isn't a field of watch.WatchRequest",
+		"/watch/{id.x}":     "{id.x} isn't a field of watch.WatchRequest",
+		"/watch/{ref}":      "{ref} can't be a message",
+		"/watch/{tags}":     "{tags} can't be repeated",
+	} {
+		_, err := run(t, testFile(path, true))
+		assert.ErrorContains(t, err, msg, path)
+	}
+}
+
+func TestGenerate_RequiresServerStreaming(t *testing.T) {
+	_, err := run(t, testFile("/watch/{id}", false))
+	assert.ErrorContains(t, err, "watch.WatchService.Watch: sse_path requires a server-streaming method")
+}
+
+func TestGenerate_SkipsUnannotated(t *testing.T) {
+	f := testFile("", true)
+	resp, err := run(t, f)
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetFile())
+}
+
+// catalogFile returns a file with a top-level and a nested error enum.
+func catalogFile(spec *prefab.ErrorSpec) *descriptorpb.FileDescriptorProto {
+	opts := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(opts, prefab.E_Error, spec)
+	gone := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(gone, prefab.E_Error, &prefab.ErrorSpec{Code: code.Code_NOT_FOUND, HttpStatus: 410, Message: "Note is gone"})
+	values := func(prefix string, opts *descriptorpb.EnumValueOptions) []*descriptorpb.EnumValueDescriptorProto {
+		return []*descriptorpb.EnumValueDescriptorProto{
+			{Name: proto.String(prefix + "_UNSPECIFIED"), Number: proto.Int32(0)},
+			{Name: proto.String(prefix + "_FAILED"), Number: proto.Int32(1), Options: opts},
+		}
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("notes.proto"),
+		Package:    proto.String("notes"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"server.proto"},
+		Options:    &descriptorpb.FileOptions{GoPackage: proto.String("example.com/notes")},
+		EnumType:   []*descriptorpb.EnumDescriptorProto{{Name: proto.String("NoteError"), Value: values("NOTE", opts)}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:     proto.String("Note"),
+			EnumType: []*descriptorpb.EnumDescriptorProto{{Name: proto.String("Error"), Value: values("GONE", gone)}},
+		}},
+	}
+}
+
+func TestGenerate_ErrorCatalog(t *testing.T) {
+	resp, err := run(t, catalogFile(&prefab.ErrorSpec{Code: code.Code_FAILED_PRECONDITION, Message: "Note %s is locked"}))
+	require.NoError(t, err)
+	require.Len(t, resp.GetFile(), 1)
+	content := resp.GetFile()[0].GetContent()
+	assert.Contains(t, content, `errors.CatalogEntry{Domain: "notes.NoteError", Reason: "NOTE_FAILED", Number: 1, Code: codes.FailedPrecondition, Message: "Note %s is locked"},`)
+	assert.Contains(t, content, `errors.CatalogEntry{Domain: "notes.Note.Error", Reason: "GONE_FAILED", Number: 1, Code: codes.NotFound, HTTPStatus: 410, Message: "Note is gone"},`)
+	assert.Contains(t, content, "func (x NoteError) Err(args ...any) *errors.Error {")
+	assert.Contains(t, content, "func (x Note_Error) Err(args ...any) *errors.Error {")
+	assert.NotContains(t, content, "UNSPECIFIED", "unannotated values aren't registered")
+
+	_, err = run(t, catalogFile(&prefab.ErrorSpec{Message: "Note %s is locked"}))
+	assert.ErrorContains(t, err, "notes.NOTE_FAILED: error requires a code other than OK")
+	_, err = run(t, catalogFile(&prefab.ErrorSpec{Code: code.Code_INTERNAL}))
+	assert.ErrorContains(t, err, "notes.NOTE_FAILED: error requires a message")
+}
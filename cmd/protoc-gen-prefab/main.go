@@ -0,0 +1,42 @@
+// Command protoc-gen-prefab is a protoc plugin which generates helpers from
+// prefab's proto annotations.
+//
+// For each server-streaming method annotated with `(prefab.sse_path)` it
+// generates a server option which serves the method as Server-Sent Events,
+// building the request from path and query parameters:
+//
+//	rpc StreamUpdates(StreamRequest) returns (stream Update) {
+//	  option (prefab.sse_path) = "/notes/{id}/updates";
+//	}
+//
+// generates `WithStreamUpdatesSSE() prefab.ServerOption` in
+// `<file>_prefab.pb.go`, alongside the code from protoc-gen-go.
+//
+// For each enum whose values are annotated with `(prefab.error)` it registers
+// the errors with errors.RegisterCatalog and generates an `Err` method which
+// creates them:
+//
+//	enum NoteError {
+//	  NOTE_ERROR_UNSPECIFIED = 0;
+//	  NOTE_NOT_FOUND = 1 [(prefab.error) = {code: NOT_FOUND, message: "Note %s was not found"}];
+//	}
+//
+// allows handlers to `return nil, NoteError_NOTE_NOT_FOUND.Err(req.Id)`.
+//
+// Usage:
+//
+//	go install github.com/dpup/prefab/cmd/protoc-gen-prefab
+//	protoc --prefab_out=. --prefab_opt=paths=source_relative notes.proto
+package main
+
+import (
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func main() {
+	protogen.Options{}.Run(func(gen *protogen.Plugin) error {
+		gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL)
+		return generate(gen)
+	})
+}
@@ -9,9 +9,9 @@ import "net/http"
 // response message, so without this the 304 would carry a stray `{}` body and
 // content headers.
 //
-// It is applied only to the Gateway mux, whose responses are unary and never
-// streamed, so the wrapper does not need to forward Flush/Hijack. Streaming
-// endpoints (e.g. SSE) are mounted separately and are unaffected.
+// It is applied to the Gateway mux, which also serves server-streaming methods
+// as newline-delimited JSON, so the wrapper forwards Flush and can be unwrapped
+// by http.ResponseController.
 func conditionalResponse(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		next.ServeHTTP(&conditionalWriter{ResponseWriter: w}, r)
@@ -49,3 +49,14 @@ func (w *conditionalWriter) Write(b []byte) (int, error) {
 	}
 	return w.ResponseWriter.Write(b)
 }
+
+func (w *conditionalWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *conditionalWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -40,6 +40,10 @@ type ConfigInjector func(context.Context) context.Context
 //   - PF__FOO_BAR__BAZ → fooBar.baz
 var Config = koanf.New(".")
 
+// Paths of config files loaded into Config, which are reloaded by
+// WithConfigWatch.
+var configFiles []string
+
 const (
 	defaultPort = "8000"
 	defaultHost = "localhost"
@@ -54,6 +58,7 @@ func init() {
 		if err := Config.Load(file.Provider(cfg), yaml.Parser()); err != nil {
 			panic("error loading config: " + err.Error())
 		}
+		configFiles = append(configFiles, cfg)
 	}
 
 	// Load environment variables with the prefix PF__.
@@ -103,6 +108,7 @@ func LoadConfigFile(path string) {
 	if err := Config.Load(file.Provider(path), yaml.Parser()); err != nil {
 		panic("error loading config file '" + path + "': " + err.Error())
 	}
+	configFiles = append(configFiles, path)
 }
 
 // LoadConfigDefaults loads default configuration values into the global
@@ -210,6 +216,44 @@ func injectConfigs(ctx context.Context, injectors []ConfigInjector) context.Cont
 func registerCoreConfigKeys() {
 	registerServerAndTLSConfigKeys()
 	registerSecurityConfigKeys()
+	registerClientConfigKeys()
+	registerLoggingConfigKeys()
+}
+
+// registerClientConfigKeys registers configuration keys used by Dial.
+func registerClientConfigKeys() {
+	config.RegisterConfigKeys(
+		ConfigKeyInfo{
+			Key:         "client.timeout",
+			Description: "Deadline applied to outgoing calls that don't already have one",
+			Type:        "duration",
+			Default:     "30s",
+		},
+		ConfigKeyInfo{
+			Key:         "client.maxRetries",
+			Description: "Number of times outgoing calls are retried when the server is unavailable",
+			Type:        "int",
+			Default:     "3",
+		},
+		ConfigKeyInfo{
+			Key:         "client.retryBackoff",
+			Description: "Initial backoff between retries, doubled on each attempt",
+			Type:        "duration",
+			Default:     "100ms",
+		},
+		ConfigKeyInfo{
+			Key:         "client.deadlineMargin",
+			Description: "Time subtracted from the request's deadline for outgoing calls, leaving room for the response",
+			Type:        "duration",
+			Default:     "0s",
+		},
+		ConfigKeyInfo{
+			Key:         "client.insecure",
+			Description: "Dial without TLS, for local development",
+			Type:        "bool",
+			Default:     "false",
+		},
+	)
 }
 
 // registerServerAndTLSConfigKeys registers general server and TLS configuration keys.
@@ -252,12 +296,174 @@ func registerServerAndTLSConfigKeys() {
 			Description: "Maximum gRPC message size in bytes",
 			Type:        "int",
 		},
+		ConfigKeyInfo{
+			Key:         "server.maxSendMsgSizeBytes",
+			Description: "Maximum gRPC message size the server will send, in bytes",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.pooledJSONBuffers",
+			Description: "Encode gateway responses into pooled buffers, reducing allocations for large responses",
+			Type:        "bool",
+			Default:     "true",
+		},
+		ConfigKeyInfo{
+			Key:         "server.backgroundWarmup",
+			Description: "Accept traffic while plugins warm up, reporting not ready on /readyz until complete",
+			Type:        "bool",
+			Default:     "false",
+		},
+		ConfigKeyInfo{
+			Key:         "server.locality.region",
+			Description: "Region the server is deployed in, added to logs, response headers, events, and audit entries",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.locality.zone",
+			Description: "Zone the server is deployed in, within the region",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.locality.instance",
+			Description: "Identifier of the server instance, such as a hostname or pod name",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.locality.headers",
+			Description: "Send the locality in X-Prefab-Region, X-Prefab-Zone, and X-Prefab-Instance response headers",
+			Type:        "bool",
+			Default:     "true",
+		},
+		ConfigKeyInfo{
+			Key:         "server.proxy.trusted",
+			Description: "CIDR ranges or IPs of proxies trusted to report the client in Forwarded, X-Forwarded-*, and PROXY protocol headers",
+			Type:        "[]string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.proxy.protocol",
+			Description: "Accept PROXY protocol v1 and v2 headers from trusted proxies on the listener",
+			Type:        "bool",
+			Default:     "false",
+		},
+		ConfigKeyInfo{
+			Key:         "server.proxy.forwardedAddress",
+			Description: "Use the scheme and host reported by trusted proxies as the server address for each request",
+			Type:        "bool",
+			Default:     "false",
+		},
+		ConfigKeyInfo{
+			Key:         "server.requestTimeout",
+			Description: "Default deadline for HTTP and gateway requests, propagated to gRPC services (none if not set)",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.maxRequestTimeout",
+			Description: "Maximum deadline clients may request with the X-Request-Timeout or Grpc-Timeout headers",
+			Type:        "duration",
+			Default:     "5m",
+		},
+		ConfigKeyInfo{
+			Key:         "server.enforceSunset",
+			Description: "Reject calls to deprecated methods after their sunset date",
+			Type:        "bool",
+			Default:     "false",
+		},
+		ConfigKeyInfo{
+			Key:         "server.versions.default",
+			Description: "API version used by requests which don't ask for one (the last declared version if not set)",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.streams.maxOpen",
+			Description: "Maximum SSE streams open across the server (unlimited if not set)",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.streams.maxPerIdentity",
+			Description: "Maximum SSE streams open for each authenticated caller (unlimited if not set)",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.streams.retryAfter",
+			Description: "Retry-After sent when a stream limit is exceeded",
+			Type:        "duration",
+			Default:     "5s",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.keepalive.time",
+			Description: "Ping clients after a connection has been idle for this long",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.keepalive.timeout",
+			Description: "Close connections when a keepalive ping isn't acknowledged within this time",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.keepalive.minTime",
+			Description: "Minimum time clients must wait between keepalive pings",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.keepalive.permitWithoutStream",
+			Description: "Allow client keepalive pings when there are no active streams",
+			Type:        "bool",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.maxConnectionIdle",
+			Description: "Close connections that have had no active streams for this long",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.maxConnectionAge",
+			Description: "Maximum lifetime of a connection before clients are asked to reconnect",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.maxConnectionAgeGrace",
+			Description: "Time allowed for in-flight streams to finish once a connection reaches its maximum age",
+			Type:        "duration",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.maxConcurrentStreams",
+			Description: "Maximum concurrent streams per gRPC connection",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.initialWindowSize",
+			Description: "Initial HTTP/2 flow control window size for gRPC streams, in bytes",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.grpc.initialConnWindowSize",
+			Description: "Initial HTTP/2 flow control window size for gRPC connections, in bytes",
+			Type:        "int",
+		},
 		ConfigKeyInfo{
 			Key:         "server.csrfSigningKey",
 			Description: "Key used to sign CSRF tokens",
 			Type:        "string",
 		},
 
+		// Admin listener configuration
+		ConfigKeyInfo{
+			Key:         "server.admin.host",
+			Description: "Host to bind the admin listener to",
+			Type:        "string",
+			Default:     defaultAdminHost,
+		},
+		ConfigKeyInfo{
+			Key:         "server.admin.port",
+			Description: "Port for the admin listener, which serves debug and admin handlers (disabled if not set)",
+			Type:        "int",
+		},
+		ConfigKeyInfo{
+			Key:         "server.admin.public",
+			Description: "Serve admin handlers and services on the public port when there is no admin listener",
+			Type:        "bool",
+			Default:     "false",
+		},
+
 		// TLS configuration
 		ConfigKeyInfo{
 			Key:         "server.tls.certFile",
@@ -269,6 +475,16 @@ func registerServerAndTLSConfigKeys() {
 			Description: "Path to TLS key file",
 			Type:        "string",
 		},
+		ConfigKeyInfo{
+			Key:         "server.tls.clientCAFile",
+			Description: "Path to CA certificates used to verify client certificates (mutual TLS)",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "server.tls.requireClientCert",
+			Description: "Reject connections that don't present a valid client certificate",
+			Type:        "bool",
+		},
 	)
 }
 
@@ -300,7 +516,7 @@ func registerSecurityConfigKeys() {
 		// CORS configuration
 		ConfigKeyInfo{
 			Key:         "server.security.corsOrigins",
-			Description: "Allowed CORS origins",
+			Description: "Allowed CORS origins, wildcard subdomains such as https://*.example.com are supported",
 			Type:        "[]string",
 		},
 		ConfigKeyInfo{
@@ -328,5 +544,15 @@ func registerSecurityConfigKeys() {
 			Description: "CORS preflight cache duration",
 			Type:        "duration",
 		},
+		ConfigKeyInfo{
+			Key:         "server.security.corsOverrides",
+			Description: "Per path-prefix CORS configuration, overriding the server-wide settings",
+			Type:        "map",
+		},
+		ConfigKeyInfo{
+			Key:         "server.hosts",
+			Description: "Virtual hosts, e.g. [{host, gateway, tls: {certFile, keyFile}, security: {...}}]",
+			Type:        "list",
+		},
 	)
 }
@@ -36,6 +36,10 @@ const (
 	csrfTokenLength = 32
 )
 
+// CSRFParam is the name of the query param, or form field, which carries the
+// CSRF token for the double-submit cookie pattern.
+const CSRFParam = csrfParam
+
 // SendCSRFToken sends a CSRF token in the response cookies and returns the
 // value for use in the response body.
 func SendCSRFToken(ctx context.Context, signingKey []byte) string {
@@ -46,15 +50,7 @@ func SendCSRFToken(ctx context.Context, signingKey []byte) string {
 
 	// Resend the cookie so we can push out expiration.
 	isSecure := strings.HasPrefix(serverutil.AddressFromContext(ctx), "https")
-	err := serverutil.SendCookie(ctx, &http.Cookie{
-		Name:     csrfCookie,
-		Value:    ct,
-		Path:     "/",
-		Secure:   isSecure,
-		HttpOnly: false, // Per OWASP recommendation.
-		Expires:  time.Now().Add(csrfExpiration),
-		SameSite: http.SameSiteLaxMode,
-	})
+	err := serverutil.SendCookie(ctx, newCSRFCookie(ct, isSecure))
 	if err != nil {
 		// This error will occur when the ctx hasn't gone through the GRPC stack,
 		// since that is a configuration error, we panic.
@@ -91,6 +87,44 @@ func VerifyCSRF(ctx context.Context, signingKey []byte) error {
 	return verifyCSRFToken(fromCookie, signingKey)
 }
 
+type csrfTokenKey struct{}
+
+// CSRFTokenFromContext returns the CSRF token which was issued for the current
+// HTTP request, for example by a handler registered with WithTemplateHandler.
+// Returns an empty string if no token was issued.
+func CSRFTokenFromContext(ctx context.Context) string {
+	if ct, ok := ctx.Value(csrfTokenKey{}).(string); ok {
+		return ct
+	}
+	return ""
+}
+
+// sendHTTPCSRFToken reuses a valid token from the request cookies or generates
+// a new one, writes the cookie to the response, and attaches the token to the
+// returned context. Unlike SendCSRFToken, it works with plain HTTP handlers.
+func sendHTTPCSRFToken(w http.ResponseWriter, r *http.Request, signingKey []byte) context.Context {
+	ct := ""
+	if c, err := r.Cookie(csrfCookie); err == nil && verifyCSRFToken(c.Value, signingKey) == nil {
+		ct = c.Value
+	} else {
+		ct = generateCSRFToken(signingKey)
+	}
+	http.SetCookie(w, newCSRFCookie(ct, r.TLS != nil))
+	return context.WithValue(r.Context(), csrfTokenKey{}, ct)
+}
+
+func newCSRFCookie(ct string, isSecure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     csrfCookie,
+		Value:    ct,
+		Path:     "/",
+		Secure:   isSecure,
+		HttpOnly: false, // Per OWASP recommendation.
+		Expires:  time.Now().Add(csrfExpiration),
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
 func csrfTokenFromCookie(ctx context.Context) string {
 	cookies := serverutil.CookiesFromIncomingContext(ctx)
 	c, ok := cookies[csrfCookie]
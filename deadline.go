@@ -0,0 +1,162 @@
+package prefab
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RequestTimeoutHeader lets HTTP clients request a deadline, as a duration
+	// such as "1.5s" or a number of seconds. It is capped by the max request
+	// timeout.
+	RequestTimeoutHeader = "X-Request-Timeout"
+
+	// Deadline in the gRPC wire format, e.g. "100m" for 100 milliseconds, which
+	// is also honored for HTTP requests.
+	grpcTimeoutHeader = "Grpc-Timeout"
+)
+
+// WithRequestTimeout sets the default deadline for HTTP requests, including
+// requests to the GRPC Gateway. The deadline is propagated to GRPC services so
+// that they, and any storage calls, are cancelled when it is exceeded. A zero
+// duration means requests have no deadline unless the client asks for one.
+//
+// Config key: `server.requestTimeout`.
+func WithRequestTimeout(d time.Duration) ServerOption {
+	return func(b *builder) {
+		b.requestTimeout = d
+	}
+}
+
+// WithMaxRequestTimeout caps the deadline clients may request with the
+// X-Request-Timeout or Grpc-Timeout headers. Zero means no cap.
+//
+// Config key: `server.maxRequestTimeout`.
+func WithMaxRequestTimeout(d time.Duration) ServerOption {
+	return func(b *builder) {
+		b.maxRequestTimeout = d
+	}
+}
+
+// WithRouteTimeout overrides the default request timeout for the handler
+// registered with the given pattern, or "/api/" for the GRPC Gateway. Gateway
+// routes can also be matched by a path prefix under "/api/", such as
+// "/api/notes/stream/", and the longest matching prefix wins. A zero duration
+// disables the default, for example for streaming endpoints.
+func WithRouteTimeout(pattern string, d time.Duration) ServerOption {
+	return func(b *builder) {
+		if b.routeTimeouts == nil {
+			b.routeTimeouts = map[string]time.Duration{}
+		}
+		b.routeTimeouts[pattern] = d
+	}
+}
+
+// timeoutForRoute returns the default timeout for a route pattern.
+func (b *builder) timeoutForRoute(pattern string) time.Duration {
+	if d, ok := b.routeTimeouts[pattern]; ok {
+		return d
+	}
+	return b.requestTimeout
+}
+
+// gatewayDeadlineMiddleware applies deadlines to GRPC Gateway requests, using
+// the route timeout for the longest matching prefix under "/api/".
+func (b *builder) gatewayDeadlineMiddleware(h http.Handler) http.Handler {
+	var prefixes []string
+	handlers := map[string]http.Handler{}
+	for prefix, d := range b.routeTimeouts {
+		if strings.HasPrefix(prefix, "/api/") && prefix != "/api/" {
+			prefixes = append(prefixes, prefix)
+			handlers[prefix] = deadlineMiddleware(h, d, b.maxRequestTimeout)
+		}
+	}
+	def := deadlineMiddleware(h, b.timeoutForRoute("/api/"), b.maxRequestTimeout)
+	if len(prefixes) == 0 {
+		return def
+	}
+	slices.SortFunc(prefixes, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				handlers[prefix].ServeHTTP(w, r)
+				return
+			}
+		}
+		def.ServeHTTP(w, r)
+	})
+}
+
+// deadlineMiddleware applies a deadline to the request context. Clients may
+// request a different deadline via headers, up to maxTimeout.
+func deadlineMiddleware(h http.Handler, timeout, maxTimeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := timeout
+		if requested, ok := requestedTimeout(r); ok {
+			d = requested
+			if maxTimeout > 0 && d > maxTimeout {
+				d = maxTimeout
+			}
+		}
+		if d > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// requestedTimeout returns the timeout requested by the client, if any.
+// Invalid values are ignored.
+func requestedTimeout(r *http.Request) (time.Duration, bool) {
+	if v := r.Header.Get(RequestTimeoutHeader); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d, true
+		}
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs > 0 {
+			return time.Duration(secs * float64(time.Second)), true
+		}
+	}
+	if v := r.Header.Get(grpcTimeoutHeader); v != "" {
+		if d, ok := parseGRPCTimeout(v); ok {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// parseGRPCTimeout parses a timeout in the gRPC wire format: up to 8 digits
+// followed by a unit of H, M, S, m, u, or n.
+func parseGRPCTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 || len(v) > 9 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	var unit time.Duration
+	switch v[len(v)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -0,0 +1,110 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// deadlineFor returns the time remaining on the request's deadline, or 0 if it
+// has none.
+func deadlineFor(h func(http.Handler) http.Handler, header, value string) time.Duration {
+	var remaining time.Duration
+	handler := h(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, ok := r.Context().Deadline(); ok {
+			remaining = time.Until(d)
+		}
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return remaining
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	mw := func(timeout, maxTimeout time.Duration) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler { return deadlineMiddleware(h, timeout, maxTimeout) }
+	}
+
+	assert.Zero(t, deadlineFor(mw(0, time.Minute), "", ""), "no deadline by default")
+	assert.InDelta(t, 10*time.Second, deadlineFor(mw(10*time.Second, time.Minute), "", ""), float64(time.Second))
+
+	// Clients may shorten or extend the deadline, up to the cap.
+	assert.InDelta(t, 2*time.Second, deadlineFor(mw(10*time.Second, time.Minute), RequestTimeoutHeader, "2s"), float64(time.Second))
+	assert.InDelta(t, 30*time.Second, deadlineFor(mw(10*time.Second, time.Minute), RequestTimeoutHeader, "30"), float64(time.Second))
+	assert.InDelta(t, time.Minute, deadlineFor(mw(10*time.Second, time.Minute), RequestTimeoutHeader, "1h"), float64(time.Second))
+	assert.InDelta(t, 5*time.Second, deadlineFor(mw(0, time.Minute), "Grpc-Timeout", "5000m"), float64(time.Second))
+
+	// Invalid values are ignored.
+	assert.InDelta(t, 10*time.Second, deadlineFor(mw(10*time.Second, time.Minute), RequestTimeoutHeader, "soon"), float64(time.Second))
+	assert.InDelta(t, 10*time.Second, deadlineFor(mw(10*time.Second, time.Minute), "Grpc-Timeout", "5x"), float64(time.Second))
+}
+
+func TestParseGRPCTimeout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"1H", time.Hour, true},
+		{"2M", 2 * time.Minute, true},
+		{"3S", 3 * time.Second, true},
+		{"100m", 100 * time.Millisecond, true},
+		{"5u", 5 * time.Microsecond, true},
+		{"7n", 7, true},
+		{"S", 0, false},
+		{"0S", 0, false},
+		{"123456789S", 0, false},
+		{"10s", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseGRPCTimeout(tt.in)
+		assert.Equal(t, tt.ok, ok, tt.in)
+		assert.Equal(t, tt.want, got, tt.in)
+	}
+}
+
+func TestRouteTimeout(t *testing.T) {
+	var fast, stream bool
+	s := New(
+		WithRequestTimeout(time.Second),
+		WithRouteTimeout("/stream", 0),
+		WithHTTPHandlerFunc("/fast", func(w http.ResponseWriter, r *http.Request) {
+			_, fast = r.Context().Deadline()
+		}),
+		WithHTTPHandlerFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+			_, stream = r.Context().Deadline()
+		}),
+	)
+
+	serveStatus(s.httpMux, "/fast")
+	serveStatus(s.httpMux, "/stream")
+	assert.True(t, fast)
+	assert.False(t, stream)
+}
+
+func TestGatewayRouteTimeout(t *testing.T) {
+	deadlines := map[string]time.Duration{}
+	b := &builder{requestTimeout: time.Second, routeTimeouts: map[string]time.Duration{
+		"/api/notes/":        time.Minute,
+		"/api/notes/stream/": 0,
+	}}
+	h := b.gatewayDeadlineMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, ok := r.Context().Deadline(); ok {
+			deadlines[r.URL.Path] = time.Until(d).Round(time.Second)
+		}
+	}))
+
+	for _, path := range []string{"/api/meta/config", "/api/notes/1", "/api/notes/stream/1"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	assert.Equal(t, map[string]time.Duration{
+		"/api/meta/config": time.Second,
+		"/api/notes/1":     time.Minute,
+	}, deadlines, "the longest matching prefix wins")
+}
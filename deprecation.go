@@ -0,0 +1,204 @@
+package prefab
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// WithSunsetEnforcement sets whether calls to deprecated methods fail once
+// their sunset date has passed, see the `deprecation` method option. Until
+// then, calls succeed with Deprecation and Sunset headers.
+//
+// Config key: `server.enforceSunset`.
+func WithSunsetEnforcement(enforce bool) ServerOption {
+	return func(b *builder) {
+		b.enforceSunset = enforce
+	}
+}
+
+// DeprecationStats reports calls to a deprecated method.
+type DeprecationStats struct {
+	// Full method name, e.g. "/notes.NoteService/ListNotes".
+	Method string `json:"method"`
+
+	// Sunset date of the method, if set.
+	Sunset string `json:"sunset,omitempty"`
+
+	// Calls to the method, including rejected calls.
+	Calls int64 `json:"calls"`
+
+	// Calls rejected because the sunset date had passed, see
+	// WithSunsetEnforcement.
+	Rejected int64 `json:"rejected"`
+}
+
+// deprecation is the parsed `deprecation` option of a method.
+type deprecation struct {
+	sunset      time.Time
+	sunsetText  string
+	replacement string
+	headers     [][2]string
+
+	calls    atomic.Int64
+	rejected atomic.Int64
+}
+
+// methodDeprecations caches the deprecation of each RPC, keyed by full method
+// name. Methods which aren't deprecated are stored as nil.
+var methodDeprecations sync.Map
+
+func init() {
+	expvar.Publish("deprecations", expvar.Func(func() any { return DeprecatedMethodStats() }))
+}
+
+// DeprecatedMethodStats returns stats for each deprecated method which has been
+// called, sorted by method. They are also published to expvar as
+// "deprecations", which the debug plugin serves at /debug/vars.
+func DeprecatedMethodStats() []DeprecationStats {
+	var out []DeprecationStats
+	methodDeprecations.Range(func(k, v any) bool {
+		d, _ := v.(*deprecation)
+		if d == nil {
+			return true
+		}
+		out = append(out, DeprecationStats{
+			Method:   k.(string),
+			Sunset:   d.sunsetText,
+			Calls:    d.calls.Load(),
+			Rejected: d.rejected.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Method < out[j].Method })
+	return out
+}
+
+// deprecationInterceptor adds deprecation headers to the responses of
+// deprecated methods, logs and counts their calls, and rejects calls after the
+// sunset date if enforceSunset is set.
+func deprecationInterceptor(enforceSunset bool, now func() time.Time) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		d := deprecationForMethod(ctx, info.FullMethod)
+		if d == nil {
+			return handler(ctx, req)
+		}
+		d.calls.Add(1)
+		md := metadata.MD{}
+		for _, h := range d.headers {
+			md.Append("grpc-metadata-"+strings.ToLower(h[0]), h[1])
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			return nil, err
+		}
+
+		logging.Track(ctx, "server.deprecated", true)
+		logging.Warnw(ctx, "prefab: deprecated method called",
+			"method", info.FullMethod, "subject", logging.SubjectFromContext(ctx),
+			"sunset", d.sunsetText, "replacement", d.replacement)
+
+		if enforceSunset && !d.sunset.IsZero() && !now().Before(d.sunset) {
+			d.rejected.Add(1)
+			return nil, errors.NewC(fmt.Errorf("prefab: %s was sunset on %s", info.FullMethod, d.sunsetText), codes.Unimplemented).
+				WithHTTPStatusCode(http.StatusGone).
+				WithUserPresentableMessage("%s", sunsetMessage(d.replacement))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// sunsetMessage returns the message for calls after the sunset date.
+func sunsetMessage(replacement string) string {
+	if replacement == "" {
+		return "This API is no longer available"
+	}
+	return "This API is no longer available, use " + replacement + " instead"
+}
+
+// deprecationForMethod returns the deprecation declared for a method, or nil
+// if it isn't deprecated. Invalid dates are logged and ignored.
+func deprecationForMethod(ctx context.Context, method string) *deprecation {
+	if v, ok := methodDeprecations.Load(method); ok {
+		d, _ := v.(*deprecation)
+		return d
+	}
+	var d *deprecation
+	if desc := methodDescriptor(method); desc != nil {
+		opts, _ := desc.Options().(*descriptorpb.MethodOptions)
+		if proto.HasExtension(opts, E_Deprecation) {
+			var err error
+			d, err = parseDeprecation(proto.GetExtension(opts, E_Deprecation).(*Deprecation))
+			if err != nil {
+				logging.Warnw(ctx, "prefab: invalid deprecation option", "method", method, "error", err)
+			}
+		}
+	}
+	v, _ := methodDeprecations.LoadOrStore(method, d)
+	d, _ = v.(*deprecation)
+	return d
+}
+
+// parseDeprecation converts a deprecation option to headers. A deprecation is
+// returned even if the dates are invalid, without them.
+func parseDeprecation(opt *Deprecation) (*deprecation, error) {
+	d := &deprecation{replacement: opt.GetReplacement()}
+	var errs errors.Multi
+
+	value := "true"
+	if opt.GetSince() != "" {
+		since, err := parseDeprecationDate(opt.GetSince())
+		errs.Add("since", err)
+		if err == nil {
+			value = "@" + strconv.FormatInt(since.Unix(), 10)
+		}
+	}
+	d.headers = append(d.headers, [2]string{"Deprecation", value})
+
+	if opt.GetSunset() != "" {
+		sunset, err := parseDeprecationDate(opt.GetSunset())
+		errs.Add("sunset", err)
+		if err == nil {
+			d.sunset, d.sunsetText = sunset, opt.GetSunset()
+			d.headers = append(d.headers, [2]string{"Sunset", sunset.Format(http.TimeFormat)})
+		}
+	}
+
+	var links []string
+	if opt.GetLink() != "" {
+		links = append(links, "<"+opt.GetLink()+`>; rel="deprecation"`)
+	}
+	if r := opt.GetReplacement(); strings.HasPrefix(r, "/") || strings.HasPrefix(r, "http://") || strings.HasPrefix(r, "https://") {
+		links = append(links, "<"+r+`>; rel="successor-version"`)
+	}
+	if len(links) > 0 {
+		d.headers = append(d.headers, [2]string{"Link", strings.Join(links, ", ")})
+	}
+	return d, errs.Err()
+}
+
+// parseDeprecationDate parses a date as YYYY-MM-DD, in UTC, or RFC 3339.
+func parseDeprecationDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, errors.Errorf("%q isn't a date, expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,100 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// registerDeprecatedMethods registers a service with a method for each
+// deprecation, named by its key.
+func registerDeprecatedMethods(t *testing.T, deprecations map[string]*Deprecation) {
+	t.Helper()
+	svc := &descriptorpb.ServiceDescriptorProto{Name: proto.String("DeprecatedService")}
+	for name, d := range deprecations {
+		opts := &descriptorpb.MethodOptions{}
+		proto.SetExtension(opts, E_Deprecation, d)
+		svc.Method = append(svc.Method, &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".prefab.ClientConfigRequest"),
+			OutputType: proto.String(".prefab.ClientConfigResponse"),
+			Options:    opts,
+		})
+	}
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("deprecation_test.proto"),
+		Package:    proto.String("deprecationtest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"metaservice.proto", "server.proto"},
+		Service:    []*descriptorpb.ServiceDescriptorProto{svc},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("deprecation_test.proto"); err != nil {
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+}
+
+func TestDeprecationInterceptor(t *testing.T) {
+	registerDeprecatedMethods(t, map[string]*Deprecation{
+		"Removed": {Since: "2024-01-01", Sunset: "2025-06-30", Replacement: "/api/v2/notes", Link: "https://example.com/migrate"},
+		"Invalid": {Sunset: "next week", Replacement: "deprecationtest.DeprecatedService.Removed"},
+	})
+	methodDeprecations.Delete("/deprecationtest.DeprecatedService/Removed")
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	call := func(enforce bool, method string) (*mockServerTransportStream, any, error) {
+		stream := &mockServerTransportStream{}
+		ctx := logging.With(t.Context(), logging.NewDevLogger())
+		ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+		resp, err := deprecationInterceptor(enforce, clock)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return stream, resp, err
+	}
+
+	stream, resp, err := call(true, "/deprecationtest.DeprecatedService/Removed")
+	require.NoError(t, err, "calls succeed before the sunset date")
+	assert.Equal(t, "ok", resp)
+	require.NotNil(t, stream.md)
+	assert.Equal(t, []string{"@1704067200"}, stream.md.Get("grpc-metadata-deprecation"))
+	assert.Equal(t, []string{"Mon, 30 Jun 2025 00:00:00 GMT"}, stream.md.Get("grpc-metadata-sunset"))
+	assert.Equal(t, []string{`<https://example.com/migrate>; rel="deprecation", </api/v2/notes>; rel="successor-version"`},
+		stream.md.Get("grpc-metadata-link"))
+
+	now = now.AddDate(0, 1, 0)
+	_, _, err = call(false, "/deprecationtest.DeprecatedService/Removed")
+	require.NoError(t, err, "the sunset isn't enforced by default")
+	_, _, err = call(true, "/deprecationtest.DeprecatedService/Removed")
+	assert.Equal(t, codes.Unimplemented, errors.Code(err))
+	assert.Equal(t, http.StatusGone, errors.HTTPStatusCode(err))
+	assert.Equal(t, "This API is no longer available, use /api/v2/notes instead", err.(*errors.Error).UserPresentableMessage())
+
+	stream, _, err = call(true, "/deprecationtest.DeprecatedService/Invalid")
+	require.NoError(t, err, "invalid dates are ignored")
+	assert.Equal(t, []string{"true"}, stream.md.Get("grpc-metadata-deprecation"))
+	assert.Empty(t, stream.md.Get("grpc-metadata-sunset"))
+
+	stream, _, err = call(true, "/prefab.MetaService/ClientConfig")
+	require.NoError(t, err)
+	assert.Nil(t, stream.md, "methods which aren't deprecated are untouched")
+
+	var stats []DeprecationStats
+	for _, s := range DeprecatedMethodStats() {
+		if s.Method == "/deprecationtest.DeprecatedService/Removed" {
+			stats = append(stats, s)
+		}
+	}
+	require.Len(t, stats, 1)
+	assert.Equal(t, DeprecationStats{Method: "/deprecationtest.DeprecatedService/Removed", Sunset: "2025-06-30", Calls: 3, Rejected: 1}, stats[0])
+}
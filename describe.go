@@ -0,0 +1,177 @@
+package prefab
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+)
+
+// VersionedPlugin can be implemented by plugins to report their version in
+// Server.Describe. Otherwise the version of the module which provides the
+// plugin is reported, if known.
+type VersionedPlugin interface {
+	Version() string
+}
+
+// Describe returns a summary of what the server is running: listen addresses,
+// TLS state, plugins, HTTP routes, GRPC services, and SSE endpoints. It is
+// logged at startup, and served by AdminService on the admin listener.
+func (s *Server) Describe() *ServerDescription {
+	d := &ServerDescription{
+		Address:            s.address(),
+		Tls:                s.certFile != "",
+		ClientCertificates: s.clientAuth != nil,
+		GoVersion:          runtime.Version(),
+	}
+	if s.adminMux != nil {
+		if s.adminListener != nil {
+			d.AdminAddress = s.adminListener.Addr().String()
+		} else {
+			d.AdminAddress = net.JoinHostPort(s.adminHost, strconv.Itoa(s.adminPort))
+		}
+	}
+
+	if s.plugins != nil {
+		for _, key := range s.plugins.keys {
+			p := s.plugins.plugins[key]
+			d.Plugins = append(d.Plugins, &PluginDescription{
+				Name:    p.Name(),
+				Key:     key,
+				Version: pluginVersion(p),
+			})
+		}
+	}
+
+	for _, r := range s.routes {
+		d.Routes = append(d.Routes, &RouteDescription{
+			Pattern:    r.Pattern,
+			Method:     r.Method,
+			Middleware: int32(r.Middleware), //nolint:gosec // Small count.
+			Admin:      r.Admin,
+		})
+	}
+
+	d.SseEndpoints = slices.Clone(s.sseEndpoints)
+
+	d.Services = describeServices(s.grpcServer, false)
+	if s.adminGRPCServer != nil {
+		d.Services = append(d.Services, describeServices(s.adminGRPCServer, true)...)
+	}
+	return d
+}
+
+// address returns the address the server listens on.
+func (s *Server) address() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+	return net.JoinHostPort(s.host, strconv.Itoa(s.port))
+}
+
+// logDescription logs a summary of the server, see Describe.
+func (s *Server) logDescription(ctx context.Context) {
+	d := s.Describe()
+	plugins := make([]string, 0, len(d.Plugins))
+	for _, p := range d.Plugins {
+		if p.Version != "" {
+			plugins = append(plugins, p.Key+"@"+p.Version)
+		} else {
+			plugins = append(plugins, p.Key)
+		}
+	}
+	services := make([]string, 0, len(d.Services))
+	for _, svc := range d.Services {
+		services = append(services, svc.Name)
+	}
+	logging.Infow(ctx, "📋  Server configuration",
+		"address", d.Address,
+		"adminAddress", d.AdminAddress,
+		"tls", d.Tls,
+		"clientCertificates", d.ClientCertificates,
+		"plugins", plugins,
+		"services", services,
+		"routes", len(d.Routes),
+		"sseEndpoints", d.SseEndpoints,
+	)
+
+	interceptors := make([]string, 0, len(s.interceptors))
+	for _, ic := range s.interceptors {
+		interceptors = append(interceptors, ic.Phase.String()+":"+ic.Name)
+	}
+	logging.Debugw(ctx, "GRPC interceptor chain", "interceptors", interceptors)
+}
+
+func describeServices(s *grpc.Server, admin bool) []*ServiceDescription {
+	if s == nil {
+		return nil
+	}
+	info := s.GetServiceInfo()
+	names := make([]string, 0, len(info))
+	for name := range info {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	services := make([]*ServiceDescription, 0, len(names))
+	for _, name := range names {
+		svc := &ServiceDescription{Name: name, Admin: admin}
+		for _, m := range info[name].Methods {
+			svc.Methods = append(svc.Methods, &MethodDescription{
+				Name:            m.Name,
+				ClientStreaming: m.IsClientStream,
+				ServerStreaming: m.IsServerStream,
+			})
+		}
+		services = append(services, svc)
+	}
+	return services
+}
+
+// pluginVersion returns the plugin's version, or the version of the module
+// which contains the plugin's package.
+func pluginVersion(p Plugin) string {
+	if vp, ok := p.(VersionedPlugin); ok {
+		return vp.Version()
+	}
+	t := reflect.TypeOf(p)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	pkg := t.PkgPath()
+	version, longest := "", 0
+	for _, m := range append([]*debug.Module{&bi.Main}, bi.Deps...) {
+		if m.Path == "" || len(m.Path) <= longest {
+			continue
+		}
+		if pkg == m.Path || strings.HasPrefix(pkg, m.Path+"/") {
+			version, longest = m.Version, len(m.Path)
+		}
+	}
+	if version == "(devel)" {
+		return ""
+	}
+	return version
+}
+
+// adminService implements AdminService.
+type adminService struct {
+	UnimplementedAdminServiceServer
+	s *Server
+}
+
+func (a *adminService) Describe(context.Context, *DescribeRequest) (*ServerDescription, error) {
+	return a.s.Describe(), nil
+}
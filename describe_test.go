@@ -0,0 +1,95 @@
+package prefab
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type versionedPlugin struct{}
+
+func (p *versionedPlugin) Name() string    { return "versioned" }
+func (p *versionedPlugin) Version() string { return "v1.2.3" }
+
+func TestDescribe(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := New(
+		WithContext(context.Background()),
+		WithHost("localhost"),
+		WithPort(9999),
+		WithAdminListener(ln),
+		WithPlugin(&adminPlugin{}),
+		WithPlugin(&versionedPlugin{}),
+		WithSSEStream[*wrapperspb.StringValue]("/events/{id}", func(context.Context, map[string]string, grpc.ClientConnInterface) (ClientStream[*wrapperspb.StringValue], error) {
+			return nil, nil
+		}),
+	)
+
+	d := s.Describe()
+	assert.Equal(t, "localhost:9999", d.GetAddress())
+	assert.Equal(t, ln.Addr().String(), d.GetAdminAddress())
+	assert.False(t, d.GetTls())
+	assert.NotEmpty(t, d.GetGoVersion())
+	assert.Equal(t, []string{"/events/{id}"}, d.GetSseEndpoints())
+
+	plugins := map[string]string{}
+	for _, p := range d.GetPlugins() {
+		plugins[p.GetName()] = p.GetVersion()
+	}
+	assert.Contains(t, plugins, "admin_test")
+	assert.Equal(t, "v1.2.3", plugins["versioned"])
+
+	var routes []string
+	for _, r := range d.GetRoutes() {
+		routes = append(routes, r.GetPattern())
+	}
+	assert.Contains(t, routes, "/debug/test")
+	assert.Contains(t, routes, "GET /debug/routes")
+
+	services := map[string]bool{}
+	for _, svc := range d.GetServices() {
+		services[svc.GetName()] = svc.GetAdmin()
+	}
+	assert.Contains(t, services, "prefab.MetaService")
+	assert.False(t, services["prefab.MetaService"])
+	assert.True(t, services["prefab.AdminService"])
+}
+
+func TestDescribe_NoAdminListener(t *testing.T) {
+	s := New(WithContext(context.Background()))
+
+	d := s.Describe()
+	assert.Empty(t, d.GetAdminAddress())
+	for _, svc := range d.GetServices() {
+		assert.NotEqual(t, "prefab.AdminService", svc.GetName(), "admin service isn't served publicly")
+	}
+}
+
+func TestAdminService(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := New(WithContext(context.Background()), WithAdminListener(ln), WithPlugin(&versionedPlugin{}))
+	s.httpServer = &http.Server{}
+	require.NoError(t, s.startAdmin(s.baseContext))
+	defer func() { require.NoError(t, s.Shutdown()) }()
+
+	conn, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	d, err := NewAdminServiceClient(conn).Describe(t.Context(), &DescribeRequest{})
+	require.NoError(t, err)
+	require.Len(t, d.GetPlugins(), 1)
+	assert.Equal(t, "versioned", d.GetPlugins()[0].GetName())
+}
@@ -0,0 +1,149 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// CatalogEntry describes an error declared in an error catalog. Catalogs are
+// proto enums whose values are annotated with `(prefab.error)`, and code
+// generated by protoc-gen-prefab registers them with RegisterCatalog.
+type CatalogEntry struct {
+	// Full name of the enum which declares the error, e.g. "notes.NoteError".
+	Domain string
+
+	// Name and number of the enum value, e.g. "NOTE_NOT_FOUND".
+	Reason string
+	Number int32
+
+	// GRPC status code of the error.
+	Code codes.Code
+
+	// HTTP status of the error, or 0 to derive it from Code.
+	HTTPStatus int
+
+	// User presentable message, with fmt verbs for the error's arguments.
+	Message string
+}
+
+type catalogKey struct {
+	domain string
+	number int32
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[catalogKey]CatalogEntry{}
+)
+
+// RegisterCatalog adds errors to the catalog. Entries replace previously
+// registered entries with the same domain and number.
+func RegisterCatalog(entries ...CatalogEntry) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	for _, e := range entries {
+		catalog[catalogKey{e.Domain, e.Number}] = e
+	}
+}
+
+// Catalog returns the registered catalog entries, sorted by domain and number.
+func Catalog() []CatalogEntry {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	out := make([]CatalogEntry, 0, len(catalog))
+	for _, e := range catalog {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Domain != out[j].Domain {
+			return out[i].Domain < out[j].Domain
+		}
+		return out[i].Number < out[j].Number
+	})
+	return out
+}
+
+// FromCatalog creates an error from a catalog entry, identified by an enum
+// value. The error has the entry's code, HTTP status, and message formatted
+// with args, along with an ErrorInfo detail so clients can identify it. The
+// message template is translated before it's formatted when the error is
+// localized.
+//
+// Generated code provides an `Err` method on the enum, so usually this is
+// called as:
+//
+//	return nil, notes.NoteError_NOTE_NOT_FOUND.Err(req.Id)
+//
+// If the value isn't registered, an Unknown error naming it is returned.
+func FromCatalog(code protoreflect.Enum, args ...any) *Error {
+	return fromCatalog(code, 1, args)
+}
+
+// FromCatalogSkip is like FromCatalog, but skips frames of the stacktrace so
+// it starts at the caller of a generated constructor.
+func FromCatalogSkip(code protoreflect.Enum, skip int, args ...any) *Error {
+	return fromCatalog(code, skip+1, args)
+}
+
+func fromCatalog(code protoreflect.Enum, skip int, args []any) *Error {
+	domain := string(code.Descriptor().FullName())
+	catalogMu.RLock()
+	entry, ok := catalog[catalogKey{domain, int32(code.Number())}]
+	catalogMu.RUnlock()
+
+	stack := make([]uintptr, MaxStackDepth)
+	length := runtime.Callers(2+skip, stack)
+	if !ok {
+		name := fmt.Sprintf("%s(%d)", domain, code.Number())
+		if v := code.Descriptor().Values().ByNumber(code.Number()); v != nil {
+			name = string(v.FullName())
+		}
+		return &Error{
+			Err:   fmt.Errorf("errors: %s isn't in the error catalog", name),
+			stack: stack[:length],
+			code:  codes.Unknown,
+		}
+	}
+
+	msg := fmt.Sprintf(entry.Message, args...)
+	info := &errdetails.ErrorInfo{Reason: entry.Reason, Domain: entry.Domain}
+	if len(args) > 0 {
+		info.Metadata = make(map[string]string, len(args))
+		for i, a := range args {
+			info.Metadata[strconv.Itoa(i)] = fmt.Sprint(a)
+		}
+	}
+	return &Error{
+		Err:                    fmt.Errorf("%s: %s", entry.Reason, msg),
+		stack:                  stack[:length],
+		code:                   entry.Code,
+		httpStatusCode:         entry.HTTPStatus,
+		userPresentableMessage: msg,
+		details:                []protoiface.MessageV1{info},
+		catalog:                &catalogRef{entry: entry, args: args},
+	}
+}
+
+// catalogRef records the catalog entry an error was created from, so its
+// message can be localized from the template.
+type catalogRef struct {
+	entry CatalogEntry
+	args  []any
+}
+
+// CatalogEntry returns the catalog entry the error was created from, see
+// FromCatalog.
+func (err *Error) CatalogEntry() (CatalogEntry, bool) {
+	if err.catalog == nil {
+		return CatalogEntry{}, false
+	}
+	return err.catalog.entry, true
+}
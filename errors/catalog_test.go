@@ -0,0 +1,64 @@
+package errors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	RegisterCatalog(
+		CatalogEntry{Domain: "google.rpc.Code", Reason: "NOT_FOUND", Number: 5, Code: codes.NotFound, Message: "Note %s was not found"},
+		CatalogEntry{Domain: "google.rpc.Code", Reason: "ABORTED", Number: 10, Code: codes.Aborted, HTTPStatus: http.StatusConflict, Message: "Edit conflict"},
+	)
+}
+
+func TestFromCatalog(t *testing.T) {
+	err := FromCatalog(code.Code_NOT_FOUND, "n1")
+	assert.Equal(t, codes.NotFound, err.Code())
+	assert.Equal(t, http.StatusNotFound, err.HTTPStatusCode())
+	assert.Equal(t, "Note n1 was not found", err.UserPresentableMessage())
+	assert.Equal(t, "NOT_FOUND: Note n1 was not found", err.Error())
+	assert.Equal(t, "TestFromCatalog", err.StackFrames()[0].Name)
+
+	entry, ok := err.CatalogEntry()
+	require.True(t, ok)
+	assert.Equal(t, "google.rpc.Code", entry.Domain)
+
+	details := err.Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "NOT_FOUND", info.GetReason())
+	assert.Equal(t, "google.rpc.Code", info.GetDomain())
+	assert.Equal(t, map[string]string{"0": "n1"}, info.GetMetadata())
+
+	wrapped := WrapPrefix(err, "loading note", 0)
+	_, ok = wrapped.CatalogEntry()
+	assert.True(t, ok, "wrapped errors retain the catalog entry")
+
+	assert.Equal(t, http.StatusConflict, FromCatalog(code.Code_ABORTED).HTTPStatusCode())
+}
+
+func TestFromCatalog_Unregistered(t *testing.T) {
+	err := FromCatalog(code.Code_INTERNAL)
+	assert.Equal(t, codes.Unknown, err.Code())
+	assert.Contains(t, err.Error(), "google.rpc.INTERNAL isn't in the error catalog")
+	_, ok := err.CatalogEntry()
+	assert.False(t, ok)
+}
+
+func TestCatalog(t *testing.T) {
+	var reasons []string
+	for _, e := range Catalog() {
+		if e.Domain == "google.rpc.Code" {
+			reasons = append(reasons, e.Reason)
+		}
+	}
+	assert.Equal(t, []string{"NOT_FOUND", "ABORTED"}, reasons, "sorted by number")
+}
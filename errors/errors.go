@@ -50,8 +50,11 @@ import (
 	"reflect"
 	"runtime"
 
+	"github.com/dpup/prefab/redact"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
 	"google.golang.org/protobuf/runtime/protoiface"
 )
 
@@ -80,6 +83,9 @@ type Error struct {
 
 	// Log fields to be unpacked when logging this error
 	logFields map[string]interface{}
+
+	// Catalog entry the error was created from, see FromCatalog.
+	catalog *catalogRef
 }
 
 // New makes an Error from the given value. If that value is already an
@@ -166,6 +172,7 @@ func WrapPrefix(e interface{}, prefix string, skip int) *Error {
 		userPresentableMessage: err.userPresentableMessage,
 		prefix:                 prefix,
 		logFields:              err.logFields,
+		catalog:                err.catalog,
 	}
 }
 
@@ -186,6 +193,7 @@ func Mark(e interface{}, skip int) *Error {
 			userPresentableMessage: err.userPresentableMessage,
 			prefix:                 err.prefix,
 			logFields:              err.logFields,
+			catalog:                err.catalog,
 		}
 	}
 
@@ -218,6 +226,13 @@ func WithDetails(err error, details ...protoiface.MessageV1) *Error {
 	return Wrap(err, 1).WithDetails(details...)
 }
 
+// WithFieldViolation takes an error and records a field violation against it,
+// using a `BadRequest` detail. If the error is not already an `Error`, it will
+// be wrapped in one.
+func WithFieldViolation(err error, field, description string) *Error {
+	return Wrap(err, 1).WithFieldViolation(field, description)
+}
+
 // WithLogField takes an error and adds a log field to it. If the error is
 // not already an `Error`, it will be wrapped in one. The field will be unpacked
 // by the logging middleware when this error is logged.
@@ -342,6 +357,37 @@ func (err *Error) WithDetails(details ...protoiface.MessageV1) *Error {
 	return err
 }
 
+// WithFieldViolation records a violation for the given field. Violations are
+// collected in a single `BadRequest` detail, which is created on first use.
+func (err *Error) WithFieldViolation(field, description string) *Error {
+	violation := &errdetails.BadRequest_FieldViolation{
+		Field:       field,
+		Description: description,
+	}
+	for _, d := range err.details {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			br.FieldViolations = append(br.FieldViolations, violation)
+			return err
+		}
+	}
+	err.details = append(err.details, &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{violation},
+	})
+	return err
+}
+
+// FieldViolations returns the field violations recorded against the error, or
+// nil if there are none.
+func (err *Error) FieldViolations() []*errdetails.BadRequest_FieldViolation {
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range err.details {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = append(violations, br.GetFieldViolations()...)
+		}
+	}
+	return violations
+}
+
 // HTTPStatusCode returns the HTTP status code that should be returned to the
 // client. If a code is set, it will be used, otherwise a default will be
 // returned based on the gRPC code.
@@ -407,7 +453,11 @@ func (err *Error) WithUserPresentableMessage(userPresentableMessage string, args
 func (err *Error) GRPCStatus() *status.Status {
 	st := status.New(err.Code(), err.UserPresentableMessage())
 	if len(err.details) > 0 {
-		st, _ = st.WithDetails(err.details...)
+		details := make([]protoiface.MessageV1, len(err.details))
+		for i, d := range err.details {
+			details[i] = protoadapt.MessageV1Of(redact.Message(protoadapt.MessageV2Of(d)))
+		}
+		st, _ = st.WithDetails(details...)
 	}
 	return st
 }
@@ -0,0 +1,166 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: errors/errors.proto
+
+package errors
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	anypb "google.golang.org/protobuf/types/known/anypb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// One of the failures combined into an error by errors.Aggregate, for example
+// an item of a batch operation. Each failure is a detail of the combined error,
+// and the GRPC Gateway lists them in the response's `errors` field.
+type ItemError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the item which failed, e.g. its index in the request or its ID.
+	Item     string `protobuf:"bytes,1,opt,name=item,proto3" json:"item,omitempty"`
+	Code     int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	CodeName string `protobuf:"bytes,3,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
+	// User presentable message of the item's error.
+	Message       string       `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Details       []*anypb.Any `protobuf:"bytes,5,rep,name=details,proto3" json:"details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ItemError) Reset() {
+	*x = ItemError{}
+	mi := &file_errors_errors_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ItemError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ItemError) ProtoMessage() {}
+
+func (x *ItemError) ProtoReflect() protoreflect.Message {
+	mi := &file_errors_errors_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ItemError.ProtoReflect.Descriptor instead.
+func (*ItemError) Descriptor() ([]byte, []int) {
+	return file_errors_errors_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ItemError) GetItem() string {
+	if x != nil {
+		return x.Item
+	}
+	return ""
+}
+
+func (x *ItemError) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ItemError) GetCodeName() string {
+	if x != nil {
+		return x.CodeName
+	}
+	return ""
+}
+
+func (x *ItemError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ItemError) GetDetails() []*anypb.Any {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+var File_errors_errors_proto protoreflect.FileDescriptor
+
+const file_errors_errors_proto_rawDesc = "" +
+	"\n" +
+	"\x13errors/errors.proto\x12\x06prefab\x1a\x19google/protobuf/any.proto\"\x9a\x01\n" +
+	"\tItemError\x12\x12\n" +
+	"\x04item\x18\x01 \x01(\tR\x04item\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\x12\x1b\n" +
+	"\tcode_name\x18\x03 \x01(\tR\bcodeName\x12\x18\n" +
+	"\amessage\x18\x04 \x01(\tR\amessage\x12.\n" +
+	"\adetails\x18\x05 \x03(\v2\x14.google.protobuf.AnyR\adetailsB\x1fZ\x1dgithub.com/dpup/prefab/errorsb\x06proto3"
+
+var (
+	file_errors_errors_proto_rawDescOnce sync.Once
+	file_errors_errors_proto_rawDescData []byte
+)
+
+func file_errors_errors_proto_rawDescGZIP() []byte {
+	file_errors_errors_proto_rawDescOnce.Do(func() {
+		file_errors_errors_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_errors_errors_proto_rawDesc), len(file_errors_errors_proto_rawDesc)))
+	})
+	return file_errors_errors_proto_rawDescData
+}
+
+var file_errors_errors_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_errors_errors_proto_goTypes = []any{
+	(*ItemError)(nil), // 0: prefab.ItemError
+	(*anypb.Any)(nil), // 1: google.protobuf.Any
+}
+var file_errors_errors_proto_depIdxs = []int32{
+	1, // 0: prefab.ItemError.details:type_name -> google.protobuf.Any
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_errors_errors_proto_init() }
+func file_errors_errors_proto_init() {
+	if File_errors_errors_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_errors_errors_proto_rawDesc), len(file_errors_errors_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_errors_errors_proto_goTypes,
+		DependencyIndexes: file_errors_errors_proto_depIdxs,
+		MessageInfos:      file_errors_errors_proto_msgTypes,
+	}.Build()
+	File_errors_errors_proto = out.File
+	file_errors_errors_proto_goTypes = nil
+	file_errors_errors_proto_depIdxs = nil
+}
@@ -64,6 +64,22 @@ func TestGRPCStatus(t *testing.T) {
 	assert.Equal(t, "test_field", st.Details()[0].(*errdetails.BadRequest).FieldViolations[0].Field)
 }
 
+func TestFieldViolations(t *testing.T) {
+	err := NewC("invalid request", codes.InvalidArgument).
+		WithFieldViolation("name", "must not be empty").
+		WithFieldViolation("email", "must be a valid email address")
+
+	assert.Len(t, err.Details(), 1, "violations should share a single BadRequest detail")
+	violations := err.FieldViolations()
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "name", violations[0].Field)
+	assert.Equal(t, "must be a valid email address", violations[1].Description)
+
+	wrapped := WithFieldViolation(fmt.Errorf("plain error"), "id", "required")
+	assert.Equal(t, "id", wrapped.FieldViolations()[0].Field)
+	assert.Equal(t, "id", wrapped.GRPCStatus().Details()[0].(*errdetails.BadRequest).FieldViolations[0].Field)
+}
+
 func TestPublicMessage(t *testing.T) {
 	err := New("test error")
 	assert.Equal(t, "test error", err.GRPCStatus().Message())
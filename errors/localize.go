@@ -0,0 +1,39 @@
+package errors
+
+import "fmt"
+
+// Localizer translates a user presentable message into the given locale. It
+// should return an empty string if no translation is available.
+type Localizer func(locale, message string) string
+
+// Localize returns a copy of err with its user presentable message translated
+// into locale. If err is not an `Error`, or no translation is available, err is
+// returned unchanged. The original error is never modified, so it is safe to
+// localize sentinel errors.
+//
+// Errors created with FromCatalog are localized by translating the message
+// template, which is then formatted with the error's arguments.
+func Localize(err error, locale string, l Localizer) error {
+	if err == nil || l == nil || locale == "" {
+		return err
+	}
+	var e *Error
+	if !As(err, &e) {
+		return err
+	}
+	// Catalog errors translate the template, unless the message was replaced.
+	var translated string
+	if e.catalog != nil && e.userPresentableMessage == fmt.Sprintf(e.catalog.entry.Message, e.catalog.args...) {
+		if t := l(locale, e.catalog.entry.Message); t != "" {
+			translated = fmt.Sprintf(t, e.catalog.args...)
+		}
+	} else {
+		translated = l(locale, e.UserPresentableMessage())
+	}
+	if translated == "" {
+		return err
+	}
+	c := *e
+	c.userPresentableMessage = translated
+	return &c
+}
@@ -0,0 +1,42 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+)
+
+func TestLocalize(t *testing.T) {
+	sentinel := NewC("not found", codes.NotFound).WithUserPresentableMessage("Not found")
+	l := func(locale, msg string) string {
+		if locale == "de" {
+			return "Nicht gefunden"
+		}
+		return ""
+	}
+
+	err := Localize(sentinel, "de", l)
+	assert.Equal(t, "Nicht gefunden", err.(*Error).UserPresentableMessage())
+	assert.Equal(t, codes.NotFound, Code(err))
+	assert.True(t, Is(err, sentinel))
+	assert.Equal(t, "Not found", sentinel.UserPresentableMessage())
+
+	assert.Same(t, sentinel, Localize(sentinel, "fr", l))
+	assert.NoError(t, Localize(nil, "de", l))
+}
+
+func TestLocalize_Catalog(t *testing.T) {
+	l := func(locale, msg string) string {
+		if msg == "Note %s was not found" {
+			return "Notiz %s wurde nicht gefunden"
+		}
+		return ""
+	}
+	err := Localize(FromCatalog(code.Code_NOT_FOUND, "n1"), "de", l)
+	assert.Equal(t, "Notiz n1 wurde nicht gefunden", err.(*Error).UserPresentableMessage(), "template is translated")
+
+	replaced := FromCatalog(code.Code_NOT_FOUND, "n1").WithUserPresentableMessage("Gone")
+	assert.Same(t, replaced, Localize(replaced, "de", l), "replaced messages aren't templated")
+}
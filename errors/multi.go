@@ -0,0 +1,206 @@
+package errors
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/runtime/protoiface"
+)
+
+// Multi collects the failures of a batch operation, keyed by the item which
+// failed. The zero value is ready to use:
+//
+//	var errs errors.Multi
+//	for _, note := range req.Notes {
+//	    if err := s.save(ctx, note); err != nil {
+//	        errs.Add(note.Id, err)
+//	    }
+//	}
+//	return resp, errs.Err()
+type Multi struct {
+	items []failure
+}
+
+type failure struct {
+	item string
+	err  error
+}
+
+// Add records that item failed with err. Nil errors are ignored.
+func (m *Multi) Add(item string, err error) {
+	if err != nil {
+		m.items = append(m.items, failure{item: item, err: err})
+	}
+}
+
+// Len returns the number of failures recorded.
+func (m *Multi) Len() int {
+	return len(m.items)
+}
+
+// Err combines the recorded failures into a single error, or returns nil if
+// there are none. See Aggregate.
+func (m *Multi) Err() error {
+	if len(m.items) == 0 {
+		return nil
+	}
+	return m.aggregate(1)
+}
+
+// Aggregate combines errors from multiple failures into one, keyed by their
+// index in errs. Like Join, nil errors are discarded, nil is returned if every
+// error is nil, and Is and As match any of the errors.
+//
+// The combined error has the most severe code of the errors, for example
+// Internal rather than NotFound, and an ItemError detail for each failure with
+// its code, user presentable message, and details. The GRPC Gateway lists the
+// failures in the `errors` field of the response.
+func Aggregate(errs ...error) error {
+	var m Multi
+	for i, err := range errs {
+		m.Add(strconv.Itoa(i), err)
+	}
+	if m.Len() == 0 {
+		return nil
+	}
+	return m.aggregate(1)
+}
+
+func (m *Multi) aggregate(skip int) *Error {
+	stack := make([]uintptr, MaxStackDepth)
+	length := runtime.Callers(2+skip, stack)
+
+	items := append([]failure(nil), m.items...)
+	combined := codes.OK
+	details := make([]protoiface.MessageV1, 0, len(items))
+	for _, it := range items {
+		c := Code(it.err)
+		if severity(c) > severity(combined) {
+			combined = c
+		}
+		details = append(details, itemDetail(it, c))
+	}
+
+	msg := fmt.Sprintf("%d items failed", len(items))
+	if len(items) == 1 {
+		msg = userPresentableMessage(items[0].err)
+	}
+	return &Error{
+		Err:                    &multiError{items: items},
+		stack:                  stack[:length],
+		code:                   combined,
+		details:                details,
+		userPresentableMessage: msg,
+	}
+}
+
+// itemDetail describes a failure for clients.
+func itemDetail(it failure, c codes.Code) *ItemError {
+	d := &ItemError{
+		Item:     it.item,
+		Code:     int32(c),                 //nolint:gosec // Codes are small.
+		CodeName: code.Code_name[int32(c)], //nolint:gosec // Codes are small.
+		Message:  userPresentableMessage(it.err),
+	}
+	var e *Error
+	if As(it.err, &e) {
+		d.Details = e.GRPCStatus().Proto().GetDetails()
+	}
+	return d
+}
+
+func userPresentableMessage(err error) string {
+	var e *Error
+	if As(err, &e) {
+		return e.UserPresentableMessage()
+	}
+	return err.Error()
+}
+
+// severity ranks codes for choosing the code of a combined error. Server-side
+// failures outrank client errors, and OK ranks lowest.
+func severity(c codes.Code) int {
+	switch c {
+	case codes.OK:
+		return 0
+	case codes.Canceled:
+		return 1
+	case codes.InvalidArgument:
+		return 2
+	case codes.NotFound:
+		return 3
+	case codes.AlreadyExists:
+		return 4
+	case codes.OutOfRange:
+		return 5
+	case codes.FailedPrecondition:
+		return 6
+	case codes.PermissionDenied:
+		return 7
+	case codes.Unauthenticated:
+		return 8
+	case codes.Aborted:
+		return 9
+	case codes.ResourceExhausted:
+		return 10
+	case codes.Unimplemented:
+		return 11
+	case codes.DeadlineExceeded:
+		return 12
+	case codes.Unavailable:
+		return 13
+	case codes.Unknown:
+		return 14
+	case codes.Internal:
+		return 15
+	case codes.DataLoss:
+		return 16
+	}
+	return 14
+}
+
+// multiError is the underlying error of an aggregated error.
+type multiError struct {
+	items []failure
+}
+
+func (e *multiError) Error() string {
+	msgs := make([]string, len(e.items))
+	for i, it := range e.items {
+		msgs[i] = it.item + ": " + it.err.Error()
+	}
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+	return fmt.Sprintf("%d errors: %s", len(msgs), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the combined errors, so Is and As match any of them.
+func (e *multiError) Unwrap() []error {
+	errs := make([]error, len(e.items))
+	for i, it := range e.items {
+		errs[i] = it.err
+	}
+	return errs
+}
+
+// ItemErrors returns the ItemError details of an aggregated error, or nil if
+// err isn't an aggregated error.
+func ItemErrors(err error) []*ItemError {
+	var e *Error
+	if !As(err, &e) {
+		return nil
+	}
+	var items []*ItemError
+	for _, d := range e.details {
+		if item, ok := protoadapt.MessageV2Of(d).(*ItemError); ok {
+			items = append(items, item)
+		}
+	}
+	return items
+}
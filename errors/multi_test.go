@@ -0,0 +1,61 @@
+package errors
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAggregate(t *testing.T) {
+	assert.NoError(t, Aggregate())
+	assert.NoError(t, Aggregate(nil, nil))
+
+	notFound := NewC("missing", codes.NotFound).WithUserPresentableMessage("Not found")
+	err := Aggregate(nil, notFound, io.EOF, NewC("bad", codes.InvalidArgument).WithFieldViolation("title", "required"))
+	require.Error(t, err)
+	assert.Equal(t, codes.Unknown, Code(err), "most severe code")
+	assert.Equal(t, "3 errors: 1: missing; 2: EOF; 3: bad", err.Error())
+	assert.True(t, Is(err, notFound))
+	assert.True(t, Is(err, io.EOF))
+
+	items := ItemErrors(err)
+	require.Len(t, items, 3)
+	assert.Equal(t, "1", items[0].GetItem())
+	assert.Equal(t, "NOT_FOUND", items[0].GetCodeName())
+	assert.Equal(t, "Not found", items[0].GetMessage())
+	assert.Equal(t, "UNKNOWN", items[1].GetCodeName())
+	assert.Equal(t, "3", items[2].GetItem())
+	require.Len(t, items[2].GetDetails(), 1)
+	br := &errdetails.BadRequest{}
+	require.NoError(t, items[2].GetDetails()[0].UnmarshalTo(br))
+	assert.Equal(t, "title", br.GetFieldViolations()[0].GetField())
+
+	st := status.Convert(err)
+	assert.Equal(t, "3 items failed", st.Message())
+	assert.Len(t, st.Details(), 3, "items are sent to GRPC clients")
+}
+
+func TestMulti(t *testing.T) {
+	var m Multi
+	m.Add("a", nil)
+	assert.NoError(t, m.Err())
+
+	m.Add("b", NewC("denied", codes.PermissionDenied).WithUserPresentableMessage("Can't edit b"))
+	err := m.Err()
+	assert.Equal(t, 1, m.Len())
+	assert.Equal(t, codes.PermissionDenied, Code(err))
+	assert.Equal(t, "Can't edit b", err.(*Error).UserPresentableMessage(), "a single failure keeps its message")
+	assert.Equal(t, "TestMulti", err.(*Error).StackFrames()[0].Name)
+
+	m.Add("c", NewC("invalid", codes.InvalidArgument))
+	assert.Equal(t, codes.PermissionDenied, Code(m.Err()))
+	m.Add("d", NewC("oops", codes.Internal))
+	assert.Equal(t, codes.Internal, Code(m.Err()))
+
+	assert.Nil(t, ItemErrors(io.EOF))
+}
@@ -0,0 +1,228 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: examples/ssestream/counterservice/counterservice.proto
+
+package counterservice
+
+import (
+	_ "github.com/dpup/prefab"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the counter, echoed in each response.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Number to start counting from.
+	Start int32 `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	// How many counts to send, defaults to 20.
+	Limit int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// Milliseconds between counts, defaults to 500.
+	IntervalMs    int32 `protobuf:"varint,4,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_examples_ssestream_counterservice_counterservice_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_examples_ssestream_counterservice_counterservice_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_examples_ssestream_counterservice_counterservice_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CountRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CountRequest) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *CountRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *CountRequest) GetIntervalMs() int32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Count         int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Timestamp     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_examples_ssestream_counterservice_counterservice_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_examples_ssestream_counterservice_counterservice_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_examples_ssestream_counterservice_counterservice_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CountResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CountResponse) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *CountResponse) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+var File_examples_ssestream_counterservice_counterservice_proto protoreflect.FileDescriptor
+
+const file_examples_ssestream_counterservice_counterservice_proto_rawDesc = "" +
+	"\n" +
+	"6examples/ssestream/counterservice/counterservice.proto\x12\x17prefab.examples.counter\x1a\x1cgoogle/api/annotations.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\fserver.proto\"o\n" +
+	"\fCountRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x05R\x05start\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1f\n" +
+	"\vinterval_ms\x18\x04 \x01(\x05R\n" +
+	"intervalMs\"s\n" +
+	"\rCountResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05count\x18\x02 \x01(\x05R\x05count\x128\n" +
+	"\ttimestamp\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp2\x9b\x01\n" +
+	"\x0eCounterService\x12\x88\x01\n" +
+	"\x05Count\x12%.prefab.examples.counter.CountRequest\x1a&.prefab.examples.counter.CountResponse\".\xba\xb5\x18\x0f/counter/{name}\x82\xd3\xe4\x93\x02\x15\x12\x13/api/counter/{name}0\x01B:Z8github.com/dpup/prefab/examples/ssestream/counterserviceb\x06proto3"
+
+var (
+	file_examples_ssestream_counterservice_counterservice_proto_rawDescOnce sync.Once
+	file_examples_ssestream_counterservice_counterservice_proto_rawDescData []byte
+)
+
+func file_examples_ssestream_counterservice_counterservice_proto_rawDescGZIP() []byte {
+	file_examples_ssestream_counterservice_counterservice_proto_rawDescOnce.Do(func() {
+		file_examples_ssestream_counterservice_counterservice_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_examples_ssestream_counterservice_counterservice_proto_rawDesc), len(file_examples_ssestream_counterservice_counterservice_proto_rawDesc)))
+	})
+	return file_examples_ssestream_counterservice_counterservice_proto_rawDescData
+}
+
+var file_examples_ssestream_counterservice_counterservice_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_examples_ssestream_counterservice_counterservice_proto_goTypes = []any{
+	(*CountRequest)(nil),          // 0: prefab.examples.counter.CountRequest
+	(*CountResponse)(nil),         // 1: prefab.examples.counter.CountResponse
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+}
+var file_examples_ssestream_counterservice_counterservice_proto_depIdxs = []int32{
+	2, // 0: prefab.examples.counter.CountResponse.timestamp:type_name -> google.protobuf.Timestamp
+	0, // 1: prefab.examples.counter.CounterService.Count:input_type -> prefab.examples.counter.CountRequest
+	1, // 2: prefab.examples.counter.CounterService.Count:output_type -> prefab.examples.counter.CountResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_examples_ssestream_counterservice_counterservice_proto_init() }
+func file_examples_ssestream_counterservice_counterservice_proto_init() {
+	if File_examples_ssestream_counterservice_counterservice_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_examples_ssestream_counterservice_counterservice_proto_rawDesc), len(file_examples_ssestream_counterservice_counterservice_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_examples_ssestream_counterservice_counterservice_proto_goTypes,
+		DependencyIndexes: file_examples_ssestream_counterservice_counterservice_proto_depIdxs,
+		MessageInfos:      file_examples_ssestream_counterservice_counterservice_proto_msgTypes,
+	}.Build()
+	File_examples_ssestream_counterservice_counterservice_proto = out.File
+	file_examples_ssestream_counterservice_counterservice_proto_goTypes = nil
+	file_examples_ssestream_counterservice_counterservice_proto_depIdxs = nil
+}
@@ -0,0 +1,25 @@
+// Code generated by protoc-gen-prefab. DO NOT EDIT.
+// source: examples/ssestream/counterservice/counterservice.proto
+
+package counterservice
+
+import (
+	context "context"
+	prefab "github.com/dpup/prefab"
+	grpc "google.golang.org/grpc"
+)
+
+// WithCountSSE returns a server option which serves CounterService.Count
+// as Server-Sent Events at "/counter/{name}". The request is populated
+// from path and query parameters, see prefab.PopulateSSERequest. The service
+// must also be registered with the server. Options can add guards, see
+// prefab.WithSSEGuard.
+func WithCountSSE(opts ...prefab.SSEOption) prefab.ServerOption {
+	return prefab.WithSSEStream("/counter/{name}", func(ctx context.Context, params map[string]string, cc grpc.ClientConnInterface) (prefab.ClientStream[*CountResponse], error) {
+		req := &CountRequest{}
+		if err := prefab.PopulateSSERequest(req, params); err != nil {
+			return nil, err
+		}
+		return NewCounterServiceClient(cc).Count(ctx, req)
+	}, opts...)
+}
@@ -1,53 +1,66 @@
 // Package main demonstrates how to use Server-Sent Events (SSE) with Prefab and gRPC streaming.
 //
+// The SSE endpoint is registered with counterservice.WithCountSSE, which is
+// generated by protoc-gen-prefab from the `(prefab.sse_path)` annotation in
+// proto/examples/ssestream/counterservice/counterservice.proto.
+//
 // Run the server:
 //
 //	go run examples/ssestream/main.go
 //
 // Test with curl:
 //
-//	curl -N http://localhost:8080/counter
+//	curl -N http://localhost:8080/counter/demo
+//	curl -N 'http://localhost:8080/counter/demo?start=100&limit=5'
 //
 // Or open client.html in a browser.
 package main
 
 import (
-	"context"
-	"fmt"
-	"io"
 	"log"
 	"time"
 
 	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/examples/ssestream/counterservice"
 	"github.com/dpup/prefab/logging"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
-// mockCounterStream is a mock gRPC client stream for demonstration.
-// In a real app, this would be the generated CounterService_StreamClient type.
-type mockCounterStream struct {
-	ctx    context.Context
-	count  int
-	ticker *time.Ticker
-	grpc.ClientStream
+// counterServer implements the streaming CounterService.
+type counterServer struct {
+	counterservice.UnimplementedCounterServiceServer
 }
 
-func (m *mockCounterStream) Recv() (*wrapperspb.StringValue, error) {
-	if m.count >= 20 {
-		return nil, io.EOF
+func (counterServer) Count(req *counterservice.CountRequest, stream grpc.ServerStreamingServer[counterservice.CountResponse]) error {
+	limit := req.GetLimit()
+	if limit <= 0 {
+		limit = 20
+	}
+	interval := time.Duration(req.GetIntervalMs()) * time.Millisecond
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
 	}
 
-	select {
-	case <-m.ctx.Done():
-		return nil, m.ctx.Err()
-	case <-m.ticker.C:
-		m.count++
-		now := timestamppb.Now()
-		msg := fmt.Sprintf(`{"count": %d, "timestamp": "%s"}`, m.count, now.AsTime().Format(time.RFC3339))
-		return wrapperspb.String(msg), nil
+	logging.Infof(stream.Context(), "Starting counter %q", req.GetName())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := range limit {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-ticker.C:
+		}
+		err := stream.Send(&counterservice.CountResponse{
+			Name:      req.GetName(),
+			Count:     req.GetStart() + i,
+			Timestamp: timestamppb.Now(),
+		})
+		if err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 func main() {
@@ -55,21 +68,20 @@ func main() {
 		prefab.WithPort(8080),
 		prefab.WithStaticFiles("/", "./examples/ssestream/static/"),
 
-		// Register SSE endpoint - Prefab handles everything
-		prefab.WithSSEStream[*wrapperspb.StringValue](
-			"/counter",
-			func(ctx context.Context, params map[string]string, cc grpc.ClientConnInterface) (prefab.ClientStream[*wrapperspb.StringValue], error) {
-				logging.Infof(ctx, "Starting mock counter stream")
-				return &mockCounterStream{
-					ctx:    ctx,
-					ticker: time.NewTicker(500 * time.Millisecond),
-				}, nil
-			},
-		),
+		// Register the streaming service, and serve it as SSE. The generated
+		// option builds the request from the path and query parameters.
+		prefab.WithGRPCService(&counterservice.CounterService_ServiceDesc, counterServer{}),
+		counterservice.WithCountSSE(),
+
+		// Also serve it through the gateway as newline-delimited JSON, without the
+		// default request timeout.
+		prefab.WithGRPCGateway(counterservice.RegisterCounterServiceHandlerFromEndpoint),
+		prefab.WithRouteTimeout("/api/counter/", 0),
 	)
 
 	log.Println("Starting SSE example server on :8080")
-	log.Println("Try: curl -N http://localhost:8080/counter")
+	log.Println("Try: curl -N http://localhost:8080/counter/demo")
+	log.Println("Or NDJSON: curl -N http://localhost:8080/api/counter/demo")
 	log.Println("Or open: http://localhost:8080/client.html")
 
 	if err := server.Start(); err != nil {
@@ -0,0 +1,241 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+const (
+	// Query params which limit the fields included in GRPC Gateway responses.
+	fieldsParam   = "fields"
+	readMaskParam = "read_mask"
+
+	fieldsMetadata   = serverutil.MetadataHTTPPrefix + "fields"
+	readMaskMetadata = serverutil.MetadataHTTPPrefix + "read-mask"
+)
+
+// ErrInvalidFieldMask is returned when a `fields` or `read_mask` query param
+// references a field that doesn't exist on the response message.
+var ErrInvalidFieldMask = errors.NewC("invalid field mask", codes.InvalidArgument)
+
+type readMaskKey struct{}
+
+// ReadMask returns the fields requested by the client, with paths normalized
+// to proto field names. Returns false if the client didn't limit the response.
+func ReadMask(ctx context.Context) (*fieldmaskpb.FieldMask, bool) {
+	m, ok := ctx.Value(readMaskKey{}).(*fieldmaskpb.FieldMask)
+	return m, ok
+}
+
+// FieldRequested returns whether a field, identified by its proto path, e.g.
+// "author.display_name", will be included in the response. Services can use
+// this to skip computing expensive fields the client didn't ask for.
+func FieldRequested(ctx context.Context, path string) bool {
+	m, ok := ReadMask(ctx)
+	if !ok {
+		return true
+	}
+	for _, p := range m.GetPaths() {
+		if p == path || strings.HasPrefix(path, p+".") || strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldMaskMetadataAnnotator maps the field mask query params to metadata.
+func fieldMaskMetadataAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	md := map[string]string{}
+	q := r.URL.Query()
+	if v := q[fieldsParam]; len(v) > 0 {
+		md[fieldsMetadata] = strings.Join(v, ",")
+	}
+	if v := q[readMaskParam]; len(v) > 0 {
+		md[readMaskMetadata] = strings.Join(v, ",")
+	}
+	return metadata.New(md)
+}
+
+// fieldMaskInterceptor validates the requested fields against the response
+// message before the handler is called, and makes them available to services
+// via ReadMask and FieldRequested.
+func fieldMaskInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	method := methodDescriptor(info.FullMethod)
+	if method == nil {
+		// Services without registered descriptors can't be validated.
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	value, ok := requestedFields(method, md)
+	if !ok {
+		return handler(ctx, req)
+	}
+	mask, err := parseFieldMask(method.Output(), value)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, readMaskKey{}, mask), req)
+}
+
+// fieldMaskForwarder removes fields the client didn't request from GRPC
+// Gateway responses.
+//
+// Example:
+//
+//	GET /api/articles/123?fields=id,title,author.name
+func fieldMaskForwarder(ctx context.Context, _ http.ResponseWriter, resp proto.Message) error {
+	name, ok := runtime.RPCMethod(ctx)
+	if !ok || resp == nil {
+		return nil
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	value, ok := requestedFields(methodDescriptor(name), md)
+	if !ok {
+		return nil
+	}
+	mask, err := parseFieldMask(resp.ProtoReflect().Descriptor(), value)
+	if err != nil {
+		return err
+	}
+	PruneMessage(resp, mask)
+	return nil
+}
+
+// requestedFields returns the field mask sent by the client. `read_mask` is
+// ignored for methods whose request declares its own `read_mask` field, since
+// the mask then belongs to the service, as described by AIP-157.
+func requestedFields(method protoreflect.MethodDescriptor, md metadata.MD) (string, bool) {
+	if v := md.Get(fieldsMetadata); len(v) > 0 {
+		return v[0], true
+	}
+	if v := md.Get(readMaskMetadata); len(v) > 0 {
+		if method != nil && method.Input().Fields().ByName(readMaskParam) != nil {
+			return "", false
+		}
+		return v[0], true
+	}
+	return "", false
+}
+
+// methodDescriptor returns the descriptor for a full method name, e.g.
+// "/prefab.MetaService/ClientConfig", or nil if it isn't registered.
+func methodDescriptor(method string) protoreflect.MethodDescriptor {
+	name := strings.TrimPrefix(strings.ReplaceAll(method, "/", "."), ".")
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil
+	}
+	md, _ := desc.(protoreflect.MethodDescriptor)
+	return md
+}
+
+// parseFieldMask parses a comma separated list of field paths, using either
+// proto or JSON field names, and validates them against a message. Paths can
+// descend into repeated and map fields, in which case they apply to each
+// element.
+func parseFieldMask(desc protoreflect.MessageDescriptor, value string) (*fieldmaskpb.FieldMask, error) {
+	mask := &fieldmaskpb.FieldMask{}
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		var names []string
+		md := desc
+		for _, seg := range strings.Split(path, ".") {
+			if md == nil {
+				return nil, errors.Mark(ErrInvalidFieldMask, 0).WithFieldViolation(fieldsParam, "cannot select fields of "+strings.Join(names, "."))
+			}
+			fd := md.Fields().ByName(protoreflect.Name(seg))
+			if fd == nil {
+				fd = md.Fields().ByJSONName(seg)
+			}
+			if fd == nil {
+				return nil, errors.Mark(ErrInvalidFieldMask, 0).WithFieldViolation(fieldsParam, "unknown field "+path)
+			}
+			names = append(names, string(fd.Name()))
+			md = nil
+			if fd.IsMap() {
+				fd = fd.MapValue()
+			}
+			if fd.Message() != nil {
+				md = fd.Message()
+			}
+		}
+		mask.Paths = append(mask.Paths, strings.Join(names, "."))
+	}
+	if len(mask.GetPaths()) == 0 {
+		return nil, errors.Mark(ErrInvalidFieldMask, 0).WithFieldViolation(fieldsParam, "no fields requested")
+	}
+	mask.Normalize()
+	return mask, nil
+}
+
+// PruneMessage clears all fields of msg which aren't included in the mask.
+// Paths through repeated and map fields apply to each element. Masks should be
+// validated against the message first, invalid paths are ignored.
+func PruneMessage(msg proto.Message, mask *fieldmaskpb.FieldMask) {
+	tree := fieldTree{}
+	for _, p := range mask.GetPaths() {
+		tree.add(strings.Split(p, "."))
+	}
+	tree.prune(msg.ProtoReflect())
+}
+
+// fieldTree is a nested set of field names. Empty subtrees keep the whole
+// field.
+type fieldTree map[protoreflect.Name]fieldTree
+
+func (t fieldTree) add(path []string) {
+	name := protoreflect.Name(path[0])
+	sub, ok := t[name]
+	if ok && len(sub) == 0 {
+		// The whole field has already been selected.
+		return
+	}
+	if len(path) == 1 {
+		t[name] = fieldTree{}
+		return
+	}
+	if sub == nil {
+		sub = fieldTree{}
+		t[name] = sub
+	}
+	sub.add(path[1:])
+}
+
+func (t fieldTree) prune(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		sub, ok := t[fd.Name()]
+		switch {
+		case !ok:
+			m.Clear(fd)
+		case len(sub) == 0:
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := range l.Len() {
+				sub.prune(l.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				sub.prune(mv.Message())
+				return true
+			})
+		case fd.Message() != nil:
+			sub.prune(v.Message())
+		}
+		return true
+	})
+}
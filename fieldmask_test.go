@@ -0,0 +1,146 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestParseFieldMask(t *testing.T) {
+	desc := (&descriptorpb.FileDescriptorProto{}).ProtoReflect().Descriptor()
+
+	mask, err := parseFieldMask(desc, "name, messageType.field.jsonName,options,options.java_package")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"message_type.field.json_name", "name", "options"}, mask.GetPaths())
+
+	_, err = parseFieldMask(desc, "name,unknown")
+	require.ErrorIs(t, err, ErrInvalidFieldMask)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	_, err = parseFieldMask(desc, "name.length")
+	require.ErrorIs(t, err, ErrInvalidFieldMask)
+
+	_, err = parseFieldMask(desc, " , ")
+	require.ErrorIs(t, err, ErrInvalidFieldMask)
+}
+
+func TestPruneMessage(t *testing.T) {
+	msg := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("A"), Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("a"), JsonName: proto.String("a")}}},
+			{Name: proto.String("B")},
+		},
+		Options: &descriptorpb.FileOptions{JavaPackage: proto.String("com.test"), GoPackage: proto.String("test")},
+	}
+	PruneMessage(msg, &fieldmaskpb.FieldMask{Paths: []string{"name", "message_type.field.name", "options.go_package"}})
+
+	assert.True(t, proto.Equal(&descriptorpb.FileDescriptorProto{
+		Name: proto.String("test.proto"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Field: []*descriptorpb.FieldDescriptorProto{{Name: proto.String("a")}}},
+			{},
+		},
+		Options: &descriptorpb.FileOptions{GoPackage: proto.String("test")},
+	}, msg), msg.String())
+}
+
+func TestPruneMessage_Map(t *testing.T) {
+	msg := &ClientConfigResponse{Configs: map[string]string{"a": "b"}, CsrfToken: "abc"}
+	PruneMessage(msg, &fieldmaskpb.FieldMask{Paths: []string{"csrf_token"}})
+	assert.Empty(t, msg.GetConfigs())
+	assert.Equal(t, "abc", msg.GetCsrfToken())
+}
+
+func TestFieldRequested(t *testing.T) {
+	ctx := t.Context()
+	assert.True(t, FieldRequested(ctx, "author"), "all fields are requested without a mask")
+
+	ctx = context.WithValue(ctx, readMaskKey{}, &fieldmaskpb.FieldMask{Paths: []string{"author.name", "title"}})
+	assert.True(t, FieldRequested(ctx, "title"))
+	assert.True(t, FieldRequested(ctx, "author"))
+	assert.True(t, FieldRequested(ctx, "author.name"))
+	assert.False(t, FieldRequested(ctx, "author.email"))
+	assert.False(t, FieldRequested(ctx, "titles"))
+}
+
+func TestFieldMaskMetadataAnnotator(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/meta/config?fields=csrfToken&fields=configs&read_mask=x", nil)
+	md := fieldMaskMetadataAnnotator(t.Context(), req)
+	assert.Equal(t, []string{"csrfToken,configs"}, md.Get(fieldsMetadata))
+	assert.Equal(t, []string{"x"}, md.Get(readMaskMetadata))
+
+	req = httptest.NewRequest(http.MethodGet, "/api/meta/config", nil)
+	assert.Empty(t, fieldMaskMetadataAnnotator(t.Context(), req))
+}
+
+func TestFieldMaskInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: MetaService_ClientConfig_FullMethodName}
+	var mask *fieldmaskpb.FieldMask
+	handler := func(ctx context.Context, _ any) (any, error) {
+		mask, _ = ReadMask(ctx)
+		return nil, nil
+	}
+
+	_, err := fieldMaskInterceptor(t.Context(), nil, info, handler)
+	require.NoError(t, err)
+	assert.Nil(t, mask)
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(readMaskMetadata, "csrfToken"))
+	_, err = fieldMaskInterceptor(ctx, nil, info, handler)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"csrf_token"}, mask.GetPaths())
+
+	ctx = metadata.NewIncomingContext(t.Context(), metadata.Pairs(fieldsMetadata, "unknown"))
+	_, err = fieldMaskInterceptor(ctx, nil, info, func(context.Context, any) (any, error) {
+		t.Fatal("handler should not be called")
+		return nil, nil
+	})
+	require.ErrorIs(t, err, ErrInvalidFieldMask)
+}
+
+func TestFieldMaskInterceptor_UnregisteredMethod(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/unknown.Service/Method"}
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(fieldsMetadata, "id"))
+	called := false
+	_, err := fieldMaskInterceptor(ctx, nil, info, func(ctx context.Context, _ any) (any, error) {
+		called = true
+		_, ok := ReadMask(ctx)
+		assert.False(t, ok)
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestFieldMaskForwarder(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/meta/config?fields=csrf_token", nil)
+	mux := runtime.NewServeMux(runtime.WithMetadata(fieldMaskMetadataAnnotator))
+	ctx, err := runtime.AnnotateContext(t.Context(), mux, req, MetaService_ClientConfig_FullMethodName)
+	require.NoError(t, err)
+
+	resp := &ClientConfigResponse{Configs: map[string]string{"a": "b"}, CsrfToken: "abc"}
+	require.NoError(t, fieldMaskForwarder(ctx, nil, resp))
+	assert.Empty(t, resp.GetConfigs())
+	assert.Equal(t, "abc", resp.GetCsrfToken())
+
+	// Responses are unchanged without a mask.
+	ctx, err = runtime.AnnotateContext(t.Context(), mux, httptest.NewRequest(http.MethodGet, "/api/meta/config", nil), MetaService_ClientConfig_FullMethodName)
+	require.NoError(t, err)
+	resp = &ClientConfigResponse{Configs: map[string]string{"a": "b"}, CsrfToken: "abc"}
+	require.NoError(t, fieldMaskForwarder(ctx, nil, resp))
+	assert.Len(t, resp.GetConfigs(), 1)
+}
@@ -22,9 +22,8 @@ func (u formDecoder) ContentType(_ interface{}) string {
 }
 
 func (u formDecoder) Marshal(v interface{}) ([]byte, error) {
-	// can marshal the response in proto message format
-	j := runtime.JSONPb{}
-	return j.Marshal(v)
+	// Responses are encoded with the server's JSON marshaler.
+	return u.Marshaler.Marshal(v)
 }
 
 // NewDecoder indicates how to decode the request.
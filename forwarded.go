@@ -0,0 +1,78 @@
+package prefab
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/metadata"
+)
+
+// WithTrustedProxies sets the proxies, as CIDR ranges or IP addresses, which
+// are trusted to report the client's address, scheme, and host in Forwarded and
+// X-Forwarded-* headers, and in PROXY protocol headers. Headers from other
+// clients are ignored. Loopback addresses are always trusted.
+//
+// The resolved values are returned by serverutil.ClientIP and
+// serverutil.ForwardedFromContext, and the client IP is added to request logs.
+//
+// Config key: `server.proxy.trusted`.
+func WithTrustedProxies(cidrs ...string) ServerOption {
+	return func(b *builder) {
+		b.trustedProxies = append(b.trustedProxies, cidrs...)
+	}
+}
+
+// WithForwardedAddress sets whether serverutil.AddressFromContext returns the
+// scheme and host reported by trusted proxies, rather than the configured
+// `address`. Useful when a service is reachable at several hostnames, but note
+// that identity tokens are bound to the address they were issued for.
+//
+// Config key: `server.proxy.forwardedAddress`.
+func WithForwardedAddress(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.forwardedAddress = enabled
+	}
+}
+
+// forwardedMiddleware resolves the client, scheme, and host of HTTP requests.
+// The request's RemoteAddr and X-Forwarded-Host header are updated, so that
+// the GRPC Gateway forwards the resolved values to GRPC services.
+func forwardedMiddleware(h http.Handler, trusted serverutil.TrustedProxies) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f := serverutil.ResolveForwarded(r, trusted)
+		r = r.WithContext(serverutil.WithForwarded(r.Context(), f))
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil && host != f.ClientIP {
+			r.RemoteAddr = net.JoinHostPort(f.ClientIP, "0")
+		}
+		r.Header = r.Header.Clone()
+		r.Header.Set("X-Forwarded-Host", f.Host)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// forwardedMetadataAnnotator passes the resolved scheme to GRPC services. The
+// gateway forwards the client IP and host itself.
+func forwardedMetadataAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	if f, ok := serverutil.ForwardedFromContext(r.Context()); ok && f.Scheme != "" {
+		return metadata.Pairs("x-forwarded-proto", f.Scheme)
+	}
+	return nil
+}
+
+// forwardedInjector adds the client IP to the request logger and, if enabled,
+// sets the server address to the one the client requested.
+func forwardedInjector(forwardedAddress bool) ConfigInjector {
+	return func(ctx context.Context) context.Context {
+		f, ok := serverutil.ForwardedFromContext(ctx)
+		if f.ClientIP != "" {
+			ctx = logging.With(ctx, logging.FromContext(ctx).With("client.ip", f.ClientIP))
+		}
+		if forwardedAddress && ok && f.Host != "" {
+			ctx = serverutil.WithAddress(ctx, f.Address())
+		}
+		return ctx
+	}
+}
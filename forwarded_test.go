@@ -0,0 +1,65 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	t.Cleanup(func() { serverutil.SetTrustedProxies(nil) })
+
+	s := New(WithTrustedProxies("10.0.0.0/8"), WithProxyProtocol(true))
+	assert.True(t, serverutil.CurrentTrustedProxies().Trusts("10.1.2.3"))
+	assert.True(t, s.proxyProtocol)
+
+	New()
+	assert.Nil(t, serverutil.CurrentTrustedProxies())
+
+	assert.Panics(t, func() { New(WithProxyProtocol(true)) }, "PROXY protocol requires trusted proxies")
+	assert.Panics(t, func() { New(WithTrustedProxies("not-an-ip")) })
+}
+
+func TestForwardedMiddleware(t *testing.T) {
+	trusted, err := serverutil.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	var got serverutil.Forwarded
+	var remoteAddr, fwdHost, address string
+	h := forwardedMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = serverutil.ForwardedFromContext(r.Context())
+		remoteAddr = r.RemoteAddr
+		fwdHost = r.Header.Get("X-Forwarded-Host")
+		ctx := forwardedInjector(true)(logging.EnsureLogger(r.Context()))
+		address = serverutil.AddressFromContext(ctx)
+	}), trusted)
+
+	r := httptest.NewRequest(http.MethodGet, "http://internal:8080/", nil)
+	r.RemoteAddr = "10.0.0.2:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "api.example.com")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, serverutil.Forwarded{ClientIP: "203.0.113.7", Scheme: "https", Host: "api.example.com"}, got)
+	assert.Equal(t, "203.0.113.7:0", remoteAddr)
+	assert.Equal(t, "api.example.com", fwdHost)
+	assert.Equal(t, "https://api.example.com", address)
+
+	// Headers from untrusted clients are replaced.
+	r = httptest.NewRequest(http.MethodGet, "http://internal:8080/", nil)
+	r.RemoteAddr = "203.0.113.7:5000"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("X-Forwarded-Host", "evil.com")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	assert.Equal(t, serverutil.Forwarded{ClientIP: "203.0.113.7", Scheme: "http", Host: "internal:8080"}, got)
+	assert.Equal(t, "203.0.113.7:5000", remoteAddr)
+	assert.Equal(t, "internal:8080", fwdHost)
+	assert.Equal(t, "http://internal:8080", address)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net/http"
 
+	"github.com/dpup/prefab/errors"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/genproto/googleapis/rpc/code"
 	"google.golang.org/protobuf/types/known/anypb"
@@ -27,15 +28,37 @@ type monkeypatcher struct {
 }
 
 func (m *monkeypatcher) Marshal(v interface{}) ([]byte, error) {
+	if r, ok := v.(*jsonResponse); ok {
+		// Errors are encoded with the same JSON options as the method's responses.
+		return m.Marshaler.Marshal(&jsonResponse{msg: customErrorResponse(r.msg), opts: r.opts})
+	}
+	return m.Marshaler.Marshal(customErrorResponse(v))
+}
+
+func customErrorResponse(v any) any {
 	if s, ok := v.(grpcStatusProto); ok {
-		v = &CustomErrorResponse{
-			Code:     s.GetCode(),
-			CodeName: code.Code_name[s.GetCode()],
-			Message:  s.GetMessage(),
-			Details:  s.GetDetails(),
+		return newCustomErrorResponse(s)
+	}
+	return v
+}
+
+func newCustomErrorResponse(s grpcStatusProto) *CustomErrorResponse {
+	resp := &CustomErrorResponse{
+		Code:     s.GetCode(),
+		CodeName: code.Code_name[s.GetCode()],
+		Message:  s.GetMessage(),
+	}
+	// Failures combined by errors.Aggregate are listed separately, so clients
+	// can tell which items failed.
+	for _, d := range s.GetDetails() {
+		item := &errors.ItemError{}
+		if d.MessageIs(item) && d.UnmarshalTo(item) == nil {
+			resp.Errors = append(resp.Errors, item)
+			continue
 		}
+		resp.Details = append(resp.Details, d)
 	}
-	return m.Marshaler.Marshal(v)
+	return resp
 }
 
 // Satisfies the interface exposed by the GRPC status proto, which in the
@@ -0,0 +1,241 @@
+package prefab
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// WithHedging sends up to maxAttempts copies of a unary call, starting a new
+// attempt each time delay passes without a response. The first successful
+// response wins and the other attempts are cancelled. An attempt that fails
+// with Unavailable starts the next attempt immediately; any other error ends
+// the call.
+//
+// Hedging trades extra load for lower tail latency, so it only applies to
+// idempotent methods: those listed, or if none are listed, those declared with
+// `option idempotency_level = IDEMPOTENT` or `NO_SIDE_EFFECTS` in their proto
+// definition. Hedged calls aren't retried with the backoff from WithRetries.
+//
+// Example:
+//
+//	prefab.Dial(ctx, "search.internal:443", prefab.WithHedging(50*time.Millisecond, 3))
+func WithHedging(delay time.Duration, maxAttempts int, methods ...string) DialOption {
+	return func(c *dialConfig) {
+		c.hedge = &hedgePolicy{delay: delay, maxAttempts: maxAttempts}
+		if len(methods) > 0 {
+			c.hedge.methods = map[string]bool{}
+			for _, m := range methods {
+				c.hedge.methods[m] = true
+			}
+		}
+	}
+}
+
+// WithDeadlineMargin shortens the deadline of outgoing calls by d, leaving
+// time for the response to make its way back before the incoming request's
+// deadline. Calls are failed with DeadlineExceeded, without being sent, if
+// less than d remains.
+//
+// Config key: `client.deadlineMargin`.
+func WithDeadlineMargin(d time.Duration) DialOption {
+	return func(c *dialConfig) {
+		c.deadlineMargin = d
+	}
+}
+
+// budgetContext applies the deadline margin to the context's deadline. The
+// returned cancel func must always be called.
+func (c *dialConfig) budgetContext(ctx context.Context) (context.Context, context.CancelFunc, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok || c.deadlineMargin <= 0 {
+		return ctx, func() {}, nil
+	}
+	if time.Until(deadline) <= c.deadlineMargin {
+		return ctx, func() {}, errors.Codef(codes.DeadlineExceeded, "prefab: deadline budget exhausted")
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline.Add(-c.deadlineMargin))
+	return ctx, cancel, nil
+}
+
+// cancelStream releases the stream's deadline once it ends.
+type cancelStream struct {
+	grpc.ClientStream
+	cancel context.CancelFunc
+}
+
+func (s *cancelStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.cancel()
+	}
+	return err
+}
+
+type hedgePolicy struct {
+	delay       time.Duration
+	maxAttempts int
+	methods     map[string]bool // Nil if methods are selected by idempotency.
+
+	idempotent sync.Map // Method name to bool.
+}
+
+// applies returns whether calls to method are hedged.
+func (h *hedgePolicy) applies(method string) bool {
+	if h == nil || h.maxAttempts < 2 {
+		return false
+	}
+	if h.methods != nil {
+		return h.methods[method]
+	}
+	if v, ok := h.idempotent.Load(method); ok {
+		return v.(bool)
+	}
+	v := isIdempotent(method)
+	h.idempotent.Store(method, v)
+	return v
+}
+
+// isIdempotent looks up the idempotency level of a method, e.g.
+// "/pkg.Service/Method", in the global proto registry.
+func isIdempotent(method string) bool {
+	name := protoreflect.FullName(strings.Replace(strings.TrimPrefix(method, "/"), "/", ".", 1))
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(name)
+	if err != nil {
+		return false
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return false
+	}
+	opts, ok := md.Options().(*descriptorpb.MethodOptions)
+	if !ok {
+		return false
+	}
+	switch opts.GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_IDEMPOTENT, descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return true
+	}
+	return false
+}
+
+type hedgeResult struct {
+	reply   proto.Message
+	err     error
+	attempt *hedgeAttempt
+}
+
+// hedgeAttempt holds the header, trailer and peer of one attempt, so that
+// concurrent attempts don't write to the caller's grpc.Header, grpc.Trailer and
+// grpc.Peer targets. Only the result that's returned is copied to them.
+type hedgeAttempt struct {
+	opts    []grpc.CallOption
+	header  metadata.MD
+	trailer metadata.MD
+	peer    peer.Peer
+	// The caller's targets.
+	headerAddr, trailerAddr *metadata.MD
+	peerAddr                *peer.Peer
+}
+
+func newHedgeAttempt(opts []grpc.CallOption) *hedgeAttempt {
+	a := &hedgeAttempt{opts: make([]grpc.CallOption, 0, len(opts))}
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case grpc.HeaderCallOption:
+			a.headerAddr = o.HeaderAddr
+			opt = grpc.Header(&a.header)
+		case grpc.TrailerCallOption:
+			a.trailerAddr = o.TrailerAddr
+			opt = grpc.Trailer(&a.trailer)
+		case grpc.PeerCallOption:
+			a.peerAddr = o.PeerAddr
+			opt = grpc.Peer(&a.peer)
+		}
+		a.opts = append(a.opts, opt)
+	}
+	return a
+}
+
+// commit copies the attempt's header, trailer and peer to the caller's
+// targets. It must only be called once the attempt has finished.
+func (a *hedgeAttempt) commit() {
+	if a.headerAddr != nil {
+		*a.headerAddr = a.header
+	}
+	if a.trailerAddr != nil {
+		*a.trailerAddr = a.trailer
+	}
+	if a.peerAddr != nil {
+		*a.peerAddr = a.peer
+	}
+}
+
+// invoke runs hedged attempts of the call. Each attempt decodes into its own
+// reply and metadata, and the winner's are copied to the caller's.
+func (h *hedgePolicy) invoke(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	msg, ok := reply.(proto.Message)
+	if !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	// Cancelling the context on return stops the attempts that lost.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, h.maxAttempts)
+	launched, finished := 0, 0
+	launch := func() {
+		launched++
+		r := msg.ProtoReflect().New().Interface()
+		a := newHedgeAttempt(opts)
+		go func() {
+			err := invoker(ctx, method, req, r, cc, a.opts...)
+			results <- hedgeResult{reply: r, err: err, attempt: a}
+		}()
+	}
+	launch()
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if launched < h.maxAttempts {
+				launch()
+				timer.Reset(h.delay)
+			}
+		case res := <-results:
+			finished++
+			if res.err == nil {
+				res.attempt.commit()
+				proto.Reset(msg)
+				proto.Merge(msg, res.reply)
+				return nil
+			}
+			if status.Code(res.err) != codes.Unavailable {
+				res.attempt.commit()
+				return res.err
+			}
+			if launched < h.maxAttempts {
+				launch()
+				timer.Reset(h.delay)
+			} else if finished == launched {
+				res.attempt.commit()
+				return res.err
+			}
+		}
+	}
+}
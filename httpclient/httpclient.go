@@ -0,0 +1,248 @@
+// Package httpclient creates HTTP clients for calling other services from a
+// prefab server, following the same conventions as prefab.Dial does for GRPC:
+//
+//   - Connection, TLS handshake, and overall timeouts are always set.
+//   - The X-Request-ID of the incoming request is forwarded, or a new ID is
+//     generated, along with trace headers such as traceparent.
+//   - Requests can be retried with backoff, or protected by a full
+//     resilience.Policy.
+//   - Bearer tokens, or tokens obtained with the OAuth client credentials
+//     grant, are added to requests.
+//   - Requests are counted per host, and published to expvar as "httpclient".
+//
+// Example:
+//
+//	client := httpclient.New(
+//		httpclient.WithRetry(resilience.RetryPolicy{MaxAttempts: 3}),
+//		httpclient.WithClientCredentials(&clientcredentials.Config{
+//			ClientID:     "reports",
+//			ClientSecret: secret,
+//			TokenURL:     "https://auth.example.com/oauth/token",
+//		}),
+//	)
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://reports.internal/v1/daily", nil)
+//	resp, err := client.Do(req)
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/internal/config"
+	"github.com/dpup/prefab/resilience"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "httpclient.timeout",
+			Description: "Overall timeout for outgoing HTTP requests, including reading the body",
+			Type:        "duration",
+			Default:     "30s",
+		},
+	)
+}
+
+const (
+	dialTimeout         = 10 * time.Second
+	tlsHandshakeTimeout = 10 * time.Second
+	maxIdleConnsPerHost = 16
+)
+
+// TraceHeaders are copied from the incoming request to outgoing requests, so
+// that traces span services. Requests via the GRPC gateway only carry headers
+// allowed with prefab.WithIncomingHeaders.
+var TraceHeaders = []string{"traceparent", "tracestate", "x-cloud-trace-context"}
+
+// Option customizes a client created with New.
+type Option func(*options)
+
+type options struct {
+	timeout   time.Duration
+	transport http.RoundTripper
+	policy    *resilience.Policy
+	tokens    oauth2.TokenSource
+	propagate bool
+	userAgent string
+}
+
+// WithTimeout sets the overall timeout for requests, including reading the
+// response body. Zero disables it, leaving only the request's context.
+//
+// Config key: `httpclient.timeout`.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithTransport sets the transport requests are sent with. By default a
+// transport with connection and TLS handshake timeouts is used.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(o *options) {
+		o.transport = rt
+	}
+}
+
+// WithRetry retries requests which fail, or receive a 5xx response, with
+// backoff. Requests with a body are only retried if the body can be replayed,
+// which is the case for requests created with a bytes or strings reader.
+// Requests aren't retried by default, since not every endpoint is idempotent.
+func WithRetry(p resilience.RetryPolicy) Option {
+	return func(o *options) {
+		if o.policy == nil {
+			o.policy = &resilience.Policy{}
+		}
+		o.policy.Retry = &p
+	}
+}
+
+// WithResilience applies a circuit breaker, retries, and bulkhead to requests.
+// It replaces any policy set by WithRetry.
+func WithResilience(p resilience.Policy) Option {
+	return func(o *options) {
+		o.policy = &p
+	}
+}
+
+// WithBearerToken sets a static token for the Authorization header.
+func WithBearerToken(token string) Option {
+	return WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token, TokenType: "Bearer"}))
+}
+
+// WithTokenSource sets the Authorization header from tokens returned by ts.
+// The source should cache tokens, see oauth2.ReuseTokenSource.
+func WithTokenSource(ts oauth2.TokenSource) Option {
+	return func(o *options) {
+		o.tokens = ts
+	}
+}
+
+// WithClientCredentials authenticates requests with tokens obtained using the
+// OAuth client credentials grant, for example from the oauth plugin's
+// `/oauth/token` endpoint. Tokens are cached until they expire.
+func WithClientCredentials(cfg *clientcredentials.Config) Option {
+	return WithTokenSource(cfg.TokenSource(context.Background()))
+}
+
+// WithoutPropagation stops the request ID and trace headers of the incoming
+// request from being forwarded, for example when calling a third-party API.
+func WithoutPropagation() Option {
+	return func(o *options) {
+		o.propagate = false
+	}
+}
+
+// WithUserAgent sets the User-Agent header on requests which don't set one.
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.userAgent = ua
+	}
+}
+
+// New returns an HTTP client configured with the options. Requests should be
+// made with the incoming request's context, using http.NewRequestWithContext,
+// so that its request ID and trace headers are forwarded and its cancellation
+// applies.
+func New(opts ...Option) *http.Client {
+	config.EnsureDefaultsLoaded(prefab.Config)
+
+	o := &options{
+		timeout:   prefab.ConfigDuration("httpclient.timeout"),
+		propagate: true,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	rt := o.transport
+	if rt == nil {
+		rt = defaultTransport()
+	}
+	// Metrics are recorded for each attempt, so retries are counted.
+	rt = &metricsTransport{rt: rt}
+	if o.policy != nil {
+		rt = resilience.Transport(*o.policy, rt)
+	}
+	if o.tokens != nil {
+		rt = &oauth2.Transport{Source: o.tokens, Base: rt}
+	}
+	rt = &headerTransport{rt: rt, propagate: o.propagate, userAgent: o.userAgent}
+
+	return &http.Client{
+		Timeout:   o.timeout,
+		Transport: rt,
+	}
+}
+
+func defaultTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	t.TLSHandshakeTimeout = tlsHandshakeTimeout
+	t.MaxIdleConnsPerHost = maxIdleConnsPerHost
+	t.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return t
+}
+
+// headerTransport adds the request ID, trace headers, and user agent to
+// requests, unless they have been set explicitly.
+type headerTransport struct {
+	rt        http.RoundTripper
+	propagate bool
+	userAgent string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	req = req.Clone(ctx)
+
+	if req.Header.Get(serverutil.RequestIDHeader) == "" {
+		id := ""
+		if t.propagate {
+			id = serverutil.RequestID(ctx)
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		req.Header.Set(serverutil.RequestIDHeader, id)
+	}
+	if t.propagate {
+		for _, h := range TraceHeaders {
+			if req.Header.Get(h) != "" {
+				continue
+			}
+			if v := incomingHeader(ctx, h); v != "" {
+				req.Header.Set(h, v)
+			}
+		}
+	}
+	if t.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// incomingHeader returns a header from a gateway request, or metadata from a
+// GRPC request.
+func incomingHeader(ctx context.Context, h string) string {
+	if v := serverutil.HTTPHeader(ctx, h); v != "" {
+		return v
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(h); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
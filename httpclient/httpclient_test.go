@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/resilience"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2/clientcredentials"
+	"google.golang.org/grpc/metadata"
+)
+
+// echoServer responds with the headers of each request it receives.
+func echoServer(t *testing.T) *httptest.Server {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(r.Header)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func get(t *testing.T, c *http.Client, req *http.Request) http.Header {
+	t.Helper()
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var h http.Header
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&h))
+	return h
+}
+
+func TestNew_Defaults(t *testing.T) {
+	c := New()
+	assert.Equal(t, 30*time.Second, c.Timeout)
+
+	c = New(WithTimeout(time.Second))
+	assert.Equal(t, time.Second, c.Timeout)
+}
+
+func TestPropagation(t *testing.T) {
+	s := echoServer(t)
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+		serverutil.RequestIDHeader, "req-1",
+		"traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	h := get(t, New(WithUserAgent("reports/1.0")), req)
+	assert.Equal(t, "req-1", h.Get("X-Request-Id"))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", h.Get("Traceparent"))
+	assert.Equal(t, "reports/1.0", h.Get("User-Agent"))
+	assert.Empty(t, req.Header, "the caller's request isn't modified")
+
+	// Explicit headers win.
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	req.Header.Set("X-Request-Id", "explicit")
+	h = get(t, New(), req)
+	assert.Equal(t, "explicit", h.Get("X-Request-Id"))
+
+	// Without propagation a new request ID is generated.
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	h = get(t, New(WithoutPropagation()), req)
+	assert.NotEmpty(t, h.Get("X-Request-Id"))
+	assert.NotEqual(t, "req-1", h.Get("X-Request-Id"))
+	assert.Empty(t, h.Get("Traceparent"))
+}
+
+func TestBearerToken(t *testing.T) {
+	s := echoServer(t)
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL, nil)
+	h := get(t, New(WithBearerToken("secret")), req)
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"))
+}
+
+func TestClientCredentials(t *testing.T) {
+	var tokens atomic.Int32
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		id, secret, _ := r.BasicAuth()
+		assert.Equal(t, "reports", id)
+		assert.Equal(t, "s3cret", secret)
+		tokens.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"tok","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer auth.Close()
+	s := echoServer(t)
+
+	c := New(WithClientCredentials(&clientcredentials.Config{
+		ClientID:     "reports",
+		ClientSecret: "s3cret",
+		TokenURL:     auth.URL + "/oauth/token",
+	}))
+	for range 2 {
+		req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL, nil)
+		h := get(t, c, req)
+		assert.Equal(t, "Bearer tok", h.Get("Authorization"))
+	}
+	assert.Equal(t, int32(1), tokens.Load(), "tokens are cached")
+}
+
+func TestRetryAndStats(t *testing.T) {
+	var calls atomic.Int32
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body := make([]byte, 4)
+		n, _ := r.Body.Read(body)
+		_, _ = w.Write(body[:n])
+	}))
+	defer s.Close()
+
+	c := New(WithRetry(resilience.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodPost, s.URL, strings.NewReader("ping"))
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load(), "the body is replayed on retry")
+
+	u, _ := url.Parse(s.URL)
+	var stats *HostStats
+	for _, hs := range Stats() {
+		if hs.Host == u.Host {
+			stats = &hs
+		}
+	}
+	require.NotNil(t, stats)
+	assert.Equal(t, int64(2), stats.Requests)
+	assert.Equal(t, map[string]int64{"2xx": 1, "5xx": 1}, stats.Responses)
+	assert.Zero(t, stats.Errors)
+}
+
+func TestStats_Errors(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	s.Close()
+
+	req, _ := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL, nil)
+	_, err := New().Do(req)
+	require.Error(t, err)
+
+	u, _ := url.Parse(s.URL)
+	for _, hs := range Stats() {
+		if hs.Host == u.Host {
+			assert.Equal(t, int64(1), hs.Errors)
+			return
+		}
+	}
+	t.Fatal("expected stats for host")
+}
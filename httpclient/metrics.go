@@ -0,0 +1,96 @@
+package httpclient
+
+import (
+	"expvar"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+func init() {
+	expvar.Publish("httpclient", expvar.Func(func() any { return Stats() }))
+}
+
+// HostStats reports requests made to a host by clients created with New.
+// Retries count as separate requests.
+type HostStats struct {
+	// Host and port, as in the request URL.
+	Host string `json:"host"`
+
+	// Requests sent, including those which failed.
+	Requests int64 `json:"requests"`
+
+	// Requests which failed without a response, e.g. connection errors and
+	// timeouts.
+	Errors int64 `json:"errors"`
+
+	// Responses by status class, e.g. "2xx".
+	Responses map[string]int64 `json:"responses"`
+
+	// Mean time until the response headers were received, in milliseconds.
+	AvgLatencyMs float64 `json:"avgLatencyMs"`
+}
+
+type hostStats struct {
+	requests  atomic.Int64
+	errors    atomic.Int64
+	responses [6]atomic.Int64 // Indexed by the status code's first digit.
+	latency   atomic.Int64    // Total, in nanoseconds.
+}
+
+// hosts maps a host to its *hostStats.
+var hosts sync.Map
+
+// Stats returns stats for each host which has been called, sorted by host.
+// They are also published to expvar as "httpclient", which the debug plugin
+// serves at /debug/vars.
+func Stats() []HostStats {
+	var out []HostStats
+	hosts.Range(func(k, v any) bool {
+		s := v.(*hostStats)
+		hs := HostStats{
+			Host:      k.(string),
+			Requests:  s.requests.Load(),
+			Errors:    s.errors.Load(),
+			Responses: map[string]int64{},
+		}
+		for i := 1; i < len(s.responses); i++ {
+			if n := s.responses[i].Load(); n > 0 {
+				hs.Responses[strconv.Itoa(i)+"xx"] = n
+			}
+		}
+		if hs.Requests > 0 {
+			hs.AvgLatencyMs = float64(s.latency.Load()) / float64(hs.Requests) / float64(time.Millisecond)
+		}
+		out = append(out, hs)
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Host < out[j].Host })
+	return out
+}
+
+// metricsTransport records stats for each request, by host.
+type metricsTransport struct {
+	rt http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	v, _ := hosts.LoadOrStore(req.URL.Host, &hostStats{})
+	s := v.(*hostStats)
+
+	start := time.Now()
+	resp, err := t.rt.RoundTrip(req)
+	s.requests.Add(1)
+	s.latency.Add(int64(time.Since(start)))
+	if err != nil {
+		s.errors.Add(1)
+		return resp, err
+	}
+	if class := resp.StatusCode / 100; class > 0 && class < len(s.responses) {
+		s.responses[class].Add(1)
+	}
+	return resp, nil
+}
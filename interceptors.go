@@ -0,0 +1,288 @@
+package prefab
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"path"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Phase controls where an interceptor runs in the GRPC chain. Interceptors run
+// in phase order, so that, for example, authorization always sees the identity
+// established during authentication regardless of the order in which plugins
+// were registered.
+type Phase int
+
+const (
+	// phaseBuiltin is used for the server's own interceptors, which always run
+	// first.
+	phaseBuiltin Phase = iota
+
+	// PhaseObservability is for interceptors that should see every request,
+	// including those rejected by later phases, e.g. metrics and localization.
+	PhaseObservability
+
+	// PhaseAuth is for interceptors that establish or verify who the caller is.
+	PhaseAuth
+
+	// PhaseAuthz is for interceptors that decide whether the caller may make the
+	// request, e.g. access rules, consent, and quotas.
+	PhaseAuthz
+
+	// PhaseValidation is for interceptors that validate the request.
+	PhaseValidation
+
+	// PhaseApp is for application interceptors, and is the default.
+	PhaseApp
+)
+
+// String returns the name of the phase, e.g. "auth".
+func (p Phase) String() string {
+	switch p {
+	case phaseBuiltin:
+		return "builtin"
+	case PhaseObservability:
+		return "observability"
+	case PhaseAuth:
+		return "auth"
+	case PhaseAuthz:
+		return "authz"
+	case PhaseValidation:
+		return "validation"
+	case PhaseApp:
+		return "app"
+	default:
+		return "phase(" + strconv.Itoa(int(p)) + ")"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so that phases are listed by
+// name.
+func (p Phase) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// InterceptorOption customizes how an interceptor is placed in the chain.
+type InterceptorOption func(*interceptor)
+
+// InterceptorPhase sets the phase the interceptor runs in. Defaults to
+// PhaseApp.
+func InterceptorPhase(phase Phase) InterceptorOption {
+	return func(i *interceptor) {
+		i.phase = phase
+	}
+}
+
+// InterceptorPriority orders interceptors within a phase, lower priorities run
+// first. Interceptors with the same phase and priority run in the order they
+// were added. Defaults to 0.
+func InterceptorPriority(priority int) InterceptorOption {
+	return func(i *interceptor) {
+		i.priority = priority
+	}
+}
+
+// InterceptorName sets the name the interceptor is listed with. Defaults to the
+// name of the function.
+func InterceptorName(name string) InterceptorOption {
+	return func(i *interceptor) {
+		i.name = name
+	}
+}
+
+// InterceptorMethods limits the interceptor to methods whose full name, e.g.
+// "/prefab.quota.QuotaService/GetUsage", matches one of the patterns. Patterns
+// use the syntax of path.Match, so "/prefab.quota.QuotaService/*" matches every
+// method of a service.
+//
+// Methods are matched once, when the server is built, and the interceptor is
+// skipped for other methods.
+func InterceptorMethods(patterns ...string) InterceptorOption {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic("prefab: invalid interceptor method pattern " + strconv.Quote(pattern))
+		}
+	}
+	return func(i *interceptor) {
+		i.scope().patterns = append(i.scope().patterns, patterns...)
+	}
+}
+
+// InterceptorMethodOptions limits the interceptor to methods which set one of
+// the proto options, e.g. `quota.E_ConsumeQuota`. Combined with
+// InterceptorMethods, methods matching either are selected.
+func InterceptorMethodOptions(exts ...protoreflect.ExtensionType) InterceptorOption {
+	return func(i *interceptor) {
+		i.scope().options = append(i.scope().options, exts...)
+	}
+}
+
+// Interceptor describes a GRPC interceptor in the server's chain.
+type Interceptor struct {
+	// Name of the interceptor, see InterceptorName.
+	Name string `json:"name"`
+
+	// Phase the interceptor runs in.
+	Phase Phase `json:"phase"`
+
+	// Priority of the interceptor within its phase.
+	Priority int `json:"priority"`
+
+	// Methods the interceptor is limited to, nil if it runs for all methods.
+	Methods []string `json:"methods"`
+}
+
+type interceptor struct {
+	fn       grpc.UnaryServerInterceptor
+	name     string
+	phase    Phase
+	priority int
+	methods  *methodScope
+}
+
+// scope returns the interceptor's method scope, creating it if necessary.
+func (i *interceptor) scope() *methodScope {
+	if i.methods == nil {
+		i.methods = &methodScope{selected: map[string]bool{}}
+	}
+	return i.methods
+}
+
+// methodScope selects the methods an interceptor runs for.
+type methodScope struct {
+	patterns []string
+	options  []protoreflect.ExtensionType
+
+	// Full names of the selected methods. Populated by resolve before the server
+	// starts, and only read afterwards.
+	selected map[string]bool
+}
+
+// resolve selects matching methods from the services registered with a
+// server.
+func (m *methodScope) resolve(services map[string]grpc.ServiceInfo) {
+	for svc, info := range services {
+		for _, method := range info.Methods {
+			fullMethod := "/" + svc + "/" + method.Name
+			if m.matches(protoreflect.FullName(svc), fullMethod, method.Name) {
+				m.selected[fullMethod] = true
+			}
+		}
+	}
+}
+
+func (m *methodScope) matches(svc protoreflect.FullName, fullMethod, name string) bool {
+	for _, pattern := range m.patterns {
+		if ok, _ := path.Match("/"+strings.TrimPrefix(pattern, "/"), fullMethod); ok {
+			return true
+		}
+	}
+	if len(m.options) == 0 {
+		return false
+	}
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(svc.Append(protoreflect.Name(name)))
+	if err != nil {
+		return false
+	}
+	opts, _ := desc.Options().(*descriptorpb.MethodOptions)
+	for _, ext := range m.options {
+		if proto.HasExtension(opts, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// wrap returns an interceptor which only calls fn for selected methods.
+func (m *methodScope) wrap(fn grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !m.selected[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		return fn(ctx, req, info, handler)
+	}
+}
+
+// interceptorChain returns the server's interceptors in the order they run:
+// built-in interceptors first, then by phase and priority.
+func (b *builder) interceptorChain() []interceptor {
+	chain := []interceptor{
+		{fn: configInterceptor(b.configInjectors), name: "prefab.config"},
+		{fn: logging.Interceptor(), name: "prefab.logging"},
+		{fn: deprecationInterceptor(b.enforceSunset, time.Now), name: "prefab.deprecation"},
+		{fn: csrfInterceptor(b.csrfSigningKey), name: "prefab.csrf"},
+		{fn: fieldMaskInterceptor, name: "prefab.fieldmask"},
+	}
+	sorted := slices.Clone(b.interceptors)
+	slices.SortStableFunc(sorted, func(x, y interceptor) int {
+		if x.phase != y.phase {
+			return cmp.Compare(x.phase, y.phase)
+		}
+		return cmp.Compare(x.priority, y.priority)
+	})
+	for i, ic := range sorted {
+		if ic.methods != nil {
+			sorted[i].fn = ic.methods.wrap(ic.fn)
+		}
+	}
+	return append(chain, sorted...)
+}
+
+// resolveInterceptors selects the methods for scoped interceptors from the
+// services registered with the server, and returns a listing of the chain.
+func (b *builder) resolveInterceptors(s *Server) []Interceptor {
+	for _, ic := range b.interceptors {
+		if ic.methods == nil {
+			continue
+		}
+		ic.methods.resolve(s.grpcServer.GetServiceInfo())
+		if s.adminGRPCServer != nil {
+			ic.methods.resolve(s.adminGRPCServer.GetServiceInfo())
+		}
+	}
+	chain := b.interceptorChain()
+	list := make([]Interceptor, 0, len(chain))
+	for _, ic := range chain {
+		info := Interceptor{Name: ic.name, Phase: ic.phase, Priority: ic.priority}
+		if ic.methods != nil {
+			info.Methods = append([]string{}, slices.Sorted(maps.Keys(ic.methods.selected))...)
+		}
+		list = append(list, info)
+	}
+	return list
+}
+
+// Interceptors returns the server's GRPC interceptors in the order they run.
+// When an admin listener is configured, they are also listed at
+// `GET /debug/interceptors`.
+func (s *Server) Interceptors() []Interceptor {
+	return slices.Clone(s.interceptors)
+}
+
+// funcName returns the name of a function, without its package path, e.g.
+// "authz.(*AuthzPlugin).Interceptor".
+func funcName(fn any) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	// Method values are wrapped, e.g. "authz.(*AuthzPlugin).Interceptor-fm".
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,138 @@
+package prefab
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+)
+
+func recordingInterceptor(calls *[]string, name string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		*calls = append(*calls, name)
+		return handler(ctx, req)
+	}
+}
+
+func TestInterceptorChain_Order(t *testing.T) {
+	var calls []string
+	b := &builder{}
+	for _, opt := range []ServerOption{
+		WithGRPCInterceptor(recordingInterceptor(&calls, "app"), InterceptorName("app")),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "authz"), InterceptorName("authz"), InterceptorPhase(PhaseAuthz)),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "late"), InterceptorName("late"), InterceptorPhase(PhaseApp), InterceptorPriority(10)),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "auth"), InterceptorName("auth"), InterceptorPhase(PhaseAuth)),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "early"), InterceptorName("early"), InterceptorPhase(PhaseApp), InterceptorPriority(-10)),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "app2"), InterceptorName("app2")),
+	} {
+		opt(b)
+	}
+
+	chain := b.interceptorChain()
+	var names []string
+	for _, ic := range chain {
+		names = append(names, ic.name)
+	}
+	assert.Equal(t, []string{
+		"prefab.config", "prefab.logging", "prefab.deprecation", "prefab.csrf", "prefab.fieldmask",
+		"auth", "authz", "early", "app", "app2", "late",
+	}, names)
+
+	// The chain runs in the listed order.
+	for _, ic := range chain {
+		if ic.phase == phaseBuiltin {
+			continue
+		}
+		_, err := ic.fn(t.Context(), nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) { return nil, nil })
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"auth", "authz", "early", "app", "app2", "late"}, calls)
+}
+
+func TestInterceptors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	noop := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(ctx, req)
+	}
+	s := New(
+		WithAdminListener(ln),
+		WithGRPCInterceptor(noop, InterceptorName("audit")),
+		WithGRPCInterceptor(recordingInterceptor(nil, ""), InterceptorPhase(PhaseAuth), InterceptorPriority(5)),
+	)
+
+	want := []Interceptor{
+		{Name: "prefab.config", Phase: phaseBuiltin},
+		{Name: "prefab.logging", Phase: phaseBuiltin},
+		{Name: "prefab.deprecation", Phase: phaseBuiltin},
+		{Name: "prefab.csrf", Phase: phaseBuiltin},
+		{Name: "prefab.fieldmask", Phase: phaseBuiltin},
+		{Name: "prefab.recordingInterceptor.func1", Phase: PhaseAuth, Priority: 5},
+		{Name: "audit", Phase: PhaseApp},
+	}
+	assert.Equal(t, want, s.Interceptors())
+
+	rec := serveRoute(s.adminMux, http.MethodGet, "/debug/interceptors")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	require.Len(t, got, len(want))
+	i := slices.IndexFunc(got, func(ic map[string]any) bool { return ic["name"] == "audit" })
+	require.NotEqual(t, -1, i)
+	assert.Equal(t, map[string]any{"name": "audit", "phase": "app", "priority": float64(0), "methods": nil}, got[i])
+
+	// The listing is only exposed on the admin listener.
+	assert.Equal(t, http.StatusNotFound, serveRoute(s.httpMux, http.MethodGet, "/debug/interceptors").Code)
+}
+
+func TestInterceptorMethods(t *testing.T) {
+	var calls []string
+	s := New(
+		WithGRPCInterceptor(recordingInterceptor(&calls, "pattern"), InterceptorName("pattern"),
+			InterceptorMethods("prefab.MetaService/*")),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "option"), InterceptorName("option"),
+			InterceptorMethodOptions(annotations.E_Http)),
+		WithGRPCInterceptor(recordingInterceptor(&calls, "none"), InterceptorName("none"),
+			InterceptorMethods("/unknown.Service/*")),
+	)
+
+	listed := map[string][]string{}
+	for _, ic := range s.Interceptors() {
+		listed[ic.Name] = ic.Methods
+	}
+	assert.Equal(t, []string{"/prefab.MetaService/ClientConfig", "/prefab.MetaService/ErrorCatalog"}, listed["pattern"])
+	assert.Equal(t, []string{"/prefab.MetaService/ClientConfig", "/prefab.MetaService/ErrorCatalog"}, listed["option"])
+	assert.Equal(t, []string{}, listed["none"])
+	assert.Nil(t, listed["prefab.logging"])
+
+	b := &builder{}
+	WithGRPCInterceptor(recordingInterceptor(&calls, "scoped"), InterceptorName("scoped"),
+		InterceptorMethods("/test.Service/Get*"))(b)
+	b.interceptors[0].methods.resolve(map[string]grpc.ServiceInfo{
+		"test.Service": {Methods: []grpc.MethodInfo{{Name: "GetThing"}, {Name: "PutThing"}}},
+	})
+	chain := b.interceptorChain()
+	i := slices.IndexFunc(chain, func(ic interceptor) bool { return ic.name == "scoped" })
+	require.NotEqual(t, -1, i)
+	fn := chain[i].fn
+	noop := func(context.Context, any) (any, error) { return nil, nil }
+	calls = nil
+	for _, method := range []string{"/test.Service/GetThing", "/test.Service/PutThing"} {
+		_, err := fn(t.Context(), nil, &grpc.UnaryServerInfo{FullMethod: method}, noop)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"scoped"}, calls)
+}
+
+func TestInterceptorMethods_InvalidPattern(t *testing.T) {
+	assert.Panics(t, func() { InterceptorMethods("/test.Service/[") })
+}
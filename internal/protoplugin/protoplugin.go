@@ -0,0 +1,178 @@
+// Package protoplugin contains helpers shared by prefab's protoc plugins.
+//
+// Prefab's method options are read from the encoded options, rather than with
+// the generated extensions such as prefab.E_SsePath, so that the plugins don't
+// depend on prefab's generated code and can be built before it.
+package protoplugin
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Field numbers of prefab's options, see proto/server.proto.
+const (
+	JSONUseProtoNamesField protowire.Number = 50005
+	SSEPathField           protowire.Number = 50007
+	ErrorSpecField         protowire.Number = 50101
+)
+
+// ErrorSpec is the value of an enum value's `(prefab.error)` option.
+type ErrorSpec struct {
+	// GRPC status code, as a google.rpc.Code number.
+	Code       int32
+	HTTPStatus int32
+	Message    string
+}
+
+// StringOption returns the value of a string option, or "" if it isn't set.
+func StringOption(opts proto.Message, num protowire.Number) string {
+	var value string
+	rangeOption(opts, num, protowire.BytesType, func(b []byte) int {
+		v, n := protowire.ConsumeBytes(b)
+		value = string(v)
+		return n
+	})
+	return value
+}
+
+// BoolOption returns the value of a bool option and whether it was set.
+func BoolOption(opts proto.Message, num protowire.Number) (value, ok bool) {
+	rangeOption(opts, num, protowire.VarintType, func(b []byte) int {
+		v, n := protowire.ConsumeVarint(b)
+		value, ok = protowire.DecodeBool(v), true
+		return n
+	})
+	return value, ok
+}
+
+// ErrorSpecOption returns the value of an enum value's `(prefab.error)` option
+// and whether it was set.
+func ErrorSpecOption(v *protogen.EnumValue) (spec ErrorSpec, ok bool) {
+	rangeOption(v.Desc.Options(), ErrorSpecField, protowire.BytesType, func(b []byte) int {
+		msg, n := protowire.ConsumeBytes(b)
+		if n < 0 {
+			return n
+		}
+		ok = true
+		for len(msg) > 0 {
+			num, typ, l := protowire.ConsumeTag(msg)
+			if l < 0 {
+				return l
+			}
+			msg = msg[l:]
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				var c uint64
+				c, l = protowire.ConsumeVarint(msg)
+				spec.Code = int32(c) //nolint:gosec // Enum values are int32.
+			case num == 2 && typ == protowire.VarintType:
+				var s uint64
+				s, l = protowire.ConsumeVarint(msg)
+				spec.HTTPStatus = int32(s) //nolint:gosec // Encoded as an int32.
+			case num == 3 && typ == protowire.BytesType:
+				var m []byte
+				m, l = protowire.ConsumeBytes(msg)
+				spec.Message = string(m)
+			default:
+				l = protowire.ConsumeFieldValue(num, typ, msg)
+			}
+			if l < 0 {
+				return l
+			}
+			msg = msg[l:]
+		}
+		return n
+	})
+	return spec, ok
+}
+
+// rangeOption calls fn with the encoded value of each occurrence of the option.
+// fn returns the length of the value, or a negative number if it's malformed.
+func rangeOption(opts proto.Message, num protowire.Number, typ protowire.Type, fn func([]byte) int) {
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return
+	}
+	for len(b) > 0 {
+		n, t, l := protowire.ConsumeTag(b)
+		if l < 0 {
+			return
+		}
+		b = b[l:]
+		if n == num && t == typ {
+			l = fn(b)
+		} else {
+			l = protowire.ConsumeFieldValue(n, t, b)
+		}
+		if l < 0 {
+			return
+		}
+		b = b[l:]
+	}
+}
+
+// SSEPath returns the value of the method's `(prefab.sse_path)` option, or ""
+// if it isn't annotated. Returns an error if the method isn't server-streaming
+// or the path is invalid.
+func SSEPath(m *protogen.Method) (string, error) {
+	path := StringOption(m.Desc.Options(), SSEPathField)
+	if path == "" {
+		return "", nil
+	}
+	if !m.Desc.IsStreamingServer() || m.Desc.IsStreamingClient() {
+		return "", fmt.Errorf("%s: sse_path requires a server-streaming method", m.Desc.FullName())
+	}
+	if err := ValidatePath(path, m.Input.Desc); err != nil {
+		return "", fmt.Errorf("%s: %v", m.Desc.FullName(), err)
+	}
+	return path, nil
+}
+
+// ValidatePath checks that each parameter in an SSE path, e.g. {id} or
+// {note.id}, names a singular, non-message field of the request.
+func ValidatePath(path string, input protoreflect.MessageDescriptor) error {
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("sse_path %q must start with /", path)
+	}
+	for _, param := range PathParams(path) {
+		msg := input
+		names := strings.Split(param, ".")
+		for i, name := range names {
+			fd := msg.Fields().ByName(protoreflect.Name(name))
+			if fd == nil {
+				return fmt.Errorf("sse_path parameter {%s} isn't a field of %s", param, input.FullName())
+			}
+			if fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("sse_path parameter {%s} can't be repeated", param)
+			}
+			last := i == len(names)-1
+			if last && fd.Message() != nil {
+				return fmt.Errorf("sse_path parameter {%s} can't be a message", param)
+			}
+			if !last {
+				if fd.Message() == nil {
+					return fmt.Errorf("sse_path parameter {%s} isn't a field of %s", param, input.FullName())
+				}
+				msg = fd.Message()
+			}
+		}
+	}
+	return nil
+}
+
+// PathParams returns the names of the parameters in an SSE path, in order.
+func PathParams(path string) []string {
+	var params []string
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			params = append(params, part[1:len(part)-1])
+		}
+	}
+	return params
+}
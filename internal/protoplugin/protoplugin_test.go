@@ -0,0 +1,65 @@
+package protoplugin
+
+import (
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestOptions(t *testing.T) {
+	opts := &descriptorpb.MethodOptions{}
+	assert.Empty(t, StringOption(opts, SSEPathField))
+	_, ok := BoolOption(opts, JSONUseProtoNamesField)
+	assert.False(t, ok)
+
+	proto.SetExtension(opts, prefab.E_CsrfMode, "off")
+	proto.SetExtension(opts, prefab.E_SsePath, "/notes/{id}")
+	proto.SetExtension(opts, prefab.E_JsonUseProtoNames, false)
+	assert.Equal(t, "/notes/{id}", StringOption(opts, SSEPathField))
+	v, ok := BoolOption(opts, JSONUseProtoNamesField)
+	assert.True(t, ok)
+	assert.False(t, v)
+}
+
+func TestErrorSpecOption(t *testing.T) {
+	opts := &descriptorpb.EnumValueOptions{}
+	proto.SetExtension(opts, prefab.E_Error, &prefab.ErrorSpec{
+		Code:       code.Code_NOT_FOUND,
+		HttpStatus: 410,
+		Message:    "Note %s was deleted",
+	})
+	spec, ok := ErrorSpecOption(enumValue(t, opts))
+	assert.True(t, ok)
+	assert.Equal(t, ErrorSpec{Code: 5, HTTPStatus: 410, Message: "Note %s was deleted"}, spec)
+
+	_, ok = ErrorSpecOption(enumValue(t, &descriptorpb.EnumValueOptions{}))
+	assert.False(t, ok)
+}
+
+// enumValue returns an enum value with the given options.
+func enumValue(t *testing.T, opts *descriptorpb.EnumValueOptions) *protogen.EnumValue {
+	t.Helper()
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("e.proto"),
+		Package: proto.String("e"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name:  proto.String("E"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("E_X"), Number: proto.Int32(0), Options: opts}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	return &protogen.EnumValue{Desc: fd.Enums().Get(0).Values().Get(0)}
+}
+
+func TestPathParams(t *testing.T) {
+	assert.Equal(t, []string{"id", "note.id"}, PathParams("/notes/{id}/{note.id}/updates"))
+	assert.Empty(t, PathParams("/notes"))
+}
@@ -0,0 +1,231 @@
+package prefab
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// JSONEndpoint returns a JSONHandler which decodes the HTTP request into Req,
+// validates it, and calls fn. Use with WithJSONHandler or WithJSONRoute.
+//
+// Requests are validated with `ValidateAll() error` or `Validate() error`, if
+// implemented, as generated by protoc-gen-validate. Validation errors without a
+// code are returned as InvalidArgument.
+//
+// Fields are populated from the query string, then the body, then the route's
+// wildcards, mirroring the GRPC Gateway. Bodies may be JSON or form encoded.
+// Proto messages are decoded with protojson, other types with encoding/json,
+// with query parameters and wildcards matched against `json` tags.
+//
+// Errors are written in the same format as the GRPC Gateway, with the HTTP
+// status derived from the error's code.
+func JSONEndpoint[Req, Resp any](fn func(context.Context, Req) (Resp, error)) JSONHandler {
+	return func(r *http.Request) (any, error) {
+		var req Req
+		target := any(&req)
+		if t := reflect.TypeOf(req); t != nil && t.Kind() == reflect.Pointer {
+			// Allocate pointer types, such as proto messages, so they can be decoded
+			// into directly.
+			req = reflect.New(t.Elem()).Interface().(Req)
+			target = req
+		}
+		if err := decodeRequest(r, target); err != nil {
+			return nil, err
+		}
+		if err := validateRequest(target); err != nil {
+			if errors.Code(err) == codes.Unknown {
+				return nil, errors.WithCode(err, codes.InvalidArgument)
+			}
+			return nil, err
+		}
+		return fn(r.Context(), req)
+	}
+}
+
+func validateRequest(req any) error {
+	switch v := req.(type) {
+	case interface{ ValidateAll() error }:
+		return v.ValidateAll()
+	case interface{ Validate() error }:
+		return v.Validate()
+	}
+	return nil
+}
+
+// decodeRequest populates target from the request's query, body, and path.
+func decodeRequest(r *http.Request, target any) error {
+	if err := populateValues(target, r.URL.Query()); err != nil {
+		return invalidRequest(err, "invalid query parameters")
+	}
+
+	if r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return invalidRequest(err, "failed to read request body")
+		}
+		if len(body) > 0 {
+			mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if mediaType == "application/x-www-form-urlencoded" {
+				values, err := url.ParseQuery(string(body))
+				if err == nil {
+					err = populateValues(target, values)
+				}
+				if err != nil {
+					return invalidRequest(err, "invalid form body")
+				}
+			} else {
+				if msg, ok := target.(proto.Message); ok {
+					// Unmarshal resets the message, so merge to keep query parameters.
+					bodyMsg := msg.ProtoReflect().New().Interface()
+					err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, bodyMsg)
+					proto.Merge(msg, bodyMsg)
+				} else {
+					err = json.Unmarshal(body, target)
+				}
+				if err != nil {
+					return invalidRequest(err, "invalid JSON body")
+				}
+			}
+		}
+	}
+
+	if err := populateValues(target, pathValues(r)); err != nil {
+		return invalidRequest(err, "invalid path parameters")
+	}
+	return nil
+}
+
+func invalidRequest(err error, msg string) error {
+	return errors.WithCode(err, codes.InvalidArgument).WithUserPresentableMessage("%s: %v", msg, err)
+}
+
+// pathValues returns the values of the wildcards in the pattern that matched
+// the request.
+func pathValues(r *http.Request) url.Values {
+	values := url.Values{}
+	rest := r.Pattern
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			return values
+		}
+		end := strings.Index(rest[start:], "}")
+		if end < 0 {
+			return values
+		}
+		name := strings.TrimSuffix(rest[start+1:start+end], "...")
+		if name != "" && name != "$" {
+			values.Set(name, r.PathValue(name))
+		}
+		rest = rest[start+end+1:]
+	}
+}
+
+// populateValues sets fields of target from string values. Proto messages use
+// the GRPC Gateway's query parameter rules, structs are matched by `json` tag.
+func populateValues(target any, values url.Values) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if msg, ok := target.(proto.Message); ok {
+		return runtime.PopulateQueryParameters(msg, values, &utilities.DoubleArray{})
+	}
+	v := reflect.ValueOf(target)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setValue(v.Field(i), vals); err != nil {
+			return errors.WithFieldViolation(err, name, err.Error())
+		}
+	}
+	return nil
+}
+
+// setValue parses strings into a field of a basic type, or a slice of them.
+func setValue(field reflect.Value, vals []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), []string{s}); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setValue(ptr.Elem(), vals); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	s := vals[len(vals)-1]
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("invalid bool %q", s)
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid integer %q", s)
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid unsigned integer %q", s)
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return errors.Errorf("invalid number %q", s)
+		}
+		field.SetFloat(n)
+	default:
+		return errors.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
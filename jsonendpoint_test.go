@@ -0,0 +1,159 @@
+package prefab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type greetRequest struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Count  int      `json:"count"`
+	Tags   []string `json:"tags"`
+	Polite *bool    `json:"polite"`
+}
+
+func (r greetRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type greetResponse struct {
+	Greeting string `json:"greeting"`
+}
+
+func greet(_ context.Context, req greetRequest) (*greetResponse, error) {
+	if req.Name == "forbidden" {
+		return nil, errors.NewC("not allowed", codes.PermissionDenied)
+	}
+	greeting := strings.Repeat("hi ", req.Count) + req.Name + " " + req.ID + " " + strings.Join(req.Tags, ",")
+	if req.Polite != nil && *req.Polite {
+		greeting += " please"
+	}
+	return &greetResponse{Greeting: greeting}, nil
+}
+
+func serveEndpoint(t *testing.T, h http.Handler, method, target, contentType, body string) (int, map[string]any) {
+	t.Helper()
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	req := httptest.NewRequestWithContext(ctx, method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestJSONEndpoint(t *testing.T) {
+	s := New(WithJSONRoute("POST /greet/{id}", JSONEndpoint(greet)))
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantCode    int
+		want        map[string]any
+	}{
+		{
+			name:        "json body",
+			target:      "/greet/1",
+			contentType: "application/json",
+			body:        `{"name":"bob","count":1,"tags":["a","b"]}`,
+			wantCode:    http.StatusOK,
+			want:        map[string]any{"greeting": "hi bob 1 a,b"},
+		},
+		{
+			name:     "query parameters",
+			target:   "/greet/2?name=sue&count=2&tags=x&tags=y&polite=true",
+			wantCode: http.StatusOK,
+			want:     map[string]any{"greeting": "hi hi sue 2 x,y please"},
+		},
+		{
+			name:        "form body",
+			target:      "/greet/3",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=ann",
+			wantCode:    http.StatusOK,
+			want:        map[string]any{"greeting": "ann 3 "},
+		},
+		{
+			name:        "path takes priority over body",
+			target:      "/greet/4",
+			contentType: "application/json",
+			body:        `{"id":"99","name":"bob"}`,
+			wantCode:    http.StatusOK,
+			want:        map[string]any{"greeting": "bob 4 "},
+		},
+		{
+			name:     "validation error",
+			target:   "/greet/5",
+			wantCode: http.StatusBadRequest,
+			want:     map[string]any{"code": float64(3), "codeName": "INVALID_ARGUMENT", "message": "name is required"},
+		},
+		{
+			name:        "malformed json",
+			target:      "/greet/6",
+			contentType: "application/json",
+			body:        `{"name":`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:     "handler error",
+			target:   "/greet/7?name=forbidden",
+			wantCode: http.StatusForbidden,
+			want:     map[string]any{"code": float64(7), "codeName": "PERMISSION_DENIED", "message": "not allowed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := serveEndpoint(t, s.httpMux, http.MethodPost, tt.target, tt.contentType, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			for k, v := range tt.want {
+				assert.Equal(t, v, resp[k], k)
+			}
+		})
+	}
+}
+
+func TestJSONEndpoint_FieldViolation(t *testing.T) {
+	h := wrapJSONHandler(JSONEndpoint(greet), JSONMarshalOptions)
+
+	code, resp := serveEndpoint(t, h, http.MethodGet, "/?name=bob&count=many", "", "")
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, "INVALID_ARGUMENT", resp["codeName"])
+	assert.Contains(t, resp["message"], "invalid query parameters")
+
+	details, _ := resp["details"].([]any)
+	require.Len(t, details, 1)
+	assert.Contains(t, details[0], "fieldViolations")
+}
+
+func TestJSONEndpoint_Proto(t *testing.T) {
+	echo := func(_ context.Context, req *CustomErrorResponse) (*CustomErrorResponse, error) {
+		return req, nil
+	}
+	s := New(WithJSONRoute("PUT /errors/{code_name}", JSONEndpoint(echo)))
+
+	code, resp := serveEndpoint(t, s.httpMux, http.MethodPut, "/errors/NOT_FOUND?code=5", "application/json", `{"message":"gone","unknown":1}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, float64(5), resp["code"])
+	assert.Equal(t, "NOT_FOUND", resp["codeName"])
+	assert.Equal(t, "gone", resp["message"])
+}
@@ -6,7 +6,8 @@ import (
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/logging"
-	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -17,34 +18,33 @@ import (
 // JSON marshaler as the gRPC Gateway.
 type JSONHandler func(req *http.Request) (any, error)
 
-func wrapJSONHandler(fn JSONHandler) http.Handler {
+func wrapJSONHandler(fn JSONHandler, opts protojson.MarshalOptions) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		err := execJSONHandler(fn, w, r)
+		err := execJSONHandler(fn, opts, w, r)
 		if err != nil {
 			// TODO: Log warning and error based on status code.
 			logging.Errorw(r.Context(), "JSON handler error", "error", err,
 				"req.method", r.Method, "req.url", r.URL.String())
-
-			// Convert the error code to int32
-			//nolint:gosec // No overflow risk as errors.Code() returns codes.Code which is already int32
-			c := int32(errors.Code(err))
-			b, ferr := JSONMarshalOptions.Marshal(&CustomErrorResponse{
-				Code:     c,
-				CodeName: code.Code_name[c],
-				Message:  err.Error(),
-			})
-			if ferr != nil {
-				http.Error(w, "error encoding response", http.StatusInternalServerError)
-				return
-			}
-			w.WriteHeader(errors.HTTPStatusCode(err))
-			w.Header().Set("Content-Type", "application/json")
-			w.Write(b)
+			WriteJSONError(w, r, err)
 		}
 	})
 }
 
-func execJSONHandler(fn JSONHandler, w http.ResponseWriter, r *http.Request) error {
+// WriteJSONError writes an error response in the same format as JSON handlers
+// and the GRPC Gateway. Useful for middleware which rejects requests.
+func WriteJSONError(w http.ResponseWriter, r *http.Request, err error) {
+	st := status.Convert(err).Proto()
+	b, ferr := JSONMarshalOptions.Marshal(newCustomErrorResponse(st))
+	if ferr != nil {
+		http.Error(w, "error encoding response", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(errors.HTTPStatusCode(err))
+	w.Write(b)
+}
+
+func execJSONHandler(fn JSONHandler, opts protojson.MarshalOptions, w http.ResponseWriter, r *http.Request) error {
 	// Execute the handler.
 	resp, err := fn(r)
 	if err != nil {
@@ -54,7 +54,7 @@ func execJSONHandler(fn JSONHandler, w http.ResponseWriter, r *http.Request) err
 	// If the response is a proto.Message, marshal it using the JSON marshaler.
 	var b []byte
 	if pb, ok := resp.(proto.Message); ok {
-		b, err = JSONMarshalOptions.Marshal(pb)
+		b, err = opts.Marshal(pb)
 	} else {
 		b, err = json.Marshal(resp)
 	}
@@ -62,8 +62,8 @@ func execJSONHandler(fn JSONHandler, w http.ResponseWriter, r *http.Request) err
 		return err
 	}
 
-	w.WriteHeader(http.StatusOK)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	w.Write(b)
 
 	return nil
@@ -19,7 +19,7 @@ func TestJSONHandler(t *testing.T) {
 		}, nil
 	}
 
-	httpHandler := wrapJSONHandler(customHandler)
+	httpHandler := wrapJSONHandler(customHandler, JSONMarshalOptions)
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 
@@ -35,7 +35,7 @@ func TestJSONHandlerError(t *testing.T) {
 		return nil, errors.NewC("test error", codes.Internal)
 	}
 
-	httpHandler := wrapJSONHandler(customHandler)
+	httpHandler := wrapJSONHandler(customHandler, JSONMarshalOptions)
 
 	req := httptest.NewRequest(http.MethodGet, "/test", nil)
 	req = req.WithContext(logging.EnsureLogger(t.Context()))
@@ -43,5 +43,33 @@ func TestJSONHandlerError(t *testing.T) {
 	httpHandler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusInternalServerError, rr.Code)
-	assert.JSONEq(t, `{"code":13,"codeName":"INTERNAL","message":"test error", "details": []}`, rr.Body.String())
+	assert.JSONEq(t, `{"code":13,"codeName":"INTERNAL","message":"test error", "details": [], "errors": []}`, rr.Body.String())
+}
+
+func TestJSONHandlerError_Aggregate(t *testing.T) {
+	customHandler := func(req *http.Request) (any, error) {
+		var errs errors.Multi
+		errs.Add("a", errors.NewC("missing", codes.NotFound).WithUserPresentableMessage("Note a not found"))
+		errs.Add("b", errors.NewC("bad", codes.InvalidArgument).WithFieldViolation("title", "required"))
+		return nil, errs.Err()
+	}
+
+	httpHandler := wrapJSONHandler(customHandler, JSONMarshalOptions)
+
+	req := httptest.NewRequest(http.MethodPost, "/test", nil)
+	req = req.WithContext(logging.EnsureLogger(t.Context()))
+	rr := httptest.NewRecorder()
+	httpHandler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{
+		"code": 5, "codeName": "NOT_FOUND", "message": "2 items failed", "details": [],
+		"errors": [
+			{"item": "a", "code": 5, "codeName": "NOT_FOUND", "message": "Note a not found", "details": []},
+			{"item": "b", "code": 3, "codeName": "INVALID_ARGUMENT", "message": "bad", "details": [{
+				"@type": "type.googleapis.com/google.rpc.BadRequest",
+				"fieldViolations": [{"field": "title", "description": "required", "reason": "", "localizedMessage": null}]
+			}]}
+		]
+	}`, rr.Body.String())
 }
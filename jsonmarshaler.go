@@ -0,0 +1,283 @@
+package prefab
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Content type of responses from server-streaming methods.
+const ndjsonContentType = "application/x-ndjson"
+
+// methodJSONOverrides caches the JSON options declared for each RPC, keyed by
+// full method name.
+var methodJSONOverrides sync.Map
+
+// messageDiscardUnknown caches whether unknown fields should be discarded when
+// decoding a request message, keyed by message name.
+var messageDiscardUnknown sync.Map
+
+// WithJSONMarshalOptions configures how the GRPC Gateway and JSON handlers
+// encode responses for this server. Defaults to JSONMarshalOptions.
+//
+// Individual methods can override `EmitUnpopulated` and `UseProtoNames` with
+// the `json_emit_unpopulated` and `json_use_proto_names` method options.
+func WithJSONMarshalOptions(opts protojson.MarshalOptions) ServerOption {
+	return func(b *builder) {
+		b.jsonMarshalOptions = opts
+	}
+}
+
+// WithJSONUnmarshalOptions configures how the GRPC Gateway decodes JSON request
+// bodies for this server. By default unknown fields are rejected.
+//
+// Individual methods can override `DiscardUnknown` with the
+// `json_discard_unknown` method option.
+func WithJSONUnmarshalOptions(opts protojson.UnmarshalOptions) ServerOption {
+	return func(b *builder) {
+		b.jsonUnmarshalOptions = opts
+	}
+}
+
+// WithPooledJSONBuffers sets whether the GRPC Gateway encodes responses into
+// pooled buffers. This reduces allocations and GC pressure for large
+// responses, and is enabled by default.
+//
+// Config key: `server.pooledJSONBuffers`.
+func WithPooledJSONBuffers(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.pooledJSONBuffers = enabled
+	}
+}
+
+// jsonMarshaler is the GRPC Gateway's JSON marshaler. It encodes with the
+// server's options, unless the response has been wrapped with method specific
+// options by rewriteResponse.
+type jsonMarshaler struct {
+	runtime.JSONPb
+
+	// Whether to encode into pooled buffers, see WithPooledJSONBuffers.
+	pooled bool
+}
+
+func newJSONMarshaler(m protojson.MarshalOptions, u protojson.UnmarshalOptions) *jsonMarshaler {
+	return &jsonMarshaler{JSONPb: runtime.JSONPb{MarshalOptions: m, UnmarshalOptions: u}}
+}
+
+// jsonResponse is a response which should be encoded with method specific
+// options.
+type jsonResponse struct {
+	msg  any
+	opts protojson.MarshalOptions
+}
+
+func (m *jsonMarshaler) Marshal(v any) ([]byte, error) {
+	// Chunks of server-streaming responses are encoded on a single line.
+	switch chunk := v.(type) {
+	case map[string]any:
+		// Messages are wrapped as {"result": ...}.
+		if msg, ok := chunk["result"]; ok && len(chunk) == 1 {
+			j, msg := m.forResponse(msg)
+			return singleLine(j).Marshal(map[string]any{"result": msg})
+		}
+	case map[string]proto.Message:
+		// Errors ending a stream are sent as {"error": ...}, in the same format as
+		// errors from unary methods.
+		if st, ok := chunk["error"]; ok && len(chunk) == 1 {
+			return singleLine(&m.JSONPb).Marshal(map[string]any{"error": customErrorResponse(st)})
+		}
+	}
+	j, v := m.forResponse(v)
+	if m.pooled {
+		var out []byte
+		if ok, err := marshalPooled(j, v, func(b []byte) error {
+			out = bytes.Clone(b)
+			return nil
+		}); ok {
+			return out, err
+		}
+	}
+	return j.Marshal(v)
+}
+
+// StreamContentType is the content type of responses from server-streaming
+// methods, which are sent as newline-delimited JSON.
+func (m *jsonMarshaler) StreamContentType(any) string {
+	return ndjsonContentType
+}
+
+// Delimiter separates messages from server-streaming methods.
+func (m *jsonMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
+
+func (m *jsonMarshaler) NewEncoder(w io.Writer) runtime.Encoder {
+	return runtime.EncoderFunc(func(v any) error {
+		j, v := m.forResponse(v)
+		if m.pooled {
+			if ok, err := marshalPooled(j, v, func(b []byte) error {
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+				_, err := w.Write(j.Delimiter())
+				return err
+			}); ok {
+				return err
+			}
+		}
+		return j.NewEncoder(w).Encode(v)
+	})
+}
+
+func (m *jsonMarshaler) Unmarshal(data []byte, v any) error {
+	return m.forRequest(v).Unmarshal(data, v)
+}
+
+func (m *jsonMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v any) error {
+		return m.forRequest(v).NewDecoder(r).Decode(v)
+	})
+}
+
+// forResponse returns the marshaler to use for a value and the value itself.
+func (m *jsonMarshaler) forResponse(v any) (*runtime.JSONPb, any) {
+	j := m.JSONPb
+	if r, ok := v.(*jsonResponse); ok {
+		j.MarshalOptions = r.opts
+		v = r.msg
+	}
+	return &j, v
+}
+
+// singleLine returns a copy of the marshaler which doesn't indent its output.
+func singleLine(j *runtime.JSONPb) *runtime.JSONPb {
+	c := *j
+	c.Multiline = false
+	c.Indent = ""
+	return &c
+}
+
+// forRequest returns the marshaler to use when decoding into v. Decoders are
+// not given the request context, so the method option is resolved from the
+// request message instead.
+func (m *jsonMarshaler) forRequest(v any) *runtime.JSONPb {
+	j := m.JSONPb
+	if msg, ok := v.(proto.Message); ok {
+		if discard, ok := discardUnknownForMessage(msg.ProtoReflect().Descriptor().FullName()); ok {
+			j.DiscardUnknown = discard
+		}
+	}
+	return &j
+}
+
+// rewriteResponse wraps responses for methods which declare JSON options, so
+// that the marshaler can apply them.
+//
+// Example:
+//
+//	rpc GetArticle(GetArticleRequest) returns (Article) {
+//	  option (prefab.json_use_proto_names) = true;
+//	  option (prefab.json_emit_unpopulated) = false;
+//	}
+func (m *jsonMarshaler) rewriteResponse(ctx context.Context, resp proto.Message) (any, error) {
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return resp, nil
+	}
+	o := jsonOverridesForMethod(method)
+	if o.emitUnpopulated == nil && o.useProtoNames == nil {
+		return resp, nil
+	}
+	opts := m.MarshalOptions
+	if o.emitUnpopulated != nil {
+		opts.EmitUnpopulated = *o.emitUnpopulated
+	}
+	if o.useProtoNames != nil {
+		opts.UseProtoNames = *o.useProtoNames
+	}
+	var v any = resp
+	if rb, ok := resp.(interface{ XXX_ResponseBody() any }); ok {
+		v = rb.XXX_ResponseBody()
+	}
+	return &jsonResponse{msg: v, opts: opts}, nil
+}
+
+// jsonOverrides holds the marshal options declared on a method, nil values are
+// inherited from the server.
+type jsonOverrides struct {
+	emitUnpopulated *bool
+	useProtoNames   *bool
+}
+
+// jsonOverridesForMethod returns the JSON options declared for a method, e.g.
+// "/prefab.MetaService/ClientConfig".
+func jsonOverridesForMethod(method string) jsonOverrides {
+	if v, ok := methodJSONOverrides.Load(method); ok {
+		return v.(jsonOverrides)
+	}
+
+	var o jsonOverrides
+	name := strings.TrimPrefix(strings.ReplaceAll(method, "/", "."), ".")
+	if desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+		opts, _ := desc.Options().(*descriptorpb.MethodOptions)
+		o = jsonOptions(opts)
+	}
+	methodJSONOverrides.Store(method, o)
+	return o
+}
+
+// jsonOptions returns the marshal options declared by method options.
+func jsonOptions(opts *descriptorpb.MethodOptions) jsonOverrides {
+	return jsonOverrides{
+		emitUnpopulated: boolOption(opts, E_JsonEmitUnpopulated),
+		useProtoNames:   boolOption(opts, E_JsonUseProtoNames),
+	}
+}
+
+// discardUnknownForMessage returns the `json_discard_unknown` option declared
+// by methods which accept the given request message.
+func discardUnknownForMessage(name protoreflect.FullName) (bool, bool) {
+	if v, ok := messageDiscardUnknown.Load(name); ok {
+		d := v.(*bool)
+		return d != nil && *d, d != nil
+	}
+
+	var discard *bool
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := range services.Len() {
+			methods := services.Get(i).Methods()
+			for j := range methods.Len() {
+				md := methods.Get(j)
+				if md.Input().FullName() != name {
+					continue
+				}
+				opts, _ := md.Options().(*descriptorpb.MethodOptions)
+				if d := boolOption(opts, E_JsonDiscardUnknown); d != nil {
+					discard = d
+					return false
+				}
+			}
+		}
+		return true
+	})
+	messageDiscardUnknown.Store(name, discard)
+	return discard != nil && *discard, discard != nil
+}
+
+func boolOption(opts *descriptorpb.MethodOptions, ext protoreflect.ExtensionType) *bool {
+	if opts == nil || !proto.HasExtension(opts, ext) {
+		return nil
+	}
+	v := proto.GetExtension(opts, ext).(bool)
+	return &v
+}
@@ -0,0 +1,196 @@
+package prefab
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONOptions(t *testing.T) {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, E_JsonEmitUnpopulated, false)
+	o := jsonOptions(opts)
+	require.NotNil(t, o.emitUnpopulated)
+	assert.False(t, *o.emitUnpopulated)
+	assert.Nil(t, o.useProtoNames, "unset options are inherited")
+
+	assert.Equal(t, jsonOverrides{}, jsonOptions(nil))
+	assert.Equal(t, jsonOverrides{}, jsonOverridesForMethod(MetaService_ClientConfig_FullMethodName))
+}
+
+func TestJSONMarshaler_RewriteResponse(t *testing.T) {
+	const method = "/prefab.test.JSONService/Get"
+	useProtoNames, emitUnpopulated := true, false
+	methodJSONOverrides.Store(method, jsonOverrides{useProtoNames: &useProtoNames, emitUnpopulated: &emitUnpopulated})
+	t.Cleanup(func() { methodJSONOverrides.Delete(method) })
+
+	m := newJSONMarshaler(protojson.MarshalOptions{EmitUnpopulated: true}, protojson.UnmarshalOptions{})
+	resp := &ClientConfigResponse{CsrfToken: "abc"}
+
+	// Methods without options use the server's options.
+	req := httptest.NewRequest(http.MethodGet, "/api/meta/config", nil)
+	ctx, err := runtime.AnnotateContext(t.Context(), runtime.NewServeMux(), req, MetaService_ClientConfig_FullMethodName)
+	require.NoError(t, err)
+	v, err := m.rewriteResponse(ctx, resp)
+	require.NoError(t, err)
+	b, err := m.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"configs":{},"csrfToken":"abc","values":null,"apiVersion":"","apiVersions":[]}`, string(b))
+
+	ctx, err = runtime.AnnotateContext(t.Context(), runtime.NewServeMux(), req, method)
+	require.NoError(t, err)
+	v, err = m.rewriteResponse(ctx, resp)
+	require.NoError(t, err)
+	b, err = m.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"csrf_token":"abc"}`, string(b))
+
+	var buf bytes.Buffer
+	require.NoError(t, m.NewEncoder(&buf).Encode(v))
+	assert.JSONEq(t, `{"csrf_token":"abc"}`, buf.String())
+
+	// Errors use the same options as the method.
+	b, err = (&monkeypatcher{Marshaler: m}).Marshal(&jsonResponse{msg: &CustomErrorResponse{Code: 5}, opts: v.(*jsonResponse).opts})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"code":5,"code_name":"NOT_FOUND"}`, string(b))
+}
+
+func TestJSONMarshaler_DiscardUnknown(t *testing.T) {
+	m := newJSONMarshaler(JSONMarshalOptions, protojson.UnmarshalOptions{})
+	body := `{"csrfToken":"abc","unknown":true}`
+
+	var resp ClientConfigResponse
+	require.Error(t, m.NewDecoder(bytes.NewBufferString(body)).Decode(&resp))
+
+	// ClientConfigResponse isn't a request message, so mark it directly.
+	name := resp.ProtoReflect().Descriptor().FullName()
+	discard := true
+	messageDiscardUnknown.Store(name, &discard)
+	t.Cleanup(func() { messageDiscardUnknown.Delete(name) })
+
+	require.NoError(t, m.NewDecoder(bytes.NewBufferString(body)).Decode(&resp))
+	assert.Equal(t, "abc", resp.GetCsrfToken())
+	require.NoError(t, m.Unmarshal([]byte(body), &resp))
+
+	// Server options apply when no method declares the option.
+	_, ok := discardUnknownForMessage((&ClientConfigRequest{}).ProtoReflect().Descriptor().FullName())
+	assert.False(t, ok)
+	m = newJSONMarshaler(JSONMarshalOptions, protojson.UnmarshalOptions{DiscardUnknown: true})
+	require.NoError(t, m.Unmarshal([]byte(`{"unknown":true}`), &ClientConfigRequest{}))
+}
+
+func TestWithJSONMarshalOptions(t *testing.T) {
+	s := New(
+		WithJSONMarshalOptions(protojson.MarshalOptions{UseProtoNames: true}),
+		WithJSONRoute("GET /config", func(*http.Request) (any, error) {
+			return &ClientConfigResponse{CsrfToken: "abc"}, nil
+		}),
+	)
+	rec := serveRoute(s.httpMux, http.MethodGet, "/config")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"csrf_token":"abc"}`, rec.Body.String())
+}
+
+func TestJSONMarshaler_StreamChunks(t *testing.T) {
+	m := newJSONMarshaler(protojson.MarshalOptions{EmitUnpopulated: true}, protojson.UnmarshalOptions{})
+	assert.Equal(t, "application/x-ndjson", m.StreamContentType(nil))
+	assert.Equal(t, []byte("\n"), m.Delimiter())
+
+	// Method options apply to each message in the stream.
+	resp := &jsonResponse{msg: &ClientConfigResponse{CsrfToken: "abc"}, opts: protojson.MarshalOptions{UseProtoNames: true}}
+	b, err := m.Marshal(map[string]any{"result": resp})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result":{"csrf_token":"abc"}}`, string(b))
+
+	// Errors ending the stream use the same format as unary errors.
+	b, err = m.Marshal(map[string]proto.Message{"error": status.New(codes.NotFound, "missing").Proto()})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"error":{"code":5,"codeName":"NOT_FOUND","message":"missing","details":[],"errors":[]}}`, string(b))
+}
+
+// streamHandler serves messages from a channel as a server-streaming gateway
+// method would, ending with err once the channel is closed.
+func streamHandler(mux *runtime.ServeMux, messages <-chan proto.Message, err error) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		ctx, _ := runtime.AnnotateContext(r.Context(), mux, r, "/prefab.test.StreamService/Stream")
+		ctx = runtime.NewServerMetadataContext(ctx, runtime.ServerMetadata{})
+		_, outbound := runtime.MarshalerForRequest(mux, r)
+		runtime.ForwardResponseStream(ctx, mux, outbound, w, r, func() (proto.Message, error) {
+			if msg, ok := <-messages; ok {
+				return msg, nil
+			}
+			return nil, err
+		})
+	}
+}
+
+func TestGatewayStreaming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	messages := make(chan proto.Message)
+	s := New(
+		WithListener(ln),
+		WithGRPCGateway(func(_ context.Context, mux *runtime.ServeMux, _ string, _ []grpc.DialOption) error {
+			return mux.HandlePath(http.MethodGet, "/api/test/stream", streamHandler(mux, messages, status.Error(codes.Unavailable, "gone")))
+		}),
+	)
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	require.Eventually(t, func() bool { return s.Ready() }, time.Second, 10*time.Millisecond)
+
+	// Asking for gzip explicitly stops the client from decompressing the body.
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+ln.Addr().String()+"/api/test/stream", nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Encoding", "gzip")
+	client := &http.Client{Transport: &http.Transport{}}
+
+	go func() { messages <- wrapperspb.String("one") }()
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Content-Encoding"), "streams aren't buffered for compression")
+
+	// Each message is flushed as it is sent.
+	r := bufio.NewReader(resp.Body)
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result":"one"}`, line)
+
+	messages <- wrapperspb.String("two")
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"result":"two"}`, line)
+
+	close(messages)
+	line, err = r.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"error":{"code":14,"codeName":"UNAVAILABLE","message":"gone","details":[],"errors":[]}}`, line)
+	_, err = r.ReadString('\n')
+	require.ErrorIs(t, err, io.EOF)
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Shutdown())
+	require.NoError(t, <-started)
+}
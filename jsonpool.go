@@ -0,0 +1,98 @@
+package prefab
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxPooledJSONBuffer is the capacity of the largest buffer returned to the
+// pool, so that an occasional huge response doesn't pin memory.
+const maxPooledJSONBuffer = 16 << 20
+
+// jsonBuffers holds buffers for encoding gateway responses.
+var jsonBuffers = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 64<<10)
+		return &b
+	},
+}
+
+var protoMessageType = reflect.TypeFor[proto.Message]()
+
+func getJSONBuffer() *[]byte {
+	return jsonBuffers.Get().(*[]byte)
+}
+
+// putJSONBuffer returns a buffer to the pool, keeping any growth.
+func putJSONBuffer(bp *[]byte, b []byte) {
+	if cap(b) <= maxPooledJSONBuffer {
+		*bp = b[:0]
+		jsonBuffers.Put(bp)
+	}
+}
+
+// marshalPooled encodes v into a pooled buffer and passes it to fn, which must
+// not retain it. Returns false, without calling fn, if v isn't a message or a
+// slice of messages, which are left to runtime.JSONPb.
+func marshalPooled(j *runtime.JSONPb, v any, fn func([]byte) error) (bool, error) {
+	bp := getJSONBuffer()
+	b, ok, err := appendJSON((*bp)[:0], j, v)
+	if ok && err == nil {
+		err = fn(b)
+	}
+	putJSONBuffer(bp, b)
+	return ok, err
+}
+
+// appendJSON appends the encoding of v to b. Repeated fields, as returned for
+// methods with a `response_body`, are encoded element by element into the same
+// buffer, matching runtime.JSONPb's output without its intermediate copies.
+func appendJSON(b []byte, j *runtime.JSONPb, v any) ([]byte, bool, error) {
+	if msg, ok := v.(proto.Message); ok {
+		b, err := j.MarshalAppend(b, msg)
+		return b, true, err
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice || rv.IsNil() || !rv.Type().Elem().Implements(protoMessageType) {
+		return b, false, nil
+	}
+	if j.Indent == "" {
+		b, err := appendMessages(b, j.MarshalOptions, rv)
+		return b, true, err
+	}
+
+	// runtime.JSONPb indents repeated fields as a whole, so encode them
+	// compactly into a scratch buffer first.
+	opts := j.MarshalOptions
+	opts.Multiline, opts.Indent = false, ""
+	sp := getJSONBuffer()
+	compact, err := appendMessages((*sp)[:0], opts, rv)
+	if err == nil {
+		buf := bytes.NewBuffer(b)
+		err = json.Indent(buf, compact, "", j.Indent)
+		b = buf.Bytes()
+	}
+	putJSONBuffer(sp, compact)
+	return b, true, err
+}
+
+// appendMessages appends a JSON array of the messages in rv to b.
+func appendMessages(b []byte, opts protojson.MarshalOptions, rv reflect.Value) ([]byte, error) {
+	b = append(b, '[')
+	for i := range rv.Len() {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		var err error
+		if b, err = opts.MarshalAppend(b, rv.Index(i).Interface().(proto.Message)); err != nil {
+			return b, err
+		}
+	}
+	return append(b, ']'), nil
+}
@@ -0,0 +1,100 @@
+package prefab
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestJSONMarshaler_Pooled(t *testing.T) {
+	for _, opts := range []protojson.MarshalOptions{{EmitUnpopulated: true}, JSONMarshalOptions} {
+		testPooledMarshaler(t, opts)
+	}
+}
+
+func testPooledMarshaler(t *testing.T, opts protojson.MarshalOptions) {
+	m := newJSONMarshaler(opts, protojson.UnmarshalOptions{})
+	m.pooled = true
+	j := &runtime.JSONPb{MarshalOptions: opts}
+
+	values := []any{
+		&ClientConfigResponse{CsrfToken: "abc", Configs: map[string]string{"a": "1"}},
+		[]*wrapperspb.StringValue{wrapperspb.String("one"), wrapperspb.String("two")},
+		[]*wrapperspb.StringValue(nil),
+		map[string]any{"result": wrapperspb.String("chunk")},
+		"plain",
+	}
+	for _, v := range values {
+		want, err := j.Marshal(v)
+		require.NoError(t, err)
+		got, err := m.Marshal(v)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(want), string(got), "%T", v)
+
+		var buf bytes.Buffer
+		require.NoError(t, m.NewEncoder(&buf).Encode(v))
+		assert.JSONEq(t, string(want), buf.String(), "%T", v)
+		assert.True(t, strings.HasSuffix(buf.String(), "\n"))
+	}
+
+	// Results don't share the pooled buffer.
+	first, err := m.Marshal(wrapperspb.String("first"))
+	require.NoError(t, err)
+	_, err = m.Marshal(wrapperspb.String("second"))
+	require.NoError(t, err)
+	assert.JSONEq(t, `"first"`, string(first))
+}
+
+// largeResponses returns multi-MB responses: a message with a large map, and a
+// repeated field as returned for methods with a `response_body`.
+func largeResponses() map[string]any {
+	configs := make(map[string]string, 50000)
+	for i := range 50000 {
+		configs[fmt.Sprintf("feature.flag.%d", i)] = strings.Repeat("x", 32)
+	}
+	items := make([]*wrapperspb.StringValue, 2000)
+	for i := range items {
+		items[i] = wrapperspb.String(strings.Repeat("y", 1024))
+	}
+	return map[string]any{
+		"message":  &ClientConfigResponse{Configs: configs},
+		"repeated": items,
+	}
+}
+
+func BenchmarkJSONMarshaler(b *testing.B) {
+	for name, v := range largeResponses() {
+		for _, pooled := range []bool{false, true} {
+			m := newJSONMarshaler(JSONMarshalOptions, protojson.UnmarshalOptions{})
+			m.pooled = pooled
+			b.Run(fmt.Sprintf("%s/pooled=%t", name, pooled), func(b *testing.B) {
+				b.ReportAllocs()
+				for b.Loop() {
+					if _, err := m.Marshal(v); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run(fmt.Sprintf("%s/encoder/pooled=%t", name, pooled), func(b *testing.B) {
+				b.ReportAllocs()
+				enc := m.NewEncoder(discard{})
+				for b.Loop() {
+					if err := enc.Encode(v); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
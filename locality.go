@@ -0,0 +1,58 @@
+package prefab
+
+import (
+	"net/http"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+)
+
+// WithLocality sets where the server is deployed. The locality is added to
+// every log entry, to response headers, to eventbus messages and audit
+// entries, and is available to application code with
+// serverutil.CurrentLocality.
+//
+// Config keys: `server.locality.region`, `server.locality.zone`, and
+// `server.locality.instance`.
+func WithLocality(l serverutil.Locality) ServerOption {
+	return func(b *builder) {
+		b.locality = l
+	}
+}
+
+// WithLocalityHeaders sets whether responses carry the X-Prefab-Region,
+// X-Prefab-Zone, and X-Prefab-Instance headers. Enabled by default, disable it
+// to keep deployment details private.
+//
+// Config key: `server.locality.headers`.
+func WithLocalityHeaders(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.localityHeaders = enabled
+	}
+}
+
+// withLocalityLogger adds the locality to the logger of ctx.
+func withLocalityLogger(logger logging.Logger, l serverutil.Locality) logging.Logger {
+	if l.Region != "" {
+		logger = logger.With("region", l.Region)
+	}
+	if l.Zone != "" {
+		logger = logger.With("zone", l.Zone)
+	}
+	if l.Instance != "" {
+		logger = logger.With("instance", l.Instance)
+	}
+	return logger
+}
+
+// localityMiddleware adds the locality to response headers. For GRPC
+// requests, they are sent as header metadata.
+func localityMiddleware(h http.Handler, l serverutil.Locality) http.Handler {
+	if l.IsZero() {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.SetHeaders(w.Header())
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,34 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocality(t *testing.T) {
+	t.Cleanup(func() { serverutil.SetLocality(serverutil.Locality{}) })
+	l := serverutil.Locality{Region: "us-east1", Zone: "us-east1-b", Instance: "web-1"}
+
+	s := New(WithLocality(l))
+	assert.Equal(t, l, serverutil.CurrentLocality())
+	assert.Equal(t, l, s.localityHeaders)
+
+	s = New(WithLocality(l), WithLocalityHeaders(false))
+	assert.True(t, s.localityHeaders.IsZero())
+}
+
+func TestLocalityMiddleware(t *testing.T) {
+	h := localityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), serverutil.Locality{Region: "us-east1", Instance: "web-1"})
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "us-east1", rr.Header().Get("X-Prefab-Region"))
+	assert.Equal(t, "web-1", rr.Header().Get("X-Prefab-Instance"))
+	assert.Empty(t, rr.Header().Get("X-Prefab-Zone"))
+}
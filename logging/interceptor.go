@@ -5,6 +5,7 @@ import (
 	"reflect"
 
 	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/redact"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_logging "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
 	"go.uber.org/zap"
@@ -15,9 +16,10 @@ import (
 const stackSize = 5
 
 // Interceptor returns a GRPC Logging interceptor configured to log using
-// the prefab logging adapter.
+// the prefab logging adapter. Payloads are logged for requests matching a rule
+// added with EnableVerbose.
 func Interceptor() grpc.UnaryServerInterceptor {
-	return grpc_middleware.ChainUnaryServer(scopingInterceptor, grpcLoggingInterceptor, errorInterceptor)
+	return grpc_middleware.ChainUnaryServer(scopingInterceptor, grpcLoggingInterceptor, errorInterceptor, verboseInterceptor)
 }
 
 // Creates a new logging scope for each request, adding the RPC method name as
@@ -81,7 +83,7 @@ var grpcLoggingInterceptor = grpc_logging.UnaryServerInterceptor(grpc_logging.Lo
 
 	for i := 0; i < len(fields); i += 2 {
 		key, _ := fields[i].(string)
-		value := fields[i+1]
+		value := redact.Any(fields[i+1])
 		logger = logger.With(key, value)
 	}
 
@@ -0,0 +1,137 @@
+package logging
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LevelOverride changes the minimum level of loggers whose name starts with
+// Logger, an empty Logger applies to all loggers. Loggers are named after the
+// GRPC method or HTTP path of the request, e.g. "/pkg.Service/Method".
+type LevelOverride struct {
+	Logger  string
+	Level   zapcore.Level
+	Expires time.Time // Zero if the override doesn't expire.
+}
+
+var levels = struct {
+	sync.RWMutex
+	overrides map[string]LevelOverride
+}{overrides: map[string]LevelOverride{}}
+
+// SetLevel overrides the minimum level of loggers created by NewDevLogger and
+// NewProdLogger, for loggers whose name starts with logger, or all loggers if
+// logger is empty. The longest matching override wins. If ttl is positive the
+// override is reverted after ttl, which avoids leaving verbose logging enabled
+// after an incident.
+//
+// Example:
+//
+//	logging.SetLevel("/billing.BillingService/", zapcore.DebugLevel, 15*time.Minute)
+func SetLevel(logger string, level zapcore.Level, ttl time.Duration) {
+	o := LevelOverride{Logger: logger, Level: level}
+	if ttl > 0 {
+		o.Expires = time.Now().Add(ttl)
+	}
+	levels.Lock()
+	defer levels.Unlock()
+	levels.overrides[logger] = o
+}
+
+// ResetLevel removes the override for logger, see SetLevel.
+func ResetLevel(logger string) {
+	levels.Lock()
+	defer levels.Unlock()
+	delete(levels.overrides, logger)
+}
+
+// ResetLevels removes all level overrides.
+func ResetLevels() {
+	levels.Lock()
+	defer levels.Unlock()
+	levels.overrides = map[string]LevelOverride{}
+}
+
+// LevelOverrides returns the active overrides, sorted by logger.
+func LevelOverrides() []LevelOverride {
+	now := time.Now()
+	levels.RLock()
+	defer levels.RUnlock()
+	out := make([]LevelOverride, 0, len(levels.overrides))
+	for _, o := range levels.overrides {
+		if !o.expired(now) {
+			out = append(out, o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Logger < out[j].Logger })
+	return out
+}
+
+func (o LevelOverride) expired(now time.Time) bool {
+	return !o.Expires.IsZero() && now.After(o.Expires)
+}
+
+// levelFor returns the minimum level for the named logger.
+func levelFor(name string, base zapcore.Level) zapcore.Level {
+	now := time.Now()
+	levels.RLock()
+	defer levels.RUnlock()
+	lvl, longest := base, -1
+	for prefix, o := range levels.overrides {
+		if len(prefix) > longest && strings.HasPrefix(name, prefix) && !o.expired(now) {
+			lvl, longest = o.Level, len(prefix)
+		}
+	}
+	return lvl
+}
+
+// minLevel returns the lowest level any logger is enabled for.
+func minLevel(base zapcore.Level) zapcore.Level {
+	now := time.Now()
+	levels.RLock()
+	defer levels.RUnlock()
+	lvl := base
+	for _, o := range levels.overrides {
+		if o.Level < lvl && !o.expired(now) {
+			lvl = o.Level
+		}
+	}
+	return lvl
+}
+
+// overridableCore filters entries by level, taking overrides into account. The
+// wrapped core must be enabled for all levels.
+type overridableCore struct {
+	zapcore.Core
+	base zapcore.Level
+}
+
+func withLevelOverrides(base zapcore.Level) zap.Option {
+	return zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return &overridableCore{Core: c, base: base}
+	})
+}
+
+func (c *overridableCore) Enabled(lvl zapcore.Level) bool {
+	return lvl >= minLevel(c.base)
+}
+
+func (c *overridableCore) Level() zapcore.Level {
+	return minLevel(c.base)
+}
+
+func (c *overridableCore) With(fields []zapcore.Field) zapcore.Core {
+	return &overridableCore{Core: c.Core.With(fields), base: c.base}
+}
+
+func (c *overridableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < levelFor(ent.LoggerName, c.base) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+)
+
+func newOverridableLogger(base zapcore.Level) (*ZapLogger, *observer.ObservedLogs) {
+	core, obs := observer.New(zap.DebugLevel)
+	return &ZapLogger{z: zap.New(core, withLevelOverrides(base)).Sugar()}, obs
+}
+
+func TestSetLevel(t *testing.T) {
+	t.Cleanup(ResetLevels)
+	logger, obs := newOverridableLogger(zapcore.InfoLevel)
+	billing := logger.Named("/billing.Billing/Charge")
+	notes := logger.Named("/notes.Notes/Get")
+
+	billing.Debug("hidden")
+	assert.Equal(t, 0, obs.Len(), "debug is below the base level")
+
+	SetLevel("/billing.", zapcore.DebugLevel, 0)
+	billing.Debug("shown")
+	notes.Debug("hidden")
+	assert.Equal(t, []string{"shown"}, messages(obs.TakeAll()))
+
+	// The longest prefix wins.
+	SetLevel("", zapcore.ErrorLevel, 0)
+	billing.Debug("shown")
+	notes.Info("hidden")
+	notes.Error("shown")
+	assert.Equal(t, []string{"shown", "shown"}, messages(obs.TakeAll()))
+
+	assert.Equal(t, []LevelOverride{
+		{Logger: "", Level: zapcore.ErrorLevel},
+		{Logger: "/billing.", Level: zapcore.DebugLevel},
+	}, LevelOverrides())
+
+	ResetLevel("/billing.")
+	billing.Debug("hidden")
+	billing.Warn("hidden")
+	assert.Equal(t, 0, obs.Len())
+}
+
+func TestSetLevel_Expires(t *testing.T) {
+	t.Cleanup(ResetLevels)
+	logger, obs := newOverridableLogger(zapcore.InfoLevel)
+
+	SetLevel("", zapcore.DebugLevel, time.Hour)
+	logger.Debug("shown")
+	assert.Equal(t, 1, obs.Len())
+	require.Len(t, LevelOverrides(), 1)
+	assert.False(t, LevelOverrides()[0].Expires.IsZero())
+
+	SetLevel("", zapcore.DebugLevel, time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	logger.Debug("hidden")
+	assert.Equal(t, 1, obs.Len(), "expired overrides revert to the base level")
+	assert.Empty(t, LevelOverrides())
+}
+
+func TestVerboseInterceptor(t *testing.T) {
+	t.Cleanup(func() { verbose.rules = map[VerboseRule]time.Time{} })
+
+	call := func(method, subject string) map[string]any {
+		logger, obs := newTestLogger()
+		ctx := With(t.Context(), logger)
+		ctx = WithSubjectFunc(ctx, func(context.Context) string { return subject })
+		info := &grpc.UnaryServerInfo{FullMethod: method}
+		_, err := verboseInterceptor(ctx, "req", info, func(ctx context.Context, _ any) (any, error) {
+			return "resp", nil
+		})
+		require.NoError(t, err)
+		FromContext(ctx).Info("done")
+		return obs.All()[0].ContextMap()
+	}
+
+	assert.NotContains(t, call("/notes.Notes/Get", "alice"), "request")
+
+	EnableVerbose("/notes.Notes/Get", "", time.Minute)
+	fields := call("/notes.Notes/Get", "alice")
+	assert.Equal(t, "req", fields["request"])
+	assert.Equal(t, "resp", fields["response"])
+	assert.NotContains(t, call("/notes.Notes/List", "alice"), "request")
+
+	EnableVerbose("", "bob", time.Minute)
+	assert.Contains(t, call("/notes.Notes/List", "bob"), "request")
+	assert.NotContains(t, call("/notes.Notes/List", "alice"), "request")
+	assert.Len(t, VerboseRules(), 2)
+
+	DisableVerbose("", "bob")
+	assert.NotContains(t, call("/notes.Notes/List", "bob"), "request")
+
+	EnableVerbose("", "carol", -time.Second)
+	assert.NotContains(t, call("/notes.Notes/List", "carol"), "request", "expired rules are ignored")
+	assert.Len(t, VerboseRules(), 1)
+}
+
+func messages(entries []observer.LoggedEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Message)
+	}
+	return out
+}
@@ -1,6 +1,10 @@
 package logging
 
-import "context"
+import (
+	"context"
+
+	"github.com/dpup/prefab/redact"
+)
 
 type ctxkey struct {
 	logger Logger
@@ -45,7 +49,7 @@ func FromContext(ctx context.Context) Logger {
 func Track(ctx context.Context, field string, value interface{}) {
 	c, ok := ctx.Value(ctxkey{}).(*ctxkey)
 	if ok {
-		c.logger = c.logger.With(field, value)
+		c.logger = c.logger.With(field, redact.Any(value))
 	}
 }
 
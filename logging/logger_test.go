@@ -2,9 +2,11 @@ package logging
 
 import (
 	"context"
+	"fmt"
 	"testing"
 
 	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/redact"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -12,6 +14,11 @@ import (
 	"go.uber.org/zap/zaptest/observer"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // Helper to create an observed logger for testing
@@ -413,3 +420,37 @@ func TestErrorInterceptorWithLogFields(t *testing.T) {
 	assert.Contains(t, fields, zap.String("error.message", "database connection failed"))
 	assert.Contains(t, fields, zap.Int("error.http_status", 500))
 }
+
+func TestTrack_Redacted(t *testing.T) {
+	opts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(opts, redact.E_Redact, true)
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("logging_test.proto"),
+		Package: proto.String("logging.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Login"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("user"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+				{Name: proto.String("password"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: opts},
+			},
+		}},
+	}, nil)
+	require.NoError(t, err)
+	md := fd.Messages().Get(0)
+	msg := dynamicpb.NewMessage(md)
+	msg.Set(md.Fields().ByName("user"), protoreflect.ValueOfString("alice"))
+	msg.Set(md.Fields().ByName("password"), protoreflect.ValueOfString("hunter2"))
+
+	logger, obs := newTestLogger()
+	ctx := With(t.Context(), logger)
+	Track(ctx, "req", msg)
+	Info(ctx, "test message")
+
+	require.Equal(t, 1, obs.Len())
+	tracked := fmt.Sprint(obs.All()[0].ContextMap()["req"])
+	assert.Contains(t, tracked, "alice")
+	assert.Contains(t, tracked, redact.Value)
+	assert.NotContains(t, tracked, "hunter2")
+	assert.Equal(t, "hunter2", msg.Get(md.Fields().ByName("password")).String(), "original should not be modified")
+}
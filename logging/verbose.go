@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// VerboseRule enables logging of request and response payloads for requests
+// to Method, by Subject, or both. Empty fields match any value.
+type VerboseRule struct {
+	Method  string
+	Subject string
+	Expires time.Time
+}
+
+var verbose = struct {
+	sync.RWMutex
+	rules map[VerboseRule]time.Time // Keyed by rule without expiry.
+}{rules: map[VerboseRule]time.Time{}}
+
+// EnableVerbose logs request and response payloads, redacted, for requests to
+// the GRPC method, by the subject, or both, for the next ttl. Payloads are
+// added to the request's log entry as `request` and `response`.
+//
+// Example:
+//
+//	logging.EnableVerbose("/notes.NoteService/Update", "", 10*time.Minute)
+func EnableVerbose(method, subject string, ttl time.Duration) {
+	verbose.Lock()
+	defer verbose.Unlock()
+	verbose.rules[VerboseRule{Method: method, Subject: subject}] = time.Now().Add(ttl)
+}
+
+// DisableVerbose removes a rule added by EnableVerbose.
+func DisableVerbose(method, subject string) {
+	verbose.Lock()
+	defer verbose.Unlock()
+	delete(verbose.rules, VerboseRule{Method: method, Subject: subject})
+}
+
+// VerboseRules returns the active rules, sorted by method and subject.
+func VerboseRules() []VerboseRule {
+	now := time.Now()
+	verbose.RLock()
+	defer verbose.RUnlock()
+	var out []VerboseRule
+	for r, expires := range verbose.rules {
+		if now.Before(expires) {
+			r.Expires = expires
+			out = append(out, r)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Method != out[j].Method {
+			return out[i].Method < out[j].Method
+		}
+		return out[i].Subject < out[j].Subject
+	})
+	return out
+}
+
+// matchVerbose returns whether payloads should be logged for the method. The
+// subject is only looked up if a rule requires it.
+func matchVerbose(ctx context.Context, method string) bool {
+	now := time.Now()
+	verbose.RLock()
+	defer verbose.RUnlock()
+	var subject *string
+	for r, expires := range verbose.rules {
+		if now.After(expires) || (r.Method != "" && r.Method != method) {
+			continue
+		}
+		if r.Subject == "" {
+			return true
+		}
+		if subject == nil {
+			s := SubjectFromContext(ctx)
+			subject = &s
+		}
+		if *subject == r.Subject {
+			return true
+		}
+	}
+	return false
+}
+
+type subjectFuncKey struct{}
+
+// WithSubjectFunc attaches a function which returns the subject of the
+// request's caller, so that verbose rules can match on identity. The auth
+// plugin injects this for each request.
+func WithSubjectFunc(ctx context.Context, fn func(context.Context) string) context.Context {
+	return context.WithValue(ctx, subjectFuncKey{}, fn)
+}
+
+// SubjectFromContext returns the subject of the request's caller, or an empty
+// string if the caller isn't authenticated or there's no subject function.
+func SubjectFromContext(ctx context.Context) string {
+	if fn, ok := ctx.Value(subjectFuncKey{}).(func(context.Context) string); ok {
+		return fn(ctx)
+	}
+	return ""
+}
+
+// Tracks payloads for requests matching a verbose rule.
+func verboseInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if matchVerbose(ctx, info.FullMethod) {
+		Track(ctx, "request", req)
+		if err == nil {
+			Track(ctx, "response", resp)
+		}
+	}
+	return resp, err
+}
@@ -1,16 +1,23 @@
 package logging
 
-import "go.uber.org/zap"
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
 
-// NewDevLogger returns a zap logger that prints dev friendly output.
+// NewDevLogger returns a zap logger that prints dev friendly output. The level
+// can be changed at runtime with SetLevel.
 func NewDevLogger() Logger {
-	l, _ := zap.NewDevelopment(zap.AddCallerSkip(2))
+	l, _ := zap.NewDevelopment(zap.AddCallerSkip(2), withLevelOverrides(zapcore.DebugLevel))
 	return &ZapLogger{z: l.Sugar()}
 }
 
-// NewProdLogger returns a zap logger that outputs JSON.
+// NewProdLogger returns a zap logger that outputs JSON. The level defaults to
+// info and can be changed at runtime with SetLevel.
 func NewProdLogger() Logger {
-	l, _ := zap.NewProduction(zap.AddCallerSkip(2))
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel) // Filtered by level overrides.
+	l, _ := cfg.Build(zap.AddCallerSkip(2), withLevelOverrides(zapcore.InfoLevel))
 	return &ZapLogger{z: l.Sugar()}
 }
 
@@ -0,0 +1,173 @@
+package prefab
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Default lifetime of verbose logging rules set via the admin service.
+const defaultVerboseDuration = 15 * time.Minute
+
+func registerLoggingConfigKeys() {
+	RegisterConfigKeys(
+		ConfigKeyInfo{
+			Key:         "logging.level",
+			Description: "Minimum log level for all loggers (debug, info, warn, or error), overrides the logger's default",
+			Type:        "string",
+		},
+		ConfigKeyInfo{
+			Key:         "logging.levels",
+			Description: "Map of logger name prefix to minimum log level, e.g. \"/billing.BillingService/\": debug",
+			Type:        "map",
+		},
+		ConfigKeyInfo{
+			Key:         "server.watchConfig",
+			Description: "Reload config files when they change and re-apply log levels",
+			Type:        "bool",
+			Default:     "false",
+		},
+	)
+}
+
+// WithConfigWatch reloads config files loaded at startup, or via
+// LoadConfigFile, when they change. Log levels from `logging.level` and
+// `logging.levels` are re-applied on each reload, other settings are only read
+// when the server is created.
+//
+// Config key: `server.watchConfig`.
+func WithConfigWatch(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.watchConfig = enabled
+	}
+}
+
+// Loggers whose level was last set from config, so that removed entries can be
+// reset on reload.
+var configLevels = struct {
+	sync.Mutex
+	loggers map[string]bool
+}{loggers: map[string]bool{}}
+
+// applyLogConfig sets log level overrides from `logging.level` and
+// `logging.levels`. Overrides set via the admin service are replaced if config
+// sets a level for the same logger.
+func applyLogConfig(ctx context.Context) {
+	levels := Config.StringMap("logging.levels")
+	if lvl := Config.String("logging.level"); lvl != "" {
+		levels[""] = lvl
+	}
+
+	configLevels.Lock()
+	defer configLevels.Unlock()
+	applied := map[string]bool{}
+	for logger, lvl := range levels {
+		level, err := zapcore.ParseLevel(lvl)
+		if err != nil {
+			logging.Errorw(ctx, "❌ Invalid log level in config", "logger", logger, "level", lvl)
+			continue
+		}
+		logging.SetLevel(logger, level, 0)
+		applied[logger] = true
+	}
+	for logger := range configLevels.loggers {
+		if !applied[logger] {
+			logging.ResetLevel(logger)
+		}
+	}
+	configLevels.loggers = applied
+}
+
+// watchConfigFiles reloads config files on change, until stopConfigWatch is
+// called.
+func (s *Server) watchConfigFiles() {
+	for _, path := range configFiles {
+		p := file.Provider(path)
+		err := p.Watch(func(_ any, err error) {
+			if err != nil {
+				logging.Errorw(s.baseContext, "❌ Config watch error", "path", path, "error", err)
+				return
+			}
+			if err := Config.Load(p, yaml.Parser()); err != nil {
+				logging.Errorw(s.baseContext, "❌ Error reloading config", "path", path, "error", err)
+				return
+			}
+			logging.Infow(s.baseContext, "🔄  Reloaded config", "path", path)
+			applyLogConfig(s.baseContext)
+		})
+		if err != nil {
+			logging.Errorw(s.baseContext, "❌ Unable to watch config", "path", path, "error", err)
+			continue
+		}
+		s.configWatchers = append(s.configWatchers, p)
+	}
+}
+
+func (s *Server) stopConfigWatch() {
+	for _, p := range s.configWatchers {
+		if err := p.Unwatch(); err != nil {
+			logging.Infof(s.baseContext, "❌ Config unwatch error: %v", err)
+		}
+	}
+	s.configWatchers = nil
+}
+
+func (a *adminService) GetLogSettings(context.Context, *GetLogSettingsRequest) (*LogSettings, error) {
+	return logSettings(), nil
+}
+
+func (a *adminService) SetLogLevel(_ context.Context, req *SetLogLevelRequest) (*LogSettings, error) {
+	if req.GetLevel() == "" {
+		logging.ResetLevel(req.GetLogger())
+		return logSettings(), nil
+	}
+	level, err := zapcore.ParseLevel(req.GetLevel())
+	if err != nil {
+		return nil, errors.Codef(codes.InvalidArgument, "prefab: invalid log level %q", req.GetLevel())
+	}
+	logging.SetLevel(req.GetLogger(), level, req.GetDuration().AsDuration())
+	return logSettings(), nil
+}
+
+func (a *adminService) SetVerboseLogging(_ context.Context, req *SetVerboseLoggingRequest) (*LogSettings, error) {
+	if req.GetMethod() == "" && req.GetSubject() == "" {
+		return nil, errors.Codef(codes.InvalidArgument, "prefab: method or subject is required")
+	}
+	if !req.GetEnabled() {
+		logging.DisableVerbose(req.GetMethod(), req.GetSubject())
+		return logSettings(), nil
+	}
+	ttl := req.GetDuration().AsDuration()
+	if ttl <= 0 {
+		ttl = defaultVerboseDuration
+	}
+	logging.EnableVerbose(req.GetMethod(), req.GetSubject(), ttl)
+	return logSettings(), nil
+}
+
+func logSettings() *LogSettings {
+	out := &LogSettings{}
+	for _, o := range logging.LevelOverrides() {
+		l := &LogLevelOverride{Logger: o.Logger, Level: o.Level.String()}
+		if !o.Expires.IsZero() {
+			l.ExpireTime = timestamppb.New(o.Expires)
+		}
+		out.Levels = append(out.Levels, l)
+	}
+	for _, r := range logging.VerboseRules() {
+		out.Verbose = append(out.Verbose, &VerboseLoggingRule{
+			Method:     r.Method,
+			Subject:    r.Subject,
+			ExpireTime: timestamppb.New(r.Expires),
+		})
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package prefab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestApplyLogConfig(t *testing.T) {
+	t.Cleanup(func() {
+		Config.Delete("logging")
+		configLevels.loggers = map[string]bool{}
+		logging.ResetLevels()
+	})
+
+	require.NoError(t, Config.Set("logging.level", "warn"))
+	require.NoError(t, Config.Set("logging.levels", map[string]any{"/billing.": "debug"}))
+	applyLogConfig(context.Background())
+	assert.Equal(t, []logging.LevelOverride{
+		{Logger: "", Level: zapcore.WarnLevel},
+		{Logger: "/billing.", Level: zapcore.DebugLevel},
+	}, logging.LevelOverrides())
+
+	// Removed loggers are reset.
+	Config.Delete("logging.levels")
+	applyLogConfig(context.Background())
+	assert.Equal(t, []logging.LevelOverride{
+		{Logger: "", Level: zapcore.WarnLevel},
+	}, logging.LevelOverrides())
+}
+
+func TestConfigWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: info\n"), 0o600))
+
+	orig := configFiles
+	t.Cleanup(func() {
+		configFiles = orig
+		Config.Delete("logging")
+		configLevels.loggers = map[string]bool{}
+		logging.ResetLevels()
+	})
+	configFiles = []string{path}
+
+	s := New(WithContext(context.Background()), WithConfigWatch(true))
+	s.watchConfigFiles()
+	defer s.stopConfigWatch()
+	require.Len(t, s.configWatchers, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: error\n"), 0o600))
+	assert.Eventually(t, func() bool {
+		o := logging.LevelOverrides()
+		return len(o) == 1 && o[0].Level == zapcore.ErrorLevel
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAdminService_LogSettings(t *testing.T) {
+	t.Cleanup(logging.ResetLevels)
+	a := &adminService{s: New(WithContext(context.Background()))}
+	ctx := t.Context()
+
+	_, err := a.SetLogLevel(ctx, &SetLogLevelRequest{Level: "loud"})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	settings, err := a.SetLogLevel(ctx, &SetLogLevelRequest{
+		Logger:   "/notes.",
+		Level:    "debug",
+		Duration: durationpb.New(time.Minute),
+	})
+	require.NoError(t, err)
+	require.Len(t, settings.GetLevels(), 1)
+	assert.Equal(t, "/notes.", settings.GetLevels()[0].GetLogger())
+	assert.Equal(t, "debug", settings.GetLevels()[0].GetLevel())
+	assert.NotNil(t, settings.GetLevels()[0].GetExpireTime())
+
+	settings, err = a.SetLogLevel(ctx, &SetLogLevelRequest{Logger: "/notes."})
+	require.NoError(t, err)
+	assert.Empty(t, settings.GetLevels(), "empty level removes the override")
+
+	_, err = a.SetVerboseLogging(ctx, &SetVerboseLoggingRequest{Enabled: true})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	settings, err = a.SetVerboseLogging(ctx, &SetVerboseLoggingRequest{Subject: "bob", Enabled: true})
+	require.NoError(t, err)
+	require.Len(t, settings.GetVerbose(), 1)
+	assert.Equal(t, "bob", settings.GetVerbose()[0].GetSubject())
+	expires := settings.GetVerbose()[0].GetExpireTime().AsTime()
+	assert.WithinDuration(t, time.Now().Add(defaultVerboseDuration), expires, time.Minute)
+
+	settings, err = a.GetLogSettings(ctx, &GetLogSettingsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, settings.GetVerbose(), 1)
+
+	settings, err = a.SetVerboseLogging(ctx, &SetVerboseLoggingRequest{Subject: "bob"})
+	require.NoError(t, err)
+	assert.Empty(t, settings.GetVerbose())
+}
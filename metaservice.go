@@ -2,18 +2,188 @@ package prefab
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// ClientConfigFunc returns client config values which are computed at request
+// time, for example feature flags for the authenticated user. Keys and values
+// are as for WithClientConfigValue, and override values configured statically.
+type ClientConfigFunc func(ctx context.Context) (map[string]any, error)
+
 // Implements MetaServiceServer.
 type meta struct {
 	UnimplementedMetaServiceServer
-	configs        map[string]string
+	configs        map[string]*structpb.Value
+	configFuncs    []ClientConfigFunc
 	csrfSigningKey []byte
+	versions       *apiVersions
 }
 
 func (s *meta) ClientConfig(ctx context.Context, in *ClientConfigRequest) (*ClientConfigResponse, error) {
-	return &ClientConfigResponse{
+	values := maps.Clone(s.configs)
+	version := APIVersion(ctx)
+	if s.versions != nil {
+		for key, v := range s.versions.clientConfigs(version) {
+			if values == nil {
+				values = map[string]*structpb.Value{}
+			}
+			values[key] = v
+		}
+	}
+	for _, fn := range s.configFuncs {
+		overrides, err := fn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for key, v := range overrides {
+			pv, err := clientConfigValue(key, v)
+			if err != nil {
+				return nil, errors.WithCode(err, codes.Internal)
+			}
+			if values == nil {
+				values = map[string]*structpb.Value{}
+			}
+			values[key] = pv
+		}
+	}
+
+	nested, err := nestClientConfig(values)
+	if err != nil {
+		return nil, errors.WithCode(err, codes.Internal)
+	}
+	resp := &ClientConfigResponse{
 		CsrfToken: SendCSRFToken(ctx, s.csrfSigningKey),
-		Configs:   s.configs,
-	}, nil
+		Configs:   flattenClientConfig(values),
+		Values:    nested,
+	}
+	if s.versions != nil {
+		resp.ApiVersion = version
+		resp.ApiVersions = s.versions.describe()
+	}
+
+	// The tag covers the CSRF token, which is stable while the client's cookie
+	// is valid.
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(resp)
+	if err != nil {
+		return nil, errors.WithCode(err, codes.Internal)
+	}
+	sum := sha256.Sum256(b)
+	tag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+	if err := serverutil.SendHeader(ctx, "etag", tag); err != nil {
+		return nil, err
+	}
+	if etagMatches(serverutil.HTTPHeader(ctx, "if-none-match"), tag) {
+		if err := serverutil.SendStatusCode(ctx, http.StatusNotModified); err != nil {
+			return nil, err
+		}
+		return &ClientConfigResponse{}, nil
+	}
+	return resp, nil
+}
+
+func (s *meta) ErrorCatalog(ctx context.Context, in *ErrorCatalogRequest) (*ErrorCatalogResponse, error) {
+	entries := errors.Catalog()
+	resp := &ErrorCatalogResponse{Errors: make([]*CatalogError, 0, len(entries))}
+	for _, e := range entries {
+		status := e.HTTPStatus
+		if status == 0 {
+			status = errors.NewC("", e.Code).HTTPStatusCode()
+		}
+		resp.Errors = append(resp.Errors, &CatalogError{
+			Domain:     e.Domain,
+			Reason:     e.Reason,
+			Code:       e.Code.String(),
+			HttpStatus: int32(status), //nolint:gosec // HTTP statuses fit in an int32.
+			Message:    serverutil.Localize(ctx, e.Message),
+		})
+	}
+	return resp, nil
+}
+
+// clientConfigValue converts a value to its JSON representation.
+func clientConfigValue(key string, v any) (*structpb.Value, error) {
+	if pv, ok := v.(*structpb.Value); ok {
+		return pv, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Errorf("prefab: client config %q isn't JSON encodable: %v", key, err)
+	}
+	pv := &structpb.Value{}
+	if err := pv.UnmarshalJSON(b); err != nil {
+		return nil, errors.Errorf("prefab: client config %q isn't JSON encodable: %v", key, err)
+	}
+	return pv, nil
+}
+
+// nestClientConfig nests values by the dotted namespaces of their keys. Values
+// for keys within a namespace that's itself a value, e.g. `features.search`
+// when `features` is an object, are merged into it.
+func nestClientConfig(values map[string]*structpb.Value) (*structpb.Struct, error) {
+	root := &structpb.Struct{Fields: map[string]*structpb.Value{}}
+	// Sorting ensures namespaces are set before the keys within them.
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		fields := root.GetFields()
+		parts := strings.Split(key, ".")
+		for i, part := range parts[:len(parts)-1] {
+			v, ok := fields[part]
+			if !ok {
+				v = structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{}})
+				fields[part] = v
+			}
+			if v.GetStructValue() == nil {
+				return nil, errors.Errorf("prefab: client config %q conflicts with %q", key, strings.Join(parts[:i+1], "."))
+			}
+			fields = v.GetStructValue().GetFields()
+		}
+		// Values are cloned as they may be modified when merging namespaces.
+		fields[parts[len(parts)-1]] = proto.Clone(values[key]).(*structpb.Value)
+	}
+	return root, nil
+}
+
+// flattenClientConfig returns values as strings, for the `configs` field. Values
+// other than strings are JSON encoded.
+func flattenClientConfig(values map[string]*structpb.Value) map[string]string {
+	configs := make(map[string]string, len(values))
+	for key, v := range values {
+		if s, ok := v.GetKind().(*structpb.Value_StringValue); ok {
+			configs[key] = s.StringValue
+			continue
+		}
+		b, _ := json.Marshal(v.AsInterface())
+		configs[key] = string(b)
+	}
+	return configs
+}
+
+// etagMatches reports whether an If-None-Match header matches tag, using weak
+// comparison.
+func etagMatches(ifNoneMatch, tag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if strings.Trim(candidate, `"`) == want {
+			return true
+		}
+	}
+	return false
 }
@@ -10,6 +10,7 @@ import (
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -64,11 +65,20 @@ func (*ClientConfigRequest) Descriptor() ([]byte, []int) {
 type ClientConfigResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// A map of key-value pairs configured by available plugins, for example
-	// auth.google.client_id.
+	// auth.google.client_id. Values which aren't strings are JSON encoded.
 	Configs map[string]string `protobuf:"bytes,1,rep,name=configs,proto3" json:"configs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
 	// Token that should be used in non-XHR requests to avoid cross-site request
 	// forgery attacks.
-	CsrfToken     string `protobuf:"bytes,2,opt,name=csrf_token,json=csrfToken,proto3" json:"csrf_token,omitempty"`
+	CsrfToken string `protobuf:"bytes,2,opt,name=csrf_token,json=csrfToken,proto3" json:"csrf_token,omitempty"`
+	// Typed config values, nested by the dotted namespaces of their keys. For
+	// example, `auth.google.clientId` is at `values.auth.google.clientId`.
+	Values *structpb.Struct `protobuf:"bytes,3,opt,name=values,proto3" json:"values,omitempty"`
+	// Version of the API negotiated for the request, from the Accept header or
+	// the server's default. Values configured for the version are included in
+	// `configs` and `values`.
+	ApiVersion string `protobuf:"bytes,4,opt,name=api_version,json=apiVersion,proto3" json:"api_version,omitempty"`
+	// Versions of the API which the server supports.
+	ApiVersions   []*ApiVersion `protobuf:"bytes,5,rep,name=api_versions,json=apiVersions,proto3" json:"api_versions,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -117,21 +127,291 @@ func (x *ClientConfigResponse) GetCsrfToken() string {
 	return ""
 }
 
+func (x *ClientConfigResponse) GetValues() *structpb.Struct {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *ClientConfigResponse) GetApiVersion() string {
+	if x != nil {
+		return x.ApiVersion
+	}
+	return ""
+}
+
+func (x *ClientConfigResponse) GetApiVersions() []*ApiVersion {
+	if x != nil {
+		return x.ApiVersions
+	}
+	return nil
+}
+
+// A version of the API, see prefab.WithAPIVersion.
+type ApiVersion struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the version, e.g. "v2".
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Whether the version is used when clients don't request one.
+	Default bool `protobuf:"varint,2,opt,name=default,proto3" json:"default,omitempty"`
+	// Date after which the version may be removed, as YYYY-MM-DD. Empty if no
+	// removal is scheduled.
+	Sunset        string `protobuf:"bytes,3,opt,name=sunset,proto3" json:"sunset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApiVersion) Reset() {
+	*x = ApiVersion{}
+	mi := &file_metaservice_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApiVersion) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApiVersion) ProtoMessage() {}
+
+func (x *ApiVersion) ProtoReflect() protoreflect.Message {
+	mi := &file_metaservice_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApiVersion.ProtoReflect.Descriptor instead.
+func (*ApiVersion) Descriptor() ([]byte, []int) {
+	return file_metaservice_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ApiVersion) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ApiVersion) GetDefault() bool {
+	if x != nil {
+		return x.Default
+	}
+	return false
+}
+
+func (x *ApiVersion) GetSunset() string {
+	if x != nil {
+		return x.Sunset
+	}
+	return ""
+}
+
+// Empty request object.
+type ErrorCatalogRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorCatalogRequest) Reset() {
+	*x = ErrorCatalogRequest{}
+	mi := &file_metaservice_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorCatalogRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorCatalogRequest) ProtoMessage() {}
+
+func (x *ErrorCatalogRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_metaservice_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorCatalogRequest.ProtoReflect.Descriptor instead.
+func (*ErrorCatalogRequest) Descriptor() ([]byte, []int) {
+	return file_metaservice_proto_rawDescGZIP(), []int{3}
+}
+
+type ErrorCatalogResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Errors        []*CatalogError        `protobuf:"bytes,1,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorCatalogResponse) Reset() {
+	*x = ErrorCatalogResponse{}
+	mi := &file_metaservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorCatalogResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorCatalogResponse) ProtoMessage() {}
+
+func (x *ErrorCatalogResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_metaservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorCatalogResponse.ProtoReflect.Descriptor instead.
+func (*ErrorCatalogResponse) Descriptor() ([]byte, []int) {
+	return file_metaservice_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ErrorCatalogResponse) GetErrors() []*CatalogError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+// An error declared in an error catalog, see ErrorSpec.
+type CatalogError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Full name of the enum which declares the error, e.g. "notes.NoteError".
+	Domain string `protobuf:"bytes,1,opt,name=domain,proto3" json:"domain,omitempty"`
+	// Name of the enum value, e.g. "NOTE_NOT_FOUND".
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	// GRPC status code name, e.g. "NotFound".
+	Code string `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"`
+	// HTTP status returned with the error.
+	HttpStatus int32 `protobuf:"varint,4,opt,name=http_status,json=httpStatus,proto3" json:"http_status,omitempty"`
+	// Message template, with fmt verbs for the error's arguments. Translated
+	// into the request's locale if the locale plugin has a localizer.
+	Message       string `protobuf:"bytes,5,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CatalogError) Reset() {
+	*x = CatalogError{}
+	mi := &file_metaservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CatalogError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CatalogError) ProtoMessage() {}
+
+func (x *CatalogError) ProtoReflect() protoreflect.Message {
+	mi := &file_metaservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CatalogError.ProtoReflect.Descriptor instead.
+func (*CatalogError) Descriptor() ([]byte, []int) {
+	return file_metaservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CatalogError) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *CatalogError) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CatalogError) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *CatalogError) GetHttpStatus() int32 {
+	if x != nil {
+		return x.HttpStatus
+	}
+	return 0
+}
+
+func (x *CatalogError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 var File_metaservice_proto protoreflect.FileDescriptor
 
 const file_metaservice_proto_rawDesc = "" +
 	"\n" +
-	"\x11metaservice.proto\x12\x06prefab\x1a\x1cgoogle/api/annotations.proto\x1a\fserver.proto\"\x15\n" +
-	"\x13ClientConfigRequest\"\xb6\x01\n" +
+	"\x11metaservice.proto\x12\x06prefab\x1a\x1cgoogle/api/annotations.proto\x1a\x1cgoogle/protobuf/struct.proto\x1a\fserver.proto\"\x15\n" +
+	"\x13ClientConfigRequest\"\xbf\x02\n" +
 	"\x14ClientConfigResponse\x12C\n" +
 	"\aconfigs\x18\x01 \x03(\v2).prefab.ClientConfigResponse.ConfigsEntryR\aconfigs\x12\x1d\n" +
 	"\n" +
-	"csrf_token\x18\x02 \x01(\tR\tcsrfToken\x1a:\n" +
+	"csrf_token\x18\x02 \x01(\tR\tcsrfToken\x12/\n" +
+	"\x06values\x18\x03 \x01(\v2\x17.google.protobuf.StructR\x06values\x12\x1f\n" +
+	"\vapi_version\x18\x04 \x01(\tR\n" +
+	"apiVersion\x125\n" +
+	"\fapi_versions\x18\x05 \x03(\v2\x12.prefab.ApiVersionR\vapiVersions\x1a:\n" +
 	"\fConfigsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012y\n" +
-	"\vMetaService\x12j\n" +
-	"\fClientConfig\x12\x1b.prefab.ClientConfigRequest\x1a\x1c.prefab.ClientConfigResponse\"\x1f\x8a\xb5\x18\x03off\x82\xd3\xe4\x93\x02\x12\x12\x10/api/meta/configB\x18Z\x16github.com/dpup/prefabb\x06proto3"
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"R\n" +
+	"\n" +
+	"ApiVersion\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x18\n" +
+	"\adefault\x18\x02 \x01(\bR\adefault\x12\x16\n" +
+	"\x06sunset\x18\x03 \x01(\tR\x06sunset\"\x15\n" +
+	"\x13ErrorCatalogRequest\"D\n" +
+	"\x14ErrorCatalogResponse\x12,\n" +
+	"\x06errors\x18\x01 \x03(\v2\x14.prefab.CatalogErrorR\x06errors\"\x8d\x01\n" +
+	"\fCatalogError\x12\x16\n" +
+	"\x06domain\x18\x01 \x01(\tR\x06domain\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x1f\n" +
+	"\vhttp_status\x18\x04 \x01(\x05R\n" +
+	"httpStatus\x12\x18\n" +
+	"\amessage\x18\x05 \x01(\tR\amessage2\xab\x02\n" +
+	"\vMetaService\x12\x7f\n" +
+	"\fClientConfig\x12\x1b.prefab.ClientConfigRequest\x1a\x1c.prefab.ClientConfigResponse\"4\x8a\xb5\x18\x03off\x92\xb5\x18\x11private, no-cache\x82\xd3\xe4\x93\x02\x12\x12\x10/api/meta/config\x12\x9a\x01\n" +
+	"\fErrorCatalog\x12\x1b.prefab.ErrorCatalogRequest\x1a\x1c.prefab.ErrorCatalogResponse\"O\x8a\xb5\x18\x03off\x92\xb5\x18\x13public, max-age=300\x9a\xb5\x18\x15Vary: Accept-Language\x82\xd3\xe4\x93\x02\x12\x12\x10/api/meta/errorsB\x18Z\x16github.com/dpup/prefabb\x06proto3"
 
 var (
 	file_metaservice_proto_rawDescOnce sync.Once
@@ -145,21 +425,31 @@ func file_metaservice_proto_rawDescGZIP() []byte {
 	return file_metaservice_proto_rawDescData
 }
 
-var file_metaservice_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_metaservice_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_metaservice_proto_goTypes = []any{
 	(*ClientConfigRequest)(nil),  // 0: prefab.ClientConfigRequest
 	(*ClientConfigResponse)(nil), // 1: prefab.ClientConfigResponse
-	nil,                          // 2: prefab.ClientConfigResponse.ConfigsEntry
+	(*ApiVersion)(nil),           // 2: prefab.ApiVersion
+	(*ErrorCatalogRequest)(nil),  // 3: prefab.ErrorCatalogRequest
+	(*ErrorCatalogResponse)(nil), // 4: prefab.ErrorCatalogResponse
+	(*CatalogError)(nil),         // 5: prefab.CatalogError
+	nil,                          // 6: prefab.ClientConfigResponse.ConfigsEntry
+	(*structpb.Struct)(nil),      // 7: google.protobuf.Struct
 }
 var file_metaservice_proto_depIdxs = []int32{
-	2, // 0: prefab.ClientConfigResponse.configs:type_name -> prefab.ClientConfigResponse.ConfigsEntry
-	0, // 1: prefab.MetaService.ClientConfig:input_type -> prefab.ClientConfigRequest
-	1, // 2: prefab.MetaService.ClientConfig:output_type -> prefab.ClientConfigResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6, // 0: prefab.ClientConfigResponse.configs:type_name -> prefab.ClientConfigResponse.ConfigsEntry
+	7, // 1: prefab.ClientConfigResponse.values:type_name -> google.protobuf.Struct
+	2, // 2: prefab.ClientConfigResponse.api_versions:type_name -> prefab.ApiVersion
+	5, // 3: prefab.ErrorCatalogResponse.errors:type_name -> prefab.CatalogError
+	0, // 4: prefab.MetaService.ClientConfig:input_type -> prefab.ClientConfigRequest
+	3, // 5: prefab.MetaService.ErrorCatalog:input_type -> prefab.ErrorCatalogRequest
+	1, // 6: prefab.MetaService.ClientConfig:output_type -> prefab.ClientConfigResponse
+	4, // 7: prefab.MetaService.ErrorCatalog:output_type -> prefab.ErrorCatalogResponse
+	6, // [6:8] is the sub-list for method output_type
+	4, // [4:6] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_metaservice_proto_init() }
@@ -174,7 +464,7 @@ func file_metaservice_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_metaservice_proto_rawDesc), len(file_metaservice_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	MetaService_ClientConfig_FullMethodName = "/prefab.MetaService/ClientConfig"
+	MetaService_ErrorCatalog_FullMethodName = "/prefab.MetaService/ErrorCatalog"
 )
 
 // MetaServiceClient is the client API for MetaService service.
@@ -29,7 +30,14 @@ type MetaServiceClient interface {
 	// ClientConfig returns configuration information that is required for clients
 	// to interact with the server in various ways. All data is safe to be served
 	// to unauthenticatd clients.
+	//
+	// Responses carry an ETag, clients which send it in `If-None-Match` receive a
+	// 304 Not Modified if the config hasn't changed.
 	ClientConfig(ctx context.Context, in *ClientConfigRequest, opts ...grpc.CallOption) (*ClientConfigResponse, error)
+	// ErrorCatalog returns the errors declared in error catalogs, so clients can
+	// show consistent messages for the errors they receive. Errors created from
+	// a catalog carry an ErrorInfo detail with the entry's domain and reason.
+	ErrorCatalog(ctx context.Context, in *ErrorCatalogRequest, opts ...grpc.CallOption) (*ErrorCatalogResponse, error)
 }
 
 type metaServiceClient struct {
@@ -50,6 +58,16 @@ func (c *metaServiceClient) ClientConfig(ctx context.Context, in *ClientConfigRe
 	return out, nil
 }
 
+func (c *metaServiceClient) ErrorCatalog(ctx context.Context, in *ErrorCatalogRequest, opts ...grpc.CallOption) (*ErrorCatalogResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ErrorCatalogResponse)
+	err := c.cc.Invoke(ctx, MetaService_ErrorCatalog_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetaServiceServer is the server API for MetaService service.
 // All implementations must embed UnimplementedMetaServiceServer
 // for forward compatibility.
@@ -57,7 +75,14 @@ type MetaServiceServer interface {
 	// ClientConfig returns configuration information that is required for clients
 	// to interact with the server in various ways. All data is safe to be served
 	// to unauthenticatd clients.
+	//
+	// Responses carry an ETag, clients which send it in `If-None-Match` receive a
+	// 304 Not Modified if the config hasn't changed.
 	ClientConfig(context.Context, *ClientConfigRequest) (*ClientConfigResponse, error)
+	// ErrorCatalog returns the errors declared in error catalogs, so clients can
+	// show consistent messages for the errors they receive. Errors created from
+	// a catalog carry an ErrorInfo detail with the entry's domain and reason.
+	ErrorCatalog(context.Context, *ErrorCatalogRequest) (*ErrorCatalogResponse, error)
 	mustEmbedUnimplementedMetaServiceServer()
 }
 
@@ -71,6 +96,9 @@ type UnimplementedMetaServiceServer struct{}
 func (UnimplementedMetaServiceServer) ClientConfig(context.Context, *ClientConfigRequest) (*ClientConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ClientConfig not implemented")
 }
+func (UnimplementedMetaServiceServer) ErrorCatalog(context.Context, *ErrorCatalogRequest) (*ErrorCatalogResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ErrorCatalog not implemented")
+}
 func (UnimplementedMetaServiceServer) mustEmbedUnimplementedMetaServiceServer() {}
 func (UnimplementedMetaServiceServer) testEmbeddedByValue()                     {}
 
@@ -110,6 +138,24 @@ func _MetaService_ClientConfig_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _MetaService_ErrorCatalog_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ErrorCatalogRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetaServiceServer).ErrorCatalog(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MetaService_ErrorCatalog_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetaServiceServer).ErrorCatalog(ctx, req.(*ErrorCatalogRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // MetaService_ServiceDesc is the grpc.ServiceDesc for MetaService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -121,6 +167,10 @@ var MetaService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ClientConfig",
 			Handler:    _MetaService_ClientConfig_Handler,
 		},
+		{
+			MethodName: "ErrorCatalog",
+			Handler:    _MetaService_ErrorCatalog_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "metaservice.proto",
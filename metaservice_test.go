@@ -0,0 +1,182 @@
+package prefab
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestNestClientConfig(t *testing.T) {
+	values := map[string]*structpb.Value{}
+	for k, v := range map[string]any{
+		"auth.google.clientId": "abc",
+		"features":             map[string]any{"search": true},
+		"features.beta":        false,
+		"limits.uploadMB":      10,
+		"regions":              []string{"us", "eu"},
+	} {
+		pv, err := clientConfigValue(k, v)
+		require.NoError(t, err)
+		values[k] = pv
+	}
+
+	nested, err := nestClientConfig(values)
+	require.NoError(t, err)
+	b, err := protojson.Marshal(nested)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"auth": {"google": {"clientId": "abc"}},
+		"features": {"search": true, "beta": false},
+		"limits": {"uploadMB": 10},
+		"regions": ["us", "eu"]
+	}`, string(b))
+	assert.Len(t, values["features"].GetStructValue().GetFields(), 1, "values aren't modified when merged")
+
+	assert.Equal(t, map[string]string{
+		"auth.google.clientId": "abc",
+		"features":             `{"search":true}`,
+		"features.beta":        "false",
+		"limits.uploadMB":      "10",
+		"regions":              `["us","eu"]`,
+	}, flattenClientConfig(values))
+}
+
+func TestNestClientConfig_Conflict(t *testing.T) {
+	_, err := nestClientConfig(map[string]*structpb.Value{
+		"features":        structpb.NewBoolValue(true),
+		"features.search": structpb.NewBoolValue(true),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `client config "features.search" conflicts with "features"`)
+
+	assert.Panics(t, func() {
+		New(WithClientConfig("features", "on"), WithClientConfigValue("features.search", true))
+	})
+}
+
+func TestWithClientConfigValue_NotJSON(t *testing.T) {
+	assert.Panics(t, func() { WithClientConfigValue("callback", func() {}) })
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.False(t, etagMatches("", `W/"abc"`))
+	assert.True(t, etagMatches(`W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"xyz", W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches("*", `W/"abc"`))
+	assert.False(t, etagMatches(`W/"xyz"`, `W/"abc"`))
+}
+
+func TestClientConfig(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := New(
+		WithListener(ln),
+		WithIncomingHeaders("x-plan"),
+		WithClientConfig("auth.google.clientId", "abc"),
+		WithClientConfigValue("features.search", false),
+		WithClientConfigFunc(func(ctx context.Context) (map[string]any, error) {
+			if serverutil.HTTPHeader(ctx, "x-plan") == "pro" {
+				return map[string]any{"features.search": true}, nil
+			}
+			return nil, nil
+		}),
+	)
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := &http.Client{Jar: jar, Transport: &http.Transport{}}
+	get := func(plan, etag string) (*http.Response, map[string]any) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+ln.Addr().String()+"/api/meta/config", nil)
+		require.NoError(t, err)
+		if plan != "" {
+			req.Header.Set("X-Plan", plan)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		if len(b) > 0 {
+			require.NoError(t, json.Unmarshal(b, &body))
+		}
+		return resp, body
+	}
+	require.Eventually(t, func() bool { return s.Ready() }, time.Second, 10*time.Millisecond)
+
+	resp, body := get("", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "private, no-cache", resp.Header.Get("Cache-Control"))
+	assert.Equal(t, map[string]any{
+		"auth":     map[string]any{"google": map[string]any{"clientId": "abc"}},
+		"features": map[string]any{"search": false},
+	}, body["values"])
+	assert.Equal(t, map[string]any{"auth.google.clientId": "abc", "features.search": "false"}, body["configs"])
+	etag := resp.Header.Get("Etag")
+	require.NotEmpty(t, etag)
+
+	// The config is unchanged, so the cached copy is current.
+	resp, body = get("", etag)
+	assert.Equal(t, http.StatusNotModified, resp.StatusCode)
+	assert.Empty(t, body)
+
+	// Request time values override static ones, and change the tag.
+	resp, body = get("pro", etag)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, map[string]any{"search": true}, body["values"].(map[string]any)["features"])
+	assert.NotEqual(t, etag, resp.Header.Get("Etag"))
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Shutdown())
+	require.NoError(t, <-started)
+}
+
+func TestErrorCatalog(t *testing.T) {
+	errors.RegisterCatalog(
+		errors.CatalogEntry{Domain: "test.MetaError", Reason: "LOCKED", Number: 1, Code: codes.FailedPrecondition, Message: "Note is locked"},
+		errors.CatalogEntry{Domain: "test.MetaError", Reason: "GONE", Number: 2, Code: codes.NotFound, HTTPStatus: http.StatusGone, Message: "Note is gone"},
+	)
+	ctx := serverutil.WithLocale(t.Context(), "de")
+	ctx = serverutil.WithLocalizer(ctx, func(locale, msg string) string {
+		if msg == "Note is locked" {
+			return "Notiz ist gesperrt"
+		}
+		return ""
+	})
+
+	resp, err := (&meta{}).ErrorCatalog(ctx, &ErrorCatalogRequest{})
+	require.NoError(t, err)
+	var got []*CatalogError
+	for _, e := range resp.GetErrors() {
+		if e.GetDomain() == "test.MetaError" {
+			got = append(got, e)
+		}
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "LOCKED", got[0].GetReason())
+	assert.Equal(t, "FailedPrecondition", got[0].GetCode())
+	assert.Equal(t, int32(http.StatusPreconditionFailed), got[0].GetHttpStatus(), "derived from the code")
+	assert.Equal(t, "Notiz ist gesperrt", got[0].GetMessage())
+	assert.Equal(t, int32(http.StatusGone), got[1].GetHttpStatus())
+	assert.Equal(t, "Note is gone", got[1].GetMessage())
+}
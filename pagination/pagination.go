@@ -0,0 +1,263 @@
+// Package pagination provides helpers for implementing List RPCs that follow
+// AIP-158 (https://google.aip.dev/158).
+//
+// Page tokens are opaque, signed cursors. They are bound to the request they
+// were issued for, so a token can't be reused with different filters, and
+// can't be forged to skip over records.
+//
+// Example:
+//
+//	func (s *server) ListNotes(ctx context.Context, req *pb.ListNotesRequest) (*pb.ListNotesResponse, error) {
+//	    notes, next, err := pagination.List(ctx, s.store, req, &Note{Owner: req.Owner})
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return &pb.ListNotesResponse{Notes: toProto(notes), NextPageToken: next}, nil
+//	}
+package pagination
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "pagination.defaultPageSize",
+			Description: "Page size used when a list request doesn't specify one",
+			Type:        "int",
+			Default:     defaultPageSize,
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "pagination.maxPageSize",
+			Description: "Maximum page size, larger requests are coerced to this value",
+			Type:        "int",
+			Default:     maxPageSize,
+		},
+	)
+}
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+var (
+	// ErrInvalidPageToken is returned when a page token is malformed, was issued
+	// by another server, or was issued for a request with different parameters.
+	ErrInvalidPageToken = errors.NewC("invalid page token", codes.InvalidArgument)
+
+	// ErrInvalidPageSize is returned when the requested page size is negative.
+	ErrInvalidPageSize = errors.NewC("page size must not be negative", codes.InvalidArgument)
+)
+
+// Request is implemented by AIP-158 list requests, which have `page_size` and
+// `page_token` fields.
+type Request interface {
+	GetPageSize() int32
+	GetPageToken() string
+}
+
+// PageSize returns the number of results to return for req. Unspecified page
+// sizes use `pagination.defaultPageSize` and large sizes are coerced to
+// `pagination.maxPageSize`.
+func PageSize(req Request) (int, error) {
+	size := int(req.GetPageSize())
+	limit := prefab.ConfigInt("pagination.maxPageSize")
+	if limit <= 0 {
+		limit = maxPageSize
+	}
+	switch {
+	case size < 0:
+		return 0, ErrInvalidPageSize
+	case size == 0:
+		size = prefab.ConfigInt("pagination.defaultPageSize")
+		if size <= 0 {
+			size = defaultPageSize
+		}
+	}
+	return min(size, limit), nil
+}
+
+// token is the signed content of a page token.
+type token struct {
+	Cursor      string `json:"c"`
+	Fingerprint string `json:"f,omitempty"`
+}
+
+// EncodeToken returns a page token that resumes listing after cursor. The
+// token is only valid for requests that match req, other than page size.
+func EncodeToken(ctx context.Context, req Request, cursor string) (string, error) {
+	payload, err := json.Marshal(&token{Cursor: cursor, Fingerprint: fingerprint(req)})
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	return enc + "." + signature(keys(ctx)[0], enc), nil
+}
+
+// DecodeToken returns the cursor encoded in the request's page token, or an
+// empty string if this is a request for the first page.
+func DecodeToken(ctx context.Context, req Request) (string, error) {
+	raw := req.GetPageToken()
+	if raw == "" {
+		return "", nil
+	}
+	enc, sig, ok := strings.Cut(raw, ".")
+	if !ok {
+		return "", ErrInvalidPageToken
+	}
+	valid := false
+	for _, key := range keys(ctx) {
+		if len(key) > 0 && hmac.Equal([]byte(sig), []byte(signature(key, enc))) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrInvalidPageToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return "", ErrInvalidPageToken
+	}
+	t := &token{}
+	if err := json.Unmarshal(payload, t); err != nil || t.Fingerprint != fingerprint(req) {
+		return "", ErrInvalidPageToken
+	}
+	return t.Cursor, nil
+}
+
+// Slice returns the page of items requested by req, along with the token for
+// the next page, which is empty on the last page. Items are ordered by key,
+// which must be unique.
+func Slice[T any](ctx context.Context, req Request, items []T, key func(T) string) ([]T, string, error) {
+	size, err := PageSize(req)
+	if err != nil {
+		return nil, "", err
+	}
+	cursor, err := DecodeToken(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sorted := slices.Clone(items)
+	slices.SortFunc(sorted, func(a, b T) int { return cmp.Compare(key(a), key(b)) })
+
+	start := 0
+	if cursor != "" {
+		start, _ = slices.BinarySearchFunc(sorted, cursor, func(item T, c string) int {
+			if key(item) <= c {
+				return -1
+			}
+			return 1
+		})
+	}
+	end := min(start+size, len(sorted))
+	page := sorted[start:end]
+
+	if end == len(sorted) {
+		return page, "", nil
+	}
+	next, err := EncodeToken(ctx, req, key(page[len(page)-1]))
+	if err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+// List returns the page of models matching filter requested by req, along with
+// the token for the next page. Models are ordered by primary key, so records
+// created or deleted between requests don't cause results to be skipped or
+// repeated.
+//
+// Only the requested page is loaded from stores that implement
+// storage.PageLister, which the built-in stores do. Other stores load every
+// matching record on each request, so should only be used with small tables.
+func List[T storage.Model](ctx context.Context, store storage.Store, req Request, filter T) ([]T, string, error) {
+	size, err := PageSize(req)
+	if err != nil {
+		return nil, "", err
+	}
+	cursor, err := DecodeToken(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Fetch an extra record to find out whether there is another page.
+	var page []T
+	if err := storage.ListPage(ctx, store, &page, filter, cursor, size+1); err != nil {
+		return nil, "", err
+	}
+	if len(page) <= size {
+		return page, "", nil
+	}
+	page = page[:size]
+	next, err := EncodeToken(ctx, req, page[size-1].PK())
+	if err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+// fingerprint hashes the fields of a proto request, other than the page size
+// and token, so that tokens can't be used with a different request.
+func fingerprint(req Request) string {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return ""
+	}
+	clone := proto.Clone(msg).ProtoReflect()
+	for _, name := range []protoreflect.Name{"page_size", "page_token"} {
+		if fd := clone.Descriptor().Fields().ByName(name); fd != nil {
+			clone.Clear(fd)
+		}
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(clone.Interface())
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(append([]byte(clone.Descriptor().FullName()+":"), b...))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// keys returns the server's signing keys, falling back to a per-process key
+// when none are configured.
+func keys(ctx context.Context) [][]byte {
+	if k := serverutil.SigningKeys(ctx); len(k) > 0 && len(k[0]) > 0 {
+		return k
+	}
+	return [][]byte{fallbackKey}
+}
+
+var fallbackKey = func() []byte {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return b
+}()
+
+// signature derives a purpose specific key, so that page tokens can't be
+// confused with other values signed by the same server key.
+func signature(key []byte, payload string) string {
+	derived := hmac.New(sha256.New, key)
+	derived.Write([]byte("prefab-page-token"))
+	mac := hmac.New(sha256.New, derived.Sum(nil))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package pagination
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/pagination/paginationtest"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type thing struct {
+	ID    string
+	Color string
+}
+
+func (t thing) PK() string { return t.ID }
+
+func TestPageSize(t *testing.T) {
+	tests := []struct {
+		requested int32
+		want      int
+		wantErr   bool
+	}{
+		{0, defaultPageSize, false},
+		{10, 10, false},
+		{5000, maxPageSize, false},
+		{-1, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.requested), func(t *testing.T) {
+			got, err := PageSize(&paginationtest.ListThingsRequest{PageSize: tt.requested})
+			if tt.wantErr {
+				assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestList_AllPages(t *testing.T) {
+	ctx := serverutil.WithSigningKeys(t.Context(), []byte("secret"))
+	store := memstore.New()
+	for i := range 7 {
+		require.NoError(t, store.Create(ctx, &thing{ID: fmt.Sprintf("%02d", i), Color: "red"}))
+	}
+	require.NoError(t, store.Create(ctx, &thing{ID: "99", Color: "blue"}))
+
+	req := &paginationtest.ListThingsRequest{PageSize: 3, Filter: "red"}
+	var ids []string
+	pages := 0
+	for {
+		items, next, err := List(ctx, store, req, thing{Color: "red"})
+		require.NoError(t, err)
+		pages++
+		for _, it := range items {
+			ids = append(ids, it.ID)
+		}
+		if next == "" {
+			break
+		}
+		req.PageToken = next
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"00", "01", "02", "03", "04", "05", "06"}, ids)
+}
+
+func TestList_StableAcrossInserts(t *testing.T) {
+	ctx := serverutil.WithSigningKeys(t.Context(), []byte("secret"))
+	store := memstore.New()
+	require.NoError(t, store.Create(ctx, &thing{ID: "b"}, &thing{ID: "d"}, &thing{ID: "f"}))
+
+	req := &paginationtest.ListThingsRequest{PageSize: 2}
+	items, next, err := List(ctx, store, req, thing{})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	// A record inserted before the cursor isn't returned, and nothing is
+	// repeated.
+	require.NoError(t, store.Create(ctx, &thing{ID: "a"}, &thing{ID: "e"}))
+
+	req.PageToken = next
+	items, next, err = List(ctx, store, req, thing{})
+	require.NoError(t, err)
+	assert.Equal(t, []thing{{ID: "e"}, {ID: "f"}}, items)
+	assert.Empty(t, next)
+}
+
+// listOnlyStore hides the store's optional interfaces.
+type listOnlyStore struct {
+	storage.Store
+}
+
+func TestList_WithoutPageLister(t *testing.T) {
+	ctx := serverutil.WithSigningKeys(t.Context(), []byte("secret"))
+	store := memstore.New()
+	for i := range 5 {
+		require.NoError(t, store.Create(ctx, &thing{ID: fmt.Sprintf("%02d", i)}))
+	}
+
+	req := &paginationtest.ListThingsRequest{PageSize: 3}
+	items, next, err := List(ctx, listOnlyStore{store}, req, thing{})
+	require.NoError(t, err)
+	assert.Equal(t, []thing{{ID: "00"}, {ID: "01"}, {ID: "02"}}, items)
+
+	req.PageToken = next
+	items, next, err = List(ctx, listOnlyStore{store}, req, thing{})
+	require.NoError(t, err)
+	assert.Equal(t, []thing{{ID: "03"}, {ID: "04"}}, items)
+	assert.Empty(t, next)
+}
+
+func TestDecodeToken(t *testing.T) {
+	ctx := serverutil.WithSigningKeys(t.Context(), []byte("secret"))
+	req := &paginationtest.ListThingsRequest{PageSize: 10, Filter: "red"}
+	tok, err := EncodeToken(ctx, req, "cursor")
+	require.NoError(t, err)
+
+	t.Run("valid with different page size", func(t *testing.T) {
+		c, err := DecodeToken(ctx, &paginationtest.ListThingsRequest{PageSize: 20, Filter: "red", PageToken: tok})
+		require.NoError(t, err)
+		assert.Equal(t, "cursor", c)
+	})
+
+	t.Run("different filter", func(t *testing.T) {
+		_, err := DecodeToken(ctx, &paginationtest.ListThingsRequest{Filter: "blue", PageToken: tok})
+		require.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		_, err := DecodeToken(ctx, &paginationtest.ListThingsRequest{Filter: "red", PageToken: "x" + tok})
+		require.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		rotated := serverutil.WithSigningKeys(context.Background(), []byte("new"), []byte("secret"))
+		_, err := DecodeToken(rotated, &paginationtest.ListThingsRequest{Filter: "red", PageToken: tok})
+		require.NoError(t, err)
+	})
+
+	t.Run("other server", func(t *testing.T) {
+		other := serverutil.WithSigningKeys(context.Background(), []byte("other"))
+		_, err := DecodeToken(other, &paginationtest.ListThingsRequest{Filter: "red", PageToken: tok})
+		require.ErrorIs(t, err, ErrInvalidPageToken)
+	})
+}
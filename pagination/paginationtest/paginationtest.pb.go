@@ -0,0 +1,142 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: pagination/paginationtest/paginationtest.proto
+
+package paginationtest
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A list request following AIP-158, used in tests.
+type ListThingsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken     string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Filter        string                 `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListThingsRequest) Reset() {
+	*x = ListThingsRequest{}
+	mi := &file_pagination_paginationtest_paginationtest_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListThingsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListThingsRequest) ProtoMessage() {}
+
+func (x *ListThingsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pagination_paginationtest_paginationtest_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListThingsRequest.ProtoReflect.Descriptor instead.
+func (*ListThingsRequest) Descriptor() ([]byte, []int) {
+	return file_pagination_paginationtest_paginationtest_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListThingsRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListThingsRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListThingsRequest) GetFilter() string {
+	if x != nil {
+		return x.Filter
+	}
+	return ""
+}
+
+var File_pagination_paginationtest_paginationtest_proto protoreflect.FileDescriptor
+
+const file_pagination_paginationtest_paginationtest_proto_rawDesc = "" +
+	"\n" +
+	".pagination/paginationtest/paginationtest.proto\x12\x16prefab.pagination_test\"g\n" +
+	"\x11ListThingsRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x16\n" +
+	"\x06filter\x18\x03 \x01(\tR\x06filterB2Z0github.com/dpup/prefab/pagination/paginationtestb\x06proto3"
+
+var (
+	file_pagination_paginationtest_paginationtest_proto_rawDescOnce sync.Once
+	file_pagination_paginationtest_paginationtest_proto_rawDescData []byte
+)
+
+func file_pagination_paginationtest_paginationtest_proto_rawDescGZIP() []byte {
+	file_pagination_paginationtest_paginationtest_proto_rawDescOnce.Do(func() {
+		file_pagination_paginationtest_paginationtest_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pagination_paginationtest_paginationtest_proto_rawDesc), len(file_pagination_paginationtest_paginationtest_proto_rawDesc)))
+	})
+	return file_pagination_paginationtest_paginationtest_proto_rawDescData
+}
+
+var file_pagination_paginationtest_paginationtest_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pagination_paginationtest_paginationtest_proto_goTypes = []any{
+	(*ListThingsRequest)(nil), // 0: prefab.pagination_test.ListThingsRequest
+}
+var file_pagination_paginationtest_paginationtest_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_pagination_paginationtest_paginationtest_proto_init() }
+func file_pagination_paginationtest_paginationtest_proto_init() {
+	if File_pagination_paginationtest_paginationtest_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pagination_paginationtest_paginationtest_proto_rawDesc), len(file_pagination_paginationtest_paginationtest_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pagination_paginationtest_paginationtest_proto_goTypes,
+		DependencyIndexes: file_pagination_paginationtest_paginationtest_proto_depIdxs,
+		MessageInfos:      file_pagination_paginationtest_paginationtest_proto_msgTypes,
+	}.Build()
+	File_pagination_paginationtest_paginationtest_proto = out.File
+	file_pagination_paginationtest_paginationtest_proto_goTypes = nil
+	file_pagination_paginationtest_paginationtest_proto_depIdxs = nil
+}
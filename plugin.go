@@ -3,6 +3,9 @@ package prefab
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/dpup/prefab/logging"
 )
 
 // The base plugin interface.
@@ -30,6 +33,14 @@ type InitializablePlugin interface {
 	Init(ctx context.Context, r *Registry) error
 }
 
+// Implemented if the plugin needs to do work before the server accepts traffic,
+// such as pre-loading caches, running migrations, or fetching keys.
+type WarmupPlugin interface {
+	// Warmup the plugin. Will be called after all plugins are initialized, in
+	// initialization order.
+	Warmup(ctx context.Context) error
+}
+
 // Implemented if the plugin needs to be shutdown.
 type ShutdownPlugin interface {
 	// Shutdown the plugin.
@@ -40,7 +51,8 @@ type ShutdownPlugin interface {
 type Registry struct {
 	plugins   map[string]Plugin
 	keys      []string
-	initOrder []string // Track initialization order for proper shutdown
+	initOrder []string          // Track initialization order for proper shutdown
+	disabled  map[string]string // Disabled plugins and the config key responsible
 }
 
 // Get a plugin.
@@ -51,37 +63,104 @@ func (r *Registry) Get(key string) Plugin {
 	return nil
 }
 
-// GetPlugin retrieves a plugin by type.
-// Returns the typed plugin and a boolean indicating success.
+// Implemented if multiple instances of a plugin can be registered, for example
+// two storage backends. Instances are registered under "name:instance", which
+// can be used with Registry.Get and in Deps.
+type InstancePlugin interface {
+	// InstanceID distinguishes the instance from others with the same name. The
+	// empty string is the default instance, which is registered under the name
+	// alone.
+	InstanceID() string
+}
+
+// PluginKey returns the key a plugin is registered under: its name, or
+// "name:instance" for named instances.
+func PluginKey(p Plugin) string {
+	if ip, ok := p.(InstancePlugin); ok {
+		if id := ip.InstanceID(); id != "" {
+			return p.Name() + ":" + id
+		}
+	}
+	return p.Name()
+}
+
+// GetPlugin retrieves a plugin by type. If an instance is given, only the
+// plugin with that InstanceID matches, otherwise the default instance is
+// preferred. Returns the typed plugin and a boolean indicating success.
 //
 // Example:
 //
 //	if store, ok := GetPlugin[*storage.StoragePlugin](r); ok {
 //	    // use store
 //	}
-func GetPlugin[T Plugin](r *Registry) (T, bool) {
-	var zero T
-	if r.plugins == nil {
-		return zero, false
+//	analytics, ok := GetPlugin[*storage.StoragePlugin](r, "analytics")
+func GetPlugin[T Plugin](r *Registry, instance ...string) (T, bool) {
+	var match T
+	var found bool
+	for _, p := range GetPlugins[T](r) {
+		id := instanceID(p)
+		if len(instance) > 0 {
+			if id == instance[0] {
+				return p, true
+			}
+			continue
+		}
+		if id == "" {
+			return p, true
+		}
+		if !found {
+			match, found = p, true
+		}
 	}
+	return match, found
+}
 
-	for _, p := range r.plugins {
-		if typed, ok := p.(T); ok {
-			return typed, true
+// GetPlugins retrieves all plugins of a type, including named instances, in
+// registration order.
+func GetPlugins[T Plugin](r *Registry) []T {
+	var results []T
+	for _, key := range r.keys {
+		if typed, ok := r.plugins[key].(T); ok {
+			results = append(results, typed)
 		}
 	}
+	return results
+}
 
-	return zero, false
+func instanceID(p Plugin) string {
+	if ip, ok := p.(InstancePlugin); ok {
+		return ip.InstanceID()
+	}
+	return ""
 }
 
-// Register a plugin.
+// Register a plugin, under the key returned by PluginKey. Registering another
+// plugin with the same key replaces it.
 func (r *Registry) Register(plugin Plugin) {
 	if r.plugins == nil {
 		r.plugins = map[string]Plugin{}
 	}
-	n := plugin.Name()
+	n := PluginKey(plugin)
+	delete(r.disabled, n)
+	if _, exists := r.plugins[n]; !exists {
+		r.keys = append(r.keys, n)
+	}
 	r.plugins[n] = plugin
-	r.keys = append(r.keys, n)
+}
+
+// Disable records that a plugin was not registered because the config key is
+// false, so that plugins depending on it fail with a clear error.
+func (r *Registry) Disable(name, key string) {
+	if r.disabled == nil {
+		r.disabled = map[string]string{}
+	}
+	r.disabled[name] = key
+}
+
+// Disabled returns whether a plugin was disabled by config.
+func (r *Registry) Disabled(name string) bool {
+	_, ok := r.disabled[name]
+	return ok
 }
 
 // Init all plugins in the Registry. Plugins will be visited in dependency order.
@@ -109,6 +188,23 @@ func (r *Registry) Init(ctx context.Context) error {
 	return nil
 }
 
+// Warmup any plugins that implement the warmup interface, in initialization
+// order, logging how long each takes.
+func (r *Registry) Warmup(ctx context.Context) error {
+	for _, key := range r.initOrder {
+		p, ok := r.plugins[key].(WarmupPlugin)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		if err := p.Warmup(ctx); err != nil {
+			return fmt.Errorf("plugin: failed to warm up '%v': %w", key, err)
+		}
+		logging.Infow(ctx, "🔥 Plugin warmed up", "plugin", key, "duration", time.Since(start))
+	}
+	return nil
+}
+
 // Shutdown any plugins that implement the shutdown interface.
 // Plugins are shut down in reverse initialization order to ensure that
 // dependencies are still available when a plugin shuts down.
@@ -149,6 +245,9 @@ func (r *Registry) validateDeps(key string, visiting map[string]bool, required b
 	if d, ok := plugin.(DependentPlugin); ok {
 		visiting[key] = true
 		for _, dep := range d.Deps() {
+			if cfgKey, ok := r.disabled[dep]; ok {
+				return fmt.Errorf("plugin: '%v' requires '%v', which is disabled by config key '%v'", key, dep, cfgKey)
+			}
 			if err := r.validateDeps(dep, visiting, true); err != nil {
 				return err
 			}
@@ -384,3 +384,67 @@ func TestGetPlugin(t *testing.T) {
 		assert.Equal(t, "target", result.Name())
 	})
 }
+
+func TestDisabledDependency(t *testing.T) {
+	r := &Registry{}
+	r.Register(&TestPlugin{name: "A", deps: []string{"B"}})
+	r.Disable("B", "plugins.b.enabled")
+	assert.True(t, r.Disabled("B"))
+
+	err := r.Init(t.Context())
+	require.Error(t, err)
+	assert.Equal(t, "plugin: 'A' requires 'B', which is disabled by config key 'plugins.b.enabled'", err.Error())
+
+	// Optional dependencies may be disabled.
+	r = &Registry{}
+	r.Register(&TestPluginWithOptDeps{name: "A", optDeps: []string{"B"}})
+	r.Disable("B", "plugins.b.enabled")
+	require.NoError(t, r.Init(t.Context()))
+}
+
+type TestInstancePlugin struct {
+	TestPlugin
+	instance string
+}
+
+func (tp *TestInstancePlugin) InstanceID() string {
+	return tp.instance
+}
+
+func TestNamedInstances(t *testing.T) {
+	r := &Registry{}
+	primary := &TestInstancePlugin{TestPlugin: TestPlugin{name: "db"}}
+	analytics := &TestInstancePlugin{TestPlugin: TestPlugin{name: "db"}, instance: "analytics"}
+	r.Register(analytics)
+	r.Register(primary)
+	r.Register(&TestPlugin{name: "reports", deps: []string{"db:analytics"}})
+
+	assert.Equal(t, "db", PluginKey(primary))
+	assert.Equal(t, "db:analytics", PluginKey(analytics))
+	assert.Same(t, primary, r.Get("db"))
+	assert.Same(t, analytics, r.Get("db:analytics"))
+
+	got, ok := GetPlugin[*TestInstancePlugin](r)
+	require.True(t, ok)
+	assert.Same(t, primary, got, "default instance is preferred")
+
+	got, ok = GetPlugin[*TestInstancePlugin](r, "analytics")
+	require.True(t, ok)
+	assert.Same(t, analytics, got)
+
+	_, ok = GetPlugin[*TestInstancePlugin](r, "missing")
+	assert.False(t, ok)
+
+	assert.Equal(t, []*TestInstancePlugin{analytics, primary}, GetPlugins[*TestInstancePlugin](r))
+
+	initOrder = []string{}
+	require.NoError(t, r.Init(t.Context()))
+	assert.Equal(t, []string{"db", "db", "reports"}, initOrder)
+
+	// Without a default instance, any instance matches.
+	r = &Registry{}
+	r.Register(analytics)
+	got, ok = GetPlugin[*TestInstancePlugin](r)
+	require.True(t, ok)
+	assert.Same(t, analytics, got)
+}
@@ -0,0 +1,532 @@
+// Package audit provides a tamper-evident audit log.
+//
+// Events record who did what to which target, along with optional snapshots
+// of the target before and after the change. Each event includes the hash of
+// the previous event, forming a chain which Verify can check to detect events
+// that were modified or removed. Events are persisted via the storage plugin
+// and can be pruned after a retention period.
+//
+// When the eventbus plugin is registered, logins, logouts, delegations, and
+// suspicious logins are recorded automatically, as are authorization denials.
+//
+// The AuditService exposes events to admins. Access is denied unless an authz
+// policy allows the ReadAction, for example:
+//
+//	prefab.WithPlugin(authz.Plugin(
+//	    authz.WithRoleDescriberFn(audit.ObjectKey, describeAdmins),
+//	    authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(audit.ReadAction)),
+//	)),
+//	prefab.WithPlugin(audit.Plugin(audit.WithRetention(365 * 24 * time.Hour))),
+//
+// Application events are appended with Append:
+//
+//	err := audit.FromContext(ctx).Append(ctx, audit.Event{
+//	    Action: "document.update",
+//	    Target: "document/" + doc.ID,
+//	    Before: oldDoc,
+//	    After:  doc,
+//	})
+package audit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "audit.retention",
+			Description: "How long audit events are kept, 0 keeps them forever",
+			Type:        "duration",
+			Default:     "0",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "audit"
+
+	// authz action for reading the audit log.
+	ReadAction = "audit.read"
+
+	// authz object key used to scope RoleDescribers.
+	ObjectKey = "audit"
+
+	// chainLock serializes appends across replicas, when the lock plugin is
+	// registered.
+	chainLock = "audit.chain"
+
+	// pruneLock elects the replica which prunes expired events.
+	pruneLock = "audit.prune"
+
+	pruneInterval = time.Hour
+)
+
+var (
+	// Returned when an event has no action.
+	ErrMissingAction = errors.NewC("audit: action is required", codes.InvalidArgument)
+)
+
+// Event is an entry in the audit log.
+type Event struct {
+	// Unique identifier, assigned by Append.
+	ID string
+
+	// Position of the event in the hash chain, assigned by Append.
+	Seq int64
+
+	// When the event occurred. Defaults to the time of Append.
+	Time time.Time
+
+	// Who performed the action. Defaults to the identity in the context.
+	Actor Actor
+
+	// What was done, e.g. "document.update".
+	Action string
+
+	// What it was done to, e.g. "document/123".
+	Target string
+
+	// Snapshots of the target before and after the change. Values are stored
+	// as JSON and are returned as json.RawMessage.
+	Before any
+	After  any
+
+	// Top-level fields which differ between Before and After, computed by
+	// Append.
+	Changes []Change
+
+	// Additional details about the event. The region, zone, and instance of
+	// the server are added, see serverutil.Locality.
+	Metadata map[string]string
+
+	// Client details. Default to the request in the context, see
+	// serverutil.RequestInfoFromContext.
+	IP        string
+	UserAgent string
+
+	// Whether personal data was removed from the event by EraseSubject.
+	Redacted bool
+
+	// Hash of the previous event and of this event, assigned by Append.
+	PrevHash string
+	Hash     string
+}
+
+// Actor identifies who performed an action.
+type Actor struct {
+	Subject  string `json:"subject,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Email    string `json:"email,omitempty"`
+
+	// Subject of the admin who assumed the actor's identity, if delegated.
+	DelegatedBy string `json:"delegatedBy,omitempty"`
+}
+
+// ActorFromIdentity returns the actor for an authenticated identity.
+func ActorFromIdentity(id auth.Identity) Actor {
+	a := Actor{Subject: id.Subject, Provider: id.Provider, Email: id.Email}
+	if id.Delegation != nil {
+		a.DelegatedBy = id.Delegation.DelegatorSub
+	}
+	return a
+}
+
+// Change describes a top-level field which differs between an event's Before
+// and After snapshots. Values are JSON encoded, and are empty if the field was
+// added or removed.
+type Change struct {
+	Field  string `json:"field"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// Filter restricts the events returned by Query. Empty fields match all
+// events.
+type Filter struct {
+	Actor  string // Actor subject
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+}
+
+// AuditOption allows configuration of the AuditPlugin.
+type AuditOption func(*AuditPlugin)
+
+// WithRetention overrides how long events are kept. Zero keeps events
+// forever. See `audit.retention`.
+func WithRetention(d time.Duration) AuditOption {
+	return func(p *AuditPlugin) {
+		p.retention = d
+	}
+}
+
+// WithAutomaticEvents controls whether auth events and authorization denials
+// are recorded automatically. Enabled by default.
+func WithAutomaticEvents(enabled bool) AuditOption {
+	return func(p *AuditPlugin) {
+		p.automatic = enabled
+	}
+}
+
+// Plugin returns a new AuditPlugin.
+func Plugin(opts ...AuditOption) *AuditPlugin {
+	p := &AuditPlugin{
+		retention: prefab.ConfigDuration("audit.retention"),
+		automatic: true,
+		now:       time.Now,
+		stop:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// AuditPlugin records events in a tamper-evident log and exposes the
+// AuditService.
+type AuditPlugin struct {
+	store     storage.Store
+	locker    *lock.LockPlugin
+	retention time.Duration
+	automatic bool
+	now       func() time.Time
+
+	mu       sync.Mutex
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// From prefab.Plugin.
+func (p *AuditPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *AuditPlugin) Deps() []string {
+	return []string{storage.PluginName, authz.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *AuditPlugin) OptDeps() []string {
+	return []string{eventbus.PluginName, lock.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *AuditPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCService(&AuditService_ServiceDesc, &impl{p: p}),
+		prefab.WithGRPCGateway(RegisterAuditServiceHandlerFromEndpoint),
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *AuditPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if err := sp.InitModel(record{}); err != nil {
+		return err
+	}
+	if err := sp.InitModel(chainHead{}); err != nil {
+		return err
+	}
+	p.store = sp
+	p.locker, _ = r.Get(lock.PluginName).(*lock.LockPlugin)
+
+	az := r.Get(authz.PluginName).(*authz.AuthzPlugin)
+	az.RegisterObjectFetcher(ObjectKey, authz.ObjectFetcherFn(func(ctx context.Context, _ any) (any, error) {
+		return p, nil
+	}))
+
+	if p.automatic {
+		az.AddAuditLogger(p.recordDenial)
+		if bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin); ok {
+			p.subscribe(bus)
+		}
+	}
+
+	if p.retention > 0 {
+		p.wg.Add(1)
+		go p.runPruning(ctx)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *AuditPlugin) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Append adds an event to the log. The actor and client details default to
+// those of the request in ctx.
+func (p *AuditPlugin) Append(ctx context.Context, e Event) error {
+	if e.Action == "" {
+		return errors.Mark(ErrMissingAction, 0)
+	}
+	if e.Time.IsZero() {
+		e.Time = p.now()
+	}
+	if e.Actor == (Actor{}) {
+		if id, err := auth.IdentityFromContext(ctx); err == nil {
+			e.Actor = ActorFromIdentity(id)
+		}
+	}
+	if e.IP == "" && e.UserAgent == "" {
+		if info, ok := serverutil.RequestInfoFromContext(ctx); ok {
+			e.IP = info.IP
+			e.UserAgent = info.UserAgent
+		}
+	}
+	if locality := serverutil.CurrentLocality().Fields(); len(locality) > 0 {
+		metadata := maps.Clone(e.Metadata)
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		for k, v := range locality {
+			if _, ok := metadata[k]; !ok {
+				metadata[k] = v
+			}
+		}
+		e.Metadata = metadata
+	}
+	e.ID = uuid.NewString()
+
+	rec, err := newRecord(e)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.locker != nil {
+		lease, err := p.locker.Lock(ctx, chainLock)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := lease.Unlock(context.WithoutCancel(ctx)); err != nil {
+				logging.Warnw(ctx, "audit: failed to release chain lock", "error", err)
+			}
+		}()
+	}
+
+	head, err := p.head(ctx)
+	if err != nil {
+		return err
+	}
+	rec.Seq = head.Seq + 1
+	rec.Key = seqKey(rec.Seq)
+	rec.ContentHash = rec.contentHash()
+	rec.PrevHash = head.Hash
+	rec.Hash = rec.computeHash()
+	if err := p.store.Create(ctx, rec); err != nil {
+		return err
+	}
+	head.Seq = rec.Seq
+	head.Hash = rec.Hash
+	if err := p.store.Upsert(ctx, *head); err != nil {
+		// The head is repaired on the next append, since the event was stored.
+		logging.Warnw(ctx, "audit: failed to update chain head", "error", err)
+	}
+	return nil
+}
+
+// Query returns events matching the filter, oldest first.
+func (p *AuditPlugin) Query(ctx context.Context, f Filter) ([]Event, error) {
+	recs, err := p.records(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]Event, len(recs))
+	for i, r := range recs {
+		events[i] = r.event()
+	}
+	return events, nil
+}
+
+// Prune removes events older than the retention period, returning how many
+// were removed. Events are removed oldest first, so the remaining events still
+// form a valid chain.
+func (p *AuditPlugin) Prune(ctx context.Context) (int, error) {
+	if p.retention <= 0 {
+		return 0, nil
+	}
+	cutoff := p.now().Add(-p.retention)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	recs, err := p.records(ctx, Filter{})
+	if err != nil {
+		return 0, err
+	}
+	head, err := p.head(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range recs {
+		if !r.Time.Before(cutoff) || r.Seq == head.Seq {
+			// Always keep the latest event, so the head can be checked against it.
+			break
+		}
+		if err := p.store.Delete(ctx, r); err != nil {
+			return n, err
+		}
+		head.PrunedSeq = r.Seq
+		n++
+	}
+	if n > 0 {
+		if err := p.store.Upsert(ctx, *head); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// EraseSubject redacts personal data from events performed by or targeting
+// the subject, for example to fulfil a GDPR erasure request. Redacted events
+// keep their action, time, and place in the chain, so the chain still
+// verifies. Returns the number of events redacted.
+func (p *AuditPlugin) EraseSubject(ctx context.Context, provider, subject string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	recs, err := p.records(ctx, Filter{})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range recs {
+		if r.Redacted || !r.involves(provider, subject) {
+			continue
+		}
+		r.redact()
+		if err := p.store.Update(ctx, r); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (p *AuditPlugin) runPruning(ctx context.Context) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if p.locker == nil {
+		p.pruneOnInterval(ctx)
+		return
+	}
+	_ = p.locker.RunWhenLeader(ctx, pruneLock, func(ctx context.Context) error {
+		p.pruneOnInterval(ctx)
+		return nil
+	})
+}
+
+func (p *AuditPlugin) pruneOnInterval(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.Prune(ctx)
+			if err != nil {
+				logging.Errorw(ctx, "audit: failed to prune events", "error", err)
+			} else if n > 0 {
+				logging.Infow(ctx, "audit: pruned events", "count", n)
+			}
+		}
+	}
+}
+
+// records returns stored records matching the filter, ordered by sequence.
+func (p *AuditPlugin) records(ctx context.Context, f Filter) ([]record, error) {
+	var all []record
+	if err := p.store.List(ctx, &all, record{}); err != nil {
+		return nil, err
+	}
+	recs := all[:0]
+	for _, r := range all {
+		if f.matches(r) {
+			recs = append(recs, r)
+		}
+	}
+	sortRecords(recs)
+	return recs, nil
+}
+
+// involves returns whether the subject performed the event or was its target.
+func (r record) involves(provider, subject string) bool {
+	a := r.Actor
+	if a.Subject == subject && (provider == "" || a.Provider == provider) {
+		return true
+	}
+	return a.DelegatedBy == subject || r.Target == "user/"+subject
+}
+
+func (f Filter) matches(r record) bool {
+	switch {
+	case f.Actor != "" && r.Actor.Subject != f.Actor:
+		return false
+	case f.Action != "" && r.Action != f.Action:
+		return false
+	case f.Target != "" && r.Target != f.Target:
+		return false
+	case !f.Since.IsZero() && r.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !r.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+func (p *AuditPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditKey{}, p)
+}
+
+// FromContext retrieves the audit plugin from a context.
+func FromContext(ctx context.Context) *AuditPlugin {
+	if p, ok := ctx.Value(auditKey{}).(*AuditPlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type auditKey struct{}
+
+// seqKey formats a sequence number so keys sort in sequence order.
+func seqKey(seq int64) string {
+	return fmt.Sprintf("%020d", seq)
+}
@@ -0,0 +1,287 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/dpup/prefab/redact"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	admin = auth.Identity{Provider: "test", Subject: "admin"}
+	user  = auth.Identity{Provider: "test", Subject: "user"}
+)
+
+func setup(t *testing.T, opts ...AuditOption) (*prefabtest.Server, *AuditPlugin) {
+	p := Plugin(opts...)
+	az := authz.Plugin(
+		authz.WithRoleDescriberFn(ObjectKey, func(_ context.Context, sub auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Subject == admin.Subject {
+				return []authz.Role{"admin"}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(ReadAction)),
+	)
+	s := prefabtest.New(t,
+		prefabtest.WithAuth(),
+		prefabtest.WithPlugins(az, p),
+	)
+	return s, p
+}
+
+type doc struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+func TestAppend(t *testing.T) {
+	_, p := setup(t)
+	ctx := auth.WithIdentityForTest(prefabtest.Context(t), user)
+
+	require.NoError(t, p.Append(ctx, Event{
+		Action: "doc.update",
+		Target: "doc/1",
+		Before: doc{Title: "Draft", Body: "hello"},
+		After:  doc{Title: "Final", Body: "hello"},
+	}))
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.delete", Target: "doc/1"}))
+
+	events, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	e := events[0]
+	assert.Equal(t, int64(1), e.Seq)
+	assert.Equal(t, "user", e.Actor.Subject)
+	assert.Equal(t, "doc.update", e.Action)
+	assert.JSONEq(t, `{"title":"Final","body":"hello"}`, string(e.After.(json.RawMessage)))
+	assert.Equal(t, []Change{{Field: "title", Before: `"Draft"`, After: `"Final"`}}, e.Changes)
+	assert.Empty(t, e.PrevHash)
+	assert.NotEmpty(t, e.Hash)
+
+	assert.Equal(t, int64(2), events[1].Seq)
+	assert.Equal(t, e.Hash, events[1].PrevHash)
+
+	events, err = p.Query(ctx, Filter{Action: "doc.delete"})
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestAppend_RedactedSnapshot(t *testing.T) {
+	_, p := setup(t)
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.Append(ctx, Event{
+		Action: "auth.token",
+		After:  &auth.LoginResponse{Issued: true, Token: "secret"},
+	}))
+	events, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Contains(t, string(events[0].After.(json.RawMessage)), redact.Value)
+	assert.NotContains(t, string(events[0].After.(json.RawMessage)), "secret")
+}
+
+func TestAppend_MissingAction(t *testing.T) {
+	_, p := setup(t)
+	err := p.Append(prefabtest.Context(t), Event{Target: "doc/1"})
+	assert.ErrorIs(t, err, ErrMissingAction)
+}
+
+func TestVerify(t *testing.T) {
+	s, p := setup(t)
+	ctx := prefabtest.Context(t)
+	for _, action := range []string{"a", "b", "c"} {
+		require.NoError(t, p.Append(ctx, Event{Action: action}))
+	}
+
+	v, err := p.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, v.Valid(), v.Reason)
+	assert.Equal(t, int64(3), v.Events)
+
+	r := &record{}
+	require.NoError(t, s.Store().Read(ctx, seqKey(2), r))
+	r.Action = "x"
+	require.NoError(t, s.Store().Update(ctx, *r))
+
+	v, err = p.Verify(ctx)
+	require.NoError(t, err)
+	assert.False(t, v.Valid())
+	assert.Equal(t, int64(2), v.InvalidSeq)
+	assert.Equal(t, "event has been modified", v.Reason)
+
+	require.NoError(t, s.Store().Delete(ctx, *r))
+
+	v, err = p.Verify(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), v.InvalidSeq)
+	assert.Equal(t, "event is missing", v.Reason)
+}
+
+func TestPrune(t *testing.T) {
+	_, p := setup(t, WithRetention(time.Hour))
+	ctx := prefabtest.Context(t)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, p.Append(ctx, Event{Action: "old", Time: now.Add(-3 * time.Hour)}))
+	require.NoError(t, p.Append(ctx, Event{Action: "old", Time: now.Add(-2 * time.Hour)}))
+	require.NoError(t, p.Append(ctx, Event{Action: "new", Time: now.Add(-time.Minute)}))
+
+	n, err := p.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	events, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "new", events[0].Action)
+
+	v, err := p.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, v.Valid(), v.Reason)
+
+	require.NoError(t, p.Append(ctx, Event{Action: "newer"}))
+	v, err = p.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, v.Valid(), v.Reason)
+	assert.Equal(t, int64(2), v.Events)
+}
+
+func TestPrune_KeepsLatestEvent(t *testing.T) {
+	_, p := setup(t, WithRetention(time.Hour))
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.Append(ctx, Event{Action: "old", Time: time.Now().Add(-2 * time.Hour)}))
+
+	n, err := p.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestEraseSubject(t *testing.T) {
+	_, p := setup(t)
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.create", Actor: Actor{Provider: "test", Subject: "alice", Email: "alice@example.com"}, IP: "10.0.0.1"}))
+	require.NoError(t, p.Append(ctx, Event{Action: "user.suspend", Actor: Actor{Subject: "admin"}, Target: "user/alice"}))
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.create", Actor: Actor{Provider: "test", Subject: "bob"}}))
+
+	n, err := p.EraseSubject(ctx, "test", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	events, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.True(t, events[0].Redacted)
+	assert.Equal(t, "doc.create", events[0].Action)
+	assert.Empty(t, events[0].Actor)
+	assert.Empty(t, events[0].IP)
+	assert.True(t, events[1].Redacted)
+	assert.Empty(t, events[1].Target)
+	assert.False(t, events[2].Redacted)
+	assert.Equal(t, "bob", events[2].Actor.Subject)
+
+	v, err := p.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, v.Valid(), v.Reason)
+}
+
+func TestAuthEvents(t *testing.T) {
+	s, p := setup(t)
+	ctx := prefabtest.Context(t)
+
+	s.Events().Publish(auth.LoginEvent, auth.NewAuthEvent(user))
+	s.Events().Publish(auth.DelegationEvent, auth.DelegationEventData{
+		Admin:           admin,
+		AssumedIdentity: user,
+		Reason:          "support-123",
+	})
+	require.NoError(t, s.Events().Wait(ctx))
+
+	events, err := p.Query(ctx, Filter{Action: LoginAction})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "user", events[0].Actor.Subject)
+
+	events, err = p.Query(ctx, Filter{Action: DelegationAction})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "admin", events[0].Actor.Subject)
+	assert.Equal(t, "user/user", events[0].Target)
+	assert.Equal(t, "support-123", events[0].Metadata["reason"])
+}
+
+func TestListEvents(t *testing.T) {
+	s, p := setup(t)
+	ctx := prefabtest.Context(t)
+	client := NewAuditServiceClient(s.Conn())
+
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.create", Actor: Actor{Subject: "alice"}}))
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.create", Actor: Actor{Subject: "bob"}}))
+
+	_, err := client.ListEvents(s.AuthContext(t.Context(), user), &ListEventsRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// The denial is recorded.
+	denials, err := p.Query(ctx, Filter{Action: DeniedAction})
+	require.NoError(t, err)
+	require.Len(t, denials, 1)
+	assert.Equal(t, "user", denials[0].Actor.Subject)
+	assert.Equal(t, ReadAction, denials[0].Metadata["action"])
+
+	resp, err := client.ListEvents(s.AuthContext(t.Context(), admin), &ListEventsRequest{Action: "doc.create", PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 1)
+	assert.Equal(t, "alice", resp.GetEvents()[0].GetActor().GetSubject())
+	require.NotEmpty(t, resp.GetNextPageToken())
+
+	resp, err = client.ListEvents(s.AuthContext(t.Context(), admin), &ListEventsRequest{Action: "doc.create", PageSize: 1, PageToken: resp.GetNextPageToken()})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEvents(), 1)
+	assert.Equal(t, "bob", resp.GetEvents()[0].GetActor().GetSubject())
+	assert.Empty(t, resp.GetNextPageToken())
+
+	v, err := client.VerifyChain(s.AuthContext(t.Context(), admin), &VerifyChainRequest{})
+	require.NoError(t, err)
+	assert.True(t, v.GetValid())
+	assert.Equal(t, int64(3), v.GetEvents())
+}
+
+func TestDiff(t *testing.T) {
+	assert.Nil(t, diff(`{"a":1}`, `{"a":1}`))
+	assert.Equal(t, []Change{
+		{Field: "a", Before: "1"},
+		{Field: "b", After: "2"},
+		{Field: "c", Before: "1", After: "3"},
+	}, diff(`{"a":1,"c":1}`, `{"b":2,"c":3}`))
+	assert.Equal(t, []Change{{Field: "a", After: `"x"`}}, diff("", `{"a":"x"}`))
+	assert.Equal(t, []Change{{Before: "1", After: "2"}}, diff("1", "2"))
+}
+
+func TestAppend_Locality(t *testing.T) {
+	_, p := setup(t)
+	serverutil.SetLocality(serverutil.Locality{Region: "eu-west1", Zone: "eu-west1-b"})
+	t.Cleanup(func() { serverutil.SetLocality(serverutil.Locality{}) })
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.Append(ctx, Event{Action: "doc.create", Metadata: map[string]string{"zone": "override"}}))
+
+	events, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]string{"region": "eu-west1", "zone": "override"}, events[0].Metadata)
+}
@@ -0,0 +1,364 @@
+package audit
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/redact"
+)
+
+// headID is the key of the chain head.
+const headID = "head"
+
+// Verification is the result of checking the hash chain.
+type Verification struct {
+	// Number of events checked.
+	Events int64
+
+	// Sequence number of the first invalid event, zero if the chain is valid.
+	InvalidSeq int64
+
+	// Why verification failed.
+	Reason string
+}
+
+// Valid returns true if no events were modified or removed.
+func (v *Verification) Valid() bool {
+	return v.InvalidSeq == 0
+}
+
+// Verify checks that each event matches its hash and links to the previous
+// event, and that no events are missing. Events removed by the retention
+// policy don't invalidate the chain; verification starts from the oldest
+// remaining event.
+//
+// Verification detects changes made directly to the store. Since the chain is
+// stored alongside the events, recording the latest hash elsewhere guards
+// against the whole chain being rewritten.
+func (p *AuditPlugin) Verify(ctx context.Context) (*Verification, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	recs, err := p.records(ctx, Filter{})
+	if err != nil {
+		return nil, err
+	}
+	head, err := p.head(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Verification{}
+	fail := func(seq int64, reason string) (*Verification, error) {
+		v.InvalidSeq = seq
+		v.Reason = reason
+		return v, nil
+	}
+
+	next := head.PrunedSeq + 1
+	prev := ""
+	if len(recs) > 0 && head.PrunedSeq > 0 {
+		// The oldest remaining event anchors the chain.
+		prev = recs[0].PrevHash
+	}
+	for _, r := range recs {
+		if r.Seq != next {
+			return fail(next, "event is missing")
+		}
+		if r.PrevHash != prev {
+			return fail(r.Seq, "previous hash does not match")
+		}
+		if r.computeHash() != r.Hash || (!r.Redacted && r.contentHash() != r.ContentHash) {
+			return fail(r.Seq, "event has been modified")
+		}
+		v.Events++
+		prev = r.Hash
+		next++
+	}
+	if head.Seq != next-1 {
+		return fail(next, "event is missing")
+	}
+	if head.Hash != prev {
+		return fail(head.Seq, "chain head does not match")
+	}
+	return v, nil
+}
+
+// record is the stored form of an event. Snapshots are stored as JSON
+// strings, so the hashed content survives stores that normalize JSON.
+type record struct {
+	Key       string            `json:"key"`
+	ID        string            `json:"id"`
+	Seq       int64             `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Actor     Actor             `json:"actor"`
+	Action    string            `json:"action"`
+	Target    string            `json:"target,omitempty"`
+	Before    string            `json:"before,omitempty"`
+	After     string            `json:"after,omitempty"`
+	Changes   []Change          `json:"changes,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"userAgent,omitempty"`
+
+	// Hash of the fields which may contain personal data. The chain covers
+	// this hash rather than the fields, so they can be redacted without
+	// breaking the chain.
+	ContentHash string `json:"contentHash"`
+	Redacted    bool   `json:"redacted,omitempty"`
+
+	PrevHash string `json:"prevHash,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// PK implements storage.Model. Records are keyed by sequence, so appends from
+// replicas with a stale head conflict rather than forking the chain.
+func (r record) PK() string {
+	return r.Key
+}
+
+// Name implements storage.Namer.
+func (r record) Name() string {
+	return "audit_events"
+}
+
+func newRecord(e Event) (record, error) {
+	before, err := snapshot(e.Before)
+	if err != nil {
+		return record{}, err
+	}
+	after, err := snapshot(e.After)
+	if err != nil {
+		return record{}, err
+	}
+	// Empty metadata is stored as nil, so the content hash is stable.
+	var metadata map[string]string
+	if len(e.Metadata) > 0 {
+		metadata = e.Metadata
+	}
+	return record{
+		ID:        e.ID,
+		Time:      e.Time.UTC(),
+		Actor:     e.Actor,
+		Action:    e.Action,
+		Target:    e.Target,
+		Before:    before,
+		After:     after,
+		Changes:   diff(before, after),
+		Metadata:  metadata,
+		IP:        e.IP,
+		UserAgent: e.UserAgent,
+	}, nil
+}
+
+func (r record) event() Event {
+	e := Event{
+		ID:        r.ID,
+		Seq:       r.Seq,
+		Time:      r.Time,
+		Actor:     r.Actor,
+		Action:    r.Action,
+		Target:    r.Target,
+		Changes:   r.Changes,
+		Metadata:  r.Metadata,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+		Redacted:  r.Redacted,
+		PrevHash:  r.PrevHash,
+		Hash:      r.Hash,
+	}
+	if r.Before != "" {
+		e.Before = json.RawMessage(r.Before)
+	}
+	if r.After != "" {
+		e.After = json.RawMessage(r.After)
+	}
+	return e
+}
+
+// computeHash returns the hex encoded SHA-256 of the record, excluding its own
+// hash. The record includes the previous hash, which chains the events.
+func (r record) computeHash() string {
+	return sha256Hex(chainLink{
+		Seq:         r.Seq,
+		ID:          r.ID,
+		Time:        r.Time,
+		Action:      r.Action,
+		ContentHash: r.ContentHash,
+		PrevHash:    r.PrevHash,
+	})
+}
+
+// contentHash returns the hex encoded SHA-256 of the redactable fields.
+func (r record) contentHash() string {
+	return sha256Hex(content{
+		Actor:     r.Actor,
+		Target:    r.Target,
+		Before:    r.Before,
+		After:     r.After,
+		Changes:   r.Changes,
+		Metadata:  r.Metadata,
+		IP:        r.IP,
+		UserAgent: r.UserAgent,
+	})
+}
+
+// redact removes the fields which may contain personal data.
+func (r *record) redact() {
+	r.Actor = Actor{}
+	r.Target = ""
+	r.Before = ""
+	r.After = ""
+	r.Changes = nil
+	r.Metadata = nil
+	r.IP = ""
+	r.UserAgent = ""
+	r.Redacted = true
+}
+
+// chainLink is the hashed form of a record.
+type chainLink struct {
+	Seq         int64     `json:"seq"`
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Action      string    `json:"action"`
+	ContentHash string    `json:"contentHash"`
+	PrevHash    string    `json:"prevHash"`
+}
+
+// content holds the redactable fields of a record.
+type content struct {
+	Actor     Actor             `json:"actor"`
+	Target    string            `json:"target"`
+	Before    string            `json:"before"`
+	After     string            `json:"after"`
+	Changes   []Change          `json:"changes"`
+	Metadata  map[string]string `json:"metadata"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"userAgent"`
+}
+
+func sha256Hex(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// chainHead tracks the latest event, so appends don't need to scan the log.
+type chainHead struct {
+	ID   string `json:"id"`
+	Seq  int64  `json:"seq"`
+	Hash string `json:"hash"`
+
+	// Sequence number of the last event removed by the retention policy.
+	PrunedSeq int64 `json:"prunedSeq,omitempty"`
+}
+
+// PK implements storage.Model.
+func (h chainHead) PK() string {
+	return h.ID
+}
+
+// Name implements storage.Namer.
+func (h chainHead) Name() string {
+	return "audit_chain"
+}
+
+// head returns the chain head. If events were stored without the head being
+// updated, it's advanced to the latest event.
+func (p *AuditPlugin) head(ctx context.Context) (*chainHead, error) {
+	head := &chainHead{}
+	err := p.store.Read(ctx, headID, head)
+	if errors.Is(err, storage.ErrNotFound) {
+		head = &chainHead{ID: headID}
+	} else if err != nil {
+		return nil, err
+	}
+	for {
+		r := record{}
+		err := p.store.Read(ctx, seqKey(head.Seq+1), &r)
+		if errors.Is(err, storage.ErrNotFound) {
+			return head, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		head.Seq = r.Seq
+		head.Hash = r.Hash
+	}
+}
+
+// snapshot encodes a before or after value as JSON. Fields of proto messages
+// marked with `prefab.redact` are masked.
+func snapshot(v any) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(redact.Any(v))
+	if err != nil {
+		return "", errors.WrapPrefix(err, "audit: invalid snapshot", 0)
+	}
+	if string(data) == "null" {
+		return "", nil
+	}
+	return string(data), nil
+}
+
+// diff compares the top-level fields of two JSON objects. If either snapshot
+// isn't an object, they're compared as a whole.
+func diff(before, after string) []Change {
+	if before == after {
+		return nil
+	}
+	b, bok := fields(before)
+	a, aok := fields(after)
+	if !bok || !aok {
+		return []Change{{Before: before, After: after}}
+	}
+
+	keys := make([]string, 0, len(b)+len(a))
+	for k := range b {
+		keys = append(keys, k)
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []Change
+	for _, k := range keys {
+		if string(b[k]) != string(a[k]) {
+			changes = append(changes, Change{Field: k, Before: string(b[k]), After: string(a[k])})
+		}
+	}
+	return changes
+}
+
+// fields decodes the top-level fields of a JSON object. An empty snapshot is
+// treated as an object without fields.
+func fields(s string) (map[string]json.RawMessage, bool) {
+	m := map[string]json.RawMessage{}
+	if s == "" {
+		return m, true
+	}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, false
+	}
+	return m, true
+}
+
+func sortRecords(recs []record) {
+	slices.SortFunc(recs, func(a, b record) int {
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/eventbus"
+)
+
+// Actions recorded automatically.
+const (
+	LoginAction           = auth.LoginEvent
+	LogoutAction          = auth.LogoutEvent
+	DelegationAction      = auth.DelegationEvent
+	SuspiciousLoginAction = auth.SuspiciousLoginEvent
+	DeniedAction          = "authz.denied"
+)
+
+// subscribe records auth events published on the bus.
+func (p *AuditPlugin) subscribe(bus eventbus.EventBus) {
+	for _, topic := range []string{LoginAction, LogoutAction} {
+		bus.Subscribe(topic, func(ctx context.Context, m *eventbus.Message) error {
+			e, ok := m.Data.(auth.AuthEvent)
+			if !ok {
+				return nil
+			}
+			return p.Append(ctx, authEvent(m.Topic, e, nil))
+		})
+	}
+
+	bus.Subscribe(SuspiciousLoginAction, func(ctx context.Context, m *eventbus.Message) error {
+		e, ok := m.Data.(auth.SuspiciousLoginEventData)
+		if !ok {
+			return nil
+		}
+		return p.Append(ctx, authEvent(m.Topic, e.AuthEvent, map[string]string{"reasons": strings.Join(e.Reasons, ",")}))
+	})
+
+	bus.Subscribe(DelegationAction, func(ctx context.Context, m *eventbus.Message) error {
+		e, ok := m.Data.(auth.DelegationEventData)
+		if !ok {
+			return nil
+		}
+		return p.Append(ctx, Event{
+			Actor:    ActorFromIdentity(e.Admin),
+			Action:   DelegationAction,
+			Target:   "user/" + e.AssumedIdentity.Subject,
+			Metadata: map[string]string{"reason": e.Reason},
+		})
+	})
+}
+
+func authEvent(action string, e auth.AuthEvent, metadata map[string]string) Event {
+	return Event{
+		Time:      e.Timestamp,
+		Actor:     ActorFromIdentity(e.Identity),
+		Action:    action,
+		Metadata:  metadata,
+		IP:        e.Request.IP,
+		UserAgent: e.Request.UserAgent,
+	}
+}
+
+// recordDenial is registered as an authz audit logger, and records decisions
+// which deny access.
+func (p *AuditPlugin) recordDenial(ctx context.Context, d authz.AuthzDecision) {
+	if d.Effect == authz.Allow {
+		return
+	}
+	target := d.Resource
+	if d.ObjectID != nil && d.ObjectID != "" {
+		target = fmt.Sprintf("%s/%v", d.Resource, d.ObjectID)
+	}
+	metadata := map[string]string{
+		"action": string(d.Action),
+		"reason": d.Reason,
+	}
+	if d.Scope != "" {
+		metadata["scope"] = string(d.Scope)
+	}
+	err := p.Append(ctx, Event{
+		Actor:     ActorFromIdentity(d.Identity),
+		Action:    DeniedAction,
+		Target:    target,
+		Metadata:  metadata,
+		IP:        d.Request.IP,
+		UserAgent: d.Request.UserAgent,
+	})
+	if err != nil {
+		logging.Errorw(ctx, "audit: failed to record denial", "error", err)
+	}
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/pagination"
+)
+
+type impl struct {
+	UnimplementedAuditServiceServer
+	p *AuditPlugin
+}
+
+func (s *impl) ListEvents(ctx context.Context, in *ListEventsRequest) (*ListEventsResponse, error) {
+	recs, err := s.p.records(ctx, Filter{
+		Actor:  in.GetActor(),
+		Action: in.GetAction(),
+		Target: in.GetTarget(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := pagination.Slice(ctx, in, recs, record.PK)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListEventsResponse{NextPageToken: next}
+	for _, r := range page {
+		out.Events = append(out.Events, r.proto())
+	}
+	return out, nil
+}
+
+func (s *impl) VerifyChain(ctx context.Context, _ *VerifyChainRequest) (*VerifyChainResponse, error) {
+	v, err := s.p.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &VerifyChainResponse{
+		Valid:      v.Valid(),
+		Events:     v.Events,
+		InvalidSeq: v.InvalidSeq,
+		Error:      v.Reason,
+	}, nil
+}
+
+func (r record) proto() *AuditEvent {
+	e := &AuditEvent{
+		Id:     r.ID,
+		Seq:    r.Seq,
+		Time:   r.Time.Unix(),
+		Action: r.Action,
+		Target: r.Target,
+		Actor: &AuditActor{
+			Subject:     r.Actor.Subject,
+			Provider:    r.Actor.Provider,
+			Email:       r.Actor.Email,
+			DelegatedBy: r.Actor.DelegatedBy,
+		},
+		Before:    r.Before,
+		After:     r.After,
+		Metadata:  r.Metadata,
+		Ip:        r.IP,
+		UserAgent: r.UserAgent,
+		PrevHash:  r.PrevHash,
+		Hash:      r.Hash,
+		Redacted:  r.Redacted,
+	}
+	for _, c := range r.Changes {
+		e.Changes = append(e.Changes, &AuditChange{Field: c.Field, Before: c.Before, After: c.After})
+	}
+	return e
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/storage"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrLoginNotFresh is returned when account linking is attempted with a login
+// older than the configured maximum age.
+var ErrLoginNotFresh = errors.NewC("auth: a recent login is required", codes.Unauthenticated).
+	WithUserPresentableMessage("Please log in again to continue")
+
+// Default for `auth.accountLinking.maxAuthAge`.
+const defaultLinkMaxAuthAge = 10 * time.Minute
+
+// AccountLinkEventData is published with AccountLinkedEvent and
+// AccountUnlinkedEvent.
+type AccountLinkEventData struct {
+	// The identity that made the request.
+	AuthEvent
+
+	// Canonical account ID the identity was linked to or unlinked from.
+	AccountID string
+
+	// The provider identity which was linked or unlinked.
+	Provider string
+	Subject  string
+}
+
+// AccountLinker maps provider identities to canonical accounts, allowing a
+// user to log in with several providers and be seen as the same subject.
+//
+// An account is identified by the provider and subject of the first identity it
+// was created from, as `provider:subject`, so that it can't be confused with a
+// subject from another provider. Once linked, identities from every provider,
+// including the first, take the account ID as their subject.
+type AccountLinker struct {
+	store      storage.Store
+	maxAuthAge time.Duration
+}
+
+// NewAccountLinker returns an account linker which persists links to store.
+// Linking requires logins more recent than maxAuthAge.
+func NewAccountLinker(store storage.Store, maxAuthAge time.Duration) *AccountLinker {
+	if maxAuthAge <= 0 {
+		maxAuthAge = defaultLinkMaxAuthAge
+	}
+	return &AccountLinker{store: store, maxAuthAge: maxAuthAge}
+}
+
+type accountLinkerKey struct{}
+
+// WithAccountLinker adds an account linker to the context.
+func WithAccountLinker(ctx context.Context, l *AccountLinker) context.Context {
+	return context.WithValue(ctx, accountLinkerKey{}, l)
+}
+
+func accountLinkerFromContext(ctx context.Context) *AccountLinker {
+	l, _ := ctx.Value(accountLinkerKey{}).(*AccountLinker)
+	return l
+}
+
+// ResolveAccount returns the canonical account ID for a provider identity. If
+// the identity hasn't been linked, its subject is returned.
+func (l *AccountLinker) ResolveAccount(ctx context.Context, provider, subject string) (string, error) {
+	link := &AccountLink{}
+	err := l.store.Read(ctx, linkKey(provider, subject), link)
+	if errors.Is(err, storage.ErrNotFound) {
+		return subject, nil
+	} else if err != nil {
+		return "", err
+	}
+	return link.AccountID, nil
+}
+
+// Link adds secondary to the account of primary, creating the account if
+// primary hasn't been linked before. Both identities must come from logins
+// more recent than the linker's max auth age. Returns the account ID.
+func (l *AccountLinker) Link(ctx context.Context, primary, secondary Identity) (string, error) {
+	if err := l.checkFresh(primary); err != nil {
+		return "", err
+	}
+	if err := l.checkFresh(secondary); err != nil {
+		return "", err
+	}
+	primarySub, secondarySub := providerSubject(primary), providerSubject(secondary)
+	if primary.Provider == secondary.Provider && primarySub == secondarySub {
+		return "", errors.NewC("auth: can not link an identity to itself", codes.InvalidArgument)
+	}
+
+	accountID, created := linkKey(primary.Provider, primarySub), true
+	primaryLink := &AccountLink{}
+	err := l.store.Read(ctx, linkKey(primary.Provider, primarySub), primaryLink)
+	if err == nil {
+		accountID, created = primaryLink.AccountID, false
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	existing := &AccountLink{}
+	err = l.store.Read(ctx, linkKey(secondary.Provider, secondarySub), existing)
+	if err == nil {
+		if existing.AccountID == accountID {
+			return accountID, nil
+		}
+		return "", errors.NewC("auth: identity is linked to another account", codes.FailedPrecondition).
+			WithUserPresentableMessage("This login is already linked to another account")
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return "", err
+	}
+
+	// An identity which other identities are linked to can't join another
+	// account, since they would be left pointing at an abandoned account.
+	members, err := l.LinkedIdentities(ctx, linkKey(secondary.Provider, secondarySub))
+	if err != nil {
+		return "", err
+	}
+	if len(members) > 0 {
+		return "", errors.NewC("auth: identity has linked identities of its own", codes.FailedPrecondition).
+			WithUserPresentableMessage("This login already has other logins linked to it")
+	}
+
+	now := timeFunc()
+	links := []storage.Model{&AccountLink{
+		Key:       linkKey(secondary.Provider, secondarySub),
+		Provider:  secondary.Provider,
+		Subject:   secondarySub,
+		AccountID: accountID,
+		LinkedAt:  now,
+	}}
+	if created {
+		// Record the primary identity as well, so the account's identities can be
+		// listed.
+		links = append(links, &AccountLink{
+			Key:       linkKey(primary.Provider, primarySub),
+			Provider:  primary.Provider,
+			Subject:   primarySub,
+			AccountID: accountID,
+			LinkedAt:  now,
+		})
+	}
+	if err := l.store.Upsert(ctx, links...); err != nil {
+		return "", err
+	}
+	return accountID, nil
+}
+
+// Unlink removes a provider identity from an account. The identity the account
+// was created from can't be unlinked, since the account ID is derived from it.
+func (l *AccountLinker) Unlink(ctx context.Context, accountID, provider, subject string) error {
+	link := &AccountLink{}
+	err := l.store.Read(ctx, linkKey(provider, subject), link)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && link.AccountID != accountID) {
+		return errors.NewC("auth: identity is not linked to this account", codes.NotFound)
+	} else if err != nil {
+		return err
+	}
+	if linkKey(provider, subject) == accountID {
+		return errors.NewC("auth: can not unlink the identity an account was created from", codes.FailedPrecondition)
+	}
+	return l.store.Delete(ctx, link)
+}
+
+// LinkedIdentities returns the provider identities linked to an account. An
+// account that has never been linked returns an empty list.
+func (l *AccountLinker) LinkedIdentities(ctx context.Context, accountID string) ([]AccountLink, error) {
+	var links []AccountLink
+	if err := l.store.List(ctx, &links, AccountLink{AccountID: accountID}); err != nil {
+		return nil, err
+	}
+	return links, nil
+}
+
+func (l *AccountLinker) checkFresh(identity Identity) error {
+	if IsDelegated(identity) {
+		return errors.NewC("auth: delegated identities can not link accounts", codes.PermissionDenied)
+	}
+	if timeFunc().Sub(identity.AuthTime) > l.maxAuthAge {
+		return ErrLoginNotFresh
+	}
+	return nil
+}
+
+// resolveAccount replaces the identity's subject with its canonical account ID,
+// if an account linker is configured and the identity has been linked.
+func resolveAccount(ctx context.Context, identity Identity) (Identity, error) {
+	l := accountLinkerFromContext(ctx)
+	if l == nil {
+		return identity, nil
+	}
+	accountID, err := l.ResolveAccount(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return Identity{}, errors.Wrap(err, 0).Append("auth: failed to resolve linked account")
+	}
+	if accountID != identity.Subject {
+		identity.ProviderSubject = identity.Subject
+		identity.Subject = accountID
+	}
+	return identity, nil
+}
+
+// providerSubject returns the subject assigned by the identity provider, before
+// any account resolution.
+func providerSubject(identity Identity) string {
+	if identity.ProviderSubject != "" {
+		return identity.ProviderSubject
+	}
+	return identity.Subject
+}
+
+func linkKey(provider, subject string) string {
+	return provider + ":" + subject
+}
+
+func (s *impl) LinkAccount(ctx context.Context, in *LinkAccountRequest) (*LinkAccountResponse, error) {
+	l := accountLinkerFromContext(ctx)
+	if l == nil {
+		return nil, errors.NewC("account linking not enabled", codes.FailedPrecondition)
+	}
+	current, err := IdentityFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.Token == "" {
+		return nil, errors.NewC("token required", codes.InvalidArgument)
+	}
+	other, err := ParseIdentityToken(ctx, in.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	accountID, err := l.Link(ctx, current, other)
+	if err != nil {
+		return nil, err
+	}
+	links, err := l.LinkedIdentities(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infow(ctx, "Account linked",
+		"account", accountID, "provider", other.Provider, "subject", other.Subject)
+	publishAccountLinkEvent(ctx, AccountLinkedEvent, current, accountID, other.Provider, other.Subject)
+
+	return &LinkAccountResponse{
+		AccountId:        accountID,
+		LinkedIdentities: linkedIdentities(links),
+	}, nil
+}
+
+func (s *impl) UnlinkAccount(ctx context.Context, in *UnlinkAccountRequest) (*UnlinkAccountResponse, error) {
+	l := accountLinkerFromContext(ctx)
+	if l == nil {
+		return nil, errors.NewC("account linking not enabled", codes.FailedPrecondition)
+	}
+	current, err := IdentityFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.Provider == "" || in.Subject == "" {
+		return nil, errors.NewC("subject and provider required", codes.InvalidArgument)
+	}
+	if err := l.checkFresh(current); err != nil {
+		return nil, err
+	}
+
+	if err := l.Unlink(ctx, current.Subject, in.Provider, in.Subject); err != nil {
+		return nil, err
+	}
+	links, err := l.LinkedIdentities(ctx, current.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Infow(ctx, "Account unlinked",
+		"account", current.Subject, "provider", in.Provider, "subject", in.Subject)
+	publishAccountLinkEvent(ctx, AccountUnlinkedEvent, current, current.Subject, in.Provider, in.Subject)
+
+	return &UnlinkAccountResponse{LinkedIdentities: linkedIdentities(links)}, nil
+}
+
+func publishAccountLinkEvent(ctx context.Context, topic string, current Identity, accountID, provider, subject string) {
+	if bus := eventbus.FromContext(ctx); bus != nil {
+		bus.Publish(topic, AccountLinkEventData{
+			AuthEvent: NewAuthEventFromContext(ctx, current),
+			AccountID: accountID,
+			Provider:  provider,
+			Subject:   subject,
+		})
+	}
+}
+
+func linkedIdentities(links []AccountLink) []*LinkedIdentity {
+	out := make([]*LinkedIdentity, 0, len(links))
+	for _, l := range links {
+		out = append(out, &LinkedIdentity{Provider: l.Provider, Subject: l.Subject})
+	}
+	return out
+}
+
+// AccountLink is a model for storing the account a provider identity is
+// linked to.
+type AccountLink struct {
+	Key       string
+	Provider  string
+	Subject   string
+	AccountID string
+	LinkedAt  time.Time
+}
+
+// Implements storage.Model.
+func (al AccountLink) PK() string {
+	return al.Key
+}
+
+// ResolveAccount replaces the identity's subject with its canonical account ID,
+// if an account linker is configured and the identity has been linked. It is
+// for identities which didn't come from IdentityFromContext, such as the
+// identity being logged in, since those are already resolved.
+func ResolveAccount(ctx context.Context, identity Identity) (Identity, error) {
+	if identity.ProviderSubject != "" {
+		return identity, nil
+	}
+	return resolveAccount(ctx, identity)
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func linkCtx(t *testing.T, l *AccountLinker, identity Identity) context.Context {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	ctx = WithIdentityForTest(ctx, identity)
+	return WithAccountLinker(ctx, l)
+}
+
+func TestAccountLinking(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	l := NewAccountLinker(memstore.New(), 10*time.Minute)
+	svc := &impl{}
+
+	google := Identity{Provider: "google", Subject: "g-1", SessionID: "s1", AuthTime: now}
+	github := Identity{Provider: "github", Subject: "gh-1", SessionID: "s2", AuthTime: now}
+
+	githubToken, err := IdentityToken(t.Context(), github)
+	require.NoError(t, err)
+
+	resp, err := svc.LinkAccount(linkCtx(t, l, google), &LinkAccountRequest{Token: githubToken})
+	require.NoError(t, err)
+	assert.Equal(t, "google:g-1", resp.AccountId)
+	assert.ElementsMatch(t, []*LinkedIdentity{
+		{Provider: "google", Subject: "g-1"},
+		{Provider: "github", Subject: "gh-1"},
+	}, resp.LinkedIdentities)
+
+	// Logging in with GitHub resolves to the account.
+	identity, err := IdentityFromContext(linkCtx(t, l, github))
+	require.NoError(t, err)
+	assert.Equal(t, "google:g-1", identity.Subject)
+	assert.Equal(t, "gh-1", identity.ProviderSubject)
+	assert.Equal(t, "github", identity.Provider)
+
+	// Re-signing a resolved identity keeps the provider subject in the token.
+	token, err := IdentityToken(t.Context(), identity)
+	require.NoError(t, err)
+	parsed, err := ParseIdentityToken(t.Context(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "gh-1", parsed.Subject)
+
+	// So does the primary identity.
+	identity, err = IdentityFromContext(linkCtx(t, l, google))
+	require.NoError(t, err)
+	assert.Equal(t, "google:g-1", identity.Subject)
+	assert.Equal(t, "g-1", identity.ProviderSubject)
+
+	// Linking again is a no-op.
+	_, err = svc.LinkAccount(linkCtx(t, l, google), &LinkAccountRequest{Token: githubToken})
+	require.NoError(t, err)
+
+	// Unlinking the primary identity isn't allowed.
+	_, err = svc.UnlinkAccount(linkCtx(t, l, github), &UnlinkAccountRequest{Provider: "google", Subject: "g-1"})
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+
+	// Only the identity in the account is matched, not subjects equal to the
+	// primary's from other providers.
+	_, err = svc.UnlinkAccount(linkCtx(t, l, google), &UnlinkAccountRequest{Provider: "github", Subject: "g-1"})
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+
+	unlinked, err := svc.UnlinkAccount(linkCtx(t, l, google), &UnlinkAccountRequest{Provider: "github", Subject: "gh-1"})
+	require.NoError(t, err)
+	assert.Equal(t, []*LinkedIdentity{{Provider: "google", Subject: "g-1"}}, unlinked.LinkedIdentities)
+
+	identity, err = IdentityFromContext(linkCtx(t, l, github))
+	require.NoError(t, err)
+	assert.Equal(t, "gh-1", identity.Subject)
+}
+
+func TestAccountLinking_Conflicts(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	l := NewAccountLinker(memstore.New(), 10*time.Minute)
+	ctx := t.Context()
+
+	a := Identity{Provider: "google", Subject: "a", AuthTime: now}
+	b := Identity{Provider: "github", Subject: "b", AuthTime: now}
+	c := Identity{Provider: "magiclink", Subject: "c", AuthTime: now}
+
+	_, err := l.Link(ctx, a, b)
+	require.NoError(t, err)
+
+	// b already belongs to a's account.
+	_, err = l.Link(ctx, c, b)
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+
+	// a has identities linked to it, so can't join another account.
+	_, err = l.Link(ctx, c, a)
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+
+	_, err = l.Link(ctx, a, a)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	// Linking via a linked identity joins the same account.
+	accountID, err := l.Link(ctx, Identity{Provider: "github", Subject: "b", AuthTime: now}, c)
+	require.NoError(t, err)
+	assert.Equal(t, "google:a", accountID)
+
+	err = l.Unlink(ctx, "other", "magiclink", "c")
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+}
+
+func TestAccountLinking_SameSubjectAcrossProviders(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	l := NewAccountLinker(memstore.New(), 10*time.Minute)
+	ctx := t.Context()
+
+	accountID, err := l.Link(ctx, Identity{Provider: "google", Subject: "123", AuthTime: now}, Identity{Provider: "github", Subject: "456", AuthTime: now})
+	require.NoError(t, err)
+
+	// An unlinked identity from another provider with the same subject isn't
+	// seen as the account.
+	resolved, err := l.ResolveAccount(ctx, "slack", "123")
+	require.NoError(t, err)
+	assert.NotEqual(t, accountID, resolved)
+
+	// And linking it to another account isn't blocked by the first account.
+	_, err = l.Link(ctx, Identity{Provider: "discord", Subject: "789", AuthTime: now}, Identity{Provider: "slack", Subject: "123", AuthTime: now})
+	require.NoError(t, err)
+
+	// A linked identity with the account's bare subject can be unlinked.
+	_, err = l.Link(ctx, Identity{Provider: "google", Subject: "123", AuthTime: now}, Identity{Provider: "magiclink", Subject: "123", AuthTime: now})
+	require.NoError(t, err)
+	require.NoError(t, l.Unlink(ctx, accountID, "magiclink", "123"))
+}
+
+func TestAccountLinking_RequiresFreshLogin(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	l := NewAccountLinker(memstore.New(), 10*time.Minute)
+	svc := &impl{}
+
+	fresh := Identity{Provider: "google", Subject: "g-1", SessionID: "s1", AuthTime: now}
+	stale := Identity{Provider: "github", Subject: "gh-1", SessionID: "s2", AuthTime: now.Add(-time.Hour)}
+
+	staleToken, err := IdentityToken(t.Context(), stale)
+	require.NoError(t, err)
+	_, err = svc.LinkAccount(linkCtx(t, l, fresh), &LinkAccountRequest{Token: staleToken})
+	require.ErrorIs(t, err, ErrLoginNotFresh)
+
+	freshToken, err := IdentityToken(t.Context(), fresh)
+	require.NoError(t, err)
+	_, err = svc.LinkAccount(linkCtx(t, l, stale), &LinkAccountRequest{Token: freshToken})
+	require.ErrorIs(t, err, ErrLoginNotFresh)
+
+	_, err = svc.UnlinkAccount(linkCtx(t, l, stale), &UnlinkAccountRequest{Provider: "google", Subject: "g-1"})
+	require.ErrorIs(t, err, ErrLoginNotFresh)
+}
+
+func TestAccountLinking_Disabled(t *testing.T) {
+	svc := &impl{}
+	ctx := WithIdentityForTest(t.Context(), Identity{Provider: "google", Subject: "g-1", AuthTime: time.Now()})
+
+	_, err := svc.LinkAccount(ctx, &LinkAccountRequest{Token: "x"})
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+	_, err = svc.UnlinkAccount(ctx, &UnlinkAccountRequest{Provider: "github", Subject: "gh-1"})
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+}
@@ -53,7 +53,11 @@ type Claims struct {
 	AuthTime      *jwt.NumericDate `json:"auth_time,omitempty"`
 
 	// Custom claims.
-	Provider string `json:"idp"`
+	Provider   string   `json:"idp"`
+	RememberMe bool     `json:"rmb,omitempty"`
+	MFA        bool     `json:"mfa,omitempty"`
+	Groups     []string `json:"grp,omitempty"`
+	Extras     Extras   `json:"ext,omitzero"`
 
 	// Delegation claims (optional, only present when identity was assumed).
 	DelegatorSub       string `json:"delegator_sub,omitempty"`
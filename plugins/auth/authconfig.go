@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/serverutil"
 )
 
 // fallbackSigningKey is an ephemeral, per-process key used only when a token
@@ -36,12 +37,83 @@ func init() {
 			Description: "JWT signing key for identity tokens",
 			Type:        "string",
 		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.previousSigningKeys",
+			Description: "Previous signing keys, still accepted when verifying signed URLs during key rotation",
+			Type:        "[]string",
+		},
 		prefab.ConfigKeyInfo{
 			Key:         "auth.expiration",
 			Description: "How long identity tokens should be valid for",
 			Type:        "duration",
 			Default:     "24h",
 		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.tokenCache.size",
+			Description: "How many verified identity tokens to cache, skipping signature checks for reused tokens (0 disables)",
+			Type:        "int",
+			Default:     "10000",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.enrichment.maxSize",
+			Description: "Maximum total size, in bytes, of data added to tokens by enrichers (0 disables the limit)",
+			Type:        "int",
+			Default:     "4096",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.extras.maxSize",
+			Description: "Maximum total size, in bytes, of an identity's extras (0 disables the limit)",
+			Type:        "int",
+			Default:     "8192",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.rememberMe.expiration",
+			Description: "How long tokens from remember me logins are valid for, other logins get session cookies (disabled if not set)",
+			Type:        "duration",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.name",
+			Description: "Name of the identity cookie",
+			Type:        "string",
+			Default:     "pf-id",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.domain",
+			Description: "Domain for the identity cookie, empty for a host-only cookie",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.path",
+			Description: "Path for the identity cookie",
+			Type:        "string",
+			Default:     "/",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.sameSite",
+			Description: "SameSite policy for the identity cookie: lax, strict or none",
+			Type:        "string",
+			Default:     "lax",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.secure",
+			Description: "Whether the identity cookie is HTTPS only, defaults to true for https server addresses",
+			Type:        "bool",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.cookie.maxAge",
+			Description: "Lifetime of the identity cookie, defaults to auth.expiration",
+			Type:        "duration",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.logout.frontChannelUris",
+			Description: "Front-channel logout endpoints of other apps sharing the login, loaded in iframes on logout",
+			Type:        "[]string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.logout.redirectOrigins",
+			Description: "Origins, other than the server's, that logout may redirect to",
+			Type:        "[]string",
+		},
 		prefab.ConfigKeyInfo{
 			Key:         "auth.delegation.enabled",
 			Description: "Enable identity delegation (admin assume user)",
@@ -60,6 +132,60 @@ func init() {
 			Type:        "duration",
 			Default:     "",
 		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.enabled",
+			Description: "Throttle failed logins per identity and per IP",
+			Type:        "bool",
+			Default:     "false",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.maxFailures",
+			Description: "Failed logins allowed for an identity within the window before lockout",
+			Type:        "int",
+			Default:     "5",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.maxFailuresPerIP",
+			Description: "Failed logins allowed from an IP within the window before lockout",
+			Type:        "int",
+			Default:     "20",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.window",
+			Description: "How long failed logins are counted for",
+			Type:        "duration",
+			Default:     "15m",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.lockout",
+			Description: "Duration of the first lockout, doubled for each consecutive lockout",
+			Type:        "duration",
+			Default:     "1m",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.throttle.maxLockout",
+			Description: "Maximum lockout duration",
+			Type:        "duration",
+			Default:     "1h",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.anomalyDetection.enabled",
+			Description: "Publish suspicious login events for logins from a new country or device",
+			Type:        "bool",
+			Default:     "false",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.accountLinking.enabled",
+			Description: "Allow users to link identities from several providers to one account",
+			Type:        "bool",
+			Default:     "false",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.accountLinking.maxAuthAge",
+			Description: "How recent logins must be to link or unlink identities",
+			Type:        "duration",
+			Default:     "10m",
+		},
 	)
 }
 
@@ -75,6 +201,16 @@ func injectSigningKey(b string) prefab.ConfigInjector {
 	}
 }
 
+func injectURLSigningKeys(current string, previous []string) prefab.ConfigInjector {
+	prev := make([][]byte, 0, len(previous))
+	for _, k := range previous {
+		prev = append(prev, []byte(k))
+	}
+	return func(ctx context.Context) context.Context {
+		return serverutil.WithSigningKeys(ctx, []byte(current), prev...)
+	}
+}
+
 func injectExpiration(d time.Duration) prefab.ConfigInjector {
 	return func(ctx context.Context) context.Context {
 		return context.WithValue(ctx, tokenExpiration{}, d)
@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -130,6 +132,67 @@ func TestSendIdentityCookie(t *testing.T) {
 		// Verify the expiration is set to use the configured duration (48 hours)
 		assert.Contains(t, cookieStr, "pf-id=test-token")
 	})
+
+	t.Run("CustomAttributes", func(t *testing.T) {
+		mockTransport := &mockServerTransportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(t.Context(), mockTransport)
+		ctx = serverutil.WithAddress(ctx, "https://app.example.com")
+		secure := false
+		ctx = injectCookieConfig(CookieConfig{
+			Name:     "session",
+			Domain:   "example.com",
+			Path:     "/app",
+			SameSite: http.SameSiteStrictMode,
+			Secure:   &secure,
+			MaxAge:   time.Hour,
+		})(ctx)
+
+		err := SendIdentityCookie(ctx, "test-token")
+		require.NoError(t, err)
+
+		setCookieHeaders := (*mockTransport.md)["grpc-metadata-set-cookie"]
+		require.Len(t, setCookieHeaders, 1)
+		cookieStr := setCookieHeaders[0]
+		assert.Contains(t, cookieStr, "session=test-token")
+		assert.Contains(t, cookieStr, "Domain=example.com")
+		assert.Contains(t, cookieStr, "Path=/app")
+		assert.Contains(t, cookieStr, "SameSite=Strict")
+		assert.Contains(t, cookieStr, "Max-Age=3600")
+		assert.NotContains(t, cookieStr, "Secure")
+	})
+
+	t.Run("SplitsLargeTokens", func(t *testing.T) {
+		mockTransport := &mockServerTransportStream{}
+		ctx := grpc.NewContextWithServerTransportStream(t.Context(), mockTransport)
+		ctx = serverutil.WithAddress(ctx, "http://localhost:8000")
+
+		token := strings.Repeat("abcdefghij", 1000)
+		err := SendIdentityCookie(ctx, token)
+		require.NoError(t, err)
+
+		setCookieHeaders := (*mockTransport.md)["grpc-metadata-set-cookie"]
+		require.Len(t, setCookieHeaders, 4)
+		var pairs []string
+		for _, h := range setCookieHeaders {
+			c, err := http.ParseSetCookie(h)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(h), 4096)
+			pairs = append(pairs, c.Name+"="+c.Value)
+		}
+		assert.True(t, strings.HasPrefix(pairs[0], "pf-id.0="))
+		assert.Equal(t, "pf-id=3", pairs[3])
+
+		// The chunks are joined when the cookies are sent back.
+		in := metadata.NewIncomingContext(t.Context(), metadata.Pairs("grpcgateway-cookie", strings.Join(pairs, "; ")))
+		got, ok := identityCookieToken(in)
+		require.True(t, ok)
+		assert.Equal(t, token, got)
+
+		// A missing chunk is treated as no cookie.
+		in = metadata.NewIncomingContext(t.Context(), metadata.Pairs("grpcgateway-cookie", strings.Join(pairs[1:], "; ")))
+		_, ok = identityCookieToken(in)
+		assert.False(t, ok)
+	})
 }
 
 // mockServerTransportStream implements grpc.ServerTransportStream for testing
@@ -142,6 +205,9 @@ func (m *mockServerTransportStream) Method() string {
 }
 
 func (m *mockServerTransportStream) SetHeader(md metadata.MD) error {
+	if m.md != nil {
+		md = metadata.Join(*m.md, md)
+	}
 	m.md = &md
 	return nil
 }
@@ -1,17 +1,36 @@
 package auth
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/serverutil"
+)
 
 const (
 	LoginEvent      = "auth.login"
 	LogoutEvent     = "auth.logout"
 	DelegationEvent = "auth.delegation"
+
+	// SuspiciousLoginEvent is published with SuspiciousLoginEventData when a
+	// login comes from a new country or device. Requires anomaly detection.
+	SuspiciousLoginEvent = "auth.suspicious_login"
+
+	// AccountLinkedEvent and AccountUnlinkedEvent are published with
+	// AccountLinkEventData when identities are linked to or unlinked from an
+	// account.
+	AccountLinkedEvent   = "auth.account_linked"
+	AccountUnlinkedEvent = "auth.account_unlinked"
 )
 
 // AuthEvent is an event that is emitted when an authentication event occurs.
 type AuthEvent struct {
 	Identity  Identity
 	Timestamp time.Time // When the event occurred
+
+	// Information about the client that triggered the event, see
+	// serverutil.RequestInfoFromContext.
+	Request serverutil.RequestInfo
 }
 
 // NewAuthEvent creates an AuthEvent with the current timestamp.
@@ -22,6 +41,14 @@ func NewAuthEvent(identity Identity) AuthEvent {
 	}
 }
 
+// NewAuthEventFromContext creates an AuthEvent with the current timestamp and
+// information about the client making the request.
+func NewAuthEventFromContext(ctx context.Context, identity Identity) AuthEvent {
+	e := NewAuthEvent(identity)
+	e.Request, _ = serverutil.RequestInfoFromContext(ctx)
+	return e
+}
+
 // DelegationEventData is emitted when an admin assumes another user's identity.
 type DelegationEventData struct {
 	// The admin user who is assuming the identity
@@ -9,8 +9,12 @@ import (
 
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
 	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/lock"
 	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/serverutil"
 	"google.golang.org/grpc/codes"
 )
 
@@ -27,6 +31,17 @@ func WithSigningKey(signingKey string) AuthOption {
 	}
 }
 
+// WithPreviousSigningKeys sets signing keys which are no longer used for
+// signing, but are still accepted when verifying signed URLs. This allows the
+// signing key to be rotated without breaking outstanding links.
+//
+// Config key: `auth.previousSigningKeys`.
+func WithPreviousSigningKeys(keys ...string) AuthOption {
+	return func(p *AuthPlugin) {
+		p.previousSigningKeys = keys
+	}
+}
+
 // WithExpiration sets the expiration to use when signing JWT tokens.
 func WithExpiration(expiration time.Duration) AuthOption {
 	return func(p *AuthPlugin) {
@@ -86,8 +101,65 @@ func WithAdminChecker(checker AdminChecker) AuthOption {
 	}
 }
 
+// WithLoginThrottle enables throttling of failed logins, locking out an
+// identity or IP after too many failures. Counters are stored using the storage
+// plugin if registered, otherwise in memory.
+//
+// Config keys: `auth.throttle.*`.
+func WithLoginThrottle(cfg ThrottleConfig) AuthOption {
+	return func(p *AuthPlugin) {
+		p.throttle = &cfg
+	}
+}
+
+// WithAnomalyDetection enables or disables publishing of SuspiciousLoginEvent
+// when a login comes from a country or device that hasn't been seen for the
+// identity. Countries and devices are populated by the requestinfo plugin.
+//
+// Config key: `auth.anomalyDetection.enabled`.
+func WithAnomalyDetection(enabled bool) AuthOption {
+	return func(p *AuthPlugin) {
+		p.detectAnomaly = enabled
+	}
+}
+
+// WithStepUpHandler configures a handler that is called for anomalous logins,
+// allowing the application to require additional verification. Implies
+// WithAnomalyDetection(true).
+func WithStepUpHandler(h StepUpHandler) AuthOption {
+	return func(p *AuthPlugin) {
+		p.detectAnomaly = true
+		p.stepUp = h
+	}
+}
+
+// WithAccountLinking enables or disables account linking, which lets users link
+// identities from several providers so that they resolve to the same subject.
+// Links are stored using the storage plugin if registered, otherwise in memory.
+//
+// Config key: `auth.accountLinking.enabled`.
+func WithAccountLinking(enabled bool) AuthOption {
+	return func(p *AuthPlugin) {
+		p.accountLinking = enabled
+	}
+}
+
+// WithAccountLinkingMaxAuthAge sets how recent both logins must be for
+// identities to be linked or unlinked.
+//
+// Config key: `auth.accountLinking.maxAuthAge`.
+func WithAccountLinkingMaxAuthAge(d time.Duration) AuthOption {
+	return func(p *AuthPlugin) {
+		p.linkMaxAuthAge = d
+	}
+}
+
 // Plugin returns a new AuthPlugin.
 func Plugin(opts ...AuthOption) *AuthPlugin {
+	// The plugin is usually constructed before prefab.New, which is where
+	// registered defaults, such as auth.expiration, would otherwise be loaded.
+	config.EnsureDefaultsLoaded(prefab.Config)
+
 	// Get signing key from config, or generate a random one with a warning
 	signingKey := prefab.ConfigString("auth.signingKey")
 	if signingKey == "" {
@@ -98,17 +170,50 @@ func Plugin(opts ...AuthOption) *AuthPlugin {
 	}
 
 	ap := &AuthPlugin{
-		authService:   &impl{},
-		jwtSigningKey: signingKey,
-		jwtExpiration: prefab.ConfigMustDuration("auth.expiration"),
+		authService:         &impl{},
+		jwtSigningKey:       signingKey,
+		previousSigningKeys: prefab.ConfigStrings("auth.previousSigningKeys"),
+		jwtExpiration:       prefab.ConfigMustDuration("auth.expiration"),
 		identityExtractors: []IdentityExtractor{
 			identityFromAuthHeader,
 			identityFromCookie,
 		},
 		delegationEnabled: prefab.ConfigBool("auth.delegation.enabled"),
 		requireReason:     true, // Default to true, can be overridden via config or WithDelegationRequireReason
+		accountLinking:    prefab.ConfigBool("auth.accountLinking.enabled"),
+		linkMaxAuthAge:    prefab.ConfigDuration("auth.accountLinking.maxAuthAge"),
+
+		rememberMeExpiration: prefab.ConfigDuration("auth.rememberMe.expiration"),
+		tokenCacheSize:       prefab.ConfigInt("auth.tokenCache.size"),
+		maxEnrichmentSize:    prefab.ConfigInt("auth.enrichment.maxSize"),
+		maxExtrasSize:        prefab.ConfigInt("auth.extras.maxSize"),
+		frontChannelURIs:     prefab.ConfigStrings("auth.logout.frontChannelUris"),
+		redirectOrigins:      prefab.ConfigStrings("auth.logout.redirectOrigins"),
 	}
 
+	ap.cookie = CookieConfig{
+		Name:     prefab.ConfigString("auth.cookie.name"),
+		Domain:   prefab.ConfigString("auth.cookie.domain"),
+		Path:     prefab.ConfigString("auth.cookie.path"),
+		SameSite: parseSameSite(prefab.ConfigString("auth.cookie.sameSite")),
+		MaxAge:   prefab.ConfigDuration("auth.cookie.maxAge"),
+	}
+	if prefab.Config.Exists("auth.cookie.secure") {
+		secure := prefab.ConfigBool("auth.cookie.secure")
+		ap.cookie.Secure = &secure
+	}
+
+	if prefab.ConfigBool("auth.throttle.enabled") {
+		ap.throttle = &ThrottleConfig{
+			MaxFailures:      prefab.ConfigInt("auth.throttle.maxFailures"),
+			MaxFailuresPerIP: prefab.ConfigInt("auth.throttle.maxFailuresPerIP"),
+			Window:           prefab.ConfigDuration("auth.throttle.window"),
+			Lockout:          prefab.ConfigDuration("auth.throttle.lockout"),
+			MaxLockout:       prefab.ConfigDuration("auth.throttle.maxLockout"),
+		}
+	}
+	ap.detectAnomaly = prefab.ConfigBool("auth.anomalyDetection.enabled")
+
 	// Override with config if set
 	if prefab.Config.Exists("auth.delegation.requireReason") {
 		ap.requireReason = prefab.ConfigBool("auth.delegation.requireReason")
@@ -140,10 +245,15 @@ func randomSigningKey() string {
 type AuthPlugin struct {
 	authService *impl
 
-	jwtSigningKey      string
-	jwtExpiration      time.Duration
-	blocklist          Blocklist
-	identityExtractors []IdentityExtractor
+	jwtSigningKey        string
+	previousSigningKeys  []string
+	jwtExpiration        time.Duration
+	rememberMeExpiration time.Duration
+	blocklist            Blocklist
+	tokenCacheSize       int
+	tokenCache           *tokenCache
+	identityExtractors   []IdentityExtractor
+	cookie               CookieConfig
 
 	// Delegation configuration
 	delegationEnabled    bool
@@ -152,6 +262,27 @@ type AuthPlugin struct {
 	adminChecker         AdminChecker
 	identityValidator    IdentityValidator
 	authorizer           Authorizer // Interface to avoid import cycle
+
+	// Login protection
+	throttle      *ThrottleConfig
+	detectAnomaly bool
+	stepUp        StepUpHandler
+	loginGuard    *LoginGuard
+	loginHooks    []LoginHook
+
+	// Identity enrichment
+	enrichers         []*enricher
+	maxEnrichmentSize int
+	maxExtrasSize     int
+
+	// Logout
+	frontChannelURIs []string
+	redirectOrigins  []string
+
+	// Account linking
+	accountLinking bool
+	linkMaxAuthAge time.Duration
+	accountLinker  *AccountLinker
 }
 
 // From prefab.Plugin.
@@ -163,13 +294,26 @@ func (ap *AuthPlugin) Name() string {
 func (ap *AuthPlugin) OptDeps() []string {
 	return []string{
 		storage.PluginName,
+		lock.PluginName,
 	}
 }
 
 // From prefab.InitializablePlugin.
 func (ap *AuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 	ap.initBlocklist(ctx, r)
+	if ap.tokenCacheSize > 0 {
+		ap.tokenCache = newTokenCache(ap.tokenCacheSize)
+	}
 	ap.initDelegation(ctx, r)
+	if err := ap.initLoginGuard(ctx, r); err != nil {
+		return err
+	}
+	if err := ap.initAccountLinker(ctx, r); err != nil {
+		return err
+	}
+	if err := ap.initLogout(); err != nil {
+		return err
+	}
 
 	// Inject delegation config into authService
 	ap.authService.delegationEnabled = ap.delegationEnabled
@@ -177,6 +321,7 @@ func (ap *AuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 	ap.authService.requireReason = ap.requireReason
 	ap.authService.adminChecker = ap.adminChecker
 	ap.authService.identityValidator = ap.identityValidator
+	ap.authService.rememberMeExpiration = ap.rememberMeExpiration
 
 	return nil
 }
@@ -197,6 +342,50 @@ func (ap *AuthPlugin) initBlocklist(ctx context.Context, r *prefab.Registry) {
 	}
 }
 
+func (ap *AuthPlugin) initLoginGuard(ctx context.Context, r *prefab.Registry) error {
+	if ap.throttle == nil && !ap.detectAnomaly {
+		return nil
+	}
+
+	var store storage.Store = memstore.New()
+	if sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin); ok && sp != nil {
+		if err := sp.InitModel(&LoginAttempts{}); err != nil {
+			return err
+		}
+		if err := sp.InitModel(&LoginHistory{}); err != nil {
+			return err
+		}
+		store = sp
+	} else {
+		logging.Warn(ctx, "auth: no storage plugin, login counters and history will be kept in memory")
+	}
+
+	ap.loginGuard = NewLoginGuard(store, ap.throttle, ap.detectAnomaly, ap.stepUp)
+	if lp, ok := r.Get(lock.PluginName).(*lock.LockPlugin); ok && lp != nil {
+		ap.loginGuard.locker = lp
+	}
+	return nil
+}
+
+func (ap *AuthPlugin) initAccountLinker(ctx context.Context, r *prefab.Registry) error {
+	if !ap.accountLinking {
+		return nil
+	}
+
+	var store storage.Store = memstore.New()
+	if sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin); ok && sp != nil {
+		if err := sp.InitModel(&AccountLink{}); err != nil {
+			return err
+		}
+		store = sp
+	} else {
+		logging.Warn(ctx, "auth: no storage plugin, account links will be kept in memory")
+	}
+
+	ap.accountLinker = NewAccountLinker(store, ap.linkMaxAuthAge)
+	return nil
+}
+
 func (ap *AuthPlugin) initDelegation(ctx context.Context, r *prefab.Registry) {
 	if !ap.delegationEnabled {
 		return
@@ -245,17 +434,31 @@ func (ap *AuthPlugin) createAuthorizerWrapper() AdminChecker {
 
 // From prefab.OptionProvider.
 func (ap *AuthPlugin) ServerOptions() []prefab.ServerOption {
-	return []prefab.ServerOption{
+	opts := []prefab.ServerOption{
 		prefab.WithGRPCService(&AuthService_ServiceDesc, ap.authService),
 		prefab.WithGRPCGateway(RegisterAuthServiceHandlerFromEndpoint),
+		prefab.WithGRPCInterceptor(stepUpInterceptor, prefab.InterceptorPhase(prefab.PhaseAuth),
+			prefab.InterceptorMethodOptions(E_MaxAuthAge, E_RequireMfa)),
 		prefab.WithRequestConfig(injectSigningKey(ap.jwtSigningKey)),
+		prefab.WithRequestConfig(injectURLSigningKeys(ap.jwtSigningKey, ap.previousSigningKeys)),
 		prefab.WithRequestConfig(injectExpiration(ap.jwtExpiration)),
+		prefab.WithRequestConfig(injectMaxExtrasSize(ap.maxExtrasSize)),
+		prefab.WithRequestConfig(injectCookieConfig(ap.cookie)),
 		prefab.WithRequestConfig(ap.injectBlocklist),
+		prefab.WithRequestConfig(ap.injectTokenCache),
 		prefab.WithRequestConfig(ap.injectIdentityExtractors),
+		prefab.WithRequestConfig(ap.injectLoginGuard),
+		prefab.WithRequestConfig(ap.injectLoginHooks),
+		prefab.WithRequestConfig(ap.injectEnrichers),
+		prefab.WithRequestConfig(ap.injectAccountLinker),
+		prefab.WithRequestConfig(injectOutgoingCredentials),
+		prefab.WithRequestConfig(injectLogSubject),
 	}
+	return append(opts, ap.frontChannelOptions()...)
 }
 
 // AddLoginHandler can be called by other plugins to register login handlers.
+// Use SetProviderInfo to describe the provider to login screens.
 func (ap *AuthPlugin) AddLoginHandler(provider string, h LoginHandler) {
 	ap.authService.AddLoginHandler(provider, h)
 }
@@ -280,6 +483,18 @@ func (ap *AuthPlugin) PrependIdentityExtractor(provider IdentityExtractor) {
 	ap.identityExtractors = append([]IdentityExtractor{provider}, ap.identityExtractors...)
 }
 
+// IdentityToken returns a token for identity, signed with the plugin's key and
+// bound to the configured server address. Unlike the package level function it
+// doesn't require a request context, which makes it useful for tests and
+// tooling that need to act as an arbitrary identity.
+func (ap *AuthPlugin) IdentityToken(ctx context.Context, identity Identity) (string, error) {
+	ctx = serverutil.WithAddress(ctx, prefab.ConfigString("address"))
+	ctx = injectSigningKey(ap.jwtSigningKey)(ctx)
+	ctx = injectExpiration(ap.jwtExpiration)(ctx)
+	ctx = injectMaxExtrasSize(ap.maxExtrasSize)(ctx)
+	return IdentityToken(ctx, identity)
+}
+
 func (ap *AuthPlugin) injectBlocklist(ctx context.Context) context.Context {
 	if ap.blocklist == nil {
 		return ctx
@@ -287,6 +502,43 @@ func (ap *AuthPlugin) injectBlocklist(ctx context.Context) context.Context {
 	return WithBlockist(ctx, ap.blocklist)
 }
 
+func (ap *AuthPlugin) injectTokenCache(ctx context.Context) context.Context {
+	if ap.tokenCache == nil {
+		return ctx
+	}
+	return withTokenCache(ctx, ap.tokenCache)
+}
+
+func (ap *AuthPlugin) injectLoginGuard(ctx context.Context) context.Context {
+	if ap.loginGuard == nil {
+		return ctx
+	}
+	return WithLoginGuard(ctx, ap.loginGuard)
+}
+
+func (ap *AuthPlugin) injectAccountLinker(ctx context.Context) context.Context {
+	if ap.accountLinker == nil {
+		return ctx
+	}
+	return WithAccountLinker(ctx, ap.accountLinker)
+}
+
+func injectOutgoingCredentials(ctx context.Context) context.Context {
+	return prefab.WithOutgoingCredentials(ctx, outgoingCredentials)
+}
+
+// injectLogSubject allows verbose logging rules to match on the caller's
+// subject, see logging.EnableVerbose.
+func injectLogSubject(ctx context.Context) context.Context {
+	return logging.WithSubjectFunc(ctx, func(ctx context.Context) string {
+		identity, err := IdentityFromContext(ctx)
+		if err != nil {
+			return ""
+		}
+		return identity.Subject
+	})
+}
+
 func (ap *AuthPlugin) injectIdentityExtractors(ctx context.Context) context.Context {
 	return WithIdentityExtractors(ctx, ap.identityExtractors...)
 }
@@ -25,6 +25,44 @@ func TestPlugin(t *testing.T) {
 	assert.Len(t, p.identityExtractors, 2) // default extractors
 }
 
+func TestPluginConfigDefaults(t *testing.T) {
+	// Plugins are usually constructed before prefab.New, so registered defaults
+	// must be available without it.
+	p := Plugin()
+	assert.Equal(t, "pf-id", p.cookie.Name)
+}
+
+func TestPluginConfig_LoginGuard(t *testing.T) {
+	p := Plugin()
+	assert.Nil(t, p.throttle)
+	assert.False(t, p.detectAnomaly)
+
+	for key, value := range map[string]any{
+		"auth.throttle.enabled":         true,
+		"auth.throttle.maxFailures":     3,
+		"auth.anomalyDetection.enabled": true,
+	} {
+		old, existed := prefab.Config.Get(key), prefab.Config.Exists(key)
+		prefab.Config.Set(key, value)
+		t.Cleanup(func() {
+			if existed {
+				prefab.Config.Set(key, old)
+			} else {
+				prefab.Config.Delete(key)
+			}
+		})
+	}
+
+	p = Plugin()
+	require.NotNil(t, p.throttle)
+	assert.Equal(t, 3, p.throttle.MaxFailures)
+	assert.True(t, p.detectAnomaly)
+
+	// Options take precedence over config.
+	p = Plugin(WithAnomalyDetection(false))
+	assert.False(t, p.detectAnomaly)
+}
+
 func TestWithSigningKey(t *testing.T) {
 	p := Plugin(
 		WithSigningKey("custom-key"),
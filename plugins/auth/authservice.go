@@ -3,7 +3,6 @@ package auth
 import (
 	"context"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/dpup/prefab/errors"
@@ -30,12 +29,24 @@ type impl struct {
 	UnimplementedAuthServiceServer
 	handlers map[string]LoginHandler
 
+	// Provider metadata for ListProviders, from provider plugins and options.
+	providerInfo      map[string]*ProviderInfo
+	providerOverrides map[string]*ProviderInfo
+
 	// Delegation configuration (injected from AuthPlugin)
 	delegationEnabled    bool
 	requireReason        bool
 	delegationExpiration time.Duration
 	adminChecker         AdminChecker
 	identityValidator    IdentityValidator
+
+	// Token lifetime for remember me logins, zero if disabled.
+	rememberMeExpiration time.Duration
+
+	// Logout configuration, see logout.go.
+	logoutHandlers   map[string]LogoutHandler
+	frontChannelURIs []string
+	redirectOrigins  []string
 }
 
 func (s *impl) AddLoginHandler(provider string, h LoginHandler) {
@@ -49,6 +60,7 @@ func (s *impl) Login(ctx context.Context, in *LoginRequest) (*LoginResponse, err
 	logging.Track(ctx, "auth.provider", in.Provider)
 	logging.Track(ctx, "auth.issueToken", in.IssueToken)
 	logging.Track(ctx, "auth.redirectUri", in.RedirectUri)
+	logging.Track(ctx, "auth.rememberMe", in.RememberMe)
 	logging.Info(ctx, "Login attempt")
 
 	if in.RedirectUri != "" && in.IssueToken {
@@ -58,7 +70,20 @@ func (s *impl) Login(ctx context.Context, in *LoginRequest) (*LoginResponse, err
 	// TODO: Verify redirect_uri is a path or has a valid host.
 
 	if h, ok := s.handlers[in.Provider]; ok {
-		resp, err := h(ctx, in)
+		guard := loginGuardFromContext(ctx)
+		var keys []string
+		if guard != nil {
+			keys = throttleKeys(ctx, in)
+			if err := guard.checkThrottle(ctx, keys...); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := h(s.loginContext(ctx, in), in)
+
+		if guard != nil {
+			s.recordAttempt(ctx, guard, keys, err)
+		}
 
 		// TODO: If the handler returns an error we may still want to send to the
 		// redirect_uri with an error message, so the user doesn't end on a raw JSON
@@ -81,41 +106,59 @@ func (s *impl) Login(ctx context.Context, in *LoginRequest) (*LoginResponse, err
 	return nil, errors.NewC("auth: unknown or unregistered provider", codes.InvalidArgument)
 }
 
+// recordAttempt updates the throttle counters based on the outcome of a login.
+// Only credential failures count towards a lockout.
+func (s *impl) recordAttempt(ctx context.Context, guard *LoginGuard, keys []string, err error) {
+	var gerr error
+	switch code := errors.Code(err); {
+	case err == nil:
+		gerr = guard.recordSuccess(ctx, keys...)
+	case code == codes.Unauthenticated || code == codes.PermissionDenied:
+		gerr = guard.recordFailure(ctx, keys...)
+	}
+	if gerr != nil {
+		logging.Errorw(ctx, "auth: failed to record login attempt", "error", gerr)
+	}
+}
+
 func (s *impl) Logout(ctx context.Context, in *LogoutRequest) (*LogoutResponse, error) {
 	id, err := identityFromCookie(ctx)
+	if errors.Is(err, ErrNotFound) {
+		// API clients can log out to block their bearer token.
+		id, err = identityFromAuthHeader(ctx)
+	}
 	if err != nil {
 		// TODO: Should double logout be idempotent?
 		return nil, err
 	}
 
+	r := in.RedirectUri
+	if r == "" {
+		r = serverutil.AddressFromContext(ctx)
+	}
+	if err := s.checkLogoutRedirect(ctx, r); err != nil {
+		return nil, err
+	}
+
 	// If enabled, block this token from future use.
 	if err := MaybeBlock(ctx, id.SessionID); err != nil {
 		logging.Errorw(ctx, "auth: failed to block tokenfor logout", "error", err)
 	}
 
-	address := serverutil.AddressFromContext(ctx)
-	isSecure := strings.HasPrefix(address, "https")
-
 	// Try to clear the cookie.
-	if err := serverutil.SendCookie(ctx, &http.Cookie{
-		Name:     IdentityTokenCookieName,
-		Value:    "[invalidated]",
-		Path:     "/",
-		Secure:   isSecure,
-		HttpOnly: true,
-		Expires:  time.Now().Add(-24 * time.Hour),
-		SameSite: http.SameSiteLaxMode,
-	}); err != nil {
+	if err := clearIdentityCookie(ctx); err != nil {
 		return nil, err
 	}
 
-	r := in.RedirectUri
-	if r == "" {
-		r = address
+	if in.EndSession {
+		r = s.endSession(ctx, id, r)
+	}
+	if r, err = s.propagateLogout(ctx, id, r); err != nil {
+		return nil, err
 	}
 
 	if bus := eventbus.FromContext(ctx); bus != nil {
-		bus.Publish(LogoutEvent, NewAuthEvent(id))
+		bus.Publish(LogoutEvent, NewAuthEventFromContext(ctx, id))
 	}
 
 	// For gateway requests, send the HTTP headers.
@@ -139,6 +182,9 @@ func (s *impl) Identity(ctx context.Context, in *IdentityRequest) (*IdentityResp
 		Email:         i.Email,
 		EmailVerified: i.EmailVerified,
 		Name:          i.Name,
+		RememberMe:    i.RememberMe,
+		Mfa:           i.MFA,
+		Groups:        i.Groups.Names(),
 	}
 	if i.Delegation != nil {
 		resp.Delegation = i.Delegation
@@ -261,8 +307,12 @@ func (s *impl) createDelegatedIdentity(ctx context.Context, adminIdentity Identi
 		Subject:   in.Subject,
 		SessionID: generateSessionID(),
 		AuthTime:  now,
+		// Delegated identities are only as strong as the admin's login.
+		MFA: adminIdentity.MFA,
 		// Note: Email, Name, EmailVerified are NOT populated
-		// The assumed identity only has provider + subject
+		// The assumed identity only has provider + subject, its own extras are
+		// added by enrichers when the token is issued.
+		Extras: delegatedExtras(adminIdentity),
 		Delegation: &DelegationInfo{
 			DelegatorSub:       adminIdentity.Subject,
 			DelegatorProvider:  adminIdentity.Provider,
@@ -7,6 +7,7 @@
 package auth
 
 import (
+	_ "github.com/dpup/prefab/redact"
 	_ "google.golang.org/genproto/googleapis/api/annotations"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
@@ -36,7 +37,11 @@ type LoginRequest struct {
 	IssueToken bool `protobuf:"varint,3,opt,name=issue_token,json=issueToken,proto3" json:"issue_token,omitempty"`
 	// The URL where the user should be redirected after the cookie is set.
 	// Incompatible with `issue_token`.
-	RedirectUri   string `protobuf:"bytes,4,opt,name=redirect_uri,json=redirectUri,proto3" json:"redirect_uri,omitempty"`
+	RedirectUri string `protobuf:"bytes,4,opt,name=redirect_uri,json=redirectUri,proto3" json:"redirect_uri,omitempty"`
+	// Whether the login should be remembered, issuing a long-lived token and
+	// cookie instead of one scoped to the browser session. Ignored unless
+	// remember me is enabled via `auth.rememberMe.expiration`.
+	RememberMe    bool `protobuf:"varint,5,opt,name=remember_me,json=rememberMe,proto3" json:"remember_me,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -99,6 +104,13 @@ func (x *LoginRequest) GetRedirectUri() string {
 	return ""
 }
 
+func (x *LoginRequest) GetRememberMe() bool {
+	if x != nil {
+		return x.RememberMe
+	}
+	return false
+}
+
 // The login response.
 type LoginResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -173,7 +185,11 @@ func (x *LoginResponse) GetRedirectUri() string {
 type LogoutRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// The URL where the user should be redirected after a successful logout.
-	RedirectUri   string `protobuf:"bytes,4,opt,name=redirect_uri,json=redirectUri,proto3" json:"redirect_uri,omitempty"`
+	RedirectUri string `protobuf:"bytes,4,opt,name=redirect_uri,json=redirectUri,proto3" json:"redirect_uri,omitempty"`
+	// Whether to also end the user's session at the identity provider, for
+	// providers which support it, such as via OpenID Connect RP-initiated
+	// logout. Ignored for delegated identities.
+	EndSession    bool `protobuf:"varint,5,opt,name=end_session,json=endSession,proto3" json:"end_session,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -215,6 +231,13 @@ func (x *LogoutRequest) GetRedirectUri() string {
 	return ""
 }
 
+func (x *LogoutRequest) GetEndSession() bool {
+	if x != nil {
+		return x.EndSession
+	}
+	return false
+}
+
 // The logout response.
 type LogoutResponse struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -263,6 +286,174 @@ func (x *LogoutResponse) GetRedirectUri() string {
 	return ""
 }
 
+// Empty request object.
+type ListProvidersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersRequest) Reset() {
+	*x = ListProvidersRequest{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersRequest) ProtoMessage() {}
+
+func (x *ListProvidersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersRequest.ProtoReflect.Descriptor instead.
+func (*ListProvidersRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{4}
+}
+
+// The login providers configured on the server.
+type ListProvidersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Providers, ordered by name.
+	Providers     []*ProviderInfo `protobuf:"bytes,1,rep,name=providers,proto3" json:"providers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListProvidersResponse) Reset() {
+	*x = ListProvidersResponse{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListProvidersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListProvidersResponse) ProtoMessage() {}
+
+func (x *ListProvidersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListProvidersResponse.ProtoReflect.Descriptor instead.
+func (*ListProvidersResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListProvidersResponse) GetProviders() []*ProviderInfo {
+	if x != nil {
+		return x.Providers
+	}
+	return nil
+}
+
+// Describes a login provider, for rendering login options.
+type ProviderInfo struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the provider, to be passed as `provider` in login requests.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Human readable name, e.g. "Google". Defaults to the provider's name.
+	DisplayName string `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	// Hint for the icon to show with the provider, e.g. "google". Clients map
+	// hints to their own assets.
+	Icon string `protobuf:"bytes,3,opt,name=icon,proto3" json:"icon,omitempty"`
+	// Whether logging in without credentials redirects the user to the provider,
+	// for example to an OAuth consent screen.
+	Redirect bool `protobuf:"varint,4,opt,name=redirect,proto3" json:"redirect,omitempty"`
+	// Names of the credentials accepted by the provider, if the user can log in
+	// by entering them, e.g. ["email", "password"].
+	Credentials   []string `protobuf:"bytes,5,rep,name=credentials,proto3" json:"credentials,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProviderInfo) Reset() {
+	*x = ProviderInfo{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderInfo) ProtoMessage() {}
+
+func (x *ProviderInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderInfo.ProtoReflect.Descriptor instead.
+func (*ProviderInfo) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ProviderInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetIcon() string {
+	if x != nil {
+		return x.Icon
+	}
+	return ""
+}
+
+func (x *ProviderInfo) GetRedirect() bool {
+	if x != nil {
+		return x.Redirect
+	}
+	return false
+}
+
+func (x *ProviderInfo) GetCredentials() []string {
+	if x != nil {
+		return x.Credentials
+	}
+	return nil
+}
+
 // Empty request object.
 type ConfigRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -272,7 +463,7 @@ type ConfigRequest struct {
 
 func (x *ConfigRequest) Reset() {
 	*x = ConfigRequest{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[4]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -284,7 +475,7 @@ func (x *ConfigRequest) String() string {
 func (*ConfigRequest) ProtoMessage() {}
 
 func (x *ConfigRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[4]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -297,7 +488,7 @@ func (x *ConfigRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConfigRequest.ProtoReflect.Descriptor instead.
 func (*ConfigRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{4}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{7}
 }
 
 // Configuration information to help clients facilitate login.
@@ -315,7 +506,7 @@ type ConfigResponse struct {
 
 func (x *ConfigResponse) Reset() {
 	*x = ConfigResponse{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[5]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -327,7 +518,7 @@ func (x *ConfigResponse) String() string {
 func (*ConfigResponse) ProtoMessage() {}
 
 func (x *ConfigResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[5]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -340,7 +531,7 @@ func (x *ConfigResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ConfigResponse.ProtoReflect.Descriptor instead.
 func (*ConfigResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{5}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ConfigResponse) GetCsrfToken() string {
@@ -366,7 +557,7 @@ type IdentityRequest struct {
 
 func (x *IdentityRequest) Reset() {
 	*x = IdentityRequest{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[6]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -378,7 +569,7 @@ func (x *IdentityRequest) String() string {
 func (*IdentityRequest) ProtoMessage() {}
 
 func (x *IdentityRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[6]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -391,7 +582,7 @@ func (x *IdentityRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IdentityRequest.ProtoReflect.Descriptor instead.
 func (*IdentityRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{6}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{9}
 }
 
 // Information about the authenticated identity.
@@ -407,14 +598,20 @@ type IdentityResponse struct {
 	// A name associated with the identity, if available.
 	Name string `protobuf:"bytes,5,opt,name=name,proto3" json:"name,omitempty"`
 	// Delegation information if this identity was assumed by an admin.
-	Delegation    *DelegationInfo `protobuf:"bytes,6,opt,name=delegation,proto3" json:"delegation,omitempty"`
+	Delegation *DelegationInfo `protobuf:"bytes,6,opt,name=delegation,proto3" json:"delegation,omitempty"`
+	// Whether the identity comes from a long-lived "remember me" login.
+	RememberMe bool `protobuf:"varint,7,opt,name=remember_me,json=rememberMe,proto3" json:"remember_me,omitempty"`
+	// Whether the login used multi-factor authentication.
+	Mfa bool `protobuf:"varint,8,opt,name=mfa,proto3" json:"mfa,omitempty"`
+	// Groups the identity belongs to at the identity provider, if available.
+	Groups        []string `protobuf:"bytes,9,rep,name=groups,proto3" json:"groups,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *IdentityResponse) Reset() {
 	*x = IdentityResponse{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[7]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -426,7 +623,7 @@ func (x *IdentityResponse) String() string {
 func (*IdentityResponse) ProtoMessage() {}
 
 func (x *IdentityResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[7]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -439,7 +636,7 @@ func (x *IdentityResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use IdentityResponse.ProtoReflect.Descriptor instead.
 func (*IdentityResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{7}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *IdentityResponse) GetProvider() string {
@@ -484,6 +681,27 @@ func (x *IdentityResponse) GetDelegation() *DelegationInfo {
 	return nil
 }
 
+func (x *IdentityResponse) GetRememberMe() bool {
+	if x != nil {
+		return x.RememberMe
+	}
+	return false
+}
+
+func (x *IdentityResponse) GetMfa() bool {
+	if x != nil {
+		return x.Mfa
+	}
+	return false
+}
+
+func (x *IdentityResponse) GetGroups() []string {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
 // Metadata about identity delegation when an admin assumes another user's identity.
 type DelegationInfo struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -503,7 +721,7 @@ type DelegationInfo struct {
 
 func (x *DelegationInfo) Reset() {
 	*x = DelegationInfo{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[8]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -515,7 +733,7 @@ func (x *DelegationInfo) String() string {
 func (*DelegationInfo) ProtoMessage() {}
 
 func (x *DelegationInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[8]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -528,7 +746,7 @@ func (x *DelegationInfo) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DelegationInfo.ProtoReflect.Descriptor instead.
 func (*DelegationInfo) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{8}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *DelegationInfo) GetDelegatorSub() string {
@@ -581,7 +799,7 @@ type AssumeIdentityRequest struct {
 
 func (x *AssumeIdentityRequest) Reset() {
 	*x = AssumeIdentityRequest{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[9]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -593,7 +811,7 @@ func (x *AssumeIdentityRequest) String() string {
 func (*AssumeIdentityRequest) ProtoMessage() {}
 
 func (x *AssumeIdentityRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[9]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -606,7 +824,7 @@ func (x *AssumeIdentityRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AssumeIdentityRequest.ProtoReflect.Descriptor instead.
 func (*AssumeIdentityRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{9}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *AssumeIdentityRequest) GetProvider() string {
@@ -641,7 +859,7 @@ type AssumeIdentityResponse struct {
 
 func (x *AssumeIdentityResponse) Reset() {
 	*x = AssumeIdentityResponse{}
-	mi := &file_plugins_auth_authservice_proto_msgTypes[10]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -653,7 +871,7 @@ func (x *AssumeIdentityResponse) String() string {
 func (*AssumeIdentityResponse) ProtoMessage() {}
 
 func (x *AssumeIdentityResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_auth_authservice_proto_msgTypes[10]
+	mi := &file_plugins_auth_authservice_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -666,7 +884,7 @@ func (x *AssumeIdentityResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use AssumeIdentityResponse.ProtoReflect.Descriptor instead.
 func (*AssumeIdentityResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{10}
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *AssumeIdentityResponse) GetToken() string {
@@ -676,38 +894,309 @@ func (x *AssumeIdentityResponse) GetToken() string {
 	return ""
 }
 
+// Request to link another provider's identity to the current account.
+type LinkAccountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identity token for the identity to link, as returned by Login with
+	// `issue_token` set.
+	Token         string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkAccountRequest) Reset() {
+	*x = LinkAccountRequest{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkAccountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkAccountRequest) ProtoMessage() {}
+
+func (x *LinkAccountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkAccountRequest.ProtoReflect.Descriptor instead.
+func (*LinkAccountRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *LinkAccountRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+// Response after linking an identity.
+type LinkAccountResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Canonical account ID, which is now the subject for all linked identities.
+	AccountId string `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	// All identities linked to the account.
+	LinkedIdentities []*LinkedIdentity `protobuf:"bytes,2,rep,name=linked_identities,json=linkedIdentities,proto3" json:"linked_identities,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *LinkAccountResponse) Reset() {
+	*x = LinkAccountResponse{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkAccountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkAccountResponse) ProtoMessage() {}
+
+func (x *LinkAccountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkAccountResponse.ProtoReflect.Descriptor instead.
+func (*LinkAccountResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LinkAccountResponse) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *LinkAccountResponse) GetLinkedIdentities() []*LinkedIdentity {
+	if x != nil {
+		return x.LinkedIdentities
+	}
+	return nil
+}
+
+// Request to unlink a provider identity from the current account.
+type UnlinkAccountRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Provider of the identity to unlink (e.g., "github")
+	Provider string `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Provider specific subject of the identity to unlink
+	Subject       string `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlinkAccountRequest) Reset() {
+	*x = UnlinkAccountRequest{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlinkAccountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlinkAccountRequest) ProtoMessage() {}
+
+func (x *UnlinkAccountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlinkAccountRequest.ProtoReflect.Descriptor instead.
+func (*UnlinkAccountRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *UnlinkAccountRequest) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *UnlinkAccountRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+// Response after unlinking an identity.
+type UnlinkAccountResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identities which remain linked to the account.
+	LinkedIdentities []*LinkedIdentity `protobuf:"bytes,1,rep,name=linked_identities,json=linkedIdentities,proto3" json:"linked_identities,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *UnlinkAccountResponse) Reset() {
+	*x = UnlinkAccountResponse{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlinkAccountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlinkAccountResponse) ProtoMessage() {}
+
+func (x *UnlinkAccountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlinkAccountResponse.ProtoReflect.Descriptor instead.
+func (*UnlinkAccountResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *UnlinkAccountResponse) GetLinkedIdentities() []*LinkedIdentity {
+	if x != nil {
+		return x.LinkedIdentities
+	}
+	return nil
+}
+
+// A provider identity which is linked to an account.
+type LinkedIdentity struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identity provider (e.g., "google")
+	Provider string `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	// Provider specific subject identifier
+	Subject       string `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LinkedIdentity) Reset() {
+	*x = LinkedIdentity{}
+	mi := &file_plugins_auth_authservice_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LinkedIdentity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LinkedIdentity) ProtoMessage() {}
+
+func (x *LinkedIdentity) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_auth_authservice_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LinkedIdentity.ProtoReflect.Descriptor instead.
+func (*LinkedIdentity) Descriptor() ([]byte, []int) {
+	return file_plugins_auth_authservice_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *LinkedIdentity) GetProvider() string {
+	if x != nil {
+		return x.Provider
+	}
+	return ""
+}
+
+func (x *LinkedIdentity) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
 var File_plugins_auth_authservice_proto protoreflect.FileDescriptor
 
 const file_plugins_auth_authservice_proto_rawDesc = "" +
 	"\n" +
-	"\x1eplugins/auth/authservice.proto\x12\vprefab.auth\x1a\x1cgoogle/api/annotations.proto\"\xe4\x01\n" +
+	"\x1eplugins/auth/authservice.proto\x12\vprefab.auth\x1a\x1cgoogle/api/annotations.proto\x1a\x13redact/redact.proto\"\x8b\x02\n" +
 	"\fLoginRequest\x12\x1a\n" +
-	"\bprovider\x18\x01 \x01(\tR\bprovider\x12:\n" +
-	"\x05creds\x18\x02 \x03(\v2$.prefab.auth.LoginRequest.CredsEntryR\x05creds\x12\x1f\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12@\n" +
+	"\x05creds\x18\x02 \x03(\v2$.prefab.auth.LoginRequest.CredsEntryB\x04\xe8\xb8\x18\x01R\x05creds\x12\x1f\n" +
 	"\vissue_token\x18\x03 \x01(\bR\n" +
 	"issueToken\x12!\n" +
-	"\fredirect_uri\x18\x04 \x01(\tR\vredirectUri\x1a8\n" +
+	"\fredirect_uri\x18\x04 \x01(\tR\vredirectUri\x12\x1f\n" +
+	"\vremember_me\x18\x05 \x01(\bR\n" +
+	"rememberMe\x1a8\n" +
 	"\n" +
 	"CredsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
-	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"`\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"f\n" +
 	"\rLoginResponse\x12\x16\n" +
-	"\x06issued\x18\x01 \x01(\bR\x06issued\x12\x14\n" +
-	"\x05token\x18\x02 \x01(\tR\x05token\x12!\n" +
-	"\fredirect_uri\x18\x03 \x01(\tR\vredirectUri\"2\n" +
+	"\x06issued\x18\x01 \x01(\bR\x06issued\x12\x1a\n" +
+	"\x05token\x18\x02 \x01(\tB\x04\xe8\xb8\x18\x01R\x05token\x12!\n" +
+	"\fredirect_uri\x18\x03 \x01(\tR\vredirectUri\"S\n" +
 	"\rLogoutRequest\x12!\n" +
-	"\fredirect_uri\x18\x04 \x01(\tR\vredirectUri\"3\n" +
+	"\fredirect_uri\x18\x04 \x01(\tR\vredirectUri\x12\x1f\n" +
+	"\vend_session\x18\x05 \x01(\bR\n" +
+	"endSession\"3\n" +
 	"\x0eLogoutResponse\x12!\n" +
-	"\fredirect_uri\x18\x01 \x01(\tR\vredirectUri\"\x0f\n" +
-	"\rConfigRequest\"\xaf\x01\n" +
-	"\x0eConfigResponse\x12\x1d\n" +
+	"\fredirect_uri\x18\x01 \x01(\tR\vredirectUri\"\x16\n" +
+	"\x14ListProvidersRequest\"P\n" +
+	"\x15ListProvidersResponse\x127\n" +
+	"\tproviders\x18\x01 \x03(\v2\x19.prefab.auth.ProviderInfoR\tproviders\"\x97\x01\n" +
+	"\fProviderInfo\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12\x12\n" +
+	"\x04icon\x18\x03 \x01(\tR\x04icon\x12\x1a\n" +
+	"\bredirect\x18\x04 \x01(\bR\bredirect\x12 \n" +
+	"\vcredentials\x18\x05 \x03(\tR\vcredentials\"\x0f\n" +
+	"\rConfigRequest\"\xb5\x01\n" +
+	"\x0eConfigResponse\x12#\n" +
 	"\n" +
-	"csrf_token\x18\x01 \x01(\tR\tcsrfToken\x12B\n" +
+	"csrf_token\x18\x01 \x01(\tB\x04\xe8\xb8\x18\x01R\tcsrfToken\x12B\n" +
 	"\aconfigs\x18\x02 \x03(\v2(.prefab.auth.ConfigResponse.ConfigsEntryR\aconfigs\x1a:\n" +
 	"\fConfigsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x11\n" +
-	"\x0fIdentityRequest\"\xd6\x01\n" +
+	"\x0fIdentityRequest\"\xa1\x02\n" +
 	"\x10IdentityResponse\x12\x1a\n" +
 	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x18\n" +
 	"\asubject\x18\x02 \x01(\tR\asubject\x12\x14\n" +
@@ -716,7 +1205,11 @@ const file_plugins_auth_authservice_proto_rawDesc = "" +
 	"\x04name\x18\x05 \x01(\tR\x04name\x12;\n" +
 	"\n" +
 	"delegation\x18\x06 \x01(\v2\x1b.prefab.auth.DelegationInfoR\n" +
-	"delegation\"\xd1\x01\n" +
+	"delegation\x12\x1f\n" +
+	"\vremember_me\x18\a \x01(\bR\n" +
+	"rememberMe\x12\x10\n" +
+	"\x03mfa\x18\b \x01(\bR\x03mfa\x12\x16\n" +
+	"\x06groups\x18\t \x03(\tR\x06groups\"\xd1\x01\n" +
 	"\x0eDelegationInfo\x12#\n" +
 	"\rdelegator_sub\x18\x01 \x01(\tR\fdelegatorSub\x12-\n" +
 	"\x12delegator_provider\x18\x02 \x01(\tR\x11delegatorProvider\x120\n" +
@@ -726,14 +1219,31 @@ const file_plugins_auth_authservice_proto_rawDesc = "" +
 	"\x15AssumeIdentityRequest\x12\x1a\n" +
 	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x18\n" +
 	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
-	"\x06reason\x18\x03 \x01(\tR\x06reason\".\n" +
-	"\x16AssumeIdentityResponse\x12\x14\n" +
-	"\x05token\x18\x01 \x01(\tR\x05token2\xc7\x03\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"4\n" +
+	"\x16AssumeIdentityResponse\x12\x1a\n" +
+	"\x05token\x18\x01 \x01(\tB\x04\xe8\xb8\x18\x01R\x05token\"0\n" +
+	"\x12LinkAccountRequest\x12\x1a\n" +
+	"\x05token\x18\x01 \x01(\tB\x04\xe8\xb8\x18\x01R\x05token\"~\n" +
+	"\x13LinkAccountResponse\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12H\n" +
+	"\x11linked_identities\x18\x02 \x03(\v2\x1b.prefab.auth.LinkedIdentityR\x10linkedIdentities\"L\n" +
+	"\x14UnlinkAccountRequest\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\"a\n" +
+	"\x15UnlinkAccountResponse\x12H\n" +
+	"\x11linked_identities\x18\x01 \x03(\v2\x1b.prefab.auth.LinkedIdentityR\x10linkedIdentities\"F\n" +
+	"\x0eLinkedIdentity\x12\x1a\n" +
+	"\bprovider\x18\x01 \x01(\tR\bprovider\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject2\x9e\x06\n" +
 	"\vAuthService\x12m\n" +
 	"\x05Login\x12\x19.prefab.auth.LoginRequest\x1a\x1a.prefab.auth.LoginResponse\"-\x82\xd3\xe4\x93\x02'Z\x14:\x01*\"\x0f/api/auth/login\x12\x0f/api/auth/login\x12r\n" +
-	"\x06Logout\x12\x1a.prefab.auth.LogoutRequest\x1a\x1b.prefab.auth.LogoutResponse\"/\x82\xd3\xe4\x93\x02)Z\x15:\x01*\"\x10/api/auth/logout\x12\x10/api/auth/logout\x12]\n" +
+	"\x06Logout\x12\x1a.prefab.auth.LogoutRequest\x1a\x1b.prefab.auth.LogoutResponse\"/\x82\xd3\xe4\x93\x02)Z\x15:\x01*\"\x10/api/auth/logout\x12\x10/api/auth/logout\x12s\n" +
+	"\rListProviders\x12!.prefab.auth.ListProvidersRequest\x1a\".prefab.auth.ListProvidersResponse\"\x1b\x82\xd3\xe4\x93\x02\x15\x12\x13/api/auth/providers\x12]\n" +
 	"\bIdentity\x12\x1c.prefab.auth.IdentityRequest\x1a\x1d.prefab.auth.IdentityResponse\"\x14\x82\xd3\xe4\x93\x02\x0e\x12\f/api/auth/me\x12v\n" +
-	"\x0eAssumeIdentity\x12\".prefab.auth.AssumeIdentityRequest\x1a#.prefab.auth.AssumeIdentityResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/api/auth/assumeB%Z#github.com/dpup/prefab/plugins/authb\x06proto3"
+	"\x0eAssumeIdentity\x12\".prefab.auth.AssumeIdentityRequest\x1a#.prefab.auth.AssumeIdentityResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/api/auth/assume\x12k\n" +
+	"\vLinkAccount\x12\x1f.prefab.auth.LinkAccountRequest\x1a .prefab.auth.LinkAccountResponse\"\x19\x82\xd3\xe4\x93\x02\x13:\x01*\"\x0e/api/auth/link\x12s\n" +
+	"\rUnlinkAccount\x12!.prefab.auth.UnlinkAccountRequest\x1a\".prefab.auth.UnlinkAccountResponse\"\x1b\x82\xd3\xe4\x93\x02\x15:\x01*\"\x10/api/auth/unlinkB%Z#github.com/dpup/prefab/plugins/authb\x06proto3"
 
 var (
 	file_plugins_auth_authservice_proto_rawDescOnce sync.Once
@@ -747,39 +1257,56 @@ func file_plugins_auth_authservice_proto_rawDescGZIP() []byte {
 	return file_plugins_auth_authservice_proto_rawDescData
 }
 
-var file_plugins_auth_authservice_proto_msgTypes = make([]protoimpl.MessageInfo, 13)
+var file_plugins_auth_authservice_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_plugins_auth_authservice_proto_goTypes = []any{
 	(*LoginRequest)(nil),           // 0: prefab.auth.LoginRequest
 	(*LoginResponse)(nil),          // 1: prefab.auth.LoginResponse
 	(*LogoutRequest)(nil),          // 2: prefab.auth.LogoutRequest
 	(*LogoutResponse)(nil),         // 3: prefab.auth.LogoutResponse
-	(*ConfigRequest)(nil),          // 4: prefab.auth.ConfigRequest
-	(*ConfigResponse)(nil),         // 5: prefab.auth.ConfigResponse
-	(*IdentityRequest)(nil),        // 6: prefab.auth.IdentityRequest
-	(*IdentityResponse)(nil),       // 7: prefab.auth.IdentityResponse
-	(*DelegationInfo)(nil),         // 8: prefab.auth.DelegationInfo
-	(*AssumeIdentityRequest)(nil),  // 9: prefab.auth.AssumeIdentityRequest
-	(*AssumeIdentityResponse)(nil), // 10: prefab.auth.AssumeIdentityResponse
-	nil,                            // 11: prefab.auth.LoginRequest.CredsEntry
-	nil,                            // 12: prefab.auth.ConfigResponse.ConfigsEntry
+	(*ListProvidersRequest)(nil),   // 4: prefab.auth.ListProvidersRequest
+	(*ListProvidersResponse)(nil),  // 5: prefab.auth.ListProvidersResponse
+	(*ProviderInfo)(nil),           // 6: prefab.auth.ProviderInfo
+	(*ConfigRequest)(nil),          // 7: prefab.auth.ConfigRequest
+	(*ConfigResponse)(nil),         // 8: prefab.auth.ConfigResponse
+	(*IdentityRequest)(nil),        // 9: prefab.auth.IdentityRequest
+	(*IdentityResponse)(nil),       // 10: prefab.auth.IdentityResponse
+	(*DelegationInfo)(nil),         // 11: prefab.auth.DelegationInfo
+	(*AssumeIdentityRequest)(nil),  // 12: prefab.auth.AssumeIdentityRequest
+	(*AssumeIdentityResponse)(nil), // 13: prefab.auth.AssumeIdentityResponse
+	(*LinkAccountRequest)(nil),     // 14: prefab.auth.LinkAccountRequest
+	(*LinkAccountResponse)(nil),    // 15: prefab.auth.LinkAccountResponse
+	(*UnlinkAccountRequest)(nil),   // 16: prefab.auth.UnlinkAccountRequest
+	(*UnlinkAccountResponse)(nil),  // 17: prefab.auth.UnlinkAccountResponse
+	(*LinkedIdentity)(nil),         // 18: prefab.auth.LinkedIdentity
+	nil,                            // 19: prefab.auth.LoginRequest.CredsEntry
+	nil,                            // 20: prefab.auth.ConfigResponse.ConfigsEntry
 }
 var file_plugins_auth_authservice_proto_depIdxs = []int32{
-	11, // 0: prefab.auth.LoginRequest.creds:type_name -> prefab.auth.LoginRequest.CredsEntry
-	12, // 1: prefab.auth.ConfigResponse.configs:type_name -> prefab.auth.ConfigResponse.ConfigsEntry
-	8,  // 2: prefab.auth.IdentityResponse.delegation:type_name -> prefab.auth.DelegationInfo
-	0,  // 3: prefab.auth.AuthService.Login:input_type -> prefab.auth.LoginRequest
-	2,  // 4: prefab.auth.AuthService.Logout:input_type -> prefab.auth.LogoutRequest
-	6,  // 5: prefab.auth.AuthService.Identity:input_type -> prefab.auth.IdentityRequest
-	9,  // 6: prefab.auth.AuthService.AssumeIdentity:input_type -> prefab.auth.AssumeIdentityRequest
-	1,  // 7: prefab.auth.AuthService.Login:output_type -> prefab.auth.LoginResponse
-	3,  // 8: prefab.auth.AuthService.Logout:output_type -> prefab.auth.LogoutResponse
-	7,  // 9: prefab.auth.AuthService.Identity:output_type -> prefab.auth.IdentityResponse
-	10, // 10: prefab.auth.AuthService.AssumeIdentity:output_type -> prefab.auth.AssumeIdentityResponse
-	7,  // [7:11] is the sub-list for method output_type
-	3,  // [3:7] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	19, // 0: prefab.auth.LoginRequest.creds:type_name -> prefab.auth.LoginRequest.CredsEntry
+	6,  // 1: prefab.auth.ListProvidersResponse.providers:type_name -> prefab.auth.ProviderInfo
+	20, // 2: prefab.auth.ConfigResponse.configs:type_name -> prefab.auth.ConfigResponse.ConfigsEntry
+	11, // 3: prefab.auth.IdentityResponse.delegation:type_name -> prefab.auth.DelegationInfo
+	18, // 4: prefab.auth.LinkAccountResponse.linked_identities:type_name -> prefab.auth.LinkedIdentity
+	18, // 5: prefab.auth.UnlinkAccountResponse.linked_identities:type_name -> prefab.auth.LinkedIdentity
+	0,  // 6: prefab.auth.AuthService.Login:input_type -> prefab.auth.LoginRequest
+	2,  // 7: prefab.auth.AuthService.Logout:input_type -> prefab.auth.LogoutRequest
+	4,  // 8: prefab.auth.AuthService.ListProviders:input_type -> prefab.auth.ListProvidersRequest
+	9,  // 9: prefab.auth.AuthService.Identity:input_type -> prefab.auth.IdentityRequest
+	12, // 10: prefab.auth.AuthService.AssumeIdentity:input_type -> prefab.auth.AssumeIdentityRequest
+	14, // 11: prefab.auth.AuthService.LinkAccount:input_type -> prefab.auth.LinkAccountRequest
+	16, // 12: prefab.auth.AuthService.UnlinkAccount:input_type -> prefab.auth.UnlinkAccountRequest
+	1,  // 13: prefab.auth.AuthService.Login:output_type -> prefab.auth.LoginResponse
+	3,  // 14: prefab.auth.AuthService.Logout:output_type -> prefab.auth.LogoutResponse
+	5,  // 15: prefab.auth.AuthService.ListProviders:output_type -> prefab.auth.ListProvidersResponse
+	10, // 16: prefab.auth.AuthService.Identity:output_type -> prefab.auth.IdentityResponse
+	13, // 17: prefab.auth.AuthService.AssumeIdentity:output_type -> prefab.auth.AssumeIdentityResponse
+	15, // 18: prefab.auth.AuthService.LinkAccount:output_type -> prefab.auth.LinkAccountResponse
+	17, // 19: prefab.auth.AuthService.UnlinkAccount:output_type -> prefab.auth.UnlinkAccountResponse
+	13, // [13:20] is the sub-list for method output_type
+	6,  // [6:13] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_plugins_auth_authservice_proto_init() }
@@ -793,7 +1320,7 @@ func file_plugins_auth_authservice_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_auth_authservice_proto_rawDesc), len(file_plugins_auth_authservice_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   13,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
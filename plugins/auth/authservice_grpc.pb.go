@@ -21,8 +21,11 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	AuthService_Login_FullMethodName          = "/prefab.auth.AuthService/Login"
 	AuthService_Logout_FullMethodName         = "/prefab.auth.AuthService/Logout"
+	AuthService_ListProviders_FullMethodName  = "/prefab.auth.AuthService/ListProviders"
 	AuthService_Identity_FullMethodName       = "/prefab.auth.AuthService/Identity"
 	AuthService_AssumeIdentity_FullMethodName = "/prefab.auth.AuthService/AssumeIdentity"
+	AuthService_LinkAccount_FullMethodName    = "/prefab.auth.AuthService/LinkAccount"
+	AuthService_UnlinkAccount_FullMethodName  = "/prefab.auth.AuthService/UnlinkAccount"
 )
 
 // AuthServiceClient is the client API for AuthService service.
@@ -35,13 +38,31 @@ type AuthServiceClient interface {
 	Login(ctx context.Context, in *LoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
 	// Logout clears the prefab id cookie. It should be noted that by default the
 	// identity token will remain valid until its expiry. Token invalidatation is
-	// supported via the addition of a blocklist.
+	// supported via the addition of a blocklist. Clients authenticating with a
+	// bearer token can call Logout to block it.
+	//
+	// If `end_session` is set, the user is also signed out of the identity
+	// provider, when the provider supports it. If front-channel logout is
+	// configured, the user is first redirected via a page which signs them out
+	// of the other apps sharing their login.
 	Logout(ctx context.Context, in *LogoutRequest, opts ...grpc.CallOption) (*LogoutResponse, error)
+	// ListProviders returns the login providers configured on the server, so that
+	// clients can render login options dynamically.
+	ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error)
 	// Identity returns information about the authenticated user.
 	Identity(ctx context.Context, in *IdentityRequest, opts ...grpc.CallOption) (*IdentityResponse, error)
 	// AssumeIdentity allows admin users to assume another user's identity.
 	// Requires delegation to be enabled and the caller to have admin privileges.
 	AssumeIdentity(ctx context.Context, in *AssumeIdentityRequest, opts ...grpc.CallOption) (*AssumeIdentityResponse, error)
+	// LinkAccount links another provider's identity to the authenticated user's
+	// account, so that logging in with either resolves to the same subject. The
+	// other identity is passed as a token, obtained by logging in with
+	// `issue_token` set. Both logins must be recent. Requires account linking to
+	// be enabled.
+	LinkAccount(ctx context.Context, in *LinkAccountRequest, opts ...grpc.CallOption) (*LinkAccountResponse, error)
+	// UnlinkAccount removes a provider identity from the authenticated user's
+	// account. Requires a recent login.
+	UnlinkAccount(ctx context.Context, in *UnlinkAccountRequest, opts ...grpc.CallOption) (*UnlinkAccountResponse, error)
 }
 
 type authServiceClient struct {
@@ -72,6 +93,16 @@ func (c *authServiceClient) Logout(ctx context.Context, in *LogoutRequest, opts
 	return out, nil
 }
 
+func (c *authServiceClient) ListProviders(ctx context.Context, in *ListProvidersRequest, opts ...grpc.CallOption) (*ListProvidersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListProvidersResponse)
+	err := c.cc.Invoke(ctx, AuthService_ListProviders_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *authServiceClient) Identity(ctx context.Context, in *IdentityRequest, opts ...grpc.CallOption) (*IdentityResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(IdentityResponse)
@@ -92,6 +123,26 @@ func (c *authServiceClient) AssumeIdentity(ctx context.Context, in *AssumeIdenti
 	return out, nil
 }
 
+func (c *authServiceClient) LinkAccount(ctx context.Context, in *LinkAccountRequest, opts ...grpc.CallOption) (*LinkAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LinkAccountResponse)
+	err := c.cc.Invoke(ctx, AuthService_LinkAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authServiceClient) UnlinkAccount(ctx context.Context, in *UnlinkAccountRequest, opts ...grpc.CallOption) (*UnlinkAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UnlinkAccountResponse)
+	err := c.cc.Invoke(ctx, AuthService_UnlinkAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AuthServiceServer is the server API for AuthService service.
 // All implementations must embed UnimplementedAuthServiceServer
 // for forward compatibility.
@@ -102,13 +153,31 @@ type AuthServiceServer interface {
 	Login(context.Context, *LoginRequest) (*LoginResponse, error)
 	// Logout clears the prefab id cookie. It should be noted that by default the
 	// identity token will remain valid until its expiry. Token invalidatation is
-	// supported via the addition of a blocklist.
+	// supported via the addition of a blocklist. Clients authenticating with a
+	// bearer token can call Logout to block it.
+	//
+	// If `end_session` is set, the user is also signed out of the identity
+	// provider, when the provider supports it. If front-channel logout is
+	// configured, the user is first redirected via a page which signs them out
+	// of the other apps sharing their login.
 	Logout(context.Context, *LogoutRequest) (*LogoutResponse, error)
+	// ListProviders returns the login providers configured on the server, so that
+	// clients can render login options dynamically.
+	ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error)
 	// Identity returns information about the authenticated user.
 	Identity(context.Context, *IdentityRequest) (*IdentityResponse, error)
 	// AssumeIdentity allows admin users to assume another user's identity.
 	// Requires delegation to be enabled and the caller to have admin privileges.
 	AssumeIdentity(context.Context, *AssumeIdentityRequest) (*AssumeIdentityResponse, error)
+	// LinkAccount links another provider's identity to the authenticated user's
+	// account, so that logging in with either resolves to the same subject. The
+	// other identity is passed as a token, obtained by logging in with
+	// `issue_token` set. Both logins must be recent. Requires account linking to
+	// be enabled.
+	LinkAccount(context.Context, *LinkAccountRequest) (*LinkAccountResponse, error)
+	// UnlinkAccount removes a provider identity from the authenticated user's
+	// account. Requires a recent login.
+	UnlinkAccount(context.Context, *UnlinkAccountRequest) (*UnlinkAccountResponse, error)
 	mustEmbedUnimplementedAuthServiceServer()
 }
 
@@ -125,12 +194,21 @@ func (UnimplementedAuthServiceServer) Login(context.Context, *LoginRequest) (*Lo
 func (UnimplementedAuthServiceServer) Logout(context.Context, *LogoutRequest) (*LogoutResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Logout not implemented")
 }
+func (UnimplementedAuthServiceServer) ListProviders(context.Context, *ListProvidersRequest) (*ListProvidersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListProviders not implemented")
+}
 func (UnimplementedAuthServiceServer) Identity(context.Context, *IdentityRequest) (*IdentityResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Identity not implemented")
 }
 func (UnimplementedAuthServiceServer) AssumeIdentity(context.Context, *AssumeIdentityRequest) (*AssumeIdentityResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AssumeIdentity not implemented")
 }
+func (UnimplementedAuthServiceServer) LinkAccount(context.Context, *LinkAccountRequest) (*LinkAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LinkAccount not implemented")
+}
+func (UnimplementedAuthServiceServer) UnlinkAccount(context.Context, *UnlinkAccountRequest) (*UnlinkAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnlinkAccount not implemented")
+}
 func (UnimplementedAuthServiceServer) mustEmbedUnimplementedAuthServiceServer() {}
 func (UnimplementedAuthServiceServer) testEmbeddedByValue()                     {}
 
@@ -188,6 +266,24 @@ func _AuthService_Logout_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_ListProviders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListProvidersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).ListProviders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_ListProviders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).ListProviders(ctx, req.(*ListProvidersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AuthService_Identity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(IdentityRequest)
 	if err := dec(in); err != nil {
@@ -224,6 +320,42 @@ func _AuthService_AssumeIdentity_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthService_LinkAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).LinkAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_LinkAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).LinkAccount(ctx, req.(*LinkAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthService_UnlinkAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlinkAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthServiceServer).UnlinkAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthService_UnlinkAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthServiceServer).UnlinkAccount(ctx, req.(*UnlinkAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // AuthService_ServiceDesc is the grpc.ServiceDesc for AuthService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -239,6 +371,10 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Logout",
 			Handler:    _AuthService_Logout_Handler,
 		},
+		{
+			MethodName: "ListProviders",
+			Handler:    _AuthService_ListProviders_Handler,
+		},
 		{
 			MethodName: "Identity",
 			Handler:    _AuthService_Identity_Handler,
@@ -247,6 +383,14 @@ var AuthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AssumeIdentity",
 			Handler:    _AuthService_AssumeIdentity_Handler,
 		},
+		{
+			MethodName: "LinkAccount",
+			Handler:    _AuthService_LinkAccount_Handler,
+		},
+		{
+			MethodName: "UnlinkAccount",
+			Handler:    _AuthService_UnlinkAccount_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "plugins/auth/authservice.proto",
@@ -181,3 +181,26 @@ func TestIdentity_Unauthenticated(t *testing.T) {
 	require.Error(t, err)
 	assert.ErrorIs(t, err, ErrNotFound)
 }
+
+func TestListProviders(t *testing.T) {
+	handler := func(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+		return &LoginResponse{}, nil
+	}
+	ap := Plugin(WithProviderInfo(&ProviderInfo{Name: "google", DisplayName: "Acme SSO"}))
+	ap.AddLoginHandler("google", handler)
+	ap.SetProviderInfo(&ProviderInfo{Name: "google", DisplayName: "Google", Icon: "google", Redirect: true})
+	ap.AddLoginHandler("custom", handler)
+
+	// Metadata for providers without handlers isn't listed.
+	ap.SetProviderInfo(&ProviderInfo{Name: "slack", DisplayName: "Slack"})
+
+	resp, err := ap.authService.ListProviders(t.Context(), &ListProvidersRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.Providers, 2)
+	assert.Equal(t, "custom", resp.Providers[0].Name)
+	assert.Equal(t, "custom", resp.Providers[0].DisplayName)
+	assert.Equal(t, "google", resp.Providers[1].Name)
+	assert.Equal(t, "Acme SSO", resp.Providers[1].DisplayName)
+	assert.Equal(t, "google", resp.Providers[1].Icon)
+	assert.True(t, resp.Providers[1].Redirect)
+}
@@ -0,0 +1,364 @@
+// Package authtest provides conformance tests for login providers, so that
+// each provider is verified the same way: logins set the identity cookie, or
+// return a token when `issue_token` is set, publish auth.LoginEvent, and are
+// rejected without side effects when credentials are invalid. Providers must
+// also be described by ListProviders.
+//
+// Providers which redirect to an identity provider are run against IdP, a mock
+// OAuth 2.0 server, and are additionally checked for the redirect, the state
+// round-trip via the provider's callback, forged state, invalid codes, and
+// users declining the login.
+//
+// Tests run against a prefabtest server, which registers the auth, storage,
+// and event bus plugins, so the provider's own plugins are all that is needed.
+//
+// Example:
+//
+//	func TestConformance(t *testing.T) {
+//		authtest.Run(t, authtest.Provider{
+//			Name:    ProviderName,
+//			Subject: "U0R7JM",
+//			Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+//				idp.Handle("/userinfo", `{"ok": true, "sub": "U0R7JM"}`)
+//				p := Plugin(WithClient(authtest.ClientID, authtest.ClientSecret))
+//				p.endpoint = idp.Endpoint()
+//				p.userInfoURL = idp.URL + "/userinfo"
+//				return []prefab.Plugin{p}
+//			},
+//		})
+//	}
+package authtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Provider describes the login provider under test.
+type Provider struct {
+	// Name used as the provider in login requests.
+	Name string
+
+	// Subject of the identity that successful logins should produce.
+	Subject string
+
+	// Plugins returns the provider's plugin, and any plugins it depends on
+	// other than auth, storage, and eventbus. Redirect based providers should
+	// be configured with ClientID and ClientSecret, and to use the IdP's
+	// endpoints. Called once per test, with a new IdP.
+	Plugins func(t *testing.T, idp *IdP) []prefab.Plugin
+
+	// Creds returns credentials for a successful login, for providers which
+	// accept credentials rather than redirecting to an identity provider. It
+	// may make requests to the server, for example to have a code sent.
+	Creds func(t *testing.T, s *prefabtest.Server) map[string]string
+
+	// InvalidCreds must be rejected by the provider. Required with Creds.
+	InvalidCreds map[string]string
+}
+
+// Run runs the conformance tests against the provider, each as a subtest with
+// its own server and IdP.
+func Run(t *testing.T, p Provider) {
+	t.Helper()
+	require.NotEmpty(t, p.Name, "authtest: provider name is required")
+	require.NotNil(t, p.Plugins, "authtest: Plugins is required")
+	if p.Creds != nil {
+		require.NotEmpty(t, p.InvalidCreds, "authtest: InvalidCreds is required with Creds")
+		runCreds(t, p)
+	} else {
+		runRedirect(t, p)
+	}
+}
+
+func runCreds(t *testing.T, p Provider) {
+	t.Run("lists provider", func(t *testing.T) {
+		info := newHarness(t, p).listedProvider()
+		assert.NotEmpty(t, info.Credentials, "credential providers should list their credentials")
+	})
+
+	t.Run("sets cookie", func(t *testing.T) {
+		h := newHarness(t, p)
+		resp := h.login(map[string]any{"creds": p.Creds(t, h.s), "redirect_uri": "/dashboard"})
+		h.assertCookieLogin(resp, "/dashboard")
+	})
+
+	t.Run("issues token", func(t *testing.T) {
+		h := newHarness(t, p)
+		resp := h.login(map[string]any{"creds": p.Creds(t, h.s), "issue_token": true})
+		h.assertTokenLogin(resp)
+	})
+
+	t.Run("rejects invalid credentials", func(t *testing.T) {
+		h := newHarness(t, p)
+		resp := h.login(map[string]any{"creds": p.InvalidCreds, "issue_token": true})
+		h.assertRejected(resp)
+	})
+}
+
+func runRedirect(t *testing.T, p Provider) {
+	t.Run("lists provider", func(t *testing.T) {
+		info := newHarness(t, p).listedProvider()
+		assert.True(t, info.Redirect, "redirect providers should be listed as such")
+	})
+
+	t.Run("redirects to IdP", func(t *testing.T) {
+		h := newHarness(t, p)
+		u := h.startLogin("/dashboard")
+		q := u.Query()
+		assert.Equal(t, ClientID, q.Get("client_id"))
+		assert.Equal(t, "code", q.Get("response_type"))
+		assert.NotEmpty(t, q.Get("redirect_uri"))
+		assert.NotEmpty(t, q.Get("state"))
+		h.s.Events().AssertNotPublished(t, auth.LoginEvent)
+	})
+
+	t.Run("sets cookie", func(t *testing.T) {
+		h := newHarness(t, p)
+		callback := h.authorize(h.startLogin("/dashboard"))
+
+		// The provider's callback forwards to the login endpoint, which
+		// redirects to the destination from the original request.
+		resp := h.get(callback.String())
+		require.Equal(t, http.StatusFound, resp.StatusCode, "callback should redirect: %s", resp.body)
+		assert.Empty(t, identityCookie(resp), "callback should not set the identity cookie")
+		resp = h.get(resp.Header.Get("Location"))
+		h.assertCookieLogin(resp, "/dashboard")
+	})
+
+	t.Run("issues token", func(t *testing.T) {
+		h := newHarness(t, p)
+		q := h.authorize(h.startLogin("")).Query()
+		resp := h.login(map[string]any{
+			"creds":       map[string]string{"code": q.Get("code"), "state": q.Get("state")},
+			"issue_token": true,
+		})
+		h.assertTokenLogin(resp)
+	})
+
+	t.Run("rejects forged state", func(t *testing.T) {
+		h := newHarness(t, p)
+		q := h.authorize(h.startLogin("")).Query()
+		resp := h.login(map[string]any{
+			"creds":       map[string]string{"code": q.Get("code"), "state": "forged-" + q.Get("state")},
+			"issue_token": true,
+		})
+		h.assertRejected(resp)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("rejects invalid code", func(t *testing.T) {
+		h := newHarness(t, p)
+		q := h.authorize(h.startLogin("")).Query()
+		resp := h.login(map[string]any{
+			"creds":       map[string]string{"code": "invalid-code", "state": q.Get("state")},
+			"issue_token": true,
+		})
+		h.assertRejected(resp)
+	})
+
+	t.Run("handles denied consent", func(t *testing.T) {
+		h := newHarness(t, p)
+		h.idp.Deny()
+		callback := h.authorize(h.startLogin("/dashboard"))
+		require.NotEmpty(t, callback.Query().Get("error"))
+
+		// The callback should fail, rather than forwarding to the login
+		// endpoint, which would restart the flow.
+		resp := h.get(callback.String())
+		h.assertRejected(resp)
+	})
+}
+
+type harness struct {
+	t      *testing.T
+	p      Provider
+	idp    *IdP
+	s      *prefabtest.Server
+	client *http.Client
+	idpc   *http.Client
+}
+
+func newHarness(t *testing.T, p Provider) *harness {
+	idp := NewIdP(t)
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(p.Plugins(t, idp)...))
+
+	noFollow := func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	client := *s.HTTPClient()
+	client.CheckRedirect = noFollow
+	idpc := *idp.Client()
+	idpc.CheckRedirect = noFollow
+
+	return &harness{t: t, p: p, idp: idp, s: s, client: &client, idpc: &idpc}
+}
+
+type response struct {
+	*http.Response
+	body string
+}
+
+func (h *harness) do(c *http.Client, req *http.Request) *response {
+	h.t.Helper()
+	resp, err := c.Do(req)
+	require.NoError(h.t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(h.t, err)
+	return &response{Response: resp, body: string(b)}
+}
+
+// get requests a URL on the IdP or, for other URLs and paths, the server.
+func (h *harness) get(u string) *response {
+	h.t.Helper()
+	c := h.client
+	if strings.HasPrefix(u, h.idp.URL) {
+		c = h.idpc
+	} else if strings.HasPrefix(u, "/") {
+		u = h.s.URL(u)
+	}
+	req, err := http.NewRequestWithContext(h.t.Context(), http.MethodGet, u, nil)
+	require.NoError(h.t, err)
+	return h.do(c, req)
+}
+
+// login posts to the login endpoint, with the provider added to the body.
+func (h *harness) login(body map[string]any) *response {
+	h.t.Helper()
+	body["provider"] = h.p.Name
+	b, err := json.Marshal(body)
+	require.NoError(h.t, err)
+	req, err := http.NewRequestWithContext(h.t.Context(), http.MethodPost, h.s.URL("/api/auth/login"), bytes.NewReader(b))
+	require.NoError(h.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Protection", "1")
+	return h.do(h.client, req)
+}
+
+// listedProvider returns the provider's entry from the providers endpoint.
+func (h *harness) listedProvider() *auth.ProviderInfo {
+	h.t.Helper()
+	resp := h.get("/api/auth/providers")
+	require.Equal(h.t, http.StatusOK, resp.StatusCode, "listing providers failed: %s", resp.body)
+	var list auth.ListProvidersResponse
+	require.NoError(h.t, protojson.Unmarshal([]byte(resp.body), &list))
+	for _, info := range list.Providers {
+		if info.Name == h.p.Name {
+			assert.NotEmpty(h.t, info.DisplayName)
+			return info
+		}
+	}
+	require.Fail(h.t, "provider should be listed", "providers: %s", resp.body)
+	return nil
+}
+
+// startLogin begins a login and returns the IdP URL it redirects to.
+func (h *harness) startLogin(dest string) *url.URL {
+	h.t.Helper()
+	q := url.Values{"provider": {h.p.Name}}
+	if dest != "" {
+		q.Set("redirect_uri", dest)
+	}
+	resp := h.get("/api/auth/login?" + q.Encode())
+	require.Equal(h.t, http.StatusFound, resp.StatusCode, "login should redirect to the IdP: %s", resp.body)
+	loc := resp.Header.Get("Location")
+	require.True(h.t, strings.HasPrefix(loc, h.idp.AuthURL()), "login redirected to %q, not the IdP", loc)
+	assert.Empty(h.t, identityCookie(resp))
+	u, err := url.Parse(loc)
+	require.NoError(h.t, err)
+	return u
+}
+
+// authorize visits the IdP and returns the callback URL it redirects to.
+func (h *harness) authorize(u *url.URL) *url.URL {
+	h.t.Helper()
+	resp := h.get(u.String())
+	require.Equal(h.t, http.StatusFound, resp.StatusCode, "IdP rejected the authorize request: %s", resp.body)
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(h.t, err)
+	assert.Equal(h.t, u.Query().Get("state"), callback.Query().Get("state"), "IdP should return the state")
+	return callback
+}
+
+func (h *harness) assertCookieLogin(resp *response, dest string) {
+	h.t.Helper()
+	require.Equal(h.t, http.StatusFound, resp.StatusCode, "login should redirect: %s", resp.body)
+	assert.Equal(h.t, dest, resp.Header.Get("Location"), "login should redirect to the requested destination")
+	cookie := identityCookie(resp)
+	require.NotEmpty(h.t, cookie, "login should set the identity cookie")
+
+	req, err := http.NewRequestWithContext(h.t.Context(), http.MethodGet, h.s.URL("/api/auth/me"), nil)
+	require.NoError(h.t, err)
+	req.AddCookie(&http.Cookie{Name: auth.IdentityTokenCookieName, Value: cookie})
+	h.assertIdentity(h.do(h.client, req))
+	h.assertLoginEvent()
+}
+
+func (h *harness) assertTokenLogin(resp *response) {
+	h.t.Helper()
+	require.Equal(h.t, http.StatusOK, resp.StatusCode, "login failed: %s", resp.body)
+	assert.Empty(h.t, identityCookie(resp), "issue_token should not set the identity cookie")
+	var body struct {
+		Issued bool   `json:"issued"`
+		Token  string `json:"token"`
+	}
+	require.NoError(h.t, json.Unmarshal([]byte(resp.body), &body))
+	assert.True(h.t, body.Issued)
+	require.NotEmpty(h.t, body.Token, "issue_token should return a token")
+
+	req, err := http.NewRequestWithContext(h.t.Context(), http.MethodGet, h.s.URL("/api/auth/me"), nil)
+	require.NoError(h.t, err)
+	req.Header.Set("Authorization", "Bearer "+body.Token)
+	h.assertIdentity(h.do(h.client, req))
+	h.assertLoginEvent()
+}
+
+func (h *harness) assertRejected(resp *response) {
+	h.t.Helper()
+	assert.GreaterOrEqual(h.t, resp.StatusCode, 400, "login should fail: %s", resp.body)
+	assert.Empty(h.t, identityCookie(resp), "failed login should not set the identity cookie")
+	h.s.Events().AssertNotPublished(h.t, auth.LoginEvent)
+}
+
+func (h *harness) assertIdentity(resp *response) {
+	h.t.Helper()
+	require.Equal(h.t, http.StatusOK, resp.StatusCode, "identity not accepted: %s", resp.body)
+	var identity struct {
+		Provider string `json:"provider"`
+		Subject  string `json:"subject"`
+	}
+	require.NoError(h.t, json.Unmarshal([]byte(resp.body), &identity))
+	assert.Equal(h.t, h.p.Name, identity.Provider)
+	assert.Equal(h.t, h.p.Subject, identity.Subject)
+}
+
+func (h *harness) assertLoginEvent() {
+	h.t.Helper()
+	events := h.s.Events().Published(auth.LoginEvent)
+	require.Len(h.t, events, 1, "login should publish one login event")
+	e, ok := events[0].(auth.AuthEvent)
+	require.True(h.t, ok, "login event should be an auth.AuthEvent, got %T", events[0])
+	assert.Equal(h.t, h.p.Name, e.Identity.Provider)
+	assert.Equal(h.t, h.p.Subject, e.Identity.Subject)
+	assert.NotEmpty(h.t, e.Identity.SessionID)
+}
+
+func identityCookie(resp *response) string {
+	for _, c := range resp.Cookies() {
+		if c.Name == auth.IdentityTokenCookieName {
+			return c.Value
+		}
+	}
+	return ""
+}
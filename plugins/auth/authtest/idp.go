@@ -0,0 +1,182 @@
+package authtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// Credentials that providers must be configured with to use an IdP.
+const (
+	ClientID     = "authtest-client"
+	ClientSecret = "authtest-secret"
+)
+
+// IdP is a mock OAuth 2.0 identity provider. Its authorize endpoint approves
+// requests without user interaction, redirecting back with a code, or with an
+// error once Deny has been called. Codes can be exchanged once, for an access
+// token that is accepted by resources registered with Handle.
+type IdP struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	denied    bool
+	codes     map[string]grant
+	tokens    map[string]bool
+	resources map[string]string
+}
+
+type grant struct {
+	redirectURI string
+	scope       string
+}
+
+// NewIdP starts an IdP, which is closed when the test completes.
+func NewIdP(t testing.TB) *IdP {
+	idp := &IdP{
+		codes:     map[string]grant{},
+		tokens:    map[string]bool{},
+		resources: map[string]string{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/", idp.resource)
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// AuthURL is the IdP's authorize endpoint.
+func (idp *IdP) AuthURL() string {
+	return idp.URL + "/authorize"
+}
+
+// TokenURL is the IdP's token endpoint.
+func (idp *IdP) TokenURL() string {
+	return idp.URL + "/token"
+}
+
+// Endpoint returns the IdP's endpoints for an oauth2.Config.
+func (idp *IdP) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:   idp.AuthURL(),
+		TokenURL:  idp.TokenURL(),
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+}
+
+// Handle serves the JSON body at path, for requests with an access token issued
+// by the IdP. Use it for the provider's user info endpoints.
+func (idp *IdP) Handle(path, body string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.resources[path] = body
+}
+
+// Deny makes the authorize endpoint redirect back with an `access_denied`
+// error, as if the user declined the login.
+func (idp *IdP) Deny() {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.denied = true
+}
+
+func (idp *IdP) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	switch {
+	case q.Get("redirect_uri") == "" || err != nil:
+		http.Error(w, "authtest: invalid redirect_uri", http.StatusBadRequest)
+		return
+	case q.Get("client_id") != ClientID:
+		http.Error(w, "authtest: unknown client_id", http.StatusBadRequest)
+		return
+	case q.Get("response_type") != "code":
+		http.Error(w, "authtest: unsupported response_type", http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	rq := redirectURI.Query()
+	rq.Set("state", q.Get("state"))
+	if idp.denied {
+		rq.Set("error", "access_denied")
+	} else {
+		code := "code-" + uuid.NewString()
+		idp.codes[code] = grant{redirectURI: q.Get("redirect_uri"), scope: q.Get("scope")}
+		rq.Set("code", code)
+	}
+	redirectURI.RawQuery = rq.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (idp *IdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id != ClientID || secret != ClientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code := r.PostForm.Get("code")
+	g, ok := idp.codes[code]
+	if !ok || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	delete(idp.codes, code)
+
+	token := "token-" + uuid.NewString()
+	idp.tokens[token] = true
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"scope":        g.scope,
+	})
+}
+
+func (idp *IdP) resource(w http.ResponseWriter, r *http.Request) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	body, ok := idp.resources[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !idp.tokens[token] {
+		http.Error(w, "authtest: invalid access token", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(body))
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
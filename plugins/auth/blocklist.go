@@ -38,8 +38,11 @@ func WithBlockist(ctx context.Context, bl Blocklist) context.Context {
 }
 
 // MaybeBlock adds a token to the blocklist if a blocklist is present in the
-// context.
+// context. Cached identity tokens for the key are discarded.
 func MaybeBlock(ctx context.Context, key string) error {
+	if c := tokenCacheFromContext(ctx); c != nil {
+		c.invalidate(key)
+	}
 	if bl, ok := ctx.Value(blocklistKey{}).(Blocklist); ok {
 		return bl.Block(ctx, key)
 	}
@@ -0,0 +1,358 @@
+// Package discord provides authentication via Discord's OAuth2 flow.
+//
+// To initiate a login, the client should make a POST request to the
+// `/api/auth/login` endpoint with the following JSON body:
+//
+// ```json
+//
+//	{
+//	  "provider": "discord",
+//	  "redirect_uri": "/dashboard"
+//	}
+//
+// ```
+//
+// The server responds with a `redirect_uri` for Discord. After the user
+// approves the login, Discord redirects back to `/api/auth/discord/callback`,
+// which completes the login and redirects to the original destination. The
+// flow is the same as the server side flow of the google plugin.
+//
+// Community apps often grant access based on server membership. Servers, which
+// Discord's API calls guilds, configured with WithGuilds are checked at login,
+// and those the user belongs to are added to the identity's groups as
+// GuildGroup(id), so that they can be mapped to roles with authz.GroupRoles.
+//
+// ## Configuring a Discord App
+//
+// Create an application at https://discord.com/developers/applications, and
+// under "OAuth2" add the redirect URL, for development:
+// http://localhost:8000/api/auth/discord/callback
+//
+// In production switch out the protocol, host, and port with your domain.
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/internal/oauthflow"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// Constant name for the Discord auth plugin.
+	PluginName = "auth_discord"
+
+	// Constant name used as the auth provider in API requests.
+	ProviderName = "discord"
+
+	authURL  = "https://discord.com/oauth2/authorize"
+	tokenURL = "https://discord.com/api/oauth2/token"
+	apiURL   = "https://discord.com/api"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "auth.discord.id",
+			Description: "Discord OAuth client ID",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.discord.secret",
+			Description: "Discord OAuth client secret",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.discord.guilds",
+			Description: "Discord server (guild) IDs whose membership is added to identity groups",
+			Type:        "[]string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.discord.requireGuild",
+			Description: "Only allow users who belong to one of the configured guilds to log in",
+			Type:        "bool",
+			Default:     "false",
+		},
+	)
+}
+
+// GuildGroup returns the group that members of a Discord server are given, for
+// use in an authz.GroupMapping.
+func GuildGroup(guildID string) string {
+	return "discord:guild:" + guildID
+}
+
+// DiscordOption allows configuration of the DiscordPlugin.
+type DiscordOption func(*DiscordPlugin)
+
+// WithClient configures the DiscordPlugin with the given client id and secret.
+func WithClient(id, secret string) DiscordOption {
+	return func(p *DiscordPlugin) {
+		p.clientID = id
+		p.clientSecret = secret
+	}
+}
+
+// WithGuilds checks whether users belong to the servers at login, adding those
+// they belong to as groups. This requests the `guilds` scope.
+//
+// Config key: `auth.discord.guilds`.
+func WithGuilds(guildIDs ...string) DiscordOption {
+	return func(p *DiscordPlugin) {
+		p.guilds = append(p.guilds, guildIDs...)
+	}
+}
+
+// WithRequiredGuild only allows users who belong to at least one of the
+// servers configured with WithGuilds to log in.
+//
+// Config key: `auth.discord.requireGuild`.
+func WithRequiredGuild() DiscordOption {
+	return func(p *DiscordPlugin) {
+		p.requireGuild = true
+	}
+}
+
+// Plugin for handling Discord authentication.
+func Plugin(opts ...DiscordOption) *DiscordPlugin {
+	p := &DiscordPlugin{
+		clientID:     prefab.Config.String("auth.discord.id"),
+		clientSecret: prefab.Config.String("auth.discord.secret"),
+		guilds:       prefab.Config.Strings("auth.discord.guilds"),
+		requireGuild: prefab.Config.Bool("auth.discord.requireGuild"),
+		endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		apiURL:       apiURL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// DiscordPlugin for handling Discord authentication.
+type DiscordPlugin struct {
+	clientID     string
+	clientSecret string
+	guilds       []string
+	requireGuild bool
+	endpoint     oauth2.Endpoint
+	apiURL       string
+}
+
+// From prefab.Plugin.
+func (p *DiscordPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *DiscordPlugin) Deps() []string {
+	return []string{auth.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *DiscordPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithHTTPHandlerFunc("/api/auth/discord/callback", oauthflow.CallbackHandler(ProviderName, p.clientSecret)),
+		prefab.WithClientConfig("auth.discord.clientId", p.clientID),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *DiscordPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.clientID == "" {
+		return errors.New("discord: config missing client id")
+	}
+	if p.clientSecret == "" {
+		return errors.New("discord: config missing client secret")
+	}
+	if p.requireGuild && len(p.guilds) == 0 {
+		return errors.New("discord: requireGuild is set but no guilds are configured")
+	}
+	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
+	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Discord",
+		Icon:        "discord",
+		Redirect:    true,
+	})
+	return nil
+}
+
+func (p *DiscordPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	if req.Provider != ProviderName {
+		return nil, errors.NewC("discord: login handler called for wrong provider", codes.InvalidArgument)
+	}
+	switch {
+	case req.Creds["code"] != "":
+		user, guilds, err := p.handleAuthorizationCode(ctx, req.Creds["code"], req.Creds["state"])
+		if err != nil {
+			return nil, err
+		}
+		return p.authenticateUser(ctx, user, guilds, req)
+	case len(req.Creds) == 0 || req.Creds["state"] != "":
+		return p.redirectToDiscord(ctx, req.RedirectUri, req.Creds["state"], req.RememberMe)
+	default:
+		return nil, errors.NewC("discord: unexpected credentials, a `code` is required", codes.InvalidArgument)
+	}
+}
+
+func (p *DiscordPlugin) config(ctx context.Context) *oauth2.Config {
+	scopes := []string{"identify", "email"}
+	if len(p.guilds) > 0 {
+		scopes = append(scopes, "guilds")
+	}
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     p.endpoint,
+		RedirectURL:  serverutil.AddressFromContext(ctx) + "/api/auth/discord/callback",
+		Scopes:       scopes,
+	}
+}
+
+func (p *DiscordPlugin) redirectToDiscord(ctx context.Context, dest, state string, rememberMe bool) (*auth.LoginResponse, error) {
+	u := p.config(ctx).AuthCodeURL(oauthflow.NewState(p.clientSecret, dest, state, rememberMe))
+	logging.Infof(ctx, "discord: redirecting to: %s", u)
+	return &auth.LoginResponse{
+		Issued:      false,
+		RedirectUri: u,
+	}, nil
+}
+
+// Exchanges the code for a token, and fetches the user and, if guilds are
+// configured, the IDs of the configured guilds the user belongs to.
+func (p *DiscordPlugin) handleAuthorizationCode(ctx context.Context, code, rawState string) (*User, []string, error) {
+	if _, err := oauthflow.ParseState(p.clientSecret, rawState); err != nil {
+		return nil, nil, errors.Wrap(err, 0).Append("discord: failed to parse state")
+	}
+
+	conf := p.config(ctx)
+	token, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return nil, nil, errors.Codef(codes.Internal, "discord: token exchange failed: %s", err)
+	}
+	client := conf.Client(ctx, token)
+
+	user := &User{}
+	if err := p.get(ctx, client, "/users/@me", user); err != nil {
+		return nil, nil, err
+	}
+	if len(p.guilds) == 0 {
+		return user, nil, nil
+	}
+
+	var guilds []Guild
+	if err := p.get(ctx, client, "/users/@me/guilds", &guilds); err != nil {
+		return nil, nil, err
+	}
+	var member []string
+	for _, g := range guilds {
+		if slices.Contains(p.guilds, g.ID) {
+			member = append(member, g.ID)
+		}
+	}
+	return user, member, nil
+}
+
+func (p *DiscordPlugin) get(ctx context.Context, client *http.Client, path string, v any) error {
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Codef(codes.Internal, "discord: failed to fetch %s: %s", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Codef(codes.Internal, "discord: failed to fetch %s, status: %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errors.Codef(codes.Internal, "discord: failed to decode %s: %s", path, err)
+	}
+	return nil
+}
+
+func (p *DiscordPlugin) authenticateUser(ctx context.Context, user *User, guilds []string, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	if p.requireGuild && len(guilds) == 0 {
+		logging.Warnw(ctx, "discord: login from user outside configured guilds rejected", "user_id", user.ID)
+		return nil, errors.NewC("discord: not a member of a required server", codes.PermissionDenied)
+	}
+
+	identity := auth.Identity{
+		Provider:      ProviderName,
+		SessionID:     uuid.NewString(),
+		AuthTime:      time.Now(),
+		Subject:       user.ID,
+		Name:          user.DisplayName(),
+		Email:         user.Email,
+		EmailVerified: user.Verified,
+	}
+	groups := make([]string, 0, len(guilds))
+	for _, g := range guilds {
+		groups = append(groups, GuildGroup(g))
+	}
+	identity.Groups = auth.NewGroups(groups...)
+
+	if err := auth.CheckLogin(ctx, identity); err != nil {
+		return nil, err
+	}
+	idt, err := auth.IdentityToken(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	logging.Infow(ctx, "discord: user authenticated", "subject", identity.Subject, "guilds", len(guilds))
+
+	if bus := eventbus.FromContext(ctx); bus != nil {
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, identity))
+	}
+
+	if req.IssueToken {
+		return &auth.LoginResponse{
+			Issued: true,
+			Token:  idt,
+		}, nil
+	}
+	if err := auth.SendIdentityCookie(ctx, idt); err != nil {
+		return nil, err
+	}
+	return &auth.LoginResponse{
+		Issued:      true,
+		RedirectUri: req.RedirectUri,
+	}, nil
+}
+
+// User is the response of Discord's `/users/@me` endpoint.
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Email      string `json:"email"`
+	Verified   bool   `json:"verified"`
+	Avatar     string `json:"avatar"`
+	Locale     string `json:"locale"`
+}
+
+// DisplayName returns the user's display name, falling back to their username.
+func (u *User) DisplayName() string {
+	if u.GlobalName != "" {
+		return u.GlobalName
+	}
+	return u.Username
+}
+
+// Guild is an entry of Discord's `/users/@me/guilds` endpoint.
+type Guild struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
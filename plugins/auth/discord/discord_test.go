@@ -0,0 +1,130 @@
+package discord
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/authtest"
+	"github.com/dpup/prefab/plugins/auth/internal/oauthflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeDiscord serves the token, user, and guild endpoints.
+func fakeDiscord(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "the-code", r.PostForm.Get("code"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"tok","token_type":"Bearer","expires_in":604800}`))
+	})
+	mux.HandleFunc("/api/users/@me", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"id":"80351110224678912","username":"nelly","global_name":"Nelly","email":"nelly@example.com","verified":true}`))
+	})
+	mux.HandleFunc("/api/users/@me/guilds", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[{"id":"111","name":"Prefab"},{"id":"222","name":"Other"}]`))
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testPlugin(t *testing.T, opts ...DiscordOption) *DiscordPlugin {
+	s := fakeDiscord(t)
+	p := Plugin(append([]DiscordOption{WithClient("id", "secret")}, opts...)...)
+	p.endpoint = oauth2.Endpoint{
+		AuthURL:   s.URL + "/oauth2/authorize",
+		TokenURL:  s.URL + "/api/oauth2/token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	p.apiURL = s.URL + "/api"
+	return p
+}
+
+func login(t *testing.T, p *DiscordPlugin) (auth.Identity, error) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	resp, err := p.handleLogin(ctx, &auth.LoginRequest{
+		Provider:   ProviderName,
+		IssueToken: true,
+		Creds: map[string]string{
+			"code":  "the-code",
+			"state": oauthflow.NewState("secret", "/", "", false),
+		},
+	})
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	return auth.ParseIdentityToken(ctx, resp.Token)
+}
+
+func TestRedirect(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+
+	resp, err := Plugin(WithClient("id", "secret")).handleLogin(ctx, &auth.LoginRequest{Provider: ProviderName})
+	require.NoError(t, err)
+	u, err := url.Parse(resp.RedirectUri)
+	require.NoError(t, err)
+	assert.Equal(t, "discord.com", u.Host)
+	assert.Equal(t, "identify email", u.Query().Get("scope"))
+	assert.Contains(t, u.Query().Get("redirect_uri"), "/api/auth/discord/callback")
+
+	resp, err = Plugin(WithClient("id", "secret"), WithGuilds("111")).handleLogin(ctx, &auth.LoginRequest{Provider: ProviderName})
+	require.NoError(t, err)
+	u, err = url.Parse(resp.RedirectUri)
+	require.NoError(t, err)
+	assert.Equal(t, "identify email guilds", u.Query().Get("scope"))
+}
+
+func TestLogin(t *testing.T) {
+	identity, err := login(t, testPlugin(t))
+	require.NoError(t, err)
+	assert.Equal(t, ProviderName, identity.Provider)
+	assert.Equal(t, "80351110224678912", identity.Subject)
+	assert.Equal(t, "Nelly", identity.Name)
+	assert.Equal(t, "nelly@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.True(t, identity.Groups.IsZero())
+}
+
+func TestLogin_Guilds(t *testing.T) {
+	identity, err := login(t, testPlugin(t, WithGuilds("111", "333")))
+	require.NoError(t, err)
+	assert.Equal(t, auth.NewGroups(GuildGroup("111")), identity.Groups, "only configured guilds are added")
+
+	_, err = login(t, testPlugin(t, WithGuilds("333"), WithRequiredGuild()))
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, errors.Code(err))
+
+	_, err = login(t, testPlugin(t, WithGuilds("111"), WithRequiredGuild()))
+	require.NoError(t, err)
+}
+
+func TestUser_DisplayName(t *testing.T) {
+	assert.Equal(t, "Nelly", (&User{Username: "nelly", GlobalName: "Nelly"}).DisplayName())
+	assert.Equal(t, "nelly", (&User{Username: "nelly"}).DisplayName())
+}
+
+func TestConformance(t *testing.T) {
+	authtest.Run(t, authtest.Provider{
+		Name:    ProviderName,
+		Subject: "80351110224678912",
+		Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+			idp.Handle("/api/users/@me", `{"id":"80351110224678912","username":"nelly","email":"nelly@example.com","verified":true}`)
+			idp.Handle("/api/users/@me/guilds", `[{"id":"111","name":"Prefab"}]`)
+			p := Plugin(WithClient(authtest.ClientID, authtest.ClientSecret), WithGuilds("111"), WithRequiredGuild())
+			p.endpoint = idp.Endpoint()
+			p.apiURL = idp.URL + "/api"
+			return []prefab.Plugin{p}
+		},
+	})
+}
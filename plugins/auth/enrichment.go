@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc/codes"
+)
+
+// Maximum number of identities cached per enricher.
+const enrichmentCacheSize = 10000
+
+// ErrEnrichmentTooLarge is returned when enrichers add more data than allowed
+// by WithMaxEnrichmentSize.
+var ErrEnrichmentTooLarge = errors.NewC("auth: enriched claims are too large", codes.Internal)
+
+// Enricher returns application data to add to an identity's token, such as
+// roles, a tenant ID, or employee flags looked up in an application database.
+// The value is JSON encoded and stored in Identity.Extras under the enricher's
+// name, so that handlers and authz can read it from the token without extra
+// lookups. Returning nil adds nothing.
+//
+// Enrichers run when a token is issued for an identity, after the provider has
+// authenticated it and login hooks have passed. Each enricher sees the extras
+// added by those registered before it.
+type Enricher func(ctx context.Context, identity Identity) (any, error)
+
+// EnricherOption configures an enricher, see WithEnricher.
+type EnricherOption func(*enricher)
+
+// CacheEnrichment caches the enricher's result for each identity for ttl, so
+// that repeated logins don't repeat the lookup. Use
+// AuthPlugin.ClearEnrichmentCache when the underlying data changes.
+func CacheEnrichment(ttl time.Duration) EnricherOption {
+	return func(e *enricher) {
+		e.cache = &enrichmentCache{ttl: ttl, entries: map[string]cachedEnrichment{}}
+	}
+}
+
+// OptionalEnrichment logs the enricher's errors and issues the token without
+// its data, instead of failing the login.
+func OptionalEnrichment() EnricherOption {
+	return func(e *enricher) {
+		e.optional = true
+	}
+}
+
+// WithEnricher registers an enricher, whose data is stored in Identity.Extras
+// under name. See AuthPlugin.AddEnricher.
+func WithEnricher(name string, fn Enricher, opts ...EnricherOption) AuthOption {
+	return func(p *AuthPlugin) {
+		p.AddEnricher(name, fn, opts...)
+	}
+}
+
+// WithMaxEnrichmentSize limits the total size, in bytes, of the JSON added by
+// enrichers, since it is included in every token and cookie. Logins that
+// exceed the limit fail with ErrEnrichmentTooLarge. Set to 0 to disable.
+//
+// Config key: `auth.enrichment.maxSize`.
+func WithMaxEnrichmentSize(n int) AuthOption {
+	return func(p *AuthPlugin) {
+		p.maxEnrichmentSize = n
+	}
+}
+
+// AddEnricher registers an enricher, whose data is stored in Identity.Extras
+// under name. Enrichers run in the order they were registered. Registering a
+// name twice replaces the earlier enricher.
+func (ap *AuthPlugin) AddEnricher(name string, fn Enricher, opts ...EnricherOption) {
+	e := &enricher{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(e)
+	}
+	for i, existing := range ap.enrichers {
+		if existing.name == name {
+			ap.enrichers[i] = e
+			return
+		}
+	}
+	ap.enrichers = append(ap.enrichers, e)
+}
+
+// ClearEnrichmentCache forgets cached enrichment for the identity, so that the
+// next token issued for it has fresh data.
+func (ap *AuthPlugin) ClearEnrichmentCache(identity Identity) {
+	for _, e := range ap.enrichers {
+		if e.cache != nil {
+			e.cache.delete(enrichmentCacheKey(identity))
+		}
+	}
+}
+
+type enricher struct {
+	name     string
+	fn       Enricher
+	optional bool
+	cache    *enrichmentCache
+}
+
+// run returns the enricher's JSON encoded data, or nil if it returned nothing.
+func (e *enricher) run(ctx context.Context, identity Identity) (json.RawMessage, error) {
+	key := enrichmentCacheKey(identity)
+	if e.cache != nil {
+		if v, ok := e.cache.get(key, timeFunc()); ok {
+			return v, nil
+		}
+	}
+	v, err := e.fn(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	var b json.RawMessage
+	if v != nil {
+		if b, err = json.Marshal(v); err != nil {
+			return nil, errors.Wrap(err, 0).WithCode(codes.Internal)
+		}
+	}
+	if e.cache != nil {
+		e.cache.set(key, b, timeFunc())
+	}
+	return b, nil
+}
+
+type enrichmentKey struct{}
+
+type enrichmentPipeline struct {
+	enrichers []*enricher
+	maxSize   int
+}
+
+// withEnrichers adds enrichers to the context, to be run by IdentityToken.
+// maxSize limits the total size of the data they add, 0 for no limit.
+func withEnrichers(ctx context.Context, maxSize int, enrichers ...*enricher) context.Context {
+	return context.WithValue(ctx, enrichmentKey{}, &enrichmentPipeline{enrichers: enrichers, maxSize: maxSize})
+}
+
+func (ap *AuthPlugin) injectEnrichers(ctx context.Context) context.Context {
+	if len(ap.enrichers) == 0 {
+		return ctx
+	}
+	return withEnrichers(ctx, ap.maxEnrichmentSize, ap.enrichers...)
+}
+
+// enrichIdentity runs the enrichers in the context, adding their data to the
+// identity's extras.
+func enrichIdentity(ctx context.Context, identity Identity) (Identity, error) {
+	p, _ := ctx.Value(enrichmentKey{}).(*enrichmentPipeline)
+	if p == nil || len(p.enrichers) == 0 {
+		return identity, nil
+	}
+
+	size := 0
+	for _, e := range p.enrichers {
+		b, err := e.run(ctx, identity)
+		if err != nil {
+			if e.optional {
+				logging.Warnw(ctx, "auth: optional enricher failed", "enricher", e.name, "error", err)
+				continue
+			}
+			return identity, errors.Wrap(err, 0).Append("auth: enricher " + e.name + " failed")
+		}
+		if b == nil {
+			identity.Extras = identity.Extras.with(e.name, nil)
+			continue
+		}
+		size += len(b)
+		if p.maxSize > 0 && size > p.maxSize {
+			logging.Errorw(ctx, "auth: enriched claims exceed size limit", "enricher", e.name, "size", size, "maxSize", p.maxSize)
+			return identity, errors.Mark(ErrEnrichmentTooLarge, 0)
+		}
+		identity.Extras = identity.Extras.with(e.name, b)
+	}
+	return identity, nil
+}
+
+func enrichmentCacheKey(identity Identity) string {
+	return identity.Provider + "\x00" + identity.Subject
+}
+
+// enrichmentCache holds an enricher's results per identity until they expire.
+type enrichmentCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedEnrichment
+}
+
+type cachedEnrichment struct {
+	value   json.RawMessage
+	expires time.Time
+}
+
+func (c *enrichmentCache) get(key string, now time.Time) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *enrichmentCache) set(key string, value json.RawMessage, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= enrichmentCacheSize {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Still full, so drop arbitrary entries rather than growing.
+		for k := range c.entries {
+			if len(c.entries) < enrichmentCacheSize {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cachedEnrichment{value: value, expires: now.Add(c.ttl)}
+}
+
+func (c *enrichmentCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type tenant struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+func TestIdentityToken_Enrichment(t *testing.T) {
+	ap := Plugin(WithEnricher("tenant", func(ctx context.Context, identity Identity) (any, error) {
+		return tenant{ID: "acme", Roles: []string{"admin"}}, nil
+	}))
+	ap.AddEnricher("employee", func(ctx context.Context, identity Identity) (any, error) {
+		// Later enrichers see earlier data.
+		_, ok := identity.Extras.Get("tenant")
+		assert.True(t, ok)
+		return strings.HasSuffix(identity.Email, "@acme.com"), nil
+	})
+	ap.AddEnricher("none", func(ctx context.Context, identity Identity) (any, error) {
+		return nil, nil
+	})
+	ctx := ap.injectEnrichers(t.Context())
+
+	token, err := IdentityToken(ctx, Identity{Provider: "google", Subject: "1", Email: "a@acme.com"})
+	require.NoError(t, err)
+	identity, err := ParseIdentityToken(ctx, token)
+	require.NoError(t, err)
+
+	b, _ := identity.Extras.Get("tenant")
+	var got tenant
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, tenant{ID: "acme", Roles: []string{"admin"}}, got)
+	b, _ = identity.Extras.Get("employee")
+	assert.JSONEq(t, "true", string(b))
+	assert.NotContains(t, identity.Extras.Map(), "none")
+}
+
+func TestIdentityToken_EnrichmentErrors(t *testing.T) {
+	failing := func(ctx context.Context, identity Identity) (any, error) {
+		return nil, errors.NewC("db down", codes.Unavailable)
+	}
+
+	ctx := Plugin(WithEnricher("roles", failing)).injectEnrichers(t.Context())
+	_, err := IdentityToken(ctx, Identity{Provider: "google", Subject: "1"})
+	require.ErrorContains(t, err, "db down")
+	assert.Equal(t, codes.Unavailable, errors.Code(err))
+
+	// Optional enrichers are skipped.
+	ctx = logging.With(t.Context(), logging.NewDevLogger())
+	ctx = Plugin(WithEnricher("roles", failing, OptionalEnrichment())).injectEnrichers(ctx)
+	token, err := IdentityToken(ctx, Identity{Provider: "google", Subject: "1"})
+	require.NoError(t, err)
+	identity, err := ParseIdentityToken(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, identity.Extras.IsZero())
+}
+
+func TestIdentityToken_EnrichmentSizeLimit(t *testing.T) {
+	big := func(ctx context.Context, identity Identity) (any, error) {
+		return strings.Repeat("x", 100), nil
+	}
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	ctx = Plugin(WithEnricher("a", big), WithEnricher("b", big), WithMaxEnrichmentSize(150)).injectEnrichers(ctx)
+
+	_, err := IdentityToken(ctx, Identity{Provider: "google", Subject: "1"})
+	require.ErrorIs(t, err, ErrEnrichmentTooLarge)
+}
+
+func TestIdentityToken_EnrichmentCache(t *testing.T) {
+	defer func() { timeFunc = time.Now }()
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+
+	calls := 0
+	ap := Plugin(WithEnricher("roles", func(ctx context.Context, identity Identity) (any, error) {
+		calls++
+		return []string{"admin"}, nil
+	}, CacheEnrichment(time.Minute)))
+	ctx := ap.injectEnrichers(t.Context())
+	alice := Identity{Provider: "google", Subject: "alice"}
+
+	for range 3 {
+		_, err := IdentityToken(ctx, alice)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Other identities aren't shared.
+	_, err := IdentityToken(ctx, Identity{Provider: "google", Subject: "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	ap.ClearEnrichmentCache(alice)
+	_, err = IdentityToken(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	now = now.Add(2 * time.Minute)
+	_, err = IdentityToken(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+)
+
+// EraseSubject deletes the login history and account links stored for a
+// subject, for example to fulfil a GDPR erasure request. If the identity is
+// linked to an account, or subject is the account ID, the history of every
+// identity in the account is deleted too. Returns the number of records
+// deleted.
+//
+// Issued tokens aren't stored, so they remain valid until they expire. Failed
+// login counters are keyed by identifier and IP, and expire on their own.
+func (ap *AuthPlugin) EraseSubject(ctx context.Context, provider, subject string) (int, error) {
+	identities := []Identity{{Provider: provider, Subject: subject}}
+	var links []AccountLink
+	if ap.accountLinker != nil {
+		accountID, err := ap.accountLinker.ResolveAccount(ctx, provider, subject)
+		if err != nil {
+			return 0, err
+		}
+		if links, err = ap.accountLinker.LinkedIdentities(ctx, accountID); err != nil {
+			return 0, err
+		}
+		for _, l := range links {
+			identities = append(identities,
+				Identity{Provider: l.Provider, Subject: l.Subject},
+				Identity{Provider: l.Provider, Subject: l.AccountID},
+			)
+		}
+	}
+
+	n := 0
+	if ap.loginGuard != nil {
+		seen := map[string]bool{}
+		for _, id := range identities {
+			key := historyKey(id)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			err := ap.loginGuard.store.Delete(ctx, &LoginHistory{Key: key})
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	for _, l := range links {
+		if err := ap.accountLinker.store.Delete(ctx, l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEraseSubject(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	store := memstore.New()
+	ap := &AuthPlugin{
+		loginGuard:    NewLoginGuard(store, nil, true, nil),
+		accountLinker: NewAccountLinker(store, time.Minute),
+	}
+
+	require.NoError(t, store.Create(ctx,
+		&LoginHistory{Key: "google:g-1", Subject: "g-1", Countries: []string{"US"}},
+		&LoginHistory{Key: "github:gh-1", Subject: "gh-1", Countries: []string{"US"}},
+		&LoginHistory{Key: "google:g-2", Subject: "g-2", Countries: []string{"FR"}},
+		AccountLink{Key: linkKey("google", "g-1"), Provider: "google", Subject: "g-1", AccountID: "google:g-1"},
+		AccountLink{Key: linkKey("github", "gh-1"), Provider: "github", Subject: "gh-1", AccountID: "google:g-1"},
+	))
+
+	n, err := ap.EraseSubject(ctx, "google", "g-1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	err = store.Read(ctx, "github:gh-1", &LoginHistory{})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	links, err := ap.accountLinker.LinkedIdentities(ctx, "google:g-1")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+
+	// Other subjects are untouched.
+	require.NoError(t, store.Read(ctx, "google:g-2", &LoginHistory{}))
+}
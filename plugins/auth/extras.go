@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// DelegatorExtrasPrefix namespaces the extras of the admin who assumed an
+// identity. When an identity is assumed, the admin's extras are copied to it
+// with this prefix, so that `tenant` becomes `delegator.tenant`, while the
+// assumed identity's own extras come from enrichers as usual. Keys with this
+// prefix are reserved for delegated identities.
+const DelegatorExtrasPrefix = "delegator."
+
+// Maximum length of an extras key.
+const maxExtrasKeyLength = 64
+
+// Default for WithMaxExtrasSize, if not configured.
+const defaultMaxExtrasSize = 8192
+
+var (
+	// ErrClaimNotFound is returned by Claim when the identity has no extra with
+	// the requested key.
+	ErrClaimNotFound = errors.NewC("auth: claim not found", codes.NotFound)
+
+	// ErrInvalidClaimKey is returned when an extras key is empty, too long,
+	// contains characters other than letters, digits, `_`, `-`, `.` and `:`, or
+	// uses a reserved namespace.
+	ErrInvalidClaimKey = errors.NewC("auth: invalid claim key", codes.InvalidArgument)
+
+	// ErrExtrasTooLarge is returned when an identity's extras exceed the limit
+	// set by WithMaxExtrasSize.
+	ErrExtrasTooLarge = errors.NewC("auth: identity extras are too large", codes.Internal)
+)
+
+// Extras holds application specific data for an identity, JSON encoded and
+// keyed by name. It is immutable, so that Identity can be copied freely and
+// compared with ==. Read and write an identity's extras with Claim and
+// SetClaim. The zero value has no extras.
+type Extras struct {
+	// JSON object with sorted keys and compacted values, empty when there are
+	// no extras.
+	raw string
+}
+
+// NewExtras returns extras holding a copy of m. Returns an error if a value
+// isn't valid JSON.
+func NewExtras(m map[string]json.RawMessage) (Extras, error) {
+	if len(m) == 0 {
+		return Extras{}, nil
+	}
+	for key, b := range m {
+		if !json.Valid(b) {
+			return Extras{}, errors.Codef(codes.InvalidArgument, "auth: claim %s is not valid JSON", key)
+		}
+	}
+	// Maps are encoded with sorted keys, and raw values are compacted, so equal
+	// extras are encoded identically.
+	b, err := json.Marshal(m)
+	if err != nil {
+		return Extras{}, errors.Wrap(err, 0).WithCode(codes.InvalidArgument)
+	}
+	return Extras{raw: string(b)}, nil
+}
+
+// Len returns the number of extras.
+func (e Extras) Len() int {
+	return len(e.Map())
+}
+
+// IsZero returns whether there are no extras.
+func (e Extras) IsZero() bool {
+	return e.raw == ""
+}
+
+// Get returns the JSON encoded extra stored under key.
+func (e Extras) Get(key string) (json.RawMessage, bool) {
+	b, ok := e.Map()[key]
+	return b, ok
+}
+
+// All returns an iterator over the extras, ordered by key.
+func (e Extras) All() iter.Seq2[string, json.RawMessage] {
+	return func(yield func(string, json.RawMessage) bool) {
+		m := e.Map()
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			if !yield(key, m[key]) {
+				return
+			}
+		}
+	}
+}
+
+// Map returns a new map of the extras, or nil if there are none.
+func (e Extras) Map() map[string]json.RawMessage {
+	if e.raw == "" {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	_ = json.Unmarshal([]byte(e.raw), &m) // Always valid, see NewExtras.
+	return m
+}
+
+// MarshalJSON encodes the extras as a JSON object.
+func (e Extras) MarshalJSON() ([]byte, error) {
+	if e.raw == "" {
+		return []byte("{}"), nil
+	}
+	return []byte(e.raw), nil
+}
+
+// UnmarshalJSON decodes a JSON object.
+func (e *Extras) UnmarshalJSON(b []byte) error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return err
+	}
+	extras, err := NewExtras(m)
+	if err != nil {
+		return err
+	}
+	*e = extras
+	return nil
+}
+
+// with returns a copy of the extras with key set to b, which must be valid
+// JSON, or removed if b is nil.
+func (e Extras) with(key string, b json.RawMessage) Extras {
+	m := e.Map()
+	if b == nil {
+		delete(m, key)
+	} else {
+		if m == nil {
+			m = map[string]json.RawMessage{}
+		}
+		m[key] = b
+	}
+	extras, _ := NewExtras(m)
+	return extras
+}
+
+// Claim decodes the identity's extra stored under key into a T. Returns
+// ErrClaimNotFound if there is no such extra.
+//
+// Example:
+//
+//	tenant, err := auth.Claim[Tenant](identity, "tenant")
+func Claim[T any](identity Identity, key string) (T, error) {
+	var v T
+	b, ok := identity.Extras.Get(key)
+	if !ok {
+		return v, errors.Mark(ErrClaimNotFound, 0).Append(key)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, errors.Wrap(err, 0).WithCode(codes.Internal).Append("auth: failed to decode claim " + key)
+	}
+	return v, nil
+}
+
+// SetClaim JSON encodes value and stores it in the identity's extras under
+// key, to be included in tokens issued for the identity. A nil value removes
+// the extra.
+//
+// Keys may be namespaced with dots, for example `billing.plan`.
+func (i *Identity) SetClaim(key string, value any) error {
+	if err := i.validateExtrasKey(key); err != nil {
+		return err
+	}
+	if value == nil {
+		i.Extras = i.Extras.with(key, nil)
+		return nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return errors.Wrap(err, 0).WithCode(codes.InvalidArgument).Append("auth: failed to encode claim " + key)
+	}
+	i.Extras = i.Extras.with(key, b)
+	return nil
+}
+
+// WithMaxExtrasSize limits the total size, in bytes, of an identity's extras,
+// since they are included in every token and cookie. Issuing a token for an
+// identity that exceeds the limit fails with ErrExtrasTooLarge. Set to 0 to
+// disable.
+//
+// Config key: `auth.extras.maxSize`.
+func WithMaxExtrasSize(n int) AuthOption {
+	return func(p *AuthPlugin) {
+		p.maxExtrasSize = n
+	}
+}
+
+func (i Identity) validateExtrasKey(key string) error {
+	if key == "" || len(key) > maxExtrasKeyLength {
+		return errors.Mark(ErrInvalidClaimKey, 0).Append(key)
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_', r == '-', r == '.', r == ':':
+		default:
+			return errors.Mark(ErrInvalidClaimKey, 0).Append(key)
+		}
+	}
+	if i.Delegation == nil && strings.HasPrefix(key, DelegatorExtrasPrefix) {
+		return errors.Mark(ErrInvalidClaimKey, 0).Append(key + " is reserved for delegated identities")
+	}
+	return nil
+}
+
+// checkExtras validates the identity's extras before they are added to a token.
+func checkExtras(ctx context.Context, identity Identity) error {
+	size := 0
+	for key, b := range identity.Extras.All() {
+		if err := identity.validateExtrasKey(key); err != nil {
+			return err
+		}
+		size += len(key) + len(b)
+	}
+	if maxSize := maxExtrasSizeFromContext(ctx); maxSize > 0 && size > maxSize {
+		return errors.Mark(ErrExtrasTooLarge, 0)
+	}
+	return nil
+}
+
+// delegatedExtras returns the admin's extras, namespaced for the identity
+// they are assuming.
+func delegatedExtras(admin Identity) Extras {
+	m := admin.Extras.Map()
+	extras := make(map[string]json.RawMessage, len(m))
+	for key, b := range m {
+		extras[DelegatorExtrasPrefix+key] = b
+	}
+	delegated, _ := NewExtras(extras) // Values were already valid.
+	return delegated
+}
+
+type extrasLimit struct{}
+
+func injectMaxExtrasSize(n int) prefab.ConfigInjector {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, extrasLimit{}, n)
+	}
+}
+
+func maxExtrasSizeFromContext(ctx context.Context) int {
+	if v, ok := ctx.Value(extrasLimit{}).(int); ok {
+		return v
+	}
+	return defaultMaxExtrasSize
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestClaim(t *testing.T) {
+	identity := Identity{Provider: "google", Subject: "1"}
+	require.NoError(t, identity.SetClaim("tenant", tenant{ID: "acme", Roles: []string{"admin"}}))
+	require.NoError(t, identity.SetClaim("billing.plan", "pro"))
+
+	token, err := IdentityToken(t.Context(), identity)
+	require.NoError(t, err)
+	parsed, err := ParseIdentityToken(t.Context(), token)
+	require.NoError(t, err)
+
+	got, err := Claim[tenant](parsed, "tenant")
+	require.NoError(t, err)
+	assert.Equal(t, tenant{ID: "acme", Roles: []string{"admin"}}, got)
+
+	plan, err := Claim[string](parsed, "billing.plan")
+	require.NoError(t, err)
+	assert.Equal(t, "pro", plan)
+
+	_, err = Claim[string](parsed, "missing")
+	require.ErrorIs(t, err, ErrClaimNotFound)
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+
+	_, err = Claim[int](parsed, "billing.plan")
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, errors.Code(err))
+
+	// Nil removes the claim.
+	require.NoError(t, parsed.SetClaim("billing.plan", nil))
+	assert.NotContains(t, parsed.Extras.Map(), "billing.plan")
+}
+
+func TestSetClaim_InvalidKey(t *testing.T) {
+	var identity Identity
+	for _, key := range []string{"", "has space", "emoji🙂", strings.Repeat("k", 65), "delegator.tenant"} {
+		require.ErrorIs(t, identity.SetClaim(key, "x"), ErrInvalidClaimKey, key)
+	}
+	require.NoError(t, identity.SetClaim("app:role_v2-beta", "x"))
+
+	// Keys set directly are checked when the token is issued.
+	extras, err := NewExtras(map[string]json.RawMessage{"bad key": []byte(`1`)})
+	require.NoError(t, err)
+	identity = Identity{Provider: "google", Subject: "1", Extras: extras}
+	_, err = IdentityToken(t.Context(), identity)
+	require.ErrorIs(t, err, ErrInvalidClaimKey)
+
+	_, err = NewExtras(map[string]json.RawMessage{"key": []byte(`{`)})
+	require.ErrorContains(t, err, "not valid JSON")
+}
+
+func TestIdentityToken_ExtrasSizeLimit(t *testing.T) {
+	identity := Identity{Provider: "google", Subject: "1"}
+	require.NoError(t, identity.SetClaim("data", strings.Repeat("x", 100)))
+
+	ctx := injectMaxExtrasSize(50)(t.Context())
+	_, err := IdentityToken(ctx, identity)
+	require.ErrorIs(t, err, ErrExtrasTooLarge)
+
+	ctx = injectMaxExtrasSize(0)(t.Context())
+	_, err = IdentityToken(ctx, identity)
+	require.NoError(t, err)
+}
+
+func TestAssumeIdentity_Extras(t *testing.T) {
+	ctx := setupTestContext(t)
+	admin := Identity{Subject: "admin123", Provider: "google", SessionID: "admin-session", AuthTime: timeFunc()}
+	require.NoError(t, admin.SetClaim("tenant", tenant{ID: "acme", Roles: []string{"support"}}))
+	ctx = WithIdentityForTest(ctx, admin)
+
+	ctx = Plugin(WithEnricher("tenant", func(ctx context.Context, identity Identity) (any, error) {
+		// Enrichers see the delegator's extras.
+		_, err := Claim[tenant](identity, "delegator.tenant")
+		require.NoError(t, err)
+		return tenant{ID: "globex"}, nil
+	})).injectEnrichers(ctx)
+
+	service := &impl{
+		delegationEnabled: true,
+		adminChecker: func(ctx context.Context, identity Identity) (bool, error) {
+			return true, nil
+		},
+	}
+	resp, err := service.AssumeIdentity(ctx, &AssumeIdentityRequest{Provider: "github", Subject: "user456", Reason: "support-case-123"})
+	require.NoError(t, err)
+
+	parsed, err := ParseIdentityToken(ctx, resp.Token)
+	require.NoError(t, err)
+	own, err := Claim[tenant](parsed, "tenant")
+	require.NoError(t, err)
+	assert.Equal(t, tenant{ID: "globex"}, own)
+	delegator, err := Claim[tenant](parsed, "delegator.tenant")
+	require.NoError(t, err)
+	assert.Equal(t, tenant{ID: "acme", Roles: []string{"support"}}, delegator)
+}
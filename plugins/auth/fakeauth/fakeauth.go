@@ -8,6 +8,7 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dpup/prefab"
@@ -66,6 +67,8 @@ func Plugin(opts ...FakeAuthOption) *FakeAuthPlugin {
 		validator: func(ctx context.Context, creds map[string]string) error {
 			return nil // Accept all by default
 		},
+		personas:      personasFromConfig(),
+		personaHeader: prefab.Config.Bool("fakeauth.personaHeader"),
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -78,6 +81,8 @@ func Plugin(opts ...FakeAuthOption) *FakeAuthPlugin {
 type FakeAuthPlugin struct {
 	defaultIdentity auth.Identity
 	validator       IdentityValidator
+	personas        map[string]Persona
+	personaHeader   bool
 }
 
 // From prefab.Plugin.
@@ -94,6 +99,22 @@ func (p *FakeAuthPlugin) Deps() []string {
 func (p *FakeAuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
 	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Fake login",
+		Credentials: []string{"id", "email", "name", "persona"},
+	})
+	if p.personaHeader {
+		ap.PrependIdentityExtractor(p.identityFromPersonaHeader)
+	}
+	return nil
+}
+
+// From prefab.OptionProvider.
+func (p *FakeAuthPlugin) ServerOptions() []prefab.ServerOption {
+	if p.personaHeader {
+		return []prefab.ServerOption{prefab.WithIncomingHeaders(PersonaHeader)}
+	}
 	return nil
 }
 
@@ -114,6 +135,10 @@ func (p *FakeAuthPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest
 		return nil, err
 	}
 
+	if err := auth.CheckLogin(ctx, id); err != nil {
+		return nil, err
+	}
+
 	// Create a token for the identity
 	token, err := auth.IdentityToken(ctx, id)
 	if err != nil {
@@ -122,7 +147,7 @@ func (p *FakeAuthPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest
 
 	// Publish login event if event bus is available
 	if bus := eventbus.FromContext(ctx); bus != nil {
-		bus.Publish(auth.LoginEvent, auth.NewAuthEvent(id))
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, id))
 	}
 
 	// Return token directly or set a cookie based on request
@@ -145,6 +170,16 @@ func (p *FakeAuthPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest
 
 func extractFakeIdentity(p *FakeAuthPlugin, req *auth.LoginRequest) (auth.Identity, error) {
 	id := p.defaultIdentity
+	if name, ok := req.Creds["persona"]; ok && name != "" {
+		if name == AnonymousPersona {
+			return auth.Identity{}, errors.NewC("fakeauth: can not log in as anonymous", codes.InvalidArgument)
+		}
+		persona, err := p.personaIdentity(name)
+		if err != nil {
+			return auth.Identity{}, err
+		}
+		id = persona
+	}
 
 	// Generate a unique session ID
 	id.SessionID = uuid.New().String()
@@ -180,6 +215,17 @@ func extractFakeIdentity(p *FakeAuthPlugin, req *auth.LoginRequest) (auth.Identi
 	if emailVerified, ok := req.Creds["email_verified"]; ok {
 		id.EmailVerified = emailVerified == "true"
 	}
+	if groups, ok := req.Creds["groups"]; ok && groups != "" {
+		id.Groups = auth.NewGroups(strings.Split(groups, ",")...)
+	}
+
+	// Simulate an admin having assumed the identity.
+	if delegator, ok := req.Creds["delegated_by"]; ok && delegator != "" {
+		return p.delegate(id, delegator, req.Creds["delegation_reason"])
+	}
+	if id.Delegation != nil {
+		id.Delegation.DelegatedAt = id.AuthTime.Unix()
+	}
 	return id, nil
 }
 
@@ -193,6 +239,18 @@ type FakeOptions struct {
 	Name          string
 	EmailVerified *bool
 
+	// Groups delivered by the identity provider, see auth.Identity.Groups.
+	Groups []string
+
+	// Persona to log in as, defined with WithPersona or in config. Other fields
+	// override the persona's values.
+	Persona string
+
+	// Name of a persona which has assumed the identity, producing a delegated
+	// identity.
+	DelegatedBy      string
+	DelegationReason string
+
 	// Error simulation
 	ErrorCode    codes.Code // If set, simulates an error with this code
 	ErrorMessage string     // Custom error message (defaults to "simulated error")
@@ -225,6 +283,20 @@ func (o FakeOptions) toCredsMap() map[string]string {
 		}
 	}
 
+	if len(o.Groups) > 0 {
+		creds["groups"] = strings.Join(o.Groups, ",")
+	}
+
+	if o.Persona != "" {
+		creds["persona"] = o.Persona
+	}
+	if o.DelegatedBy != "" {
+		creds["delegated_by"] = o.DelegatedBy
+		if o.DelegationReason != "" {
+			creds["delegation_reason"] = o.DelegationReason
+		}
+	}
+
 	// Add error simulation fields if provided
 	if o.ErrorCode != 0 {
 		creds["error_code"] = strconv.Itoa(int(o.ErrorCode))
@@ -6,7 +6,10 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/authtest"
+	"github.com/dpup/prefab/prefabtest"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -229,3 +232,17 @@ type mockAuthClient struct {
 func (m *mockAuthClient) Login(ctx context.Context, req *auth.LoginRequest, opts ...grpc.CallOption) (*auth.LoginResponse, error) {
 	return m.LoginFunc(ctx, req, opts...)
 }
+
+func TestConformance(t *testing.T) {
+	authtest.Run(t, authtest.Provider{
+		Name:    ProviderName,
+		Subject: "user-1",
+		Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+			return []prefab.Plugin{Plugin()}
+		},
+		Creds: func(t *testing.T, s *prefabtest.Server) map[string]string {
+			return map[string]string{"id": "user-1"}
+		},
+		InvalidCreds: map[string]string{"error_code": strconv.Itoa(int(codes.Unauthenticated))},
+	})
+}
@@ -0,0 +1,188 @@
+package fakeauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "fakeauth.personas",
+			Description: "Named fake identities, e.g. admin: {id, email, name, emailVerified, mfa, delegatedBy, reason}",
+			Type:        "map",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "fakeauth.personaHeader",
+			Description: "Authenticate requests as the persona named in the X-Fake-User header (development only)",
+			Type:        "bool",
+			Default:     "false",
+		},
+	)
+}
+
+const (
+	// PersonaHeader selects the persona to authenticate a request as, when
+	// enabled with WithPersonaHeader.
+	PersonaHeader = "X-Fake-User"
+
+	// AnonymousPersona is a reserved persona which has no identity.
+	AnonymousPersona = "anonymous"
+
+	// Delegation reason used when a persona doesn't specify one.
+	defaultDelegationReason = "fakeauth persona"
+)
+
+// Persona is a named fake identity.
+type Persona struct {
+	ID            string
+	Email         string
+	Name          string
+	EmailVerified bool
+	MFA           bool
+	Groups        []string
+
+	// Name of another persona which has assumed this one. When set, the persona
+	// produces a delegated identity, for testing the delegation flows.
+	DelegatedBy      string
+	DelegationReason string
+}
+
+// WithPersona defines a named persona, which can be selected at login with the
+// `persona` credential or per request with the X-Fake-User header.
+func WithPersona(name string, persona Persona) FakeAuthOption {
+	return func(p *FakeAuthPlugin) {
+		if p.personas == nil {
+			p.personas = make(map[string]Persona)
+		}
+		p.personas[name] = persona
+	}
+}
+
+// WithPersonaHeader allows requests to authenticate as the persona named in the
+// X-Fake-User header, taking priority over tokens and cookies. This is
+// intended for local development only.
+func WithPersonaHeader(enabled bool) FakeAuthOption {
+	return func(p *FakeAuthPlugin) {
+		p.personaHeader = enabled
+	}
+}
+
+// personasFromConfig reads personas from `fakeauth.personas`.
+func personasFromConfig() map[string]Persona {
+	c := prefab.Config.Cut("fakeauth.personas")
+	names := c.MapKeys("")
+	if len(names) == 0 {
+		return nil
+	}
+	personas := make(map[string]Persona, len(names))
+	for _, name := range names {
+		personas[name] = Persona{
+			ID:               c.String(name + ".id"),
+			Email:            c.String(name + ".email"),
+			Name:             c.String(name + ".name"),
+			EmailVerified:    c.Bool(name + ".emailVerified"),
+			MFA:              c.Bool(name + ".mfa"),
+			DelegatedBy:      c.String(name + ".delegatedBy"),
+			DelegationReason: c.String(name + ".reason"),
+		}
+		if c.Exists(name + ".groups") {
+			persona := personas[name]
+			persona.Groups = c.Strings(name + ".groups")
+			personas[name] = persona
+		}
+	}
+	return personas
+}
+
+// personaIdentity returns the identity for a named persona. Returns
+// auth.ErrNotFound for the anonymous persona.
+func (p *FakeAuthPlugin) personaIdentity(name string) (auth.Identity, error) {
+	id, persona, err := p.basePersonaIdentity(name)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if persona.DelegatedBy != "" {
+		return p.delegate(id, persona.DelegatedBy, persona.DelegationReason)
+	}
+	return id, nil
+}
+
+// basePersonaIdentity returns the identity for a named persona, without
+// following DelegatedBy.
+func (p *FakeAuthPlugin) basePersonaIdentity(name string) (auth.Identity, Persona, error) {
+	if name == AnonymousPersona {
+		return auth.Identity{}, Persona{}, errors.Mark(auth.ErrNotFound, 0)
+	}
+	persona, ok := p.personas[name]
+	if !ok {
+		return auth.Identity{}, Persona{}, errors.NewC("fakeauth: unknown persona "+name, codes.InvalidArgument)
+	}
+	id := auth.Identity{
+		Provider:      ProviderName,
+		Subject:       persona.ID,
+		SessionID:     "fakeauth-" + name,
+		AuthTime:      time.Now(),
+		Email:         persona.Email,
+		EmailVerified: persona.EmailVerified,
+		Name:          persona.Name,
+		MFA:           persona.MFA,
+		Groups:        auth.NewGroups(persona.Groups...),
+	}
+	if id.Subject == "" {
+		id.Subject = name
+	}
+	return id, persona, nil
+}
+
+// delegate returns id as if it had been assumed by the named persona, which
+// must not itself be delegated.
+func (p *FakeAuthPlugin) delegate(id auth.Identity, delegatorName, reason string) (auth.Identity, error) {
+	if delegatorName == AnonymousPersona {
+		return auth.Identity{}, errors.NewC("fakeauth: anonymous can not assume identities", codes.InvalidArgument)
+	}
+	// The delegator's own DelegatedBy isn't followed, so personas which
+	// delegate to themselves, or to each other, can't recurse.
+	delegator, persona, err := p.basePersonaIdentity(delegatorName)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if persona.DelegatedBy != "" {
+		return auth.Identity{}, errors.NewC("fakeauth: delegated persona "+delegatorName+" can not assume identities", codes.InvalidArgument)
+	}
+	if reason == "" {
+		reason = defaultDelegationReason
+	}
+	id.MFA = delegator.MFA
+	id.Delegation = &auth.DelegationInfo{
+		DelegatorSub:       delegator.Subject,
+		DelegatorProvider:  delegator.Provider,
+		DelegatorSessionId: delegator.SessionID,
+		Reason:             reason,
+		DelegatedAt:        id.AuthTime.Unix(),
+	}
+	return id, nil
+}
+
+// identityFromPersonaHeader is an identity extractor which authenticates
+// requests as the persona named in the X-Fake-User header or metadata.
+func (p *FakeAuthPlugin) identityFromPersonaHeader(ctx context.Context) (auth.Identity, error) {
+	name := serverutil.HTTPHeader(ctx, PersonaHeader)
+	if name == "" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(PersonaHeader); len(v) > 0 {
+			name = v[0]
+		}
+	}
+	if name == "" {
+		return auth.Identity{}, errors.Mark(auth.ErrNotFound, 0)
+	}
+	return p.personaIdentity(name)
+}
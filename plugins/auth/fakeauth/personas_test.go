@@ -0,0 +1,159 @@
+package fakeauth
+
+import (
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func personaPlugin() *FakeAuthPlugin {
+	return Plugin(
+		WithPersona("admin", Persona{ID: "admin-1", Email: "admin@example.com", MFA: true, Groups: []string{"admins"}}),
+		WithPersona("member", Persona{ID: "member-1", Email: "member@example.com", EmailVerified: true}),
+		WithPersona("support", Persona{ID: "member-1", DelegatedBy: "admin", DelegationReason: "ticket 123"}),
+	)
+}
+
+func TestPersonaIdentity(t *testing.T) {
+	p := personaPlugin()
+
+	id, err := p.personaIdentity("member")
+	require.NoError(t, err)
+	assert.Equal(t, "member-1", id.Subject)
+	assert.Equal(t, ProviderName, id.Provider)
+	assert.True(t, id.EmailVerified)
+	assert.False(t, auth.IsDelegated(id))
+
+	id, err = p.personaIdentity("support")
+	require.NoError(t, err)
+	assert.Equal(t, "member-1", id.Subject)
+	sub, _, _, ok := auth.GetDelegator(id)
+	require.True(t, ok)
+	assert.Equal(t, "admin-1", sub)
+	assert.Equal(t, "ticket 123", id.Delegation.Reason)
+	assert.True(t, id.MFA, "delegated identities inherit the admin's MFA")
+
+	// Delegated identities round trip through tokens.
+	token, err := auth.IdentityToken(t.Context(), id)
+	require.NoError(t, err)
+	parsed, err := auth.ParseIdentityToken(t.Context(), token)
+	require.NoError(t, err)
+	assert.True(t, auth.IsDelegated(parsed))
+
+	_, err = p.personaIdentity(AnonymousPersona)
+	require.ErrorIs(t, err, auth.ErrNotFound)
+
+	_, err = p.personaIdentity("unknown")
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+func TestPersonaIdentity_DelegationCycles(t *testing.T) {
+	p := Plugin(
+		WithPersona("self", Persona{DelegatedBy: "self"}),
+		WithPersona("a", Persona{DelegatedBy: "b"}),
+		WithPersona("b", Persona{DelegatedBy: "a"}),
+	)
+	for _, name := range []string{"self", "a", "b"} {
+		_, err := p.personaIdentity(name)
+		assert.Equal(t, codes.InvalidArgument, errors.Code(err), name)
+		assert.ErrorContains(t, err, "can not assume identities", name)
+	}
+}
+
+func TestPersonaLogin(t *testing.T) {
+	p := personaPlugin()
+
+	id, err := extractFakeIdentity(p, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    (FakeOptions{Persona: "admin", Name: "Override"}).toCredsMap(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", id.Subject)
+	assert.Equal(t, "admin@example.com", id.Email)
+	assert.Equal(t, "Override", id.Name)
+	assert.Equal(t, auth.NewGroups("admins"), id.Groups)
+
+	id, err = extractFakeIdentity(p, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    (FakeOptions{ID: "user-8", Groups: []string{"eng", "oncall"}}).toCredsMap(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, auth.NewGroups("eng", "oncall"), id.Groups)
+
+	id, err = extractFakeIdentity(p, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    (FakeOptions{ID: "user-9", DelegatedBy: "admin"}).toCredsMap(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user-9", id.Subject)
+	require.True(t, auth.IsDelegated(id))
+	assert.Equal(t, defaultDelegationReason, id.Delegation.Reason)
+
+	_, err = extractFakeIdentity(p, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    (FakeOptions{Persona: AnonymousPersona}).toCredsMap(),
+	})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	_, err = extractFakeIdentity(p, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    (FakeOptions{DelegatedBy: "support"}).toCredsMap(),
+	})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+func TestIdentityFromPersonaHeader(t *testing.T) {
+	p := personaPlugin()
+	ctx := auth.WithIdentityExtractors(t.Context(), p.identityFromPersonaHeader)
+
+	_, err := auth.IdentityFromContext(ctx)
+	require.ErrorIs(t, err, auth.ErrNotFound)
+
+	adminCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-fake-user", "admin"))
+	id, err := auth.IdentityFromContext(adminCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", id.Subject)
+
+	anonCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-fake-user", AnonymousPersona))
+	_, err = auth.IdentityFromContext(anonCtx)
+	require.ErrorIs(t, err, auth.ErrNotFound)
+
+	unknownCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("x-fake-user", "nobody"))
+	_, err = auth.IdentityFromContext(unknownCtx)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+func TestPersonasFromConfig(t *testing.T) {
+	originalConfig := prefab.Config
+	defer func() { prefab.Config = originalConfig }()
+
+	prefab.Config = koanf.New(".")
+	require.NoError(t, prefab.Config.Load(confmap.Provider(map[string]interface{}{
+		"fakeauth.personaHeader":               true,
+		"fakeauth.personas.admin.id":           "admin-1",
+		"fakeauth.personas.admin.mfa":          true,
+		"fakeauth.personas.admin.groups":       []string{"admins"},
+		"fakeauth.personas.member.email":       "member@example.com",
+		"fakeauth.personas.member.delegatedBy": "admin",
+	}, "."), nil))
+
+	p := Plugin()
+	assert.True(t, p.personaHeader)
+	assert.Equal(t, map[string]Persona{
+		"admin":  {ID: "admin-1", MFA: true, Groups: []string{"admins"}},
+		"member": {Email: "member@example.com", DelegatedBy: "admin"},
+	}, p.personas)
+
+	id, err := p.personaIdentity("member")
+	require.NoError(t, err)
+	assert.Equal(t, "member", id.Subject, "subject defaults to the persona name")
+	assert.True(t, auth.IsDelegated(id))
+}
@@ -57,6 +57,12 @@
 // 8. The server redirects the user to the destination specified in the original request.
 // 9. Subsequent API requests are authenticated via the cookie.
 //
+// ## Groups
+//
+// Users of a Google Workspace are added to the identity's groups as
+// DomainGroup(domain), so that the domain can be mapped to roles with
+// authz.GroupRoles.
+//
 // ## Configuring Google OAuth App
 //
 // Follow the official steps here: https://support.google.com/cloud/answer/6158849
@@ -71,6 +77,7 @@ import (
 	"context"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
@@ -79,6 +86,7 @@ import (
 	"github.com/dpup/prefab/logging"
 	"github.com/dpup/prefab/plugins/auth"
 	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/tokenmanager"
 	"github.com/dpup/prefab/serverutil"
 	"github.com/google/uuid"
 
@@ -96,6 +104,13 @@ const (
 	ProviderName = "google"
 )
 
+// DomainGroup returns the group that users of a Google Workspace domain are
+// given, for use in an authz.GroupMapping. The domain comes from the `hd`
+// claim, which Google only sets for Workspace accounts.
+func DomainGroup(domain string) string {
+	return "google:domain:" + domain
+}
+
 func init() {
 	// Register google auth plugin config keys
 	prefab.RegisterConfigKeys(
@@ -131,7 +146,8 @@ func WithClient(id, secret string) GoogleOption {
 // the user explicitly re-consents. If you need to force a new refresh token,
 // the user must revoke access in their Google account settings.
 //
-// Use in combination with WithTokenHandler to receive and store the tokens.
+// Use in combination with WithTokenHandler, or register the tokenmanager plugin,
+// to receive and store the tokens.
 func WithOfflineAccess() GoogleOption {
 	return func(p *GooglePlugin) {
 		p.offlineAccess = true
@@ -154,6 +170,31 @@ func WithScopes(scopes ...string) GoogleOption {
 	}
 }
 
+// WithIncrementalScopes allows scopes to be requested after the user has
+// logged in, when a feature needs them, rather than asking for everything up
+// front. To request them, start a login with the scopes in the `scope`
+// credential, space separated:
+//
+// ```json
+//
+//	{
+//	  "provider": "google",
+//	  "creds": {"scope": "https://www.googleapis.com/auth/calendar.readonly"},
+//	  "redirect_uri": "/settings/calendar"
+//	}
+//
+// ```
+//
+// Google merges the new grant with the user's earlier grants, so the resulting
+// token covers all scopes approved so far. Check OAuthToken.GrantedScopes, or
+// GrantedScopes on the token manager, for which scopes the user approved.
+// Scopes requested with WithScopes can also be requested again this way.
+func WithIncrementalScopes(scopes ...string) GoogleOption {
+	return func(p *GooglePlugin) {
+		p.incrementalScopes = append(p.incrementalScopes, scopes...)
+	}
+}
+
 // WithTokenHandler registers a callback that receives OAuth tokens after
 // successful authentication. The handler is called with the authenticated
 // identity and the OAuth tokens before the login event is published.
@@ -177,6 +218,9 @@ func Plugin(opts ...GoogleOption) *GooglePlugin {
 	p := &GooglePlugin{
 		clientID:     prefab.Config.String("auth.google.id"),
 		clientSecret: prefab.Config.String("auth.google.secret"),
+		endpoint:     oauth2.Endpoint{AuthURL: authEndpoint, TokenURL: google.Endpoint.TokenURL, AuthStyle: google.Endpoint.AuthStyle},
+		userInfoURL:  userInfoEndpoint,
+		revokeURL:    revokeEndpoint,
 	}
 	for _, opt := range opts {
 		opt(p)
@@ -186,11 +230,16 @@ func Plugin(opts ...GoogleOption) *GooglePlugin {
 
 // GooglePlugin for handling Google authentication.
 type GooglePlugin struct {
-	clientID      string
-	clientSecret  string
-	offlineAccess bool
-	extraScopes   []string
-	tokenHandler  TokenHandler
+	clientID          string
+	clientSecret      string
+	offlineAccess     bool
+	extraScopes       []string
+	incrementalScopes []string
+	tokenHandler      TokenHandler
+	tokens            *tokenmanager.TokenManagerPlugin
+	endpoint          oauth2.Endpoint
+	userInfoURL       string
+	revokeURL         string
 }
 
 // From prefab.Plugin.
@@ -203,6 +252,11 @@ func (p *GooglePlugin) Deps() []string {
 	return []string{auth.PluginName}
 }
 
+// From prefab.OptionalDependentPlugin.
+func (p *GooglePlugin) OptDeps() []string {
+	return []string{tokenmanager.PluginName}
+}
+
 // From prefab.OptionProvider.
 func (p *GooglePlugin) ServerOptions() []prefab.ServerOption {
 	return []prefab.ServerOption{
@@ -220,14 +274,32 @@ func (p *GooglePlugin) Init(ctx context.Context, r *prefab.Registry) error {
 		return errors.New("google: config missing client secret")
 	}
 
-	// Warn if offline access is enabled but no token handler is configured.
-	// The refresh token would be obtained but discarded, which is likely a mistake.
-	if p.offlineAccess && p.tokenHandler == nil {
-		logging.Warn(ctx, "google: offline access enabled but no token handler configured; refresh tokens will be discarded")
+	// Tokens are stored by the token manager when it is registered, so that
+	// they can be used and refreshed later.
+	if tm, ok := r.Get(tokenmanager.PluginName).(*tokenmanager.TokenManagerPlugin); ok {
+		tm.RegisterProvider(ProviderName, &oauth2.Config{
+			ClientID:     p.clientID,
+			ClientSecret: p.clientSecret,
+			Endpoint:     p.endpoint,
+		})
+		p.tokens = tm
+	}
+
+	// Warn if offline access is enabled but tokens aren't stored. The refresh
+	// token would be obtained but discarded, which is likely a mistake.
+	if p.offlineAccess && p.tokenHandler == nil && p.tokens == nil {
+		logging.Warn(ctx, "google: offline access enabled but no token handler or token manager configured; refresh tokens will be discarded")
 	}
 
 	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
 	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Google",
+		Icon:        "google",
+		Redirect:    true,
+		Credentials: []string{"idtoken"},
+	})
 
 	return nil
 }
@@ -250,9 +322,16 @@ func (p *GooglePlugin) handleLogin(ctx context.Context, req *auth.LoginRequest)
 		// Verifies the id token and uses the claims to set up the identity cookies.
 		// Note: ID token flow does not provide OAuth tokens for API access.
 		userInfo, err = p.handleIDToken(ctx, req.Creds["idtoken"])
-	case len(req.Creds) == 0 || req.Creds["state"] != "":
-		// Initiates a server side OAuth flow.
-		return p.redirectToGoogle(ctx, req.RedirectUri, req.Creds["state"])
+	case len(req.Creds) == 0 || req.Creds["state"] != "" || req.Creds["scope"] != "":
+		// Initiates a server side OAuth flow, optionally requesting additional
+		// scopes from a user who has already logged in.
+		scopes := strings.Fields(req.Creds["scope"])
+		for _, scope := range scopes {
+			if !slices.Contains(p.incrementalScopes, scope) && !slices.Contains(p.extraScopes, scope) {
+				return nil, errors.Codef(codes.InvalidArgument, "google: scope %q can't be requested, see WithIncrementalScopes", scope)
+			}
+		}
+		return p.redirectToGoogle(ctx, req.RedirectUri, req.Creds["state"], req.RememberMe, scopes)
 	default:
 		return nil, errors.NewC("google: unexpected credentials, a `code` or an `idtoken` are required", codes.InvalidArgument)
 	}
@@ -265,17 +344,18 @@ func (p *GooglePlugin) handleLogin(ctx context.Context, req *auth.LoginRequest)
 
 // Trigger a redirect to google login. This will result in an authorization code
 // being sent back to the callback endpoint.
-func (p *GooglePlugin) redirectToGoogle(ctx context.Context, dest string, state string) (*auth.LoginResponse, error) {
-	wrappedState := p.newOauthState(dest, state)
-
-	// Build scope string with default scopes plus any extra scopes.
-	var scopesSb strings.Builder
-	scopesSb.WriteString("openid email profile")
-	for _, scope := range p.extraScopes {
-		scopesSb.WriteByte(' ')
-		scopesSb.WriteString(scope)
+func (p *GooglePlugin) redirectToGoogle(ctx context.Context, dest string, state string, rememberMe bool, incremental []string) (*auth.LoginResponse, error) {
+	wrappedState := p.newOauthState(dest, state, rememberMe)
+
+	// Build scope string with default scopes plus any extra scopes, and any
+	// requested incrementally.
+	scopeList := []string{"openid", "email", "profile"}
+	for _, scope := range append(slices.Clone(p.extraScopes), incremental...) {
+		if !slices.Contains(scopeList, scope) {
+			scopeList = append(scopeList, scope)
+		}
 	}
-	scopes := scopesSb.String()
+	scopes := strings.Join(scopeList, " ")
 
 	q := url.Values{}
 	q.Add("client_id", p.clientID)
@@ -284,6 +364,16 @@ func (p *GooglePlugin) redirectToGoogle(ctx context.Context, dest string, state
 	q.Add("redirect_uri", oauthCallback(ctx))
 	q.Add("state", wrappedState.Encode())
 
+	// Merge with scopes the user has already granted, so that the token covers
+	// them as well as any requested incrementally.
+	q.Add("include_granted_scopes", "true")
+	if len(incremental) > 0 {
+		// Skip the account chooser when asking the current user for more access.
+		if identity, err := auth.IdentityFromContext(ctx); err == nil && identity.Email != "" {
+			q.Add("login_hint", identity.Email)
+		}
+	}
+
 	if p.offlineAccess {
 		q.Add("access_type", "offline")
 		// Use "consent" to ensure refresh token is returned. Google only returns
@@ -294,18 +384,13 @@ func (p *GooglePlugin) redirectToGoogle(ctx context.Context, dest string, state
 		q.Add("prompt", "select_account")
 	}
 
-	u := url.URL{
-		Scheme:   "https",
-		Host:     "accounts.google.com",
-		Path:     "/o/oauth2/v2/auth",
-		RawQuery: q.Encode(),
-	}
+	u := p.endpoint.AuthURL + "?" + q.Encode()
 
-	logging.Infof(ctx, "google: redirecting to: %s", u.String())
+	logging.Infof(ctx, "google: redirecting to: %s", u)
 
 	return &auth.LoginResponse{
 		Issued:      false,
-		RedirectUri: u.String(),
+		RedirectUri: u,
 	}, nil
 }
 
@@ -328,11 +413,22 @@ func (p *GooglePlugin) handleGoogleCallback(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// The user declined access, or Google failed. Forwarding without a code
+	// would restart the login.
+	if e := r.URL.Query().Get("error"); e != "" {
+		logging.Infow(ctx, "google: authorization failed", "error", e)
+		http.Error(w, "google: authorization failed: "+e, http.StatusForbidden)
+		return
+	}
+
 	q := url.Values{}
 	q.Add("provider", "google")
 	q.Add("redirect_uri", s.RequestUri)
 	q.Add("creds[code]", code)
 	q.Add("creds[state]", rawState)
+	if s.RememberMe {
+		q.Add("remember_me", "true")
+	}
 
 	u := url.URL{}
 	u.Path = "/api/auth/login"
@@ -367,7 +463,7 @@ func (p *GooglePlugin) handleAuthorizationCode(ctx context.Context, code, rawSta
 	var conf = &oauth2.Config{
 		ClientID:     p.clientID,
 		ClientSecret: p.clientSecret,
-		Endpoint:     google.Endpoint,
+		Endpoint:     p.endpoint,
 		RedirectURL:  oauthCallback(ctx),
 		Scopes:       scopes,
 	}
@@ -382,16 +478,17 @@ func (p *GooglePlugin) handleAuthorizationCode(ctx context.Context, code, rawSta
 
 	// Convert to our OAuthToken type.
 	oauthToken := &OAuthToken{
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
-		TokenType:    token.TokenType,
-		Expiry:       token.Expiry,
+		AccessToken:   token.AccessToken,
+		RefreshToken:  token.RefreshToken,
+		TokenType:     token.TokenType,
+		Expiry:        token.Expiry,
+		GrantedScopes: tokenmanager.Scopes(token),
 	}
 
 	// Use the access token to fetch the user's profile.
 	logging.Info(ctx, "google: fetching user profile")
 	client := conf.Client(ctx, token)
-	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, userInfoEndpoint, nil)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, nil, errors.Codef(codes.Internal, "google: failed to fetch user profile: %s", err)
@@ -420,14 +517,9 @@ func (p *GooglePlugin) handleIDToken(ctx context.Context, token string) (*UserIn
 	return UserInfoFromClaims(payload.Claims)
 }
 
-// Maps the Google UserInfo to a prefab Identity. If req.IssueToken is true,
-// then the token is returned to the client. If not, then the token is set as a
-// cookie.
-//
-// If a TokenHandler is configured and an OAuth token is provided, the handler
-// is called before the login event is published. This allows applications to
-// store tokens for later use with Google APIs.
-func (p *GooglePlugin) authenticateUserInfo(ctx context.Context, userInfo *UserInfo, oauthToken *OAuthToken, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+// userIdentity maps the Google UserInfo to a new session's Identity. Users of
+// a Google Workspace are added to the DomainGroup of its domain.
+func userIdentity(userInfo *UserInfo) auth.Identity {
 	identity := auth.Identity{
 		Provider:      ProviderName,
 		SessionID:     uuid.NewString(),
@@ -437,6 +529,26 @@ func (p *GooglePlugin) authenticateUserInfo(ctx context.Context, userInfo *UserI
 		Email:         userInfo.Email,
 		EmailVerified: userInfo.IsConfirmed(),
 	}
+	if userInfo.Hd != "" {
+		identity.Groups = auth.NewGroups(DomainGroup(userInfo.Hd))
+	}
+	return identity
+}
+
+// Maps the Google UserInfo to a prefab Identity. If req.IssueToken is true,
+// then the token is returned to the client. If not, then the token is set as a
+// cookie.
+//
+// If a TokenHandler is configured and an OAuth token is provided, the handler
+// is called before the login event is published. This allows applications to
+// store tokens for later use with Google APIs. When the tokenmanager plugin is
+// registered, the token is also stored there.
+func (p *GooglePlugin) authenticateUserInfo(ctx context.Context, userInfo *UserInfo, oauthToken *OAuthToken, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	identity := userIdentity(userInfo)
+
+	if err := auth.CheckLogin(ctx, identity); err != nil {
+		return nil, err
+	}
 
 	// Create an identity token and return it to the client.
 	idt, err := auth.IdentityToken(ctx, identity)
@@ -456,9 +568,19 @@ func (p *GooglePlugin) authenticateUserInfo(ctx context.Context, userInfo *UserI
 		}
 		logging.Info(ctx, "google: token handler completed successfully")
 	}
+	if p.tokens != nil && oauthToken != nil {
+		if err := p.tokens.Store(ctx, identity, ProviderName, &oauth2.Token{
+			AccessToken:  oauthToken.AccessToken,
+			RefreshToken: oauthToken.RefreshToken,
+			TokenType:    oauthToken.TokenType,
+			Expiry:       oauthToken.Expiry,
+		}); err != nil {
+			return nil, errors.Wrap(err, 0).Append("google: failed to store token")
+		}
+	}
 
 	if bus := eventbus.FromContext(ctx); bus != nil {
-		bus.Publish(auth.LoginEvent, auth.NewAuthEvent(identity))
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, identity))
 	}
 
 	if req.IssueToken {
@@ -1,12 +1,17 @@
 package google
 
 import (
+	"net/url"
 	"testing"
 
 	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
 	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/authtest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 )
 
 func TestPlugin(t *testing.T) {
@@ -122,3 +127,66 @@ func TestWithClient(t *testing.T) {
 	assert.Equal(t, "my-id", p.clientID)
 	assert.Equal(t, "my-secret", p.clientSecret)
 }
+
+func TestUserIdentity_Groups(t *testing.T) {
+	identity := userIdentity(&UserInfo{ID: "1", Email: "han@example.com", Hd: "example.com"})
+	assert.Equal(t, auth.NewGroups("google:domain:example.com"), identity.Groups)
+
+	identity = userIdentity(&UserInfo{ID: "2", Email: "han@gmail.com"})
+	assert.True(t, identity.Groups.IsZero())
+}
+
+func TestHandleLogin_IncrementalScopes(t *testing.T) {
+	const calendar = "https://www.googleapis.com/auth/calendar.readonly"
+	p := Plugin(WithClient("id", "secret"), WithIncrementalScopes(calendar))
+	base := logging.With(t.Context(), logging.NewDevLogger())
+	ctx := auth.WithIdentityForTest(base, auth.Identity{
+		Provider: ProviderName,
+		Subject:  "123",
+		Email:    "alice@example.com",
+	})
+
+	resp, err := p.handleLogin(ctx, &auth.LoginRequest{
+		Provider:    ProviderName,
+		Creds:       map[string]string{"scope": calendar},
+		RedirectUri: "/settings",
+	})
+	require.NoError(t, err)
+	u, err := url.Parse(resp.RedirectUri)
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "openid email profile "+calendar, q.Get("scope"))
+	assert.Equal(t, "true", q.Get("include_granted_scopes"))
+	assert.Equal(t, "alice@example.com", q.Get("login_hint"))
+
+	// Scopes which weren't allowed are rejected.
+	_, err = p.handleLogin(ctx, &auth.LoginRequest{
+		Provider: ProviderName,
+		Creds:    map[string]string{"scope": "https://mail.google.com/"},
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+
+	// Regular logins only request the default scopes, merged with earlier grants.
+	resp, err = p.handleLogin(base, &auth.LoginRequest{Provider: ProviderName})
+	require.NoError(t, err)
+	u, err = url.Parse(resp.RedirectUri)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email profile", u.Query().Get("scope"))
+	assert.Equal(t, "true", u.Query().Get("include_granted_scopes"))
+	assert.Empty(t, u.Query().Get("login_hint"))
+}
+
+func TestConformance(t *testing.T) {
+	authtest.Run(t, authtest.Provider{
+		Name:    ProviderName,
+		Subject: "1234567890",
+		Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+			idp.Handle("/userinfo", `{"sub":"1234567890","email":"ada@example.com","email_verified":true,"name":"Ada"}`)
+			p := Plugin(WithClient(authtest.ClientID, authtest.ClientSecret))
+			p.endpoint = idp.Endpoint()
+			p.userInfoURL = idp.URL + "/userinfo"
+			return []prefab.Plugin{p}
+		},
+	})
+}
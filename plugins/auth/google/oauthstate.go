@@ -20,6 +20,7 @@ const (
 type oauthState struct {
 	OriginalState string    `json:"s"`
 	RequestUri    string    `json:"r"`
+	RememberMe    bool      `json:"rm,omitempty"`
 	TimeStamp     time.Time `json:"t"`
 	Signature     string    `json:"sig"`
 }
@@ -29,10 +30,11 @@ func (s *oauthState) Encode() string {
 	return base64.StdEncoding.EncodeToString(b)
 }
 
-func (p *GooglePlugin) newOauthState(code string, redirectUri string) *oauthState {
+func (p *GooglePlugin) newOauthState(code string, redirectUri string, rememberMe bool) *oauthState {
 	s := &oauthState{
 		OriginalState: redirectUri,
 		RequestUri:    code,
+		RememberMe:    rememberMe,
 		TimeStamp:     time.Now(),
 	}
 
@@ -44,7 +44,7 @@ func TestGooglePlugin_newOauthState(t *testing.T) {
 	code := "original-code"
 	redirectUri := "/redirect-uri"
 
-	state := p.newOauthState(code, redirectUri)
+	state := p.newOauthState(code, redirectUri, false)
 
 	assert.NotNil(t, state)
 	assert.Equal(t, redirectUri, state.OriginalState)
@@ -71,7 +71,7 @@ func TestGooglePlugin_parseState(t *testing.T) {
 		{
 			name: "valid state",
 			setupState: func() string {
-				state := p.newOauthState("test-code", "/dashboard")
+				state := p.newOauthState("test-code", "/dashboard", false)
 				return state.Encode()
 			},
 			expectedError: false,
@@ -112,7 +112,7 @@ func TestGooglePlugin_parseState(t *testing.T) {
 
 				// Re-parse to get the signature
 				p2 := &GooglePlugin{clientSecret: "test-client-secret"}
-				fullState := p2.newOauthState(state.RequestUri, state.OriginalState)
+				fullState := p2.newOauthState(state.RequestUri, state.OriginalState, false)
 				fullState.TimeStamp = state.TimeStamp
 
 				return fullState.Encode()
@@ -123,7 +123,7 @@ func TestGooglePlugin_parseState(t *testing.T) {
 			name: "wrong signature",
 			setupState: func() string {
 				// Create state with correct signature
-				state := p.newOauthState("test-code", "/dashboard")
+				state := p.newOauthState("test-code", "/dashboard", false)
 
 				// Tamper with the state by changing the request URI
 				decoded, _ := base64.StdEncoding.DecodeString(state.Encode())
@@ -180,7 +180,7 @@ func TestOauthState_RoundTrip(t *testing.T) {
 	originalRedirect := "/original-redirect"
 
 	// Create and encode state
-	state := p.newOauthState(originalCode, originalRedirect)
+	state := p.newOauthState(originalCode, originalRedirect, false)
 	encoded := state.Encode()
 
 	// Parse it back
@@ -198,7 +198,7 @@ func TestOauthState_DifferentSecrets(t *testing.T) {
 	p2 := &GooglePlugin{clientSecret: "secret2"}
 
 	// Create state with p1
-	state := p1.newOauthState("code", "redirect")
+	state := p1.newOauthState("code", "redirect", false)
 	encoded := state.Encode()
 
 	// Try to parse with p2 (different secret)
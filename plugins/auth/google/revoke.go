@@ -0,0 +1,79 @@
+package google
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/httpclient"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/tokenmanager"
+	"google.golang.org/grpc/codes"
+)
+
+// https://developers.google.com/identity/protocols/oauth2/web-server#tokenrevoke
+const revokeEndpoint = "https://oauth2.googleapis.com/revoke"
+
+// Revoke revokes the access the identity granted to the app, including all
+// scopes granted incrementally, and deletes the stored token. Requires the
+// tokenmanager plugin. Use it when a user disconnects their Google account.
+//
+// Revoking doesn't log the user out, since their identity token was issued by
+// this server.
+func (p *GooglePlugin) Revoke(ctx context.Context, identity auth.Identity) error {
+	if p.tokens == nil {
+		return errors.NewC("google: tokenmanager plugin is required to revoke tokens", codes.FailedPrecondition)
+	}
+	token, err := p.tokens.StoredToken(ctx, identity, ProviderName)
+	if errors.Is(err, tokenmanager.ErrNoToken) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Revoking the refresh token also revokes access tokens issued from it.
+	t := token.RefreshToken
+	if t == "" {
+		t = token.AccessToken
+	}
+	if err := p.RevokeToken(ctx, t); err != nil {
+		return err
+	}
+	return p.tokens.Delete(ctx, identity, ProviderName)
+}
+
+// RevokeToken revokes an access or refresh token with Google, for apps which
+// store tokens themselves via WithTokenHandler. Tokens which have already been
+// revoked or have expired are ignored.
+func (p *GooglePlugin) RevokeToken(ctx context.Context, token string) error {
+	form := url.Values{"token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.revokeURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := httpclient.New(httpclient.WithoutPropagation()).Do(req)
+	if err != nil {
+		return errors.Codef(codes.Unavailable, "google: token revocation failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		logging.Info(ctx, "google: token revoked")
+		return nil
+	}
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error == "invalid_token" {
+		logging.Info(ctx, "google: token was already invalid")
+		return nil
+	}
+	return errors.Codef(codes.Unavailable, "google: token revocation failed, status: %d, error: %s", resp.StatusCode, body.Error)
+}
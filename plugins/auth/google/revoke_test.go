@@ -0,0 +1,75 @@
+package google
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/plugins/tokenmanager"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+func TestRevoke(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+
+	var revoked []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		token := r.PostForm.Get("token")
+		revoked = append(revoked, token)
+		if token == "already-revoked" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_token","error_description":"Token expired or revoked"}`))
+			return
+		}
+		if token != "refresh" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}))
+	defer s.Close()
+
+	p := Plugin(WithClient("id", "secret"))
+	p.revokeURL = s.URL
+	tm := tokenmanager.Plugin(tokenmanager.WithEncryptionKey("key"))
+	r := &prefab.Registry{}
+	r.Register(auth.Plugin())
+	r.Register(p)
+	r.Register(tm)
+	r.Register(storage.Plugin(memstore.New()))
+	require.NoError(t, r.Init(ctx))
+
+	identity := auth.Identity{Provider: ProviderName, Subject: "123"}
+	require.NoError(t, tm.Store(ctx, identity, ProviderName, &oauth2.Token{AccessToken: "access", RefreshToken: "refresh"}))
+
+	require.NoError(t, p.Revoke(ctx, identity))
+	assert.Equal(t, []string{"refresh"}, revoked, "the refresh token is revoked")
+	_, err := tm.StoredToken(ctx, identity, ProviderName)
+	require.ErrorIs(t, err, tokenmanager.ErrNoToken)
+
+	// Nothing to revoke.
+	require.NoError(t, p.Revoke(ctx, identity))
+	assert.Len(t, revoked, 1)
+
+	require.NoError(t, p.RevokeToken(ctx, "already-revoked"))
+
+	err = p.RevokeToken(ctx, "other")
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, errors.Code(err))
+}
+
+func TestRevoke_RequiresTokenManager(t *testing.T) {
+	p := Plugin(WithClient("id", "secret"))
+	err := p.Revoke(t.Context(), auth.Identity{Subject: "123"})
+	require.Error(t, err)
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+}
@@ -2,6 +2,7 @@ package google
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/dpup/prefab/plugins/auth"
@@ -26,6 +27,11 @@ type OAuthToken struct {
 	// Expiry is the time at which the access token expires.
 	// A zero value means the token does not expire.
 	Expiry time.Time
+
+	// GrantedScopes are the scopes the user approved, which may be fewer than
+	// were requested, since users can deselect scopes on the consent screen.
+	// Includes scopes granted in earlier logins, see WithIncrementalScopes.
+	GrantedScopes []string
 }
 
 // HasRefreshToken returns true if the token includes a refresh token.
@@ -33,6 +39,16 @@ func (t OAuthToken) HasRefreshToken() bool {
 	return t.RefreshToken != ""
 }
 
+// HasScopes returns true if the user granted all of the scopes.
+func (t OAuthToken) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		if !slices.Contains(t.GrantedScopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
 // IsExpired returns true if the access token has expired.
 // Returns false if the token has no expiry time set.
 func (t OAuthToken) IsExpired() bool {
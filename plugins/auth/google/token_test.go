@@ -201,3 +201,11 @@ func TestWithTokenHandler_ErrorAborts(t *testing.T) {
 	err := p.tokenHandler(context.Background(), auth.Identity{}, OAuthToken{})
 	assert.ErrorIs(t, err, expectedErr)
 }
+
+func TestOAuthToken_HasScopes(t *testing.T) {
+	token := OAuthToken{GrantedScopes: []string{"openid", "email", "calendar"}}
+	assert.True(t, token.HasScopes())
+	assert.True(t, token.HasScopes("email", "calendar"))
+	assert.False(t, token.HasScopes("email", "drive"))
+	assert.False(t, OAuthToken{}.HasScopes("email"))
+}
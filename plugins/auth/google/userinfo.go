@@ -9,7 +9,10 @@ import (
 	"google.golang.org/grpc/codes"
 )
 
-const userInfoEndpoint = "https://www.googleapis.com/oauth2/v3/userinfo"
+const (
+	authEndpoint     = "https://accounts.google.com/o/oauth2/v2/auth"
+	userInfoEndpoint = "https://www.googleapis.com/oauth2/v3/userinfo"
+)
 
 // UserInfoFromClaims returns a UserInfo struct from the claims map. If the
 // claims are invalid or missing, an error is returned.
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"encoding/json"
+	"iter"
+	"strings"
+)
+
+// Separates the names in Groups, it can't appear in a group name.
+const groupSeparator = "\x00"
+
+// Groups is an ordered list of group names. It is immutable, so that Identity
+// can be copied freely and compared with ==. The zero value has no groups.
+type Groups struct {
+	names string
+}
+
+// NewGroups returns the named groups. Empty names, names containing NUL, and
+// duplicates are dropped.
+func NewGroups(names ...string) Groups {
+	var b strings.Builder
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || strings.Contains(name, groupSeparator) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if b.Len() > 0 {
+			b.WriteString(groupSeparator)
+		}
+		b.WriteString(name)
+	}
+	return Groups{names: b.String()}
+}
+
+// Len returns the number of groups.
+func (g Groups) Len() int {
+	if g.names == "" {
+		return 0
+	}
+	return strings.Count(g.names, groupSeparator) + 1
+}
+
+// IsZero returns whether there are no groups.
+func (g Groups) IsZero() bool {
+	return g.names == ""
+}
+
+// Contains returns whether name is one of the groups.
+func (g Groups) Contains(name string) bool {
+	for n := range g.All() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns an iterator over the group names, in order.
+func (g Groups) All() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		if g.names == "" {
+			return
+		}
+		for name := range strings.SplitSeq(g.names, groupSeparator) {
+			if !yield(name) {
+				return
+			}
+		}
+	}
+}
+
+// Names returns a new slice of the group names, or nil if there are none.
+func (g Groups) Names() []string {
+	if g.names == "" {
+		return nil
+	}
+	return strings.Split(g.names, groupSeparator)
+}
+
+// String returns the group names, separated by commas.
+func (g Groups) String() string {
+	return strings.ReplaceAll(g.names, groupSeparator, ",")
+}
+
+// MarshalJSON encodes the groups as an array of names.
+func (g Groups) MarshalJSON() ([]byte, error) {
+	names := g.Names()
+	if names == nil {
+		names = []string{}
+	}
+	return json.Marshal(names)
+}
+
+// UnmarshalJSON decodes an array of names.
+func (g *Groups) UnmarshalJSON(b []byte) error {
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	*g = NewGroups(names...)
+	return nil
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/dpup/prefab"
+)
+
+// RequireIdentity is HTTP middleware which rejects requests that are not
+// authenticated, for use with prefab.WithRoute.
+func RequireIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := IdentityFromContext(r.Context()); err != nil {
+			prefab.WriteJSONError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -14,6 +14,11 @@ import (
 // Leeway for JWT expiration checks.
 const jwtLeeway = 5 * time.Second
 
+// ServiceProvider is the provider of identities which authenticate a peer
+// service rather than a user, such as those derived from client certificates
+// by the auth/mtls plugin.
+const ServiceProvider = "mtls"
+
 type Identity struct {
 
 	// Unique identifier for the session that authenticated the identity. Maps to
@@ -24,9 +29,15 @@ type Identity struct {
 	// claim. May differ from IssuedAt if a token is refreshed.
 	AuthTime time.Time
 
-	// Identity provider specific identifier. Maps to `sub` JWT claim.
+	// Identity provider specific identifier. Maps to `sub` JWT claim. When
+	// account linking is enabled and the identity has been linked, this is the
+	// canonical account ID instead.
 	Subject string
 
+	// The identity provider's own identifier, when Subject has been resolved to
+	// a linked account. Empty otherwise.
+	ProviderSubject string
+
 	// Name of the identity provider used to authenticate the user. Maps to custom
 	// `idp` JWT claim.
 	Provider string
@@ -43,11 +54,37 @@ type Identity struct {
 	// claim.
 	Name string
 
+	// Whether the identity comes from a long-lived "remember me" login, which
+	// may warrant lower assurance than a session scoped login. Maps to custom
+	// `rmb` JWT claim.
+	RememberMe bool
+
+	// Whether the login used multi-factor authentication. Set by login handlers
+	// which verify a second factor. Maps to custom `mfa` JWT claim.
+	MFA bool
+
+	// Groups the identity belongs to at the identity provider, such as directory
+	// or SSO groups. Providers which receive groups in tokens or assertions
+	// should set this, so they can be mapped to roles, see authz.GroupRoles.
+	// Maps to custom `grp` JWT claim.
+	Groups Groups
+
+	// Application specific data, JSON encoded and keyed by name, such as that
+	// added by enrichers, see WithEnricher. Read and write with Claim and
+	// SetClaim. Maps to custom `ext` JWT claim.
+	Extras Extras
+
 	// Delegation contains metadata when this identity was assumed by an admin user.
 	// If nil, this is a normal (non-delegated) identity.
 	Delegation *DelegationInfo
 }
 
+// IsService returns whether the identity is a peer service, rather than a
+// user. See ServiceProvider.
+func (i Identity) IsService() bool {
+	return i.Provider == ServiceProvider
+}
+
 // IdentityExtractor is a function which returns a user identity from a given
 // context. Providers should return ErrNotFound if no identity is found. By default,
 // JWT identities are extracted from the `Authorization` header, and then from
@@ -64,7 +101,8 @@ func WithIdentityExtractors(ctx context.Context, providers ...IdentityExtractor)
 // IdentityFromContext parses and verifies a JWT received from the incoming
 // request context (including GRPC metadata.) An `Authorization` header will
 // take precedence over a `Cookie`, which in turn will take precedence over
-// other identity extractors.
+// other identity extractors. If account linking is enabled, the subject is
+// resolved to the identity's canonical account ID.
 func IdentityFromContext(ctx context.Context) (Identity, error) {
 	providers, ok := ctx.Value(identityExtractorsKey{}).([]IdentityExtractor)
 	if !ok {
@@ -75,13 +113,24 @@ func IdentityFromContext(ctx context.Context) (Identity, error) {
 		if errors.Is(err, ErrNotFound) {
 			continue
 		}
-		return i, err
+		if err != nil {
+			return i, err
+		}
+		return resolveAccount(ctx, i)
 	}
 	return Identity{}, ErrNotFound
 }
 
 // IdentityToken creates a signed JWT for the given identity.
 func IdentityToken(ctx context.Context, identity Identity) (string, error) {
+	identity, err := enrichIdentity(ctx, identity)
+	if err != nil {
+		return "", err
+	}
+	if err := checkExtras(ctx, identity); err != nil {
+		return "", err
+	}
+
 	// Both issuer and audience are set to the current server, indicating that the
 	// token was created by this server and is only intended to be used for this
 	// server.
@@ -90,7 +139,7 @@ func IdentityToken(ctx context.Context, identity Identity) (string, error) {
 	claims := &Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			ID:        identity.SessionID,
-			Subject:   identity.Subject,
+			Subject:   providerSubject(identity),
 			Audience:  jwt.ClaimStrings{address},
 			Issuer:    address,
 			IssuedAt:  jwt.NewNumericDate(timeFunc()),
@@ -101,6 +150,10 @@ func IdentityToken(ctx context.Context, identity Identity) (string, error) {
 		EmailVerified: identity.EmailVerified,
 		Provider:      identity.Provider,
 		AuthTime:      jwt.NewNumericDate(identity.AuthTime),
+		RememberMe:    identity.RememberMe || rememberMeFromContext(ctx),
+		MFA:           identity.MFA,
+		Groups:        identity.Groups.Names(),
+		Extras:        identity.Extras,
 	}
 
 	// Include delegation information if present
@@ -122,14 +175,74 @@ func IdentityToken(ctx context.Context, identity Identity) (string, error) {
 
 // ParseIdentityToken takes a signed JWT, validates it, and returns the identity
 // information encoded within. Invalid and expired tokens will error.
+//
+// Verified tokens are cached by the auth plugin, see WithTokenCacheSize, and
+// are checked against the blocklist on every call.
 func ParseIdentityToken(ctx context.Context, tokenString string) (Identity, error) {
 	address := serverutil.AddressFromContext(ctx)
+	key := signingKeyFromContext(ctx)
+	cache := tokenCacheFromContext(ctx)
+
+	var claims *Claims
+	if cache != nil {
+		claims = cache.get(tokenString, string(key), address, timeFunc())
+	}
+	if claims == nil {
+		var err error
+		if claims, err = verifyIdentityToken(tokenString, key, address); err != nil {
+			return Identity{}, err
+		}
+		if cache != nil {
+			cache.add(tokenString, string(key), address, claims)
+		}
+	}
+
+	// Check to see if the token has been revoked or blocked.
+	if blocked, err := IsBlocked(ctx, claims.ID); blocked || err != nil {
+		if err != nil {
+			return Identity{}, err
+		}
+		if cache != nil {
+			cache.invalidate(claims.ID)
+		}
+		return Identity{}, ErrRevoked
+	}
 
+	identity := Identity{
+		Provider:      claims.Provider,
+		SessionID:     claims.ID,
+		AuthTime:      claims.AuthTime.Time,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+		RememberMe:    claims.RememberMe,
+		MFA:           claims.MFA,
+		Groups:        NewGroups(claims.Groups...),
+		Extras:        claims.Extras,
+	}
+
+	// Extract delegation information if present
+	if claims.DelegatorSub != "" {
+		identity.Delegation = &DelegationInfo{
+			DelegatorSub:       claims.DelegatorSub,
+			DelegatorProvider:  claims.DelegatorProvider,
+			DelegatorSessionId: claims.DelegatorSessionID,
+			Reason:             claims.DelegationReason,
+			DelegatedAt:        claims.DelegatedAt,
+		}
+	}
+
+	return identity, nil
+}
+
+// verifyIdentityToken checks the signature and claims of a token.
+func verifyIdentityToken(tokenString string, key []byte, address string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenString,
 		&Claims{},
 		func(token *jwt.Token) (interface{}, error) {
-			return signingKeyFromContext(ctx), nil
+			return key, nil
 		},
 		jwt.WithIssuer(address), // TODO: Possibly relax to allow tokens created by other issuers.
 		jwt.WithAudience(address),
@@ -138,47 +251,17 @@ func ParseIdentityToken(ctx context.Context, tokenString string) (Identity, erro
 		jwt.WithIssuedAt(),
 	)
 	if err != nil {
-		return Identity{}, errors.Wrap(err, 0).WithCode(codes.Unauthenticated)
+		return nil, errors.Wrap(err, 0).WithCode(codes.Unauthenticated)
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		if err := claims.Validate(); err != nil {
-			return Identity{}, err
-		}
-
-		// Check to see if the token has been revoked or blocked.
-		if blocked, err := IsBlocked(ctx, claims.ID); blocked || err != nil {
-			if err != nil {
-				return Identity{}, err
-			}
-			return Identity{}, ErrRevoked
-		}
-
-		identity := Identity{
-			Provider:      claims.Provider,
-			SessionID:     claims.ID,
-			AuthTime:      claims.AuthTime.Time,
-			Subject:       claims.Subject,
-			Email:         claims.Email,
-			EmailVerified: claims.EmailVerified,
-			Name:          claims.Name,
-		}
-
-		// Extract delegation information if present
-		if claims.DelegatorSub != "" {
-			identity.Delegation = &DelegationInfo{
-				DelegatorSub:       claims.DelegatorSub,
-				DelegatorProvider:  claims.DelegatorProvider,
-				DelegatorSessionId: claims.DelegatorSessionID,
-				Reason:             claims.DelegationReason,
-				DelegatedAt:        claims.DelegatedAt,
-			}
-		}
-
-		return identity, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, errors.Mark(ErrInvalidToken, 0).Append("invalid claims")
 	}
-
-	return Identity{}, errors.Mark(ErrInvalidToken, 0).Append("invalid claims")
+	if err := claims.Validate(); err != nil {
+		return nil, err
+	}
+	return claims, nil
 }
 
 // WithIdentityForTest creates a new context with the given identity
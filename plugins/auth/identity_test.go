@@ -26,7 +26,9 @@ func TestTokenRoundTrip(t *testing.T) {
 		Email:         "andor@aldhani-rebels.org",
 		EmailVerified: true,
 		Name:          "Casian Andor",
+		Groups:        NewGroups("rebels", "pilots"),
 	}
+	require.NoError(t, original.SetClaim("ship", "u-wing"))
 
 	tokenString, err := IdentityToken(ctx, original)
 	require.NoError(t, err, "failed to issue token")
@@ -37,6 +39,20 @@ func TestTokenRoundTrip(t *testing.T) {
 	assert.Equal(t, original, parsed, "Parsed and original identities do not match")
 }
 
+func TestIdentityComparable(t *testing.T) {
+	a := Identity{Subject: "1", Groups: NewGroups("rebels", "pilots")}
+	require.NoError(t, a.SetClaim("ship", "u-wing"))
+	require.NoError(t, a.SetClaim("base", "yavin"))
+
+	b := Identity{Subject: "1", Groups: NewGroups("rebels", "pilots")}
+	require.NoError(t, b.SetClaim("base", "yavin"))
+	require.NoError(t, b.SetClaim("ship", "u-wing"))
+
+	assert.True(t, a == b, "extras set in a different order should be equal")
+	assert.False(t, a == Identity{Subject: "1", Groups: NewGroups("rebels")})
+	assert.False(t, Identity{Groups: NewGroups("rebels")} == Identity{})
+}
+
 func TestTokenExpiration(t *testing.T) {
 	ctx := t.Context()
 	identity := Identity{Subject: "2", Provider: "test"}
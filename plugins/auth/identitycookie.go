@@ -3,41 +3,227 @@ package auth
 import (
 	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/metadata"
 )
 
-// Cookie name used for storing the prefab identity token.
+// Default cookie name used for storing the prefab identity token.
 const IdentityTokenCookieName = "pf-id"
 
+// Browsers reject cookies larger than 4KB, including the name and attributes.
+// Tokens longer than this are split across numbered cookies.
+const cookieChunkSize = 3800
+
+// CookieConfig controls the attributes of the identity cookie. Zero values use
+// the defaults.
+type CookieConfig struct {
+	// Cookie name, defaults to "pf-id".
+	Name string
+
+	// Domain the cookie is sent to. Empty creates a host-only cookie, set to the
+	// parent domain (e.g. "example.com") to share logins across subdomains.
+	Domain string
+
+	// Path the cookie is sent to, defaults to "/".
+	Path string
+
+	// SameSite policy, defaults to http.SameSiteLaxMode.
+	SameSite http.SameSite
+
+	// Whether the cookie is only sent over HTTPS. If nil, the cookie is secure
+	// when the server address uses https.
+	Secure *bool
+
+	// Lifetime of the cookie. Defaults to the token expiration.
+	MaxAge time.Duration
+}
+
+// WithIdentityCookie configures the attributes of the identity cookie.
+//
+// Config keys: `auth.cookie.*`.
+func WithIdentityCookie(cfg CookieConfig) AuthOption {
+	return func(p *AuthPlugin) {
+		p.cookie = cfg
+	}
+}
+
+// parseSameSite converts the `auth.cookie.sameSite` config value.
+func parseSameSite(s string) http.SameSite {
+	switch strings.ToLower(s) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	case "lax":
+		return http.SameSiteLaxMode
+	}
+	return 0
+}
+
+type cookieConfigKey struct{}
+
+func injectCookieConfig(cfg CookieConfig) prefab.ConfigInjector {
+	return func(ctx context.Context) context.Context {
+		return context.WithValue(ctx, cookieConfigKey{}, cfg)
+	}
+}
+
+// cookieConfigFromContext returns the cookie config from the context, with
+// defaults applied.
+func cookieConfigFromContext(ctx context.Context) CookieConfig {
+	cfg, _ := ctx.Value(cookieConfigKey{}).(CookieConfig)
+	if cfg.Name == "" {
+		cfg.Name = IdentityTokenCookieName
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+	return cfg
+}
+
+func (cfg CookieConfig) cookie(ctx context.Context, name, value string) *http.Cookie {
+	secure := strings.HasPrefix(serverutil.AddressFromContext(ctx), "https")
+	if cfg.Secure != nil {
+		secure = *cfg.Secure
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   cfg.Domain,
+		Path:     cfg.Path,
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: cfg.SameSite,
+	}
+}
+
 // SendIdentityCookie attaches the token to the outgoing GRPC metadata such
 // that it will be propagated as a `Set-Cookie` HTTP header by the Gateway.
+// Tokens too large for a single cookie are split across numbered cookies, in
+// which case the main cookie holds the number of chunks. Logins without
+// remember me, when it is enabled, get cookies scoped to the browser session.
 func SendIdentityCookie(ctx context.Context, token string) error {
-	address := serverutil.AddressFromContext(ctx)
-	isSecure := strings.HasPrefix(address, "https")
-	return serverutil.SendCookie(ctx, &http.Cookie{
-		Name:     IdentityTokenCookieName,
-		Value:    token,
-		Path:     "/",
-		Secure:   isSecure,
-		HttpOnly: true,
-		Expires:  time.Now().Add(expirationFromContext(ctx)),
-		SameSite: http.SameSiteLaxMode,
-	})
+	cfg := cookieConfigFromContext(ctx)
+	lifetime := expirationFromContext(ctx)
+	if cfg.MaxAge > 0 {
+		lifetime = cfg.MaxAge
+	}
+	session := isSessionLogin(ctx)
+
+	send := func(name, value string) error {
+		c := cfg.cookie(ctx, name, value)
+		if !session {
+			c.Expires = time.Now().Add(lifetime)
+			if cfg.MaxAge > 0 {
+				c.MaxAge = int(cfg.MaxAge.Seconds())
+			}
+		}
+		return serverutil.SendCookie(ctx, c)
+	}
+
+	if len(token) <= cookieChunkSize {
+		return send(cfg.Name, token)
+	}
+
+	var n int
+	for ; len(token) > 0; n++ {
+		chunk := token[:min(cookieChunkSize, len(token))]
+		token = token[len(chunk):]
+		if err := send(chunkCookieName(cfg.Name, n), chunk); err != nil {
+			return err
+		}
+	}
+	return send(cfg.Name, strconv.Itoa(n))
 }
 
-func identityFromCookie(ctx context.Context) (Identity, error) {
+// clearIdentityCookie expires the identity cookie, and any chunks sent by the
+// client.
+func clearIdentityCookie(ctx context.Context) error {
+	for _, c := range expiredIdentityCookies(ctx) {
+		if err := serverutil.SendCookie(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expiredIdentityCookies returns cookies which clear the identity cookie and
+// any chunks sent by the client.
+func expiredIdentityCookies(ctx context.Context) []*http.Cookie {
+	cfg := cookieConfigFromContext(ctx)
+	names := []string{cfg.Name}
+	for name := range serverutil.CookiesFromIncomingContext(ctx) {
+		if strings.HasPrefix(name, cfg.Name+".") {
+			names = append(names, name)
+		}
+	}
+	cookies := make([]*http.Cookie, 0, len(names))
+	for _, name := range names {
+		c := cfg.cookie(ctx, name, "[invalidated]")
+		c.Expires = time.Now().Add(-24 * time.Hour)
+		cookies = append(cookies, c)
+	}
+	return cookies
+}
+
+// identityCookieToken returns the token from the identity cookie, joining it
+// if it was split.
+func identityCookieToken(ctx context.Context) (string, bool) {
+	cfg := cookieConfigFromContext(ctx)
 	cookies := serverutil.CookiesFromIncomingContext(ctx)
-	c, ok := cookies[IdentityTokenCookieName]
+	c, ok := cookies[cfg.Name]
+	if !ok {
+		return "", false
+	}
+	n, err := strconv.Atoi(c.Value)
+	if err != nil {
+		return c.Value, true
+	}
+	var b strings.Builder
+	for i := range n {
+		chunk, ok := cookies[chunkCookieName(cfg.Name, i)]
+		if !ok {
+			return "", false
+		}
+		b.WriteString(chunk.Value)
+	}
+	return b.String(), true
+}
+
+func chunkCookieName(name string, i int) string {
+	return name + "." + strconv.Itoa(i)
+}
+
+func identityFromCookie(ctx context.Context) (Identity, error) {
+	token, ok := identityCookieToken(ctx)
 	if !ok {
 		return Identity{}, errors.Mark(ErrNotFound, 0)
 	}
-	identity, err := ParseIdentityToken(ctx, c.Value)
+	identity, err := ParseIdentityToken(ctx, token)
 	if err != nil {
 		return Identity{}, err
 	}
 	return identity, nil
 }
+
+// outgoingCredentials forwards the caller's identity token on calls made with
+// prefab.Dial, including tokens that arrived via the identity cookie.
+func outgoingCredentials(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if t, err := findToken(md); err == nil && t != "" {
+		return "Bearer " + t
+	}
+	if t, ok := identityCookieToken(ctx); ok && t != "" {
+		return "Bearer " + t
+	}
+	return ""
+}
@@ -0,0 +1,128 @@
+// Package oauthflow contains the parts of the server side OAuth authorization
+// code flow which are shared by login providers: signing the state parameter
+// and forwarding the provider's callback to the login endpoint.
+//
+// The flow mirrors the google plugin:
+//
+//  1. The client requests a login URL from `/api/auth/login`.
+//  2. The provider plugin redirects to the provider with a signed state.
+//  3. The provider redirects back to `/api/auth/{provider}/callback`.
+//  4. The callback handler forwards the code and state to `/api/auth/login`.
+//  5. The provider plugin verifies the state, exchanges the code, and logs in.
+package oauthflow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc/codes"
+)
+
+const stateExpiration = time.Minute * 5
+
+// State wraps the client's state with what the server needs to complete the
+// flow. It is signed with the client secret, so it can't be forged.
+type State struct {
+	OriginalState string    `json:"s"`
+	RequestURI    string    `json:"r"`
+	RememberMe    bool      `json:"rm,omitempty"`
+	TimeStamp     time.Time `json:"t"`
+	Signature     string    `json:"sig"`
+}
+
+func (s *State) encode() string {
+	b, _ := json.Marshal(s)
+	return base64.URLEncoding.EncodeToString(b)
+}
+
+// NewState returns a signed, encoded state for the provider's authorize URL.
+func NewState(secret, dest, clientState string, rememberMe bool) string {
+	s := &State{
+		OriginalState: clientState,
+		RequestURI:    dest,
+		RememberMe:    rememberMe,
+		TimeStamp:     time.Now(),
+	}
+	s.Signature = sign(secret, s)
+	return s.encode()
+}
+
+// ParseState decodes a state from NewState, verifying its signature and that
+// it hasn't expired.
+func ParseState(secret, raw string) (*State, error) {
+	if raw == "" {
+		return nil, errors.NewC("oauth: state parameter is empty", codes.InvalidArgument)
+	}
+	b, err := base64.URLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, errors.NewC("oauth: invalid state parameter, not base64 encoded", codes.InvalidArgument)
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, errors.NewC("oauth: invalid state parameter, json decode failed", codes.InvalidArgument)
+	}
+	if s.TimeStamp.Add(stateExpiration).Before(time.Now()) {
+		return nil, errors.NewC("oauth: state parameter has expired", codes.InvalidArgument)
+	}
+	actual, err := hex.DecodeString(s.Signature)
+	if err != nil {
+		return nil, errors.NewC("oauth: state parameter has invalid signature", codes.InvalidArgument)
+	}
+	s.Signature = ""
+	expected, _ := hex.DecodeString(sign(secret, &s))
+	if !hmac.Equal(actual, expected) {
+		return nil, errors.NewC("oauth: state parameter has invalid signature", codes.InvalidArgument)
+	}
+	return &s, nil
+}
+
+func sign(secret string, s *State) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(s.encode()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CallbackHandler returns a handler for the provider's redirect, which
+// forwards the authorization code and state to the login endpoint, where the
+// provider's login handler completes the flow.
+func CallbackHandler(provider, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		q := r.URL.Query()
+		rawState := q.Get("state")
+
+		s, err := ParseState(secret, rawState)
+		if err != nil {
+			logging.Errorw(ctx, "oauth: invalid callback state", "provider", provider, "error", err)
+			http.Error(w, provider+": invalid oauth state", http.StatusBadRequest)
+			return
+		}
+
+		// The user denied access, or the provider failed.
+		if e := q.Get("error"); e != "" {
+			logging.Infow(ctx, "oauth: provider returned an error", "provider", provider, "error", e)
+			http.Error(w, provider+": authorization failed: "+e, http.StatusForbidden)
+			return
+		}
+
+		fwd := url.Values{}
+		fwd.Add("provider", provider)
+		fwd.Add("redirect_uri", s.RequestURI)
+		fwd.Add("creds[code]", q.Get("code"))
+		fwd.Add("creds[state]", rawState)
+		if s.RememberMe {
+			fwd.Add("remember_me", "true")
+		}
+		u := url.URL{Path: "/api/auth/login", RawQuery: fwd.Encode()}
+		w.Header().Add("location", u.String())
+		w.WriteHeader(http.StatusFound)
+	}
+}
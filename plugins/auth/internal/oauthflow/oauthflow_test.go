@@ -0,0 +1,63 @@
+package oauthflow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	raw := NewState("secret", "/dashboard", "client-state", true)
+
+	s, err := ParseState("secret", raw)
+	require.NoError(t, err)
+	assert.Equal(t, "/dashboard", s.RequestURI)
+	assert.Equal(t, "client-state", s.OriginalState)
+	assert.True(t, s.RememberMe)
+
+	_, err = ParseState("other-secret", raw)
+	require.ErrorContains(t, err, "invalid signature")
+
+	_, err = ParseState("secret", "")
+	require.Error(t, err)
+	_, err = ParseState("secret", "not base64!")
+	require.Error(t, err)
+}
+
+func TestCallbackHandler(t *testing.T) {
+	h := CallbackHandler("acme", "secret")
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+
+	state := NewState("secret", "/dashboard", "", true)
+	req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/auth/acme/callback?code=abc&state="+url.QueryEscape(state), nil)
+	w := httptest.NewRecorder()
+	h(w, req)
+	require.Equal(t, http.StatusFound, w.Code)
+
+	u, err := url.Parse(w.Header().Get("location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/auth/login", u.Path)
+	q := u.Query()
+	assert.Equal(t, "acme", q.Get("provider"))
+	assert.Equal(t, "/dashboard", q.Get("redirect_uri"))
+	assert.Equal(t, "abc", q.Get("creds[code]"))
+	assert.Equal(t, state, q.Get("creds[state]"))
+	assert.Equal(t, "true", q.Get("remember_me"))
+
+	// Denied by the user.
+	req = httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/auth/acme/callback?error=access_denied&state="+url.QueryEscape(state), nil)
+	w = httptest.NewRecorder()
+	h(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Forged state.
+	req = httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/auth/acme/callback?code=abc&state="+url.QueryEscape(NewState("other", "/", "", false)), nil)
+	w = httptest.NewRecorder()
+	h(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
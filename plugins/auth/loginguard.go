@@ -0,0 +1,348 @@
+package auth
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/lock/memlock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrLoginThrottled is returned when too many failed logins have been observed
+// for an identity or IP, and logins are temporarily locked.
+var ErrLoginThrottled = errors.NewC("auth: too many failed login attempts", codes.ResourceExhausted).
+	WithUserPresentableMessage("Too many failed login attempts, please try again later")
+
+// Reasons reported in SuspiciousLoginEventData.
+const (
+	AnomalyNewCountry = "new_country"
+	AnomalyNewDevice  = "new_device"
+)
+
+// ThrottleConfig controls how failed logins are throttled.
+type ThrottleConfig struct {
+	// Number of failures allowed for a single identity within Window before it is
+	// locked out.
+	MaxFailures int
+
+	// Number of failures allowed for a single IP within Window before it is
+	// locked out. Usually higher than MaxFailures since many users may share an
+	// IP.
+	MaxFailuresPerIP int
+
+	// Failures older than Window are forgotten.
+	Window time.Duration
+
+	// Duration of the first lockout, each consecutive lockout doubles the
+	// duration up to MaxLockout.
+	Lockout    time.Duration
+	MaxLockout time.Duration
+}
+
+// StepUpHandler is called when a successful login looks anomalous, before a
+// token is issued. Returning an error aborts the login, for example after
+// sending the user a verification code. Once the user has been verified the
+// application should call RememberLogin so future logins from the same country
+// and device are trusted.
+type StepUpHandler func(ctx context.Context, identity Identity, reasons []string) error
+
+// SuspiciousLoginEventData is published with SuspiciousLoginEvent when a login
+// comes from a country or device that hasn't been seen for the identity before.
+type SuspiciousLoginEventData struct {
+	AuthEvent
+	Reasons []string
+}
+
+// LoginGuard tracks failed logins and login history, in order to throttle
+// brute-force attacks and detect anomalous logins.
+type LoginGuard struct {
+	store         storage.Store
+	locker        lock.Locker
+	throttle      *ThrottleConfig
+	detectAnomaly bool
+	stepUp        StepUpHandler
+}
+
+// NewLoginGuard returns a login guard which persists counters and history to
+// store. Throttling is disabled if throttle is nil. Counter updates are
+// serialized with an in-memory lock, the auth plugin uses the lock plugin when
+// it is registered.
+func NewLoginGuard(store storage.Store, throttle *ThrottleConfig, detectAnomaly bool, stepUp StepUpHandler) *LoginGuard {
+	return &LoginGuard{
+		store:         store,
+		locker:        memlock.New(),
+		throttle:      throttle,
+		detectAnomaly: detectAnomaly,
+		stepUp:        stepUp,
+	}
+}
+
+type loginGuardKey struct{}
+
+// WithLoginGuard adds a login guard to the context.
+func WithLoginGuard(ctx context.Context, g *LoginGuard) context.Context {
+	return context.WithValue(ctx, loginGuardKey{}, g)
+}
+
+func loginGuardFromContext(ctx context.Context) *LoginGuard {
+	g, _ := ctx.Value(loginGuardKey{}).(*LoginGuard)
+	return g
+}
+
+// CheckLogin should be called by login providers once an identity has been
+// authenticated, but before a token is issued. Registered login hooks are run
+// first, and may abort the login. If anomaly detection is enabled and the login
+// comes from a new country or device, a SuspiciousLoginEvent is published and
+// the configured StepUpHandler may abort the login.
+func CheckLogin(ctx context.Context, identity Identity) error {
+	if err := runLoginHooks(ctx, identity); err != nil {
+		return err
+	}
+
+	g := loginGuardFromContext(ctx)
+	if g == nil || !g.detectAnomaly {
+		return nil
+	}
+
+	info, _ := serverutil.RequestInfoFromContext(ctx)
+	h := &LoginHistory{Key: historyKey(identity)}
+	if err := g.store.Read(ctx, h.Key, h); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	var reasons []string
+	if info.Country != "" && len(h.Countries) > 0 && !slices.Contains(h.Countries, info.Country) {
+		reasons = append(reasons, AnomalyNewCountry)
+	}
+	if d := deviceKey(info.Device); d != "" && len(h.Devices) > 0 && !slices.Contains(h.Devices, d) {
+		reasons = append(reasons, AnomalyNewDevice)
+	}
+
+	if len(reasons) > 0 {
+		logging.Warnw(ctx, "auth: suspicious login", "subject", identity.Subject, "reasons", reasons)
+		if bus := eventbus.FromContext(ctx); bus != nil {
+			bus.Publish(SuspiciousLoginEvent, SuspiciousLoginEventData{
+				AuthEvent: NewAuthEventFromContext(ctx, identity),
+				Reasons:   reasons,
+			})
+		}
+		if g.stepUp != nil {
+			if err := g.stepUp(ctx, identity, reasons); err != nil {
+				return err
+			}
+		}
+	}
+
+	return g.remember(ctx, identity, h, info)
+}
+
+// RememberLogin records the country and device of the current request as
+// trusted for identity. Applications should call this after a step-up
+// verification succeeds.
+func RememberLogin(ctx context.Context, identity Identity) error {
+	g := loginGuardFromContext(ctx)
+	if g == nil || !g.detectAnomaly {
+		return nil
+	}
+	info, _ := serverutil.RequestInfoFromContext(ctx)
+	h := &LoginHistory{Key: historyKey(identity)}
+	if err := g.store.Read(ctx, h.Key, h); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+	return g.remember(ctx, identity, h, info)
+}
+
+func (g *LoginGuard) remember(ctx context.Context, identity Identity, h *LoginHistory, info serverutil.RequestInfo) error {
+	changed := false
+	if info.Country != "" && !slices.Contains(h.Countries, info.Country) {
+		h.Countries = append(h.Countries, info.Country)
+		changed = true
+	}
+	if d := deviceKey(info.Device); d != "" && !slices.Contains(h.Devices, d) {
+		h.Devices = append(h.Devices, d)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	h.Subject = identity.Subject
+	return g.store.Upsert(ctx, h)
+}
+
+// checkThrottle returns ErrLoginThrottled if any of the keys are locked out.
+func (g *LoginGuard) checkThrottle(ctx context.Context, keys ...string) error {
+	if g.throttle == nil {
+		return nil
+	}
+	now := timeFunc()
+	for _, key := range keys {
+		a := &LoginAttempts{Key: key}
+		if err := g.store.Read(ctx, key, a); errors.Is(err, storage.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if now.Before(a.LockedUntil) {
+			retry := int(math.Ceil(a.LockedUntil.Sub(now).Seconds()))
+			_ = serverutil.SendHeader(ctx, "retry-after", strconv.Itoa(retry))
+			logging.Track(ctx, "auth.throttled", key)
+			return ErrLoginThrottled
+		}
+	}
+	return nil
+}
+
+// recordFailure increments the failure counters for the keys, locking them out
+// once the limit is reached.
+func (g *LoginGuard) recordFailure(ctx context.Context, keys ...string) error {
+	if g.throttle == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if err := g.incrementFailures(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// incrementFailures updates a failure counter while holding its lock, so that
+// concurrent failures from other requests or replicas aren't lost.
+func (g *LoginGuard) incrementFailures(ctx context.Context, key string) error {
+	lease, err := g.locker.Lock(ctx, "auth:"+key)
+	if err != nil {
+		return errors.Wrap(err, 0).Append("auth: failed to lock login counter")
+	}
+	defer func() {
+		if err := lease.Unlock(context.WithoutCancel(ctx)); err != nil {
+			logging.Errorw(ctx, "auth: failed to unlock login counter", "error", err)
+		}
+	}()
+
+	now := timeFunc()
+	a := &LoginAttempts{Key: key}
+	if err := g.store.Read(ctx, key, a); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	// Forget failures outside the window, and the lockout history once a full
+	// window has passed since the last lockout ended.
+	if now.Sub(a.LastFailure) > g.throttle.Window {
+		a.Failures = 0
+		if now.Sub(a.LockedUntil) > g.throttle.Window {
+			a.Lockouts = 0
+		}
+	}
+
+	a.Failures++
+	a.LastFailure = now
+
+	limit := g.throttle.MaxFailures
+	if strings.HasPrefix(key, "ip:") {
+		limit = g.throttle.MaxFailuresPerIP
+	}
+	if limit > 0 && a.Failures >= limit {
+		a.LockedUntil = now.Add(g.lockoutDuration(a.Lockouts))
+		a.Lockouts++
+		a.Failures = 0
+		logging.Warnw(ctx, "auth: login locked", "key", key, "until", a.LockedUntil)
+	}
+
+	return g.store.Upsert(ctx, a)
+}
+
+// recordSuccess clears the failure counters for the keys. IP counters are left
+// in place, so that an attacker can't reset them using a known account.
+func (g *LoginGuard) recordSuccess(ctx context.Context, keys ...string) error {
+	if g.throttle == nil {
+		return nil
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, "ip:") {
+			continue
+		}
+		err := g.store.Delete(ctx, &LoginAttempts{Key: key})
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *LoginGuard) lockoutDuration(previous int) time.Duration {
+	d := g.throttle.Lockout
+	for range previous {
+		d *= 2
+		if g.throttle.MaxLockout > 0 && d >= g.throttle.MaxLockout {
+			return g.throttle.MaxLockout
+		}
+	}
+	return d
+}
+
+// throttleKeys returns the counter keys for a login request: one for the
+// identifier in the credentials, if present, and one for the client IP. The IP
+// is the connection address unless the request came through a trusted proxy,
+// so clients can't rotate it with X-Forwarded-For headers.
+func throttleKeys(ctx context.Context, in *LoginRequest) []string {
+	var keys []string
+	for _, field := range []string{"email", "username"} {
+		if v := in.Creds[field]; v != "" {
+			keys = append(keys, "login:"+in.Provider+":"+strings.ToLower(v))
+			break
+		}
+	}
+	if ip := serverutil.ClientIP(ctx); ip != "" {
+		keys = append(keys, "ip:"+ip)
+	}
+	return keys
+}
+
+func historyKey(identity Identity) string {
+	return identity.Provider + ":" + identity.Subject
+}
+
+func deviceKey(d serverutil.Device) string {
+	if d.Type == "" && d.OS == "" && d.Browser == "" {
+		return ""
+	}
+	return d.Type + "/" + d.OS + "/" + d.Browser
+}
+
+// LoginAttempts is a model for storing failed login counters.
+type LoginAttempts struct {
+	Key         string
+	Failures    int
+	LastFailure time.Time
+	Lockouts    int
+	LockedUntil time.Time
+}
+
+// Implements storage.Model.
+func (la *LoginAttempts) PK() string {
+	return la.Key
+}
+
+// LoginHistory is a model for storing the countries and devices an identity
+// has logged in from.
+type LoginHistory struct {
+	Key       string
+	Subject   string
+	Countries []string
+	Devices   []string
+}
+
+// Implements storage.Model.
+func (lh *LoginHistory) PK() string {
+	return lh.Key
+}
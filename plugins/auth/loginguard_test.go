@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func guardCtx(t *testing.T, g *LoginGuard, ip string) context.Context {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", ip))
+	return WithLoginGuard(ctx, g)
+}
+
+func TestLogin_Throttle(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	g := NewLoginGuard(memstore.New(), &ThrottleConfig{
+		MaxFailures:      3,
+		MaxFailuresPerIP: 10,
+		Window:           15 * time.Minute,
+		Lockout:          time.Minute,
+		MaxLockout:       3 * time.Minute,
+	}, false, nil)
+
+	svc := &impl{}
+	svc.AddLoginHandler("pwd", func(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+		if req.Creds["password"] != "correct" {
+			return nil, errors.NewC("invalid email or password", codes.Unauthenticated)
+		}
+		return &LoginResponse{Issued: true}, nil
+	})
+	login := func(ip, email, password string) error {
+		_, err := svc.Login(guardCtx(t, g, ip), &LoginRequest{
+			Provider: "pwd",
+			Creds:    map[string]string{"email": email, "password": password},
+		})
+		return err
+	}
+
+	for range 3 {
+		require.Equal(t, codes.Unauthenticated, errors.Code(login("10.0.0.1", "a@example.com", "wrong")))
+	}
+
+	// Locked out, even with the right password and from another IP.
+	err := login("10.0.0.2", "A@example.com", "correct")
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err))
+
+	// Other identities are unaffected.
+	require.NoError(t, login("10.0.0.1", "b@example.com", "correct"))
+
+	// Lockout expires.
+	now = now.Add(time.Minute + time.Second)
+	for range 3 {
+		require.Equal(t, codes.Unauthenticated, errors.Code(login("10.0.0.1", "a@example.com", "wrong")))
+	}
+
+	// Second lockout is twice as long.
+	now = now.Add(time.Minute + time.Second)
+	require.ErrorIs(t, login("10.0.0.1", "a@example.com", "correct"), ErrLoginThrottled)
+	now = now.Add(time.Minute)
+	require.NoError(t, login("10.0.0.1", "a@example.com", "correct"))
+}
+
+func TestLogin_ThrottlePerIP(t *testing.T) {
+	g := NewLoginGuard(memstore.New(), &ThrottleConfig{
+		MaxFailures:      100,
+		MaxFailuresPerIP: 2,
+		Window:           time.Minute,
+		Lockout:          time.Minute,
+	}, false, nil)
+
+	svc := &impl{}
+	svc.AddLoginHandler("pwd", func(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+		return nil, errors.NewC("invalid email or password", codes.Unauthenticated)
+	})
+
+	ctx := guardCtx(t, g, "10.0.0.9")
+	for _, email := range []string{"a@example.com", "b@example.com"} {
+		_, err := svc.Login(ctx, &LoginRequest{Provider: "pwd", Creds: map[string]string{"email": email}})
+		require.Equal(t, codes.Unauthenticated, errors.Code(err))
+	}
+	_, err := svc.Login(ctx, &LoginRequest{Provider: "pwd", Creds: map[string]string{"email": "c@example.com"}})
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+}
+
+func TestLogin_ThrottleConcurrentFailures(t *testing.T) {
+	store := memstore.New()
+	g := NewLoginGuard(slowStore{store}, &ThrottleConfig{
+		MaxFailures:      100,
+		MaxFailuresPerIP: 100,
+		Window:           time.Minute,
+		Lockout:          time.Minute,
+	}, false, nil)
+
+	ctx := guardCtx(t, g, "10.0.0.1")
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			assert.NoError(t, g.recordFailure(ctx, "login:pwd:a@example.com"))
+		})
+	}
+	wg.Wait()
+
+	a := &LoginAttempts{}
+	require.NoError(t, store.Read(ctx, "login:pwd:a@example.com", a))
+	assert.Equal(t, 20, a.Failures, "no failures should be lost")
+}
+
+// slowStore delays reads, so that unsynchronized read-modify-writes overlap.
+type slowStore struct {
+	storage.Store
+}
+
+func (s slowStore) Read(ctx context.Context, id string, model storage.Model) error {
+	err := s.Store.Read(ctx, id, model)
+	time.Sleep(10 * time.Millisecond)
+	return err
+}
+
+func TestThrottleKeys_SpoofedForwardedFor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-forwarded-for", "10.0.0.1"))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.2"), Port: 4000}})
+
+	// Untrusted clients are keyed by their connection address.
+	keys := throttleKeys(ctx, &LoginRequest{Provider: "pwd", Creds: map[string]string{"email": "a@example.com"}})
+	assert.Equal(t, []string{"login:pwd:a@example.com", "ip:198.51.100.2"}, keys)
+}
+
+func TestCheckLogin_Anomaly(t *testing.T) {
+	var stepUpReasons []string
+	g := NewLoginGuard(memstore.New(), nil, true, func(ctx context.Context, identity Identity, reasons []string) error {
+		stepUpReasons = reasons
+		return errors.NewC("verification required", codes.Unauthenticated)
+	})
+
+	id := Identity{Provider: "pwd", Subject: "123"}
+	desktop := serverutil.Device{Type: "desktop", OS: "macOS", Browser: "Chrome"}
+	ctxWith := func(country string, device serverutil.Device) context.Context {
+		ctx := guardCtx(t, g, "10.0.0.1")
+		return serverutil.WithRequestInfo(ctx, serverutil.RequestInfo{Country: country, Device: device})
+	}
+
+	// First login establishes the history.
+	require.NoError(t, CheckLogin(ctxWith("NZ", desktop), id))
+	require.NoError(t, CheckLogin(ctxWith("NZ", desktop), id))
+	assert.Nil(t, stepUpReasons)
+
+	// New country and device triggers step-up.
+	mobile := serverutil.Device{Type: "mobile", OS: "iOS", Browser: "Safari"}
+	err := CheckLogin(ctxWith("FR", mobile), id)
+	require.Error(t, err)
+	assert.Equal(t, []string{AnomalyNewCountry, AnomalyNewDevice}, stepUpReasons)
+
+	// Once verified, the country and device are trusted.
+	require.NoError(t, RememberLogin(ctxWith("FR", mobile), id))
+	stepUpReasons = nil
+	require.NoError(t, CheckLogin(ctxWith("FR", mobile), id))
+	assert.Nil(t, stepUpReasons)
+}
+
+func TestCheckLogin_NoGuard(t *testing.T) {
+	assert.NoError(t, CheckLogin(t.Context(), Identity{Subject: "123"}))
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+)
+
+// LoginDetails describes a login which is being checked by a LoginHook.
+type LoginDetails struct {
+	// Name of the provider which authenticated the identity.
+	Provider string
+
+	// Information about the client that is logging in, see
+	// serverutil.RequestInfoFromContext.
+	Request serverutil.RequestInfo
+}
+
+// LoginHook is called once an identity has been authenticated by a provider,
+// but before a token or cookie is issued. Returning an error aborts the login,
+// for example to deny unverified emails or banned domains. Errors without a
+// GRPC code are returned as PermissionDenied.
+type LoginHook func(ctx context.Context, identity Identity, details LoginDetails) error
+
+// WithLoginHook registers a hook that is run for every login, regardless of
+// provider. See AuthPlugin.OnLogin.
+func WithLoginHook(h LoginHook) AuthOption {
+	return func(p *AuthPlugin) {
+		p.loginHooks = append(p.loginHooks, h)
+	}
+}
+
+// OnLogin registers a hook that is run for every login, regardless of
+// provider. Hooks are run synchronously, in the order they were registered,
+// and the first error aborts the login.
+func (ap *AuthPlugin) OnLogin(h LoginHook) {
+	ap.loginHooks = append(ap.loginHooks, h)
+}
+
+type loginHooksKey struct{}
+
+// WithLoginHooks adds login hooks to the context, to be run by CheckLogin.
+func WithLoginHooks(ctx context.Context, hooks ...LoginHook) context.Context {
+	return context.WithValue(ctx, loginHooksKey{}, hooks)
+}
+
+func (ap *AuthPlugin) injectLoginHooks(ctx context.Context) context.Context {
+	if len(ap.loginHooks) == 0 {
+		return ctx
+	}
+	return WithLoginHooks(ctx, ap.loginHooks...)
+}
+
+// runLoginHooks calls the login hooks in the context, stopping at the first
+// error.
+func runLoginHooks(ctx context.Context, identity Identity) error {
+	hooks, _ := ctx.Value(loginHooksKey{}).([]LoginHook)
+	if len(hooks) == 0 {
+		return nil
+	}
+	details := LoginDetails{Provider: identity.Provider}
+	details.Request, _ = serverutil.RequestInfoFromContext(ctx)
+	for _, h := range hooks {
+		if err := h(ctx, identity, details); err != nil {
+			if errors.Code(err) == codes.Unknown {
+				return errors.Wrap(err, 0).WithCode(codes.PermissionDenied)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCheckLogin_Hooks(t *testing.T) {
+	ap := Plugin()
+
+	var calls []string
+	var got LoginDetails
+	ap.OnLogin(func(ctx context.Context, identity Identity, details LoginDetails) error {
+		calls = append(calls, "verified")
+		got = details
+		if !identity.EmailVerified {
+			return errors.NewC("email not verified", codes.FailedPrecondition)
+		}
+		return nil
+	})
+	ap.OnLogin(func(ctx context.Context, identity Identity, details LoginDetails) error {
+		calls = append(calls, "domain")
+		if strings.HasSuffix(identity.Email, "@banned.com") {
+			return errors.New("banned domain")
+		}
+		return nil
+	})
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+		"x-forwarded-for", "10.0.0.1",
+		"user-agent", "test-agent",
+	))
+	ctx = ap.injectLoginHooks(ctx)
+
+	err := CheckLogin(ctx, Identity{Provider: "google", Email: "a@example.com", EmailVerified: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"verified", "domain"}, calls)
+	assert.Equal(t, "google", got.Provider)
+	assert.Equal(t, "10.0.0.1", got.Request.IP)
+	assert.Equal(t, "test-agent", got.Request.UserAgent)
+
+	// The first error stops the chain.
+	calls = nil
+	err = CheckLogin(ctx, Identity{Provider: "google", Email: "a@example.com"})
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+	assert.Equal(t, []string{"verified"}, calls)
+
+	// Errors without a code are denials.
+	err = CheckLogin(ctx, Identity{Provider: "google", Email: "a@banned.com", EmailVerified: true})
+	assert.Equal(t, codes.PermissionDenied, errors.Code(err))
+}
+
+func TestCheckLogin_NoHooks(t *testing.T) {
+	require.NoError(t, CheckLogin(t.Context(), Identity{Provider: "google"}))
+	require.NoError(t, CheckLogin(Plugin().injectLoginHooks(t.Context()), Identity{Provider: "google"}))
+}
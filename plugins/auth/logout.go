@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// FrontChannelLogoutPath is the endpoint which other apps load in an iframe
+	// to sign the user out of this app, see WithFrontChannelLogout.
+	FrontChannelLogoutPath = "/api/auth/logout/frontchannel"
+
+	// Page which signs the user out of other apps before continuing.
+	logoutPropagatePath = "/api/auth/logout/propagate"
+
+	// How long the propagation page waits for other apps before continuing.
+	frontChannelTimeout = 5 * time.Second
+
+	// How long the link to the propagation page is valid for.
+	logoutPropagateExpiry = 5 * time.Minute
+)
+
+// LogoutHandler returns a URL which ends the user's session at their identity
+// provider before returning them to redirectURI. Used when a logout request
+// sets `end_session`, see AuthPlugin.AddLogoutHandler.
+type LogoutHandler func(ctx context.Context, identity Identity, redirectURI string) (string, error)
+
+// WithLogoutHandler registers a handler that ends sessions at a provider, see
+// AuthPlugin.AddLogoutHandler.
+func WithLogoutHandler(provider string, h LogoutHandler) AuthOption {
+	return func(p *AuthPlugin) {
+		p.AddLogoutHandler(provider, h)
+	}
+}
+
+// AddLogoutHandler can be called by other plugins to register a handler that
+// ends sessions at their provider.
+func (ap *AuthPlugin) AddLogoutHandler(provider string, h LogoutHandler) {
+	if ap.authService.logoutHandlers == nil {
+		ap.authService.logoutHandlers = map[string]LogoutHandler{}
+	}
+	ap.authService.logoutHandlers[provider] = h
+}
+
+// EndSessionLogout returns a LogoutHandler for OpenID Connect providers which
+// support RP-initiated logout. endpoint is the `end_session_endpoint` from the
+// provider's discovery document, and the redirect URI must be registered with
+// the provider as a post logout redirect URI.
+//
+// Example:
+//
+//	authPlugin.AddLogoutHandler("keycloak", auth.EndSessionLogout(
+//		"https://sso.example.com/realms/acme/protocol/openid-connect/logout", clientID))
+func EndSessionLogout(endpoint, clientID string) LogoutHandler {
+	return func(ctx context.Context, identity Identity, redirectURI string) (string, error) {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return "", errors.Wrap(err, 0).WithCode(codes.Internal)
+		}
+		if strings.HasPrefix(redirectURI, "/") {
+			redirectURI = serverutil.AddressFromContext(ctx) + redirectURI
+		}
+		q := u.Query()
+		q.Set("client_id", clientID)
+		q.Set("post_logout_redirect_uri", redirectURI)
+		u.RawQuery = q.Encode()
+		return u.String(), nil
+	}
+}
+
+// WithLogoutRedirectOrigins allows logout to redirect to the given origins,
+// such as "https://www.example.com", in addition to paths and URLs on this
+// server and the origins of front-channel logout URIs. Logout requests with
+// other redirect URIs are rejected.
+//
+// Config key: `auth.logout.redirectOrigins`.
+func WithLogoutRedirectOrigins(origins ...string) AuthOption {
+	return func(p *AuthPlugin) {
+		p.redirectOrigins = append(p.redirectOrigins, origins...)
+	}
+}
+
+// WithFrontChannelLogout signs users out of other apps which share their
+// login, such as apps using the same SSO provider. After logout, the user is
+// shown a page which loads each URI, the front-channel logout endpoints of the
+// other apps, in a hidden iframe before continuing to the redirect URI.
+//
+// This app's own endpoint, FrontChannelLogoutPath, is enabled for the other
+// apps to load, and may be framed by the origins of uris. As in OpenID Connect
+// Front-Channel Logout, the endpoint requires `iss` and `sid` query parameters
+// and only signs out the session with that ID, when issued by this app or one
+// of the other apps. Cookies are only sent to iframes from the same site, so
+// apps on different sites need `auth.cookie.sameSite` set to `none`.
+//
+// Config key: `auth.logout.frontChannelUris`.
+func WithFrontChannelLogout(uris ...string) AuthOption {
+	return func(p *AuthPlugin) {
+		p.frontChannelURIs = append(p.frontChannelURIs, uris...)
+	}
+}
+
+func (ap *AuthPlugin) initLogout() error {
+	for _, uri := range ap.frontChannelURIs {
+		u, err := url.Parse(uri)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.Codef(codes.InvalidArgument, "auth: invalid front-channel logout uri %q", uri)
+		}
+		ap.authService.redirectOrigins = append(ap.authService.redirectOrigins, origin(u))
+	}
+	for _, o := range ap.redirectOrigins {
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return errors.Codef(codes.InvalidArgument, "auth: invalid logout redirect origin %q", o)
+		}
+		ap.authService.redirectOrigins = append(ap.authService.redirectOrigins, origin(u))
+	}
+	ap.authService.frontChannelURIs = ap.frontChannelURIs
+	return nil
+}
+
+// origin returns the scheme and host of a URL, e.g. "https://example.com".
+func origin(u *url.URL) string {
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// checkLogoutRedirect returns an error unless uri is a path on this server, or
+// a URL with this server's origin or one of the allowed redirect origins.
+func (s *impl) checkLogoutRedirect(ctx context.Context, uri string) error {
+	u, err := url.Parse(uri)
+	switch {
+	case err != nil || strings.ContainsAny(uri, "\\\r\n\t"):
+	case u.Scheme == "" && u.Host == "":
+		// Network-path references, "//example.com", have a host.
+		if strings.HasPrefix(u.Path, "/") {
+			return nil
+		}
+	case u.Scheme == "https" || u.Scheme == "http":
+		if a, err := url.Parse(serverutil.AddressFromContext(ctx)); err == nil && origin(a) == origin(u) {
+			return nil
+		}
+		if slices.Contains(s.redirectOrigins, origin(u)) {
+			return nil
+		}
+	}
+	return errors.Codef(codes.InvalidArgument, "auth: logout redirect uri %q is not allowed", uri)
+}
+
+func (ap *AuthPlugin) frontChannelOptions() []prefab.ServerOption {
+	if len(ap.frontChannelURIs) == 0 {
+		return nil
+	}
+	return []prefab.ServerOption{
+		prefab.WithHTTPHandlerFunc(FrontChannelLogoutPath, ap.handleFrontChannelLogout),
+		prefab.WithHTTPHandler(logoutPropagatePath, RequireSignedURL(http.HandlerFunc(ap.handleLogoutPropagate))),
+	}
+}
+
+// endSession returns the provider's URL for ending the identity's session, or
+// redirectURI if the provider doesn't support it.
+func (s *impl) endSession(ctx context.Context, identity Identity, redirectURI string) string {
+	// The admin didn't log in to the assumed identity's provider.
+	if identity.Delegation != nil {
+		return redirectURI
+	}
+	h, ok := s.logoutHandlers[identity.Provider]
+	if !ok {
+		return redirectURI
+	}
+	u, err := h(ctx, identity, redirectURI)
+	if err != nil {
+		// The local session has already ended, so the logout continues.
+		logging.Errorw(ctx, "auth: failed to end provider session", "provider", identity.Provider, "error", err)
+		return redirectURI
+	}
+	return u
+}
+
+// propagateLogout returns a signed link to the page which signs the user out
+// of other apps, before continuing to next.
+func (s *impl) propagateLogout(ctx context.Context, identity Identity, next string) (string, error) {
+	if len(s.frontChannelURIs) == 0 {
+		return next, nil
+	}
+	link, err := serverutil.SignURL(ctx, logoutPropagatePath, logoutPropagateExpiry, map[string]string{
+		"next": next,
+		"sid":  identity.SessionID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return serverutil.AddressFromContext(ctx) + link, nil
+}
+
+var logoutPropagateTemplate = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Timeout}};url={{.Next}}">
+<title>Signing out</title>
+<script>
+var pending = {{len .URIs}};
+function loaded() {
+  if (--pending === 0) location.replace({{.Next}});
+}
+</script>
+</head>
+<body>
+<p>Signing out&hellip;</p>
+{{range .URIs}}<iframe src="{{.}}" onload="loaded()" hidden></iframe>
+{{end}}</body>
+</html>
+`))
+
+// handleLogoutPropagate renders a page which loads the other apps' front-channel
+// logout endpoints, with the issuer and ID of the ended session, continuing
+// once they have loaded or after a timeout.
+func (ap *AuthPlugin) handleLogoutPropagate(w http.ResponseWriter, r *http.Request) {
+	s, _ := serverutil.SignedURLFromContext(r.Context())
+	next, err := url.Parse(s.Claims["next"])
+	if err != nil || (next.Scheme != "" && next.Scheme != "https" && next.Scheme != "http") {
+		http.Error(w, "invalid redirect", http.StatusBadRequest)
+		return
+	}
+	uris := make([]string, 0, len(ap.frontChannelURIs))
+	for _, uri := range ap.frontChannelURIs {
+		u, _ := url.Parse(uri) // Checked in initLogout.
+		q := u.Query()
+		q.Set("iss", serverutil.AddressFromContext(r.Context()))
+		q.Set("sid", s.Claims["sid"])
+		u.RawQuery = q.Encode()
+		uris = append(uris, u.String())
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	err = logoutPropagateTemplate.Execute(w, map[string]any{
+		"Next":    next.String(),
+		"URIs":    uris,
+		"Timeout": int(frontChannelTimeout.Seconds()),
+	})
+	if err != nil {
+		logging.Errorw(r.Context(), "auth: failed to render logout page", "error", err)
+	}
+}
+
+// handleFrontChannelLogout signs the user out when loaded by another app
+// during its logout. Per OpenID Connect Front-Channel Logout, the request names
+// the session with `iss` and `sid`, and other sessions are left alone.
+func (ap *AuthPlugin) handleFrontChannelLogout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ancestors := []string{"'self'"}
+	for _, uri := range ap.frontChannelURIs {
+		if u, err := url.Parse(uri); err == nil {
+			ancestors = append(ancestors, origin(u))
+		}
+	}
+	w.Header().Del("X-Frame-Options")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors "+strings.Join(ancestors, " "))
+	w.Header().Set("Cache-Control", "no-store")
+
+	iss, sid := r.URL.Query().Get("iss"), r.URL.Query().Get("sid")
+	if iss == "" || sid == "" {
+		http.Error(w, "missing iss or sid", http.StatusBadRequest)
+		return
+	}
+	if id, err := identityFromCookie(ctx); err == nil && id.SessionID == sid && ap.frontChannelIssuer(ctx, iss) {
+		if err := MaybeBlock(ctx, id.SessionID); err != nil {
+			logging.Errorw(ctx, "auth: failed to block token for front-channel logout", "error", err)
+		}
+		if bus := eventbus.FromContext(ctx); bus != nil {
+			bus.Publish(LogoutEvent, NewAuthEventFromContext(ctx, id))
+		}
+		for _, c := range expiredIdentityCookies(ctx) {
+			http.SetCookie(w, c)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte("<!DOCTYPE html><title>Signed out</title>"))
+}
+
+// frontChannelIssuer returns whether iss is this app, the issuer of its
+// sessions, or one of the other apps sharing the login.
+func (ap *AuthPlugin) frontChannelIssuer(ctx context.Context, iss string) bool {
+	u, err := url.Parse(iss)
+	if err != nil {
+		return false
+	}
+	if a, err := url.Parse(serverutil.AddressFromContext(ctx)); err == nil && origin(a) == origin(u) {
+		return true
+	}
+	for _, uri := range ap.frontChannelURIs {
+		if f, err := url.Parse(uri); err == nil && origin(f) == origin(u) {
+			return true
+		}
+	}
+	return false
+}
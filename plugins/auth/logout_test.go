@@ -0,0 +1,225 @@
+package auth_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const otherApp = "https://b.example.com" + auth.FrontChannelLogoutPath
+
+var alice = auth.Identity{Provider: "oidc", Subject: "alice", SessionID: "session-1"}
+
+func noRedirects(s *prefabtest.Server) *http.Client {
+	c := *s.HTTPClient()
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &c
+}
+
+func logout(t *testing.T, s *prefabtest.Server, body string, identity auth.Identity) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, s.URL("/api/auth/logout"), strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CSRF-Protection", "1")
+	req.AddCookie(&http.Cookie{Name: auth.IdentityTokenCookieName, Value: s.Token(identity)})
+	resp, err := noRedirects(s).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp
+}
+
+func get(t *testing.T, s *prefabtest.Server, u string, identity auth.Identity) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, u, nil)
+	require.NoError(t, err)
+	if identity != (auth.Identity{}) {
+		req.AddCookie(&http.Cookie{Name: auth.IdentityTokenCookieName, Value: s.Token(identity)})
+	}
+	resp, err := noRedirects(s).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(b)
+}
+
+func TestLogout_EndSession(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth(
+		auth.WithLogoutHandler("oidc", auth.EndSessionLogout("https://sso.example.com/logout", "client-1")),
+		auth.WithLogoutRedirectOrigins("https://a.example.com"),
+	))
+
+	resp := logout(t, s, `{"redirect_uri": "https://a.example.com/bye", "end_session": true}`, alice)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "sso.example.com", loc.Host)
+	assert.Equal(t, "client-1", loc.Query().Get("client_id"))
+	assert.Equal(t, "https://a.example.com/bye", loc.Query().Get("post_logout_redirect_uri"))
+	s.Events().AssertPublished(t, auth.LogoutEvent)
+
+	// Without end_session, only the local session ends. Sessions are blocked on
+	// logout, so each needs a new one.
+	bob := auth.Identity{Provider: "oidc", Subject: "bob", SessionID: "session-2"}
+	resp = logout(t, s, `{"redirect_uri": "https://a.example.com/bye"}`, bob)
+	assert.Equal(t, "https://a.example.com/bye", resp.Header.Get("Location"))
+
+	// Admins assuming an identity didn't log in to its provider.
+	delegated := alice
+	delegated.SessionID = "session-3"
+	delegated.Delegation = &auth.DelegationInfo{
+		DelegatorSub:       "admin",
+		DelegatorProvider:  "google",
+		DelegatorSessionId: "admin-session",
+		Reason:             "support",
+		DelegatedAt:        time.Now().Unix(),
+	}
+	resp = logout(t, s, `{"redirect_uri": "https://a.example.com/bye", "end_session": true}`, delegated)
+	assert.Equal(t, "https://a.example.com/bye", resp.Header.Get("Location"))
+}
+
+func TestLogout_RedirectURI(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth(
+		auth.WithLogoutRedirectOrigins("https://a.example.com"),
+		auth.WithFrontChannelLogout(otherApp),
+	))
+
+	allowed := []string{
+		"/bye",
+		"http://localhost:8000/bye", // The server address, from prefab.yaml.
+		"https://a.example.com/bye",
+	}
+	for i, uri := range allowed {
+		// Front-channel logout sends allowed URIs via the propagation page.
+		id := auth.Identity{Provider: "oidc", Subject: "alice", SessionID: fmt.Sprintf("allowed-%d", i)}
+		resp := logout(t, s, `{"redirect_uri": "`+uri+`"}`, id)
+		require.Equal(t, http.StatusFound, resp.StatusCode, uri)
+		loc, err := url.Parse(resp.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/api/auth/logout/propagate", loc.Path, uri)
+	}
+
+	// The origin of a front-channel logout URI is allowed.
+	resp := logout(t, s, `{"redirect_uri": "https://b.example.com/bye"}`, auth.Identity{Provider: "oidc", Subject: "alice", SessionID: "peer"})
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	rejected := []string{
+		"https://evil.example.com/",
+		"//evil.example.com/",
+		"/\\\\evil.example.com/",
+		"javascript:alert(1)",
+		"bye",
+	}
+	for i, uri := range rejected {
+		id := auth.Identity{Provider: "oidc", Subject: "alice", SessionID: fmt.Sprintf("rejected-%d", i)}
+		resp := logout(t, s, `{"redirect_uri": "`+uri+`"}`, id)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, uri)
+
+		// The session is left alone.
+		resp, _ = get(t, s, s.URL("/api/auth/me"), id)
+		assert.Equal(t, http.StatusOK, resp.StatusCode, uri)
+	}
+}
+
+func TestLogout_BearerToken(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth())
+	token := s.Token(alice)
+
+	do := func(method, path string) int {
+		req, err := http.NewRequestWithContext(t.Context(), method, s.URL(path), strings.NewReader("{}"))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-CSRF-Protection", "1")
+		resp, err := noRedirects(s).Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/auth/me"))
+	assert.Equal(t, http.StatusFound, do(http.MethodPost, "/api/auth/logout"))
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/auth/me"))
+}
+
+func TestLogout_FrontChannel(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth(
+		auth.WithFrontChannelLogout(otherApp),
+		auth.WithLogoutRedirectOrigins("https://a.example.com"),
+	))
+
+	resp := logout(t, s, `{"redirect_uri": "https://a.example.com/bye"}`, alice)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "/api/auth/logout/propagate", loc.Path)
+
+	resp, body := get(t, s, s.URL(loc.RequestURI()), auth.Identity{})
+	require.Equal(t, http.StatusOK, resp.StatusCode, body)
+	iss := url.QueryEscape("http://localhost:8000")
+	assert.Contains(t, body, `<iframe src="`+otherApp+`?iss=`+iss+`&amp;sid=session-1"`)
+	assert.Contains(t, body, "url=https://a.example.com/bye")
+
+	// The page can only be reached from a logout.
+	q := loc.Query()
+	q.Set("pf-sig", q.Get("pf-sig")+"x")
+	resp, _ = get(t, s, s.URL(loc.Path+"?"+q.Encode()), auth.Identity{})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestFrontChannelLogoutEndpoint(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth(auth.WithFrontChannelLogout(otherApp)))
+
+	endpoint := func(iss, sid string) string {
+		return s.URL(auth.FrontChannelLogoutPath) + "?" + url.Values{"iss": {iss}, "sid": {sid}}.Encode()
+	}
+	cleared := func(resp *http.Response) bool {
+		for _, c := range resp.Cookies() {
+			if c.Name == auth.IdentityTokenCookieName {
+				return c.MaxAge < 0 || c.Value == "[invalidated]"
+			}
+		}
+		return false
+	}
+
+	// Both parameters are required.
+	resp, _ := get(t, s, s.URL(auth.FrontChannelLogoutPath)+"?sid=session-1", alice)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = get(t, s, s.URL(auth.FrontChannelLogoutPath)+"?iss=https://b.example.com", alice)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// Other sessions, and unknown issuers, are left alone.
+	resp, _ = get(t, s, endpoint("https://b.example.com", "session-2"), alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, cleared(resp))
+	resp, _ = get(t, s, endpoint("https://evil.example.com", "session-1"), alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.False(t, cleared(resp))
+	s.Events().AssertNotPublished(t, auth.LogoutEvent)
+
+	resp, _ = get(t, s, endpoint("https://b.example.com", "session-1"), alice)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "frame-ancestors 'self' https://b.example.com", resp.Header.Get("Content-Security-Policy"))
+	assert.Empty(t, resp.Header.Get("X-Frame-Options"))
+	assert.True(t, cleared(resp), "identity cookie should be cleared")
+	s.Events().AssertPublished(t, auth.LogoutEvent)
+
+	// The session is blocked.
+	resp, _ = get(t, s, s.URL("/api/auth/me"), alice)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// The endpoint is only enabled for front-channel logout.
+	s = prefabtest.New(t, prefabtest.WithAuth())
+	resp, _ = get(t, s, endpoint("https://b.example.com", "session-1"), alice)
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+}
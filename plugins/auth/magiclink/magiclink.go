@@ -140,6 +140,12 @@ func (p *MagicLinkPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 
 	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
 	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Email link",
+		Icon:        "email",
+		Credentials: []string{"email"},
+	})
 	return nil
 }
 
@@ -148,7 +154,7 @@ func (p *MagicLinkPlugin) handleLogin(ctx context.Context, req *auth.LoginReques
 		return nil, errors.NewC("magiclink login handler called for wrong provider", codes.InvalidArgument)
 	}
 	if req.Creds["email"] != "" {
-		return p.handleEmail(ctx, req.Creds["email"], req.RedirectUri)
+		return p.handleEmail(ctx, req.Creds["email"], req.RedirectUri, req.RememberMe)
 	}
 	if req.Creds["token"] != "" {
 		return p.handleToken(ctx, req.Creds["token"], req.IssueToken, req.RedirectUri)
@@ -156,7 +162,7 @@ func (p *MagicLinkPlugin) handleLogin(ctx context.Context, req *auth.LoginReques
 	return nil, errors.NewC("missing credentials, magiclink login requires an `email` or `token`", codes.InvalidArgument)
 }
 
-func (p *MagicLinkPlugin) handleEmail(ctx context.Context, email string, redirectUri string) (*auth.LoginResponse, error) {
+func (p *MagicLinkPlugin) handleEmail(ctx context.Context, email string, redirectUri string, rememberMe bool) (*auth.LoginResponse, error) {
 	token, err := p.generateToken(email)
 	if err != nil {
 		return nil, err
@@ -171,6 +177,9 @@ func (p *MagicLinkPlugin) handleEmail(ctx context.Context, email string, redirec
 	default:
 		address := serverutil.AddressFromContext(ctx)
 		url = address + "/api/auth/login?provider=magiclink&creds[token]=" + token
+		if rememberMe {
+			url += "&remember_me=true"
+		}
 	}
 
 	subject, err := p.renderer.Render(ctx, "auth_magiclink_subject", nil)
@@ -204,13 +213,17 @@ func (p *MagicLinkPlugin) handleToken(ctx context.Context, token string, issueTo
 		return nil, err
 	}
 
+	if err := auth.CheckLogin(ctx, identity); err != nil {
+		return nil, err
+	}
+
 	idt, err := auth.IdentityToken(ctx, identity)
 	if err != nil {
 		return nil, err
 	}
 
 	if bus := eventbus.FromContext(ctx); bus != nil {
-		bus.Publish(auth.LoginEvent, auth.NewAuthEvent(identity))
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, identity))
 	}
 
 	if issueToken {
@@ -0,0 +1,185 @@
+// Package mtls provides an authentication plugin that identifies peer services
+// by their client certificate, for use with prefab.WithClientCertificates.
+//
+// Certificates carrying a SPIFFE ID (a `spiffe://` URI SAN) are identified by
+// that ID, otherwise the first DNS SAN or the common name is used. The
+// resulting identity has provider "mtls", and a group for each organizational
+// unit in the certificate's subject, see OrganizationalUnitGroup:
+//
+//	s := prefab.New(
+//	    prefab.WithTLS(certFile, keyFile),
+//	    prefab.WithClientCertificates(caFile, false),
+//	    prefab.WithPlugin(auth.Plugin()),
+//	    prefab.WithPlugin(mtls.Plugin(mtls.WithTrustDomains("prod.example.com"))),
+//	)
+//
+// Only native GRPC requests are authenticated this way. Requests arriving via
+// the GRPC Gateway are proxied over a loopback connection, so the peer
+// certificate does not belong to the caller and is ignored.
+//
+// A bearer token on the request takes precedence, so a service calling on
+// behalf of a user is identified as the user. Use PeerIdentity to retrieve the
+// calling service in that case.
+package mtls
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"net/url"
+	"slices"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "auth_mtls"
+
+	// ProviderName is the auth provider of identities derived from client
+	// certificates, which are service identities, see auth.Identity.IsService.
+	ProviderName = auth.ServiceProvider
+)
+
+// ErrUntrustedDomain is returned when a certificate's SPIFFE ID is not in one
+// of the configured trust domains.
+var ErrUntrustedDomain = errors.NewC("mtls: spiffe id is not in a trusted domain", codes.Unauthenticated)
+
+// OrganizationalUnitGroup returns the group that services are given for each
+// organizational unit (OU) in their certificate's subject, for use in an
+// authz.GroupMapping.
+func OrganizationalUnitGroup(ou string) string {
+	return "mtls:ou:" + ou
+}
+
+// IdentityMapper converts a verified client certificate into an identity.
+type IdentityMapper func(ctx context.Context, cert *x509.Certificate) (auth.Identity, error)
+
+// MTLSOption allows configuration of the MTLSPlugin.
+type MTLSOption func(*MTLSPlugin)
+
+// WithTrustDomains restricts SPIFFE IDs to the given trust domains, e.g.
+// "prod.example.com". By default any SPIFFE ID signed by the client CA is
+// accepted.
+func WithTrustDomains(domains ...string) MTLSOption {
+	return func(p *MTLSPlugin) {
+		p.trustDomains = append(p.trustDomains, domains...)
+	}
+}
+
+// WithIdentityMapper replaces the default mapping from certificate to identity.
+func WithIdentityMapper(m IdentityMapper) MTLSOption {
+	return func(p *MTLSPlugin) {
+		p.mapper = m
+	}
+}
+
+// Plugin for authenticating peer services by client certificate.
+func Plugin(opts ...MTLSOption) *MTLSPlugin {
+	p := &MTLSPlugin{}
+	p.mapper = p.defaultMapper
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// MTLSPlugin maps verified client certificates to identities.
+type MTLSPlugin struct {
+	trustDomains []string
+	mapper       IdentityMapper
+}
+
+// From prefab.Plugin.
+func (p *MTLSPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *MTLSPlugin) Deps() []string {
+	return []string{auth.PluginName}
+}
+
+// From prefab.InitializablePlugin.
+func (p *MTLSPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
+	ap.AddIdentityExtractor(p.PeerIdentity)
+	return nil
+}
+
+// PeerIdentity returns the identity of the peer service that made the request,
+// regardless of any other credentials on the request. Returns auth.ErrNotFound
+// if the peer did not present a verified certificate.
+func (p *MTLSPlugin) PeerIdentity(ctx context.Context) (auth.Identity, error) {
+	cert := PeerCertificate(ctx)
+	if cert == nil {
+		return auth.Identity{}, errors.Mark(auth.ErrNotFound, 0)
+	}
+	return p.mapper(ctx, cert)
+}
+
+// PeerCertificate returns the verified client certificate of a native GRPC
+// request, or nil.
+func PeerCertificate(ctx context.Context) *x509.Certificate {
+	if serverutil.HTTPMethod(ctx) != "" {
+		// Gateway request, the peer is the gateway itself.
+		return nil
+	}
+	pr, ok := peer.FromContext(ctx)
+	if !ok || pr.AuthInfo == nil {
+		return nil
+	}
+	info, ok := pr.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+// SPIFFEID returns the SPIFFE ID of a certificate, or nil.
+func SPIFFEID(cert *x509.Certificate) *url.URL {
+	for _, u := range cert.URIs {
+		if u.Scheme == "spiffe" {
+			return u
+		}
+	}
+	return nil
+}
+
+func (p *MTLSPlugin) defaultMapper(_ context.Context, cert *x509.Certificate) (auth.Identity, error) {
+	id := auth.Identity{
+		Provider:  ProviderName,
+		SessionID: hex.EncodeToString(cert.SerialNumber.Bytes()),
+		AuthTime:  cert.NotBefore,
+		Name:      cert.Subject.CommonName,
+	}
+	switch {
+	case SPIFFEID(cert) != nil:
+		spiffe := SPIFFEID(cert)
+		if len(p.trustDomains) > 0 && !slices.Contains(p.trustDomains, spiffe.Host) {
+			return auth.Identity{}, ErrUntrustedDomain
+		}
+		id.Subject = spiffe.String()
+	case len(p.trustDomains) > 0:
+		return auth.Identity{}, ErrUntrustedDomain
+	case len(cert.DNSNames) > 0:
+		id.Subject = cert.DNSNames[0]
+	default:
+		id.Subject = cert.Subject.CommonName
+	}
+	if id.Subject == "" {
+		return auth.Identity{}, errors.NewC("mtls: certificate has no usable subject", codes.Unauthenticated)
+	}
+	groups := make([]string, 0, len(cert.Subject.OrganizationalUnit))
+	for _, ou := range cert.Subject.OrganizationalUnit {
+		groups = append(groups, OrganizationalUnitGroup(ou))
+	}
+	id.Groups = auth.NewGroups(groups...)
+	return id, nil
+}
@@ -0,0 +1,129 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func newCert(t *testing.T, cn string, dnsNames []string, uris ...string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	for _, u := range uris {
+		parsed, err := url.Parse(u)
+		require.NoError(t, err)
+		tmpl.URIs = append(tmpl.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func peerCtx(ctx context.Context, cert *x509.Certificate) context.Context {
+	return peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{
+			VerifiedChains: [][]*x509.Certificate{{cert}},
+		}},
+	})
+}
+
+func TestPeerIdentity(t *testing.T) {
+	p := Plugin()
+
+	t.Run("SPIFFE", func(t *testing.T) {
+		cert := newCert(t, "billing", []string{"billing.internal"}, "spiffe://prod.example.com/billing")
+		id, err := p.PeerIdentity(peerCtx(t.Context(), cert))
+		require.NoError(t, err)
+		assert.Equal(t, ProviderName, id.Provider)
+		assert.Equal(t, "spiffe://prod.example.com/billing", id.Subject)
+		assert.Equal(t, "billing", id.Name)
+		assert.NotEmpty(t, id.SessionID)
+	})
+
+	t.Run("DNSName", func(t *testing.T) {
+		cert := newCert(t, "billing", []string{"billing.internal"})
+		id, err := p.PeerIdentity(peerCtx(t.Context(), cert))
+		require.NoError(t, err)
+		assert.Equal(t, "billing.internal", id.Subject)
+	})
+
+	t.Run("CommonName", func(t *testing.T) {
+		cert := newCert(t, "billing", nil)
+		id, err := p.PeerIdentity(peerCtx(t.Context(), cert))
+		require.NoError(t, err)
+		assert.Equal(t, "billing", id.Subject)
+	})
+
+	t.Run("OrganizationalUnits", func(t *testing.T) {
+		cert := newCert(t, "billing", nil)
+		cert.Subject.OrganizationalUnit = []string{"payments", "platform"}
+		id, err := p.PeerIdentity(peerCtx(t.Context(), cert))
+		require.NoError(t, err)
+		assert.Equal(t, auth.NewGroups("mtls:ou:payments", "mtls:ou:platform"), id.Groups)
+	})
+
+	t.Run("NoCertificate", func(t *testing.T) {
+		_, err := p.PeerIdentity(t.Context())
+		assert.True(t, errors.Is(err, auth.ErrNotFound))
+	})
+
+	t.Run("GatewayRequest", func(t *testing.T) {
+		cert := newCert(t, "server", nil)
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("pf-http-method", "GET"))
+		_, err := p.PeerIdentity(peerCtx(ctx, cert))
+		assert.True(t, errors.Is(err, auth.ErrNotFound))
+	})
+}
+
+func TestTrustDomains(t *testing.T) {
+	p := Plugin(WithTrustDomains("prod.example.com"))
+
+	cert := newCert(t, "billing", nil, "spiffe://prod.example.com/billing")
+	_, err := p.PeerIdentity(peerCtx(t.Context(), cert))
+	require.NoError(t, err)
+
+	cert = newCert(t, "billing", nil, "spiffe://staging.example.com/billing")
+	_, err = p.PeerIdentity(peerCtx(t.Context(), cert))
+	assert.True(t, errors.Is(err, ErrUntrustedDomain))
+
+	cert = newCert(t, "billing", []string{"billing.internal"})
+	_, err = p.PeerIdentity(peerCtx(t.Context(), cert))
+	assert.True(t, errors.Is(err, ErrUntrustedDomain))
+}
+
+func TestIdentityMapper(t *testing.T) {
+	p := Plugin(WithIdentityMapper(func(_ context.Context, cert *x509.Certificate) (auth.Identity, error) {
+		return auth.Identity{Provider: ProviderName, Subject: "svc:" + cert.Subject.CommonName}, nil
+	}))
+	id, err := p.PeerIdentity(peerCtx(t.Context(), newCert(t, "billing", nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "svc:billing", id.Subject)
+}
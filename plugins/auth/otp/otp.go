@@ -0,0 +1,512 @@
+// Package otp provides passwordless authentication with one-time codes sent to
+// a phone number, by SMS or WhatsApp.
+//
+// ### Basic Flow
+//
+//  1. An initial request to the login endpoint is made with the user's phone
+//     number in the creds map, `{"provider": "otp", "creds": {"phone": "+14155550123"}}`.
+//  2. A code is generated, stored via the storage plugin, and sent to the
+//     phone using the configured Sender.
+//  3. A second request is made with the phone number and the code the user
+//     entered, `{"provider": "otp", "creds": {"phone": "+14155550123", "code": "123456"}}`.
+//  4. If the code matches, an identity token is issued, or set as a cookie.
+//
+// Identities use the "otp" provider and the phone number, in E.164 format, as
+// the subject. Phone numbers may be given with spaces, dashes, and parentheses,
+// but must include the country code.
+//
+// Sending codes is rate limited per phone number, with a minimum interval
+// between codes and a maximum number of codes per window, and each code can
+// only be guessed a limited number of times.
+//
+// Messages are sent via Twilio when `auth.otp.twilio.*` is configured, or by
+// a Sender set with WithSender.
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/lock/memlock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// Constant name for the OTP auth plugin.
+	PluginName = "auth_otp"
+
+	// Constant name used as the auth provider in API requests, and for
+	// identities authenticated with a phone number.
+	ProviderName = "otp"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.codeLength",
+			Description: "Number of digits in one-time codes",
+			Type:        "int",
+			Default:     "6",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.expiration",
+			Description: "How long one-time codes are valid for",
+			Type:        "duration",
+			Default:     "10m",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.maxAttempts",
+			Description: "How many times a code can be guessed before a new one must be requested",
+			Type:        "int",
+			Default:     "5",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.resendInterval",
+			Description: "Minimum time between codes sent to a phone number",
+			Type:        "duration",
+			Default:     "30s",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.maxSends",
+			Description: "Maximum codes sent to a phone number per send window",
+			Type:        "int",
+			Default:     "5",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.sendWindow",
+			Description: "Window over which auth.otp.maxSends applies",
+			Type:        "duration",
+			Default:     "1h",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.twilio.accountSid",
+			Description: "Twilio account SID, for sending codes via Twilio",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.twilio.authToken",
+			Description: "Twilio auth token",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.otp.twilio.from",
+			Description: "Twilio number or messaging service SID codes are sent from, prefix with whatsapp: for WhatsApp",
+			Type:        "string",
+		},
+	)
+}
+
+var (
+	// Returned when a phone number isn't in E.164 format.
+	ErrInvalidPhone = errors.NewC("otp: invalid phone number, include the country code, e.g. +14155550123", codes.InvalidArgument)
+
+	// Returned when codes are requested too often for a phone number.
+	ErrRateLimited = errors.NewC("otp: too many codes requested, try again later", codes.ResourceExhausted)
+
+	// Returned when a code doesn't match, or has expired.
+	ErrInvalidCode = errors.NewC("otp: invalid or expired code", codes.InvalidArgument)
+
+	// Returned when a code has been guessed too many times. A new code must be
+	// requested.
+	ErrTooManyAttempts = errors.NewC("otp: too many attempts, request a new code", codes.ResourceExhausted)
+)
+
+// challenge is the stored state for a phone number: the current code, and
+// counters for rate limiting.
+type challenge struct {
+	ID string `json:"id"` // The phone number.
+
+	// HMAC of the code, keyed with Salt. Empty once the code has been used.
+	CodeHash  string    `json:"codeHash"`
+	Salt      string    `json:"salt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Attempts  int       `json:"attempts"`
+
+	Sends       int       `json:"sends"`
+	WindowStart time.Time `json:"windowStart"`
+	LastSentAt  time.Time `json:"lastSentAt"`
+}
+
+// PK implements storage.Model.
+func (c challenge) PK() string {
+	return c.ID
+}
+
+// Name implements storage.Namer.
+func (c challenge) Name() string {
+	return "otp_challenges"
+}
+
+// OTPOption allows configuration of the OTPPlugin.
+type OTPOption func(*OTPPlugin)
+
+// WithSender sets how codes are delivered.
+func WithSender(s Sender) OTPOption {
+	return func(p *OTPPlugin) {
+		p.sender = s
+	}
+}
+
+// WithMessage sets the message sent to users, given the code and how long it
+// is valid for.
+func WithMessage(fn func(code string, expiration time.Duration) string) OTPOption {
+	return func(p *OTPPlugin) {
+		p.message = fn
+	}
+}
+
+// WithCodeLength sets the number of digits in codes.
+//
+// Config key: `auth.otp.codeLength`.
+func WithCodeLength(n int) OTPOption {
+	return func(p *OTPPlugin) {
+		p.codeLength = n
+	}
+}
+
+// WithExpiration sets how long codes are valid for.
+//
+// Config key: `auth.otp.expiration`.
+func WithExpiration(d time.Duration) OTPOption {
+	return func(p *OTPPlugin) {
+		p.expiration = d
+	}
+}
+
+// WithMaxAttempts sets how many times a code can be guessed.
+//
+// Config key: `auth.otp.maxAttempts`.
+func WithMaxAttempts(n int) OTPOption {
+	return func(p *OTPPlugin) {
+		p.maxAttempts = n
+	}
+}
+
+// WithRateLimit sets the minimum interval between codes sent to a phone
+// number, and the maximum codes sent per window.
+//
+// Config keys: `auth.otp.resendInterval`, `auth.otp.maxSends`, `auth.otp.sendWindow`.
+func WithRateLimit(resendInterval time.Duration, maxSends int, window time.Duration) OTPOption {
+	return func(p *OTPPlugin) {
+		p.resendInterval = resendInterval
+		p.maxSends = maxSends
+		p.sendWindow = window
+	}
+}
+
+// Plugin for handling passwordless authentication via one-time codes.
+func Plugin(opts ...OTPOption) *OTPPlugin {
+	config.EnsureDefaultsLoaded(prefab.Config)
+
+	p := &OTPPlugin{
+		codeLength:     prefab.ConfigInt("auth.otp.codeLength"),
+		expiration:     prefab.ConfigDuration("auth.otp.expiration"),
+		maxAttempts:    prefab.ConfigInt("auth.otp.maxAttempts"),
+		resendInterval: prefab.ConfigDuration("auth.otp.resendInterval"),
+		maxSends:       prefab.ConfigInt("auth.otp.maxSends"),
+		sendWindow:     prefab.ConfigDuration("auth.otp.sendWindow"),
+		message:        defaultMessage,
+		now:            time.Now,
+	}
+	if sid := prefab.ConfigString("auth.otp.twilio.accountSid"); sid != "" {
+		p.sender = NewTwilioSender(sid,
+			prefab.ConfigString("auth.otp.twilio.authToken"),
+			prefab.ConfigString("auth.otp.twilio.from"))
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// OTPPlugin for handling passwordless authentication via one-time codes.
+type OTPPlugin struct {
+	sender  Sender
+	message func(code string, expiration time.Duration) string
+	store   storage.Store
+	locker  lock.Locker
+	now     func() time.Time
+
+	codeLength     int
+	expiration     time.Duration
+	maxAttempts    int
+	resendInterval time.Duration
+	maxSends       int
+	sendWindow     time.Duration
+}
+
+// From prefab.Plugin.
+func (p *OTPPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *OTPPlugin) Deps() []string {
+	return []string{auth.PluginName, storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *OTPPlugin) OptDeps() []string {
+	return []string{lock.PluginName}
+}
+
+// From prefab.InitializablePlugin.
+func (p *OTPPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.sender == nil {
+		return errors.NewC("otp: plugin requires a sender, configure auth.otp.twilio or use WithSender", codes.FailedPrecondition)
+	}
+	if p.codeLength < 4 {
+		return errors.New("otp: codes must have at least 4 digits")
+	}
+
+	sp := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if err := sp.InitModel(challenge{}); err != nil {
+		return err
+	}
+	p.store = sp
+
+	if lp, ok := r.Get(lock.PluginName).(*lock.LockPlugin); ok {
+		p.locker = lp
+	} else {
+		p.locker = memlock.New()
+	}
+
+	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
+	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Phone",
+		Icon:        "phone",
+		Credentials: []string{"phone", "code"},
+	})
+	return nil
+}
+
+func (p *OTPPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	if req.Provider != ProviderName {
+		return nil, errors.NewC("otp: login handler called for wrong provider", codes.InvalidArgument)
+	}
+	if req.Creds["phone"] == "" {
+		return nil, errors.NewC("otp: missing credentials, otp login requires a `phone`", codes.InvalidArgument)
+	}
+	phone, err := NormalizePhone(req.Creds["phone"])
+	if err != nil {
+		return nil, err
+	}
+	if req.Creds["code"] == "" {
+		if err := p.SendCode(ctx, phone); err != nil {
+			return nil, err
+		}
+		return &auth.LoginResponse{Issued: false}, nil
+	}
+	if err := p.VerifyCode(ctx, phone, req.Creds["code"]); err != nil {
+		return nil, err
+	}
+	return p.login(ctx, phone, req)
+}
+
+// SendCode generates a new code for the phone number, which must be in E.164
+// format, and sends it. Any previous code is replaced. Returns ErrRateLimited
+// if codes have been requested too often.
+func (p *OTPPlugin) SendCode(ctx context.Context, phone string) error {
+	unlock, err := p.lock(ctx, phone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	now := p.now()
+	c := &challenge{ID: phone}
+	if err := p.store.Read(ctx, phone, c); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	if now.Sub(c.LastSentAt) < p.resendInterval {
+		return errors.Mark(ErrRateLimited, 0)
+	}
+	if now.Sub(c.WindowStart) >= p.sendWindow {
+		c.WindowStart = now
+		c.Sends = 0
+	}
+	if c.Sends >= p.maxSends {
+		logging.Warnw(ctx, "otp: send limit reached", "phone", redact(phone))
+		return errors.Mark(ErrRateLimited, 0)
+	}
+
+	code, err := p.generateCode()
+	if err != nil {
+		return err
+	}
+	salt := uuid.NewString()
+	c.CodeHash = hashCode(salt, code)
+	c.Salt = salt
+	c.ExpiresAt = now.Add(p.expiration)
+	c.Attempts = 0
+	c.Sends++
+	c.LastSentAt = now
+
+	// Stored before sending, so a slow or failed send still counts towards the
+	// rate limit.
+	if err := p.store.Upsert(ctx, c); err != nil {
+		return err
+	}
+	if err := p.sender.Send(ctx, phone, p.message(code, p.expiration)); err != nil {
+		return errors.Wrap(err, 0).Append("otp: failed to send code")
+	}
+	logging.Infow(ctx, "otp: code sent", "phone", redact(phone))
+	return nil
+}
+
+// VerifyCode checks a code sent to the phone number, which must be in E.164
+// format. Codes can only be used once.
+func (p *OTPPlugin) VerifyCode(ctx context.Context, phone, code string) error {
+	// Held until the attempt is recorded, so that concurrent guesses can't
+	// exceed the attempt limit or reuse a code.
+	unlock, err := p.lock(ctx, phone)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	c := &challenge{}
+	if err := p.store.Read(ctx, phone, c); errors.Is(err, storage.ErrNotFound) {
+		return errors.Mark(ErrInvalidCode, 0)
+	} else if err != nil {
+		return err
+	}
+	if c.CodeHash == "" || p.now().After(c.ExpiresAt) {
+		return errors.Mark(ErrInvalidCode, 0)
+	}
+	if c.Attempts >= p.maxAttempts {
+		return errors.Mark(ErrTooManyAttempts, 0)
+	}
+
+	if !hmac.Equal([]byte(hashCode(c.Salt, strings.TrimSpace(code))), []byte(c.CodeHash)) {
+		c.Attempts++
+		if err := p.store.Update(ctx, c); err != nil {
+			return err
+		}
+		logging.Infow(ctx, "otp: invalid code", "phone", redact(phone), "attempts", c.Attempts)
+		return errors.Mark(ErrInvalidCode, 0)
+	}
+
+	// Clear the code so it can't be replayed, keeping the rate limit counters.
+	c.CodeHash = ""
+	c.Salt = ""
+	return p.store.Update(ctx, c)
+}
+
+// lock acquires the lock for a phone number's challenge. The returned function
+// releases the lock.
+func (p *OTPPlugin) lock(ctx context.Context, phone string) (func(), error) {
+	lease, err := p.locker.Lock(ctx, "otp:"+phone)
+	if err != nil {
+		return nil, errors.Wrap(err, 0).Append("otp: failed to lock challenge")
+	}
+	return func() {
+		if err := lease.Unlock(context.WithoutCancel(ctx)); err != nil {
+			logging.Errorw(ctx, "otp: failed to unlock challenge", "error", err)
+		}
+	}, nil
+}
+
+func (p *OTPPlugin) login(ctx context.Context, phone string, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	identity := auth.Identity{
+		Provider:   ProviderName,
+		SessionID:  uuid.NewString(),
+		AuthTime:   time.Now(),
+		Subject:    phone,
+		Name:       phone,
+		RememberMe: req.RememberMe,
+	}
+
+	if err := auth.CheckLogin(ctx, identity); err != nil {
+		return nil, err
+	}
+	idt, err := auth.IdentityToken(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	logging.Infow(ctx, "otp: user authenticated", "phone", redact(phone))
+
+	if bus := eventbus.FromContext(ctx); bus != nil {
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, identity))
+	}
+
+	if req.IssueToken {
+		return &auth.LoginResponse{
+			Issued: true,
+			Token:  idt,
+		}, nil
+	}
+	if err := auth.SendIdentityCookie(ctx, idt); err != nil {
+		return nil, err
+	}
+	return &auth.LoginResponse{
+		Issued:      true,
+		RedirectUri: req.RedirectUri,
+	}, nil
+}
+
+func (p *OTPPlugin) generateCode() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.codeLength)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return fmt.Sprintf("%0*d", p.codeLength, n), nil
+}
+
+func hashCode(salt, code string) string {
+	h := hmac.New(sha256.New, []byte(salt))
+	h.Write([]byte(code))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func defaultMessage(code string, expiration time.Duration) string {
+	return fmt.Sprintf("Your verification code is %s. It expires in %s.", code, expiration)
+}
+
+var (
+	phoneSeparators = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+	e164            = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+)
+
+// NormalizePhone returns the phone number in E.164 format, removing spaces,
+// dashes, dots, and parentheses. Returns ErrInvalidPhone if the number doesn't
+// include a country code.
+func NormalizePhone(phone string) (string, error) {
+	phone = phoneSeparators.Replace(strings.TrimSpace(phone))
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	}
+	if !e164.MatchString(phone) {
+		return "", errors.Mark(ErrInvalidPhone, 0)
+	}
+	return phone, nil
+}
+
+// redact hides all but the last digits of a phone number, for logging.
+func redact(phone string) string {
+	if len(phone) <= 4 {
+		return phone
+	}
+	return strings.Repeat("*", len(phone)-4) + phone[len(phone)-4:]
+}
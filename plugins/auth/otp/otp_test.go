@@ -0,0 +1,268 @@
+package otp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/authtest"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+const phone = "+14155550123"
+
+var codeRe = regexp.MustCompile(`[0-9]{6}`)
+
+// testPlugin returns an initialized plugin, a function returning the last code
+// sent, and a clock which can be advanced.
+func testPlugin(ctx context.Context, t *testing.T, opts ...OTPOption) (*OTPPlugin, func() string, *time.Time) {
+	var last string
+	sender := SenderFunc(func(ctx context.Context, to, message string) error {
+		assert.Equal(t, phone, to)
+		last = codeRe.FindString(message)
+		return nil
+	})
+	p := Plugin(append([]OTPOption{WithSender(sender)}, opts...)...)
+	now := time.Now()
+	p.now = func() time.Time { return now }
+
+	r := &prefab.Registry{}
+	r.Register(auth.Plugin())
+	r.Register(storage.Plugin(memstore.New()))
+	r.Register(p)
+	require.NoError(t, r.Init(ctx))
+	return p, func() string { return last }, &now
+}
+
+func login(ctx context.Context, p *OTPPlugin, creds map[string]string) (*auth.LoginResponse, error) {
+	return p.handleLogin(ctx, &auth.LoginRequest{Provider: ProviderName, IssueToken: true, Creds: creds})
+}
+
+func TestLogin(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p, lastCode, _ := testPlugin(ctx, t)
+
+	resp, err := login(ctx, p, map[string]string{"phone": "+1 (415) 555-0123"})
+	require.NoError(t, err)
+	assert.False(t, resp.Issued)
+	require.Len(t, lastCode(), 6)
+
+	resp, err = login(ctx, p, map[string]string{"phone": phone, "code": lastCode()})
+	require.NoError(t, err)
+	require.True(t, resp.Issued)
+
+	identity, err := auth.ParseIdentityToken(ctx, resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderName, identity.Provider)
+	assert.Equal(t, phone, identity.Subject)
+
+	// Codes can only be used once.
+	_, err = login(ctx, p, map[string]string{"phone": phone, "code": lastCode()})
+	require.ErrorIs(t, err, ErrInvalidCode)
+}
+
+func TestConformance(t *testing.T) {
+	var p *OTPPlugin
+	var code string
+	authtest.Run(t, authtest.Provider{
+		Name:    ProviderName,
+		Subject: phone,
+		Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+			p = Plugin(WithSender(SenderFunc(func(ctx context.Context, to, message string) error {
+				code = codeRe.FindString(message)
+				return nil
+			})))
+			return []prefab.Plugin{p}
+		},
+		Creds: func(t *testing.T, s *prefabtest.Server) map[string]string {
+			ctx := logging.With(t.Context(), logging.NewDevLogger())
+			require.NoError(t, p.SendCode(ctx, phone))
+			return map[string]string{"phone": phone, "code": code}
+		},
+		InvalidCreds: map[string]string{"phone": phone, "code": "000000"},
+	})
+}
+
+func TestVerifyCode_Expired(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p, lastCode, now := testPlugin(ctx, t, WithExpiration(time.Minute))
+
+	require.NoError(t, p.SendCode(ctx, phone))
+	*now = now.Add(2 * time.Minute)
+	require.ErrorIs(t, p.VerifyCode(ctx, phone, lastCode()), ErrInvalidCode)
+}
+
+func TestVerifyCode_MaxAttempts(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p, lastCode, now := testPlugin(ctx, t, WithMaxAttempts(2))
+
+	require.NoError(t, p.SendCode(ctx, phone))
+	require.ErrorIs(t, p.VerifyCode(ctx, phone, "000000x"), ErrInvalidCode)
+	require.ErrorIs(t, p.VerifyCode(ctx, phone, "000000x"), ErrInvalidCode)
+
+	// The right code is rejected once the attempts are used up.
+	err := p.VerifyCode(ctx, phone, lastCode())
+	require.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err))
+
+	// A new code resets the attempts.
+	*now = now.Add(time.Minute)
+	require.NoError(t, p.SendCode(ctx, phone))
+	require.NoError(t, p.VerifyCode(ctx, phone, lastCode()))
+}
+
+func TestVerifyCode_ConcurrentAttempts(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p, _, _ := testPlugin(ctx, t, WithMaxAttempts(3))
+	require.NoError(t, p.SendCode(ctx, phone))
+	p.store = slowStore{p.store}
+
+	var mu sync.Mutex
+	var invalid int
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			if errors.Is(p.VerifyCode(ctx, phone, "000000x"), ErrInvalidCode) {
+				mu.Lock()
+				invalid++
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, 3, invalid, "only maxAttempts guesses should be checked")
+}
+
+// slowStore delays reads, so that unsynchronized read-modify-writes overlap.
+type slowStore struct {
+	storage.Store
+}
+
+func (s slowStore) Read(ctx context.Context, id string, model storage.Model) error {
+	err := s.Store.Read(ctx, id, model)
+	time.Sleep(10 * time.Millisecond)
+	return err
+}
+
+func TestPlugin_ConfigDefaults(t *testing.T) {
+	p := Plugin()
+	assert.Equal(t, 6, p.codeLength)
+	assert.Equal(t, 10*time.Minute, p.expiration)
+	assert.Equal(t, 5, p.maxAttempts)
+	assert.Equal(t, 30*time.Second, p.resendInterval)
+	assert.Equal(t, 5, p.maxSends)
+	assert.Equal(t, time.Hour, p.sendWindow)
+}
+
+func TestSendCode_RateLimit(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p, _, now := testPlugin(ctx, t, WithRateLimit(30*time.Second, 2, time.Hour))
+
+	require.NoError(t, p.SendCode(ctx, phone))
+
+	// Too soon.
+	err := p.SendCode(ctx, phone)
+	require.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err))
+
+	*now = now.Add(time.Minute)
+	require.NoError(t, p.SendCode(ctx, phone))
+
+	// Window is used up.
+	*now = now.Add(time.Minute)
+	require.ErrorIs(t, p.SendCode(ctx, phone), ErrRateLimited)
+
+	// A new window.
+	*now = now.Add(time.Hour)
+	require.NoError(t, p.SendCode(ctx, phone))
+}
+
+func TestInit_RequiresSender(t *testing.T) {
+	r := &prefab.Registry{}
+	r.Register(auth.Plugin())
+	r.Register(storage.Plugin(memstore.New()))
+	r.Register(Plugin())
+	require.Error(t, r.Init(logging.With(t.Context(), logging.NewDevLogger())))
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  bool
+	}{
+		{in: "+14155550123", want: "+14155550123"},
+		{in: " +1 415-555-0123 ", want: "+14155550123"},
+		{in: "+44 (20) 7946.0958", want: "+442079460958"},
+		{in: "0044 20 7946 0958", want: "+442079460958"},
+		{in: "4155550123", err: true},
+		{in: "+0123456789", err: true},
+		{in: "+1415abc0123", err: true},
+		{in: "+1234567890123456", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := NormalizePhone(tt.in)
+			if tt.err {
+				require.ErrorIs(t, err, ErrInvalidPhone)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTwilioSender(t *testing.T) {
+	var form map[string]string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/Accounts/AC123/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "token", pass)
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		if form["To"] == "+10000000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	ctx := t.Context()
+	sender := NewTwilioSender("AC123", "token", "+15005550006")
+	sender.BaseURL = s.URL
+	require.NoError(t, sender.Send(ctx, phone, "hello"))
+	assert.Equal(t, map[string]string{"To": phone, "From": "+15005550006", "Body": "hello"}, form)
+
+	sender.From = "whatsapp:+15005550006"
+	require.NoError(t, sender.Send(ctx, phone, "hello"))
+	assert.Equal(t, "whatsapp:"+phone, form["To"])
+
+	sender.From = "MG123"
+	require.NoError(t, sender.Send(ctx, phone, "hello"))
+	assert.Equal(t, "MG123", form["MessagingServiceSid"])
+	assert.Empty(t, form["From"])
+
+	err := sender.Send(ctx, "+10000000000", "hello")
+	require.ErrorContains(t, err, "Invalid 'To' Phone Number")
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
@@ -0,0 +1,104 @@
+package otp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/httpclient"
+	"google.golang.org/grpc/codes"
+)
+
+// Sender delivers a message containing a code to a phone number, which is in
+// E.164 format, e.g. "+14155550123". Implementations exist for SMS and
+// WhatsApp via Twilio; other channels, such as a Telegram bot, can be added by
+// implementing this interface.
+type Sender interface {
+	Send(ctx context.Context, phone, message string) error
+}
+
+// SenderFunc adapts a function to a Sender, which is useful for tests and for
+// logging codes in development.
+type SenderFunc func(ctx context.Context, phone, message string) error
+
+// Send calls f.
+func (f SenderFunc) Send(ctx context.Context, phone, message string) error {
+	return f(ctx, phone, message)
+}
+
+const twilioAPI = "https://api.twilio.com/2010-04-01"
+
+// TwilioSender sends messages using Twilio's Messages API.
+type TwilioSender struct {
+	AccountSID string
+	AuthToken  string
+
+	// The Twilio number, or messaging service SID, messages are sent from. For
+	// WhatsApp use "whatsapp:+14155550123", and messages are sent to the
+	// recipient's WhatsApp account.
+	From string
+
+	// Base URL of the API, which can be changed for testing.
+	BaseURL string
+
+	client *http.Client
+}
+
+// NewTwilioSender returns a Sender which sends messages via Twilio.
+func NewTwilioSender(accountSID, authToken, from string) *TwilioSender {
+	return &TwilioSender{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		BaseURL:    twilioAPI,
+		client:     httpclient.New(httpclient.WithoutPropagation()),
+	}
+}
+
+// Send implements Sender.
+func (s *TwilioSender) Send(ctx context.Context, phone, message string) error {
+	to := phone
+	if strings.HasPrefix(s.From, "whatsapp:") {
+		to = "whatsapp:" + phone
+	}
+	form := url.Values{}
+	form.Set("To", to)
+	form.Set("Body", message)
+	if strings.HasPrefix(s.From, "MG") {
+		form.Set("MessagingServiceSid", s.From)
+	} else {
+		form.Set("From", s.From)
+	}
+
+	u := s.BaseURL + "/Accounts/" + url.PathEscape(s.AccountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, strings.NewReader(form.Encode()))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.AccountSID, s.AuthToken)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Codef(codes.Unavailable, "otp: twilio request failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	code := codes.Unavailable
+	if resp.StatusCode == http.StatusBadRequest {
+		// For example, the number can't receive messages.
+		code = codes.InvalidArgument
+	}
+	return errors.Codef(code, "otp: twilio returned status %d: %d %s", resp.StatusCode, body.Code, body.Message)
+}
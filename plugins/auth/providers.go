@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// SetProviderInfo describes a login provider, for clients rendering a login
+// screen via ListProviders. Called by provider plugins alongside
+// AddLoginHandler. Metadata set by the application with WithProviderInfo takes
+// precedence.
+func (ap *AuthPlugin) SetProviderInfo(info *ProviderInfo) {
+	ap.authService.setProviderInfo(info, false)
+}
+
+// WithProviderInfo overrides metadata returned by ListProviders for a
+// provider, such as its display name or icon. Empty fields keep the values set
+// by the provider plugin.
+//
+// Example:
+//
+//	auth.WithProviderInfo(&auth.ProviderInfo{Name: "google", DisplayName: "Acme SSO"})
+func WithProviderInfo(info *ProviderInfo) AuthOption {
+	return func(p *AuthPlugin) {
+		p.authService.setProviderInfo(info, true)
+	}
+}
+
+func (s *impl) setProviderInfo(info *ProviderInfo, override bool) {
+	m := &s.providerInfo
+	if override {
+		m = &s.providerOverrides
+	}
+	if *m == nil {
+		*m = map[string]*ProviderInfo{}
+	}
+	(*m)[info.Name] = info
+}
+
+// ListProviders returns the providers with registered login handlers.
+func (s *impl) ListProviders(ctx context.Context, in *ListProvidersRequest) (*ListProvidersResponse, error) {
+	resp := &ListProvidersResponse{}
+	for name := range s.handlers {
+		info := &ProviderInfo{Name: name}
+		mergeProviderInfo(info, s.providerInfo[name])
+		mergeProviderInfo(info, s.providerOverrides[name])
+		if info.DisplayName == "" {
+			info.DisplayName = name
+		}
+		resp.Providers = append(resp.Providers, info)
+	}
+	slices.SortFunc(resp.Providers, func(a, b *ProviderInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return resp, nil
+}
+
+// mergeProviderInfo copies the non-empty fields of src to dst.
+func mergeProviderInfo(dst, src *ProviderInfo) {
+	if src == nil {
+		return
+	}
+	if src.DisplayName != "" {
+		dst.DisplayName = src.DisplayName
+	}
+	if src.Icon != "" {
+		dst.Icon = src.Icon
+	}
+	if src.Redirect {
+		dst.Redirect = true
+	}
+	if len(src.Credentials) > 0 {
+		dst.Credentials = slices.Clone(src.Credentials)
+	}
+}
@@ -76,6 +76,12 @@ func (p *PwdAuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 	}
 	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
 	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Password",
+		Icon:        "password",
+		Credentials: []string{"email", "password"},
+	})
 	return nil
 }
 
@@ -101,13 +107,17 @@ func (p *PwdAuthPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest)
 
 	id := identityFromAccount(a)
 
+	if err := auth.CheckLogin(ctx, id); err != nil {
+		return nil, err
+	}
+
 	idt, err := auth.IdentityToken(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
 	if bus := eventbus.FromContext(ctx); bus != nil {
-		bus.Publish(auth.LoginEvent, auth.NewAuthEvent(id))
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, id))
 	}
 
 	if req.IssueToken {
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// WithRememberMe enables remember me logins. Logins which set `remember_me`
+// receive a token and cookie valid for expiration, while other logins receive
+// a cookie scoped to the browser session and a token valid for
+// `auth.expiration`.
+//
+// Config key: `auth.rememberMe.expiration`.
+func WithRememberMe(expiration time.Duration) AuthOption {
+	return func(p *AuthPlugin) {
+		p.rememberMeExpiration = expiration
+	}
+}
+
+// loginContext configures the token and cookie lifetimes for a login, when
+// remember me is enabled.
+func (s *impl) loginContext(ctx context.Context, in *LoginRequest) context.Context {
+	if s.rememberMeExpiration <= 0 {
+		return ctx
+	}
+	if in.RememberMe {
+		ctx = injectExpiration(s.rememberMeExpiration)(ctx)
+	}
+	return context.WithValue(ctx, rememberMeKey{}, in.RememberMe)
+}
+
+type rememberMeKey struct{}
+
+// rememberMeFromContext returns true if the request is a remember me login.
+func rememberMeFromContext(ctx context.Context) bool {
+	v, _ := ctx.Value(rememberMeKey{}).(bool)
+	return v
+}
+
+// isSessionLogin returns true if the request is a login without remember me,
+// while remember me is enabled, in which case the identity cookie is scoped to
+// the browser session.
+func isSessionLogin(ctx context.Context) bool {
+	v, ok := ctx.Value(rememberMeKey{}).(bool)
+	return ok && !v
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestLogin_RememberMe(t *testing.T) {
+	svc := &impl{rememberMeExpiration: 30 * 24 * time.Hour}
+	svc.AddLoginHandler("test", func(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+		token, err := IdentityToken(ctx, Identity{Provider: "test", Subject: "user", AuthTime: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		if req.IssueToken {
+			return &LoginResponse{Issued: true, Token: token}, nil
+		}
+		return &LoginResponse{Issued: true}, SendIdentityCookie(ctx, token)
+	})
+
+	login := func(rememberMe bool) (*http.Cookie, Identity) {
+		transport := &mockServerTransportStream{}
+		ctx := logging.With(t.Context(), logging.NewDevLogger())
+		ctx = grpc.NewContextWithServerTransportStream(ctx, transport)
+		ctx = serverutil.WithAddress(ctx, "http://localhost:8000")
+		ctx = injectExpiration(time.Hour)(ctx)
+
+		resp, err := svc.Login(ctx, &LoginRequest{Provider: "test", RememberMe: rememberMe, IssueToken: true})
+		require.NoError(t, err)
+		identity, err := ParseIdentityToken(ctx, resp.Token)
+		require.NoError(t, err)
+
+		_, err = svc.Login(ctx, &LoginRequest{Provider: "test", RememberMe: rememberMe})
+		require.NoError(t, err)
+		cookies := (*transport.md)["grpc-metadata-set-cookie"]
+		require.Len(t, cookies, 1)
+		c, err := http.ParseSetCookie(cookies[0])
+		require.NoError(t, err)
+		return c, identity
+	}
+
+	// Session logins get a cookie scoped to the browser session.
+	c, identity := login(false)
+	assert.True(t, c.Expires.IsZero())
+	assert.Zero(t, c.MaxAge)
+	assert.False(t, identity.RememberMe)
+
+	// Remember me logins get a long-lived cookie and are marked in the identity.
+	c, identity = login(true)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), c.Expires, time.Minute)
+	assert.True(t, identity.RememberMe)
+}
+
+func TestLogin_RememberMeDisabled(t *testing.T) {
+	svc := &impl{}
+	svc.AddLoginHandler("test", func(ctx context.Context, req *LoginRequest) (*LoginResponse, error) {
+		assert.False(t, isSessionLogin(ctx))
+		assert.False(t, rememberMeFromContext(ctx))
+		return &LoginResponse{}, nil
+	})
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	_, err := svc.Login(ctx, &LoginRequest{Provider: "test", RememberMe: true})
+	require.NoError(t, err)
+}
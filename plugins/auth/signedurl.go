@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+)
+
+// RequireSignedURL wraps an HTTP handler so that it can only be reached via a
+// URL created with serverutil.SignURL. Requests are not otherwise
+// authenticated, the verified URL is available to the handler via
+// serverutil.SignedURLFromContext.
+//
+// Example:
+//
+//	prefab.WithHTTPHandler("/exports/", auth.RequireSignedURL(exportHandler))
+func RequireSignedURL(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		s, err := serverutil.VerifySignedURL(ctx, r.URL)
+		if err != nil {
+			logging.Infow(ctx, "auth: rejected signed url", "error", err)
+			http.Error(w, "invalid or expired link", http.StatusForbidden)
+			return
+		}
+		if blocked, err := IsBlocked(ctx, signedURLBlockKey(s.ID)); err != nil {
+			logging.Errorw(ctx, "auth: failed to check signed url blocklist", "error", err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			http.Error(w, "invalid or expired link", http.StatusForbidden)
+			return
+		}
+		logging.Track(ctx, "auth.signedURL", s.ID)
+		h.ServeHTTP(w, r.WithContext(serverutil.WithSignedURL(ctx, s)))
+	})
+}
+
+// RevokeSignedURL adds a signed URL to the blocklist, so that it can no longer
+// be used even if it hasn't expired. Requires a blocklist to be configured.
+func RevokeSignedURL(ctx context.Context, signedURL string) error {
+	s, err := serverutil.ParseSignedURL(signedURL)
+	if err != nil {
+		return err
+	}
+	return MaybeBlock(ctx, signedURLBlockKey(s.ID))
+}
+
+func signedURLBlockKey(id string) string {
+	return "signedurl:" + id
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireSignedURL(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	ctx = serverutil.WithSigningKeys(ctx, []byte("key"))
+	ctx = WithBlockist(ctx, NewBlocklist(memstore.New()))
+
+	var claims map[string]string
+	h := RequireSignedURL(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, ok := serverutil.SignedURLFromContext(r.Context())
+		require.True(t, ok)
+		claims = s.Claims
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(target string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+		return rec.Code
+	}
+
+	signed, err := serverutil.SignURL(ctx, "/exports/1", time.Minute, map[string]string{"user": "u1"})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, serve(signed))
+	assert.Equal(t, "u1", claims["user"])
+
+	assert.Equal(t, http.StatusForbidden, serve("/exports/1"))
+
+	require.NoError(t, RevokeSignedURL(ctx, signed))
+	assert.Equal(t, http.StatusForbidden, serve(signed))
+}
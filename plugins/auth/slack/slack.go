@@ -0,0 +1,306 @@
+// Package slack provides authentication via Sign in with Slack, which is built
+// on OpenID Connect.
+//
+// To initiate a login, the client should make a POST request to the
+// `/api/auth/login` endpoint with the following JSON body:
+//
+// ```json
+//
+//	{
+//	  "provider": "slack",
+//	  "redirect_uri": "/dashboard"
+//	}
+//
+// ```
+//
+// The server responds with a `redirect_uri` for Slack. After the user approves
+// the login, Slack redirects back to `/api/auth/slack/callback`, which
+// completes the login and redirects to the original destination. The flow is
+// the same as the server side flow of the google plugin.
+//
+// The user's workspace is added to the identity's groups, as TeamGroup(id), so
+// that it can be mapped to roles with authz.GroupRoles. Logins can be limited
+// to a single workspace, which is typical for internal tools, with WithTeam.
+//
+// ## Configuring a Slack App
+//
+// Create an app at https://api.slack.com/apps, and under "OAuth & Permissions"
+// add the redirect URL, for development:
+// http://localhost:8000/api/auth/slack/callback
+//
+// In production switch out the protocol, host, and port with your domain.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/internal/oauthflow"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// Constant name for the Slack auth plugin.
+	PluginName = "auth_slack"
+
+	// Constant name used as the auth provider in API requests.
+	ProviderName = "slack"
+
+	authURL     = "https://slack.com/openid/connect/authorize"
+	tokenURL    = "https://slack.com/api/openid.connect.token"
+	userInfoURL = "https://slack.com/api/openid.connect.userInfo"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "auth.slack.id",
+			Description: "Slack OAuth client ID",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.slack.secret",
+			Description: "Slack OAuth client secret",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "auth.slack.team",
+			Description: "Slack workspace ID which users must belong to (any workspace if not set)",
+			Type:        "string",
+		},
+	)
+}
+
+// TeamGroup returns the group that members of a Slack workspace are given, for
+// use in an authz.GroupMapping.
+func TeamGroup(teamID string) string {
+	return "slack:team:" + teamID
+}
+
+// SlackOption allows configuration of the SlackPlugin.
+type SlackOption func(*SlackPlugin)
+
+// WithClient configures the SlackPlugin with the given client id and secret.
+func WithClient(id, secret string) SlackOption {
+	return func(p *SlackPlugin) {
+		p.clientID = id
+		p.clientSecret = secret
+	}
+}
+
+// WithTeam only allows members of the workspace to log in. Slack will also
+// skip the workspace picker.
+//
+// Config key: `auth.slack.team`.
+func WithTeam(teamID string) SlackOption {
+	return func(p *SlackPlugin) {
+		p.team = teamID
+	}
+}
+
+// Plugin for handling Slack authentication.
+func Plugin(opts ...SlackOption) *SlackPlugin {
+	p := &SlackPlugin{
+		clientID:     prefab.Config.String("auth.slack.id"),
+		clientSecret: prefab.Config.String("auth.slack.secret"),
+		team:         prefab.Config.String("auth.slack.team"),
+		endpoint:     oauth2.Endpoint{AuthURL: authURL, TokenURL: tokenURL, AuthStyle: oauth2.AuthStyleInParams},
+		userInfoURL:  userInfoURL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// SlackPlugin for handling Slack authentication.
+type SlackPlugin struct {
+	clientID     string
+	clientSecret string
+	team         string
+	endpoint     oauth2.Endpoint
+	userInfoURL  string
+}
+
+// From prefab.Plugin.
+func (p *SlackPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *SlackPlugin) Deps() []string {
+	return []string{auth.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *SlackPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithHTTPHandlerFunc("/api/auth/slack/callback", oauthflow.CallbackHandler(ProviderName, p.clientSecret)),
+		prefab.WithClientConfig("auth.slack.clientId", p.clientID),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *SlackPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.clientID == "" {
+		return errors.New("slack: config missing client id")
+	}
+	if p.clientSecret == "" {
+		return errors.New("slack: config missing client secret")
+	}
+	ap := r.Get(auth.PluginName).(*auth.AuthPlugin)
+	ap.AddLoginHandler(ProviderName, p.handleLogin)
+	ap.SetProviderInfo(&auth.ProviderInfo{
+		Name:        ProviderName,
+		DisplayName: "Slack",
+		Icon:        "slack",
+		Redirect:    true,
+	})
+	return nil
+}
+
+func (p *SlackPlugin) handleLogin(ctx context.Context, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	if req.Provider != ProviderName {
+		return nil, errors.NewC("slack: login handler called for wrong provider", codes.InvalidArgument)
+	}
+	switch {
+	case req.Creds["code"] != "":
+		userInfo, err := p.handleAuthorizationCode(ctx, req.Creds["code"], req.Creds["state"])
+		if err != nil {
+			return nil, err
+		}
+		return p.authenticateUserInfo(ctx, userInfo, req)
+	case len(req.Creds) == 0 || req.Creds["state"] != "":
+		return p.redirectToSlack(ctx, req.RedirectUri, req.Creds["state"], req.RememberMe)
+	default:
+		return nil, errors.NewC("slack: unexpected credentials, a `code` is required", codes.InvalidArgument)
+	}
+}
+
+func (p *SlackPlugin) config(ctx context.Context) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.clientID,
+		ClientSecret: p.clientSecret,
+		Endpoint:     p.endpoint,
+		RedirectURL:  serverutil.AddressFromContext(ctx) + "/api/auth/slack/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}
+}
+
+func (p *SlackPlugin) redirectToSlack(ctx context.Context, dest, state string, rememberMe bool) (*auth.LoginResponse, error) {
+	var opts []oauth2.AuthCodeOption
+	if p.team != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("team", p.team))
+	}
+	u := p.config(ctx).AuthCodeURL(oauthflow.NewState(p.clientSecret, dest, state, rememberMe), opts...)
+	logging.Infof(ctx, "slack: redirecting to: %s", u)
+	return &auth.LoginResponse{
+		Issued:      false,
+		RedirectUri: u,
+	}, nil
+}
+
+func (p *SlackPlugin) handleAuthorizationCode(ctx context.Context, code, rawState string) (*UserInfo, error) {
+	if _, err := oauthflow.ParseState(p.clientSecret, rawState); err != nil {
+		return nil, errors.Wrap(err, 0).Append("slack: failed to parse state")
+	}
+
+	conf := p.config(ctx)
+	token, err := conf.Exchange(ctx, code)
+	if err != nil {
+		return nil, errors.Codef(codes.Internal, "slack: token exchange failed: %s", err)
+	}
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, p.userInfoURL, nil)
+	resp, err := conf.Client(ctx, token).Do(req)
+	if err != nil {
+		return nil, errors.Codef(codes.Internal, "slack: failed to fetch user info: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Codef(codes.Internal, "slack: failed to get user info, status: %d", resp.StatusCode)
+	}
+	userInfo := &UserInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(userInfo); err != nil {
+		return nil, errors.Codef(codes.Internal, "slack: failed to decode user info: %s", err)
+	}
+	// Slack's Web API reports errors in the body, with a 200 status.
+	if !userInfo.OK {
+		return nil, errors.Codef(codes.Internal, "slack: failed to get user info: %s", userInfo.Error)
+	}
+	return userInfo, nil
+}
+
+func (p *SlackPlugin) authenticateUserInfo(ctx context.Context, userInfo *UserInfo, req *auth.LoginRequest) (*auth.LoginResponse, error) {
+	if p.team != "" && userInfo.TeamID != p.team {
+		logging.Warnw(ctx, "slack: login from another workspace rejected", "team_id", userInfo.TeamID)
+		return nil, errors.NewC("slack: workspace not allowed", codes.PermissionDenied)
+	}
+
+	identity := auth.Identity{
+		Provider:      ProviderName,
+		SessionID:     uuid.NewString(),
+		AuthTime:      time.Now(),
+		Subject:       userInfo.Subject,
+		Name:          userInfo.Name,
+		Email:         userInfo.Email,
+		EmailVerified: userInfo.EmailVerified,
+	}
+	if userInfo.TeamID != "" {
+		identity.Groups = auth.NewGroups(TeamGroup(userInfo.TeamID))
+	}
+
+	if err := auth.CheckLogin(ctx, identity); err != nil {
+		return nil, err
+	}
+	idt, err := auth.IdentityToken(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	logging.Infow(ctx, "slack: user authenticated", "subject", identity.Subject, "team_id", userInfo.TeamID)
+
+	if bus := eventbus.FromContext(ctx); bus != nil {
+		bus.Publish(auth.LoginEvent, auth.NewAuthEventFromContext(ctx, identity))
+	}
+
+	if req.IssueToken {
+		return &auth.LoginResponse{
+			Issued: true,
+			Token:  idt,
+		}, nil
+	}
+	if err := auth.SendIdentityCookie(ctx, idt); err != nil {
+		return nil, err
+	}
+	return &auth.LoginResponse{
+		Issued:      true,
+		RedirectUri: req.RedirectUri,
+	}, nil
+}
+
+// UserInfo is the response of Slack's openid.connect.userInfo method.
+type UserInfo struct {
+	OK            bool   `json:"ok"`
+	Error         string `json:"error,omitempty"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+	Picture       string `json:"picture"`
+	Locale        string `json:"locale"`
+	TeamID        string `json:"https://slack.com/team_id"`
+	TeamName      string `json:"https://slack.com/team_name"`
+	TeamDomain    string `json:"https://slack.com/team_domain"`
+}
@@ -0,0 +1,148 @@
+package slack
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/authtest"
+	"github.com/dpup/prefab/plugins/auth/internal/oauthflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeSlack serves the token and user info endpoints.
+func fakeSlack(t *testing.T, userInfo string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/openid.connect.token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "the-code", r.PostForm.Get("code"))
+		assert.Equal(t, "id", r.PostForm.Get("client_id"))
+		assert.Equal(t, "secret", r.PostForm.Get("client_secret"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"ok":true,"access_token":"xoxp-1","token_type":"Bearer","id_token":"ignored"}`))
+	})
+	mux.HandleFunc("/api/openid.connect.userInfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer xoxp-1", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(userInfo))
+	})
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func testPlugin(s *httptest.Server, opts ...SlackOption) *SlackPlugin {
+	p := Plugin(append([]SlackOption{WithClient("id", "secret")}, opts...)...)
+	p.endpoint = oauth2.Endpoint{
+		AuthURL:   s.URL + "/openid/connect/authorize",
+		TokenURL:  s.URL + "/api/openid.connect.token",
+		AuthStyle: oauth2.AuthStyleInParams,
+	}
+	p.userInfoURL = s.URL + "/api/openid.connect.userInfo"
+	return p
+}
+
+const krane = `{
+	"ok": true,
+	"sub": "U0R7JM",
+	"email": "krane@example.com",
+	"email_verified": true,
+	"name": "Krane",
+	"https://slack.com/team_id": "T0R7GR",
+	"https://slack.com/team_name": "Kraneflannel"
+}`
+
+func TestRedirect(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p := Plugin(WithClient("id", "secret"), WithTeam("T0R7GR"))
+
+	resp, err := p.handleLogin(ctx, &auth.LoginRequest{Provider: ProviderName, RedirectUri: "/dashboard"})
+	require.NoError(t, err)
+	assert.False(t, resp.Issued)
+
+	u, err := url.Parse(resp.RedirectUri)
+	require.NoError(t, err)
+	assert.Equal(t, "slack.com", u.Host)
+	q := u.Query()
+	assert.Equal(t, "id", q.Get("client_id"))
+	assert.Equal(t, "openid email profile", q.Get("scope"))
+	assert.Equal(t, "T0R7GR", q.Get("team"))
+	assert.Contains(t, q.Get("redirect_uri"), "/api/auth/slack/callback")
+
+	s, err := oauthflow.ParseState("secret", q.Get("state"))
+	require.NoError(t, err)
+	assert.Equal(t, "/dashboard", s.RequestURI)
+}
+
+func TestLogin(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p := testPlugin(fakeSlack(t, krane))
+
+	resp, err := p.handleLogin(ctx, &auth.LoginRequest{
+		Provider:   ProviderName,
+		IssueToken: true,
+		Creds: map[string]string{
+			"code":  "the-code",
+			"state": oauthflow.NewState("secret", "/", "", false),
+		},
+	})
+	require.NoError(t, err)
+	require.True(t, resp.Issued)
+
+	identity, err := auth.ParseIdentityToken(ctx, resp.Token)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderName, identity.Provider)
+	assert.Equal(t, "U0R7JM", identity.Subject)
+	assert.Equal(t, "krane@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Krane", identity.Name)
+	assert.Equal(t, auth.NewGroups(TeamGroup("T0R7GR")), identity.Groups)
+}
+
+func TestLogin_Errors(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	login := func(p *SlackPlugin, state string) error {
+		_, err := p.handleLogin(ctx, &auth.LoginRequest{
+			Provider:   ProviderName,
+			IssueToken: true,
+			Creds:      map[string]string{"code": "the-code", "state": state},
+		})
+		return err
+	}
+	state := oauthflow.NewState("secret", "/", "", false)
+
+	// Another workspace.
+	err := login(testPlugin(fakeSlack(t, krane), WithTeam("T999")), state)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, errors.Code(err))
+
+	// Slack reports errors in the body.
+	err = login(testPlugin(fakeSlack(t, `{"ok":false,"error":"invalid_auth"}`)), state)
+	require.ErrorContains(t, err, "invalid_auth")
+
+	// Forged state.
+	err = login(testPlugin(fakeSlack(t, krane)), oauthflow.NewState("other", "/", "", false))
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+func TestConformance(t *testing.T) {
+	authtest.Run(t, authtest.Provider{
+		Name:    ProviderName,
+		Subject: "U0R7JM",
+		Plugins: func(t *testing.T, idp *authtest.IdP) []prefab.Plugin {
+			idp.Handle("/userinfo", krane)
+			p := Plugin(WithClient(authtest.ClientID, authtest.ClientSecret))
+			p.endpoint = idp.Endpoint()
+			p.userInfoURL = idp.URL + "/userinfo"
+			return []prefab.Plugin{p}
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab"
+)
+
+// Default interval at which RequireSSEIdentity rechecks the caller's identity.
+const defaultSSERecheckInterval = 30 * time.Second
+
+// SSEIdentityOption configures RequireSSEIdentity.
+type SSEIdentityOption func(*sseIdentityGuard)
+
+// SSERecheckInterval sets how often an open stream rechecks the caller's
+// identity. Defaults to 30 seconds.
+func SSERecheckInterval(d time.Duration) SSEIdentityOption {
+	return func(g *sseIdentityGuard) {
+		g.interval = d
+	}
+}
+
+// RequireSSEIdentity rejects SSE streams from callers who aren't
+// authenticated, for use with prefab.WithSSEStream. The identity is extracted
+// from the request's cookie or bearer token before the stream is started.
+//
+// While the stream is open the identity is periodically rechecked, and the
+// stream is closed if the session has been revoked, for example by logging out,
+// or has expired. Revocation requires a blocklist, see WithBlocklist.
+func RequireSSEIdentity(opts ...SSEIdentityOption) prefab.SSEOption {
+	g := &sseIdentityGuard{interval: defaultSSERecheckInterval}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return prefab.WithSSEGuard(g.guard)
+}
+
+type sseIdentityGuard struct {
+	interval time.Duration
+}
+
+func (g *sseIdentityGuard) guard(ctx context.Context, _ map[string]string) (func(context.Context) error, error) {
+	if _, err := IdentityFromContext(ctx); err != nil {
+		return nil, err
+	}
+	return g.watch, nil
+}
+
+func (g *sseIdentityGuard) watch(ctx context.Context) error {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := IdentityFromContext(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireSSEIdentity(t *testing.T) {
+	g := &sseIdentityGuard{interval: time.Millisecond}
+
+	_, err := g.guard(WithIdentityExtractorsForTest(t.Context()), nil)
+	require.ErrorIs(t, err, ErrNotFound)
+
+	bl := NewBlocklist(memstore.New())
+	ctx := WithIdentityForTest(WithBlockist(t.Context(), bl), Identity{Provider: "test", Subject: "1234", SessionID: "s1"})
+	watch, err := g.guard(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, watch)
+
+	// Watching stops without error when the stream ends.
+	done, cancel := context.WithCancel(ctx)
+	cancel()
+	require.NoError(t, watch(done))
+
+	// Revoking the session closes the stream.
+	errs := make(chan error, 1)
+	go func() { errs <- watch(ctx) }()
+	require.NoError(t, bl.Block(ctx, "s1"))
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, ErrRevoked)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream wasn't closed after the session was revoked")
+	}
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrStepUpRequired is returned when a request needs a stronger or more recent
+// login than the caller has. The error carries an `ErrorInfo` detail, with
+// reason StepUpReason, describing the flow the client should run.
+var ErrStepUpRequired = errors.NewC("auth: step-up authentication required", codes.Unauthenticated).
+	WithUserPresentableMessage("Please verify your identity to continue")
+
+// Reason and domain of the `ErrorInfo` detail attached to step-up errors.
+const (
+	StepUpReason = "STEP_UP_REQUIRED"
+	StepUpDomain = "prefab.auth"
+)
+
+// Step-up flows reported to the client in the `flow` metadata.
+const (
+	// The user should log in again.
+	StepUpFlowReauthenticate = "reauthenticate"
+
+	// The user should log in with a second factor.
+	StepUpFlowMFA = "mfa"
+
+	// The admin should assume the identity again, the delegated identity can't
+	// be stepped up itself.
+	StepUpFlowReassume = "reassume"
+)
+
+// StepUp describes the login strength required by an RPC.
+type StepUp struct {
+	// Maximum age of the login, zero for no limit.
+	MaxAuthAge time.Duration
+
+	// Whether the login must have used multi-factor authentication.
+	RequireMFA bool
+}
+
+// StepUpOptions returns the step-up requirements declared on a method via the
+// `prefab.auth.max_auth_age` and `prefab.auth.require_mfa` options.
+func StepUpOptions(info *grpc.UnaryServerInfo) (StepUp, error) {
+	var s StepUp
+	if v, ok := serverutil.MethodOption(info, E_MaxAuthAge); ok && v.(string) != "" {
+		d, err := time.ParseDuration(v.(string))
+		if err != nil {
+			return s, errors.Wrap(err, 0).
+				Append("auth: invalid max_auth_age on " + info.FullMethod).
+				WithCode(codes.Internal)
+		}
+		s.MaxAuthAge = d
+	}
+	if v, ok := serverutil.MethodOption(info, E_RequireMfa); ok {
+		s.RequireMFA = v.(bool)
+	}
+	return s, nil
+}
+
+// RequireStepUp checks the identity in the context meets the requirements,
+// returning ErrStepUpRequired if not. Can be called from handlers that need to
+// decide on step-up at runtime.
+func RequireStepUp(ctx context.Context, s StepUp) error {
+	identity, err := IdentityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	return checkStepUp(identity, s)
+}
+
+func checkStepUp(identity Identity, s StepUp) error {
+	stale := s.MaxAuthAge > 0 && timeFunc().Sub(identity.AuthTime) > s.MaxAuthAge
+	needsMFA := s.RequireMFA && !identity.MFA
+	if !stale && !needsMFA {
+		return nil
+	}
+
+	flow := StepUpFlowReauthenticate
+	if IsDelegated(identity) {
+		flow = StepUpFlowReassume
+	} else if needsMFA {
+		flow = StepUpFlowMFA
+	}
+	metadata := map[string]string{
+		"flow":        flow,
+		"require_mfa": strconv.FormatBool(s.RequireMFA),
+	}
+	if s.MaxAuthAge > 0 {
+		metadata["max_auth_age"] = s.MaxAuthAge.String()
+	}
+	return errors.Mark(ErrStepUpRequired, 1).WithDetails(&errdetails.ErrorInfo{
+		Reason:   StepUpReason,
+		Domain:   StepUpDomain,
+		Metadata: metadata,
+	})
+}
+
+// stepUpInterceptor enforces step-up requirements declared via method options.
+// Methods without options are passed through untouched.
+func stepUpInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	s, err := StepUpOptions(info)
+	if err != nil {
+		return nil, err
+	}
+	if s != (StepUp{}) {
+		if err := RequireStepUp(ctx, s); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
@@ -0,0 +1,100 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/auth/stepup.proto
+
+package auth
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_plugins_auth_stepup_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50031,
+		Name:          "prefab.auth.max_auth_age",
+		Tag:           "bytes,50031,opt,name=max_auth_age",
+		Filename:      "plugins/auth/stepup.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50032,
+		Name:          "prefab.auth.require_mfa",
+		Tag:           "varint,50032,opt,name=require_mfa",
+		Filename:      "plugins/auth/stepup.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Maximum age of the login, as a duration such as "15m".
+	//
+	// optional string max_auth_age = 50031;
+	E_MaxAuthAge = &file_plugins_auth_stepup_proto_extTypes[0]
+	// Whether the login must have used multi-factor authentication.
+	//
+	// optional bool require_mfa = 50032;
+	E_RequireMfa = &file_plugins_auth_stepup_proto_extTypes[1]
+)
+
+var File_plugins_auth_stepup_proto protoreflect.FileDescriptor
+
+const file_plugins_auth_stepup_proto_rawDesc = "" +
+	"\n" +
+	"\x19plugins/auth/stepup.proto\x12\vprefab.auth\x1a google/protobuf/descriptor.proto:B\n" +
+	"\fmax_auth_age\x12\x1e.google.protobuf.MethodOptions\x18\xef\x86\x03 \x01(\tR\n" +
+	"maxAuthAge:A\n" +
+	"\vrequire_mfa\x12\x1e.google.protobuf.MethodOptions\x18\xf0\x86\x03 \x01(\bR\n" +
+	"requireMfaB%Z#github.com/dpup/prefab/plugins/authb\x06proto3"
+
+var file_plugins_auth_stepup_proto_goTypes = []any{
+	(*descriptorpb.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
+}
+var file_plugins_auth_stepup_proto_depIdxs = []int32{
+	0, // 0: prefab.auth.max_auth_age:extendee -> google.protobuf.MethodOptions
+	0, // 1: prefab.auth.require_mfa:extendee -> google.protobuf.MethodOptions
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	0, // [0:2] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_plugins_auth_stepup_proto_init() }
+func file_plugins_auth_stepup_proto_init() {
+	if File_plugins_auth_stepup_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_auth_stepup_proto_rawDesc), len(file_plugins_auth_stepup_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 2,
+			NumServices:   0,
+		},
+		GoTypes:           file_plugins_auth_stepup_proto_goTypes,
+		DependencyIndexes: file_plugins_auth_stepup_proto_depIdxs,
+		ExtensionInfos:    file_plugins_auth_stepup_proto_extTypes,
+	}.Build()
+	File_plugins_auth_stepup_proto = out.File
+	file_plugins_auth_stepup_proto_goTypes = nil
+	file_plugins_auth_stepup_proto_depIdxs = nil
+}
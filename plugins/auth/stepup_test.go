@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+)
+
+func stepUpDetail(t *testing.T, err error) *errdetails.ErrorInfo {
+	t.Helper()
+	var e *errors.Error
+	require.ErrorAs(t, err, &e)
+	for _, d := range e.Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	t.Fatal("missing ErrorInfo detail")
+	return nil
+}
+
+func TestRequireStepUp(t *testing.T) {
+	now := time.Now()
+	timeFunc = func() time.Time { return now }
+	defer func() { timeFunc = time.Now }()
+
+	recent := Identity{Provider: "google", Subject: "1", AuthTime: now.Add(-time.Minute)}
+	stale := Identity{Provider: "google", Subject: "1", AuthTime: now.Add(-time.Hour)}
+	mfa := Identity{Provider: "google", Subject: "1", AuthTime: now.Add(-time.Minute), MFA: true}
+	delegated := Identity{Provider: "google", Subject: "1", AuthTime: now.Add(-time.Hour), Delegation: &DelegationInfo{DelegatorSub: "admin", DelegatorProvider: "google", DelegatorSessionId: "s1", Reason: "support", DelegatedAt: now.Unix()}}
+
+	tests := []struct {
+		name     string
+		identity Identity
+		stepUp   StepUp
+		flow     string
+	}{
+		{"recent login", recent, StepUp{MaxAuthAge: 15 * time.Minute}, ""},
+		{"stale login", stale, StepUp{MaxAuthAge: 15 * time.Minute}, StepUpFlowReauthenticate},
+		{"missing mfa", recent, StepUp{RequireMFA: true}, StepUpFlowMFA},
+		{"mfa login", mfa, StepUp{MaxAuthAge: 15 * time.Minute, RequireMFA: true}, ""},
+		{"delegated", delegated, StepUp{MaxAuthAge: 15 * time.Minute}, StepUpFlowReassume},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RequireStepUp(WithIdentityForTest(t.Context(), tt.identity), tt.stepUp)
+			if tt.flow == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrStepUpRequired)
+			info := stepUpDetail(t, err)
+			assert.Equal(t, StepUpReason, info.Reason)
+			assert.Equal(t, tt.flow, info.Metadata["flow"])
+		})
+	}
+}
+
+func TestRequireStepUp_Metadata(t *testing.T) {
+	ctx := WithIdentityForTest(t.Context(), Identity{Provider: "google", Subject: "1", AuthTime: time.Now().Add(-time.Hour)})
+	err := RequireStepUp(ctx, StepUp{MaxAuthAge: 5 * time.Minute, RequireMFA: true})
+	info := stepUpDetail(t, err)
+	assert.Equal(t, map[string]string{
+		"flow":         StepUpFlowMFA,
+		"require_mfa":  "true",
+		"max_auth_age": "5m0s",
+	}, info.Metadata)
+
+	// The sentinel isn't modified.
+	assert.Empty(t, ErrStepUpRequired.Details())
+}
+
+func TestRequireStepUp_Unauthenticated(t *testing.T) {
+	err := RequireStepUp(t.Context(), StepUp{RequireMFA: true})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrStepUpRequired)
+}
+
+func TestStepUpInterceptor_NoOptions(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: AuthService_Identity_FullMethodName}
+	s, err := StepUpOptions(info)
+	require.NoError(t, err)
+	assert.Equal(t, StepUp{}, s)
+
+	// Methods without options don't require an identity.
+	resp, err := stepUpInterceptor(t.Context(), nil, info, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
+
+func TestIdentityToken_MFA(t *testing.T) {
+	token, err := IdentityToken(t.Context(), Identity{Provider: "google", Subject: "1", AuthTime: time.Now(), MFA: true})
+	require.NoError(t, err)
+	identity, err := ParseIdentityToken(t.Context(), token)
+	require.NoError(t, err)
+	assert.True(t, identity.MFA)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// WithTokenCacheSize sets how many verified identity tokens are cached, so that
+// clients which reuse a token skip signature verification. Cached tokens are
+// still checked against the blocklist on every request. Set to 0 to disable.
+//
+// Config key: `auth.tokenCache.size`.
+func WithTokenCacheSize(size int) AuthOption {
+	return func(p *AuthPlugin) {
+		p.tokenCacheSize = size
+	}
+}
+
+type tokenCacheKey struct{}
+
+// withTokenCache adds a token cache to the context.
+func withTokenCache(ctx context.Context, c *tokenCache) context.Context {
+	return context.WithValue(ctx, tokenCacheKey{}, c)
+}
+
+func tokenCacheFromContext(ctx context.Context) *tokenCache {
+	c, _ := ctx.Value(tokenCacheKey{}).(*tokenCache)
+	return c
+}
+
+// tokenCache is a bounded LRU of verified identity tokens, keyed by the token
+// string.
+type tokenCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used at the front.
+}
+
+// cachedToken holds the claims of a verified token, along with the signing key
+// and address it was verified against, so that a token is only trusted under
+// the same configuration.
+type cachedToken struct {
+	token   string
+	key     string
+	address string
+	claims  *Claims
+	expires time.Time // Zero if the token doesn't expire.
+}
+
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{size: size, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// get returns the claims of a verified token, or nil if the token isn't cached,
+// was verified with another key or address, or has expired.
+func (c *tokenCache) get(token, key, address string, now time.Time) *Claims {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[token]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*cachedToken)
+	if e.key != key || e.address != address {
+		return nil
+	}
+	if !e.expires.IsZero() && !now.Before(e.expires) {
+		c.remove(el)
+		return nil
+	}
+	c.order.MoveToFront(el)
+	return e.claims
+}
+
+// add caches the claims of a verified token, evicting the least recently used
+// token if the cache is full.
+func (c *tokenCache) add(token, key, address string, claims *Claims) {
+	e := &cachedToken{token: token, key: key, address: address, claims: claims}
+	if claims.ExpiresAt != nil {
+		e.expires = claims.ExpiresAt.Add(jwtLeeway)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[token]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[token] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate removes the tokens for a session, e.g. when it's blocked.
+func (c *tokenCache) invalidate(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if el.Value.(*cachedToken).claims.ID == sessionID {
+			c.remove(el)
+		}
+		el = next
+	}
+}
+
+func (c *tokenCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cachedToken).token)
+}
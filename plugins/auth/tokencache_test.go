@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenCache_LRU(t *testing.T) {
+	c := newTokenCache(2)
+	now := time.Now()
+
+	c.add("a", "key", "addr", &Claims{})
+	c.add("b", "key", "addr", &Claims{})
+	assert.NotNil(t, c.get("a", "key", "addr", now), "a should be cached")
+
+	// b is the least recently used token.
+	c.add("c", "key", "addr", &Claims{})
+	assert.NotNil(t, c.get("a", "key", "addr", now))
+	assert.Nil(t, c.get("b", "key", "addr", now), "b should be evicted")
+	assert.NotNil(t, c.get("c", "key", "addr", now))
+}
+
+func TestTokenCache_Expiry(t *testing.T) {
+	c := newTokenCache(10)
+	now := time.Now()
+
+	claims := &Claims{}
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(time.Minute))
+	c.add("a", "key", "addr", claims)
+
+	assert.NotNil(t, c.get("a", "key", "addr", now))
+	assert.NotNil(t, c.get("a", "key", "addr", now.Add(time.Minute)), "leeway should apply")
+	assert.Nil(t, c.get("a", "key", "addr", now.Add(time.Minute+jwtLeeway)))
+	assert.Empty(t, c.entries, "expired token should be removed")
+}
+
+func TestTokenCache_KeyAndAddressMismatch(t *testing.T) {
+	c := newTokenCache(10)
+	now := time.Now()
+
+	c.add("a", "key", "addr", &Claims{})
+	assert.Nil(t, c.get("a", "other-key", "addr", now))
+	assert.Nil(t, c.get("a", "key", "other-addr", now))
+	assert.NotNil(t, c.get("a", "key", "addr", now))
+}
+
+func TestTokenCache_Invalidate(t *testing.T) {
+	c := newTokenCache(10)
+	now := time.Now()
+
+	s1 := &Claims{}
+	s1.ID = "session-1"
+	s2 := &Claims{}
+	s2.ID = "session-2"
+	c.add("a", "key", "addr", s1)
+	c.add("b", "key", "addr", s1)
+	c.add("c", "key", "addr", s2)
+
+	c.invalidate("session-1")
+	assert.Nil(t, c.get("a", "key", "addr", now))
+	assert.Nil(t, c.get("b", "key", "addr", now))
+	assert.NotNil(t, c.get("c", "key", "addr", now))
+}
+
+func TestParseIdentityToken_cached(t *testing.T) {
+	cache := newTokenCache(10)
+	blocklist := NewBlocklist(memstore.New())
+	ctx := WithBlockist(withTokenCache(t.Context(), cache), blocklist)
+
+	idt := Identity{
+		SessionID: "12345",
+		Subject:   "4",
+		AuthTime:  jwt.NewNumericDate(time.Now()).Time,
+		Provider:  "test",
+		Groups:    NewGroups("rebels"),
+	}
+	tokenString, err := IdentityToken(ctx, idt)
+	require.NoError(t, err, "failed to issue token")
+
+	parsed, err := ParseIdentityToken(ctx, tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, idt, parsed)
+	assert.Len(t, cache.entries, 1, "token should be cached")
+
+	// Identities from the cache shouldn't share state.
+	require.NoError(t, parsed.SetClaim("ship", "tie-fighter"))
+	parsed, err = ParseIdentityToken(ctx, tokenString)
+	require.NoError(t, err)
+	assert.Equal(t, idt, parsed)
+
+	// Blocking the session discards the cached token.
+	require.NoError(t, MaybeBlock(ctx, "12345"))
+	assert.Empty(t, cache.entries)
+
+	_, err = ParseIdentityToken(ctx, tokenString)
+	require.ErrorIs(t, err, ErrRevoked)
+	assert.Empty(t, cache.entries, "revoked token shouldn't be cached")
+}
+
+func TestParseIdentityToken_cachedBlockedElsewhere(t *testing.T) {
+	cache := newTokenCache(10)
+	blocklist := NewBlocklist(memstore.New())
+	ctx := WithBlockist(withTokenCache(t.Context(), cache), blocklist)
+
+	tokenString, err := IdentityToken(ctx, Identity{SessionID: "12345", Subject: "4", Provider: "test"})
+	require.NoError(t, err, "failed to issue token")
+
+	_, err = ParseIdentityToken(ctx, tokenString)
+	require.NoError(t, err)
+
+	// Block directly, as another instance sharing the store would.
+	require.NoError(t, blocklist.Block(ctx, "12345"))
+
+	_, err = ParseIdentityToken(ctx, tokenString)
+	require.ErrorIs(t, err, ErrRevoked)
+}
+
+func BenchmarkParseIdentityToken(b *testing.B) {
+	for _, size := range []int{0, 100} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			ctx := b.Context()
+			if size > 0 {
+				ctx = withTokenCache(ctx, newTokenCache(size))
+			}
+			tokenString, err := IdentityToken(ctx, Identity{SessionID: "1", Subject: "4", Provider: "test"})
+			require.NoError(b, err)
+
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := ParseIdentityToken(ctx, tokenString); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -79,6 +79,18 @@
 //	  string document_id = 2 [(prefab.authz.id) = true]; // Required to identify resource.
 //	}
 //
+// Collection-level actions, such as create and list, are authorized against a
+// parent loaded by a ParentFetcher:
+//
+//	rpc CreateDocument(CreateDocumentRequest) returns (Document) {
+//	  option (prefab.authz.action) = "documents.create";
+//	  option (prefab.authz.parent) = "folder";
+//	}
+//
+//	message CreateDocumentRequest {
+//	  string folder_id = 1 [(prefab.authz.parent_id) = true];
+//	}
+//
 // # Common Patterns
 //
 // This package provides several common patterns to simplify authorization setup:
@@ -191,6 +203,7 @@ package authz
 
 import (
 	"context"
+	"sync"
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/auth"
@@ -237,6 +250,13 @@ type PolicyEvaluation struct {
 	Effect Effect
 }
 
+// Policy allows or denies a role to perform an action, see DefinePolicy.
+type Policy struct {
+	Effect Effect
+	Role   Role
+	Action Action
+}
+
 // AuthzDecision captures the complete authorization decision context.
 // This is useful for debugging, audit logging, and providing detailed error messages.
 type AuthzDecision struct {
@@ -251,6 +271,8 @@ type AuthzDecision struct {
 	DefaultEffect     Effect
 	Reason            string
 	EvaluatedPolicies []PolicyEvaluation
+	Parent            bool                   // Whether Resource and ObjectID identify the parent of a collection
+	Request           serverutil.RequestInfo // Client details, see serverutil.RequestInfoFromContext
 }
 
 // scopeValidationKey is the context key for tracking scope validation.
@@ -342,8 +364,12 @@ type TypedObjectFetcher[K comparable, T any] func(ctx context.Context, key K) (T
 type TypedRoleDescriber[T any] func(ctx context.Context, subject auth.Identity, object T, scope Scope) ([]Role, error)
 
 // MethodOptions returns Authz related method options from the method descriptor.
-// associated with the given info..
+// associated with the given info. Options are cached per method.
 func MethodOptions(info *grpc.UnaryServerInfo) (objectKey string, action Action, defaultEffect Effect) {
+	if v, ok := methodSpecs.Load(info.FullMethod); ok {
+		spec := v.(methodSpec)
+		return spec.objectKey, spec.action, spec.defaultEffect
+	}
 	if v, ok := serverutil.MethodOption(info, E_Resource); ok {
 		objectKey = v.(string)
 	} else {
@@ -363,9 +389,20 @@ func MethodOptions(info *grpc.UnaryServerInfo) (objectKey string, action Action,
 			defaultEffect = Deny
 		}
 	}
+	methodSpecs.Store(info.FullMethod, methodSpec{objectKey: objectKey, action: action, defaultEffect: defaultEffect})
 	return
 }
 
+// methodSpecs caches the authz options of each method, keyed by full method
+// name.
+var methodSpecs sync.Map
+
+type methodSpec struct {
+	objectKey     string
+	action        Action
+	defaultEffect Effect
+}
+
 // FieldOptions returns proto fields that are tagged with Authz related options.
 // It returns the object ID and scope string.
 func FieldOptions(req proto.Message) (any, string, error) {
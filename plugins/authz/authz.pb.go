@@ -46,6 +46,14 @@ var file_plugins_authz_authz_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,50013,opt,name=default_effect",
 		Filename:      "plugins/authz/authz.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50014,
+		Name:          "prefab.authz.parent",
+		Tag:           "bytes,50014,opt,name=parent",
+		Filename:      "plugins/authz/authz.proto",
+	},
 	{
 		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
 		ExtensionType: (*bool)(nil),
@@ -70,6 +78,22 @@ var file_plugins_authz_authz_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "varint,50023,opt,name=scope",
 		Filename:      "plugins/authz/authz.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50024,
+		Name:          "prefab.authz.self",
+		Tag:           "varint,50024,opt,name=self",
+		Filename:      "plugins/authz/authz.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50025,
+		Name:          "prefab.authz.parent_id",
+		Tag:           "varint,50025,opt,name=parent_id",
+		Filename:      "plugins/authz/authz.proto",
+	},
 }
 
 // Extension fields to descriptorpb.MethodOptions.
@@ -80,16 +104,32 @@ var (
 	E_Resource = &file_plugins_authz_authz_proto_extTypes[1]
 	// optional string default_effect = 50013;
 	E_DefaultEffect = &file_plugins_authz_authz_proto_extTypes[2]
+	// Marks a collection-level action, such as create or list, which is
+	// authorized against the parent resource (e.g. a folder) rather than an
+	// existing object. The value is the key of the parent's ParentFetcher.
+	//
+	// optional string parent = 50014;
+	E_Parent = &file_plugins_authz_authz_proto_extTypes[3]
 )
 
 // Extension fields to descriptorpb.FieldOptions.
 var (
 	// optional bool id = 50021;
-	E_Id = &file_plugins_authz_authz_proto_extTypes[3]
+	E_Id = &file_plugins_authz_authz_proto_extTypes[4]
 	// optional bool domain = 50022;
-	E_Domain = &file_plugins_authz_authz_proto_extTypes[4] // Deprecated: use scope instead
+	E_Domain = &file_plugins_authz_authz_proto_extTypes[5] // Deprecated: use scope instead
 	// optional bool scope = 50023;
-	E_Scope = &file_plugins_authz_authz_proto_extTypes[5]
+	E_Scope = &file_plugins_authz_authz_proto_extTypes[6]
+	// Marks a field which must equal the caller's subject, e.g. a user_id, so
+	// that callers can only act on themselves.
+	//
+	// optional bool self = 50024;
+	E_Self = &file_plugins_authz_authz_proto_extTypes[7]
+	// Marks the field containing the parent identifier for methods with the
+	// `parent` option.
+	//
+	// optional bool parent_id = 50025;
+	E_ParentId = &file_plugins_authz_authz_proto_extTypes[8]
 )
 
 var File_plugins_authz_authz_proto protoreflect.FileDescriptor
@@ -99,10 +139,13 @@ const file_plugins_authz_authz_proto_rawDesc = "" +
 	"\x19plugins/authz/authz.proto\x12\fprefab.authz\x1a google/protobuf/descriptor.proto:8\n" +
 	"\x06action\x12\x1e.google.protobuf.MethodOptions\x18ۆ\x03 \x01(\tR\x06action:<\n" +
 	"\bresource\x12\x1e.google.protobuf.MethodOptions\x18܆\x03 \x01(\tR\bresource:G\n" +
-	"\x0edefault_effect\x12\x1e.google.protobuf.MethodOptions\x18݆\x03 \x01(\tR\rdefaultEffect:/\n" +
+	"\x0edefault_effect\x12\x1e.google.protobuf.MethodOptions\x18݆\x03 \x01(\tR\rdefaultEffect:8\n" +
+	"\x06parent\x12\x1e.google.protobuf.MethodOptions\x18ކ\x03 \x01(\tR\x06parent:/\n" +
 	"\x02id\x12\x1d.google.protobuf.FieldOptions\x18\xe5\x86\x03 \x01(\bR\x02id:7\n" +
 	"\x06domain\x12\x1d.google.protobuf.FieldOptions\x18\xe6\x86\x03 \x01(\bR\x06domain:5\n" +
-	"\x05scope\x12\x1d.google.protobuf.FieldOptions\x18\xe7\x86\x03 \x01(\bR\x05scopeB&Z$github.com/dpup/prefab/plugins/authzb\x06proto3"
+	"\x05scope\x12\x1d.google.protobuf.FieldOptions\x18\xe7\x86\x03 \x01(\bR\x05scope:3\n" +
+	"\x04self\x12\x1d.google.protobuf.FieldOptions\x18\xe8\x86\x03 \x01(\bR\x04self:<\n" +
+	"\tparent_id\x12\x1d.google.protobuf.FieldOptions\x18\xe9\x86\x03 \x01(\bR\bparentIdB&Z$github.com/dpup/prefab/plugins/authzb\x06proto3"
 
 var file_plugins_authz_authz_proto_goTypes = []any{
 	(*descriptorpb.MethodOptions)(nil), // 0: google.protobuf.MethodOptions
@@ -112,13 +155,16 @@ var file_plugins_authz_authz_proto_depIdxs = []int32{
 	0, // 0: prefab.authz.action:extendee -> google.protobuf.MethodOptions
 	0, // 1: prefab.authz.resource:extendee -> google.protobuf.MethodOptions
 	0, // 2: prefab.authz.default_effect:extendee -> google.protobuf.MethodOptions
-	1, // 3: prefab.authz.id:extendee -> google.protobuf.FieldOptions
-	1, // 4: prefab.authz.domain:extendee -> google.protobuf.FieldOptions
-	1, // 5: prefab.authz.scope:extendee -> google.protobuf.FieldOptions
-	6, // [6:6] is the sub-list for method output_type
-	6, // [6:6] is the sub-list for method input_type
-	6, // [6:6] is the sub-list for extension type_name
-	0, // [0:6] is the sub-list for extension extendee
+	0, // 3: prefab.authz.parent:extendee -> google.protobuf.MethodOptions
+	1, // 4: prefab.authz.id:extendee -> google.protobuf.FieldOptions
+	1, // 5: prefab.authz.domain:extendee -> google.protobuf.FieldOptions
+	1, // 6: prefab.authz.scope:extendee -> google.protobuf.FieldOptions
+	1, // 7: prefab.authz.self:extendee -> google.protobuf.FieldOptions
+	1, // 8: prefab.authz.parent_id:extendee -> google.protobuf.FieldOptions
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	0, // [0:9] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
 }
 
@@ -134,7 +180,7 @@ func file_plugins_authz_authz_proto_init() {
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_authz_authz_proto_rawDesc), len(file_plugins_authz_authz_proto_rawDesc)),
 			NumEnums:      0,
 			NumMessages:   0,
-			NumExtensions: 6,
+			NumExtensions: 9,
 			NumServices:   0,
 		},
 		GoTypes:           file_plugins_authz_authz_proto_goTypes,
@@ -3,16 +3,20 @@ package authz
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"slices"
+	"strings"
 
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/logging"
 	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/serverutil"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // Constant name for identifying the core Authz plugin.
@@ -90,14 +94,18 @@ func Plugin(opts ...AuthzOption) *AuthzPlugin {
 	return ap
 }
 
-// WithDebugEndpoint enables the /debug/authz HTTP endpoint, which renders the
-// full role hierarchy and policy set as plaintext. It is disabled by default
-// because it exposes the authorization model with no access control; only
-// enable it in trusted environments (e.g. behind a private network or an
-// authenticating proxy).
+// WithDebugEndpoint enables the /debug/authz HTTP endpoint, an interactive page
+// describing the role hierarchy, policies, and registrations, and
+// /debug/authz/explain, which explains decisions for any identity, see
+// ExplainHandler.
+//
+// Callers must be allowed ActionDebug, with roles described relative to
+// DebugObjectKey, for example with WithGroupRoles or a '*' role describer.
+// Without such a policy the endpoints are unavailable.
 func WithDebugEndpoint() AuthzOption {
 	return func(ap *AuthzPlugin) {
 		ap.debugEnabled = true
+		ap.RegisterObjectFetcher(DebugObjectKey, ObjectFetcherFn(ap.fetchDebugObject))
 	}
 }
 
@@ -177,14 +185,36 @@ func WithAuditLogger(logger AuditLogger) AuthzOption {
 // AuditLogger is a function that receives authorization decisions for audit logging.
 type AuditLogger func(ctx context.Context, decision AuthzDecision)
 
+// AddAuditLogger adds an audit logger which is called after any existing
+// loggers. This allows other plugins, such as audit, to receive decisions
+// without replacing a logger configured with WithAuditLogger.
+func (ap *AuthzPlugin) AddAuditLogger(logger AuditLogger) {
+	prev := ap.auditLogger
+	if prev == nil {
+		ap.auditLogger = logger
+		return
+	}
+	ap.auditLogger = func(ctx context.Context, decision AuthzDecision) {
+		prev(ctx, decision)
+		logger(ctx, decision)
+	}
+}
+
 // AuthzPlugin provides functionality for authorizing requests and access to resources.
 type AuthzPlugin struct {
-	policies       map[Action]map[Role]Effect
-	objectFetchers map[string]ObjectFetcher
-	roleDescribers map[string]RoleDescriber
-	roleParents    map[Role]Role
-	auditLogger    AuditLogger
-	debugEnabled   bool
+	policies        map[Action]map[Role]Effect
+	objectFetchers  map[string]ObjectFetcher
+	parentFetchers  map[string]ParentFetcher
+	responseFilters map[protoreflect.FullName][]ResponseFilter
+	roleDescribers  map[string]RoleDescriber
+	groupRoles      map[string]GroupMapping
+	roleParents     map[Role]Role
+	auditLogger     AuditLogger
+	debugEnabled    bool
+	requiredScopes  map[Action]string
+	scopeProvider   OAuthScopeProvider
+
+	denyDelegatedSelf bool
 }
 
 // From plugin.Plugin.
@@ -203,13 +233,21 @@ func (ap *AuthzPlugin) Deps() []string {
 // is only registered when explicitly enabled via WithDebugEndpoint, since it
 // would otherwise leak the authorization model to any unauthenticated caller.
 func (ap *AuthzPlugin) ServerOptions() []prefab.ServerOption {
-	opts := []prefab.ServerOption{
-		prefab.WithGRPCInterceptor(ap.Interceptor),
+	return []prefab.ServerOption{
+		prefab.WithGRPCInterceptor(ap.Interceptor, prefab.InterceptorPhase(prefab.PhaseAuthz)),
 	}
+}
+
+// From prefab.AdminOptionProvider, registers the debug and explain handlers if
+// enabled. Both require ActionDebug.
+func (ap *AuthzPlugin) AdminOptions() []prefab.ServerOption {
 	if ap.debugEnabled {
-		opts = append(opts, prefab.WithHTTPHandlerFunc("/debug/authz", ap.DebugHandler))
+		return []prefab.ServerOption{
+			prefab.WithAdminHTTPHandler("/debug/authz", ap.debugMiddleware(ap.DebugHandler)),
+			prefab.WithAdminHTTPHandler("/debug/authz/explain", ap.debugMiddleware(ap.ExplainHandler)),
+		}
 	}
-	return opts
+	return nil
 }
 
 // DefinePolicy defines an policy which allows/denies the given role to perform
@@ -224,6 +262,23 @@ func (ap *AuthzPlugin) DefinePolicy(effect Effect, role Role, action Action) {
 	ap.policies[action][role] = effect
 }
 
+// Policies returns the defined policies, sorted by action and role.
+func (ap *AuthzPlugin) Policies() []Policy {
+	var policies []Policy
+	for action, roles := range ap.policies {
+		for role, effect := range roles {
+			policies = append(policies, Policy{Effect: effect, Role: role, Action: action})
+		}
+	}
+	slices.SortFunc(policies, func(a, b Policy) int {
+		if c := strings.Compare(string(a.Action), string(b.Action)); c != 0 {
+			return c
+		}
+		return strings.Compare(string(a.Role), string(b.Role))
+	})
+	return policies
+}
+
 // RegisterObjectFetcher registers an object fetcher for a specified object key.
 // '*' can be used as a wildcard to match any key which doesn't have a more specific fetcher.
 func (ap *AuthzPlugin) RegisterObjectFetcher(objectKey string, fetcher ObjectFetcher) {
@@ -311,9 +366,43 @@ func (ap *AuthzPlugin) describerForKey(objectKey string) RoleDescriber {
 // 3. Fetches the object based on the object key and id (ObjectFetcher).
 // 4. Gets the user's role relative to the object (RoleDescriber).
 // 5. Checks if the role can perform the action on the object.
+//
+// Collection-level methods, such as create and list, are tagged with the
+// `parent` option. The parent identified by the `parent_id` field is fetched
+// with a ParentFetcher and roles are described relative to the parent instead.
+//
+// Requests with a field tagged with the `self` option are allowed when the
+// field matches the caller's subject. Otherwise the method's policies are
+// checked, so that, for example, admins can act on other users. Without an
+// action the request is denied.
+//
+// Registered response filters are applied to the handler's response.
 func (ap *AuthzPlugin) Interceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	if len(ap.responseFilters) > 0 {
+		handler = ap.filterResponses(handler)
+	}
+
 	// Get the Authz spec from the method descriptor.
 	objectKey, action, defaultEffect := MethodOptions(info)
+
+	self, hasSelf, err := SelfField(req.(proto.Message))
+	if err != nil {
+		return nil, err
+	}
+	if hasSelf {
+		// OAuth clients must still hold the scope required for the action.
+		if err := ap.checkRequiredScope(ctx, AuthorizeParams{ObjectKey: objectKey, ObjectID: self, Action: action}); err != nil {
+			return nil, err
+		}
+		err := ap.authorizeSelf(ctx, self, action)
+		if err == nil {
+			return handler(ctx, req)
+		}
+		if action == "" {
+			return nil, err
+		}
+	}
+
 	if action == "" {
 		// No policies to enforce.
 		return handler(ctx, req)
@@ -325,12 +414,22 @@ func (ap *AuthzPlugin) Interceptor(ctx context.Context, req interface{}, info *g
 		return nil, err
 	}
 
+	// Collection-level methods are authorized against the parent.
+	parentKey, isParent := MethodParent(info)
+	if isParent {
+		objectKey = parentKey
+		if objectID, err = ParentField(req.(proto.Message)); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := ap.Authorize(ctx, AuthorizeParams{
 		ObjectKey:     objectKey,
 		ObjectID:      objectID,
 		Scope:         Scope(scopeStr),
 		Action:        action,
 		DefaultEffect: defaultEffect,
+		Parent:        isParent,
 		Info:          info.FullMethod,
 	}); err != nil {
 		return nil, err
@@ -339,6 +438,51 @@ func (ap *AuthzPlugin) Interceptor(ctx context.Context, req interface{}, info *g
 	return handler(ctx, req)
 }
 
+// Middleware returns HTTP middleware which authorizes the action on the object
+// identified by the route's {id} wildcard, for use with prefab.WithRoute. Access
+// is denied unless a policy grants it.
+func (ap *AuthzPlugin) Middleware(objectKey string, action Action) prefab.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var objectID any
+			if id := r.PathValue("id"); id != "" {
+				objectID = id
+			}
+			if err := ap.Authorize(r.Context(), AuthorizeParams{
+				ObjectKey:     objectKey,
+				ObjectID:      objectID,
+				Action:        action,
+				DefaultEffect: Deny,
+				Info:          r.Pattern,
+			}); err != nil {
+				prefab.WriteJSONError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthorizeSSE returns an option for prefab.WithSSEStream which authorizes the
+// action before the stream is started. The object is identified by the path
+// parameter named param, e.g. "id" for "/notes/{id}/updates", or by nothing
+// if param is empty. Access is denied unless a policy grants it.
+func (ap *AuthzPlugin) AuthorizeSSE(objectKey string, action Action, param string) prefab.SSEOption {
+	return prefab.WithSSEGuard(func(ctx context.Context, params map[string]string) (func(context.Context) error, error) {
+		var objectID any
+		if id := params[param]; param != "" && id != "" {
+			objectID = id
+		}
+		return nil, ap.Authorize(ctx, AuthorizeParams{
+			ObjectKey:     objectKey,
+			ObjectID:      objectID,
+			Action:        action,
+			DefaultEffect: Deny,
+			Info:          "SSE " + objectKey,
+		})
+	})
+}
+
 // Parameters for the Authorize method.
 type AuthorizeParams struct {
 	ObjectKey     string
@@ -346,26 +490,26 @@ type AuthorizeParams struct {
 	Scope         Scope
 	Action        Action
 	DefaultEffect Effect
+	Parent        bool // ObjectKey and ObjectID identify the parent of a collection
 	Info          string
 }
 
 // Authorize takes the configuration and verifies that the caller is authorized
 // to perform the action on the object.
 func (ap *AuthzPlugin) Authorize(ctx context.Context, cfg AuthorizeParams) error {
-	if ap.policies[cfg.Action] == nil {
-		return errors.Codef(codes.Internal, "authz error: no policies configured for '%s' on %s", cfg.Action, cfg.Info)
-	}
-	fetcher := ap.fetcherForKey(cfg.ObjectKey)
-	if fetcher == nil {
-		return errors.Codef(codes.Internal, "authz error: no object fetcher for key '%s' on %s", cfg.ObjectKey, cfg.Info)
+	fetch, err := ap.fetcherForParams(cfg)
+	if err != nil {
+		return err
 	}
-	describer := ap.describerForKey(cfg.ObjectKey)
-	if describer == nil {
-		return errors.Codef(codes.Internal, "authz error: no role describer for key '%s' on %s", cfg.ObjectKey, cfg.Info)
+
+	// OAuth clients must hold the scope required for the action, if any,
+	// regardless of the roles their user has.
+	if err := ap.checkRequiredScope(ctx, cfg); err != nil {
+		return err
 	}
 
-	// Fetch the object that the action is being performed on.
-	object, err := fetcher.FetchObject(ctx, cfg.ObjectID)
+	// Fetch the object, or parent, that the action is being performed on.
+	object, err := fetch(ctx, cfg.ObjectID)
 	if err != nil {
 		return err
 	}
@@ -385,7 +529,7 @@ func (ap *AuthzPlugin) Authorize(ctx context.Context, cfg AuthorizeParams) error
 	}
 
 	// Get the user's roles relative to the object.
-	roles, err := describer.DescribeRoles(ctx, identity, object, cfg.Scope)
+	roles, err := ap.describeRoles(ctx, cfg.ObjectKey, identity, object, cfg.Scope)
 	if err != nil {
 		logging.Track(ctx, "authz.reason", "failed to describe roles")
 		return err
@@ -396,6 +540,9 @@ func (ap *AuthzPlugin) Authorize(ctx context.Context, cfg AuthorizeParams) error
 	logging.Track(ctx, "authz.object", object)
 	logging.Track(ctx, "authz.scope", cfg.Scope)
 	logging.Track(ctx, "authz.roles", roles)
+	if cfg.Parent {
+		logging.Track(ctx, "authz.parent", true)
+	}
 
 	// Determine the authorization effect and track which policies were evaluated
 	finalEffect, evaluatedPolicies := ap.DetermineEffect(cfg.Action, roles, cfg.DefaultEffect)
@@ -414,7 +561,9 @@ func (ap *AuthzPlugin) Authorize(ctx context.Context, cfg AuthorizeParams) error
 		Effect:            finalEffect,
 		DefaultEffect:     cfg.DefaultEffect,
 		EvaluatedPolicies: evaluatedPolicies,
+		Parent:            cfg.Parent,
 	}
+	decision.Request, _ = serverutil.RequestInfoFromContext(ctx)
 
 	if finalEffect == Allow {
 		return ap.handleAllowed(ctx, decision)
@@ -423,6 +572,30 @@ func (ap *AuthzPlugin) Authorize(ctx context.Context, cfg AuthorizeParams) error
 	return ap.handleDenied(ctx, decision, roles, evaluatedPolicies, cfg.Action, defaultError)
 }
 
+// fetcherForParams verifies that policies, a fetcher, and a source of roles are
+// configured for the params, and returns the fetch function to use.
+func (ap *AuthzPlugin) fetcherForParams(cfg AuthorizeParams) (ObjectFetcherFn, error) {
+	if ap.policies[cfg.Action] == nil {
+		return nil, errors.Codef(codes.Internal, "authz error: no policies configured for '%s' on %s", cfg.Action, cfg.Info)
+	}
+	kind, fetch := "object", ObjectFetcherFn(nil)
+	if cfg.Parent {
+		kind = "parent"
+		if fetcher := ap.parentFetcherForKey(cfg.ObjectKey); fetcher != nil {
+			fetch = fetcher.FetchParent
+		}
+	} else if fetcher := ap.fetcherForKey(cfg.ObjectKey); fetcher != nil {
+		fetch = fetcher.FetchObject
+	}
+	if fetch == nil {
+		return nil, errors.Codef(codes.Internal, "authz error: no %s fetcher for key '%s' on %s", kind, cfg.ObjectKey, cfg.Info)
+	}
+	if !ap.hasRoleSource(cfg.ObjectKey) {
+		return nil, errors.Codef(codes.Internal, "authz error: no role describer for key '%s' on %s", cfg.ObjectKey, cfg.Info)
+	}
+	return fetch, nil
+}
+
 // handleAllowed processes an allowed authorization decision.
 func (ap *AuthzPlugin) handleAllowed(ctx context.Context, decision AuthzDecision) error {
 	decision.Reason = "allowed by policy"
@@ -2,17 +2,23 @@ package authz_test
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
+	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/auth"
 	"github.com/dpup/prefab/plugins/authz"
 	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/dpup/prefab/prefabtest"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestAuthzPlugin_determineEffect(t *testing.T) {
@@ -486,3 +492,220 @@ func TestWithAuditLogger(t *testing.T) {
 		t.Error("decision.EvaluatedPolicies is empty, expected at least one")
 	}
 }
+
+func TestAddAuditLogger(t *testing.T) {
+	var calls []string
+
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.Role("viewer"), authz.Action("read")),
+		authz.WithAuditLogger(func(ctx context.Context, decision authz.AuthzDecision) {
+			calls = append(calls, "configured")
+		}),
+		authz.WithObjectFetcher("test", authz.AsObjectFetcher(
+			authz.MapFetcher(map[string]*testDocument{
+				"1": {id: "1", author: "alice", title: "Test", body: "test"},
+			}),
+		)),
+		authz.WithRoleDescriberFn("test", func(_ context.Context, _ auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			return nil, nil
+		}),
+	)
+	ap.AddAuditLogger(func(ctx context.Context, decision authz.AuthzDecision) {
+		calls = append(calls, "added")
+	})
+
+	ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Subject: "user1", Provider: "test"})
+	err := ap.Authorize(ctx, authz.AuthorizeParams{
+		ObjectKey:     "test",
+		ObjectID:      "1",
+		Action:        authz.Action("read"),
+		DefaultEffect: authz.Deny,
+		Info:          "test",
+	})
+	require.Error(t, err)
+	assert.Equal(t, []string{"configured", "added"}, calls)
+}
+
+type testScopeProvider struct{}
+
+func (testScopeProvider) Name() string { return "testscopes" }
+
+func (testScopeProvider) OAuthScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(testScopesKey{}).([]string)
+	return scopes, ok
+}
+
+type testScopesKey struct{}
+
+func TestWithRequiredScope(t *testing.T) {
+	var auditedDecisions []authz.AuthzDecision
+
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.Role("editor"), authz.Action("documents.write")),
+		authz.WithRequiredScope("documents.write", "write"),
+		authz.WithOAuthScopeProvider(testScopeProvider{}),
+		authz.WithAuditLogger(func(ctx context.Context, decision authz.AuthzDecision) {
+			auditedDecisions = append(auditedDecisions, decision)
+		}),
+		authz.WithObjectFetcher("test", authz.AsObjectFetcher(
+			authz.MapFetcher(map[string]*testDocument{
+				"1": {id: "1", author: "alice", title: "Test", body: "test"},
+			}),
+		)),
+		authz.WithRoleDescriber("test", authz.Compose(
+			authz.StaticRole(authz.Role("editor"), func(_ context.Context, _ auth.Identity, _ *testDocument, _ authz.Scope) bool {
+				return true
+			}),
+		)),
+	)
+
+	authorize := func(ctx context.Context) error {
+		return ap.Authorize(ctx, authz.AuthorizeParams{
+			ObjectKey:     "test",
+			ObjectID:      "1",
+			Action:        authz.Action("documents.write"),
+			DefaultEffect: authz.Deny,
+			Info:          "test",
+		})
+	}
+
+	ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Subject: "user1", Provider: "test"})
+
+	t.Run("FirstPartyRequest", func(t *testing.T) {
+		require.NoError(t, authorize(ctx))
+	})
+
+	t.Run("OAuthWithScope", func(t *testing.T) {
+		oauthCtx := context.WithValue(ctx, testScopesKey{}, []string{"read", "write"})
+		require.NoError(t, authorize(oauthCtx))
+	})
+
+	t.Run("OAuthMissingScope", func(t *testing.T) {
+		auditedDecisions = nil
+		oauthCtx := context.WithValue(ctx, testScopesKey{}, []string{"read"})
+		err := authorize(oauthCtx)
+		require.Error(t, err)
+		assert.Equal(t, codes.PermissionDenied, errors.Code(err))
+		var perr *errors.Error
+		require.True(t, errors.As(err, &perr))
+		assert.Contains(t, perr.UserPresentableMessage(), `"write"`)
+
+		require.Len(t, auditedDecisions, 1)
+		assert.Equal(t, authz.Deny, auditedDecisions[0].Effect)
+		assert.Equal(t, "missing oauth scope", auditedDecisions[0].Reason)
+	})
+
+	t.Run("ActionWithoutRequiredScope", func(t *testing.T) {
+		ap.DefinePolicy(authz.Allow, authz.Role("editor"), authz.Action("documents.read"))
+		oauthCtx := context.WithValue(ctx, testScopesKey{}, []string{})
+		err := ap.Authorize(oauthCtx, authz.AuthorizeParams{
+			ObjectKey:     "test",
+			ObjectID:      "1",
+			Action:        authz.Action("documents.read"),
+			DefaultEffect: authz.Deny,
+			Info:          "test",
+		})
+		require.NoError(t, err)
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.Role("author"), authz.Action("documents.view")),
+		authz.WithObjectFetcherFn("document", func(ctx context.Context, key any) (any, error) {
+			if key != "1" {
+				return nil, errors.Codef(codes.NotFound, "document not found")
+			}
+			return &testDocument{id: "1", author: "bob@test.com"}, nil
+		}),
+		authz.WithRoleDescriberFn("document", func(ctx context.Context, subject auth.Identity, object any, scope authz.Scope) ([]authz.Role, error) {
+			if subject.Email == object.(*testDocument).author {
+				return []authz.Role{"author"}, nil
+			}
+			return nil, nil
+		}),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /documents/{id}", ap.Middleware("document", "documents.view")(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	serve := func(email, path string) int {
+		ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Email: email, Provider: "test"})
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("bob@test.com", "/documents/1"))
+	assert.Equal(t, http.StatusForbidden, serve("betty@test.com", "/documents/1"))
+	assert.Equal(t, http.StatusNotFound, serve("bob@test.com", "/documents/2"))
+}
+
+// oneMessageStream is a client stream which sends a single message.
+type oneMessageStream struct {
+	grpc.ClientStream
+	sent bool
+}
+
+func (s *oneMessageStream) Recv() (*wrapperspb.StringValue, error) {
+	if s.sent {
+		return nil, io.EOF
+	}
+	s.sent = true
+	return wrapperspb.String("hello"), nil
+}
+
+func TestAuthorizeSSE(t *testing.T) {
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.Role("author"), authz.Action("documents.view")),
+		authz.WithObjectFetcherFn("document", func(ctx context.Context, key any) (any, error) {
+			if key != "1" {
+				return nil, errors.Codef(codes.NotFound, "document not found")
+			}
+			return &testDocument{id: "1", author: "bob@test.com"}, nil
+		}),
+		authz.WithRoleDescriberFn("document", func(ctx context.Context, subject auth.Identity, object any, scope authz.Scope) ([]authz.Role, error) {
+			if subject.Email == object.(*testDocument).author {
+				return []authz.Role{"author"}, nil
+			}
+			return nil, nil
+		}),
+	)
+	srv := prefabtest.New(t,
+		prefabtest.WithAuth(),
+		prefabtest.WithPlugins(ap),
+		prefabtest.WithOptions(prefab.WithSSEStream("/documents/{id}/updates",
+			func(context.Context, map[string]string, grpc.ClientConnInterface) (prefab.ClientStream[*wrapperspb.StringValue], error) {
+				return &oneMessageStream{}, nil
+			},
+			auth.RequireSSEIdentity(),
+			ap.AuthorizeSSE("document", "documents.view", "id"),
+		)),
+	)
+
+	get := func(email, path string) (int, string) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL(path), nil)
+		require.NoError(t, err)
+		if email != "" {
+			srv.AuthRequest(req, auth.Identity{Email: email, Provider: "test", Subject: email})
+		}
+		resp, err := srv.HTTPClient().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("bob@test.com", "/documents/1/updates")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "data: \"hello\"\n\n", body)
+
+	code, _ = get("", "/documents/1/updates")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = get("betty@test.com", "/documents/1/updates")
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = get("bob@test.com", "/documents/2/updates")
+	assert.Equal(t, http.StatusNotFound, code)
+}
@@ -111,6 +111,50 @@ func (x *Response) GetSuccess() bool {
 	return false
 }
 
+type ProfileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProfileRequest) Reset() {
+	*x = ProfileRequest{}
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProfileRequest) ProtoMessage() {}
+
+func (x *ProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProfileRequest.ProtoReflect.Descriptor instead.
+func (*ProfileRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ProfileRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
 type GetDocumentRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	OrgId         string                 `protobuf:"bytes,1,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
@@ -121,7 +165,7 @@ type GetDocumentRequest struct {
 
 func (x *GetDocumentRequest) Reset() {
 	*x = GetDocumentRequest{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[2]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -133,7 +177,7 @@ func (x *GetDocumentRequest) String() string {
 func (*GetDocumentRequest) ProtoMessage() {}
 
 func (x *GetDocumentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[2]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -146,7 +190,7 @@ func (x *GetDocumentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDocumentRequest.ProtoReflect.Descriptor instead.
 func (*GetDocumentRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{2}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *GetDocumentRequest) GetOrgId() string {
@@ -174,7 +218,7 @@ type GetDocumentResponse struct {
 
 func (x *GetDocumentResponse) Reset() {
 	*x = GetDocumentResponse{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[3]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -186,7 +230,7 @@ func (x *GetDocumentResponse) String() string {
 func (*GetDocumentResponse) ProtoMessage() {}
 
 func (x *GetDocumentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[3]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -199,7 +243,7 @@ func (x *GetDocumentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetDocumentResponse.ProtoReflect.Descriptor instead.
 func (*GetDocumentResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{3}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *GetDocumentResponse) GetId() string {
@@ -235,7 +279,7 @@ type SaveDocumentRequest struct {
 
 func (x *SaveDocumentRequest) Reset() {
 	*x = SaveDocumentRequest{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[4]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -247,7 +291,7 @@ func (x *SaveDocumentRequest) String() string {
 func (*SaveDocumentRequest) ProtoMessage() {}
 
 func (x *SaveDocumentRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[4]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -260,7 +304,7 @@ func (x *SaveDocumentRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SaveDocumentRequest.ProtoReflect.Descriptor instead.
 func (*SaveDocumentRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{4}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *SaveDocumentRequest) GetOrgId() string {
@@ -291,6 +335,66 @@ func (x *SaveDocumentRequest) GetBody() string {
 	return ""
 }
 
+type CreateDocumentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	OrgId         string                 `protobuf:"bytes,1,opt,name=org_id,json=orgId,proto3" json:"org_id,omitempty"`
+	FolderId      string                 `protobuf:"bytes,2,opt,name=folder_id,json=folderId,proto3" json:"folder_id,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateDocumentRequest) Reset() {
+	*x = CreateDocumentRequest{}
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateDocumentRequest) ProtoMessage() {}
+
+func (x *CreateDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateDocumentRequest.ProtoReflect.Descriptor instead.
+func (*CreateDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CreateDocumentRequest) GetOrgId() string {
+	if x != nil {
+		return x.OrgId
+	}
+	return ""
+}
+
+func (x *CreateDocumentRequest) GetFolderId() string {
+	if x != nil {
+		return x.FolderId
+	}
+	return ""
+}
+
+func (x *CreateDocumentRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
 type SaveDocumentResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -302,7 +406,7 @@ type SaveDocumentResponse struct {
 
 func (x *SaveDocumentResponse) Reset() {
 	*x = SaveDocumentResponse{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[5]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -314,7 +418,7 @@ func (x *SaveDocumentResponse) String() string {
 func (*SaveDocumentResponse) ProtoMessage() {}
 
 func (x *SaveDocumentResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[5]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -327,7 +431,7 @@ func (x *SaveDocumentResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SaveDocumentResponse.ProtoReflect.Descriptor instead.
 func (*SaveDocumentResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{5}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *SaveDocumentResponse) GetId() string {
@@ -360,7 +464,7 @@ type ListDocumentsRequest struct {
 
 func (x *ListDocumentsRequest) Reset() {
 	*x = ListDocumentsRequest{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[6]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[8]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -372,7 +476,7 @@ func (x *ListDocumentsRequest) String() string {
 func (*ListDocumentsRequest) ProtoMessage() {}
 
 func (x *ListDocumentsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[6]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[8]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -385,7 +489,7 @@ func (x *ListDocumentsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDocumentsRequest.ProtoReflect.Descriptor instead.
 func (*ListDocumentsRequest) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{6}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ListDocumentsRequest) GetOrgId() string {
@@ -398,13 +502,14 @@ func (x *ListDocumentsRequest) GetOrgId() string {
 type ListDocumentsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	DocumentIds   []string               `protobuf:"bytes,1,rep,name=document_ids,json=documentIds,proto3" json:"document_ids,omitempty"`
+	Documents     []*GetDocumentResponse `protobuf:"bytes,2,rep,name=documents,proto3" json:"documents,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ListDocumentsResponse) Reset() {
 	*x = ListDocumentsResponse{}
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[7]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[9]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -416,7 +521,7 @@ func (x *ListDocumentsResponse) String() string {
 func (*ListDocumentsResponse) ProtoMessage() {}
 
 func (x *ListDocumentsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[7]
+	mi := &file_plugins_authz_authztest_acltest_proto_msgTypes[9]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -429,7 +534,7 @@ func (x *ListDocumentsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListDocumentsResponse.ProtoReflect.Descriptor instead.
 func (*ListDocumentsResponse) Descriptor() ([]byte, []int) {
-	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{7}
+	return file_plugins_authz_authztest_acltest_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *ListDocumentsResponse) GetDocumentIds() []string {
@@ -439,6 +544,13 @@ func (x *ListDocumentsResponse) GetDocumentIds() []string {
 	return nil
 }
 
+func (x *ListDocumentsResponse) GetDocuments() []*GetDocumentResponse {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
 var File_plugins_authz_authztest_acltest_proto protoreflect.FileDescriptor
 
 const file_plugins_authz_authztest_acltest_proto_rawDesc = "" +
@@ -447,7 +559,9 @@ const file_plugins_authz_authztest_acltest_proto_rawDesc = "" +
 	"\aRequest\x12\x14\n" +
 	"\x05field\x18\x01 \x01(\tR\x05field\"$\n" +
 	"\bResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\"X\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\"/\n" +
+	"\x0eProfileRequest\x12\x1d\n" +
+	"\auser_id\x18\x01 \x01(\tB\x04\xc0\xb6\x18\x01R\x06userId\"X\n" +
 	"\x12GetDocumentRequest\x12\x1b\n" +
 	"\x06org_id\x18\x01 \x01(\tB\x04\xb0\xb6\x18\x01R\x05orgId\x12%\n" +
 	"\vdocument_id\x18\x02 \x01(\tB\x04\xa8\xb6\x18\x01R\n" +
@@ -461,21 +575,31 @@ const file_plugins_authz_authztest_acltest_proto_rawDesc = "" +
 	"\vdocument_id\x18\x02 \x01(\tB\x04\xa8\xb6\x18\x01R\n" +
 	"documentId\x12\x14\n" +
 	"\x05title\x18\x03 \x01(\tR\x05title\x12\x12\n" +
-	"\x04body\x18\x04 \x01(\tR\x04body\"P\n" +
+	"\x04body\x18\x04 \x01(\tR\x04body\"m\n" +
+	"\x15CreateDocumentRequest\x12\x1b\n" +
+	"\x06org_id\x18\x01 \x01(\tB\x04\xb8\xb6\x18\x01R\x05orgId\x12!\n" +
+	"\tfolder_id\x18\x02 \x01(\tB\x04ȶ\x18\x01R\bfolderId\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\"P\n" +
 	"\x14SaveDocumentResponse\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
 	"\x05title\x18\x02 \x01(\tR\x05title\x12\x12\n" +
 	"\x04body\x18\x03 \x01(\tR\x04body\"3\n" +
 	"\x14ListDocumentsRequest\x12\x1b\n" +
-	"\x06org_id\x18\x01 \x01(\tB\x04\xa8\xb6\x18\x01R\x05orgId\":\n" +
+	"\x06org_id\x18\x01 \x01(\tB\x04\xa8\xb6\x18\x01R\x05orgId\"\x80\x01\n" +
 	"\x15ListDocumentsResponse\x12!\n" +
-	"\fdocument_ids\x18\x01 \x03(\tR\vdocumentIds2\x8f\a\n" +
+	"\fdocument_ids\x18\x01 \x03(\tR\vdocumentIds\x12D\n" +
+	"\tdocuments\x18\x02 \x03(\v2&.prefab.authz_test.GetDocumentResponseR\tdocuments2\xba\n" +
+	"\n" +
 	"\x10AuthzTestService\x12[\n" +
 	"\bNoPolicy\x12\x1a.prefab.authz_test.Request\x1a\x1b.prefab.authz_test.Response\"\x16\x82\xd3\xe4\x93\x02\x10\x12\x0e/api/no-policy\x12b\n" +
 	"\x04Self\x12\x1a.prefab.authz_test.Request\x1a\x1b.prefab.authz_test.Response\"!ڵ\x18\fself.inspect\x82\xd3\xe4\x93\x02\v\x12\t/api/self\x12\xac\x01\n" +
 	"\vGetDocument\x12%.prefab.authz_test.GetDocumentRequest\x1a&.prefab.authz_test.GetDocumentResponse\"Nڵ\x18\x0edocuments.view\xe2\xb5\x18\bdocument\xea\xb5\x18\x04deny\x82\xd3\xe4\x93\x02\"\x12 /api/{org_id}/docs/{document_id}\x12\xb0\x01\n" +
 	"\fSaveDocument\x12&.prefab.authz_test.SaveDocumentRequest\x1a'.prefab.authz_test.SaveDocumentResponse\"Oڵ\x18\x0fdocuments.write\xe2\xb5\x18\bdocument\xea\xb5\x18\x04deny\x82\xd3\xe4\x93\x02\"\x1a /api/{org_id}/docs/{document_id}\x12\xbd\x01\n" +
-	"\x10GetDocumentTitle\x12%.prefab.authz_test.GetDocumentRequest\x1a&.prefab.authz_test.GetDocumentResponse\"Zڵ\x18\x13documents.view_meta\xe2\xb5\x18\bdocument\xea\xb5\x18\x05allow\x82\xd3\xe4\x93\x02(\x12&/api/{org_id}/docs/{document_id}/title\x12\x97\x01\n" +
+	"\x10GetDocumentTitle\x12%.prefab.authz_test.GetDocumentRequest\x1a&.prefab.authz_test.GetDocumentResponse\"Zڵ\x18\x13documents.view_meta\xe2\xb5\x18\bdocument\xea\xb5\x18\x05allow\x82\xd3\xe4\x93\x02(\x12&/api/{org_id}/docs/{document_id}/title\x12m\n" +
+	"\n" +
+	"GetProfile\x12!.prefab.authz_test.ProfileRequest\x1a\x1b.prefab.authz_test.Response\"\x1f\x82\xd3\xe4\x93\x02\x19\x12\x17/api/profiles/{user_id}\x12\x82\x01\n" +
+	"\rUpdateProfile\x12!.prefab.authz_test.ProfileRequest\x1a\x1b.prefab.authz_test.Response\"1ڵ\x18\x0eprofiles.write\x82\xd3\xe4\x93\x02\x19\x1a\x17/api/profiles/{user_id}\x12\xb4\x01\n" +
+	"\x0eCreateDocument\x12(.prefab.authz_test.CreateDocumentRequest\x1a'.prefab.authz_test.SaveDocumentResponse\"Oڵ\x18\x10documents.create\xf2\xb5\x18\x06folder\x82\xd3\xe4\x93\x02+:\x01*\"&/api/{org_id}/folders/{folder_id}/docs\x12\x97\x01\n" +
 	"\rListDocuments\x12'.prefab.authz_test.ListDocumentsRequest\x1a(.prefab.authz_test.ListDocumentsResponse\"3ڵ\x18\x0edocuments.list\xe2\xb5\x18\x03org\x82\xd3\xe4\x93\x02\x14\x12\x12/api/{org_id}/docsB0Z.github.com/dpup/prefab/plugins/authz/authztestb\x06proto3"
 
 var (
@@ -490,35 +614,44 @@ func file_plugins_authz_authztest_acltest_proto_rawDescGZIP() []byte {
 	return file_plugins_authz_authztest_acltest_proto_rawDescData
 }
 
-var file_plugins_authz_authztest_acltest_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_plugins_authz_authztest_acltest_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
 var file_plugins_authz_authztest_acltest_proto_goTypes = []any{
 	(*Request)(nil),               // 0: prefab.authz_test.Request
 	(*Response)(nil),              // 1: prefab.authz_test.Response
-	(*GetDocumentRequest)(nil),    // 2: prefab.authz_test.GetDocumentRequest
-	(*GetDocumentResponse)(nil),   // 3: prefab.authz_test.GetDocumentResponse
-	(*SaveDocumentRequest)(nil),   // 4: prefab.authz_test.SaveDocumentRequest
-	(*SaveDocumentResponse)(nil),  // 5: prefab.authz_test.SaveDocumentResponse
-	(*ListDocumentsRequest)(nil),  // 6: prefab.authz_test.ListDocumentsRequest
-	(*ListDocumentsResponse)(nil), // 7: prefab.authz_test.ListDocumentsResponse
+	(*ProfileRequest)(nil),        // 2: prefab.authz_test.ProfileRequest
+	(*GetDocumentRequest)(nil),    // 3: prefab.authz_test.GetDocumentRequest
+	(*GetDocumentResponse)(nil),   // 4: prefab.authz_test.GetDocumentResponse
+	(*SaveDocumentRequest)(nil),   // 5: prefab.authz_test.SaveDocumentRequest
+	(*CreateDocumentRequest)(nil), // 6: prefab.authz_test.CreateDocumentRequest
+	(*SaveDocumentResponse)(nil),  // 7: prefab.authz_test.SaveDocumentResponse
+	(*ListDocumentsRequest)(nil),  // 8: prefab.authz_test.ListDocumentsRequest
+	(*ListDocumentsResponse)(nil), // 9: prefab.authz_test.ListDocumentsResponse
 }
 var file_plugins_authz_authztest_acltest_proto_depIdxs = []int32{
-	0, // 0: prefab.authz_test.AuthzTestService.NoPolicy:input_type -> prefab.authz_test.Request
-	0, // 1: prefab.authz_test.AuthzTestService.Self:input_type -> prefab.authz_test.Request
-	2, // 2: prefab.authz_test.AuthzTestService.GetDocument:input_type -> prefab.authz_test.GetDocumentRequest
-	4, // 3: prefab.authz_test.AuthzTestService.SaveDocument:input_type -> prefab.authz_test.SaveDocumentRequest
-	2, // 4: prefab.authz_test.AuthzTestService.GetDocumentTitle:input_type -> prefab.authz_test.GetDocumentRequest
-	6, // 5: prefab.authz_test.AuthzTestService.ListDocuments:input_type -> prefab.authz_test.ListDocumentsRequest
-	1, // 6: prefab.authz_test.AuthzTestService.NoPolicy:output_type -> prefab.authz_test.Response
-	1, // 7: prefab.authz_test.AuthzTestService.Self:output_type -> prefab.authz_test.Response
-	3, // 8: prefab.authz_test.AuthzTestService.GetDocument:output_type -> prefab.authz_test.GetDocumentResponse
-	5, // 9: prefab.authz_test.AuthzTestService.SaveDocument:output_type -> prefab.authz_test.SaveDocumentResponse
-	3, // 10: prefab.authz_test.AuthzTestService.GetDocumentTitle:output_type -> prefab.authz_test.GetDocumentResponse
-	7, // 11: prefab.authz_test.AuthzTestService.ListDocuments:output_type -> prefab.authz_test.ListDocumentsResponse
-	6, // [6:12] is the sub-list for method output_type
-	0, // [0:6] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4,  // 0: prefab.authz_test.ListDocumentsResponse.documents:type_name -> prefab.authz_test.GetDocumentResponse
+	0,  // 1: prefab.authz_test.AuthzTestService.NoPolicy:input_type -> prefab.authz_test.Request
+	0,  // 2: prefab.authz_test.AuthzTestService.Self:input_type -> prefab.authz_test.Request
+	3,  // 3: prefab.authz_test.AuthzTestService.GetDocument:input_type -> prefab.authz_test.GetDocumentRequest
+	5,  // 4: prefab.authz_test.AuthzTestService.SaveDocument:input_type -> prefab.authz_test.SaveDocumentRequest
+	3,  // 5: prefab.authz_test.AuthzTestService.GetDocumentTitle:input_type -> prefab.authz_test.GetDocumentRequest
+	2,  // 6: prefab.authz_test.AuthzTestService.GetProfile:input_type -> prefab.authz_test.ProfileRequest
+	2,  // 7: prefab.authz_test.AuthzTestService.UpdateProfile:input_type -> prefab.authz_test.ProfileRequest
+	6,  // 8: prefab.authz_test.AuthzTestService.CreateDocument:input_type -> prefab.authz_test.CreateDocumentRequest
+	8,  // 9: prefab.authz_test.AuthzTestService.ListDocuments:input_type -> prefab.authz_test.ListDocumentsRequest
+	1,  // 10: prefab.authz_test.AuthzTestService.NoPolicy:output_type -> prefab.authz_test.Response
+	1,  // 11: prefab.authz_test.AuthzTestService.Self:output_type -> prefab.authz_test.Response
+	4,  // 12: prefab.authz_test.AuthzTestService.GetDocument:output_type -> prefab.authz_test.GetDocumentResponse
+	7,  // 13: prefab.authz_test.AuthzTestService.SaveDocument:output_type -> prefab.authz_test.SaveDocumentResponse
+	4,  // 14: prefab.authz_test.AuthzTestService.GetDocumentTitle:output_type -> prefab.authz_test.GetDocumentResponse
+	1,  // 15: prefab.authz_test.AuthzTestService.GetProfile:output_type -> prefab.authz_test.Response
+	1,  // 16: prefab.authz_test.AuthzTestService.UpdateProfile:output_type -> prefab.authz_test.Response
+	7,  // 17: prefab.authz_test.AuthzTestService.CreateDocument:output_type -> prefab.authz_test.SaveDocumentResponse
+	9,  // 18: prefab.authz_test.AuthzTestService.ListDocuments:output_type -> prefab.authz_test.ListDocumentsResponse
+	10, // [10:19] is the sub-list for method output_type
+	1,  // [1:10] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_plugins_authz_authztest_acltest_proto_init() }
@@ -532,7 +665,7 @@ func file_plugins_authz_authztest_acltest_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_authz_authztest_acltest_proto_rawDesc), len(file_plugins_authz_authztest_acltest_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   10,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
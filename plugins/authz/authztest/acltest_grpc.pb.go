@@ -24,6 +24,9 @@ const (
 	AuthzTestService_GetDocument_FullMethodName      = "/prefab.authz_test.AuthzTestService/GetDocument"
 	AuthzTestService_SaveDocument_FullMethodName     = "/prefab.authz_test.AuthzTestService/SaveDocument"
 	AuthzTestService_GetDocumentTitle_FullMethodName = "/prefab.authz_test.AuthzTestService/GetDocumentTitle"
+	AuthzTestService_GetProfile_FullMethodName       = "/prefab.authz_test.AuthzTestService/GetProfile"
+	AuthzTestService_UpdateProfile_FullMethodName    = "/prefab.authz_test.AuthzTestService/UpdateProfile"
+	AuthzTestService_CreateDocument_FullMethodName   = "/prefab.authz_test.AuthzTestService/CreateDocument"
 	AuthzTestService_ListDocuments_FullMethodName    = "/prefab.authz_test.AuthzTestService/ListDocuments"
 )
 
@@ -42,6 +45,14 @@ type AuthzTestServiceClient interface {
 	// Demonstrates a default allow Authz. Policies can be used to restrict certain
 	// roles from viewing the title.
 	GetDocumentTitle(ctx context.Context, in *GetDocumentRequest, opts ...grpc.CallOption) (*GetDocumentResponse, error)
+	// Demonstrates a self check, callers may only view their own profile.
+	GetProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*Response, error)
+	// Demonstrates a self check with a fallback policy, callers may update their
+	// own profile and policies may allow other roles to update any profile.
+	UpdateProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*Response, error)
+	// Demonstrates a collection-level check, documents are created in a folder
+	// and the caller's roles are described relative to the folder.
+	CreateDocument(ctx context.Context, in *CreateDocumentRequest, opts ...grpc.CallOption) (*SaveDocumentResponse, error)
 	ListDocuments(ctx context.Context, in *ListDocumentsRequest, opts ...grpc.CallOption) (*ListDocumentsResponse, error)
 }
 
@@ -103,6 +114,36 @@ func (c *authzTestServiceClient) GetDocumentTitle(ctx context.Context, in *GetDo
 	return out, nil
 }
 
+func (c *authzTestServiceClient) GetProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Response)
+	err := c.cc.Invoke(ctx, AuthzTestService_GetProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authzTestServiceClient) UpdateProfile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*Response, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Response)
+	err := c.cc.Invoke(ctx, AuthzTestService_UpdateProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *authzTestServiceClient) CreateDocument(ctx context.Context, in *CreateDocumentRequest, opts ...grpc.CallOption) (*SaveDocumentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SaveDocumentResponse)
+	err := c.cc.Invoke(ctx, AuthzTestService_CreateDocument_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *authzTestServiceClient) ListDocuments(ctx context.Context, in *ListDocumentsRequest, opts ...grpc.CallOption) (*ListDocumentsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListDocumentsResponse)
@@ -128,6 +169,14 @@ type AuthzTestServiceServer interface {
 	// Demonstrates a default allow Authz. Policies can be used to restrict certain
 	// roles from viewing the title.
 	GetDocumentTitle(context.Context, *GetDocumentRequest) (*GetDocumentResponse, error)
+	// Demonstrates a self check, callers may only view their own profile.
+	GetProfile(context.Context, *ProfileRequest) (*Response, error)
+	// Demonstrates a self check with a fallback policy, callers may update their
+	// own profile and policies may allow other roles to update any profile.
+	UpdateProfile(context.Context, *ProfileRequest) (*Response, error)
+	// Demonstrates a collection-level check, documents are created in a folder
+	// and the caller's roles are described relative to the folder.
+	CreateDocument(context.Context, *CreateDocumentRequest) (*SaveDocumentResponse, error)
 	ListDocuments(context.Context, *ListDocumentsRequest) (*ListDocumentsResponse, error)
 	mustEmbedUnimplementedAuthzTestServiceServer()
 }
@@ -154,6 +203,15 @@ func (UnimplementedAuthzTestServiceServer) SaveDocument(context.Context, *SaveDo
 func (UnimplementedAuthzTestServiceServer) GetDocumentTitle(context.Context, *GetDocumentRequest) (*GetDocumentResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDocumentTitle not implemented")
 }
+func (UnimplementedAuthzTestServiceServer) GetProfile(context.Context, *ProfileRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
+}
+func (UnimplementedAuthzTestServiceServer) UpdateProfile(context.Context, *ProfileRequest) (*Response, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
+}
+func (UnimplementedAuthzTestServiceServer) CreateDocument(context.Context, *CreateDocumentRequest) (*SaveDocumentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDocument not implemented")
+}
 func (UnimplementedAuthzTestServiceServer) ListDocuments(context.Context, *ListDocumentsRequest) (*ListDocumentsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListDocuments not implemented")
 }
@@ -268,6 +326,60 @@ func _AuthzTestService_GetDocumentTitle_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AuthzTestService_GetProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthzTestServiceServer).GetProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthzTestService_GetProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthzTestServiceServer).GetProfile(ctx, req.(*ProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthzTestService_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthzTestServiceServer).UpdateProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthzTestService_UpdateProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthzTestServiceServer).UpdateProfile(ctx, req.(*ProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AuthzTestService_CreateDocument_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDocumentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthzTestServiceServer).CreateDocument(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthzTestService_CreateDocument_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthzTestServiceServer).CreateDocument(ctx, req.(*CreateDocumentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _AuthzTestService_ListDocuments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListDocumentsRequest)
 	if err := dec(in); err != nil {
@@ -313,6 +425,18 @@ var AuthzTestService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDocumentTitle",
 			Handler:    _AuthzTestService_GetDocumentTitle_Handler,
 		},
+		{
+			MethodName: "GetProfile",
+			Handler:    _AuthzTestService_GetProfile_Handler,
+		},
+		{
+			MethodName: "UpdateProfile",
+			Handler:    _AuthzTestService_UpdateProfile_Handler,
+		},
+		{
+			MethodName: "CreateDocument",
+			Handler:    _AuthzTestService_CreateDocument_Handler,
+		},
 		{
 			MethodName: "ListDocuments",
 			Handler:    _AuthzTestService_ListDocuments_Handler,
@@ -0,0 +1,202 @@
+package authztest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// UpdateGoldenEnv is the environment variable which, when set, causes
+// AssertGolden to rewrite golden files instead of comparing against them.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// Case is a policy expectation: the identity performing the action on the
+// object should have the wanted effect.
+type Case struct {
+	Name     string
+	Identity auth.Identity
+
+	// Resource is the object key used to find the role describer, and Object is
+	// the fixture passed to it. Object fetchers aren't called.
+	Resource string
+	Object   any
+	Scope    authz.Scope
+
+	// Roles, if set, are used instead of describing the identity's roles.
+	Roles []authz.Role
+
+	Action        authz.Action
+	DefaultEffect authz.Effect
+	Want          authz.Effect
+}
+
+// Coverage reports parts of the authorization model which weren't exercised by
+// any case.
+type Coverage struct {
+	// Actions from policies, or from RPC action options, without a case.
+	UntestedActions []authz.Action
+
+	// Roles from policies, or the role hierarchy, which no case had.
+	UntestedRoles []authz.Role
+
+	// Policies which were never evaluated.
+	UnexercisedPolicies []authz.Policy
+}
+
+// Run runs each case as a subtest against the plugin and returns the coverage
+// of the plugin's policies.
+//
+// Example:
+//
+//	cov := authztest.Run(t, authzPlugin, []authztest.Case{
+//		{Name: "owner can edit", Identity: alice, Resource: "document", Object: aliceDoc, Action: "documents.edit", Want: authz.Allow},
+//		{Name: "viewer can't edit", Roles: []authz.Role{"viewer"}, Resource: "document", Action: "documents.edit", Want: authz.Deny},
+//	})
+//	cov.AssertGolden(t, "testdata/authz_coverage.golden")
+func Run(t *testing.T, ap *authz.AuthzPlugin, cases []Case) *Coverage {
+	t.Helper()
+	actions := map[authz.Action]bool{}
+	roles := map[authz.Role]bool{}
+	policies := map[authz.Policy]bool{}
+
+	for _, c := range cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("%s %s", c.Identity.Subject, c.Action)
+		}
+		t.Run(name, func(t *testing.T) {
+			caseRoles := c.Roles
+			if caseRoles == nil {
+				ctx := auth.WithIdentityForTest(t.Context(), c.Identity)
+				var err error
+				caseRoles, err = ap.DescribeRoles(ctx, c.Resource, c.Identity, c.Object, c.Scope)
+				require.NoError(t, err, "failed to describe roles")
+			}
+			effect, evaluated := ap.DetermineEffect(c.Action, caseRoles, c.DefaultEffect)
+			assert.Equal(t, c.Want.String(), effect.String(), "unexpected effect for roles %v", caseRoles)
+
+			actions[c.Action] = true
+			for _, r := range caseRoles {
+				roles[r] = true
+			}
+			for _, e := range evaluated {
+				policies[authz.Policy{Effect: e.Effect, Role: e.Role, Action: c.Action}] = true
+			}
+		})
+	}
+
+	cov := &Coverage{}
+	for _, a := range knownActions(ap) {
+		if !actions[a] {
+			cov.UntestedActions = append(cov.UntestedActions, a)
+		}
+	}
+	for _, r := range knownRoles(ap) {
+		if !roles[r] {
+			cov.UntestedRoles = append(cov.UntestedRoles, r)
+		}
+	}
+	for _, p := range ap.Policies() {
+		if !policies[p] {
+			cov.UnexercisedPolicies = append(cov.UnexercisedPolicies, p)
+		}
+	}
+	return cov
+}
+
+// Complete returns whether every action, role, and policy was exercised.
+func (c *Coverage) Complete() bool {
+	return len(c.UntestedActions) == 0 && len(c.UntestedRoles) == 0 && len(c.UnexercisedPolicies) == 0
+}
+
+// String returns a stable, human readable report.
+func (c *Coverage) String() string {
+	var sb strings.Builder
+	sb.WriteString("Untested actions:\n")
+	for _, a := range c.UntestedActions {
+		sb.WriteString("  " + string(a) + "\n")
+	}
+	sb.WriteString("\nUntested roles:\n")
+	for _, r := range c.UntestedRoles {
+		sb.WriteString("  " + string(r) + "\n")
+	}
+	sb.WriteString("\nUnexercised policies:\n")
+	for _, p := range c.UnexercisedPolicies {
+		sb.WriteString(fmt.Sprintf("  %s %s %s\n", p.Action, p.Effect, p.Role))
+	}
+	return sb.String()
+}
+
+// AssertGolden compares the report against a golden file, so that changes to
+// coverage show up in review. Set UPDATE_GOLDEN=1 to rewrite the file.
+func (c *Coverage) AssertGolden(t testing.TB, path string) {
+	t.Helper()
+	report := c.String()
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(report), 0o600))
+		return
+	}
+	want, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read golden file, run with %s=1 to create it", UpdateGoldenEnv)
+	assert.Equal(t, string(want), report, "authz coverage changed, run with %s=1 to update %s", UpdateGoldenEnv, path)
+}
+
+// knownActions returns actions from policies and from the action option on
+// registered RPCs.
+func knownActions(ap *authz.AuthzPlugin) []authz.Action {
+	seen := map[authz.Action]bool{}
+	for _, p := range ap.Policies() {
+		seen[p.Action] = true
+	}
+	protoregistry.GlobalFiles.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		services := fd.Services()
+		for i := range services.Len() {
+			methods := services.Get(i).Methods()
+			for j := range methods.Len() {
+				opts, _ := methods.Get(j).Options().(*descriptorpb.MethodOptions)
+				if a, _ := proto.GetExtension(opts, authz.E_Action).(string); a != "" {
+					seen[authz.Action(a)] = true
+				}
+			}
+		}
+		return true
+	})
+	return sortedKeys(seen)
+}
+
+// knownRoles returns roles from policies and the role hierarchy.
+func knownRoles(ap *authz.AuthzPlugin) []authz.Role {
+	seen := map[authz.Role]bool{}
+	for _, p := range ap.Policies() {
+		seen[p.Role] = true
+	}
+	for parent, children := range ap.RoleTree() {
+		seen[parent] = true
+		for _, child := range children {
+			seen[child] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys[K ~string](m map[K]bool) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,41 @@
+package authztest_test
+
+import (
+	"testing"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+)
+
+type doc struct {
+	owner string
+}
+
+func TestRun(t *testing.T) {
+	ap := authz.Plugin(
+		authz.WithRoleHierarchy(authz.RoleEditor, authz.RoleViewer),
+		authz.WithPolicy(authz.Allow, authz.RoleViewer, "documents.view"),
+		authz.WithPolicy(authz.Allow, authz.RoleEditor, "documents.write"),
+		authz.WithPolicy(authz.Deny, "suspended", "documents.write"),
+		authz.WithRoleDescriber("document", authz.Compose(
+			authz.OwnershipRole(authz.RoleEditor, func(d *doc) string { return d.owner }),
+		)),
+	)
+	alice := auth.Identity{Provider: "test", Subject: "alice"}
+
+	cov := authztest.Run(t, ap, []authztest.Case{
+		{Name: "owner can write", Identity: alice, Resource: "document", Object: &doc{owner: "alice"}, Action: "documents.write", Want: authz.Allow},
+		{Name: "owner can view", Identity: alice, Resource: "document", Object: &doc{owner: "alice"}, Action: "documents.view", Want: authz.Allow},
+		{Name: "others can't write", Identity: alice, Resource: "document", Object: &doc{owner: "bob"}, Action: "documents.write", Want: authz.Deny},
+		{Name: "viewers can't write", Roles: []authz.Role{authz.RoleViewer}, Action: "documents.write", Want: authz.Deny},
+	})
+
+	assert.False(t, cov.Complete())
+	assert.Contains(t, cov.UntestedActions, authz.Action("documents.create"), "actions from RPC options are included")
+	assert.NotContains(t, cov.UntestedActions, authz.Action("documents.write"))
+	assert.Equal(t, []authz.Role{"suspended"}, cov.UntestedRoles)
+	assert.Equal(t, []authz.Policy{{Effect: authz.Deny, Role: "suspended", Action: "documents.write"}}, cov.UnexercisedPolicies)
+	cov.AssertGolden(t, "testdata/coverage.golden")
+}
@@ -1,19 +1,126 @@
 package authz
 
 import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
 	"net/http"
+	"slices"
 	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
 )
 
-// DebugHandler renders information about registered policies and roles.
+// ActionDebug is the action required to use the /debug/authz endpoints. Roles
+// are described relative to DebugObjectKey, whose object is the plugin itself.
+//
+// Example:
+//
+//	authz.WithDebugEndpoint(),
+//	authz.WithPolicy(authz.Allow, authz.RoleAdmin, authz.ActionDebug),
+//	authz.WithGroupRoles(authz.DebugObjectKey, authz.GroupMapping{"platform-admins": {authz.RoleAdmin}}),
+const ActionDebug Action = "authz.debug"
+
+// DebugObjectKey is the object key used to authorize the debug endpoints.
+const DebugObjectKey = "authz"
+
+// debugMiddleware authorizes access to the debug endpoints.
+func (ap *AuthzPlugin) debugMiddleware(h func(http.ResponseWriter, *http.Request)) http.Handler {
+	return ap.Middleware(DebugObjectKey, ActionDebug)(http.HandlerFunc(h))
+}
+
+// fetchDebugObject is the object fetcher for DebugObjectKey.
+func (ap *AuthzPlugin) fetchDebugObject(_ context.Context, _ any) (any, error) {
+	return ap, nil
+}
+
+// DebugHandler renders an interactive page describing the authorization model:
+// a searchable policy table, the role hierarchy, registered fetchers and
+// describers, and a dry run form which calls ExplainHandler.
 func (ap *AuthzPlugin) DebugHandler(resp http.ResponseWriter, req *http.Request) {
-	resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	resp.Write([]byte("Authz Configuration\n"))
-	resp.Write([]byte("===================\n\n\n"))
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		resp.Header().Set("Allow", "GET, HEAD")
+		http.Error(resp, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := debugTemplate.Execute(resp, ap.debugPage()); err != nil {
+		http.Error(resp, errors.Wrap(err, 0).WithCode(codes.Internal).Error(), http.StatusInternalServerError)
+	}
+}
 
-	resp.Write([]byte("Role Hierarchy\n"))
-	resp.Write([]byte("--------------\n\n"))
+type debugRegistration struct {
+	Key           string
+	Fetcher       string
+	ParentFetcher string
+	Describer     string
+	Groups        []string
+}
+
+type debugFilter struct {
+	Message string
+	Filters int
+}
 
+type debugPage struct {
+	Hierarchy string
+	Policies  []Policy
+	Actions   []Action
+	Registry  []debugRegistration
+	Filters   []debugFilter
+}
+
+func (ap *AuthzPlugin) debugPage() debugPage {
+	page := debugPage{
+		Hierarchy: ap.hierarchyText(),
+		Policies:  ap.Policies(),
+	}
+	for _, p := range page.Policies {
+		if !slices.Contains(page.Actions, p.Action) {
+			page.Actions = append(page.Actions, p.Action)
+		}
+	}
+
+	keys := map[string]bool{}
+	for k := range ap.objectFetchers {
+		keys[k] = true
+	}
+	for k := range ap.parentFetchers {
+		keys[k] = true
+	}
+	for k := range ap.roleDescribers {
+		keys[k] = true
+	}
+	for k := range ap.groupRoles {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		reg := debugRegistration{
+			Key:           k,
+			Fetcher:       typeName(ap.objectFetchers[k]),
+			ParentFetcher: typeName(ap.parentFetchers[k]),
+			Describer:     typeName(ap.roleDescribers[k]),
+		}
+		for group, roles := range ap.groupRoles[k] {
+			reg.Groups = append(reg.Groups, fmt.Sprintf("%s → %v", group, roles))
+		}
+		slices.Sort(reg.Groups)
+		page.Registry = append(page.Registry, reg)
+	}
+
+	for name, filters := range ap.responseFilters {
+		page.Filters = append(page.Filters, debugFilter{Message: string(name), Filters: len(filters)})
+	}
+	slices.SortFunc(page.Filters, func(a, b debugFilter) int {
+		return strings.Compare(a.Message, b.Message)
+	})
+	return page
+}
+
+// hierarchyText renders the role hierarchy as a tree.
+func (ap *AuthzPlugin) hierarchyText() string {
 	// Avoid picking up any roles that are known to be children,
 	isChild := make(map[Role]bool)
 	for child := range ap.roleParents {
@@ -38,41 +145,30 @@ func (ap *AuthzPlugin) DebugHandler(resp http.ResponseWriter, req *http.Request)
 		}
 	}
 
-	// Print role tree.
+	var sb strings.Builder
 	tree := ap.RoleTree()
-	for root := range roots {
-		printTree(resp, tree, root, "", true, true)
-	}
-
-	resp.Write([]byte("\n\n\nPolicies\n"))
-	resp.Write([]byte("--------\n\n"))
-
-	padding := 20
-	for action, policy := range ap.policies {
-		resp.Write([]byte("  " + pad(string(action), padding) + "\n"))
-		for role, effect := range policy {
-			resp.Write([]byte("    " + effect.String() + " " + string(role) + "\n"))
-		}
-		resp.Write([]byte("\n"))
+	for _, root := range sortedKeys(roots) {
+		printTree(&sb, tree, root, "", true, true)
 	}
+	return sb.String()
 }
 
-func printTree(resp http.ResponseWriter, tree map[Role][]Role, role Role, prefix string, isRoot, isTail bool) {
+func printTree(w io.Writer, tree map[Role][]Role, role Role, prefix string, isRoot, isTail bool) {
 	if isRoot {
-		resp.Write([]byte("  " + string(role) + "\n"))
+		io.WriteString(w, "  "+string(role)+"\n")
 	} else {
 		if isTail {
-			resp.Write([]byte(prefix + "└── " + string(role) + "\n"))
+			io.WriteString(w, prefix+"└── "+string(role)+"\n")
 		} else {
-			resp.Write([]byte(prefix + "├── " + string(role) + "\n"))
+			io.WriteString(w, prefix+"├── "+string(role)+"\n")
 		}
 	}
 	children := tree[role]
 	for i := range len(children) - 1 {
-		printTree(resp, tree, children[i], prefix+getPrefix(isRoot, isTail), false, false)
+		printTree(w, tree, children[i], prefix+getPrefix(isRoot, isTail), false, false)
 	}
 	if len(children) > 0 {
-		printTree(resp, tree, children[len(children)-1], prefix+getPrefix(isRoot, isTail), false, true)
+		printTree(w, tree, children[len(children)-1], prefix+getPrefix(isRoot, isTail), false, true)
 	}
 }
 
@@ -86,14 +182,116 @@ func getPrefix(isRoot, isTail bool) string {
 	return "│   "
 }
 
-func pad(str string, n int) string {
-	padding := n - len(str)
-	if padding <= 0 {
-		return str
+func typeName(v any) string {
+	if v == nil {
+		return ""
 	}
-	var sb strings.Builder
-	sb.Grow(n)
-	sb.WriteString(str)
-	sb.WriteString(strings.Repeat(" ", padding))
-	return sb.String()
+	return fmt.Sprintf("%T", v)
+}
+
+func sortedKeys[K ~string](m map[K]bool) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Authz Configuration</title>
+<style>
+  body { font-family: system-ui, sans-serif; margin: 2em; color: #222; }
+  table { border-collapse: collapse; margin-bottom: 1em; }
+  th, td { text-align: left; padding: 4px 12px; border-bottom: 1px solid #ddd; vertical-align: top; }
+  pre { background: #f6f6f6; padding: 1em; }
+  code, pre, td { font-family: ui-monospace, monospace; font-size: 13px; }
+  .ALLOW { color: #060; } .DENY { color: #a00; }
+  label { display: block; margin: 4px 0; }
+  input, textarea { font-family: ui-monospace, monospace; width: 32em; }
+</style>
+</head>
+<body>
+<h1>Authz Configuration</h1>
+
+<h2>Policies</h2>
+<input id="search" type="search" placeholder="Filter by action, role, or effect">
+<table id="policies">
+<tr><th>Action</th><th>Role</th><th>Effect</th></tr>
+{{range .Policies}}<tr><td>{{.Action}}</td><td>{{.Role}}</td><td class="{{.Effect}}">{{.Effect}}</td></tr>
+{{end}}</table>
+
+<h2>Role Hierarchy</h2>
+<pre>{{.Hierarchy}}</pre>
+
+<h2>Registry</h2>
+<table>
+<tr><th>Key</th><th>Object Fetcher</th><th>Parent Fetcher</th><th>Role Describer</th><th>Group Roles</th></tr>
+{{range .Registry}}<tr><td>{{.Key}}</td><td>{{.Fetcher}}</td><td>{{.ParentFetcher}}</td><td>{{.Describer}}</td><td>{{range .Groups}}{{.}}<br>{{end}}</td></tr>
+{{end}}</table>
+{{if .Filters}}
+<h3>Response Filters</h3>
+<table>
+<tr><th>Message</th><th>Filters</th></tr>
+{{range .Filters}}<tr><td>{{.Message}}</td><td>{{.Filters}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>Dry Run</h2>
+<p>Explains a decision without enforcing it. Provide either a method and request, or an action and resource.</p>
+<form id="explain">
+  <label>Method <input name="method" placeholder="/pkg.Service/Method"></label>
+  <label>Request <textarea name="request" rows="3" placeholder='{"id": "123"}'></textarea></label>
+  <label>Action <input name="action" list="actions"></label>
+  <datalist id="actions">{{range .Actions}}<option value="{{.}}">{{end}}</datalist>
+  <label>Resource <input name="resource"></label>
+  <label>ID <input name="id"></label>
+  <label>Scope <input name="scope"></label>
+  <label>Provider <input name="provider"></label>
+  <label>Subject <input name="subject"></label>
+  <label>Email <input name="email"></label>
+  <label>Groups <input name="groups" placeholder="comma separated"></label>
+  <label>Hypothetical roles <input name="roles" placeholder="comma separated, optional"></label>
+  <button type="submit">Explain</button>
+</form>
+<pre id="result"></pre>
+
+<script>
+document.getElementById("search").addEventListener("input", function (e) {
+  var q = e.target.value.toLowerCase();
+  document.querySelectorAll("#policies tr:not(:first-child)").forEach(function (row) {
+    row.style.display = row.textContent.toLowerCase().includes(q) ? "" : "none";
+  });
+});
+
+function list(s) {
+  return s.split(",").map(function (v) { return v.trim(); }).filter(Boolean);
 }
+
+document.getElementById("explain").addEventListener("submit", function (e) {
+  e.preventDefault();
+  var f = e.target.elements;
+  var body = {
+    method: f.method.value, action: f.action.value, resource: f.resource.value,
+    id: f.id.value, scope: f.scope.value,
+    identity: { provider: f.provider.value, subject: f.subject.value, email: f.email.value, groups: list(f.groups.value) }
+  };
+  if (f.request.value) {
+    try { body.request = JSON.parse(f.request.value); } catch (err) {
+      document.getElementById("result").textContent = "Invalid request JSON: " + err;
+      return;
+    }
+  }
+  if (f.roles.value) { body.roles = list(f.roles.value); }
+  fetch("/debug/authz/explain", { method: "POST", headers: { "Content-Type": "application/json" }, body: JSON.stringify(body) })
+    .then(function (r) { return r.text(); })
+    .then(function (t) {
+      try { t = JSON.stringify(JSON.parse(t), null, 2); } catch (err) {}
+      document.getElementById("result").textContent = t;
+    });
+});
+</script>
+</body>
+</html>
+`))
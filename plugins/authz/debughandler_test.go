@@ -0,0 +1,71 @@
+package authz_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugHandler(t *testing.T) {
+	ap := explainPlugin()
+	ap.DefinePolicy(authz.Allow, authz.RoleAdmin, authz.ActionDebug)
+	ap.RegisterGroupRoles(authz.DebugObjectKey, authz.GroupMapping{"platform-admins": {authz.RoleAdmin}})
+
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithOptions(prefab.WithAdminOnPublic(), prefab.WithPlugin(ap)))
+
+	get := func(method, path string, identity auth.Identity, body string) (int, string) {
+		req, err := http.NewRequestWithContext(t.Context(), method, s.URL(path), strings.NewReader(body))
+		require.NoError(t, err)
+		if identity != (auth.Identity{}) {
+			s.AuthRequest(req, identity)
+		}
+		resp, err := s.HTTPClient().Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+
+	admin := auth.Identity{Provider: "test", Subject: "ada", Groups: auth.NewGroups("platform-admins")}
+	bob := auth.Identity{Provider: "test", Subject: "bob", Email: "bob@test.com"}
+
+	code, body := get(http.MethodGet, "/debug/authz", admin, "")
+	require.Equal(t, http.StatusOK, code, body)
+	assert.Contains(t, body, "<td>documents.write</td><td>suspended</td>")
+	assert.Contains(t, body, "editor\n")
+	assert.Contains(t, body, "<td>document</td>", "registry lists object keys")
+	assert.Contains(t, body, "platform-admins → [admin]")
+	assert.Contains(t, body, `<form id="explain">`)
+
+	code, body = get(http.MethodPost, "/debug/authz/explain", admin, `{"action": "documents.view", "resource": "document", "id": "1"}`)
+	assert.Equal(t, http.StatusOK, code, body)
+
+	code, _ = get(http.MethodGet, "/debug/authz", bob, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = get(http.MethodPost, "/debug/authz/explain", bob, `{"action": "documents.view", "resource": "document", "id": "1"}`)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = get(http.MethodGet, "/debug/authz", auth.Identity{}, "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+}
+
+func TestDebugHandler_NoPolicy(t *testing.T) {
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithOptions(prefab.WithAdminOnPublic(), prefab.WithPlugin(explainPlugin())))
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL("/debug/authz"), nil)
+	require.NoError(t, err)
+	resp, err := s.HTTPClient().Do(s.AuthRequest(req, auth.Identity{Provider: "test", Subject: "ada"}))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode, "endpoints are unavailable without an ActionDebug policy")
+}
@@ -0,0 +1,280 @@
+package authz
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/redact"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Explanation is the full trace of an authorization decision, see Explain.
+type Explanation struct {
+	Action        Action
+	Resource      string
+	ObjectID      any
+	Scope         Scope
+	Parent        bool
+	Identity      auth.Identity
+	Object        string // Type and value of the fetched object, with redacted fields masked
+	Roles         []Role
+	Hypothetical  bool // Whether Roles were provided rather than described
+	RequiredScope string
+	Policies      []PolicyEvaluation
+	DefaultEffect Effect
+	Effect        Effect
+	Reason        string
+}
+
+// Explain evaluates an authorization decision without enforcing, tracking, or
+// auditing it, and returns how the effect was reached. The identity is read
+// from the context.
+//
+// If hypotheticalRoles is non-nil, the roles are used instead of calling the
+// role describer, to answer "what if the user had these roles?". The object is
+// still fetched, so that missing objects are reported.
+//
+// OAuth scope requirements aren't evaluated, since they depend on the token
+// used for the request, but the required scope is included.
+func (ap *AuthzPlugin) Explain(ctx context.Context, cfg AuthorizeParams, hypotheticalRoles []Role) (*Explanation, error) {
+	fetch, err := ap.fetcherForParams(cfg)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil && !errors.Is(err, auth.ErrNotFound) {
+		return nil, err
+	}
+	object, err := fetch(ctx, cfg.ObjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := &Explanation{
+		Action:        cfg.Action,
+		Resource:      cfg.ObjectKey,
+		ObjectID:      cfg.ObjectID,
+		Scope:         cfg.Scope,
+		Parent:        cfg.Parent,
+		Identity:      identity,
+		Object:        fmt.Sprintf("%T %v", object, redact.Any(object)),
+		RequiredScope: ap.requiredScopes[cfg.Action],
+		DefaultEffect: cfg.DefaultEffect,
+	}
+	if hypotheticalRoles != nil {
+		ex.Roles = hypotheticalRoles
+		ex.Hypothetical = true
+	} else if ex.Roles, err = ap.describeRoles(ctx, cfg.ObjectKey, identity, object, cfg.Scope); err != nil {
+		return nil, err
+	}
+
+	ex.Effect, ex.Policies = ap.DetermineEffect(cfg.Action, ex.Roles, cfg.DefaultEffect)
+	switch {
+	case ex.Effect == Deny:
+		ex.Reason = buildDenialExplanation(cfg.Action, ex.Roles, ex.Policies)
+	case len(ex.Policies) == 0:
+		ex.Reason = "allowed by default effect"
+	default:
+		ex.Reason = "allowed by policy"
+	}
+	return ex, nil
+}
+
+// MethodParams returns the params the interceptor would authorize for a request
+// to the GRPC method, such as "/pkg.Service/Method".
+func MethodParams(method string, req proto.Message) (AuthorizeParams, error) {
+	info := &grpc.UnaryServerInfo{FullMethod: method}
+	objectKey, action, defaultEffect := MethodOptions(info)
+	if action == "" {
+		return AuthorizeParams{}, errors.Codef(codes.InvalidArgument, "authz: no action configured for %s", method)
+	}
+	objectID, scope, err := FieldOptions(req)
+	if err != nil {
+		return AuthorizeParams{}, err
+	}
+	parentKey, isParent := MethodParent(info)
+	if isParent {
+		objectKey = parentKey
+		if objectID, err = ParentField(req); err != nil {
+			return AuthorizeParams{}, err
+		}
+	}
+	return AuthorizeParams{
+		ObjectKey:     objectKey,
+		ObjectID:      objectID,
+		Scope:         Scope(scope),
+		Action:        action,
+		DefaultEffect: defaultEffect,
+		Parent:        isParent,
+		Info:          method,
+	}, nil
+}
+
+// explainRequest is the body accepted by ExplainHandler. Either a method and
+// request, or the params directly, must be provided.
+type explainRequest struct {
+	Method  string          `json:"method"`
+	Request json.RawMessage `json:"request"`
+
+	Action        string `json:"action"`
+	Resource      string `json:"resource"`
+	ID            string `json:"id"`
+	Scope         string `json:"scope"`
+	Parent        bool   `json:"parent"`
+	DefaultEffect string `json:"defaultEffect"`
+
+	Identity struct {
+		Provider string   `json:"provider"`
+		Subject  string   `json:"subject"`
+		Email    string   `json:"email"`
+		Groups   []string `json:"groups"`
+	} `json:"identity"`
+
+	// Hypothetical roles, if set the role describer isn't called.
+	Roles []Role `json:"roles"`
+}
+
+type explainPolicy struct {
+	Role   Role   `json:"role"`
+	Effect string `json:"effect"`
+}
+
+type explainResponse struct {
+	Action        Action          `json:"action"`
+	Resource      string          `json:"resource"`
+	ObjectID      any             `json:"objectId"`
+	Scope         Scope           `json:"scope,omitempty"`
+	Parent        bool            `json:"parent,omitempty"`
+	Subject       string          `json:"subject,omitempty"`
+	Object        string          `json:"object"`
+	Roles         []Role          `json:"roles"`
+	Hypothetical  bool            `json:"hypothetical,omitempty"`
+	RequiredScope string          `json:"requiredScope,omitempty"`
+	Policies      []explainPolicy `json:"policies"`
+	DefaultEffect string          `json:"defaultEffect"`
+	Effect        string          `json:"effect"`
+	Reason        string          `json:"reason"`
+}
+
+// ExplainHandler explains an authorization decision for a JSON request
+// describing the identity and either a GRPC method and request, or the
+// resource, id, and action, see Explain.
+//
+// Example:
+//
+//	curl -X POST localhost:8081/debug/authz/explain -d '{
+//	  "method": "/docs.DocumentService/GetDocument",
+//	  "request": {"documentId": "doc-1"},
+//	  "identity": {"provider": "google", "subject": "1234"}
+//	}'
+func (ap *AuthzPlugin) ExplainHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		prefab.WriteJSONError(w, r, errors.NewC("authz: explain requires POST", codes.InvalidArgument).WithHTTPStatusCode(http.StatusMethodNotAllowed))
+		return
+	}
+	var in explainRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		prefab.WriteJSONError(w, r, errors.Wrap(err, 0).WithCode(codes.InvalidArgument))
+		return
+	}
+	params, err := explainParams(in)
+	if err != nil {
+		prefab.WriteJSONError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	if in.Identity.Subject != "" {
+		identity := auth.Identity{
+			Provider: in.Identity.Provider,
+			Subject:  in.Identity.Subject,
+			Email:    in.Identity.Email,
+			Groups:   auth.NewGroups(in.Identity.Groups...),
+		}
+		ctx = auth.WithIdentityExtractors(ctx, func(context.Context) (auth.Identity, error) {
+			return identity, nil
+		})
+	} else {
+		ctx = auth.WithIdentityExtractors(ctx)
+	}
+
+	ex, err := ap.Explain(ctx, params, in.Roles)
+	if err != nil {
+		prefab.WriteJSONError(w, r, err)
+		return
+	}
+	out := explainResponse{
+		Action:        ex.Action,
+		Resource:      ex.Resource,
+		ObjectID:      ex.ObjectID,
+		Scope:         ex.Scope,
+		Parent:        ex.Parent,
+		Subject:       ex.Identity.Subject,
+		Object:        ex.Object,
+		Roles:         ex.Roles,
+		Hypothetical:  ex.Hypothetical,
+		RequiredScope: ex.RequiredScope,
+		Policies:      []explainPolicy{},
+		DefaultEffect: ex.DefaultEffect.String(),
+		Effect:        ex.Effect.String(),
+		Reason:        ex.Reason,
+	}
+	for _, p := range ex.Policies {
+		out.Policies = append(out.Policies, explainPolicy{Role: p.Role, Effect: p.Effect.String()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func explainParams(in explainRequest) (AuthorizeParams, error) {
+	if in.Method == "" {
+		if in.Action == "" || in.Resource == "" {
+			return AuthorizeParams{}, errors.NewC("authz: method, or action and resource, are required", codes.InvalidArgument)
+		}
+		effect := Deny
+		if in.DefaultEffect == "allow" {
+			effect = Allow
+		}
+		return AuthorizeParams{
+			ObjectKey:     in.Resource,
+			ObjectID:      in.ID,
+			Scope:         Scope(in.Scope),
+			Action:        Action(in.Action),
+			DefaultEffect: effect,
+			Parent:        in.Parent,
+			Info:          "explain",
+		}, nil
+	}
+
+	name := strings.ReplaceAll(strings.TrimPrefix(in.Method, "/"), "/", ".")
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return AuthorizeParams{}, errors.Codef(codes.NotFound, "authz: unknown method %s", in.Method)
+	}
+	md, ok := desc.(protoreflect.MethodDescriptor)
+	if !ok {
+		return AuthorizeParams{}, errors.Codef(codes.InvalidArgument, "authz: %s is not a method", in.Method)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+	if err != nil {
+		return AuthorizeParams{}, errors.Wrap(err, 0).WithCode(codes.Internal)
+	}
+	req := mt.New().Interface()
+	if len(in.Request) > 0 {
+		if err := protojson.Unmarshal(in.Request, req); err != nil {
+			return AuthorizeParams{}, errors.Wrap(err, 0).WithCode(codes.InvalidArgument)
+		}
+	}
+	return MethodParams("/"+string(md.Parent().FullName())+"/"+string(md.Name()), req)
+}
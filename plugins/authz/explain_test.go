@@ -0,0 +1,127 @@
+package authz_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func explainPlugin() *authz.AuthzPlugin {
+	docs := map[string]*testDocument{
+		"1": {id: "1", author: "bob@test.com", title: "Test Document"},
+	}
+	return authz.Plugin(
+		authz.WithDebugEndpoint(),
+		authz.WithRoleHierarchy(authz.RoleEditor, authz.RoleViewer),
+		authz.WithPolicy(authz.Allow, authz.RoleViewer, authz.Action("documents.view")),
+		authz.WithPolicy(authz.Allow, authz.RoleEditor, authz.Action("documents.write")),
+		authz.WithPolicy(authz.Deny, authz.Role("suspended"), authz.Action("documents.write")),
+		authz.WithObjectFetcher("document", authz.AsObjectFetcher(authz.MapFetcher(docs))),
+		authz.WithRoleDescriberFn("document", func(_ context.Context, sub auth.Identity, object any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Email == object.(*testDocument).author {
+				return []authz.Role{authz.RoleEditor}, nil
+			}
+			return nil, nil
+		}),
+	)
+}
+
+func TestExplain(t *testing.T) {
+	ap := explainPlugin()
+	params := authz.AuthorizeParams{ObjectKey: "document", ObjectID: "1", Action: "documents.view"}
+
+	ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: "bob", Email: "bob@test.com"})
+	ex, err := ap.Explain(ctx, params, nil)
+	require.NoError(t, err)
+	assert.Equal(t, authz.Allow, ex.Effect)
+	assert.Equal(t, []authz.Role{authz.RoleEditor}, ex.Roles)
+	assert.Equal(t, []authz.PolicyEvaluation{{Role: authz.RoleViewer, Effect: authz.Allow}}, ex.Policies, "inherited roles are evaluated")
+	assert.Equal(t, "allowed by policy", ex.Reason)
+	assert.Contains(t, ex.Object, "*authz_test.testDocument")
+
+	ctx = auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: "betty", Email: "betty@test.com"})
+	ex, err = ap.Explain(ctx, params, nil)
+	require.NoError(t, err)
+	assert.Equal(t, authz.Deny, ex.Effect)
+	assert.Equal(t, "no roles assigned", ex.Reason)
+
+	// Hypothetical roles replace the role describer.
+	params.Action = "documents.write"
+	ex, err = ap.Explain(ctx, params, []authz.Role{authz.RoleEditor, "suspended"})
+	require.NoError(t, err)
+	assert.True(t, ex.Hypothetical)
+	assert.Equal(t, authz.Deny, ex.Effect)
+	assert.Equal(t, "explicitly denied by role 'suspended'", ex.Reason)
+
+	params.ObjectID = "2"
+	_, err = ap.Explain(ctx, params, nil)
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+}
+
+func TestExplainHandler(t *testing.T) {
+	ap := explainPlugin()
+
+	explain := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/debug/authz/explain", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		ap.ExplainHandler(rec, req)
+		return rec
+	}
+
+	rec := explain(`{
+		"method": "` + authztest.AuthzTestService_SaveDocument_FullMethodName + `",
+		"request": {"documentId": "1"},
+		"identity": {"provider": "test", "subject": "bob", "email": "bob@test.com"}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var out map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Equal(t, "documents.write", out["action"])
+	assert.Equal(t, "document", out["resource"])
+	assert.Equal(t, "bob", out["subject"])
+	assert.Equal(t, "ALLOW", out["effect"])
+	assert.Equal(t, []any{map[string]any{"role": "editor", "effect": "ALLOW"}}, out["policies"])
+
+	rec = explain(`{"action": "documents.view", "resource": "document", "id": "1"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	assert.Equal(t, "DENY", out["effect"], "anonymous callers have no roles")
+
+	rec = explain(`{"method": "/unknown.Service/Method"}`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = explain(`{"identity": {"subject": "bob"}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/authz/explain", nil)
+	rec = httptest.NewRecorder()
+	ap.ExplainHandler(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestMethodParams(t *testing.T) {
+	params, err := authz.MethodParams(authztest.AuthzTestService_CreateDocument_FullMethodName, &authztest.CreateDocumentRequest{OrgId: "o1", FolderId: "f1"})
+	require.NoError(t, err)
+	assert.Equal(t, authz.AuthorizeParams{
+		ObjectKey: "folder",
+		ObjectID:  "f1",
+		Scope:     "o1",
+		Action:    "documents.create",
+		Parent:    true,
+		Info:      authztest.AuthzTestService_CreateDocument_FullMethodName,
+	}, params)
+
+	_, err = authz.MethodParams(authztest.AuthzTestService_NoPolicy_FullMethodName, &authztest.Request{})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
@@ -0,0 +1,211 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ResponseFilter is an interface for modifying a message after the handler has
+// run, for example to remove data the caller isn't authorized to see.
+type ResponseFilter interface {
+	// FilterResponse modifies the message in place
+	FilterResponse(ctx context.Context, msg proto.Message) error
+}
+
+// ResponseFilterFn adapts a function to the ResponseFilter interface.
+type ResponseFilterFn func(ctx context.Context, msg proto.Message) error
+
+// FilterResponse implements the ResponseFilter interface.
+func (f ResponseFilterFn) FilterResponse(ctx context.Context, msg proto.Message) error {
+	return f(ctx, msg)
+}
+
+// WithResponseFilter registers a filter for messages of type T. Filters apply
+// to responses of type T and to messages of type T nested within responses, so
+// a filter for a Document also applies to each Document in a list response.
+//
+// Since Go doesn't support generic methods, this is provided as a package
+// function.
+func WithResponseFilter[T proto.Message](filter func(ctx context.Context, msg T) error) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.RegisterResponseFilter(messageName[T](), ResponseFilterFn(func(ctx context.Context, msg proto.Message) error {
+			typed, ok := msg.(T)
+			if !ok {
+				return errors.Codef(codes.Internal, "authz error: expected message type %T, got %T", *new(T), msg)
+			}
+			return filter(ctx, typed)
+		}))
+	}
+}
+
+// WithListFilter removes elements from a repeated message field on T, unless
+// the caller is allowed to perform the action on the element. Roles are
+// described relative to each element, using the role describer registered for
+// the object key, without a scope.
+//
+// Example:
+//
+//	// Only return documents the caller can view.
+//	authz.WithListFilter[*pb.ListDocumentsResponse]("documents", "document", "documents.view")
+func WithListFilter[T proto.Message](field protoreflect.Name, objectKey string, action Action) AuthzOption {
+	fd := messageField[T](field)
+	if !fd.IsList() || fd.Message() == nil {
+		panic("authz: list filter field '" + string(fd.FullName()) + "' is not a repeated message")
+	}
+	return func(ap *AuthzPlugin) {
+		ap.RegisterResponseFilter(fd.ContainingMessage().FullName(), ResponseFilterFn(func(ctx context.Context, msg proto.Message) error {
+			list := msg.ProtoReflect().Get(fd).List()
+			kept := 0
+			for i := range list.Len() {
+				allowed, err := ap.Can(ctx, objectKey, list.Get(i).Message().Interface(), "", action)
+				if err != nil {
+					return err
+				}
+				if allowed {
+					list.Set(kept, list.Get(i))
+					kept++
+				}
+			}
+			if removed := list.Len() - kept; removed > 0 {
+				list.Truncate(kept)
+				trackFiltered(ctx, removed)
+			}
+			return nil
+		}))
+	}
+}
+
+// WithFieldFilter clears a field on T unless the caller is allowed to perform
+// the action on the message. Roles are described relative to the message,
+// using the role describer registered for the object key, without a scope.
+//
+// Example:
+//
+//	// Only include salaries for callers who can view them.
+//	authz.WithFieldFilter[*pb.Employee]("salary", "employee", "employees.view_salary")
+func WithFieldFilter[T proto.Message](field protoreflect.Name, objectKey string, action Action) AuthzOption {
+	fd := messageField[T](field)
+	return func(ap *AuthzPlugin) {
+		ap.RegisterResponseFilter(fd.ContainingMessage().FullName(), ResponseFilterFn(func(ctx context.Context, msg proto.Message) error {
+			m := msg.ProtoReflect()
+			if !m.Has(fd) {
+				return nil
+			}
+			allowed, err := ap.Can(ctx, objectKey, msg, "", action)
+			if err != nil || allowed {
+				return err
+			}
+			m.Clear(fd)
+			trackFiltered(ctx, 1)
+			return nil
+		}))
+	}
+}
+
+// RegisterResponseFilter registers a filter for messages with the given full
+// name. Multiple filters for the same message are run in registration order.
+func (ap *AuthzPlugin) RegisterResponseFilter(name protoreflect.FullName, filter ResponseFilter) {
+	if ap.responseFilters == nil {
+		ap.responseFilters = make(map[protoreflect.FullName][]ResponseFilter)
+	}
+	ap.responseFilters[name] = append(ap.responseFilters[name], filter)
+}
+
+// Can returns whether the caller is allowed to perform the action on an object
+// which has already been fetched, using the role describer registered for the
+// object key. Unlike Authorize, the result isn't audited.
+func (ap *AuthzPlugin) Can(ctx context.Context, objectKey string, object any, scope Scope, action Action) (bool, error) {
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil && !errors.Is(err, auth.ErrNotFound) {
+		return false, err
+	}
+	roles, err := ap.DescribeRoles(ctx, objectKey, identity, object, scope)
+	if err != nil {
+		return false, err
+	}
+	effect, _ := ap.DetermineEffect(action, roles, Deny)
+	return effect == Allow, nil
+}
+
+// filterResponses wraps a handler so that registered filters are applied to
+// its response.
+func (ap *AuthzPlugin) filterResponses(handler grpc.UnaryHandler) grpc.UnaryHandler {
+	return func(ctx context.Context, req any) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		if msg, ok := resp.(proto.Message); ok && msg != nil {
+			filtered := 0
+			if err := ap.filterMessage(context.WithValue(ctx, filteredKey{}, &filtered), msg.ProtoReflect()); err != nil {
+				return nil, err
+			}
+			if filtered > 0 {
+				logging.Track(ctx, "authz.filtered", filtered)
+			}
+		}
+		return resp, nil
+	}
+}
+
+// filterMessage applies filters to the message before descending into its
+// fields, so that nested messages removed by a list filter aren't visited.
+func (ap *AuthzPlugin) filterMessage(ctx context.Context, m protoreflect.Message) error {
+	if !m.IsValid() {
+		return nil
+	}
+	for _, filter := range ap.responseFilters[m.Descriptor().FullName()] {
+		if err := filter.FilterResponse(ctx, m.Interface()); err != nil {
+			return err
+		}
+	}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := 0; i < l.Len() && err == nil; i++ {
+				err = ap.filterMessage(ctx, l.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = ap.filterMessage(ctx, mv.Message())
+				return err == nil
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			err = ap.filterMessage(ctx, v.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+// filteredKey is the context key for counting the elements and fields removed
+// from a response.
+type filteredKey struct{}
+
+func trackFiltered(ctx context.Context, n int) {
+	if filtered, ok := ctx.Value(filteredKey{}).(*int); ok {
+		*filtered += n
+	}
+}
+
+func messageName[T proto.Message]() protoreflect.FullName {
+	return (*new(T)).ProtoReflect().Descriptor().FullName()
+}
+
+func messageField[T proto.Message](field protoreflect.Name) protoreflect.FieldDescriptor {
+	md := (*new(T)).ProtoReflect().Descriptor()
+	fd := md.Fields().ByName(field)
+	if fd == nil {
+		panic("authz: unknown field '" + string(field) + "' on " + string(md.FullName()))
+	}
+	return fd
+}
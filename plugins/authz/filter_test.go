@@ -0,0 +1,85 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestResponseFilters(t *testing.T) {
+	// Bob can view document 1 and its body, betty can view document 1 but not its
+	// body, and nobody can view document 2.
+	describer := func(_ context.Context, sub auth.Identity, object any, _ authz.Scope) ([]authz.Role, error) {
+		doc := object.(*authztest.GetDocumentResponse)
+		switch {
+		case doc.GetId() != "1":
+			return nil, nil
+		case sub.Subject == "bob":
+			return []authz.Role{authz.RoleEditor}, nil
+		default:
+			return []authz.Role{authz.RoleViewer}, nil
+		}
+	}
+	ap := authz.Plugin(
+		authz.WithRoleHierarchy(authz.RoleEditor, authz.RoleViewer),
+		authz.WithPolicy(authz.Allow, authz.RoleViewer, authz.Action("documents.view")),
+		authz.WithPolicy(authz.Allow, authz.RoleEditor, authz.Action("documents.view_body")),
+		authz.WithRoleDescriberFn("document", describer),
+		authz.WithListFilter[*authztest.ListDocumentsResponse]("documents", "document", "documents.view"),
+		authz.WithFieldFilter[*authztest.GetDocumentResponse]("body", "document", "documents.view_body"),
+	)
+
+	list := func(subject string) *authztest.ListDocumentsResponse {
+		ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: subject})
+		info := &grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_NoPolicy_FullMethodName}
+		resp, err := ap.Interceptor(ctx, &authztest.Request{}, info, func(context.Context, any) (any, error) {
+			return &authztest.ListDocumentsResponse{
+				Documents: []*authztest.GetDocumentResponse{
+					{Id: "1", Title: "One", Body: "Secret"},
+					{Id: "2", Title: "Two", Body: "Secret"},
+				},
+			}, nil
+		})
+		require.NoError(t, err)
+		return resp.(*authztest.ListDocumentsResponse)
+	}
+
+	docs := list("bob").GetDocuments()
+	require.Len(t, docs, 1, "documents without view access should be removed")
+	assert.Equal(t, "Secret", docs[0].GetBody())
+
+	docs = list("betty").GetDocuments()
+	require.Len(t, docs, 1)
+	assert.Equal(t, "One", docs[0].GetTitle())
+	assert.Empty(t, docs[0].GetBody(), "body should be cleared without view_body access")
+}
+
+func TestWithResponseFilter(t *testing.T) {
+	ap := authz.Plugin(
+		authz.WithResponseFilter(func(_ context.Context, resp *authztest.Response) error {
+			resp.Success = false
+			return nil
+		}),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_NoPolicy_FullMethodName}
+	resp, err := ap.Interceptor(t.Context(), &authztest.Request{}, info, func(context.Context, any) (any, error) {
+		return &authztest.Response{Success: true}, nil
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.(*authztest.Response).GetSuccess())
+}
+
+func TestWithListFilter_InvalidField(t *testing.T) {
+	assert.Panics(t, func() {
+		authz.WithListFilter[*authztest.ListDocumentsResponse]("document_ids", "document", "documents.view")
+	})
+	assert.Panics(t, func() {
+		authz.WithFieldFilter[*authztest.GetDocumentResponse]("unknown", "document", "documents.view")
+	})
+}
@@ -0,0 +1,108 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"google.golang.org/grpc/codes"
+)
+
+// GroupMapping maps groups delivered by an identity provider, such as SSO or
+// directory groups, to roles. See auth.Identity.Groups.
+type GroupMapping map[string][]Role
+
+// Roles returns the roles granted by the identity's groups.
+func (m GroupMapping) Roles(identity auth.Identity) []Role {
+	var roles []Role
+	for group := range identity.Groups.All() {
+		roles = append(roles, m[group]...)
+	}
+	return roles
+}
+
+// GroupRoles grants roles based on the identity provider groups the subject
+// belongs to, for use with Compose.
+//
+// Example:
+//
+//	authz.Compose(
+//	    authz.GroupRoles[*Document](authz.GroupMapping{
+//	        "docs-admins": {authz.RoleAdmin},
+//	        "engineering": {authz.RoleViewer},
+//	    }),
+//	    authz.RoleOwner.IfOwner(func(doc *Document) string { return doc.OwnerID }),
+//	)
+func GroupRoles[T any](mapping GroupMapping) TypedRoleDescriber[T] {
+	return func(_ context.Context, subject auth.Identity, _ T, _ Scope) ([]Role, error) {
+		return mapping.Roles(subject), nil
+	}
+}
+
+// WithGroupRoles maps identity provider groups to roles for objects with the
+// given key, in addition to the roles returned by the key's role describer. A
+// describer isn't required when groups are mapped. Mappings registered for '*'
+// apply to all keys.
+//
+// Example:
+//
+//	authz.WithGroupRoles("*", authz.GroupMapping{"platform-admins": {authz.RoleAdmin}})
+//	authz.WithGroupRoles("document", authz.GroupMapping{"writers": {authz.RoleEditor}})
+func WithGroupRoles(objectKey string, mapping GroupMapping) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.RegisterGroupRoles(objectKey, mapping)
+	}
+}
+
+// WithGroupRoles maps identity provider groups to roles for objects with the
+// given key.
+func (b *Builder) WithGroupRoles(objectKey string, mapping GroupMapping) *Builder {
+	b.plugin.RegisterGroupRoles(objectKey, mapping)
+	return b
+}
+
+// RegisterGroupRoles maps identity provider groups to roles for objects with
+// the given key. Multiple registrations for the same key are merged.
+func (ap *AuthzPlugin) RegisterGroupRoles(objectKey string, mapping GroupMapping) {
+	if ap.groupRoles == nil {
+		ap.groupRoles = make(map[string]GroupMapping)
+	}
+	if ap.groupRoles[objectKey] == nil {
+		ap.groupRoles[objectKey] = GroupMapping{}
+	}
+	for group, roles := range mapping {
+		ap.groupRoles[objectKey][group] = append(ap.groupRoles[objectKey][group], roles...)
+	}
+}
+
+// DescribeRoles returns the subject's roles relative to an object which has
+// already been fetched, from the role describer and group mappings registered
+// for the object key.
+func (ap *AuthzPlugin) DescribeRoles(ctx context.Context, objectKey string, subject auth.Identity, object any, scope Scope) ([]Role, error) {
+	if !ap.hasRoleSource(objectKey) {
+		return nil, errors.Codef(codes.Internal, "authz error: no role describer for key '%s'", objectKey)
+	}
+	return ap.describeRoles(ctx, objectKey, subject, object, scope)
+}
+
+// describeRoles returns the subject's roles relative to the object, from the
+// role describer and group mappings for the key.
+func (ap *AuthzPlugin) describeRoles(ctx context.Context, objectKey string, subject auth.Identity, object any, scope Scope) ([]Role, error) {
+	var roles []Role
+	if describer := ap.describerForKey(objectKey); describer != nil {
+		var err error
+		if roles, err = describer.DescribeRoles(ctx, subject, object, scope); err != nil {
+			return nil, err
+		}
+	}
+	roles = append(roles, ap.groupRoles[objectKey].Roles(subject)...)
+	if objectKey != "*" {
+		roles = append(roles, ap.groupRoles["*"].Roles(subject)...)
+	}
+	return roles, nil
+}
+
+// hasRoleSource returns whether roles can be described for the key.
+func (ap *AuthzPlugin) hasRoleSource(objectKey string) bool {
+	return ap.describerForKey(objectKey) != nil || ap.groupRoles[objectKey] != nil || ap.groupRoles["*"] != nil
+}
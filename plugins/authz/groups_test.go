@@ -0,0 +1,64 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestGroupMapping(t *testing.T) {
+	m := authz.GroupMapping{
+		"admins": {authz.RoleAdmin},
+		"eng":    {authz.RoleEditor, authz.RoleViewer},
+	}
+	assert.Equal(t, []authz.Role{authz.RoleEditor, authz.RoleViewer, authz.RoleAdmin}, m.Roles(auth.Identity{Groups: auth.NewGroups("eng", "sales", "admins")}))
+	assert.Empty(t, m.Roles(auth.Identity{}))
+
+	roles, err := authz.GroupRoles[*testDocument](m)(t.Context(), auth.Identity{Groups: auth.NewGroups("admins")}, &testDocument{}, "")
+	require.NoError(t, err)
+	assert.Equal(t, []authz.Role{authz.RoleAdmin}, roles)
+}
+
+func TestWithGroupRoles(t *testing.T) {
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.RoleAdmin, authz.Action("documents.write")),
+		authz.WithPolicy(authz.Allow, authz.RoleViewer, authz.Action("documents.view")),
+		authz.WithObjectFetcherFn("document", func(context.Context, any) (any, error) { return &testDocument{}, nil }),
+		authz.WithGroupRoles("*", authz.GroupMapping{"platform-admins": {authz.RoleAdmin}}),
+		authz.WithGroupRoles("document", authz.GroupMapping{"readers": {authz.RoleViewer}}),
+	)
+
+	tests := []struct {
+		name    string
+		groups  []string
+		method  string
+		allowed bool
+	}{
+		{"reader can view", []string{"readers"}, authztest.AuthzTestService_GetDocument_FullMethodName, true},
+		{"reader can't save", []string{"readers"}, authztest.AuthzTestService_SaveDocument_FullMethodName, false},
+		{"wildcard mapping applies", []string{"platform-admins"}, authztest.AuthzTestService_SaveDocument_FullMethodName, true},
+		{"no groups", nil, authztest.AuthzTestService_GetDocument_FullMethodName, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: "alice", Groups: auth.NewGroups(tt.groups...)})
+			called := false
+			_, err := ap.Interceptor(ctx, &authztest.GetDocumentRequest{DocumentId: "1"}, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			})
+			assert.Equal(t, tt.allowed, called)
+			if tt.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, authz.ErrPermissionDenied)
+			}
+		})
+	}
+}
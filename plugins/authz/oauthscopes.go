@@ -0,0 +1,139 @@
+package authz
+
+import (
+	"context"
+	"log"
+	"slices"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/serverutil"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "authz.requiredScopes",
+			Description: "OAuth scopes mapped to the actions which require them, e.g. write: [documents.write]",
+			Type:        "map",
+		},
+	)
+}
+
+// OAuthScopeProvider exposes the OAuth scopes granted to the current request.
+// The OAuth plugin implements it; the authz plugin discovers it during Init so
+// that required scopes can be enforced without a direct dependency between the
+// two packages.
+type OAuthScopeProvider interface {
+	prefab.Plugin
+
+	// OAuthScopes returns the scopes granted to the request's access token, and
+	// whether the request was authenticated with an OAuth token at all.
+	OAuthScopes(ctx context.Context) ([]string, bool)
+}
+
+// WithRequiredScope requires requests authenticated with an OAuth access token
+// to carry the given scope in order to perform the action. The check is in
+// addition to the usual policy evaluation and does not apply to first-party
+// requests, such as those authenticated with a session cookie.
+//
+// Example:
+//
+//	authz.WithRequiredScope("documents.write", "write")
+func WithRequiredScope(action Action, scope string) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.RequireScope(action, scope)
+	}
+}
+
+// WithOAuthScopeProvider sets the provider used to read OAuth scopes from the
+// request. By default the registered plugin implementing OAuthScopeProvider,
+// usually the OAuth plugin, is used.
+func WithOAuthScopeProvider(provider OAuthScopeProvider) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.scopeProvider = provider
+	}
+}
+
+// WithRequiredScope requires OAuth-authenticated requests to carry the scope in
+// order to perform the action.
+func (b *Builder) WithRequiredScope(action Action, scope string) *Builder {
+	b.plugin.RequireScope(action, scope)
+	return b
+}
+
+// RequireScope requires OAuth-authenticated requests to carry the scope in
+// order to perform the action.
+func (ap *AuthzPlugin) RequireScope(action Action, scope string) {
+	if ap.requiredScopes == nil {
+		ap.requiredScopes = make(map[Action]string)
+	}
+	ap.requiredScopes[action] = scope
+}
+
+// From prefab.InitializablePlugin, loads required scopes from config and looks
+// up the OAuth scope provider.
+func (ap *AuthzPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	c := prefab.Config.Cut("authz.requiredScopes")
+	for _, scope := range c.MapKeys("") {
+		for _, action := range c.Strings(scope) {
+			// Options take precedence over config.
+			if _, ok := ap.requiredScopes[Action(action)]; !ok {
+				ap.RequireScope(Action(action), scope)
+			}
+		}
+	}
+
+	if ap.scopeProvider == nil {
+		if provider, ok := prefab.GetPlugin[OAuthScopeProvider](r); ok {
+			ap.scopeProvider = provider
+		}
+	}
+	if len(ap.requiredScopes) > 0 && ap.scopeProvider == nil {
+		log.Println("⚠️  WARNING: authz required scopes are configured but no OAuth " +
+			"plugin is registered, so they will not be enforced.")
+	}
+	return nil
+}
+
+// checkRequiredScope denies OAuth-authenticated requests whose token lacks the
+// scope required for the action. Requests that were not authenticated via
+// OAuth are not affected.
+func (ap *AuthzPlugin) checkRequiredScope(ctx context.Context, cfg AuthorizeParams) error {
+	scope, ok := ap.requiredScopes[cfg.Action]
+	if !ok || ap.scopeProvider == nil {
+		return nil
+	}
+	granted, isOAuth := ap.scopeProvider.OAuthScopes(ctx)
+	if !isOAuth || slices.Contains(granted, scope) {
+		return nil
+	}
+
+	identity, _ := auth.IdentityFromContext(ctx)
+	decision := AuthzDecision{
+		Action:        cfg.Action,
+		Resource:      cfg.ObjectKey,
+		ObjectID:      cfg.ObjectID,
+		Scope:         cfg.Scope,
+		Identity:      identity,
+		Effect:        Deny,
+		DefaultEffect: cfg.DefaultEffect,
+		Reason:        "missing oauth scope",
+	}
+	decision.Request, _ = serverutil.RequestInfoFromContext(ctx)
+
+	logging.Track(ctx, "authz.action", cfg.Action)
+	logging.Track(ctx, "authz.requiredScope", scope)
+	logging.Track(ctx, "authz.reason", decision.Reason)
+
+	if ap.auditLogger != nil {
+		ap.auditLogger(ctx, decision)
+	}
+
+	return errors.WithUserPresentableMessage(
+		errors.Mark(ErrPermissionDenied, 0),
+		"Access denied: the access token is missing the %q scope required for %s", scope, cfg.Action,
+	)
+}
@@ -0,0 +1,106 @@
+package authz
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// ParentFetcher fetches the parent of a collection, such as the folder a
+// document is being created in, for collection-level actions like create and
+// list where no object exists yet.
+type ParentFetcher interface {
+	// FetchParent retrieves the parent based on the provided key
+	FetchParent(ctx context.Context, key any) (any, error)
+}
+
+// ParentFetcherFn adapts a function to the ParentFetcher interface.
+type ParentFetcherFn func(ctx context.Context, key any) (any, error)
+
+// FetchParent implements the ParentFetcher interface.
+func (f ParentFetcherFn) FetchParent(ctx context.Context, key any) (any, error) {
+	return f(ctx, key)
+}
+
+// AsParentFetcher converts a TypedObjectFetcher to the ParentFetcher interface,
+// so the fetcher patterns can be used for parents.
+func AsParentFetcher[K comparable, T any](fetcher TypedObjectFetcher[K, T]) ParentFetcher {
+	return ParentFetcherFn(AsObjectFetcher(fetcher).FetchObject)
+}
+
+// WithParentFetcher adds a parent fetcher to the plugin.
+//
+// Example:
+//
+//	authz.WithParentFetcher("folder", authz.AsParentFetcher(
+//		authz.Fetcher(db.GetFolderByID),
+//	))
+func WithParentFetcher(parentKey string, fetcher ParentFetcher) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.RegisterParentFetcher(parentKey, fetcher)
+	}
+}
+
+// WithParentFetcherFn adds a function-based parent fetcher to the plugin.
+func WithParentFetcherFn(parentKey string, fetcher func(ctx context.Context, key any) (any, error)) AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.RegisterParentFetcher(parentKey, ParentFetcherFn(fetcher))
+	}
+}
+
+// WithParentFetcher adds a parent fetcher to the builder.
+func (b *Builder) WithParentFetcher(parentKey string, fetcher ParentFetcher) *Builder {
+	b.plugin.RegisterParentFetcher(parentKey, fetcher)
+	return b
+}
+
+// WithParentFetcherFn adds a function-based parent fetcher to the builder.
+func (b *Builder) WithParentFetcherFn(parentKey string, fetcher func(ctx context.Context, key any) (any, error)) *Builder {
+	b.plugin.RegisterParentFetcher(parentKey, ParentFetcherFn(fetcher))
+	return b
+}
+
+// RegisterParentFetcher registers a parent fetcher for a specified parent key.
+// '*' can be used as a wildcard to match any key which doesn't have a more
+// specific fetcher. Roles are described using the role describer registered for
+// the same key.
+func (ap *AuthzPlugin) RegisterParentFetcher(parentKey string, fetcher ParentFetcher) {
+	if ap.parentFetchers == nil {
+		ap.parentFetchers = make(map[string]ParentFetcher)
+	}
+	ap.parentFetchers[parentKey] = fetcher
+}
+
+func (ap *AuthzPlugin) parentFetcherForKey(parentKey string) ParentFetcher {
+	if fetcher, ok := ap.parentFetchers[parentKey]; ok {
+		return fetcher
+	}
+	if fetcher, ok := ap.parentFetchers["*"]; ok {
+		return fetcher
+	}
+	return nil
+}
+
+// MethodParent returns the parent key for collection-level methods, configured
+// with the `parent` method option.
+func MethodParent(info *grpc.UnaryServerInfo) (string, bool) {
+	v, ok := serverutil.MethodOption(info, E_Parent)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// ParentField returns the value of the request field tagged with the
+// `parent_id` option.
+func ParentField(req proto.Message) (any, error) {
+	v, ok := serverutil.FieldOption(req, E_ParentId)
+	if !ok || len(v) != 1 {
+		return nil, errors.Codef(codes.Internal, "authz error: require exactly one parent_id on request descriptor: %s", req.ProtoReflect().Descriptor().FullName())
+	}
+	return v[0].FieldValue, nil
+}
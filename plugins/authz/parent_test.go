@@ -0,0 +1,110 @@
+package authz_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+type testFolder struct {
+	id      string
+	editors []string
+}
+
+func TestInterceptor_Parent(t *testing.T) {
+	folders := map[string]*testFolder{
+		"f1": {id: "f1", editors: []string{"bob"}},
+	}
+	var decisions []authz.AuthzDecision
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.RoleEditor, authz.Action("documents.create")),
+		authz.WithParentFetcher("folder", authz.AsParentFetcher(authz.MapFetcher(folders))),
+		authz.WithRoleDescriberFn("folder", func(_ context.Context, sub auth.Identity, object any, _ authz.Scope) ([]authz.Role, error) {
+			if slices.Contains(object.(*testFolder).editors, sub.Subject) {
+				return []authz.Role{authz.RoleEditor}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithAuditLogger(func(_ context.Context, d authz.AuthzDecision) {
+			decisions = append(decisions, d)
+		}),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_CreateDocument_FullMethodName}
+
+	tests := []struct {
+		name     string
+		subject  string
+		folderID string
+		code     codes.Code
+	}{
+		{"editor can create", "bob", "f1", codes.OK},
+		{"non-editor denied", "betty", "f1", codes.PermissionDenied},
+		{"missing folder", "bob", "f2", codes.NotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions = nil
+			called := false
+			ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: tt.subject})
+			req := &authztest.CreateDocumentRequest{OrgId: "o1", FolderId: tt.folderID}
+			_, err := ap.Interceptor(ctx, req, info, func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			})
+			assert.Equal(t, tt.code, errors.Code(err), "unexpected error: %v", err)
+			assert.Equal(t, tt.code == codes.OK, called)
+			if tt.code != codes.NotFound {
+				require.Len(t, decisions, 1)
+				assert.True(t, decisions[0].Parent)
+				assert.Equal(t, "folder", decisions[0].Resource)
+				assert.Equal(t, tt.folderID, decisions[0].ObjectID)
+				assert.Equal(t, authz.Scope("o1"), decisions[0].Scope)
+			}
+		})
+	}
+}
+
+func TestInterceptor_ParentFetcherRequired(t *testing.T) {
+	// Object fetchers aren't used for parents.
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.RoleEditor, authz.Action("documents.create")),
+		authz.WithObjectFetcherFn("*", func(context.Context, any) (any, error) { return 1, nil }),
+		authz.WithRoleDescriberFn("*", func(context.Context, auth.Identity, any, authz.Scope) ([]authz.Role, error) {
+			return []authz.Role{authz.RoleEditor}, nil
+		}),
+	)
+	ctx := auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: "bob"})
+	info := &grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_CreateDocument_FullMethodName}
+	_, err := ap.Interceptor(ctx, &authztest.CreateDocumentRequest{FolderId: "f1"}, info, func(context.Context, any) (any, error) {
+		t.Fatal("handler should not be called")
+		return nil, nil
+	})
+	require.Error(t, err)
+	assert.Equal(t, codes.Internal, errors.Code(err))
+	assert.Contains(t, err.Error(), "no parent fetcher for key 'folder'")
+}
+
+func TestParentField(t *testing.T) {
+	v, err := authz.ParentField(&authztest.CreateDocumentRequest{FolderId: "f1"})
+	require.NoError(t, err)
+	assert.Equal(t, "f1", v)
+
+	_, err = authz.ParentField(&authztest.Request{})
+	assert.Equal(t, codes.Internal, errors.Code(err))
+
+	key, ok := authz.MethodParent(&grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_CreateDocument_FullMethodName})
+	assert.True(t, ok)
+	assert.Equal(t, "folder", key)
+
+	_, ok = authz.MethodParent(&grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_GetDocument_FullMethodName})
+	assert.False(t, ok)
+}
@@ -2,6 +2,7 @@ package authz
 
 import (
 	"context"
+	"strings"
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/auth"
@@ -143,6 +144,34 @@ func GlobalRole[T any](role Role, predicate func(context.Context) bool) TypedRol
 	}
 }
 
+// ServiceRole grants a role to peer services, such as those authenticated by
+// client certificate (see the auth/mtls plugin), whose identity matches one of
+// the principals. See auth.Identity.IsService. A principal ending in "*" matches any identity with that prefix.
+//
+// Example:
+//
+//	authz.ServiceRole[*Invoice](roleBillingService,
+//	    "spiffe://prod.example.com/billing",
+//	    "spiffe://prod.example.com/reports/*",
+//	)
+func ServiceRole[T any](role Role, principals ...string) TypedRoleDescriber[T] {
+	return StaticRole(role, func(_ context.Context, subject auth.Identity, _ T, _ Scope) bool {
+		if !subject.IsService() || subject.Subject == "" {
+			return false
+		}
+		for _, p := range principals {
+			if prefix, ok := strings.CutSuffix(p, "*"); ok {
+				if strings.HasPrefix(subject.Subject, prefix) {
+					return true
+				}
+			} else if subject.Subject == p {
+				return true
+			}
+		}
+		return false
+	})
+}
+
 // OwnershipRole grants a role if the subject owns the object.
 // This is a common pattern for granting elevated permissions to resource creators.
 // Returns no roles for anonymous users (zero-value Identity).
@@ -148,6 +148,38 @@ func TestGlobalRole(t *testing.T) {
 	})
 }
 
+func TestServiceRole(t *testing.T) {
+	const serviceRole = authz.Role("billing-service")
+	doc := testDoc{id: "1", orgID: "org1"}
+	describer := authz.ServiceRole[testDoc](serviceRole,
+		"spiffe://prod.example.com/billing",
+		"spiffe://prod.example.com/reports/*",
+	)
+
+	cases := []struct {
+		name     string
+		identity auth.Identity
+		want     bool
+	}{
+		{"exact match", auth.Identity{Provider: auth.ServiceProvider, Subject: "spiffe://prod.example.com/billing"}, true},
+		{"prefix match", auth.Identity{Provider: auth.ServiceProvider, Subject: "spiffe://prod.example.com/reports/daily"}, true},
+		{"no match", auth.Identity{Provider: auth.ServiceProvider, Subject: "spiffe://prod.example.com/web"}, false},
+		{"other provider", auth.Identity{Provider: "google", Subject: "spiffe://prod.example.com/billing"}, false},
+		{"anonymous", auth.Identity{}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			roles, err := describer(t.Context(), c.identity, doc, "org1")
+			require.NoError(t, err)
+			if c.want {
+				assert.Equal(t, []authz.Role{serviceRole}, roles)
+			} else {
+				assert.Empty(t, roles)
+			}
+		})
+	}
+}
+
 func TestOwnershipRole(t *testing.T) {
 	ownerIdentity := auth.Identity{Subject: "user123"}
 	otherIdentity := auth.Identity{Subject: "user456"}
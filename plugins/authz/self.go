@@ -0,0 +1,104 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// WithoutDelegatedSelf stops delegated identities, where an admin has assumed
+// a user's identity, from satisfying `self` checks. Such requests fall back to
+// the method's policies, if any.
+func WithoutDelegatedSelf() AuthzOption {
+	return func(ap *AuthzPlugin) {
+		ap.denyDelegatedSelf = true
+	}
+}
+
+// WithoutDelegatedSelf stops delegated identities from satisfying `self`
+// checks.
+func (b *Builder) WithoutDelegatedSelf() *Builder {
+	b.plugin.denyDelegatedSelf = true
+	return b
+}
+
+// SelfField returns the value of the request field tagged with the `self`
+// option, and whether the request has one.
+//
+// Example:
+//
+//	message GetProfileRequest {
+//	  string user_id = 1 [(prefab.authz.self) = true];
+//	}
+func SelfField(req proto.Message) (string, bool, error) {
+	v, ok := serverutil.FieldOption(req, E_Self)
+	if !ok {
+		return "", false, nil
+	}
+	if len(v) != 1 {
+		return "", false, errors.Codef(codes.Internal, "authz error: expected exactly one self field on request descriptor: %s", req.ProtoReflect().Descriptor().FullName())
+	}
+	return fmt.Sprint(v[0].FieldValue), true, nil
+}
+
+// AuthorizeSelf verifies that the caller's subject is the given subject, for
+// example in HTTP handlers which act on a user ID from the route.
+func (ap *AuthzPlugin) AuthorizeSelf(ctx context.Context, subject string) error {
+	return ap.authorizeSelf(ctx, subject, "")
+}
+
+// authorizeSelf checks the caller is the subject. If an action is given, the
+// caller falls back to the action's policies when the check fails, so only
+// allowed decisions are audited here.
+func (ap *AuthzPlugin) authorizeSelf(ctx context.Context, subject string, action Action) error {
+	identity, err := auth.IdentityFromContext(ctx)
+	if errors.Is(err, auth.ErrNotFound) {
+		logging.Track(ctx, "authz.reason", "unauthenticated")
+		return errors.Mark(ErrUnauthenticated, 0)
+	} else if err != nil {
+		logging.Track(ctx, "authz.reason", "authentication error")
+		return err
+	}
+
+	decision := AuthzDecision{
+		Action:   action,
+		Resource: "self",
+		ObjectID: subject,
+		Identity: identity,
+		Effect:   Deny,
+	}
+	decision.Request, _ = serverutil.RequestInfoFromContext(ctx)
+
+	switch {
+	case subject == "" || identity.Subject != subject:
+		decision.Reason = "not self"
+	case auth.IsDelegated(identity) && ap.denyDelegatedSelf:
+		decision.Reason = "delegated identity"
+	default:
+		decision.Effect = Allow
+		decision.Reason = "self"
+	}
+
+	logging.Track(ctx, "authz.self", subject)
+	logging.Track(ctx, "authz.reason", decision.Reason)
+	if sub, _, _, ok := auth.GetDelegator(identity); ok {
+		logging.Track(ctx, "authz.delegator", sub)
+	}
+	if ap.auditLogger != nil && (decision.Effect == Allow || action == "") {
+		ap.auditLogger(ctx, decision)
+	}
+
+	if decision.Effect == Allow {
+		return nil
+	}
+	return errors.WithUserPresentableMessage(
+		errors.Mark(ErrPermissionDenied, 0),
+		"Access denied: %s", decision.Reason,
+	)
+}
@@ -0,0 +1,114 @@
+package authz_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/authz/authztest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// delegated is alice's identity, assumed by a support user.
+var delegated = auth.Identity{
+	Provider: "test",
+	Subject:  "alice",
+	Delegation: &auth.DelegationInfo{
+		DelegatorSub:       "support",
+		DelegatorProvider:  "test",
+		DelegatorSessionId: "session-1",
+		Reason:             "ticket-1",
+		DelegatedAt:        time.Now().Unix(),
+	},
+}
+
+func TestSelfField(t *testing.T) {
+	v, ok, err := authz.SelfField(&authztest.ProfileRequest{UserId: "alice"})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "alice", v)
+
+	_, ok, err = authz.SelfField(&authztest.Request{})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestInterceptor_Self(t *testing.T) {
+	var decisions []authz.AuthzDecision
+	ap := authz.Plugin(
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action("profiles.write")),
+		authz.WithObjectFetcherFn("*", func(context.Context, any) (any, error) { return 1, nil }),
+		authz.WithRoleDescriberFn("*", func(_ context.Context, sub auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Subject == "admin" {
+				return []authz.Role{"admin"}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithAuditLogger(func(_ context.Context, d authz.AuthzDecision) {
+			decisions = append(decisions, d)
+		}),
+	)
+
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		method   string
+		userID   string
+		err      error
+		reason   string
+	}{
+		{"own profile", auth.Identity{Provider: "test", Subject: "alice"}, authztest.AuthzTestService_GetProfile_FullMethodName, "alice", nil, "self"},
+		{"other profile", auth.Identity{Provider: "test", Subject: "bob"}, authztest.AuthzTestService_GetProfile_FullMethodName, "alice", authz.ErrPermissionDenied, "not self"},
+		{"empty field", auth.Identity{Provider: "test", Subject: "alice"}, authztest.AuthzTestService_GetProfile_FullMethodName, "", authz.ErrPermissionDenied, "not self"},
+		{"unauthenticated", auth.Identity{}, authztest.AuthzTestService_GetProfile_FullMethodName, "alice", authz.ErrUnauthenticated, ""},
+		{"delegated", delegated, authztest.AuthzTestService_GetProfile_FullMethodName, "alice", nil, "self"},
+		{"update own profile", auth.Identity{Provider: "test", Subject: "alice"}, authztest.AuthzTestService_UpdateProfile_FullMethodName, "alice", nil, "self"},
+		{"admin falls back to policy", auth.Identity{Provider: "test", Subject: "admin"}, authztest.AuthzTestService_UpdateProfile_FullMethodName, "alice", nil, "allowed by policy"},
+		{"other user denied by policy", auth.Identity{Provider: "test", Subject: "bob"}, authztest.AuthzTestService_UpdateProfile_FullMethodName, "alice", authz.ErrPermissionDenied, "no roles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decisions = nil
+			called := false
+			ctx := auth.WithIdentityForTest(t.Context(), tt.identity)
+			_, err := ap.Interceptor(ctx, &authztest.ProfileRequest{UserId: tt.userID}, &grpc.UnaryServerInfo{FullMethod: tt.method}, func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				assert.False(t, called)
+			} else {
+				require.NoError(t, err)
+				assert.True(t, called)
+			}
+			if tt.reason != "" {
+				require.Len(t, decisions, 1, "exactly one decision is audited")
+				assert.Equal(t, tt.reason, decisions[0].Reason)
+			}
+		})
+	}
+}
+
+func TestWithoutDelegatedSelf(t *testing.T) {
+	ap := authz.Plugin(authz.WithoutDelegatedSelf())
+	ctx := auth.WithIdentityForTest(t.Context(), delegated)
+
+	err := ap.AuthorizeSelf(ctx, "alice")
+	require.ErrorIs(t, err, authz.ErrPermissionDenied)
+
+	req := proto.Message(&authztest.ProfileRequest{UserId: "alice"})
+	_, err = ap.Interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: authztest.AuthzTestService_GetProfile_FullMethodName}, func(context.Context, any) (any, error) {
+		t.Fatal("handler should not be called")
+		return nil, nil
+	})
+	require.ErrorIs(t, err, authz.ErrPermissionDenied)
+
+	ctx = auth.WithIdentityForTest(t.Context(), auth.Identity{Provider: "test", Subject: "alice"})
+	require.NoError(t, ap.AuthorizeSelf(ctx, "alice"))
+}
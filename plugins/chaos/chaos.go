@@ -0,0 +1,406 @@
+// Package chaos injects latency, errors, and dropped requests into RPCs, so
+// that client retry logic, timeouts, and SLO alerts can be validated against a
+// prefab server without external tooling. It is intended for development and
+// staging environments.
+//
+// Faults match requests by method pattern, caller subject, or both, and apply
+// to a percentage of matching requests. They can be configured:
+//
+//	chaos:
+//	  faults:
+//	    - name: slow-notes
+//	      method: /notes.NoteService/*
+//	      latency: 2s
+//	      percent: 25
+//	    - name: flaky-update
+//	      method: /notes.NoteService/Update
+//	      code: UNAVAILABLE
+//	      percent: 10
+//
+// Or added at runtime, with an expiry, via AddFault or the ChaosService on the
+// admin listener. Access to the service is denied unless an authz policy allows
+// the ManageAction, for example:
+//
+//	prefab.WithPlugin(authz.Plugin(
+//	    authz.WithRoleDescriberFn(chaos.ObjectKey, describeAdmins),
+//	    authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(chaos.ManageAction)),
+//	)),
+package chaos
+
+import (
+	"context"
+	"math/rand/v2"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/authz"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "chaos.faults",
+			Description: "Faults to inject, e.g. [{name, method, subject, percent, latency, code, message, drop}]",
+			Type:        "list",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "chaos"
+
+	// authz action for managing faults via the ChaosService.
+	ManageAction = "chaos.manage"
+
+	// authz object key used to scope RoleDescribers.
+	ObjectKey = "chaos"
+
+	// How long faults added via the ChaosService last by default.
+	defaultDuration = 15 * time.Minute
+)
+
+// Fault describes a failure injected into matching requests. At least one of
+// Latency, Code, or Drop must be set. When several are set, the latency is
+// added before the request fails.
+type Fault struct {
+	// Identifies the fault, so it can be replaced or removed.
+	Name string
+
+	// Pattern of full GRPC method names, using the syntax of path.Match, e.g.
+	// "/notes.NoteService/*". Empty matches any method.
+	Method string
+
+	// Subject of the caller's identity. Empty matches any caller.
+	Subject string
+
+	// Percentage of matching requests the fault applies to, from 0 to 100.
+	// Defaults to 100.
+	Percent float64
+
+	// Delay added before the request is handled.
+	Latency time.Duration
+
+	// Status code to fail the request with, instead of handling it.
+	Code codes.Code
+
+	// Message returned with Code. Defaults to a message naming the fault.
+	Message string
+
+	// Drop the request, so it never receives a response and the client waits
+	// until its deadline.
+	Drop bool
+}
+
+// ChaosOption allows configuration of the ChaosPlugin.
+type ChaosOption func(*ChaosPlugin)
+
+// WithFault adds a fault which doesn't expire, in addition to those in
+// `chaos.faults`.
+func WithFault(f Fault) ChaosOption {
+	return func(p *ChaosPlugin) {
+		p.initial = append(p.initial, f)
+	}
+}
+
+// Plugin returns a new ChaosPlugin.
+func Plugin(opts ...ChaosOption) *ChaosPlugin {
+	initial, err := faultsFromConfig()
+	p := &ChaosPlugin{
+		initial:   initial,
+		configErr: err,
+		faults:    map[string]*activeFault{},
+		now:       time.Now,
+		sample:    rand.Float64, //nolint:gosec // Sampling doesn't need a secure source.
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ChaosPlugin injects faults into matching requests.
+type ChaosPlugin struct {
+	initial   []Fault
+	configErr error
+
+	mu     sync.RWMutex
+	faults map[string]*activeFault
+
+	now    func() time.Time
+	sample func() float64
+}
+
+// activeFault is a fault along with its expiry, zero if it doesn't expire.
+type activeFault struct {
+	Fault
+	expires time.Time
+}
+
+// From prefab.Plugin.
+func (p *ChaosPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *ChaosPlugin) Deps() []string {
+	return []string{authz.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *ChaosPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		// Runs after other observability interceptors, so that metrics and SLOs
+		// see the injected faults.
+		prefab.WithGRPCInterceptor(p.interceptor,
+			prefab.InterceptorPhase(prefab.PhaseObservability),
+			prefab.InterceptorPriority(100)),
+	}
+}
+
+// From prefab.AdminOptionProvider.
+func (p *ChaosPlugin) AdminOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithAdminGRPCService(&ChaosService_ServiceDesc, &impl{p: p}),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *ChaosPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.configErr != nil {
+		return p.configErr
+	}
+	az := r.Get(authz.PluginName).(*authz.AuthzPlugin)
+	az.RegisterObjectFetcher(ObjectKey, authz.ObjectFetcherFn(func(ctx context.Context, _ any) (any, error) {
+		return p, nil
+	}))
+	for _, f := range p.initial {
+		if err := p.AddFault(f, 0); err != nil {
+			return err
+		}
+	}
+	logging.Warn(ctx, "chaos: fault injection is enabled, do not enable in production")
+	return nil
+}
+
+// AddFault injects a fault into matching requests, replacing any fault with the
+// same name. The fault is removed after ttl, or never if ttl is zero.
+func (p *ChaosPlugin) AddFault(f Fault, ttl time.Duration) error {
+	_, err := p.addFault(f, ttl)
+	return err
+}
+
+func (p *ChaosPlugin) addFault(f Fault, ttl time.Duration) (*activeFault, error) {
+	if err := validate(f); err != nil {
+		return nil, err
+	}
+	now := p.now()
+	af := &activeFault{Fault: f}
+	if ttl > 0 {
+		af.expires = now.Add(ttl)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, f := range p.faults {
+		if f.expired(now) {
+			delete(p.faults, name)
+		}
+	}
+	p.faults[f.Name] = af
+	return af, nil
+}
+
+// RemoveFault removes a fault, returning false if there was no fault with the
+// name.
+func (p *ChaosPlugin) RemoveFault(name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.faults[name]
+	delete(p.faults, name)
+	return ok
+}
+
+// Faults returns the active faults, sorted by name.
+func (p *ChaosPlugin) Faults() []*InjectedFault {
+	now := p.now()
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]*InjectedFault, 0, len(p.faults))
+	for _, f := range p.faults {
+		if f.expired(now) {
+			continue
+		}
+		out = append(out, f.proto())
+	}
+	slices.SortFunc(out, func(a, b *InjectedFault) int {
+		return strings.Compare(a.GetName(), b.GetName())
+	})
+	return out
+}
+
+func (p *ChaosPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	f := p.match(ctx, info.FullMethod)
+	if f == nil {
+		return handler(ctx, req)
+	}
+	logging.Track(ctx, "chaos.fault", f.Name)
+
+	if f.Latency > 0 {
+		t := time.NewTimer(f.Latency)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case <-t.C:
+		}
+	}
+	if f.Drop {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	if f.Code != codes.OK {
+		msg := f.Message
+		if msg == "" {
+			msg = "chaos: injected by fault " + strconv.Quote(f.Name)
+		}
+		return nil, errors.NewC(msg, f.Code)
+	}
+	return handler(ctx, req)
+}
+
+// match returns the first fault, by name, which matches the request and is
+// sampled, or nil. The subject is only looked up if a fault requires it.
+func (p *ChaosPlugin) match(ctx context.Context, method string) *Fault {
+	now := p.now()
+	p.mu.RLock()
+	candidates := make([]*activeFault, 0, len(p.faults))
+	for _, f := range p.faults {
+		if f.expired(now) {
+			continue
+		}
+		if ok, _ := path.Match(f.Method, method); f.Method != "" && !ok {
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+	p.mu.RUnlock()
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	slices.SortFunc(candidates, func(a, b *activeFault) int { return strings.Compare(a.Name, b.Name) })
+	var subject *string
+	for _, f := range candidates {
+		if f.Subject != "" {
+			if subject == nil {
+				s := logging.SubjectFromContext(ctx)
+				subject = &s
+			}
+			if *subject != f.Subject {
+				continue
+			}
+		}
+		if percent := f.percent(); percent >= 100 || p.sample()*100 < percent {
+			return &f.Fault
+		}
+	}
+	return nil
+}
+
+func (f *activeFault) expired(now time.Time) bool {
+	return !f.expires.IsZero() && !now.Before(f.expires)
+}
+
+func (f *Fault) percent() float64 {
+	if f.Percent == 0 {
+		return 100
+	}
+	return f.Percent
+}
+
+func (f *activeFault) proto() *InjectedFault {
+	out := &InjectedFault{
+		Name:    f.Name,
+		Method:  f.Method,
+		Subject: f.Subject,
+		Percent: f.percent(),
+		Message: f.Message,
+		Drop:    f.Drop,
+	}
+	if f.Latency > 0 {
+		out.Latency = durationpb.New(f.Latency)
+	}
+	if f.Code != codes.OK {
+		out.Code = code.Code_name[int32(f.Code)] //nolint:gosec // Codes are small.
+	}
+	if !f.expires.IsZero() {
+		out.ExpireTime = timestamppb.New(f.expires)
+	}
+	return out
+}
+
+func validate(f Fault) error {
+	if f.Name == "" {
+		return errors.NewC("chaos: fault has no name", codes.InvalidArgument)
+	}
+	if _, err := path.Match(f.Method, ""); err != nil {
+		return errors.Codef(codes.InvalidArgument, "chaos: invalid method pattern %q for fault %q", f.Method, f.Name)
+	}
+	if f.Percent < 0 || f.Percent > 100 {
+		return errors.Codef(codes.InvalidArgument, "chaos: percent for fault %q must be between 0 and 100, got %v", f.Name, f.Percent)
+	}
+	if f.Latency < 0 {
+		return errors.Codef(codes.InvalidArgument, "chaos: latency for fault %q must not be negative", f.Name)
+	}
+	if f.Latency == 0 && f.Code == codes.OK && !f.Drop {
+		return errors.Codef(codes.InvalidArgument, "chaos: fault %q has no latency, code, or drop", f.Name)
+	}
+	return nil
+}
+
+// parseCode parses a status code name, e.g. "UNAVAILABLE". Empty is OK.
+func parseCode(name string) (codes.Code, error) {
+	if name == "" {
+		return codes.OK, nil
+	}
+	c, ok := code.Code_value[strings.ToUpper(name)]
+	if !ok {
+		return codes.OK, errors.Codef(codes.InvalidArgument, "chaos: unknown status code %q", name)
+	}
+	return codes.Code(c), nil //nolint:gosec // Codes are small.
+}
+
+// faultsFromConfig reads faults from `chaos.faults`.
+func faultsFromConfig() ([]Fault, error) {
+	var out []Fault
+	for _, c := range prefab.Config.Slices("chaos.faults") {
+		sc, err := parseCode(c.String("code"))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Fault{
+			Name:    c.String("name"),
+			Method:  c.String("method"),
+			Subject: c.String("subject"),
+			Percent: c.Float64("percent"),
+			Latency: c.Duration("latency"),
+			Code:    sc,
+			Message: c.String("message"),
+			Drop:    c.Bool("drop"),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,466 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/chaos/chaos.proto
+
+package chaos
+
+import (
+	_ "github.com/dpup/prefab/plugins/authz"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Empty request object.
+type ListFaultsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFaultsRequest) Reset() {
+	*x = ListFaultsRequest{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFaultsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFaultsRequest) ProtoMessage() {}
+
+func (x *ListFaultsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFaultsRequest.ProtoReflect.Descriptor instead.
+func (*ListFaultsRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{0}
+}
+
+type ListFaultsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Faults        []*InjectedFault       `protobuf:"bytes,1,rep,name=faults,proto3" json:"faults,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListFaultsResponse) Reset() {
+	*x = ListFaultsResponse{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListFaultsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListFaultsResponse) ProtoMessage() {}
+
+func (x *ListFaultsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListFaultsResponse.ProtoReflect.Descriptor instead.
+func (*ListFaultsResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListFaultsResponse) GetFaults() []*InjectedFault {
+	if x != nil {
+		return x.Faults
+	}
+	return nil
+}
+
+type AddFaultRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Fault *InjectedFault         `protobuf:"bytes,1,opt,name=fault,proto3" json:"fault,omitempty"`
+	// How long until the fault expires, defaults to 15 minutes.
+	Duration      *durationpb.Duration `protobuf:"bytes,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddFaultRequest) Reset() {
+	*x = AddFaultRequest{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddFaultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddFaultRequest) ProtoMessage() {}
+
+func (x *AddFaultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddFaultRequest.ProtoReflect.Descriptor instead.
+func (*AddFaultRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AddFaultRequest) GetFault() *InjectedFault {
+	if x != nil {
+		return x.Fault
+	}
+	return nil
+}
+
+func (x *AddFaultRequest) GetDuration() *durationpb.Duration {
+	if x != nil {
+		return x.Duration
+	}
+	return nil
+}
+
+type RemoveFaultRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFaultRequest) Reset() {
+	*x = RemoveFaultRequest{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFaultRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFaultRequest) ProtoMessage() {}
+
+func (x *RemoveFaultRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFaultRequest.ProtoReflect.Descriptor instead.
+func (*RemoveFaultRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RemoveFaultRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type RemoveFaultResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether a fault with the name existed.
+	Removed       bool `protobuf:"varint,1,opt,name=removed,proto3" json:"removed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveFaultResponse) Reset() {
+	*x = RemoveFaultResponse{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveFaultResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveFaultResponse) ProtoMessage() {}
+
+func (x *RemoveFaultResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveFaultResponse.ProtoReflect.Descriptor instead.
+func (*RemoveFaultResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RemoveFaultResponse) GetRemoved() bool {
+	if x != nil {
+		return x.Removed
+	}
+	return false
+}
+
+// InjectedFault describes a failure injected into matching requests.
+type InjectedFault struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Identifies the fault, required.
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// Pattern of full GRPC method names, e.g. "/notes.NoteService/*". Empty
+	// matches any method.
+	Method string `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	// Subject of the caller's identity. Empty matches any caller.
+	Subject string `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	// Percentage of matching requests the fault applies to, from 0 to 100.
+	// Defaults to 100.
+	Percent float64 `protobuf:"fixed64,4,opt,name=percent,proto3" json:"percent,omitempty"`
+	// Delay added before the request is handled.
+	Latency *durationpb.Duration `protobuf:"bytes,5,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Name of the status code to fail the request with, e.g. "UNAVAILABLE".
+	Code string `protobuf:"bytes,6,opt,name=code,proto3" json:"code,omitempty"`
+	// Message returned with the status code.
+	Message string `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`
+	// Whether to drop the request, so that it never receives a response.
+	Drop bool `protobuf:"varint,8,opt,name=drop,proto3" json:"drop,omitempty"`
+	// When the fault expires, unset for faults from config.
+	ExpireTime    *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=expire_time,json=expireTime,proto3" json:"expire_time,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InjectedFault) Reset() {
+	*x = InjectedFault{}
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InjectedFault) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InjectedFault) ProtoMessage() {}
+
+func (x *InjectedFault) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_chaos_chaos_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InjectedFault.ProtoReflect.Descriptor instead.
+func (*InjectedFault) Descriptor() ([]byte, []int) {
+	return file_plugins_chaos_chaos_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *InjectedFault) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InjectedFault) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *InjectedFault) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *InjectedFault) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *InjectedFault) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *InjectedFault) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *InjectedFault) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *InjectedFault) GetDrop() bool {
+	if x != nil {
+		return x.Drop
+	}
+	return false
+}
+
+func (x *InjectedFault) GetExpireTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpireTime
+	}
+	return nil
+}
+
+var File_plugins_chaos_chaos_proto protoreflect.FileDescriptor
+
+const file_plugins_chaos_chaos_proto_rawDesc = "" +
+	"\n" +
+	"\x19plugins/chaos/chaos.proto\x12\fprefab.chaos\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x19plugins/authz/authz.proto\"\x13\n" +
+	"\x11ListFaultsRequest\"I\n" +
+	"\x12ListFaultsResponse\x123\n" +
+	"\x06faults\x18\x01 \x03(\v2\x1b.prefab.chaos.InjectedFaultR\x06faults\"{\n" +
+	"\x0fAddFaultRequest\x121\n" +
+	"\x05fault\x18\x01 \x01(\v2\x1b.prefab.chaos.InjectedFaultR\x05fault\x125\n" +
+	"\bduration\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\bduration\"(\n" +
+	"\x12RemoveFaultRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"/\n" +
+	"\x13RemoveFaultResponse\x12\x18\n" +
+	"\aremoved\x18\x01 \x01(\bR\aremoved\"\xa3\x02\n" +
+	"\rInjectedFault\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\x12\x18\n" +
+	"\apercent\x18\x04 \x01(\x01R\apercent\x123\n" +
+	"\alatency\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\alatency\x12\x12\n" +
+	"\x04code\x18\x06 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\a \x01(\tR\amessage\x12\x12\n" +
+	"\x04drop\x18\b \x01(\bR\x04drop\x12;\n" +
+	"\vexpire_time\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"expireTime2\xe4\x02\n" +
+	"\fChaosService\x12r\n" +
+	"\n" +
+	"ListFaults\x12\x1f.prefab.chaos.ListFaultsRequest\x1a .prefab.chaos.ListFaultsResponse\"!ڵ\x18\fchaos.manage\xe2\xb5\x18\x05chaos\xea\xb5\x18\x04deny\x12i\n" +
+	"\bAddFault\x12\x1d.prefab.chaos.AddFaultRequest\x1a\x1b.prefab.chaos.InjectedFault\"!ڵ\x18\fchaos.manage\xe2\xb5\x18\x05chaos\xea\xb5\x18\x04deny\x12u\n" +
+	"\vRemoveFault\x12 .prefab.chaos.RemoveFaultRequest\x1a!.prefab.chaos.RemoveFaultResponse\"!ڵ\x18\fchaos.manage\xe2\xb5\x18\x05chaos\xea\xb5\x18\x04denyB&Z$github.com/dpup/prefab/plugins/chaosb\x06proto3"
+
+var (
+	file_plugins_chaos_chaos_proto_rawDescOnce sync.Once
+	file_plugins_chaos_chaos_proto_rawDescData []byte
+)
+
+func file_plugins_chaos_chaos_proto_rawDescGZIP() []byte {
+	file_plugins_chaos_chaos_proto_rawDescOnce.Do(func() {
+		file_plugins_chaos_chaos_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_chaos_chaos_proto_rawDesc), len(file_plugins_chaos_chaos_proto_rawDesc)))
+	})
+	return file_plugins_chaos_chaos_proto_rawDescData
+}
+
+var file_plugins_chaos_chaos_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_plugins_chaos_chaos_proto_goTypes = []any{
+	(*ListFaultsRequest)(nil),     // 0: prefab.chaos.ListFaultsRequest
+	(*ListFaultsResponse)(nil),    // 1: prefab.chaos.ListFaultsResponse
+	(*AddFaultRequest)(nil),       // 2: prefab.chaos.AddFaultRequest
+	(*RemoveFaultRequest)(nil),    // 3: prefab.chaos.RemoveFaultRequest
+	(*RemoveFaultResponse)(nil),   // 4: prefab.chaos.RemoveFaultResponse
+	(*InjectedFault)(nil),         // 5: prefab.chaos.InjectedFault
+	(*durationpb.Duration)(nil),   // 6: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+}
+var file_plugins_chaos_chaos_proto_depIdxs = []int32{
+	5, // 0: prefab.chaos.ListFaultsResponse.faults:type_name -> prefab.chaos.InjectedFault
+	5, // 1: prefab.chaos.AddFaultRequest.fault:type_name -> prefab.chaos.InjectedFault
+	6, // 2: prefab.chaos.AddFaultRequest.duration:type_name -> google.protobuf.Duration
+	6, // 3: prefab.chaos.InjectedFault.latency:type_name -> google.protobuf.Duration
+	7, // 4: prefab.chaos.InjectedFault.expire_time:type_name -> google.protobuf.Timestamp
+	0, // 5: prefab.chaos.ChaosService.ListFaults:input_type -> prefab.chaos.ListFaultsRequest
+	2, // 6: prefab.chaos.ChaosService.AddFault:input_type -> prefab.chaos.AddFaultRequest
+	3, // 7: prefab.chaos.ChaosService.RemoveFault:input_type -> prefab.chaos.RemoveFaultRequest
+	1, // 8: prefab.chaos.ChaosService.ListFaults:output_type -> prefab.chaos.ListFaultsResponse
+	5, // 9: prefab.chaos.ChaosService.AddFault:output_type -> prefab.chaos.InjectedFault
+	4, // 10: prefab.chaos.ChaosService.RemoveFault:output_type -> prefab.chaos.RemoveFaultResponse
+	8, // [8:11] is the sub-list for method output_type
+	5, // [5:8] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_plugins_chaos_chaos_proto_init() }
+func file_plugins_chaos_chaos_proto_init() {
+	if File_plugins_chaos_chaos_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_chaos_chaos_proto_rawDesc), len(file_plugins_chaos_chaos_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_chaos_chaos_proto_goTypes,
+		DependencyIndexes: file_plugins_chaos_chaos_proto_depIdxs,
+		MessageInfos:      file_plugins_chaos_chaos_proto_msgTypes,
+	}.Build()
+	File_plugins_chaos_chaos_proto = out.File
+	file_plugins_chaos_chaos_proto_goTypes = nil
+	file_plugins_chaos_chaos_proto_depIdxs = nil
+}
@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: plugins/chaos/chaos.proto
+
+package chaos
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ChaosService_ListFaults_FullMethodName  = "/prefab.chaos.ChaosService/ListFaults"
+	ChaosService_AddFault_FullMethodName    = "/prefab.chaos.ChaosService/AddFault"
+	ChaosService_RemoveFault_FullMethodName = "/prefab.chaos.ChaosService/RemoveFault"
+)
+
+// ChaosServiceClient is the client API for ChaosService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ChaosService manages the faults injected into requests. It is served on the
+// admin listener. Access is denied unless an authz policy grants the
+// `chaos.manage` action.
+type ChaosServiceClient interface {
+	// ListFaults returns the active faults.
+	ListFaults(ctx context.Context, in *ListFaultsRequest, opts ...grpc.CallOption) (*ListFaultsResponse, error)
+	// AddFault adds a fault, replacing any fault with the same name. Faults
+	// added at runtime expire automatically.
+	AddFault(ctx context.Context, in *AddFaultRequest, opts ...grpc.CallOption) (*InjectedFault, error)
+	// RemoveFault removes a fault by name.
+	RemoveFault(ctx context.Context, in *RemoveFaultRequest, opts ...grpc.CallOption) (*RemoveFaultResponse, error)
+}
+
+type chaosServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewChaosServiceClient(cc grpc.ClientConnInterface) ChaosServiceClient {
+	return &chaosServiceClient{cc}
+}
+
+func (c *chaosServiceClient) ListFaults(ctx context.Context, in *ListFaultsRequest, opts ...grpc.CallOption) (*ListFaultsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListFaultsResponse)
+	err := c.cc.Invoke(ctx, ChaosService_ListFaults_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chaosServiceClient) AddFault(ctx context.Context, in *AddFaultRequest, opts ...grpc.CallOption) (*InjectedFault, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InjectedFault)
+	err := c.cc.Invoke(ctx, ChaosService_AddFault_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chaosServiceClient) RemoveFault(ctx context.Context, in *RemoveFaultRequest, opts ...grpc.CallOption) (*RemoveFaultResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveFaultResponse)
+	err := c.cc.Invoke(ctx, ChaosService_RemoveFault_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ChaosServiceServer is the server API for ChaosService service.
+// All implementations must embed UnimplementedChaosServiceServer
+// for forward compatibility.
+//
+// ChaosService manages the faults injected into requests. It is served on the
+// admin listener. Access is denied unless an authz policy grants the
+// `chaos.manage` action.
+type ChaosServiceServer interface {
+	// ListFaults returns the active faults.
+	ListFaults(context.Context, *ListFaultsRequest) (*ListFaultsResponse, error)
+	// AddFault adds a fault, replacing any fault with the same name. Faults
+	// added at runtime expire automatically.
+	AddFault(context.Context, *AddFaultRequest) (*InjectedFault, error)
+	// RemoveFault removes a fault by name.
+	RemoveFault(context.Context, *RemoveFaultRequest) (*RemoveFaultResponse, error)
+	mustEmbedUnimplementedChaosServiceServer()
+}
+
+// UnimplementedChaosServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedChaosServiceServer struct{}
+
+func (UnimplementedChaosServiceServer) ListFaults(context.Context, *ListFaultsRequest) (*ListFaultsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListFaults not implemented")
+}
+func (UnimplementedChaosServiceServer) AddFault(context.Context, *AddFaultRequest) (*InjectedFault, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddFault not implemented")
+}
+func (UnimplementedChaosServiceServer) RemoveFault(context.Context, *RemoveFaultRequest) (*RemoveFaultResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveFault not implemented")
+}
+func (UnimplementedChaosServiceServer) mustEmbedUnimplementedChaosServiceServer() {}
+func (UnimplementedChaosServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeChaosServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ChaosServiceServer will
+// result in compilation errors.
+type UnsafeChaosServiceServer interface {
+	mustEmbedUnimplementedChaosServiceServer()
+}
+
+func RegisterChaosServiceServer(s grpc.ServiceRegistrar, srv ChaosServiceServer) {
+	// If the following call pancis, it indicates UnimplementedChaosServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ChaosService_ServiceDesc, srv)
+}
+
+func _ChaosService_ListFaults_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListFaultsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChaosServiceServer).ListFaults(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChaosService_ListFaults_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChaosServiceServer).ListFaults(ctx, req.(*ListFaultsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChaosService_AddFault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddFaultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChaosServiceServer).AddFault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChaosService_AddFault_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChaosServiceServer).AddFault(ctx, req.(*AddFaultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChaosService_RemoveFault_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveFaultRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChaosServiceServer).RemoveFault(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ChaosService_RemoveFault_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChaosServiceServer).RemoveFault(ctx, req.(*RemoveFaultRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ChaosService_ServiceDesc is the grpc.ServiceDesc for ChaosService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ChaosService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "prefab.chaos.ChaosService",
+	HandlerType: (*ChaosServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListFaults",
+			Handler:    _ChaosService_ListFaults_Handler,
+		},
+		{
+			MethodName: "AddFault",
+			Handler:    _ChaosService_AddFault_Handler,
+		},
+		{
+			MethodName: "RemoveFault",
+			Handler:    _ChaosService_RemoveFault_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins/chaos/chaos.proto",
+}
@@ -0,0 +1,230 @@
+package chaos
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+const method = "/notes.NoteService/Get"
+
+func setup(t *testing.T, opts ...ChaosOption) (*ChaosPlugin, *time.Time) {
+	p := Plugin(opts...)
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	prefabtest.Init(t, auth.Plugin(), authz.Plugin(), p)
+	return p, &now
+}
+
+// call invokes the interceptor, returning whether the handler was called.
+func call(ctx context.Context, p *ChaosPlugin, method string) (bool, error) {
+	called := false
+	_, err := p.interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+		called = true
+		return "ok", nil
+	})
+	return called, err
+}
+
+func withSubject(ctx context.Context, subject string) context.Context {
+	return logging.WithSubjectFunc(ctx, func(context.Context) string { return subject })
+}
+
+func TestInterceptor_Code(t *testing.T) {
+	p, _ := setup(t, WithFault(Fault{Name: "flaky", Method: "/notes.NoteService/*", Code: codes.Unavailable}))
+
+	called, err := call(prefabtest.Context(t), p, method)
+	assert.False(t, called)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Contains(t, err.Error(), `fault "flaky"`)
+
+	called, err = call(prefabtest.Context(t), p, "/other.Service/Get")
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestInterceptor_Latency(t *testing.T) {
+	p, _ := setup(t, WithFault(Fault{Name: "slow", Method: method, Latency: 20 * time.Millisecond}))
+
+	start := time.Now()
+	called, err := call(prefabtest.Context(t), p, method)
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// The delay ends early if the request is cancelled.
+	require.NoError(t, p.AddFault(Fault{Name: "slow", Method: method, Latency: time.Hour}, 0))
+	ctx, cancel := context.WithTimeout(prefabtest.Context(t), 10*time.Millisecond)
+	defer cancel()
+	called, err = call(ctx, p, method)
+	assert.False(t, called)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestInterceptor_Drop(t *testing.T) {
+	p, _ := setup(t, WithFault(Fault{Name: "drop", Drop: true}))
+
+	ctx, cancel := context.WithTimeout(prefabtest.Context(t), 10*time.Millisecond)
+	defer cancel()
+	called, err := call(ctx, p, method)
+	assert.False(t, called)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestInterceptor_Subject(t *testing.T) {
+	p, _ := setup(t, WithFault(Fault{Name: "alice", Subject: "alice", Code: codes.Internal}))
+
+	_, err := call(withSubject(prefabtest.Context(t), "alice"), p, method)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	called, err := call(withSubject(prefabtest.Context(t), "bob"), p, method)
+	require.NoError(t, err)
+	assert.True(t, called)
+
+	called, err = call(prefabtest.Context(t), p, method)
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestInterceptor_Percent(t *testing.T) {
+	p, _ := setup(t, WithFault(Fault{Name: "some", Percent: 25, Code: codes.Internal}))
+
+	p.sample = func() float64 { return 0.2 }
+	_, err := call(prefabtest.Context(t), p, method)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	p.sample = func() float64 { return 0.3 }
+	called, err := call(prefabtest.Context(t), p, method)
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestAddFault_Expires(t *testing.T) {
+	p, now := setup(t)
+	require.NoError(t, p.AddFault(Fault{Name: "temp", Code: codes.Internal}, time.Minute))
+	require.Len(t, p.Faults(), 1)
+	assert.Equal(t, now.Add(time.Minute), p.Faults()[0].GetExpireTime().AsTime())
+
+	_, err := call(prefabtest.Context(t), p, method)
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	*now = now.Add(time.Minute)
+	called, err := call(prefabtest.Context(t), p, method)
+	require.NoError(t, err)
+	assert.True(t, called)
+	assert.Empty(t, p.Faults())
+
+	assert.False(t, p.RemoveFault("missing"))
+}
+
+func TestAddFault_Invalid(t *testing.T) {
+	p, _ := setup(t)
+	for _, f := range []Fault{
+		{Code: codes.Internal},
+		{Name: "none"},
+		{Name: "pattern", Method: "/notes.NoteService/[", Code: codes.Internal},
+		{Name: "percent", Percent: 101, Code: codes.Internal},
+		{Name: "latency", Latency: -time.Second},
+	} {
+		err := p.AddFault(f, 0)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "%+v", f)
+	}
+}
+
+func TestFaultsFromConfig(t *testing.T) {
+	require.NoError(t, prefab.Config.Set("chaos.faults", []any{
+		map[string]any{"name": "slow", "method": method, "latency": "2s", "percent": 25},
+		map[string]any{"name": "flaky", "subject": "alice", "code": "unavailable", "message": "try again"},
+	}))
+	t.Cleanup(func() { prefab.Config.Delete("chaos") })
+
+	faults, err := faultsFromConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []Fault{
+		{Name: "slow", Method: method, Percent: 25, Latency: 2 * time.Second},
+		{Name: "flaky", Subject: "alice", Code: codes.Unavailable, Message: "try again"},
+	}, faults)
+
+	require.NoError(t, prefab.Config.Set("chaos.faults", []any{map[string]any{"name": "bad", "code": "NOPE"}}))
+	_, err = faultsFromConfig()
+	require.Error(t, err)
+	assert.Error(t, Plugin().Init(prefabtest.Context(t), &prefab.Registry{}))
+}
+
+func TestService(t *testing.T) {
+	p, _ := setup(t)
+	svc := &impl{p: p}
+
+	f, err := svc.AddFault(t.Context(), &AddFaultRequest{Fault: &InjectedFault{
+		Name:    "slow",
+		Method:  method,
+		Latency: durationpb.New(time.Second),
+		Code:    "RESOURCE_EXHAUSTED",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "RESOURCE_EXHAUSTED", f.GetCode())
+	assert.InDelta(t, 100, f.GetPercent(), 0)
+	assert.Equal(t, p.now().Add(defaultDuration), f.GetExpireTime().AsTime())
+
+	_, err = svc.AddFault(t.Context(), &AddFaultRequest{Fault: &InjectedFault{Name: "bad", Code: "NOPE"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = svc.AddFault(t.Context(), &AddFaultRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := svc.ListFaults(t.Context(), &ListFaultsRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.GetFaults(), 1)
+	assert.Equal(t, "slow", list.GetFaults()[0].GetName())
+
+	removed, err := svc.RemoveFault(t.Context(), &RemoveFaultRequest{Name: "slow"})
+	require.NoError(t, err)
+	assert.True(t, removed.GetRemoved())
+	assert.Empty(t, p.Faults())
+}
+
+func TestServer(t *testing.T) {
+	p := Plugin(WithFault(Fault{Name: "alice", Method: "/prefab.auth.AuthService/Identity", Subject: "alice", Code: codes.Unavailable}))
+	az := authz.Plugin(
+		authz.WithRoleDescriberFn(ObjectKey, func(_ context.Context, sub auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Subject == "admin" {
+				return []authz.Role{"admin"}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(ManageAction)),
+	)
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(az, p), prefabtest.WithOptions(prefab.WithAdminOnPublic()))
+	client := auth.NewAuthServiceClient(s.Conn())
+
+	ctx := s.AuthContext(t.Context(), auth.Identity{Provider: "test", Subject: "alice"})
+	_, err := client.Identity(ctx, &auth.IdentityRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	ctx = s.AuthContext(t.Context(), auth.Identity{Provider: "test", Subject: "bob"})
+	_, err = client.Identity(ctx, &auth.IdentityRequest{})
+	require.NoError(t, err)
+
+	// The service is denied by default.
+	admin := NewChaosServiceClient(s.Conn())
+	_, err = admin.AddFault(ctx, &AddFaultRequest{Fault: &InjectedFault{Name: "all", Code: "UNAVAILABLE"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = admin.ListFaults(t.Context(), &ListFaultsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx = s.AuthContext(t.Context(), auth.Identity{Provider: "test", Subject: "admin"})
+	list, err := admin.ListFaults(ctx, &ListFaultsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetFaults(), 1)
+}
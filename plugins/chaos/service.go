@@ -0,0 +1,54 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+type impl struct {
+	UnimplementedChaosServiceServer
+	p *ChaosPlugin
+}
+
+func (s *impl) ListFaults(context.Context, *ListFaultsRequest) (*ListFaultsResponse, error) {
+	return &ListFaultsResponse{Faults: s.p.Faults()}, nil
+}
+
+func (s *impl) AddFault(_ context.Context, in *AddFaultRequest) (*InjectedFault, error) {
+	pf := in.GetFault()
+	if pf == nil {
+		return nil, errors.NewC("chaos: fault is required", codes.InvalidArgument)
+	}
+	c, err := parseCode(pf.GetCode())
+	if err != nil {
+		return nil, err
+	}
+	f := Fault{
+		Name:    pf.GetName(),
+		Method:  pf.GetMethod(),
+		Subject: pf.GetSubject(),
+		Percent: pf.GetPercent(),
+		Latency: pf.GetLatency().AsDuration(),
+		Code:    c,
+		Message: pf.GetMessage(),
+		Drop:    pf.GetDrop(),
+	}
+	ttl := defaultDuration
+	if in.GetDuration() != nil {
+		ttl = in.GetDuration().AsDuration()
+		if ttl <= 0 {
+			return nil, errors.NewC("chaos: duration must be positive", codes.InvalidArgument)
+		}
+	}
+	af, err := s.p.addFault(f, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return af.proto(), nil
+}
+
+func (s *impl) RemoveFault(_ context.Context, in *RemoveFaultRequest) (*RemoveFaultResponse, error) {
+	return &RemoveFaultResponse{Removed: s.p.RemoveFault(in.GetName())}, nil
+}
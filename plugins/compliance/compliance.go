@@ -0,0 +1,487 @@
+// Package compliance provides data retention and subject erasure workflows,
+// for example to meet GDPR obligations.
+//
+// Models registered with WithModels can implement Expiring, to be purged once
+// their retention period ends, and Owned, to be erased along with their owner.
+// Models which implement Anonymizer are anonymized instead of deleted.
+//
+// EraseSubject cascades an erasure request across the application's models,
+// erasers registered with WithEraser, and every plugin which implements
+// SubjectEraser, such as auth, oauth, audit, and notifications. The outcome
+// is recorded in an ErasureReport.
+//
+// Example:
+//
+//	prefab.WithPlugin(compliance.Plugin(
+//		compliance.WithModels(Comment{}, Session{}),
+//		compliance.WithEraser("uploads", eraseUploads),
+//	)),
+//
+//	report, err := compliance.FromContext(ctx).EraseSubject(ctx, identity.Provider, identity.Subject)
+package compliance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/audit"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "compliance.purgeInterval",
+			Description: "How often expired records are purged, 0 disables",
+			Type:        "duration",
+			Default:     "1h",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "compliance"
+
+	// ErasureAction is the audit action recorded for erasure requests.
+	ErasureAction = "compliance.erasure"
+
+	// purgeLock elects the replica which purges expired records.
+	purgeLock = "compliance.purge"
+
+	defaultPurgeInterval = time.Hour
+)
+
+var (
+	// Returned when an erasure request has no subject.
+	ErrMissingSubject = errors.NewC("compliance: subject is required", codes.InvalidArgument)
+
+	// Returned when a registered model implements neither Expiring nor Owned.
+	ErrUnsupportedModel = errors.NewC("compliance: model must implement Expiring or Owned", codes.InvalidArgument)
+)
+
+// Expiring is implemented by models which are purged after a retention
+// period.
+type Expiring interface {
+	storage.Model
+
+	// RetentionPeriod returns how long records are kept.
+	RetentionPeriod() time.Duration
+
+	// RetainedSince returns when the retention period began, typically when
+	// the record was created. A zero time exempts the record, for example once
+	// it has been anonymized.
+	RetainedSince() time.Time
+}
+
+// Owned is implemented by models which hold a subject's personal data.
+type Owned interface {
+	storage.Model
+
+	// Owner returns the subject the record belongs to. The provider may be
+	// empty, in which case records match on subject alone.
+	Owner() (provider, subject string)
+}
+
+// Anonymizer is implemented by models which should be anonymized rather than
+// deleted, for example to keep aggregate statistics.
+type Anonymizer interface {
+	// Anonymize returns a copy of the record with personal data removed. It is
+	// stored under the same key.
+	Anonymize() storage.Model
+}
+
+// SubjectEraser is implemented by plugins which store data about subjects.
+// EraseSubject is called for every registered plugin which implements it.
+type SubjectEraser interface {
+	prefab.Plugin
+
+	// EraseSubject deletes or anonymizes data about the subject, returning the
+	// number of records affected.
+	EraseSubject(ctx context.Context, provider, subject string) (int, error)
+}
+
+// EraserFunc erases application data about a subject which isn't covered by
+// Owned models, returning the number of records affected.
+type EraserFunc func(ctx context.Context, provider, subject string) (int, error)
+
+// ComplianceOption allows configuration of the CompliancePlugin.
+type ComplianceOption func(*CompliancePlugin)
+
+// WithModels registers models for retention and erasure. Each model must
+// implement Expiring, Owned, or both, and must be a value rather than a
+// pointer, as with storage.Store.List.
+func WithModels(models ...storage.Model) ComplianceOption {
+	return func(p *CompliancePlugin) {
+		p.models = append(p.models, models...)
+	}
+}
+
+// WithEraser registers a function which is called by EraseSubject. The name
+// identifies the eraser in erasure reports.
+func WithEraser(name string, fn EraserFunc) ComplianceOption {
+	return func(p *CompliancePlugin) {
+		p.erasers = append(p.erasers, namedEraser{name: name, fn: fn})
+	}
+}
+
+// WithPurgeInterval overrides how often expired records are purged. Zero
+// disables the background worker, in which case Purge should be called by the
+// application. See `compliance.purgeInterval`.
+func WithPurgeInterval(d time.Duration) ComplianceOption {
+	return func(p *CompliancePlugin) {
+		p.purgeInterval = d
+	}
+}
+
+// Plugin returns a new CompliancePlugin.
+func Plugin(opts ...ComplianceOption) *CompliancePlugin {
+	p := &CompliancePlugin{
+		purgeInterval: purgeIntervalFromConfig(),
+		now:           time.Now,
+		stop:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// CompliancePlugin purges expired records and handles erasure requests.
+type CompliancePlugin struct {
+	models  []storage.Model
+	erasers []namedEraser
+
+	store         storage.Store
+	registry      *prefab.Registry
+	auditor       *audit.AuditPlugin
+	purgeInterval time.Duration
+	now           func() time.Time
+	stop          chan struct{}
+	stopOnce      sync.Once
+	wg            sync.WaitGroup
+}
+
+type namedEraser struct {
+	name string
+	fn   EraserFunc
+}
+
+// From prefab.Plugin.
+func (p *CompliancePlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *CompliancePlugin) Deps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *CompliancePlugin) OptDeps() []string {
+	return []string{audit.PluginName, lock.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *CompliancePlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *CompliancePlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if err := sp.InitModel(ErasureReport{}); err != nil {
+		return err
+	}
+	for _, m := range p.models {
+		_, expiring := m.(Expiring)
+		_, owned := m.(Owned)
+		if !expiring && !owned {
+			return errors.Mark(ErrUnsupportedModel, 0).Append(storage.Name(m))
+		}
+		if err := sp.InitModel(m); err != nil {
+			return err
+		}
+	}
+	p.store = sp
+	p.registry = r
+	p.auditor, _ = r.Get(audit.PluginName).(*audit.AuditPlugin)
+
+	if p.purgeInterval > 0 {
+		locker, _ := r.Get(lock.PluginName).(*lock.LockPlugin)
+		p.wg.Add(1)
+		go p.runPurge(ctx, locker)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *CompliancePlugin) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Purge deletes or anonymizes records of Expiring models whose retention
+// period has ended, returning the number of records affected.
+func (p *CompliancePlugin) Purge(ctx context.Context) (int, error) {
+	now := p.now()
+	n := 0
+	for _, m := range p.models {
+		if _, ok := m.(Expiring); !ok {
+			continue
+		}
+		records, err := p.list(ctx, m)
+		if err != nil {
+			return n, err
+		}
+		for _, rec := range records {
+			e := rec.(Expiring)
+			since := e.RetainedSince()
+			if since.IsZero() || now.Before(since.Add(e.RetentionPeriod())) {
+				continue
+			}
+			if err := p.remove(ctx, rec); err != nil {
+				return n, err
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+// EraseSubject deletes or anonymizes data about a subject across Owned
+// models, registered erasers, and plugins implementing SubjectEraser. Erasure
+// continues if a source fails; the report records the outcome of each source
+// and the errors are returned together.
+//
+// The report is stored, and if the audit plugin is registered, the request is
+// recorded in the audit log after existing entries are redacted.
+func (p *CompliancePlugin) EraseSubject(ctx context.Context, provider, subject string) (*ErasureReport, error) {
+	if subject == "" {
+		return nil, errors.Mark(ErrMissingSubject, 0)
+	}
+	report := &ErasureReport{
+		ID:          uuid.NewString(),
+		SubjectHash: SubjectHash(provider, subject),
+		RequestedAt: p.now(),
+	}
+
+	var errs []error
+	record := func(source string, count int, err error) {
+		res := ErasureResult{Source: source, Count: count}
+		if err != nil {
+			res.Error = err.Error()
+			errs = append(errs, errors.WrapPrefix(err, "compliance: "+source, 0))
+		}
+		report.Results = append(report.Results, res)
+	}
+
+	for _, m := range p.models {
+		if _, ok := m.(Owned); ok {
+			count, err := p.eraseModel(ctx, m, provider, subject)
+			record(storage.Name(m), count, err)
+		}
+	}
+	for _, e := range p.erasers {
+		count, err := e.fn(ctx, provider, subject)
+		record(e.name, count, err)
+	}
+	for _, e := range prefab.GetPlugins[SubjectEraser](p.registry) {
+		count, err := e.EraseSubject(ctx, provider, subject)
+		record(prefab.PluginKey(e), count, err)
+	}
+
+	report.CompletedAt = p.now()
+	if err := p.store.Create(ctx, *report); err != nil {
+		errs = append(errs, err)
+	}
+	if p.auditor != nil {
+		err := p.auditor.Append(ctx, audit.Event{
+			Action: ErasureAction,
+			Target: "erasure/" + report.ID,
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	logging.Infow(ctx, "compliance: subject erased", "reportId", report.ID, "records", report.Total())
+	return report, errors.Join(errs...)
+}
+
+// Report returns a stored erasure report.
+func (p *CompliancePlugin) Report(ctx context.Context, id string) (*ErasureReport, error) {
+	report := &ErasureReport{}
+	if err := p.store.Read(ctx, id, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// eraseModel removes the records of an Owned model which belong to the
+// subject.
+func (p *CompliancePlugin) eraseModel(ctx context.Context, m storage.Model, provider, subject string) (int, error) {
+	records, err := p.list(ctx, m)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, rec := range records {
+		ownerProvider, ownerSubject := rec.(Owned).Owner()
+		if ownerSubject != subject || (provider != "" && ownerProvider != "" && ownerProvider != provider) {
+			continue
+		}
+		if err := p.remove(ctx, rec); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// remove anonymizes a record if it supports it, otherwise deletes it.
+func (p *CompliancePlugin) remove(ctx context.Context, m storage.Model) error {
+	if a, ok := m.(Anonymizer); ok {
+		return p.store.Update(ctx, a.Anonymize())
+	}
+	return p.store.Delete(ctx, m)
+}
+
+// list returns every stored record of the model's type.
+func (p *CompliancePlugin) list(ctx context.Context, m storage.Model) ([]storage.Model, error) {
+	t := reflect.TypeOf(m)
+	slice := reflect.New(reflect.SliceOf(t))
+	if err := p.store.List(ctx, slice.Interface(), reflect.Zero(t).Interface().(storage.Model)); err != nil {
+		return nil, err
+	}
+	items := slice.Elem()
+	records := make([]storage.Model, items.Len())
+	for i := range records {
+		records[i] = items.Index(i).Interface().(storage.Model)
+	}
+	return records, nil
+}
+
+func (p *CompliancePlugin) runPurge(ctx context.Context, locker *lock.LockPlugin) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if locker == nil {
+		p.purgeOnInterval(ctx)
+		return
+	}
+	_ = locker.RunWhenLeader(ctx, purgeLock, func(ctx context.Context) error {
+		p.purgeOnInterval(ctx)
+		return nil
+	})
+}
+
+func (p *CompliancePlugin) purgeOnInterval(ctx context.Context) {
+	ticker := time.NewTicker(p.purgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := p.Purge(ctx)
+			if err != nil {
+				logging.Errorw(ctx, "compliance: failed to purge expired records", "error", err)
+			} else if n > 0 {
+				logging.Infow(ctx, "compliance: purged expired records", "count", n)
+			}
+		}
+	}
+}
+
+func (p *CompliancePlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, complianceKey{}, p)
+}
+
+// FromContext retrieves the compliance plugin from a context.
+func FromContext(ctx context.Context) *CompliancePlugin {
+	if p, ok := ctx.Value(complianceKey{}).(*CompliancePlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type complianceKey struct{}
+
+func purgeIntervalFromConfig() time.Duration {
+	if !prefab.ConfigExists("compliance.purgeInterval") {
+		return defaultPurgeInterval
+	}
+	return prefab.ConfigDuration("compliance.purgeInterval")
+}
+
+// ErasureReport records the outcome of an erasure request.
+type ErasureReport struct {
+	ID string `json:"id"`
+
+	// SubjectHash identifies the erased subject without retaining it, see
+	// SubjectHash.
+	SubjectHash string `json:"subjectHash"`
+
+	RequestedAt time.Time       `json:"requestedAt"`
+	CompletedAt time.Time       `json:"completedAt"`
+	Results     []ErasureResult `json:"results"`
+}
+
+// PK implements storage.Model.
+func (r ErasureReport) PK() string {
+	return r.ID
+}
+
+// Name implements storage.Namer.
+func (r ErasureReport) Name() string {
+	return "erasure_reports"
+}
+
+// Total returns the number of records erased across all sources.
+func (r *ErasureReport) Total() int {
+	n := 0
+	for _, res := range r.Results {
+		n += res.Count
+	}
+	return n
+}
+
+// ErasureResult is the outcome of erasing a subject from one source.
+type ErasureResult struct {
+	// Source is a model name, eraser name, or plugin name.
+	Source string `json:"source"`
+
+	// Number of records deleted or anonymized.
+	Count int `json:"count"`
+
+	// Error is set if the source failed.
+	Error string `json:"error,omitempty"`
+}
+
+// SubjectHash returns the hex encoded SHA-256 of a provider and subject, used
+// to find the erasure reports for a known subject.
+func SubjectHash(provider, subject string) string {
+	sum := sha256.Sum256([]byte(provider + ":" + subject))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package compliance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/audit"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type comment struct {
+	ID        string    `json:"id"`
+	Provider  string    `json:"provider"`
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (c comment) PK() string { return c.ID }
+
+func (c comment) Owner() (string, string) { return c.Provider, c.Author }
+
+type visit struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"subject"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func (v visit) PK() string { return v.ID }
+
+func (v visit) Owner() (string, string) { return "", v.Subject }
+
+func (v visit) RetentionPeriod() time.Duration { return 24 * time.Hour }
+
+func (v visit) RetainedSince() time.Time {
+	if v.Subject == "" {
+		return time.Time{}
+	}
+	return v.CreatedAt
+}
+
+func (v visit) Anonymize() storage.Model {
+	v.Subject = ""
+	return v
+}
+
+type unsupported struct {
+	ID string `json:"id"`
+}
+
+func (u unsupported) PK() string { return u.ID }
+
+func TestPurge(t *testing.T) {
+	p := Plugin(WithModels(visit{}), WithPurgeInterval(0))
+	s := prefabtest.New(t, prefabtest.WithPlugins(p))
+	ctx := prefabtest.Context(t)
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, s.Store().Create(ctx,
+		visit{ID: "1", Subject: "alice", Path: "/a", CreatedAt: now.Add(-48 * time.Hour)},
+		visit{ID: "2", Subject: "alice", Path: "/b", CreatedAt: now.Add(-time.Hour)},
+	))
+
+	n, err := p.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	v := &visit{}
+	require.NoError(t, s.Store().Read(ctx, "1", v))
+	assert.Empty(t, v.Subject, "expired visit should be anonymized")
+	assert.Equal(t, "/a", v.Path)
+
+	require.NoError(t, s.Store().Read(ctx, "2", v))
+	assert.Equal(t, "alice", v.Subject)
+
+	// Anonymized records are exempt.
+	n, err = p.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestEraseSubject(t *testing.T) {
+	var erased []string
+	p := Plugin(
+		WithModels(comment{}, visit{}),
+		WithEraser("uploads", func(_ context.Context, provider, subject string) (int, error) {
+			erased = append(erased, provider+":"+subject)
+			return 3, nil
+		}),
+		WithPurgeInterval(0),
+	)
+	ap := audit.Plugin()
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(authz.Plugin(), ap, p))
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, s.Store().Create(ctx,
+		comment{ID: "c1", Provider: "test", Author: "alice", Body: "hi"},
+		comment{ID: "c2", Provider: "test", Author: "bob", Body: "hey"},
+		comment{ID: "c3", Provider: "other", Author: "alice", Body: "yo"},
+		visit{ID: "v1", Subject: "alice", Path: "/a", CreatedAt: time.Now()},
+	))
+	require.NoError(t, ap.Append(ctx, audit.Event{Action: "comment.create", Actor: audit.Actor{Provider: "test", Subject: "alice"}}))
+
+	report, err := p.EraseSubject(ctx, "test", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, SubjectHash("test", "alice"), report.SubjectHash)
+	assert.Equal(t, []ErasureResult{
+		{Source: "comments", Count: 1},
+		{Source: "visits", Count: 1},
+		{Source: "uploads", Count: 3},
+		{Source: "auth", Count: 0},
+		{Source: audit.PluginName, Count: 1},
+	}, report.Results)
+	assert.Equal(t, 6, report.Total())
+	assert.Equal(t, []string{"test:alice"}, erased)
+
+	err = s.Store().Read(ctx, "c1", &comment{})
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+	require.NoError(t, s.Store().Read(ctx, "c2", &comment{}))
+	require.NoError(t, s.Store().Read(ctx, "c3", &comment{}), "other providers are untouched")
+
+	v := &visit{}
+	require.NoError(t, s.Store().Read(ctx, "v1", v))
+	assert.Empty(t, v.Subject)
+
+	stored, err := p.Report(ctx, report.ID)
+	require.NoError(t, err)
+	assert.Equal(t, report.Results, stored.Results)
+
+	events, err := ap.Query(ctx, audit.Filter{Action: ErasureAction})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "erasure/"+report.ID, events[0].Target)
+}
+
+func TestEraseSubject_ContinuesAfterError(t *testing.T) {
+	p := Plugin(
+		WithEraser("broken", func(context.Context, string, string) (int, error) {
+			return 0, errors.New("unavailable")
+		}),
+		WithEraser("files", func(context.Context, string, string) (int, error) {
+			return 2, nil
+		}),
+		WithPurgeInterval(0),
+	)
+	prefabtest.New(t, prefabtest.WithPlugins(p))
+
+	report, err := p.EraseSubject(prefabtest.Context(t), "test", "alice")
+	require.Error(t, err)
+	assert.Equal(t, []ErasureResult{
+		{Source: "broken", Error: "unavailable"},
+		{Source: "files", Count: 2},
+	}, report.Results)
+}
+
+func TestEraseSubject_MissingSubject(t *testing.T) {
+	p := Plugin(WithPurgeInterval(0))
+	prefabtest.New(t, prefabtest.WithPlugins(p))
+
+	_, err := p.EraseSubject(prefabtest.Context(t), "test", "")
+	assert.ErrorIs(t, err, ErrMissingSubject)
+}
+
+func TestInit_UnsupportedModel(t *testing.T) {
+	p := Plugin(WithModels(unsupported{}), WithPurgeInterval(0))
+	registry := &prefab.Registry{}
+	registry.Register(storage.Plugin(memstore.New()))
+
+	err := p.Init(prefabtest.Context(t), registry)
+	assert.ErrorIs(t, err, ErrUnsupportedModel)
+}
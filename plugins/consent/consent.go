@@ -0,0 +1,356 @@
+// Package consent records users' acceptance of documents such as terms of
+// service and privacy policies, and can require up-to-date acceptance before
+// an RPC is served.
+//
+// Documents are registered with WithDocument. Methods declare the documents
+// they require with the `prefab.consent.require_consent` option:
+//
+//	rpc CreatePost(CreatePostRequest) returns (CreatePostResponse) {
+//	  option (prefab.consent.require_consent) = "tos";
+//	}
+//
+// Requests from users who haven't accepted the current version fail with
+// ErrConsentRequired, whose `ErrorInfo` detail includes the document URL so
+// clients can prompt for acceptance via the ConsentService.
+package consent
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// PluginName is the name of this plugin.
+const PluginName = "consent"
+
+// Reason and domain of the `ErrorInfo` detail attached to consent errors.
+const (
+	ConsentReason = "CONSENT_REQUIRED"
+	ConsentDomain = "prefab.consent"
+)
+
+var (
+	// ErrConsentRequired is returned when the user hasn't accepted the current
+	// version of a required document. The error carries an `ErrorInfo` detail,
+	// with reason ConsentReason, and `document`, `version`, and `url` metadata.
+	ErrConsentRequired = errors.NewC("consent: acceptance required", codes.FailedPrecondition).
+				WithUserPresentableMessage("Please review and accept the updated terms to continue")
+
+	// Returned when a document hasn't been registered with WithDocument.
+	ErrUnknownDocument = errors.NewC("consent: unknown document", codes.NotFound)
+
+	// Returned when accepting a version other than the current one.
+	ErrStaleVersion = errors.NewC("consent: only the current version can be accepted", codes.FailedPrecondition)
+)
+
+// Document is a versioned document which users accept, such as terms of
+// service or a privacy policy.
+type Document struct {
+	// ID identifies the document, e.g. "tos" or "privacy".
+	ID string
+
+	// Version is the current version. Users must accept it again whenever it
+	// changes.
+	Version string
+
+	// URL where the current version can be read.
+	URL string
+}
+
+// ConsentOption allows configuration of the ConsentPlugin.
+type ConsentOption func(*ConsentPlugin)
+
+// WithDocument registers a document, or updates the current version of a
+// document registered earlier.
+func WithDocument(d Document) ConsentOption {
+	return func(p *ConsentPlugin) {
+		p.documents[d.ID] = d
+	}
+}
+
+// Plugin returns a new ConsentPlugin.
+func Plugin(opts ...ConsentOption) *ConsentPlugin {
+	p := &ConsentPlugin{
+		documents: map[string]Document{},
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ConsentPlugin stores document acceptances, enforces the
+// `require_consent` method option, and exposes the ConsentService.
+type ConsentPlugin struct {
+	documents map[string]Document
+	store     storage.Store
+	now       func() time.Time
+
+	// Serializes updates to latest acceptances.
+	mu sync.Mutex
+}
+
+// From prefab.Plugin.
+func (p *ConsentPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *ConsentPlugin) Deps() []string {
+	return []string{auth.PluginName, storage.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *ConsentPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCService(&ConsentService_ServiceDesc, &impl{p: p}),
+		prefab.WithGRPCGateway(RegisterConsentServiceHandlerFromEndpoint),
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseAuthz),
+			prefab.InterceptorMethodOptions(E_RequireConsent)),
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *ConsentPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if err := sp.InitModel(Acceptance{}); err != nil {
+		return err
+	}
+	if err := sp.InitModel(latestAcceptance{}); err != nil {
+		return err
+	}
+	p.store = sp
+	return nil
+}
+
+// Documents returns the registered documents, ordered by ID.
+func (p *ConsentPlugin) Documents() []Document {
+	docs := make([]Document, 0, len(p.documents))
+	for _, d := range p.documents {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].ID < docs[j].ID })
+	return docs
+}
+
+// Accept records that the user in the context accepted the current version of
+// a document. If version is non-empty it must match the current version, so
+// that a client can't accept a version it didn't display.
+func (p *ConsentPlugin) Accept(ctx context.Context, document, version string) (*Acceptance, error) {
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	doc, ok := p.documents[document]
+	if !ok {
+		return nil, errors.Mark(ErrUnknownDocument, 0).Append(document)
+	}
+	if version != "" && version != doc.Version {
+		return nil, errors.Mark(ErrStaleVersion, 0).Append(version)
+	}
+
+	a := Acceptance{
+		ID:         uuid.NewString(),
+		Subject:    identity.Subject,
+		Document:   doc.ID,
+		Version:    doc.Version,
+		AcceptedAt: p.now(),
+	}
+	if info, ok := serverutil.RequestInfoFromContext(ctx); ok {
+		a.IP = info.IP
+		a.UserAgent = info.UserAgent
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := p.store.Create(ctx, a); err != nil {
+		return nil, err
+	}
+	latest := latestAcceptance{
+		Key:        latestKey(a.Subject, a.Document),
+		Subject:    a.Subject,
+		Document:   a.Document,
+		Version:    a.Version,
+		AcceptedAt: a.AcceptedAt,
+	}
+	if err := p.store.Upsert(ctx, latest); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AcceptedVersion returns the version of a document the subject last
+// accepted, or an empty string if they never have.
+func (p *ConsentPlugin) AcceptedVersion(ctx context.Context, subject, document string) (string, error) {
+	latest := &latestAcceptance{}
+	err := p.store.Read(ctx, latestKey(subject, document), latest)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	return latest.Version, nil
+}
+
+// History returns the subject's acceptances, oldest first. If document is
+// non-empty only acceptances of that document are returned.
+func (p *ConsentPlugin) History(ctx context.Context, subject, document string) ([]Acceptance, error) {
+	var history []Acceptance
+	if err := p.store.List(ctx, &history, Acceptance{Subject: subject, Document: document}); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].AcceptedAt.Before(history[j].AcceptedAt)
+	})
+	return history, nil
+}
+
+// Require checks the user in the context has accepted the current version of
+// each document, returning ErrConsentRequired for the first which they
+// haven't. Can be called from handlers that need to decide on consent at
+// runtime.
+func (p *ConsentPlugin) Require(ctx context.Context, documents ...string) error {
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range documents {
+		doc, ok := p.documents[id]
+		if !ok {
+			return errors.Mark(ErrUnknownDocument, 0).Append(id).WithCode(codes.Internal)
+		}
+		accepted, err := p.AcceptedVersion(ctx, identity.Subject, id)
+		if err != nil {
+			return err
+		}
+		if accepted != doc.Version {
+			return errors.Mark(ErrConsentRequired, 0).WithDetails(&errdetails.ErrorInfo{
+				Reason: ConsentReason,
+				Domain: ConsentDomain,
+				Metadata: map[string]string{
+					"document": doc.ID,
+					"version":  doc.Version,
+					"url":      doc.URL,
+				},
+			})
+		}
+	}
+	return nil
+}
+
+// EraseSubject deletes the subject's acceptance history. Returns the number of
+// records deleted.
+func (p *ConsentPlugin) EraseSubject(ctx context.Context, _, subject string) (int, error) {
+	history, err := p.History(ctx, subject, "")
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, a := range history {
+		if err := p.store.Delete(ctx, a); err != nil {
+			return n, err
+		}
+		n++
+	}
+	var latest []latestAcceptance
+	if err := p.store.List(ctx, &latest, latestAcceptance{Subject: subject}); err != nil {
+		return n, err
+	}
+	for _, l := range latest {
+		if err := p.store.Delete(ctx, l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RequiredDocuments returns the documents declared on a method via the
+// `prefab.consent.require_consent` option.
+func RequiredDocuments(info *grpc.UnaryServerInfo) []string {
+	if v, ok := serverutil.MethodOption(info, E_RequireConsent); ok {
+		return v.([]string)
+	}
+	return nil
+}
+
+// interceptor enforces consent requirements declared via method options.
+// Methods without options are passed through untouched.
+func (p *ConsentPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if docs := RequiredDocuments(info); len(docs) > 0 {
+		if err := p.Require(ctx, docs...); err != nil {
+			return nil, err
+		}
+	}
+	return handler(ctx, req)
+}
+
+func (p *ConsentPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consentKey{}, p)
+}
+
+// FromContext retrieves the consent plugin from a context.
+func FromContext(ctx context.Context) *ConsentPlugin {
+	if p, ok := ctx.Value(consentKey{}).(*ConsentPlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type consentKey struct{}
+
+// Acceptance records a user accepting a version of a document.
+type Acceptance struct {
+	ID         string    `json:"id"`
+	Subject    string    `json:"subject"`
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+	IP         string    `json:"ip,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+}
+
+// PK implements storage.Model.
+func (a Acceptance) PK() string {
+	return a.ID
+}
+
+// Name implements storage.Namer.
+func (a Acceptance) Name() string {
+	return "consent_acceptances"
+}
+
+// latestAcceptance is the most recent acceptance of a document by a subject,
+// so that checks don't need to scan the history.
+type latestAcceptance struct {
+	Key        string    `json:"key"`
+	Subject    string    `json:"subject"`
+	Document   string    `json:"document"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"acceptedAt"`
+}
+
+func (l latestAcceptance) PK() string {
+	return l.Key
+}
+
+func (l latestAcceptance) Name() string {
+	return "consent_latest"
+}
+
+func latestKey(subject, document string) string {
+	return subject + ":" + document
+}
@@ -0,0 +1,573 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/consent/consent.proto
+
+package consent
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListDocumentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDocumentsRequest) Reset() {
+	*x = ListDocumentsRequest{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDocumentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDocumentsRequest) ProtoMessage() {}
+
+func (x *ListDocumentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDocumentsRequest.ProtoReflect.Descriptor instead.
+func (*ListDocumentsRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{0}
+}
+
+type ListDocumentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Documents     []*DocumentStatus      `protobuf:"bytes,1,rep,name=documents,proto3" json:"documents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDocumentsResponse) Reset() {
+	*x = ListDocumentsResponse{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDocumentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDocumentsResponse) ProtoMessage() {}
+
+func (x *ListDocumentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDocumentsResponse.ProtoReflect.Descriptor instead.
+func (*ListDocumentsResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListDocumentsResponse) GetDocuments() []*DocumentStatus {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+type DocumentStatus struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// The current version of the document.
+	Version string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// Where the document can be read.
+	Url string `protobuf:"bytes,3,opt,name=url,proto3" json:"url,omitempty"`
+	// The version the user last accepted, empty if never.
+	AcceptedVersion string `protobuf:"bytes,4,opt,name=accepted_version,json=acceptedVersion,proto3" json:"accepted_version,omitempty"`
+	// Whether the user has accepted the current version.
+	Current       bool `protobuf:"varint,5,opt,name=current,proto3" json:"current,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DocumentStatus) Reset() {
+	*x = DocumentStatus{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocumentStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocumentStatus) ProtoMessage() {}
+
+func (x *DocumentStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocumentStatus.ProtoReflect.Descriptor instead.
+func (*DocumentStatus) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *DocumentStatus) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DocumentStatus) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *DocumentStatus) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *DocumentStatus) GetAcceptedVersion() string {
+	if x != nil {
+		return x.AcceptedVersion
+	}
+	return ""
+}
+
+func (x *DocumentStatus) GetCurrent() bool {
+	if x != nil {
+		return x.Current
+	}
+	return false
+}
+
+type AcceptRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Document string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	// The version being accepted, which must be the current version. Defaults
+	// to the current version.
+	Version       string `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcceptRequest) Reset() {
+	*x = AcceptRequest{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcceptRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcceptRequest) ProtoMessage() {}
+
+func (x *AcceptRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcceptRequest.ProtoReflect.Descriptor instead.
+func (*AcceptRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AcceptRequest) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *AcceptRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type AcceptResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Acceptance    *ConsentAcceptance     `protobuf:"bytes,1,opt,name=acceptance,proto3" json:"acceptance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AcceptResponse) Reset() {
+	*x = AcceptResponse{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AcceptResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AcceptResponse) ProtoMessage() {}
+
+func (x *AcceptResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AcceptResponse.ProtoReflect.Descriptor instead.
+func (*AcceptResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AcceptResponse) GetAcceptance() *ConsentAcceptance {
+	if x != nil {
+		return x.Acceptance
+	}
+	return nil
+}
+
+type ListAcceptancesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only return acceptances of this document.
+	Document      string `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAcceptancesRequest) Reset() {
+	*x = ListAcceptancesRequest{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAcceptancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAcceptancesRequest) ProtoMessage() {}
+
+func (x *ListAcceptancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAcceptancesRequest.ProtoReflect.Descriptor instead.
+func (*ListAcceptancesRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListAcceptancesRequest) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+type ListAcceptancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Acceptances   []*ConsentAcceptance   `protobuf:"bytes,1,rep,name=acceptances,proto3" json:"acceptances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListAcceptancesResponse) Reset() {
+	*x = ListAcceptancesResponse{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListAcceptancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAcceptancesResponse) ProtoMessage() {}
+
+func (x *ListAcceptancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAcceptancesResponse.ProtoReflect.Descriptor instead.
+func (*ListAcceptancesResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListAcceptancesResponse) GetAcceptances() []*ConsentAcceptance {
+	if x != nil {
+		return x.Acceptances
+	}
+	return nil
+}
+
+type ConsentAcceptance struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Document string                 `protobuf:"bytes,1,opt,name=document,proto3" json:"document,omitempty"`
+	Version  string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	// When the document was accepted (Unix timestamp in seconds).
+	AcceptedAt    int64  `protobuf:"varint,3,opt,name=accepted_at,json=acceptedAt,proto3" json:"accepted_at,omitempty"`
+	Ip            string `protobuf:"bytes,4,opt,name=ip,proto3" json:"ip,omitempty"`
+	UserAgent     string `protobuf:"bytes,5,opt,name=user_agent,json=userAgent,proto3" json:"user_agent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConsentAcceptance) Reset() {
+	*x = ConsentAcceptance{}
+	mi := &file_plugins_consent_consent_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConsentAcceptance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConsentAcceptance) ProtoMessage() {}
+
+func (x *ConsentAcceptance) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_consent_consent_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConsentAcceptance.ProtoReflect.Descriptor instead.
+func (*ConsentAcceptance) Descriptor() ([]byte, []int) {
+	return file_plugins_consent_consent_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ConsentAcceptance) GetDocument() string {
+	if x != nil {
+		return x.Document
+	}
+	return ""
+}
+
+func (x *ConsentAcceptance) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *ConsentAcceptance) GetAcceptedAt() int64 {
+	if x != nil {
+		return x.AcceptedAt
+	}
+	return 0
+}
+
+func (x *ConsentAcceptance) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+func (x *ConsentAcceptance) GetUserAgent() string {
+	if x != nil {
+		return x.UserAgent
+	}
+	return ""
+}
+
+var file_plugins_consent_consent_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50041,
+		Name:          "prefab.consent.require_consent",
+		Tag:           "bytes,50041,rep,name=require_consent",
+		Filename:      "plugins/consent/consent.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// IDs of documents, such as "tos", which must have been accepted.
+	//
+	// repeated string require_consent = 50041;
+	E_RequireConsent = &file_plugins_consent_consent_proto_extTypes[0]
+)
+
+var File_plugins_consent_consent_proto protoreflect.FileDescriptor
+
+const file_plugins_consent_consent_proto_rawDesc = "" +
+	"\n" +
+	"\x1dplugins/consent/consent.proto\x12\x0eprefab.consent\x1a\x1cgoogle/api/annotations.proto\x1a google/protobuf/descriptor.proto\"\x16\n" +
+	"\x14ListDocumentsRequest\"U\n" +
+	"\x15ListDocumentsResponse\x12<\n" +
+	"\tdocuments\x18\x01 \x03(\v2\x1e.prefab.consent.DocumentStatusR\tdocuments\"\x91\x01\n" +
+	"\x0eDocumentStatus\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x10\n" +
+	"\x03url\x18\x03 \x01(\tR\x03url\x12)\n" +
+	"\x10accepted_version\x18\x04 \x01(\tR\x0facceptedVersion\x12\x18\n" +
+	"\acurrent\x18\x05 \x01(\bR\acurrent\"E\n" +
+	"\rAcceptRequest\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"S\n" +
+	"\x0eAcceptResponse\x12A\n" +
+	"\n" +
+	"acceptance\x18\x01 \x01(\v2!.prefab.consent.ConsentAcceptanceR\n" +
+	"acceptance\"4\n" +
+	"\x16ListAcceptancesRequest\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\"^\n" +
+	"\x17ListAcceptancesResponse\x12C\n" +
+	"\vacceptances\x18\x01 \x03(\v2!.prefab.consent.ConsentAcceptanceR\vacceptances\"\x99\x01\n" +
+	"\x11ConsentAcceptance\x12\x1a\n" +
+	"\bdocument\x18\x01 \x01(\tR\bdocument\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\x12\x1f\n" +
+	"\vaccepted_at\x18\x03 \x01(\x03R\n" +
+	"acceptedAt\x12\x0e\n" +
+	"\x02ip\x18\x04 \x01(\tR\x02ip\x12\x1d\n" +
+	"\n" +
+	"user_agent\x18\x05 \x01(\tR\tuserAgent2\x93\x03\n" +
+	"\x0eConsentService\x12|\n" +
+	"\rListDocuments\x12$.prefab.consent.ListDocumentsRequest\x1a%.prefab.consent.ListDocumentsResponse\"\x1e\x82\xd3\xe4\x93\x02\x18\x12\x16/api/consent/documents\x12|\n" +
+	"\x06Accept\x12\x1d.prefab.consent.AcceptRequest\x1a\x1e.prefab.consent.AcceptResponse\"3\x82\xd3\xe4\x93\x02-:\x01*\"(/api/consent/documents/{document}/accept\x12\x84\x01\n" +
+	"\x0fListAcceptances\x12&.prefab.consent.ListAcceptancesRequest\x1a'.prefab.consent.ListAcceptancesResponse\" \x82\xd3\xe4\x93\x02\x1a\x12\x18/api/consent/acceptances:I\n" +
+	"\x0frequire_consent\x12\x1e.google.protobuf.MethodOptions\x18\xf9\x86\x03 \x03(\tR\x0erequireConsentB(Z&github.com/dpup/prefab/plugins/consentb\x06proto3"
+
+var (
+	file_plugins_consent_consent_proto_rawDescOnce sync.Once
+	file_plugins_consent_consent_proto_rawDescData []byte
+)
+
+func file_plugins_consent_consent_proto_rawDescGZIP() []byte {
+	file_plugins_consent_consent_proto_rawDescOnce.Do(func() {
+		file_plugins_consent_consent_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_consent_consent_proto_rawDesc), len(file_plugins_consent_consent_proto_rawDesc)))
+	})
+	return file_plugins_consent_consent_proto_rawDescData
+}
+
+var file_plugins_consent_consent_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_plugins_consent_consent_proto_goTypes = []any{
+	(*ListDocumentsRequest)(nil),       // 0: prefab.consent.ListDocumentsRequest
+	(*ListDocumentsResponse)(nil),      // 1: prefab.consent.ListDocumentsResponse
+	(*DocumentStatus)(nil),             // 2: prefab.consent.DocumentStatus
+	(*AcceptRequest)(nil),              // 3: prefab.consent.AcceptRequest
+	(*AcceptResponse)(nil),             // 4: prefab.consent.AcceptResponse
+	(*ListAcceptancesRequest)(nil),     // 5: prefab.consent.ListAcceptancesRequest
+	(*ListAcceptancesResponse)(nil),    // 6: prefab.consent.ListAcceptancesResponse
+	(*ConsentAcceptance)(nil),          // 7: prefab.consent.ConsentAcceptance
+	(*descriptorpb.MethodOptions)(nil), // 8: google.protobuf.MethodOptions
+}
+var file_plugins_consent_consent_proto_depIdxs = []int32{
+	2, // 0: prefab.consent.ListDocumentsResponse.documents:type_name -> prefab.consent.DocumentStatus
+	7, // 1: prefab.consent.AcceptResponse.acceptance:type_name -> prefab.consent.ConsentAcceptance
+	7, // 2: prefab.consent.ListAcceptancesResponse.acceptances:type_name -> prefab.consent.ConsentAcceptance
+	8, // 3: prefab.consent.require_consent:extendee -> google.protobuf.MethodOptions
+	0, // 4: prefab.consent.ConsentService.ListDocuments:input_type -> prefab.consent.ListDocumentsRequest
+	3, // 5: prefab.consent.ConsentService.Accept:input_type -> prefab.consent.AcceptRequest
+	5, // 6: prefab.consent.ConsentService.ListAcceptances:input_type -> prefab.consent.ListAcceptancesRequest
+	1, // 7: prefab.consent.ConsentService.ListDocuments:output_type -> prefab.consent.ListDocumentsResponse
+	4, // 8: prefab.consent.ConsentService.Accept:output_type -> prefab.consent.AcceptResponse
+	6, // 9: prefab.consent.ConsentService.ListAcceptances:output_type -> prefab.consent.ListAcceptancesResponse
+	7, // [7:10] is the sub-list for method output_type
+	4, // [4:7] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	3, // [3:4] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_plugins_consent_consent_proto_init() }
+func file_plugins_consent_consent_proto_init() {
+	if File_plugins_consent_consent_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_consent_consent_proto_rawDesc), len(file_plugins_consent_consent_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 1,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_consent_consent_proto_goTypes,
+		DependencyIndexes: file_plugins_consent_consent_proto_depIdxs,
+		MessageInfos:      file_plugins_consent_consent_proto_msgTypes,
+		ExtensionInfos:    file_plugins_consent_consent_proto_extTypes,
+	}.Build()
+	File_plugins_consent_consent_proto = out.File
+	file_plugins_consent_consent_proto_goTypes = nil
+	file_plugins_consent_consent_proto_depIdxs = nil
+}
@@ -0,0 +1,161 @@
+package consent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	tos     = Document{ID: "tos", Version: "2025-01", URL: "https://example.com/tos"}
+	privacy = Document{ID: "privacy", Version: "v3", URL: "https://example.com/privacy"}
+	user    = auth.Identity{Provider: "test", Subject: "alice"}
+)
+
+func setup(t *testing.T, opts ...ConsentOption) (*prefabtest.Server, *ConsentPlugin) {
+	opts = append([]ConsentOption{WithDocument(tos), WithDocument(privacy)}, opts...)
+	p := Plugin(opts...)
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(p))
+	return s, p
+}
+
+func userContext(t *testing.T) context.Context {
+	return auth.WithIdentityForTest(prefabtest.Context(t), user)
+}
+
+func TestAccept(t *testing.T) {
+	_, p := setup(t)
+	ctx := userContext(t)
+
+	a, err := p.Accept(ctx, "tos", "")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", a.Subject)
+	assert.Equal(t, "2025-01", a.Version)
+
+	v, err := p.AcceptedVersion(ctx, "alice", "tos")
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", v)
+
+	_, err = p.Accept(ctx, "tos", "2024-06")
+	require.ErrorIs(t, err, ErrStaleVersion)
+
+	_, err = p.Accept(ctx, "cookies", "")
+	require.ErrorIs(t, err, ErrUnknownDocument)
+}
+
+func TestRequire(t *testing.T) {
+	_, p := setup(t)
+	ctx := userContext(t)
+
+	err := p.Require(ctx, "tos")
+	require.ErrorIs(t, err, ErrConsentRequired)
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+
+	var info *errdetails.ErrorInfo
+	for _, d := range status.Convert(err).Details() {
+		if i, ok := d.(*errdetails.ErrorInfo); ok {
+			info = i
+		}
+	}
+	require.NotNil(t, info)
+	assert.Equal(t, ConsentReason, info.GetReason())
+	assert.Equal(t, map[string]string{
+		"document": "tos",
+		"version":  "2025-01",
+		"url":      "https://example.com/tos",
+	}, info.GetMetadata())
+
+	_, err = p.Accept(ctx, "tos", "")
+	require.NoError(t, err)
+	require.NoError(t, p.Require(ctx, "tos"))
+
+	// A new version must be accepted again.
+	p.documents["tos"] = Document{ID: "tos", Version: "2025-06", URL: tos.URL}
+	require.ErrorIs(t, p.Require(ctx, "tos"), ErrConsentRequired)
+}
+
+func TestRequire_Unauthenticated(t *testing.T) {
+	_, p := setup(t)
+	err := p.Require(t.Context(), "tos")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrConsentRequired)
+}
+
+func TestInterceptor_NoOptions(t *testing.T) {
+	_, p := setup(t)
+	info := &grpc.UnaryServerInfo{FullMethod: ConsentService_ListDocuments_FullMethodName}
+	called := false
+	_, err := p.interceptor(t.Context(), nil, info, func(context.Context, any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestHistory(t *testing.T) {
+	_, p := setup(t)
+	ctx := userContext(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { now = now.Add(time.Minute); return now }
+
+	_, err := p.Accept(ctx, "tos", "")
+	require.NoError(t, err)
+	_, err = p.Accept(ctx, "privacy", "")
+	require.NoError(t, err)
+	p.documents["tos"] = Document{ID: "tos", Version: "2025-06"}
+	_, err = p.Accept(ctx, "tos", "")
+	require.NoError(t, err)
+
+	history, err := p.History(ctx, "alice", "tos")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "2025-01", history[0].Version)
+	assert.Equal(t, "2025-06", history[1].Version)
+
+	n, err := p.EraseSubject(ctx, "test", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+
+	history, err = p.History(ctx, "alice", "")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestConsentService(t *testing.T) {
+	s, _ := setup(t)
+	client := NewConsentServiceClient(s.Conn())
+	ctx := s.AuthContext(t.Context(), user)
+
+	resp, err := client.ListDocuments(ctx, &ListDocumentsRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.GetDocuments(), 2)
+	assert.Equal(t, "privacy", resp.GetDocuments()[0].GetId())
+	assert.False(t, resp.GetDocuments()[1].GetCurrent())
+
+	_, err = client.Accept(ctx, &AcceptRequest{Document: "tos", Version: "2025-01"})
+	require.NoError(t, err)
+
+	resp, err = client.ListDocuments(ctx, &ListDocumentsRequest{})
+	require.NoError(t, err)
+	assert.True(t, resp.GetDocuments()[1].GetCurrent())
+	assert.Equal(t, "2025-01", resp.GetDocuments()[1].GetAcceptedVersion())
+
+	history, err := client.ListAcceptances(ctx, &ListAcceptancesRequest{})
+	require.NoError(t, err)
+	require.Len(t, history.GetAcceptances(), 1)
+	assert.Equal(t, "tos", history.GetAcceptances()[0].GetDocument())
+
+	_, err = client.ListDocuments(t.Context(), &ListDocumentsRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
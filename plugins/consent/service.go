@@ -0,0 +1,68 @@
+package consent
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/plugins/auth"
+)
+
+type impl struct {
+	UnimplementedConsentServiceServer
+	p *ConsentPlugin
+}
+
+func (s *impl) ListDocuments(ctx context.Context, _ *ListDocumentsRequest) (*ListDocumentsResponse, error) {
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListDocumentsResponse{}
+	for _, d := range s.p.Documents() {
+		accepted, err := s.p.AcceptedVersion(ctx, identity.Subject, d.ID)
+		if err != nil {
+			return nil, err
+		}
+		out.Documents = append(out.Documents, &DocumentStatus{
+			Id:              d.ID,
+			Version:         d.Version,
+			Url:             d.URL,
+			AcceptedVersion: accepted,
+			Current:         accepted == d.Version,
+		})
+	}
+	return out, nil
+}
+
+func (s *impl) Accept(ctx context.Context, in *AcceptRequest) (*AcceptResponse, error) {
+	a, err := s.p.Accept(ctx, in.GetDocument(), in.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	return &AcceptResponse{Acceptance: a.proto()}, nil
+}
+
+func (s *impl) ListAcceptances(ctx context.Context, in *ListAcceptancesRequest) (*ListAcceptancesResponse, error) {
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.p.History(ctx, identity.Subject, in.GetDocument())
+	if err != nil {
+		return nil, err
+	}
+	out := &ListAcceptancesResponse{}
+	for _, a := range history {
+		out.Acceptances = append(out.Acceptances, a.proto())
+	}
+	return out, nil
+}
+
+func (a Acceptance) proto() *ConsentAcceptance {
+	return &ConsentAcceptance{
+		Document:   a.Document,
+		Version:    a.Version,
+		AcceptedAt: a.AcceptedAt.Unix(),
+		Ip:         a.IP,
+		UserAgent:  a.UserAgent,
+	}
+}
@@ -0,0 +1,336 @@
+// Package debug exposes operational endpoints on the admin listener: pprof
+// profiles, runtime stats, build information, and the effective config with
+// secrets redacted.
+//
+// Endpoints:
+//
+//	/debug/pprof/   Profiles from net/http/pprof
+//	/debug/vars     Variables published with expvar
+//	/debug/runtime  Goroutines, heap, and GC stats
+//	/debug/build    Version, commit, and module information
+//	/debug/config   Effective config, with secret values redacted
+//
+// The endpoints are served on the admin listener, so the plugin fails to
+// initialize unless `server.admin.port` is set or WithMiddleware is used. To
+// serve them on the public port, use prefab.WithAdminOnPublic and require
+// authorization with WithMiddleware, for example:
+//
+//	authzPlugin := authz.Plugin(
+//	    authz.WithDebugEndpoint(),
+//	    authz.WithPolicy(authz.Allow, authz.RoleAdmin, authz.ActionDebug),
+//	)
+//	prefab.WithAdminOnPublic(),
+//	prefab.WithPlugin(debug.Plugin(
+//	    debug.WithMiddleware(authzPlugin.Middleware(authz.DebugObjectKey, authz.ActionDebug)),
+//	))
+//
+// Build information is read from the binary. Version, Commit, and BuildTime
+// can be overridden at compile time:
+//
+//	go build -ldflags "-X github.com/dpup/prefab/plugins/debug.Version=1.2.3"
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"runtime"
+	rdebug "runtime/debug"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/redact"
+)
+
+// PluginName is the name of the debug plugin.
+const PluginName = "debug"
+
+// Build information, set with -ldflags "-X". When unset, values are read from
+// the module and VCS information embedded by the go tool.
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
+// Default fragments which mark a config key as secret. Matched against the
+// end of the last segment of the key, case-insensitively.
+var defaultSecretKeys = []string{
+	"secret", "password", "key", "keys", "token", "dsn",
+}
+
+// Suffixes of config keys whose values are URLs. Their userinfo and query,
+// which often carry credentials, are redacted. Userinfo is redacted from URLs
+// in other values too.
+var urlKeys = []string{
+	"url", "urls", "uri", "uris",
+}
+
+// DebugOption customizes the debug plugin.
+type DebugOption func(*DebugPlugin)
+
+// WithMiddleware wraps each endpoint with the middleware, for example to
+// require authorization. The first middleware runs first.
+func WithMiddleware(mw ...prefab.Middleware) DebugOption {
+	return func(p *DebugPlugin) {
+		p.middleware = append(p.middleware, mw...)
+	}
+}
+
+// WithRedactedKeys adds to the suffixes which mark a config key as secret.
+// Defaults include "secret", "password", "key", "token", and "dsn".
+func WithRedactedKeys(suffixes ...string) DebugOption {
+	return func(p *DebugPlugin) {
+		for _, s := range suffixes {
+			p.secretKeys = append(p.secretKeys, strings.ToLower(s))
+		}
+	}
+}
+
+// WithoutProfiling disables the pprof endpoints.
+func WithoutProfiling() DebugOption {
+	return func(p *DebugPlugin) {
+		p.noProfiling = true
+	}
+}
+
+// Plugin returns a new DebugPlugin.
+func Plugin(opts ...DebugOption) *DebugPlugin {
+	p := &DebugPlugin{
+		secretKeys: append([]string{}, defaultSecretKeys...),
+		started:    time.Now(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// DebugPlugin serves profiling and diagnostic endpoints.
+type DebugPlugin struct {
+	middleware  []prefab.Middleware
+	secretKeys  []string
+	noProfiling bool
+	started     time.Time
+}
+
+// From prefab.Plugin.
+func (p *DebugPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.InitializablePlugin.
+func (p *DebugPlugin) Init(ctx context.Context, _ *prefab.Registry) error {
+	if s, ok := prefab.ServerFromContext(ctx); ok && !s.HasAdminListener() && len(p.middleware) == 0 {
+		return errors.New("debug: endpoints require an admin listener, set server.admin.port or use debug.WithMiddleware")
+	}
+	return nil
+}
+
+// From prefab.AdminOptionProvider.
+func (p *DebugPlugin) AdminOptions() []prefab.ServerOption {
+	opts := []prefab.ServerOption{
+		prefab.WithAdminHTTPHandler("/debug/vars", p.wrap(expvar.Handler())),
+		prefab.WithAdminHTTPHandler("/debug/runtime", p.wrap(jsonHandler(func() any { return p.RuntimeStats() }))),
+		prefab.WithAdminHTTPHandler("/debug/build", p.wrap(jsonHandler(func() any { return ReadBuildInfo() }))),
+		prefab.WithAdminHTTPHandler("/debug/config", p.wrap(jsonHandler(func() any { return p.Config() }))),
+	}
+	if !p.noProfiling {
+		opts = append(opts,
+			prefab.WithAdminHTTPHandler("/debug/pprof/", p.wrap(http.HandlerFunc(pprof.Index))),
+			prefab.WithAdminHTTPHandler("/debug/pprof/cmdline", p.wrap(http.HandlerFunc(pprof.Cmdline))),
+			prefab.WithAdminHTTPHandler("/debug/pprof/profile", p.wrap(http.HandlerFunc(pprof.Profile))),
+			prefab.WithAdminHTTPHandler("/debug/pprof/symbol", p.wrap(http.HandlerFunc(pprof.Symbol))),
+			prefab.WithAdminHTTPHandler("/debug/pprof/trace", p.wrap(http.HandlerFunc(pprof.Trace))),
+		)
+	}
+	return opts
+}
+
+// RuntimeStats is a snapshot of the Go runtime.
+type RuntimeStats struct {
+	GoVersion    string        `json:"goVersion"`
+	CPUs         int           `json:"cpus"`
+	Goroutines   int           `json:"goroutines"`
+	Uptime       time.Duration `json:"uptime"`
+	HeapAlloc    uint64        `json:"heapAlloc"`
+	HeapInuse    uint64        `json:"heapInuse"`
+	HeapObjects  uint64        `json:"heapObjects"`
+	Sys          uint64        `json:"sys"`
+	NumGC        uint32        `json:"numGC"`
+	GCPauseTotal time.Duration `json:"gcPauseTotal"`
+	LastGC       time.Time     `json:"lastGC,omitzero"`
+}
+
+// RuntimeStats returns a snapshot of goroutine, heap, and GC stats. Reading
+// memory stats briefly stops the world.
+func (p *DebugPlugin) RuntimeStats() RuntimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	stats := RuntimeStats{
+		GoVersion:    runtime.Version(),
+		CPUs:         runtime.NumCPU(),
+		Goroutines:   runtime.NumGoroutine(),
+		Uptime:       time.Since(p.started),
+		HeapAlloc:    m.HeapAlloc,
+		HeapInuse:    m.HeapInuse,
+		HeapObjects:  m.HeapObjects,
+		Sys:          m.Sys,
+		NumGC:        m.NumGC,
+		GCPauseTotal: time.Duration(m.PauseTotalNs),
+	}
+	if m.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(m.LastGC)).UTC()
+	}
+	return stats
+}
+
+// BuildInfo describes the running binary.
+type BuildInfo struct {
+	Version   string            `json:"version,omitempty"`
+	Commit    string            `json:"commit,omitempty"`
+	BuildTime string            `json:"buildTime,omitempty"`
+	Modified  bool              `json:"modified,omitempty"`
+	GoVersion string            `json:"goVersion"`
+	Path      string            `json:"path,omitempty"`
+	Deps      map[string]string `json:"deps,omitempty"`
+}
+
+// ReadBuildInfo returns information about the running binary. Values set with
+// -ldflags take precedence over those embedded by the go tool.
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{GoVersion: runtime.Version()}
+	if bi, ok := rdebug.ReadBuildInfo(); ok {
+		info.Path = bi.Main.Path
+		if bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.time":
+				info.BuildTime = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+		if len(bi.Deps) > 0 {
+			info.Deps = make(map[string]string, len(bi.Deps))
+			for _, d := range bi.Deps {
+				info.Deps[d.Path] = d.Version
+			}
+		}
+	}
+	if Version != "" {
+		info.Version = Version
+	}
+	if Commit != "" {
+		info.Commit = Commit
+	}
+	if BuildTime != "" {
+		info.BuildTime = BuildTime
+	}
+	return info
+}
+
+// Config returns the effective config, with the values of secret keys
+// replaced by redact.Value, and credentials removed from URLs.
+func (p *DebugPlugin) Config() map[string]any {
+	all := prefab.ConfigAll()
+	out := make(map[string]any, len(all))
+	for k, v := range all {
+		switch {
+		case hasSuffix(k, p.secretKeys):
+			v = redact.Value
+		case hasSuffix(k, urlKeys):
+			v = redactURLs(v, true)
+		default:
+			v = redactURLs(v, false)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// hasSuffix returns whether the last segment of key ends with one of suffixes,
+// case-insensitively.
+func hasSuffix(key string, suffixes []string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	return slices.ContainsFunc(suffixes, func(s string) bool {
+		return strings.HasSuffix(name, s)
+	})
+}
+
+// redactURLs redacts the userinfo of URLs in a string or list value, and their
+// query if query is true.
+func redactURLs(v any, query bool) any {
+	switch v := v.(type) {
+	case string:
+		return redactURL(v, query)
+	case []string:
+		out := make([]string, len(v))
+		for i, s := range v {
+			out[i] = redactURL(s, query)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = redactURLs(e, query)
+		}
+		return out
+	}
+	return v
+}
+
+func redactURL(s string, query bool) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		// May still contain credentials, such as a malformed password.
+		return redact.Value
+	}
+	if u.User == nil && (!query || u.RawQuery == "") {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(u.Scheme + "://")
+	if u.User != nil {
+		b.WriteString(redact.Value + "@")
+	}
+	b.WriteString(u.Host + u.EscapedPath())
+	if u.RawQuery != "" {
+		if query {
+			b.WriteString("?" + redact.Value)
+		} else {
+			b.WriteString("?" + u.RawQuery)
+		}
+	}
+	return b.String()
+}
+
+// wrap applies the configured middleware, such that the first runs first.
+func (p *DebugPlugin) wrap(h http.Handler) http.Handler {
+	for i := len(p.middleware) - 1; i >= 0; i-- {
+		h = p.middleware[i](h)
+	}
+	return h
+}
+
+func jsonHandler(fn func() any) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(fn())
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
 )
 
 const (
@@ -29,6 +30,10 @@ type Message struct {
 	Data    any    // Payload
 	Attempt int    // Delivery attempt (1-based)
 
+	// Where the message was published, see serverutil.CurrentLocality.
+	// Distributed implementations should carry it with the message.
+	Origin serverutil.Locality
+
 	ack  func() // Called on successful processing
 	nack func() // Called on processing failure
 }
@@ -54,6 +59,7 @@ func NewMessage(id, topic string, data any) *Message {
 		Topic:   topic,
 		Data:    data,
 		Attempt: 1,
+		Origin:  serverutil.CurrentLocality(),
 		ack:     func() {},
 		nack:    func() {},
 	}
@@ -66,6 +72,7 @@ func NewMessageWithCallbacks(id, topic string, data any, attempt int, ack, nack
 		Topic:   topic,
 		Data:    data,
 		Attempt: attempt,
+		Origin:  serverutil.CurrentLocality(),
 		ack:     ack,
 		nack:    nack,
 	}
@@ -122,16 +129,16 @@ type Shutdownable interface {
 // From prefab.ShutdownPlugin.
 func (p *EventBusPlugin) Shutdown(ctx context.Context) error {
 	// If the bus implements Shutdownable, use that for graceful shutdown
+	var err error
 	if bus, ok := p.EventBus.(Shutdownable); ok {
-		err := bus.Shutdown(ctx)
-		if err == nil {
-			logging.Info(ctx, "Event bus drained")
-		}
-		return err
+		err = bus.Shutdown(ctx)
+	} else {
+		// Otherwise, just wait for completion
+		err = p.Wait(ctx)
+	}
+	if err == nil {
+		err = WaitAsync(ctx)
 	}
-
-	// Otherwise, just wait for completion
-	err := p.Wait(ctx)
 	if err == nil {
 		logging.Info(ctx, "Event bus drained")
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/dpup/prefab/logging"
 	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/serverutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,6 +33,26 @@ func TestBus_BasicPubSub(t *testing.T) {
 		"subscriber should have been called")
 }
 
+func TestBus_Origin(t *testing.T) {
+	serverutil.SetLocality(serverutil.Locality{Region: "us-east1"})
+	t.Cleanup(func() { serverutil.SetLocality(serverutil.Locality{}) })
+	bus := New(logging.EnsureLogger(t.Context()))
+
+	origin := make(chan serverutil.Locality, 1)
+	bus.Subscribe("topic", func(ctx context.Context, msg *eventbus.Message) error {
+		origin <- msg.Origin
+		return nil
+	})
+	bus.Publish("topic", "hello")
+
+	select {
+	case o := <-origin:
+		assert.Equal(t, "us-east1", o.Region)
+	case <-time.After(time.Second):
+		t.Fatal("subscriber should have been called")
+	}
+}
+
 func TestBus_MultipleSubscribers(t *testing.T) {
 	bus := New(logging.EnsureLogger(t.Context()))
 
@@ -0,0 +1,81 @@
+package eventbus
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/resilience"
+)
+
+// Middleware wraps a Handler, e.g. to add logging or retries.
+type Middleware func(Handler) Handler
+
+// Chain wraps a handler with middleware, the first being outermost.
+func Chain(h Handler, mw ...Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// Logging logs each message handled, with its topic, ID, attempt, and
+// duration. Failures are logged as warnings, successes at debug level.
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			fields := []any{
+				"topic", msg.Topic,
+				"message_id", msg.ID,
+				"attempt", msg.Attempt,
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				logging.Warnw(ctx, "eventbus: handler failed", append(fields, "error", err)...)
+			} else {
+				logging.Debugw(ctx, "eventbus: handled message", fields...)
+			}
+			return err
+		}
+	}
+}
+
+// Retry retries failed handlers with backoff, according to the policy. The
+// message's Attempt is incremented for each retry. Wrap Retry with Recover to
+// also retry handlers which panic.
+//
+// Example:
+//
+//	eventbus.Retry(resilience.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Second})
+func Retry(policy resilience.RetryPolicy) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) error {
+			first := true
+			return policy.Do(ctx, func(ctx context.Context) error {
+				if !first {
+					msg.Attempt++
+				}
+				first = false
+				return next(ctx, msg)
+			})
+		}
+	}
+}
+
+// Recover turns panics in the handler into errors, so that they can be retried
+// or dead-lettered like other failures.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg *Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = errors.Errorf("eventbus: handler panicked: %v", r)
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
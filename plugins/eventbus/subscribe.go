@@ -0,0 +1,213 @@
+package eventbus
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc/codes"
+)
+
+// DeadLetterTopic is the topic PublishDeadLetters publishes DeadLetter
+// payloads to.
+const DeadLetterTopic = "eventbus.dead_letter"
+
+// SubscribeOption configures a subscription made with Subscribe.
+type SubscribeOption func(*subscription)
+
+type subscription struct {
+	middleware []Middleware
+	async      bool
+	deadLetter DeadLetterHandler
+}
+
+// WithMiddleware wraps the subscription's handler. Middleware runs in the order
+// given, so the first is outermost.
+func WithMiddleware(mw ...Middleware) SubscribeOption {
+	return func(s *subscription) {
+		s.middleware = append(s.middleware, mw...)
+	}
+}
+
+// Async acknowledges messages as soon as they are delivered and handles them in
+// a separate goroutine, so that slow handlers don't hold up the bus. Failures
+// can't be redelivered, so should be handled with WithDeadLetter. By default
+// handlers run synchronously, as part of delivery. Use WaitAsync to wait for
+// async handlers to finish.
+func Async() SubscribeOption {
+	return func(s *subscription) {
+		s.async = true
+	}
+}
+
+// WithDeadLetter sets a handler for messages which fail, after any retries, or
+// whose payload isn't of the subscribed type. Dead-lettered messages are
+// treated as handled, so they aren't redelivered.
+func WithDeadLetter(h DeadLetterHandler) SubscribeOption {
+	return func(s *subscription) {
+		s.deadLetter = h
+	}
+}
+
+// DeadLetterHandler receives messages which couldn't be handled, along with the
+// handler's error.
+type DeadLetterHandler func(ctx context.Context, msg *Message, err error)
+
+// DeadLetter is published to DeadLetterTopic by PublishDeadLetters.
+type DeadLetter struct {
+	Topic     string
+	MessageID string
+	Data      any
+	Attempt   int
+	Error     string
+}
+
+// PublishDeadLetters returns a dead-letter handler which publishes failed
+// messages to DeadLetterTopic, so they can be logged, stored, or replayed in
+// one place.
+//
+// Example:
+//
+//	eventbus.Subscribe(bus, "order.created", sendReceipt,
+//	    eventbus.WithDeadLetter(eventbus.PublishDeadLetters(bus)))
+func PublishDeadLetters(bus EventBus) DeadLetterHandler {
+	return func(_ context.Context, msg *Message, err error) {
+		bus.Publish(DeadLetterTopic, DeadLetter{
+			Topic:     msg.Topic,
+			MessageID: msg.ID,
+			Data:      msg.Data,
+			Attempt:   msg.Attempt,
+			Error:     err.Error(),
+		})
+	}
+}
+
+// Subscribe registers a handler which receives the payloads of messages on a
+// topic as type T. Payloads of type *T are dereferenced. Other payloads fail
+// with an InvalidArgument error, and are passed to the dead-letter handler if
+// there is one. The message itself is available via MessageFromContext.
+//
+// Example:
+//
+//	eventbus.Subscribe(bus, storage.ChangeTopic("Document"),
+//	    func(ctx context.Context, c storage.Change) error {
+//	        return reindex(ctx, c.ID)
+//	    },
+//	    eventbus.WithMiddleware(eventbus.Recover(), eventbus.Retry(resilience.RetryPolicy{})),
+//	)
+func Subscribe[T any](bus EventBus, topic string, fn func(context.Context, T) error, opts ...SubscribeOption) {
+	SubscribeHandler(bus, topic, func(ctx context.Context, msg *Message) error {
+		data, err := payload[T](msg)
+		if err != nil {
+			return err
+		}
+		return fn(withMessage(ctx, msg), data)
+	}, opts...)
+}
+
+// SubscribeHandler registers an untyped handler, with the same options as
+// Subscribe.
+func SubscribeHandler(bus EventBus, topic string, h Handler, opts ...SubscribeOption) {
+	s := &subscription{}
+	for _, opt := range opts {
+		opt(s)
+	}
+	h = Chain(h, s.middleware...)
+	if s.deadLetter != nil {
+		h = deadLetters(h, s.deadLetter)
+	}
+	if s.async {
+		h = async(h)
+	}
+	bus.Subscribe(topic, h)
+}
+
+// payload returns the message's data as a T.
+func payload[T any](msg *Message) (T, error) {
+	if v, ok := msg.Data.(T); ok {
+		return v, nil
+	}
+	if p, ok := msg.Data.(*T); ok && p != nil {
+		return *p, nil
+	}
+	var zero T
+	return zero, errors.Codef(codes.InvalidArgument, "eventbus: %s expected a %v payload, got %T",
+		msg.Topic, reflect.TypeFor[T](), msg.Data)
+}
+
+func deadLetters(h Handler, dl DeadLetterHandler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		if err := h(ctx, msg); err != nil {
+			dl(ctx, msg, err)
+		}
+		return nil
+	}
+}
+
+// asyncHandlers tracks handlers running in the background, across buses. A
+// WaitGroup isn't used since handlers may start while WaitAsync is waiting.
+var asyncHandlers struct {
+	mu      sync.Mutex
+	running int
+	idle    chan struct{} // Closed when running drops to zero.
+}
+
+func async(h Handler) Handler {
+	return func(ctx context.Context, msg *Message) error {
+		asyncHandlers.mu.Lock()
+		if asyncHandlers.running == 0 {
+			asyncHandlers.idle = make(chan struct{})
+		}
+		asyncHandlers.running++
+		asyncHandlers.mu.Unlock()
+
+		go func() {
+			defer func() {
+				asyncHandlers.mu.Lock()
+				asyncHandlers.running--
+				if asyncHandlers.running == 0 {
+					close(asyncHandlers.idle)
+				}
+				asyncHandlers.mu.Unlock()
+			}()
+			if err := h(ctx, msg); err != nil {
+				logging.Errorw(ctx, "eventbus: async handler error", "error", err, "message_id", msg.ID)
+			}
+		}()
+		return nil
+	}
+}
+
+// WaitAsync blocks until handlers subscribed with Async have finished. It is
+// called by the plugin on shutdown.
+func WaitAsync(ctx context.Context) error {
+	asyncHandlers.mu.Lock()
+	if asyncHandlers.running == 0 {
+		asyncHandlers.mu.Unlock()
+		return nil
+	}
+	idle := asyncHandlers.idle
+	asyncHandlers.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return errors.New("eventbus: timeout waiting for async handlers to finish")
+	}
+}
+
+type messageKey struct{}
+
+func withMessage(ctx context.Context, msg *Message) context.Context {
+	return context.WithValue(ctx, messageKey{}, msg)
+}
+
+// MessageFromContext returns the message being handled by a handler registered
+// with Subscribe, or nil.
+func MessageFromContext(ctx context.Context) *Message {
+	msg, _ := ctx.Value(messageKey{}).(*Message)
+	return msg
+}
@@ -0,0 +1,136 @@
+package eventbus_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+	"github.com/dpup/prefab/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type order struct {
+	ID    string
+	Total int
+}
+
+func TestSubscribe_Typed(t *testing.T) {
+	bus := membus.New(logging.EnsureLogger(t.Context()))
+
+	got := make(chan order, 2)
+	eventbus.Subscribe(bus, "order", func(ctx context.Context, o order) error {
+		assert.Equal(t, "order", eventbus.MessageFromContext(ctx).Topic)
+		got <- o
+		return nil
+	})
+
+	bus.Publish("order", order{ID: "1", Total: 10})
+	bus.Publish("order", &order{ID: "2", Total: 20})
+	require.NoError(t, bus.Wait(t.Context()))
+
+	assert.Equal(t, order{ID: "1", Total: 10}, <-got)
+	assert.Equal(t, order{ID: "2", Total: 20}, <-got)
+}
+
+func TestSubscribe_DeadLetter(t *testing.T) {
+	bus := membus.New(logging.EnsureLogger(t.Context()))
+
+	dead := make(chan eventbus.DeadLetter, 2)
+	eventbus.Subscribe(bus, eventbus.DeadLetterTopic, func(_ context.Context, dl eventbus.DeadLetter) error {
+		dead <- dl
+		return nil
+	})
+	eventbus.Subscribe(bus, "order", func(context.Context, order) error {
+		return errors.New("boom")
+	}, eventbus.WithDeadLetter(eventbus.PublishDeadLetters(bus)))
+
+	// Failed handlers and unexpected payloads are both dead-lettered.
+	bus.Publish("order", order{ID: "1"})
+	bus.Publish("order", "not an order")
+	require.NoError(t, bus.Wait(t.Context()))
+
+	var errs []string
+	for range 2 {
+		select {
+		case dl := <-dead:
+			assert.Equal(t, "order", dl.Topic)
+			assert.NotEmpty(t, dl.MessageID)
+			errs = append(errs, dl.Error)
+		case <-time.After(time.Second):
+			t.Fatal("expected dead letter")
+		}
+	}
+	assert.ElementsMatch(t, []string{"boom", "eventbus: order expected a eventbus_test.order payload, got string"}, errs)
+}
+
+func TestMiddleware_RetryAndRecover(t *testing.T) {
+	ctx := logging.EnsureLogger(t.Context())
+	calls := 0
+	h := eventbus.Chain(func(context.Context, *eventbus.Message) error {
+		calls++
+		if calls < 3 {
+			panic("not yet")
+		}
+		return nil
+	},
+		eventbus.Logging(),
+		eventbus.Retry(resilience.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}),
+		eventbus.Recover(),
+	)
+
+	msg := eventbus.NewMessage("1", "topic", nil)
+	require.NoError(t, h(ctx, msg))
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 3, msg.Attempt)
+}
+
+func TestMiddleware_RetrySkipsInvalidPayloads(t *testing.T) {
+	bus := membus.New(logging.EnsureLogger(t.Context()))
+
+	errs := make(chan error, 1)
+	eventbus.Subscribe(bus, "order", func(context.Context, order) error {
+		return nil
+	},
+		eventbus.WithMiddleware(eventbus.Retry(resilience.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond})),
+		eventbus.WithDeadLetter(func(_ context.Context, msg *eventbus.Message, err error) {
+			assert.Equal(t, 1, msg.Attempt)
+			errs <- err
+		}),
+	)
+
+	bus.Publish("order", 42)
+	require.NoError(t, bus.Wait(t.Context()))
+	assert.Equal(t, codes.InvalidArgument, status.Code(<-errs))
+}
+
+func TestSubscribe_Async(t *testing.T) {
+	bus := membus.New(logging.EnsureLogger(t.Context()))
+
+	release := make(chan struct{})
+	done := make(chan order, 1)
+	eventbus.Subscribe(bus, "order", func(_ context.Context, o order) error {
+		<-release
+		done <- o
+		return nil
+	}, eventbus.Async())
+
+	// The bus drains while the handler is still running.
+	bus.Publish("order", order{ID: "1"})
+	require.NoError(t, bus.Wait(t.Context()))
+	assert.Empty(t, done)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	require.Error(t, eventbus.WaitAsync(ctx))
+
+	close(release)
+	require.NoError(t, eventbus.WaitAsync(t.Context()))
+	assert.Equal(t, order{ID: "1"}, <-done)
+}
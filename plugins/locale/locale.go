@@ -0,0 +1,332 @@
+// Package locale provides a plugin which negotiates the locale and timezone of
+// each request.
+//
+// The locale is matched from the Accept-Language header against the supported
+// locales, and the timezone is read from a header or cookie set by the client,
+// for example from `Intl.DateTimeFormat().resolvedOptions().timeZone`. Both are
+// available via serverutil.LocaleFromContext and
+// serverutil.TimezoneFromContext, and the negotiated locale is returned to the
+// client in a Content-Language header.
+//
+// A Localizer can be configured to translate the user presentable messages of
+// errors returned by gRPC handlers:
+//
+//	prefab.WithPlugin(locale.Plugin(
+//	    locale.WithSupportedLocales("en", "fr", "de"),
+//	    locale.WithLocalizer(func(locale, msg string) string {
+//	        return catalog.Translate(locale, msg)
+//	    }),
+//	)),
+package locale
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// PluginName is the name of the locale plugin.
+const PluginName = "locale"
+
+const (
+	defaultLocale         = "en"
+	defaultTimezoneHeader = "X-Timezone"
+	defaultTimezoneCookie = "pf-tz"
+
+	// Maximum number of timezone names cached by a plugin. The IANA database
+	// has around 600, and unknown names are cached too.
+	maxCachedTimezones = 1024
+
+	// Longest timezone name accepted, IANA names are much shorter.
+	maxTimezoneLength = 64
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "locale.supported",
+			Description: "BCP 47 tags of the locales supported by the application",
+			Type:        "[]string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "locale.default",
+			Description: "Locale used when none of the client's preferred locales are supported",
+			Type:        "string",
+			Default:     defaultLocale,
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "locale.timezoneHeader",
+			Description: "Request header containing the client's IANA timezone",
+			Type:        "string",
+			Default:     defaultTimezoneHeader,
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "locale.timezoneCookie",
+			Description: "Cookie containing the client's IANA timezone, used when the header is absent",
+			Type:        "string",
+			Default:     defaultTimezoneCookie,
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "locale.defaultTimezone",
+			Description: "Timezone used when the client doesn't provide one",
+			Type:        "string",
+			Default:     "UTC",
+		},
+	)
+}
+
+// LocaleOption customizes the locale plugin.
+type LocaleOption func(*LocalePlugin)
+
+// WithSupportedLocales sets the locales the application supports, as BCP 47
+// tags such as "en", "en-GB", or "pt-BR".
+//
+// Config key: `locale.supported`.
+func WithSupportedLocales(locales ...string) LocaleOption {
+	return func(p *LocalePlugin) {
+		p.supported = locales
+	}
+}
+
+// WithDefaultLocale sets the locale used when none of the client's preferences
+// are supported.
+//
+// Config key: `locale.default`.
+func WithDefaultLocale(locale string) LocaleOption {
+	return func(p *LocalePlugin) {
+		p.defaultLocale = locale
+	}
+}
+
+// WithTimezoneSource sets the header and cookie the client's timezone is read
+// from. Either may be empty to disable that source.
+//
+// Config keys: `locale.timezoneHeader`, `locale.timezoneCookie`.
+func WithTimezoneSource(header, cookie string) LocaleOption {
+	return func(p *LocalePlugin) {
+		p.timezoneHeader = header
+		p.timezoneCookie = cookie
+	}
+}
+
+// WithDefaultTimezone sets the timezone used when the client doesn't provide
+// one.
+//
+// Config key: `locale.defaultTimezone`.
+func WithDefaultTimezone(loc *time.Location) LocaleOption {
+	return func(p *LocalePlugin) {
+		p.defaultTimezone = loc
+	}
+}
+
+// WithLocalizer configures a function used to translate the user presentable
+// messages of errors returned to the client. It is also available to handlers
+// via serverutil.Localize.
+func WithLocalizer(l errors.Localizer) LocaleOption {
+	return func(p *LocalePlugin) {
+		p.localizer = l
+	}
+}
+
+// Plugin returns a new LocalePlugin.
+func Plugin(opts ...LocaleOption) *LocalePlugin {
+	p := &LocalePlugin{
+		supported:      prefab.ConfigStrings("locale.supported"),
+		defaultLocale:  prefab.ConfigString("locale.default"),
+		timezoneHeader: prefab.ConfigString("locale.timezoneHeader"),
+		timezoneCookie: prefab.ConfigString("locale.timezoneCookie"),
+	}
+	if p.defaultLocale == "" {
+		p.defaultLocale = defaultLocale
+	}
+	if p.timezoneHeader == "" {
+		p.timezoneHeader = defaultTimezoneHeader
+	}
+	if p.timezoneCookie == "" {
+		p.timezoneCookie = defaultTimezoneCookie
+	}
+	if tz := prefab.ConfigString("locale.defaultTimezone"); tz != "" {
+		p.defaultTimezone = p.loadLocation(tz)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// LocalePlugin negotiates the locale and timezone of each request.
+type LocalePlugin struct {
+	supported       []string
+	defaultLocale   string
+	timezoneHeader  string
+	timezoneCookie  string
+	defaultTimezone *time.Location
+	localizer       errors.Localizer
+
+	tags    []language.Tag
+	matcher language.Matcher
+
+	// Timezones loaded for client provided names, nil if unknown.
+	mu        sync.Mutex
+	timezones map[string]*time.Location
+}
+
+// From prefab.Plugin.
+func (p *LocalePlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionProvider.
+func (p *LocalePlugin) ServerOptions() []prefab.ServerOption {
+	opts := []prefab.ServerOption{
+		prefab.WithRequestConfig(p.negotiate),
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseObservability)),
+	}
+	if p.timezoneHeader != "" {
+		opts = append(opts, prefab.WithIncomingHeaders(p.timezoneHeader))
+	}
+	return opts
+}
+
+// From prefab.InitializablePlugin.
+func (p *LocalePlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	// The default locale comes first, the matcher falls back to it when there is
+	// no match.
+	p.tags = nil
+	for _, l := range append([]string{p.defaultLocale}, p.supported...) {
+		tag, err := language.Parse(l)
+		if err != nil {
+			return errors.WrapPrefix(err, "locale: invalid locale "+l, 0).WithCode(codes.InvalidArgument)
+		}
+		p.tags = append(p.tags, tag)
+	}
+	p.matcher = language.NewMatcher(p.tags)
+	return nil
+}
+
+// Negotiate returns the supported locale that best matches an Accept-Language
+// header value.
+func (p *LocalePlugin) Negotiate(acceptLanguage string) string {
+	if p.matcher == nil {
+		return p.defaultLocale
+	}
+	prefs, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(prefs) == 0 {
+		return p.tags[0].String()
+	}
+	_, idx, conf := p.matcher.Match(prefs...)
+	if conf == language.No {
+		return p.tags[0].String()
+	}
+	return p.tags[idx].String()
+}
+
+// Timezone returns the timezone provided by the client, or the default timezone
+// if it is missing or invalid.
+func (p *LocalePlugin) Timezone(ctx context.Context) *time.Location {
+	name := ""
+	if p.timezoneHeader != "" {
+		name = header(ctx, p.timezoneHeader)
+	}
+	if name == "" && p.timezoneCookie != "" {
+		if c, ok := serverutil.CookiesFromIncomingContext(ctx)[p.timezoneCookie]; ok {
+			name = c.Value
+		}
+	}
+	if loc := p.loadLocation(name); loc != nil {
+		return loc
+	}
+	if p.defaultTimezone != nil {
+		return p.defaultTimezone
+	}
+	return time.UTC
+}
+
+// loadLocation returns the timezone with an IANA name, or nil. Names come from
+// clients, so results are cached rather than reading the timezone database on
+// every request, and names which couldn't be IANA names aren't looked up.
+func (p *LocalePlugin) loadLocation(name string) *time.Location {
+	if !isTimezoneName(name) {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if loc, ok := p.timezones[name]; ok {
+		return loc
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		loc = nil
+	}
+	if p.timezones == nil || len(p.timezones) >= maxCachedTimezones {
+		p.timezones = map[string]*time.Location{}
+	}
+	p.timezones[name] = loc
+	return loc
+}
+
+// isTimezoneName returns whether name looks like an IANA timezone name, such as
+// "America/Port-au-Prince" or "Etc/GMT+5". "Local" is rejected, since it is
+// the server's timezone.
+func isTimezoneName(name string) bool {
+	if name == "" || len(name) > maxTimezoneLength || name == "Local" {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+		for _, r := range part {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			case r == '_', r == '-', r == '+':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (p *LocalePlugin) negotiate(ctx context.Context) context.Context {
+	ctx = serverutil.WithLocale(ctx, p.Negotiate(header(ctx, "accept-language")))
+	if p.localizer != nil {
+		ctx = serverutil.WithLocalizer(ctx, p.localizer)
+	}
+	return serverutil.WithTimezone(ctx, p.Timezone(ctx))
+}
+
+func (p *LocalePlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	locale := serverutil.LocaleFromContext(ctx)
+	if locale != "" {
+		// Fails outside of a gRPC stream, in which case there's no one to tell.
+		_ = serverutil.SendHeader(ctx, "content-language", locale)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil && p.localizer != nil {
+		return resp, errors.Localize(err, locale, p.localizer)
+	}
+	return resp, err
+}
+
+// header reads a request header, either forwarded by the gateway or sent as
+// metadata by a gRPC client.
+func header(ctx context.Context, name string) string {
+	if v := serverutil.HTTPHeader(ctx, name); v != "" {
+		return v
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package locale
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newPlugin(t *testing.T, opts ...LocaleOption) *LocalePlugin {
+	p := Plugin(opts...)
+	require.NoError(t, p.Init(t.Context(), nil))
+	return p
+}
+
+func TestNegotiate(t *testing.T) {
+	p := newPlugin(t, WithSupportedLocales("en", "en-GB", "fr", "pt-BR"))
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"fr-CA,fr;q=0.9,en;q=0.8", "fr"},
+		{"en-GB", "en-GB"},
+		{"en-AU", "en-GB"},
+		{"pt", "pt-BR"},
+		{"de-DE,ja;q=0.5", "en"},
+		{"garbage;;;", "en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, p.Negotiate(tt.header))
+		})
+	}
+}
+
+func TestInit_InvalidLocale(t *testing.T) {
+	err := Plugin(WithSupportedLocales("en", "not a locale")).Init(t.Context(), nil)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+func TestTimezone(t *testing.T) {
+	p := newPlugin(t)
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+		"pf-header-x-timezone", "Pacific/Auckland",
+		"grpcgateway-cookie", "pf-tz=Europe/Paris",
+	))
+	assert.Equal(t, "Pacific/Auckland", p.Timezone(ctx).String())
+
+	ctx = metadata.NewIncomingContext(t.Context(), metadata.Pairs("grpcgateway-cookie", "pf-tz=Europe/Paris"))
+	assert.Equal(t, "Europe/Paris", p.Timezone(ctx).String())
+
+	ctx = metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-timezone", "Mars/Olympus"))
+	assert.Equal(t, "UTC", p.Timezone(ctx).String())
+}
+
+func TestTimezone_Names(t *testing.T) {
+	p := newPlugin(t)
+	for _, name := range []string{"Local", "../../etc/passwd", "/etc/localtime", "Europe//Paris", "Europe/Paris ", strings.Repeat("A", 65)} {
+		assert.Nil(t, p.loadLocation(name), name)
+	}
+	for _, name := range []string{"UTC", "America/Port-au-Prince", "Etc/GMT+5", "America/Argentina/Buenos_Aires"} {
+		assert.Equal(t, name, p.loadLocation(name).String())
+	}
+
+	// Lookups are cached, and the cache is bounded.
+	assert.Same(t, p.loadLocation("Europe/Paris"), p.loadLocation("Europe/Paris"))
+	for i := range maxCachedTimezones + 10 {
+		p.loadLocation(fmt.Sprintf("Mars/Crater%d", i))
+	}
+	assert.LessOrEqual(t, len(p.timezones), maxCachedTimezones)
+}
+
+func TestInterceptor_Localize(t *testing.T) {
+	errNotFound := errors.NewC("note not found", codes.NotFound).WithUserPresentableMessage("Note not found")
+	p := newPlugin(t,
+		WithSupportedLocales("en", "fr"),
+		WithLocalizer(func(locale, msg string) string {
+			if locale == "fr" && msg == "Note not found" {
+				return "Note introuvable"
+			}
+			return ""
+		}),
+	)
+
+	call := func(acceptLanguage string) error {
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("accept-language", acceptLanguage))
+		_, err := p.interceptor(p.negotiate(ctx), nil, &grpc.UnaryServerInfo{}, func(context.Context, any) (any, error) {
+			return nil, errNotFound
+		})
+		return err
+	}
+
+	st := status.Convert(call("fr"))
+	assert.Equal(t, codes.NotFound, st.Code())
+	assert.Equal(t, "Note introuvable", st.Message())
+
+	assert.Equal(t, "Note not found", status.Convert(call("en")).Message())
+
+	// The sentinel isn't modified.
+	assert.Equal(t, "Note not found", errNotFound.UserPresentableMessage())
+}
+
+func TestGateway(t *testing.T) {
+	var gotLocale, gotTZ string
+	s := prefabtest.New(t,
+		prefabtest.WithPlugins(Plugin(WithSupportedLocales("en", "de"))),
+		prefabtest.WithOptions(prefab.WithHTTPHandlerFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+			gotLocale = serverutil.LocaleFromContext(r.Context())
+			gotTZ = serverutil.TimezoneFromContext(r.Context()).String()
+		})),
+	)
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL("/api/meta/config"), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "de-AT,de;q=0.9")
+	resp, err := s.HTTPClient().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "de", resp.Header.Get("Content-Language"))
+
+	req, err = http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL("/whoami"), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("X-Timezone", "America/New_York")
+	resp, err = s.HTTPClient().Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "de", gotLocale)
+	assert.Equal(t, "America/New_York", gotTZ)
+}
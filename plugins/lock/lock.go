@@ -0,0 +1,173 @@
+// Package lock provides distributed locks and leader election, so that
+// background work such as token cleanup or job scheduling runs on a single
+// replica at a time.
+//
+// Implementations are provided in sub-packages: memlock for single-process
+// deployments and tests, and pglock which uses PostgreSQL advisory locks.
+//
+// Example:
+//
+//	server := prefab.New(
+//		prefab.WithPlugin(lock.Plugin(pglock.New(connString))),
+//	)
+//
+//	// In a plugin that depends on lock.PluginName:
+//	lp := r.Get(lock.PluginName).(*lock.LockPlugin)
+//	go lp.RunWhenLeader(ctx, "reports", func(ctx context.Context) error {
+//		// Runs on at most one replica until ctx is cancelled.
+//		return nil
+//	})
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// PluginName identifies this plugin.
+	PluginName = "lock"
+)
+
+// ErrNotAcquired is returned by TryLock when the lock is held elsewhere.
+var ErrNotAcquired = errors.NewC("lock: not acquired", codes.Aborted)
+
+// Locker acquires named locks which are exclusive across all processes sharing
+// the same backend.
+type Locker interface {
+	// Lock blocks until the named lock is acquired or the context is done.
+	Lock(ctx context.Context, name string) (Lease, error)
+
+	// TryLock acquires the named lock if it is free, otherwise it returns
+	// ErrNotAcquired.
+	TryLock(ctx context.Context, name string) (Lease, error)
+}
+
+// Lease represents a held lock.
+type Lease interface {
+	// Unlock releases the lock.
+	Unlock(ctx context.Context) error
+
+	// Lost returns a channel which is closed when the lock is no longer held,
+	// either because it was released or because the connection to the backend
+	// was lost.
+	Lost() <-chan struct{}
+}
+
+// Plugin registers a Locker with a Prefab server for use by other plugins. The
+// locker can be retrieved from the request context using the FromContext
+// function.
+func Plugin(l Locker) *LockPlugin {
+	return &LockPlugin{
+		Locker:     l,
+		RetryDelay: 5 * time.Second,
+	}
+}
+
+// LockPlugin provides access to distributed locks and leader election.
+type LockPlugin struct {
+	Locker
+
+	// RetryDelay is how long RunWhenLeader waits before retrying after the
+	// backend returns an error.
+	RetryDelay time.Duration
+}
+
+// From prefab.Plugin.
+func (p *LockPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionProvider.
+func (p *LockPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// RunWhenLeader runs fn while this process holds the named lock. It blocks
+// until the lock is acquired, then calls fn with a context that is cancelled
+// if leadership is lost. If leadership is lost, the lock is reacquired and fn
+// is called again.
+//
+// RunWhenLeader returns the result of fn once it returns while still holding
+// the lock, or nil when ctx is done.
+func (p *LockPlugin) RunWhenLeader(ctx context.Context, name string, fn func(context.Context) error) error {
+	for {
+		lease, err := p.Lock(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			logging.Errorw(ctx, "lock: failed to acquire leadership", "lock", name, "error", err)
+			if !sleep(ctx, p.RetryDelay) {
+				return nil
+			}
+			continue
+		}
+
+		logging.Infow(ctx, "lock: acquired leadership", "lock", name)
+		lost, err := runWithLease(ctx, lease, fn)
+		if !lost {
+			if uerr := lease.Unlock(context.WithoutCancel(ctx)); uerr != nil {
+				logging.Errorw(ctx, "lock: failed to release leadership", "lock", name, "error", uerr)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		logging.Warnw(ctx, "lock: lost leadership", "lock", name)
+	}
+}
+
+// runWithLease calls fn with a context that is cancelled when the lease is
+// lost, and reports whether the lease was lost.
+func runWithLease(ctx context.Context, lease Lease, fn func(context.Context) error) (bool, error) {
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-lease.Lost():
+			cancel()
+		case <-leaderCtx.Done():
+		}
+	}()
+	err := fn(leaderCtx)
+	select {
+	case <-lease.Lost():
+		return true, err
+	default:
+		return false, err
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+func (p *LockPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lockKey{}, p)
+}
+
+// FromContext retrieves the locker from a context.
+func FromContext(ctx context.Context) *LockPlugin {
+	if p, ok := ctx.Value(lockKey{}).(*LockPlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type lockKey struct{}
@@ -0,0 +1,94 @@
+package lock_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/lock/memlock"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWhenLeader_SingleLeader(t *testing.T) {
+	ctx, cancel := context.WithCancel(prefabtest.Context(t))
+	p := lock.Plugin(memlock.New())
+
+	var running, maxRunning atomic.Int32
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.RunWhenLeader(ctx, "worker", func(ctx context.Context) error {
+				n := running.Add(1)
+				if n > maxRunning.Load() {
+					maxRunning.Store(n)
+				}
+				time.Sleep(5 * time.Millisecond)
+				running.Add(-1)
+				<-ctx.Done()
+				return nil
+			})
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.Eventually(t, func() bool { return maxRunning.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	assert.Equal(t, int32(1), maxRunning.Load(), "only one leader runs at a time")
+}
+
+func TestRunWhenLeader_ReturnsError(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	l := memlock.New()
+	p := lock.Plugin(l)
+
+	boom := errors.New("boom")
+	err := p.RunWhenLeader(ctx, "worker", func(context.Context) error { return boom })
+	require.ErrorIs(t, err, boom)
+
+	lease, err := l.TryLock(ctx, "worker")
+	require.NoError(t, err, "lock is released when fn returns")
+	require.NoError(t, lease.Unlock(ctx))
+}
+
+func TestRunWhenLeader_LostLeadership(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	l := &losingLocker{Locker: memlock.New()}
+	p := lock.Plugin(l)
+
+	var calls int
+	err := p.RunWhenLeader(ctx, "worker", func(ctx context.Context) error {
+		calls++
+		if calls == 1 {
+			// Simulate the backend dropping the lock.
+			require.NoError(t, l.last.Unlock(ctx))
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "fn is called again after leadership is reacquired")
+}
+
+// losingLocker records the last lease so tests can release it out from under
+// RunWhenLeader.
+type losingLocker struct {
+	lock.Locker
+	last lock.Lease
+}
+
+func (l *losingLocker) Lock(ctx context.Context, name string) (lock.Lease, error) {
+	lease, err := l.Locker.Lock(ctx, name)
+	l.last = lease
+	return lease, err
+}
@@ -0,0 +1,87 @@
+// Package memlock provides an in-memory implementation of lock.Locker. Locks are
+// only exclusive within a single process, so it is suitable for development,
+// tests, and single-replica deployments.
+package memlock
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/lock"
+)
+
+// New returns a new in-memory Locker.
+func New() lock.Locker {
+	return &Locker{
+		held: map[string]*lease{},
+	}
+}
+
+// Locker is an in-memory implementation of lock.Locker.
+type Locker struct {
+	mu   sync.Mutex
+	held map[string]*lease
+}
+
+// Lock blocks until the named lock is acquired or the context is done.
+func (l *Locker) Lock(ctx context.Context, name string) (lock.Lease, error) {
+	for {
+		l.mu.Lock()
+		current, ok := l.held[name]
+		if !ok {
+			le := l.acquire(name)
+			l.mu.Unlock()
+			return le, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.Wrap(ctx.Err(), 0)
+		case <-current.lost:
+		}
+	}
+}
+
+// TryLock acquires the named lock if it is free.
+func (l *Locker) TryLock(_ context.Context, name string) (lock.Lease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[name]; ok {
+		return nil, errors.Mark(lock.ErrNotAcquired, 0)
+	}
+	return l.acquire(name), nil
+}
+
+// acquire records a new lease. Must be called with l.mu held.
+func (l *Locker) acquire(name string) *lease {
+	le := &lease{locker: l, name: name, lost: make(chan struct{})}
+	l.held[name] = le
+	return le
+}
+
+type lease struct {
+	locker *Locker
+	name   string
+	lost   chan struct{}
+	once   sync.Once
+}
+
+// Unlock releases the lock. Unlocking more than once is a no-op.
+func (le *lease) Unlock(_ context.Context) error {
+	le.once.Do(func() {
+		le.locker.mu.Lock()
+		if le.locker.held[le.name] == le {
+			delete(le.locker.held, le.name)
+		}
+		le.locker.mu.Unlock()
+		close(le.lost)
+	})
+	return nil
+}
+
+// Lost returns a channel which is closed once the lock is released.
+func (le *lease) Lost() <-chan struct{} {
+	return le.lost
+}
@@ -0,0 +1,80 @@
+package memlock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTryLock(t *testing.T) {
+	l := New()
+	ctx := t.Context()
+
+	lease, err := l.TryLock(ctx, "a")
+	require.NoError(t, err)
+
+	_, err = l.TryLock(ctx, "a")
+	assert.True(t, errors.Is(err, lock.ErrNotAcquired))
+
+	other, err := l.TryLock(ctx, "b")
+	require.NoError(t, err, "locks are independent")
+	require.NoError(t, other.Unlock(ctx))
+
+	require.NoError(t, lease.Unlock(ctx))
+	select {
+	case <-lease.Lost():
+	default:
+		t.Fatal("expected lease to be released")
+	}
+	require.NoError(t, lease.Unlock(ctx), "unlocking twice is a no-op")
+
+	lease, err = l.TryLock(ctx, "a")
+	require.NoError(t, err)
+	require.NoError(t, lease.Unlock(ctx))
+}
+
+func TestLock_Blocks(t *testing.T) {
+	l := New()
+	ctx := t.Context()
+
+	lease, err := l.Lock(ctx, "a")
+	require.NoError(t, err)
+
+	acquired := make(chan lock.Lease)
+	go func() {
+		next, err := l.Lock(ctx, "a")
+		assert.NoError(t, err)
+		acquired <- next
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired while held")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	require.NoError(t, lease.Unlock(ctx))
+	select {
+	case next := <-acquired:
+		require.NoError(t, next.Unlock(ctx))
+	case <-time.After(time.Second):
+		t.Fatal("lock not acquired after release")
+	}
+}
+
+func TestLock_ContextDone(t *testing.T) {
+	l := New()
+	lease, err := l.Lock(t.Context(), "a")
+	require.NoError(t, err)
+	defer lease.Unlock(t.Context())
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,101 @@
+package pglock
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lockRows(acquired bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(acquired)
+}
+
+func TestTryLock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	l := NewWithDB(db, WithKeepAliveInterval(time.Hour))
+	key := Key("jobs")
+
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).WillReturnRows(lockRows(false))
+	_, err = l.TryLock(t.Context(), "jobs")
+	assert.True(t, errors.Is(err, lock.ErrNotAcquired))
+
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WithArgs(key).WillReturnRows(lockRows(true))
+	mock.ExpectExec("SELECT pg_advisory_unlock").WithArgs(key).WillReturnResult(driver.ResultNoRows)
+	lease, err := l.TryLock(t.Context(), "jobs")
+	require.NoError(t, err)
+	require.NoError(t, lease.Unlock(t.Context()))
+	require.NoError(t, lease.Unlock(t.Context()), "unlocking twice is a no-op")
+
+	select {
+	case <-lease.Lost():
+	default:
+		t.Fatal("expected lease to be released")
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLock_Polls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	l := NewWithDB(db, WithPollInterval(time.Millisecond), WithKeepAliveInterval(time.Hour))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(lockRows(false))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(lockRows(false))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(lockRows(true))
+
+	_, err = l.Lock(t.Context(), "jobs")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLock_ContextDone(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	l := NewWithDB(db, WithPollInterval(time.Hour))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(lockRows(false))
+
+	ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "jobs")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestLease_Lost(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	defer db.Close()
+
+	l := NewWithDB(db, WithKeepAliveInterval(5*time.Millisecond))
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").WillReturnRows(lockRows(true))
+	mock.ExpectPing()
+	mock.ExpectPing().WillReturnError(driver.ErrBadConn)
+
+	lease, err := l.TryLock(t.Context(), "jobs")
+	require.NoError(t, err)
+
+	select {
+	case <-lease.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("expected lease to be lost")
+	}
+	require.NoError(t, lease.Unlock(t.Context()), "no unlock is sent once the session is gone")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, Key("jobs"), Key("jobs"))
+	assert.NotEqual(t, Key("jobs"), Key("oauth.cleanup"))
+}
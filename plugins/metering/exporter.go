@@ -0,0 +1,98 @@
+package metering
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dpup/prefab/errors"
+)
+
+// WebhookSignatureHeader carries the HMAC-SHA256 of the request body when a
+// webhook secret is configured.
+const WebhookSignatureHeader = "X-Prefab-Signature"
+
+// Exporter sends usage records whose period has ended to a billing system.
+// Records may be sent more than once, for example if late usage changes their
+// quantity, so exporters should use the record ID as an idempotency key.
+type Exporter interface {
+	Export(ctx context.Context, records []UsageRecord) error
+}
+
+// ExporterFunc adapts a function to the Exporter interface.
+type ExporterFunc func(ctx context.Context, records []UsageRecord) error
+
+// Export calls f.
+func (f ExporterFunc) Export(ctx context.Context, records []UsageRecord) error {
+	return f(ctx, records)
+}
+
+// WebhookOption configures a WebhookExporter.
+type WebhookOption func(*webhookExporter)
+
+// WithWebhookSecret signs requests with an HMAC-SHA256 of the body, sent in
+// the X-Prefab-Signature header, so receivers can verify their origin.
+func WithWebhookSecret(secret string) WebhookOption {
+	return func(e *webhookExporter) {
+		e.secret = []byte(secret)
+	}
+}
+
+// WithWebhookClient overrides the HTTP client used to deliver webhooks.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(e *webhookExporter) {
+		e.client = client
+	}
+}
+
+// WebhookExporter POSTs usage records as JSON to a URL, in the form
+// `{"records": [...]}`, for example to a service which reports usage to
+// Stripe.
+func WebhookExporter(url string, opts ...WebhookOption) Exporter {
+	e := &webhookExporter{url: url, client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+type webhookExporter struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// webhookPayload is the JSON body sent by the webhook exporter.
+type webhookPayload struct {
+	Records []UsageRecord `json:"records"`
+}
+
+func (e *webhookExporter) Export(ctx context.Context, records []UsageRecord) error {
+	body, err := json.Marshal(webhookPayload{Records: records})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(e.secret) > 0 {
+		mac := hmac.New(sha256.New, e.secret)
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.WrapPrefix(err, "metering: webhook failed", 0)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("metering: webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
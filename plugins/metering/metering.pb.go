@@ -0,0 +1,357 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/metering/metering.proto
+
+package metering
+
+import (
+	_ "github.com/dpup/prefab/plugins/authz"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ListUsageRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	PageSize  int32                  `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken string                 `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Only return usage of this meter, e.g. "api_calls".
+	Meter string `protobuf:"bytes,3,opt,name=meter,proto3" json:"meter,omitempty"`
+	// Only return usage by this identity subject or tenant.
+	Subject string `protobuf:"bytes,4,opt,name=subject,proto3" json:"subject,omitempty"`
+	// Only return periods starting at or after this time (Unix timestamp in
+	// seconds).
+	Since int64 `protobuf:"varint,5,opt,name=since,proto3" json:"since,omitempty"`
+	// Only return periods starting before this time (Unix timestamp in
+	// seconds).
+	Until         int64 `protobuf:"varint,6,opt,name=until,proto3" json:"until,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsageRequest) Reset() {
+	*x = ListUsageRequest{}
+	mi := &file_plugins_metering_metering_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsageRequest) ProtoMessage() {}
+
+func (x *ListUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_metering_metering_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsageRequest.ProtoReflect.Descriptor instead.
+func (*ListUsageRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_metering_metering_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ListUsageRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListUsageRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListUsageRequest) GetMeter() string {
+	if x != nil {
+		return x.Meter
+	}
+	return ""
+}
+
+func (x *ListUsageRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *ListUsageRequest) GetSince() int64 {
+	if x != nil {
+		return x.Since
+	}
+	return 0
+}
+
+func (x *ListUsageRequest) GetUntil() int64 {
+	if x != nil {
+		return x.Until
+	}
+	return 0
+}
+
+type ListUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Records       []*MeteredUsage        `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsageResponse) Reset() {
+	*x = ListUsageResponse{}
+	mi := &file_plugins_metering_metering_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsageResponse) ProtoMessage() {}
+
+func (x *ListUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_metering_metering_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsageResponse.ProtoReflect.Descriptor instead.
+func (*ListUsageResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_metering_metering_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListUsageResponse) GetRecords() []*MeteredUsage {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+func (x *ListUsageResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type MeteredUsage struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Meter      string                 `protobuf:"bytes,2,opt,name=meter,proto3" json:"meter,omitempty"`
+	Subject    string                 `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Dimensions map[string]string      `protobuf:"bytes,4,rep,name=dimensions,proto3" json:"dimensions,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// The aggregation period (Unix timestamps in seconds).
+	PeriodStart int64   `protobuf:"varint,5,opt,name=period_start,json=periodStart,proto3" json:"period_start,omitempty"`
+	PeriodEnd   int64   `protobuf:"varint,6,opt,name=period_end,json=periodEnd,proto3" json:"period_end,omitempty"`
+	Quantity    float64 `protobuf:"fixed64,7,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	// Whether the record has been sent to the configured exporters.
+	Exported      bool `protobuf:"varint,8,opt,name=exported,proto3" json:"exported,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MeteredUsage) Reset() {
+	*x = MeteredUsage{}
+	mi := &file_plugins_metering_metering_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MeteredUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeteredUsage) ProtoMessage() {}
+
+func (x *MeteredUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_metering_metering_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeteredUsage.ProtoReflect.Descriptor instead.
+func (*MeteredUsage) Descriptor() ([]byte, []int) {
+	return file_plugins_metering_metering_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MeteredUsage) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MeteredUsage) GetMeter() string {
+	if x != nil {
+		return x.Meter
+	}
+	return ""
+}
+
+func (x *MeteredUsage) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *MeteredUsage) GetDimensions() map[string]string {
+	if x != nil {
+		return x.Dimensions
+	}
+	return nil
+}
+
+func (x *MeteredUsage) GetPeriodStart() int64 {
+	if x != nil {
+		return x.PeriodStart
+	}
+	return 0
+}
+
+func (x *MeteredUsage) GetPeriodEnd() int64 {
+	if x != nil {
+		return x.PeriodEnd
+	}
+	return 0
+}
+
+func (x *MeteredUsage) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *MeteredUsage) GetExported() bool {
+	if x != nil {
+		return x.Exported
+	}
+	return false
+}
+
+var File_plugins_metering_metering_proto protoreflect.FileDescriptor
+
+const file_plugins_metering_metering_proto_rawDesc = "" +
+	"\n" +
+	"\x1fplugins/metering/metering.proto\x12\x0fprefab.metering\x1a\x1cgoogle/api/annotations.proto\x1a\x19plugins/authz/authz.proto\"\xaa\x01\n" +
+	"\x10ListUsageRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x14\n" +
+	"\x05meter\x18\x03 \x01(\tR\x05meter\x12\x18\n" +
+	"\asubject\x18\x04 \x01(\tR\asubject\x12\x14\n" +
+	"\x05since\x18\x05 \x01(\x03R\x05since\x12\x14\n" +
+	"\x05until\x18\x06 \x01(\x03R\x05until\"t\n" +
+	"\x11ListUsageResponse\x127\n" +
+	"\arecords\x18\x01 \x03(\v2\x1d.prefab.metering.MeteredUsageR\arecords\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xd6\x02\n" +
+	"\fMeteredUsage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05meter\x18\x02 \x01(\tR\x05meter\x12\x18\n" +
+	"\asubject\x18\x03 \x01(\tR\asubject\x12M\n" +
+	"\n" +
+	"dimensions\x18\x04 \x03(\v2-.prefab.metering.MeteredUsage.DimensionsEntryR\n" +
+	"dimensions\x12!\n" +
+	"\fperiod_start\x18\x05 \x01(\x03R\vperiodStart\x12\x1d\n" +
+	"\n" +
+	"period_end\x18\x06 \x01(\x03R\tperiodEnd\x12\x1a\n" +
+	"\bquantity\x18\a \x01(\x01R\bquantity\x12\x1a\n" +
+	"\bexported\x18\b \x01(\bR\bexported\x1a=\n" +
+	"\x0fDimensionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x012\xae\x01\n" +
+	"\x0fMeteringService\x12\x9a\x01\n" +
+	"\tListUsage\x12!.prefab.metering.ListUsageRequest\x1a\".prefab.metering.ListUsageResponse\"Fڵ\x18\rmetering.read\xe2\xb5\x18\bmetering\xea\xb5\x18\x04deny\x82\xd3\xe4\x93\x02\x1b\x12\x19/api/admin/metering/usageB)Z'github.com/dpup/prefab/plugins/meteringb\x06proto3"
+
+var (
+	file_plugins_metering_metering_proto_rawDescOnce sync.Once
+	file_plugins_metering_metering_proto_rawDescData []byte
+)
+
+func file_plugins_metering_metering_proto_rawDescGZIP() []byte {
+	file_plugins_metering_metering_proto_rawDescOnce.Do(func() {
+		file_plugins_metering_metering_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_metering_metering_proto_rawDesc), len(file_plugins_metering_metering_proto_rawDesc)))
+	})
+	return file_plugins_metering_metering_proto_rawDescData
+}
+
+var file_plugins_metering_metering_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_plugins_metering_metering_proto_goTypes = []any{
+	(*ListUsageRequest)(nil),  // 0: prefab.metering.ListUsageRequest
+	(*ListUsageResponse)(nil), // 1: prefab.metering.ListUsageResponse
+	(*MeteredUsage)(nil),      // 2: prefab.metering.MeteredUsage
+	nil,                       // 3: prefab.metering.MeteredUsage.DimensionsEntry
+}
+var file_plugins_metering_metering_proto_depIdxs = []int32{
+	2, // 0: prefab.metering.ListUsageResponse.records:type_name -> prefab.metering.MeteredUsage
+	3, // 1: prefab.metering.MeteredUsage.dimensions:type_name -> prefab.metering.MeteredUsage.DimensionsEntry
+	0, // 2: prefab.metering.MeteringService.ListUsage:input_type -> prefab.metering.ListUsageRequest
+	1, // 3: prefab.metering.MeteringService.ListUsage:output_type -> prefab.metering.ListUsageResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_plugins_metering_metering_proto_init() }
+func file_plugins_metering_metering_proto_init() {
+	if File_plugins_metering_metering_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_metering_metering_proto_rawDesc), len(file_plugins_metering_metering_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_metering_metering_proto_goTypes,
+		DependencyIndexes: file_plugins_metering_metering_proto_depIdxs,
+		MessageInfos:      file_plugins_metering_metering_proto_msgTypes,
+	}.Build()
+	File_plugins_metering_metering_proto = out.File
+	file_plugins_metering_metering_proto_goTypes = nil
+	file_plugins_metering_metering_proto_depIdxs = nil
+}
@@ -0,0 +1,129 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: plugins/metering/metering.proto
+
+package metering
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MeteringService_ListUsage_FullMethodName = "/prefab.metering.MeteringService/ListUsage"
+)
+
+// MeteringServiceClient is the client API for MeteringService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// MeteringService exports aggregated usage, for example to reconcile billing.
+// Access is denied unless an authz policy grants the `metering.read` action.
+type MeteringServiceClient interface {
+	// ListUsage returns usage records matching the request, oldest first.
+	ListUsage(ctx context.Context, in *ListUsageRequest, opts ...grpc.CallOption) (*ListUsageResponse, error)
+}
+
+type meteringServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMeteringServiceClient(cc grpc.ClientConnInterface) MeteringServiceClient {
+	return &meteringServiceClient{cc}
+}
+
+func (c *meteringServiceClient) ListUsage(ctx context.Context, in *ListUsageRequest, opts ...grpc.CallOption) (*ListUsageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListUsageResponse)
+	err := c.cc.Invoke(ctx, MeteringService_ListUsage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MeteringServiceServer is the server API for MeteringService service.
+// All implementations must embed UnimplementedMeteringServiceServer
+// for forward compatibility.
+//
+// MeteringService exports aggregated usage, for example to reconcile billing.
+// Access is denied unless an authz policy grants the `metering.read` action.
+type MeteringServiceServer interface {
+	// ListUsage returns usage records matching the request, oldest first.
+	ListUsage(context.Context, *ListUsageRequest) (*ListUsageResponse, error)
+	mustEmbedUnimplementedMeteringServiceServer()
+}
+
+// UnimplementedMeteringServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMeteringServiceServer struct{}
+
+func (UnimplementedMeteringServiceServer) ListUsage(context.Context, *ListUsageRequest) (*ListUsageResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsage not implemented")
+}
+func (UnimplementedMeteringServiceServer) mustEmbedUnimplementedMeteringServiceServer() {}
+func (UnimplementedMeteringServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeMeteringServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MeteringServiceServer will
+// result in compilation errors.
+type UnsafeMeteringServiceServer interface {
+	mustEmbedUnimplementedMeteringServiceServer()
+}
+
+func RegisterMeteringServiceServer(s grpc.ServiceRegistrar, srv MeteringServiceServer) {
+	// If the following call pancis, it indicates UnimplementedMeteringServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MeteringService_ServiceDesc, srv)
+}
+
+func _MeteringService_ListUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeteringServiceServer).ListUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeteringService_ListUsage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeteringServiceServer).ListUsage(ctx, req.(*ListUsageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MeteringService_ServiceDesc is the grpc.ServiceDesc for MeteringService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MeteringService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "prefab.metering.MeteringService",
+	HandlerType: (*MeteringServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListUsage",
+			Handler:    _MeteringService_ListUsage_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins/metering/metering.proto",
+}
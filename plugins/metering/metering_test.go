@@ -0,0 +1,195 @@
+package metering
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	admin = auth.Identity{Provider: "test", Subject: "admin"}
+	user  = auth.Identity{Provider: "test", Subject: "alice"}
+)
+
+func setup(t *testing.T, opts ...MeteringOption) (*prefabtest.Server, *MeteringPlugin) {
+	opts = append([]MeteringOption{WithFlushInterval(0)}, opts...)
+	p := Plugin(opts...)
+	az := authz.Plugin(
+		authz.WithRoleDescriberFn(ObjectKey, func(_ context.Context, sub auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Subject == admin.Subject {
+				return []authz.Role{"admin"}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(ReadAction)),
+	)
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(az, p))
+	return s, p
+}
+
+func TestRecordAndFlush(t *testing.T) {
+	_, p := setup(t)
+	ctx := auth.WithIdentityForTest(prefabtest.Context(t), user)
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, p.Record(ctx,
+		Event{Meter: "storage_bytes", Quantity: 100},
+		Event{Meter: "storage_bytes", Quantity: 50},
+		Event{Meter: "storage_bytes", Quantity: 10, Dimensions: map[string]string{"region": "eu"}},
+		Event{Meter: "stream_minutes", Subject: "acme", Quantity: 3, Time: now.Add(-time.Hour)},
+	))
+	require.NoError(t, p.Flush(ctx))
+	require.NoError(t, p.Record(ctx, Event{Meter: "storage_bytes", Quantity: 25}))
+	require.NoError(t, p.Flush(ctx))
+
+	records, err := p.Query(ctx, Filter{Meter: "storage_bytes"})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for _, r := range records {
+		assert.Equal(t, "alice", r.Subject)
+		assert.Equal(t, time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), r.PeriodStart)
+		assert.Equal(t, time.Date(2025, 1, 1, 11, 0, 0, 0, time.UTC), r.PeriodEnd)
+		if r.Dimensions["region"] == "eu" {
+			assert.InDelta(t, 10, r.Quantity, 0.001)
+		} else {
+			assert.InDelta(t, 175, r.Quantity, 0.001)
+		}
+	}
+
+	records, err = p.Query(ctx, Filter{Subject: "acme"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), records[0].PeriodStart)
+}
+
+func TestRecord_Validation(t *testing.T) {
+	_, p := setup(t)
+	ctx := prefabtest.Context(t)
+
+	require.ErrorIs(t, p.Record(ctx, Event{Quantity: 1}), ErrMissingMeter)
+	require.ErrorIs(t, p.Record(ctx, Event{Meter: "storage_bytes", Quantity: 1}), ErrMissingSubject)
+}
+
+func TestExport(t *testing.T) {
+	var received []webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write(body)
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get(WebhookSignatureHeader))
+		var payload webhookPayload
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		received = append(received, payload)
+	}))
+	defer srv.Close()
+
+	_, p := setup(t, WithExporter(WebhookExporter(srv.URL, WithWebhookSecret("secret"))))
+	ctx := prefabtest.Context(t)
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, p.Record(ctx, Event{Meter: "api_calls", Subject: "acme", Quantity: 5}))
+	require.NoError(t, p.Flush(ctx))
+
+	// The period hasn't ended.
+	n, err := p.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	now = now.Add(time.Hour)
+	n, err = p.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, received, 1)
+	require.Len(t, received[0].Records, 1)
+	assert.InDelta(t, 5, received[0].Records[0].Quantity, 0.001)
+
+	n, err = p.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n, "exported records aren't sent again")
+
+	// Late usage is sent again with the new total.
+	require.NoError(t, p.Record(ctx, Event{Meter: "api_calls", Subject: "acme", Quantity: 1, Time: now.Add(-time.Hour)}))
+	require.NoError(t, p.Flush(ctx))
+	n, err = p.Export(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.InDelta(t, 6, received[1].Records[0].Quantity, 0.001)
+	assert.Equal(t, received[0].Records[0].ID, received[1].Records[0].ID)
+}
+
+func TestExport_Failure(t *testing.T) {
+	_, p := setup(t, WithExporter(ExporterFunc(func(context.Context, []UsageRecord) error {
+		return assert.AnError
+	})))
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.Record(ctx, Event{Meter: "api_calls", Subject: "acme", Quantity: 1, Time: time.Now().Add(-2 * time.Hour)}))
+	require.NoError(t, p.Flush(ctx))
+
+	_, err := p.Export(ctx)
+	require.ErrorIs(t, err, assert.AnError)
+	records, err := p.Query(ctx, Filter{})
+	require.NoError(t, err)
+	assert.False(t, records[0].Exported)
+}
+
+func TestOnEvent(t *testing.T) {
+	type streamEnded struct {
+		Owner   string
+		Minutes float64
+	}
+	s, p := setup(t, OnEvent("stream.ended", func(_ context.Context, data any) ([]Event, error) {
+		e := data.(streamEnded)
+		return []Event{{Meter: "stream_minutes", Subject: e.Owner, Quantity: e.Minutes}}, nil
+	}))
+	ctx := prefabtest.Context(t)
+
+	s.Events().Publish("stream.ended", streamEnded{Owner: "acme", Minutes: 12})
+	require.NoError(t, s.Events().Wait(ctx))
+	require.NoError(t, p.Flush(ctx))
+
+	records, err := p.Query(ctx, Filter{Meter: "stream_minutes"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.InDelta(t, 12, records[0].Quantity, 0.001)
+}
+
+func TestListUsage(t *testing.T) {
+	s, p := setup(t)
+	ctx := prefabtest.Context(t)
+	client := NewMeteringServiceClient(s.Conn())
+
+	_, err := client.ListUsage(s.AuthContext(t.Context(), user), &ListUsageRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := client.ListUsage(s.AuthContext(t.Context(), admin), &ListUsageRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, resp.GetRecords())
+
+	// The successful call above was metered.
+	require.NoError(t, p.Flush(ctx))
+	resp, err = client.ListUsage(s.AuthContext(t.Context(), admin), &ListUsageRequest{Meter: APICallsMeter})
+	require.NoError(t, err)
+	require.Len(t, resp.GetRecords(), 1)
+	r := resp.GetRecords()[0]
+	assert.Equal(t, "admin", r.GetSubject())
+	assert.Equal(t, MeteringService_ListUsage_FullMethodName, r.GetDimensions()["method"])
+	assert.InDelta(t, 1, r.GetQuantity(), 0.001)
+}
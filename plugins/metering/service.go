@@ -0,0 +1,49 @@
+package metering
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/pagination"
+)
+
+type impl struct {
+	UnimplementedMeteringServiceServer
+	p *MeteringPlugin
+}
+
+func (s *impl) ListUsage(ctx context.Context, in *ListUsageRequest) (*ListUsageResponse, error) {
+	f := Filter{Meter: in.GetMeter(), Subject: in.GetSubject()}
+	if in.GetSince() > 0 {
+		f.Since = time.Unix(in.GetSince(), 0)
+	}
+	if in.GetUntil() > 0 {
+		f.Until = time.Unix(in.GetUntil(), 0)
+	}
+	records, err := s.p.Query(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	page, next, err := pagination.Slice(ctx, in, records, UsageRecord.PK)
+	if err != nil {
+		return nil, err
+	}
+	out := &ListUsageResponse{NextPageToken: next}
+	for _, r := range page {
+		out.Records = append(out.Records, r.proto())
+	}
+	return out, nil
+}
+
+func (r UsageRecord) proto() *MeteredUsage {
+	return &MeteredUsage{
+		Id:          r.ID,
+		Meter:       r.Meter,
+		Subject:     r.Subject,
+		Dimensions:  r.Dimensions,
+		PeriodStart: r.PeriodStart.Unix(),
+		PeriodEnd:   r.PeriodEnd.Unix(),
+		Quantity:    r.Quantity,
+		Exported:    r.Exported,
+	}
+}
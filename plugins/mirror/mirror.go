@@ -0,0 +1,304 @@
+// Package mirror sends a copy of selected RPCs to a shadow target, such as a
+// new version of a service, so it can be tested with production traffic
+// without affecting clients.
+//
+// Mirrored requests are sent asynchronously, after credentials and fields
+// marked with the `prefab.redact` option are removed, and the shadow's
+// responses are discarded. Requests are dropped, rather than queued, when the
+// shadow falls behind.
+//
+// Example:
+//
+//	prefab.WithPlugin(mirror.Plugin(
+//	    mirror.WithTarget("notes-canary.internal:443"),
+//	    mirror.WithPercent(10),
+//	    mirror.WithMethods("/notes.NoteService/*"),
+//	))
+package mirror
+
+import (
+	"context"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/redact"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// PluginName is the name of the mirror plugin.
+const PluginName = "mirror"
+
+// MetadataKey is attached to mirrored requests, so the shadow can tell them
+// apart from real traffic, and so they aren't mirrored again.
+const MetadataKey = "prefab-mirror"
+
+// Fragments which mark a metadata key as sensitive. Matching keys aren't sent
+// to the shadow.
+var sensitiveMetadata = []string{
+	"authorization", "cookie", "csrf", "token", "secret", "password", "api-key", "apikey",
+}
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "mirror.target",
+			Description: "Address of the GRPC endpoint to mirror requests to, mirroring is disabled if unset",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "mirror.percent",
+			Description: "Percentage of selected requests to mirror, from 0 to 100",
+			Type:        "float",
+			Default:     "100",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "mirror.methods",
+			Description: "Patterns of methods to mirror, e.g. /notes.NoteService/*, all methods if unset",
+			Type:        "[]string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "mirror.timeout",
+			Description: "Deadline for mirrored requests",
+			Type:        "duration",
+			Default:     "5s",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "mirror.maxInFlight",
+			Description: "Maximum number of concurrent mirrored requests, others are dropped",
+			Type:        "int",
+			Default:     "100",
+		},
+	)
+}
+
+// MirrorOption customizes the mirror plugin.
+type MirrorOption func(*MirrorPlugin)
+
+// WithTarget sets the address of the shadow GRPC endpoint. The connection is
+// made with prefab.Dial, so `client.*` config applies, but credentials aren't
+// forwarded and calls aren't retried.
+//
+// Config key: `mirror.target`.
+func WithTarget(target string, opts ...prefab.DialOption) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.target = target
+		p.dialOpts = append(p.dialOpts, opts...)
+	}
+}
+
+// WithConn sends mirrored requests over an existing connection, instead of
+// dialing a target.
+func WithConn(conn grpc.ClientConnInterface) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.conn = conn
+	}
+}
+
+// WithPercent sets the percentage of selected requests which are mirrored, from
+// 0 to 100.
+//
+// Config key: `mirror.percent`.
+func WithPercent(percent float64) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.percent = percent
+	}
+}
+
+// WithMethods limits mirroring to methods matching one of the patterns, using
+// the syntax of prefab.InterceptorMethods. By default all methods are mirrored.
+//
+// Config key: `mirror.methods`.
+func WithMethods(patterns ...string) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.methods = append(p.methods, patterns...)
+	}
+}
+
+// WithTimeout sets the deadline for mirrored requests.
+//
+// Config key: `mirror.timeout`.
+func WithTimeout(d time.Duration) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.timeout = d
+	}
+}
+
+// WithMaxInFlight limits how many mirrored requests may be outstanding.
+// Requests selected while the limit is reached are dropped.
+//
+// Config key: `mirror.maxInFlight`.
+func WithMaxInFlight(n int) MirrorOption {
+	return func(p *MirrorPlugin) {
+		p.maxInFlight = n
+	}
+}
+
+// Plugin returns a new MirrorPlugin.
+func Plugin(opts ...MirrorOption) *MirrorPlugin {
+	config.EnsureDefaultsLoaded(prefab.Config)
+	p := &MirrorPlugin{
+		target:      prefab.ConfigString("mirror.target"),
+		percent:     prefab.ConfigFloat64("mirror.percent"),
+		methods:     prefab.ConfigStrings("mirror.methods"),
+		timeout:     prefab.ConfigDuration("mirror.timeout"),
+		maxInFlight: prefab.ConfigInt("mirror.maxInFlight"),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.inFlight = make(chan struct{}, max(p.maxInFlight, 1))
+	return p
+}
+
+// MirrorPlugin mirrors requests to a shadow target.
+type MirrorPlugin struct {
+	target      string
+	dialOpts    []prefab.DialOption
+	percent     float64
+	methods     []string
+	timeout     time.Duration
+	maxInFlight int
+
+	conn     grpc.ClientConnInterface
+	closer   *grpc.ClientConn // Set if the plugin dialed the connection.
+	inFlight chan struct{}
+
+	// Called once a mirrored request completes, for tests.
+	done func(method string, err error)
+}
+
+// From prefab.Plugin.
+func (p *MirrorPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionProvider.
+func (p *MirrorPlugin) ServerOptions() []prefab.ServerOption {
+	opts := []prefab.InterceptorOption{prefab.InterceptorName("mirror")}
+	if len(p.methods) > 0 {
+		opts = append(opts, prefab.InterceptorMethods(p.methods...))
+	}
+	return []prefab.ServerOption{
+		prefab.WithGRPCInterceptor(p.interceptor, opts...),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *MirrorPlugin) Init(ctx context.Context, _ *prefab.Registry) error {
+	if p.percent < 0 || p.percent > 100 {
+		return errors.Errorf("mirror: percent must be between 0 and 100, got %v", p.percent)
+	}
+	if p.conn != nil {
+		return nil
+	}
+	if p.target == "" {
+		logging.Info(ctx, "mirror: no target configured, requests will not be mirrored")
+		return nil
+	}
+	opts := append([]prefab.DialOption{
+		prefab.WithoutCredentialPropagation(),
+		prefab.WithRetries(0, 0),
+	}, p.dialOpts...)
+	conn, err := prefab.Dial(ctx, p.target, opts...)
+	if err != nil {
+		return errors.WrapPrefix(err, "mirror: failed to create client connection", 0)
+	}
+	p.conn = conn
+	p.closer = conn
+	logging.Infow(ctx, "mirror: mirroring requests", "target", p.target, "percent", p.percent)
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *MirrorPlugin) Shutdown(_ context.Context) error {
+	if p.closer != nil {
+		return p.closer.Close()
+	}
+	return nil
+}
+
+func (p *MirrorPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if msg, ok := req.(proto.Message); ok && !prefab.IsStreaming(ctx) && p.sample(ctx) {
+		p.mirror(ctx, info.FullMethod, msg)
+	}
+	return handler(ctx, req)
+}
+
+// sample reports whether the request should be mirrored.
+func (p *MirrorPlugin) sample(ctx context.Context) bool {
+	if p.conn == nil || p.percent <= 0 {
+		return false
+	}
+	if md, _ := metadata.FromIncomingContext(ctx); len(md.Get(MetadataKey)) > 0 {
+		return false
+	}
+	return p.percent >= 100 || rand.Float64()*100 < p.percent //nolint:gosec // Sampling doesn't need a secure source.
+}
+
+// mirror sends a redacted copy of the request to the shadow in the background.
+// The copy is taken before the handler runs, so it can't observe changes the
+// handler makes to the request.
+func (p *MirrorPlugin) mirror(ctx context.Context, method string, msg proto.Message) {
+	select {
+	case p.inFlight <- struct{}{}:
+	default:
+		logging.Debugw(ctx, "mirror: too many requests in flight, dropping", "method", method)
+		return
+	}
+
+	req := redact.Message(msg)
+	if req == msg {
+		req = proto.Clone(msg)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	out := sanitizeMetadata(md)
+	out.Set(MetadataKey, "1")
+
+	mctx, cancel := serverutil.DetachContextWithTimeout(ctx, p.timeout)
+	mctx = metadata.NewOutgoingContext(mctx, out)
+	go func() {
+		defer func() { <-p.inFlight }()
+		defer cancel()
+		// The response is decoded into an empty message, which accepts any
+		// payload, since it is discarded.
+		err := p.conn.Invoke(mctx, method, req, &emptypb.Empty{})
+		if err != nil {
+			logging.Debugw(mctx, "mirror: shadow request failed", "method", method, "error", err)
+		}
+		if p.done != nil {
+			p.done(method, err)
+		}
+	}()
+}
+
+// sanitizeMetadata returns a copy of md with sensitive keys, and keys reserved
+// by GRPC, removed.
+func sanitizeMetadata(md metadata.MD) metadata.MD {
+	out := metadata.MD{}
+	for k, v := range md {
+		if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || isSensitive(k) {
+			continue
+		}
+		out[k] = append([]string{}, v...)
+	}
+	return out
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, f := range sensitiveMetadata {
+		if strings.Contains(key, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package mirror
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const loginMethod = "/prefab.auth.AuthService/Login"
+
+// shadowCall is a request received by the shadow server.
+type shadowCall struct {
+	method string
+	md     metadata.MD
+	req    *auth.LoginRequest
+}
+
+// startShadow runs a server which records every request it receives, and
+// returns the plugin options for mirroring to it.
+func startShadow(t *testing.T, fail bool) (chan shadowCall, []MirrorOption) {
+	calls := make(chan shadowCall, 10)
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(grpc.UnknownServiceHandler(func(_ any, stream grpc.ServerStream) error {
+		method, _ := grpc.MethodFromServerStream(stream)
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		req := &auth.LoginRequest{}
+		if err := stream.RecvMsg(req); err != nil {
+			return err
+		}
+		calls <- shadowCall{method: method, md: md, req: req}
+		if fail {
+			return status.Error(codes.Internal, "shadow is broken")
+		}
+		return stream.SendMsg(&auth.LoginResponse{Issued: true})
+	}))
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	return calls, []MirrorOption{
+		WithTarget("passthrough:///shadow",
+			prefab.WithInsecure(),
+			prefab.WithDialOptions(grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+				return lis.DialContext(ctx)
+			})),
+		),
+	}
+}
+
+func newPlugin(t *testing.T, opts ...MirrorOption) *MirrorPlugin {
+	p := Plugin(opts...)
+	require.NoError(t, p.Init(prefabtest.Context(t), &prefab.Registry{}))
+	t.Cleanup(func() { _ = p.Shutdown(prefabtest.Context(t)) })
+	return p
+}
+
+func receive(t *testing.T, calls chan shadowCall) shadowCall {
+	select {
+	case c := <-calls:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for mirrored request")
+		return shadowCall{}
+	}
+}
+
+func login(ctx context.Context, p *MirrorPlugin, req *auth.LoginRequest) (any, error) {
+	return p.interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req any) (any, error) {
+		return &auth.LoginResponse{Issued: true, Token: "primary"}, nil
+	})
+}
+
+func TestInterceptor_Mirrors(t *testing.T) {
+	calls, opts := startShadow(t, false)
+	p := newPlugin(t, opts...)
+	done := make(chan error, 1)
+	p.done = func(_ string, err error) { done <- err }
+
+	ctx := metadata.NewIncomingContext(prefabtest.Context(t), metadata.Pairs(
+		"authorization", "Bearer xyz",
+		"grpcgateway-cookie", "pf-id=xyz",
+		"x-request-id", "req-1",
+		"grpcgateway-user-agent", "curl",
+	))
+	req := &auth.LoginRequest{Provider: "password", Creds: map[string]string{"password": "hunter2"}}
+	resp, err := login(ctx, p, req)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.(*auth.LoginResponse).GetToken())
+
+	c := receive(t, calls)
+	assert.Equal(t, loginMethod, c.method)
+	assert.Equal(t, "password", c.req.GetProvider())
+	assert.Empty(t, c.req.GetCreds(), "redacted fields should be removed")
+	assert.Equal(t, "hunter2", req.GetCreds()["password"], "original should not be modified")
+
+	assert.Empty(t, c.md.Get("authorization"))
+	assert.Empty(t, c.md.Get("grpcgateway-cookie"))
+	assert.Equal(t, []string{"req-1"}, c.md.Get("x-request-id"))
+	assert.Equal(t, []string{"curl"}, c.md.Get("grpcgateway-user-agent"))
+	assert.Equal(t, []string{"1"}, c.md.Get(MetadataKey))
+	require.NoError(t, <-done, "the shadow's response should be discarded")
+}
+
+func TestInterceptor_IgnoresShadowErrors(t *testing.T) {
+	calls, opts := startShadow(t, true)
+	p := newPlugin(t, opts...)
+	done := make(chan error, 1)
+	p.done = func(_ string, err error) { done <- err }
+
+	resp, err := login(prefabtest.Context(t), p, &auth.LoginRequest{Provider: "password"})
+	require.NoError(t, err)
+	assert.True(t, resp.(*auth.LoginResponse).GetIssued())
+
+	receive(t, calls)
+	assert.Equal(t, codes.Internal, status.Code(<-done))
+}
+
+func TestInterceptor_DetachesFromRequest(t *testing.T) {
+	calls, opts := startShadow(t, false)
+	p := newPlugin(t, opts...)
+
+	ctx, cancel := context.WithCancel(prefabtest.Context(t))
+	_, err := login(ctx, p, &auth.LoginRequest{Provider: "password"})
+	require.NoError(t, err)
+	cancel()
+
+	c := receive(t, calls)
+	assert.Equal(t, loginMethod, c.method)
+}
+
+func TestInterceptor_SkipsMirroredRequests(t *testing.T) {
+	_, opts := startShadow(t, false)
+	p := newPlugin(t, opts...)
+
+	ctx := metadata.NewIncomingContext(prefabtest.Context(t), metadata.Pairs(MetadataKey, "1"))
+	assert.False(t, p.sample(ctx))
+	assert.True(t, p.sample(prefabtest.Context(t)))
+}
+
+func TestInterceptor_Percent(t *testing.T) {
+	_, opts := startShadow(t, false)
+
+	p := newPlugin(t, append(opts, WithPercent(0))...)
+	for range 100 {
+		assert.False(t, p.sample(prefabtest.Context(t)))
+	}
+
+	p = newPlugin(t, append(opts, WithPercent(50))...)
+	sampled := 0
+	for range 1000 {
+		if p.sample(prefabtest.Context(t)) {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 500, sampled, 100)
+}
+
+func TestInterceptor_DropsWhenBusy(t *testing.T) {
+	calls, opts := startShadow(t, false)
+	p := newPlugin(t, append(opts, WithMaxInFlight(1))...)
+	p.inFlight <- struct{}{}
+
+	_, err := login(prefabtest.Context(t), p, &auth.LoginRequest{Provider: "password"})
+	require.NoError(t, err)
+	select {
+	case <-calls:
+		t.Fatal("request should have been dropped")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	<-p.inFlight
+	_, err = login(prefabtest.Context(t), p, &auth.LoginRequest{Provider: "password"})
+	require.NoError(t, err)
+	receive(t, calls)
+}
+
+func TestInterceptor_NoTarget(t *testing.T) {
+	p := newPlugin(t)
+	assert.False(t, p.sample(prefabtest.Context(t)))
+	_, err := login(prefabtest.Context(t), p, &auth.LoginRequest{Provider: "password"})
+	require.NoError(t, err)
+}
+
+func TestInit_InvalidPercent(t *testing.T) {
+	p := Plugin(WithPercent(150))
+	require.Error(t, p.Init(prefabtest.Context(t), &prefab.Registry{}))
+}
+
+func TestSanitizeMetadata(t *testing.T) {
+	md := sanitizeMetadata(metadata.Pairs(
+		":authority", "example.com",
+		"grpc-timeout", "1S",
+		"x-api-key", "secret",
+		"pf-header-x-csrf-protection", "1",
+		"x-request-id", "req-1",
+	))
+	assert.Equal(t, metadata.MD{"x-request-id": {"req-1"}}, md)
+}
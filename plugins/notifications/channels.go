@@ -0,0 +1,179 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/email"
+	"gopkg.in/gomail.v2"
+)
+
+// Channel names used in preferences.
+const (
+	EmailChannelName   = "email"
+	WebhookChannelName = "webhook"
+	PushChannelName    = "push"
+)
+
+// WebhookSignatureHeader carries the hex encoded HMAC-SHA256 of the request
+// body, when a webhook secret is configured.
+const WebhookSignatureHeader = "X-Prefab-Signature"
+
+// Channel delivers messages to a user.
+type Channel interface {
+	// Name identifies the channel in preferences.
+	Name() string
+
+	// Deliver sends a message to a user. Digests are delivered as a single
+	// message, with the batched messages in Items.
+	Deliver(ctx context.Context, userID string, msg Message) error
+}
+
+// InitializableChannel is implemented by channels which need access to other
+// plugins. Init is called when the notifications plugin is initialized.
+type InitializableChannel interface {
+	Init(ctx context.Context, r *prefab.Registry) error
+}
+
+// AddressResolver looks up the email address for a user.
+type AddressResolver func(ctx context.Context, userID string) (string, error)
+
+// EmailChannel delivers messages using the email plugin, which must be
+// registered. Message bodies are sent as HTML.
+func EmailChannel(resolve AddressResolver) Channel {
+	return &emailChannel{resolve: resolve}
+}
+
+type emailChannel struct {
+	resolve AddressResolver
+	emailer *email.EmailPlugin
+}
+
+func (c *emailChannel) Name() string {
+	return EmailChannelName
+}
+
+func (c *emailChannel) Init(_ context.Context, r *prefab.Registry) error {
+	ep, ok := r.Get(email.PluginName).(*email.EmailPlugin)
+	if !ok {
+		return errors.New("notifications: email channel requires the email plugin")
+	}
+	c.emailer = ep
+	return nil
+}
+
+func (c *emailChannel) Deliver(ctx context.Context, userID string, msg Message) error {
+	addr, err := c.resolve(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if addr == "" {
+		// The user has no address, so there's nothing to deliver.
+		return nil
+	}
+	m := gomail.NewMessage()
+	m.SetHeader("To", addr)
+	m.SetHeader("Subject", msg.Subject)
+	m.SetBody("text/html", msg.Body)
+	return c.emailer.Send(ctx, m)
+}
+
+// WebhookOption configures the webhook channel.
+type WebhookOption func(*webhookChannel)
+
+// WithWebhookSecret signs requests with an HMAC-SHA256 of the body, sent in
+// the X-Prefab-Signature header, so receivers can verify their origin.
+func WithWebhookSecret(secret string) WebhookOption {
+	return func(c *webhookChannel) {
+		c.secret = []byte(secret)
+	}
+}
+
+// WithWebhookClient overrides the HTTP client used to deliver webhooks.
+func WithWebhookClient(client *http.Client) WebhookOption {
+	return func(c *webhookChannel) {
+		c.client = client
+	}
+}
+
+// WebhookChannel delivers messages by POSTing them as JSON to a URL, for
+// example to relay notifications to chat or a separate delivery service.
+func WebhookChannel(url string, opts ...WebhookOption) Channel {
+	c := &webhookChannel{url: url, client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type webhookChannel struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// webhookPayload is the JSON body sent by the webhook channel.
+type webhookPayload struct {
+	UserID string `json:"userId"`
+	Message
+}
+
+func (c *webhookChannel) Name() string {
+	return WebhookChannelName
+}
+
+func (c *webhookChannel) Deliver(ctx context.Context, userID string, msg Message) error {
+	body, err := json.Marshal(webhookPayload{UserID: userID, Message: msg})
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(c.secret) > 0 {
+		mac := hmac.New(sha256.New, c.secret)
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.WrapPrefix(err, "notifications: webhook failed", 0)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("notifications: webhook returned %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// PushSender sends a push notification to a user's devices, for example via
+// FCM or APNs. Implementations are responsible for looking up device tokens.
+type PushSender interface {
+	Push(ctx context.Context, userID string, msg Message) error
+}
+
+// PushChannel delivers messages with a PushSender.
+func PushChannel(sender PushSender) Channel {
+	return &pushChannel{sender: sender}
+}
+
+type pushChannel struct {
+	sender PushSender
+}
+
+func (c *pushChannel) Name() string {
+	return PushChannelName
+}
+
+func (c *pushChannel) Deliver(ctx context.Context, userID string, msg Message) error {
+	return c.sender.Push(ctx, userID, msg)
+}
@@ -0,0 +1,105 @@
+package notifications
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/email"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/gomail.v2"
+)
+
+type fakeSender struct {
+	sent []*gomail.Message
+}
+
+func (s *fakeSender) DialAndSend(m *gomail.Message) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
+
+func TestEmailChannel(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	sender := &fakeSender{}
+	r := &prefab.Registry{}
+	r.Register(email.Plugin(
+		email.WithFrom("noreply@example.com"),
+		email.WithSMTP("localhost", 25, "user", "pass"),
+		email.WithSender(sender),
+	))
+
+	c := EmailChannel(func(_ context.Context, userID string) (string, error) {
+		if userID == "nobody" {
+			return "", nil
+		}
+		return userID + "@example.com", nil
+	})
+	require.NoError(t, c.(InitializableChannel).Init(ctx, r))
+
+	require.NoError(t, c.Deliver(ctx, "ann", Message{Subject: "Hi", Body: "<p>Hello</p>"}))
+	require.NoError(t, c.Deliver(ctx, "nobody", Message{Subject: "Hi"}))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, []string{"ann@example.com"}, sender.sent[0].GetHeader("To"))
+	assert.Equal(t, []string{"Hi"}, sender.sent[0].GetHeader("Subject"))
+
+	err := EmailChannel(nil).(InitializableChannel).Init(ctx, &prefab.Registry{})
+	assert.ErrorContains(t, err, "requires the email plugin")
+}
+
+func TestWebhookChannel(t *testing.T) {
+	var body []byte
+	var signature string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(WebhookSignatureHeader)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	c := WebhookChannel(srv.URL, WithWebhookSecret("s3cret"))
+	require.NoError(t, c.Deliver(t.Context(), "u1", Message{Topic: "t", Subject: "Hi", Data: map[string]any{"id": "1"}}))
+
+	var payload map[string]any
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, "u1", payload["userId"])
+	assert.Equal(t, "t", payload["topic"])
+	assert.Equal(t, "Hi", payload["subject"])
+	assert.Equal(t, map[string]any{"id": "1"}, payload["data"])
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signature)
+
+	status = http.StatusBadGateway
+	assert.ErrorContains(t, c.Deliver(t.Context(), "u1", Message{}), "webhook returned 502")
+}
+
+type fakePusher struct {
+	userID string
+	msg    Message
+}
+
+func (f *fakePusher) Push(_ context.Context, userID string, msg Message) error {
+	f.userID, f.msg = userID, msg
+	return nil
+}
+
+func TestPushChannel(t *testing.T) {
+	pusher := &fakePusher{}
+	c := PushChannel(pusher)
+	assert.Equal(t, PushChannelName, c.Name())
+	require.NoError(t, c.Deliver(t.Context(), "u1", Message{Subject: "Hi"}))
+	assert.Equal(t, "u1", pusher.userID)
+	assert.Equal(t, "Hi", pusher.msg.Subject)
+}
@@ -0,0 +1,171 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/google/uuid"
+)
+
+// DigestFormatter combines batched messages into a single digest message.
+// Messages are ordered oldest first.
+type DigestFormatter func(userID string, msgs []Message) Message
+
+// DefaultDigestFormatter lists each message's subject and body as HTML.
+func DefaultDigestFormatter(_ string, msgs []Message) Message {
+	var b strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&b, "<p><strong>%s</strong><br>%s</p>\n", html.EscapeString(m.Subject), m.Body)
+	}
+	subject := fmt.Sprintf("You have %d new notifications", len(msgs))
+	if len(msgs) == 1 {
+		subject = "You have 1 new notification"
+	}
+	return Message{
+		Topic:   "digest",
+		Subject: subject,
+		Body:    b.String(),
+	}
+}
+
+// pendingNotification is a message queued for a digest.
+type pendingNotification struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"userId"`
+	Channel   string    `json:"channel"`
+	Message   Message   `json:"message"`
+	DeliverAt time.Time `json:"deliverAt"`
+}
+
+// PK implements storage.Model.
+func (n pendingNotification) PK() string {
+	return n.ID
+}
+
+// Name implements storage.Namer.
+func (n pendingNotification) Name() string {
+	return "pending_notifications"
+}
+
+// enqueue stores a message for delivery in the next digest.
+func (p *NotificationsPlugin) enqueue(ctx context.Context, userID, channel string, f Frequency, msg Message) error {
+	return p.store.Create(ctx, pendingNotification{
+		ID:        uuid.NewString(),
+		UserID:    userID,
+		Channel:   channel,
+		Message:   msg,
+		DeliverAt: nextDigest(msg.CreatedAt, f),
+	})
+}
+
+// nextDigest returns when a message created at t should be delivered.
+func nextDigest(t time.Time, f Frequency) time.Time {
+	t = t.UTC()
+	if f == Daily {
+		return time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour).Add(time.Hour)
+}
+
+// FlushDigests delivers digests for messages which are due, combining each
+// user's messages for a channel into a single message. Messages which fail to
+// deliver are retried on the next flush.
+func (p *NotificationsPlugin) FlushDigests(ctx context.Context) error {
+	var pending []pendingNotification
+	if err := p.store.List(ctx, &pending, pendingNotification{}); err != nil {
+		return err
+	}
+
+	now := p.now()
+	type key struct{ userID, channel string }
+	groups := map[key][]pendingNotification{}
+	var order []key
+	for _, n := range pending {
+		if n.DeliverAt.After(now) {
+			continue
+		}
+		k := key{n.UserID, n.Channel}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], n)
+	}
+
+	var errs []error
+	for _, k := range order {
+		items := groups[k]
+		sort.SliceStable(items, func(i, j int) bool {
+			return items[i].Message.CreatedAt.Before(items[j].Message.CreatedAt)
+		})
+
+		c, ok := p.channels[k.channel]
+		if !ok {
+			logging.Warnw(ctx, "notifications: dropping digest for unknown channel", "channel", k.channel, "userId", k.userID)
+		} else {
+			msgs := make([]Message, len(items))
+			for i, n := range items {
+				msgs[i] = n.Message
+			}
+			digest := p.formatter(k.userID, msgs)
+			digest.Items = msgs
+			digest.CreatedAt = now
+			if err := c.Deliver(ctx, k.userID, digest); err != nil {
+				errs = append(errs, errors.WrapPrefix(err, "notifications: "+k.channel, 0))
+				continue
+			}
+		}
+		for _, n := range items {
+			if err := p.store.Delete(ctx, n); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// runDigests flushes digests on an interval until the plugin is shut down. If
+// a locker is provided, only the replica holding the digest lock delivers
+// digests, so users don't receive duplicates.
+func (p *NotificationsPlugin) runDigests(ctx context.Context, locker *lock.LockPlugin) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if locker == nil {
+		p.flushDigestsOnInterval(ctx)
+		return
+	}
+	_ = locker.RunWhenLeader(ctx, digestLock, func(ctx context.Context) error {
+		p.flushDigestsOnInterval(ctx)
+		return nil
+	})
+}
+
+func (p *NotificationsPlugin) flushDigestsOnInterval(ctx context.Context) {
+	ticker := time.NewTicker(p.digestInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.FlushDigests(ctx); err != nil {
+				logging.Errorw(ctx, "notifications: failed to deliver digests", "error", err)
+			}
+		}
+	}
+}
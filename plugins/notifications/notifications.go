@@ -0,0 +1,362 @@
+// Package notifications routes messages to users over email, webhooks, push,
+// or custom channels, according to per-user preferences.
+//
+// Preferences are stored via the storage plugin and are keyed by topic, such
+// as "comment.created". Each topic can be delivered on a set of channels,
+// either immediately or batched into hourly or daily digests. Content can be
+// provided directly or rendered with the templates plugin, and business events
+// published on the event bus can trigger notifications declaratively.
+//
+// Example:
+//
+//	prefab.WithPlugin(notifications.Plugin(
+//		notifications.WithChannel(notifications.EmailChannel(lookupEmail)),
+//		notifications.WithChannel(notifications.WebhookChannel(slackRelayURL)),
+//		notifications.OnEvent("comment.created", func(ctx context.Context, data any) ([]notifications.Notification, error) {
+//			c := data.(*Comment)
+//			return []notifications.Notification{{
+//				UserID:   c.PostAuthorID,
+//				Topic:    "comment.created",
+//				Template: "notify_comment",
+//				Data:     map[string]any{"Comment": c},
+//			}}, nil
+//		}),
+//	))
+package notifications
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/email"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/templates"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "notifications.digestInterval",
+			Description: "How often pending digests are checked and delivered, 0 disables",
+			Type:        "duration",
+			Default:     "1m",
+		},
+	)
+}
+
+// PluginName identifies this plugin.
+const PluginName = "notifications"
+
+// defaultDigestInterval is used when notifications.digestInterval is unset.
+const defaultDigestInterval = time.Minute
+
+// digestLock names the lock which elects the replica that delivers digests.
+const digestLock = "notifications.digest"
+
+var (
+	// Returned when a notification or preferences have no user.
+	ErrMissingUser = errors.NewC("notifications: user ID is required", codes.InvalidArgument)
+
+	// Returned when a notification has no topic.
+	ErrMissingTopic = errors.NewC("notifications: topic is required", codes.InvalidArgument)
+
+	// Returned when a preference has an unknown frequency.
+	ErrInvalidFrequency = errors.NewC("notifications: invalid frequency", codes.InvalidArgument)
+
+	// Returned when a notification uses a template, but the templates plugin
+	// isn't registered.
+	ErrTemplatesUnavailable = errors.NewC("notifications: templates plugin is required to render templates", codes.FailedPrecondition)
+)
+
+// Notification is sent to a user with Notify.
+type Notification struct {
+	// UserID of the recipient.
+	UserID string
+
+	// Topic determines which preference applies, e.g. "comment.created".
+	Topic string
+
+	// Subject and Body of the notification. Ignored if Template is set.
+	Subject string
+	Body    string
+
+	// Template renders the subject from "<Template>_subject" and the body from
+	// "<Template>", using the templates plugin.
+	Template string
+
+	// Data is passed to templates and included in messages, so channels such as
+	// webhooks and push can use structured data.
+	Data map[string]any
+}
+
+// Message is the rendered content delivered to a channel.
+type Message struct {
+	Topic     string         `json:"topic"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Data      map[string]any `json:"data,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+
+	// Items holds the batched messages, for digests.
+	Items []Message `json:"items,omitempty"`
+}
+
+// EventMapper converts an event bus message into notifications.
+type EventMapper func(ctx context.Context, data any) ([]Notification, error)
+
+// NotificationsOption configures the notifications plugin.
+type NotificationsOption func(*NotificationsPlugin)
+
+// WithChannel registers a delivery channel.
+func WithChannel(c Channel) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.channels[c.Name()] = c
+		p.channelOrder = append(p.channelOrder, c.Name())
+	}
+}
+
+// WithDefaults sets the preference used for users who haven't configured a
+// topic. If unset, topics are delivered immediately on every channel.
+func WithDefaults(tp TopicPreference) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.defaults = tp
+	}
+}
+
+// WithTopic sets the default preference for a topic, for users who haven't
+// configured it.
+func WithTopic(topic string, tp TopicPreference) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.topicDefaults[topic] = tp
+	}
+}
+
+// OnEvent sends the notifications returned by fn whenever a message is
+// published to the event bus topic. Requires the eventbus plugin.
+func OnEvent(topic string, fn EventMapper) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.events = append(p.events, eventTrigger{topic: topic, mapper: fn})
+	}
+}
+
+// WithDigestInterval sets how often pending digests are checked and delivered.
+// Zero disables the background worker, in which case FlushDigests should be
+// called by the application.
+func WithDigestInterval(d time.Duration) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.digestInterval = d
+	}
+}
+
+// WithDigestFormatter overrides how batched messages are combined into a
+// digest.
+func WithDigestFormatter(f DigestFormatter) NotificationsOption {
+	return func(p *NotificationsPlugin) {
+		p.formatter = f
+	}
+}
+
+// Plugin returns a new NotificationsPlugin.
+func Plugin(opts ...NotificationsOption) *NotificationsPlugin {
+	p := &NotificationsPlugin{
+		channels:       map[string]Channel{},
+		topicDefaults:  map[string]TopicPreference{},
+		digestInterval: digestIntervalFromConfig(),
+		formatter:      DefaultDigestFormatter,
+		now:            time.Now,
+		stop:           make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.defaults.Channels == nil {
+		p.defaults.Channels = p.channelOrder
+	}
+	return p
+}
+
+// NotificationsPlugin delivers notifications according to user preferences.
+type NotificationsPlugin struct {
+	channels      map[string]Channel
+	channelOrder  []string
+	defaults      TopicPreference
+	topicDefaults map[string]TopicPreference
+	events        []eventTrigger
+
+	store    storage.Store
+	renderer *templates.TemplatePlugin
+
+	digestInterval time.Duration
+	formatter      DigestFormatter
+	now            func() time.Time
+	stop           chan struct{}
+	stopOnce       sync.Once
+	wg             sync.WaitGroup
+}
+
+type eventTrigger struct {
+	topic  string
+	mapper EventMapper
+}
+
+// From prefab.Plugin.
+func (p *NotificationsPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *NotificationsPlugin) Deps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *NotificationsPlugin) OptDeps() []string {
+	return []string{email.PluginName, templates.PluginName, eventbus.PluginName, lock.PluginName}
+}
+
+// From prefab.InitializablePlugin.
+func (p *NotificationsPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if !ok {
+		return errors.New("notifications: storage plugin is required")
+	}
+	if err := sp.InitModel(Preferences{}); err != nil {
+		return err
+	}
+	if err := sp.InitModel(pendingNotification{}); err != nil {
+		return err
+	}
+	p.store = sp
+
+	p.renderer, _ = r.Get(templates.PluginName).(*templates.TemplatePlugin)
+
+	for _, name := range p.channelOrder {
+		if c, ok := p.channels[name].(InitializableChannel); ok {
+			if err := c.Init(ctx, r); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(p.events) > 0 {
+		bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin)
+		if !ok {
+			return errors.New("notifications: OnEvent requires the eventbus plugin")
+		}
+		for _, e := range p.events {
+			bus.Subscribe(e.topic, p.eventHandler(e.mapper))
+		}
+	}
+
+	if p.digestInterval > 0 {
+		locker, _ := r.Get(lock.PluginName).(*lock.LockPlugin)
+		p.wg.Add(1)
+		go p.runDigests(ctx, locker)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *NotificationsPlugin) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Notify sends a notification to a user on each channel enabled by their
+// preferences. Notifications for topics with a digest frequency are queued
+// and delivered by FlushDigests. Delivery continues if a channel fails, and
+// the errors are returned together.
+func (p *NotificationsPlugin) Notify(ctx context.Context, n Notification) error {
+	if n.UserID == "" {
+		return errors.Mark(ErrMissingUser, 0)
+	}
+	if n.Topic == "" {
+		return errors.Mark(ErrMissingTopic, 0)
+	}
+	msg, err := p.render(ctx, n)
+	if err != nil {
+		return err
+	}
+	prefs, err := p.Preferences(ctx, n.UserID)
+	if err != nil {
+		return err
+	}
+
+	tp := p.preferenceFor(prefs, n.Topic)
+	var errs []error
+	for _, name := range tp.Channels {
+		c, ok := p.channels[name]
+		if !ok {
+			logging.Warnw(ctx, "notifications: unknown channel in preferences", "channel", name, "userId", n.UserID)
+			continue
+		}
+		if tp.Frequency == Immediate {
+			if err := c.Deliver(ctx, n.UserID, msg); err != nil {
+				errs = append(errs, errors.WrapPrefix(err, "notifications: "+name, 0))
+			}
+			continue
+		}
+		if err := p.enqueue(ctx, n.UserID, name, tp.Frequency, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// render produces the message for a notification.
+func (p *NotificationsPlugin) render(ctx context.Context, n Notification) (Message, error) {
+	msg := Message{
+		Topic:     n.Topic,
+		Subject:   n.Subject,
+		Body:      n.Body,
+		Data:      n.Data,
+		CreatedAt: p.now(),
+	}
+	if n.Template == "" {
+		return msg, nil
+	}
+	if p.renderer == nil {
+		return msg, errors.Mark(ErrTemplatesUnavailable, 0)
+	}
+	var err error
+	if msg.Subject, err = p.renderer.Render(ctx, n.Template+"_subject", n.Data); err != nil {
+		return msg, err
+	}
+	if msg.Body, err = p.renderer.Render(ctx, n.Template, n.Data); err != nil {
+		return msg, err
+	}
+	return msg, nil
+}
+
+// eventHandler returns an event bus handler which sends the notifications
+// produced by the mapper.
+func (p *NotificationsPlugin) eventHandler(mapper EventMapper) eventbus.Handler {
+	return func(ctx context.Context, m *eventbus.Message) error {
+		notifications, err := mapper(ctx, m.Data)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, n := range notifications {
+			if err := p.Notify(ctx, n); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func digestIntervalFromConfig() time.Duration {
+	if !prefab.ConfigExists("notifications.digestInterval") {
+		return defaultDigestInterval
+	}
+	return prefab.ConfigDuration("notifications.digestInterval")
+}
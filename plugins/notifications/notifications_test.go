@@ -0,0 +1,299 @@
+package notifications
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/plugins/templates"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type delivery struct {
+	userID string
+	msg    Message
+}
+
+type fakeChannel struct {
+	name string
+	err  error
+
+	mu         sync.Mutex
+	deliveries []delivery
+}
+
+func (c *fakeChannel) Name() string { return c.name }
+
+func (c *fakeChannel) Deliver(_ context.Context, userID string, msg Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.deliveries = append(c.deliveries, delivery{userID, msg})
+	return nil
+}
+
+func (c *fakeChannel) Deliveries() []delivery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]delivery(nil), c.deliveries...)
+}
+
+func setup(t *testing.T, extra []prefab.Plugin, opts ...NotificationsOption) *NotificationsPlugin {
+	t.Helper()
+	p := Plugin(append([]NotificationsOption{WithDigestInterval(0)}, opts...)...)
+	prefabtest.Init(t, append([]prefab.Plugin{p, storage.Plugin(memstore.New())}, extra...)...)
+	return p
+}
+
+func TestNotify_Defaults(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	push := &fakeChannel{name: PushChannelName}
+	p := setup(t, nil, WithChannel(mail), WithChannel(push))
+
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "welcome", Subject: "Hi", Body: "Welcome"}))
+
+	require.Len(t, mail.Deliveries(), 1, "all channels are enabled by default")
+	require.Len(t, push.Deliveries(), 1)
+	d := mail.Deliveries()[0]
+	assert.Equal(t, "u1", d.userID)
+	assert.Equal(t, "welcome", d.msg.Topic)
+	assert.Equal(t, "Hi", d.msg.Subject)
+	assert.Equal(t, "Welcome", d.msg.Body)
+}
+
+func TestNotify_Preferences(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	push := &fakeChannel{name: PushChannelName}
+	p := setup(t, nil,
+		WithChannel(mail),
+		WithChannel(push),
+		WithDefaults(TopicPreference{Channels: []string{EmailChannelName}}),
+		WithTopic("security", TopicPreference{Channels: []string{EmailChannelName, PushChannelName}}),
+	)
+
+	prefs, err := p.Preferences(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, prefs.Topics)
+
+	require.NoError(t, p.SetPreferences(ctx, &Preferences{UserID: "u1", Topics: map[string]TopicPreference{
+		"marketing": {Channels: []string{}},
+		AllTopics:   {Channels: []string{PushChannelName}},
+	}}))
+
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "marketing", Subject: "Sale"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "comments", Subject: "New comment"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u2", Topic: "comments", Subject: "New comment"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u2", Topic: "security", Subject: "New login"}))
+
+	var mailed, pushed []string
+	for _, d := range mail.Deliveries() {
+		mailed = append(mailed, d.userID+":"+d.msg.Subject)
+	}
+	for _, d := range push.Deliveries() {
+		pushed = append(pushed, d.userID+":"+d.msg.Subject)
+	}
+	assert.Equal(t, []string{"u2:New comment", "u2:New login"}, mailed)
+	assert.Equal(t, []string{"u1:New comment", "u2:New login"}, pushed)
+
+	err = p.SetPreferences(ctx, &Preferences{UserID: "u1", Topics: map[string]TopicPreference{"x": {Frequency: "weekly"}}})
+	assert.True(t, errors.Is(err, ErrInvalidFrequency))
+}
+
+func TestNotify_Errors(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	failing := &fakeChannel{name: "failing", err: errors.New("down")}
+	ok := &fakeChannel{name: "ok"}
+	p := setup(t, nil, WithChannel(failing), WithChannel(ok))
+
+	err := p.Notify(ctx, Notification{UserID: "u1", Topic: "t", Subject: "Hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "down")
+	assert.Len(t, ok.Deliveries(), 1, "other channels are still delivered")
+
+	assert.True(t, errors.Is(p.Notify(ctx, Notification{Topic: "t"}), ErrMissingUser))
+	assert.True(t, errors.Is(p.Notify(ctx, Notification{UserID: "u1"}), ErrMissingTopic))
+	assert.True(t, errors.Is(p.Notify(ctx, Notification{UserID: "u1", Topic: "t", Template: "x"}), ErrTemplatesUnavailable))
+}
+
+func TestNotify_Template(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	tmpl := templates.Plugin(templates.WithFS(fstest.MapFS{
+		"comment_subject.tmpl": {Data: []byte(`{{define "notify_comment_subject"}}New comment from {{.Data.author}}{{end}}`)},
+		"comment.tmpl":         {Data: []byte(`{{define "notify_comment"}}<p>{{.Data.text}}</p>{{end}}`)},
+	}))
+	p := setup(t, []prefab.Plugin{tmpl}, WithChannel(mail))
+
+	require.NoError(t, p.Notify(ctx, Notification{
+		UserID:   "u1",
+		Topic:    "comment.created",
+		Template: "notify_comment",
+		Data:     map[string]any{"author": "ann", "text": "Nice post"},
+	}))
+	require.Len(t, mail.Deliveries(), 1)
+	msg := mail.Deliveries()[0].msg
+	assert.Equal(t, "New comment from ann", msg.Subject)
+	assert.Equal(t, "<p>Nice post</p>", msg.Body)
+	assert.Equal(t, "ann", msg.Data["author"])
+}
+
+func TestOnEvent(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	bus := membus.New(ctx)
+	setup(t, []prefab.Plugin{eventbus.Plugin(bus)},
+		WithChannel(mail),
+		OnEvent("comment.created", func(_ context.Context, data any) ([]Notification, error) {
+			return []Notification{{UserID: data.(string), Topic: "comment.created", Subject: "New comment"}}, nil
+		}),
+	)
+
+	bus.Publish("comment.created", "u1")
+	require.NoError(t, bus.Wait(ctx))
+	require.Len(t, mail.Deliveries(), 1)
+	assert.Equal(t, "u1", mail.Deliveries()[0].userID)
+}
+
+func TestOnEvent_RequiresEventBus(t *testing.T) {
+	p := Plugin(WithDigestInterval(0), OnEvent("x", nil))
+	r := &prefab.Registry{}
+	r.Register(p)
+	r.Register(storage.Plugin(memstore.New()))
+	assert.ErrorContains(t, r.Init(prefabtest.Context(t)), "requires the eventbus plugin")
+}
+
+func TestDigests(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	push := &fakeChannel{name: PushChannelName}
+	p := setup(t, nil,
+		WithChannel(mail),
+		WithChannel(push),
+		WithDefaults(TopicPreference{Channels: []string{EmailChannelName}, Frequency: Hourly}),
+		WithTopic("urgent", TopicPreference{Channels: []string{PushChannelName}}),
+	)
+	require.NoError(t, p.SetPreferences(ctx, &Preferences{UserID: "u2", Topics: map[string]TopicPreference{
+		AllTopics: {Channels: []string{EmailChannelName}, Frequency: Daily},
+	}}))
+
+	now := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "comment", Subject: "First"}))
+	now = now.Add(10 * time.Minute)
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "comment", Subject: "<Second>", Body: "body"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "urgent", Subject: "Now"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u2", Topic: "comment", Subject: "Daily"}))
+
+	assert.Empty(t, mail.Deliveries(), "digest topics are queued")
+	assert.Len(t, push.Deliveries(), 1, "immediate topics are delivered")
+
+	require.NoError(t, p.FlushDigests(ctx))
+	assert.Empty(t, mail.Deliveries(), "nothing is due yet")
+
+	now = time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC)
+	require.NoError(t, p.FlushDigests(ctx))
+	require.Len(t, mail.Deliveries(), 1)
+	digest := mail.Deliveries()[0]
+	assert.Equal(t, "u1", digest.userID)
+	assert.Equal(t, "You have 2 new notifications", digest.msg.Subject)
+	assert.Contains(t, digest.msg.Body, "&lt;Second&gt;")
+	require.Len(t, digest.msg.Items, 2)
+	assert.Equal(t, "First", digest.msg.Items[0].Subject, "oldest first")
+
+	require.NoError(t, p.FlushDigests(ctx))
+	assert.Len(t, mail.Deliveries(), 1, "delivered messages are removed")
+
+	now = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, p.FlushDigests(ctx))
+	require.Len(t, mail.Deliveries(), 2)
+	assert.Equal(t, "u2", mail.Deliveries()[1].userID)
+	assert.Equal(t, "You have 1 new notification", mail.Deliveries()[1].msg.Subject)
+}
+
+func TestEraseSubject(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	p := setup(t, nil,
+		WithChannel(mail),
+		WithDefaults(TopicPreference{Channels: []string{EmailChannelName}, Frequency: Daily}),
+	)
+	require.NoError(t, p.SetPreferences(ctx, &Preferences{UserID: "u1", Topics: map[string]TopicPreference{
+		"comment": {Channels: []string{EmailChannelName}, Frequency: Hourly},
+	}}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "comment", Subject: "First"}))
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u2", Topic: "comment", Subject: "Other"}))
+
+	n, err := p.EraseSubject(ctx, "", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	prefs, err := p.Preferences(ctx, "u1")
+	require.NoError(t, err)
+	assert.Empty(t, prefs.Topics)
+
+	p.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	require.NoError(t, p.FlushDigests(ctx))
+	require.Len(t, mail.Deliveries(), 1)
+	assert.Equal(t, "u2", mail.Deliveries()[0].userID)
+}
+
+func TestDigests_RetryOnFailure(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName, err: errors.New("down")}
+	p := setup(t, nil, WithChannel(mail), WithDefaults(TopicPreference{Channels: []string{EmailChannelName}, Frequency: Hourly}))
+
+	now := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	require.NoError(t, p.Notify(ctx, Notification{UserID: "u1", Topic: "t", Subject: "Hi"}))
+
+	now = now.Add(time.Hour)
+	require.Error(t, p.FlushDigests(ctx))
+
+	mail.err = nil
+	require.NoError(t, p.FlushDigests(ctx))
+	assert.Len(t, mail.Deliveries(), 1)
+}
+
+func TestNextDigest(t *testing.T) {
+	t1 := time.Date(2026, 3, 31, 23, 59, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), nextDigest(t1, Hourly))
+	assert.Equal(t, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), nextDigest(t1, Daily))
+
+	t2 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 1, 11, 0, 0, 0, time.UTC), nextDigest(t2, Hourly))
+}
+
+func TestDigestWorker(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	mail := &fakeChannel{name: EmailChannelName}
+	p := Plugin(WithChannel(mail), WithDigestInterval(time.Millisecond))
+	r := &prefab.Registry{}
+	r.Register(p)
+	r.Register(storage.Plugin(memstore.New()))
+	require.NoError(t, r.Init(ctx))
+
+	require.NoError(t, p.store.Create(ctx, pendingNotification{
+		ID:        "1",
+		UserID:    "u1",
+		Channel:   EmailChannelName,
+		Message:   Message{Subject: "Queued"},
+		DeliverAt: time.Now().Add(-time.Minute),
+	}))
+	require.Eventually(t, func() bool { return len(mail.Deliveries()) == 1 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Shutdown(ctx))
+}
@@ -0,0 +1,136 @@
+package notifications
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+)
+
+// Frequency controls how often notifications are delivered on a channel.
+type Frequency string
+
+const (
+	// Immediate delivers each notification as it is sent.
+	Immediate Frequency = "immediate"
+
+	// Hourly batches notifications into a digest sent at the top of each hour.
+	Hourly Frequency = "hourly"
+
+	// Daily batches notifications into a digest sent at midnight UTC.
+	Daily Frequency = "daily"
+)
+
+// AllTopics is the topic key for a user's default preference, used for topics
+// without a specific preference.
+const AllTopics = "*"
+
+// TopicPreference configures delivery of a topic.
+type TopicPreference struct {
+	// Channels the topic is delivered on. An empty list mutes the topic.
+	Channels []string `json:"channels"`
+
+	// Frequency of delivery, defaults to Immediate.
+	Frequency Frequency `json:"frequency,omitempty"`
+}
+
+// Preferences holds a user's notification settings, keyed by topic. Use
+// AllTopics to set a default for topics which aren't listed.
+type Preferences struct {
+	UserID string                     `json:"userId"`
+	Topics map[string]TopicPreference `json:"topics"`
+}
+
+// PK implements storage.Model.
+func (p Preferences) PK() string {
+	return p.UserID
+}
+
+// Name implements storage.Namer.
+func (p Preferences) Name() string {
+	return "notification_preferences"
+}
+
+// Preferences returns the stored preferences for a user. Users who haven't
+// set any preferences get an empty set, and the plugin defaults apply.
+func (p *NotificationsPlugin) Preferences(ctx context.Context, userID string) (*Preferences, error) {
+	prefs := &Preferences{}
+	err := p.store.Read(ctx, userID, prefs)
+	if errors.Is(err, storage.ErrNotFound) {
+		return &Preferences{UserID: userID, Topics: map[string]TopicPreference{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if prefs.Topics == nil {
+		prefs.Topics = map[string]TopicPreference{}
+	}
+	return prefs, nil
+}
+
+// SetPreferences stores a user's preferences, replacing any existing ones.
+func (p *NotificationsPlugin) SetPreferences(ctx context.Context, prefs *Preferences) error {
+	if prefs.UserID == "" {
+		return errors.Mark(ErrMissingUser, 0)
+	}
+	for topic, tp := range prefs.Topics {
+		if !tp.Frequency.valid() {
+			return errors.Mark(ErrInvalidFrequency, 0).Append(topic)
+		}
+	}
+	return p.store.Upsert(ctx, *prefs)
+}
+
+// EraseSubject deletes a user's preferences and undelivered digest messages,
+// for example to fulfil a GDPR erasure request. Notifications are addressed by
+// user ID, which is expected to be the subject. Returns the number of records
+// deleted.
+func (p *NotificationsPlugin) EraseSubject(ctx context.Context, _, subject string) (int, error) {
+	n := 0
+	err := p.store.Delete(ctx, Preferences{UserID: subject})
+	if err == nil {
+		n++
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return n, err
+	}
+
+	var pending []pendingNotification
+	if err := p.store.List(ctx, &pending, pendingNotification{UserID: subject}); err != nil {
+		return n, err
+	}
+	for _, pn := range pending {
+		if err := p.store.Delete(ctx, pn); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// preferenceFor resolves the preference for a topic, falling back to the
+// user's default, then the plugin's topic default, then the plugin default.
+func (p *NotificationsPlugin) preferenceFor(prefs *Preferences, topic string) TopicPreference {
+	tp, ok := prefs.Topics[topic]
+	if !ok {
+		tp, ok = prefs.Topics[AllTopics]
+	}
+	if !ok {
+		tp, ok = p.topicDefaults[topic]
+	}
+	if !ok {
+		tp = p.defaults
+	}
+	if tp.Frequency == "" {
+		tp.Frequency = Immediate
+	}
+	return tp
+}
+
+func (f Frequency) valid() bool {
+	switch f {
+	case "", Immediate, Hourly, Daily:
+		return true
+	default:
+		return false
+	}
+}
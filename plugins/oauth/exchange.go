@@ -0,0 +1,277 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// Token exchange grant and token type identifiers, per RFC 8693.
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+)
+
+// Delegation reason recorded on identities authenticated with an exchanged
+// token.
+const tokenExchangeReason = "oauth-token-exchange"
+
+// ErrInvalidTarget indicates the client may not obtain a token for the
+// requested audience.
+var ErrInvalidTarget = errors.NewC("invalid_target", codes.PermissionDenied)
+
+// TokenExchangeRequest describes a token exchange for a TokenExchangePolicy to
+// evaluate.
+type TokenExchangeRequest struct {
+	// Client is the authenticated client performing the exchange. It becomes
+	// the actor of the issued token.
+	Client *Client
+
+	// Subject is the identity the subject token was issued for.
+	Subject auth.Identity
+
+	// SubjectClientID is the client the subject token was issued to, or empty
+	// if the subject token is a first-party identity token.
+	SubjectClientID string
+
+	// SubjectActors are the actors of a subject token which was itself
+	// obtained via token exchange, most recent first. The issued token's `act`
+	// claim nests them under Client.
+	SubjectActors []string
+
+	// Audience contains the requested `audience` and `resource` values.
+	Audience []string
+
+	// Scopes are the scopes the issued token will carry.
+	Scopes []string
+}
+
+// TokenExchangePolicy decides whether a token exchange is allowed. Return nil to
+// allow the exchange, or an error to deny it.
+type TokenExchangePolicy func(ctx context.Context, req TokenExchangeRequest) error
+
+// WithTokenExchange enables the token exchange grant (RFC 8693) at
+// /oauth/token. A service holding a user's token can exchange it for a new
+// token, scoped to a downstream audience, which identifies the service as the
+// actor. Identities authenticated with an exchanged token are delegated, see
+// auth.IsDelegated.
+//
+// The policy is consulted for every exchange and controls which clients may
+// exchange tokens for which audiences. See AllowTokenExchange.
+func (b *Builder) WithTokenExchange(policy TokenExchangePolicy) *Builder {
+	b.plugin.exchangePolicy = policy
+	return b
+}
+
+// AllowTokenExchange returns a TokenExchangePolicy which allows each client to
+// exchange tokens only for the listed audiences. Clients which aren't listed,
+// and requests without an audience, are denied.
+func AllowTokenExchange(audiences map[string][]string) TokenExchangePolicy {
+	return func(ctx context.Context, req TokenExchangeRequest) error {
+		allowed := map[string]bool{}
+		for _, a := range audiences[req.Client.ID] {
+			allowed[a] = true
+		}
+		if len(req.Audience) == 0 {
+			return errors.Mark(ErrInvalidTarget, 0).Append("audience required")
+		}
+		for _, a := range req.Audience {
+			if !allowed[a] {
+				return errors.Mark(ErrInvalidTarget, 0).Append(a)
+			}
+		}
+		return nil
+	}
+}
+
+// handleTokenExchange issues a token in exchange for a subject token.
+func (p *OAuthPlugin) handleTokenExchange(w http.ResponseWriter, r *http.Request, logger logging.Logger) {
+	ctx := r.Context()
+
+	client, err := p.authenticateClient(r)
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+		return
+	}
+
+	subjectToken := r.FormValue("subject_token")
+	subjectTokenType := r.FormValue("subject_token_type")
+	if subjectToken == "" || (subjectTokenType != TokenTypeAccessToken && subjectTokenType != TokenTypeJWT) {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "A subject_token of a supported type is required")
+		return
+	}
+	if r.FormValue("actor_token") != "" {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "actor_token is not supported, the authenticated client is the actor")
+		return
+	}
+	if t := r.FormValue("requested_token_type"); t != "" && t != TokenTypeAccessToken {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "Only access tokens can be requested")
+		return
+	}
+
+	subject, err := p.resolveSubjectToken(ctx, subjectToken)
+	if err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "The subject token is invalid")
+		return
+	}
+
+	// Tokens issued to OAuth clients can't be exchanged for broader scopes.
+	scope := r.FormValue("scope")
+	if subject.clientID != "" {
+		subjectScope := subject.scope
+		if scope == "" {
+			scope = subjectScope
+		} else if !isScopeSubset(scope, subjectScope) {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "The requested scope exceeds the subject token's scope")
+			return
+		}
+	}
+	if scope, err = p.validateScopes(ctx, client.ID, scope); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_scope", "The requested scope is invalid")
+		return
+	}
+
+	req := TokenExchangeRequest{
+		Client:          client,
+		Subject:         subject.identity,
+		SubjectClientID: subject.clientID,
+		SubjectActors:   subject.actor.chain(),
+		Audience:        append(r.Form["audience"], r.Form["resource"]...),
+		Scopes:          ParseScopes(scope),
+	}
+	if err := p.exchangePolicy(ctx, req); err != nil {
+		logger.Infow("token exchange denied", "client", client.ID, "audience", req.Audience, "error", err)
+		writeOAuthError(w, http.StatusBadRequest, "invalid_target", "The client may not exchange tokens for the requested audience")
+		return
+	}
+
+	info, err := p.issueExchangedToken(ctx, req, scope)
+	if err != nil {
+		logger.Errorw("token exchange failed", "client", client.ID, "error", err)
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", "Failed to issue token")
+		return
+	}
+
+	resp := map[string]any{
+		"access_token":      info.Access,
+		"issued_token_type": TokenTypeAccessToken,
+		"token_type":        "Bearer",
+		"expires_in":        int64(info.AccessExpiresIn / time.Second),
+	}
+	if scope != "" {
+		resp["scope"] = scope
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Errorw("failed to encode token response", "error", err)
+	}
+}
+
+// resolveSubjectToken validates a subject token. Access tokens issued by this
+// server are accepted whatever their audience, so that a service can exchange
+// a token it received for one addressed to the next service in a chain. Other
+// tokens are resolved through the identity extractor chain, as if they had
+// been presented as a bearer token.
+func (p *OAuthPlugin) resolveSubjectToken(ctx context.Context, subjectToken string) (accessToken, error) {
+	t, err := p.resolveAccessToken(ctx, subjectToken)
+	if !errors.Is(err, auth.ErrNotFound) {
+		return t, err
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	md.Set("authorization", "Bearer "+subjectToken)
+	identity, err := auth.IdentityFromContext(metadata.NewIncomingContext(ctx, md))
+	if err != nil {
+		return accessToken{}, err
+	}
+	return accessToken{identity: identity}, nil
+}
+
+// issueExchangedToken creates and stores the access token for an approved
+// exchange. No refresh token is issued; the actor repeats the exchange once the
+// token expires.
+func (p *OAuthPlugin) issueExchangedToken(ctx context.Context, req TokenExchangeRequest, scope string) (TokenInfo, error) {
+	now := time.Now()
+	info := TokenInfo{
+		ClientID:        req.Client.ID,
+		UserID:          req.Subject.Subject,
+		Scope:           scope,
+		AccessCreateAt:  now,
+		AccessExpiresIn: p.accessTokenExpiry,
+		Actor:           req.Client.ID,
+		PriorActors:     strings.Join(req.SubjectActors, " "),
+		Audience:        strings.Join(req.Audience, " "),
+	}
+
+	if len(p.jwtKeys) > 0 {
+		claims := &jwtAccessClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        uuid.NewString(),
+				Issuer:    p.issuer,
+				Subject:   req.Subject.Subject,
+				Audience:  req.Audience,
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(p.accessTokenExpiry)),
+			},
+			ClientID: req.Client.ID,
+			Scope:    scope,
+			Actor:    info.actorClaims(),
+		}
+		access, err := p.signJWTAccessToken(claims)
+		if err != nil {
+			return TokenInfo{}, err
+		}
+		info.Access = access
+	} else {
+		access, _, err := generates.NewAccessGenerate().Token(ctx, &oauth2.GenerateBasic{
+			Client:    &clientAdapter{client: *req.Client},
+			UserID:    req.Subject.Subject,
+			CreateAt:  now,
+			TokenInfo: &tokenInfoAdapter{info: info},
+		}, false)
+		if err != nil {
+			return TokenInfo{}, err
+		}
+		info.Access = access
+	}
+
+	if err := p.tokenStore.store.Create(ctx, info); err != nil {
+		return TokenInfo{}, err
+	}
+	captureIssuedToken(ctx, info)
+	return info, nil
+}
+
+// actorClaims returns the `act` claim of a token obtained via token exchange,
+// or nil if the token was issued directly to a client.
+func (t TokenInfo) actorClaims() *actorClaims {
+	if t.Actor == "" {
+		return nil
+	}
+	return nestActors(append([]string{t.Actor}, strings.Fields(t.PriorActors)...))
+}
+
+// exchangeDelegation describes the actor of an exchanged token as a delegation.
+func exchangeDelegation(actor string, at time.Time) *auth.DelegationInfo {
+	return &auth.DelegationInfo{
+		DelegatorSub:      actor,
+		DelegatorProvider: "oauth:" + actor,
+		Reason:            tokenExchangeReason,
+		DelegatedAt:       at.Unix(),
+	}
+}
@@ -2,9 +2,9 @@ package oauth
 
 import (
 	"context"
-	"crypto/subtle"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/dpup/prefab/logging"
@@ -100,7 +100,22 @@ func (p *OAuthPlugin) tokenHandler() http.Handler {
 		// gating on err==nil would let a malformed field like `x=%ZZ` skip
 		// the entire auth check.
 		_ = r.ParseForm()
-		if r.FormValue("grant_type") == grantTypeRefreshToken {
+
+		// Capture the issued token for usage counters and events.
+		ctx, issued := withIssuedTokenCapture(ctx)
+		r = r.WithContext(ctx)
+		grantType := r.FormValue("grant_type")
+		defer p.recordTokenRequest(r, grantType, issued)
+
+		if grantType == GrantTypeTokenExchange {
+			if p.exchangePolicy == nil {
+				writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "Token exchange is not enabled")
+				return
+			}
+			p.handleTokenExchange(w, r, logger)
+			return
+		}
+		if grantType == grantTypeRefreshToken {
 			if err := p.authenticateRefreshGrant(r); err != nil {
 				logger.Warn("refresh token client authentication failed", "error", err)
 				writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "Client authentication failed")
@@ -194,6 +209,11 @@ func (p *OAuthPlugin) metadataHandler() http.Handler {
 			pkceMethods = []string{"plain", "S256"}
 		}
 
+		grantTypes := []string{"authorization_code", "refresh_token", "client_credentials"}
+		if p.exchangePolicy != nil {
+			grantTypes = append(grantTypes, GrantTypeTokenExchange)
+		}
+
 		metadata := map[string]interface{}{
 			"issuer":                                        issuer,
 			"authorization_endpoint":                        issuer + "/oauth/authorize",
@@ -201,12 +221,15 @@ func (p *OAuthPlugin) metadataHandler() http.Handler {
 			"revocation_endpoint":                           issuer + "/oauth/revoke",
 			"introspection_endpoint":                        issuer + "/oauth/introspect",
 			"response_types_supported":                      []string{"code"},
-			"grant_types_supported":                         []string{"authorization_code", "refresh_token", "client_credentials"},
+			"grant_types_supported":                         grantTypes,
 			"token_endpoint_auth_methods_supported":         []string{"client_secret_basic", "client_secret_post"},
 			"revocation_endpoint_auth_methods_supported":    []string{"client_secret_basic", "client_secret_post"},
 			"introspection_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
 			"code_challenge_methods_supported":              pkceMethods,
 		}
+		if len(p.jwtKeys) > 0 {
+			metadata["jwks_uri"] = issuer + "/.well-known/jwks.json"
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		if err := json.NewEncoder(w).Encode(metadata); err != nil {
@@ -427,6 +450,15 @@ func (p *OAuthPlugin) buildIntrospectionResponse(tokenInfo TokenInfo, expiresAt
 		response["iss"] = p.issuer
 	}
 
+	// Resource servers check the audience of exchanged tokens, and may log or
+	// authorize the actors.
+	if tokenInfo.Audience != "" {
+		response["aud"] = strings.Fields(tokenInfo.Audience)
+	}
+	if act := tokenInfo.actorClaims(); act != nil {
+		response["act"] = act
+	}
+
 	return response
 }
 
@@ -455,7 +487,8 @@ func (p *OAuthPlugin) getClientCredentials(r *http.Request) (clientID, clientSec
 }
 
 // authenticateClient authenticates a client from request credentials.
-// Uses constant-time comparison to prevent timing attacks on client secrets.
+// Secrets are checked against the stored hash, or compared in constant time
+// when stored in plaintext, to prevent timing attacks.
 func (p *OAuthPlugin) authenticateClient(r *http.Request) (*Client, error) {
 	clientID, clientSecret, err := p.getClientCredentials(r)
 	if err != nil {
@@ -472,15 +505,10 @@ func (p *OAuthPlugin) authenticateClient(r *http.Request) (*Client, error) {
 		return client, nil
 	}
 
-	// Refuse to authenticate a confidential client with an empty stored secret;
+	// VerifySecret refuses a confidential client with an empty stored secret;
 	// otherwise subtle.ConstantTimeCompare("", "") returns 1 and any caller
 	// authenticates as the misconfigured client.
-	if client.Secret == "" {
-		return nil, ErrInvalidClient
-	}
-
-	// Use constant-time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare([]byte(client.Secret), []byte(clientSecret)) != 1 {
+	if !client.VerifySecret(clientSecret) {
 		return nil, ErrInvalidClient
 	}
 
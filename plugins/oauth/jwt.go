@@ -0,0 +1,344 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/generates"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// JWT header type for access tokens, per RFC 9068 §2.1.
+const jwtAccessTokenType = "at+jwt"
+
+// SigningKey is a private key used to sign JWT access tokens. The ID is
+// included in the token's `kid` header and in the JWKS; if empty, the key's
+// RFC 7638 thumbprint is used.
+//
+// Supported keys are *rsa.PrivateKey (RS256), *ecdsa.PrivateKey on P-256
+// (ES256), and ed25519.PrivateKey (EdDSA).
+type SigningKey struct {
+	ID  string
+	Key crypto.Signer
+}
+
+// WithJWTAccessTokens issues self-contained JWT access tokens (RFC 9068)
+// instead of opaque tokens. Tokens are signed with the current key and carry
+// `sub`, `client_id` and `scope` claims, so resource servers can validate them
+// locally using the keys published at /.well-known/jwks.json rather than
+// sharing the TokenStore.
+//
+// Previous keys are no longer used for signing, but tokens signed with them are
+// still accepted and their public keys are still published, allowing keys to
+// be rotated without invalidating outstanding tokens.
+//
+// Because JWT access tokens are validated without a store lookup, revoking one
+// only takes effect once it expires. Keep the access token expiry short.
+//
+// Panics if a key is not of a supported type.
+//
+// Config key: `oauth.jwtSigningKeyFile`.
+func (b *Builder) WithJWTAccessTokens(current SigningKey, previous ...SigningKey) *Builder {
+	keys := make([]*jwtKey, 0, len(previous)+1)
+	for _, k := range append([]SigningKey{current}, previous...) {
+		jk, err := newJWTKey(k)
+		if err != nil {
+			panic("oauth: invalid JWT signing key: " + err.Error())
+		}
+		keys = append(keys, jk)
+	}
+	b.plugin.jwtKeys = keys
+	return b
+}
+
+// LoadSigningKey reads a PEM encoded private key from a file, in PKCS #8,
+// PKCS #1 (RSA), or SEC 1 (EC) form.
+func LoadSigningKey(file string) (SigningKey, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return SigningKey{}, errors.Wrap(err, 0)
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return SigningKey{}, errors.Errorf("oauth: no PEM data found in %s", file)
+	}
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, errors.Wrap(err, 0)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return SigningKey{}, errors.Errorf("oauth: unsupported key type %T", key)
+	}
+	return SigningKey{Key: signer}, nil
+}
+
+// jwtKey is a signing key with its resolved ID, algorithm and JWK.
+type jwtKey struct {
+	id     string
+	signer crypto.Signer
+	method jwt.SigningMethod
+	jwk    map[string]string
+}
+
+func newJWTKey(k SigningKey) (*jwtKey, error) {
+	jk := &jwtKey{id: k.ID, signer: k.Key}
+	switch pub := k.Key.Public().(type) {
+	case *rsa.PublicKey:
+		jk.method = jwt.SigningMethodRS256
+		jk.jwk = map[string]string{
+			"kty": "RSA",
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+			"n":   b64(pub.N.Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("only P-256 EC keys are supported")
+		}
+		ecdh, err := pub.ECDH()
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		// Uncompressed point: 0x04 || X || Y.
+		point := ecdh.Bytes()
+		jk.method = jwt.SigningMethodES256
+		jk.jwk = map[string]string{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   b64(point[1:33]),
+			"y":   b64(point[33:]),
+		}
+	case ed25519.PublicKey:
+		jk.method = jwt.SigningMethodEdDSA
+		jk.jwk = map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   b64(pub),
+		}
+	default:
+		return nil, errors.Errorf("unsupported key type %T", pub)
+	}
+	if jk.id == "" {
+		jk.id = jwkThumbprint(jk.jwk)
+	}
+	return jk, nil
+}
+
+// jwkThumbprint computes the RFC 7638 thumbprint of a JWK's required members.
+// json.Marshal sorts map keys lexicographically, as the RFC requires.
+func jwkThumbprint(jwk map[string]string) string {
+	b, _ := json.Marshal(jwk)
+	sum := sha256.Sum256(b)
+	return b64(sum[:])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// jwtAccessClaims are the claims of a JWT access token, per RFC 9068 §2.2.
+type jwtAccessClaims struct {
+	jwt.RegisteredClaims
+	ClientID string       `json:"client_id"`
+	Scope    string       `json:"scope,omitempty"`
+	Actor    *actorClaims `json:"act,omitempty"`
+}
+
+// actorClaims identify the party acting on behalf of the subject of a token
+// obtained via token exchange, per RFC 8693 §4.1. Prior actors in a
+// delegation chain are nested.
+type actorClaims struct {
+	Subject string       `json:"sub"`
+	Actor   *actorClaims `json:"act,omitempty"`
+}
+
+// nestActors returns the `act` claim for a chain of actors, most recent first.
+func nestActors(chain []string) *actorClaims {
+	var act *actorClaims
+	for i := len(chain) - 1; i >= 0; i-- {
+		act = &actorClaims{Subject: chain[i], Actor: act}
+	}
+	return act
+}
+
+// chain returns the actors of an `act` claim, most recent first.
+func (a *actorClaims) chain() []string {
+	var chain []string
+	for ; a != nil; a = a.Actor {
+		chain = append(chain, a.Subject)
+	}
+	return chain
+}
+
+// jwtAccessGenerate issues JWT access tokens. Refresh tokens remain opaque,
+// since they're only ever presented to the authorization server.
+type jwtAccessGenerate struct {
+	plugin  *OAuthPlugin
+	refresh *generates.AccessGenerate
+}
+
+// From oauth2.AccessGenerate.
+func (g *jwtAccessGenerate) Token(ctx context.Context, data *oauth2.GenerateBasic, isGenRefresh bool) (string, string, error) {
+	clientID := data.Client.GetID()
+
+	// Tokens issued via client credentials have no user, so the client is the
+	// subject (RFC 9068 §2.2).
+	subject := data.UserID
+	if subject == "" {
+		subject = clientID
+	}
+
+	claims := jwtAccessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    g.plugin.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(data.CreateAt),
+			ExpiresAt: jwt.NewNumericDate(data.CreateAt.Add(data.TokenInfo.GetAccessExpiresIn())),
+		},
+		ClientID: clientID,
+		Scope:    data.TokenInfo.GetScope(),
+	}
+	if g.plugin.issuer != "" {
+		claims.Audience = jwt.ClaimStrings{g.plugin.issuer}
+	}
+	access, err := g.plugin.signJWTAccessToken(&claims)
+	if err != nil {
+		return "", "", err
+	}
+
+	refresh := ""
+	if isGenRefresh {
+		_, refresh, err = g.refresh.Token(ctx, data, true)
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return access, refresh, nil
+}
+
+// signJWTAccessToken signs access token claims with the current key.
+func (p *OAuthPlugin) signJWTAccessToken(claims *jwtAccessClaims) (string, error) {
+	key := p.jwtKeys[0]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["typ"] = jwtAccessTokenType
+	token.Header["kid"] = key.id
+	access, err := token.SignedString(key.signer)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return access, nil
+}
+
+// parseJWTAccessToken validates a JWT access token issued by this server. The
+// boolean result is false if the token isn't a JWT access token at all, in
+// which case other extractors should handle it. The audience isn't checked,
+// see acceptsAudience.
+func (p *OAuthPlugin) parseJWTAccessToken(tokenString string) (*jwtAccessClaims, bool, error) {
+	if len(p.jwtKeys) == 0 || strings.Count(tokenString, ".") != 2 {
+		return nil, false, nil
+	}
+
+	claims := &jwtAccessClaims{}
+	parser := jwt.NewParser(jwt.WithExpirationRequired(), jwt.WithLeeway(5*time.Second))
+	_, err := parser.ParseWithClaims(tokenString, claims, p.jwtKeyFunc)
+	if errors.Is(err, errNotAccessToken) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, errors.Mark(ErrInvalidToken, 0)
+	}
+	if p.issuer != "" && claims.Issuer != p.issuer {
+		return nil, true, errors.Mark(ErrInvalidToken, 0)
+	}
+	return claims, true, nil
+}
+
+var errNotAccessToken = errors.New("oauth: not a JWT access token")
+
+// jwtKeyFunc resolves the verification key for a token by its `kid` header,
+// rejecting tokens which aren't access tokens or are signed with an
+// unexpected algorithm.
+func (p *OAuthPlugin) jwtKeyFunc(token *jwt.Token) (any, error) {
+	if typ, _ := token.Header["typ"].(string); !strings.EqualFold(typ, jwtAccessTokenType) &&
+		!strings.EqualFold(typ, "application/"+jwtAccessTokenType) {
+		return nil, errNotAccessToken
+	}
+	kid, _ := token.Header["kid"].(string)
+	for _, k := range p.jwtKeys {
+		if k.id == kid {
+			if token.Method.Alg() != k.method.Alg() {
+				return nil, errors.New("oauth: unexpected signing method")
+			}
+			return k.signer.Public(), nil
+		}
+	}
+	return nil, errors.New("oauth: unknown signing key")
+}
+
+// identityFromJWTClaims builds an identity from a validated JWT access token.
+func identityFromJWTClaims(claims *jwtAccessClaims) auth.Identity {
+	identity := auth.Identity{
+		SessionID: claims.ID,
+		Subject:   claims.Subject,
+		Provider:  "oauth:" + claims.ClientID,
+	}
+	if claims.IssuedAt != nil {
+		identity.AuthTime = claims.IssuedAt.Time
+	}
+	if claims.Actor != nil {
+		identity.Delegation = exchangeDelegation(claims.Actor.Subject, identity.AuthTime)
+	}
+	return identity
+}
+
+// jwksHandler publishes the public keys used to verify JWT access tokens as a
+// JSON Web Key Set (RFC 7517).
+func (p *OAuthPlugin) jwksHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := make([]map[string]string, 0, len(p.jwtKeys))
+		for _, k := range p.jwtKeys {
+			jwk := map[string]string{
+				"kid": k.id,
+				"use": "sig",
+				"alg": k.method.Alg(),
+			}
+			for name, v := range k.jwk {
+				jwk[name] = v
+			}
+			keys = append(keys, jwk)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+		if err := json.NewEncoder(w).Encode(map[string]any{"keys": keys}); err != nil {
+			http.Error(w, "failed to encode jwks", http.StatusInternalServerError)
+		}
+	})
+}
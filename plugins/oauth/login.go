@@ -0,0 +1,89 @@
+package oauth
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+)
+
+// defaultLoginReturnParam is the login URL query parameter which carries the
+// authorization request to resume after login.
+const defaultLoginReturnParam = "return_to"
+
+// WithLoginURL sets the page unauthenticated users are redirected to from
+// /oauth/authorize. The authorization request, with all of its parameters, is
+// passed to the page in the `return_to` query parameter (see
+// WithLoginReturnParam); once the user has logged in and the identity cookie
+// is set, redirecting back to it resumes the authorization. For example,
+// "/api/auth/login?provider=google" with the "redirect_uri" return param sends
+// users straight through Google sign in.
+//
+// If not set, the value is read from config key "oauth.loginUrl". When empty,
+// unauthenticated authorization requests fail with an error.
+func (b *Builder) WithLoginURL(loginURL string) *Builder {
+	b.plugin.loginURL = loginURL
+	return b
+}
+
+// WithLoginReturnParam sets the login URL query parameter which carries the
+// authorization request to resume after login. Defaults to "return_to", or
+// the config key "oauth.loginReturnParam".
+func (b *Builder) WithLoginReturnParam(name string) *Builder {
+	b.plugin.loginReturnParam = name
+	return b
+}
+
+// RedirectToLogin sends the browser to the configured login page, with a
+// continuation that resumes the authorization request after login. It returns
+// false, without writing a response, when no login URL is configured. Custom
+// UserAuthorizationHandlers can use it for unauthenticated users, returning
+// ("", nil) when it succeeds.
+func (p *OAuthPlugin) RedirectToLogin(w http.ResponseWriter, r *http.Request) bool {
+	loginURL := p.loginURL
+	if loginURL == "" {
+		loginURL = prefab.Config.String("oauth.loginUrl")
+	}
+	if loginURL == "" {
+		return false
+	}
+	u, err := url.Parse(loginURL)
+	if err != nil {
+		return false
+	}
+
+	param := p.loginReturnParam
+	if param == "" {
+		param = prefab.Config.String("oauth.loginReturnParam")
+	}
+	if param == "" {
+		param = defaultLoginReturnParam
+	}
+
+	// Rebuild the authorization request as a GET, so that parameters sent in
+	// a POST body also survive the round trip through the login page.
+	_ = r.ParseForm()
+	q := u.Query()
+	q.Set(param, r.URL.Path+"?"+r.Form.Encode())
+	u.RawQuery = q.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+	return true
+}
+
+// defaultUserAuthorizationHandler resolves the authenticated user's subject
+// directly from the auth context, treating authentication as consent. This is
+// only safe when every registered client is first-party. Unauthenticated users
+// are sent to the login page, if configured.
+func (p *OAuthPlugin) defaultUserAuthorizationHandler(w http.ResponseWriter, r *http.Request) (string, error) {
+	identity, err := auth.IdentityFromContext(r.Context())
+	if errors.Is(err, auth.ErrNotFound) && p.RedirectToLogin(w, r) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return identity.Subject, nil
+}
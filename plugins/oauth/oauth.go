@@ -35,6 +35,8 @@
 package oauth
 
 import (
+	"time"
+
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
 	"google.golang.org/grpc/codes"
@@ -42,6 +44,11 @@ import (
 
 func init() {
 	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "oauth.audiences",
+			Description: "Audiences, besides the issuer, that access tokens accepted by this server may be issued for",
+			Type:        "[]string",
+		},
 		prefab.ConfigKeyInfo{
 			Key:         "oauth.enforcePkce",
 			Description: "Require PKCE (Proof Key for Code Exchange) for public OAuth clients",
@@ -53,12 +60,37 @@ func init() {
 			Description: "OAuth token issuer URL (defaults to the server's address config key)",
 			Type:        "string",
 		},
+		prefab.ConfigKeyInfo{
+			Key:         "oauth.jwtSigningKeyFile",
+			Description: "PEM encoded private key used to sign JWT access tokens; enables JWT access tokens when set",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "oauth.loginUrl",
+			Description: "Login page unauthenticated users are redirected to from /oauth/authorize",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "oauth.loginReturnParam",
+			Description: "Login page query parameter that receives the authorization request to resume",
+			Type:        "string",
+			Default:     "return_to",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "oauth.tokenCleanupInterval",
+			Description: "How often expired tokens are purged from the token store, 0 disables",
+			Type:        "duration",
+			Default:     "1h",
+		},
 	)
 }
 
 // PluginName is the identifier for the OAuth plugin.
 const PluginName = "oauth"
 
+// defaultTokenCleanupInterval is used when oauth.tokenCleanupInterval is unset.
+const defaultTokenCleanupInterval = time.Hour
+
 // Standard OAuth2 errors.
 var (
 	ErrInvalidClient      = errors.NewC("invalid_client", codes.Unauthenticated)
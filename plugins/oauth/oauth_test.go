@@ -2,16 +2,28 @@ package oauth
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
 	"github.com/go-oauth2/oauth2/v4"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/metadata"
@@ -88,14 +100,18 @@ func TestMemoryClientStore(t *testing.T) {
 	retrieved, err := store.GetClient(ctx, "test-client")
 	require.NoError(t, err)
 	assert.Equal(t, client.ID, retrieved.ID)
-	assert.Equal(t, client.Secret, retrieved.Secret)
+	assert.NotEqual(t, "secret", retrieved.Secret, "secret should be hashed at rest")
+	assert.True(t, retrieved.VerifySecret("secret"))
 
 	// Test adapter wraps store correctly
 	adapter := newClientStoreAdapter(store)
 	clientInfo, err := adapter.GetByID(ctx, "test-client")
 	require.NoError(t, err)
 	assert.Equal(t, "test-client", clientInfo.GetID())
-	assert.Equal(t, "secret", clientInfo.GetSecret())
+	verifier, ok := clientInfo.(oauth2.ClientPasswordVerifier)
+	require.True(t, ok)
+	assert.True(t, verifier.VerifyPassword("secret"))
+	assert.False(t, verifier.VerifyPassword("wrong"))
 
 	// Try to create duplicate
 	err = store.CreateClient(ctx, client)
@@ -123,6 +139,78 @@ func TestMemoryClientStore(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestClient_VerifySecret(t *testing.T) {
+	hashed, err := HashClientSecret("current")
+	require.NoError(t, err)
+	rehashed, err := HashClientSecret(hashed)
+	require.NoError(t, err)
+	assert.Equal(t, hashed, rehashed, "hashing should be idempotent")
+
+	t.Run("Hashed", func(t *testing.T) {
+		c := &Client{ID: "c", Secret: hashed}
+		assert.True(t, c.VerifySecret("current"))
+		assert.False(t, c.VerifySecret("other"))
+		assert.False(t, c.VerifySecret(""))
+	})
+
+	t.Run("Plaintext", func(t *testing.T) {
+		c := &Client{ID: "c", Secret: "current"}
+		assert.True(t, c.VerifySecret("current"))
+		assert.False(t, c.VerifySecret("other"))
+	})
+
+	t.Run("EmptyStoredSecret", func(t *testing.T) {
+		c := &Client{ID: "c"}
+		assert.False(t, c.VerifySecret(""))
+	})
+
+	t.Run("PreviousSecret", func(t *testing.T) {
+		c := &Client{ID: "c", Secret: hashed, PreviousSecret: "old"}
+		assert.True(t, c.VerifySecret("old"))
+
+		c.PreviousSecretExpiresAt = time.Now().Add(time.Minute)
+		assert.True(t, c.VerifySecret("old"))
+
+		c.PreviousSecretExpiresAt = time.Now().Add(-time.Minute)
+		assert.False(t, c.VerifySecret("old"))
+		assert.True(t, c.VerifySecret("current"))
+	})
+}
+
+func TestOAuthPlugin_RotateClientSecret(t *testing.T) {
+	plugin := NewBuilder().
+		WithClient(Client{ID: "svc", Secret: "original", Scopes: []string{"read"}}).
+		WithClient(Client{ID: "spa", Public: true, RedirectURIs: []string{"https://app.example.com/cb"}}).
+		Build()
+	ctx := context.Background()
+
+	secret, err := plugin.RotateClientSecret(ctx, "svc", time.Hour)
+	require.NoError(t, err)
+	assert.NotEmpty(t, secret)
+	assert.NotEqual(t, "original", secret)
+
+	client, err := plugin.GetClientStore().GetClient(ctx, "svc")
+	require.NoError(t, err)
+	assert.True(t, client.VerifySecret(secret), "new secret should be accepted")
+	assert.True(t, client.VerifySecret("original"), "old secret accepted during grace period")
+	assert.NotContains(t, []string{client.Secret, client.PreviousSecret}, secret)
+
+	// Rotating again without a grace period revokes the outgoing secret.
+	next, err := plugin.RotateClientSecret(ctx, "svc", 0)
+	require.NoError(t, err)
+	client, err = plugin.GetClientStore().GetClient(ctx, "svc")
+	require.NoError(t, err)
+	assert.True(t, client.VerifySecret(next))
+	assert.False(t, client.VerifySecret(secret))
+	assert.False(t, client.VerifySecret("original"))
+
+	_, err = plugin.RotateClientSecret(ctx, "spa", time.Hour)
+	require.ErrorIs(t, err, ErrInvalidClient)
+
+	_, err = plugin.RotateClientSecret(ctx, "missing", time.Hour)
+	require.ErrorIs(t, err, ErrInvalidClient)
+}
+
 func TestTokenStoreAdapter(t *testing.T) {
 	memStore := NewMemoryTokenStore()
 	store := newTokenStoreAdapter(memStore)
@@ -298,6 +386,21 @@ func TestRequireScope(t *testing.T) {
 	})
 }
 
+func TestOAuthPlugin_OAuthScopes(t *testing.T) {
+	var _ authz.OAuthScopeProvider = (*OAuthPlugin)(nil)
+	p := NewBuilder().Build()
+
+	scopes, ok := p.OAuthScopes(WithOAuthScopes(context.Background(), []string{"read"}))
+	assert.False(t, ok, "scopes without a client ID are not an OAuth request")
+	assert.Equal(t, []string{"read"}, scopes)
+
+	ctx := WithOAuthClientID(context.Background(), "test-client")
+	ctx = WithOAuthScopes(ctx, []string{"read", "write"})
+	scopes, ok = p.OAuthScopes(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"read", "write"}, scopes)
+}
+
 func TestExtractBearerToken(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -1770,6 +1873,98 @@ func TestOAuthPlugin_WithUserAuthorizationHandler(t *testing.T) {
 	assert.Equal(t, "/consent", w.Header().Get("Location"))
 }
 
+func TestOAuthPlugin_AuthorizeRedirectsToLogin(t *testing.T) {
+	newPlugin := func() *Builder {
+		return NewBuilder().
+			WithClient(Client{
+				ID:           "demo",
+				Secret:       "secret",
+				RedirectURIs: []string{"http://localhost/cb"},
+				Scopes:       []string{"read"},
+			})
+	}
+	authorize := "/oauth/authorize?client_id=demo&response_type=code&redirect_uri=http://localhost/cb&scope=read&state=xyz"
+	unauthenticated := func(req *http.Request) *http.Request {
+		return req.WithContext(auth.WithIdentityExtractorsForTest(req.Context()))
+	}
+
+	t.Run("NoLoginURL", func(t *testing.T) {
+		req := unauthenticated(httptest.NewRequest("GET", authorize, nil))
+		w := httptest.NewRecorder()
+		newPlugin().Build().authorizeHandler().ServeHTTP(w, req)
+
+		// Without a login page the request fails back to the client.
+		loc, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", loc.Host)
+		assert.NotEmpty(t, loc.Query().Get("error"))
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		plugin := newPlugin().WithLoginURL("/login?theme=dark").Build()
+		req := unauthenticated(httptest.NewRequest("GET", authorize, nil))
+		w := httptest.NewRecorder()
+		plugin.authorizeHandler().ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		loc, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "/login", loc.Path)
+		assert.Equal(t, "dark", loc.Query().Get("theme"))
+
+		returnTo, err := url.Parse(loc.Query().Get("return_to"))
+		require.NoError(t, err)
+		assert.Equal(t, "/oauth/authorize", returnTo.Path)
+		params := returnTo.Query()
+		assert.Equal(t, "demo", params.Get("client_id"))
+		assert.Equal(t, "code", params.Get("response_type"))
+		assert.Equal(t, "http://localhost/cb", params.Get("redirect_uri"))
+		assert.Equal(t, "read", params.Get("scope"))
+		assert.Equal(t, "xyz", params.Get("state"))
+	})
+
+	t.Run("PostParamsAndCustomReturnParam", func(t *testing.T) {
+		plugin := newPlugin().
+			WithLoginURL("/api/auth/login?provider=google").
+			WithLoginReturnParam("redirect_uri").
+			Build()
+		form := url.Values{
+			"client_id":     {"demo"},
+			"response_type": {"code"},
+			"redirect_uri":  {"http://localhost/cb"},
+			"state":         {"abc"},
+		}
+		req := httptest.NewRequest("POST", "/oauth/authorize", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req = unauthenticated(req)
+		w := httptest.NewRecorder()
+		plugin.authorizeHandler().ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		loc, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "google", loc.Query().Get("provider"))
+		returnTo, err := url.Parse(loc.Query().Get("redirect_uri"))
+		require.NoError(t, err)
+		assert.Equal(t, "abc", returnTo.Query().Get("state"))
+	})
+
+	t.Run("ResumesAfterLogin", func(t *testing.T) {
+		plugin := newPlugin().WithLoginURL("/login").Build()
+		req := httptest.NewRequest("GET", authorize, nil)
+		req = req.WithContext(auth.WithIdentityForTest(req.Context(), auth.Identity{Subject: "user-1", Provider: "test"}))
+		w := httptest.NewRecorder()
+		plugin.authorizeHandler().ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusFound, w.Code)
+		loc, err := url.Parse(w.Header().Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", loc.Host, "should redirect to the client with a code")
+		assert.NotEmpty(t, loc.Query().Get("code"))
+		assert.Equal(t, "xyz", loc.Query().Get("state"))
+	})
+}
+
 // TestClient_Validate exercises the redirect URI and secret rules.
 func TestClient_Validate(t *testing.T) {
 	tests := []struct {
@@ -1829,3 +2024,421 @@ func TestClient_Validate(t *testing.T) {
 		})
 	}
 }
+
+func newJWTTestPlugin(t *testing.T, current SigningKey, previous ...SigningKey) *OAuthPlugin {
+	t.Helper()
+	plugin := NewBuilder().
+		WithClient(Client{
+			ID:           "jwt-client",
+			Secret:       "secret",
+			RedirectURIs: []string{"http://localhost/callback"},
+			Scopes:       []string{"read", "write"},
+		}).
+		WithIssuer("https://auth.example.com").
+		WithJWTAccessTokens(current, previous...).
+		Build()
+	return plugin
+}
+
+func issueClientCredentialsToken(t *testing.T, plugin *OAuthPlugin) string {
+	t.Helper()
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", "jwt-client")
+	form.Set("client_secret", "secret")
+	form.Set("scope", "read write")
+
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	plugin.tokenHandler().ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	token, _ := response["access_token"].(string)
+	require.NotEmpty(t, token)
+	return token
+}
+
+func TestOAuthPlugin_JWTAccessTokens(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	plugin := newJWTTestPlugin(t, SigningKey{ID: "key-1", Key: ecKey})
+
+	token := issueClientCredentialsToken(t, plugin)
+	require.Equal(t, 2, strings.Count(token, "."), "access token should be a JWT")
+
+	t.Run("claims", func(t *testing.T) {
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &jwtAccessClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, "at+jwt", parsed.Header["typ"])
+		assert.Equal(t, "key-1", parsed.Header["kid"])
+		assert.Equal(t, "ES256", parsed.Header["alg"])
+
+		claims := parsed.Claims.(*jwtAccessClaims)
+		assert.Equal(t, "https://auth.example.com", claims.Issuer)
+		assert.Equal(t, "jwt-client", claims.Subject)
+		assert.Equal(t, "jwt-client", claims.ClientID)
+		assert.Equal(t, "read write", claims.Scope)
+		assert.NotEmpty(t, claims.ID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt.Time, time.Minute)
+	})
+
+	t.Run("validated locally by extractor", func(t *testing.T) {
+		// Remove the token from the store to prove validation doesn't need it.
+		require.NoError(t, plugin.tokenStore.store.RemoveByAccess(context.Background(), token))
+
+		md := metadata.Pairs("authorization", "Bearer "+token)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+
+		id, err := plugin.extractIdentityFromOAuthToken(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "jwt-client", id.Subject)
+		assert.Equal(t, "oauth:jwt-client", id.Provider)
+
+		ctx = plugin.injectOAuthContext(ctx)
+		assert.Equal(t, "jwt-client", OAuthClientIDFromContext(ctx))
+		assert.True(t, HasAllScopes(ctx, "read", "write"))
+	})
+
+	t.Run("tampered token is a hard error", func(t *testing.T) {
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + parts[1] + "." + strings.Repeat("A", len(parts[2]))
+		md := metadata.Pairs("authorization", "Bearer "+tampered)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+
+		_, err := plugin.extractIdentityFromOAuthToken(ctx)
+		require.ErrorIs(t, err, auth.ErrInvalidToken)
+		assert.Empty(t, OAuthClientIDFromContext(plugin.injectOAuthContext(ctx)))
+	})
+
+	t.Run("other JWTs are deferred", func(t *testing.T) {
+		other, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "x"}).SignedString([]byte("key"))
+		require.NoError(t, err)
+		md := metadata.Pairs("authorization", "Bearer "+other)
+		ctx := metadata.NewIncomingContext(context.Background(), md)
+
+		_, err = plugin.extractIdentityFromOAuthToken(ctx)
+		assert.ErrorIs(t, err, auth.ErrNotFound)
+	})
+}
+
+func TestOAuthPlugin_JWTKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	oldToken := issueClientCredentialsToken(t, newJWTTestPlugin(t, SigningKey{Key: oldKey}))
+
+	plugin := newJWTTestPlugin(t, SigningKey{Key: newKey}, SigningKey{Key: oldKey})
+	newToken := issueClientCredentialsToken(t, plugin)
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, ok, err := plugin.parseJWTAccessToken(token)
+		require.True(t, ok)
+		require.NoError(t, err)
+		assert.Equal(t, "jwt-client", claims.ClientID)
+	}
+
+	// A plugin without the old key rejects its tokens.
+	rotated := newJWTTestPlugin(t, SigningKey{Key: newKey})
+	_, ok, err := rotated.parseJWTAccessToken(oldToken)
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestOAuthPlugin_JWKS(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	plugin := newJWTTestPlugin(t, SigningKey{ID: "ec", Key: ecKey}, SigningKey{Key: rsaKey})
+
+	w := httptest.NewRecorder()
+	plugin.jwksHandler().ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var jwks struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 2)
+
+	assert.Equal(t, "ec", jwks.Keys[0]["kid"])
+	assert.Equal(t, "EC", jwks.Keys[0]["kty"])
+	assert.Equal(t, "ES256", jwks.Keys[0]["alg"])
+	assert.NotEmpty(t, jwks.Keys[0]["x"])
+	assert.NotEmpty(t, jwks.Keys[0]["y"])
+
+	assert.Equal(t, "RSA", jwks.Keys[1]["kty"])
+	assert.Equal(t, "RS256", jwks.Keys[1]["alg"])
+	assert.Equal(t, "AQAB", jwks.Keys[1]["e"])
+	assert.NotEmpty(t, jwks.Keys[1]["kid"], "kid should default to the key thumbprint")
+
+	// The metadata advertises the JWKS.
+	w = httptest.NewRecorder()
+	plugin.metadataHandler().ServeHTTP(w, httptest.NewRequest("GET", "/.well-known/oauth-authorization-server", nil))
+	var meta map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &meta))
+	assert.Equal(t, "https://auth.example.com/.well-known/jwks.json", meta["jwks_uri"])
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+
+	file := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+
+	key, err := LoadSigningKey(file)
+	require.NoError(t, err)
+	assert.True(t, ecKey.PublicKey.Equal(key.Key.Public()))
+
+	require.NoError(t, os.WriteFile(file, []byte("not a key"), 0600))
+	_, err = LoadSigningKey(file)
+	assert.Error(t, err)
+}
+
+func newExchangeTestPlugin(t *testing.T, opts ...func(*Builder)) *OAuthPlugin {
+	t.Helper()
+	b := NewBuilder().
+		WithClient(Client{
+			ID:           "frontend",
+			Secret:       "frontend-secret",
+			RedirectURIs: []string{"http://localhost/callback"},
+			Scopes:       []string{"read", "write"},
+		}).
+		WithClient(Client{
+			ID:           "svc",
+			Secret:       "svc-secret",
+			RedirectURIs: []string{"http://localhost/callback"},
+			Scopes:       []string{"read", "write"},
+		}).
+		WithIssuer("https://auth.example.com").
+		WithTokenExchange(AllowTokenExchange(map[string][]string{"svc": {"billing"}}))
+	for _, opt := range opts {
+		opt(b)
+	}
+	plugin := b.Build()
+
+	require.NoError(t, plugin.tokenStore.store.Create(context.Background(), TokenInfo{
+		ClientID:        "frontend",
+		UserID:          "user-1",
+		Scope:           "read write",
+		Access:          "user-access",
+		AccessCreateAt:  time.Now(),
+		AccessExpiresIn: time.Hour,
+	}))
+	return plugin
+}
+
+func exchangeToken(t *testing.T, plugin *OAuthPlugin, form url.Values) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	form.Set("grant_type", GrantTypeTokenExchange)
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req = req.WithContext(auth.WithIdentityExtractors(req.Context(), plugin.extractIdentityFromOAuthToken))
+	w := httptest.NewRecorder()
+	plugin.tokenHandler().ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w, response
+}
+
+func exchangeForm(subjectToken string) url.Values {
+	return url.Values{
+		"client_id":          {"svc"},
+		"client_secret":      {"svc-secret"},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {TokenTypeAccessToken},
+		"audience":           {"billing"},
+	}
+}
+
+func TestOAuthPlugin_TokenExchange(t *testing.T) {
+	plugin := newExchangeTestPlugin(t)
+
+	form := exchangeForm("user-access")
+	form.Set("scope", "read")
+	w, resp := exchangeToken(t, plugin, form)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, TokenTypeAccessToken, resp["issued_token_type"])
+	assert.Equal(t, "Bearer", resp["token_type"])
+	assert.Equal(t, "read", resp["scope"])
+	assert.Nil(t, resp["refresh_token"])
+
+	token := resp["access_token"].(string)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+
+	// The token is for the billing service, not this server.
+	_, err := plugin.extractIdentityFromOAuthToken(ctx)
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+	assert.Empty(t, OAuthScopesFromContext(plugin.injectOAuthContext(ctx)))
+
+	plugin.audiences = []string{"billing"}
+	id, err := plugin.extractIdentityFromOAuthToken(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", id.Subject)
+	assert.Equal(t, "oauth:svc", id.Provider)
+	require.True(t, auth.IsDelegated(id))
+	assert.Equal(t, "svc", id.Delegation.DelegatorSub)
+
+	ctx = plugin.injectOAuthContext(ctx)
+	assert.Equal(t, []string{"read"}, OAuthScopesFromContext(ctx))
+
+	info, err := plugin.tokenStore.store.GetByAccess(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, "svc", info.Actor)
+	assert.Equal(t, "billing", info.Audience)
+}
+
+func TestOAuthPlugin_TokenExchange_Rejections(t *testing.T) {
+	plugin := newExchangeTestPlugin(t)
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := NewBuilder().Build()
+		w, resp := exchangeToken(t, disabled, exchangeForm("user-access"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "unsupported_grant_type", resp["error"])
+	})
+
+	t.Run("bad client secret", func(t *testing.T) {
+		form := exchangeForm("user-access")
+		form.Set("client_secret", "wrong")
+		w, resp := exchangeToken(t, plugin, form)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "invalid_client", resp["error"])
+	})
+
+	t.Run("invalid subject token", func(t *testing.T) {
+		w, resp := exchangeToken(t, plugin, exchangeForm("unknown"))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, "invalid_grant", resp["error"])
+	})
+
+	t.Run("unsupported subject token type", func(t *testing.T) {
+		form := exchangeForm("user-access")
+		form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:saml2")
+		_, resp := exchangeToken(t, plugin, form)
+		assert.Equal(t, "invalid_request", resp["error"])
+	})
+
+	t.Run("scope escalation", func(t *testing.T) {
+		require.NoError(t, plugin.tokenStore.store.Create(context.Background(), TokenInfo{
+			ClientID:        "frontend",
+			UserID:          "user-2",
+			Scope:           "read",
+			Access:          "read-only-access",
+			AccessCreateAt:  time.Now(),
+			AccessExpiresIn: time.Hour,
+		}))
+		form := exchangeForm("read-only-access")
+		form.Set("scope", "read write")
+		_, resp := exchangeToken(t, plugin, form)
+		assert.Equal(t, "invalid_scope", resp["error"])
+	})
+
+	t.Run("audience not allowed", func(t *testing.T) {
+		form := exchangeForm("user-access")
+		form.Set("audience", "payroll")
+		_, resp := exchangeToken(t, plugin, form)
+		assert.Equal(t, "invalid_target", resp["error"])
+	})
+
+	t.Run("client not allowed", func(t *testing.T) {
+		form := exchangeForm("user-access")
+		form.Set("client_id", "frontend")
+		form.Set("client_secret", "frontend-secret")
+		_, resp := exchangeToken(t, plugin, form)
+		assert.Equal(t, "invalid_target", resp["error"])
+	})
+}
+
+func TestOAuthPlugin_TokenExchange_JWT(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	plugin := newExchangeTestPlugin(t, func(b *Builder) {
+		b.WithJWTAccessTokens(SigningKey{Key: key})
+	})
+
+	w, resp := exchangeToken(t, plugin, exchangeForm("user-access"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "read write", resp["scope"], "scope defaults to the subject token's scope")
+
+	claims, ok, err := plugin.parseJWTAccessToken(resp["access_token"].(string))
+	require.True(t, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, jwt.ClaimStrings{"billing"}, claims.Audience)
+	require.NotNil(t, claims.Actor)
+	assert.Equal(t, "svc", claims.Actor.Subject)
+
+	id := identityFromJWTClaims(claims)
+	require.True(t, auth.IsDelegated(id))
+	assert.Equal(t, "svc", id.Delegation.DelegatorSub)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+resp["access_token"].(string)))
+	_, err = plugin.extractIdentityFromOAuthToken(ctx)
+	require.ErrorIs(t, err, auth.ErrInvalidToken, "tokens for other audiences are rejected")
+}
+
+func TestOAuthPlugin_TokenExchange_ActorChain(t *testing.T) {
+	for _, jwtTokens := range []bool{false, true} {
+		t.Run(fmt.Sprintf("jwt=%v", jwtTokens), func(t *testing.T) {
+			plugin := newExchangeTestPlugin(t, func(b *Builder) {
+				b.WithClient(Client{
+					ID:           "billing",
+					Secret:       "billing-secret",
+					RedirectURIs: []string{"http://localhost/callback"},
+					Scopes:       []string{"read"},
+				})
+				b.WithTokenExchange(AllowTokenExchange(map[string][]string{
+					"svc":     {"billing"},
+					"billing": {"ledger"},
+				}))
+				if jwtTokens {
+					key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+					require.NoError(t, err)
+					b.WithJWTAccessTokens(SigningKey{Key: key})
+				}
+			})
+
+			form := exchangeForm("user-access")
+			form.Set("scope", "read")
+			w, resp := exchangeToken(t, plugin, form)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			// The billing service exchanges the token it received, which isn't
+			// addressed to this server, for one for the ledger.
+			form = exchangeForm(resp["access_token"].(string))
+			form.Set("client_id", "billing")
+			form.Set("client_secret", "billing-secret")
+			form.Set("audience", "ledger")
+			w, resp = exchangeToken(t, plugin, form)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+			token := resp["access_token"].(string)
+			want := &actorClaims{Subject: "billing", Actor: &actorClaims{Subject: "svc"}}
+			if jwtTokens {
+				claims, ok, err := plugin.parseJWTAccessToken(token)
+				require.True(t, ok)
+				require.NoError(t, err)
+				assert.Equal(t, want, claims.Actor)
+			} else {
+				info, err := plugin.tokenStore.store.GetByAccess(context.Background(), token)
+				require.NoError(t, err)
+				assert.Equal(t, "billing", info.Actor)
+				assert.Equal(t, "svc", info.PriorActors)
+
+				response := plugin.buildIntrospectionResponse(info, time.Now(), true)
+				assert.Equal(t, want, response["act"])
+				assert.Equal(t, []string{"ledger"}, response["aud"])
+			}
+		})
+	}
+}
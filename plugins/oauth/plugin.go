@@ -4,13 +4,17 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/lock"
 	"github.com/go-oauth2/oauth2/v4"
+	"github.com/go-oauth2/oauth2/v4/generates"
 	"github.com/go-oauth2/oauth2/v4/manage"
 	"github.com/go-oauth2/oauth2/v4/server"
 	"google.golang.org/grpc/codes"
@@ -39,6 +43,7 @@ type OAuthPlugin struct {
 	refreshTokenExpiry time.Duration
 	authCodeExpiry     time.Duration
 	issuer             string
+	audiences          []string
 	enforcePKCE        *bool // nil means use config, non-nil means use this value
 
 	staticClients   []Client
@@ -49,6 +54,27 @@ type OAuthPlugin struct {
 	// default in-memory store is in use. Tracked so Init can warn operators.
 	usingMemoryTokenStore bool
 	userAuthHandler       server.UserAuthorizationHandler
+
+	// loginURL and loginReturnParam configure the redirect for
+	// unauthenticated users, see WithLoginURL.
+	loginURL         string
+	loginReturnParam string
+
+	// jwtKeys are used to sign and verify JWT access tokens, current key
+	// first. When empty, opaque access tokens are issued.
+	jwtKeys []*jwtKey
+
+	// exchangePolicy enables the token exchange grant when set.
+	exchangePolicy TokenExchangePolicy
+
+	usage tokenUsage
+
+	// cleanupInterval is how often expired tokens are purged from stores
+	// implementing TokenPurger. Zero disables the cleanup worker.
+	cleanupInterval time.Duration
+	stop            chan struct{}
+	stopOnce        sync.Once
+	wg              sync.WaitGroup
 }
 
 // Builder provides a fluent interface for configuring the OAuth plugin.
@@ -64,6 +90,8 @@ func NewBuilder() *Builder {
 			refreshTokenExpiry: 14 * 24 * time.Hour, // 2 weeks
 			authCodeExpiry:     10 * time.Minute,
 			staticClients:      []Client{},
+			cleanupInterval:    tokenCleanupIntervalFromConfig(),
+			stop:               make(chan struct{}),
 		},
 	}
 }
@@ -107,6 +135,16 @@ func (b *Builder) WithIssuer(issuer string) *Builder {
 	return b
 }
 
+// WithAudiences sets audiences, besides the issuer, which access tokens
+// accepted by this server may be issued for. Tokens obtained via token exchange
+// for other audiences are rejected, since they are meant for other services.
+//
+// Config key: `oauth.audiences`.
+func (b *Builder) WithAudiences(audiences ...string) *Builder {
+	b.plugin.audiences = append(b.plugin.audiences, audiences...)
+	return b
+}
+
 // WithClientStore sets a custom client store for persistent/dynamic client management.
 // Use this when you need to store clients in a database or allow users to create clients.
 func (b *Builder) WithClientStore(store ClientStore) *Builder {
@@ -121,6 +159,14 @@ func (b *Builder) WithTokenStore(store TokenStore) *Builder {
 	return b
 }
 
+// WithTokenCleanupInterval sets how often expired tokens are purged from the
+// token store, when it implements TokenPurger. Zero disables the cleanup. If
+// not set, the value is read from config key "oauth.tokenCleanupInterval".
+func (b *Builder) WithTokenCleanupInterval(d time.Duration) *Builder {
+	b.plugin.cleanupInterval = d
+	return b
+}
+
 // WithEnforcePKCE sets whether PKCE is required for public clients.
 // When true, public clients must provide a code_challenge in authorization requests.
 // If not set, the value is read from config key "oauth.enforcePkce".
@@ -156,6 +202,7 @@ func (b *Builder) Build() *OAuthPlugin {
 	p.clientStore = newClientStoreAdapter(clientStore)
 	p.tokenStore = newTokenStoreAdapter(tokenStore)
 	p.registerStaticClients(clientStore)
+	p.resolveJWTKeys()
 
 	p.manager = p.buildManager()
 	p.server = p.buildServer()
@@ -177,6 +224,25 @@ func (p *OAuthPlugin) resolveStores() (ClientStore, TokenStore) {
 	return clientStore, tokenStore
 }
 
+// resolveJWTKeys loads the JWT signing key from config, unless keys were
+// provided with WithJWTAccessTokens. Panics if the key can't be loaded, since
+// a misconfigured key is a deployment error that should fail at startup.
+func (p *OAuthPlugin) resolveJWTKeys() {
+	file := prefab.Config.String("oauth.jwtSigningKeyFile")
+	if len(p.jwtKeys) > 0 || file == "" {
+		return
+	}
+	key, err := LoadSigningKey(file)
+	if err != nil {
+		panic("oauth: failed to load JWT signing key: " + err.Error())
+	}
+	jk, err := newJWTKey(key)
+	if err != nil {
+		panic("oauth: invalid JWT signing key: " + err.Error())
+	}
+	p.jwtKeys = []*jwtKey{jk}
+}
+
 // registerStaticClients inserts each statically-declared client. On a fresh
 // store CreateClient succeeds; on a persistent store the client may already
 // exist, so we fall back to Update. Both paths validate the client config.
@@ -213,6 +279,9 @@ func (p *OAuthPlugin) buildManager() *manage.Manager {
 	m.SetAuthorizeCodeExp(p.authCodeExpiry)
 	m.MapClientStorage(p.clientStore)
 	m.MapTokenStorage(p.tokenStore)
+	if len(p.jwtKeys) > 0 {
+		m.MapAccessGenerate(&jwtAccessGenerate{plugin: p, refresh: generates.NewAccessGenerate()})
+	}
 
 	// Custom redirect URI validation — baseURI holds all registered redirect
 	// URIs joined by newline (see clientAdapter.GetDomain). Redirect URIs
@@ -272,23 +341,12 @@ func (p *OAuthPlugin) buildServer() *server.Server {
 	if p.userAuthHandler != nil {
 		srv.SetUserAuthorizationHandler(p.userAuthHandler)
 	} else {
-		srv.SetUserAuthorizationHandler(defaultUserAuthorizationHandler)
+		srv.SetUserAuthorizationHandler(p.defaultUserAuthorizationHandler)
 	}
 
 	return srv
 }
 
-// defaultUserAuthorizationHandler resolves the authenticated user's subject
-// directly from the auth context, treating authentication as consent. This is
-// only safe when every registered client is first-party.
-func defaultUserAuthorizationHandler(w http.ResponseWriter, r *http.Request) (string, error) {
-	identity, err := auth.IdentityFromContext(r.Context())
-	if err != nil {
-		return "", err
-	}
-	return identity.Subject, nil
-}
-
 // isScopeSubset returns true if every scope in requested is present in granted.
 // Scopes are space-separated per RFC 6749 §3.3.
 func isScopeSubset(requested, granted string) bool {
@@ -314,6 +372,12 @@ func (p *OAuthPlugin) Deps() []string {
 	return []string{auth.PluginName}
 }
 
+// OptDeps returns the optional plugin dependencies. When the lock plugin is
+// registered, token cleanup only runs on the elected leader.
+func (p *OAuthPlugin) OptDeps() []string {
+	return []string{lock.PluginName}
+}
+
 // Init initializes the OAuth plugin.
 func (p *OAuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 	// Get auth plugin to register identity extractor
@@ -342,10 +406,33 @@ func (p *OAuthPlugin) Init(ctx context.Context, r *prefab.Registry) error {
 			p.issuer = prefab.Config.String("address")
 		}
 	}
+	p.audiences = append(p.audiences, prefab.Config.Strings("oauth.audiences")...)
+
+	if purger, ok := p.tokenStore.store.(TokenPurger); ok && p.cleanupInterval > 0 {
+		locker, _ := r.Get(lock.PluginName).(*lock.LockPlugin)
+		p.wg.Add(1)
+		go p.runTokenCleanup(ctx, purger, locker)
+	}
 
 	return nil
 }
 
+// Shutdown stops the token cleanup worker.
+func (p *OAuthPlugin) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// tokenCleanupIntervalFromConfig returns the configured cleanup interval,
+// defaulting to defaultTokenCleanupInterval when unset.
+func tokenCleanupIntervalFromConfig() time.Duration {
+	if !prefab.ConfigExists("oauth.tokenCleanupInterval") {
+		return defaultTokenCleanupInterval
+	}
+	return prefab.ConfigDuration("oauth.tokenCleanupInterval")
+}
+
 // shouldEnforcePKCE returns whether PKCE should be enforced for public clients.
 func (p *OAuthPlugin) shouldEnforcePKCE() bool {
 	if p.enforcePKCE != nil {
@@ -356,7 +443,7 @@ func (p *OAuthPlugin) shouldEnforcePKCE() bool {
 
 // ServerOptions returns the server options for the OAuth plugin.
 func (p *OAuthPlugin) ServerOptions() []prefab.ServerOption {
-	return []prefab.ServerOption{
+	opts := []prefab.ServerOption{
 		prefab.WithHTTPHandler("/oauth/authorize", p.authorizeHandler()),
 		prefab.WithHTTPHandler("/oauth/token", p.tokenHandler()),
 		prefab.WithHTTPHandler("/oauth/revoke", p.revokeHandler()),
@@ -364,6 +451,10 @@ func (p *OAuthPlugin) ServerOptions() []prefab.ServerOption {
 		prefab.WithHTTPHandler("/.well-known/oauth-authorization-server", p.metadataHandler()),
 		prefab.WithRequestConfig(p.injectOAuthContext),
 	}
+	if len(p.jwtKeys) > 0 {
+		opts = append(opts, prefab.WithHTTPHandler("/.well-known/jwks.json", p.jwksHandler()))
+	}
+	return opts
 }
 
 // GetClientStore returns the client store for external management.
@@ -376,12 +467,44 @@ func (p *OAuthPlugin) GetTokenStore() TokenStore {
 	return p.tokenStore.store
 }
 
+// EraseSubject deletes the clients registered by a user and, if the token
+// store implements TokenEraser, revokes the tokens issued to them. Returns the
+// number of clients and tokens removed.
+func (p *OAuthPlugin) EraseSubject(ctx context.Context, _, subject string) (int, error) {
+	clients, err := p.clientStore.store.ListClientsByUser(ctx, subject)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, c := range clients {
+		if err := p.clientStore.store.DeleteClient(ctx, c.ID); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if eraser, ok := p.tokenStore.store.(TokenEraser); ok {
+		removed, err := eraser.RemoveByUser(ctx, subject)
+		n += removed
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
 // AddClient adds a client dynamically at runtime. Returns an error if the
 // client configuration is invalid or if the store rejects the registration.
 func (p *OAuthPlugin) AddClient(client Client) error {
 	return p.clientStore.store.CreateClient(context.Background(), &client)
 }
 
+// OAuthScopes returns the scopes granted to the request's access token and
+// whether the request was authenticated via OAuth. It lets the authz plugin
+// enforce scopes required by actions, see authz.WithRequiredScope.
+func (p *OAuthPlugin) OAuthScopes(ctx context.Context) ([]string, bool) {
+	return OAuthScopesFromContext(ctx), IsOAuthRequest(ctx)
+}
+
 // validateScopes validates that the requested scopes are allowed for the client.
 // Returns the validated scope string or an error if any scope is not allowed.
 func (p *OAuthPlugin) validateScopes(ctx context.Context, clientID, requestedScope string) (string, error) {
@@ -426,27 +549,16 @@ func (p *OAuthPlugin) injectOAuthContext(ctx context.Context) context.Context {
 		return ctx
 	}
 
-	// Get token info from store
-	ti, err := p.tokenStore.GetByAccess(ctx, tokenString)
-	if err != nil || ti == nil {
-		return ctx
-	}
-
-	// Reject expired tokens. Without this check, scopes/client_id from an
-	// expired token would still be injected into the context, allowing
-	// HasScope-based authorization to succeed after the identity-layer check
-	// (which does verify expiry) has failed.
-	if ti.GetAccessCreateAt().Add(ti.GetAccessExpiresIn()).Before(time.Now()) {
+	// Invalid, expired, and other services' tokens don't contribute scopes.
+	// Without this, HasScope-based authorization could succeed after the
+	// identity-layer check has failed.
+	t, err := p.resolveAccessToken(ctx, tokenString)
+	if err != nil || !p.acceptsAudience(t.audience) {
 		return ctx
 	}
 
-	// Inject OAuth-specific values
-	scope := ti.GetScope()
-	scopes := strings.Fields(scope)
-	ctx = WithOAuthScopes(ctx, scopes)
-	ctx = WithOAuthClientID(ctx, ti.GetClientID())
-
-	return ctx
+	ctx = WithOAuthScopes(ctx, strings.Fields(t.scope))
+	return WithOAuthClientID(ctx, t.clientID)
 }
 
 // extractIdentityFromOAuthToken extracts identity from an OAuth access token.
@@ -455,13 +567,20 @@ func (p *OAuthPlugin) injectOAuthContext(ctx context.Context) context.Context {
 //
 //   - No bearer in the request → auth.ErrNotFound (fall through to the next
 //     extractor in the chain).
-//   - Bearer that looks like a JWT (three dot-separated parts) → auth.ErrNotFound
-//     (defer to identityFromAuthHeader, which parses JWTs authoritatively).
+//   - Bearer that is a JWT access token issued by this server (`typ` at+jwt,
+//     see WithJWTAccessTokens) → validated locally, returning the identity or
+//     auth.ErrInvalidToken.
+//   - Other bearers that look like a JWT (three dot-separated parts) →
+//     auth.ErrNotFound (defer to identityFromAuthHeader, which parses JWTs
+//     authoritatively).
 //   - Bearer that is opaque (not JWT-shaped) → looked up in the token store.
 //     On success, the identity is returned. On failure (unknown or expired
 //     token) the extractor returns auth.ErrInvalidToken, a hard error that
 //     stops the chain — without this, a stolen-then-revoked bearer would
 //     silently fall back to the user's session cookie.
+//
+// Tokens issued for an audience this server doesn't accept, see WithAudiences,
+// are rejected with auth.ErrInvalidToken.
 func (p *OAuthPlugin) extractIdentityFromOAuthToken(ctx context.Context) (auth.Identity, error) {
 	md, _ := metadata.FromIncomingContext(ctx)
 	tokenString := extractBearerToken(md)
@@ -469,33 +588,95 @@ func (p *OAuthPlugin) extractIdentityFromOAuthToken(ctx context.Context) (auth.I
 		return auth.Identity{}, errors.Mark(auth.ErrNotFound, 0)
 	}
 
-	// JWT-shaped tokens belong to identityFromAuthHeader; let the chain
+	t, err := p.resolveAccessToken(ctx, tokenString)
+	if err != nil {
+		return auth.Identity{}, err
+	}
+	if !p.acceptsAudience(t.audience) {
+		return auth.Identity{}, errors.Mark(auth.ErrInvalidToken, 0)
+	}
+	return t.identity, nil
+}
+
+// accessToken describes a valid access token issued by this server.
+type accessToken struct {
+	identity auth.Identity
+	clientID string
+	scope    string
+	audience []string
+
+	// Actor chain of a token obtained via token exchange.
+	actor *actorClaims
+}
+
+// resolveAccessToken validates an access token issued by this server, without
+// checking that it was issued for this server's audience. Returns
+// auth.ErrNotFound for JWTs that aren't access tokens, and auth.ErrInvalidToken
+// for invalid, unknown, or expired tokens.
+func (p *OAuthPlugin) resolveAccessToken(ctx context.Context, tokenString string) (accessToken, error) {
+	if claims, ok, err := p.parseJWTAccessToken(tokenString); ok {
+		if err != nil {
+			return accessToken{}, errors.Mark(auth.ErrInvalidToken, 0)
+		}
+		return accessToken{
+			identity: identityFromJWTClaims(claims),
+			clientID: claims.ClientID,
+			scope:    claims.Scope,
+			audience: claims.Audience,
+			actor:    claims.Actor,
+		}, nil
+	}
+
+	// Other JWT-shaped tokens belong to identityFromAuthHeader; let the chain
 	// continue rather than treating an opaque-token lookup miss as definitive.
 	if strings.Count(tokenString, ".") == 2 {
-		return auth.Identity{}, errors.Mark(auth.ErrNotFound, 0)
+		return accessToken{}, errors.Mark(auth.ErrNotFound, 0)
 	}
 
 	// Opaque bearer — it must resolve here or the request is unauthorized.
-	ti, err := p.tokenStore.GetByAccess(ctx, tokenString)
-	if err != nil || ti == nil {
-		return auth.Identity{}, errors.Mark(auth.ErrInvalidToken, 0)
+	info, err := p.tokenStore.store.GetByAccess(ctx, tokenString)
+	if err != nil {
+		return accessToken{}, errors.Mark(auth.ErrInvalidToken, 0)
 	}
-	if ti.GetAccessCreateAt().Add(ti.GetAccessExpiresIn()).Before(time.Now()) {
-		return auth.Identity{}, errors.Mark(auth.ErrInvalidToken, 0)
+	if info.AccessCreateAt.Add(info.AccessExpiresIn).Before(time.Now()) {
+		return accessToken{}, errors.Mark(auth.ErrInvalidToken, 0)
 	}
 
-	accessToken := ti.GetAccess()
-	sessionID := accessToken
-	if len(accessToken) > 16 {
-		sessionID = accessToken[:16]
+	sessionID := info.Access
+	if len(sessionID) > 16 {
+		sessionID = sessionID[:16]
 	}
 
-	return auth.Identity{
-		SessionID: sessionID,
-		Subject:   ti.GetUserID(),
-		Provider:  "oauth:" + ti.GetClientID(),
-		AuthTime:  ti.GetAccessCreateAt(),
-	}, nil
+	t := accessToken{
+		identity: auth.Identity{
+			SessionID: sessionID,
+			Subject:   info.UserID,
+			Provider:  "oauth:" + info.ClientID,
+			AuthTime:  info.AccessCreateAt,
+		},
+		clientID: info.ClientID,
+		scope:    info.Scope,
+		audience: strings.Fields(info.Audience),
+		actor:    info.actorClaims(),
+	}
+	if t.actor != nil {
+		t.identity.Delegation = exchangeDelegation(t.actor.Subject, info.AccessCreateAt)
+	}
+	return t, nil
+}
+
+// acceptsAudience reports whether a token issued for the audiences may be used
+// with this server. Tokens without an audience were issued for this server.
+func (p *OAuthPlugin) acceptsAudience(audience []string) bool {
+	if len(audience) == 0 {
+		return true
+	}
+	for _, a := range audience {
+		if (p.issuer != "" && a == p.issuer) || slices.Contains(p.audiences, a) {
+			return true
+		}
+	}
+	return false
 }
 
 // extractBearerToken extracts a bearer token from metadata. Only the Bearer
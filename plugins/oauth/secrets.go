@@ -0,0 +1,114 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// secretHashCost is the bcrypt cost used when hashing client secrets.
+var secretHashCost = bcrypt.DefaultCost
+
+// HashClientSecret returns a bcrypt hash of a client secret, suitable for
+// storing at rest. Secrets which are already hashed are returned unchanged, so
+// it is safe to call on clients read back from a store. Custom ClientStore
+// implementations should hash Secret and PreviousSecret before persisting them.
+func HashClientSecret(secret string) (string, error) {
+	if secret == "" || isHashedSecret(secret) {
+		return secret, nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), secretHashCost)
+	if err != nil {
+		return "", errors.Wrap(err, 0).Append("oauth: failed to hash client secret")
+	}
+	return string(hash), nil
+}
+
+// GenerateClientSecret returns a new random client secret.
+func GenerateClientSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// VerifySecret reports whether secret matches the client's current secret, or
+// its previous secret while that remains valid. Stored secrets may be bcrypt
+// hashes or, for custom stores which don't hash, plaintext, which is compared
+// in constant time. A client with no stored secret never verifies.
+func (c *Client) VerifySecret(secret string) bool {
+	if c.Secret == "" || secret == "" {
+		return false
+	}
+	current := compareSecret(c.Secret, secret)
+	previous := c.PreviousSecret != "" &&
+		(c.PreviousSecretExpiresAt.IsZero() || time.Now().Before(c.PreviousSecretExpiresAt)) &&
+		compareSecret(c.PreviousSecret, secret)
+	return current || previous
+}
+
+// hashSecrets replaces the client's secrets with their hashes.
+func (c *Client) hashSecrets() error {
+	var err error
+	if c.Secret, err = HashClientSecret(c.Secret); err != nil {
+		return err
+	}
+	if c.PreviousSecret, err = HashClientSecret(c.PreviousSecret); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RotateClientSecret issues a new secret for a confidential client and returns
+// it. The plaintext secret is not stored, so it must be handed to the client
+// owner now. The outgoing secret remains valid for gracePeriod, allowing
+// deployments to switch over without downtime; a zero grace period revokes it
+// immediately.
+func (p *OAuthPlugin) RotateClientSecret(ctx context.Context, clientID string, gracePeriod time.Duration) (string, error) {
+	client, err := p.clientStore.store.GetClient(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	if client.Public {
+		return "", errors.Wrap(ErrInvalidClient, 0).Append("public clients do not have a secret")
+	}
+
+	secret, err := GenerateClientSecret()
+	if err != nil {
+		return "", err
+	}
+
+	updated := *client
+	updated.Secret = secret
+	updated.PreviousSecret = ""
+	updated.PreviousSecretExpiresAt = time.Time{}
+	if gracePeriod > 0 {
+		updated.PreviousSecret = client.Secret
+		updated.PreviousSecretExpiresAt = time.Now().Add(gracePeriod)
+	}
+	if err := p.clientStore.store.UpdateClient(ctx, &updated); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// compareSecret checks a presented secret against a stored hash or plaintext
+// secret.
+func compareSecret(stored, secret string) bool {
+	if isHashedSecret(stored) {
+		return bcrypt.CompareHashAndPassword([]byte(stored), []byte(secret)) == nil
+	}
+	return subtle.ConstantTimeCompare([]byte(stored), []byte(secret)) == 1
+}
+
+// isHashedSecret reports whether s is a bcrypt hash.
+func isHashedSecret(s string) bool {
+	_, err := bcrypt.Cost([]byte(s))
+	return err == nil
+}
@@ -2,7 +2,6 @@ package oauth
 
 import (
 	"context"
-	"crypto/subtle"
 	"net/url"
 	"strings"
 	"sync"
@@ -41,6 +40,15 @@ type TokenStore interface {
 	GetByRefresh(ctx context.Context, refresh string) (TokenInfo, error)
 }
 
+// TokenEraser is implemented by token stores which can remove every token
+// issued to a user. When the configured TokenStore implements it,
+// OAuthPlugin.EraseSubject revokes the user's tokens.
+type TokenEraser interface {
+	// RemoveByUser removes codes and tokens issued to userID, returning the
+	// number removed.
+	RemoveByUser(ctx context.Context, userID string) (int, error)
+}
+
 // TokenInfo represents the data stored for an OAuth token.
 // This is a simplified version of oauth2.TokenInfo for storage purposes.
 type TokenInfo struct {
@@ -59,6 +67,16 @@ type TokenInfo struct {
 	RefreshCreateAt     time.Time
 	RefreshExpiresIn    time.Duration
 	RedirectURI         string
+
+	// Actor is the client which obtained the token via token exchange, acting
+	// on behalf of UserID. Empty for tokens issued directly to a client.
+	Actor string
+	// PriorActors is the space-separated actors of the exchanged token, most
+	// recent first, when a token obtained via token exchange is exchanged
+	// again.
+	PriorActors string
+	// Audience is the space-separated audiences a token was exchanged for.
+	Audience string
 }
 
 // clientStoreAdapter adapts ClientStore to go-oauth2's ClientStore interface.
@@ -108,6 +126,10 @@ func (s *memoryClientStore) CreateClient(ctx context.Context, client *Client) er
 	if err := client.Validate(); err != nil {
 		return err
 	}
+	c := *client
+	if err := c.hashSecrets(); err != nil {
+		return err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -115,7 +137,6 @@ func (s *memoryClientStore) CreateClient(ctx context.Context, client *Client) er
 	if _, exists := s.clients[client.ID]; exists {
 		return ErrInvalidClient
 	}
-	c := *client
 	s.clients[client.ID] = &c
 	return nil
 }
@@ -125,6 +146,10 @@ func (s *memoryClientStore) UpdateClient(ctx context.Context, client *Client) er
 	if err := client.Validate(); err != nil {
 		return err
 	}
+	c := *client
+	if err := c.hashSecrets(); err != nil {
+		return err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -132,7 +157,6 @@ func (s *memoryClientStore) UpdateClient(ctx context.Context, client *Client) er
 	if _, exists := s.clients[client.ID]; !exists {
 		return ErrInvalidClient
 	}
-	c := *client
 	s.clients[client.ID] = &c
 	return nil
 }
@@ -190,11 +214,8 @@ func (c *clientAdapter) VerifyPassword(secret string) bool {
 		// Public clients authenticate by client_id alone; no secret involved.
 		return true
 	}
-	if c.client.Secret == "" {
-		// Refuse to authenticate a confidential client with no stored secret.
-		return false
-	}
-	return subtle.ConstantTimeCompare([]byte(c.client.Secret), []byte(secret)) == 1
+	// VerifySecret refuses a confidential client with no stored secret.
+	return c.client.VerifySecret(secret)
 }
 
 // tokenStoreAdapter adapts TokenStore to go-oauth2's TokenStore interface.
@@ -208,7 +229,12 @@ func newTokenStoreAdapter(store TokenStore) *tokenStoreAdapter {
 
 // Create stores a new token.
 func (s *tokenStoreAdapter) Create(ctx context.Context, info oauth2.TokenInfo) error {
-	return s.store.Create(ctx, tokenInfoFromOAuth2(info))
+	ti := tokenInfoFromOAuth2(info)
+	if err := s.store.Create(ctx, ti); err != nil {
+		return err
+	}
+	captureIssuedToken(ctx, ti)
+	return nil
 }
 
 // RemoveByCode removes a token by authorization code.
@@ -356,23 +382,37 @@ func (s *memoryTokenStore) Create(ctx context.Context, info TokenInfo) error {
 	return nil
 }
 
-// sweepExpiredLocked removes expired entries from every map. Caller holds mu.
-func (s *memoryTokenStore) sweepExpiredLocked(now time.Time) {
+// PurgeExpired implements TokenPurger.
+func (s *memoryTokenStore) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sweepExpiredLocked(now), nil
+}
+
+// sweepExpiredLocked removes expired entries from every map, returning the
+// number removed. Caller holds mu.
+func (s *memoryTokenStore) sweepExpiredLocked(now time.Time) int {
+	n := 0
 	for k, v := range s.codes {
 		if v.CodeExpiresIn > 0 && v.CodeCreateAt.Add(v.CodeExpiresIn).Before(now) {
 			delete(s.codes, k)
+			n++
 		}
 	}
 	for k, v := range s.accessTokens {
 		if v.AccessExpiresIn > 0 && v.AccessCreateAt.Add(v.AccessExpiresIn).Before(now) {
 			delete(s.accessTokens, k)
+			n++
 		}
 	}
 	for k, v := range s.refresh {
 		if v.RefreshExpiresIn > 0 && v.RefreshCreateAt.Add(v.RefreshExpiresIn).Before(now) {
 			delete(s.refresh, k)
+			n++
 		}
 	}
+	return n
 }
 
 // RemoveByCode removes a token by authorization code.
@@ -402,6 +442,23 @@ func (s *memoryTokenStore) RemoveByRefresh(ctx context.Context, refresh string)
 	return nil
 }
 
+// RemoveByUser implements TokenEraser.
+func (s *memoryTokenStore) RemoveByUser(ctx context.Context, userID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, m := range []map[string]TokenInfo{s.codes, s.accessTokens, s.refresh} {
+		for k, v := range m {
+			if v.UserID == userID {
+				delete(m, k)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
+
 // GetByCode retrieves a token by authorization code.
 func (s *memoryTokenStore) GetByCode(ctx context.Context, code string) (TokenInfo, error) {
 	s.mu.RLock()
@@ -443,7 +500,14 @@ type Client struct {
 	// ID is the unique client identifier.
 	ID string
 	// Secret is the client secret for confidential clients. Leave empty for public clients.
+	// The builtin stores hash secrets at rest, see HashClientSecret.
 	Secret string
+	// PreviousSecret is a secret which is still accepted during rotation, see
+	// OAuthPlugin.RotateClientSecret.
+	PreviousSecret string
+	// PreviousSecretExpiresAt is when PreviousSecret stops being accepted. Zero
+	// means it remains valid until removed.
+	PreviousSecretExpiresAt time.Time
 	// Name is a human-readable name for the client.
 	Name string
 	// RedirectURIs is the list of allowed redirect URIs for authorization code flow.
@@ -468,7 +532,7 @@ func (c *Client) Validate() error {
 	if !c.Public && c.Secret == "" {
 		return errors.Wrap(ErrInvalidClient, 0).Append("confidential client must have a non-empty secret")
 	}
-	if c.Public && c.Secret != "" {
+	if c.Public && (c.Secret != "" || c.PreviousSecret != "") {
 		return errors.Wrap(ErrInvalidClient, 0).Append("public client must not have a secret")
 	}
 	for _, u := range c.RedirectURIs {
@@ -0,0 +1,204 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/serverutil"
+)
+
+// TokenIssuedEvent is published with TokenIssuedEventData whenever the token
+// endpoint issues an access token, including on refresh and token exchange.
+const TokenIssuedEvent = "oauth.token_issued"
+
+// TokenIssuedEventData describes an issued access token. It never contains
+// the token itself.
+type TokenIssuedEventData struct {
+	ClientID  string
+	UserID    string // Empty for the client credentials grant
+	Actor     string // Client acting on behalf of UserID, for token exchange
+	GrantType string
+	Scope     string
+	Timestamp time.Time
+
+	// Information about the client that requested the token, see
+	// serverutil.RequestInfoFromContext.
+	Request serverutil.RequestInfo
+}
+
+// ClientUsage holds token endpoint counters for a client.
+type ClientUsage struct {
+	// Issued counts access tokens issued by grants other than refresh.
+	Issued int64
+	// Refreshed counts access tokens issued via the refresh_token grant.
+	Refreshed int64
+	// Denied counts token requests which were rejected.
+	Denied int64
+}
+
+// TokenPurger is implemented by token stores which can remove expired tokens
+// in bulk. When the configured TokenStore implements it, the plugin purges
+// expired tokens every `oauth.tokenCleanupInterval`.
+type TokenPurger interface {
+	// PurgeExpired removes codes and tokens which expired before now,
+	// returning the number removed.
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// unknownClient is the usage key for denied requests from unregistered
+// clients, so that arbitrary client IDs can't grow the counters unboundedly.
+const unknownClient = ""
+
+// tokenUsage tracks per-client token endpoint counters.
+type tokenUsage struct {
+	mu      sync.Mutex
+	clients map[string]*ClientUsage
+}
+
+func (u *tokenUsage) add(clientID string, fn func(*ClientUsage)) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.clients == nil {
+		u.clients = make(map[string]*ClientUsage)
+	}
+	c, ok := u.clients[clientID]
+	if !ok {
+		c = &ClientUsage{}
+		u.clients[clientID] = c
+	}
+	fn(c)
+}
+
+func (u *tokenUsage) snapshot() map[string]ClientUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make(map[string]ClientUsage, len(u.clients))
+	for id, c := range u.clients {
+		out[id] = *c
+	}
+	return out
+}
+
+// Usage returns a snapshot of token endpoint counters, keyed by client ID.
+// Denied requests from unregistered clients are counted under the empty
+// client ID. Counters are kept in memory and reset on restart; subscribe to
+// TokenIssuedEvent to build persistent usage dashboards.
+func (p *OAuthPlugin) Usage() map[string]ClientUsage {
+	return p.usage.snapshot()
+}
+
+// issuedTokenKey is the context key for capturing the token issued during a
+// token request.
+type issuedTokenKey struct{}
+
+// withIssuedTokenCapture returns a context which records the access token
+// stored while handling a token request.
+func withIssuedTokenCapture(ctx context.Context) (context.Context, *TokenInfo) {
+	issued := &TokenInfo{}
+	return context.WithValue(ctx, issuedTokenKey{}, issued), issued
+}
+
+// captureIssuedToken records an issued access token on the request context,
+// if it is being captured.
+func captureIssuedToken(ctx context.Context, info TokenInfo) {
+	if issued, ok := ctx.Value(issuedTokenKey{}).(*TokenInfo); ok && info.Access != "" {
+		*issued = info
+	}
+}
+
+// recordTokenRequest updates the usage counters once a token request has been
+// handled and publishes TokenIssuedEvent for successful requests.
+func (p *OAuthPlugin) recordTokenRequest(r *http.Request, grantType string, issued *TokenInfo) {
+	ctx := r.Context()
+	if issued.Access == "" {
+		p.usage.add(p.usageClientID(r), func(c *ClientUsage) { c.Denied++ })
+		return
+	}
+
+	if grantType == grantTypeRefreshToken {
+		p.usage.add(issued.ClientID, func(c *ClientUsage) { c.Refreshed++ })
+	} else {
+		p.usage.add(issued.ClientID, func(c *ClientUsage) { c.Issued++ })
+	}
+
+	if bus := eventbus.FromContext(ctx); bus != nil {
+		data := TokenIssuedEventData{
+			ClientID:  issued.ClientID,
+			UserID:    issued.UserID,
+			Actor:     issued.Actor,
+			GrantType: grantType,
+			Scope:     issued.Scope,
+			Timestamp: time.Now(),
+		}
+		data.Request, _ = serverutil.RequestInfoFromContext(ctx)
+		bus.Publish(TokenIssuedEvent, data)
+	}
+}
+
+// usageClientID returns the registered client a denied request claimed to be
+// from, or unknownClient.
+func (p *OAuthPlugin) usageClientID(r *http.Request) string {
+	clientID, _, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.FormValue("client_id")
+	}
+	if clientID == "" {
+		return unknownClient
+	}
+	if _, err := p.clientStore.store.GetClient(r.Context(), clientID); err != nil {
+		return unknownClient
+	}
+	return clientID
+}
+
+// tokenCleanupLock names the lock which elects the replica that purges tokens.
+const tokenCleanupLock = "oauth.token-cleanup"
+
+// runTokenCleanup purges expired tokens from the store on an interval until
+// the plugin is shut down. If a locker is provided, only the replica holding
+// the cleanup lock purges tokens.
+func (p *OAuthPlugin) runTokenCleanup(ctx context.Context, purger TokenPurger, locker *lock.LockPlugin) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if locker == nil {
+		p.purgeExpiredTokens(ctx, purger)
+		return
+	}
+	_ = locker.RunWhenLeader(ctx, tokenCleanupLock, func(ctx context.Context) error {
+		p.purgeExpiredTokens(ctx, purger)
+		return nil
+	})
+}
+
+// purgeExpiredTokens purges expired tokens on an interval until ctx is done.
+func (p *OAuthPlugin) purgeExpiredTokens(ctx context.Context, purger TokenPurger) {
+	ticker := time.NewTicker(p.cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := purger.PurgeExpired(ctx, time.Now())
+			if err != nil {
+				logging.Errorw(ctx, "oauth: failed to purge expired tokens", "error", err)
+			} else if n > 0 {
+				logging.Infow(ctx, "oauth: purged expired tokens", "count", n)
+			}
+		}
+	}
+}
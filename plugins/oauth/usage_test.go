@@ -0,0 +1,214 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/lock/memlock"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postTokenForm(t *testing.T, handler http.Handler, form url.Values) int {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestOAuthPlugin_Usage(t *testing.T) {
+	plugin := NewBuilder().
+		WithClient(Client{
+			ID:     "svc",
+			Secret: "secret",
+			Scopes: []string{"read"},
+		}).
+		Build()
+	require.NoError(t, plugin.tokenStore.store.Create(context.Background(), TokenInfo{
+		ClientID:         "svc",
+		UserID:           "user-1",
+		Scope:            "read",
+		Access:           "access",
+		AccessCreateAt:   time.Now(),
+		AccessExpiresIn:  time.Hour,
+		Refresh:          "refresh",
+		RefreshCreateAt:  time.Now(),
+		RefreshExpiresIn: time.Hour,
+	}))
+	handler := plugin.tokenHandler()
+
+	code := postTokenForm(t, handler, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc"},
+		"client_secret": {"secret"},
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	code = postTokenForm(t, handler, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {"refresh"},
+		"client_id":     {"svc"},
+		"client_secret": {"secret"},
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	code = postTokenForm(t, handler, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc"},
+		"client_secret": {"wrong"},
+	})
+	require.NotEqual(t, http.StatusOK, code)
+
+	code = postTokenForm(t, handler, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"made-up"},
+		"client_secret": {"secret"},
+	})
+	require.NotEqual(t, http.StatusOK, code)
+
+	usage := plugin.Usage()
+	assert.Equal(t, ClientUsage{Issued: 1, Refreshed: 1, Denied: 1}, usage["svc"])
+	assert.Equal(t, ClientUsage{Denied: 1}, usage[unknownClient])
+	assert.NotContains(t, usage, "made-up")
+}
+
+func TestOAuthPlugin_TokenIssuedEvent(t *testing.T) {
+	plugin := NewBuilder().
+		WithClient(Client{
+			ID:     "svc",
+			Secret: "secret",
+			Scopes: []string{"read"},
+		}).
+		Build()
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(plugin))
+
+	resp, err := s.HTTPClient().PostForm(s.URL("/oauth/token"), url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {"svc"},
+		"client_secret": {"secret"},
+		"scope":         {"read"},
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	data, ok := s.Events().AssertPublished(t, TokenIssuedEvent).(TokenIssuedEventData)
+	require.True(t, ok)
+	assert.Equal(t, "svc", data.ClientID)
+	assert.Equal(t, "client_credentials", data.GrantType)
+	assert.Equal(t, "read", data.Scope)
+	assert.False(t, data.Timestamp.IsZero())
+}
+
+func TestMemoryTokenStore_PurgeExpired(t *testing.T) {
+	store := NewMemoryTokenStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, store.Create(ctx, TokenInfo{
+		ClientID:        "c",
+		Access:          "live",
+		AccessCreateAt:  now,
+		AccessExpiresIn: time.Hour,
+	}))
+	require.NoError(t, store.Create(ctx, TokenInfo{
+		ClientID:         "c",
+		Access:           "short",
+		AccessCreateAt:   now,
+		AccessExpiresIn:  time.Minute,
+		Refresh:          "short-refresh",
+		RefreshCreateAt:  now,
+		RefreshExpiresIn: time.Minute,
+	}))
+
+	purger, ok := store.(TokenPurger)
+	require.True(t, ok)
+
+	n, err := purger.PurgeExpired(ctx, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = store.GetByAccess(ctx, "live")
+	require.NoError(t, err)
+	_, err = store.GetByAccess(ctx, "short")
+	require.Error(t, err)
+	_, err = store.GetByRefresh(ctx, "short-refresh")
+	require.Error(t, err)
+}
+
+func TestOAuthPlugin_TokenCleanupInterval(t *testing.T) {
+	assert.Equal(t, defaultTokenCleanupInterval, NewBuilder().Build().cleanupInterval)
+
+	prefab.LoadConfigDefaults(map[string]interface{}{"oauth.tokenCleanupInterval": "0s"})
+	t.Cleanup(func() { prefab.Config.Delete("oauth.tokenCleanupInterval") })
+	assert.Equal(t, time.Duration(0), NewBuilder().Build().cleanupInterval)
+
+	p := NewBuilder().WithTokenCleanupInterval(time.Minute).Build()
+	assert.Equal(t, time.Minute, p.cleanupInterval)
+	require.NoError(t, p.Shutdown(context.Background()))
+}
+
+type countingPurger struct {
+	calls atomic.Int32
+}
+
+func (c *countingPurger) PurgeExpired(context.Context, time.Time) (int, error) {
+	c.calls.Add(1)
+	return 0, nil
+}
+
+func TestOAuthPlugin_TokenCleanupRequiresLeadership(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	locker := lock.Plugin(memlock.New())
+	held, err := locker.TryLock(ctx, tokenCleanupLock)
+	require.NoError(t, err)
+
+	p := NewBuilder().WithTokenCleanupInterval(time.Millisecond).Build()
+	purger := &countingPurger{}
+	p.wg.Add(1)
+	go p.runTokenCleanup(ctx, purger, locker)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(0), purger.calls.Load(), "cleanup waits for leadership")
+
+	require.NoError(t, held.Unlock(ctx))
+	require.Eventually(t, func() bool { return purger.calls.Load() > 0 }, time.Second, time.Millisecond)
+	require.NoError(t, p.Shutdown(ctx))
+}
+
+func TestOAuthPlugin_EraseSubject(t *testing.T) {
+	ctx := t.Context()
+	p := NewBuilder().Build()
+	t.Cleanup(func() { _ = p.Shutdown(ctx) })
+
+	require.NoError(t, p.AddClient(Client{ID: "mine", Secret: "s", RedirectURIs: []string{"https://example.com/cb"}, CreatedBy: "u1"}))
+	require.NoError(t, p.AddClient(Client{ID: "theirs", Secret: "s", RedirectURIs: []string{"https://example.com/cb"}, CreatedBy: "u2"}))
+
+	store := p.GetTokenStore()
+	now := time.Now()
+	require.NoError(t, store.Create(ctx, TokenInfo{ClientID: "theirs", UserID: "u1", Access: "a1", AccessCreateAt: now, AccessExpiresIn: time.Hour, Refresh: "r1", RefreshCreateAt: now, RefreshExpiresIn: time.Hour}))
+	require.NoError(t, store.Create(ctx, TokenInfo{ClientID: "theirs", UserID: "u2", Access: "a2", AccessCreateAt: now, AccessExpiresIn: time.Hour}))
+
+	n, err := p.EraseSubject(ctx, "", "u1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	_, err = p.GetClientStore().GetClient(ctx, "mine")
+	require.Error(t, err)
+	_, err = store.GetByRefresh(ctx, "r1")
+	require.Error(t, err)
+	_, err = store.GetByAccess(ctx, "a2")
+	require.NoError(t, err)
+}
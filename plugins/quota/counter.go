@@ -0,0 +1,111 @@
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Counter stores usage counters. Implementations must apply Add atomically
+// across all processes sharing the backend, so that concurrent requests can't
+// exceed a quota.
+//
+// NewMemoryCounter is suitable for single-process deployments and tests, and
+// pgquota stores counters in PostgreSQL. Other backends, such as Redis, can be
+// added by implementing this interface.
+type Counter interface {
+	// Add adds delta to the named counter, returning the new value and true.
+	// If delta is positive and the result would exceed max, the counter is
+	// unchanged and the current value and false are returned. Counters start
+	// at zero and don't go below it.
+	Add(ctx context.Context, key string, delta, max int64) (int64, bool, error)
+
+	// Get returns the value of the named counter, zero if it doesn't exist.
+	Get(ctx context.Context, key string) (int64, error)
+}
+
+// ExpiringCounter is an optional interface for counters which can discard
+// counters once the period they count has ended. The quota plugin calls
+// AddExpiring instead of Add when it's implemented.
+type ExpiringCounter interface {
+	// AddExpiring is like Add, but the counter may be discarded once expires
+	// has passed. A zero expires never expires.
+	AddExpiring(ctx context.Context, key string, delta, max int64, expires time.Time) (int64, bool, error)
+}
+
+// NewMemoryCounter returns a Counter which keeps usage in memory. Usage is
+// lost on restart and isn't shared between replicas. Counters for periods that
+// have ended are discarded.
+func NewMemoryCounter() Counter {
+	return newMemoryCounter(time.Now)
+}
+
+func newMemoryCounter(now func() time.Time) *memoryCounter {
+	return &memoryCounter{counts: map[string]memoryCount{}, now: now}
+}
+
+type memoryCounter struct {
+	mu     sync.Mutex
+	counts map[string]memoryCount
+	now    func() time.Time
+	// The earliest expiry of the counters, zero if none expire.
+	nextExpiry time.Time
+}
+
+type memoryCount struct {
+	value   int64
+	expires time.Time
+}
+
+func (c *memoryCounter) Add(ctx context.Context, key string, delta, max int64) (int64, bool, error) {
+	return c.AddExpiring(ctx, key, delta, max, time.Time{})
+}
+
+// From ExpiringCounter.
+func (c *memoryCounter) AddExpiring(_ context.Context, key string, delta, max int64, expires time.Time) (int64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	count := c.counts[key]
+	v := count.value + delta
+	if delta > 0 && v > max {
+		return count.value, false, nil
+	}
+	if v < 0 {
+		v = 0
+	}
+	if !expires.IsZero() {
+		count.expires = expires
+		if c.nextExpiry.IsZero() || expires.Before(c.nextExpiry) {
+			c.nextExpiry = expires
+		}
+	}
+	count.value = v
+	c.counts[key] = count
+	return v, true, nil
+}
+
+func (c *memoryCounter) Get(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.evict()
+	return c.counts[key].value, nil
+}
+
+// evict discards expired counters, once the earliest of them has expired.
+func (c *memoryCounter) evict() {
+	now := c.now()
+	if c.nextExpiry.IsZero() || now.Before(c.nextExpiry) {
+		return
+	}
+	c.nextExpiry = time.Time{}
+	for key, count := range c.counts {
+		switch {
+		case count.expires.IsZero():
+		case !now.Before(count.expires):
+			delete(c.counts, key)
+		case c.nextExpiry.IsZero() || count.expires.Before(c.nextExpiry):
+			c.nextExpiry = count.expires
+		}
+	}
+}
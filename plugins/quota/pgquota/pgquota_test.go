@@ -0,0 +1,77 @@
+package pgquota
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dpup/prefab/plugins/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCounter(t *testing.T) (quota.Counter, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	c, err := NewWithDB(db, WithAutoCreateTables(false))
+	require.NoError(t, err)
+	return c, mock
+}
+
+func TestNewWithDB_CreatesTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "usage"`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = NewWithDB(db, WithTableName("usage"))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdd(t *testing.T) {
+	c, mock := newTestCounter(t)
+
+	mock.ExpectQuery(`INSERT INTO "prefab_quota_usage" .* ON CONFLICT \(key\) DO UPDATE .* RETURNING used`).
+		WithArgs("api:alice", int64(1), int64(10)).
+		WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(4))
+
+	used, ok, err := c.Add(t.Context(), "api:alice", 1, 10)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(4), used)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdd_Exceeded(t *testing.T) {
+	c, mock := newTestCounter(t)
+
+	mock.ExpectQuery(`INSERT INTO "prefab_quota_usage"`).
+		WithArgs("api:alice", int64(1), int64(10)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT used FROM "prefab_quota_usage" WHERE key = \$1`).
+		WithArgs("api:alice").
+		WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(10))
+
+	used, ok, err := c.Add(t.Context(), "api:alice", 1, 10)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(10), used)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGet_Missing(t *testing.T) {
+	c, mock := newTestCounter(t)
+
+	mock.ExpectQuery(`SELECT used FROM "prefab_quota_usage"`).
+		WithArgs("api:bob").
+		WillReturnRows(sqlmock.NewRows([]string{"used"}))
+
+	used, err := c.Get(t.Context(), "api:bob")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), used)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,537 @@
+// Package quota enforces usage quotas per identity or per tenant, such as 10k
+// API calls a month or 5 projects. Unlike rate limiting, which smooths bursts
+// of traffic, quotas cap total usage over a billing-style period.
+//
+// Quotas are defined with WithLimit or under `quota.limits` in config, and can
+// be raised or lowered for individual subjects with SetLimit, which is stored
+// via the storage plugin. Methods declare the quotas they consume with the
+// `prefab.quota.consume_quota` option:
+//
+//	rpc Search(SearchRequest) returns (SearchResponse) {
+//	  option (prefab.quota.consume_quota) = "api_calls";
+//	}
+//
+// Quotas which don't map to RPCs, such as the number of projects, can be
+// managed from handlers with Consume and Release.
+//
+// When the eventbus plugin is registered, ThresholdEvent is published as usage
+// crosses each threshold, 80% and 100% by default.
+package quota
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "quota.limits",
+			Description: "Quotas by name, e.g. api_calls: {limit: 10000, period: month, scope: identity}",
+			Type:        "map",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "quota"
+
+	// ThresholdEvent is published with ThresholdEventData when usage of a
+	// quota crosses a threshold.
+	ThresholdEvent = "quota.threshold"
+)
+
+var (
+	// ErrQuotaExceeded is returned when consuming a quota would exceed its
+	// limit. The error carries a `QuotaFailure` detail naming the quota and
+	// subject.
+	ErrQuotaExceeded = errors.NewC("quota: limit exceeded", codes.ResourceExhausted).
+				WithUserPresentableMessage("You have reached your usage limit")
+
+	// Returned when a quota hasn't been defined.
+	ErrUnknownQuota = errors.NewC("quota: unknown quota", codes.Internal)
+
+	// Returned when a tenant scoped quota is used without WithTenantFunc.
+	ErrNoTenant = errors.NewC("quota: tenant could not be determined", codes.FailedPrecondition)
+)
+
+// Period is how often usage of a quota resets.
+type Period string
+
+const (
+	// Usage never resets, e.g. for the number of projects. Use Release when
+	// the resource is removed.
+	Never Period = ""
+
+	Hourly  Period = "hour"
+	Daily   Period = "day"
+	Monthly Period = "month"
+)
+
+// Scope is who a quota applies to.
+type Scope string
+
+const (
+	// The quota applies to each authenticated identity, keyed by subject.
+	IdentityScope Scope = "identity"
+
+	// The quota is shared by a tenant, as determined by WithTenantFunc.
+	TenantScope Scope = "tenant"
+)
+
+// Limit defines a quota.
+type Limit struct {
+	// Name identifies the quota, e.g. "api_calls".
+	Name string
+
+	// Max is the default limit per period, which can be overridden per subject
+	// with SetLimit.
+	Max int64
+
+	// Period is how often usage resets, in UTC.
+	Period Period
+
+	// Scope is who the quota applies to, IdentityScope if empty.
+	Scope Scope
+}
+
+// Usage reports the usage of a quota by a subject.
+type Usage struct {
+	Quota string
+
+	// Subject is the identity subject or tenant the quota applies to.
+	Subject string
+
+	Used  int64
+	Limit int64
+
+	// ResetsAt is when usage resets, zero if it never does.
+	ResetsAt time.Time
+}
+
+// Remaining returns how much of the quota is left.
+func (u Usage) Remaining() int64 {
+	return max(u.Limit-u.Used, 0)
+}
+
+// ThresholdEventData is published with ThresholdEvent.
+type ThresholdEventData struct {
+	Usage
+
+	// Threshold is the fraction of the limit which was crossed, e.g. 0.8.
+	Threshold float64
+}
+
+// TenantFunc returns the tenant a request belongs to, for tenant scoped
+// quotas.
+type TenantFunc func(ctx context.Context) (string, error)
+
+// QuotaOption allows configuration of the QuotaPlugin.
+type QuotaOption func(*QuotaPlugin)
+
+// WithLimit defines a quota. Options take precedence over `quota.limits`.
+func WithLimit(l Limit) QuotaOption {
+	return func(p *QuotaPlugin) {
+		p.limits[l.Name] = l
+	}
+}
+
+// WithCounter sets where usage is counted. Defaults to NewMemoryCounter.
+func WithCounter(c Counter) QuotaOption {
+	return func(p *QuotaPlugin) {
+		p.counter = c
+	}
+}
+
+// WithTenantFunc sets how the tenant of a request is determined, for example
+// from a header or an organization claim. Required for tenant scoped quotas.
+func WithTenantFunc(fn TenantFunc) QuotaOption {
+	return func(p *QuotaPlugin) {
+		p.tenantFn = fn
+	}
+}
+
+// WithThresholds sets the fractions of a limit at which ThresholdEvent is
+// published. Defaults to 0.8 and 1.
+func WithThresholds(thresholds ...float64) QuotaOption {
+	return func(p *QuotaPlugin) {
+		p.thresholds = thresholds
+	}
+}
+
+// Plugin returns a new QuotaPlugin.
+func Plugin(opts ...QuotaOption) *QuotaPlugin {
+	p := &QuotaPlugin{
+		limits:     map[string]Limit{},
+		thresholds: []float64{0.8, 1},
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.counter == nil {
+		// Counters expire according to the plugin's clock.
+		p.counter = newMemoryCounter(func() time.Time { return p.now() })
+	}
+	return p
+}
+
+// QuotaPlugin tracks and enforces quotas.
+type QuotaPlugin struct {
+	limits     map[string]Limit
+	counter    Counter
+	tenantFn   TenantFunc
+	thresholds []float64
+	store      storage.Store
+	bus        eventbus.EventBus
+	now        func() time.Time
+}
+
+// From prefab.Plugin.
+func (p *QuotaPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *QuotaPlugin) Deps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *QuotaPlugin) OptDeps() []string {
+	return []string{eventbus.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *QuotaPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCService(&QuotaService_ServiceDesc, &impl{p: p}),
+		prefab.WithGRPCGateway(RegisterQuotaServiceHandlerFromEndpoint),
+		// Quotas are consumed after other access checks, so rejected requests
+		// don't count against them.
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseAuthz), prefab.InterceptorPriority(100),
+			prefab.InterceptorMethodOptions(E_ConsumeQuota)),
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *QuotaPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	c := prefab.Config.Cut("quota.limits")
+	for _, name := range c.MapKeys("") {
+		// Options take precedence over config.
+		if _, ok := p.limits[name]; !ok {
+			p.limits[name] = Limit{
+				Name:   name,
+				Max:    c.Int64(name + ".limit"),
+				Period: Period(c.String(name + ".period")),
+				Scope:  Scope(c.String(name + ".scope")),
+			}
+		}
+	}
+	for _, l := range p.limits {
+		switch l.Period {
+		case Never, Hourly, Daily, Monthly:
+		default:
+			return errors.Codef(codes.InvalidArgument, "quota: invalid period %q for %s", l.Period, l.Name)
+		}
+		switch l.Scope {
+		case "", IdentityScope:
+		case TenantScope:
+			if p.tenantFn == nil {
+				return errors.Mark(ErrNoTenant, 0).Append(l.Name + " is tenant scoped but no tenant func is set")
+			}
+		default:
+			return errors.Codef(codes.InvalidArgument, "quota: invalid scope %q for %s", l.Scope, l.Name)
+		}
+	}
+
+	sp := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if err := sp.InitModel(override{}); err != nil {
+		return err
+	}
+	p.store = sp
+	if bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin); ok {
+		p.bus = bus
+	}
+	return nil
+}
+
+// Limits returns the defined quotas, ordered by name.
+func (p *QuotaPlugin) Limits() []Limit {
+	limits := make([]Limit, 0, len(p.limits))
+	for _, l := range p.limits {
+		limits = append(limits, l)
+	}
+	sort.Slice(limits, func(i, j int) bool { return limits[i].Name < limits[j].Name })
+	return limits
+}
+
+// Consume uses n units of a quota on behalf of a subject, returning
+// ErrQuotaExceeded if that would exceed the subject's limit, in which case
+// nothing is consumed.
+func (p *QuotaPlugin) Consume(ctx context.Context, quota, subject string, n int64) (Usage, error) {
+	u, err := p.usage(ctx, quota, subject)
+	if err != nil {
+		return u, err
+	}
+	used, ok, err := p.add(ctx, quota, subject, n, u.Limit)
+	if err != nil {
+		return u, err
+	}
+	u.Used = used
+	if !ok {
+		return u, errors.Mark(ErrQuotaExceeded, 0).WithDetails(&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{{
+				Subject:     quota + ":" + subject,
+				Description: "limit of " + strconv.FormatInt(u.Limit, 10) + " reached",
+			}},
+		})
+	}
+	// Compare against the counter's value rather than the usage read above,
+	// which may be stale if other requests consumed concurrently.
+	p.publishCrossings(u, used-n)
+	return u, nil
+}
+
+// Release returns n units of a quota, for example when a project counted
+// against a Never quota is deleted.
+func (p *QuotaPlugin) Release(ctx context.Context, quota, subject string, n int64) (Usage, error) {
+	u, err := p.usage(ctx, quota, subject)
+	if err != nil {
+		return u, err
+	}
+	used, _, err := p.add(ctx, quota, subject, -n, u.Limit)
+	if err != nil {
+		return u, err
+	}
+	u.Used = used
+	return u, nil
+}
+
+// Check returns ErrQuotaExceeded if consuming n units of a quota would exceed
+// the subject's limit, without consuming anything.
+func (p *QuotaPlugin) Check(ctx context.Context, quota, subject string, n int64) (Usage, error) {
+	u, err := p.usage(ctx, quota, subject)
+	if err != nil {
+		return u, err
+	}
+	if u.Used+n > u.Limit {
+		return u, errors.Mark(ErrQuotaExceeded, 0)
+	}
+	return u, nil
+}
+
+// Usage returns a subject's current usage of a quota.
+func (p *QuotaPlugin) Usage(ctx context.Context, quota, subject string) (Usage, error) {
+	return p.usage(ctx, quota, subject)
+}
+
+// SetLimit overrides the limit of a quota for one subject, for example for a
+// customer on a larger plan.
+func (p *QuotaPlugin) SetLimit(ctx context.Context, quota, subject string, limit int64) error {
+	if _, ok := p.limits[quota]; !ok {
+		return errors.Mark(ErrUnknownQuota, 0).Append(quota)
+	}
+	return p.store.Upsert(ctx, override{
+		Key:     overrideKey(quota, subject),
+		Quota:   quota,
+		Subject: subject,
+		Limit:   limit,
+	})
+}
+
+// ClearLimit removes an override set with SetLimit, so the subject reverts to
+// the quota's default limit.
+func (p *QuotaPlugin) ClearLimit(ctx context.Context, quota, subject string) error {
+	err := p.store.Delete(ctx, override{Key: overrideKey(quota, subject)})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Subject returns who a quota applies to for the request in ctx: the
+// identity's subject or the tenant, depending on the quota's scope.
+func (p *QuotaPlugin) Subject(ctx context.Context, quota string) (string, error) {
+	l, ok := p.limits[quota]
+	if !ok {
+		return "", errors.Mark(ErrUnknownQuota, 0).Append(quota)
+	}
+	if l.Scope == TenantScope {
+		tenant, err := p.tenantFn(ctx)
+		if err != nil {
+			return "", err
+		}
+		if tenant == "" {
+			return "", errors.Mark(ErrNoTenant, 0)
+		}
+		return tenant, nil
+	}
+	identity, err := auth.IdentityFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return identity.Subject, nil
+}
+
+// ConsumedQuotas returns the quotas declared on a method via the
+// `prefab.quota.consume_quota` option.
+func ConsumedQuotas(info *grpc.UnaryServerInfo) []string {
+	if v, ok := serverutil.MethodOption(info, E_ConsumeQuota); ok {
+		return v.([]string)
+	}
+	return nil
+}
+
+// interceptor consumes quotas declared via method options. If any quota is
+// exceeded, those already consumed for the call are released. Methods without
+// options are passed through untouched.
+func (p *QuotaPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	type consumed struct{ quota, subject string }
+	var done []consumed
+	for _, quota := range ConsumedQuotas(info) {
+		subject, err := p.Subject(ctx, quota)
+		if err == nil {
+			_, err = p.Consume(ctx, quota, subject, 1)
+		}
+		if err != nil {
+			for _, c := range done {
+				_, _ = p.Release(ctx, c.quota, c.subject, 1)
+			}
+			return nil, err
+		}
+		done = append(done, consumed{quota, subject})
+	}
+	return handler(ctx, req)
+}
+
+// usage returns the subject's usage of a quota in the current period.
+func (p *QuotaPlugin) usage(ctx context.Context, quota, subject string) (Usage, error) {
+	l, ok := p.limits[quota]
+	if !ok {
+		return Usage{}, errors.Mark(ErrUnknownQuota, 0).Append(quota)
+	}
+	u := Usage{
+		Quota:    quota,
+		Subject:  subject,
+		Limit:    l.Max,
+		ResetsAt: periodEnd(l.Period, p.now()),
+	}
+	o := &override{}
+	err := p.store.Read(ctx, overrideKey(quota, subject), o)
+	if err == nil {
+		u.Limit = o.Limit
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		return u, err
+	}
+	used, err := p.counter.Get(ctx, p.key(quota, subject))
+	if err != nil {
+		return u, err
+	}
+	u.Used = used
+	return u, nil
+}
+
+// add adds delta to the subject's usage in the current period, see Counter.Add.
+func (p *QuotaPlugin) add(ctx context.Context, quota, subject string, delta, max int64) (int64, bool, error) {
+	key := p.key(quota, subject)
+	if ec, ok := p.counter.(ExpiringCounter); ok {
+		return ec.AddExpiring(ctx, key, delta, max, periodEnd(p.limits[quota].Period, p.now()))
+	}
+	return p.counter.Add(ctx, key, delta, max)
+}
+
+// key returns the counter key for the subject's usage in the current period.
+func (p *QuotaPlugin) key(quota, subject string) string {
+	k := quota + ":" + subject
+	if start := periodStart(p.limits[quota].Period, p.now()); !start.IsZero() {
+		k += ":" + start.Format("2006-01-02T15")
+	}
+	return k
+}
+
+func (p *QuotaPlugin) publishCrossings(u Usage, before int64) {
+	if p.bus == nil || u.Limit <= 0 {
+		return
+	}
+	for _, t := range p.thresholds {
+		mark := t * float64(u.Limit)
+		if float64(before) < mark && float64(u.Used) >= mark {
+			p.bus.Publish(ThresholdEvent, ThresholdEventData{Usage: u, Threshold: t})
+		}
+	}
+}
+
+func (p *QuotaPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, quotaKey{}, p)
+}
+
+// FromContext retrieves the quota plugin from a context.
+func FromContext(ctx context.Context) *QuotaPlugin {
+	if p, ok := ctx.Value(quotaKey{}).(*QuotaPlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type quotaKey struct{}
+
+func periodStart(period Period, now time.Time) time.Time {
+	now = now.UTC()
+	switch period {
+	case Hourly:
+		return now.Truncate(time.Hour)
+	case Daily:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	case Monthly:
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Time{}
+}
+
+func periodEnd(period Period, now time.Time) time.Time {
+	start := periodStart(period, now)
+	switch period {
+	case Hourly:
+		return start.Add(time.Hour)
+	case Daily:
+		return start.AddDate(0, 0, 1)
+	case Monthly:
+		return start.AddDate(0, 1, 0)
+	}
+	return time.Time{}
+}
+
+// override is a per-subject limit set with SetLimit.
+type override struct {
+	Key     string `json:"key"`
+	Quota   string `json:"quota"`
+	Subject string `json:"subject"`
+	Limit   int64  `json:"limit"`
+}
+
+func (o override) PK() string {
+	return o.Key
+}
+
+func (o override) Name() string {
+	return "quota_overrides"
+}
+
+func overrideKey(quota, subject string) string {
+	return quota + ":" + subject
+}
@@ -0,0 +1,286 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/quota/quota.proto
+
+package quota
+
+import (
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetUsageRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only return usage for this quota.
+	Quota         string `protobuf:"bytes,1,opt,name=quota,proto3" json:"quota,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageRequest) Reset() {
+	*x = GetUsageRequest{}
+	mi := &file_plugins_quota_quota_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageRequest) ProtoMessage() {}
+
+func (x *GetUsageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_quota_quota_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageRequest.ProtoReflect.Descriptor instead.
+func (*GetUsageRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_quota_quota_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetUsageRequest) GetQuota() string {
+	if x != nil {
+		return x.Quota
+	}
+	return ""
+}
+
+type GetUsageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Usage         []*QuotaUsage          `protobuf:"bytes,1,rep,name=usage,proto3" json:"usage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUsageResponse) Reset() {
+	*x = GetUsageResponse{}
+	mi := &file_plugins_quota_quota_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUsageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUsageResponse) ProtoMessage() {}
+
+func (x *GetUsageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_quota_quota_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUsageResponse.ProtoReflect.Descriptor instead.
+func (*GetUsageResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_quota_quota_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetUsageResponse) GetUsage() []*QuotaUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+type QuotaUsage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Quota string                 `protobuf:"bytes,1,opt,name=quota,proto3" json:"quota,omitempty"`
+	// The identity subject or tenant the quota applies to.
+	Subject string `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Used    int64  `protobuf:"varint,3,opt,name=used,proto3" json:"used,omitempty"`
+	Limit   int64  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	// When usage resets (Unix timestamp in seconds), zero if it never does.
+	ResetsAt      int64 `protobuf:"varint,5,opt,name=resets_at,json=resetsAt,proto3" json:"resets_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuotaUsage) Reset() {
+	*x = QuotaUsage{}
+	mi := &file_plugins_quota_quota_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuotaUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuotaUsage) ProtoMessage() {}
+
+func (x *QuotaUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_quota_quota_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuotaUsage.ProtoReflect.Descriptor instead.
+func (*QuotaUsage) Descriptor() ([]byte, []int) {
+	return file_plugins_quota_quota_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QuotaUsage) GetQuota() string {
+	if x != nil {
+		return x.Quota
+	}
+	return ""
+}
+
+func (x *QuotaUsage) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *QuotaUsage) GetUsed() int64 {
+	if x != nil {
+		return x.Used
+	}
+	return 0
+}
+
+func (x *QuotaUsage) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QuotaUsage) GetResetsAt() int64 {
+	if x != nil {
+		return x.ResetsAt
+	}
+	return 0
+}
+
+var file_plugins_quota_quota_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50051,
+		Name:          "prefab.quota.consume_quota",
+		Tag:           "bytes,50051,rep,name=consume_quota",
+		Filename:      "plugins/quota/quota.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// Names of quotas, such as "api_calls", consumed by the method.
+	//
+	// repeated string consume_quota = 50051;
+	E_ConsumeQuota = &file_plugins_quota_quota_proto_extTypes[0]
+)
+
+var File_plugins_quota_quota_proto protoreflect.FileDescriptor
+
+const file_plugins_quota_quota_proto_rawDesc = "" +
+	"\n" +
+	"\x19plugins/quota/quota.proto\x12\fprefab.quota\x1a\x1cgoogle/api/annotations.proto\x1a google/protobuf/descriptor.proto\"'\n" +
+	"\x0fGetUsageRequest\x12\x14\n" +
+	"\x05quota\x18\x01 \x01(\tR\x05quota\"B\n" +
+	"\x10GetUsageResponse\x12.\n" +
+	"\x05usage\x18\x01 \x03(\v2\x18.prefab.quota.QuotaUsageR\x05usage\"\x83\x01\n" +
+	"\n" +
+	"QuotaUsage\x12\x14\n" +
+	"\x05quota\x18\x01 \x01(\tR\x05quota\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x12\n" +
+	"\x04used\x18\x03 \x01(\x03R\x04used\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x03R\x05limit\x12\x1b\n" +
+	"\tresets_at\x18\x05 \x01(\x03R\bresetsAt2s\n" +
+	"\fQuotaService\x12c\n" +
+	"\bGetUsage\x12\x1d.prefab.quota.GetUsageRequest\x1a\x1e.prefab.quota.GetUsageResponse\"\x18\x82\xd3\xe4\x93\x02\x12\x12\x10/api/quota/usage:E\n" +
+	"\rconsume_quota\x12\x1e.google.protobuf.MethodOptions\x18\x83\x87\x03 \x03(\tR\fconsumeQuotaB&Z$github.com/dpup/prefab/plugins/quotab\x06proto3"
+
+var (
+	file_plugins_quota_quota_proto_rawDescOnce sync.Once
+	file_plugins_quota_quota_proto_rawDescData []byte
+)
+
+func file_plugins_quota_quota_proto_rawDescGZIP() []byte {
+	file_plugins_quota_quota_proto_rawDescOnce.Do(func() {
+		file_plugins_quota_quota_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_quota_quota_proto_rawDesc), len(file_plugins_quota_quota_proto_rawDesc)))
+	})
+	return file_plugins_quota_quota_proto_rawDescData
+}
+
+var file_plugins_quota_quota_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_plugins_quota_quota_proto_goTypes = []any{
+	(*GetUsageRequest)(nil),            // 0: prefab.quota.GetUsageRequest
+	(*GetUsageResponse)(nil),           // 1: prefab.quota.GetUsageResponse
+	(*QuotaUsage)(nil),                 // 2: prefab.quota.QuotaUsage
+	(*descriptorpb.MethodOptions)(nil), // 3: google.protobuf.MethodOptions
+}
+var file_plugins_quota_quota_proto_depIdxs = []int32{
+	2, // 0: prefab.quota.GetUsageResponse.usage:type_name -> prefab.quota.QuotaUsage
+	3, // 1: prefab.quota.consume_quota:extendee -> google.protobuf.MethodOptions
+	0, // 2: prefab.quota.QuotaService.GetUsage:input_type -> prefab.quota.GetUsageRequest
+	1, // 3: prefab.quota.QuotaService.GetUsage:output_type -> prefab.quota.GetUsageResponse
+	3, // [3:4] is the sub-list for method output_type
+	2, // [2:3] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	1, // [1:2] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_plugins_quota_quota_proto_init() }
+func file_plugins_quota_quota_proto_init() {
+	if File_plugins_quota_quota_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_quota_quota_proto_rawDesc), len(file_plugins_quota_quota_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 1,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_quota_quota_proto_goTypes,
+		DependencyIndexes: file_plugins_quota_quota_proto_depIdxs,
+		MessageInfos:      file_plugins_quota_quota_proto_msgTypes,
+		ExtensionInfos:    file_plugins_quota_quota_proto_extTypes,
+	}.Build()
+	File_plugins_quota_quota_proto = out.File
+	file_plugins_quota_quota_proto_goTypes = nil
+	file_plugins_quota_quota_proto_depIdxs = nil
+}
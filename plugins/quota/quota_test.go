@@ -0,0 +1,211 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var user = auth.Identity{Provider: "test", Subject: "alice"}
+
+func setup(t *testing.T, opts ...QuotaOption) (*prefabtest.Server, *QuotaPlugin) {
+	p := Plugin(opts...)
+	s := prefabtest.New(t, prefabtest.WithAuth(), prefabtest.WithPlugins(p))
+	return s, p
+}
+
+func TestConsume(t *testing.T) {
+	_, p := setup(t, WithLimit(Limit{Name: "api_calls", Max: 3, Period: Monthly}))
+	ctx := prefabtest.Context(t)
+
+	u, err := p.Consume(ctx, "api_calls", "alice", 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), u.Used)
+	assert.Equal(t, int64(1), u.Remaining())
+
+	_, err = p.Check(ctx, "api_calls", "alice", 2)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	u, err = p.Consume(ctx, "api_calls", "alice", 2)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err))
+	assert.Equal(t, int64(2), u.Used, "nothing is consumed when the limit would be exceeded")
+
+	var failure *errdetails.QuotaFailure
+	for _, d := range status.Convert(err).Details() {
+		if f, ok := d.(*errdetails.QuotaFailure); ok {
+			failure = f
+		}
+	}
+	require.NotNil(t, failure)
+	assert.Equal(t, "api_calls:alice", failure.GetViolations()[0].GetSubject())
+
+	// Other subjects have their own usage.
+	_, err = p.Consume(ctx, "api_calls", "bob", 3)
+	require.NoError(t, err)
+
+	_, err = p.Consume(ctx, "storage", "alice", 1)
+	require.ErrorIs(t, err, ErrUnknownQuota)
+}
+
+func TestConsume_Period(t *testing.T) {
+	_, p := setup(t, WithLimit(Limit{Name: "api_calls", Max: 1, Period: Monthly}))
+	ctx := prefabtest.Context(t)
+	now := time.Date(2025, 1, 31, 23, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+
+	u, err := p.Consume(ctx, "api_calls", "alice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), u.ResetsAt)
+	_, err = p.Consume(ctx, "api_calls", "alice", 1)
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	now = now.Add(2 * time.Hour)
+	u, err = p.Consume(ctx, "api_calls", "alice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), u.Used)
+}
+
+func TestMemoryCounter_EvictsEndedPeriods(t *testing.T) {
+	_, p := setup(t,
+		WithLimit(Limit{Name: "api_calls", Max: 10, Period: Hourly}),
+		WithLimit(Limit{Name: "projects", Max: 10, Period: Never}),
+	)
+	ctx := prefabtest.Context(t)
+	now := time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	counter := p.counter.(*memoryCounter)
+
+	for _, subject := range []string{"alice", "bob"} {
+		_, err := p.Consume(ctx, "api_calls", subject, 1)
+		require.NoError(t, err)
+	}
+	_, err := p.Consume(ctx, "projects", "alice", 1)
+	require.NoError(t, err)
+	assert.Len(t, counter.counts, 3)
+
+	now = now.Add(time.Hour)
+	_, err = p.Consume(ctx, "api_calls", "alice", 1)
+	require.NoError(t, err)
+	assert.Len(t, counter.counts, 2, "counters for the previous hour should be discarded")
+
+	u, err := p.Usage(ctx, "projects", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), u.Used, "counters without a period are kept")
+}
+
+func TestRelease(t *testing.T) {
+	_, p := setup(t, WithLimit(Limit{Name: "projects", Max: 2}))
+	ctx := prefabtest.Context(t)
+
+	_, err := p.Consume(ctx, "projects", "alice", 2)
+	require.NoError(t, err)
+	u, err := p.Release(ctx, "projects", "alice", 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), u.Used)
+	assert.True(t, u.ResetsAt.IsZero())
+
+	u, err = p.Release(ctx, "projects", "alice", 5)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), u.Used, "usage doesn't go below zero")
+}
+
+func TestSetLimit(t *testing.T) {
+	_, p := setup(t, WithLimit(Limit{Name: "projects", Max: 1}))
+	ctx := prefabtest.Context(t)
+
+	require.NoError(t, p.SetLimit(ctx, "projects", "alice", 5))
+	u, err := p.Consume(ctx, "projects", "alice", 3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), u.Limit)
+
+	require.NoError(t, p.ClearLimit(ctx, "projects", "alice"))
+	u, err = p.Usage(ctx, "projects", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), u.Limit)
+
+	require.ErrorIs(t, p.SetLimit(ctx, "storage", "alice", 5), ErrUnknownQuota)
+}
+
+func TestThresholdEvents(t *testing.T) {
+	s, p := setup(t, WithLimit(Limit{Name: "api_calls", Max: 10, Period: Daily}))
+	ctx := prefabtest.Context(t)
+
+	_, err := p.Consume(ctx, "api_calls", "alice", 7)
+	require.NoError(t, err)
+	s.Events().AssertNotPublished(t, ThresholdEvent)
+
+	_, err = p.Consume(ctx, "api_calls", "alice", 3)
+	require.NoError(t, err)
+	events := s.Events().Published(ThresholdEvent)
+	require.Len(t, events, 2)
+	assert.InDelta(t, 0.8, events[0].(ThresholdEventData).Threshold, 0.001)
+	assert.InDelta(t, 1.0, events[1].(ThresholdEventData).Threshold, 0.001)
+	assert.Equal(t, "alice", events[1].(ThresholdEventData).Subject)
+}
+
+func TestSubject_Tenant(t *testing.T) {
+	_, p := setup(t,
+		WithLimit(Limit{Name: "seats", Max: 5, Scope: TenantScope}),
+		WithTenantFunc(func(context.Context) (string, error) { return "acme", nil }),
+	)
+	subject, err := p.Subject(prefabtest.Context(t), "seats")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", subject)
+}
+
+func TestInit_TenantScopeRequiresFunc(t *testing.T) {
+	p := Plugin(WithLimit(Limit{Name: "seats", Max: 5, Scope: TenantScope}))
+	registry := &prefab.Registry{}
+	registry.Register(storage.Plugin(memstore.New()))
+
+	err := p.Init(prefabtest.Context(t), registry)
+	assert.ErrorIs(t, err, ErrNoTenant)
+}
+
+func TestInterceptor_NoOptions(t *testing.T) {
+	_, p := setup(t)
+	info := &grpc.UnaryServerInfo{FullMethod: QuotaService_GetUsage_FullMethodName}
+	called := false
+	_, err := p.interceptor(t.Context(), nil, info, func(context.Context, any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestGetUsage(t *testing.T) {
+	s, p := setup(t,
+		WithLimit(Limit{Name: "api_calls", Max: 100, Period: Monthly}),
+		WithLimit(Limit{Name: "projects", Max: 5}),
+	)
+	_, err := p.Consume(prefabtest.Context(t), "projects", "alice", 2)
+	require.NoError(t, err)
+
+	client := NewQuotaServiceClient(s.Conn())
+	resp, err := client.GetUsage(s.AuthContext(t.Context(), user), &GetUsageRequest{})
+	require.NoError(t, err)
+	require.Len(t, resp.GetUsage(), 2)
+	assert.Equal(t, "api_calls", resp.GetUsage()[0].GetQuota())
+	assert.NotZero(t, resp.GetUsage()[0].GetResetsAt())
+	assert.Equal(t, "projects", resp.GetUsage()[1].GetQuota())
+	assert.Equal(t, int64(2), resp.GetUsage()[1].GetUsed())
+	assert.Equal(t, int64(5), resp.GetUsage()[1].GetLimit())
+
+	_, err = client.GetUsage(s.AuthContext(t.Context(), user), &GetUsageRequest{Quota: "storage"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
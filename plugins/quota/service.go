@@ -0,0 +1,48 @@
+package quota
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+type impl struct {
+	UnimplementedQuotaServiceServer
+	p *QuotaPlugin
+}
+
+func (s *impl) GetUsage(ctx context.Context, in *GetUsageRequest) (*GetUsageResponse, error) {
+	out := &GetUsageResponse{}
+	for _, l := range s.p.Limits() {
+		if in.GetQuota() != "" && in.GetQuota() != l.Name {
+			continue
+		}
+		subject, err := s.p.Subject(ctx, l.Name)
+		if err != nil {
+			return nil, err
+		}
+		u, err := s.p.Usage(ctx, l.Name, subject)
+		if err != nil {
+			return nil, err
+		}
+		out.Usage = append(out.Usage, u.proto())
+	}
+	if in.GetQuota() != "" && len(out.Usage) == 0 {
+		return nil, errors.Mark(ErrUnknownQuota, 0).Append(in.GetQuota()).WithCode(codes.NotFound)
+	}
+	return out, nil
+}
+
+func (u Usage) proto() *QuotaUsage {
+	out := &QuotaUsage{
+		Quota:   u.Quota,
+		Subject: u.Subject,
+		Used:    u.Used,
+		Limit:   u.Limit,
+	}
+	if !u.ResetsAt.IsZero() {
+		out.ResetsAt = u.ResetsAt.Unix()
+	}
+	return out
+}
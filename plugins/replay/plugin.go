@@ -0,0 +1,355 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/google/uuid"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// PluginName is the name of the replay plugin.
+const PluginName = "replay"
+
+const (
+	// Metadata key attached to replayed requests, so they are not recorded again.
+	replayMetadataKey = "prefab-replay"
+
+	// Default URL prefix for the admin endpoints.
+	defaultPath = "/debug/replay/"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "replay.dir",
+			Description: "Directory to write recordings to, if unset the storage plugin is used",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "replay.path",
+			Description: "URL prefix for the replay admin endpoints",
+			Type:        "string",
+			Default:     defaultPath,
+		},
+	)
+}
+
+// ReplayOption customizes the replay plugin.
+type ReplayOption func(*ReplayPlugin)
+
+// WithStore configures where recordings are persisted. By default recordings
+// are written to `replay.dir` if configured, otherwise to the storage plugin if
+// registered, otherwise they are kept in memory.
+func WithStore(s Store) ReplayOption {
+	return func(p *ReplayPlugin) {
+		p.store = s
+	}
+}
+
+// WithDir configures recordings to be written as JSON files to dir.
+//
+// Config key: `replay.dir`.
+func WithDir(dir string) ReplayOption {
+	return func(p *ReplayPlugin) {
+		p.store = NewDiskStore(dir)
+	}
+}
+
+// WithPath overrides the URL prefix for the admin endpoints.
+//
+// Config key: `replay.path`.
+func WithPath(path string) ReplayOption {
+	return func(p *ReplayPlugin) {
+		p.path = path
+	}
+}
+
+// WithMethodFilter restricts recording to methods for which fn returns true.
+func WithMethodFilter(fn func(fullMethod string) bool) ReplayOption {
+	return func(p *ReplayPlugin) {
+		p.filter = fn
+	}
+}
+
+// WithRedactedFields adds to the list of name fragments which mark a payload
+// field as sensitive. Matching is case-insensitive and a field is redacted if
+// its name contains any fragment. Defaults include "password", "secret", and
+// "token".
+func WithRedactedFields(fragments ...string) ReplayOption {
+	return func(p *ReplayPlugin) {
+		for _, f := range fragments {
+			p.redactedFields = append(p.redactedFields, strings.ToLower(f))
+		}
+	}
+}
+
+// Plugin returns a new ReplayPlugin.
+func Plugin(opts ...ReplayOption) *ReplayPlugin {
+	p := &ReplayPlugin{
+		path:           prefab.ConfigString("replay.path"),
+		redactedFields: append([]string{}, defaultSensitiveFields...),
+	}
+	if dir := prefab.ConfigString("replay.dir"); dir != "" {
+		p.store = NewDiskStore(dir)
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.path == "" {
+		p.path = defaultPath
+	}
+	if !strings.HasSuffix(p.path, "/") {
+		p.path += "/"
+	}
+	return p
+}
+
+// ReplayPlugin records requests and exposes endpoints to replay them.
+type ReplayPlugin struct {
+	store          Store
+	path           string
+	filter         func(string) bool
+	redactedFields []string
+
+	// Connection used to replay requests, created lazily.
+	conn grpc.ClientConnInterface
+}
+
+// From prefab.Plugin.
+func (p *ReplayPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *ReplayPlugin) OptDeps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *ReplayPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseObservability)),
+		prefab.WithJSONHandler(p.path, p.handle),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *ReplayPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.store == nil {
+		if sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin); ok && sp != nil {
+			if err := sp.InitModel(&Recording{}); err != nil {
+				return err
+			}
+			p.store = NewStorageStore(sp)
+		} else {
+			p.store = NewStorageStore(memstore.New())
+		}
+	}
+
+	if s, ok := prefab.ServerFromContext(ctx); ok && p.conn == nil {
+		_, _, endpoint, opts := s.GatewayArgs()
+		conn, err := grpc.NewClient(endpoint, opts...)
+		if err != nil {
+			return errors.WrapPrefix(err, "replay: failed to create client connection", 0)
+		}
+		p.conn = conn
+	}
+
+	logging.Warn(ctx, "replay: recording requests, do not enable in production")
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *ReplayPlugin) Shutdown(_ context.Context) error {
+	if c, ok := p.conn.(*grpc.ClientConn); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Replay executes a recorded request against the server. Credentials are not
+// recorded, so the request is sent with the given credentials, such as the
+// `authorization` and `grpcgateway-cookie` metadata of the caller.
+func (p *ReplayPlugin) Replay(ctx context.Context, id string, credentials metadata.MD) (*Result, error) {
+	rec, err := p.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if p.conn == nil {
+		return nil, errors.NewC("replay: no connection available to replay requests", codes.FailedPrecondition)
+	}
+
+	req, err := newMessage(rec.RequestType)
+	if err != nil {
+		return nil, err
+	}
+	if err := protojson.Unmarshal([]byte(rec.Request), req); err != nil {
+		return nil, errors.WrapPrefix(err, "replay: failed to decode request", 0)
+	}
+	resp, err := responseFor(rec)
+	if err != nil {
+		return nil, err
+	}
+
+	md := metadata.MD{}
+	for k, v := range rec.Metadata {
+		md.Append(k, v...)
+	}
+	for k, v := range credentials {
+		md.Set(k, v...)
+	}
+	md.Set(replayMetadataKey, rec.ID)
+
+	start := time.Now()
+	ierr := p.conn.Invoke(metadata.NewOutgoingContext(ctx, md), rec.Method, req, resp)
+	result := &Result{
+		Recording: rec,
+		Code:      status.Code(ierr).String(),
+		Duration:  time.Since(start),
+	}
+	if ierr != nil {
+		result.Error = errorMessage(ierr)
+		result.Matches = result.Code == rec.Code && result.Error == rec.Error
+		return result, nil
+	}
+
+	b, err := protojson.Marshal(redact(resp, p.redactedFields))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "replay: failed to encode response", 0)
+	}
+	result.Response = string(b)
+	if rec.Response != "" && result.Code == rec.Code {
+		recorded := resp.ProtoReflect().New().Interface()
+		if err := protojson.Unmarshal([]byte(rec.Response), recorded); err == nil {
+			result.Matches = proto.Equal(recorded, redact(resp, p.redactedFields))
+		}
+	}
+	return result, nil
+}
+
+func (p *ReplayPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	if p.store == nil || !p.shouldRecord(ctx, info.FullMethod) {
+		return resp, err
+	}
+	reqMsg, ok := req.(proto.Message)
+	if !ok {
+		return resp, err
+	}
+
+	rec := &Recording{
+		ID:          uuid.NewString(),
+		Method:      info.FullMethod,
+		RequestType: string(reqMsg.ProtoReflect().Descriptor().FullName()),
+		Code:        status.Code(err).String(),
+		Duration:    time.Since(start),
+		CreatedAt:   start,
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		rec.Metadata = sanitizeMetadata(md)
+	}
+	if b, merr := protojson.Marshal(redact(reqMsg, p.redactedFields)); merr == nil {
+		rec.Request = string(b)
+	}
+	if err != nil {
+		rec.Error = errorMessage(err)
+	} else if respMsg, ok := resp.(proto.Message); ok {
+		rec.ResponseType = string(respMsg.ProtoReflect().Descriptor().FullName())
+		if b, merr := protojson.Marshal(redact(respMsg, p.redactedFields)); merr == nil {
+			rec.Response = string(b)
+		}
+	}
+
+	if serr := p.store.Save(ctx, rec); serr != nil {
+		logging.Errorw(ctx, "replay: failed to save recording", "error", serr)
+	}
+	return resp, err
+}
+
+func (p *ReplayPlugin) shouldRecord(ctx context.Context, method string) bool {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(replayMetadataKey)) > 0 {
+		return false
+	}
+	if prefab.IsStreaming(ctx) {
+		// Replays are sent as unary calls.
+		return false
+	}
+	return p.filter == nil || p.filter(method)
+}
+
+func (p *ReplayPlugin) handle(r *http.Request) (any, error) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, p.path), "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && parts[0] == "":
+		return p.store.List(r.Context())
+	case r.Method == http.MethodGet && len(parts) == 1:
+		return p.store.Get(r.Context(), parts[0])
+	case r.Method == http.MethodPost && len(parts) == 2 && parts[1] == "replay":
+		return p.Replay(r.Context(), parts[0], requestCredentials(r))
+	}
+	return nil, errors.NewC("replay: unknown endpoint", codes.NotFound)
+}
+
+// requestCredentials returns the credentials of the HTTP request, as the
+// metadata the GRPC Gateway would send.
+func requestCredentials(r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	if v := r.Header.Get("Authorization"); v != "" {
+		md.Set("authorization", v)
+	}
+	if v := r.Header.Values("Cookie"); len(v) > 0 {
+		md.Set(runtime.MetadataPrefix+"cookie", v...)
+	}
+	return md
+}
+
+// responseFor returns an empty response message for the recording. The type is
+// taken from the recording if available, otherwise from the method descriptor.
+func responseFor(rec *Recording) (proto.Message, error) {
+	if rec.ResponseType != "" {
+		return newMessage(rec.ResponseType)
+	}
+	name := strings.ReplaceAll(strings.TrimPrefix(rec.Method, "/"), "/", ".")
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "replay: unknown method", 0).WithCode(codes.NotFound)
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, errors.NewC("replay: unknown method "+rec.Method, codes.NotFound)
+	}
+	return newMessage(string(md.Output().FullName()))
+}
+
+func newMessage(name string) (proto.Message, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "replay: unknown message type", 0).WithCode(codes.NotFound)
+	}
+	return mt.New().Interface(), nil
+}
+
+// errorMessage returns the message a client would see for err. Recorded errors
+// are local and replayed errors arrive over the wire, so both are normalized
+// via their gRPC status.
+func errorMessage(err error) string {
+	return status.Convert(err).Message()
+}
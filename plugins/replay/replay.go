@@ -0,0 +1,167 @@
+// Package replay provides a development plugin which records gRPC requests and
+// responses so they can be inspected and replayed against the current build of
+// the server. This is useful when reproducing a bug reported by a user: find
+// the failing request, fix the code, and replay the request to check the fix.
+//
+// Recordings are sanitized before they are stored. Metadata which looks like a
+// credential (authorization headers, cookies, CSRF tokens, etc.) is dropped and
+// payload fields with sensitive names (password, secret, token, etc.) are
+// redacted. When a request is replayed the Authorization header and cookies of
+// the replay request are used in place of the originals.
+//
+// Usage:
+//
+//	s := prefab.New(
+//	    prefab.WithPlugin(replay.Plugin(replay.WithDir("./recordings"))),
+//	)
+//
+// The admin endpoints are registered under `/debug/replay/`:
+//
+//	GET  /debug/replay/            lists recordings, most recent first
+//	GET  /debug/replay/{id}        returns a single recording
+//	POST /debug/replay/{id}/replay replays a recording and returns the result
+//
+// The endpoints expose request payloads and allow arbitrary requests to be
+// re-executed, so the plugin should only be registered in development or other
+// trusted environments.
+package replay
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	pfredact "github.com/dpup/prefab/redact"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// RedactedValue replaces the value of sensitive string fields in recorded
+// payloads.
+const RedactedValue = pfredact.Value
+
+// Fragments which mark a metadata key as sensitive. Matching keys are dropped
+// from recordings.
+var sensitiveMetadata = []string{
+	"authorization", "cookie", "csrf", "token", "secret", "password", "api-key", "apikey",
+}
+
+// Default fragments which mark a payload field as sensitive.
+var defaultSensitiveFields = []string{
+	"password", "secret", "token", "creds", "credential", "api_key", "apikey",
+}
+
+// Recording is a sanitized record of a single unary RPC.
+type Recording struct {
+	ID string `json:"id"`
+
+	// Full method name, e.g. `/prefab.MetaService/ClientConfig`.
+	Method string `json:"method"`
+
+	// Incoming metadata, with sensitive keys removed.
+	Metadata map[string][]string `json:"metadata,omitempty"`
+
+	// Fully qualified proto type and protojson encoding of the request.
+	RequestType string `json:"requestType"`
+	Request     string `json:"request"`
+
+	// Fully qualified proto type and protojson encoding of the response, empty
+	// if the RPC failed.
+	ResponseType string `json:"responseType,omitempty"`
+	Response     string `json:"response,omitempty"`
+
+	// Status code name and error message of the RPC.
+	Code  string `json:"code"`
+	Error string `json:"error,omitempty"`
+
+	Duration  time.Duration `json:"duration"`
+	CreatedAt time.Time     `json:"createdAt"`
+}
+
+// PK implements storage.Model.
+func (r Recording) PK() string {
+	return r.ID
+}
+
+// Result is returned when a recording is replayed.
+type Result struct {
+	Recording *Recording `json:"recording"`
+
+	// Protojson encoding of the new response, empty if the RPC failed.
+	Response string `json:"response,omitempty"`
+
+	// Status code name and error message of the replayed RPC.
+	Code  string `json:"code"`
+	Error string `json:"error,omitempty"`
+
+	// Whether the replayed response matched the recorded response, including
+	// the status code.
+	Matches bool `json:"matches"`
+
+	Duration time.Duration `json:"duration"`
+}
+
+// sanitizeMetadata returns a copy of md with sensitive keys removed.
+func sanitizeMetadata(md metadata.MD) map[string][]string {
+	out := map[string][]string{}
+	for k, v := range md {
+		if isSensitive(k, sensitiveMetadata) {
+			continue
+		}
+		out[k] = slices.Clone(v)
+	}
+	return out
+}
+
+// redact returns a copy of msg with sensitive fields, those marked with the
+// `prefab.redact` option or with a name matching one of the fragments,
+// redacted. String fields are replaced with RedactedValue, other sensitive
+// fields are cleared.
+func redact(msg proto.Message, fragments []string) proto.Message {
+	c := proto.Clone(msg)
+	redactMessage(c.ProtoReflect(), fragments)
+	return c
+}
+
+func redactMessage(m protoreflect.Message, fragments []string) {
+	var sensitive []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if pfredact.Field(fd) || isSensitive(string(fd.Name()), fragments) {
+			sensitive = append(sensitive, fd)
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := range l.Len() {
+				redactMessage(l.Get(i).Message(), fragments)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				redactMessage(mv.Message(), fragments)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			redactMessage(v.Message(), fragments)
+		}
+		return true
+	})
+	for _, fd := range sensitive {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(RedactedValue))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+func isSensitive(name string, fragments []string) bool {
+	name = strings.ToLower(name)
+	for _, f := range fragments {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package replay
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const loginMethod = "/prefab.auth.AuthService/Login"
+
+func TestRedact(t *testing.T) {
+	req := &auth.LoginRequest{
+		Provider: "password",
+		Creds:    map[string]string{"email": "a@b.com", "password": "hunter2"},
+	}
+	redacted := redact(req, defaultSensitiveFields).(*auth.LoginRequest)
+	assert.Equal(t, "password", redacted.GetProvider())
+	assert.Empty(t, redacted.GetCreds(), "creds should be cleared")
+	assert.Equal(t, "hunter2", req.GetCreds()["password"], "original should not be modified")
+
+	resp := redact(&auth.LoginResponse{Issued: true, Token: "abc"}, defaultSensitiveFields).(*auth.LoginResponse)
+	assert.True(t, resp.GetIssued())
+	assert.Equal(t, RedactedValue, resp.GetToken())
+}
+
+func TestSanitizeMetadata(t *testing.T) {
+	md := sanitizeMetadata(metadata.Pairs(
+		"authorization", "Bearer xyz",
+		"grpcgateway-cookie", "pf-id=xyz",
+		"pf-header-x-csrf-protection", "1",
+		"grpcgateway-user-agent", "curl",
+	))
+	assert.Equal(t, map[string][]string{"grpcgateway-user-agent": {"curl"}}, md)
+}
+
+func TestInterceptor_Records(t *testing.T) {
+	p := Plugin(WithDir(t.TempDir()))
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer xyz", "x-request-id", "123"))
+	info := &grpc.UnaryServerInfo{FullMethod: loginMethod}
+
+	_, err := p.interceptor(ctx, &auth.LoginRequest{Provider: "password"}, info, func(ctx context.Context, req any) (any, error) {
+		return &auth.LoginResponse{Issued: true, Token: "abc"}, nil
+	})
+	require.NoError(t, err)
+
+	_, err = p.interceptor(ctx, &auth.LoginRequest{Provider: "magiclink"}, info, func(ctx context.Context, req any) (any, error) {
+		return nil, errors.NewC("bad creds", codes.Unauthenticated)
+	})
+	require.Error(t, err)
+
+	recordings, err := p.store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+
+	failed, ok := recordings[0], recordings[0].Code == codes.Unauthenticated.String()
+	if !ok {
+		failed = recordings[1]
+	}
+	assert.Equal(t, loginMethod, failed.Method)
+	assert.Equal(t, "bad creds", failed.Error)
+	assert.Empty(t, failed.Response)
+	assert.NotContains(t, failed.Metadata, "authorization")
+	assert.Equal(t, []string{"123"}, failed.Metadata["x-request-id"])
+}
+
+func TestInterceptor_SkipsReplays(t *testing.T) {
+	p := Plugin(WithDir(t.TempDir()))
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(replayMetadataKey, "abc"))
+	_, err := p.interceptor(ctx, &auth.LoginRequest{}, &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req any) (any, error) {
+		return &auth.LoginResponse{}, nil
+	})
+	require.NoError(t, err)
+
+	recordings, err := p.store.List(t.Context())
+	require.NoError(t, err)
+	assert.Empty(t, recordings)
+}
+
+func TestReplay(t *testing.T) {
+	p := Plugin(WithDir(t.TempDir()))
+	conn := &fakeConn{resp: &auth.LoginResponse{Issued: true}}
+	p.conn = conn
+
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-request-id", "123"))
+	_, err := p.interceptor(ctx, &auth.LoginRequest{Provider: "fake"}, &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req any) (any, error) {
+		return &auth.LoginResponse{Issued: true}, nil
+	})
+	require.NoError(t, err)
+	recordings, err := p.store.List(t.Context())
+	require.NoError(t, err)
+	require.Len(t, recordings, 1)
+
+	// Credentials of the caller should be forwarded, and nothing else from the
+	// incoming context.
+	callerCtx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("authorization", "Bearer other"))
+	creds := metadata.Pairs("authorization", "Bearer me")
+	result, err := p.Replay(callerCtx, recordings[0].ID, creds)
+	require.NoError(t, err)
+	assert.True(t, result.Matches)
+	assert.Equal(t, codes.OK.String(), result.Code)
+	assert.Equal(t, loginMethod, conn.method)
+	assert.Equal(t, "fake", conn.req.(*auth.LoginRequest).GetProvider())
+	assert.Equal(t, []string{"Bearer me"}, conn.md.Get("authorization"))
+	assert.Equal(t, []string{"123"}, conn.md.Get("x-request-id"))
+	assert.Equal(t, []string{recordings[0].ID}, conn.md.Get(replayMetadataKey))
+
+	// A changed response should be reported as a mismatch.
+	conn.resp = &auth.LoginResponse{Issued: false}
+	result, err = p.Replay(callerCtx, recordings[0].ID, creds)
+	require.NoError(t, err)
+	assert.False(t, result.Matches)
+
+	_, err = p.Replay(callerCtx, "missing", creds)
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+}
+
+func TestHandler(t *testing.T) {
+	p := Plugin(WithDir(t.TempDir()))
+	_, err := p.interceptor(t.Context(), &auth.LoginRequest{Provider: "fake"}, &grpc.UnaryServerInfo{FullMethod: loginMethod}, func(ctx context.Context, req any) (any, error) {
+		return &auth.LoginResponse{}, nil
+	})
+	require.NoError(t, err)
+
+	resp, err := p.handle(httptest.NewRequest("GET", "/debug/replay/", nil))
+	require.NoError(t, err)
+	recordings := resp.([]*Recording)
+	require.Len(t, recordings, 1)
+
+	resp, err = p.handle(httptest.NewRequest("GET", "/debug/replay/"+recordings[0].ID, nil))
+	require.NoError(t, err)
+	assert.Equal(t, recordings[0].ID, resp.(*Recording).ID)
+
+	// Replays use the credentials of the HTTP request.
+	conn := &fakeConn{resp: &auth.LoginResponse{}}
+	p.conn = conn
+	req := httptest.NewRequest("POST", "/debug/replay/"+recordings[0].ID+"/replay", nil)
+	req.Header.Set("Authorization", "Bearer me")
+	req.AddCookie(&http.Cookie{Name: "pf-id", Value: "abc"})
+	_, err = p.handle(req)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer me"}, conn.md.Get("authorization"))
+	assert.Equal(t, []string{"pf-id=abc"}, conn.md.Get("grpcgateway-cookie"))
+
+	_, err = p.handle(httptest.NewRequest("DELETE", "/debug/replay/"+recordings[0].ID, nil))
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+
+	_, err = p.handle(httptest.NewRequest("GET", "/debug/replay/..%2fsecrets", nil))
+	assert.Equal(t, codes.NotFound, errors.Code(err))
+}
+
+type fakeConn struct {
+	resp   proto.Message
+	method string
+	req    any
+	md     metadata.MD
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args any, reply any, _ ...grpc.CallOption) error {
+	c.method = method
+	c.req = args
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	proto.Merge(reply.(proto.Message), c.resp)
+	return nil
+}
+
+func (c *fakeConn) NewStream(context.Context, *grpc.StreamDesc, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+	return nil, errors.NewC("not implemented", codes.Unimplemented)
+}
@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrNotFound is returned when a recording does not exist.
+var ErrNotFound = errors.NewC("replay: recording not found", codes.NotFound)
+
+// Store persists recordings.
+type Store interface {
+	// Save a new recording.
+	Save(ctx context.Context, r *Recording) error
+
+	// Get a recording by ID.
+	Get(ctx context.Context, id string) (*Recording, error)
+
+	// List all recordings, most recent first.
+	List(ctx context.Context) ([]*Recording, error)
+}
+
+// NewDiskStore returns a Store which writes each recording to a JSON file in
+// dir. The directory is created if it does not exist.
+func NewDiskStore(dir string) Store {
+	return &diskStore{dir: dir}
+}
+
+type diskStore struct {
+	dir string
+}
+
+func (s *diskStore) Save(_ context.Context, r *Recording) error {
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return errors.WrapPrefix(err, "replay: failed to create directory", 0)
+	}
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.WrapPrefix(err, "replay: failed to encode recording", 0)
+	}
+	if err := os.WriteFile(s.path(r.ID), b, 0o600); err != nil {
+		return errors.WrapPrefix(err, "replay: failed to write recording", 0)
+	}
+	return nil
+}
+
+func (s *diskStore) Get(_ context.Context, id string) (*Recording, error) {
+	// IDs are generated by the plugin, anything that could escape the directory
+	// can not exist.
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, errors.Mark(ErrNotFound, 0)
+	}
+	b, err := os.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, errors.Mark(ErrNotFound, 0)
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, "replay: failed to read recording", 0)
+	}
+	r := &Recording{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, errors.WrapPrefix(err, "replay: failed to decode recording", 0)
+	}
+	return r, nil
+}
+
+func (s *diskStore) List(ctx context.Context) ([]*Recording, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.WrapPrefix(err, "replay: failed to read directory", 0)
+	}
+	var out []*Recording
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		r, err := s.Get(ctx, strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	sortRecordings(out)
+	return out, nil
+}
+
+func (s *diskStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// NewStorageStore returns a Store backed by a storage plugin implementation.
+func NewStorageStore(store storage.Store) Store {
+	return &storageStore{store: store}
+}
+
+type storageStore struct {
+	store storage.Store
+}
+
+func (s *storageStore) Save(ctx context.Context, r *Recording) error {
+	return s.store.Create(ctx, r)
+}
+
+func (s *storageStore) Get(ctx context.Context, id string) (*Recording, error) {
+	r := &Recording{}
+	if err := s.store.Read(ctx, id, r); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, errors.Mark(ErrNotFound, 0)
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *storageStore) List(ctx context.Context) ([]*Recording, error) {
+	var recordings []Recording
+	if err := s.store.List(ctx, &recordings, Recording{}); err != nil {
+		return nil, err
+	}
+	out := make([]*Recording, len(recordings))
+	for i := range recordings {
+		out[i] = &recordings[i]
+	}
+	sortRecordings(out)
+	return out, nil
+}
+
+func sortRecordings(r []*Recording) {
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].CreatedAt.After(r[j].CreatedAt)
+	})
+}
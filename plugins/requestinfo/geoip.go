@@ -0,0 +1,89 @@
+package requestinfo
+
+import (
+	"net"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/oschwald/geoip2-golang"
+)
+
+// GeoInfo is the result of resolving an IP address.
+type GeoInfo struct {
+	Country string
+	ASN     uint
+	ASOrg   string
+}
+
+// GeoResolver resolves an IP address to a location and network.
+type GeoResolver interface {
+	Resolve(ip net.IP) (GeoInfo, error)
+}
+
+// GeoResolverFunc adapts a function to the GeoResolver interface.
+type GeoResolverFunc func(ip net.IP) (GeoInfo, error)
+
+// Resolve implements GeoResolver.
+func (f GeoResolverFunc) Resolve(ip net.IP) (GeoInfo, error) {
+	return f(ip)
+}
+
+// MaxMindResolver resolves IPs using MaxMind GeoIP2 or GeoLite2 databases.
+type MaxMindResolver struct {
+	country *geoip2.Reader
+	asn     *geoip2.Reader
+}
+
+// NewMaxMindResolver opens the given MaxMind databases. countryDB should be a
+// Country or City database and asnDB an ASN database, either may be empty.
+func NewMaxMindResolver(countryDB, asnDB string) (*MaxMindResolver, error) {
+	r := &MaxMindResolver{}
+	if countryDB != "" {
+		db, err := geoip2.Open(countryDB)
+		if err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		r.country = db
+	}
+	if asnDB != "" {
+		db, err := geoip2.Open(asnDB)
+		if err != nil {
+			r.Close()
+			return nil, errors.Wrap(err, 0)
+		}
+		r.asn = db
+	}
+	return r, nil
+}
+
+// Resolve implements GeoResolver.
+func (r *MaxMindResolver) Resolve(ip net.IP) (GeoInfo, error) {
+	var info GeoInfo
+	if r.country != nil {
+		c, err := r.country.Country(ip)
+		if err != nil {
+			return info, errors.Wrap(err, 0)
+		}
+		info.Country = c.Country.IsoCode
+	}
+	if r.asn != nil {
+		a, err := r.asn.ASN(ip)
+		if err != nil {
+			return info, errors.Wrap(err, 0)
+		}
+		info.ASN = a.AutonomousSystemNumber
+		info.ASOrg = a.AutonomousSystemOrganization
+	}
+	return info, nil
+}
+
+// Close releases the underlying databases.
+func (r *MaxMindResolver) Close() error {
+	var errs []error
+	if r.country != nil {
+		errs = append(errs, r.country.Close())
+	}
+	if r.asn != nil {
+		errs = append(errs, r.asn.Close())
+	}
+	return errors.Join(errs...)
+}
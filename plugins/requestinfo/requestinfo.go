@@ -0,0 +1,152 @@
+// Package requestinfo provides a plugin which enriches requests with
+// information about the client: the country and network the client IP resolves
+// to, and the type of device parsed from the User-Agent.
+//
+// The results are available via serverutil.RequestInfoFromContext, are attached
+// to auth login events and authz audit decisions, and can be used from authz
+// ConditionalRole predicates:
+//
+//	authz.ConditionalRole(roleDomestic, func(ctx context.Context, _ auth.Identity, _ *Doc, _ authz.Scope) (bool, error) {
+//	    info, _ := serverutil.RequestInfoFromContext(ctx)
+//	    return info.Country == "US", nil
+//	})
+//
+// IP resolution uses MaxMind GeoIP2/GeoLite2 databases, configured via
+// `requestInfo.countryDB` and `requestInfo.asnDB`, or a custom GeoResolver.
+package requestinfo
+
+import (
+	"context"
+	"net"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+)
+
+// PluginName is the name of the request info plugin.
+const PluginName = "requestinfo"
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "requestInfo.countryDB",
+			Description: "Path to a MaxMind Country or City database used to resolve client IPs",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "requestInfo.asnDB",
+			Description: "Path to a MaxMind ASN database used to resolve client IPs",
+			Type:        "string",
+		},
+	)
+}
+
+// RequestInfoOption customizes the request info plugin.
+type RequestInfoOption func(*RequestInfoPlugin)
+
+// WithGeoResolver configures a custom resolver for client IPs, replacing the
+// MaxMind databases from config.
+func WithGeoResolver(r GeoResolver) RequestInfoOption {
+	return func(p *RequestInfoPlugin) {
+		p.resolver = r
+	}
+}
+
+// WithMaxMindDBs configures the paths to MaxMind databases, either may be
+// empty.
+//
+// Config keys: `requestInfo.countryDB`, `requestInfo.asnDB`.
+func WithMaxMindDBs(countryDB, asnDB string) RequestInfoOption {
+	return func(p *RequestInfoPlugin) {
+		p.countryDB = countryDB
+		p.asnDB = asnDB
+	}
+}
+
+// WithDeviceParser replaces the default User-Agent parser.
+func WithDeviceParser(fn func(userAgent string) serverutil.Device) RequestInfoOption {
+	return func(p *RequestInfoPlugin) {
+		p.parseDevice = fn
+	}
+}
+
+// Plugin returns a new RequestInfoPlugin.
+func Plugin(opts ...RequestInfoOption) *RequestInfoPlugin {
+	p := &RequestInfoPlugin{
+		countryDB:   prefab.ConfigString("requestInfo.countryDB"),
+		asnDB:       prefab.ConfigString("requestInfo.asnDB"),
+		parseDevice: ParseUserAgent,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// RequestInfoPlugin adds serverutil.RequestInfo to the request context.
+type RequestInfoPlugin struct {
+	resolver    GeoResolver
+	countryDB   string
+	asnDB       string
+	parseDevice func(string) serverutil.Device
+}
+
+// From prefab.Plugin.
+func (p *RequestInfoPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionProvider.
+func (p *RequestInfoPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.enrich),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *RequestInfoPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if p.resolver == nil && (p.countryDB != "" || p.asnDB != "") {
+		resolver, err := NewMaxMindResolver(p.countryDB, p.asnDB)
+		if err != nil {
+			return err
+		}
+		p.resolver = resolver
+		logging.Info(ctx, "requestinfo: loaded MaxMind databases")
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *RequestInfoPlugin) Shutdown(_ context.Context) error {
+	if c, ok := p.resolver.(*MaxMindResolver); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Lookup builds the request info for the current request.
+func (p *RequestInfoPlugin) Lookup(ctx context.Context) serverutil.RequestInfo {
+	info := serverutil.RequestInfo{
+		IP:        serverutil.ClientIP(ctx),
+		UserAgent: serverutil.UserAgent(ctx),
+	}
+	if info.UserAgent != "" && p.parseDevice != nil {
+		info.Device = p.parseDevice(info.UserAgent)
+	}
+	if ip := net.ParseIP(info.IP); ip != nil && p.resolver != nil {
+		geo, err := p.resolver.Resolve(ip)
+		if err != nil {
+			logging.Warnw(ctx, "requestinfo: failed to resolve ip", "ip", info.IP, "error", err)
+		} else {
+			info.Country = geo.Country
+			info.ASN = geo.ASN
+			info.ASOrg = geo.ASOrg
+		}
+	}
+	return info
+}
+
+func (p *RequestInfoPlugin) enrich(ctx context.Context) context.Context {
+	return serverutil.WithRequestInfo(ctx, p.Lookup(ctx))
+}
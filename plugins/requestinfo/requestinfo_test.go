@@ -0,0 +1,93 @@
+package requestinfo
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		name string
+		ua   string
+		want serverutil.Device
+	}{
+		{"empty", "", serverutil.Device{}},
+		{
+			"mac chrome",
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			serverutil.Device{Type: DeviceDesktop, OS: "macOS", Browser: "Chrome"},
+		},
+		{
+			"windows edge",
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.0.0",
+			serverutil.Device{Type: DeviceDesktop, OS: "Windows", Browser: "Edge"},
+		},
+		{
+			"iphone safari",
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+			serverutil.Device{Type: DeviceMobile, OS: "iOS", Browser: "Safari"},
+		},
+		{
+			"android tablet",
+			"Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+			serverutil.Device{Type: DeviceTablet, OS: "Android", Browser: "Chrome"},
+		},
+		{
+			"googlebot",
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			serverutil.Device{Type: DeviceBot},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, ParseUserAgent(c.ua))
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	resolver := GeoResolverFunc(func(ip net.IP) (GeoInfo, error) {
+		if ip.String() == "203.0.113.7" {
+			return GeoInfo{Country: "NZ", ASN: 64500, ASOrg: "Example Net"}, nil
+		}
+		return GeoInfo{}, errors.New("not found")
+	})
+	p := Plugin(WithGeoResolver(resolver))
+
+	t.Run("Resolved", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+			"x-forwarded-for", "203.0.113.7",
+			"grpcgateway-user-agent", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) Mobile Safari/604.1",
+		))
+		ctx = p.enrich(ctx)
+
+		info, ok := serverutil.RequestInfoFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, "203.0.113.7", info.IP)
+		assert.Equal(t, "NZ", info.Country)
+		assert.Equal(t, uint(64500), info.ASN)
+		assert.Equal(t, "Example Net", info.ASOrg)
+		assert.Equal(t, DeviceMobile, info.Device.Type)
+	})
+
+	t.Run("ResolverError", func(t *testing.T) {
+		ctx := logging.EnsureLogger(t.Context())
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "198.51.100.2"))
+
+		info := p.Lookup(ctx)
+		assert.Equal(t, "198.51.100.2", info.IP)
+		assert.Empty(t, info.Country)
+	})
+}
+
+func TestNewMaxMindResolver_MissingFile(t *testing.T) {
+	_, err := NewMaxMindResolver("/does/not/exist.mmdb", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package requestinfo
+
+import (
+	"strings"
+
+	"github.com/dpup/prefab/serverutil"
+)
+
+// Device types returned by ParseUserAgent.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Ordered lists of substring matches, the first match wins.
+var (
+	uaOperatingSystems = []struct{ match, name string }{
+		{"windows", "Windows"},
+		{"iphone", "iOS"},
+		{"ipad", "iOS"},
+		{"android", "Android"},
+		{"cros", "ChromeOS"},
+		{"mac os x", "macOS"},
+		{"macintosh", "macOS"},
+		{"linux", "Linux"},
+	}
+	uaBrowsers = []struct{ match, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"fxios/", "Firefox"},
+		{"crios/", "Chrome"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"curl/", "curl"},
+		{"grpc-", "gRPC"},
+	}
+	uaBots = []string{"bot", "crawler", "spider", "slurp", "headless"}
+)
+
+// ParseUserAgent infers device information from a User-Agent string using
+// simple heuristics. It covers common browsers and operating systems, use
+// WithDeviceParser to plug in a more complete parser.
+func ParseUserAgent(ua string) serverutil.Device {
+	var d serverutil.Device
+	if ua == "" {
+		return d
+	}
+	lower := strings.ToLower(ua)
+
+	for _, os := range uaOperatingSystems {
+		if strings.Contains(lower, os.match) {
+			d.OS = os.name
+			break
+		}
+	}
+	for _, b := range uaBrowsers {
+		if strings.Contains(lower, b.match) {
+			d.Browser = b.name
+			break
+		}
+	}
+
+	switch {
+	case containsAny(lower, uaBots):
+		d.Type = DeviceBot
+	case strings.Contains(lower, "ipad") || strings.Contains(lower, "tablet") ||
+		(strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		d.Type = DeviceTablet
+	case strings.Contains(lower, "mobile") || strings.Contains(lower, "iphone"):
+		d.Type = DeviceMobile
+	case d.OS != "" && d.Browser != "":
+		d.Type = DeviceDesktop
+	}
+	return d
+}
+
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,279 @@
+// Package elastic provides a search.Engine backed by an Elasticsearch or
+// OpenSearch cluster, using the REST API directly so no client library is
+// required.
+//
+// Each search index maps to a cluster index with a configurable prefix.
+// Document fields are stored as the document source, and queries match every
+// term across all fields.
+//
+// Example:
+//
+//	engine := elastic.New("https://search.internal:9200",
+//		elastic.WithAPIKey(os.Getenv("ELASTIC_API_KEY")),
+//		elastic.WithIndexPrefix("myapp_"),
+//	)
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+	"google.golang.org/grpc/codes"
+)
+
+// Option is a functional option for configuring the engine.
+type Option func(*engine)
+
+// WithHTTPClient overrides the client used to talk to the cluster. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(c *http.Client) Option {
+	return func(e *engine) {
+		e.client = c
+	}
+}
+
+// WithIndexPrefix sets the prefix applied to index names. Default is
+// "prefab_".
+func WithIndexPrefix(prefix string) Option {
+	return func(e *engine) {
+		e.prefix = prefix
+	}
+}
+
+// WithBasicAuth authenticates requests with a username and password.
+func WithBasicAuth(username, password string) Option {
+	return func(e *engine) {
+		e.authorize = func(r *http.Request) { r.SetBasicAuth(username, password) }
+	}
+}
+
+// WithAPIKey authenticates requests with an Elasticsearch API key.
+func WithAPIKey(key string) Option {
+	return func(e *engine) {
+		e.authorize = func(r *http.Request) { r.Header.Set("Authorization", "ApiKey "+key) }
+	}
+}
+
+// WithRefresh makes writes wait until they're visible to search. Useful for
+// tests and low-volume indexes; it reduces throughput on busy clusters.
+func WithRefresh(enabled bool) Option {
+	return func(e *engine) {
+		e.refresh = enabled
+	}
+}
+
+// New returns an engine which talks to the cluster at baseURL.
+func New(baseURL string, opts ...Option) search.Engine {
+	e := &engine{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  http.DefaultClient,
+		prefix:  "prefab_",
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+type engine struct {
+	baseURL   string
+	client    *http.Client
+	prefix    string
+	refresh   bool
+	authorize func(*http.Request)
+}
+
+func (e *engine) Index(ctx context.Context, index string, docs ...search.Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return errors.Mark(search.ErrMissingID, 0)
+		}
+		if err := enc.Encode(bulkAction{Index: &bulkTarget{Index: e.indexName(index), ID: doc.ID}}); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if err := enc.Encode(doc.Fields); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return e.bulk(ctx, &buf)
+}
+
+func (e *engine) Delete(ctx context.Context, index string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, id := range ids {
+		if err := enc.Encode(bulkAction{Delete: &bulkTarget{Index: e.indexName(index), ID: id}}); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return e.bulk(ctx, &buf)
+}
+
+func (e *engine) Query(ctx context.Context, index string, q search.Query) (*search.Results, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"track_total_hits": true,
+		"query": map[string]any{
+			"multi_match": map[string]any{
+				"query":    q.Text,
+				"fields":   []string{"*"},
+				"type":     "cross_fields",
+				"operator": "and",
+			},
+		},
+	}
+
+	var resp searchResponse
+	status, err := e.do(ctx, http.MethodPost, "/"+url.PathEscape(e.indexName(index))+"/_search", body, &resp)
+	if status == http.StatusNotFound {
+		// Nothing has been indexed yet.
+		return &search.Results{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results := &search.Results{Total: resp.Hits.Total.Value}
+	for _, h := range resp.Hits.Hits {
+		results.Hits = append(results.Hits, search.Hit{ID: h.ID, Score: h.Score, Fields: h.Source})
+	}
+	return results, nil
+}
+
+// bulk sends a newline delimited bulk request, returning the first item error.
+// Deletes of missing documents aren't considered errors.
+func (e *engine) bulk(ctx context.Context, body io.Reader) error {
+	path := "/_bulk"
+	if e.refresh {
+		path += "?refresh=wait_for"
+	}
+	var resp bulkResponse
+	if _, err := e.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return err
+	}
+	if !resp.Errors {
+		return nil
+	}
+	for _, item := range resp.Items {
+		for op, r := range item {
+			if r.Error == nil || (op == "delete" && r.Status == http.StatusNotFound) {
+				continue
+			}
+			return errors.Codef(codes.Internal, "elastic: failed to %s document '%s': %s", op, r.ID, r.Error.Reason)
+		}
+	}
+	return nil
+}
+
+// do sends a request to the cluster and decodes the response into out. Bodies
+// which aren't an io.Reader are JSON encoded.
+func (e *engine) do(ctx context.Context, method, path string, body any, out any) (int, error) {
+	var r io.Reader
+	contentType := "application/json"
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		r = b
+		contentType = "application/x-ndjson"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return 0, errors.Wrap(err, 0)
+		}
+		r = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.baseURL+path, r)
+	if err != nil {
+		return 0, errors.Wrap(err, 0)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if e.authorize != nil {
+		e.authorize(req)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, errors.WithCode(errors.WrapPrefix(err, "elastic: request failed", 0), codes.Unavailable)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var er errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&er)
+		code := codes.Internal
+		if resp.StatusCode >= 500 {
+			code = codes.Unavailable
+		}
+		return resp.StatusCode, errors.Codef(code, "elastic: %s %s returned %d: %s", method, path, resp.StatusCode, er.Error.Reason)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return resp.StatusCode, errors.WrapPrefix(err, "elastic: invalid response", 0)
+	}
+	return resp.StatusCode, nil
+}
+
+func (e *engine) indexName(index string) string {
+	return strings.ToLower(e.prefix + index)
+}
+
+type bulkTarget struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type bulkAction struct {
+	Index  *bulkTarget `json:"index,omitempty"`
+	Delete *bulkTarget `json:"delete,omitempty"`
+}
+
+type errorDetail struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+type errorResponse struct {
+	Error errorDetail `json:"error"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string       `json:"_id"`
+		Status int          `json:"status"`
+		Error  *errorDetail `json:"error"`
+	} `json:"items"`
+}
+
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			ID     string            `json:"_id"`
+			Score  float64           `json:"_score"`
+			Source map[string]string `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
@@ -0,0 +1,136 @@
+package elastic
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type request struct {
+	method, path, auth, contentType, body string
+}
+
+func newTestServer(t *testing.T, status int, response string) (*httptest.Server, *[]request) {
+	t.Helper()
+	var reqs []request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		reqs = append(reqs, request{
+			method:      r.Method,
+			path:        r.URL.RequestURI(),
+			auth:        r.Header.Get("Authorization"),
+			contentType: r.Header.Get("Content-Type"),
+			body:        string(body),
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &reqs
+}
+
+func TestIndex(t *testing.T) {
+	srv, reqs := newTestServer(t, http.StatusOK, `{"errors":false,"items":[]}`)
+	e := New(srv.URL, WithAPIKey("secret"), WithRefresh(true), WithIndexPrefix("app_"))
+
+	err := e.Index(t.Context(), "Articles",
+		search.Document{ID: "1", Fields: map[string]string{"title": "Hello"}},
+		search.Document{ID: "2", Fields: map[string]string{"title": "World"}},
+	)
+	require.NoError(t, err)
+	require.Len(t, *reqs, 1)
+
+	req := (*reqs)[0]
+	assert.Equal(t, http.MethodPost, req.method)
+	assert.Equal(t, "/_bulk?refresh=wait_for", req.path)
+	assert.Equal(t, "ApiKey secret", req.auth)
+	assert.Equal(t, "application/x-ndjson", req.contentType)
+	assert.Equal(t, strings.Join([]string{
+		`{"index":{"_index":"app_articles","_id":"1"}}`,
+		`{"title":"Hello"}`,
+		`{"index":{"_index":"app_articles","_id":"2"}}`,
+		`{"title":"World"}`,
+		``,
+	}, "\n"), req.body)
+}
+
+func TestIndex_ItemError(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusOK, `{"errors":true,"items":[
+		{"index":{"_id":"1","status":201}},
+		{"index":{"_id":"2","status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}}
+	]}`)
+	e := New(srv.URL)
+
+	err := e.Index(t.Context(), "a", search.Document{ID: "1"}, search.Document{ID: "2"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to index document '2': bad field")
+
+	err = e.Index(t.Context(), "a", search.Document{})
+	assert.True(t, errors.Is(err, search.ErrMissingID))
+}
+
+func TestDelete(t *testing.T) {
+	srv, reqs := newTestServer(t, http.StatusOK, `{"errors":true,"items":[
+		{"delete":{"_id":"1","status":200}},
+		{"delete":{"_id":"2","status":404,"error":{"type":"not_found","reason":"missing"}}}
+	]}`)
+	e := New(srv.URL, WithBasicAuth("user", "pass"))
+
+	require.NoError(t, e.Delete(t.Context(), "a", "1", "2"), "missing documents are ignored")
+	require.Len(t, *reqs, 1)
+	assert.Equal(t, "/_bulk", (*reqs)[0].path)
+	assert.True(t, strings.HasPrefix((*reqs)[0].auth, "Basic "))
+	assert.Contains(t, (*reqs)[0].body, `{"delete":{"_index":"prefab_a","_id":"2"}}`)
+}
+
+func TestQuery(t *testing.T) {
+	srv, reqs := newTestServer(t, http.StatusOK, `{"hits":{"total":{"value":7},"hits":[
+		{"_id":"3","_score":2.5,"_source":{"title":"Hello world"}},
+		{"_id":"1","_score":1.1,"_source":{"title":"Hello"}}
+	]}}`)
+	e := New(srv.URL + "/")
+
+	r, err := e.Query(t.Context(), "articles", search.Query{Text: "hello", Limit: 2, Offset: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 7, r.Total)
+	assert.Equal(t, []search.Hit{
+		{ID: "3", Score: 2.5, Fields: map[string]string{"title": "Hello world"}},
+		{ID: "1", Score: 1.1, Fields: map[string]string{"title": "Hello"}},
+	}, r.Hits)
+
+	req := (*reqs)[0]
+	assert.Equal(t, "/prefab_articles/_search", req.path)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal([]byte(req.body), &body))
+	assert.Equal(t, float64(4), body["from"])
+	assert.Equal(t, float64(2), body["size"])
+	match := body["query"].(map[string]any)["multi_match"].(map[string]any)
+	assert.Equal(t, "hello", match["query"])
+	assert.Equal(t, "and", match["operator"])
+}
+
+func TestQuery_Errors(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusNotFound, `{"error":{"type":"index_not_found_exception","reason":"no such index"}}`)
+	r, err := New(srv.URL).Query(t.Context(), "missing", search.Query{Text: "x"})
+	require.NoError(t, err, "missing indexes have no results")
+	assert.Equal(t, 0, r.Total)
+
+	srv, _ = newTestServer(t, http.StatusServiceUnavailable, `{"error":{"type":"cluster_block_exception","reason":"blocked"}}`)
+	_, err = New(srv.URL).Query(t.Context(), "a", search.Query{Text: "x"})
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, errors.Code(err))
+	assert.Contains(t, err.Error(), "blocked")
+
+	_, err = New(srv.URL).Query(t.Context(), "a", search.Query{})
+	assert.True(t, errors.Is(err, search.ErrEmptyQuery))
+}
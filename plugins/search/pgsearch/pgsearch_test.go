@@ -0,0 +1,93 @@
+package pgsearch
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T) (search.Engine, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	e, err := NewWithDB(db, WithAutoCreateTables(false))
+	require.NoError(t, err)
+	return e, mock
+}
+
+func TestNewWithDB_CreatesTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "docs"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX IF NOT EXISTS "docs_document_idx" ON "docs" USING GIN`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	_, err = NewWithDB(db, WithTableName("docs"))
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIndex(t *testing.T) {
+	e, mock := newTestEngine(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "prefab_search" .* to_tsvector\(\$4::regconfig, \$5\)\) ON CONFLICT`).
+		WithArgs("articles", "1", []byte(`{"body":"World","title":"Hello"}`), "english", "World\nHello").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := e.Index(t.Context(), "articles", search.Document{
+		ID:     "1",
+		Fields: map[string]string{"title": "Hello", "body": "World"},
+	})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	err = e.Index(t.Context(), "articles", search.Document{})
+	assert.True(t, errors.Is(err, search.ErrMissingID))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDelete(t *testing.T) {
+	e, mock := newTestEngine(t)
+
+	mock.ExpectExec(`DELETE FROM "prefab_search" WHERE idx = \$1 AND id = ANY\(\$2\)`).
+		WithArgs("articles", pq.Array([]string{"1", "2"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	require.NoError(t, e.Delete(t.Context(), "articles", "1", "2"))
+	require.NoError(t, e.Delete(t.Context(), "articles"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuery(t *testing.T) {
+	e, mock := newTestEngine(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM "prefab_search"`).
+		WithArgs("articles", "english", "hello world").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery(`SELECT id, fields, ts_rank\(document, query\) AS score .* LIMIT \$4 OFFSET \$5`).
+		WithArgs("articles", "english", "hello world", search.DefaultLimit, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "fields", "score"}).
+			AddRow("2", []byte(`{"title":"Hello world"}`), 0.5).
+			AddRow("1", []byte(`{"title":"Hello there, world"}`), 0.25))
+
+	r, err := e.Query(t.Context(), "articles", search.Query{Text: " hello world "})
+	require.NoError(t, err)
+	assert.Equal(t, 3, r.Total)
+	require.Len(t, r.Hits, 2)
+	assert.Equal(t, search.Hit{ID: "2", Score: 0.5, Fields: map[string]string{"title": "Hello world"}}, r.Hits[0])
+	assert.Equal(t, "1", r.Hits[1].ID)
+
+	_, err = e.Query(t.Context(), "articles", search.Query{})
+	assert.True(t, errors.Is(err, search.ErrEmptyQuery))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
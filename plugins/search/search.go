@@ -0,0 +1,258 @@
+// Package search provides full-text search with pluggable backends.
+//
+// Documents are grouped into named indexes, and consist of an ID and a set of
+// text fields. Implementations are provided in sub-packages: sqlitesearch uses
+// SQLite FTS5, pgsearch uses PostgreSQL full-text search, and elastic talks to
+// an Elasticsearch or OpenSearch cluster.
+//
+// Models stored via the storage plugin can opt into automatic indexing by
+// implementing Indexable. They are indexed on Create, Update and Upsert, and
+// removed from the index on Delete.
+//
+// Example:
+//
+//	server := prefab.New(
+//		prefab.WithPlugin(storage.Plugin(store)),
+//		prefab.WithPlugin(search.Plugin(sqlitesearch.New("file:search.db"))),
+//	)
+//
+//	func (a *Article) SearchDocument() search.Document {
+//		return search.Document{
+//			ID:     a.ID,
+//			Fields: map[string]string{"title": a.Title, "body": a.Body},
+//		}
+//	}
+//
+//	results, err := search.FromContext(ctx).Query(ctx, "articles", search.Query{Text: "prefab"})
+package search
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/storage"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// PluginName identifies this plugin.
+	PluginName = "search"
+
+	// DefaultLimit is the number of hits returned when Query.Limit is zero.
+	DefaultLimit = 20
+)
+
+var (
+	// Returned when a query has no search text.
+	ErrEmptyQuery = errors.NewC("search: query text is required", codes.InvalidArgument)
+
+	// Returned when a document has no ID.
+	ErrMissingID = errors.NewC("search: document ID is required", codes.InvalidArgument)
+)
+
+// Document is a unit of indexed content.
+type Document struct {
+	// ID uniquely identifies the document within its index.
+	ID string
+
+	// Fields holds the searchable text, keyed by field name. Fields are returned
+	// with search hits.
+	Fields map[string]string
+}
+
+// Text returns the document's fields concatenated in a stable order, for
+// engines which index a single body of text.
+func (d Document) Text() string {
+	keys := make([]string, 0, len(d.Fields))
+	for k := range d.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	values := make([]string, len(keys))
+	for i, k := range keys {
+		values[i] = d.Fields[k]
+	}
+	return strings.Join(values, "\n")
+}
+
+// Query describes a search.
+type Query struct {
+	// Text is the search text. Terms are matched against all fields, and
+	// documents must match every term.
+	Text string
+
+	// Limit is the maximum number of hits to return, defaults to DefaultLimit.
+	Limit int
+
+	// Offset is the number of hits to skip, for pagination.
+	Offset int
+}
+
+// Results of a search.
+type Results struct {
+	// Hits for the requested page, ordered by descending relevance.
+	Hits []Hit
+
+	// Total number of documents which matched the query.
+	Total int
+}
+
+// Hit is a document which matched a query.
+type Hit struct {
+	ID     string
+	Score  float64
+	Fields map[string]string
+}
+
+// Engine indexes documents and queries them.
+type Engine interface {
+	// Index adds documents to an index, replacing any with the same ID.
+	Index(ctx context.Context, index string, docs ...Document) error
+
+	// Delete removes documents from an index. Missing IDs are ignored.
+	Delete(ctx context.Context, index string, ids ...string) error
+
+	// Query searches an index.
+	Query(ctx context.Context, index string, q Query) (*Results, error)
+}
+
+// Indexable is implemented by models which should be indexed automatically
+// when they're written via the storage plugin. Documents are added to an index
+// named after the model, see storage.Name.
+type Indexable interface {
+	storage.Model
+
+	// SearchDocument returns the document to index for the model. The ID should
+	// be the model's primary key.
+	SearchDocument() Document
+}
+
+// Normalize applies defaults to a query and validates it. Engines should call
+// it before executing a query.
+func (q Query) Normalize() (Query, error) {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return q, errors.Mark(ErrEmptyQuery, 0)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	return q, nil
+}
+
+// Option configures the search plugin.
+type Option func(*SearchPlugin)
+
+// WithAutoIndex controls whether Indexable models written via the storage
+// plugin are indexed automatically. Enabled by default.
+func WithAutoIndex(enabled bool) Option {
+	return func(p *SearchPlugin) {
+		p.autoIndex = enabled
+	}
+}
+
+// Plugin registers a search engine with a Prefab server for use by other
+// plugins. The engine can be retrieved from the request context using the
+// FromContext function.
+func Plugin(e Engine, opts ...Option) *SearchPlugin {
+	p := &SearchPlugin{
+		Engine:    e,
+		autoIndex: true,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// SearchPlugin provides access to a search engine.
+type SearchPlugin struct {
+	Engine
+	autoIndex bool
+}
+
+// From prefab.Plugin.
+func (p *SearchPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *SearchPlugin) OptDeps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.InitializablePlugin.
+func (p *SearchPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if !p.autoIndex {
+		return nil
+	}
+	if sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin); ok && sp != nil {
+		sp.AddHook(p.storageHook)
+	}
+	return nil
+}
+
+// From prefab.OptionProvider.
+func (p *SearchPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// storageHook keeps indexes in sync with Indexable models.
+func (p *SearchPlugin) storageHook(ctx context.Context, op storage.Op, models ...storage.Model) error {
+	byIndex := map[string][]Indexable{}
+	var order []string
+	for _, m := range models {
+		im, ok := m.(Indexable)
+		if !ok {
+			continue
+		}
+		index := storage.Name(m)
+		if _, seen := byIndex[index]; !seen {
+			order = append(order, index)
+		}
+		byIndex[index] = append(byIndex[index], im)
+	}
+
+	for _, index := range order {
+		if op == storage.OpDelete {
+			ids := make([]string, len(byIndex[index]))
+			for i, m := range byIndex[index] {
+				ids[i] = m.PK()
+			}
+			if err := p.Delete(ctx, index, ids...); err != nil {
+				return err
+			}
+			continue
+		}
+		docs := make([]Document, len(byIndex[index]))
+		for i, m := range byIndex[index] {
+			docs[i] = m.SearchDocument()
+		}
+		if err := p.Index(ctx, index, docs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *SearchPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, searchKey{}, p)
+}
+
+// FromContext retrieves the search engine from a context.
+func FromContext(ctx context.Context) Engine {
+	if p, ok := ctx.Value(searchKey{}).(*SearchPlugin); ok {
+		return p.Engine
+	}
+	return nil
+}
+
+type searchKey struct{}
@@ -0,0 +1,94 @@
+package search_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+	"github.com/dpup/prefab/plugins/search/sqlitesearch"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type article struct {
+	ID    string
+	Title string
+}
+
+func (a article) PK() string { return a.ID }
+
+func (a article) SearchDocument() search.Document {
+	return search.Document{ID: a.ID, Fields: map[string]string{"title": a.Title}}
+}
+
+type comment struct {
+	ID   string
+	Text string
+}
+
+func (c comment) PK() string { return c.ID }
+
+func setup(t *testing.T, opts ...search.Option) (*storage.StoragePlugin, *search.SearchPlugin) {
+	t.Helper()
+	sp := storage.Plugin(memstore.New()).(*storage.StoragePlugin)
+	engine := sqlitesearch.New("file:" + filepath.Join(t.TempDir(), "search.db"))
+	p := search.Plugin(engine, opts...)
+
+	prefabtest.Init(t, p, sp)
+	return sp, p
+}
+
+func TestAutoIndex(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	store, p := setup(t)
+	index := storage.Name(article{})
+
+	require.NoError(t, store.Create(ctx, article{ID: "1", Title: "Prefab plugins"}, comment{ID: "c1", Text: "plugins"}))
+	require.NoError(t, store.Upsert(ctx, article{ID: "2", Title: "Prefab storage"}))
+
+	r, err := p.Query(ctx, index, search.Query{Text: "prefab"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, r.Total)
+
+	r, err = p.Query(ctx, storage.Name(comment{}), search.Query{Text: "plugins"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total, "models must opt into indexing")
+
+	require.NoError(t, store.Update(ctx, article{ID: "1", Title: "Renamed"}))
+	r, err = p.Query(ctx, index, search.Query{Text: "plugins"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total, "updates are reindexed")
+
+	require.NoError(t, store.Delete(ctx, article{ID: "2"}))
+	r, err = p.Query(ctx, index, search.Query{Text: "prefab"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total, "deletes are removed from the index")
+}
+
+func TestAutoIndex_Disabled(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	store, p := setup(t, search.WithAutoIndex(false))
+
+	require.NoError(t, store.Create(ctx, article{ID: "1", Title: "Prefab"}))
+	r, err := p.Query(ctx, storage.Name(article{}), search.Query{Text: "prefab"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total)
+}
+
+func TestQueryNormalize(t *testing.T) {
+	q, err := search.Query{Text: " hello ", Offset: -1}.Normalize()
+	require.NoError(t, err)
+	assert.Equal(t, search.Query{Text: "hello", Limit: search.DefaultLimit}, q)
+
+	_, err = search.Query{}.Normalize()
+	assert.True(t, errors.Is(err, search.ErrEmptyQuery))
+}
+
+func TestDocumentText(t *testing.T) {
+	doc := search.Document{Fields: map[string]string{"title": "Hello", "body": "World"}}
+	assert.Equal(t, "World\nHello", doc.Text())
+}
@@ -0,0 +1,169 @@
+// Package sqlitesearch provides a search.Engine backed by SQLite FTS5.
+//
+// All indexes are stored in a single FTS5 table. Query text is split into
+// terms which are each quoted, so FTS5 query syntax in user input is treated
+// as literal text, and documents must match every term. Results are ranked
+// using BM25.
+//
+// Examples:
+//
+//	engine := sqlitesearch.New("file:search.db")
+//
+//	engine := sqlitesearch.New("file:app.db", sqlitesearch.WithTableName("app_search"))
+//
+//nolint:gosec // Reports on G202. SQL string concat used to parameterize table.
+package sqlitesearch
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+
+	_ "modernc.org/sqlite" // Register the sqlite driver.
+)
+
+// Option is a functional option for configuring the engine.
+type Option func(*engine)
+
+// WithTableName overrides the default table name, "prefab_search".
+func WithTableName(name string) Option {
+	return func(e *engine) {
+		e.table = name
+	}
+}
+
+// New returns an engine that stores indexes in the given sqlite database. The
+// table is created on initialization. Any errors are considered
+// non-recoverable and will panic, unless SafeNew is used instead.
+func New(conn string, opts ...Option) search.Engine {
+	e, err := SafeNew(conn, opts...)
+	if err != nil {
+		panic(err.Error())
+	}
+	return e
+}
+
+// SafeNew is like New but returns errors instead of panicking.
+func SafeNew(conn string, opts ...Option) (search.Engine, error) {
+	db, err := sql.Open("sqlite", conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite connection: %w", err)
+	}
+	e := &engine{
+		db:    db,
+		table: "prefab_search",
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if err := e.ensureTable(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+type engine struct {
+	db    *sql.DB
+	table string
+}
+
+func (e *engine) Index(ctx context.Context, index string, docs ...search.Document) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return errors.Mark(search.ErrMissingID, 0)
+		}
+		fields, err := json.Marshal(doc.Fields)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+e.table+` WHERE idx = ? AND id = ?`, index, doc.ID); err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO `+e.table+` (idx, id, body, fields) VALUES (?, ?, ?, ?)`,
+			index, doc.ID, doc.Text(), string(fields)); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, 0)
+	}
+	return nil
+}
+
+func (e *engine) Delete(ctx context.Context, index string, ids ...string) error {
+	for _, id := range ids {
+		if _, err := e.db.ExecContext(ctx, `DELETE FROM `+e.table+` WHERE idx = ? AND id = ?`, index, id); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+func (e *engine) Query(ctx context.Context, index string, q search.Query) (*search.Results, error) {
+	q, err := q.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	match := matchExpr(q.Text)
+	results := &search.Results{}
+	err = e.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM `+e.table+` WHERE `+e.table+` MATCH ? AND idx = ?`,
+		match, index).Scan(&results.Total)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+
+	rows, err := e.db.QueryContext(ctx, `SELECT id, fields, bm25(`+e.table+`) FROM `+e.table+`
+		WHERE `+e.table+` MATCH ? AND idx = ?
+		ORDER BY bm25(`+e.table+`) LIMIT ? OFFSET ?`, match, index, q.Limit, q.Offset)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var hit search.Hit
+		var fields string
+		if err := rows.Scan(&hit.ID, &fields, &hit.Score); err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		if err := json.Unmarshal([]byte(fields), &hit.Fields); err != nil {
+			return nil, errors.Wrap(err, 0)
+		}
+		// BM25 scores are negative, with lower being more relevant.
+		hit.Score = -hit.Score
+		results.Hits = append(results.Hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return results, nil
+}
+
+func (e *engine) ensureTable() error {
+	_, err := e.db.ExecContext(context.Background(), `CREATE VIRTUAL TABLE IF NOT EXISTS `+e.table+`
+		USING fts5(idx UNINDEXED, id UNINDEXED, body, fields UNINDEXED)`)
+	if err != nil {
+		return fmt.Errorf("failed to create search table: %w", err)
+	}
+	return nil
+}
+
+// matchExpr converts search text into an FTS5 expression which matches every
+// term, treating each term as a literal string.
+func matchExpr(text string) string {
+	terms := strings.Fields(text)
+	for i, t := range terms {
+		terms[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
@@ -0,0 +1,97 @@
+package sqlitesearch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/search"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEngine(t *testing.T) search.Engine {
+	t.Helper()
+	e, err := SafeNew("file:" + filepath.Join(t.TempDir(), "search.db"))
+	require.NoError(t, err)
+	return e
+}
+
+func ids(r *search.Results) []string {
+	var out []string
+	for _, h := range r.Hits {
+		out = append(out, h.ID)
+	}
+	return out
+}
+
+func TestIndexAndQuery(t *testing.T) {
+	ctx := t.Context()
+	e := newTestEngine(t)
+
+	require.NoError(t, e.Index(ctx, "articles",
+		search.Document{ID: "1", Fields: map[string]string{"title": "Getting started with Go", "body": "Install the toolchain"}},
+		search.Document{ID: "2", Fields: map[string]string{"title": "Go plugins", "body": "Plugins extend a Go server. Go go go."}},
+		search.Document{ID: "3", Fields: map[string]string{"title": "Baking bread", "body": "Flour and water"}},
+	))
+	require.NoError(t, e.Index(ctx, "recipes",
+		search.Document{ID: "1", Fields: map[string]string{"title": "Go-go bread"}},
+	))
+
+	r, err := e.Query(ctx, "articles", search.Query{Text: "go"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, r.Total)
+	assert.Equal(t, []string{"2", "1"}, ids(r), "ranked by relevance")
+	assert.Equal(t, "Go plugins", r.Hits[0].Fields["title"])
+	assert.Greater(t, r.Hits[0].Score, r.Hits[1].Score)
+
+	r, err = e.Query(ctx, "articles", search.Query{Text: "go toolchain"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids(r), "all terms must match")
+
+	r, err = e.Query(ctx, "articles", search.Query{Text: "go", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, r.Total)
+	assert.Equal(t, []string{"1"}, ids(r))
+
+	r, err = e.Query(ctx, "recipes", search.Query{Text: "bread"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1"}, ids(r), "indexes are isolated")
+}
+
+func TestReindexAndDelete(t *testing.T) {
+	ctx := t.Context()
+	e := newTestEngine(t)
+
+	require.NoError(t, e.Index(ctx, "a", search.Document{ID: "1", Fields: map[string]string{"t": "apple"}}))
+	require.NoError(t, e.Index(ctx, "a", search.Document{ID: "1", Fields: map[string]string{"t": "banana"}}))
+
+	r, err := e.Query(ctx, "a", search.Query{Text: "apple"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total, "indexing replaces existing documents")
+
+	r, err = e.Query(ctx, "a", search.Query{Text: "banana"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Total)
+
+	require.NoError(t, e.Delete(ctx, "a", "1", "missing"))
+	r, err = e.Query(ctx, "a", search.Query{Text: "banana"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Total)
+}
+
+func TestQuery_Syntax(t *testing.T) {
+	ctx := t.Context()
+	e := newTestEngine(t)
+	require.NoError(t, e.Index(ctx, "a", search.Document{ID: "1", Fields: map[string]string{"t": `say "hello" OR NOT`}}))
+
+	r, err := e.Query(ctx, "a", search.Query{Text: `"hello" OR`})
+	require.NoError(t, err, "query syntax is treated as text")
+	assert.Equal(t, 1, r.Total)
+
+	_, err = e.Query(ctx, "a", search.Query{Text: "  "})
+	assert.True(t, errors.Is(err, search.ErrEmptyQuery))
+
+	err = e.Index(ctx, "a", search.Document{})
+	assert.True(t, errors.Is(err, search.ErrMissingID))
+}
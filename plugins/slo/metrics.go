@@ -0,0 +1,57 @@
+package slo
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// metricsHandler serves error budgets in the Prometheus text exposition
+// format.
+func (p *SLOPlugin) metricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		p.writeMetrics(w)
+	})
+}
+
+func (p *SLOPlugin) writeMetrics(w io.Writer) {
+	budgets := p.Budgets()
+	gauge := func(name, help string, value func(*ErrorBudget) float64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		for _, b := range budgets {
+			fmt.Fprintf(w, "%s{objective=%s,method=%s} %s\n", name,
+				quote(b.GetObjective()), quote(b.GetMethod()), formatFloat(value(b)))
+		}
+	}
+	gauge("prefab_slo_target", "Fraction of requests which must be good.",
+		func(b *ErrorBudget) float64 { return b.GetTarget() })
+	gauge("prefab_slo_requests", "Requests within the objective's window.",
+		func(b *ErrorBudget) float64 { return float64(b.GetTotal()) })
+	gauge("prefab_slo_bad_requests", "Requests within the objective's window which failed the objective.",
+		func(b *ErrorBudget) float64 { return float64(b.GetBad()) })
+	gauge("prefab_slo_error_budget_remaining", "Fraction of the error budget left.",
+		func(b *ErrorBudget) float64 { return b.GetBudgetRemaining() })
+
+	const name = "prefab_slo_burn_rate"
+	fmt.Fprintf(w, "# HELP %s Rate the error budget is being consumed over the alert window.\n# TYPE %s gauge\n", name, name)
+	for _, b := range budgets {
+		for _, br := range b.GetBurnRates() {
+			fmt.Fprintf(w, "%s{objective=%s,method=%s,window=%s} %s\n", name,
+				quote(b.GetObjective()), quote(b.GetMethod()), quote(br.GetWindow().AsDuration().String()),
+				formatFloat(br.GetRate()))
+		}
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quote(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
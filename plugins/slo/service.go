@@ -0,0 +1,26 @@
+package slo
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+type impl struct {
+	UnimplementedSLOServiceServer
+	p *SLOPlugin
+}
+
+func (s *impl) GetErrorBudgets(_ context.Context, in *GetErrorBudgetsRequest) (*GetErrorBudgetsResponse, error) {
+	out := &GetErrorBudgetsResponse{}
+	for _, b := range s.p.Budgets() {
+		if in.GetObjective() == "" || in.GetObjective() == b.GetObjective() {
+			out.Budgets = append(out.Budgets, b)
+		}
+	}
+	if in.GetObjective() != "" && len(out.Budgets) == 0 {
+		return nil, errors.Codef(codes.NotFound, "slo: objective %q not found", in.GetObjective())
+	}
+	return out, nil
+}
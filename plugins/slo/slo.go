@@ -0,0 +1,506 @@
+// Package slo tracks service level objectives for RPC methods and reports how
+// quickly their error budgets are being consumed.
+//
+// An objective declares the fraction of requests to a method which must be
+// good, either because they succeed (availability) or because they complete
+// within a latency threshold. Requests are counted in one minute buckets, and
+// the error budget is calculated over a rolling window, 30 days by default.
+//
+// Burn rate is how fast the budget is being consumed relative to the window: a
+// burn rate of 1 exhausts the budget exactly at the end of the window. When the
+// burn rate over an alert window exceeds its threshold a BurnRateAlert is
+// published to the event bus, and a second event is published once it
+// recovers. The default alerts follow the multi-window approach from the Google
+// SRE workbook, 14.4 over 1 hour and 6 over 6 hours.
+//
+// Budgets are exposed in Prometheus text format at /metrics and via the
+// SLOService on the admin listener.
+//
+// Objectives can be configured in code or config:
+//
+//	slo:
+//	  objectives:
+//	    - method: /notes.NoteService/Get
+//	      target: 0.999
+//	    - name: notes-get-latency
+//	      method: /notes.NoteService/Get
+//	      target: 0.99
+//	      latency: 300ms
+package slo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "slo.objectives",
+			Description: "Service level objectives, e.g. [{name, method, target, latency, window}]",
+			Type:        "list",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "slo.window",
+			Description: "Default rolling window error budgets are calculated over",
+			Type:        "duration",
+			Default:     "720h",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "slo.evaluationInterval",
+			Description: "How often burn rates are checked against alert thresholds, 0 disables",
+			Type:        "duration",
+			Default:     "1m",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "slo"
+
+	// BurnRateEvent is published with a BurnRateAlert when an objective's burn
+	// rate exceeds an alert threshold.
+	BurnRateEvent = "slo.burn_rate"
+
+	// BurnRateResolvedEvent is published with a BurnRateAlert when the burn rate
+	// drops back below the threshold.
+	BurnRateResolvedEvent = "slo.burn_rate_resolved"
+
+	// Width of the buckets requests are counted in.
+	bucketWidth = time.Minute
+
+	defaultWindow             = 30 * 24 * time.Hour
+	defaultEvaluationInterval = time.Minute
+)
+
+// Default alerts, used when no alerts are configured.
+var defaultAlerts = []BurnRateAlertRule{
+	{Window: time.Hour, Threshold: 14.4},
+	{Window: 6 * time.Hour, Threshold: 6},
+}
+
+// Codes which count against availability objectives. Other errors are
+// considered the caller's fault.
+var serverErrors = map[codes.Code]bool{
+	codes.Unknown:          true,
+	codes.DeadlineExceeded: true,
+	codes.Unimplemented:    true,
+	codes.Internal:         true,
+	codes.Unavailable:      true,
+	codes.DataLoss:         true,
+}
+
+// Objective is a target for the fraction of good requests to a method.
+type Objective struct {
+	// Name identifies the objective in metrics and alerts. Defaults to the
+	// method, suffixed with ":latency" for latency objectives.
+	Name string
+
+	// Full GRPC method name, e.g. "/notes.NoteService/Get".
+	Method string
+
+	// Fraction of requests which must be good, e.g. 0.999.
+	Target float64
+
+	// If set, requests slower than Latency count against the budget, making
+	// this a latency objective. Otherwise server errors count against it.
+	Latency time.Duration
+
+	// Rolling window the budget is calculated over. Defaults to `slo.window`.
+	Window time.Duration
+}
+
+// BurnRateAlertRule triggers an alert when the burn rate measured over Window
+// exceeds Threshold.
+type BurnRateAlertRule struct {
+	Window    time.Duration
+	Threshold float64
+}
+
+// BurnRateAlert is the payload of BurnRateEvent and BurnRateResolvedEvent.
+type BurnRateAlert struct {
+	Objective       string
+	Method          string
+	Window          time.Duration
+	BurnRate        float64
+	Threshold       float64
+	BudgetRemaining float64
+}
+
+// SLOOption allows configuration of the SLOPlugin.
+type SLOOption func(*SLOPlugin)
+
+// WithObjective adds an objective, in addition to those in `slo.objectives`.
+func WithObjective(o Objective) SLOOption {
+	return func(p *SLOPlugin) {
+		p.objectives = append(p.objectives, o)
+	}
+}
+
+// WithBurnRateAlert adds an alert rule. When any rules are added the default
+// rules are not used.
+func WithBurnRateAlert(window time.Duration, threshold float64) SLOOption {
+	return func(p *SLOPlugin) {
+		p.alerts = append(p.alerts, BurnRateAlertRule{Window: window, Threshold: threshold})
+	}
+}
+
+// WithWindow overrides the default window for objectives which don't specify
+// one. See `slo.window`.
+func WithWindow(d time.Duration) SLOOption {
+	return func(p *SLOPlugin) {
+		p.window = d
+	}
+}
+
+// WithEvaluationInterval overrides how often burn rates are checked. Zero
+// disables the background check, in which case Evaluate should be called by
+// the application. See `slo.evaluationInterval`.
+func WithEvaluationInterval(d time.Duration) SLOOption {
+	return func(p *SLOPlugin) {
+		p.evaluationInterval = d
+	}
+}
+
+// Plugin returns a new SLOPlugin.
+func Plugin(opts ...SLOOption) *SLOPlugin {
+	p := &SLOPlugin{
+		window:             durationFromConfig("slo.window", defaultWindow),
+		evaluationInterval: durationFromConfig("slo.evaluationInterval", defaultEvaluationInterval),
+		objectives:         objectivesFromConfig(),
+		now:                time.Now,
+		stop:               make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// SLOPlugin counts requests to methods with objectives and tracks their error
+// budgets.
+type SLOPlugin struct {
+	objectives         []Objective
+	alerts             []BurnRateAlertRule
+	window             time.Duration
+	evaluationInterval time.Duration
+
+	bus eventbus.EventBus
+	now func() time.Time
+
+	// Trackers for each objective, and the objectives for each method.
+	trackers []*tracker
+	byMethod map[string][]*tracker
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// From prefab.Plugin.
+func (p *SLOPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *SLOPlugin) OptDeps() []string {
+	return []string{eventbus.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *SLOPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseObservability)),
+	}
+}
+
+// From prefab.AdminOptionProvider.
+func (p *SLOPlugin) AdminOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithAdminHTTPHandler("/metrics", p.metricsHandler()),
+		prefab.WithAdminGRPCService(&SLOService_ServiceDesc, &impl{p: p}),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *SLOPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	if len(p.alerts) == 0 {
+		p.alerts = defaultAlerts
+	}
+	p.trackers = nil
+	p.byMethod = map[string][]*tracker{}
+	names := map[string]bool{}
+	for _, o := range p.objectives {
+		if o.Method == "" {
+			return errors.Codef(codes.InvalidArgument, "slo: objective %q has no method", o.Name)
+		}
+		if o.Target <= 0 || o.Target >= 1 {
+			return errors.Codef(codes.InvalidArgument, "slo: target for %s must be between 0 and 1, got %v", o.Method, o.Target)
+		}
+		if o.Name == "" {
+			o.Name = o.Method
+			if o.Latency > 0 {
+				o.Name += ":latency"
+			}
+		}
+		if names[o.Name] {
+			return errors.Codef(codes.InvalidArgument, "slo: duplicate objective %q", o.Name)
+		}
+		names[o.Name] = true
+		if o.Window <= 0 {
+			o.Window = p.window
+		}
+		t := &tracker{Objective: o, buckets: map[int64]*bucket{}}
+		p.trackers = append(p.trackers, t)
+		p.byMethod[o.Method] = append(p.byMethod[o.Method], t)
+	}
+
+	if bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin); ok {
+		p.bus = bus
+	}
+
+	if p.evaluationInterval > 0 && len(p.trackers) > 0 {
+		p.wg.Add(1)
+		go p.runEvaluate(ctx)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *SLOPlugin) Shutdown(context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Observe records the outcome of a request to a method. Requests to methods
+// with objectives are recorded automatically, Observe is for requests which
+// don't pass through the GRPC interceptor.
+func (p *SLOPlugin) Observe(method string, duration time.Duration, err error) {
+	trackers := p.byMethod[method]
+	if len(trackers) == 0 {
+		return
+	}
+	now := p.now()
+	for _, t := range trackers {
+		var bad bool
+		if t.Latency > 0 {
+			bad = duration > t.Latency
+		} else {
+			bad = err != nil && serverErrors[status.Code(err)]
+		}
+		t.observe(now, bad)
+	}
+}
+
+// Budgets returns the current error budget of each objective, in the order
+// they were added.
+func (p *SLOPlugin) Budgets() []*ErrorBudget {
+	now := p.now()
+	out := make([]*ErrorBudget, 0, len(p.trackers))
+	for _, t := range p.trackers {
+		out = append(out, t.budget(now, p.alerts))
+	}
+	return out
+}
+
+// Evaluate checks burn rates against the alert rules, and publishes
+// BurnRateEvent or BurnRateResolvedEvent when an alert starts or stops firing.
+// Requires the eventbus plugin, otherwise alerts are only logged.
+func (p *SLOPlugin) Evaluate(ctx context.Context) {
+	now := p.now()
+	for _, t := range p.trackers {
+		b := t.budget(now, p.alerts)
+		for i, rate := range b.GetBurnRates() {
+			rule := p.alerts[i]
+			if !t.setAlerting(rule, rate.GetAlerting()) {
+				continue
+			}
+			alert := BurnRateAlert{
+				Objective:       t.Name,
+				Method:          t.Method,
+				Window:          rule.Window,
+				BurnRate:        rate.GetRate(),
+				Threshold:       rule.Threshold,
+				BudgetRemaining: b.GetBudgetRemaining(),
+			}
+			topic := BurnRateResolvedEvent
+			if rate.GetAlerting() {
+				topic = BurnRateEvent
+				logging.Warnw(ctx, "🔥 SLO burn rate exceeded",
+					"objective", t.Name, "window", rule.Window, "burnRate", alert.BurnRate, "threshold", rule.Threshold)
+			} else {
+				logging.Infow(ctx, "SLO burn rate recovered",
+					"objective", t.Name, "window", rule.Window, "burnRate", alert.BurnRate)
+			}
+			if p.bus != nil {
+				p.bus.Publish(topic, alert)
+			}
+		}
+	}
+}
+
+func (p *SLOPlugin) runEvaluate(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.evaluationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.Evaluate(ctx)
+		case <-p.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *SLOPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if len(p.byMethod[info.FullMethod]) == 0 {
+		return handler(ctx, req)
+	}
+	start := p.now()
+	resp, err := handler(ctx, req)
+	p.Observe(info.FullMethod, p.now().Sub(start), err)
+	return resp, err
+}
+
+// tracker counts requests for an objective.
+type tracker struct {
+	Objective
+
+	mu       sync.Mutex
+	buckets  map[int64]*bucket // Keyed by minutes since the epoch.
+	alerting map[BurnRateAlertRule]bool
+}
+
+type bucket struct {
+	total int64
+	bad   int64
+}
+
+func (t *tracker) observe(now time.Time, bad bool) {
+	idx := now.Unix() / int64(bucketWidth/time.Second)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b, ok := t.buckets[idx]
+	if !ok {
+		b = &bucket{}
+		t.buckets[idx] = b
+		t.prune(idx)
+	}
+	b.total++
+	if bad {
+		b.bad++
+	}
+}
+
+// prune removes buckets which have left the window. Must be called with the
+// lock held.
+func (t *tracker) prune(idx int64) {
+	oldest := idx - int64(t.Window/bucketWidth)
+	for k := range t.buckets {
+		if k <= oldest {
+			delete(t.buckets, k)
+		}
+	}
+}
+
+// counts returns the requests within d of now.
+func (t *tracker) counts(now time.Time, d time.Duration) (total, bad int64) {
+	idx := now.Unix() / int64(bucketWidth/time.Second)
+	oldest := idx - int64(d/bucketWidth)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, b := range t.buckets {
+		if k > oldest && k <= idx {
+			total += b.total
+			bad += b.bad
+		}
+	}
+	return total, bad
+}
+
+func (t *tracker) budget(now time.Time, alerts []BurnRateAlertRule) *ErrorBudget {
+	total, bad := t.counts(now, t.Window)
+	allowed := 1 - t.Target
+	remaining := 1.0
+	if total > 0 {
+		remaining = 1 - float64(bad)/float64(total)/allowed
+	}
+	b := &ErrorBudget{
+		Objective:       t.Name,
+		Method:          t.Method,
+		Target:          t.Target,
+		Window:          durationpb.New(t.Window),
+		Total:           total,
+		Bad:             bad,
+		BudgetRemaining: remaining,
+	}
+	if t.Latency > 0 {
+		b.Latency = durationpb.New(t.Latency)
+	}
+	for _, a := range alerts {
+		total, bad := t.counts(now, a.Window)
+		var rate float64
+		if total > 0 {
+			rate = float64(bad) / float64(total) / allowed
+		}
+		b.BurnRates = append(b.BurnRates, &BurnRate{
+			Window:    durationpb.New(a.Window),
+			Rate:      rate,
+			Threshold: a.Threshold,
+			Alerting:  rate > a.Threshold,
+		})
+	}
+	return b
+}
+
+// setAlerting records whether the rule is firing, and returns true if that
+// changed.
+func (t *tracker) setAlerting(rule BurnRateAlertRule, alerting bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.alerting == nil {
+		t.alerting = map[BurnRateAlertRule]bool{}
+	}
+	if t.alerting[rule] == alerting {
+		return false
+	}
+	t.alerting[rule] = alerting
+	return true
+}
+
+// objectivesFromConfig reads objectives from `slo.objectives`.
+func objectivesFromConfig() []Objective {
+	var out []Objective
+	for _, c := range prefab.Config.Slices("slo.objectives") {
+		out = append(out, Objective{
+			Name:    c.String("name"),
+			Method:  c.String("method"),
+			Target:  c.Float64("target"),
+			Latency: c.Duration("latency"),
+			Window:  c.Duration("window"),
+		})
+	}
+	return out
+}
+
+func durationFromConfig(key string, def time.Duration) time.Duration {
+	if !prefab.ConfigExists(key) {
+		return def
+	}
+	return prefab.ConfigDuration(key)
+}
@@ -0,0 +1,391 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/slo/slo.proto
+
+package slo
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetErrorBudgetsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only return the budget for this objective.
+	Objective     string `protobuf:"bytes,1,opt,name=objective,proto3" json:"objective,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetErrorBudgetsRequest) Reset() {
+	*x = GetErrorBudgetsRequest{}
+	mi := &file_plugins_slo_slo_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetErrorBudgetsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetErrorBudgetsRequest) ProtoMessage() {}
+
+func (x *GetErrorBudgetsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_slo_slo_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetErrorBudgetsRequest.ProtoReflect.Descriptor instead.
+func (*GetErrorBudgetsRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_slo_slo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetErrorBudgetsRequest) GetObjective() string {
+	if x != nil {
+		return x.Objective
+	}
+	return ""
+}
+
+type GetErrorBudgetsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Budgets       []*ErrorBudget         `protobuf:"bytes,1,rep,name=budgets,proto3" json:"budgets,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetErrorBudgetsResponse) Reset() {
+	*x = GetErrorBudgetsResponse{}
+	mi := &file_plugins_slo_slo_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetErrorBudgetsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetErrorBudgetsResponse) ProtoMessage() {}
+
+func (x *GetErrorBudgetsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_slo_slo_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetErrorBudgetsResponse.ProtoReflect.Descriptor instead.
+func (*GetErrorBudgetsResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_slo_slo_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetErrorBudgetsResponse) GetBudgets() []*ErrorBudget {
+	if x != nil {
+		return x.Budgets
+	}
+	return nil
+}
+
+type ErrorBudget struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Name of the objective.
+	Objective string `protobuf:"bytes,1,opt,name=objective,proto3" json:"objective,omitempty"`
+	// Full GRPC method name the objective applies to.
+	Method string `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	// Fraction of requests which must be good, e.g. 0.999.
+	Target float64 `protobuf:"fixed64,3,opt,name=target,proto3" json:"target,omitempty"`
+	// Requests slower than this count against the budget. Unset for
+	// availability objectives, where errors count against the budget.
+	Latency *durationpb.Duration `protobuf:"bytes,4,opt,name=latency,proto3" json:"latency,omitempty"`
+	// Rolling window the budget is calculated over.
+	Window *durationpb.Duration `protobuf:"bytes,5,opt,name=window,proto3" json:"window,omitempty"`
+	// Requests within the window.
+	Total int64 `protobuf:"varint,6,opt,name=total,proto3" json:"total,omitempty"`
+	// Requests within the window which failed the objective.
+	Bad int64 `protobuf:"varint,7,opt,name=bad,proto3" json:"bad,omitempty"`
+	// Fraction of the error budget left, negative once the budget is exhausted.
+	BudgetRemaining float64     `protobuf:"fixed64,8,opt,name=budget_remaining,json=budgetRemaining,proto3" json:"budget_remaining,omitempty"`
+	BurnRates       []*BurnRate `protobuf:"bytes,9,rep,name=burn_rates,json=burnRates,proto3" json:"burn_rates,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ErrorBudget) Reset() {
+	*x = ErrorBudget{}
+	mi := &file_plugins_slo_slo_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorBudget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorBudget) ProtoMessage() {}
+
+func (x *ErrorBudget) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_slo_slo_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorBudget.ProtoReflect.Descriptor instead.
+func (*ErrorBudget) Descriptor() ([]byte, []int) {
+	return file_plugins_slo_slo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ErrorBudget) GetObjective() string {
+	if x != nil {
+		return x.Objective
+	}
+	return ""
+}
+
+func (x *ErrorBudget) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *ErrorBudget) GetTarget() float64 {
+	if x != nil {
+		return x.Target
+	}
+	return 0
+}
+
+func (x *ErrorBudget) GetLatency() *durationpb.Duration {
+	if x != nil {
+		return x.Latency
+	}
+	return nil
+}
+
+func (x *ErrorBudget) GetWindow() *durationpb.Duration {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+func (x *ErrorBudget) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *ErrorBudget) GetBad() int64 {
+	if x != nil {
+		return x.Bad
+	}
+	return 0
+}
+
+func (x *ErrorBudget) GetBudgetRemaining() float64 {
+	if x != nil {
+		return x.BudgetRemaining
+	}
+	return 0
+}
+
+func (x *ErrorBudget) GetBurnRates() []*BurnRate {
+	if x != nil {
+		return x.BurnRates
+	}
+	return nil
+}
+
+type BurnRate struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Window the burn rate is measured over, e.g. 1h.
+	Window *durationpb.Duration `protobuf:"bytes,1,opt,name=window,proto3" json:"window,omitempty"`
+	// Rate the budget is being consumed, where 1 exhausts the budget exactly at
+	// the end of the objective's window.
+	Rate float64 `protobuf:"fixed64,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	// Burn rate which triggers an alert.
+	Threshold float64 `protobuf:"fixed64,3,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	// Whether the burn rate is above the threshold.
+	Alerting      bool `protobuf:"varint,4,opt,name=alerting,proto3" json:"alerting,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BurnRate) Reset() {
+	*x = BurnRate{}
+	mi := &file_plugins_slo_slo_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BurnRate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BurnRate) ProtoMessage() {}
+
+func (x *BurnRate) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_slo_slo_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BurnRate.ProtoReflect.Descriptor instead.
+func (*BurnRate) Descriptor() ([]byte, []int) {
+	return file_plugins_slo_slo_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BurnRate) GetWindow() *durationpb.Duration {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+func (x *BurnRate) GetRate() float64 {
+	if x != nil {
+		return x.Rate
+	}
+	return 0
+}
+
+func (x *BurnRate) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *BurnRate) GetAlerting() bool {
+	if x != nil {
+		return x.Alerting
+	}
+	return false
+}
+
+var File_plugins_slo_slo_proto protoreflect.FileDescriptor
+
+const file_plugins_slo_slo_proto_rawDesc = "" +
+	"\n" +
+	"\x15plugins/slo/slo.proto\x12\n" +
+	"prefab.slo\x1a\x1egoogle/protobuf/duration.proto\"6\n" +
+	"\x16GetErrorBudgetsRequest\x12\x1c\n" +
+	"\tobjective\x18\x01 \x01(\tR\tobjective\"L\n" +
+	"\x17GetErrorBudgetsResponse\x121\n" +
+	"\abudgets\x18\x01 \x03(\v2\x17.prefab.slo.ErrorBudgetR\abudgets\"\xcb\x02\n" +
+	"\vErrorBudget\x12\x1c\n" +
+	"\tobjective\x18\x01 \x01(\tR\tobjective\x12\x16\n" +
+	"\x06method\x18\x02 \x01(\tR\x06method\x12\x16\n" +
+	"\x06target\x18\x03 \x01(\x01R\x06target\x123\n" +
+	"\alatency\x18\x04 \x01(\v2\x19.google.protobuf.DurationR\alatency\x121\n" +
+	"\x06window\x18\x05 \x01(\v2\x19.google.protobuf.DurationR\x06window\x12\x14\n" +
+	"\x05total\x18\x06 \x01(\x03R\x05total\x12\x10\n" +
+	"\x03bad\x18\a \x01(\x03R\x03bad\x12)\n" +
+	"\x10budget_remaining\x18\b \x01(\x01R\x0fbudgetRemaining\x123\n" +
+	"\n" +
+	"burn_rates\x18\t \x03(\v2\x14.prefab.slo.BurnRateR\tburnRates\"\x8b\x01\n" +
+	"\bBurnRate\x121\n" +
+	"\x06window\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\x06window\x12\x12\n" +
+	"\x04rate\x18\x02 \x01(\x01R\x04rate\x12\x1c\n" +
+	"\tthreshold\x18\x03 \x01(\x01R\tthreshold\x12\x1a\n" +
+	"\balerting\x18\x04 \x01(\bR\balerting2h\n" +
+	"\n" +
+	"SLOService\x12Z\n" +
+	"\x0fGetErrorBudgets\x12\".prefab.slo.GetErrorBudgetsRequest\x1a#.prefab.slo.GetErrorBudgetsResponseB$Z\"github.com/dpup/prefab/plugins/slob\x06proto3"
+
+var (
+	file_plugins_slo_slo_proto_rawDescOnce sync.Once
+	file_plugins_slo_slo_proto_rawDescData []byte
+)
+
+func file_plugins_slo_slo_proto_rawDescGZIP() []byte {
+	file_plugins_slo_slo_proto_rawDescOnce.Do(func() {
+		file_plugins_slo_slo_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_slo_slo_proto_rawDesc), len(file_plugins_slo_slo_proto_rawDesc)))
+	})
+	return file_plugins_slo_slo_proto_rawDescData
+}
+
+var file_plugins_slo_slo_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_plugins_slo_slo_proto_goTypes = []any{
+	(*GetErrorBudgetsRequest)(nil),  // 0: prefab.slo.GetErrorBudgetsRequest
+	(*GetErrorBudgetsResponse)(nil), // 1: prefab.slo.GetErrorBudgetsResponse
+	(*ErrorBudget)(nil),             // 2: prefab.slo.ErrorBudget
+	(*BurnRate)(nil),                // 3: prefab.slo.BurnRate
+	(*durationpb.Duration)(nil),     // 4: google.protobuf.Duration
+}
+var file_plugins_slo_slo_proto_depIdxs = []int32{
+	2, // 0: prefab.slo.GetErrorBudgetsResponse.budgets:type_name -> prefab.slo.ErrorBudget
+	4, // 1: prefab.slo.ErrorBudget.latency:type_name -> google.protobuf.Duration
+	4, // 2: prefab.slo.ErrorBudget.window:type_name -> google.protobuf.Duration
+	3, // 3: prefab.slo.ErrorBudget.burn_rates:type_name -> prefab.slo.BurnRate
+	4, // 4: prefab.slo.BurnRate.window:type_name -> google.protobuf.Duration
+	0, // 5: prefab.slo.SLOService.GetErrorBudgets:input_type -> prefab.slo.GetErrorBudgetsRequest
+	1, // 6: prefab.slo.SLOService.GetErrorBudgets:output_type -> prefab.slo.GetErrorBudgetsResponse
+	6, // [6:7] is the sub-list for method output_type
+	5, // [5:6] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_plugins_slo_slo_proto_init() }
+func file_plugins_slo_slo_proto_init() {
+	if File_plugins_slo_slo_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_slo_slo_proto_rawDesc), len(file_plugins_slo_slo_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_slo_slo_proto_goTypes,
+		DependencyIndexes: file_plugins_slo_slo_proto_depIdxs,
+		MessageInfos:      file_plugins_slo_slo_proto_msgTypes,
+	}.Build()
+	File_plugins_slo_slo_proto = out.File
+	file_plugins_slo_slo_proto_goTypes = nil
+	file_plugins_slo_slo_proto_depIdxs = nil
+}
@@ -0,0 +1,177 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const method = "/notes.NoteService/Get"
+
+func setup(t *testing.T, plugins []prefab.Plugin, opts ...SLOOption) (*SLOPlugin, *time.Time) {
+	opts = append([]SLOOption{WithEvaluationInterval(0)}, opts...)
+	p := Plugin(opts...)
+	now := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	prefabtest.Init(t, append(plugins, p)...)
+	return p, &now
+}
+
+func TestBudgets(t *testing.T) {
+	p, now := setup(t, nil,
+		WithObjective(Objective{Method: method, Target: 0.9}),
+		WithObjective(Objective{Method: method, Target: 0.5, Latency: 100 * time.Millisecond}),
+		WithBurnRateAlert(time.Hour, 2),
+	)
+
+	for range 18 {
+		p.Observe(method, 10*time.Millisecond, nil)
+	}
+	p.Observe(method, 10*time.Millisecond, status.Error(codes.Internal, "boom"))
+	p.Observe(method, time.Second, status.Error(codes.InvalidArgument, "caller's fault"))
+	p.Observe("/other.Service/Get", time.Second, errors.New("ignored"))
+
+	budgets := p.Budgets()
+	require.Len(t, budgets, 2)
+
+	avail := budgets[0]
+	assert.Equal(t, method, avail.GetObjective())
+	assert.Equal(t, int64(20), avail.GetTotal())
+	assert.Equal(t, int64(1), avail.GetBad())
+	assert.InDelta(t, 0.5, avail.GetBudgetRemaining(), 0.0001)
+	require.Len(t, avail.GetBurnRates(), 1)
+	assert.InDelta(t, 0.5, avail.GetBurnRates()[0].GetRate(), 0.0001)
+	assert.False(t, avail.GetBurnRates()[0].GetAlerting())
+
+	latency := budgets[1]
+	assert.Equal(t, method+":latency", latency.GetObjective())
+	assert.Equal(t, int64(1), latency.GetBad())
+	assert.Equal(t, 100*time.Millisecond, latency.GetLatency().AsDuration())
+
+	// Requests outside the alert window don't affect the burn rate, and requests
+	// outside the objective's window are dropped.
+	*now = now.Add(2 * time.Hour)
+	assert.Zero(t, p.Budgets()[0].GetBurnRates()[0].GetRate())
+	assert.Equal(t, int64(20), p.Budgets()[0].GetTotal())
+
+	*now = now.Add(defaultWindow)
+	assert.Zero(t, p.Budgets()[0].GetTotal())
+	assert.Equal(t, 1.0, p.Budgets()[0].GetBudgetRemaining())
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := prefabtest.Context(t)
+	bus := membus.New(ctx)
+
+	var mu sync.Mutex
+	var events []string
+	var alert BurnRateAlert
+	for _, topic := range []string{BurnRateEvent, BurnRateResolvedEvent} {
+		bus.Subscribe(topic, func(_ context.Context, m *eventbus.Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, m.Topic)
+			alert = m.Data.(BurnRateAlert)
+			return nil
+		})
+	}
+
+	p, now := setup(t, []prefab.Plugin{eventbus.Plugin(bus)},
+		WithObjective(Objective{Name: "notes", Method: method, Target: 0.99}),
+		WithBurnRateAlert(time.Hour, 10),
+	)
+
+	p.Observe(method, 0, nil)
+	p.Observe(method, 0, status.Error(codes.Unavailable, "down"))
+	p.Evaluate(ctx)
+	p.Evaluate(ctx) // Only transitions are published.
+	require.NoError(t, bus.Wait(ctx))
+
+	mu.Lock()
+	assert.Equal(t, []string{BurnRateEvent}, events)
+	assert.Equal(t, "notes", alert.Objective)
+	assert.Equal(t, time.Hour, alert.Window)
+	assert.InDelta(t, 50, alert.BurnRate, 0.0001)
+	mu.Unlock()
+
+	*now = now.Add(2 * time.Hour)
+	p.Evaluate(ctx)
+	require.NoError(t, bus.Wait(ctx))
+
+	mu.Lock()
+	assert.Equal(t, []string{BurnRateEvent, BurnRateResolvedEvent}, events)
+	mu.Unlock()
+}
+
+func TestInit_Invalid(t *testing.T) {
+	for _, o := range []Objective{
+		{Target: 0.9},
+		{Method: method, Target: 1},
+		{Method: method},
+	} {
+		r := &prefab.Registry{}
+		r.Register(Plugin(WithObjective(o)))
+		assert.Error(t, r.Init(prefabtest.Context(t)), "%+v", o)
+	}
+
+	r := &prefab.Registry{}
+	r.Register(Plugin(
+		WithObjective(Objective{Method: method, Target: 0.9}),
+		WithObjective(Objective{Method: method, Target: 0.99}),
+	))
+	assert.ErrorContains(t, r.Init(prefabtest.Context(t)), "duplicate objective")
+}
+
+func TestObjectivesFromConfig(t *testing.T) {
+	require.NoError(t, prefab.Config.Set("slo.objectives", []any{
+		map[string]any{"method": method, "target": 0.999},
+		map[string]any{"name": "slow", "method": method, "target": 0.99, "latency": "300ms", "window": "24h"},
+	}))
+	t.Cleanup(func() { prefab.Config.Delete("slo") })
+
+	assert.Equal(t, []Objective{
+		{Method: method, Target: 0.999},
+		{Name: "slow", Method: method, Target: 0.99, Latency: 300 * time.Millisecond, Window: 24 * time.Hour},
+	}, objectivesFromConfig())
+}
+
+func TestMetricsAndService(t *testing.T) {
+	p := Plugin(
+		WithEvaluationInterval(0),
+		WithObjective(Objective{Name: `say "hi"`, Method: method, Target: 0.9}),
+	)
+	s := prefabtest.New(t, prefabtest.WithPlugins(p), prefabtest.WithOptions(prefab.WithAdminOnPublic()))
+	p.Observe(method, 0, nil)
+
+	resp, err := s.HTTPClient().Get(s.URL("/metrics"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "# TYPE prefab_slo_error_budget_remaining gauge\n")
+	assert.Contains(t, string(body), `prefab_slo_requests{objective="say \"hi\"",method="/notes.NoteService/Get"} 1`)
+	assert.Contains(t, string(body), `prefab_slo_burn_rate{objective="say \"hi\"",method="/notes.NoteService/Get",window="1h0m0s"} 0`)
+
+	svc := &impl{p: p}
+	out, err := svc.GetErrorBudgets(t.Context(), &GetErrorBudgetsRequest{})
+	require.NoError(t, err)
+	require.Len(t, out.GetBudgets(), 1)
+	assert.Equal(t, int64(1), out.GetBudgets()[0].GetTotal())
+
+	_, err = svc.GetErrorBudgets(t.Context(), &GetErrorBudgetsRequest{Objective: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+)
+
+// PluginOption configures the storage plugin.
+type PluginOption func(*StoragePlugin)
+
+// WithChangeEvents publishes a Change to the event bus after each write made
+// via the plugin, for cache invalidation, search indexing, or pushing updates
+// to clients. Changes are published to ChangeTopic(name) for the model's name.
+// Requires the eventbus plugin.
+//
+// If the store implements ChangeNotifier, such as the postgres store, changes
+// are propagated to every instance of the server, including the one which
+// made the write, before being published to each instance's event bus.
+func WithChangeEvents() PluginOption {
+	return func(p *StoragePlugin) {
+		p.changeEvents = true
+	}
+}
+
+// WithChangePayloads enables change events, see WithChangeEvents, and includes
+// the JSON encoded model in changes other than deletes.
+func WithChangePayloads() PluginOption {
+	return func(p *StoragePlugin) {
+		p.changeEvents = true
+		p.changePayloads = true
+	}
+}
+
+// ChangeTopic returns the event bus topic that changes to models with the
+// given name are published to, see Name.
+//
+//	bus.Subscribe(storage.ChangeTopic(storage.Name(User{})), handler)
+func ChangeTopic(model string) string {
+	return "storage.change." + model
+}
+
+// Change describes a write to a model, and is the data of messages published
+// by WithChangeEvents.
+type Change struct {
+	Op    Op     `json:"op"`
+	Model string `json:"model"`
+	ID    string `json:"id"`
+
+	// Instance of the storage plugin which made the write, empty for the
+	// default store, see NamedPlugin.
+	Instance string `json:"instance,omitempty"`
+
+	// Payload is the JSON encoded model, when enabled with WithChangePayloads.
+	// Stores may omit large payloads when propagating changes between
+	// instances, in which case subscribers should read the model.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ChangeNotifier is an optional interface for stores which can propagate
+// changes between instances of the server.
+type ChangeNotifier interface {
+	// NotifyChanges sends changes to every listener, including those in the
+	// current process.
+	NotifyChanges(ctx context.Context, changes ...Change) error
+
+	// ListenChanges calls fn for each change sent by NotifyChanges, until the
+	// context is canceled. Returns once listening has started.
+	ListenChanges(ctx context.Context, fn func(context.Context, Change)) error
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *StoragePlugin) OptDeps() []string {
+	if p.changeEvents {
+		return []string{eventbus.PluginName}
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin. Stops listening for changes.
+func (p *StoragePlugin) Shutdown(context.Context) error {
+	if p.stopChanges != nil {
+		p.stopChanges()
+	}
+	return nil
+}
+
+func (p *StoragePlugin) initChanges(ctx context.Context, r *prefab.Registry) error {
+	bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin)
+	if !ok {
+		return errors.New("storage: WithChangeEvents requires the eventbus plugin")
+	}
+	p.bus = bus
+
+	if n, ok := unwrapHooks(p.Store).(ChangeNotifier); ok {
+		ctx, p.stopChanges = context.WithCancel(context.WithoutCancel(ctx))
+		if err := n.ListenChanges(ctx, p.receiveChange); err != nil {
+			p.stopChanges()
+			return errors.WrapPrefix(err, "storage: failed to listen for changes", 0)
+		}
+		p.notifier = n
+	}
+	p.AddHook(p.publishChanges)
+	return nil
+}
+
+// publishChanges is a Hook which sends changes via the store's notifier, or
+// directly to the event bus if there isn't one or it fails.
+func (p *StoragePlugin) publishChanges(ctx context.Context, op Op, models ...Model) error {
+	changes := make([]Change, 0, len(models))
+	for _, m := range models {
+		c := Change{Op: op, Model: Name(m), ID: m.PK(), Instance: p.instance}
+		if p.changePayloads && op != OpDelete {
+			b, err := json.Marshal(m)
+			if err != nil {
+				return errors.Wrap(err, 0)
+			}
+			c.Payload = b
+		}
+		changes = append(changes, c)
+	}
+	if p.notifier != nil {
+		err := p.notifier.NotifyChanges(ctx, changes...)
+		if err == nil {
+			return nil
+		}
+		logging.Warnw(ctx, "storage: failed to notify changes, publishing locally", "error", err)
+	}
+	for _, c := range changes {
+		p.bus.Publish(ChangeTopic(c.Model), c)
+	}
+	return nil
+}
+
+// receiveChange publishes changes received by the store's notifier. Plugins
+// for other instances sharing the store publish their own changes.
+func (p *StoragePlugin) receiveChange(_ context.Context, c Change) {
+	if c.Instance != p.instance {
+		return
+	}
+	p.bus.Publish(ChangeTopic(c.Model), c)
+}
+
+// unwrapHooks returns the store underlying any hooks.
+func unwrapHooks(s Store) Store {
+	switch hs := s.(type) {
+	case *hookedStore:
+		return hs.Store
+	case *hookedDumper:
+		return hs.Store
+	}
+	return s
+}
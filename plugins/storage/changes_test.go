@@ -0,0 +1,161 @@
+package storage_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// changeRecorder collects changes published to the bus.
+type changeRecorder struct {
+	mu      sync.Mutex
+	changes []storage.Change
+}
+
+func (r *changeRecorder) handle(_ context.Context, m *eventbus.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, m.Data.(storage.Change))
+	return nil
+}
+
+func setupChanges(t *testing.T, store storage.Store, opts ...storage.PluginOption) (context.Context, *storage.StoragePlugin, *eventbus.EventBusPlugin) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	bus := eventbus.Plugin(membus.New(ctx))
+	sp := storage.Plugin(store, opts...).(*storage.StoragePlugin)
+
+	r := &prefab.Registry{}
+	r.Register(sp)
+	r.Register(bus)
+	require.NoError(t, r.Init(ctx))
+	t.Cleanup(func() { _ = r.Shutdown(context.WithoutCancel(ctx)) })
+	return ctx, sp, bus
+}
+
+func TestChangeEvents(t *testing.T) {
+	ctx, sp, bus := setupChanges(t, memstore.New(), storage.WithChangeEvents())
+
+	rec := &changeRecorder{}
+	bus.Subscribe(storage.ChangeTopic(storage.Name(note{})), rec.handle)
+
+	require.NoError(t, sp.Create(ctx, note{ID: "1"}, note{ID: "2"}))
+	require.NoError(t, sp.Update(ctx, note{ID: "1", Text: "updated"}))
+	require.NoError(t, sp.Delete(ctx, note{ID: "2"}))
+	require.NoError(t, bus.Wait(ctx))
+
+	assert.ElementsMatch(t, []storage.Change{
+		{Op: storage.OpCreate, Model: "notes", ID: "1"},
+		{Op: storage.OpCreate, Model: "notes", ID: "2"},
+		{Op: storage.OpUpdate, Model: "notes", ID: "1"},
+		{Op: storage.OpDelete, Model: "notes", ID: "2"},
+	}, rec.changes)
+}
+
+func TestChangeEvents_Payloads(t *testing.T) {
+	ctx, sp, bus := setupChanges(t, memstore.New(), storage.WithChangePayloads())
+
+	rec := &changeRecorder{}
+	bus.Subscribe(storage.ChangeTopic("notes"), rec.handle)
+
+	require.NoError(t, sp.Upsert(ctx, note{ID: "1", Text: "hello"}))
+	require.NoError(t, bus.Wait(ctx))
+	require.NoError(t, sp.Delete(ctx, note{ID: "1"}))
+	require.NoError(t, bus.Wait(ctx))
+
+	require.Len(t, rec.changes, 2)
+	assert.JSONEq(t, `{"ID":"1","Text":"hello"}`, string(rec.changes[0].Payload))
+	assert.Nil(t, rec.changes[1].Payload, "deletes don't include payloads")
+}
+
+func TestChangeEvents_RequiresEventBus(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	r := &prefab.Registry{}
+	r.Register(storage.Plugin(memstore.New(), storage.WithChangeEvents()))
+	require.ErrorContains(t, r.Init(ctx), "requires the eventbus plugin")
+}
+
+// notifyingStore propagates changes through a shared channel, standing in for
+// a store shared by several servers.
+type notifyingStore struct {
+	storage.Store
+	listeners *[]func(context.Context, storage.Change)
+	fail      bool
+}
+
+func (s *notifyingStore) NotifyChanges(ctx context.Context, changes ...storage.Change) error {
+	if s.fail {
+		return errors.New("notify failed")
+	}
+	for _, c := range changes {
+		// Round trip the change, as a store would.
+		b, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		var decoded storage.Change
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			return err
+		}
+		for _, fn := range *s.listeners {
+			fn(ctx, decoded)
+		}
+	}
+	return nil
+}
+
+func (s *notifyingStore) ListenChanges(_ context.Context, fn func(context.Context, storage.Change)) error {
+	*s.listeners = append(*s.listeners, fn)
+	return nil
+}
+
+func TestChangeEvents_Notifier(t *testing.T) {
+	var listeners []func(context.Context, storage.Change)
+	shared := memstore.New()
+	store1 := &notifyingStore{Store: shared, listeners: &listeners}
+	store2 := &notifyingStore{Store: shared, listeners: &listeners}
+
+	ctx, sp1, bus1 := setupChanges(t, store1, storage.WithChangeEvents())
+	_, _, bus2 := setupChanges(t, store2, storage.WithChangeEvents())
+
+	rec1, rec2 := &changeRecorder{}, &changeRecorder{}
+	bus1.Subscribe(storage.ChangeTopic("notes"), rec1.handle)
+	bus2.Subscribe(storage.ChangeTopic("notes"), rec2.handle)
+
+	require.NoError(t, sp1.Create(ctx, note{ID: "1"}))
+	require.NoError(t, bus1.Wait(ctx))
+	require.NoError(t, bus2.Wait(ctx))
+
+	want := []storage.Change{{Op: storage.OpCreate, Model: "notes", ID: "1"}}
+	assert.Equal(t, want, rec1.changes, "changes are published once by the writer")
+	assert.Equal(t, want, rec2.changes, "changes are published by other servers")
+
+	// If notification fails, changes are still published locally.
+	store1.fail = true
+	require.NoError(t, sp1.Create(ctx, note{ID: "2"}))
+	require.NoError(t, bus1.Wait(ctx))
+	require.NoError(t, bus2.Wait(ctx))
+	assert.Len(t, rec1.changes, 2)
+	assert.Len(t, rec2.changes, 1)
+}
+
+func TestOpText(t *testing.T) {
+	b, err := json.Marshal(storage.OpUpsert)
+	require.NoError(t, err)
+	assert.JSONEq(t, `"upsert"`, string(b))
+
+	var op storage.Op
+	require.NoError(t, json.Unmarshal(b, &op))
+	assert.Equal(t, storage.OpUpsert, op)
+	require.Error(t, json.Unmarshal([]byte(`"truncate"`), &op))
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrDumpUnsupported is returned by Export and Import when the store doesn't
+// implement Dumper.
+var ErrDumpUnsupported = errors.NewC("store does not support export", codes.Unimplemented)
+
+// importBatchSize is the number of records passed to Dumper.Load at a time.
+const importBatchSize = 100
+
+// Record is the raw, backend independent form of a stored model.
+type Record struct {
+	// Type is the model name, see storage.Name.
+	Type string `json:"type"`
+
+	// ID is the primary key of the model.
+	ID string `json:"id"`
+
+	// Value is the JSON encoded model.
+	Value json.RawMessage `json:"value"`
+}
+
+// Optional interface that stores can implement in order to support Export and
+// Import.
+type Dumper interface {
+	// Dump calls fn for every record in the store. Records are grouped by type.
+	Dump(ctx context.Context, fn func(Record) error) error
+
+	// Load inserts records, replacing any existing records with the same type
+	// and ID. Records for models that have been initialized with InitModel are
+	// written to the model's dedicated table.
+	Load(ctx context.Context, records ...Record) error
+}
+
+// Export writes every record in store to w, as JSON lines. The output is
+// portable between backends, so can be used for backups and for migrating
+// data from one store to another. Returns the number of records written.
+//
+// Example:
+//
+//	f, _ := os.Create("backup.jsonl")
+//	n, err := storage.Export(ctx, store, f)
+func Export(ctx context.Context, store Store, w io.Writer) (int, error) {
+	d, ok := store.(Dumper)
+	if !ok {
+		return 0, ErrDumpUnsupported
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	n := 0
+	err := d.Dump(ctx, func(r Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n++
+		return enc.Encode(r)
+	})
+	if err != nil {
+		return n, errors.Wrap(err, 0)
+	}
+	if err := bw.Flush(); err != nil {
+		return n, errors.Wrap(err, 0)
+	}
+	return n, nil
+}
+
+// Import reads records written by Export and loads them into store. Models
+// with dedicated tables should be initialized before calling Import, otherwise
+// their records are written to the default table. Returns the number of
+// records imported.
+func Import(ctx context.Context, store Store, r io.Reader) (int, error) {
+	d, ok := store.(Dumper)
+	if !ok {
+		return 0, ErrDumpUnsupported
+	}
+	dec := json.NewDecoder(r)
+	batch := make([]Record, 0, importBatchSize)
+	n := 0
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := d.Load(ctx, batch...); err != nil {
+			return err
+		}
+		n += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var rec Record
+		if err := dec.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return n, errors.Mark(ErrInvalidModel, 0).Append(err.Error())
+		}
+		if rec.Type == "" || rec.ID == "" || len(rec.Value) == 0 {
+			return n, errors.Mark(ErrInvalidModel, 0).Append("record is missing type, id or value")
+		}
+		batch = append(batch, rec)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, flush()
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+)
+
+// Op identifies the kind of write that triggered a hook.
+type Op int
+
+const (
+	OpCreate Op = iota + 1
+	OpUpdate
+	OpUpsert
+	OpDelete
+)
+
+// String returns the name of the operation.
+func (o Op) String() string {
+	switch o {
+	case OpCreate:
+		return "create"
+	case OpUpdate:
+		return "update"
+	case OpUpsert:
+		return "upsert"
+	case OpDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the operation by name, so that it's readable in JSON.
+func (o Op) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes an operation name.
+func (o *Op) UnmarshalText(b []byte) error {
+	for _, op := range []Op{OpCreate, OpUpdate, OpUpsert, OpDelete} {
+		if op.String() == string(b) {
+			*o = op
+			return nil
+		}
+	}
+	return errors.Errorf("storage: unknown op %q", b)
+}
+
+// Hook is called after models have been successfully written to a store. For
+// OpDelete, only the model's primary key is guaranteed to be populated. Errors
+// are logged, but don't fail the write, since it has already been committed.
+type Hook func(ctx context.Context, op Op, models ...Model) error
+
+// WithHooks wraps a store so that hooks are called after each write. Optional
+// interfaces, such as ModelInitializer, PageLister and Dumper, are forwarded to
+// the underlying store. Records loaded with Import don't trigger hooks.
+func WithHooks(s Store, hooks ...Hook) Store {
+	hs := &hookedStore{Store: s, hooks: hooks}
+	switch inner := s.(type) {
+	case *hookedStore:
+		hs = &hookedStore{Store: inner.Store, hooks: append(append([]Hook{}, inner.hooks...), hooks...)}
+	case *hookedDumper:
+		hs = &hookedStore{Store: inner.Store, hooks: append(append([]Hook{}, inner.hooks...), hooks...)}
+	}
+	if d, ok := hs.Store.(Dumper); ok {
+		return &hookedDumper{hookedStore: hs, dumper: d}
+	}
+	return hs
+}
+
+// AddHook registers a hook which is called after models are written via the
+// plugin. Hooks should be added during Init, by plugins which depend on
+// storage, so that they're in place before the store is used. Plugins which
+// copy the underlying Store before the hook is added won't trigger it.
+func (p *StoragePlugin) AddHook(h Hook) {
+	p.Store = WithHooks(p.Store, h)
+}
+
+type hookedStore struct {
+	Store
+	hooks []Hook
+}
+
+func (s *hookedStore) Create(ctx context.Context, models ...Model) error {
+	if err := s.Store.Create(ctx, models...); err != nil {
+		return err
+	}
+	s.notify(ctx, OpCreate, models)
+	return nil
+}
+
+func (s *hookedStore) Update(ctx context.Context, models ...Model) error {
+	if err := s.Store.Update(ctx, models...); err != nil {
+		return err
+	}
+	s.notify(ctx, OpUpdate, models)
+	return nil
+}
+
+func (s *hookedStore) Upsert(ctx context.Context, models ...Model) error {
+	if err := s.Store.Upsert(ctx, models...); err != nil {
+		return err
+	}
+	s.notify(ctx, OpUpsert, models)
+	return nil
+}
+
+func (s *hookedStore) Delete(ctx context.Context, model Model) error {
+	if err := s.Store.Delete(ctx, model); err != nil {
+		return err
+	}
+	s.notify(ctx, OpDelete, []Model{model})
+	return nil
+}
+
+func (s *hookedStore) notify(ctx context.Context, op Op, models []Model) {
+	for _, h := range s.hooks {
+		if err := h(ctx, op, models...); err != nil {
+			logging.Errorw(ctx, "storage: hook failed", "op", op.String(), "error", err)
+		}
+	}
+}
+
+// From ModelInitializer.
+func (s *hookedStore) InitModel(model Model) error {
+	if i, ok := s.Store.(ModelInitializer); ok {
+		return i.InitModel(model)
+	}
+	return nil
+}
+
+// From PageLister.
+func (s *hookedStore) ListPage(ctx context.Context, models any, filter Model, cursor string, limit int) error {
+	return ListPage(ctx, s.Store, models, filter, cursor, limit)
+}
+
+// hookedDumper is a hookedStore whose underlying store implements Dumper.
+type hookedDumper struct {
+	*hookedStore
+	dumper Dumper
+}
+
+// From Dumper.
+func (s *hookedDumper) Dump(ctx context.Context, fn func(Record) error) error {
+	return s.dumper.Dump(ctx, fn)
+}
+
+// From Dumper.
+func (s *hookedDumper) Load(ctx context.Context, records ...Record) error {
+	return s.dumper.Load(ctx, records...)
+}
@@ -0,0 +1,82 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type note struct {
+	ID   string
+	Text string
+}
+
+func (n note) PK() string { return n.ID }
+
+type hookCall struct {
+	op  storage.Op
+	ids []string
+}
+
+func TestAddHook(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	p := storage.Plugin(memstore.New()).(*storage.StoragePlugin)
+
+	var calls []hookCall
+	p.AddHook(func(_ context.Context, op storage.Op, models ...storage.Model) error {
+		c := hookCall{op: op}
+		for _, m := range models {
+			c.ids = append(c.ids, m.PK())
+		}
+		calls = append(calls, c)
+		return nil
+	})
+	var failing int
+	p.AddHook(func(context.Context, storage.Op, ...storage.Model) error {
+		failing++
+		return errors.New("hook failed")
+	})
+
+	require.NoError(t, p.Create(ctx, note{ID: "1"}, note{ID: "2"}))
+	require.NoError(t, p.Update(ctx, note{ID: "1", Text: "updated"}))
+	require.NoError(t, p.Upsert(ctx, note{ID: "3"}))
+	require.NoError(t, p.Delete(ctx, note{ID: "2"}))
+
+	// Failed writes don't trigger hooks.
+	require.Error(t, p.Create(ctx, note{ID: "1"}))
+
+	assert.Equal(t, []hookCall{
+		{op: storage.OpCreate, ids: []string{"1", "2"}},
+		{op: storage.OpUpdate, ids: []string{"1"}},
+		{op: storage.OpUpsert, ids: []string{"3"}},
+		{op: storage.OpDelete, ids: []string{"2"}},
+	}, calls)
+	assert.Equal(t, 4, failing, "hook errors don't fail writes")
+
+	var n note
+	require.NoError(t, p.Read(ctx, "1", &n))
+	assert.Equal(t, "updated", n.Text)
+}
+
+func TestWithHooks_OptionalInterfaces(t *testing.T) {
+	s := storage.WithHooks(memstore.New())
+	_, ok := s.(storage.Dumper)
+	assert.True(t, ok, "Dumper is forwarded when the store supports it")
+	_, ok = s.(storage.ModelInitializer)
+	assert.True(t, ok)
+
+	s = storage.WithHooks(plainStore{Store: memstore.New()})
+	_, ok = s.(storage.Dumper)
+	assert.False(t, ok, "Dumper isn't exposed when the store doesn't support it")
+}
+
+// plainStore hides optional interfaces of the underlying store.
+type plainStore struct {
+	storage.Store
+}
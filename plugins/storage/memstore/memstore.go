@@ -147,6 +147,15 @@ func (s *store) Delete(ctx context.Context, model storage.Model) error {
 
 // List always performs a full scan of all items.
 func (s *store) List(ctx context.Context, models interface{}, filter storage.Model) error {
+	return s.list(ctx, models, filter, "", 0)
+}
+
+// From storage.PageLister.
+func (s *store) ListPage(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
+	return s.list(ctx, models, filter, cursor, limit)
+}
+
+func (s *store) list(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -172,11 +181,18 @@ func (s *store) List(ctx context.Context, models interface{}, filter storage.Mod
 		pks = append(pks, pk)
 	}
 	sort.Strings(pks)
+	if cursor != "" {
+		pks = pks[sort.Search(len(pks), func(i int) bool { return pks[i] > cursor }):]
+	}
 
 	filterValue := reflect.ValueOf(filter)
 
 	// Fetch and filter models.
+	found := 0
 	for _, pk := range pks {
+		if limit > 0 && found == limit {
+			break
+		}
 		newElemPtr := reflect.New(elemType)
 		newElem := newElemPtr.Elem()
 		if err := s.Read(ctx, pk, newElemPtr.Interface().(storage.Model)); err != nil {
@@ -196,6 +212,7 @@ func (s *store) List(ctx context.Context, models interface{}, filter storage.Mod
 		}
 		if !skip {
 			sliceVal.Set(reflect.Append(sliceVal, newElem))
+			found++
 		}
 	}
 
@@ -216,6 +233,48 @@ func (s *store) Exists(ctx context.Context, id string, model storage.Model) (boo
 	return true, nil
 }
 
+// From storage.Dumper interface.
+func (s *store) Dump(ctx context.Context, fn func(storage.Record) error) error {
+	s.mu.RLock()
+	var records []storage.Record
+	for _, n := range sortedKeys(s.data) {
+		for _, id := range sortedKeys(s.data[n]) {
+			records = append(records, storage.Record{Type: n, ID: id, Value: s.data[n][id]})
+		}
+	}
+	s.mu.RUnlock()
+
+	// Callbacks run without the lock held, so they may access the store.
+	for _, r := range records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// From storage.Dumper interface.
+func (s *store) Load(ctx context.Context, records ...storage.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range records {
+		if s.data[r.Type] == nil {
+			s.data[r.Type] = map[string][]byte{}
+		}
+		s.data[r.Type][r.ID] = append([]byte(nil), r.Value...)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // shouldFilter returns true for non-zero values and non-nil pointers.
 func shouldFilter(v reflect.Value) bool {
 	switch v.Kind() {
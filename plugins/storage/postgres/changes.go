@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/lib/pq"
+)
+
+// Postgres rejects NOTIFY payloads of 8000 bytes or more.
+const maxNotifyPayload = 7999
+
+// How often the listener connection is checked when there are no changes.
+const listenerPingInterval = time.Minute
+
+// WithChangeChannel sets the channel used to propagate changes between
+// instances with LISTEN/NOTIFY, when the storage plugin is configured with
+// storage.WithChangeEvents. Defaults to "<prefix>changes".
+//
+// Listening requires a session level connection, so won't work through
+// connection poolers in transaction mode.
+func WithChangeChannel(channel string) Option {
+	return func(s *store) {
+		s.changeChannel = channel
+	}
+}
+
+func (s *store) notifyChannel() string {
+	if s.changeChannel != "" {
+		return s.changeChannel
+	}
+	return s.prefix + "changes"
+}
+
+// From storage.ChangeNotifier. Changes are sent with NOTIFY, and payloads which
+// would exceed the NOTIFY size limit are dropped.
+func (s *store) NotifyChanges(ctx context.Context, changes ...storage.Change) error {
+	for _, c := range changes {
+		b, err := json.Marshal(c)
+		if err != nil {
+			return errors.Wrap(err, 0)
+		}
+		if len(b) > maxNotifyPayload {
+			c.Payload = nil
+			if b, err = json.Marshal(c); err != nil {
+				return errors.Wrap(err, 0)
+			}
+		}
+		if _, err := s.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", s.notifyChannel(), string(b)); err != nil {
+			return errors.Wrap(err, 0)
+		}
+	}
+	return nil
+}
+
+// From storage.ChangeNotifier. Listens on a dedicated connection, which is
+// reestablished if it is lost. Changes made while disconnected are missed.
+func (s *store) ListenChanges(ctx context.Context, fn func(context.Context, storage.Change)) error {
+	l := pq.NewListener(s.connString, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			logging.Warnw(ctx, "postgres: change listener error", "event", ev, "error", err)
+		}
+	})
+	if err := l.Listen(s.notifyChannel()); err != nil {
+		l.Close()
+		return errors.Wrap(err, 0)
+	}
+	go s.receiveChanges(ctx, l, fn)
+	return nil
+}
+
+func (s *store) receiveChanges(ctx context.Context, l *pq.Listener, fn func(context.Context, storage.Change)) {
+	defer l.Close()
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			go l.Ping() //nolint:errcheck // Failures are reported to the event callback.
+		case n, ok := <-l.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				logging.Warn(ctx, "postgres: change listener reconnected, changes may have been missed")
+				continue
+			}
+			var c storage.Change
+			if err := json.Unmarshal([]byte(n.Extra), &c); err != nil {
+				logging.Warnw(ctx, "postgres: invalid change notification", "error", err)
+				continue
+			}
+			fn(ctx, c)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyChanges(t *testing.T) {
+	s, mock := newMockStore(t)
+	defer s.db.Close()
+
+	small := storage.Change{Op: storage.OpUpdate, Model: "test_models", ID: "1", Payload: json.RawMessage(`{"ID":"1"}`)}
+	large := storage.Change{Op: storage.OpCreate, Model: "test_models", ID: "2",
+		Payload: json.RawMessage(`"` + strings.Repeat("x", maxNotifyPayload) + `"`)}
+
+	b, _ := json.Marshal(small)
+	mock.ExpectExec("SELECT pg_notify").WithArgs("test_changes", string(b)).WillReturnResult(sqlmock.NewResult(0, 0))
+	// Large payloads are dropped, so that the notification fits.
+	mock.ExpectExec("SELECT pg_notify").
+		WithArgs("test_changes", `{"op":"create","model":"test_models","id":"2"}`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, s.NotifyChanges(t.Context(), small, large))
+
+	s.changeChannel = "custom"
+	mock.ExpectExec("SELECT pg_notify").WithArgs("custom", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+	require.NoError(t, s.NotifyChanges(t.Context(), small))
+
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Implements(t, (*storage.ChangeNotifier)(nil), s)
+}
@@ -13,6 +13,9 @@
 //		postgres.WithPrefix("prefab_"),
 //	)
 //
+//	// Send reads to replicas, and writes to the primary
+//	store := postgres.New(primaryDSN, postgres.WithReadReplica(replicaDSN))
+//
 //	// Use with the storage plugin
 //	server := prefab.New(
 //		prefab.WithPlugin(storage.Plugin(store)),
@@ -26,7 +29,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/storage"
@@ -86,15 +91,34 @@ func SafeNew(connString string, opts ...Option) (storage.Store, error) {
 	}
 
 	s := &store{
-		db:               db,
-		prefix:           "prefab_",
-		schema:           "public",
-		tables:           map[string]bool{},
-		autoCreateTables: true, // Default to automatically creating tables
+		db:                   db,
+		connString:           connString,
+		prefix:               "prefab_",
+		schema:               "public",
+		tables:               map[string]bool{},
+		autoCreateTables:     true, // Default to automatically creating tables
+		replicaRetryInterval: defaultReplicaRetryInterval,
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	if len(s.replicaConnStrings) > 0 {
+		// Replicas aren't pinged, so that an unavailable replica doesn't prevent
+		// startup. Reads go to the primary until it is reachable.
+		replicas := make([]*sql.DB, 0, len(s.replicaConnStrings))
+		for _, conn := range s.replicaConnStrings {
+			rdb, err := sql.Open("postgres", conn)
+			if err != nil {
+				db.Close()
+				for _, r := range replicas {
+					r.Close()
+				}
+				return nil, fmt.Errorf("failed to open PostgreSQL replica connection: %w", err)
+			}
+			replicas = append(replicas, rdb)
+		}
+		s.replicas = newReplicaSet(replicas, s.replicaRetryInterval)
+	}
 	if s.autoCreateTables {
 		if err := s.ensureDefaultTable(); err != nil {
 			db.Close()
@@ -106,10 +130,19 @@ func SafeNew(connString string, opts ...Option) (storage.Store, error) {
 
 type store struct {
 	db               *sql.DB
+	connString       string
 	prefix           string
 	schema           string
 	tables           map[string]bool
 	autoCreateTables bool
+
+	replicaConnStrings   []string
+	replicaRetryInterval time.Duration
+	replicas             *replicaSet
+	readYourWrites       time.Duration
+	writes               writeTracker
+
+	changeChannel string
 }
 
 // From ModelInitializer interface. Sets up dedicated table for the model.
@@ -144,10 +177,10 @@ func (s *store) Read(ctx context.Context, id string, model storage.Model) error
 		args = []interface{}{id}
 	}
 
-	row := s.db.QueryRowContext(ctx, query, args...)
-
 	var value []byte
-	err := row.Scan(&value)
+	err := s.read(ctx, storage.Name(model), func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, query, args...).Scan(&value)
+	})
 	if err != nil {
 		return translateError(err)
 	}
@@ -194,6 +227,7 @@ func (s *store) Update(ctx context.Context, models ...storage.Model) error {
 		tx.Rollback()
 		return translateError(err)
 	}
+	s.recordWrite(modelNames(models)...)
 
 	return nil
 }
@@ -228,11 +262,21 @@ func (s *store) Delete(ctx context.Context, model storage.Model) error {
 	if i, errAff := res.RowsAffected(); i == 0 || errAff != nil {
 		return errors.Wrap(storage.ErrNotFound, 0)
 	}
+	s.recordWrite(storage.Name(model))
 
 	return nil
 }
 
 func (s *store) List(ctx context.Context, models any, filter storage.Model) error {
+	return s.list(ctx, models, filter, "", 0)
+}
+
+// From storage.PageLister.
+func (s *store) ListPage(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
+	return s.list(ctx, models, filter, cursor, limit)
+}
+
+func (s *store) list(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
 	modelsVal := reflect.ValueOf(models)
 	if modelsVal.Kind() != reflect.Ptr || modelsVal.Elem().Kind() != reflect.Slice {
 		return storage.ErrSliceRequired
@@ -243,19 +287,29 @@ func (s *store) List(ctx context.Context, models any, filter storage.Model) erro
 		return storage.ErrTypeMismatch
 	}
 
-	query, args := s.buildListQuery(filter)
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	query, args := s.buildPageQuery(filter, cursor, limit)
+	var values []string
+	err := s.read(ctx, storage.Name(filter), func(db *sql.DB) error {
+		values = values[:0]
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var value string
+			if err := rows.Scan(&value); err != nil {
+				return err
+			}
+			values = append(values, value)
+		}
+		return rows.Err()
+	})
 	if err != nil {
 		return translateError(err)
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var value string
-		if err := rows.Scan(&value); err != nil {
-			return translateError(err)
-		}
 
+	for _, value := range values {
 		newElemPtr := reflect.New(elemType)
 		newElem := newElemPtr.Elem()
 		err := json.Unmarshal([]byte(value), newElem.Addr().Interface())
@@ -268,10 +322,6 @@ func (s *store) List(ctx context.Context, models any, filter storage.Model) erro
 		sliceVal.Set(reflect.Append(sliceVal, newElem))
 	}
 
-	if err := rows.Err(); err != nil {
-		return translateError(err)
-	}
-
 	return nil
 }
 
@@ -288,7 +338,9 @@ func (s *store) Exists(ctx context.Context, id string, model storage.Model) (boo
 	}
 
 	var count int
-	err := s.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	err := s.read(ctx, storage.Name(model), func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, query, args...).Scan(&count)
+	})
 	if err != nil {
 		return false, translateError(err)
 	}
@@ -296,6 +348,152 @@ func (s *store) Exists(ctx context.Context, id string, model storage.Model) (boo
 	return count > 0, nil
 }
 
+// From storage.Dumper interface. Dumps the default table followed by each
+// dedicated table in the schema, including tables created by previous
+// processes.
+func (s *store) Dump(ctx context.Context, fn func(storage.Record) error) error {
+	err := dumpRows(ctx, s.db, fn, "",
+		"SELECT entity_type, id, value FROM "+s.schema+"."+s.prefix+"default ORDER BY entity_type, id")
+	if err != nil {
+		return err
+	}
+
+	types, err := s.dedicatedTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		err := dumpRows(ctx, s.db, fn, t, "SELECT id, value FROM "+s.schema+"."+s.prefix+t+" ORDER BY id")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// From storage.Dumper interface.
+func (s *store) Load(ctx context.Context, records ...storage.Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, r := range records {
+		if s.tables[r.Type] {
+			_, err = prepareAndExec(ctx, tx, `
+				INSERT INTO `+s.schema+`.`+s.prefix+r.Type+` (id, value, created_at, updated_at)
+				VALUES ($1, $2, NOW(), NOW())
+				ON CONFLICT (id) DO UPDATE SET
+				value = $2, updated_at = NOW()
+			`, r.ID, []byte(r.Value))
+		} else {
+			_, err = prepareAndExec(ctx, tx, `
+				INSERT INTO `+s.schema+`.`+s.prefix+`default (id, entity_type, value, created_at, updated_at)
+				VALUES ($1, $2, $3, NOW(), NOW())
+				ON CONFLICT (id, entity_type) DO UPDATE SET
+				value = $3, updated_at = NOW()
+			`, r.ID, r.Type, []byte(r.Value))
+		}
+		if err != nil {
+			tx.Rollback()
+			return translateError(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return translateError(err)
+	}
+	for _, r := range records {
+		s.recordWrite(r.Type)
+	}
+	return nil
+}
+
+// From storage.SchemaChecker.
+func (s *store) Ping(ctx context.Context) error {
+	return translateError(s.db.PingContext(ctx))
+}
+
+// From storage.SchemaChecker.
+func (s *store) MissingTables(ctx context.Context, models ...storage.Model) ([]string, error) {
+	existing, err := s.dedicatedTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var hasDefault bool
+	err = s.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM information_schema.tables WHERE table_schema = $1 AND table_name = $2)",
+		s.schema, s.prefix+"default").Scan(&hasDefault)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	qualifier := s.schema + "." + s.prefix
+	var missing []string
+	if !hasDefault {
+		missing = append(missing, qualifier+"default")
+	}
+	for _, m := range models {
+		name := storage.Name(m)
+		if !slices.Contains(existing, name) && !slices.Contains(missing, qualifier+name) {
+			missing = append(missing, qualifier+name)
+		}
+	}
+	return missing, nil
+}
+
+// dedicatedTables returns the model names of dedicated tables in the schema.
+func (s *store) dedicatedTables(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = $1 ORDER BY table_name", s.schema)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+	var types []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, translateError(err)
+		}
+		if t, ok := strings.CutPrefix(name, s.prefix); ok && t != "default" {
+			types = append(types, t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateError(err)
+	}
+	return types, nil
+}
+
+// dumpRows calls fn for each row returned by query. If entityType is empty, the
+// query must select the entity type as its first column.
+func dumpRows(ctx context.Context, db *sql.DB, fn func(storage.Record) error, entityType, query string) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return translateError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := storage.Record{Type: entityType}
+		var value []byte
+		if entityType == "" {
+			err = rows.Scan(&r.Type, &r.ID, &value)
+		} else {
+			err = rows.Scan(&r.ID, &value)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+		r.Value = value
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
 func (s *store) tableName(model storage.Model) (string, bool) {
 	name := storage.Name(model)
 	if _, ok := s.tables[name]; !ok {
@@ -365,6 +563,7 @@ func (s *store) insert(ctx context.Context, upsert bool, models ...storage.Model
 		tx.Rollback()
 		return translateError(err)
 	}
+	s.recordWrite(modelNames(models)...)
 
 	return nil
 }
@@ -493,6 +692,12 @@ func (s *store) ensureTable(tableName string) error {
 }
 
 func (s *store) buildListQuery(model storage.Model) (string, []interface{}) {
+	return s.buildPageQuery(model, "", 0)
+}
+
+// buildPageQuery builds a list query which only returns the first limit
+// records after cursor. An empty cursor and a limit of 0 are ignored.
+func (s *store) buildPageQuery(model storage.Model, cursor string, limit int) (string, []interface{}) {
 	tableName, isDefault := s.tableName(model)
 	modelType := reflect.TypeOf(model)
 	modelValue := reflect.ValueOf(model)
@@ -530,10 +735,22 @@ func (s *store) buildListQuery(model storage.Model) (string, []interface{}) {
 		}
 	}
 
+	if cursor != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf(`id COLLATE "C" > $%d`, paramIdx))
+		args = append(args, cursor)
+		paramIdx++
+	}
+
 	query := "SELECT value FROM " + tableName
 	if len(whereClauses) > 0 {
 		query += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
+	// Order by bytes, rather than the database's collation, to match other stores.
+	query += ` ORDER BY id COLLATE "C"`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", paramIdx)
+		args = append(args, limit)
+	}
 
 	return query, args
 }
@@ -571,6 +788,15 @@ func translateError(err error) error {
 	return errors.MaybeWrap(err, 0)
 }
 
+// modelNames returns the storage names of models.
+func modelNames(models []storage.Model) []string {
+	names := make([]string, len(models))
+	for i, m := range models {
+		names[i] = storage.Name(m)
+	}
+	return names
+}
+
 func prepareAndExec(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (sql.Result, error) {
 	stmt, err := tx.PrepareContext(ctx, query)
 	if err != nil {
@@ -273,6 +273,13 @@ func TestBuildListQuery(t *testing.T) {
 		assert.Len(t, args, 1)
 		assert.Equal(t, "test", args[0])
 	})
+
+	t.Run("Page", func(t *testing.T) {
+		query, args := s.buildPageQuery(FilterModel{Name: "test"}, "abc", 10)
+		assert.Contains(t, query, `value->>'Name' = $1 AND id COLLATE "C" > $2`)
+		assert.Contains(t, query, ` ORDER BY id COLLATE "C" LIMIT $3`)
+		assert.Equal(t, []interface{}{"test", "abc", 10}, args)
+	})
 }
 
 func TestCreateWithMock(t *testing.T) {
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/lib/pq"
+)
+
+// Default time an unavailable replica is skipped before it is checked again.
+const defaultReplicaRetryInterval = 10 * time.Second
+
+// WithReadReplica adds read replicas. Read, List, and Exists are spread across
+// healthy replicas in turn, while writes, dumps, and schema changes use the
+// primary. Reads fall back to the primary if no replica is available.
+//
+// A replica which fails with a connection error is skipped, and the read is
+// retried on the primary. The replica is pinged before it is used again, see
+// WithReplicaRetryInterval.
+func WithReadReplica(connStrings ...string) Option {
+	return func(s *store) {
+		s.replicaConnStrings = append(s.replicaConnStrings, connStrings...)
+	}
+}
+
+// WithReplicaRetryInterval sets how long an unavailable replica is skipped
+// before it is checked again. Defaults to 10 seconds.
+func WithReplicaRetryInterval(interval time.Duration) Option {
+	return func(s *store) {
+		s.replicaRetryInterval = interval
+	}
+}
+
+// WithReadYourWrites sends reads of a model type to the primary for a window
+// after the type is written through the store, so that callers see their own
+// writes despite replication lag. The window should exceed the typical lag.
+func WithReadYourWrites(window time.Duration) Option {
+	return func(s *store) {
+		s.readYourWrites = window
+	}
+}
+
+type readFromPrimaryKey struct{}
+
+// ReadFromPrimary returns a context which sends reads to the primary, for
+// sequences of calls which must see a consistent view of the data, such as a
+// read-modify-write.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readFromPrimaryKey{}, true)
+}
+
+// replica is a read replica, which is skipped until retryAt if it has failed.
+type replica struct {
+	index   int
+	db      *sql.DB
+	retryAt atomic.Int64 // Unix nanoseconds, zero if healthy.
+}
+
+// available returns true if the replica can be used. A replica that failed is
+// pinged once its retry interval has passed.
+func (r *replica) available(ctx context.Context, interval time.Duration) bool {
+	at := r.retryAt.Load()
+	if at == 0 {
+		return true
+	}
+	now := time.Now()
+	// Only one caller checks the replica, others continue to skip it.
+	if now.UnixNano() < at || !r.retryAt.CompareAndSwap(at, now.Add(interval).UnixNano()) {
+		return false
+	}
+	if err := r.db.PingContext(ctx); err != nil {
+		return false
+	}
+	r.retryAt.Store(0)
+	logging.Infow(ctx, "postgres: read replica available", "replica", r.index)
+	return true
+}
+
+func (r *replica) fail(ctx context.Context, err error, interval time.Duration) {
+	r.retryAt.Store(time.Now().Add(interval).UnixNano())
+	logging.Warnw(ctx, "postgres: read replica unavailable", "replica", r.index, "error", err)
+}
+
+// replicaSet picks replicas in turn.
+type replicaSet struct {
+	replicas      []*replica
+	next          atomic.Uint64
+	retryInterval time.Duration
+}
+
+func newReplicaSet(dbs []*sql.DB, retryInterval time.Duration) *replicaSet {
+	rs := &replicaSet{retryInterval: retryInterval}
+	for i, db := range dbs {
+		rs.replicas = append(rs.replicas, &replica{index: i, db: db})
+	}
+	return rs
+}
+
+// pick returns the next available replica, or nil if there are none.
+func (rs *replicaSet) pick(ctx context.Context) *replica {
+	n := uint64(len(rs.replicas))
+	start := rs.next.Add(1)
+	for i := range n {
+		if r := rs.replicas[(start+i)%n]; r.available(ctx, rs.retryInterval) {
+			return r
+		}
+	}
+	return nil
+}
+
+// writeTracker records when model types were last written, for
+// WithReadYourWrites.
+type writeTracker struct {
+	mu      sync.Mutex
+	written map[string]time.Time
+}
+
+func (w *writeTracker) record(entityTypes ...string) {
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.written == nil {
+		w.written = map[string]time.Time{}
+	}
+	for _, t := range entityTypes {
+		w.written[t] = now
+	}
+}
+
+func (w *writeTracker) writtenWithin(entityType string, window time.Duration) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	at, ok := w.written[entityType]
+	return ok && time.Since(at) < window
+}
+
+// recordWrite notes that model types were written, for WithReadYourWrites.
+func (s *store) recordWrite(entityTypes ...string) {
+	if s.replicas != nil && s.readYourWrites > 0 {
+		s.writes.record(entityTypes...)
+	}
+}
+
+// read runs fn against a replica if one is available and the read needn't go
+// to the primary, otherwise against the primary. Reads which fail due to the
+// replica being unavailable are retried on the primary.
+func (s *store) read(ctx context.Context, entityType string, fn func(db *sql.DB) error) error {
+	if s.replicas == nil || ctx.Value(readFromPrimaryKey{}) != nil ||
+		(s.readYourWrites > 0 && s.writes.writtenWithin(entityType, s.readYourWrites)) {
+		return fn(s.db)
+	}
+	r := s.replicas.pick(ctx)
+	if r == nil {
+		return fn(s.db)
+	}
+	err := fn(r.db)
+	if err != nil && isConnError(err) {
+		r.fail(ctx, err, s.replicas.retryInterval)
+		return fn(s.db)
+	}
+	return err
+}
+
+// isConnError returns true if the error indicates the database couldn't be
+// reached, rather than a problem with the query.
+func isConnError(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exceptions, and class 57 operator intervention,
+		// such as the server shutting down, other than canceled queries.
+		return pqErr.Code.Class() == "08" || (pqErr.Code.Class() == "57" && pqErr.Code != "57014")
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type OtherModel struct {
+	ID string
+}
+
+func (m OtherModel) PK() string { return m.ID }
+
+// newReplicatedMockStore returns a mock store with a primary and n replicas.
+func newReplicatedMockStore(t *testing.T, n int) (*store, sqlmock.Sqlmock, []sqlmock.Sqlmock) {
+	s, primary := newMockStore(t)
+	t.Cleanup(func() { s.db.Close() })
+	s.replicaRetryInterval = time.Hour
+
+	var mocks []sqlmock.Sqlmock
+	var dbs []*sql.DB
+	for range n {
+		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		dbs = append(dbs, db)
+		mocks = append(mocks, mock)
+	}
+	s.replicas = newReplicaSet(dbs, s.replicaRetryInterval)
+	return s, primary, mocks
+}
+
+func expectRead(mock sqlmock.Sqlmock, id string) {
+	data, _ := json.Marshal(TestModel{ID: id})
+	mock.ExpectQuery("SELECT value FROM").
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(data))
+}
+
+func TestReadReplicas_RoundRobin(t *testing.T) {
+	s, primary, replicas := newReplicatedMockStore(t, 2)
+
+	for _, r := range replicas {
+		expectRead(r, "1")
+	}
+	for range 2 {
+		var m TestModel
+		require.NoError(t, s.Read(t.Context(), "1", &m))
+		assert.Equal(t, "1", m.ID)
+	}
+
+	replicas[1].ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	exists, err := s.Exists(t.Context(), "1", &TestModel{})
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	replicas[0].ExpectQuery("SELECT value FROM").WillReturnRows(sqlmock.NewRows([]string{"value"}))
+	var list []TestModel
+	require.NoError(t, s.List(t.Context(), &list, TestModel{}))
+
+	// Writes go to the primary.
+	primary.ExpectPrepare("DELETE FROM").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, s.Delete(t.Context(), TestModel{ID: "1"}))
+
+	require.NoError(t, primary.ExpectationsWereMet())
+	for _, r := range replicas {
+		require.NoError(t, r.ExpectationsWereMet())
+	}
+}
+
+func TestReadReplicas_ReadFromPrimary(t *testing.T) {
+	s, primary, replicas := newReplicatedMockStore(t, 1)
+
+	expectRead(primary, "1")
+	var m TestModel
+	require.NoError(t, s.Read(ReadFromPrimary(t.Context()), "1", &m))
+
+	require.NoError(t, primary.ExpectationsWereMet())
+	require.NoError(t, replicas[0].ExpectationsWereMet())
+}
+
+func TestReadReplicas_ReadYourWrites(t *testing.T) {
+	s, primary, replicas := newReplicatedMockStore(t, 1)
+	s.readYourWrites = time.Minute
+
+	primary.ExpectPrepare("DELETE FROM").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, s.Delete(t.Context(), TestModel{ID: "1"}))
+
+	// Reads of the written type go to the primary, others to the replica.
+	expectRead(primary, "2")
+	var m TestModel
+	require.NoError(t, s.Read(t.Context(), "2", &m))
+
+	replicas[0].ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	_, err := s.Exists(t.Context(), "1", OtherModel{})
+	require.NoError(t, err)
+
+	// Once the window has passed, reads go to the replica again.
+	s.writes.written[storage.Name(TestModel{})] = time.Now().Add(-time.Hour)
+	expectRead(replicas[0], "2")
+	require.NoError(t, s.Read(t.Context(), "2", &m))
+
+	require.NoError(t, primary.ExpectationsWereMet())
+	require.NoError(t, replicas[0].ExpectationsWereMet())
+}
+
+func TestReadReplicas_Failover(t *testing.T) {
+	s, primary, replicas := newReplicatedMockStore(t, 1)
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	connErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	// The read is retried on the primary, and the replica is skipped.
+	replicas[0].ExpectQuery("SELECT value FROM").WillReturnError(connErr)
+	expectRead(primary, "1")
+	expectRead(primary, "1")
+	var m TestModel
+	require.NoError(t, s.Read(ctx, "1", &m))
+	require.NoError(t, s.Read(ctx, "1", &m))
+
+	// Query errors aren't retried.
+	s.replicas.replicas[0].retryAt.Store(0)
+	replicas[0].ExpectQuery("SELECT value FROM").WillReturnError(errors.New("syntax error"))
+	require.Error(t, s.Read(ctx, "1", &m))
+	assert.Zero(t, s.replicas.replicas[0].retryAt.Load())
+
+	// After the retry interval, the replica is pinged before it is used.
+	s.replicas.replicas[0].retryAt.Store(time.Now().Add(-time.Second).UnixNano())
+	replicas[0].ExpectPing()
+	expectRead(replicas[0], "1")
+	require.NoError(t, s.Read(ctx, "1", &m))
+	assert.Zero(t, s.replicas.replicas[0].retryAt.Load())
+
+	require.NoError(t, primary.ExpectationsWereMet())
+	require.NoError(t, replicas[0].ExpectationsWereMet())
+}
+
+func TestIsConnError(t *testing.T) {
+	assert.True(t, isConnError(driver.ErrBadConn))
+	assert.True(t, isConnError(errors.Wrap(&net.OpError{Op: "dial", Err: errors.New("refused")}, 0)))
+	assert.True(t, isConnError(&pq.Error{Code: "08006"}))
+	assert.True(t, isConnError(&pq.Error{Code: "57P01"}))
+	assert.False(t, isConnError(&pq.Error{Code: "57014"}), "canceled queries aren't connection errors")
+	assert.False(t, isConnError(&pq.Error{Code: "23505"}))
+	assert.False(t, isConnError(context.Canceled))
+}
@@ -0,0 +1,32 @@
+//go:build !sqlcipher
+
+package sqlite
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/dpup/prefab/errors"
+)
+
+// driverName is the database/sql driver used to open databases, registered by
+// modernc.org/sqlite.
+const driverName = "sqlite"
+
+// connParams returns the connection string parameters for the store's
+// options. Pragmas are set via the connection string so that they apply to
+// every connection in the pool.
+func connParams(s *store) (url.Values, error) {
+	if s.encryptionKey != "" {
+		return nil, errors.New("sqlite: WithEncryptionKey requires building with -tags sqlcipher")
+	}
+	params := url.Values{}
+	// The busy timeout is set first, as enabling WAL requires a lock.
+	if s.busyTimeout > 0 {
+		params.Add("_pragma", "busy_timeout("+strconv.FormatInt(s.busyTimeout.Milliseconds(), 10)+")")
+	}
+	if s.wal {
+		params.Add("_pragma", "journal_mode(WAL)")
+	}
+	return params, nil
+}
@@ -0,0 +1,29 @@
+//go:build sqlcipher
+
+package sqlite
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// driverName is the database/sql driver used to open databases. When built
+// with the `sqlcipher` tag, the application must import a SQLCipher driver
+// which registers as "sqlite3", such as github.com/mutecomm/go-sqlcipher/v4.
+const driverName = "sqlite3"
+
+// connParams returns the connection string parameters for the store's
+// options, using the parameter names of mattn/go-sqlite3 derived drivers.
+func connParams(s *store) (url.Values, error) {
+	params := url.Values{}
+	if s.encryptionKey != "" {
+		params.Set("_pragma_key", s.encryptionKey)
+	}
+	if s.busyTimeout > 0 {
+		params.Set("_busy_timeout", strconv.FormatInt(s.busyTimeout.Milliseconds(), 10))
+	}
+	if s.wal {
+		params.Set("_journal_mode", "WAL")
+	}
+	return params, nil
+}
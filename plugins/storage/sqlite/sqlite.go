@@ -10,6 +10,20 @@
 //
 //	store := sqlitestore.New(":memory:")
 //
+// For production use with a database file, enable write-ahead logging and a
+// busy timeout, and serialize writes to avoid SQLITE_BUSY errors under
+// concurrency:
+//
+//	store := sqlitestore.New(
+//		"file:app.s3db",
+//		sqlitestore.WithWAL(true),
+//		sqlitestore.WithBusyTimeout(5*time.Second),
+//		sqlitestore.WithSingleWriter(true),
+//	)
+//
+// Databases can be encrypted with WithEncryptionKey when built with the
+// `sqlcipher` tag, see sqlcipher.go.
+//
 //nolint:gosec // Reports on G202. SQL string concat used to parameterize table.
 package sqlite
 
@@ -18,8 +32,12 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/plugins/storage"
@@ -38,22 +56,76 @@ func WithPrefix(prefix string) Option {
 	}
 }
 
+// WithWAL controls whether write-ahead logging is enabled, which allows reads
+// to proceed while a write is in progress. Has no effect on in-memory
+// databases.
+func WithWAL(enabled bool) Option {
+	return func(s *store) {
+		s.wal = enabled
+	}
+}
+
+// WithBusyTimeout sets how long a connection waits for a lock held by another
+// connection before failing with SQLITE_BUSY. By default, it fails
+// immediately.
+func WithBusyTimeout(timeout time.Duration) Option {
+	return func(s *store) {
+		s.busyTimeout = timeout
+	}
+}
+
+// WithSingleWriter controls whether writes are serialized within the process.
+// SQLite allows a single writer at a time, so concurrent writes otherwise
+// contend for the database lock and may fail with SQLITE_BUSY.
+func WithSingleWriter(enabled bool) Option {
+	return func(s *store) {
+		s.singleWriter = enabled
+	}
+}
+
+// WithStatementCache controls whether prepared statements are reused across
+// calls. Enabled by default, except for in-memory databases.
+func WithStatementCache(enabled bool) Option {
+	return func(s *store) {
+		s.cacheStatements = enabled
+	}
+}
+
+// WithEncryptionKey sets the key for an encrypted database. Requires building
+// with the `sqlcipher` tag, otherwise New panics.
+func WithEncryptionKey(key string) Option {
+	return func(s *store) {
+		s.encryptionKey = key
+	}
+}
+
 // New returns a store that provides sqlite backed storage, the table will be
 // created optimistically on initialization. Any errors are considered
 // non-recoverable and will panic.
 func New(conn string, opts ...Option) storage.Store {
-	db, err := sql.Open("sqlite", conn)
-	if err != nil {
-		panic("failed to open sqlite connection: " + err.Error())
-	}
 	s := &store{
-		db:     db,
-		prefix: "prefab_",
-		tables: map[string]bool{},
+		prefix:          "prefab_",
+		tables:          map[string]bool{},
+		cacheStatements: true,
+		stmts:           map[string]*sql.Stmt{},
 	}
 	for _, opt := range opts {
 		opt(s)
 	}
+	params, err := connParams(s)
+	if err != nil {
+		panic(err.Error())
+	}
+	db, err := sql.Open(driverName, withParams(conn, params))
+	if err != nil {
+		panic("failed to open sqlite connection: " + err.Error())
+	}
+	if isMemory(conn) {
+		// Each connection to an in-memory database opens a separate database, so
+		// statements prepared on one connection can't be used on another.
+		s.cacheStatements = false
+	}
+	s.db = db
 	s.ensureDefaultTable()
 	return s
 }
@@ -62,6 +134,17 @@ type store struct {
 	db     *sql.DB
 	prefix string
 	tables map[string]bool
+
+	wal           bool
+	busyTimeout   time.Duration
+	encryptionKey string
+
+	singleWriter bool
+	writeMu      sync.Mutex
+
+	cacheStatements bool
+	stmtMu          sync.Mutex
+	stmts           map[string]*sql.Stmt
 }
 
 // From ModelInitializer interface. Sets up dedicated for the model.
@@ -86,7 +169,7 @@ func (s *store) Read(ctx context.Context, id string, model storage.Model) error
 	} else {
 		query = "SELECT value FROM " + tableName + " WHERE id = ?"
 	}
-	row := s.db.QueryRowContext(ctx, query, id, storage.Name(model))
+	row := s.queryRow(ctx, query, id, storage.Name(model))
 
 	var value []byte
 	err := row.Scan(&value)
@@ -98,6 +181,7 @@ func (s *store) Read(ctx context.Context, id string, model storage.Model) error
 }
 
 func (s *store) Update(ctx context.Context, models ...storage.Model) error {
+	defer s.lockWrites()()
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -114,11 +198,11 @@ func (s *store) Update(ctx context.Context, models ...storage.Model) error {
 
 		var res sql.Result
 		if tableName, isDefault := s.tableName(model); isDefault {
-			res, err = prepareAndExec(ctx, tx,
+			res, err = s.exec(ctx, tx,
 				"UPDATE "+tableName+" SET value = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ? AND entity_type = ?",
 				value, id, entityType)
 		} else {
-			res, err = prepareAndExec(ctx, tx,
+			res, err = s.exec(ctx, tx,
 				"UPDATE "+tableName+" SET value = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?",
 				value, id)
 		}
@@ -145,25 +229,17 @@ func (s *store) Upsert(ctx context.Context, models ...storage.Model) error {
 }
 
 func (s *store) Delete(ctx context.Context, model storage.Model) error {
-	var params []any
-	var stmt *sql.Stmt
+	defer s.lockWrites()()
+	var res sql.Result
 	var err error
 	if tableName, isDefault := s.tableName(model); isDefault {
-		stmt, err = s.db.PrepareContext(ctx, "DELETE FROM "+tableName+" WHERE id = ? AND entity_type = ?")
-		params = []any{model.PK(), storage.Name(model)}
+		res, err = s.exec(ctx, nil, "DELETE FROM "+tableName+" WHERE id = ? AND entity_type = ?", model.PK(), storage.Name(model))
 	} else {
-		stmt, err = s.db.PrepareContext(ctx, "DELETE FROM "+tableName+" WHERE id = ?")
-		params = []any{model.PK()}
+		res, err = s.exec(ctx, nil, "DELETE FROM "+tableName+" WHERE id = ?", model.PK())
 	}
 	if err != nil {
 		return translateError(err)
 	}
-	defer stmt.Close()
-
-	res, err := stmt.ExecContext(ctx, params...)
-	if err != nil {
-		return translateError(err)
-	}
 	if i, err := res.RowsAffected(); i == 0 || err != nil {
 		return errors.Mark(storage.ErrNotFound, 0)
 	}
@@ -171,6 +247,15 @@ func (s *store) Delete(ctx context.Context, model storage.Model) error {
 }
 
 func (s *store) List(ctx context.Context, models any, filter storage.Model) error {
+	return s.list(ctx, models, filter, "", 0)
+}
+
+// From storage.PageLister.
+func (s *store) ListPage(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
+	return s.list(ctx, models, filter, cursor, limit)
+}
+
+func (s *store) list(ctx context.Context, models any, filter storage.Model, cursor string, limit int) error {
 	modelsVal := reflect.ValueOf(models)
 	if modelsVal.Kind() != reflect.Ptr || modelsVal.Elem().Kind() != reflect.Slice {
 		return storage.ErrSliceRequired
@@ -181,8 +266,8 @@ func (s *store) List(ctx context.Context, models any, filter storage.Model) erro
 		return storage.ErrTypeMismatch
 	}
 
-	query, args := s.buildListQuery(filter)
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	query, args := s.buildPageQuery(filter, cursor, limit)
+	rows, err := s.query(ctx, query, args...)
 	if err != nil {
 		return translateError(err)
 	}
@@ -222,13 +307,133 @@ func (s *store) Exists(ctx context.Context, id string, model storage.Model) (boo
 	}
 
 	var value int
-	err := s.db.QueryRowContext(ctx, query, id, storage.Name(model)).Scan(&value)
+	err := s.queryRow(ctx, query, id, storage.Name(model)).Scan(&value)
 	if err != nil {
 		return false, translateError(err)
 	}
 	return value > 0, nil
 }
 
+// From storage.Dumper interface. Dumps the default table followed by each
+// dedicated table, including tables created by previous processes.
+func (s *store) Dump(ctx context.Context, fn func(storage.Record) error) error {
+	err := dumpRows(ctx, s.db, fn, "",
+		"SELECT entity_type, id, value FROM "+s.prefix+"default ORDER BY entity_type, id")
+	if err != nil {
+		return err
+	}
+
+	types, err := s.dedicatedTables(ctx)
+	if err != nil {
+		return err
+	}
+	for _, t := range types {
+		if err := dumpRows(ctx, s.db, fn, t, "SELECT id, value FROM "+s.prefix+t+" ORDER BY id"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// From storage.Dumper interface.
+func (s *store) Load(ctx context.Context, records ...storage.Record) error {
+	defer s.lockWrites()()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return translateError(err)
+	}
+	for _, r := range records {
+		if s.tables[r.Type] {
+			_, err = s.exec(ctx, tx, `INSERT INTO `+s.prefix+r.Type+` (id, value, created_at, updated_at)
+				VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+				ON CONFLICT(id) DO UPDATE SET
+				value = excluded.value, updated_at = CURRENT_TIMESTAMP`, r.ID, []byte(r.Value))
+		} else {
+			_, err = s.exec(ctx, tx, `INSERT INTO `+s.prefix+`default (id, entity_type, value, created_at, updated_at)
+				VALUES (?, ?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+				ON CONFLICT(id, entity_type) DO UPDATE SET
+				value = excluded.value, updated_at = CURRENT_TIMESTAMP`, r.ID, r.Type, []byte(r.Value))
+		}
+		if err != nil {
+			tx.Rollback()
+			return translateError(err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tx.Rollback()
+		return translateError(err)
+	}
+	return nil
+}
+
+// From storage.SchemaChecker.
+func (s *store) Ping(ctx context.Context) error {
+	return translateError(s.db.PingContext(ctx))
+}
+
+// From storage.SchemaChecker.
+func (s *store) MissingTables(ctx context.Context, models ...storage.Model) ([]string, error) {
+	existing, err := s.dedicatedTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var missing []string
+	for _, m := range models {
+		name := storage.Name(m)
+		if !slices.Contains(existing, name) && !slices.Contains(missing, s.prefix+name) {
+			missing = append(missing, s.prefix+name)
+		}
+	}
+	return missing, nil
+}
+
+// dedicatedTables returns the model names of dedicated tables in the database.
+func (s *store) dedicatedTables(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' ORDER BY name")
+	if err != nil {
+		return nil, translateError(err)
+	}
+	defer rows.Close()
+	var types []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, translateError(err)
+		}
+		if t, ok := strings.CutPrefix(name, s.prefix); ok && t != "default" {
+			types = append(types, t)
+		}
+	}
+	return types, translateError(rows.Err())
+}
+
+// dumpRows calls fn for each row returned by query. If entityType is empty, the
+// query must select the entity type as its first column.
+func dumpRows(ctx context.Context, db *sql.DB, fn func(storage.Record) error, entityType, query string) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return translateError(err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := storage.Record{Type: entityType}
+		var value []byte
+		if entityType == "" {
+			err = rows.Scan(&r.Type, &r.ID, &value)
+		} else {
+			err = rows.Scan(&r.ID, &value)
+		}
+		if err != nil {
+			return translateError(err)
+		}
+		r.Value = value
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return translateError(rows.Err())
+}
+
 func (s *store) tableName(model storage.Model) (string, bool) {
 	name := storage.Name(model)
 	if _, ok := s.tables[name]; !ok {
@@ -238,6 +443,7 @@ func (s *store) tableName(model storage.Model) (string, bool) {
 }
 
 func (s *store) insert(ctx context.Context, upsert bool, models ...storage.Model) error {
+	defer s.lockWrites()()
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return translateError(err)
@@ -260,7 +466,7 @@ func (s *store) insert(ctx context.Context, upsert bool, models ...storage.Model
 					ON CONFLICT(id, entity_type) DO UPDATE SET 
 					value = excluded.value, updated_at = CURRENT_TIMESTAMP`
 			}
-			_, err = prepareAndExec(ctx, tx, query, id, entityType, value)
+			_, err = s.exec(ctx, tx, query, id, entityType, value)
 		} else {
 			query := `INSERT INTO ` + tableName + ` (id, value, created_at, updated_at)
 				VALUES (?, ?, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)`
@@ -269,7 +475,7 @@ func (s *store) insert(ctx context.Context, upsert bool, models ...storage.Model
 					ON CONFLICT(id) DO UPDATE SET
 					value = excluded.value, updated_at = CURRENT_TIMESTAMP`
 			}
-			_, err = prepareAndExec(ctx, tx, query, id, value)
+			_, err = s.exec(ctx, tx, query, id, value)
 		}
 		if err != nil {
 			tx.Rollback()
@@ -313,6 +519,12 @@ func (s *store) ensureTable(tableName string) error {
 }
 
 func (s *store) buildListQuery(model storage.Model) (string, []any) {
+	return s.buildPageQuery(model, "", 0)
+}
+
+// buildPageQuery builds a list query which only returns the first limit
+// records after cursor. An empty cursor and a limit of 0 are ignored.
+func (s *store) buildPageQuery(model storage.Model, cursor string, limit int) (string, []any) {
 	tableName, isDefault := s.tableName(model)
 	filterValue := reflect.ValueOf(model)
 
@@ -336,10 +548,20 @@ func (s *store) buildListQuery(model storage.Model) (string, []any) {
 		}
 	}
 
+	if cursor != "" {
+		whereClauses = append(whereClauses, "id > ?")
+		params = append(params, cursor)
+	}
+
 	query := "SELECT value FROM " + tableName
 	if len(whereClauses) > 0 {
 		query += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
+	query += " ORDER BY id"
+	if limit > 0 {
+		query += " LIMIT ?"
+		params = append(params, limit)
+	}
 	return query, params
 }
 
@@ -358,14 +580,101 @@ func translateError(err error) error {
 			return errors.Mark(storage.ErrAlreadyExists, 0)
 		}
 	}
+	// Other drivers, such as SQLCipher's, are matched by message.
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return errors.Mark(storage.ErrAlreadyExists, 0)
+	}
 	return errors.MaybeWrap(err, 0)
 }
 
-func prepareAndExec(ctx context.Context, tx *sql.Tx, query string, params ...any) (sql.Result, error) {
-	stmt, err := tx.PrepareContext(ctx, query)
+// lockWrites serializes writes when WithSingleWriter is enabled, returning a
+// function which releases the lock.
+func (s *store) lockWrites() func() {
+	if !s.singleWriter {
+		return func() {}
+	}
+	s.writeMu.Lock()
+	return s.writeMu.Unlock
+}
+
+// prepare returns a prepared statement for the query from the cache, preparing
+// it if necessary.
+func (s *store) prepare(ctx context.Context, query string) (*sql.Stmt, error) {
+	s.stmtMu.Lock()
+	defer s.stmtMu.Unlock()
+	if stmt, ok := s.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := s.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	s.stmts[query] = stmt
+	return stmt, nil
+}
+
+// exec executes a statement, within the transaction if tx isn't nil.
+func (s *store) exec(ctx context.Context, tx *sql.Tx, query string, params ...any) (sql.Result, error) {
+	if s.cacheStatements {
+		stmt, err := s.prepare(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		if tx != nil {
+			// Closed when the transaction ends.
+			stmt = tx.StmtContext(ctx, stmt)
+		}
+		return stmt.ExecContext(ctx, params...)
+	}
+
+	var stmt *sql.Stmt
+	var err error
+	if tx != nil {
+		stmt, err = tx.PrepareContext(ctx, query)
+	} else {
+		stmt, err = s.db.PrepareContext(ctx, query)
+	}
 	if err != nil {
 		return nil, errors.Wrap(err, 0)
 	}
 	defer stmt.Close()
 	return stmt.ExecContext(ctx, params...)
 }
+
+func (s *store) query(ctx context.Context, query string, params ...any) (*sql.Rows, error) {
+	if s.cacheStatements {
+		stmt, err := s.prepare(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return stmt.QueryContext(ctx, params...)
+	}
+	return s.db.QueryContext(ctx, query, params...)
+}
+
+func (s *store) queryRow(ctx context.Context, query string, params ...any) *sql.Row {
+	if s.cacheStatements {
+		if stmt, err := s.prepare(ctx, query); err == nil {
+			return stmt.QueryRowContext(ctx, params...)
+		}
+		// Querying directly surfaces the error via Row.Scan.
+	}
+	return s.db.QueryRowContext(ctx, query, params...)
+}
+
+// isMemory returns true if the connection string is for an in-memory database.
+func isMemory(conn string) bool {
+	return strings.HasPrefix(conn, ":memory:") || strings.HasPrefix(conn, "file::memory:") ||
+		strings.Contains(conn, "mode=memory")
+}
+
+// withParams appends query parameters to a connection string.
+func withParams(conn string, params url.Values) string {
+	if len(params) == 0 {
+		return conn
+	}
+	if strings.Contains(conn, "?") {
+		return conn + "&" + params.Encode()
+	}
+	return conn + "?" + params.Encode()
+}
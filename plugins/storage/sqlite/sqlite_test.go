@@ -1,11 +1,19 @@
 package sqlite
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/dpup/prefab/plugins/storage"
 	"github.com/dpup/prefab/plugins/storage/storagetests"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSqliteStore(t *testing.T) {
@@ -25,6 +33,117 @@ func TestSqliteStore_withPrefixAndDedicatedTable(t *testing.T) {
 	})
 }
 
+func TestSqliteStore_file(t *testing.T) {
+	storagetests.Run(t, func() storage.Store {
+		return New(filepath.Join(t.TempDir(), "test.s3db"),
+			WithWAL(true),
+			WithBusyTimeout(time.Second),
+			WithSingleWriter(true),
+		)
+	})
+}
+
+func TestSqliteStore_fileWithoutStatementCache(t *testing.T) {
+	storagetests.Run(t, func() storage.Store {
+		return New(filepath.Join(t.TempDir(), "test.s3db"), WithStatementCache(false))
+	},
+		storagetests.WithConcurrency(1), // Concurrent writes need a busy timeout.
+		storagetests.WithFaultInjector(closeDB),
+	)
+}
+
+// closeDB swaps the store's database for a closed one, so every operation
+// fails. Statements must not be cached on the original database.
+func closeDB(t *testing.T, s storage.Store) func() {
+	st := s.(*store)
+	closed, err := sql.Open(driverName, ":memory:")
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+	db := st.db
+	st.db = closed
+	return func() { st.db = db }
+}
+
+func TestPragmas(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "test.s3db"),
+		WithWAL(true),
+		WithBusyTimeout(2500*time.Millisecond),
+	).(*store)
+	s.db.SetMaxOpenConns(3)
+
+	// Pragmas apply to every connection in the pool.
+	conns := make([]interface{ Close() error }, 0, 3)
+	for range 3 {
+		conn, err := s.db.Conn(t.Context())
+		require.NoError(t, err)
+		conns = append(conns, conn)
+
+		var mode string
+		var timeout int
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA journal_mode").Scan(&mode))
+		require.NoError(t, conn.QueryRowContext(t.Context(), "PRAGMA busy_timeout").Scan(&timeout))
+		assert.Equal(t, "wal", mode)
+		assert.Equal(t, 2500, timeout)
+	}
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+}
+
+func TestSingleWriter(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "test.s3db"), WithWAL(true), WithSingleWriter(true))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Create(context.Background(), Animal{ID: fmt.Sprint(i), Type: "cat", Legs: 4})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	var animals []Animal
+	require.NoError(t, s.List(t.Context(), &animals, Animal{}))
+	assert.Len(t, animals, 50)
+}
+
+func TestStatementCache(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "test.s3db")).(*store)
+	require.NoError(t, s.Create(t.Context(), Animal{ID: "1"}, Animal{ID: "2"}))
+	exists, err := s.Exists(t.Context(), "1", Animal{})
+	require.NoError(t, err)
+	assert.True(t, exists)
+	n := len(s.stmts)
+	assert.Positive(t, n)
+
+	require.NoError(t, s.Create(t.Context(), Animal{ID: "3"}))
+	_, err = s.Exists(t.Context(), "2", Animal{})
+	require.NoError(t, err)
+	assert.Len(t, s.stmts, n, "statements should be reused")
+
+	assert.False(t, New(":memory:").(*store).cacheStatements, "in-memory databases can't share statements")
+}
+
+func TestWithEncryptionKey_RequiresSQLCipher(t *testing.T) {
+	assert.PanicsWithValue(t, "sqlite: WithEncryptionKey requires building with -tags sqlcipher", func() {
+		New(filepath.Join(t.TempDir(), "test.s3db"), WithEncryptionKey("secret"))
+	})
+}
+
+func TestWithParams(t *testing.T) {
+	assert.Equal(t, "test.s3db", withParams("test.s3db", nil))
+	assert.Equal(t, "file:test.s3db?_pragma=journal_mode%28WAL%29",
+		withParams("file:test.s3db", map[string][]string{"_pragma": {"journal_mode(WAL)"}}))
+	assert.Equal(t, "file:test.s3db?cache=shared&_pragma=journal_mode%28WAL%29",
+		withParams("file:test.s3db?cache=shared", map[string][]string{"_pragma": {"journal_mode(WAL)"}}))
+}
+
 type Vehicle struct {
 	ID     string
 	Type   string
@@ -57,31 +176,31 @@ func TestBuildListQuery(t *testing.T) {
 		{
 			"empty",
 			Vehicle{},
-			"SELECT value FROM custom_default WHERE entity_type = ?",
+			"SELECT value FROM custom_default WHERE entity_type = ? ORDER BY id",
 			[]any{"vehicles"},
 		},
 		{
 			"single field filter",
 			Vehicle{Type: "car"},
-			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Type') = ?",
+			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Type') = ? ORDER BY id",
 			[]any{"vehicles", "car"},
 		},
 		{
 			"two field filter",
 			Vehicle{Type: "car", Wheels: 4},
-			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Type') = ? AND json_extract(value, '$.Wheels') = ?",
+			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Type') = ? AND json_extract(value, '$.Wheels') = ? ORDER BY id",
 			[]any{"vehicles", "car", 4},
 		},
 		{
 			"zero pointer filter",
 			Vehicle{Mods: &emptyString},
-			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Mods') = ?",
+			"SELECT value FROM custom_default WHERE entity_type = ? AND json_extract(value, '$.Mods') = ? ORDER BY id",
 			[]any{"vehicles", &emptyString},
 		},
 		{
 			"dedicated table",
 			Animal{Legs: 3},
-			"SELECT value FROM custom_animals WHERE json_extract(value, '$.Legs') = ?",
+			"SELECT value FROM custom_animals WHERE json_extract(value, '$.Legs') = ? ORDER BY id",
 			[]any{3},
 		},
 	}
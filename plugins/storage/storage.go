@@ -13,19 +13,55 @@
 //	 }
 package storage
 
-import "github.com/dpup/prefab"
+import (
+	"context"
+	"strings"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"google.golang.org/grpc/codes"
+)
 
 // PluginName can be used to query the storage plugin.
 const PluginName = "storage"
 
 // Plugin wraps a storage implementation for registration.
-func Plugin(impl Store) prefab.Plugin {
-	return &StoragePlugin{Store: impl}
+func Plugin(impl Store, opts ...PluginOption) prefab.Plugin {
+	p := &StoragePlugin{Store: impl}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// NamedPlugin wraps a storage implementation for registration alongside the
+// default store, for example a separate analytics database. Query it with
+// `prefab.GetPlugin[*storage.StoragePlugin](r, instance)` or depend on it as
+// "storage:<instance>".
+func NamedPlugin(instance string, impl Store, opts ...PluginOption) prefab.Plugin {
+	p := &StoragePlugin{Store: impl, instance: instance}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // StoragePlugin exposes a Plugin interface for persisting data.
 type StoragePlugin struct {
 	Store
+	instance string
+
+	// Models passed to InitModel, and whether initialization is a dry run.
+	models []Model
+	dryRun bool
+
+	// Change events, see WithChangeEvents.
+	changeEvents   bool
+	changePayloads bool
+	bus            *eventbus.EventBusPlugin
+	notifier       ChangeNotifier
+	stopChanges    context.CancelFunc
 }
 
 // From prefab.Plugin.
@@ -33,10 +69,50 @@ func (p *StoragePlugin) Name() string {
 	return PluginName
 }
 
+// From prefab.InstancePlugin.
+func (p *StoragePlugin) InstanceID() string {
+	return p.instance
+}
+
+// From prefab.InitializablePlugin.
+func (p *StoragePlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	p.dryRun = prefab.IsDryRun(ctx)
+	if p.changeEvents && !p.dryRun {
+		return p.initChanges(ctx, r)
+	}
+	return nil
+}
+
+// From prefab.CheckPlugin. Verifies that stores implementing SchemaChecker are
+// reachable and have the tables for every model passed to InitModel.
+func (p *StoragePlugin) Check(ctx context.Context) error {
+	sc, ok := p.Store.(SchemaChecker)
+	if !ok {
+		return nil
+	}
+	if err := sc.Ping(ctx); err != nil {
+		return errors.Wrap(err, 0).WithCode(codes.Unavailable)
+	}
+	missing, err := sc.MissingTables(ctx, p.models...)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return errors.Codef(codes.FailedPrecondition, "storage: pending migrations, missing tables: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // InitModel can be called by a plugin or application to perform per model
 // initialization. Stores that do not implement ModelInitializer should still
-// function correctly, but may store data in a shared table.
+// function correctly, but may store data in a shared table. During a dry run,
+// see prefab.IsDryRun, the model is recorded for Check but the store isn't
+// changed.
 func (p *StoragePlugin) InitModel(m Model) error {
+	p.models = append(p.models, m)
+	if p.dryRun {
+		return nil
+	}
 	if i, ok := p.Store.(ModelInitializer); ok {
 		return i.InitModel(m)
 	}
@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/plugins/storage/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamedPlugin(t *testing.T) {
+	r := &prefab.Registry{}
+	r.Register(storage.Plugin(memstore.New()))
+	r.Register(storage.NamedPlugin("analytics", memstore.New()))
+
+	primary, ok := prefab.GetPlugin[*storage.StoragePlugin](r)
+	require.True(t, ok)
+	assert.Empty(t, primary.InstanceID())
+	assert.Same(t, primary, r.Get(storage.PluginName))
+
+	analytics, ok := prefab.GetPlugin[*storage.StoragePlugin](r, "analytics")
+	require.True(t, ok)
+	assert.Equal(t, "analytics", analytics.InstanceID())
+	assert.Same(t, analytics, r.Get(storage.PluginName+":analytics"))
+	assert.NotSame(t, primary.Store, analytics.Store)
+}
+
+type widget struct {
+	ID string
+}
+
+func (w widget) PK() string { return w.ID }
+
+// widgetPlugin initializes a dedicated table for widgets.
+type widgetPlugin struct{}
+
+func (widgetPlugin) Name() string   { return "widgets" }
+func (widgetPlugin) Deps() []string { return []string{storage.PluginName} }
+
+func (widgetPlugin) Init(_ context.Context, r *prefab.Registry) error {
+	return r.Get(storage.PluginName).(*storage.StoragePlugin).InitModel(widget{})
+}
+
+func TestCheck_PendingMigrations(t *testing.T) {
+	store := sqlite.New(":memory:")
+	report := prefab.Validate(
+		prefab.WithPlugin(storage.Plugin(store)),
+		prefab.WithPlugin(widgetPlugin{}),
+	)
+	assert.False(t, report.OK())
+	assert.Contains(t, report.String(), "❌ storage: storage: pending migrations, missing tables: prefab_widget")
+
+	require.NoError(t, store.(storage.ModelInitializer).InitModel(widget{}))
+	report = prefab.Validate(
+		prefab.WithPlugin(storage.Plugin(store)),
+		prefab.WithPlugin(widgetPlugin{}),
+	)
+	assert.True(t, report.OK(), report.String())
+}
@@ -0,0 +1,224 @@
+// Package storageadmin provides a plugin that exposes an admin RPC for
+// exporting and importing the contents of the store, and optionally takes
+// scheduled backups.
+//
+// Access to the RPCs is denied unless an authz policy allows the ExportAction
+// or ImportAction, for example:
+//
+//	prefab.WithPlugin(authz.Plugin(
+//	    authz.WithRoleDescriberFn(storageadmin.ObjectKey, describeAdmins),
+//	    authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(storageadmin.ExportAction)),
+//	)),
+//	prefab.WithPlugin(storageadmin.Plugin(
+//	    storageadmin.WithBackups(upload.NewFSBackend("/var/backups/myapp")),
+//	)),
+//
+// Stores must implement storage.Dumper, as memstore, sqlite, and postgres do.
+package storageadmin
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/plugins/storage"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "storageAdmin.backupInterval",
+			Description: "How often scheduled backups are taken, when a backup sink is configured",
+			Type:        "duration",
+			Default:     "24h",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "storageAdmin.backupPrefix",
+			Description: "Path prefix for backup files",
+			Type:        "string",
+			Default:     "backups/",
+		},
+	)
+}
+
+const (
+	// PluginName is the name of this plugin.
+	PluginName = "storageadmin"
+
+	// authz action for exporting the store.
+	ExportAction = "storage.export"
+
+	// authz action for importing into the store.
+	ImportAction = "storage.import"
+
+	// authz object key used to scope RoleDescribers.
+	ObjectKey = "storage"
+
+	defaultBackupInterval = 24 * time.Hour
+)
+
+// BackupSink receives scheduled backups. upload.Backend implementations, such
+// as upload.NewFSBackend, satisfy this interface.
+type BackupSink interface {
+	Save(path string, data []byte) error
+}
+
+// StorageAdminOption allows configuration of the StorageAdminPlugin.
+type StorageAdminOption func(*StorageAdminPlugin)
+
+// WithBackups enables scheduled backups to sink. See
+// `storageAdmin.backupInterval` and `storageAdmin.backupPrefix`.
+func WithBackups(sink BackupSink) StorageAdminOption {
+	return func(p *StorageAdminPlugin) {
+		p.sink = sink
+	}
+}
+
+// WithBackupInterval overrides the interval between scheduled backups.
+func WithBackupInterval(d time.Duration) StorageAdminOption {
+	return func(p *StorageAdminPlugin) {
+		p.interval = d
+	}
+}
+
+// Plugin returns a new StorageAdminPlugin.
+func Plugin(opts ...StorageAdminOption) *StorageAdminPlugin {
+	p := &StorageAdminPlugin{
+		interval: prefab.ConfigDuration("storageAdmin.backupInterval"),
+		prefix:   prefab.ConfigString("storageAdmin.backupPrefix"),
+		stop:     make(chan struct{}),
+	}
+	if p.interval == 0 {
+		p.interval = defaultBackupInterval
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// StorageAdminPlugin exposes the StorageAdminService and runs scheduled
+// backups.
+type StorageAdminPlugin struct {
+	store    storage.Store
+	sink     BackupSink
+	interval time.Duration
+	prefix   string
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// From prefab.Plugin.
+func (p *StorageAdminPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *StorageAdminPlugin) Deps() []string {
+	return []string{storage.PluginName, authz.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *StorageAdminPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCService(&StorageAdminService_ServiceDesc, &impl{p: p}),
+		prefab.WithGRPCGateway(RegisterStorageAdminServiceHandlerFromEndpoint),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *StorageAdminPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	p.store = r.Get(storage.PluginName).(*storage.StoragePlugin).Store
+	if _, ok := p.store.(storage.Dumper); !ok {
+		return errors.NewC("storageadmin: store does not implement storage.Dumper", codes.FailedPrecondition)
+	}
+
+	az := r.Get(authz.PluginName).(*authz.AuthzPlugin)
+	az.RegisterObjectFetcher(ObjectKey, authz.ObjectFetcherFn(func(ctx context.Context, _ any) (any, error) {
+		return p.store, nil
+	}))
+
+	if p.sink != nil {
+		p.wg.Add(1)
+		go p.runBackups(ctx)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *StorageAdminPlugin) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Backup exports the store to the backup sink, returning the path of the
+// backup.
+func (p *StorageAdminPlugin) Backup(ctx context.Context) (string, error) {
+	if p.sink == nil {
+		return "", errors.NewC("storageadmin: no backup sink configured", codes.FailedPrecondition)
+	}
+	var buf bytes.Buffer
+	n, err := storage.Export(ctx, p.store, &buf)
+	if err != nil {
+		return "", err
+	}
+	path := p.prefix + time.Now().UTC().Format("20060102T150405Z") + ".jsonl"
+	if err := p.sink.Save(path, buf.Bytes()); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	logging.Infow(ctx, "storageadmin: backup complete", "path", path, "records", n)
+	return path, nil
+}
+
+func (p *StorageAdminPlugin) runBackups(ctx context.Context) {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.Backup(ctx); err != nil {
+				logging.Errorw(ctx, "storageadmin: backup failed", "error", err)
+			}
+		}
+	}
+}
+
+type impl struct {
+	UnimplementedStorageAdminServiceServer
+	p *StorageAdminPlugin
+}
+
+func (s *impl) Export(ctx context.Context, _ *ExportRequest) (*ExportResponse, error) {
+	var buf bytes.Buffer
+	n, err := storage.Export(ctx, s.p.store, &buf)
+	if err != nil {
+		return nil, err
+	}
+	logging.Track(ctx, "storageadmin.exported", n)
+	return &ExportResponse{Dump: buf.Bytes(), Records: int32(n)}, nil //nolint:gosec // Record counts fit in int32.
+}
+
+func (s *impl) Import(ctx context.Context, in *ImportRequest) (*ImportResponse, error) {
+	n, err := storage.Import(ctx, s.p.store, bytes.NewReader(in.GetDump()))
+	if err != nil {
+		return nil, errors.WrapPrefix(err, "storageadmin: import failed", 0).
+			WithCode(errors.Code(err)).
+			WithUserPresentableMessage("Import failed after %d records", n)
+	}
+	logging.Track(ctx, "storageadmin.imported", n)
+	return &ImportResponse{Records: int32(n)}, nil //nolint:gosec // Record counts fit in int32.
+}
@@ -0,0 +1,276 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: plugins/storage/storageadmin/storageadmin.proto
+
+package storageadmin
+
+import (
+	_ "github.com/dpup/prefab/plugins/authz"
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ExportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportRequest) Reset() {
+	*x = ExportRequest{}
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportRequest) ProtoMessage() {}
+
+func (x *ExportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportRequest.ProtoReflect.Descriptor instead.
+func (*ExportRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_storage_storageadmin_storageadmin_proto_rawDescGZIP(), []int{0}
+}
+
+type ExportResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// JSON-lines dump, one record per line.
+	Dump []byte `protobuf:"bytes,1,opt,name=dump,proto3" json:"dump,omitempty"`
+	// Number of records in the dump.
+	Records       int32 `protobuf:"varint,2,opt,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportResponse) Reset() {
+	*x = ExportResponse{}
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportResponse) ProtoMessage() {}
+
+func (x *ExportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportResponse.ProtoReflect.Descriptor instead.
+func (*ExportResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_storage_storageadmin_storageadmin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ExportResponse) GetDump() []byte {
+	if x != nil {
+		return x.Dump
+	}
+	return nil
+}
+
+func (x *ExportResponse) GetRecords() int32 {
+	if x != nil {
+		return x.Records
+	}
+	return 0
+}
+
+type ImportRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// JSON-lines dump, as returned by Export.
+	Dump          []byte `protobuf:"bytes,1,opt,name=dump,proto3" json:"dump,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportRequest) Reset() {
+	*x = ImportRequest{}
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportRequest) ProtoMessage() {}
+
+func (x *ImportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportRequest.ProtoReflect.Descriptor instead.
+func (*ImportRequest) Descriptor() ([]byte, []int) {
+	return file_plugins_storage_storageadmin_storageadmin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ImportRequest) GetDump() []byte {
+	if x != nil {
+		return x.Dump
+	}
+	return nil
+}
+
+type ImportResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of records imported.
+	Records       int32 `protobuf:"varint,1,opt,name=records,proto3" json:"records,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportResponse) Reset() {
+	*x = ImportResponse{}
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportResponse) ProtoMessage() {}
+
+func (x *ImportResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_plugins_storage_storageadmin_storageadmin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportResponse.ProtoReflect.Descriptor instead.
+func (*ImportResponse) Descriptor() ([]byte, []int) {
+	return file_plugins_storage_storageadmin_storageadmin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ImportResponse) GetRecords() int32 {
+	if x != nil {
+		return x.Records
+	}
+	return 0
+}
+
+var File_plugins_storage_storageadmin_storageadmin_proto protoreflect.FileDescriptor
+
+const file_plugins_storage_storageadmin_storageadmin_proto_rawDesc = "" +
+	"\n" +
+	"/plugins/storage/storageadmin/storageadmin.proto\x12\x13prefab.storageadmin\x1a\x1cgoogle/api/annotations.proto\x1a\x19plugins/authz/authz.proto\"\x0f\n" +
+	"\rExportRequest\">\n" +
+	"\x0eExportResponse\x12\x12\n" +
+	"\x04dump\x18\x01 \x01(\fR\x04dump\x12\x18\n" +
+	"\arecords\x18\x02 \x01(\x05R\arecords\"#\n" +
+	"\rImportRequest\x12\x12\n" +
+	"\x04dump\x18\x01 \x01(\fR\x04dump\"*\n" +
+	"\x0eImportResponse\x12\x18\n" +
+	"\arecords\x18\x01 \x01(\x05R\arecords2\xd3\x02\n" +
+	"\x13StorageAdminService\x12\x9c\x01\n" +
+	"\x06Export\x12\".prefab.storageadmin.ExportRequest\x1a#.prefab.storageadmin.ExportResponse\"Iڵ\x18\x0estorage.export\xe2\xb5\x18\astorage\xea\xb5\x18\x04deny\x82\xd3\xe4\x93\x02\x1e:\x01*\"\x19/api/admin/storage/export\x12\x9c\x01\n" +
+	"\x06Import\x12\".prefab.storageadmin.ImportRequest\x1a#.prefab.storageadmin.ImportResponse\"Iڵ\x18\x0estorage.import\xe2\xb5\x18\astorage\xea\xb5\x18\x04deny\x82\xd3\xe4\x93\x02\x1e:\x01*\"\x19/api/admin/storage/importB5Z3github.com/dpup/prefab/plugins/storage/storageadminb\x06proto3"
+
+var (
+	file_plugins_storage_storageadmin_storageadmin_proto_rawDescOnce sync.Once
+	file_plugins_storage_storageadmin_storageadmin_proto_rawDescData []byte
+)
+
+func file_plugins_storage_storageadmin_storageadmin_proto_rawDescGZIP() []byte {
+	file_plugins_storage_storageadmin_storageadmin_proto_rawDescOnce.Do(func() {
+		file_plugins_storage_storageadmin_storageadmin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_plugins_storage_storageadmin_storageadmin_proto_rawDesc), len(file_plugins_storage_storageadmin_storageadmin_proto_rawDesc)))
+	})
+	return file_plugins_storage_storageadmin_storageadmin_proto_rawDescData
+}
+
+var file_plugins_storage_storageadmin_storageadmin_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_plugins_storage_storageadmin_storageadmin_proto_goTypes = []any{
+	(*ExportRequest)(nil),  // 0: prefab.storageadmin.ExportRequest
+	(*ExportResponse)(nil), // 1: prefab.storageadmin.ExportResponse
+	(*ImportRequest)(nil),  // 2: prefab.storageadmin.ImportRequest
+	(*ImportResponse)(nil), // 3: prefab.storageadmin.ImportResponse
+}
+var file_plugins_storage_storageadmin_storageadmin_proto_depIdxs = []int32{
+	0, // 0: prefab.storageadmin.StorageAdminService.Export:input_type -> prefab.storageadmin.ExportRequest
+	2, // 1: prefab.storageadmin.StorageAdminService.Import:input_type -> prefab.storageadmin.ImportRequest
+	1, // 2: prefab.storageadmin.StorageAdminService.Export:output_type -> prefab.storageadmin.ExportResponse
+	3, // 3: prefab.storageadmin.StorageAdminService.Import:output_type -> prefab.storageadmin.ImportResponse
+	2, // [2:4] is the sub-list for method output_type
+	0, // [0:2] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_plugins_storage_storageadmin_storageadmin_proto_init() }
+func file_plugins_storage_storageadmin_storageadmin_proto_init() {
+	if File_plugins_storage_storageadmin_storageadmin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugins_storage_storageadmin_storageadmin_proto_rawDesc), len(file_plugins_storage_storageadmin_storageadmin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_plugins_storage_storageadmin_storageadmin_proto_goTypes,
+		DependencyIndexes: file_plugins_storage_storageadmin_storageadmin_proto_depIdxs,
+		MessageInfos:      file_plugins_storage_storageadmin_storageadmin_proto_msgTypes,
+	}.Build()
+	File_plugins_storage_storageadmin_storageadmin_proto = out.File
+	file_plugins_storage_storageadmin_storageadmin_proto_goTypes = nil
+	file_plugins_storage_storageadmin_storageadmin_proto_depIdxs = nil
+}
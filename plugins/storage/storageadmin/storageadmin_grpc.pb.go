@@ -0,0 +1,173 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: plugins/storage/storageadmin/storageadmin.proto
+
+package storageadmin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	StorageAdminService_Export_FullMethodName = "/prefab.storageadmin.StorageAdminService/Export"
+	StorageAdminService_Import_FullMethodName = "/prefab.storageadmin.StorageAdminService/Import"
+)
+
+// StorageAdminServiceClient is the client API for StorageAdminService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// StorageAdminService exports and imports the contents of the store. Access is
+// denied unless an authz policy grants the `storage.export` or
+// `storage.import` action.
+type StorageAdminServiceClient interface {
+	// Export returns a JSON-lines dump of every record in the store.
+	Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error)
+	// Import loads a dump produced by Export, replacing records with matching
+	// keys.
+	Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResponse, error)
+}
+
+type storageAdminServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewStorageAdminServiceClient(cc grpc.ClientConnInterface) StorageAdminServiceClient {
+	return &storageAdminServiceClient{cc}
+}
+
+func (c *storageAdminServiceClient) Export(ctx context.Context, in *ExportRequest, opts ...grpc.CallOption) (*ExportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExportResponse)
+	err := c.cc.Invoke(ctx, StorageAdminService_Export_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *storageAdminServiceClient) Import(ctx context.Context, in *ImportRequest, opts ...grpc.CallOption) (*ImportResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ImportResponse)
+	err := c.cc.Invoke(ctx, StorageAdminService_Import_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StorageAdminServiceServer is the server API for StorageAdminService service.
+// All implementations must embed UnimplementedStorageAdminServiceServer
+// for forward compatibility.
+//
+// StorageAdminService exports and imports the contents of the store. Access is
+// denied unless an authz policy grants the `storage.export` or
+// `storage.import` action.
+type StorageAdminServiceServer interface {
+	// Export returns a JSON-lines dump of every record in the store.
+	Export(context.Context, *ExportRequest) (*ExportResponse, error)
+	// Import loads a dump produced by Export, replacing records with matching
+	// keys.
+	Import(context.Context, *ImportRequest) (*ImportResponse, error)
+	mustEmbedUnimplementedStorageAdminServiceServer()
+}
+
+// UnimplementedStorageAdminServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedStorageAdminServiceServer struct{}
+
+func (UnimplementedStorageAdminServiceServer) Export(context.Context, *ExportRequest) (*ExportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Export not implemented")
+}
+func (UnimplementedStorageAdminServiceServer) Import(context.Context, *ImportRequest) (*ImportResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Import not implemented")
+}
+func (UnimplementedStorageAdminServiceServer) mustEmbedUnimplementedStorageAdminServiceServer() {}
+func (UnimplementedStorageAdminServiceServer) testEmbeddedByValue()                             {}
+
+// UnsafeStorageAdminServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to StorageAdminServiceServer will
+// result in compilation errors.
+type UnsafeStorageAdminServiceServer interface {
+	mustEmbedUnimplementedStorageAdminServiceServer()
+}
+
+func RegisterStorageAdminServiceServer(s grpc.ServiceRegistrar, srv StorageAdminServiceServer) {
+	// If the following call pancis, it indicates UnimplementedStorageAdminServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&StorageAdminService_ServiceDesc, srv)
+}
+
+func _StorageAdminService_Export_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAdminServiceServer).Export(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAdminService_Export_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAdminServiceServer).Export(ctx, req.(*ExportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StorageAdminService_Import_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StorageAdminServiceServer).Import(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: StorageAdminService_Import_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StorageAdminServiceServer).Import(ctx, req.(*ImportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// StorageAdminService_ServiceDesc is the grpc.ServiceDesc for StorageAdminService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var StorageAdminService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "prefab.storageadmin.StorageAdminService",
+	HandlerType: (*StorageAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Export",
+			Handler:    _StorageAdminService_Export_Handler,
+		},
+		{
+			MethodName: "Import",
+			Handler:    _StorageAdminService_Import_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "plugins/storage/storageadmin/storageadmin.proto",
+}
@@ -0,0 +1,99 @@
+package storageadmin
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/authz"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type note struct {
+	ID   string
+	Text string
+}
+
+func (n *note) PK() string { return n.ID }
+
+type sink map[string][]byte
+
+func (s sink) Save(path string, data []byte) error {
+	s[path] = data
+	return nil
+}
+
+var (
+	admin = auth.Identity{Provider: "test", Subject: "admin"}
+	user  = auth.Identity{Provider: "test", Subject: "user"}
+)
+
+func setup(t *testing.T, opts ...StorageAdminOption) (*prefabtest.Server, *StorageAdminPlugin) {
+	p := Plugin(opts...)
+	az := authz.Plugin(
+		authz.WithRoleDescriberFn(ObjectKey, func(_ context.Context, sub auth.Identity, _ any, _ authz.Scope) ([]authz.Role, error) {
+			if sub.Subject == admin.Subject {
+				return []authz.Role{"admin"}, nil
+			}
+			return nil, nil
+		}),
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(ExportAction)),
+		authz.WithPolicy(authz.Allow, authz.Role("admin"), authz.Action(ImportAction)),
+	)
+	s := prefabtest.New(t,
+		prefabtest.WithAuth(),
+		prefabtest.WithPlugins(az, p),
+		prefabtest.WithFixtures(&note{ID: "1", Text: "hello"}, &note{ID: "2", Text: "world"}),
+	)
+	return s, p
+}
+
+func TestExportImport(t *testing.T) {
+	s, _ := setup(t)
+	client := NewStorageAdminServiceClient(s.Conn())
+
+	_, err := client.Export(s.AuthContext(t.Context(), user), &ExportRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := client.Export(s.AuthContext(t.Context(), admin), &ExportRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), resp.GetRecords())
+	assert.Contains(t, string(resp.GetDump()), `"Text":"hello"`)
+
+	require.NoError(t, s.Store().Delete(t.Context(), &note{ID: "1"}))
+
+	_, err = client.Import(s.AuthContext(t.Context(), user), &ImportRequest{Dump: resp.GetDump()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	imp, err := client.Import(s.AuthContext(t.Context(), admin), &ImportRequest{Dump: resp.GetDump()})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), imp.GetRecords())
+
+	n := &note{}
+	require.NoError(t, s.Store().Read(t.Context(), "1", n))
+	assert.Equal(t, "hello", n.Text)
+}
+
+func TestImport_Invalid(t *testing.T) {
+	s, _ := setup(t)
+	client := NewStorageAdminServiceClient(s.Conn())
+
+	_, err := client.Import(s.AuthContext(t.Context(), admin), &ImportRequest{Dump: []byte(`{"type":"note"}`)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestBackup(t *testing.T) {
+	backups := sink{}
+	_, p := setup(t, WithBackups(backups))
+
+	path, err := p.Backup(logging.With(t.Context(), logging.NewDevLogger()))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(path, "backups/"), path)
+	assert.Equal(t, 2, strings.Count(string(backups[path]), "\n"))
+}
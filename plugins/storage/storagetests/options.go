@@ -0,0 +1,57 @@
+package storagetests
+
+import (
+	"testing"
+
+	"github.com/dpup/prefab/plugins/storage"
+)
+
+// Option configures the acceptance tests run by Run.
+type Option func(*config)
+
+type config struct {
+	concurrency int
+	payloadSize int
+	injectFault FaultInjector
+}
+
+// FaultInjector makes operations on the store fail, for example by closing its
+// connection or stopping the database, and returns a function which restores
+// it.
+type FaultInjector func(t *testing.T, store storage.Store) (restore func())
+
+// WithConcurrency sets the number of goroutines used by the concurrency tests.
+// Defaults to 8.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithPayloadSize sets the size, in bytes, of models written by the large
+// payload tests. Defaults to 1MiB.
+func WithPayloadSize(n int) Option {
+	return func(c *config) {
+		c.payloadSize = n
+	}
+}
+
+// WithFaultInjector enables tests of how the store behaves when its backend
+// fails: operations should return errors, rather than reporting records as
+// missing, and the store should recover once the backend is restored.
+func WithFaultInjector(fn FaultInjector) Option {
+	return func(c *config) {
+		c.injectFault = fn
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		concurrency: 8,
+		payloadSize: 1 << 20,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
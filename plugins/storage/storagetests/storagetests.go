@@ -3,6 +3,7 @@
 package storagetests
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -55,8 +56,17 @@ func pint(i int) *int {
 	return &i
 }
 
+// Run runs the acceptance tests against stores returned by newStore, which
+// should return an empty store on each call.
+//
 //nolint:funlen // This is a test helper.
-func Run(t *testing.T, newStore func() storage.Store) {
+func Run(t *testing.T, newStore func() storage.Store, opts ...Option) {
+	c := newConfig(opts)
+	runConcurrencyTests(t, newStore, c)
+	runPayloadTests(t, newStore, c)
+	runOrderTests(t, newStore)
+	runFaultTests(t, newStore, c)
+
 	t.Run("TestCreateReadRoundTrip", func(t *testing.T) {
 		apple := Fruit{
 			ID:    "1",
@@ -355,4 +365,39 @@ func Run(t *testing.T, newStore func() storage.Store) {
 		assert.True(t, exists)
 		require.NoError(t, err)
 	})
+
+	t.Run("TestExportImportRoundTrip", func(t *testing.T) {
+		src := newStore()
+		if _, ok := src.(storage.Dumper); !ok {
+			t.Skip("store does not implement storage.Dumper")
+		}
+		err := src.Create(context.Background(),
+			Fruit{"1", "Apple", ColorGreen, pint(2)},
+			Fruit{"2", "Banana", ColorYellow, nil},
+			Planet{"1", "Mercury"},
+		)
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		n, err := storage.Export(context.Background(), src, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		dst := newStore()
+		require.NoError(t, dst.Create(context.Background(), Fruit{"1", "Old Apple", ColorRed, nil}))
+		n, err = storage.Import(context.Background(), dst, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		fruits := []Fruit{}
+		require.NoError(t, dst.List(context.Background(), &fruits, Fruit{}))
+		assert.Equal(t, []Fruit{
+			{"1", "Apple", ColorGreen, pint(2)},
+			{"2", "Banana", ColorYellow, nil},
+		}, fruits)
+
+		planet := Planet{}
+		require.NoError(t, dst.Read(context.Background(), "1", &planet))
+		assert.Equal(t, "Mercury", planet.Name)
+	})
 }
@@ -0,0 +1,295 @@
+package storagetests
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parallel runs fn in n goroutines and waits for them to finish.
+func parallel(n int, fn func(worker int)) {
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i)
+		}()
+	}
+	wg.Wait()
+}
+
+func runConcurrencyTests(t *testing.T, newStore func() storage.Store, c *config) {
+	t.Run("TestConcurrentUpserts", func(t *testing.T) {
+		store := newStore()
+		const perWorker = 20
+		errs := make(chan error, c.concurrency*perWorker*2)
+		parallel(c.concurrency, func(w int) {
+			for i := range perWorker {
+				own := Fruit{ID: fmt.Sprintf("w%02d-%02d", w, i), Name: "Own", Color: ColorGreen}
+				shared := Fruit{ID: fmt.Sprintf("shared-%02d", i), Name: fmt.Sprintf("Worker %d", w)}
+				errs <- store.Upsert(context.Background(), own)
+				errs <- store.Upsert(context.Background(), shared)
+			}
+		})
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		fruits := []Fruit{}
+		require.NoError(t, store.List(context.Background(), &fruits, Fruit{}))
+		assert.Len(t, fruits, c.concurrency*perWorker+perWorker)
+		for _, f := range fruits {
+			if strings.HasPrefix(f.ID, "shared-") {
+				assert.True(t, strings.HasPrefix(f.Name, "Worker "), "shared record should hold one worker's write, got %q", f.Name)
+			}
+		}
+	})
+
+	t.Run("TestConcurrentCreateConflict", func(t *testing.T) {
+		store := newStore()
+		errs := make(chan error, c.concurrency)
+		parallel(c.concurrency, func(w int) {
+			errs <- store.Create(context.Background(), Fruit{ID: "1", Name: fmt.Sprintf("Worker %d", w)})
+		})
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			if err == nil {
+				created++
+			} else {
+				require.ErrorIs(t, err, storage.ErrAlreadyExists)
+			}
+		}
+		assert.Equal(t, 1, created, "exactly one create should succeed")
+	})
+
+	t.Run("TestConcurrentReadAfterWrite", func(t *testing.T) {
+		store := newStore()
+		errs := make(chan error, c.concurrency)
+		parallel(c.concurrency, func(w int) {
+			id := fmt.Sprintf("%02d", w)
+			for i := range 10 {
+				want := Fruit{ID: id, Name: fmt.Sprintf("Version %d", i), Count: pint(i)}
+				if err := store.Upsert(context.Background(), want); err != nil {
+					errs <- err
+					return
+				}
+				got := Fruit{}
+				if err := store.Read(context.Background(), id, &got); err != nil {
+					errs <- err
+					return
+				}
+				if got.Name != want.Name {
+					errs <- fmt.Errorf("read %q after writing %q", got.Name, want.Name)
+					return
+				}
+			}
+		})
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+	})
+
+	// Stores don't detect conflicting updates, so the last write wins. The
+	// record should hold one complete write, rather than a mix of several.
+	t.Run("TestConflictingUpdates", func(t *testing.T) {
+		store := newStore()
+		require.NoError(t, store.Create(context.Background(), Fruit{ID: "1", Name: "Apple"}))
+
+		errs := make(chan error, c.concurrency*10)
+		parallel(c.concurrency, func(w int) {
+			for range 10 {
+				errs <- store.Update(context.Background(), Fruit{
+					ID:    "1",
+					Name:  fmt.Sprintf("Worker %d", w),
+					Color: Color(w),
+					Count: pint(w),
+				})
+			}
+		})
+		close(errs)
+		for err := range errs {
+			require.NoError(t, err)
+		}
+
+		got := Fruit{}
+		require.NoError(t, store.Read(context.Background(), "1", &got))
+		require.NotNil(t, got.Count)
+		assert.Equal(t, fmt.Sprintf("Worker %d", *got.Count), got.Name)
+		assert.Equal(t, Color(*got.Count), got.Color)
+	})
+}
+
+func runPayloadTests(t *testing.T, newStore func() storage.Store, c *config) {
+	t.Run("TestLargePayload", func(t *testing.T) {
+		store := newStore()
+
+		// Multi-byte characters and characters which need escaping in JSON.
+		const chars = "🍎\"\\\n"
+		name := strings.Repeat(chars, c.payloadSize/len(chars)+1)[:c.payloadSize]
+		name = strings.ToValidUTF8(name, "")
+		apple := Fruit{ID: "1", Name: name, Color: ColorGreen}
+		require.NoError(t, store.Create(context.Background(), apple))
+
+		got := Fruit{}
+		require.NoError(t, store.Read(context.Background(), "1", &got))
+		assert.Equal(t, apple, got)
+
+		apple.Name = strings.Repeat("x", c.payloadSize)
+		require.NoError(t, store.Update(context.Background(), apple))
+		fruits := []Fruit{}
+		require.NoError(t, store.List(context.Background(), &fruits, Fruit{Color: ColorGreen}))
+		require.Len(t, fruits, 1)
+		assert.Equal(t, apple, fruits[0])
+	})
+
+	t.Run("TestLargeBatch", func(t *testing.T) {
+		store := newStore()
+		batch := make([]storage.Model, 500)
+		for i := range batch {
+			batch[i] = Fruit{ID: fmt.Sprintf("%04d", i), Name: "Fruit", Count: pint(i)}
+		}
+		require.NoError(t, store.Create(context.Background(), batch...))
+
+		fruits := []Fruit{}
+		require.NoError(t, store.List(context.Background(), &fruits, Fruit{}))
+		assert.Len(t, fruits, len(batch))
+	})
+}
+
+func runOrderTests(t *testing.T, newStore func() storage.Store) {
+	t.Run("TestListOrder", func(t *testing.T) {
+		store := newStore()
+
+		ids := make([]string, 120)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("%03d", i)
+		}
+		ids = append(ids, "9", "10", "a", "B")
+		shuffled := slices.Clone(ids)
+		rand.New(rand.NewPCG(1, 2)).Shuffle(len(shuffled), func(i, j int) { //nolint:gosec // Deterministic.
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		for _, id := range shuffled {
+			color := ColorRed
+			if len(id) == 3 && id[2]%2 == 0 {
+				color = ColorGreen
+			}
+			require.NoError(t, store.Create(context.Background(), Fruit{ID: id, Color: color}))
+		}
+
+		// Records are listed in byte order of their primary keys.
+		slices.Sort(ids)
+		fruits := []Fruit{}
+		require.NoError(t, store.List(context.Background(), &fruits, Fruit{}))
+		assert.Equal(t, ids, fruitIDs(fruits))
+
+		// Filtering preserves the order.
+		fruits = []Fruit{}
+		require.NoError(t, store.List(context.Background(), &fruits, Fruit{Color: ColorGreen}))
+		got := fruitIDs(fruits)
+		assert.Len(t, got, 60)
+		assert.True(t, slices.IsSorted(got), "filtered records should be sorted")
+	})
+
+	t.Run("TestListPage", func(t *testing.T) {
+		store := newStore()
+		for _, id := range []string{"d", "a", "e", "c", "b", "f"} {
+			color := ColorRed
+			if id == "c" || id == "e" {
+				color = ColorGreen
+			}
+			require.NoError(t, store.Create(context.Background(), Fruit{ID: id, Color: color}))
+		}
+
+		fruits := []Fruit{}
+		require.NoError(t, storage.ListPage(context.Background(), store, &fruits, Fruit{}, "", 2))
+		assert.Equal(t, []string{"a", "b"}, fruitIDs(fruits))
+
+		fruits = []Fruit{}
+		require.NoError(t, storage.ListPage(context.Background(), store, &fruits, Fruit{}, "b", 3))
+		assert.Equal(t, []string{"c", "d", "e"}, fruitIDs(fruits))
+
+		// The cursor doesn't need to exist, and a limit of 0 returns the rest.
+		fruits = []Fruit{}
+		require.NoError(t, storage.ListPage(context.Background(), store, &fruits, Fruit{}, "bb", 0))
+		assert.Equal(t, []string{"c", "d", "e", "f"}, fruitIDs(fruits))
+
+		// The limit applies to records matching the filter.
+		fruits = []Fruit{}
+		require.NoError(t, storage.ListPage(context.Background(), store, &fruits, Fruit{Color: ColorRed}, "a", 2))
+		assert.Equal(t, []string{"b", "d"}, fruitIDs(fruits))
+	})
+}
+
+func runFaultTests(t *testing.T, newStore func() storage.Store, c *config) {
+	t.Run("TestBatchFailureIsAtomic", func(t *testing.T) {
+		store := newStore()
+		require.NoError(t, store.Create(context.Background(), Fruit{ID: "2", Name: "Banana"}))
+
+		err := store.Create(context.Background(), Fruit{ID: "1", Name: "Apple"}, Fruit{ID: "2", Name: "Banana"})
+		require.ErrorIs(t, err, storage.ErrAlreadyExists)
+		exists, err := store.Exists(context.Background(), "1", &Fruit{})
+		require.NoError(t, err)
+		assert.False(t, exists, "earlier models in a failed batch shouldn't be written")
+
+		err = store.Update(context.Background(), Fruit{ID: "2", Name: "Plantain"}, Fruit{ID: "3"})
+		require.ErrorIs(t, err, storage.ErrNotFound)
+		got := Fruit{}
+		require.NoError(t, store.Read(context.Background(), "2", &got))
+		assert.Equal(t, "Banana", got.Name, "earlier models in a failed batch shouldn't be updated")
+	})
+
+	t.Run("TestBackendFailure", func(t *testing.T) {
+		if c.injectFault == nil {
+			t.Skip("no fault injector, see WithFaultInjector")
+		}
+		store := newStore()
+		ctx := context.Background()
+		apple := Fruit{ID: "1", Name: "Apple"}
+		require.NoError(t, store.Create(ctx, apple))
+
+		restore := c.injectFault(t, store)
+
+		err := store.Read(ctx, "1", &Fruit{})
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, storage.ErrNotFound, "unavailable records shouldn't be reported as missing")
+		_, err = store.Exists(ctx, "1", &Fruit{})
+		require.Error(t, err)
+		require.Error(t, store.List(ctx, &[]Fruit{}, Fruit{}))
+		require.Error(t, store.Create(ctx, Fruit{ID: "2"}))
+		require.Error(t, store.Update(ctx, Fruit{ID: "1", Name: "Changed"}))
+		require.Error(t, store.Upsert(ctx, Fruit{ID: "3"}))
+		require.Error(t, store.Delete(ctx, apple))
+
+		restore()
+
+		got := Fruit{}
+		require.NoError(t, store.Read(ctx, "1", &got))
+		assert.Equal(t, apple, got)
+		exists, err := store.Exists(ctx, "2", &Fruit{})
+		require.NoError(t, err)
+		assert.False(t, exists, "failed writes shouldn't be applied")
+		require.NoError(t, store.Create(ctx, Fruit{ID: "2"}))
+	})
+}
+
+func fruitIDs(fruits []Fruit) []string {
+	ids := make([]string, len(fruits))
+	for i, f := range fruits {
+		ids[i] = f.ID
+	}
+	return ids
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"reflect"
+	"sort"
 
 	"github.com/dpup/prefab/errors"
 	"google.golang.org/grpc/codes"
@@ -52,7 +54,8 @@ type Store interface {
 
 	// List populates the slice of models with records that have fields which
 	// match the fields of filter. Zero-value fields will be ignored, unless the
-	// field is a pointer.
+	// field is a pointer. Records are ordered by primary key, compared as
+	// bytes.
 	List(ctx context.Context, models any, filter Model) error
 
 	// Exists returns true if a record with the given id exists.
@@ -67,3 +70,54 @@ type ModelInitializer interface {
 	// data will be stored in a shared table.
 	InitModel(model Model) error
 }
+
+// Optional interface for stores with a schema, such as SQL databases, which
+// lets prefab.Validate check connectivity and detect pending migrations
+// without changing the database.
+type SchemaChecker interface {
+	// Ping verifies that the store is reachable.
+	Ping(ctx context.Context) error
+
+	// MissingTables returns the tables required by the models, and by the store
+	// itself, which don't exist.
+	MissingTables(ctx context.Context, models ...Model) ([]string, error)
+}
+
+// Optional interface for stores which can list a page of records without
+// loading every matching record, such as SQL databases. See ListPage.
+type PageLister interface {
+	// ListPage is like List, but only returns the first limit records whose
+	// primary key sorts after cursor.
+	ListPage(ctx context.Context, models any, filter Model, cursor string, limit int) error
+}
+
+// ListPage populates the slice of models with up to limit records that match
+// filter and whose primary key sorts after cursor, in primary key order. Pass
+// an empty cursor for the first page, and the primary key of the last record
+// for the next. A limit of 0 returns every record after the cursor.
+//
+// Stores that don't implement PageLister list every matching record before
+// returning the page, so should only be used with small tables.
+func ListPage(ctx context.Context, store Store, models any, filter Model, cursor string, limit int) error {
+	if pl, ok := store.(PageLister); ok {
+		return pl.ListPage(ctx, models, filter, cursor, limit)
+	}
+	if err := store.List(ctx, models, filter); err != nil {
+		return err
+	}
+	// List has checked that models is a pointer to a slice of filter's type,
+	// and returns records ordered by primary key.
+	sliceVal := reflect.ValueOf(models).Elem()
+	start := 0
+	if cursor != "" {
+		start = sort.Search(sliceVal.Len(), func(i int) bool {
+			return sliceVal.Index(i).Interface().(Model).PK() > cursor
+		})
+	}
+	end := sliceVal.Len()
+	if limit > 0 {
+		end = min(start+limit, end)
+	}
+	sliceVal.Set(sliceVal.Slice(start, end))
+	return nil
+}
@@ -1,5 +1,27 @@
 // Package templates provides plugins access to Go templates.
 //
+// Templates are loaded from directories on disk and from fs.FS sources, such as
+// an embed.FS. Files ending in `.tmpl` are parsed and can be rendered by their
+// path within the directory, such as `admin/index.tmpl`, by file name if no
+// other template has the same file name, or by the name of any template they
+// define. When several directories contain the same path, the last one loaded
+// wins.
+//
+// Files within a `layouts` or `partials` directory are shared: they are
+// available to every other template, and each page template is parsed in
+// isolation alongside them. This allows pages to define the same blocks and
+// wrap themselves in a common layout:
+//
+//	{{/* layouts/base.tmpl */}}
+//	{{define "base"}}<html><body>{{block "content" .}}{{end}}</body></html>{{end}}
+//
+//	{{/* home.tmpl */}}
+//	{{template "base" .}}
+//	{{define "content"}}Hello, {{.Data.Name}}{{end}}
+//
+// When rendered as part of a request, templates also receive a CSRF token and
+// the authenticated identity, if any. See TemplateData.
+//
 // Configuration:
 // |-----------------------------------|-----------------------|
 // | Env                               | JSON                  |
@@ -14,12 +36,15 @@ import (
 	"bytes"
 	"context"
 	"html/template"
+	"io/fs"
 	"os"
-	"path/filepath"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/dpup/prefab"
 	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
 	"google.golang.org/grpc/codes"
 )
 
@@ -41,12 +66,50 @@ func init() {
 // Constant name for identifying the templates plugin.
 const PluginName = "templates"
 
+// Directory names containing templates which are shared with every page.
+var sharedDirs = []string{"layouts", "partials"}
+
+// TemplateOption customizes the templates plugin.
+type TemplateOption func(*TemplatePlugin)
+
+// WithDirs adds directories to load templates from, in addition to those
+// specified by `templates.dirs`.
+func WithDirs(dirs ...string) TemplateOption {
+	return func(p *TemplatePlugin) {
+		p.dirs = append(p.dirs, dirs...)
+	}
+}
+
+// WithFS adds a filesystem to load templates from, typically an embed.FS
+// compiled into the binary. Templates from a filesystem are loaded after
+// directories.
+func WithFS(fsys fs.FS) TemplateOption {
+	return func(p *TemplatePlugin) {
+		p.fsys = append(p.fsys, fsys)
+	}
+}
+
+// WithFuncs adds functions which can be called from templates.
+func WithFuncs(funcs template.FuncMap) TemplateOption {
+	return func(p *TemplatePlugin) {
+		if p.funcs == nil {
+			p.funcs = template.FuncMap{}
+		}
+		for k, v := range funcs {
+			p.funcs[k] = v
+		}
+	}
+}
+
 // Plugin returns a new TemplatePlugin.
-func Plugin() *TemplatePlugin {
+func Plugin(opts ...TemplateOption) *TemplatePlugin {
 	p := &TemplatePlugin{
 		alwaysParse: prefab.Config.Bool("templates.alwaysParse"),
 		dirs:        prefab.Config.Strings("templates.dirs"),
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
 	return p
 }
 
@@ -54,7 +117,17 @@ func Plugin() *TemplatePlugin {
 type TemplatePlugin struct {
 	alwaysParse bool
 	dirs        []string
-	templates   *template.Template
+	fsys        []fs.FS
+	funcs       template.FuncMap
+
+	mu sync.RWMutex
+	// All templates, used to render templates by their defined name.
+	templates *template.Template
+	// Page templates parsed in isolation with the shared templates, keyed by
+	// path, and by file name when it's unique.
+	pages map[string]*template.Template
+	// Paths of the pages sharing each ambiguous file name.
+	ambiguous map[string][]string
 }
 
 // From prefab.Plugin.
@@ -71,15 +144,19 @@ func (p *TemplatePlugin) Init(ctx context.Context, r *prefab.Registry) error {
 // Load templates (*.tmpl) contained within the provided directory and all
 // sub-directories.
 func (p *TemplatePlugin) Load(dirs []string) error {
-	p.init()
+	p.mu.Lock()
 	p.dirs = append(p.dirs, dirs...)
-	for _, dir := range p.dirs {
-		if err := p.parse(dir); err != nil {
-			return err
-		}
-	}
+	p.mu.Unlock()
+	return p.parseAll()
+}
 
-	return nil
+// LoadFS loads templates (*.tmpl) from the provided filesystem, for example an
+// embed.FS. Templates in the filesystem can't be reloaded when they change.
+func (p *TemplatePlugin) LoadFS(fsys fs.FS) error {
+	p.mu.Lock()
+	p.fsys = append(p.fsys, fsys)
+	p.mu.Unlock()
+	return p.parseAll()
 }
 
 // Render executes a template by name with the provided data.
@@ -98,12 +175,26 @@ func (p *TemplatePlugin) Render(ctx context.Context, name string, data interface
 			return "", err
 		}
 	}
-	if p.templates == nil {
+
+	p.mu.RLock()
+	t := p.templates
+	paths := p.ambiguous[name]
+	if page, ok := p.pages[name]; ok {
+		// Pages are parsed under their file name.
+		t, name = page, path.Base(name)
+	}
+	p.mu.RUnlock()
+
+	if len(paths) > 0 {
+		return "", errors.Codef(codes.InvalidArgument, "template %s is ambiguous, render it by path: %s", name, strings.Join(paths, ", "))
+	}
+
+	if t == nil {
 		return "", errors.NewC("no templates have been initialized", codes.Internal)
 	}
 	var b bytes.Buffer
 	w := bufio.NewWriter(&b)
-	err := p.templates.ExecuteTemplate(w, name, TemplateData{Data: data, Config: prefab.Config.All()})
+	err := t.ExecuteTemplate(w, name, newTemplateData(ctx, data))
 	if err != nil {
 		w.Flush()
 		return "", errors.WrapPrefix(err, "template execution failed (hint: data is wrapped, use .Data.FieldName to access fields)", 0)
@@ -113,33 +204,112 @@ func (p *TemplatePlugin) Render(ctx context.Context, name string, data interface
 	return b.String(), nil
 }
 
-func (p *TemplatePlugin) init() {
-	if p.templates == nil || p.alwaysParse {
-		p.templates = template.New("").Funcs(template.FuncMap{
-			// template functions can be added here.
-		})
-	}
-}
-
 func (p *TemplatePlugin) parseAll() error {
-	p.init()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sources := make([]fs.FS, 0, len(p.dirs)+len(p.fsys))
 	for _, dir := range p.dirs {
-		if err := p.parse(dir); err != nil {
+		sources = append(sources, os.DirFS(dir))
+	}
+	sources = append(sources, p.fsys...)
+
+	var shared, pages []templateFile
+	for _, fsys := range sources {
+		files, err := findTemplates(fsys)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if f.shared {
+				shared = append(shared, f)
+			} else {
+				pages = append(pages, f)
+			}
+		}
+	}
+
+	base := template.New("").Funcs(p.funcs)
+	for _, f := range shared {
+		if _, err := base.ParseFS(f.fsys, f.path); err != nil {
+			return err
+		}
+	}
+
+	all, err := base.Clone()
+	if err != nil {
+		return err
+	}
+	pageTemplates := make(map[string]*template.Template, len(pages))
+	pagePaths := map[string][]string{} // Paths of the pages with each file name.
+	for _, f := range pages {
+		if _, err := all.ParseFS(f.fsys, f.path); err != nil {
+			return err
+		}
+		page, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if _, err := page.ParseFS(f.fsys, f.path); err != nil {
 			return err
 		}
+		if _, ok := pageTemplates[f.path]; !ok {
+			name := path.Base(f.path)
+			pagePaths[name] = append(pagePaths[name], f.path)
+		}
+		pageTemplates[f.path] = page
 	}
+
+	ambiguous := map[string][]string{}
+	for name, paths := range pagePaths {
+		switch {
+		case len(paths) == 1:
+			pageTemplates[name] = pageTemplates[paths[0]]
+		case pageTemplates[name] != nil:
+			// The file name is also the path of a page in the root directory.
+		default:
+			ambiguous[name] = paths
+		}
+	}
+
+	p.templates = all
+	p.pages = pageTemplates
+	p.ambiguous = ambiguous
 	return nil
 }
 
-func (p *TemplatePlugin) parse(dir string) error {
-	return filepath.Walk(dir, func(path string, _ os.FileInfo, _ error) error {
-		if strings.HasSuffix(path, ".tmpl") {
-			if _, err := p.templates.ParseFiles(path); err != nil {
-				return err
-			}
+type templateFile struct {
+	fsys   fs.FS
+	path   string
+	shared bool
+}
+
+func findTemplates(fsys fs.FS) ([]templateFile, error) {
+	var files []templateFile
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Missing or unreadable directories are skipped, since default
+			// directories may not exist.
+			return nil //nolint:nilerr // Intentional.
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".tmpl") {
+			return nil
 		}
+		files = append(files, templateFile{fsys: fsys, path: p, shared: isShared(p)})
 		return nil
 	})
+	return files, err
+}
+
+func isShared(p string) bool {
+	for _, part := range strings.Split(path.Dir(p), "/") {
+		for _, dir := range sharedDirs {
+			if part == dir {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // TemplateData is the wrapper struct passed to all templates during rendering.
@@ -149,4 +319,25 @@ type TemplateData struct {
 	Data interface{}
 	// Config contains all configuration values from prefab.Config.
 	Config map[string]interface{}
+	// CSRFToken contains the CSRF token for the current request, when rendered
+	// via prefab.WithTemplateHandler.
+	CSRFToken string
+	// CSRFField is a hidden form input containing the CSRF token, which can be
+	// included in forms which POST to the server: `{{.CSRFField}}`.
+	CSRFField template.HTML
+	// Identity is the authenticated user making the request, or nil.
+	Identity *auth.Identity
+}
+
+func newTemplateData(ctx context.Context, data interface{}) TemplateData {
+	td := TemplateData{Data: data, Config: prefab.Config.All()}
+	if ct := prefab.CSRFTokenFromContext(ctx); ct != "" {
+		td.CSRFToken = ct
+		td.CSRFField = template.HTML(`<input type="hidden" name="` + prefab.CSRFParam + `" value="` +
+			template.HTMLEscapeString(ct) + `">`) //nolint:gosec // Token is escaped.
+	}
+	if identity, err := auth.IdentityFromContext(ctx); err == nil {
+		td.Identity = &identity
+	}
+	return td
 }
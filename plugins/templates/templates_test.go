@@ -1,13 +1,20 @@
 package templates
 
 import (
+	"context"
+	"html/template"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 )
 
 func TestPlugin(t *testing.T) {
@@ -296,3 +303,109 @@ func TestSubdirectories(t *testing.T) {
 		assert.Equal(t, "Nested: Success", result)
 	})
 }
+
+func TestDuplicateFileNames(t *testing.T) {
+	p := &TemplatePlugin{}
+	require.NoError(t, p.LoadFS(fstest.MapFS{
+		"admin/users/list.tmpl": {Data: []byte(`Admin users`)},
+		"users/list.tmpl":       {Data: []byte(`Users`)},
+		"admin/index.tmpl":      {Data: []byte(`Admin`)},
+		"index.tmpl":            {Data: []byte(`Home`)},
+	}))
+
+	for name, want := range map[string]string{
+		"admin/users/list.tmpl": "Admin users",
+		"users/list.tmpl":       "Users",
+		"admin/index.tmpl":      "Admin",
+		"index.tmpl":            "Home",
+	} {
+		result, err := p.Render(t.Context(), name, nil)
+		require.NoError(t, err, name)
+		assert.Equal(t, want, result, name)
+	}
+
+	_, err := p.Render(t.Context(), "list.tmpl", nil)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+	assert.ErrorContains(t, err, "admin/users/list.tmpl, users/list.tmpl")
+}
+
+func TestLayouts(t *testing.T) {
+	tempDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "layouts"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "partials"), 0755))
+
+	files := map[string]string{
+		"layouts/base.tmpl":  `{{define "base"}}<main>{{block "content" .}}{{end}}</main>{{end}}`,
+		"partials/name.tmpl": `{{define "name"}}<b>{{.Data}}</b>{{end}}`,
+		"home.tmpl":          `{{template "base" .}}{{define "content"}}Home {{template "name" .}}{{end}}`,
+		"about.tmpl":         `{{template "base" .}}{{define "content"}}About {{template "name" .}}{{end}}`,
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), []byte(content), 0644))
+	}
+
+	p := &TemplatePlugin{}
+	require.NoError(t, p.Load([]string{tempDir}))
+
+	result, err := p.Render(t.Context(), "home.tmpl", "Alice")
+	require.NoError(t, err)
+	assert.Equal(t, "<main>Home <b>Alice</b></main>", result)
+
+	result, err = p.Render(t.Context(), "about.tmpl", "Bob")
+	require.NoError(t, err)
+	assert.Equal(t, "<main>About <b>Bob</b></main>", result)
+
+	// Shared templates can be rendered directly.
+	result, err = p.Render(t.Context(), "name", "Carol")
+	require.NoError(t, err)
+	assert.Equal(t, "<b>Carol</b>", result)
+}
+
+func TestLoadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pages/hello.tmpl":     {Data: []byte(`{{define "title"}}Hi{{end}}Hello {{.Data}}`)},
+		"pages/ignored.txt":    {Data: []byte(`Ignored`)},
+		"partials/footer.tmpl": {Data: []byte(`{{define "footer"}}Bye{{end}}`)},
+	}
+
+	p := Plugin(WithFS(fsys), WithFuncs(template.FuncMap{"upper": strings.ToUpper}))
+	require.NoError(t, p.Init(t.Context(), nil))
+
+	result, err := p.Render(t.Context(), "hello.tmpl", "World")
+	require.NoError(t, err)
+	assert.Equal(t, "Hello World", result)
+
+	result, err = p.Render(t.Context(), "title", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi", result)
+
+	require.NoError(t, p.LoadFS(fstest.MapFS{
+		"shout.tmpl": {Data: []byte(`{{upper .Data}} {{template "footer"}}`)},
+	}))
+	result, err = p.Render(t.Context(), "shout.tmpl", "hey")
+	require.NoError(t, err)
+	assert.Equal(t, "HEY Bye", result)
+}
+
+func TestRequestData(t *testing.T) {
+	fsys := fstest.MapFS{
+		"form.tmpl": {Data: []byte(`<form>{{.CSRFField}}</form>{{if .Identity}}{{.Identity.Email}}{{else}}anon{{end}}`)},
+	}
+	p := &TemplatePlugin{}
+	require.NoError(t, p.LoadFS(fsys))
+
+	t.Run("Anonymous", func(t *testing.T) {
+		result, err := p.Render(t.Context(), "form.tmpl", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "<form></form>anon", result)
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		ctx := auth.WithIdentityExtractors(t.Context(), func(context.Context) (auth.Identity, error) {
+			return auth.Identity{Email: "alice@example.com"}, nil
+		})
+		result, err := p.Render(ctx, "form.tmpl", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "<form></form>alice@example.com", result)
+	})
+}
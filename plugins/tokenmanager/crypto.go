@@ -0,0 +1,76 @@
+package tokenmanager
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/dpup/prefab/errors"
+)
+
+// sealer encrypts tokens with AES-256-GCM. Keys are derived from secrets with
+// SHA-256, so secrets of any length can be configured.
+type sealer struct {
+	current  cipher.AEAD
+	previous []cipher.AEAD
+}
+
+func newSealer(key string, previous []string) (*sealer, error) {
+	s := &sealer{}
+	var err error
+	if s.current, err = newAEAD(key); err != nil {
+		return nil, err
+	}
+	for _, k := range previous {
+		aead, err := newAEAD(k)
+		if err != nil {
+			return nil, err
+		}
+		s.previous = append(s.previous, aead)
+	}
+	return s, nil
+}
+
+func newAEAD(secret string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	return aead, nil
+}
+
+// seal encrypts plaintext with the current key, returning the nonce and
+// ciphertext base64 encoded.
+func (s *sealer) seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, s.current.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+	return base64.StdEncoding.EncodeToString(s.current.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// open decrypts a value from seal, trying the current key and then previous
+// keys, so that keys can be rotated.
+func (s *sealer) open(sealed string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return nil, errors.Wrap(err, 0)
+	}
+	for _, aead := range append([]cipher.AEAD{s.current}, s.previous...) {
+		if len(b) < aead.NonceSize() {
+			break
+		}
+		nonce, ciphertext := b[:aead.NonceSize()], b[aead.NonceSize():]
+		if plaintext, err := aead.Open(nil, nonce, ciphertext, nil); err == nil {
+			return plaintext, nil
+		}
+	}
+	return nil, errors.New("tokenmanager: failed to decrypt token, the encryption key may have changed")
+}
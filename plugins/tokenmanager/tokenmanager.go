@@ -0,0 +1,583 @@
+// Package tokenmanager stores OAuth tokens which users have granted for
+// third-party APIs, such as Google Calendar, and keeps them fresh.
+//
+// Tokens are stored per identity and provider via the storage plugin,
+// encrypted with AES-256-GCM. GetToken returns a valid access token, using the
+// refresh token to obtain a new one when it has expired. Refreshes are
+// serialized with a lock, via the lock plugin when it is registered, so that
+// replicas don't race to use a refresh token which the provider rotates.
+//
+// When a refresh token has been revoked or has expired, the user needs to
+// grant access again. The token is marked as requiring consent, GetToken
+// returns ErrConsentRequired, and ConsentRequiredEvent is published when the
+// eventbus plugin is registered.
+//
+// The google plugin stores the tokens it receives at login, when this plugin
+// is registered. Other providers can be added with WithProvider.
+//
+// Example:
+//
+//	prefab.WithPlugin(tokenmanager.Plugin())
+//	prefab.WithPlugin(google.Plugin(google.WithOfflineAccess(), google.WithScopes(calendar.CalendarReadonlyScope)))
+//
+//	// In a handler:
+//	client := httpclient.New(httpclient.WithTransport(
+//		tokenmanager.FromContext(ctx).Transport(identity, google.ProviderName, nil)))
+package tokenmanager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/internal/config"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/lock/memlock"
+	"github.com/dpup/prefab/plugins/storage"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "tokenmanager.encryptionKey",
+			Description: "Secret used to encrypt stored OAuth tokens",
+			Type:        "string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "tokenmanager.previousEncryptionKeys",
+			Description: "Previous encryption keys, still used to decrypt tokens during key rotation",
+			Type:        "[]string",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "tokenmanager.refreshLeeway",
+			Description: "How long before expiry access tokens are refreshed",
+			Type:        "duration",
+			Default:     "1m",
+		},
+	)
+}
+
+const (
+	// PluginName identifies this plugin.
+	PluginName = "tokenmanager"
+
+	// ConsentRequiredEvent is published with ConsentRequiredEventData when a
+	// token can't be refreshed and the user needs to grant access again.
+	ConsentRequiredEvent = "tokenmanager.consent_required"
+
+	defaultRefreshLeeway = time.Minute
+)
+
+var (
+	// Returned when there is no token for the identity and provider.
+	ErrNoToken = errors.NewC("tokenmanager: no token for provider", codes.NotFound)
+
+	// Returned when the token can't be refreshed, because the refresh token is
+	// missing, has expired, or has been revoked. The user needs to grant access
+	// again.
+	ErrConsentRequired = errors.NewC("tokenmanager: consent required", codes.FailedPrecondition)
+
+	// Returned for providers which weren't registered.
+	ErrUnknownProvider = errors.NewC("tokenmanager: unknown provider", codes.InvalidArgument)
+)
+
+// ConsentRequiredEventData is published with ConsentRequiredEvent.
+type ConsentRequiredEventData struct {
+	Subject   string    `json:"subject"`
+	Provider  string    `json:"provider"`
+	Error     string    `json:"error"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// storedToken is an encrypted token for an identity and provider.
+type storedToken struct {
+	ID       string `json:"id"`
+	Subject  string `json:"subject"`
+	Provider string `json:"provider"`
+
+	// The oauth2.Token as JSON, sealed with the encryption key.
+	Token string `json:"token"`
+
+	// Scopes the user has granted, from the token response's "scope" field.
+	Scopes []string `json:"scopes,omitempty"`
+
+	// Set when the token couldn't be refreshed, until a new one is stored.
+	ConsentRequired bool `json:"consentRequired"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PK implements storage.Model.
+func (t storedToken) PK() string {
+	return t.ID
+}
+
+// Name implements storage.Namer.
+func (t storedToken) Name() string {
+	return "oauth_tokens"
+}
+
+func tokenID(subject, provider string) string {
+	return provider + ":" + subject
+}
+
+// TokenManagerOption configures the token manager plugin.
+type TokenManagerOption func(*TokenManagerPlugin)
+
+// WithProvider registers the OAuth config used to refresh tokens for a
+// provider. Only the client credentials and endpoint are used.
+func WithProvider(name string, cfg *oauth2.Config) TokenManagerOption {
+	return func(p *TokenManagerPlugin) {
+		p.RegisterProvider(name, cfg)
+	}
+}
+
+// WithEncryptionKey sets the secret tokens are encrypted with. Previous keys
+// are used to decrypt tokens stored before the key was rotated, and tokens are
+// re-encrypted with the current key when they are next refreshed.
+//
+// Config keys: `tokenmanager.encryptionKey`, `tokenmanager.previousEncryptionKeys`.
+func WithEncryptionKey(key string, previous ...string) TokenManagerOption {
+	return func(p *TokenManagerPlugin) {
+		p.encryptionKey = key
+		p.previousKeys = previous
+	}
+}
+
+// WithRefreshLeeway sets how long before expiry access tokens are refreshed,
+// so that they don't expire while a request is in flight.
+//
+// Config key: `tokenmanager.refreshLeeway`.
+func WithRefreshLeeway(d time.Duration) TokenManagerOption {
+	return func(p *TokenManagerPlugin) {
+		p.refreshLeeway = d
+	}
+}
+
+// Plugin returns a new TokenManagerPlugin.
+func Plugin(opts ...TokenManagerOption) *TokenManagerPlugin {
+	config.EnsureDefaultsLoaded(prefab.Config)
+
+	p := &TokenManagerPlugin{
+		providers:     map[string]*oauth2.Config{},
+		encryptionKey: prefab.ConfigString("tokenmanager.encryptionKey"),
+		previousKeys:  prefab.ConfigStrings("tokenmanager.previousEncryptionKeys"),
+		refreshLeeway: defaultRefreshLeeway,
+		now:           time.Now,
+	}
+	if prefab.ConfigExists("tokenmanager.refreshLeeway") {
+		p.refreshLeeway = prefab.ConfigDuration("tokenmanager.refreshLeeway")
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// TokenManagerPlugin stores and refreshes OAuth tokens for third-party APIs.
+type TokenManagerPlugin struct {
+	encryptionKey string
+	previousKeys  []string
+	refreshLeeway time.Duration
+
+	mu        sync.RWMutex
+	providers map[string]*oauth2.Config
+
+	sealer *sealer
+	store  storage.Store
+	locker lock.Locker
+	bus    eventbus.EventBus
+	now    func() time.Time
+}
+
+// From prefab.Plugin.
+func (p *TokenManagerPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *TokenManagerPlugin) Deps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *TokenManagerPlugin) OptDeps() []string {
+	return []string{lock.PluginName, eventbus.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *TokenManagerPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *TokenManagerPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if !ok {
+		return errors.New("tokenmanager: storage plugin is required")
+	}
+	if err := sp.InitModel(storedToken{}); err != nil {
+		return err
+	}
+	p.store = sp
+
+	key := p.encryptionKey
+	if key == "" {
+		log.Println("⚠️  WARNING: tokenmanager encryption key not configured; using an " +
+			"ephemeral per-process key. Stored tokens can't be decrypted after a restart " +
+			"or by other replicas. Set tokenmanager.encryptionKey in production.")
+		key = randomKey()
+	}
+	s, err := newSealer(key, p.previousKeys)
+	if err != nil {
+		return err
+	}
+	p.sealer = s
+
+	if lp, ok := r.Get(lock.PluginName).(*lock.LockPlugin); ok {
+		p.locker = lp
+	} else {
+		p.locker = memlock.New()
+	}
+	if bus, ok := r.Get(eventbus.PluginName).(*eventbus.EventBusPlugin); ok {
+		p.bus = bus
+	}
+	return nil
+}
+
+// RegisterProvider registers the OAuth config used to refresh tokens for a
+// provider. It is called by identity plugins, such as google, during Init.
+func (p *TokenManagerPlugin) RegisterProvider(name string, cfg *oauth2.Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.providers[name] = cfg
+}
+
+func (p *TokenManagerPlugin) provider(name string) (*oauth2.Config, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	cfg, ok := p.providers[name]
+	if !ok {
+		return nil, errors.Mark(ErrUnknownProvider, 0).Append(name)
+	}
+	return cfg, nil
+}
+
+// Store saves a token granted by the identity for the provider, replacing any
+// existing token. If the new token has no refresh token, the existing refresh
+// token is kept, since providers usually only return one on first consent.
+// Likewise, granted scopes are taken from the token's "scope" field, if the
+// provider returned one, and are otherwise kept.
+func (p *TokenManagerPlugin) Store(ctx context.Context, identity auth.Identity, provider string, token *oauth2.Token) error {
+	if _, err := p.provider(provider); err != nil {
+		return err
+	}
+	subject, err := subjectFor(ctx, identity)
+	if err != nil {
+		return err
+	}
+	t := *token
+	scopes := Scopes(token)
+	if t.RefreshToken == "" || scopes == nil {
+		rec, existing, err := p.load(ctx, subject, provider)
+		if err == nil {
+			if t.RefreshToken == "" {
+				t.RefreshToken = existing.RefreshToken
+			}
+			if scopes == nil {
+				scopes = rec.Scopes
+			}
+		} else if !errors.Is(err, ErrNoToken) {
+			return err
+		}
+	}
+	return p.write(ctx, subject, provider, &t, scopes)
+}
+
+// GetToken returns a valid token granted by the identity for the provider,
+// refreshing it if it has expired, or expires within the refresh leeway.
+// Returns ErrNoToken if no token has been stored, and ErrConsentRequired if
+// the token can't be refreshed.
+func (p *TokenManagerPlugin) GetToken(ctx context.Context, identity auth.Identity, provider string) (*oauth2.Token, error) {
+	cfg, err := p.provider(provider)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := subjectFor(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	rec, token, err := p.load(ctx, subject, provider)
+	if err != nil {
+		return nil, err
+	}
+	if rec.ConsentRequired {
+		return nil, errors.Mark(ErrConsentRequired, 0)
+	}
+	if p.fresh(token) {
+		return token, nil
+	}
+	return p.refresh(ctx, cfg, subject, provider)
+}
+
+// StoredToken returns the token granted by the identity for the provider as it
+// was stored, without refreshing it, for example to revoke it.
+func (p *TokenManagerPlugin) StoredToken(ctx context.Context, identity auth.Identity, provider string) (*oauth2.Token, error) {
+	subject, err := subjectFor(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	_, token, err := p.load(ctx, subject, provider)
+	return token, err
+}
+
+// GrantedScopes returns the scopes the identity has granted the provider, as
+// reported by the provider's token responses. Returns nil if the provider
+// doesn't report scopes.
+func (p *TokenManagerPlugin) GrantedScopes(ctx context.Context, identity auth.Identity, provider string) ([]string, error) {
+	subject, err := subjectFor(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	rec, _, err := p.load(ctx, subject, provider)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Scopes, nil
+}
+
+// Delete removes the token granted by the identity for the provider, for
+// example after the user disconnects the integration. It doesn't revoke the
+// token with the provider.
+func (p *TokenManagerPlugin) Delete(ctx context.Context, identity auth.Identity, provider string) error {
+	subject, err := subjectFor(ctx, identity)
+	if err != nil {
+		return err
+	}
+	err = p.store.Delete(ctx, storedToken{ID: tokenID(subject, provider)})
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// TokenSource returns an oauth2.TokenSource for the identity and provider,
+// which calls GetToken with ctx.
+func (p *TokenManagerPlugin) TokenSource(ctx context.Context, identity auth.Identity, provider string) oauth2.TokenSource {
+	return &tokenSource{ctx: ctx, p: p, identity: identity, provider: provider}
+}
+
+// Transport returns an http.RoundTripper which sets the Authorization header of
+// requests with a fresh token for the identity and provider. Tokens are
+// fetched with each request's context. A nil base uses
+// http.DefaultTransport.
+func (p *TokenManagerPlugin) Transport(identity auth.Identity, provider string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{p: p, identity: identity, provider: provider, base: base}
+}
+
+// refresh obtains a new access token while holding the token's lock. Another
+// process may have refreshed it while waiting, so the token is read again
+// first.
+func (p *TokenManagerPlugin) refresh(ctx context.Context, cfg *oauth2.Config, subject, provider string) (*oauth2.Token, error) {
+	lease, err := p.locker.Lock(ctx, "tokenmanager:"+tokenID(subject, provider))
+	if err != nil {
+		return nil, errors.Wrap(err, 0).Append("tokenmanager: failed to lock token")
+	}
+	defer func() {
+		if err := lease.Unlock(context.WithoutCancel(ctx)); err != nil {
+			logging.Errorw(ctx, "tokenmanager: failed to unlock token", "error", err)
+		}
+	}()
+
+	rec, token, err := p.load(ctx, subject, provider)
+	if err != nil {
+		return nil, err
+	}
+	if rec.ConsentRequired {
+		return nil, errors.Mark(ErrConsentRequired, 0)
+	}
+	if p.fresh(token) {
+		return token, nil
+	}
+	if token.RefreshToken == "" {
+		return nil, p.requireConsent(ctx, rec, "no refresh token")
+	}
+
+	// Clearing the access token forces the token source to refresh.
+	stale := *token
+	stale.AccessToken = ""
+	refreshed, err := cfg.TokenSource(ctx, &stale).Token()
+	if err != nil {
+		var re *oauth2.RetrieveError
+		if errors.As(err, &re) && re.ErrorCode == "invalid_grant" {
+			return nil, p.requireConsent(ctx, rec, err.Error())
+		}
+		return nil, errors.Wrap(err, 0).WithCode(codes.Unavailable).Append("tokenmanager: failed to refresh token")
+	}
+	scopes := Scopes(refreshed)
+	if scopes == nil {
+		scopes = rec.Scopes
+	}
+	if err := p.write(ctx, subject, provider, refreshed, scopes); err != nil {
+		return nil, err
+	}
+	logging.Infow(ctx, "tokenmanager: refreshed token", "provider", provider, "subject", subject)
+	return refreshed, nil
+}
+
+// requireConsent marks the token as needing the user to grant access again.
+func (p *TokenManagerPlugin) requireConsent(ctx context.Context, rec *storedToken, reason string) error {
+	logging.Warnw(ctx, "tokenmanager: token can't be refreshed, consent required",
+		"provider", rec.Provider, "subject", rec.Subject, "reason", reason)
+	rec.ConsentRequired = true
+	rec.UpdatedAt = p.now()
+	if err := p.store.Update(ctx, rec); err != nil {
+		return err
+	}
+	if p.bus != nil {
+		p.bus.Publish(ConsentRequiredEvent, ConsentRequiredEventData{
+			Subject:   rec.Subject,
+			Provider:  rec.Provider,
+			Error:     reason,
+			Timestamp: rec.UpdatedAt,
+		})
+	}
+	return errors.Mark(ErrConsentRequired, 0).Append(reason)
+}
+
+func (p *TokenManagerPlugin) fresh(token *oauth2.Token) bool {
+	if token.AccessToken == "" {
+		return false
+	}
+	return token.Expiry.IsZero() || token.Expiry.After(p.now().Add(p.refreshLeeway))
+}
+
+func (p *TokenManagerPlugin) load(ctx context.Context, subject, provider string) (*storedToken, *oauth2.Token, error) {
+	rec := &storedToken{}
+	if err := p.store.Read(ctx, tokenID(subject, provider), rec); errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, errors.Mark(ErrNoToken, 0)
+	} else if err != nil {
+		return nil, nil, err
+	}
+	b, err := p.sealer.open(rec.Token)
+	if err != nil {
+		return nil, nil, err
+	}
+	token := &oauth2.Token{}
+	if err := json.Unmarshal(b, token); err != nil {
+		return nil, nil, errors.Wrap(err, 0)
+	}
+	return rec, token, nil
+}
+
+func (p *TokenManagerPlugin) write(ctx context.Context, subject, provider string, token *oauth2.Token, scopes []string) error {
+	b, err := json.Marshal(token)
+	if err != nil {
+		return errors.Wrap(err, 0)
+	}
+	sealed, err := p.sealer.seal(b)
+	if err != nil {
+		return err
+	}
+	return p.store.Upsert(ctx, storedToken{
+		ID:        tokenID(subject, provider),
+		Subject:   subject,
+		Provider:  provider,
+		Token:     sealed,
+		Scopes:    scopes,
+		UpdatedAt: p.now(),
+	})
+}
+
+func (p *TokenManagerPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tokenManagerKey{}, p)
+}
+
+// FromContext retrieves the token manager plugin from context. Returns nil if
+// the plugin isn't registered.
+func FromContext(ctx context.Context) *TokenManagerPlugin {
+	if p, ok := ctx.Value(tokenManagerKey{}).(*TokenManagerPlugin); ok {
+		return p
+	}
+	return nil
+}
+
+type tokenManagerKey struct{}
+
+// subjectFor returns the subject tokens are stored under. When account linking
+// is enabled this is the canonical account ID, so that a token stored at login
+// is found by requests from any of the account's identities.
+func subjectFor(ctx context.Context, identity auth.Identity) (string, error) {
+	identity, err := auth.ResolveAccount(ctx, identity)
+	if err != nil {
+		return "", err
+	}
+	return identity.Subject, nil
+}
+
+// Scopes returns the space-delimited "scope" field of a token response, which
+// lists the scopes granted. Returns nil if the provider didn't return one.
+func Scopes(token *oauth2.Token) []string {
+	s, _ := token.Extra("scope").(string)
+	if s == "" {
+		return nil
+	}
+	return strings.Fields(s)
+}
+
+func randomKey() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic("tokenmanager: failed to generate encryption key: " + err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+type tokenSource struct {
+	ctx      context.Context
+	p        *TokenManagerPlugin
+	identity auth.Identity
+	provider string
+}
+
+func (s *tokenSource) Token() (*oauth2.Token, error) {
+	return s.p.GetToken(s.ctx, s.identity, s.provider)
+}
+
+type transport struct {
+	p        *TokenManagerPlugin
+	identity auth.Identity
+	provider string
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.p.GetToken(req.Context(), t.identity, t.provider)
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	token.SetAuthHeader(req)
+	return t.base.RoundTrip(req)
+}
@@ -0,0 +1,265 @@
+package tokenmanager
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+)
+
+// fakeProvider is a token endpoint which issues "access-N" tokens for the
+// refresh token "refresh", and rejects other refresh tokens.
+type fakeProvider struct {
+	*httptest.Server
+	refreshes atomic.Int32
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	f := &fakeProvider{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "client", r.PostForm.Get("client_id"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("refresh_token") != "refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"invalid_grant","error_description":"Token has been expired or revoked."}`))
+			return
+		}
+		n := f.refreshes.Add(1)
+		_, _ = w.Write([]byte(`{"access_token":"access-` + string(rune('0'+n)) + `","token_type":"Bearer","expires_in":3600}`))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeProvider) config() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		Endpoint:     oauth2.Endpoint{TokenURL: f.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+	}
+}
+
+func setup(t *testing.T, opts ...TokenManagerOption) (context.Context, *TokenManagerPlugin, *fakeProvider, eventbus.EventBus) {
+	ctx := prefabtest.Context(t)
+	f := newFakeProvider(t)
+	bus := membus.New(ctx)
+
+	opts = append([]TokenManagerOption{WithProvider("acme", f.config()), WithEncryptionKey("key")}, opts...)
+	p := Plugin(opts...)
+	prefabtest.Init(t, p, storage.Plugin(memstore.New()), eventbus.Plugin(bus))
+	return ctx, p, f, bus
+}
+
+var alice = auth.Identity{Provider: "acme", Subject: "alice"}
+
+func TestGetToken_Refresh(t *testing.T) {
+	ctx, p, f, _ := setup(t)
+
+	_, err := p.GetToken(ctx, alice, "acme")
+	require.ErrorIs(t, err, ErrNoToken)
+
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "initial",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour),
+	}))
+
+	token, err := p.GetToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "initial", token.AccessToken)
+	assert.Equal(t, int32(0), f.refreshes.Load())
+
+	// Within the leeway the token is refreshed, and the refresh token kept.
+	p.now = func() time.Time { return time.Now().Add(time.Hour - 30*time.Second) }
+	token, err = p.GetToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, "refresh", token.RefreshToken)
+
+	p.now = time.Now
+	token, err = p.GetToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token.AccessToken, "refreshed token is stored")
+	assert.Equal(t, int32(1), f.refreshes.Load())
+}
+
+func TestGetToken_ConcurrentRefresh(t *testing.T) {
+	ctx, p, f, _ := setup(t)
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	}))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			token, err := p.GetToken(ctx, alice, "acme")
+			assert.NoError(t, err)
+			assert.Equal(t, "access-1", token.AccessToken)
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), f.refreshes.Load(), "only one refresh is made")
+}
+
+func TestGetToken_ConsentRequired(t *testing.T) {
+	ctx, p, f, bus := setup(t)
+
+	events := make(chan ConsentRequiredEventData, 1)
+	bus.Subscribe(ConsentRequiredEvent, func(_ context.Context, msg *eventbus.Message) error {
+		events <- msg.Data.(ConsentRequiredEventData)
+		return nil
+	})
+
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "expired",
+		RefreshToken: "revoked",
+		Expiry:       time.Now().Add(-time.Minute),
+	}))
+	_, err := p.GetToken(ctx, alice, "acme")
+	require.ErrorIs(t, err, ErrConsentRequired)
+	assert.Equal(t, codes.FailedPrecondition, errors.Code(err))
+
+	select {
+	case e := <-events:
+		assert.Equal(t, "alice", e.Subject)
+		assert.Equal(t, "acme", e.Provider)
+		assert.Contains(t, e.Error, "invalid_grant")
+	case <-time.After(time.Second):
+		t.Fatal("expected consent required event")
+	}
+
+	// The provider isn't called again until a new token is stored.
+	_, err = p.GetToken(ctx, alice, "acme")
+	require.ErrorIs(t, err, ErrConsentRequired)
+
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	}))
+	token, err := p.GetToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "access-1", token.AccessToken)
+	assert.Equal(t, int32(1), f.refreshes.Load())
+}
+
+func TestGetToken_NoRefreshToken(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken: "expired",
+		Expiry:      time.Now().Add(-time.Minute),
+	}))
+	_, err := p.GetToken(ctx, alice, "acme")
+	require.ErrorIs(t, err, ErrConsentRequired)
+}
+
+func TestUnknownProvider(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	_, err := p.GetToken(ctx, alice, "other")
+	require.ErrorIs(t, err, ErrUnknownProvider)
+	require.ErrorIs(t, p.Store(ctx, alice, "other", &oauth2.Token{}), ErrUnknownProvider)
+}
+
+func TestEncryption(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "plaintext-access",
+		RefreshToken: "plaintext-refresh",
+	}))
+
+	rec := &storedToken{}
+	require.NoError(t, p.store.Read(ctx, tokenID("alice", "acme"), rec))
+	assert.NotContains(t, rec.Token, "plaintext")
+
+	// After rotation, tokens encrypted with the previous key can be read.
+	rotated, err := newSealer("new-key", []string{"key"})
+	require.NoError(t, err)
+	p.sealer = rotated
+	token, err := p.GetToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext-access", token.AccessToken)
+
+	p.sealer, err = newSealer("other-key", nil)
+	require.NoError(t, err)
+	_, err = p.GetToken(ctx, alice, "acme")
+	require.Error(t, err)
+}
+
+func TestDelete(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{AccessToken: "a"}))
+	require.NoError(t, p.Delete(ctx, alice, "acme"))
+	require.NoError(t, p.Delete(ctx, alice, "acme"))
+	_, err := p.GetToken(ctx, alice, "acme")
+	require.ErrorIs(t, err, ErrNoToken)
+}
+
+func TestTransport(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{
+		AccessToken:  "expired",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(-time.Minute),
+	}))
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer api.Close()
+
+	client := &http.Client{Transport: p.Transport(alice, "acme", nil)}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, api.URL, nil)
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	b := make([]byte, 64)
+	n, _ := resp.Body.Read(b)
+	assert.Equal(t, "Bearer access-1", string(b[:n]))
+	assert.Empty(t, req.Header.Get("Authorization"), "the caller's request isn't modified")
+
+	// Errors from the token manager are returned by the client.
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, api.URL, nil)
+	_, err = (&http.Client{Transport: p.Transport(auth.Identity{Subject: "bob"}, "acme", nil)}).Do(req)
+	require.ErrorIs(t, err, ErrNoToken)
+}
+
+func TestGrantedScopes(t *testing.T) {
+	ctx, p, _, _ := setup(t)
+	token := (&oauth2.Token{AccessToken: "a", RefreshToken: "refresh"}).
+		WithExtra(map[string]any{"scope": "openid email calendar"})
+	require.NoError(t, p.Store(ctx, alice, "acme", token))
+
+	scopes, err := p.GrantedScopes(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openid", "email", "calendar"}, scopes)
+
+	// Tokens without a scope field keep the granted scopes and refresh token.
+	require.NoError(t, p.Store(ctx, alice, "acme", &oauth2.Token{AccessToken: "b"}))
+	scopes, err = p.GrantedScopes(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openid", "email", "calendar"}, scopes)
+
+	stored, err := p.StoredToken(ctx, alice, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, "b", stored.AccessToken)
+	assert.Equal(t, "refresh", stored.RefreshToken)
+}
@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"context"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// PluginName is the name of the validation plugin.
+const PluginName = "validation"
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "validation.validateResponses",
+			Description: "Also validate response messages, intended for development",
+			Type:        "bool",
+			Default:     false,
+		},
+	)
+}
+
+// ValidationOption customizes the validation plugin.
+type ValidationOption func(*ValidationPlugin)
+
+// WithValidator overrides the validator used to check messages. Defaults to
+// GeneratedValidator.
+func WithValidator(v Validator) ValidationOption {
+	return func(p *ValidationPlugin) {
+		p.validator = v
+	}
+}
+
+// WithResponseValidation configures whether responses should be validated in
+// addition to requests. A response that fails validation is logged and
+// replaced with an Internal error. This is useful in development to catch
+// handlers that violate their own API contract, but adds overhead to every
+// request so is off by default.
+//
+// Config key: `validation.validateResponses`.
+func WithResponseValidation(enabled bool) ValidationOption {
+	return func(p *ValidationPlugin) {
+		p.validateResponses = enabled
+	}
+}
+
+// Plugin returns a new ValidationPlugin.
+//
+//	s := prefab.New(prefab.WithPlugin(validation.Plugin()))
+func Plugin(opts ...ValidationOption) *ValidationPlugin {
+	p := &ValidationPlugin{
+		validator:         GeneratedValidator(),
+		validateResponses: prefab.ConfigBool("validation.validateResponses"),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// ValidationPlugin registers an interceptor which validates request messages.
+type ValidationPlugin struct {
+	validator         Validator
+	validateResponses bool
+}
+
+// From prefab.Plugin.
+func (p *ValidationPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.OptionProvider.
+func (p *ValidationPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithGRPCInterceptor(p.interceptor, prefab.InterceptorPhase(prefab.PhaseValidation)),
+	}
+}
+
+func (p *ValidationPlugin) interceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if msg, ok := req.(proto.Message); ok {
+		if err := p.validator.Validate(msg); err != nil {
+			return nil, ToError(err)
+		}
+	}
+
+	resp, err := handler(ctx, req)
+	if err != nil || !p.validateResponses {
+		return resp, err
+	}
+
+	if msg, ok := resp.(proto.Message); ok {
+		if verr := p.validator.Validate(msg); verr != nil {
+			logging.Errorw(ctx, "validation: response failed validation",
+				"method", info.FullMethod, "error", verr)
+			return nil, errors.Mark(ErrInvalidResponse, 0)
+		}
+	}
+	return resp, nil
+}
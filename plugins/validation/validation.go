@@ -0,0 +1,157 @@
+// Package validation provides a Prefab plugin that validates incoming request
+// messages before they reach a handler.
+//
+// By default, messages are validated using the methods generated by
+// protoc-gen-validate (`ValidateAll() error` or `Validate() error`). Messages
+// that do not expose either method are passed through untouched. Other
+// validation libraries, such as buf's protovalidate, can be plugged in with
+// WithValidator:
+//
+//	validator, _ := protovalidate.New()
+//	s := prefab.New(
+//	    prefab.WithPlugin(validation.Plugin(
+//	        validation.WithValidator(validation.ValidatorFunc(validator.Validate)),
+//	    )),
+//	)
+//
+// Violations are converted to an InvalidArgument error carrying a `BadRequest`
+// detail with one field violation per failed constraint, so clients can
+// highlight the offending fields.
+package validation
+
+import (
+	"strings"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrInvalidRequest is returned when a request message fails validation.
+	// Field level details are attached as a `BadRequest` detail.
+	ErrInvalidRequest = errors.NewC("validation: invalid request", codes.InvalidArgument)
+
+	// ErrInvalidResponse is returned when response validation is enabled and a
+	// handler returns a message that fails validation.
+	ErrInvalidResponse = errors.NewC("validation: invalid response", codes.Internal)
+)
+
+// Validator validates a proto message, returning an error describing any
+// constraint violations.
+type Validator interface {
+	Validate(msg proto.Message) error
+}
+
+// ValidatorFunc adapts a function to the Validator interface.
+type ValidatorFunc func(msg proto.Message) error
+
+// Validate implements Validator.
+func (f ValidatorFunc) Validate(msg proto.Message) error {
+	return f(msg)
+}
+
+// GeneratedValidator returns a Validator that calls the methods generated by
+// protoc-gen-validate. `ValidateAll` is preferred, since it reports every
+// violation rather than only the first.
+func GeneratedValidator() Validator {
+	return ValidatorFunc(func(msg proto.Message) error {
+		switch m := msg.(type) {
+		case validateAller:
+			return m.ValidateAll()
+		case validator:
+			return m.Validate()
+		}
+		return nil
+	})
+}
+
+// ToError converts an error returned by a Validator into an error with an
+// InvalidArgument code and a field violation per failed constraint. Errors
+// which already carry field violations are returned as is.
+func ToError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var perr *errors.Error
+	if errors.As(err, &perr) && len(perr.FieldViolations()) > 0 {
+		return err
+	}
+
+	verr := errors.Mark(ErrInvalidRequest, 1)
+	messages := []string{}
+	for _, v := range violations("", err) {
+		verr = verr.WithFieldViolation(v.field, v.description)
+		if v.field != "" {
+			messages = append(messages, v.field+": "+v.description)
+		} else {
+			messages = append(messages, v.description)
+		}
+	}
+	return verr.WithUserPresentableMessage("invalid request: %s", strings.Join(messages, "; "))
+}
+
+type violation struct {
+	field       string
+	description string
+}
+
+// violations flattens a validation error into individual field violations.
+// Errors from protoc-gen-validate are structured as a tree, with a multi-error
+// at each message level and per-field errors which may have a cause describing
+// a nested message.
+func violations(prefix string, err error) []violation {
+	if multi, ok := err.(multiError); ok {
+		var out []violation
+		for _, e := range multi.AllErrors() {
+			out = append(out, violations(prefix, e)...)
+		}
+		return out
+	}
+
+	if fe, ok := err.(fieldError); ok {
+		field := joinPath(prefix, fe.Field())
+		if c, ok := err.(causer); ok && c.Cause() != nil {
+			if nested := violations(field, c.Cause()); len(nested) > 0 {
+				return nested
+			}
+		}
+		return []violation{{field: field, description: fe.Reason()}}
+	}
+
+	return []violation{{field: prefix, description: err.Error()}}
+}
+
+func joinPath(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
+
+// Interfaces implemented by code generated by protoc-gen-validate.
+
+type validator interface {
+	Validate() error
+}
+
+type validateAller interface {
+	ValidateAll() error
+}
+
+type multiError interface {
+	AllErrors() []error
+}
+
+type fieldError interface {
+	Field() string
+	Reason() string
+}
+
+type causer interface {
+	Cause() error
+}
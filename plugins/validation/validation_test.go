@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Mimics the error types generated by protoc-gen-validate.
+type fakeFieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e fakeFieldError) Field() string  { return e.field }
+func (e fakeFieldError) Reason() string { return e.reason }
+func (e fakeFieldError) Cause() error   { return e.cause }
+func (e fakeFieldError) Error() string  { return fmt.Sprintf("invalid %s: %s", e.field, e.reason) }
+
+type fakeMultiError []error
+
+func (m fakeMultiError) Error() string      { return fmt.Sprintf("%d errors", len(m)) }
+func (m fakeMultiError) AllErrors() []error { return m }
+
+type validatingMsg struct {
+	*emptypb.Empty
+	err error
+}
+
+func (m *validatingMsg) ValidateAll() error { return m.err }
+
+func TestToError(t *testing.T) {
+	err := ToError(fakeMultiError{
+		fakeFieldError{field: "name", reason: "value length must be at least 1 runes"},
+		fakeFieldError{field: "address", reason: "embedded message failed validation", cause: fakeMultiError{
+			fakeFieldError{field: "zip", reason: "value does not match regex pattern"},
+		}},
+	})
+
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+	assert.True(t, errors.Is(err, ErrInvalidRequest))
+
+	var perr *errors.Error
+	require.True(t, errors.As(err, &perr))
+	violations := perr.FieldViolations()
+	require.Len(t, violations, 2)
+	assert.Equal(t, "name", violations[0].Field)
+	assert.Equal(t, "value length must be at least 1 runes", violations[0].Description)
+	assert.Equal(t, "address.zip", violations[1].Field)
+	assert.Contains(t, perr.UserPresentableMessage(), "address.zip: value does not match regex pattern")
+}
+
+func TestToError_Unstructured(t *testing.T) {
+	err := ToError(fmt.Errorf("something is wrong"))
+
+	var perr *errors.Error
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, codes.InvalidArgument, perr.Code())
+	assert.Equal(t, "something is wrong", perr.FieldViolations()[0].Description)
+}
+
+func TestToError_PassesThroughFieldViolations(t *testing.T) {
+	original := errors.NewC("bad", codes.InvalidArgument).WithFieldViolation("id", "required")
+	assert.Same(t, original, ToError(original))
+	assert.NoError(t, ToError(nil))
+}
+
+func TestInterceptor_Request(t *testing.T) {
+	p := Plugin()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return &emptypb.Empty{}, nil
+	}
+
+	req := &validatingMsg{Empty: &emptypb.Empty{}, err: fakeFieldError{field: "name", reason: "required"}}
+	_, err := p.interceptor(t.Context(), req, info, handler)
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+	assert.False(t, called, "handler should not be called for invalid requests")
+
+	req.err = nil
+	_, err = p.interceptor(t.Context(), req, info, handler)
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestInterceptor_Response(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &validatingMsg{Empty: &emptypb.Empty{}, err: fakeFieldError{field: "id", reason: "required"}}, nil
+	}
+
+	resp, err := Plugin(WithResponseValidation(false)).interceptor(t.Context(), &emptypb.Empty{}, info, handler)
+	require.NoError(t, err)
+	assert.NotNil(t, resp)
+
+	ctx := logging.EnsureLogger(t.Context())
+	resp, err = Plugin(WithResponseValidation(true)).interceptor(ctx, &emptypb.Empty{}, info, handler)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, errors.Code(err))
+}
+
+func TestWithValidator(t *testing.T) {
+	p := Plugin(WithValidator(ValidatorFunc(func(msg proto.Message) error {
+		return errors.NewC("custom", codes.InvalidArgument).WithFieldViolation("custom", "nope")
+	})))
+	_, err := p.interceptor(t.Context(), &emptypb.Empty{}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	var perr *errors.Error
+	require.True(t, errors.As(err, &perr))
+	assert.Equal(t, "custom", perr.FieldViolations()[0].Field)
+}
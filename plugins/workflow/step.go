@@ -0,0 +1,160 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/resilience"
+	"google.golang.org/grpc/codes"
+)
+
+// Definition is implemented by Workflow, so that workflows with different state
+// types can be registered with WithWorkflow.
+type Definition interface {
+	// Name identifies the workflow in stored runs.
+	Name() string
+
+	definition() []step
+}
+
+// Workflow is a sequence of steps which share a state of type T. The state is
+// stored as JSON after each step, so it should contain everything later steps
+// and compensations need.
+type Workflow[T any] struct {
+	name  string
+	steps []step
+}
+
+// Step is one step of a workflow.
+type Step[T any] struct {
+	// Name identifies the step in logs.
+	Name string
+
+	// Do performs the step. Changes to the state are stored even if Do fails.
+	// A step runs again if the server stops before its completion is stored, so
+	// steps should be idempotent.
+	Do func(ctx context.Context, state *T) error
+
+	// Compensate undoes the step when a later step fails. Optional.
+	Compensate func(ctx context.Context, state *T) error
+
+	// Retry controls how failures of Do and Compensate are retried. Backoffs
+	// are stored with the run, so retries continue after a restart. Zero fields
+	// use the defaults of resilience.RetryPolicy.
+	Retry resilience.RetryPolicy
+
+	delay   time.Duration
+	signal  string
+	timeout time.Duration
+}
+
+// Define returns a workflow which runs the steps in order. If a step fails,
+// after retries, the steps before it are compensated in reverse order.
+//
+// Example:
+//
+//	var signup = workflow.Define("signup",
+//		workflow.Step[Signup]{Name: "create-account", Do: createAccount, Compensate: deleteAccount},
+//		workflow.Step[Signup]{Name: "send-verification", Do: sendVerification},
+//		workflow.AwaitSignal[Signup]("await-verification", "verified", 24*time.Hour),
+//		workflow.Step[Signup]{Name: "provision", Do: provision},
+//	)
+func Define[T any](name string, steps ...Step[T]) *Workflow[T] {
+	w := &Workflow[T]{name: name}
+	for _, s := range steps {
+		w.steps = append(w.steps, s.step())
+	}
+	return w
+}
+
+// Delay returns a step which pauses the workflow. The wake-up time is stored,
+// so the delay survives restarts.
+func Delay[T any](name string, d time.Duration) Step[T] {
+	return Step[T]{Name: name, delay: d}
+}
+
+// AwaitSignal returns a step which pauses the workflow until the signal is sent
+// with WorkflowPlugin.Signal, for example when a user follows a verification
+// link. If the signal isn't received within the timeout the step fails with
+// ErrSignalTimeout, and earlier steps are compensated. Zero waits forever.
+// Signals sent before the step is reached are kept until it runs.
+func AwaitSignal[T any](name, signal string, timeout time.Duration) Step[T] {
+	return Step[T]{Name: name, signal: signal, timeout: timeout}
+}
+
+// Name returns the workflow's name.
+func (w *Workflow[T]) Name() string {
+	return w.name
+}
+
+// State decodes the state of a run of this workflow.
+func (w *Workflow[T]) State(r *Run) (T, error) {
+	var state T
+	if r.Workflow != w.name {
+		return state, errors.Codef(codes.InvalidArgument, "workflow: run %s is of workflow %q, not %q", r.ID, r.Workflow, w.name)
+	}
+	err := decode(r, &state)
+	return state, err
+}
+
+func (w *Workflow[T]) definition() []step {
+	return w.steps
+}
+
+// step is a Step without its state type.
+type step struct {
+	name       string
+	do         func(context.Context, *Run) error
+	compensate func(context.Context, *Run) error
+	retry      resilience.RetryPolicy
+	delay      time.Duration
+	signal     string
+	timeout    time.Duration
+}
+
+func (s Step[T]) step() step {
+	out := step{
+		name:    s.Name,
+		retry:   s.Retry,
+		delay:   s.delay,
+		signal:  s.signal,
+		timeout: s.timeout,
+	}
+	if s.Do != nil {
+		out.do = withState(s.Do)
+	}
+	if s.Compensate != nil {
+		out.compensate = withState(s.Compensate)
+	}
+	return out
+}
+
+// withState adapts fn to decode the run's state before it is called, and
+// encode it afterwards.
+func withState[T any](fn func(context.Context, *T) error) func(context.Context, *Run) error {
+	return func(ctx context.Context, r *Run) error {
+		var state T
+		if err := decode(r, &state); err != nil {
+			return err
+		}
+		err := fn(ctx, &state)
+		b, merr := json.Marshal(state)
+		if merr != nil {
+			return errors.Join(err, errors.Codef(codes.InvalidArgument, "workflow: encoding state: %v", merr))
+		}
+		r.State = b
+		return err
+	}
+}
+
+func decode(r *Run, state any) error {
+	if len(r.State) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(r.State, state); err != nil {
+		return errors.Codef(codes.InvalidArgument, "workflow: decoding state: %v", err)
+	}
+	return nil
+}
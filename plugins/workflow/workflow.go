@@ -0,0 +1,713 @@
+// Package workflow runs multi-step business processes, or sagas, such as a
+// signup flow which creates an account, waits for email verification, and then
+// provisions resources with an external API.
+//
+// Each run of a workflow is stored via the storage plugin, along with its
+// state, after every step. Runs resume where they left off after a restart,
+// failed steps are retried with backoff, and if a step fails for good the
+// completed steps are compensated in reverse order. Delays and signal timeouts
+// are stored as wake-up times, so they also survive restarts.
+//
+// Runs are resumed by a poller, which runs on a single replica when the lock
+// plugin is registered. When the workqueue plugin is registered, runs are
+// executed by queue workers, spreading them across replicas.
+//
+// Example:
+//
+//	prefab.WithPlugin(workflow.Plugin(workflow.WithWorkflow(signup)))
+//
+//	// In a handler:
+//	id, err := workflow.Start(ctx, workflow.FromContext(ctx), signup, Signup{Email: req.Email})
+//
+//	// When the user follows the verification link:
+//	err := workflow.FromContext(ctx).Signal(ctx, id, "verified")
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/lock"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/workqueue"
+	"github.com/dpup/prefab/resilience"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	prefab.RegisterConfigKeys(
+		prefab.ConfigKeyInfo{
+			Key:         "workflow.pollInterval",
+			Description: "How often runs which are due or were interrupted are resumed, 0 disables",
+			Type:        "duration",
+			Default:     "5s",
+		},
+		prefab.ConfigKeyInfo{
+			Key:         "workflow.leaseDuration",
+			Description: "How long a run is claimed by a replica while it executes steps, after which it is considered interrupted",
+			Type:        "duration",
+			Default:     "1m",
+		},
+	)
+}
+
+const (
+	// PluginName identifies this plugin.
+	PluginName = "workflow"
+
+	// QueueName is the workqueue queue which runs are executed from.
+	QueueName = "workflow.runs"
+
+	// pollLock names the lock which elects the replica that resumes runs.
+	pollLock = "workflow.poll"
+
+	defaultPollInterval  = 5 * time.Second
+	defaultLeaseDuration = time.Minute
+
+	// Matches the default of resilience.RetryPolicy.
+	defaultMaxAttempts = 3
+)
+
+var (
+	// Returned when starting a run without the workflow plugin.
+	ErrPluginRequired = errors.NewC("workflow: workflow plugin is required", codes.FailedPrecondition)
+
+	// Returned when starting a run of a workflow which wasn't registered.
+	ErrUnknownWorkflow = errors.NewC("workflow: unknown workflow", codes.InvalidArgument)
+
+	// Returned when signalling a run which has finished.
+	ErrFinished = errors.NewC("workflow: run has finished", codes.FailedPrecondition)
+
+	// Fails AwaitSignal steps when the signal isn't received in time.
+	ErrSignalTimeout = errors.NewC("workflow: timed out waiting for signal", codes.DeadlineExceeded)
+
+	// Returned by steps which pause the run.
+	errWaiting = errors.New("workflow: waiting")
+)
+
+// Status of a run.
+type Status string
+
+const (
+	// Steps are being run, or the run is waiting for a delay, signal, or retry.
+	StatusRunning Status = "running"
+
+	// A step failed and earlier steps are being compensated.
+	StatusCompensating Status = "compensating"
+
+	// All steps completed.
+	StatusCompleted Status = "completed"
+
+	// A step failed and earlier steps were compensated.
+	StatusFailed Status = "failed"
+
+	// A compensation failed, so the run needs manual attention.
+	StatusCompensationFailed Status = "compensation_failed"
+)
+
+// Run is a stored run of a workflow.
+type Run struct {
+	ID       string          `json:"id"`
+	Workflow string          `json:"workflow"`
+	Status   Status          `json:"status"`
+	State    json.RawMessage `json:"state"`
+
+	// Index of the step being run, or compensated.
+	Step int `json:"step"`
+
+	// Attempt of the current step, starting at 1.
+	Attempt int `json:"attempt"`
+
+	// Last error returned by a step.
+	Error string `json:"error,omitempty"`
+
+	// When the run should continue. Zero means immediately, unless the run is
+	// waiting for a signal.
+	WakeAt time.Time `json:"wakeAt"`
+
+	// What the current step is waiting for, e.g. "delay" or "signal:verified".
+	WaitingFor string `json:"waitingFor,omitempty"`
+
+	// When the replica executing the run will be considered interrupted.
+	LeaseUntil time.Time `json:"leaseUntil"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// PK implements storage.Model.
+func (r Run) PK() string {
+	return r.ID
+}
+
+// Name implements storage.Namer.
+func (r Run) Name() string {
+	return "workflow_runs"
+}
+
+// Finished reports whether the run has completed or failed.
+func (r *Run) Finished() bool {
+	return r.Status != StatusRunning && r.Status != StatusCompensating
+}
+
+// due reports whether the run should continue now.
+func (r *Run) due(now time.Time) bool {
+	if r.Finished() || (r.WaitingFor != "" && r.WakeAt.IsZero()) {
+		return false
+	}
+	return !now.Before(r.WakeAt)
+}
+
+const waitingForDelay = "delay"
+
+func waitingForSignal(signal string) string {
+	return "signal:" + signal
+}
+
+// runSignal records a signal sent to a run, until an AwaitSignal step consumes
+// it. Signals are stored separately from runs so that sending one doesn't race
+// with the run's updates.
+type runSignal struct {
+	ID        string    `json:"id"`
+	RunID     string    `json:"runId"`
+	Signal    string    `json:"signal"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// PK implements storage.Model.
+func (s runSignal) PK() string {
+	return s.ID
+}
+
+// Name implements storage.Namer.
+func (s runSignal) Name() string {
+	return "workflow_signals"
+}
+
+func signalID(runID, signal string) string {
+	return runID + ":" + signal
+}
+
+// WorkflowOption configures the workflow plugin.
+type WorkflowOption func(*WorkflowPlugin)
+
+// WithWorkflow registers a workflow, so that it can be started and its runs
+// resumed.
+func WithWorkflow(d Definition) WorkflowOption {
+	return func(p *WorkflowPlugin) {
+		p.workflows[d.Name()] = d.definition()
+	}
+}
+
+// WithPollInterval sets how often runs which are due, or were interrupted, are
+// resumed. Zero disables the poller, in which case Resume should be called by
+// the application.
+func WithPollInterval(d time.Duration) WorkflowOption {
+	return func(p *WorkflowPlugin) {
+		p.pollInterval = d
+	}
+}
+
+// WithLeaseDuration sets how long a run is claimed by a replica while it
+// executes steps. Runs whose lease expires are resumed by the poller, so this
+// should be longer than the slowest step.
+func WithLeaseDuration(d time.Duration) WorkflowOption {
+	return func(p *WorkflowPlugin) {
+		p.leaseDuration = d
+	}
+}
+
+// Plugin returns a new WorkflowPlugin.
+func Plugin(opts ...WorkflowOption) *WorkflowPlugin {
+	p := &WorkflowPlugin{
+		workflows:     map[string][]step{},
+		pollInterval:  durationFromConfig("workflow.pollInterval", defaultPollInterval),
+		leaseDuration: durationFromConfig("workflow.leaseDuration", defaultLeaseDuration),
+		now:           time.Now,
+		active:        map[string]bool{},
+		stop:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// WorkflowPlugin stores and executes runs of workflows.
+type WorkflowPlugin struct {
+	workflows     map[string][]step
+	pollInterval  time.Duration
+	leaseDuration time.Duration
+
+	ctx   context.Context // Steps run with the server's context.
+	store storage.Store
+	queue workqueue.WorkQueue
+	now   func() time.Time
+
+	mu     sync.Mutex
+	active map[string]bool // Runs being executed by this process.
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// From prefab.Plugin.
+func (p *WorkflowPlugin) Name() string {
+	return PluginName
+}
+
+// From prefab.DependentPlugin.
+func (p *WorkflowPlugin) Deps() []string {
+	return []string{storage.PluginName}
+}
+
+// From prefab.OptionalDependentPlugin.
+func (p *WorkflowPlugin) OptDeps() []string {
+	return []string{workqueue.PluginName, lock.PluginName}
+}
+
+// From prefab.OptionProvider.
+func (p *WorkflowPlugin) ServerOptions() []prefab.ServerOption {
+	return []prefab.ServerOption{
+		prefab.WithRequestConfig(p.inject),
+	}
+}
+
+// From prefab.InitializablePlugin.
+func (p *WorkflowPlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	sp, ok := r.Get(storage.PluginName).(*storage.StoragePlugin)
+	if !ok {
+		return errors.New("workflow: storage plugin is required")
+	}
+	if err := sp.InitModel(Run{}); err != nil {
+		return err
+	}
+	if err := sp.InitModel(runSignal{}); err != nil {
+		return err
+	}
+	p.store = sp
+	p.ctx = ctx
+
+	if wq, ok := r.Get(workqueue.PluginName).(*workqueue.WorkQueuePlugin); ok {
+		p.queue = wq
+		wq.Subscribe(QueueName, func(ctx context.Context, t *workqueue.Task) error {
+			if id, ok := t.Data.(string); ok {
+				p.execute(ctx, id)
+			}
+			return nil
+		})
+	}
+
+	if p.pollInterval > 0 {
+		locker, _ := r.Get(lock.PluginName).(*lock.LockPlugin)
+		p.wg.Add(1)
+		go p.runPoller(ctx, locker)
+	}
+	return nil
+}
+
+// From prefab.ShutdownPlugin.
+func (p *WorkflowPlugin) Shutdown(context.Context) error {
+	p.stopOnce.Do(func() { close(p.stop) })
+	p.wg.Wait()
+	return nil
+}
+
+// Start begins a run of the workflow with the initial state, returning the
+// run's ID. Steps are executed in the background.
+func Start[T any](ctx context.Context, p *WorkflowPlugin, w *Workflow[T], state T) (string, error) {
+	if p == nil {
+		return "", errors.Mark(ErrPluginRequired, 0)
+	}
+	if _, ok := p.workflows[w.name]; !ok {
+		return "", errors.Mark(ErrUnknownWorkflow, 0)
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return "", errors.Codef(codes.InvalidArgument, "workflow: encoding state: %v", err)
+	}
+	now := p.now()
+	r := &Run{
+		ID:        uuid.NewString(),
+		Workflow:  w.name,
+		Status:    StatusRunning,
+		State:     b,
+		Attempt:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.store.Create(ctx, r); err != nil {
+		return "", err
+	}
+	logging.Infow(ctx, "workflow: started run", "workflow", w.name, "run_id", r.ID)
+	p.dispatch(r.ID)
+	return r.ID, nil
+}
+
+// Get returns a run.
+func (p *WorkflowPlugin) Get(ctx context.Context, id string) (*Run, error) {
+	r := &Run{}
+	if err := p.store.Read(ctx, id, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Signal sends a signal to a run, which continues an AwaitSignal step waiting
+// for it. Signals sent before the step is reached are kept until it runs.
+func (p *WorkflowPlugin) Signal(ctx context.Context, runID, signal string) error {
+	r, err := p.Get(ctx, runID)
+	if err != nil {
+		return err
+	}
+	if r.Finished() {
+		return errors.Mark(ErrFinished, 0)
+	}
+	if err := p.store.Upsert(ctx, runSignal{
+		ID:        signalID(runID, signal),
+		RunID:     runID,
+		Signal:    signal,
+		CreatedAt: p.now(),
+	}); err != nil {
+		return err
+	}
+	p.dispatch(runID)
+	return nil
+}
+
+// Resume executes runs which are due, including runs interrupted by a restart
+// and runs which have been sent the signal they are waiting for. It is called
+// by the poller.
+func (p *WorkflowPlugin) Resume(ctx context.Context) error {
+	now := p.now()
+	for _, status := range []Status{StatusRunning, StatusCompensating} {
+		var runs []Run
+		if err := p.store.List(ctx, &runs, Run{Status: status}); err != nil {
+			return err
+		}
+		for _, r := range runs {
+			if r.LeaseUntil.After(now) {
+				continue
+			}
+			if r.due(now) || p.signalled(ctx, &r) {
+				p.dispatch(r.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// dispatch executes a run, via the workqueue if there is one.
+func (p *WorkflowPlugin) dispatch(id string) {
+	if p.queue != nil {
+		p.queue.Enqueue(QueueName, id)
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.execute(p.ctx, id)
+	}()
+}
+
+// execute runs steps until the run finishes, or has to wait.
+func (p *WorkflowPlugin) execute(ctx context.Context, id string) {
+	p.mu.Lock()
+	if p.active[id] {
+		p.mu.Unlock()
+		return
+	}
+	p.active[id] = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.active, id)
+		p.mu.Unlock()
+	}()
+
+	if err := p.advance(ctx, id); err != nil {
+		logging.Errorw(ctx, "workflow: failed to execute run", "run_id", id, "error", err)
+	}
+}
+
+func (p *WorkflowPlugin) advance(ctx context.Context, id string) error {
+	r, err := p.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	steps, ok := p.workflows[r.Workflow]
+	if !ok {
+		// Possibly registered by a newer version on another replica.
+		logging.Warnw(ctx, "workflow: skipping run of unknown workflow", "workflow", r.Workflow, "run_id", id)
+		return nil
+	}
+	now := p.now()
+	if r.LeaseUntil.After(now) || !(r.due(now) || p.signalled(ctx, r)) {
+		return nil
+	}
+
+	// Claim the run, so the poller doesn't resume it elsewhere.
+	r.LeaseUntil = now.Add(p.leaseDuration)
+	if err := p.save(ctx, r, now); err != nil {
+		return err
+	}
+	for {
+		p.step(ctx, r, steps, now)
+		now = p.now()
+		more := r.due(now) && !p.stopping()
+		if more {
+			r.LeaseUntil = now.Add(p.leaseDuration)
+		} else {
+			r.LeaseUntil = time.Time{}
+		}
+		if err := p.save(ctx, r, now); err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+	if r.Finished() {
+		return p.finish(ctx, r)
+	}
+	return nil
+}
+
+// step runs or compensates the current step, and updates the run accordingly.
+func (p *WorkflowPlugin) step(ctx context.Context, r *Run, steps []step, now time.Time) {
+	if r.Status == StatusCompensating {
+		p.compensate(ctx, r, steps, now)
+		return
+	}
+
+	s := steps[r.Step]
+	var err error
+	switch {
+	case s.delay > 0:
+		err = p.delay(r, s, now)
+	case s.signal != "":
+		err = p.awaitSignal(ctx, r, s, now)
+	case s.do != nil:
+		err = s.do(ctx, r)
+	}
+
+	switch {
+	case err == nil:
+		logging.Debugw(ctx, "workflow: step completed", "run_id", r.ID, "step", s.name)
+		r.Error = ""
+		r.Step++
+		r.Attempt = 1
+		r.WakeAt = time.Time{}
+		if r.Step == len(steps) {
+			r.Status = StatusCompleted
+		}
+	case errors.Is(err, errWaiting):
+	case p.retry(r, s, err, now):
+		logging.Warnw(ctx, "workflow: step failed, retrying", "run_id", r.ID, "step", s.name, "attempt", r.Attempt-1, "error", err)
+	default:
+		logging.Errorw(ctx, "workflow: step failed, compensating", "run_id", r.ID, "step", s.name, "error", err)
+		r.Status = StatusCompensating
+		r.Error = err.Error()
+		r.Step--
+		r.Attempt = 1
+		r.WakeAt = time.Time{}
+		r.WaitingFor = ""
+	}
+}
+
+// compensate undoes the current step, working back from the step before the
+// one which failed.
+func (p *WorkflowPlugin) compensate(ctx context.Context, r *Run, steps []step, now time.Time) {
+	for r.Step >= 0 && steps[r.Step].compensate == nil {
+		r.Step--
+	}
+	if r.Step < 0 {
+		r.Status = StatusFailed
+		return
+	}
+
+	s := steps[r.Step]
+	err := s.compensate(ctx, r)
+	switch {
+	case err == nil:
+		logging.Debugw(ctx, "workflow: step compensated", "run_id", r.ID, "step", s.name)
+		r.Step--
+		r.Attempt = 1
+		r.WakeAt = time.Time{}
+	case p.retry(r, s, err, now):
+		logging.Warnw(ctx, "workflow: compensation failed, retrying", "run_id", r.ID, "step", s.name, "attempt", r.Attempt-1, "error", err)
+	default:
+		logging.Errorw(ctx, "workflow: compensation failed", "run_id", r.ID, "step", s.name, "error", err)
+		r.Status = StatusCompensationFailed
+		r.Error = "compensating " + s.name + ": " + err.Error()
+	}
+}
+
+// retry schedules another attempt of the step, if the error is retryable and
+// the attempts aren't used up.
+func (p *WorkflowPlugin) retry(r *Run, s step, err error, now time.Time) bool {
+	maxAttempts := s.retry.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	retryable := s.retry.Retryable
+	if retryable == nil {
+		retryable = resilience.IsFailure
+	}
+	if r.Attempt >= maxAttempts || errors.Is(err, ErrSignalTimeout) || !retryable(err) {
+		return false
+	}
+	r.WakeAt = now.Add(s.retry.Backoff(r.Attempt))
+	r.Attempt++
+	r.Error = err.Error()
+	return true
+}
+
+func (p *WorkflowPlugin) delay(r *Run, s step, now time.Time) error {
+	if r.WaitingFor == waitingForDelay {
+		// The run is due, so the delay has passed.
+		r.WaitingFor = ""
+		return nil
+	}
+	r.WaitingFor = waitingForDelay
+	r.WakeAt = now.Add(s.delay)
+	return errWaiting
+}
+
+func (p *WorkflowPlugin) awaitSignal(ctx context.Context, r *Run, s step, now time.Time) error {
+	id := signalID(r.ID, s.signal)
+	ok, err := p.store.Exists(ctx, id, runSignal{})
+	if err != nil {
+		return err
+	}
+	if ok {
+		r.WaitingFor = ""
+		return p.store.Delete(ctx, runSignal{ID: id})
+	}
+
+	waitingFor := waitingForSignal(s.signal)
+	if r.WaitingFor == waitingFor {
+		if !r.WakeAt.IsZero() && !now.Before(r.WakeAt) {
+			r.WaitingFor = ""
+			return errors.Mark(ErrSignalTimeout, 0)
+		}
+		return errWaiting
+	}
+	r.WaitingFor = waitingFor
+	r.WakeAt = time.Time{}
+	if s.timeout > 0 {
+		r.WakeAt = now.Add(s.timeout)
+	}
+	return errWaiting
+}
+
+// signalled reports whether a run is waiting for a signal which has been sent.
+func (p *WorkflowPlugin) signalled(ctx context.Context, r *Run) bool {
+	if r.Finished() || r.WaitingFor == "" || r.WaitingFor == waitingForDelay {
+		return false
+	}
+	s := r.WaitingFor[len(waitingForSignal("")):]
+	ok, err := p.store.Exists(ctx, signalID(r.ID, s), runSignal{})
+	if err != nil {
+		logging.Errorw(ctx, "workflow: failed to check for signal", "run_id", r.ID, "error", err)
+	}
+	return ok
+}
+
+func (p *WorkflowPlugin) save(ctx context.Context, r *Run, now time.Time) error {
+	r.UpdatedAt = now
+	return p.store.Update(ctx, r)
+}
+
+// finish logs the outcome of a run and deletes signals it didn't consume.
+func (p *WorkflowPlugin) finish(ctx context.Context, r *Run) error {
+	if r.Status == StatusCompleted {
+		logging.Infow(ctx, "workflow: run completed", "workflow", r.Workflow, "run_id", r.ID)
+	} else {
+		logging.Warnw(ctx, "workflow: run failed", "workflow", r.Workflow, "run_id", r.ID, "status", r.Status, "error", r.Error)
+	}
+	var signals []runSignal
+	if err := p.store.List(ctx, &signals, runSignal{RunID: r.ID}); err != nil {
+		return err
+	}
+	for _, s := range signals {
+		if err := p.store.Delete(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *WorkflowPlugin) stopping() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// runPoller resumes runs on an interval until the plugin is shut down. If a
+// locker is provided, only the replica holding the poll lock resumes runs.
+func (p *WorkflowPlugin) runPoller(ctx context.Context, locker *lock.LockPlugin) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if locker == nil {
+		p.resumeOnInterval(ctx)
+		return
+	}
+	_ = locker.RunWhenLeader(ctx, pollLock, func(ctx context.Context) error {
+		p.resumeOnInterval(ctx)
+		return nil
+	})
+}
+
+func (p *WorkflowPlugin) resumeOnInterval(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Resume(ctx); err != nil {
+				logging.Errorw(ctx, "workflow: failed to resume runs", "error", err)
+			}
+		}
+	}
+}
+
+func (p *WorkflowPlugin) inject(ctx context.Context) context.Context {
+	return context.WithValue(ctx, workflowKey{}, p)
+}
+
+// FromContext returns the workflow plugin from a request context, or nil.
+func FromContext(ctx context.Context) *WorkflowPlugin {
+	p, _ := ctx.Value(workflowKey{}).(*WorkflowPlugin)
+	return p
+}
+
+type workflowKey struct{}
+
+func durationFromConfig(key string, def time.Duration) time.Duration {
+	if !prefab.ConfigExists(key) {
+		return def
+	}
+	return prefab.ConfigDuration(key)
+}
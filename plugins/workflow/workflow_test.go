@@ -0,0 +1,373 @@
+package workflow
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"github.com/dpup/prefab/plugins/workqueue"
+	"github.com/dpup/prefab/plugins/workqueue/memqueue"
+	"github.com/dpup/prefab/prefabtest"
+	"github.com/dpup/prefab/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type signup struct {
+	Email   string
+	Account string
+	Steps   []string
+}
+
+// recorder records the steps and compensations which ran.
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (rec *recorder) step(name string, err error) func(context.Context, *signup) error {
+	return func(_ context.Context, s *signup) error {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.calls = append(rec.calls, name)
+		s.Steps = append(s.Steps, name)
+		return err
+	}
+}
+
+func (rec *recorder) Calls() []string {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]string(nil), rec.calls...)
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func setup(t *testing.T, extra []prefab.Plugin, workflows ...Definition) (*WorkflowPlugin, *clock) {
+	t.Helper()
+	opts := []WorkflowOption{WithPollInterval(0)}
+	for _, w := range workflows {
+		opts = append(opts, WithWorkflow(w))
+	}
+	p := Plugin(opts...)
+	c := &clock{now: time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)}
+	p.now = c.Now
+
+	prefabtest.Init(t, append([]prefab.Plugin{p, storage.Plugin(memstore.New())}, extra...)...)
+	return p, c
+}
+
+// resume resumes due runs and waits for them to stop.
+func resume(t *testing.T, p *WorkflowPlugin) {
+	t.Helper()
+	require.NoError(t, p.Resume(prefabtest.Context(t)))
+	p.wg.Wait()
+}
+
+func TestStart_Completes(t *testing.T) {
+	rec := &recorder{}
+	w := Define("signup",
+		Step[signup]{Name: "create", Do: func(ctx context.Context, s *signup) error {
+			s.Account = "acct-" + s.Email
+			return rec.step("create", nil)(ctx, s)
+		}},
+		Step[signup]{Name: "welcome", Do: rec.step("welcome", nil)},
+	)
+	p, _ := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{Email: "a@example.com"})
+	require.NoError(t, err)
+	p.wg.Wait()
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, r.Status)
+	assert.Equal(t, 2, r.Step)
+	assert.True(t, r.LeaseUntil.IsZero())
+
+	state, err := w.State(r)
+	require.NoError(t, err)
+	assert.Equal(t, signup{Email: "a@example.com", Account: "acct-a@example.com", Steps: []string{"create", "welcome"}}, state)
+	assert.Equal(t, []string{"create", "welcome"}, rec.Calls())
+}
+
+func TestStart_Errors(t *testing.T) {
+	w := Define[signup]("signup")
+	ctx := prefabtest.Context(t)
+
+	_, err := Start(ctx, nil, w, signup{})
+	require.ErrorIs(t, err, ErrPluginRequired)
+
+	p, _ := setup(t, nil)
+	_, err = Start(ctx, p, w, signup{})
+	require.ErrorIs(t, err, ErrUnknownWorkflow)
+}
+
+func TestRetry(t *testing.T) {
+	failures := 2
+	w := Define("retry",
+		Step[signup]{
+			Name:  "flaky",
+			Retry: resilience.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Minute, MaxBackoff: time.Hour, Jitter: -1},
+			Do: func(context.Context, *signup) error {
+				if failures > 0 {
+					failures--
+					return errors.New("unavailable")
+				}
+				return nil
+			},
+		},
+	)
+	p, c := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	p.wg.Wait()
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, r.Status)
+	assert.Equal(t, 2, r.Attempt)
+	assert.Equal(t, "unavailable", r.Error)
+	assert.Equal(t, c.Now().Add(time.Minute), r.WakeAt)
+
+	// Not due yet.
+	resume(t, p)
+	r, _ = p.Get(ctx, id)
+	assert.Equal(t, 2, r.Attempt)
+
+	c.Add(time.Minute)
+	resume(t, p)
+	r, _ = p.Get(ctx, id)
+	assert.Equal(t, 3, r.Attempt)
+	assert.Equal(t, c.Now().Add(2*time.Minute), r.WakeAt)
+
+	c.Add(2 * time.Minute)
+	resume(t, p)
+	r, _ = p.Get(ctx, id)
+	assert.Equal(t, StatusCompleted, r.Status)
+	assert.Empty(t, r.Error)
+}
+
+func TestCompensation(t *testing.T) {
+	rec := &recorder{}
+	w := Define("compensate",
+		Step[signup]{Name: "one", Do: rec.step("one", nil), Compensate: rec.step("undo-one", nil)},
+		Step[signup]{Name: "two", Do: rec.step("two", nil)},
+		Step[signup]{Name: "three", Do: rec.step("three", nil), Compensate: rec.step("undo-three", nil)},
+		Step[signup]{Name: "four", Do: rec.step("four", errors.NewC("rejected", codes.InvalidArgument)), Compensate: rec.step("undo-four", nil)},
+	)
+	p, _ := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	p.wg.Wait()
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, r.Status)
+	assert.Equal(t, "rejected", r.Error)
+	assert.Equal(t, []string{"one", "two", "three", "four", "undo-three", "undo-one"}, rec.Calls(),
+		"the failed step isn't compensated, and steps without compensation are skipped")
+
+	state, err := w.State(r)
+	require.NoError(t, err)
+	assert.Equal(t, rec.Calls(), state.Steps, "state changes are kept during compensation")
+}
+
+func TestCompensationFailed(t *testing.T) {
+	rec := &recorder{}
+	w := Define("compensate",
+		Step[signup]{
+			Name:       "one",
+			Do:         rec.step("one", nil),
+			Compensate: rec.step("undo-one", errors.NewC("stuck", codes.FailedPrecondition)),
+		},
+		Step[signup]{Name: "two", Do: rec.step("two", errors.NewC("rejected", codes.InvalidArgument))},
+	)
+	p, _ := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	p.wg.Wait()
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompensationFailed, r.Status)
+	assert.Equal(t, "compensating one: stuck", r.Error)
+	assert.Equal(t, 0, r.Step)
+}
+
+func TestDelay(t *testing.T) {
+	rec := &recorder{}
+	w := Define("delay",
+		Step[signup]{Name: "one", Do: rec.step("one", nil)},
+		Delay[signup]("wait", time.Hour),
+		Step[signup]{Name: "two", Do: rec.step("two", nil)},
+	)
+	p, c := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	p.wg.Wait()
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, 1, r.Step)
+	assert.Equal(t, waitingForDelay, r.WaitingFor)
+	assert.Equal(t, c.Now().Add(time.Hour), r.WakeAt)
+
+	c.Add(59 * time.Minute)
+	resume(t, p)
+	assert.Equal(t, []string{"one"}, rec.Calls())
+
+	c.Add(time.Minute)
+	resume(t, p)
+	assert.Equal(t, []string{"one", "two"}, rec.Calls())
+	r, _ = p.Get(ctx, id)
+	assert.Equal(t, StatusCompleted, r.Status)
+}
+
+func TestAwaitSignal(t *testing.T) {
+	rec := &recorder{}
+	w := Define("signal",
+		Step[signup]{Name: "send", Do: rec.step("send", nil), Compensate: rec.step("undo-send", nil)},
+		AwaitSignal[signup]("verify", "verified", time.Hour),
+		Step[signup]{Name: "provision", Do: rec.step("provision", nil)},
+	)
+	p, c := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	t.Run("received", func(t *testing.T) {
+		id, err := Start(ctx, p, w, signup{})
+		require.NoError(t, err)
+		p.wg.Wait()
+
+		r, err := p.Get(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, waitingForSignal("verified"), r.WaitingFor)
+
+		require.NoError(t, p.Signal(ctx, id, "verified"))
+		p.wg.Wait()
+		r, _ = p.Get(ctx, id)
+		assert.Equal(t, StatusCompleted, r.Status)
+
+		exists, err := p.store.Exists(ctx, signalID(id, "verified"), runSignal{})
+		require.NoError(t, err)
+		assert.False(t, exists, "signal should be consumed")
+
+		require.ErrorIs(t, p.Signal(ctx, id, "verified"), ErrFinished)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		rec.calls = nil
+		id, err := Start(ctx, p, w, signup{})
+		require.NoError(t, err)
+		p.wg.Wait()
+
+		c.Add(time.Hour)
+		resume(t, p)
+		r, _ := p.Get(ctx, id)
+		assert.Equal(t, StatusFailed, r.Status)
+		assert.Equal(t, ErrSignalTimeout.Error(), r.Error)
+		assert.Equal(t, []string{"send", "undo-send"}, rec.Calls())
+	})
+}
+
+func TestAwaitSignal_SentEarly(t *testing.T) {
+	w := Define("signal",
+		Delay[signup]("wait", time.Hour),
+		AwaitSignal[signup]("verify", "verified", 0),
+	)
+	p, c := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	p.wg.Wait()
+	require.NoError(t, p.Signal(ctx, id, "verified"))
+	p.wg.Wait()
+
+	r, _ := p.Get(ctx, id)
+	assert.Equal(t, waitingForDelay, r.WaitingFor)
+
+	c.Add(time.Hour)
+	resume(t, p)
+	r, _ = p.Get(ctx, id)
+	assert.Equal(t, StatusCompleted, r.Status)
+}
+
+func TestResume_Interrupted(t *testing.T) {
+	rec := &recorder{}
+	w := Define("resume",
+		Step[signup]{Name: "one", Do: rec.step("one", nil)},
+		Step[signup]{Name: "two", Do: rec.step("two", nil)},
+	)
+	p, c := setup(t, nil, w)
+	ctx := prefabtest.Context(t)
+
+	// A run which another replica was executing when it stopped.
+	r := &Run{
+		ID:         "interrupted",
+		Workflow:   "resume",
+		Status:     StatusRunning,
+		State:      []byte(`{}`),
+		Step:       1,
+		Attempt:    1,
+		LeaseUntil: c.Now().Add(time.Minute),
+	}
+	require.NoError(t, p.store.Create(ctx, r))
+
+	resume(t, p)
+	assert.Empty(t, rec.Calls(), "leased runs aren't resumed")
+
+	c.Add(time.Minute)
+	resume(t, p)
+	assert.Equal(t, []string{"two"}, rec.Calls())
+	r, _ = p.Get(ctx, "interrupted")
+	assert.Equal(t, StatusCompleted, r.Status)
+}
+
+func TestWorkQueue(t *testing.T) {
+	rec := &recorder{}
+	w := Define("queued", Step[signup]{Name: "one", Do: rec.step("one", nil)})
+	wq := workqueue.Plugin(memqueue.New(logging.EnsureLogger(t.Context())))
+	p, _ := setup(t, []prefab.Plugin{wq}, w)
+	ctx := prefabtest.Context(t)
+
+	id, err := Start(ctx, p, w, signup{})
+	require.NoError(t, err)
+	require.NoError(t, wq.Wait(ctx))
+
+	r, err := p.Get(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, StatusCompleted, r.Status)
+	assert.Equal(t, []string{"one"}, rec.Calls())
+}
@@ -0,0 +1,243 @@
+// Package prefabtest provides a harness for end-to-end tests of prefab
+// servers. The server runs on an in-memory transport, so tests don't need to
+// manage ports and can run in parallel.
+//
+// The harness always registers an in-memory storage plugin and an event bus
+// that records published events, so these shouldn't be registered separately.
+//
+// Example:
+//
+//	func TestNotes(t *testing.T) {
+//	    s := prefabtest.New(t,
+//	        prefabtest.WithAuth(),
+//	        prefabtest.WithFixtures(&Note{ID: "1", Owner: "alice"}),
+//	        prefabtest.WithOptions(prefab.WithGRPCService(&notes.Notes_ServiceDesc, impl)),
+//	    )
+//
+//	    ctx := s.AuthContext(t.Context(), auth.Identity{Subject: "alice"})
+//	    _, err := notes.NewNotesClient(s.Conn()).Delete(ctx, &notes.DeleteRequest{Id: "1"})
+//	    require.NoError(t, err)
+//
+//	    s.Events().AssertPublished(t, "note.deleted")
+//	}
+package prefabtest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/storage"
+	"github.com/dpup/prefab/plugins/storage/memstore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// SigningKey is used to sign identity tokens when auth is enabled with
+// WithAuth.
+const SigningKey = "prefabtest-signing-key"
+
+// BaseURL is the origin that requests made with Server.HTTPClient are sent to.
+const BaseURL = "http://prefabtest"
+
+const bufSize = 1024 * 1024
+
+// Option customizes the test server.
+type Option func(*config)
+
+type config struct {
+	serverOpts []prefab.ServerOption
+	authOpts   []auth.AuthOption
+	withAuth   bool
+	fixtures   []storage.Model
+}
+
+// WithOptions passes server options through to prefab.New.
+func WithOptions(opts ...prefab.ServerOption) Option {
+	return func(c *config) {
+		c.serverOpts = append(c.serverOpts, opts...)
+	}
+}
+
+// WithPlugins registers plugins with the server.
+func WithPlugins(plugins ...prefab.Plugin) Option {
+	return func(c *config) {
+		for _, p := range plugins {
+			c.serverOpts = append(c.serverOpts, prefab.WithPlugin(p))
+		}
+	}
+}
+
+// WithAuth registers the auth plugin, using SigningKey so that tokens for
+// arbitrary identities can be minted with Server.Token.
+func WithAuth(opts ...auth.AuthOption) Option {
+	return func(c *config) {
+		c.withAuth = true
+		c.authOpts = append(c.authOpts, opts...)
+	}
+}
+
+// WithFixtures seeds the in-memory store with models before the server starts.
+func WithFixtures(models ...storage.Model) Option {
+	return func(c *config) {
+		c.fixtures = append(c.fixtures, models...)
+	}
+}
+
+// Server is a running prefab server and clients connected to it.
+type Server struct {
+	*prefab.Server
+
+	t      testing.TB
+	conn   *grpc.ClientConn
+	http   *http.Client
+	store  storage.Store
+	events *Recorder
+	auth   *auth.AuthPlugin
+}
+
+// New starts a server on an in-memory transport. The server is shut down when
+// the test completes.
+func New(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+
+	cfg := &config{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	ctx := logging.EnsureLogger(context.Background())
+	ts := &Server{
+		t:      t,
+		store:  memstore.New(),
+		events: NewRecorder(ctx),
+	}
+	if len(cfg.fixtures) > 0 {
+		if err := ts.store.Create(ctx, cfg.fixtures...); err != nil {
+			t.Fatalf("prefabtest: failed to seed fixtures: %v", err)
+		}
+	}
+
+	lis := bufconn.Listen(bufSize)
+	serverOpts := []prefab.ServerOption{
+		prefab.WithContext(ctx),
+		prefab.WithListener(lis),
+		prefab.WithPlugin(storage.Plugin(ts.store)),
+		prefab.WithPlugin(eventbus.Plugin(ts.events)),
+	}
+	if cfg.withAuth {
+		ts.auth = auth.Plugin(append([]auth.AuthOption{auth.WithSigningKey(SigningKey)}, cfg.authOpts...)...)
+		serverOpts = append(serverOpts, prefab.WithPlugin(ts.auth))
+	}
+	ts.Server = prefab.New(append(serverOpts, cfg.serverOpts...)...)
+
+	errc := make(chan error, 1)
+	go func() { errc <- ts.Start() }()
+
+	dial := func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.DialContext(ctx)
+	}
+	conn, err := grpc.NewClient("passthrough:///"+lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(dial),
+	)
+	if err != nil {
+		t.Fatalf("prefabtest: failed to create client: %v", err)
+	}
+	ts.conn = conn
+	ts.http = &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dial(ctx, addr)
+			},
+		},
+	}
+
+	// Connections are only accepted once plugins have been initialized, so a
+	// successful call means the server is ready.
+	ready := make(chan error, 1)
+	go func() {
+		_, err := prefab.NewMetaServiceClient(conn).ClientConfig(ctx, &prefab.ClientConfigRequest{})
+		ready <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("prefabtest: server failed to start: %v", err)
+	case err := <-ready:
+		if err != nil {
+			t.Fatalf("prefabtest: server not ready: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		ts.http.CloseIdleConnections()
+		if err := ts.Shutdown(); err != nil {
+			t.Errorf("prefabtest: shutdown failed: %v", err)
+		}
+	})
+	return ts
+}
+
+// Conn returns a GRPC client connection to the server.
+func (s *Server) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// HTTPClient returns a HTTP client whose requests are sent to the server,
+// regardless of the host in the URL. See URL.
+func (s *Server) HTTPClient() *http.Client {
+	return s.http
+}
+
+// URL returns the URL for path on the server.
+func (s *Server) URL(path string) string {
+	return BaseURL + "/" + strings.TrimPrefix(path, "/")
+}
+
+// Store returns the in-memory store used by the storage plugin.
+func (s *Server) Store() storage.Store {
+	return s.store
+}
+
+// Events returns the recorder for events published to the event bus.
+func (s *Server) Events() *Recorder {
+	return s.events
+}
+
+// Token mints an identity token that the server will accept. Requires
+// WithAuth.
+func (s *Server) Token(identity auth.Identity) string {
+	s.t.Helper()
+	if s.auth == nil {
+		s.t.Fatal("prefabtest: Token requires WithAuth")
+	}
+	token, err := s.auth.IdentityToken(context.Background(), identity)
+	if err != nil {
+		s.t.Fatalf("prefabtest: failed to mint token: %v", err)
+	}
+	return token
+}
+
+// AuthContext returns an outgoing context that authenticates GRPC calls as
+// identity. Requires WithAuth.
+func (s *Server) AuthContext(ctx context.Context, identity auth.Identity) context.Context {
+	s.t.Helper()
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+s.Token(identity))
+}
+
+// AuthRequest adds an authorization header to r that authenticates it as
+// identity. Requires WithAuth.
+func (s *Server) AuthRequest(r *http.Request, identity auth.Identity) *http.Request {
+	s.t.Helper()
+	r.Header.Set("Authorization", "Bearer "+s.Token(identity))
+	return r
+}
@@ -0,0 +1,116 @@
+package prefabtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/plugins/auth/fakeauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fixture struct {
+	ID   string
+	Name string
+}
+
+func (f *fixture) PK() string { return f.ID }
+
+func TestNew_GRPCWithToken(t *testing.T) {
+	s := New(t, WithAuth())
+	client := auth.NewAuthServiceClient(s.Conn())
+
+	_, err := client.Identity(t.Context(), &auth.IdentityRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := s.AuthContext(t.Context(), auth.Identity{Provider: "test", Subject: "alice", Email: "alice@example.com"})
+	resp, err := client.Identity(ctx, &auth.IdentityRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", resp.GetSubject())
+	assert.Equal(t, "alice@example.com", resp.GetEmail())
+}
+
+func TestNew_HTTPThroughGateway(t *testing.T) {
+	s := New(t, WithAuth())
+
+	req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, s.URL("/api/auth/me"), nil)
+	require.NoError(t, err)
+	s.AuthRequest(req, auth.Identity{Provider: "test", Subject: "bob"})
+
+	resp, err := s.HTTPClient().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "bob", body["subject"])
+}
+
+func TestNew_EventsAreRecorded(t *testing.T) {
+	s := New(t, WithAuth(), WithPlugins(fakeauth.Plugin()))
+
+	s.Events().AssertNotPublished(t, auth.LoginEvent)
+	fakeauth.MustLogin(t.Context(), auth.NewAuthServiceClient(s.Conn()), fakeauth.FakeOptions{ID: "carol"})
+
+	data := s.Events().AssertPublished(t, auth.LoginEvent)
+	event, ok := data.(auth.AuthEvent)
+	require.True(t, ok)
+	assert.Equal(t, "carol", event.Identity.Subject)
+
+	s.Events().Reset()
+	assert.Empty(t, s.Events().Events())
+}
+
+func TestNew_Fixtures(t *testing.T) {
+	s := New(t, WithFixtures(&fixture{ID: "1", Name: "one"}, &fixture{ID: "2", Name: "two"}))
+
+	f := &fixture{}
+	require.NoError(t, s.Store().Read(t.Context(), "2", f))
+	assert.Equal(t, "two", f.Name)
+}
+
+func TestNew_ParallelServers(t *testing.T) {
+	for range 3 {
+		t.Run("server", func(t *testing.T) {
+			t.Parallel()
+			s := New(t, WithAuth())
+			ctx := s.AuthContext(t.Context(), auth.Identity{Provider: "test", Subject: "dave"})
+			_, err := auth.NewAuthServiceClient(s.Conn()).Identity(ctx, &auth.IdentityRequest{})
+			require.NoError(t, err)
+		})
+	}
+}
+
+type lifecyclePlugin struct {
+	initialized, shutdown bool
+}
+
+func (p *lifecyclePlugin) Name() string { return "lifecycle" }
+
+func (p *lifecyclePlugin) Init(ctx context.Context, r *prefab.Registry) error {
+	p.initialized = true
+	return nil
+}
+
+func (p *lifecyclePlugin) Shutdown(ctx context.Context) error {
+	p.shutdown = true
+	return nil
+}
+
+func TestInit(t *testing.T) {
+	p := &lifecyclePlugin{}
+	t.Run("plugins", func(t *testing.T) {
+		r := Init(t, p)
+		assert.True(t, p.initialized)
+		assert.Same(t, p, r.Get("lifecycle"))
+		assert.False(t, p.shutdown)
+	})
+	assert.True(t, p.shutdown, "plugins should be shut down when the test completes")
+}
@@ -0,0 +1,91 @@
+package prefabtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/dpup/prefab/plugins/eventbus"
+	"github.com/dpup/prefab/plugins/eventbus/membus"
+)
+
+// Event is a message published to the event bus.
+type Event struct {
+	Topic string
+	Data  any
+}
+
+// Recorder is an in-memory event bus that records published events, so that
+// tests can assert on them. Events are still delivered to subscribers.
+type Recorder struct {
+	eventbus.EventBus
+
+	mu     sync.Mutex
+	events []Event
+}
+
+// NewRecorder returns a recording event bus backed by membus.
+func NewRecorder(ctx context.Context) *Recorder {
+	return &Recorder{EventBus: membus.New(ctx)}
+}
+
+// Publish records the event before passing it to the underlying bus.
+func (r *Recorder) Publish(topic string, data any) {
+	r.mu.Lock()
+	r.events = append(r.events, Event{Topic: topic, Data: data})
+	r.mu.Unlock()
+	r.EventBus.Publish(topic, data)
+}
+
+// Events returns all events published so far, in order.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event(nil), r.events...)
+}
+
+// Published returns the data of events published to topic, in order.
+func (r *Recorder) Published(topic string) []any {
+	var data []any
+	for _, e := range r.Events() {
+		if e.Topic == topic {
+			data = append(data, e.Data)
+		}
+	}
+	return data
+}
+
+// Reset forgets all recorded events.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// AssertPublished fails the test if no event was published to topic, and
+// returns the data of the most recent one.
+func (r *Recorder) AssertPublished(t testing.TB, topic string) any {
+	t.Helper()
+	data := r.Published(topic)
+	if len(data) == 0 {
+		t.Errorf("prefabtest: expected event %q to be published", topic)
+		return nil
+	}
+	return data[len(data)-1]
+}
+
+// AssertNotPublished fails the test if an event was published to topic.
+func (r *Recorder) AssertNotPublished(t testing.TB, topic string) {
+	t.Helper()
+	if n := len(r.Published(topic)); n > 0 {
+		t.Errorf("prefabtest: expected event %q not to be published, got %d", topic, n)
+	}
+}
+
+// Shutdown drains the underlying bus, if it supports it.
+func (r *Recorder) Shutdown(ctx context.Context) error {
+	if s, ok := r.EventBus.(eventbus.Shutdownable); ok {
+		return s.Shutdown(ctx)
+	}
+	return r.Wait(ctx)
+}
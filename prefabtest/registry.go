@@ -0,0 +1,43 @@
+package prefabtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab"
+	"github.com/dpup/prefab/logging"
+)
+
+// Context returns a context for calling plugins directly, which logs to the
+// console and is canceled when the test completes.
+func Context(t testing.TB) context.Context {
+	return logging.With(t.Context(), logging.NewDevLogger())
+}
+
+// Init registers plugins with a new registry and initializes them, for unit
+// tests which call plugins directly rather than through a server. Plugins are
+// shut down when the test completes.
+//
+// Example:
+//
+//	p := quota.Plugin(quota.WithLimit(limit))
+//	prefabtest.Init(t, storage.Plugin(memstore.New()), p)
+//	_, err := p.Consume(prefabtest.Context(t), "api_calls", "alice", 1)
+func Init(t testing.TB, plugins ...prefab.Plugin) *prefab.Registry {
+	t.Helper()
+	r := &prefab.Registry{}
+	for _, p := range plugins {
+		r.Register(p)
+	}
+	if err := r.Init(Context(t)); err != nil {
+		t.Fatalf("prefabtest: failed to initialize plugins: %v", err)
+	}
+	t.Cleanup(func() {
+		// The test's context has been canceled by the time cleanup runs.
+		ctx := logging.With(context.Background(), logging.NewDevLogger())
+		if err := r.Shutdown(ctx); err != nil {
+			t.Errorf("prefabtest: shutdown failed: %v", err)
+		}
+	})
+	return r
+}
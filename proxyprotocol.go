@@ -0,0 +1,188 @@
+package prefab
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+)
+
+// WithProxyProtocol sets whether the listener accepts PROXY protocol v1 and v2
+// headers, as sent by HAProxy and AWS Network Load Balancers, so that the
+// client's address is used instead of the load balancer's. Headers are only
+// accepted from trusted proxies, see WithTrustedProxies, and connections
+// without a header are served as is.
+//
+// Config key: `server.proxy.protocol`.
+func WithProxyProtocol(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.proxyProtocol = enabled
+	}
+}
+
+// proxyHeaderTimeout is how long a connection has to send its PROXY header.
+const proxyHeaderTimeout = 5 * time.Second
+
+// proxyV2Signature prefixes PROXY protocol v2 headers.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyListener reads PROXY protocol headers from accepted connections.
+type proxyListener struct {
+	net.Listener
+	trusted serverutil.TrustedProxies
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: c, trusted: l.trusted}, nil
+}
+
+// proxyConn is a connection whose remote address may be replaced by a PROXY
+// header. The header is read on first use, rather than in Accept, so that slow
+// clients don't block other connections.
+type proxyConn struct {
+	net.Conn
+	trusted serverutil.TrustedProxies
+
+	once   sync.Once
+	r      *bufio.Reader
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) readHeader() {
+	c.r = bufio.NewReader(c.Conn)
+	if err := c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout)); err != nil {
+		c.err = err
+		return
+	}
+	defer func() {
+		if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+			c.err = err
+		}
+	}()
+
+	// Only wait for as many bytes as needed to rule out a header, as clients
+	// which don't send one may send very little.
+	first, err := c.r.Peek(1)
+	if err != nil {
+		return
+	}
+	var remote net.Addr
+	switch {
+	case first[0] == proxyV2Signature[0] && c.hasPrefix(proxyV2Signature):
+		remote, err = readProxyV2(c.r, c.Conn.RemoteAddr())
+	case first[0] == 'P' && c.hasPrefix([]byte("PROXY ")):
+		remote, err = readProxyV1(c.r, c.Conn.RemoteAddr())
+	default:
+		return // No header.
+	}
+	if err != nil {
+		c.err = err
+		return
+	}
+	if host, _, _ := net.SplitHostPort(c.Conn.RemoteAddr().String()); !c.trusted.Trusts(host) {
+		c.err = errors.Errorf("prefab: PROXY header from untrusted address %s", c.Conn.RemoteAddr())
+		return
+	}
+	c.remote = remote
+}
+
+func (c *proxyConn) hasPrefix(prefix []byte) bool {
+	b, _ := c.r.Peek(len(prefix))
+	return bytes.Equal(b, prefix)
+}
+
+// readProxyV1 reads a human-readable header, e.g.
+// "PROXY TCP4 203.0.113.7 10.0.0.1 56324 443\r\n". Returns addr if the header
+// doesn't carry an address.
+func readProxyV1(r *bufio.Reader, addr net.Addr) (net.Addr, error) {
+	// Headers are at most 107 bytes, including the CRLF.
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, errors.WrapPrefix(err, "prefab: reading PROXY header", 0)
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("prefab: PROXY header too long")
+	}
+	fields := strings.Fields(string(line))
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return addr, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.Errorf("prefab: invalid PROXY header %q", strings.TrimSpace(string(line)))
+	}
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if ip == nil || err != nil {
+		return nil, errors.Errorf("prefab: invalid PROXY header %q", strings.TrimSpace(string(line)))
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readProxyV2 reads a binary header. Returns addr for LOCAL connections, such
+// as health checks, and address families other than TCP.
+func readProxyV2(r *bufio.Reader, addr net.Addr) (net.Addr, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, errors.WrapPrefix(err, "prefab: reading PROXY header", 0)
+	}
+	if hdr[12]>>4 != 2 {
+		return nil, errors.Errorf("prefab: unsupported PROXY version %d", hdr[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(hdr[14:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, errors.WrapPrefix(err, "prefab: reading PROXY header", 0)
+	}
+	if hdr[12]&0xf == 0 { // LOCAL
+		return addr, nil
+	}
+	switch hdr[13] {
+	case 0x11: // TCP over IPv4
+		if len(body) < 12 {
+			return nil, errors.New("prefab: short PROXY header")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:]))}, nil
+	case 0x21: // TCP over IPv6
+		if len(body) < 36 {
+			return nil, errors.New("prefab: short PROXY header")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:]))}, nil
+	default:
+		return addr, nil
+	}
+}
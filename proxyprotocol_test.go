@@ -0,0 +1,110 @@
+package prefab
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/dpup/prefab/serverutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addrConn overrides the remote address of a connection.
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c addrConn) RemoteAddr() net.Addr { return c.remote }
+
+// proxyConnFor returns a proxyConn reading what's written to the other end of a
+// pipe, from the given address.
+func proxyConnFor(t *testing.T, from string, data []byte) *proxyConn {
+	t.Helper()
+	client, server := net.Pipe()
+	t.Cleanup(func() { client.Close(); server.Close() })
+	go func() {
+		_, _ = client.Write(data)
+		client.Close()
+	}()
+	trusted, err := serverutil.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	remote := &net.TCPAddr{IP: net.ParseIP(from), Port: 5000}
+	return &proxyConn{Conn: addrConn{Conn: server, remote: remote}, trusted: trusted}
+}
+
+func proxyV2Header(cmd, family byte, body []byte) []byte {
+	h := append([]byte{}, proxyV2Signature...)
+	h = append(h, 0x20|cmd, family)
+	h = binary.BigEndian.AppendUint16(h, uint16(len(body)))
+	return append(h, body...)
+}
+
+func TestProxyConn_V1(t *testing.T) {
+	c := proxyConnFor(t, "10.0.0.1", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 56324 443\r\nGET / HTTP/1.1\r\n"))
+	assert.Equal(t, "203.0.113.7:56324", c.RemoteAddr().String())
+
+	b, err := io.ReadAll(c)
+	require.NoError(t, err)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(b))
+}
+
+func TestProxyConn_V1Unknown(t *testing.T) {
+	c := proxyConnFor(t, "10.0.0.1", []byte("PROXY UNKNOWN\r\nGET /"))
+	assert.Equal(t, "10.0.0.1:5000", c.RemoteAddr().String())
+}
+
+func TestProxyConn_V1Invalid(t *testing.T) {
+	c := proxyConnFor(t, "10.0.0.1", []byte("PROXY TCP4 nope 10.0.0.1 56324 443\r\nGET /"))
+	_, err := io.ReadAll(c)
+	require.Error(t, err)
+}
+
+func TestProxyConn_V2(t *testing.T) {
+	body := []byte{203, 0, 113, 7, 10, 0, 0, 1}
+	body = binary.BigEndian.AppendUint16(body, 56324)
+	body = binary.BigEndian.AppendUint16(body, 443)
+	body = append(body, 0x04, 0x00, 0x01, 0xff) // A TLV, which is skipped.
+	c := proxyConnFor(t, "10.0.0.1", append(proxyV2Header(1, 0x11, body), "GET /"...))
+	assert.Equal(t, "203.0.113.7:56324", c.RemoteAddr().String())
+
+	b, err := io.ReadAll(c)
+	require.NoError(t, err)
+	assert.Equal(t, "GET /", string(b))
+}
+
+func TestProxyConn_V2IPv6(t *testing.T) {
+	body := append(net.ParseIP("2001:db8::7").To16(), net.ParseIP("2001:db8::1").To16()...)
+	body = binary.BigEndian.AppendUint16(body, 56324)
+	body = binary.BigEndian.AppendUint16(body, 443)
+	c := proxyConnFor(t, "10.0.0.1", proxyV2Header(1, 0x21, body))
+	assert.Equal(t, "[2001:db8::7]:56324", c.RemoteAddr().String())
+}
+
+func TestProxyConn_V2Local(t *testing.T) {
+	c := proxyConnFor(t, "10.0.0.1", append(proxyV2Header(0, 0x00, nil), "GET /"...))
+	assert.Equal(t, "10.0.0.1:5000", c.RemoteAddr().String())
+
+	b, err := io.ReadAll(c)
+	require.NoError(t, err)
+	assert.Equal(t, "GET /", string(b))
+}
+
+func TestProxyConn_NoHeader(t *testing.T) {
+	c := proxyConnFor(t, "203.0.113.7", []byte("GET / HTTP/1.1\r\n"))
+	assert.Equal(t, "203.0.113.7:5000", c.RemoteAddr().String())
+
+	b, err := io.ReadAll(c)
+	require.NoError(t, err)
+	assert.Equal(t, "GET / HTTP/1.1\r\n", string(b))
+}
+
+func TestProxyConn_Untrusted(t *testing.T) {
+	c := proxyConnFor(t, "203.0.113.7", []byte("PROXY TCP4 198.51.100.1 10.0.0.1 56324 443\r\nGET /"))
+	assert.Equal(t, "203.0.113.7:5000", c.RemoteAddr().String(), "header from untrusted address should be ignored")
+
+	_, err := io.ReadAll(c)
+	require.Error(t, err, "connection should be rejected")
+}
@@ -0,0 +1,125 @@
+// Package redact masks proto fields which are marked as sensitive with the
+// `prefab.redact` field option, so that passwords, tokens, and personal data
+// don't leak into logs, error details, audit trails, or recorded payloads.
+//
+// Usage:
+//
+//	import "redact/redact.proto";
+//
+//	message LoginRequest {
+//	  string email = 1;
+//	  string password = 2 [(prefab.redact) = true];
+//	}
+//
+// Redaction is applied by the logging package to tracked values, by the errors
+// package to error details, and by plugins which persist payloads. Use Message
+// to apply it elsewhere.
+package redact
+
+import (
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Value replaces the value of redacted string fields. Other redacted fields are
+// cleared.
+const Value = "[REDACTED]"
+
+// containsCache records whether a message type has redacted fields, directly
+// or in nested messages, keyed by full name.
+var containsCache sync.Map
+
+// Field returns whether a field is marked with the `prefab.redact` option.
+func Field(fd protoreflect.FieldDescriptor) bool {
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+	v, _ := proto.GetExtension(opts, E_Redact).(bool)
+	return v
+}
+
+// Message returns a copy of msg with redacted fields masked. If the message
+// type has no redacted fields msg is returned as is, so the result should not
+// be modified.
+func Message(msg proto.Message) proto.Message {
+	if msg == nil || !msg.ProtoReflect().IsValid() || !contains(msg.ProtoReflect().Descriptor()) {
+		return msg
+	}
+	c := proto.Clone(msg)
+	mask(c.ProtoReflect())
+	return c
+}
+
+// Any redacts v if it is a proto message, other values are returned as is.
+func Any(v any) any {
+	if msg, ok := v.(proto.Message); ok {
+		return Message(msg)
+	}
+	return v
+}
+
+func mask(m protoreflect.Message) {
+	var redacted []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if Field(fd) {
+			redacted = append(redacted, fd)
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			l := v.List()
+			for i := range l.Len() {
+				mask(l.Get(i).Message())
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				mask(mv.Message())
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			mask(v.Message())
+		}
+		return true
+	})
+	for _, fd := range redacted {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(Value))
+		} else {
+			m.Clear(fd)
+		}
+	}
+}
+
+func contains(md protoreflect.MessageDescriptor) bool {
+	if v, ok := containsCache.Load(md.FullName()); ok {
+		return v.(bool)
+	}
+	c := containsRedacted(md, map[protoreflect.FullName]bool{})
+	containsCache.Store(md.FullName(), c)
+	return c
+}
+
+func containsRedacted(md protoreflect.MessageDescriptor, seen map[protoreflect.FullName]bool) bool {
+	if seen[md.FullName()] {
+		return false
+	}
+	seen[md.FullName()] = true
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		if Field(fd) {
+			return true
+		}
+		if fd.IsMap() {
+			fd = fd.MapValue()
+		}
+		if fd.Message() != nil && containsRedacted(fd.Message(), seen) {
+			return true
+		}
+	}
+	return false
+}
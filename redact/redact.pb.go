@@ -0,0 +1,86 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: redact/redact.proto
+
+package redact
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+var file_redact_redact_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50061,
+		Name:          "prefab.redact",
+		Tag:           "varint,50061,opt,name=redact",
+		Filename:      "redact/redact.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// Marks a field as sensitive, for example passwords, tokens, and personal
+	// data. Redacted fields are masked in request logs, tracked values, error
+	// details, audit snapshots, and recorded payloads.
+	//
+	// optional bool redact = 50061;
+	E_Redact = &file_redact_redact_proto_extTypes[0]
+)
+
+var File_redact_redact_proto protoreflect.FileDescriptor
+
+const file_redact_redact_proto_rawDesc = "" +
+	"\n" +
+	"\x13redact/redact.proto\x12\x06prefab\x1a google/protobuf/descriptor.proto:7\n" +
+	"\x06redact\x12\x1d.google.protobuf.FieldOptions\x18\x8d\x87\x03 \x01(\bR\x06redactB\x1fZ\x1dgithub.com/dpup/prefab/redactb\x06proto3"
+
+var file_redact_redact_proto_goTypes = []any{
+	(*descriptorpb.FieldOptions)(nil), // 0: google.protobuf.FieldOptions
+}
+var file_redact_redact_proto_depIdxs = []int32{
+	0, // 0: prefab.redact:extendee -> google.protobuf.FieldOptions
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	0, // [0:1] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_redact_redact_proto_init() }
+func file_redact_redact_proto_init() {
+	if File_redact_redact_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_redact_redact_proto_rawDesc), len(file_redact_redact_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   0,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_redact_redact_proto_goTypes,
+		DependencyIndexes: file_redact_redact_proto_depIdxs,
+		ExtensionInfos:    file_redact_redact_proto_extTypes,
+	}.Build()
+	File_redact_redact_proto = out.File
+	file_redact_redact_proto_goTypes = nil
+	file_redact_redact_proto_depIdxs = nil
+}
@@ -0,0 +1,51 @@
+package redact_test
+
+import (
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/plugins/auth"
+	"github.com/dpup/prefab/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMessage(t *testing.T) {
+	req := &auth.LoginRequest{
+		Provider: "password",
+		Creds:    map[string]string{"email": "a@b.com", "password": "hunter2"},
+	}
+	redacted := redact.Message(req).(*auth.LoginRequest)
+	assert.Equal(t, "password", redacted.GetProvider())
+	assert.Empty(t, redacted.GetCreds(), "non-string fields are cleared")
+	assert.Equal(t, "hunter2", req.GetCreds()["password"], "original should not be modified")
+
+	resp := redact.Message(&auth.LoginResponse{Issued: true, Token: "abc"}).(*auth.LoginResponse)
+	assert.True(t, resp.GetIssued())
+	assert.Equal(t, redact.Value, resp.GetToken())
+
+	// Messages without redacted fields aren't copied.
+	logout := &auth.LogoutRequest{}
+	assert.Same(t, logout, redact.Message(logout))
+	assert.Nil(t, redact.Message(nil))
+}
+
+func TestAny(t *testing.T) {
+	assert.Equal(t, "abc", redact.Any("abc"))
+	assert.Equal(t, redact.Value, redact.Any(&auth.LoginResponse{Token: "abc"}).(*auth.LoginResponse).GetToken())
+}
+
+func TestField(t *testing.T) {
+	fields := (&auth.LoginResponse{}).ProtoReflect().Descriptor().Fields()
+	assert.True(t, redact.Field(fields.ByName("token")))
+	assert.False(t, redact.Field(fields.ByName("issued")))
+}
+
+func TestErrorDetails(t *testing.T) {
+	err := errors.NewC("failed", codes.Internal).WithDetails(&auth.LoginResponse{Token: "abc"})
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	assert.Equal(t, redact.Value, details[0].(*auth.LoginResponse).GetToken())
+}
@@ -0,0 +1,242 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrOpen is returned without calling the dependency while a breaker is open.
+var ErrOpen = errors.NewC("resilience: circuit breaker is open", codes.Unavailable)
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenTimeout      = 30 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+// State of a circuit breaker.
+type State int
+
+const (
+	// StateClosed lets calls through and counts consecutive failures.
+	StateClosed State = iota
+
+	// StateOpen rejects calls with ErrOpen until the open timeout elapses.
+	StateOpen
+
+	// StateHalfOpen lets a limited number of trial calls through. The breaker
+	// closes if they succeed, and opens again if any fail.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOption configures a Breaker.
+type BreakerOption func(*Breaker)
+
+// WithFailureThreshold sets how many consecutive failures open the breaker.
+// Default is 5.
+func WithFailureThreshold(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.threshold = n
+	}
+}
+
+// WithOpenTimeout sets how long the breaker stays open before letting trial
+// calls through. Default is 30 seconds.
+func WithOpenTimeout(d time.Duration) BreakerOption {
+	return func(b *Breaker) {
+		b.openTimeout = d
+	}
+}
+
+// WithHalfOpenRequests sets how many trial calls must succeed in the half-open
+// state before the breaker closes. Default is 1.
+func WithHalfOpenRequests(n int) BreakerOption {
+	return func(b *Breaker) {
+		b.halfOpenRequests = n
+	}
+}
+
+// WithBreakerFailureFunc sets which errors count as failures. Default is
+// IsFailure.
+func WithBreakerFailureFunc(fn func(error) bool) BreakerOption {
+	return func(b *Breaker) {
+		b.isFailure = fn
+	}
+}
+
+// WithStateChange registers a function which is called when the breaker
+// changes state, for example to log or alert. It is called with the breaker's
+// lock held, so must not call back into the breaker.
+func WithStateChange(fn func(name string, from, to State)) BreakerOption {
+	return func(b *Breaker) {
+		b.onChange = fn
+	}
+}
+
+// NewBreaker creates a circuit breaker and registers it under name, replacing
+// any breaker previously registered with the same name.
+func NewBreaker(name string, opts ...BreakerOption) *Breaker {
+	b := &Breaker{
+		name:             name,
+		threshold:        defaultFailureThreshold,
+		openTimeout:      defaultOpenTimeout,
+		halfOpenRequests: defaultHalfOpenRequests,
+		isFailure:        IsFailure,
+		now:              time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	registry.Lock()
+	registry.breakers[name] = b
+	registry.Unlock()
+	return b
+}
+
+// Breaker stops calling a dependency after repeated failures, giving it time
+// to recover and failing fast in the meantime.
+type Breaker struct {
+	name             string
+	threshold        int
+	openTimeout      time.Duration
+	halfOpenRequests int
+	isFailure        func(error) bool
+	onChange         func(name string, from, to State)
+	now              func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int       // Consecutive failures while closed.
+	openedAt  time.Time // When the breaker last opened.
+	trials    int       // Trial calls in flight while half-open.
+	successes int       // Successful trial calls while half-open.
+	rejected  int64
+}
+
+// Name returns the name the breaker is registered under.
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// Stats returns a snapshot of the breaker.
+func (b *Breaker) Stats() Stats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return Stats{
+		Name:     b.name,
+		Kind:     "breaker",
+		State:    b.state.String(),
+		Failures: b.failures,
+		Rejected: b.rejected,
+	}
+}
+
+// Do calls fn unless the breaker is open, in which case ErrOpen is returned.
+// A nil Breaker calls fn directly.
+func (b *Breaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn(ctx)
+	b.record(err)
+	return err
+}
+
+// allow reserves a call, or returns ErrOpen.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	switch b.state {
+	case StateOpen:
+		b.rejected++
+		return errors.Mark(ErrOpen, 0).WithLogField("breaker", b.name)
+	case StateHalfOpen:
+		if b.trials >= b.halfOpenRequests {
+			b.rejected++
+			return errors.Mark(ErrOpen, 0).WithLogField("breaker", b.name)
+		}
+		b.trials++
+	}
+	return nil
+}
+
+func (b *Breaker) record(err error) {
+	failed := b.isFailure(err)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.transition(StateOpen)
+		}
+	case StateHalfOpen:
+		b.trials--
+		if failed {
+			b.transition(StateOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenRequests {
+			b.transition(StateClosed)
+		}
+	case StateOpen:
+		// A call which started before the breaker opened.
+	}
+}
+
+// refresh moves an open breaker to half-open once the timeout has elapsed.
+// Must be called with the lock held.
+func (b *Breaker) refresh() {
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.transition(StateHalfOpen)
+	}
+}
+
+// Must be called with the lock held.
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.failures = 0
+	b.trials = 0
+	b.successes = 0
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+	if b.onChange != nil {
+		b.onChange(b.name, from, to)
+	}
+}
@@ -0,0 +1,103 @@
+package resilience
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	errUnavailable = status.Error(codes.Unavailable, "down")
+	errNotFound    = status.Error(codes.NotFound, "missing")
+)
+
+func fail(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	var changes []string
+	b := NewBreaker("breaker_test",
+		WithFailureThreshold(3),
+		WithOpenTimeout(time.Minute),
+		WithHalfOpenRequests(2),
+		WithStateChange(func(_ string, from, to State) {
+			changes = append(changes, from.String()+"->"+to.String())
+		}),
+	)
+	b.now = func() time.Time { return now }
+	ctx := t.Context()
+
+	// Errors which aren't failures reset the count.
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	require.Error(t, b.Do(ctx, fail(errNotFound)))
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	assert.Equal(t, StateClosed, b.State())
+
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	assert.Equal(t, StateOpen, b.State())
+
+	called := false
+	err := b.Do(ctx, func(context.Context) error { called = true; return nil })
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, codes.Unavailable, code(err))
+	assert.False(t, called)
+	assert.Equal(t, int64(1), b.Stats().Rejected)
+
+	// A failed trial reopens the breaker.
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	assert.Equal(t, StateOpen, b.State())
+
+	// Successful trials close it.
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Do(ctx, fail(nil)))
+	assert.Equal(t, StateHalfOpen, b.State())
+	require.NoError(t, b.Do(ctx, fail(nil)))
+	assert.Equal(t, StateClosed, b.State())
+
+	assert.Equal(t, []string{
+		"closed->open", "open->half-open", "half-open->open",
+		"open->half-open", "half-open->closed",
+	}, changes)
+}
+
+func TestBreaker_HalfOpenLimitsTrials(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker("breaker_test_trials", WithFailureThreshold(1))
+	b.now = func() time.Time { return now }
+	ctx := t.Context()
+
+	require.Error(t, b.Do(ctx, fail(errUnavailable)))
+	now = now.Add(defaultOpenTimeout)
+
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(ctx, func(context.Context) error { <-release; return nil })
+	}()
+	assert.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return b.trials == 1
+	}, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, b.Do(ctx, fail(nil)), ErrOpen, "only one trial at a time")
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_Nil(t *testing.T) {
+	var b *Breaker
+	assert.ErrorIs(t, b.Do(t.Context(), fail(errUnavailable)), errUnavailable)
+}
@@ -0,0 +1,106 @@
+package resilience
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrBulkheadFull is returned when a bulkhead has no free slot within its
+// maximum wait.
+var ErrBulkheadFull = errors.NewC("resilience: too many concurrent calls", codes.ResourceExhausted)
+
+// BulkheadOption configures a Bulkhead.
+type BulkheadOption func(*Bulkhead)
+
+// WithMaxWait sets how long a call waits for a free slot before failing with
+// ErrBulkheadFull. Default is 0, which fails immediately.
+func WithMaxWait(d time.Duration) BulkheadOption {
+	return func(b *Bulkhead) {
+		b.maxWait = d
+	}
+}
+
+// NewBulkhead creates a bulkhead which allows limit concurrent calls, and
+// registers it under name, replacing any bulkhead previously registered with
+// the same name.
+func NewBulkhead(name string, limit int, opts ...BulkheadOption) *Bulkhead {
+	b := &Bulkhead{
+		name:  name,
+		limit: limit,
+		slots: make(chan struct{}, limit),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	registry.Lock()
+	registry.bulkheads[name] = b
+	registry.Unlock()
+	return b
+}
+
+// Bulkhead limits concurrent calls to a dependency, so that a slow dependency
+// can't tie up every goroutine serving requests.
+type Bulkhead struct {
+	name    string
+	limit   int
+	maxWait time.Duration
+	slots   chan struct{}
+
+	rejected atomic.Int64
+}
+
+// Name returns the name the bulkhead is registered under.
+func (b *Bulkhead) Name() string {
+	return b.name
+}
+
+// Stats returns a snapshot of the bulkhead.
+func (b *Bulkhead) Stats() Stats {
+	return Stats{
+		Name:     b.name,
+		Kind:     "bulkhead",
+		InFlight: len(b.slots),
+		Limit:    b.limit,
+		Rejected: b.rejected.Load(),
+	}
+}
+
+// Do calls fn once a slot is free. If none frees up within the maximum wait,
+// or the context is done first, ErrBulkheadFull is returned. A nil Bulkhead
+// calls fn directly.
+func (b *Bulkhead) Do(ctx context.Context, fn func(context.Context) error) error {
+	if b == nil {
+		return fn(ctx)
+	}
+	if !b.acquire(ctx) {
+		b.rejected.Add(1)
+		return errors.Mark(ErrBulkheadFull, 0).WithLogField("bulkhead", b.name)
+	}
+	defer func() { <-b.slots }()
+	return fn(ctx)
+}
+
+func (b *Bulkhead) acquire(ctx context.Context) bool {
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if b.maxWait <= 0 {
+		return false
+	}
+	timer := time.NewTimer(b.maxWait)
+	defer timer.Stop()
+	select {
+	case b.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package resilience
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc"
+)
+
+// UnaryClientInterceptor applies the policy to unary GRPC calls.
+func UnaryClientInterceptor(p Policy) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return p.Do(ctx, func(ctx context.Context) error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// StreamClientInterceptor applies the breaker and bulkhead to opening GRPC
+// streams. Streams aren't retried, and failures after the stream is opened
+// aren't counted.
+func StreamClientInterceptor(p Policy) grpc.StreamClientInterceptor {
+	p.Retry = nil
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return Call(ctx, p, func(ctx context.Context) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		})
+	}
+}
+
+// Transport applies the policy to HTTP requests made with the round tripper,
+// or http.DefaultTransport if nil. Responses with a 5xx status, other than 501
+// Not Implemented, count as failures. Requests with a body are only retried if
+// the body can be replayed, see http.Request.GetBody.
+//
+// Example:
+//
+//	client := &http.Client{Transport: resilience.Transport(policy, nil)}
+func Transport(p Policy, rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return &transport{p: p, rt: rt}
+}
+
+type transport struct {
+	p  Policy
+	rt http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.p
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		p.Retry = nil
+	}
+	var resp *http.Response
+	attempts := 0
+	err := p.Do(req.Context(), func(ctx context.Context) error {
+		r := req.WithContext(ctx)
+		if attempts++; attempts > 1 {
+			if resp != nil {
+				_, _ = io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+				resp = nil
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return errors.Wrap(err, 0)
+				}
+				r.Body = body
+			}
+		}
+
+		var err error
+		resp, err = t.rt.RoundTrip(r)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented {
+			return &statusError{resp: resp}
+		}
+		return nil
+	})
+
+	// A failed response is returned to the caller as-is, once the policy has
+	// given up on it.
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.resp, nil
+	}
+	if err != nil {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// statusError carries a 5xx response through the policy. The body of a response
+// which is retried is drained and closed before the next attempt.
+type statusError struct {
+	resp *http.Response
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("resilience: HTTP %d from %s", e.resp.StatusCode, e.resp.Request.URL.Redacted())
+}
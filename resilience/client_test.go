@@ -0,0 +1,80 @@
+package resilience
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	var calls int
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if calls < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(Policy{
+		Retry: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+	}, nil)}
+
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("hello"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "ok", string(body))
+	assert.Equal(t, []string{"hello", "hello", "hello"}, bodies, "body is replayed on retry")
+}
+
+func TestTransport_ReturnsLastFailure(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte("down"))
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(Policy{
+		Retry: &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond},
+	}, nil)}
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "down", string(body))
+	assert.Equal(t, 2, calls)
+}
+
+func TestTransport_BreakerOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(Policy{
+		Breaker: NewBreaker("transport_test", WithFailureThreshold(1)),
+	}, nil)}
+
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	_, err = client.Get(srv.URL)
+	assert.ErrorIs(t, err, ErrOpen)
+}
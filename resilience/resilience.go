@@ -0,0 +1,175 @@
+// Package resilience protects calls to outbound dependencies with circuit
+// breakers, bounded retries, and bulkheads.
+//
+// A Policy combines the three, and can wrap any call with Do or Call, be added
+// to GRPC clients with UnaryClientInterceptor or prefab.WithResilience, or wrap
+// an HTTP client's transport with Transport:
+//
+//	policy := resilience.Policy{
+//		Breaker:  resilience.NewBreaker("billing"),
+//		Retry:    &resilience.RetryPolicy{MaxAttempts: 3},
+//		Bulkhead: resilience.NewBulkhead("billing", 20),
+//	}
+//	conn, err := prefab.Dial(ctx, "billing.internal:443", prefab.WithResilience(policy))
+//
+//	// Or around a storage call.
+//	err := policy.Do(ctx, func(ctx context.Context) error {
+//		return store.Read(ctx, id, &model)
+//	})
+//
+// Breakers and bulkheads are registered by name when created. Their state is
+// published to expvar as "resilience", which the debug plugin serves at
+// /debug/vars, and Handler reports it for health checks.
+package resilience
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func init() {
+	expvar.Publish("resilience", expvar.Func(func() any { return Snapshot() }))
+}
+
+// Policy combines a circuit breaker, retries, and a bulkhead. Nil fields are
+// skipped.
+//
+// Each attempt acquires the bulkhead and passes through the breaker, so a
+// retry waits for a free slot and stops as soon as the breaker opens.
+type Policy struct {
+	Breaker  *Breaker
+	Retry    *RetryPolicy
+	Bulkhead *Bulkhead
+}
+
+// Do calls fn according to the policy.
+func (p Policy) Do(ctx context.Context, fn func(context.Context) error) error {
+	attempt := func(ctx context.Context) error {
+		if p.Bulkhead != nil {
+			return p.Bulkhead.Do(ctx, func(ctx context.Context) error {
+				return p.Breaker.Do(ctx, fn)
+			})
+		}
+		return p.Breaker.Do(ctx, fn)
+	}
+	if p.Retry != nil {
+		return p.Retry.Do(ctx, attempt)
+	}
+	return attempt(ctx)
+}
+
+// Call calls fn according to the policy and returns its result.
+func Call[T any](ctx context.Context, p Policy, fn func(context.Context) (T, error)) (T, error) {
+	var out T
+	err := p.Do(ctx, func(ctx context.Context) error {
+		var err error
+		out, err = fn(ctx)
+		return err
+	})
+	return out, err
+}
+
+// IsFailure reports whether an error indicates that a dependency is unhealthy,
+// as opposed to a problem with the request. Used by default to decide whether
+// a call counts against a breaker and whether it is retried.
+//
+// Unavailable, DeadlineExceeded, ResourceExhausted, Internal, Unknown, and
+// DataLoss are failures. Errors which aren't GRPC statuses or prefab errors are
+// also failures, except for context cancellation.
+func IsFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unknown, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+func code(err error) codes.Code {
+	if s, ok := status.FromError(err); ok {
+		return s.Code()
+	}
+	return errors.Code(err)
+}
+
+// Stats is a snapshot of a breaker or bulkhead.
+type Stats struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"` // "breaker" or "bulkhead"
+
+	// Breaker state, one of "closed", "open", or "half-open".
+	State string `json:"state,omitempty"`
+
+	// Consecutive failures seen by a breaker.
+	Failures int `json:"failures,omitempty"`
+
+	// Calls in progress in a bulkhead, and its limit.
+	InFlight int `json:"inFlight,omitempty"`
+	Limit    int `json:"limit,omitempty"`
+
+	// Calls rejected because a breaker was open or a bulkhead was full.
+	Rejected int64 `json:"rejected"`
+}
+
+var registry = struct {
+	sync.Mutex
+	breakers  map[string]*Breaker
+	bulkheads map[string]*Bulkhead
+}{breakers: map[string]*Breaker{}, bulkheads: map[string]*Bulkhead{}}
+
+// Snapshot returns the state of every registered breaker and bulkhead, sorted
+// by name.
+func Snapshot() []Stats {
+	registry.Lock()
+	defer registry.Unlock()
+	out := make([]Stats, 0, len(registry.breakers)+len(registry.bulkheads))
+	for _, b := range registry.breakers {
+		out = append(out, b.Stats())
+	}
+	for _, b := range registry.bulkheads {
+		out = append(out, b.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Kind < out[j].Kind
+	})
+	return out
+}
+
+// Handler reports the state of every breaker and bulkhead as JSON. It responds
+// with 503 Service Unavailable while any breaker is open, so it can be used as
+// a dependency health check.
+//
+// Example:
+//
+//	prefab.WithAdminHTTPHandler("/healthz/dependencies", resilience.Handler())
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		stats := Snapshot()
+		code := http.StatusOK
+		for _, s := range stats {
+			if s.State == StateOpen.String() {
+				code = http.StatusServiceUnavailable
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(stats)
+	})
+}
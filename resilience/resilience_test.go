@@ -0,0 +1,157 @@
+package resilience
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	prefaberrors "github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsFailure(t *testing.T) {
+	assert.False(t, IsFailure(nil))
+	assert.False(t, IsFailure(context.Canceled))
+	assert.True(t, IsFailure(context.DeadlineExceeded))
+	assert.True(t, IsFailure(errors.New("connection reset")))
+	assert.True(t, IsFailure(status.Error(codes.Unavailable, "")))
+	assert.False(t, IsFailure(status.Error(codes.InvalidArgument, "")))
+	assert.False(t, IsFailure(prefaberrors.NewC("missing", codes.NotFound)))
+	assert.True(t, IsFailure(prefaberrors.NewC("broken", codes.Internal)))
+}
+
+func TestRetry(t *testing.T) {
+	r := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+
+	calls := 0
+	err := r.Do(t.Context(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errUnavailable
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = r.Do(t.Context(), func(context.Context) error { calls++; return errUnavailable })
+	assert.ErrorIs(t, err, errUnavailable)
+	assert.Equal(t, 3, calls, "gives up after MaxAttempts")
+
+	calls = 0
+	err = r.Do(t.Context(), func(context.Context) error { calls++; return errNotFound })
+	assert.ErrorIs(t, err, errNotFound)
+	assert.Equal(t, 1, calls, "only failures are retried")
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	calls = 0
+	_ = (&RetryPolicy{InitialBackoff: time.Hour}).Do(ctx, func(context.Context) error { calls++; return errUnavailable })
+	assert.Equal(t, 1, calls, "stops when the context is done")
+}
+
+func TestRetry_Backoff(t *testing.T) {
+	r := &RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: -1}
+	assert.Equal(t, 100*time.Millisecond, r.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, r.Backoff(2))
+	assert.Equal(t, 800*time.Millisecond, r.Backoff(4))
+	assert.Equal(t, time.Second, r.Backoff(10))
+
+	r.Jitter = 0.5
+	for range 20 {
+		d := r.Backoff(1)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.Less(t, d, 150*time.Millisecond)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	b := NewBulkhead("bulkhead_test", 1, WithMaxWait(10*time.Millisecond))
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- b.Do(t.Context(), func(context.Context) error { <-release; return nil })
+	}()
+	assert.Eventually(t, func() bool { return b.Stats().InFlight == 1 }, time.Second, time.Millisecond)
+
+	err := b.Do(t.Context(), fail(nil))
+	assert.ErrorIs(t, err, ErrBulkheadFull)
+	assert.Equal(t, codes.ResourceExhausted, code(err))
+	assert.Equal(t, int64(1), b.Stats().Rejected)
+
+	close(release)
+	require.NoError(t, <-done)
+	require.NoError(t, b.Do(t.Context(), fail(nil)))
+	assert.Zero(t, b.Stats().InFlight)
+}
+
+func TestPolicy(t *testing.T) {
+	p := Policy{
+		Breaker:  NewBreaker("policy_test", WithFailureThreshold(2)),
+		Retry:    &RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Millisecond},
+		Bulkhead: NewBulkhead("policy_test", 1),
+	}
+
+	calls := 0
+	_, err := Call(t.Context(), p, func(context.Context) (string, error) {
+		calls++
+		return "", errUnavailable
+	})
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, 2, calls, "retries stop once the breaker opens")
+
+	p.Breaker = nil
+	out, err := Call(t.Context(), p, func(context.Context) (string, error) { return "ok", nil })
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out)
+}
+
+func TestSnapshotAndHandler(t *testing.T) {
+	b := NewBreaker("snapshot_test", WithFailureThreshold(1))
+	NewBulkhead("snapshot_test", 4)
+
+	var found []Stats
+	for _, s := range Snapshot() {
+		if s.Name == "snapshot_test" {
+			found = append(found, s)
+		}
+	}
+	assert.Equal(t, []Stats{
+		{Name: "snapshot_test", Kind: "breaker", State: "closed"},
+		{Name: "snapshot_test", Kind: "bulkhead", Limit: 4},
+	}, found)
+
+	var published []Stats
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("resilience").String()), &published))
+	assert.NotEmpty(t, published)
+
+	_ = b.Do(t.Context(), fail(errUnavailable))
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"open"`)
+
+	// Other tests leave breakers open, so remove this one to check the healthy
+	// response in isolation.
+	registry.Lock()
+	saved := registry.breakers
+	registry.breakers = map[string]*Breaker{}
+	registry.Unlock()
+	t.Cleanup(func() {
+		registry.Lock()
+		registry.breakers = saved
+		registry.Unlock()
+	})
+	w = httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
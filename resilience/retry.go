@@ -0,0 +1,100 @@
+package resilience
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+)
+
+const (
+	defaultMaxAttempts    = 3
+	defaultInitialBackoff = 100 * time.Millisecond
+	defaultMaxBackoff     = 5 * time.Second
+	defaultMultiplier     = 2
+	defaultJitter         = 0.2
+)
+
+// RetryPolicy retries failed calls with exponential backoff. Zero fields use
+// the defaults.
+type RetryPolicy struct {
+	// Total number of attempts, including the first. Default is 3.
+	MaxAttempts int
+
+	// Backoff before the first retry. Default is 100ms.
+	InitialBackoff time.Duration
+
+	// Upper bound on the backoff. Default is 5s.
+	MaxBackoff time.Duration
+
+	// Factor the backoff grows by after each retry. Default is 2.
+	Multiplier float64
+
+	// Fraction of the backoff which is randomized, so that clients don't retry
+	// in lockstep. Default is 0.2, set to a negative value to disable.
+	Jitter float64
+
+	// Retryable reports whether an error should be retried. Default is
+	// IsFailure. ErrOpen and ErrBulkheadFull are never retried.
+	Retryable func(error) bool
+}
+
+// Do calls fn until it succeeds, returns an error which isn't retryable, the
+// attempts are used up, or the context is done. The last error is returned.
+func (r *RetryPolicy) Do(ctx context.Context, fn func(context.Context) error) error {
+	if r == nil {
+		return fn(ctx)
+	}
+	attempts := r.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultMaxAttempts
+	}
+	retryable := r.Retryable
+	if retryable == nil {
+		retryable = IsFailure
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= attempts || !retryable(err) ||
+			errors.Is(err, ErrOpen) || errors.Is(err, ErrBulkheadFull) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(r.Backoff(attempt)):
+		}
+	}
+}
+
+// Backoff returns how long to wait after the given attempt, starting at 1.
+func (r *RetryPolicy) Backoff(attempt int) time.Duration {
+	initial, maxBackoff, mult, jitter := r.InitialBackoff, r.MaxBackoff, r.Multiplier, r.Jitter
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if mult <= 0 {
+		mult = defaultMultiplier
+	}
+	if jitter == 0 {
+		jitter = defaultJitter
+	}
+
+	d := float64(initial)
+	for range attempt - 1 {
+		d *= mult
+		if d >= float64(maxBackoff) {
+			break
+		}
+	}
+	d = min(d, float64(maxBackoff))
+	if jitter > 0 {
+		// Spread evenly over [d*(1-jitter), d*(1+jitter)).
+		d *= 1 - jitter + 2*jitter*rand.Float64() //nolint:gosec // Jitter doesn't need a secure source.
+	}
+	return time.Duration(d)
+}
@@ -0,0 +1,74 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// methodHeaders caches the headers declared for each RPC, keyed by full method
+// name, since descriptors don't change after startup.
+var methodHeaders sync.Map
+
+// responseHeaderForwarder sets the Cache-Control and custom headers declared on
+// an RPC with the `cache_control` and `response_headers` method options.
+// Headers already set by the handler, via serverutil.SendHeader, are kept.
+//
+// Example:
+//
+//	rpc GetArticle(GetArticleRequest) returns (Article) {
+//	  option (prefab.cache_control) = "public, max-age=300";
+//	  option (prefab.response_headers) = "Vary: Accept-Language";
+//	}
+func responseHeaderForwarder(ctx context.Context, w http.ResponseWriter, _ proto.Message) error {
+	method, ok := runtime.RPCMethod(ctx)
+	if !ok {
+		return nil
+	}
+	for _, h := range headersForMethod(method) {
+		if w.Header().Get(h[0]) == "" {
+			w.Header().Set(h[0], h[1])
+		}
+	}
+	return nil
+}
+
+// headersForMethod returns the name/value pairs declared for a method, e.g.
+// "/prefab.MetaService/ClientConfig".
+func headersForMethod(method string) [][2]string {
+	if v, ok := methodHeaders.Load(method); ok {
+		return v.([][2]string)
+	}
+
+	var headers [][2]string
+	name := strings.TrimPrefix(strings.ReplaceAll(method, "/", "."), ".")
+	if desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+		opts, _ := desc.Options().(*descriptorpb.MethodOptions)
+		headers = headerOptions(opts)
+	}
+	methodHeaders.Store(method, headers)
+	return headers
+}
+
+// headerOptions returns the headers declared by method options.
+func headerOptions(opts *descriptorpb.MethodOptions) [][2]string {
+	var headers [][2]string
+	if cc := proto.GetExtension(opts, E_CacheControl).(string); cc != "" {
+		headers = append(headers, [2]string{"Cache-Control", cc})
+	}
+	for _, h := range proto.GetExtension(opts, E_ResponseHeaders).([]string) {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok {
+			continue
+		}
+		headers = append(headers, [2]string{http.CanonicalHeaderKey(strings.TrimSpace(k)), strings.TrimSpace(v)})
+	}
+	return headers
+}
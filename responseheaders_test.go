@@ -0,0 +1,53 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestHeadersForMethod(t *testing.T) {
+	assert.Equal(t, [][2]string{{"Cache-Control", "private, no-cache"}},
+		headersForMethod(MetaService_ClientConfig_FullMethodName))
+	assert.Empty(t, headersForMethod("/prefab.MetaService/Unknown"))
+}
+
+func TestHeaderOptions(t *testing.T) {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, E_CacheControl, "public, max-age=60")
+	proto.SetExtension(opts, E_ResponseHeaders, []string{"vary: Accept-Language", "invalid", "X-Robots-Tag:noindex"})
+	assert.Equal(t, [][2]string{
+		{"Cache-Control", "public, max-age=60"},
+		{"Vary", "Accept-Language"},
+		{"X-Robots-Tag", "noindex"},
+	}, headerOptions(opts))
+
+	assert.Empty(t, headerOptions(nil))
+}
+
+func TestResponseHeaderForwarder(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/meta/config", nil)
+	ctx, err := runtime.AnnotateContext(t.Context(), runtime.NewServeMux(), req, MetaService_ClientConfig_FullMethodName)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	require.NoError(t, responseHeaderForwarder(ctx, rec, nil))
+	assert.Equal(t, "private, no-cache", rec.Header().Get("Cache-Control"))
+
+	// Headers set by the handler are kept.
+	rec = httptest.NewRecorder()
+	rec.Header().Set("Cache-Control", "private")
+	require.NoError(t, responseHeaderForwarder(ctx, rec, nil))
+	assert.Equal(t, "private", rec.Header().Get("Cache-Control"))
+
+	// Requests without an RPC method are ignored.
+	rec = httptest.NewRecorder()
+	require.NoError(t, responseHeaderForwarder(t.Context(), rec, nil))
+	assert.Empty(t, rec.Header())
+}
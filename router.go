@@ -0,0 +1,175 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/grpc/codes"
+)
+
+// Middleware wraps an HTTP handler, for example to require authentication.
+type Middleware func(http.Handler) http.Handler
+
+// Route describes an HTTP handler registered with the server.
+type Route struct {
+	// Pattern the route was registered with, e.g. "GET /users/{id}".
+	Pattern string `json:"pattern"`
+
+	// HTTP method the route is restricted to, empty for all methods.
+	Method string `json:"method,omitempty"`
+
+	// Host the route is restricted to, empty for all hosts. See WithVirtualHost.
+	Host string `json:"host,omitempty"`
+
+	// Number of middleware specific to the route.
+	Middleware int `json:"middleware"`
+
+	// Whether the route is served on the admin listener.
+	Admin bool `json:"admin"`
+}
+
+// WithRoute registers an HTTP handler for a pattern, using the syntax of
+// http.ServeMux, e.g. "GET /users/{id}". Wildcards are available to handlers
+// via RouteParam. Middleware is applied in order, so the first middleware sees
+// the request first.
+func WithRoute(pattern string, h http.Handler, mw ...Middleware) ServerOption {
+	return func(b *builder) {
+		b.handlers = append(b.handlers, handler{
+			prefix:      pattern,
+			httpHandler: h,
+			middleware:  mw,
+		})
+	}
+}
+
+// WithRouteFunc registers an HTTP handler function for a pattern, see WithRoute.
+func WithRouteFunc(pattern string, h func(http.ResponseWriter, *http.Request), mw ...Middleware) ServerOption {
+	return WithRoute(pattern, http.HandlerFunc(h), mw...)
+}
+
+// WithJSONRoute registers a JSON handler for a pattern, see WithRoute and
+// WithJSONHandler.
+func WithJSONRoute(pattern string, h JSONHandler, mw ...Middleware) ServerOption {
+	return func(b *builder) {
+		b.handlers = append(b.handlers, handler{
+			prefix:      pattern,
+			jsonHandler: h,
+			middleware:  mw,
+		})
+	}
+}
+
+// Routes returns the HTTP routes registered with the server, excluding the
+// GRPC Gateway.
+func (s *Server) Routes() []Route {
+	return slices.Clone(s.routes)
+}
+
+type routeRequestKey struct{}
+
+// RouteParam returns the value of a wildcard in the pattern of the route that
+// matched the request, or "" if there is no such wildcard.
+func RouteParam(ctx context.Context, name string) string {
+	if r, ok := ctx.Value(routeRequestKey{}).(*http.Request); ok {
+		return r.PathValue(name)
+	}
+	return ""
+}
+
+// withRouteParams makes the request's wildcards available via RouteParam.
+func withRouteParams(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeRequestKey{}, r)))
+	})
+}
+
+// chainMiddleware wraps h such that the first middleware runs first.
+func chainMiddleware(h http.Handler, mw []Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// RateLimit is middleware which allows each client IP n requests per interval,
+// rejecting further requests with ResourceExhausted. The client IP is the
+// connection address, unless the request came through a trusted proxy, see
+// WithTrustedProxies.
+func RateLimit(n int, interval time.Duration) Middleware {
+	l := &rateLimiter{n: n, interval: interval, windows: map[string]*rateWindow{}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !l.allow(rateLimitKey(r), time.Now()) {
+				WriteJSONError(w, r, errors.NewC("rate limit exceeded", codes.ResourceExhausted).
+					WithUserPresentableMessage("Too many requests, please try again later"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey returns the client IP of the request. Requests handled by the
+// server have already been resolved, otherwise the request is resolved here so
+// that forwarded headers are only used from trusted proxies.
+func rateLimitKey(r *http.Request) string {
+	if ip := serverutil.ClientIP(r.Context()); ip != "" {
+		return ip
+	}
+	return serverutil.ResolveForwarded(r, serverutil.CurrentTrustedProxies()).ClientIP
+}
+
+// rateLimiter counts requests per key in fixed windows.
+type rateLimiter struct {
+	n        int
+	interval time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+
+	// Windows in the order they started, so that expired windows can be dropped
+	// from the front without scanning every client seen.
+	order []*rateWindow
+}
+
+type rateWindow struct {
+	key   string
+	start time.Time
+	count int
+}
+
+// allow counts a request for key and reports whether it is within the limit.
+func (l *rateLimiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(l.order) > 0 && now.Sub(l.order[0].start) >= l.interval {
+		delete(l.windows, l.order[0].key)
+		l.order[0] = nil
+		l.order = l.order[1:]
+	}
+
+	win, ok := l.windows[key]
+	if !ok {
+		win = &rateWindow{key: key, start: now}
+		l.windows[key] = win
+		l.order = append(l.order, win)
+	}
+	win.count++
+	return win.count <= l.n
+}
+
+// routeMethod returns the method of a pattern, or "" if it matches all methods.
+func routeMethod(pattern string) string {
+	method, _, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return ""
+	}
+	return method
+}
@@ -0,0 +1,158 @@
+package prefab
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveRoute(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	ctx := logging.With(context.Background(), logging.NewDevLogger())
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, method, path, nil))
+	return rec
+}
+
+func TestRoute_Params(t *testing.T) {
+	s := New(
+		WithRouteFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("user " + RouteParam(r.Context(), "id") + RouteParam(r.Context(), "missing")))
+		}),
+		WithJSONRoute("DELETE /users/{id}", func(r *http.Request) (any, error) {
+			return map[string]string{"deleted": RouteParam(r.Context(), "id")}, nil
+		}),
+	)
+
+	rec := serveRoute(s.httpMux, http.MethodGet, "/users/42")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "user 42", rec.Body.String())
+
+	rec = serveRoute(s.httpMux, http.MethodDelete, "/users/42")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"deleted":"42"}`, rec.Body.String())
+
+	rec = serveRoute(s.httpMux, http.MethodPost, "/users/42")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	assert.Empty(t, RouteParam(context.Background(), "id"))
+}
+
+func TestRoute_Middleware(t *testing.T) {
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+	}
+
+	s := New(
+		WithRouteFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		}, mw("first"), mw("second")),
+		WithRouteFunc("/denied", func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		}, mw("first"), deny),
+	)
+
+	assert.Equal(t, http.StatusOK, serveRoute(s.httpMux, http.MethodGet, "/ok").Code)
+	assert.Equal(t, []string{"first", "second", "handler"}, calls)
+
+	calls = nil
+	assert.Equal(t, http.StatusForbidden, serveRoute(s.httpMux, http.MethodGet, "/denied").Code)
+	assert.Equal(t, []string{"first"}, calls)
+}
+
+func TestRoutes(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	s := New(
+		WithAdminListener(ln),
+		WithRouteFunc("GET /users/{id}", noop, RateLimit(10, time.Minute)),
+		WithHTTPHandlerFunc("/legacy/", noop),
+		WithAdminHTTPHandlerFunc("/debug/test", noop),
+	)
+
+	want := []Route{
+		{Pattern: "GET /users/{id}", Method: http.MethodGet, Middleware: 1},
+		{Pattern: "/legacy/"},
+		{Pattern: "/debug/test", Admin: true},
+		{Pattern: "GET /readyz", Method: http.MethodGet, Admin: true},
+		{Pattern: "GET /debug/routes", Method: http.MethodGet, Admin: true},
+		{Pattern: "GET /debug/interceptors", Method: http.MethodGet, Admin: true},
+	}
+	assert.Equal(t, want, s.Routes())
+
+	rec := serveRoute(s.adminMux, http.MethodGet, "/debug/routes")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got []Route
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, want, got)
+
+	// The route listing is only exposed on the admin listener.
+	assert.Equal(t, http.StatusNotFound, serveRoute(s.httpMux, http.MethodGet, "/debug/routes").Code)
+}
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(2, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	assert.Equal(t, http.StatusOK, serveRoute(h, http.MethodGet, "/").Code)
+	assert.Equal(t, http.StatusOK, serveRoute(h, http.MethodGet, "/").Code)
+	rec := serveRoute(h, http.MethodGet, "/")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Contains(t, rec.Body.String(), "RESOURCE_EXHAUSTED")
+
+	h = RateLimit(1, time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Equal(t, http.StatusOK, serveRoute(h, http.MethodGet, "/").Code)
+	time.Sleep(2 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, serveRoute(h, http.MethodGet, "/").Code, "window should reset")
+}
+
+func TestRateLimit_IgnoresSpoofedForwardedFor(t *testing.T) {
+	h := RateLimit(1, time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(xff string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "198.51.100.2:4000"
+		r.Header.Set("X-Forwarded-For", xff)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, serve("10.0.0.1"))
+	assert.Equal(t, http.StatusTooManyRequests, serve("10.0.0.2"), "client shouldn't be able to rotate its IP")
+}
+
+func TestRateLimiter_ExpiresWindows(t *testing.T) {
+	l := &rateLimiter{n: 1, interval: time.Minute, windows: map[string]*rateWindow{}}
+	now := time.Now()
+	assert.True(t, l.allow("a", now))
+	assert.True(t, l.allow("b", now.Add(30*time.Second)))
+	assert.False(t, l.allow("a", now.Add(59*time.Second)))
+
+	// Only the expired window is dropped.
+	assert.True(t, l.allow("c", now.Add(time.Minute)))
+	assert.Len(t, l.windows, 2)
+	assert.Len(t, l.order, 2)
+	assert.False(t, l.allow("b", now.Add(time.Minute)))
+
+	assert.True(t, l.allow("a", now.Add(2*time.Minute)))
+	assert.Len(t, l.windows, 1)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/textproto"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -41,8 +42,18 @@ type SecurityHeaders struct {
 
 	// Access-Control headers define which origins are allowed to access the
 	// resource and what methods are allowed.
-	// TODO: Should this allow for patterns instead of only exact origin matches?
-	CORSOrigins          []string
+	//
+	// Origins are matched exactly, unless they contain a wildcard subdomain, in
+	// which case any subdomain will be matched, e.g. `https://*.example.com`
+	// matches `https://app.example.com` but not `https://example.com`.
+	CORSOrigins []string
+
+	// CORSOriginValidator is consulted for origins which do not match
+	// CORSOrigins, allowing origins to be validated dynamically, for example
+	// against a list of customer domains. CORS is enabled if either CORSOrigins
+	// or CORSOriginValidator is set.
+	CORSOriginValidator func(origin string) bool
+
 	CORSAllowMethods     []string
 	CORSAllowHeaders     []string
 	CORSExposeHeaders    []string
@@ -53,9 +64,41 @@ type SecurityHeaders struct {
 	staticHeaders    map[string]string
 	preflightHeaders map[string]string
 	allowedOrigins   map[string]bool
+	originPatterns   []originPattern
 	mu               sync.Mutex // Protects precomputed fields.
 }
 
+// CORSOverride replaces the server-wide CORS configuration for a subset of
+// requests, see WithCORSOverride. Fields have the same meaning as the
+// corresponding CORS fields on SecurityHeaders.
+type CORSOverride struct {
+	Origins          []string
+	OriginValidator  func(origin string) bool
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// WithCORSOverride returns a copy of the security headers, with the CORS
+// configuration replaced by the override.
+func (s *SecurityHeaders) WithCORSOverride(o CORSOverride) *SecurityHeaders {
+	return &SecurityHeaders{
+		XFramesOptions:        s.XFramesOptions,
+		HSTSExpiration:        s.HSTSExpiration,
+		HSTSIncludeSubdomains: s.HSTSIncludeSubdomains,
+		HSTSPreload:           s.HSTSPreload,
+		CORSOrigins:           o.Origins,
+		CORSOriginValidator:   o.OriginValidator,
+		CORSAllowMethods:      o.AllowMethods,
+		CORSAllowHeaders:      o.AllowHeaders,
+		CORSExposeHeaders:     o.ExposeHeaders,
+		CORSAllowCredentials:  o.AllowCredentials,
+		CORSMaxAge:            o.MaxAge,
+	}
+}
+
 // Apply the security headers to the given response.
 func (s *SecurityHeaders) Apply(w http.ResponseWriter, r *http.Request) error {
 	if err := s.compute(); err != nil {
@@ -65,9 +108,18 @@ func (s *SecurityHeaders) Apply(w http.ResponseWriter, r *http.Request) error {
 		w.Header().Set(k, v)
 	}
 
-	if len(s.CORSOrigins) > 0 {
+	if s.corsEnabled() {
+		// The response depends on the origin, and for preflight requests on the
+		// requested method and headers, so caches must key on them, whether or
+		// not the origin is allowed.
+		addVary(w.Header(), "Origin")
+		if r.Method == http.MethodOptions {
+			addVary(w.Header(), "Access-Control-Request-Method")
+			addVary(w.Header(), "Access-Control-Request-Headers")
+		}
+
 		origin := r.Header.Get("Origin")
-		if s.allowedOrigins[origin] {
+		if s.isAllowedOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			if r.Method == http.MethodOptions {
 				for k, v := range s.preflightHeaders {
@@ -104,7 +156,7 @@ func (s *SecurityHeaders) compute() error {
 			}
 		}
 
-		if len(s.CORSOrigins) > 0 {
+		if s.corsEnabled() {
 			s.computeCORSHeaders()
 		}
 	}
@@ -129,8 +181,6 @@ func (s *SecurityHeaders) computeHSTSHeaders() error {
 // See https://fetch.spec.whatwg.org/#http-responses for details on
 // headers required on preflight and non-preflight requests.
 func (s *SecurityHeaders) computeCORSHeaders() {
-	s.staticHeaders["Vary"] = "Origin"
-
 	if s.CORSAllowCredentials {
 		s.staticHeaders["Access-Control-Allow-Credentials"] = "true"
 	}
@@ -150,19 +200,110 @@ func (s *SecurityHeaders) computeCORSHeaders() {
 
 	s.allowedOrigins = map[string]bool{}
 	for _, origin := range s.CORSOrigins {
-		s.allowedOrigins[origin] = true
+		if p, ok := parseOriginPattern(origin); ok {
+			s.originPatterns = append(s.originPatterns, p)
+		} else {
+			s.allowedOrigins[origin] = true
+		}
 	}
 }
 
+func (s *SecurityHeaders) corsEnabled() bool {
+	return len(s.CORSOrigins) > 0 || s.CORSOriginValidator != nil
+}
+
+func (s *SecurityHeaders) isAllowedOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	if s.allowedOrigins[origin] {
+		return true
+	}
+	for _, p := range s.originPatterns {
+		if p.matches(origin) {
+			return true
+		}
+	}
+	return s.CORSOriginValidator != nil && s.CORSOriginValidator(origin)
+}
+
+// originPattern matches origins against a wildcard subdomain entry, such as
+// `https://*.example.com`.
+type originPattern struct {
+	scheme string // e.g. "https://"
+	suffix string // e.g. ".example.com"
+}
+
+func parseOriginPattern(origin string) (originPattern, bool) {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || !strings.HasPrefix(host, "*.") {
+		return originPattern{}, false
+	}
+	return originPattern{scheme: scheme + "://", suffix: host[1:]}, true
+}
+
+func (p originPattern) matches(origin string) bool {
+	host, ok := strings.CutPrefix(origin, p.scheme)
+	if !ok {
+		return false
+	}
+	sub, ok := strings.CutSuffix(host, p.suffix)
+	return ok && sub != "" && !strings.ContainsAny(sub, "/:@")
+}
+
+// addVary adds a value to the Vary header, unless it is already present.
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
 func (s *SecurityHeaders) normalizeHeaders(h []string) {
 	for i, v := range h {
 		h[i] = textproto.CanonicalMIMEHeaderKey(v)
 	}
 }
 
-func securityMiddleware(h http.Handler, sh *SecurityHeaders) http.Handler {
+// securityPolicy selects the security headers to apply to a request, allowing
+// the server-wide configuration to be overridden for specific path prefixes.
+type securityPolicy struct {
+	base      *SecurityHeaders
+	overrides []prefixedSecurityHeaders // Sorted longest prefix first.
+}
+
+type prefixedSecurityHeaders struct {
+	prefix  string
+	headers *SecurityHeaders
+}
+
+func newSecurityPolicy(base *SecurityHeaders, overrides map[string]CORSOverride) *securityPolicy {
+	p := &securityPolicy{base: base}
+	for prefix, o := range overrides {
+		p.overrides = append(p.overrides, prefixedSecurityHeaders{prefix: prefix, headers: base.WithCORSOverride(o)})
+	}
+	sort.Slice(p.overrides, func(i, j int) bool {
+		return len(p.overrides[i].prefix) > len(p.overrides[j].prefix)
+	})
+	return p
+}
+
+func (p *securityPolicy) forPath(path string) *SecurityHeaders {
+	for _, o := range p.overrides {
+		if strings.HasPrefix(path, o.prefix) {
+			return o.headers
+		}
+	}
+	return p.base
+}
+
+func securityMiddleware(h http.Handler, p *securityPolicy) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if err := sh.Apply(w, r); err != nil {
+		if err := p.forPath(r.URL.Path).Apply(w, r); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			logging.Errorw(r.Context(), "Failed to apply security headers", "error", err)
 			return
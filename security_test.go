@@ -235,3 +235,104 @@ func TestSecurityHeaders(t *testing.T) {
 		})
 	}
 }
+
+func TestSecurityHeaders_DynamicOrigins(t *testing.T) {
+	sh := &SecurityHeaders{
+		CORSOrigins: []string{"https://*.example.com", "https://exact.com"},
+		CORSOriginValidator: func(origin string) bool {
+			return origin == "https://dynamic.com"
+		},
+	}
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://evil.com/.example.com", false},
+		{"https://exact.com", true},
+		{"https://dynamic.com", true},
+		{"https://other.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/foo", nil)
+			r.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			require.NoError(t, sh.Apply(w, r))
+
+			if tt.allowed {
+				assert.Equal(t, tt.origin, w.Header().Get("Access-Control-Allow-Origin"))
+			} else {
+				assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+			}
+			assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+		})
+	}
+}
+
+func TestSecurityHeaders_VaryPreflight(t *testing.T) {
+	sh := &SecurityHeaders{CORSOrigins: []string{"https://example.com"}}
+
+	r := httptest.NewRequest(http.MethodOptions, "/api/foo", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	w.Header().Add("Vary", "Accept-Encoding, origin")
+	require.NoError(t, sh.Apply(w, r))
+
+	assert.Equal(t, []string{
+		"Accept-Encoding, origin",
+		"Access-Control-Request-Method",
+		"Access-Control-Request-Headers",
+	}, w.Header().Values("Vary"), "existing values should be preserved and not duplicated")
+}
+
+func TestSecurityPolicy_Overrides(t *testing.T) {
+	base := &SecurityHeaders{
+		XFramesOptions: XFramesOptionsDeny,
+		CORSOrigins:    []string{"https://app.com"},
+	}
+	policy := newSecurityPolicy(base, map[string]CORSOverride{
+		"/api/public/":        {Origins: []string{"https://partner.com"}},
+		"/api/public/locked/": {},
+	})
+	h := securityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), policy)
+
+	allowOrigin := func(path, origin string) http.Header {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Header()
+	}
+
+	assert.Equal(t, "https://app.com", allowOrigin("/api/foo", "https://app.com").Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, allowOrigin("/api/foo", "https://partner.com").Get("Access-Control-Allow-Origin"))
+
+	hdr := allowOrigin("/api/public/foo", "https://partner.com")
+	assert.Equal(t, "https://partner.com", hdr.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "DENY", hdr.Get("X-Frame-Options"), "non-CORS headers should be inherited")
+	assert.Empty(t, allowOrigin("/api/public/foo", "https://app.com").Get("Access-Control-Allow-Origin"))
+
+	hdr = allowOrigin("/api/public/locked/foo", "https://partner.com")
+	assert.Empty(t, hdr.Get("Access-Control-Allow-Origin"), "longest prefix should win")
+	assert.Empty(t, hdr.Get("Vary"), "CORS should be disabled")
+}
+
+func TestCORSOverridesFromConfig(t *testing.T) {
+	LoadConfigDefaults(map[string]interface{}{
+		"server.security.corsOverrides./api/public/.origins":          []string{"https://*.partner.com"},
+		"server.security.corsOverrides./api/public/.allowCredentials": true,
+		"server.security.corsOverrides./api/public/.maxAge":           "1h",
+	})
+	t.Cleanup(func() { Config.Delete("server.security.corsOverrides") })
+
+	overrides := corsOverridesFromConfig()
+	require.Contains(t, overrides, "/api/public/")
+	assert.Equal(t, []string{"https://*.partner.com"}, overrides["/api/public/"].Origins)
+	assert.True(t, overrides["/api/public/"].AllowCredentials)
+	assert.Equal(t, time.Hour, overrides["/api/public/"].MaxAge)
+}
@@ -2,19 +2,23 @@ package prefab
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/NYTimes/gziphandler"
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/knadh/koanf/providers/file"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -54,6 +58,12 @@ type Server struct {
 	// Location of key file, if TLS to be used.
 	keyFile string
 
+	// Policy for verifying client certificates, if mutual TLS is enabled.
+	clientAuth *clientAuth
+
+	// Listener to serve on, instead of host and port.
+	listener net.Listener
+
 	// Context that is propagated to gateway handlers.
 	baseContext context.Context
 
@@ -77,6 +87,56 @@ type Server struct {
 
 	// Shared gRPC client connection for SSE endpoints (reused across all SSE streams).
 	sseClientConn *grpc.ClientConn
+
+	// Paths of SSE endpoints, see WithSSEStream.
+	sseEndpoints []string
+
+	// Open streams, see WithStreamLimits.
+	streams *streamTracker
+
+	// API versions and their routes, see WithAPIVersion.
+	versions *apiVersions
+
+	// Admin listener address, see WithAdminAddress.
+	adminHost     string
+	adminPort     int
+	adminListener net.Listener
+
+	// Whether admin handlers and services are served publicly when there is no
+	// admin listener, see WithAdminOnPublic.
+	adminOnPublic bool
+
+	// Admin HTTP handlers and GRPC services, nil if there is no admin listener.
+	adminMux        *http.ServeMux
+	adminGRPCServer *grpc.Server
+	adminHTTPServer *http.Server
+
+	// HTTP routes, excluding the GRPC Gateway.
+	routes []Route
+
+	// GRPC interceptors, in the order they run.
+	interceptors []Interceptor
+
+	// Whether to warm up plugins after the listener starts, and whether warmup
+	// has completed.
+	backgroundWarmup bool
+	ready            atomic.Bool
+
+	// Locality sent in response headers, zero if disabled. See WithLocality.
+	localityHeaders serverutil.Locality
+
+	// Proxies trusted to report the client, and whether the listener accepts
+	// PROXY protocol headers. See WithTrustedProxies and WithProxyProtocol.
+	trustedProxies serverutil.TrustedProxies
+	proxyProtocol  bool
+
+	// Selects the certificate of a virtual host by SNI, nil if no virtual host
+	// has one. See HostTLS.
+	hostCertificates func(*tls.ClientHelloInfo) (*tls.Certificate, error)
+
+	// Whether to reload config files on change, see WithConfigWatch.
+	watchConfig    bool
+	configWatchers []*file.File
 }
 
 // GRPCServer returns the GRPC Service Registrar for use with service
@@ -103,7 +163,10 @@ func (s *Server) GatewayArgs() (ctx context.Context, mux *runtime.ServeMux, endp
 	ctx = s.baseContext
 	mux = s.grpcGateway
 	opts = s.gatewayOpts
-	if s.host == "0.0.0.0" {
+	if s.listener != nil {
+		// In-memory listeners are reached via a custom dialer, see WithListener.
+		endpoint = "passthrough:///" + s.listener.Addr().String()
+	} else if s.host == "0.0.0.0" {
 		// Special case of 0.0.0.0 is a listen-only IP, and must be changed into
 		// localhost in a containerized environment.
 		endpoint = fmt.Sprintf("localhost:%d", s.port)
@@ -187,6 +250,14 @@ func (s *Server) Start() error {
 		return err
 	}
 
+	// Warm up plugins before accepting traffic, unless configured to do so in
+	// the background.
+	if !s.backgroundWarmup {
+		if err := s.warmup(ctx); err != nil {
+			return err
+		}
+	}
+
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
 	s.httpServer = &http.Server{
 		Addr:              addr,
@@ -197,13 +268,23 @@ func (s *Server) Start() error {
 	}
 
 	var done = make(chan struct{})
+	var signaled atomic.Bool
 	var err error
 
+	var gracefulStop = make(chan os.Signal, 1)
+	signal.Notify(gracefulStop, syscall.SIGTERM)
+	signal.Notify(gracefulStop, syscall.SIGINT)
+	defer signal.Stop(gracefulStop)
+	stopped := make(chan struct{})
+	defer close(stopped)
 	go func() {
-		var gracefulStop = make(chan os.Signal, 1)
-		signal.Notify(gracefulStop, syscall.SIGTERM)
-		signal.Notify(gracefulStop, syscall.SIGINT)
-		sig := <-gracefulStop
+		var sig os.Signal
+		select {
+		case sig = <-gracefulStop:
+		case <-stopped:
+			return
+		}
+		signaled.Store(true)
 		logging.Infof(s.baseContext, "👋 Graceful shutdown triggered... (sig %+v)\n", sig)
 		if serr := s.Shutdown(); serr != nil {
 			logging.Errorw(s.baseContext, "❌ Shutdown error", "error", serr)
@@ -211,54 +292,132 @@ func (s *Server) Start() error {
 		close(done)
 	}()
 
-	// TODO: Allow bufconn to be injected to allow tests to avoid the network.
-	var listenCfg net.ListenConfig
-	ln, err := listenCfg.Listen(ctx, "tcp", addr)
-	if err != nil {
-		return fmt.Errorf("failed to listen: %w", err)
+	ln := s.listener
+	if ln == nil {
+		var listenCfg net.ListenConfig
+		ln, err = listenCfg.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+	} else {
+		addr = ln.Addr().String()
+	}
+	if s.proxyProtocol {
+		ln = &proxyListener{Listener: ln, trusted: s.trustedProxies}
 	}
 	defer ln.Close()
 
-	grpcHandler := s.grpcServer
-	httpHandler := gziphandler.GzipHandler(s.httpMux)
-	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.ProtoMajor == 2 && strings.Contains(r.Header.Get("Content-Type"), "application/grpc") {
-			grpcHandler.ServeHTTP(w, r)
-		} else {
-			httpHandler.ServeHTTP(w, r)
-		}
-	})
+	if err := s.startAdmin(ctx); err != nil {
+		return err
+	}
 
+	if s.backgroundWarmup {
+		go func() {
+			if werr := s.warmup(ctx); werr != nil {
+				logging.Errorw(s.baseContext, "❌ Warmup failed, server will not report ready", "error", werr)
+			}
+		}()
+	}
+
+	if s.watchConfig {
+		s.watchConfigFiles()
+	}
+
+	s.logDescription(s.baseContext)
+	var httpHandler http.Handler = compressResponses(s.httpMux)
+	if s.trustedProxies != nil {
+		httpHandler = forwardedMiddleware(httpHandler, s.trustedProxies)
+	}
+	handler := localityMiddleware(grpcOrHTTPHandler(s.grpcServer, httpHandler), s.localityHeaders)
 	if s.certFile != "" {
-		s.httpServer.Handler = handler
-		s.httpServer.TLSConfig = safeTLSConfig()
 		logging.Infof(s.baseContext, "🚀  Listening for traffic on https://%s\n", addr)
-		err = s.httpServer.ServeTLS(ln, s.certFile, s.keyFile)
 	} else {
-		// Enable cleartext HTTP/2 (h2c) alongside HTTP/1.1 so that gRPC traffic
-		// works without TLS. This replaces the deprecated h2c.NewHandler wrapper.
-		protocols := new(http.Protocols)
-		protocols.SetHTTP1(true)
-		protocols.SetUnencryptedHTTP2(true)
-		s.httpServer.Handler = handler
-		s.httpServer.Protocols = protocols
 		logging.Infof(s.baseContext, "🚀  Listening for traffic on http://%s\n", addr)
-		err = s.httpServer.Serve(ln)
 	}
+	err = s.serve(s.httpServer, ln, handler)
 
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err // The server wasn't shutdown gracefully.
 	}
 
-	<-done
+	// When shutdown was triggered by a signal, wait for it to complete. A direct
+	// call to Shutdown blocks its caller instead.
+	if signaled.Load() {
+		<-done
+	}
 	return nil
 }
 
+// Content types which are gzipped, when larger than a minimum size. Other types,
+// such as images and archives, are usually compressed already, while streams of
+// newline-delimited JSON and Server-Sent Events must be written as soon as
+// they're flushed, rather than buffered until the size is known.
+var compressibleContentTypes = []string{
+	"application/javascript",
+	"application/json",
+	"application/manifest+json",
+	"application/problem+json",
+	"application/wasm",
+	"application/xml",
+	"image/svg+xml",
+	"text/css",
+	"text/csv",
+	"text/html",
+	"text/javascript",
+	"text/markdown",
+	"text/plain",
+	"text/xml",
+}
+
+// compressResponses gzips responses with compressible content types for
+// clients which accept it.
+func compressResponses(h http.Handler) http.Handler {
+	wrap, err := gziphandler.GzipHandlerWithOpts(gziphandler.ContentTypes(compressibleContentTypes))
+	if err != nil {
+		panic(err) // Only returned for invalid options.
+	}
+	return wrap(h)
+}
+
+// grpcOrHTTPHandler routes GRPC requests to grpcHandler and everything else to
+// httpHandler.
+func grpcOrHTTPHandler(grpcHandler, httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.Contains(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcHandler.ServeHTTP(w, r)
+		} else {
+			httpHandler.ServeHTTP(w, r)
+		}
+	})
+}
+
+// serve accepts connections on ln, using TLS if configured.
+func (s *Server) serve(srv *http.Server, ln net.Listener, handler http.Handler) error {
+	srv.Handler = handler
+	if s.certFile != "" {
+		srv.TLSConfig = safeTLSConfig()
+		srv.TLSConfig.GetCertificate = s.hostCertificates
+		s.clientAuth.apply(srv.TLSConfig)
+		return srv.ServeTLS(ln, s.certFile, s.keyFile)
+	}
+	// Enable cleartext HTTP/2 (h2c) alongside HTTP/1.1 so that gRPC traffic
+	// works without TLS. This replaces the deprecated h2c.NewHandler wrapper.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Protocols = protocols
+	return srv.Serve(ln)
+}
+
 // Shutdown gracefully shuts down the server with a 2s timeout.
 func (s *Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(s.baseContext, shutdownGracePeriod)
 	defer cancel()
 
+	// Report as not ready so load balancers stop sending traffic while draining.
+	s.ready.Store(false)
+	s.stopConfigWatch()
+
 	err := s.httpServer.Shutdown(ctx)
 	if err != nil {
 		logging.Infof(s.baseContext, "❌ HTTP shutdown error: %v", err)
@@ -267,6 +426,13 @@ func (s *Server) Shutdown() error {
 	}
 	s.httpServer = nil
 
+	if s.adminHTTPServer != nil {
+		if aerr := s.adminHTTPServer.Shutdown(ctx); aerr != nil {
+			logging.Infof(s.baseContext, "❌ Admin HTTP shutdown error: %v", aerr)
+		}
+		s.adminHTTPServer = nil
+	}
+
 	// Close the shared SSE client connection if it exists
 	if s.sseClientConn != nil {
 		if cerr := s.sseClientConn.Close(); cerr != nil {
@@ -284,4 +450,12 @@ func (s *Server) Shutdown() error {
 	return err
 }
 
+// ServerFromContext returns the server associated with the context. The server
+// is available to plugins during Init and to HTTP handlers, since it is
+// attached to the base context used for incoming requests.
+func ServerFromContext(ctx context.Context) (*Server, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Server)
+	return s, ok
+}
+
 type ctxKey struct{}
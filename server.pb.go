@@ -7,6 +7,8 @@
 package prefab
 
 import (
+	errors "github.com/dpup/prefab/errors"
+	code "google.golang.org/genproto/googleapis/rpc/code"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
@@ -23,21 +25,167 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Describes the deprecation of a method.
+type Deprecation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Date the method was deprecated, as YYYY-MM-DD or an RFC 3339 timestamp.
+	Since string `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	// Date after which the method may be removed, as YYYY-MM-DD or an RFC 3339
+	// timestamp. Dates without a time are the start of the day, in UTC.
+	Sunset string `protobuf:"bytes,2,opt,name=sunset,proto3" json:"sunset,omitempty"`
+	// Hint at what to use instead, for example a method name. Paths and URLs are
+	// also sent as a Link with rel="successor-version".
+	Replacement string `protobuf:"bytes,3,opt,name=replacement,proto3" json:"replacement,omitempty"`
+	// URL of documentation about the deprecation, sent as a Link with
+	// rel="deprecation".
+	Link          string `protobuf:"bytes,4,opt,name=link,proto3" json:"link,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Deprecation) Reset() {
+	*x = Deprecation{}
+	mi := &file_server_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Deprecation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Deprecation) ProtoMessage() {}
+
+func (x *Deprecation) ProtoReflect() protoreflect.Message {
+	mi := &file_server_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Deprecation.ProtoReflect.Descriptor instead.
+func (*Deprecation) Descriptor() ([]byte, []int) {
+	return file_server_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Deprecation) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+func (x *Deprecation) GetSunset() string {
+	if x != nil {
+		return x.Sunset
+	}
+	return ""
+}
+
+func (x *Deprecation) GetReplacement() string {
+	if x != nil {
+		return x.Replacement
+	}
+	return ""
+}
+
+func (x *Deprecation) GetLink() string {
+	if x != nil {
+		return x.Link
+	}
+	return ""
+}
+
+// An error declared in an error catalog.
+type ErrorSpec struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// GRPC status code of the error.
+	Code code.Code `protobuf:"varint,1,opt,name=code,proto3,enum=google.rpc.Code" json:"code,omitempty"`
+	// HTTP status of the error, when it differs from the one derived from code.
+	HttpStatus int32 `protobuf:"varint,2,opt,name=http_status,json=httpStatus,proto3" json:"http_status,omitempty"`
+	// User presentable message, with fmt verbs for the arguments passed when the
+	// error is created. The template is translated before it's formatted when
+	// errors are localized, so use indexed verbs such as %[1]s if translations
+	// may reorder them.
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorSpec) Reset() {
+	*x = ErrorSpec{}
+	mi := &file_server_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorSpec) ProtoMessage() {}
+
+func (x *ErrorSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_server_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorSpec.ProtoReflect.Descriptor instead.
+func (*ErrorSpec) Descriptor() ([]byte, []int) {
+	return file_server_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ErrorSpec) GetCode() code.Code {
+	if x != nil {
+		return x.Code
+	}
+	return code.Code(0)
+}
+
+func (x *ErrorSpec) GetHttpStatus() int32 {
+	if x != nil {
+		return x.HttpStatus
+	}
+	return 0
+}
+
+func (x *ErrorSpec) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
 // Overrides the default error gateway error response to include a code_name
 // for convenience.
 type CustomErrorResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
-	CodeName      string                 `protobuf:"bytes,2,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
-	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
-	Details       []*anypb.Any           `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Code     int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	CodeName string                 `protobuf:"bytes,2,opt,name=code_name,json=codeName,proto3" json:"code_name,omitempty"`
+	Message  string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Details  []*anypb.Any           `protobuf:"bytes,4,rep,name=details,proto3" json:"details,omitempty"`
+	// Failures combined into the error, for example by a batch operation. See
+	// errors.Aggregate.
+	Errors        []*errors.ItemError `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *CustomErrorResponse) Reset() {
 	*x = CustomErrorResponse{}
-	mi := &file_server_proto_msgTypes[0]
+	mi := &file_server_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -49,7 +197,7 @@ func (x *CustomErrorResponse) String() string {
 func (*CustomErrorResponse) ProtoMessage() {}
 
 func (x *CustomErrorResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_server_proto_msgTypes[0]
+	mi := &file_server_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -62,7 +210,7 @@ func (x *CustomErrorResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CustomErrorResponse.ProtoReflect.Descriptor instead.
 func (*CustomErrorResponse) Descriptor() ([]byte, []int) {
-	return file_server_proto_rawDescGZIP(), []int{0}
+	return file_server_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CustomErrorResponse) GetCode() int32 {
@@ -93,6 +241,13 @@ func (x *CustomErrorResponse) GetDetails() []*anypb.Any {
 	return nil
 }
 
+func (x *CustomErrorResponse) GetErrors() []*errors.ItemError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
 var file_server_proto_extTypes = []protoimpl.ExtensionInfo{
 	{
 		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
@@ -102,6 +257,70 @@ var file_server_proto_extTypes = []protoimpl.ExtensionInfo{
 		Tag:           "bytes,50001,opt,name=csrf_mode",
 		Filename:      "server.proto",
 	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50002,
+		Name:          "prefab.cache_control",
+		Tag:           "bytes,50002,opt,name=cache_control",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: ([]string)(nil),
+		Field:         50003,
+		Name:          "prefab.response_headers",
+		Tag:           "bytes,50003,rep,name=response_headers",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50004,
+		Name:          "prefab.json_emit_unpopulated",
+		Tag:           "varint,50004,opt,name=json_emit_unpopulated",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50005,
+		Name:          "prefab.json_use_proto_names",
+		Tag:           "varint,50005,opt,name=json_use_proto_names",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*bool)(nil),
+		Field:         50006,
+		Name:          "prefab.json_discard_unknown",
+		Tag:           "varint,50006,opt,name=json_discard_unknown",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*string)(nil),
+		Field:         50007,
+		Name:          "prefab.sse_path",
+		Tag:           "bytes,50007,opt,name=sse_path",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*Deprecation)(nil),
+		Field:         50008,
+		Name:          "prefab.deprecation",
+		Tag:           "bytes,50008,opt,name=deprecation",
+		Filename:      "server.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
+		ExtensionType: (*ErrorSpec)(nil),
+		Field:         50101,
+		Name:          "prefab.error",
+		Tag:           "bytes,50101,opt,name=error",
+		Filename:      "server.proto",
+	},
 }
 
 // Extension fields to descriptorpb.MethodOptions.
@@ -119,19 +338,104 @@ var (
 	//
 	// optional string csrf_mode = 50001;
 	E_CsrfMode = &file_server_proto_extTypes[0]
+	// Value of the Cache-Control header for successful GRPC Gateway responses,
+	// for example "no-store" or "public, max-age=300".
+	//
+	// optional string cache_control = 50002;
+	E_CacheControl = &file_server_proto_extTypes[1]
+	// Additional headers for successful GRPC Gateway responses, in the form
+	// "Name: value". Headers set by the handler take precedence.
+	//
+	// repeated string response_headers = 50003;
+	E_ResponseHeaders = &file_server_proto_extTypes[2]
+	// Overrides whether zero values are included in GRPC Gateway JSON responses.
+	// Defaults to the server's marshal options, see WithJSONMarshalOptions.
+	//
+	// optional bool json_emit_unpopulated = 50004;
+	E_JsonEmitUnpopulated = &file_server_proto_extTypes[3]
+	// Overrides whether GRPC Gateway JSON responses use the proto field names,
+	// e.g. "user_id", rather than lowerCamelCase, e.g. "userId".
+	//
+	// optional bool json_use_proto_names = 50005;
+	E_JsonUseProtoNames = &file_server_proto_extTypes[4]
+	// Overrides whether unknown fields in JSON request bodies are ignored rather
+	// than rejected. Request bodies are decoded before the method is known, so
+	// this applies to every method that uses the same request message.
+	//
+	// optional bool json_discard_unknown = 50006;
+	E_JsonDiscardUnknown = &file_server_proto_extTypes[5]
+	// Serves a server-streaming method as Server-Sent Events at the given path,
+	// for example "/notes/{id}/updates". Path parameters and query parameters
+	// populate fields of the request with the same name.
+	//
+	// protoc-gen-prefab generates a server option which registers the endpoint,
+	// e.g. `WithStreamUpdatesSSE()`.
+	//
+	// optional string sse_path = 50007;
+	E_SsePath = &file_server_proto_extTypes[6]
+	// Marks the method as deprecated. Responses carry Deprecation, Sunset, and
+	// Link headers, and calls are logged with the caller's identity and counted
+	// in the "deprecations" expvar. With `server.enforceSunset`, calls after the
+	// sunset date fail with Unimplemented and HTTP 410 Gone.
+	//
+	//   option (prefab.deprecation) = {
+	//     sunset: "2025-06-30"
+	//     replacement: "notes.v2.NoteService.ListNotes"
+	//     link: "https://example.com/docs/migrate-notes-v2"
+	//   };
+	//
+	// optional prefab.Deprecation deprecation = 50008;
+	E_Deprecation = &file_server_proto_extTypes[7]
+)
+
+// Extension fields to descriptorpb.EnumValueOptions.
+var (
+	// Declares an error in an error catalog. The enum's values name the errors,
+	// and protoc-gen-prefab generates an `Err(args...)` method which creates the
+	// error, see errors.FromCatalog:
+	//
+	//   enum NoteError {
+	//     NOTE_ERROR_UNSPECIFIED = 0;
+	//     NOTE_NOT_FOUND = 1 [(prefab.error) = {
+	//       code: NOT_FOUND
+	//       message: "Note %s was not found"
+	//     }];
+	//   }
+	//
+	// optional prefab.ErrorSpec error = 50101;
+	E_Error = &file_server_proto_extTypes[8]
 )
 
 var File_server_proto protoreflect.FileDescriptor
 
 const file_server_proto_rawDesc = "" +
 	"\n" +
-	"\fserver.proto\x12\x06prefab\x1a\x19google/protobuf/any.proto\x1a google/protobuf/descriptor.proto\"\x90\x01\n" +
+	"\fserver.proto\x12\x06prefab\x1a\x19google/protobuf/any.proto\x1a google/protobuf/descriptor.proto\x1a\x15google/rpc/code.proto\x1a\x13errors/errors.proto\"q\n" +
+	"\vDeprecation\x12\x14\n" +
+	"\x05since\x18\x01 \x01(\tR\x05since\x12\x16\n" +
+	"\x06sunset\x18\x02 \x01(\tR\x06sunset\x12 \n" +
+	"\vreplacement\x18\x03 \x01(\tR\vreplacement\x12\x12\n" +
+	"\x04link\x18\x04 \x01(\tR\x04link\"l\n" +
+	"\tErrorSpec\x12$\n" +
+	"\x04code\x18\x01 \x01(\x0e2\x10.google.rpc.CodeR\x04code\x12\x1f\n" +
+	"\vhttp_status\x18\x02 \x01(\x05R\n" +
+	"httpStatus\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"\xbb\x01\n" +
 	"\x13CustomErrorResponse\x12\x12\n" +
 	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x1b\n" +
 	"\tcode_name\x18\x02 \x01(\tR\bcodeName\x12\x18\n" +
 	"\amessage\x18\x03 \x01(\tR\amessage\x12.\n" +
-	"\adetails\x18\x04 \x03(\v2\x14.google.protobuf.AnyR\adetails:=\n" +
-	"\tcsrf_mode\x12\x1e.google.protobuf.MethodOptions\x18ц\x03 \x01(\tR\bcsrfModeB\x18Z\x16github.com/dpup/prefabb\x06proto3"
+	"\adetails\x18\x04 \x03(\v2\x14.google.protobuf.AnyR\adetails\x12)\n" +
+	"\x06errors\x18\x05 \x03(\v2\x11.prefab.ItemErrorR\x06errors:=\n" +
+	"\tcsrf_mode\x12\x1e.google.protobuf.MethodOptions\x18ц\x03 \x01(\tR\bcsrfMode:E\n" +
+	"\rcache_control\x12\x1e.google.protobuf.MethodOptions\x18҆\x03 \x01(\tR\fcacheControl:K\n" +
+	"\x10response_headers\x12\x1e.google.protobuf.MethodOptions\x18ӆ\x03 \x03(\tR\x0fresponseHeaders:T\n" +
+	"\x15json_emit_unpopulated\x12\x1e.google.protobuf.MethodOptions\x18Ԇ\x03 \x01(\bR\x13jsonEmitUnpopulated:Q\n" +
+	"\x14json_use_proto_names\x12\x1e.google.protobuf.MethodOptions\x18Ն\x03 \x01(\bR\x11jsonUseProtoNames:R\n" +
+	"\x14json_discard_unknown\x12\x1e.google.protobuf.MethodOptions\x18ֆ\x03 \x01(\bR\x12jsonDiscardUnknown:;\n" +
+	"\bsse_path\x12\x1e.google.protobuf.MethodOptions\x18׆\x03 \x01(\tR\assePath:W\n" +
+	"\vdeprecation\x12\x1e.google.protobuf.MethodOptions\x18؆\x03 \x01(\v2\x13.prefab.DeprecationR\vdeprecation:L\n" +
+	"\x05error\x12!.google.protobuf.EnumValueOptions\x18\xb5\x87\x03 \x01(\v2\x11.prefab.ErrorSpecR\x05errorB\x18Z\x16github.com/dpup/prefabb\x06proto3"
 
 var (
 	file_server_proto_rawDescOnce sync.Once
@@ -145,20 +449,37 @@ func file_server_proto_rawDescGZIP() []byte {
 	return file_server_proto_rawDescData
 }
 
-var file_server_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_server_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_server_proto_goTypes = []any{
-	(*CustomErrorResponse)(nil),        // 0: prefab.CustomErrorResponse
-	(*anypb.Any)(nil),                  // 1: google.protobuf.Any
-	(*descriptorpb.MethodOptions)(nil), // 2: google.protobuf.MethodOptions
+	(*Deprecation)(nil),                   // 0: prefab.Deprecation
+	(*ErrorSpec)(nil),                     // 1: prefab.ErrorSpec
+	(*CustomErrorResponse)(nil),           // 2: prefab.CustomErrorResponse
+	(code.Code)(0),                        // 3: google.rpc.Code
+	(*anypb.Any)(nil),                     // 4: google.protobuf.Any
+	(*errors.ItemError)(nil),              // 5: prefab.ItemError
+	(*descriptorpb.MethodOptions)(nil),    // 6: google.protobuf.MethodOptions
+	(*descriptorpb.EnumValueOptions)(nil), // 7: google.protobuf.EnumValueOptions
 }
 var file_server_proto_depIdxs = []int32{
-	1, // 0: prefab.CustomErrorResponse.details:type_name -> google.protobuf.Any
-	2, // 1: prefab.csrf_mode:extendee -> google.protobuf.MethodOptions
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	1, // [1:2] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	3,  // 0: prefab.ErrorSpec.code:type_name -> google.rpc.Code
+	4,  // 1: prefab.CustomErrorResponse.details:type_name -> google.protobuf.Any
+	5,  // 2: prefab.CustomErrorResponse.errors:type_name -> prefab.ItemError
+	6,  // 3: prefab.csrf_mode:extendee -> google.protobuf.MethodOptions
+	6,  // 4: prefab.cache_control:extendee -> google.protobuf.MethodOptions
+	6,  // 5: prefab.response_headers:extendee -> google.protobuf.MethodOptions
+	6,  // 6: prefab.json_emit_unpopulated:extendee -> google.protobuf.MethodOptions
+	6,  // 7: prefab.json_use_proto_names:extendee -> google.protobuf.MethodOptions
+	6,  // 8: prefab.json_discard_unknown:extendee -> google.protobuf.MethodOptions
+	6,  // 9: prefab.sse_path:extendee -> google.protobuf.MethodOptions
+	6,  // 10: prefab.deprecation:extendee -> google.protobuf.MethodOptions
+	7,  // 11: prefab.error:extendee -> google.protobuf.EnumValueOptions
+	0,  // 12: prefab.deprecation:type_name -> prefab.Deprecation
+	1,  // 13: prefab.error:type_name -> prefab.ErrorSpec
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	12, // [12:14] is the sub-list for extension type_name
+	3,  // [3:12] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_server_proto_init() }
@@ -172,8 +493,8 @@ func file_server_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_server_proto_rawDesc), len(file_server_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
-			NumExtensions: 1,
+			NumMessages:   3,
+			NumExtensions: 9,
 			NumServices:   0,
 		},
 		GoTypes:           file_server_proto_goTypes,
@@ -0,0 +1,42 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressResponses(t *testing.T) {
+	body := strings.Repeat("x", 2048)
+	h := compressResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", r.URL.Query().Get("type"))
+		_, _ = w.Write([]byte(body))
+	}))
+
+	tests := []struct {
+		contentType string
+		compressed  bool
+	}{
+		{"application/json", true},
+		{"text/html; charset=utf-8", true},
+		{"application/x-ndjson", false},
+		{"text/event-stream", false},
+		{"image/png", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/?type="+url.QueryEscape(tt.contentType), nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if tt.compressed {
+			assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"), tt.contentType)
+		} else {
+			assert.Empty(t, rec.Header().Get("Content-Encoding"), tt.contentType)
+			assert.Equal(t, body, rec.Body.String(), tt.contentType)
+		}
+	}
+}
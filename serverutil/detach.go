@@ -0,0 +1,66 @@
+package serverutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"google.golang.org/grpc"
+)
+
+type detachedKey struct{}
+
+// detached records the request's deadline at the time it was detached.
+type detached struct {
+	deadline time.Time
+	ok       bool
+}
+
+// DetachContext returns a context for work which outlives the request, such as
+// a goroutine started by a handler. Values are retained, so identity, config,
+// locale and tracked log fields remain available, but the context isn't
+// canceled when the request ends and has no deadline.
+//
+// The logger is scoped with a `detached` field, so fields tracked by the
+// background work don't leak into the request's log entry. Functions which
+// write to the response, such as SendHeader, return errors.
+func DetachContext(ctx context.Context) context.Context {
+	d := &detached{}
+	d.deadline, d.ok = ctx.Deadline()
+	ctx = context.WithValue(context.WithoutCancel(ctx), detachedKey{}, d)
+	if logger := logging.FromContext(ctx); logger != nil {
+		ctx = logging.With(ctx, logger.With("detached", true))
+	}
+	return grpc.NewContextWithServerTransportStream(ctx, nil)
+}
+
+// DetachContextWithTimeout detaches the context, see DetachContext, and bounds
+// the background work by a timeout instead.
+func DetachContextWithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(DetachContext(ctx), timeout)
+}
+
+// IsDetached returns true if the context was created by DetachContext.
+func IsDetached(ctx context.Context) bool {
+	_, ok := ctx.Value(detachedKey{}).(*detached)
+	return ok
+}
+
+// DetachedDeadline returns the deadline the request had when its context was
+// detached. ok is false if the context isn't detached or had no deadline.
+func DetachedDeadline(ctx context.Context) (deadline time.Time, ok bool) {
+	if d, found := ctx.Value(detachedKey{}).(*detached); found {
+		return d.deadline, d.ok
+	}
+	return time.Time{}, false
+}
+
+// ReattachDeadline applies the deadline the request had when its context was
+// detached, for background work that should give up when the caller would
+// have. If there was no deadline, the context is only made cancelable.
+func ReattachDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := DetachedDeadline(ctx); ok {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithCancel(ctx)
+}
@@ -0,0 +1,81 @@
+package serverutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+type testKey struct{}
+
+func TestDetachContext(t *testing.T) {
+	logger := logging.NewDevLogger()
+	ctx := logging.With(t.Context(), logger)
+	ctx = context.WithValue(ctx, testKey{}, "identity")
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &mockServerTransportStream{})
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+
+	detached := DetachContext(ctx)
+	cancel()
+
+	require.Error(t, ctx.Err())
+	require.NoError(t, detached.Err(), "detached context shouldn't be canceled with the request")
+	_, hasDeadline := detached.Deadline()
+	assert.False(t, hasDeadline)
+	assert.Equal(t, "identity", detached.Value(testKey{}))
+	assert.True(t, IsDetached(detached))
+	assert.False(t, IsDetached(ctx))
+
+	// The logger is scoped, so tracked fields don't leak into the request's.
+	assert.NotSame(t, logger, logging.FromContext(detached))
+	logging.Track(detached, "job", "resize")
+	assert.Same(t, logger, logging.FromContext(ctx))
+
+	// The response has been sent, so can't be modified.
+	require.Error(t, SendHeader(detached, "x-test", "1"))
+	require.NoError(t, SendHeader(ctx, "x-test", "1"))
+}
+
+func TestReattachDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(t.Context(), deadline)
+	defer cancel()
+
+	detached := DetachContext(ctx)
+	got, ok := DetachedDeadline(detached)
+	require.True(t, ok)
+	assert.Equal(t, deadline, got)
+
+	reattached, cancel := ReattachDeadline(detached)
+	defer cancel()
+	got, ok = reattached.Deadline()
+	require.True(t, ok)
+	assert.Equal(t, deadline, got)
+
+	// Without a deadline, the context is only made cancelable.
+	reattached, cancel = ReattachDeadline(DetachContext(t.Context()))
+	_, ok = reattached.Deadline()
+	assert.False(t, ok)
+	cancel()
+	require.ErrorIs(t, reattached.Err(), context.Canceled)
+
+	_, ok = DetachedDeadline(ctx)
+	assert.False(t, ok, "context isn't detached")
+}
+
+func TestDetachContextWithTimeout(t *testing.T) {
+	parent, cancelParent := context.WithCancel(t.Context())
+	ctx, cancel := DetachContextWithTimeout(parent, time.Minute)
+	defer cancel()
+	cancelParent()
+
+	require.NoError(t, ctx.Err())
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
@@ -0,0 +1,256 @@
+package serverutil
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Forwarded describes the original request made by a client, as reported by
+// trusted proxies in the Forwarded, X-Forwarded-For, X-Forwarded-Proto, and
+// X-Forwarded-Host headers.
+type Forwarded struct {
+	// IP address of the client.
+	ClientIP string
+
+	// Scheme the client used, "http" or "https".
+	Scheme string
+
+	// Host the client requested, which may include a port.
+	Host string
+}
+
+// Address returns the scheme and host the client requested, e.g.
+// "https://example.com", or "" if the host isn't known.
+func (f Forwarded) Address() string {
+	if f.Host == "" {
+		return ""
+	}
+	scheme := f.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+	return scheme + "://" + f.Host
+}
+
+// WithForwarded adds the resolved details of the original request to the
+// context.
+func WithForwarded(ctx context.Context, f Forwarded) context.Context {
+	return context.WithValue(ctx, forwardedKey{}, f)
+}
+
+// ForwardedFromContext returns the details of the original request. ok is
+// false if no trusted proxies are configured, or the request didn't come from
+// one, in which case only the ClientIP is populated.
+func ForwardedFromContext(ctx context.Context) (f Forwarded, ok bool) {
+	if f, ok := ctx.Value(forwardedKey{}).(Forwarded); ok {
+		return f, true
+	}
+	f.ClientIP = ClientIP(ctx)
+	trusted := CurrentTrustedProxies()
+	if trusted == nil || !trusted.Trusts(peerIP(ctx)) {
+		return f, false
+	}
+	// Gateway requests carry the host and scheme resolved by the HTTP server.
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get("x-forwarded-host"); len(v) > 0 {
+		f.Host = v[0]
+	}
+	if v := md.Get("x-forwarded-proto"); len(v) > 0 {
+		f.Scheme = v[0]
+	}
+	return f, f.Host != ""
+}
+
+type forwardedKey struct{}
+
+// TrustedProxies is a list of networks whose proxies are trusted to report the
+// client's address, scheme, and host.
+type TrustedProxies []netip.Prefix
+
+// ParseTrustedProxies parses a list of CIDR ranges or IP addresses.
+func ParseTrustedProxies(values []string) (TrustedProxies, error) {
+	t := make(TrustedProxies, 0, len(values))
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if strings.Contains(v, "/") {
+			p, err := netip.ParsePrefix(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+			}
+			t = append(t, p.Masked())
+			continue
+		}
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+		}
+		t = append(t, netip.PrefixFrom(ip, ip.BitLen()))
+	}
+	return t, nil
+}
+
+// Trusts reports whether a connection from addr is trusted. Loopback
+// addresses, and in-memory connections that don't have an IP address, are
+// always trusted, since the GRPC Gateway connects to the server locally.
+func (t TrustedProxies) Trusts(addr string) bool {
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return true
+	}
+	ip = ip.Unmap()
+	if ip.IsLoopback() {
+		return true
+	}
+	return t.contains(ip)
+}
+
+func (t TrustedProxies) contains(ip netip.Addr) bool {
+	for _, p := range t {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// client walks a chain of hops from right to left, skipping trusted proxies,
+// and returns the index of the client. Walking stops at a hop which isn't a
+// valid IP, such as "unknown", returning the hop to its right.
+func (t TrustedProxies) client(hops []string) int {
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(hops[i])
+		if err != nil {
+			return min(i+1, len(hops)-1)
+		}
+		if ip = ip.Unmap(); !ip.IsLoopback() && !t.contains(ip) {
+			return i
+		}
+	}
+	return 0
+}
+
+var trustedProxies atomic.Pointer[TrustedProxies]
+
+// SetTrustedProxies sets the proxies which are trusted by ClientIP and
+// ForwardedFromContext. It is called by prefab.New, pass nil to only use the
+// address of the connection.
+func SetTrustedProxies(t TrustedProxies) {
+	if t == nil {
+		trustedProxies.Store(nil)
+		return
+	}
+	trustedProxies.Store(&t)
+}
+
+// CurrentTrustedProxies returns the trusted proxies, or nil if none are
+// configured.
+func CurrentTrustedProxies() TrustedProxies {
+	if t := trustedProxies.Load(); t != nil {
+		return *t
+	}
+	return nil
+}
+
+// ResolveForwarded determines the client, scheme, and host of an HTTP request.
+// Forwarding headers are only used when the request was made by a trusted
+// proxy, and the Forwarded header takes precedence over X-Forwarded-*.
+func ResolveForwarded(r *http.Request, trusted TrustedProxies) Forwarded {
+	peer := hostOnly(r.RemoteAddr)
+	f := Forwarded{ClientIP: peer, Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		f.Scheme = "https"
+	}
+	if !trusted.Trusts(peer) {
+		return f
+	}
+
+	if elems := parseForwardedHeader(r.Header.Values("Forwarded")); len(elems) > 0 {
+		chain := make([]string, 0, len(elems)+1)
+		for _, e := range elems {
+			chain = append(chain, hostOnly(e["for"]))
+		}
+		chain = append(chain, peer)
+		i := trusted.client(chain)
+		f.ClientIP = chain[i]
+		// Use the scheme and host seen by the proxy the client connected to.
+		for _, e := range elems[min(i, len(elems)-1):] {
+			if e["proto"] != "" || e["host"] != "" {
+				if e["proto"] != "" {
+					f.Scheme = strings.ToLower(e["proto"])
+				}
+				if e["host"] != "" {
+					f.Host = e["host"]
+				}
+				break
+			}
+		}
+		return f
+	}
+
+	var chain []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		for hop := range strings.SplitSeq(v, ",") {
+			chain = append(chain, hostOnly(strings.TrimSpace(hop)))
+		}
+	}
+	chain = append(chain, peer)
+	f.ClientIP = chain[trusted.client(chain)]
+	if v := firstValue(r.Header.Get("X-Forwarded-Proto")); v != "" {
+		f.Scheme = strings.ToLower(v)
+	}
+	if v := firstValue(r.Header.Get("X-Forwarded-Host")); v != "" {
+		f.Host = v
+	}
+	return f
+}
+
+// parseForwardedHeader parses RFC 7239 Forwarded headers into a list of
+// elements, one per hop, with lowercase parameter names and unquoted values.
+func parseForwardedHeader(values []string) []map[string]string {
+	var elems []map[string]string
+	for _, v := range values {
+		for elem := range strings.SplitSeq(v, ",") {
+			e := map[string]string{}
+			for pair := range strings.SplitSeq(elem, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				e[strings.ToLower(k)] = strings.Trim(v, `"`)
+			}
+			if len(e) > 0 {
+				elems = append(elems, e)
+			}
+		}
+	}
+	return elems
+}
+
+// hostOnly strips the port, and the brackets around IPv6 addresses.
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+func firstValue(v string) string {
+	first, _, _ := strings.Cut(v, ",")
+	return strings.TrimSpace(first)
+}
+
+// peerIP returns the address of the connection a GRPC request arrived on.
+func peerIP(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return hostOnly(p.Addr.String())
+	}
+	return ""
+}
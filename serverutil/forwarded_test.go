@@ -0,0 +1,137 @@
+package serverutil
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.1 ", "2001:db8::/32"})
+	require.NoError(t, err)
+	assert.True(t, trusted.Trusts("10.1.2.3"))
+	assert.True(t, trusted.Trusts("192.0.2.1"))
+	assert.False(t, trusted.Trusts("192.0.2.2"))
+	assert.True(t, trusted.Trusts("2001:db8::1"))
+	assert.True(t, trusted.Trusts("::ffff:10.0.0.1"), "mapped IPv4 addresses should match")
+	assert.True(t, trusted.Trusts("127.0.0.1"), "loopback is always trusted")
+	assert.True(t, trusted.Trusts("bufconn"), "in-memory connections are always trusted")
+	assert.False(t, trusted.Trusts("203.0.113.7"))
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	require.Error(t, err)
+	_, err = ParseTrustedProxies([]string{"proxy.internal"})
+	require.Error(t, err)
+}
+
+func TestResolveForwarded(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		expected   Forwarded
+	}{
+		{
+			name:       "Untrusted peer",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.com"},
+			expected:   Forwarded{ClientIP: "203.0.113.7", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "X-Forwarded-For",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1, 203.0.113.7, 10.0.0.1", "X-Forwarded-Proto": "HTTPS", "X-Forwarded-Host": "api.example.com"},
+			expected:   Forwarded{ClientIP: "203.0.113.7", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "All hops trusted",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.1"},
+			expected:   Forwarded{ClientIP: "10.0.0.3", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "No headers",
+			remoteAddr: "10.0.0.2:5000",
+			expected:   Forwarded{ClientIP: "10.0.0.2", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded",
+			remoteAddr: "10.0.0.2:5000",
+			headers: map[string]string{
+				"Forwarded":       `for=198.51.100.1, for="[2001:db8:cafe::17]:4711";proto=https;host=api.example.com, for=10.0.0.1`,
+				"X-Forwarded-For": "192.0.2.9",
+			},
+			expected: Forwarded{ClientIP: "2001:db8:cafe::17", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "Forwarded unknown",
+			remoteAddr: "10.0.0.2:5000",
+			headers:    map[string]string{"Forwarded": `for=unknown;proto=https, for=10.0.0.1`},
+			expected:   Forwarded{ClientIP: "10.0.0.1", Scheme: "http", Host: "example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			assert.Equal(t, tt.expected, ResolveForwarded(r, trusted))
+		})
+	}
+}
+
+func TestForwardedAddress(t *testing.T) {
+	assert.Equal(t, "https://example.com", Forwarded{Scheme: "https", Host: "example.com"}.Address())
+	assert.Equal(t, "http://example.com:8080", Forwarded{Host: "example.com:8080"}.Address())
+	assert.Empty(t, Forwarded{Scheme: "https"}.Address())
+}
+
+func TestClientIP_trustedProxies(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	SetTrustedProxies(trusted)
+	t.Cleanup(func() { SetTrustedProxies(nil) })
+
+	withPeer := func(ip string) *peer.Peer {
+		return &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}}
+	}
+	md := metadata.Pairs("x-forwarded-for", "198.51.100.1, 203.0.113.7, 10.0.0.1")
+
+	t.Run("TrustedPeer", func(t *testing.T) {
+		ctx := peer.NewContext(metadata.NewIncomingContext(t.Context(), md), withPeer("10.0.0.2"))
+		assert.Equal(t, "203.0.113.7", ClientIP(ctx))
+	})
+
+	t.Run("UntrustedPeer", func(t *testing.T) {
+		ctx := peer.NewContext(metadata.NewIncomingContext(t.Context(), md), withPeer("192.0.2.5"))
+		assert.Equal(t, "192.0.2.5", ClientIP(ctx))
+	})
+
+	t.Run("Gateway", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+			"x-forwarded-for", "203.0.113.7",
+			"x-forwarded-host", "api.example.com",
+			"x-forwarded-proto", "https",
+		))
+		ctx = peer.NewContext(ctx, withPeer("127.0.0.1"))
+		f, ok := ForwardedFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, Forwarded{ClientIP: "203.0.113.7", Scheme: "https", Host: "api.example.com"}, f)
+	})
+
+	t.Run("Resolved", func(t *testing.T) {
+		ctx := WithForwarded(metadata.NewIncomingContext(t.Context(), md), Forwarded{ClientIP: "198.51.100.9"})
+		assert.Equal(t, "198.51.100.9", ClientIP(ctx))
+	})
+}
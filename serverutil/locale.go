@@ -0,0 +1,60 @@
+package serverutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+)
+
+// WithLocale adds the negotiated locale, a BCP 47 language tag such as
+// "en-GB", to the context.
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale negotiated for the request, or an empty
+// string if no negotiation has taken place. See plugins/locale.
+func LocaleFromContext(ctx context.Context) string {
+	l, _ := ctx.Value(localeKey{}).(string)
+	return l
+}
+
+// WithTimezone adds the client's timezone to the context.
+func WithTimezone(ctx context.Context, loc *time.Location) context.Context {
+	return context.WithValue(ctx, timezoneKey{}, loc)
+}
+
+// TimezoneFromContext returns the client's timezone, or UTC if it isn't known.
+func TimezoneFromContext(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(timezoneKey{}).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// WithLocalizer adds a function which translates messages into the request's
+// locale to the context.
+func WithLocalizer(ctx context.Context, l errors.Localizer) context.Context {
+	return context.WithValue(ctx, localizerKey{}, l)
+}
+
+// Localize translates msg into the locale negotiated for the request. It
+// returns msg unchanged if there's no localizer or no translation.
+func Localize(ctx context.Context, msg string) string {
+	l, _ := ctx.Value(localizerKey{}).(errors.Localizer)
+	locale := LocaleFromContext(ctx)
+	if l == nil || locale == "" {
+		return msg
+	}
+	if translated := l(locale, msg); translated != "" {
+		return translated
+	}
+	return msg
+}
+
+type localeKey struct{}
+
+type localizerKey struct{}
+
+type timezoneKey struct{}
@@ -0,0 +1,80 @@
+package serverutil
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Response headers which identify the deployment that served a request, see
+// Locality.
+const (
+	RegionHeader   = "X-Prefab-Region"
+	ZoneHeader     = "X-Prefab-Zone"
+	InstanceHeader = "X-Prefab-Instance"
+)
+
+// Locality describes where the server is deployed, which helps to debug
+// multi-region deployments. It is configured with the `server.locality` config
+// keys or prefab.WithLocality.
+type Locality struct {
+	// Region the server runs in, e.g. "us-east1".
+	Region string
+
+	// Zone within the region, e.g. "us-east1-b".
+	Zone string
+
+	// Identifier of the server instance, such as a hostname or pod name.
+	Instance string
+}
+
+// IsZero reports whether no locality is set.
+func (l Locality) IsZero() bool {
+	return l == Locality{}
+}
+
+// Fields returns the locality as key/value pairs, omitting empty values. Keys
+// are "region", "zone", and "instance", which suits loggers, event metadata,
+// and audit entries.
+func (l Locality) Fields() map[string]string {
+	m := map[string]string{}
+	if l.Region != "" {
+		m["region"] = l.Region
+	}
+	if l.Zone != "" {
+		m["zone"] = l.Zone
+	}
+	if l.Instance != "" {
+		m["instance"] = l.Instance
+	}
+	return m
+}
+
+// SetHeaders adds the locality to response headers, omitting empty values.
+func (l Locality) SetHeaders(h http.Header) {
+	if l.Region != "" {
+		h.Set(RegionHeader, l.Region)
+	}
+	if l.Zone != "" {
+		h.Set(ZoneHeader, l.Zone)
+	}
+	if l.Instance != "" {
+		h.Set(InstanceHeader, l.Instance)
+	}
+}
+
+var locality atomic.Pointer[Locality]
+
+// SetLocality sets the locality of the process. It is called by prefab.New,
+// and is only needed by code which runs without a server.
+func SetLocality(l Locality) {
+	locality.Store(&l)
+}
+
+// CurrentLocality returns the locality of the process, or the zero value if
+// none is configured.
+func CurrentLocality() Locality {
+	if l := locality.Load(); l != nil {
+		return *l
+	}
+	return Locality{}
+}
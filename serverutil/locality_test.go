@@ -0,0 +1,25 @@
+package serverutil
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocality(t *testing.T) {
+	t.Cleanup(func() { SetLocality(Locality{}) })
+
+	assert.True(t, CurrentLocality().IsZero())
+
+	l := Locality{Region: "us-east1", Instance: "web-7f9c"}
+	SetLocality(l)
+	assert.Equal(t, l, CurrentLocality())
+	assert.Equal(t, map[string]string{"region": "us-east1", "instance": "web-7f9c"}, l.Fields())
+
+	h := http.Header{}
+	l.SetHeaders(h)
+	assert.Equal(t, "us-east1", h.Get(RegionHeader))
+	assert.Equal(t, "web-7f9c", h.Get(InstanceHeader))
+	assert.NotContains(t, h, ZoneHeader, "empty values are omitted")
+}
@@ -76,3 +76,20 @@ func HttpMetadataAnnotator(_ context.Context, r *http.Request) metadata.MD {
 	md[MetadataHTTPPrefix+"method"] = r.Method
 	return metadata.New(md)
 }
+
+// RequestIDHeader is the header, and metadata key, used to correlate requests
+// across services.
+const RequestIDHeader = "x-request-id"
+
+// RequestID returns the ID of the current request, as supplied by the caller
+// via the X-Request-ID header or metadata, or "" if none was supplied.
+func RequestID(ctx context.Context) string {
+	if v := HTTPHeader(ctx, RequestIDHeader); v != "" {
+		return v
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(RequestIDHeader); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package serverutil
+
+import (
+	"context"
+	"strings"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestInfo describes the client that made a request. The IP and UserAgent
+// are always available when the transport provides them, the remaining fields
+// are only populated when an enrichment plugin is registered.
+type RequestInfo struct {
+	// IP address of the client, see ClientIP.
+	IP string
+
+	// Raw User-Agent of the client.
+	UserAgent string
+
+	// ISO 3166-1 alpha-2 country code that the IP resolved to.
+	Country string
+
+	// Autonomous System Number and organization that the IP resolved to.
+	ASN   uint
+	ASOrg string
+
+	// Device information parsed from the User-Agent.
+	Device Device
+}
+
+// Device describes the client device, as inferred from the User-Agent.
+type Device struct {
+	Type    string // desktop, mobile, tablet, bot, or "" if unknown
+	OS      string
+	Browser string
+}
+
+// WithRequestInfo adds request info to the context.
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info stored in the context. If no
+// enrichment has taken place, a RequestInfo containing just the client IP and
+// User-Agent is returned and ok is false.
+func RequestInfoFromContext(ctx context.Context) (info RequestInfo, ok bool) {
+	if v, ok := ctx.Value(requestInfoKey{}).(RequestInfo); ok {
+		return v, true
+	}
+	return RequestInfo{IP: ClientIP(ctx), UserAgent: UserAgent(ctx)}, false
+}
+
+type requestInfoKey struct{}
+
+// ClientIP returns the IP address of the client that made the request.
+//
+// The X-Forwarded-For chain is walked from the right, skipping trusted
+// proxies, and is ignored entirely unless the connection came from a trusted
+// proxy. Loopback and in-process connections, such as the GRPC Gateway's, are
+// always trusted, and other proxies can be trusted with
+// prefab.WithTrustedProxies. Otherwise the address of the connection is used,
+// so the result can't be spoofed by the client.
+func ClientIP(ctx context.Context) string {
+	if f, ok := ctx.Value(forwardedKey{}).(Forwarded); ok && f.ClientIP != "" {
+		return f.ClientIP
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	peer := peerIP(ctx)
+	trusted := CurrentTrustedProxies()
+	if peer != "" && !trusted.Trusts(peer) {
+		return peer
+	}
+	var chain []string
+	for _, v := range md.Get("x-forwarded-for") {
+		for hop := range strings.SplitSeq(v, ",") {
+			chain = append(chain, hostOnly(strings.TrimSpace(hop)))
+		}
+	}
+	if peer != "" {
+		chain = append(chain, peer)
+	}
+	if len(chain) == 0 {
+		return ""
+	}
+	return chain[trusted.client(chain)]
+}
+
+// UserAgent returns the User-Agent of the client that made the request.
+func UserAgent(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(runtime.MetadataPrefix + "user-agent"); len(v) > 0 {
+		return v[0]
+	}
+	if v := md.Get("user-agent"); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
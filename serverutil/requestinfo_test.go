@@ -0,0 +1,59 @@
+package serverutil
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func TestClientIP(t *testing.T) {
+	t.Run("ForwardedFor", func(t *testing.T) {
+		// The gateway appends the address of the HTTP client.
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-forwarded-for", "203.0.113.7, 10.0.0.1"))
+		assert.Equal(t, "10.0.0.1", ClientIP(ctx))
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000}})
+		assert.Equal(t, "10.0.0.1", ClientIP(ctx))
+	})
+
+	t.Run("SpoofedForwardedFor", func(t *testing.T) {
+		ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.2"), Port: 5000}})
+		assert.Equal(t, "198.51.100.2", ClientIP(ctx))
+	})
+
+	t.Run("Peer", func(t *testing.T) {
+		ctx := peer.NewContext(t.Context(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("198.51.100.2"), Port: 5000}})
+		assert.Equal(t, "198.51.100.2", ClientIP(ctx))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		assert.Empty(t, ClientIP(t.Context()))
+	})
+}
+
+func TestUserAgent(t *testing.T) {
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs(
+		"grpcgateway-user-agent", "Mozilla/5.0",
+		"user-agent", "grpc-go/1.0",
+	))
+	assert.Equal(t, "Mozilla/5.0", UserAgent(ctx))
+
+	ctx = metadata.NewIncomingContext(t.Context(), metadata.Pairs("user-agent", "grpc-go/1.0"))
+	assert.Equal(t, "grpc-go/1.0", UserAgent(ctx))
+}
+
+func TestRequestInfoFromContext(t *testing.T) {
+	ctx := metadata.NewIncomingContext(t.Context(), metadata.Pairs("x-forwarded-for", "203.0.113.7"))
+
+	info, ok := RequestInfoFromContext(ctx)
+	assert.False(t, ok)
+	assert.Equal(t, "203.0.113.7", info.IP)
+
+	ctx = WithRequestInfo(ctx, RequestInfo{IP: "203.0.113.7", Country: "NZ"})
+	info, ok = RequestInfoFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "NZ", info.Country)
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"google.golang.org/grpc"
@@ -63,7 +64,9 @@ func ParseCookies(headers ...string) map[string]*http.Cookie {
 	return cookies
 }
 
-// MethodOption queries the value of a proto option for a GRPC method.
+// MethodOption queries the value of a proto option for a GRPC method. Values
+// are cached per method and option, so only the first call for each walks the
+// descriptors.
 //
 // TODO: Consider creating an interceptor which injects the MethodDescriptor
 // into the context. Then use methods which query options from the context, such
@@ -73,37 +76,91 @@ func ParseCookies(headers ...string) map[string]*http.Cookie {
 //
 //	ok, value, err := MethodOption(info, SomeProto.E_Option)
 func MethodOption(info *grpc.UnaryServerInfo, ext protoreflect.ExtensionType) (any, bool) {
+	key := methodOptionKey{method: info.FullMethod, ext: ext}
+	if v, ok := methodOptions.Load(key); ok {
+		o := v.(methodOptionValue)
+		return o.value, o.ok
+	}
 	name := strings.ReplaceAll(info.FullMethod, "/", ".")
 	name = strings.TrimPrefix(name, ".")
 	methodDesc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
 	if err != nil {
 		panic("unexpected error accessing method descriptor for " + name + ": " + err.Error())
 	}
+	var o methodOptionValue
 	opts, _ := methodDesc.Options().(*descriptorpb.MethodOptions)
 	if proto.HasExtension(opts, ext) {
-		return proto.GetExtension(opts, ext), true
+		o = methodOptionValue{value: proto.GetExtension(opts, ext), ok: true}
 	}
-	return nil, false
+	methodOptions.Store(key, o)
+	return o.value, o.ok
+}
+
+// methodOptions caches method option values, keyed by full method name and
+// option.
+var methodOptions sync.Map
+
+type methodOptionKey struct {
+	method string
+	ext    protoreflect.ExtensionType
+}
+
+type methodOptionValue struct {
+	value any
+	ok    bool
 }
 
 // FieldOption queries a request proto and returns all fields which have the
-// option set.
+// option set. The tagged fields of each message type are cached, so only the
+// first call for each walks the descriptor.
 func FieldOption(msg proto.Message, ext protoreflect.ExtensionType) ([]*FieldOptionValue, bool) {
-	var results []*FieldOptionValue
 	m := msg.ProtoReflect()
-	fields := m.Descriptor().Fields()
+	tagged := taggedFieldsFor(m.Descriptor(), ext)
+	if len(tagged) == 0 {
+		return nil, false
+	}
+	results := make([]*FieldOptionValue, len(tagged))
+	for i, f := range tagged {
+		results[i] = &FieldOptionValue{
+			FieldName:   f.name,
+			FieldValue:  m.Get(f.fd).Interface(),
+			OptionValue: f.option,
+		}
+	}
+	return results, true
+}
+
+// taggedFields caches the fields of each message type which have an option
+// set, keyed by message descriptor and option.
+var taggedFields sync.Map
+
+type taggedFieldKey struct {
+	msg protoreflect.MessageDescriptor
+	ext protoreflect.ExtensionType
+}
+
+type taggedField struct {
+	fd     protoreflect.FieldDescriptor
+	name   string
+	option any
+}
+
+func taggedFieldsFor(desc protoreflect.MessageDescriptor, ext protoreflect.ExtensionType) []taggedField {
+	key := taggedFieldKey{msg: desc, ext: ext}
+	if v, ok := taggedFields.Load(key); ok {
+		return v.([]taggedField)
+	}
+	var tagged []taggedField
+	fields := desc.Fields()
 	for i := range fields.Len() {
 		fd := fields.Get(i)
 		opts, _ := fd.Options().(*descriptorpb.FieldOptions)
 		if proto.HasExtension(opts, ext) {
-			results = append(results, &FieldOptionValue{
-				FieldName:   string(fd.Name()),
-				FieldValue:  m.Get(fd).Interface(),
-				OptionValue: proto.GetExtension(opts, ext),
-			})
+			tagged = append(tagged, taggedField{fd: fd, name: string(fd.Name()), option: proto.GetExtension(opts, ext)})
 		}
 	}
-	return results, len(results) > 0
+	taggedFields.Store(key, tagged)
+	return tagged
 }
 
 type FieldOptionValue struct {
@@ -2,13 +2,21 @@ package serverutil
 
 import (
 	"net/http"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 func TestSendCookie(t *testing.T) {
@@ -282,3 +290,94 @@ func (m *mockServerTransportStream) SendHeader(md metadata.MD) error {
 func (m *mockServerTransportStream) SetTrailer(md metadata.MD) error {
 	panic("Not implemented")
 }
+
+var registerOptionsProto = sync.OnceValue(func() protoreflect.FileDescriptor {
+	fieldOpts := &descriptorpb.FieldOptions{}
+	proto.SetExtension(fieldOpts, annotations.E_FieldBehavior, []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED})
+	methodOpts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(methodOpts, annotations.E_Http, &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/items/{id}"}})
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("serverutil_options_test.proto"),
+		Package:    proto.String("serverutiltest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/api/annotations.proto", "google/api/field_behavior.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("GetItemRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Options: fieldOpts},
+				{Name: proto.String("other"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()},
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ItemService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("GetItem"), InputType: proto.String(".serverutiltest.GetItemRequest"), OutputType: proto.String(".serverutiltest.GetItemRequest"), Options: methodOpts},
+				{Name: proto.String("ListItems"), InputType: proto.String(".serverutiltest.GetItemRequest"), OutputType: proto.String(".serverutiltest.GetItemRequest")},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		panic(err)
+	}
+	if err := protoregistry.GlobalFiles.RegisterFile(fd); err != nil {
+		panic(err)
+	}
+	return fd
+})
+
+func newGetItemRequest(id string) proto.Message {
+	desc := registerOptionsProto().Messages().ByName("GetItemRequest")
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(desc.Fields().ByName("id"), protoreflect.ValueOfString(id))
+	return msg
+}
+
+func TestMethodOption(t *testing.T) {
+	registerOptionsProto()
+	info := &grpc.UnaryServerInfo{FullMethod: "/serverutiltest.ItemService/GetItem"}
+
+	// The second lookup is served from the cache.
+	for range 2 {
+		v, ok := MethodOption(info, annotations.E_Http)
+		require.True(t, ok)
+		assert.Equal(t, "/items/{id}", v.(*annotations.HttpRule).GetGet())
+	}
+
+	_, ok := MethodOption(&grpc.UnaryServerInfo{FullMethod: "/serverutiltest.ItemService/ListItems"}, annotations.E_Http)
+	assert.False(t, ok)
+
+	assert.Panics(t, func() {
+		MethodOption(&grpc.UnaryServerInfo{FullMethod: "/serverutiltest.ItemService/Missing"}, annotations.E_Http)
+	})
+}
+
+func TestFieldOption(t *testing.T) {
+	for _, id := range []string{"a", "b"} {
+		v, ok := FieldOption(newGetItemRequest(id), annotations.E_FieldBehavior)
+		require.True(t, ok)
+		require.Len(t, v, 1)
+		assert.Equal(t, "id", v[0].FieldName)
+		assert.Equal(t, id, v[0].FieldValue, "values are read from each message")
+		assert.Equal(t, []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED}, v[0].OptionValue)
+	}
+
+	_, ok := FieldOption(newGetItemRequest("a"), annotations.E_ResourceReference)
+	assert.False(t, ok)
+}
+
+func BenchmarkMethodOption(b *testing.B) {
+	registerOptionsProto()
+	info := &grpc.UnaryServerInfo{FullMethod: "/serverutiltest.ItemService/GetItem"}
+	b.ReportAllocs()
+	for b.Loop() {
+		MethodOption(info, annotations.E_Http)
+	}
+}
+
+func BenchmarkFieldOption(b *testing.B) {
+	req := newGetItemRequest("a")
+	b.ReportAllocs()
+	for b.Loop() {
+		FieldOption(req, annotations.E_FieldBehavior)
+	}
+}
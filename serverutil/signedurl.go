@@ -0,0 +1,202 @@
+package serverutil
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// SignedURLParam is the query parameter that carries the signature of a signed
+// URL.
+const SignedURLParam = "pf-sig"
+
+var (
+	// ErrInvalidSignedURL is returned when a signed URL is malformed, has been
+	// tampered with, or was signed with an unknown key.
+	ErrInvalidSignedURL = errors.NewC("invalid signed url", codes.PermissionDenied)
+
+	// ErrExpiredSignedURL is returned when a signed URL has expired.
+	ErrExpiredSignedURL = errors.NewC("signed url has expired", codes.PermissionDenied)
+
+	// ErrNoSigningKey is returned when no signing keys are available in the
+	// context.
+	ErrNoSigningKey = errors.NewC("no signing key configured", codes.FailedPrecondition)
+)
+
+// SignedURL describes a verified signed URL.
+type SignedURL struct {
+	ID      string // Unique ID, used for revocation
+	Path    string
+	Expires time.Time
+	Claims  map[string]string
+
+	query string
+}
+
+// signedURLPayload is the signed portion of the URL.
+type signedURLPayload struct {
+	ID     string            `json:"id"`
+	Path   string            `json:"path"`
+	Query  string            `json:"query,omitempty"`
+	Exp    int64             `json:"exp"`
+	Claims map[string]string `json:"claims,omitempty"`
+}
+
+// WithSigningKeys adds the keys used to sign and verify signed URLs to the
+// context. URLs are signed with current, previous keys are accepted during
+// verification so that keys can be rotated without invalidating outstanding
+// links.
+func WithSigningKeys(ctx context.Context, current []byte, previous ...[]byte) context.Context {
+	keys := append([][]byte{current}, previous...)
+	return context.WithValue(ctx, signingKeysKey{}, keys)
+}
+
+type signingKeysKey struct{}
+
+// SigningKeys returns the keys added by WithSigningKeys, current key first.
+// Callers should derive a purpose specific key rather than using them
+// directly.
+func SigningKeys(ctx context.Context) [][]byte {
+	return signingKeysFromContext(ctx)
+}
+
+func signingKeysFromContext(ctx context.Context) [][]byte {
+	keys, _ := ctx.Value(signingKeysKey{}).([][]byte)
+	return keys
+}
+
+// SignURL returns a copy of path, which may include a query string, with a
+// signature that grants access to the path until expiry elapses. The query
+// string is covered by the signature, so can't be modified by the holder. Claims are
+// included in the signature and are available to the handler once verified.
+//
+// Example:
+//
+//	link, err := serverutil.SignURL(ctx, "/exports/123.csv", 15*time.Minute, map[string]string{"user": id.Subject})
+func SignURL(ctx context.Context, path string, expiry time.Duration, claims map[string]string) (string, error) {
+	keys := signingKeysFromContext(ctx)
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return "", ErrNoSigningKey
+	}
+
+	u, err := url.Parse(path)
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	payload, err := json.Marshal(&signedURLPayload{
+		ID:     hex.EncodeToString(id),
+		Path:   u.Path,
+		Query:  u.Query().Encode(),
+		Exp:    time.Now().Add(expiry).Unix(),
+		Claims: claims,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, 0)
+	}
+
+	enc := base64.RawURLEncoding.EncodeToString(payload)
+	q := u.Query()
+	q.Set(SignedURLParam, enc+"."+signature(keys[0], enc))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// VerifySignedURL checks that u carries a valid, unexpired signature for its
+// path, using the signing keys in the context.
+func VerifySignedURL(ctx context.Context, u *url.URL) (*SignedURL, error) {
+	keys := signingKeysFromContext(ctx)
+	if len(keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	s, err := ParseSignedURL(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	enc, sig, _ := strings.Cut(q.Get(SignedURLParam), ".")
+	q.Del(SignedURLParam)
+	valid := false
+	for _, key := range keys {
+		if len(key) > 0 && hmac.Equal([]byte(sig), []byte(signature(key, enc))) {
+			valid = true
+			break
+		}
+	}
+	if !valid || s.Path != u.Path || s.query != q.Encode() {
+		return nil, ErrInvalidSignedURL
+	}
+	if time.Now().After(s.Expires) {
+		return nil, ErrExpiredSignedURL
+	}
+	return s, nil
+}
+
+// ParseSignedURL decodes the signed URL payload without verifying the
+// signature. Useful for extracting the ID of a URL in order to revoke it.
+func ParseSignedURL(rawURL string) (*SignedURL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrInvalidSignedURL
+	}
+	enc, _, ok := strings.Cut(u.Query().Get(SignedURLParam), ".")
+	if !ok {
+		return nil, ErrInvalidSignedURL
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(enc)
+	if err != nil {
+		return nil, ErrInvalidSignedURL
+	}
+	p := &signedURLPayload{}
+	if err := json.Unmarshal(payload, p); err != nil {
+		return nil, ErrInvalidSignedURL
+	}
+	return &SignedURL{
+		ID:      p.ID,
+		Path:    p.Path,
+		Expires: time.Unix(p.Exp, 0),
+		Claims:  p.Claims,
+		query:   p.Query,
+	}, nil
+}
+
+// WithSignedURL adds a verified signed URL to the context.
+func WithSignedURL(ctx context.Context, s *SignedURL) context.Context {
+	return context.WithValue(ctx, signedURLKey{}, s)
+}
+
+// SignedURLFromContext returns the signed URL that authorized the current
+// request, if any.
+func SignedURLFromContext(ctx context.Context) (*SignedURL, bool) {
+	s, ok := ctx.Value(signedURLKey{}).(*SignedURL)
+	return s, ok
+}
+
+type signedURLKey struct{}
+
+// signature derives a purpose specific key, so that signed URLs can't be
+// confused with other values signed by the same server key.
+func signature(key []byte, payload string) string {
+	derived := hmac.New(sha256.New, key)
+	derived.Write([]byte("prefab-signed-url"))
+	mac := hmac.New(sha256.New, derived.Sum(nil))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,83 @@
+package serverutil
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignURL(t *testing.T) {
+	ctx := WithSigningKeys(t.Context(), []byte("current"))
+
+	signed, err := SignURL(ctx, "/exports/123.csv?format=csv", time.Minute, map[string]string{"user": "u1"})
+	require.NoError(t, err)
+
+	u, err := url.Parse(signed)
+	require.NoError(t, err)
+	assert.Equal(t, "/exports/123.csv", u.Path)
+	assert.Equal(t, "csv", u.Query().Get("format"))
+
+	s, err := VerifySignedURL(ctx, u)
+	require.NoError(t, err)
+	assert.Equal(t, "/exports/123.csv", s.Path)
+	assert.Equal(t, "u1", s.Claims["user"])
+	assert.NotEmpty(t, s.ID)
+
+	parsed, err := ParseSignedURL(signed)
+	require.NoError(t, err)
+	assert.Equal(t, s.ID, parsed.ID)
+}
+
+func TestVerifySignedURL(t *testing.T) {
+	ctx := WithSigningKeys(t.Context(), []byte("current"))
+	signed, err := SignURL(ctx, "/exports/123.csv?format=csv", time.Minute, nil)
+	require.NoError(t, err)
+
+	verify := func(ctx context.Context, raw string) error {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		_, err = VerifySignedURL(ctx, u)
+		return err
+	}
+
+	t.Run("DifferentPath", func(t *testing.T) {
+		err := verify(ctx, strings.Replace(signed, "123", "456", 1))
+		assert.True(t, errors.Is(err, ErrInvalidSignedURL))
+	})
+
+	t.Run("ModifiedQuery", func(t *testing.T) {
+		err := verify(ctx, strings.Replace(signed, "format=csv", "format=xls", 1))
+		assert.True(t, errors.Is(err, ErrInvalidSignedURL))
+	})
+
+	t.Run("UnknownKey", func(t *testing.T) {
+		err := verify(WithSigningKeys(t.Context(), []byte("other")), signed)
+		assert.True(t, errors.Is(err, ErrInvalidSignedURL))
+	})
+
+	t.Run("RotatedKey", func(t *testing.T) {
+		err := verify(WithSigningKeys(t.Context(), []byte("next"), []byte("current")), signed)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expired, err := SignURL(ctx, "/exports/123.csv", -time.Minute, nil)
+		require.NoError(t, err)
+		assert.True(t, errors.Is(verify(ctx, expired), ErrExpiredSignedURL))
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		assert.True(t, errors.Is(verify(ctx, "/exports/123.csv"), ErrInvalidSignedURL))
+	})
+
+	t.Run("NoKeys", func(t *testing.T) {
+		_, err := SignURL(t.Context(), "/exports/123.csv", time.Minute, nil)
+		assert.True(t, errors.Is(err, ErrNoSigningKey))
+	})
+}
@@ -2,14 +2,23 @@ package prefab
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/dpup/prefab/errors"
 	"github.com/dpup/prefab/logging"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/utilities"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/encoding/protojson"
@@ -35,6 +44,166 @@ type ClientStream[T proto.Message] interface {
 //	}
 type SSEStreamStarter[T proto.Message] func(ctx context.Context, params map[string]string, cc grpc.ClientConnInterface) (ClientStream[T], error)
 
+// SSEOption configures an endpoint registered with WithSSEStream.
+type SSEOption func(*sseOptions)
+
+type sseOptions struct {
+	guards       []SSEGuard
+	bufferSize   int
+	slowClient   SSESlowClientPolicy
+	writeTimeout time.Duration
+	replaySize   int
+	replayTTL    time.Duration
+	eventID      func(proto.Message) string
+	replay       *sseReplay
+	stats        *sseEndpointStats
+}
+
+// Defaults for SSE endpoints, see WithSSEBufferSize and WithSSEWriteTimeout.
+const (
+	defaultSSEBufferSize   = 16
+	defaultSSEWriteTimeout = 10 * time.Second
+)
+
+func newSSEOptions(path string, opts []SSEOption) *sseOptions {
+	o := &sseOptions{
+		bufferSize:   defaultSSEBufferSize,
+		writeTimeout: defaultSSEWriteTimeout,
+		stats:        sseStatsFor(path),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.replaySize > 0 {
+		o.replay = newSSEReplay(o.replaySize, o.replayTTL)
+	}
+	return o
+}
+
+// SSEGuard is called before an SSE stream is started, with the request context
+// and the params that will be passed to the SSEStreamStarter. Returning an error
+// rejects the request, with a status derived from the error's code.
+//
+// Guards may return a watch function, which is called in its own goroutine
+// while the stream is open. It should return nil once the context is done, or
+// an error to close the stream early, for example when the caller's session is
+// revoked.
+type SSEGuard func(ctx context.Context, params map[string]string) (watch func(ctx context.Context) error, err error)
+
+// WithSSEGuard adds a guard to an SSE endpoint. Guards are run in the order
+// they are added. SSE endpoints aren't served through the GRPC interceptors, so
+// guards are the place to authenticate and authorize callers, see
+// auth.RequireSSEIdentity and authz.AuthzPlugin.AuthorizeSSE.
+func WithSSEGuard(g SSEGuard) SSEOption {
+	return func(o *sseOptions) {
+		o.guards = append(o.guards, g)
+	}
+}
+
+// SSESlowClientPolicy determines what happens when a client doesn't read events
+// as fast as the stream produces them, and its send buffer is full.
+type SSESlowClientPolicy int
+
+const (
+	// SSEDisconnect closes the stream, so the client can reconnect and catch up.
+	// This is the default.
+	SSEDisconnect SSESlowClientPolicy = iota
+
+	// SSEDropOldest discards the oldest buffered event to make room for the new
+	// one. Suits streams where each event supersedes the last, such as progress
+	// updates.
+	SSEDropOldest
+
+	// SSEDropNewest discards new events until there is room in the buffer.
+	SSEDropNewest
+)
+
+// WithSSEBufferSize sets how many events are buffered for each connection
+// while they are written to the client. When the buffer is full the endpoint's
+// SSESlowClientPolicy applies. Defaults to 16.
+func WithSSEBufferSize(n int) SSEOption {
+	return func(o *sseOptions) {
+		o.bufferSize = max(n, 1)
+	}
+}
+
+// WithSSESlowClientPolicy sets what happens when a connection's send buffer is
+// full. Defaults to SSEDisconnect.
+func WithSSESlowClientPolicy(p SSESlowClientPolicy) SSEOption {
+	return func(o *sseOptions) {
+		o.slowClient = p
+	}
+}
+
+// WithSSEWriteTimeout sets how long writing a single event may take before the
+// client is considered stalled and the stream is closed. Zero disables the
+// deadline. Defaults to 10 seconds.
+func WithSSEWriteTimeout(d time.Duration) SSEOption {
+	return func(o *sseOptions) {
+		o.writeTimeout = d
+	}
+}
+
+// SSEStats is a snapshot of the streams served by an SSE endpoint.
+type SSEStats struct {
+	Path string `json:"path"`
+
+	// Streams which are currently open.
+	Open int64 `json:"open"`
+
+	// Events dropped because a client's send buffer was full.
+	Dropped int64 `json:"dropped"`
+
+	// Streams closed because a client fell behind or a write timed out.
+	SlowDisconnects int64 `json:"slowDisconnects"`
+
+	// Requests rejected because a stream limit was exceeded, see
+	// WithStreamLimits.
+	Rejected int64 `json:"rejected"`
+}
+
+type sseEndpointStats struct {
+	open            atomic.Int64
+	dropped         atomic.Int64
+	slowDisconnects atomic.Int64
+	rejected        atomic.Int64
+}
+
+var sseStats sync.Map // path -> *sseEndpointStats
+
+func init() {
+	expvar.Publish("sse", expvar.Func(func() any { return SSEStreamStats() }))
+}
+
+func sseStatsFor(path string) *sseEndpointStats {
+	s, _ := sseStats.LoadOrStore(path, &sseEndpointStats{})
+	return s.(*sseEndpointStats)
+}
+
+// SSEStreamStats returns stats for each SSE endpoint registered in the
+// process, sorted by path. They are also published to expvar as "sse", which
+// the debug plugin serves at /debug/vars.
+func SSEStreamStats() []SSEStats {
+	var out []SSEStats
+	sseStats.Range(func(k, v any) bool {
+		s := v.(*sseEndpointStats)
+		out = append(out, SSEStats{
+			Path:            k.(string),
+			Open:            s.open.Load(),
+			Dropped:         s.dropped.Load(),
+			SlowDisconnects: s.slowDisconnects.Load(),
+			Rejected:        s.rejected.Load(),
+		})
+		return true
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Path < out[j].Path })
+	return out
+}
+
+// errSSESlowClient is the cause of a stream being closed because the client
+// fell behind.
+var errSSESlowClient = errors.NewC("sse: client is too slow", codes.ResourceExhausted)
+
 // pathPattern represents a parsed path pattern with parameter extraction.
 type pathPattern struct {
 	pattern *regexp.Regexp
@@ -113,7 +282,7 @@ func (p *pathPattern) extractParams(path string) (map[string]string, bool) {
 }
 
 // createSSEHandler creates an HTTP handler that serves Server-Sent Events from a gRPC stream.
-func createSSEHandler[T proto.Message](pattern *pathPattern, starter SSEStreamStarter[T], s *Server) http.Handler {
+func createSSEHandler[T proto.Message](pattern *pathPattern, starter SSEStreamStarter[T], opts *sseOptions, s *Server) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
@@ -139,6 +308,31 @@ func createSSEHandler[T proto.Message](pattern *pathPattern, starter SSEStreamSt
 			}
 		}
 
+		// Run guards before anything is written, so they can reject the request.
+		var watches []func(context.Context) error
+		for _, guard := range opts.guards {
+			watch, err := guard(ctx, params)
+			if err != nil {
+				logging.Infow(ctx, "sse: request rejected", "path", r.URL.Path, "error", err)
+				WriteJSONError(w, r, err)
+				return
+			}
+			if watch != nil {
+				watches = append(watches, watch)
+			}
+		}
+
+		// Reserve a stream once the caller is known to be allowed one.
+		subject := logging.SubjectFromContext(ctx)
+		release, err := s.streams.acquire(subject)
+		if err != nil {
+			opts.stats.rejected.Add(1)
+			logging.Infow(ctx, "sse: stream limit exceeded", "path", r.URL.Path, "error", err)
+			s.streams.reject(w, r, err)
+			return
+		}
+		defer release()
+
 		// Set SSE headers
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Set("Cache-Control", "no-cache")
@@ -153,9 +347,20 @@ func createSSEHandler[T proto.Message](pattern *pathPattern, starter SSEStreamSt
 			return
 		}
 
-		// Create a context that will be cancelled when the client disconnects
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
+		// Create a context that will be cancelled when the client disconnects, or
+		// when a guard closes the stream.
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		for _, watch := range watches {
+			go func() {
+				if err := watch(ctx); err != nil {
+					cancel(err)
+				}
+			}()
+		}
+
+		// Find events the client missed, before the stream delivers new ones.
+		conn := newSSEConn(r, subject, opts)
 
 		// Use the shared gRPC client connection
 		cc := s.sseClientConn
@@ -164,16 +369,156 @@ func createSSEHandler[T proto.Message](pattern *pathPattern, starter SSEStreamSt
 		stream, err := starter(ctx, params, cc)
 		if err != nil {
 			logging.Errorw(ctx, "sse: failed to start stream", "error", err)
-			http.Error(w, fmt.Sprintf("Failed to start stream: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to start stream: %v", err), runtime.HTTPStatusFromCode(errors.Code(err)))
 			return
 		}
 
 		logging.Infow(ctx, "sse: client connected", "path", r.URL.Path, "params", params)
-		streamMessages(ctx, stream, r, w, flusher)
+		opts.stats.open.Add(1)
+		defer opts.stats.open.Add(-1)
+		streamMessages(ctx, cancel, stream, r, w, flusher, conn)
 	})
 }
 
-func streamMessages[T proto.Message](ctx context.Context, stream ClientStream[T], r *http.Request, w http.ResponseWriter, flusher http.Flusher) {
+// sseConn holds the state of a single SSE connection.
+type sseConn struct {
+	opts *sseOptions
+
+	// Stream the connection's events are retained under, see WithSSEReplay.
+	key string
+
+	// Events missed since the client's Last-Event-ID, and their IDs, which
+	// aren't sent again.
+	replayed []replayEvent
+	skip     map[string]bool
+}
+
+func newSSEConn(r *http.Request, subject string, opts *sseOptions) *sseConn {
+	c := &sseConn{opts: opts}
+	if opts.replay == nil {
+		return c
+	}
+	c.key = opts.replay.key(r, subject)
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		c.replayed = opts.replay.since(c.key, lastID)
+		c.skip = map[string]bool{lastID: true}
+		for _, e := range c.replayed {
+			c.skip[e.id] = true
+		}
+	}
+	return c
+}
+
+// format returns msg formatted as an event, with an ID if the endpoint assigns
+// them, and retains it for replay. Returns nil if the event was already
+// replayed to the client.
+func (c *sseConn) format(msg proto.Message, data []byte) []byte {
+	var id string
+	switch {
+	case c.opts.eventID != nil:
+		id = c.opts.eventID(msg)
+	case c.opts.replay != nil:
+		id = c.opts.replay.nextID(c.key)
+	}
+	if id == "" {
+		return []byte("data: " + string(data) + "\n\n")
+	}
+	if c.skip[id] {
+		return nil
+	}
+	event := []byte("id: " + id + "\ndata: " + string(data) + "\n\n")
+	if c.opts.replay != nil {
+		c.opts.replay.add(c.key, id, event)
+	}
+	return event
+}
+
+// streamMessages reads messages from the stream into a buffer, which is written
+// to the client as it is able to accept them. This way a stalled client only
+// holds up to the buffer size in memory, and the slow client policy decides
+// what happens to events that don't fit.
+func streamMessages[T proto.Message](ctx context.Context, cancel context.CancelCauseFunc, stream ClientStream[T], r *http.Request, w http.ResponseWriter, flusher http.Flusher, conn *sseConn) {
+	opts := conn.opts
+	events := make(chan []byte, opts.bufferSize)
+	var recvErr error
+	go func() {
+		defer close(events)
+		recvErr = receiveMessages(ctx, cancel, stream, events, conn)
+	}()
+	// If the client goes away, stop the reader and wait for it to finish, so it
+	// doesn't outlive the request.
+	defer func() {
+		cancel(nil)
+		for range events {
+		}
+	}()
+
+	rc := http.NewResponseController(w)
+	write := func(event []byte) bool {
+		if opts.writeTimeout > 0 {
+			if err := rc.SetWriteDeadline(time.Now().Add(opts.writeTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				logging.Errorw(ctx, "sse: failed to set write deadline", "error", err)
+			}
+		}
+		if _, err := w.Write(event); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				opts.stats.slowDisconnects.Add(1)
+				logging.Warnw(ctx, "sse: write timed out, closing stream", "path", r.URL.Path)
+			} else {
+				logging.Errorw(ctx, "sse: failed to write event", "error", err)
+			}
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if len(conn.replayed) > 0 {
+		logging.Infow(ctx, "sse: replaying missed events", "path", r.URL.Path, "count", len(conn.replayed))
+	}
+	for _, e := range conn.replayed {
+		if !write(e.data) {
+			return
+		}
+	}
+	for event := range events {
+		if ctx.Err() != nil {
+			break
+		}
+		if !write(event) {
+			return
+		}
+	}
+
+	// Wait for the reader to stop, so its error can be read.
+	for range events {
+	}
+	cancelled := ctx.Err() != nil
+	cause := context.Cause(ctx)
+	switch {
+	case errors.Is(recvErr, io.EOF):
+		logging.Infow(ctx, "sse: stream completed", "path", r.URL.Path)
+	case errors.Is(cause, errSSESlowClient):
+		// The client isn't keeping up, so don't try to write any more.
+	case cancelled && cause != nil && !errors.Is(cause, context.Canceled):
+		logging.Infow(ctx, "sse: stream closed by guard", "path", r.URL.Path, "error", cause)
+		fmt.Fprintf(w, ": error: %s\n\n", cause.Error())
+		flusher.Flush()
+	case cancelled:
+		// The client disconnected.
+	case recvErr != nil:
+		logging.Errorw(ctx, "sse: stream error", "error", recvErr)
+		// Send error as SSE comment (not visible to EventSource API but visible in raw stream)
+		fmt.Fprintf(w, ": error: %s\n\n", recvErr.Error())
+		flusher.Flush()
+	}
+}
+
+// receiveMessages formats messages from the stream as SSE events and buffers
+// them, applying the slow client policy when the buffer is full. It returns
+// the error which ended the stream.
+func receiveMessages[T proto.Message](ctx context.Context, cancel context.CancelCauseFunc, stream ClientStream[T], events chan []byte, conn *sseConn) error {
+	opts := conn.opts
 	// Marshal options for JSON conversion
 	marshaler := protojson.MarshalOptions{
 		EmitUnpopulated: true,
@@ -182,17 +527,8 @@ func streamMessages[T proto.Message](ctx context.Context, stream ClientStream[T]
 
 	for {
 		msg, err := stream.Recv()
-		if errors.Is(err, io.EOF) {
-			// Stream completed normally
-			logging.Infow(ctx, "sse: stream completed", "path", r.URL.Path)
-			return
-		}
 		if err != nil {
-			logging.Errorw(ctx, "sse: stream error", "error", err)
-			// Send error as SSE comment (not visible to EventSource API but visible in raw stream)
-			fmt.Fprintf(w, ": error: %s\n\n", err.Error())
-			flusher.Flush()
-			return
+			return err
 		}
 
 		// Convert proto message to JSON
@@ -201,15 +537,33 @@ func streamMessages[T proto.Message](ctx context.Context, stream ClientStream[T]
 			logging.Errorw(ctx, "sse: failed to marshal message", "error", err)
 			continue
 		}
-
-		// Write SSE event
-		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
-			logging.Errorw(ctx, "sse: failed to write event", "error", err)
-			return
+		event := conn.format(msg, data)
+		if event == nil {
+			continue
 		}
 
-		// Flush the data immediately
-		flusher.Flush()
+		select {
+		case events <- event:
+			continue
+		default:
+		}
+		switch opts.slowClient {
+		case SSEDisconnect:
+			opts.stats.slowDisconnects.Add(1)
+			logging.Warnw(ctx, "sse: send buffer full, closing stream", "bufferSize", opts.bufferSize)
+			cancel(errSSESlowClient)
+			return errSSESlowClient
+		case SSEDropOldest:
+			// Only this goroutine sends, so after removing an event there's room.
+			select {
+			case <-events:
+				opts.stats.dropped.Add(1)
+			default:
+			}
+			events <- event
+		case SSEDropNewest:
+			opts.stats.dropped.Add(1)
+		}
 	}
 }
 
@@ -240,12 +594,21 @@ func streamMessages[T proto.Message](ctx context.Context, stream ClientStream[T]
 // All stream management (reading, cancellation, error handling, SSE formatting) is handled automatically.
 //
 // Multiple SSE endpoints share a single gRPC client connection for efficiency.
-func WithSSEStream[T proto.Message](path string, starter SSEStreamStarter[T]) ServerOption {
+//
+// Streams bypass the server's GRPC interceptors, so aren't authenticated or
+// authorized unless guards are added with SSEOptions:
+//
+//	prefab.WithSSEStream("/notes/{id}/updates", starter,
+//	    auth.RequireSSEIdentity(),
+//	    authzPlugin.AuthorizeSSE("note", "notes.view", "id"),
+//	)
+func WithSSEStream[T proto.Message](path string, starter SSEStreamStarter[T], opts ...SSEOption) ServerOption {
 	return func(b *builder) {
 		pattern, err := parsePathPattern(path)
 		if err != nil {
 			panic(err)
 		}
+		sseOpts := newSSEOptions(path, opts)
 
 		// Capture the server reference to access the shared connection
 		var server *Server
@@ -255,6 +618,7 @@ func WithSSEStream[T proto.Message](path string, starter SSEStreamStarter[T]) Se
 		// 2. Stores the server reference for handlers
 		b.serverBuilders = append(b.serverBuilders, func(s *Server) {
 			server = s
+			s.sseEndpoints = append(s.sseEndpoints, path)
 
 			// Create the shared SSE client connection if this is the first SSE endpoint
 			if s.sseClientConn == nil {
@@ -273,9 +637,41 @@ func WithSSEStream[T proto.Message](path string, starter SSEStreamStarter[T]) Se
 			prefix: pattern.prefix,
 			httpHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				// Use the server's shared connection
-				h := createSSEHandler(pattern, starter, server)
+				h := createSSEHandler(pattern, starter, sseOpts, server)
 				h.ServeHTTP(w, r)
 			}),
 		})
 	}
 }
+
+// PopulateSSERequest sets fields of an SSE stream's request from the params
+// passed to its SSEStreamStarter. Path parameters set the field of the same
+// name, which may be nested, e.g. "note.id". Query parameters, prefixed with
+// "query.", are parsed the same way as by the GRPC Gateway, and are ignored if
+// they'd override a path parameter. Code generated by protoc-gen-prefab uses it
+// to build requests.
+//
+// Example:
+//
+//	req := &StreamRequest{}
+//	if err := prefab.PopulateSSERequest(req, params); err != nil {
+//	    return nil, err
+//	}
+func PopulateSSERequest(req proto.Message, params map[string]string) error {
+	query := url.Values{}
+	var pathFields [][]string
+	for key, value := range params {
+		if name, ok := strings.CutPrefix(key, "query."); ok {
+			query.Set(name, value)
+			continue
+		}
+		if err := runtime.PopulateFieldFromPath(req, key, value); err != nil {
+			return errors.Codef(codes.InvalidArgument, "sse: invalid path parameter %q: %v", key, err)
+		}
+		pathFields = append(pathFields, strings.Split(key, "."))
+	}
+	if err := runtime.PopulateQueryParameters(req, query, utilities.NewDoubleArray(pathFields)); err != nil {
+		return errors.Codef(codes.InvalidArgument, "sse: invalid query parameter: %v", err)
+	}
+	return nil
+}
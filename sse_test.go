@@ -4,10 +4,18 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
@@ -391,3 +399,174 @@ func TestSharedSSEConnection(t *testing.T) {
 
 	t.Log("✓ Shared connection cleanup works correctly")
 }
+
+func TestPopulateSSERequest(t *testing.T) {
+	req := &SetVerboseLoggingRequest{}
+	err := PopulateSSERequest(req, map[string]string{
+		"method":        "/notes.NotesService/StreamUpdates",
+		"query.method":  "/ignored",
+		"query.subject": "alice",
+		"query.enabled": "true",
+		"query.unknown": "ignored",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/notes.NotesService/StreamUpdates", req.GetMethod(), "path parameters take precedence")
+	assert.Equal(t, "alice", req.GetSubject())
+	assert.True(t, req.GetEnabled())
+
+	err = PopulateSSERequest(&SetVerboseLoggingRequest{}, map[string]string{"query.enabled": "maybe"})
+	assert.Equal(t, codes.InvalidArgument, errors.Code(err))
+}
+
+// blockingClientStream blocks until its context is done, like a GRPC stream
+// with no messages.
+type blockingClientStream struct {
+	ctx context.Context
+	grpc.ClientStream
+}
+
+func (b *blockingClientStream) Recv() (*wrapperspb.StringValue, error) {
+	<-b.ctx.Done()
+	return nil, status.FromContextError(b.ctx.Err()).Err()
+}
+
+func TestSSEGuards(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	pattern, err := parsePathPattern("/notes/{id}")
+	require.NoError(t, err)
+
+	started := false
+	starter := func(ctx context.Context, _ map[string]string, _ grpc.ClientConnInterface) (ClientStream[*wrapperspb.StringValue], error) {
+		started = true
+		return &blockingClientStream{ctx: ctx}, nil
+	}
+	serve := func(opts ...SSEOption) *httptest.ResponseRecorder {
+		o := newSSEOptions("/test/guards", opts)
+		started = false
+		rec := httptest.NewRecorder()
+		createSSEHandler(pattern, starter, o, &Server{}).ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, "/notes/123", nil))
+		return rec
+	}
+
+	var gotParams map[string]string
+	rec := serve(
+		WithSSEGuard(func(_ context.Context, params map[string]string) (func(context.Context) error, error) {
+			gotParams = params
+			return nil, nil
+		}),
+		WithSSEGuard(func(context.Context, map[string]string) (func(context.Context) error, error) {
+			return nil, errors.NewC("not allowed", codes.PermissionDenied)
+		}),
+	)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, map[string]string{"id": "123"}, gotParams)
+	assert.False(t, started, "stream shouldn't be started when a guard rejects the request")
+
+	rec = serve(WithSSEGuard(func(context.Context, map[string]string) (func(context.Context) error, error) {
+		return func(context.Context) error {
+			return errors.NewC("session revoked", codes.Unauthenticated)
+		}, nil
+	}))
+	assert.True(t, started)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, ": error: session revoked\n\n", rec.Body.String(), "watch errors close the stream")
+}
+
+// burstClientStream sends msgs as fast as they are read, except that the second
+// message waits until the first is being written to the client.
+type burstClientStream struct {
+	grpc.ClientStream
+	msgs    []string
+	writing chan struct{}
+	done    chan struct{}
+}
+
+func (b *burstClientStream) Recv() (*wrapperspb.StringValue, error) {
+	if len(b.msgs) == 0 {
+		close(b.done)
+		return nil, io.EOF
+	}
+	if len(b.msgs) == 4 {
+		<-b.writing
+	}
+	msg := b.msgs[0]
+	b.msgs = b.msgs[1:]
+	return wrapperspb.String(msg), nil
+}
+
+// stalledWriter blocks the first write until released, like a client which
+// has stopped reading.
+type stalledWriter struct {
+	*httptest.ResponseRecorder
+	writing chan struct{}
+	release chan struct{}
+	writes  int
+}
+
+func (s *stalledWriter) Write(b []byte) (int, error) {
+	s.writes++
+	if s.writes == 1 {
+		close(s.writing)
+		<-s.release
+	}
+	return s.ResponseRecorder.Write(b)
+}
+
+func TestSSESlowClients(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	pattern, err := parsePathPattern("/counter")
+	require.NoError(t, err)
+
+	serve := func(path string, policy SSESlowClientPolicy) (string, SSEStats) {
+		w := &stalledWriter{ResponseRecorder: httptest.NewRecorder(), writing: make(chan struct{}), release: make(chan struct{})}
+		stream := &burstClientStream{msgs: []string{"1", "2", "3", "4", "5"}, writing: w.writing, done: make(chan struct{})}
+		o := newSSEOptions(path, []SSEOption{WithSSEBufferSize(1), WithSSESlowClientPolicy(policy)})
+		dropped, disconnects := o.stats.dropped.Load(), o.stats.slowDisconnects.Load()
+		starter := func(context.Context, map[string]string, grpc.ClientConnInterface) (ClientStream[*wrapperspb.StringValue], error) {
+			return stream, nil
+		}
+
+		served := make(chan struct{})
+		go func() {
+			defer close(served)
+			createSSEHandler(pattern, starter, o, &Server{}).ServeHTTP(w, httptest.NewRequestWithContext(ctx, http.MethodGet, "/counter", nil))
+		}()
+		// Release the client once the stream has ended or been closed.
+		assert.Eventually(t, func() bool {
+			select {
+			case <-stream.done:
+				return true
+			default:
+				return o.stats.slowDisconnects.Load() > disconnects
+			}
+		}, time.Second, time.Millisecond)
+		close(w.release)
+		<-served
+
+		return w.Body.String(), SSEStats{
+			Path:            path,
+			Open:            o.stats.open.Load(),
+			Dropped:         o.stats.dropped.Load() - dropped,
+			SlowDisconnects: o.stats.slowDisconnects.Load() - disconnects,
+		}
+	}
+
+	body, stats := serve("/test/drop-newest", SSEDropNewest)
+	assert.Equal(t, "data: \"1\"\n\ndata: \"2\"\n\n", body)
+	assert.Equal(t, SSEStats{Path: "/test/drop-newest", Dropped: 3}, stats)
+
+	body, stats = serve("/test/drop-oldest", SSEDropOldest)
+	assert.Equal(t, "data: \"1\"\n\ndata: \"5\"\n\n", body)
+	assert.Equal(t, SSEStats{Path: "/test/drop-oldest", Dropped: 3}, stats)
+
+	body, stats = serve("/test/disconnect", SSEDisconnect)
+	assert.Equal(t, "data: \"1\"\n\n", body, "buffered events aren't written once the client is disconnected")
+	assert.Equal(t, SSEStats{Path: "/test/disconnect", SlowDisconnects: 1}, stats)
+
+	var found bool
+	for _, s := range SSEStreamStats() {
+		found = found || s.Path == "/test/disconnect"
+	}
+	assert.True(t, found, "stats should be reported for each endpoint")
+}
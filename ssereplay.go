@@ -0,0 +1,165 @@
+package prefab
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// WithSSEReplay retains the last size events sent on each stream, for up to
+// ttl, so that clients which reconnect with a Last-Event-ID header receive the
+// events they missed. The upstream GRPC stream doesn't need to support
+// resumption. A ttl of 0 defaults to 5 minutes.
+//
+// Events are retained separately for each path, query, and caller, so streams
+// for different users aren't mixed. Each event is sent with an ID, which is a
+// sequence number unless WithSSEEventID is used.
+//
+// Events are retained as each connection receives them, including events that
+// are dropped for slow clients. If several clients stream the same path at
+// once, set an ID with WithSSEEventID so that each event is only retained once.
+func WithSSEReplay(size int, ttl time.Duration) SSEOption {
+	return func(o *sseOptions) {
+		o.replaySize = size
+		o.replayTTL = ttl
+	}
+}
+
+// WithSSEEventID sets the ID of each event from its message, for example a
+// version or sequence number assigned by the service. IDs are sent to clients
+// as the event's "id" field. With WithSSEReplay, events are retained once per
+// ID, and events which have been replayed to a client aren't sent again when
+// the upstream stream delivers them.
+func WithSSEEventID(fn func(proto.Message) string) SSEOption {
+	return func(o *sseOptions) {
+		o.eventID = fn
+	}
+}
+
+// sseReplay holds the replay buffers for an endpoint, keyed by stream.
+type sseReplay struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu        sync.Mutex
+	rings     map[string]*replayRing
+	lastSweep time.Time
+}
+
+// Default time events are retained for, see WithSSEReplay.
+const defaultSSEReplayTTL = 5 * time.Minute
+
+func newSSEReplay(size int, ttl time.Duration) *sseReplay {
+	if ttl <= 0 {
+		ttl = defaultSSEReplayTTL
+	}
+	return &sseReplay{size: max(size, 1), ttl: ttl, now: time.Now, rings: map[string]*replayRing{}}
+}
+
+// key identifies a stream by its path, query, and caller.
+func (r *sseReplay) key(req *http.Request, subject string) string {
+	return req.URL.Path + "?" + req.URL.RawQuery + "\x00" + subject
+}
+
+// replayRing is a buffer of recent events on a single stream.
+type replayRing struct {
+	// Prefix for sequence IDs, so IDs from a discarded buffer aren't mistaken
+	// for IDs in its replacement.
+	epoch  string
+	seq    int64
+	events []replayEvent
+}
+
+type replayEvent struct {
+	id   string
+	data []byte
+	at   time.Time
+}
+
+// ring returns the buffer for a stream, creating it if needed. Must be called
+// with the lock held.
+func (r *sseReplay) ring(key string) *replayRing {
+	ring, ok := r.rings[key]
+	if !ok {
+		ring = &replayRing{epoch: strconv.FormatInt(r.now().UnixNano(), 36)}
+		r.rings[key] = ring
+	}
+	return ring
+}
+
+// nextID returns a sequence ID for the next event on a stream.
+func (r *sseReplay) nextID(key string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ring := r.ring(key)
+	ring.seq++
+	return ring.epoch + "-" + strconv.FormatInt(ring.seq, 10)
+}
+
+// add retains an event, unless one with the same ID is already retained.
+func (r *sseReplay) add(key, id string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.sweep(now)
+	ring := r.ring(key)
+	for _, e := range ring.events {
+		if e.id == id {
+			return
+		}
+	}
+	ring.events = append(ring.events, replayEvent{id: id, data: data, at: now})
+	if len(ring.events) > r.size {
+		ring.events = ring.events[len(ring.events)-r.size:]
+	}
+}
+
+// since returns the events retained after the event with ID lastID. Nothing is
+// returned if lastID is no longer retained, since it isn't known which events
+// were missed.
+func (r *sseReplay) since(key, lastID string) []replayEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.sweep(now)
+	ring, ok := r.rings[key]
+	if !ok {
+		return nil
+	}
+	for i, e := range ring.events {
+		if e.id != lastID {
+			continue
+		}
+		var out []replayEvent
+		for _, e := range ring.events[i+1:] {
+			if now.Sub(e.at) <= r.ttl {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// sweep discards expired events, and buffers with no events left, at most
+// every half TTL. Must be called with the lock held.
+func (r *sseReplay) sweep(now time.Time) {
+	if now.Sub(r.lastSweep) < r.ttl/2 {
+		return
+	}
+	r.lastSweep = now
+	for key, ring := range r.rings {
+		i := 0
+		for i < len(ring.events) && now.Sub(ring.events[i].at) > r.ttl {
+			i++
+		}
+		ring.events = ring.events[i:]
+		if len(ring.events) == 0 {
+			delete(r.rings, key)
+		}
+	}
+}
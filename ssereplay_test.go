@@ -0,0 +1,122 @@
+package prefab
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestSSEReplay_Buffer(t *testing.T) {
+	now := time.Now()
+	r := newSSEReplay(3, time.Minute)
+	r.now = func() time.Time { return now }
+
+	for _, id := range []string{"1", "2", "3", "3", "4"} {
+		r.add("k", id, []byte(id))
+	}
+	ids := func(events []replayEvent) []string {
+		var out []string
+		for _, e := range events {
+			out = append(out, e.id)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"3", "4"}, ids(r.since("k", "2")), "duplicate IDs are retained once")
+	assert.Empty(t, r.since("k", "1"), "evicted IDs replay nothing")
+	assert.Empty(t, r.since("k", "4"))
+	assert.Empty(t, r.since("other", "2"))
+
+	now = now.Add(45 * time.Second)
+	r.add("k", "5", []byte("5"))
+	now = now.Add(30 * time.Second)
+	r.add("k", "6", []byte("6"))
+	assert.Empty(t, r.since("k", "4"), "expired IDs replay nothing")
+	assert.Equal(t, []string{"6"}, ids(r.since("k", "5")))
+
+	now = now.Add(2 * time.Minute)
+	assert.Empty(t, r.since("k", "5"))
+	assert.Empty(t, r.rings, "empty buffers are discarded")
+
+	a, b := r.nextID("k"), r.nextID("k")
+	assert.NotEqual(t, a, b)
+	assert.True(t, strings.HasSuffix(b, "-2"), b)
+}
+
+// sliceClientStream sends msgs and then ends.
+type sliceClientStream struct {
+	grpc.ClientStream
+	msgs []string
+}
+
+func (s *sliceClientStream) Recv() (*wrapperspb.StringValue, error) {
+	if len(s.msgs) == 0 {
+		return nil, io.EOF
+	}
+	msg := s.msgs[0]
+	s.msgs = s.msgs[1:]
+	return wrapperspb.String(msg), nil
+}
+
+func TestSSEReplay(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+	pattern, err := parsePathPattern("/feed")
+	require.NoError(t, err)
+
+	serve := func(o *sseOptions, lastID string, msgs ...string) string {
+		starter := func(context.Context, map[string]string, grpc.ClientConnInterface) (ClientStream[*wrapperspb.StringValue], error) {
+			return &sliceClientStream{msgs: msgs}, nil
+		}
+		req := httptest.NewRequestWithContext(ctx, http.MethodGet, "/feed?topic=a", nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		rec := httptest.NewRecorder()
+		createSSEHandler(pattern, starter, o, &Server{}).ServeHTTP(rec, req)
+		return rec.Body.String()
+	}
+
+	t.Run("sequence IDs", func(t *testing.T) {
+		o := newSSEOptions("/test/replay", []SSEOption{WithSSEReplay(10, time.Minute)})
+		body := serve(o, "", "a", "b", "c")
+		var ids []string
+		for _, line := range strings.Split(body, "\n") {
+			if id, ok := strings.CutPrefix(line, "id: "); ok {
+				ids = append(ids, id)
+			}
+		}
+		require.Len(t, ids, 3)
+
+		// The client disconnected after the first event, and events continue.
+		body = serve(o, ids[0], "d")
+		missed := "id: " + ids[1] + "\ndata: \"b\"\n\nid: " + ids[2] + "\ndata: \"c\"\n\n"
+		assert.True(t, strings.HasPrefix(body, missed), "missed events are sent first: %q", body)
+		assert.Contains(t, body, "data: \"d\"")
+	})
+
+	t.Run("message IDs", func(t *testing.T) {
+		o := newSSEOptions("/test/replay-ids", []SSEOption{
+			WithSSEReplay(10, time.Minute),
+			WithSSEEventID(func(m proto.Message) string { return m.(*wrapperspb.StringValue).GetValue() }),
+		})
+		assert.Equal(t, "id: a\ndata: \"a\"\n\nid: b\ndata: \"b\"\n\n", serve(o, "", "a", "b"))
+
+		// Replayed events aren't sent again by the new stream.
+		assert.Equal(t, "id: b\ndata: \"b\"\n\nid: c\ndata: \"c\"\n\n", serve(o, "a", "a", "b", "c"))
+	})
+
+	t.Run("without replay", func(t *testing.T) {
+		o := newSSEOptions("/test/no-replay", nil)
+		assert.Equal(t, "data: \"a\"\n\n", serve(o, "x", "a"))
+	})
+}
@@ -0,0 +1,208 @@
+package prefab
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Cache-Control for files whose name contains a content hash, and so will
+	// never change.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+
+	// Cache-Control for all other files, which must be revalidated using their
+	// ETag.
+	revalidateCacheControl = "no-cache"
+)
+
+// Matches the final segment of a file name, before its extension, which may be
+// a content hash, e.g. `app.3f2a1b9c.js` or `index-BkQ1x_9z.css`.
+var contentHashPattern = regexp.MustCompile(`[.-]([0-9A-Za-z_]{8,})\.[^./]+$`)
+
+// Pre-compressed variants, in order of preference.
+var staticEncodings = []struct {
+	name string
+	ext  string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// StaticFSOption customizes the handler created by WithStaticFS.
+type StaticFSOption func(*staticFS)
+
+// StaticSPA enables single page application mode: requests for paths which
+// don't exist, and which don't look like a file, are served the root
+// index.html so that client side routing can handle them.
+func StaticSPA() StaticFSOption {
+	return func(s *staticFS) {
+		s.spa = true
+	}
+}
+
+// WithStaticFS configures the server to serve static files from a filesystem,
+// such as an embed.FS, for HTTP requests that match the given prefix. The
+// prefix is stripped from the request path before looking up the file, use
+// fs.Sub to serve a sub-directory of an embedded bundle.
+//
+// Files whose name contains a content hash (e.g. `app.3f2a1b9c.js`) are served
+// with a long-lived immutable Cache-Control header, other files must be
+// revalidated using their ETag. If the client accepts it, a pre-compressed
+// `.br` or `.gz` variant of the file is served when one exists alongside it.
+//
+// Example:
+//
+//	//go:embed dist
+//	var dist embed.FS
+//
+//	assets, _ := fs.Sub(dist, "dist")
+//	prefab.WithStaticFS("/", assets, prefab.StaticSPA())
+func WithStaticFS(prefix string, fsys fs.FS, opts ...StaticFSOption) ServerOption {
+	s := &staticFS{prefix: prefix, fsys: fsys, etags: map[string]string{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return func(b *builder) {
+		b.handlers = append(b.handlers, handler{
+			prefix:      prefix,
+			httpHandler: s,
+		})
+	}
+}
+
+type staticFS struct {
+	prefix string
+	fsys   fs.FS
+	spa    bool
+
+	mu    sync.RWMutex
+	etags map[string]string
+}
+
+func (s *staticFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(s.prefix, "/"))
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "index.html"
+	}
+
+	if s.isDir(name) {
+		name = path.Join(name, "index.html")
+	}
+
+	if !s.exists(name) {
+		if !s.spa || path.Ext(name) != "" || !s.exists("index.html") {
+			http.NotFound(w, r)
+			return
+		}
+		name = "index.html"
+	}
+
+	s.serveFile(w, r, name)
+}
+
+func (s *staticFS) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	// Select a pre-compressed variant if the client accepts it.
+	file := name
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	for _, enc := range staticEncodings {
+		if acceptsEncoding(r, enc.name) && s.exists(name+enc.ext) {
+			file = name + enc.ext
+			h.Set("Content-Encoding", enc.name)
+			break
+		}
+	}
+
+	b, err := fs.ReadFile(s.fsys, file)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if ct := mime.TypeByExtension(path.Ext(name)); ct != "" {
+		h.Set("Content-Type", ct)
+	}
+	if isContentHashed(path.Base(name)) {
+		h.Set("Cache-Control", immutableCacheControl)
+	} else {
+		h.Set("Cache-Control", revalidateCacheControl)
+	}
+	h.Set("ETag", s.etag(file, b))
+
+	// Embedded files have no modification time, so conditional requests rely on
+	// the ETag alone.
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(b))
+}
+
+// etag returns a strong ETag derived from the file's content, caching it since
+// files in an fs.FS are assumed not to change.
+func (s *staticFS) etag(file string, b []byte) string {
+	s.mu.RLock()
+	tag, ok := s.etags[file]
+	s.mu.RUnlock()
+	if ok {
+		return tag
+	}
+	sum := sha256.Sum256(b)
+	tag = strconv.Quote(hex.EncodeToString(sum[:16]))
+	s.mu.Lock()
+	s.etags[file] = tag
+	s.mu.Unlock()
+	return tag
+}
+
+// isContentHashed reports whether a file name contains a content hash, as
+// produced by common bundlers. Hashes must contain a digit so that names such as
+// `my.component.js` aren't mistaken for one.
+func isContentHashed(name string) bool {
+	m := contentHashPattern.FindStringSubmatch(name)
+	return m != nil && strings.ContainsAny(m[1], "0123456789")
+}
+
+func (s *staticFS) exists(name string) bool {
+	fi, err := fs.Stat(s.fsys, name)
+	return err == nil && !fi.IsDir()
+}
+
+func (s *staticFS) isDir(name string) bool {
+	fi, err := fs.Stat(s.fsys, name)
+	return err == nil && fi.IsDir()
+}
+
+// acceptsEncoding reports whether the request's Accept-Encoding header allows
+// the given content coding.
+func acceptsEncoding(r *http.Request, encoding string) bool {
+	for _, v := range r.Header.Values("Accept-Encoding") {
+		for _, part := range strings.Split(v, ",") {
+			coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+				continue
+			}
+			q := strings.TrimSpace(params)
+			if after, ok := strings.CutPrefix(q, "q="); ok {
+				if f, err := strconv.ParseFloat(after, 64); err == nil && f == 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package prefab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testStaticFS(opts ...StaticFSOption) http.Handler {
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("<html>index</html>")},
+		"app.3f2a1b9c.js":          {Data: []byte("console.log('app')")},
+		"app.3f2a1b9c.js.br":       {Data: []byte("brotli")},
+		"app.3f2a1b9c.js.gz":       {Data: []byte("gzip")},
+		"style.css":                {Data: []byte("body {}")},
+		"docs/index.html":          {Data: []byte("<html>docs</html>")},
+		"docs/guide.html":          {Data: []byte("<html>guide</html>")},
+		"assets/logo-BkQ1x9zA.svg": {Data: []byte("<svg></svg>")},
+	}
+	s := &staticFS{prefix: "/static/", fsys: fsys, etags: map[string]string{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func serveStatic(h http.Handler, method, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestStaticFS(t *testing.T) {
+	h := testStaticFS()
+
+	t.Run("Index", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "<html>index</html>", rr.Body.String())
+		assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, revalidateCacheControl, rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("DirectoryIndex", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/docs")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "<html>docs</html>", rr.Body.String())
+	})
+
+	t.Run("HashedFile", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/app.3f2a1b9c.js")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "console.log('app')", rr.Body.String())
+		assert.Equal(t, immutableCacheControl, rr.Header().Get("Cache-Control"))
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+
+		rr = serveStatic(h, http.MethodGet, "/static/assets/logo-BkQ1x9zA.svg")
+		assert.Equal(t, immutableCacheControl, rr.Header().Get("Cache-Control"))
+	})
+
+	t.Run("PrecompressedBrotli", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/app.3f2a1b9c.js", "Accept-Encoding", "gzip, deflate, br")
+		assert.Equal(t, "brotli", rr.Body.String())
+		assert.Equal(t, "br", rr.Header().Get("Content-Encoding"))
+		assert.Contains(t, rr.Header().Get("Content-Type"), "javascript")
+	})
+
+	t.Run("PrecompressedGzip", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/app.3f2a1b9c.js", "Accept-Encoding", "gzip, br;q=0")
+		assert.Equal(t, "gzip", rr.Body.String())
+		assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("NoVariant", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/style.css", "Accept-Encoding", "br")
+		assert.Equal(t, "body {}", rr.Body.String())
+		assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("ETag", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/style.css")
+		etag := rr.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		rr = serveStatic(h, http.MethodGet, "/static/style.css", "If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, rr.Code)
+		assert.Empty(t, rr.Body.String())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/settings")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("PathTraversal", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodGet, "/static/../../etc/passwd")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		rr := serveStatic(h, http.MethodPost, "/static/style.css")
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	})
+}
+
+func TestStaticFS_SPA(t *testing.T) {
+	h := testStaticFS(StaticSPA())
+
+	rr := serveStatic(h, http.MethodGet, "/static/settings/profile")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "<html>index</html>", rr.Body.String())
+	assert.Equal(t, revalidateCacheControl, rr.Header().Get("Cache-Control"))
+
+	// Missing files are still not found.
+	rr = serveStatic(h, http.MethodGet, "/static/missing.js")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Existing pages are served as normal.
+	rr = serveStatic(h, http.MethodGet, "/static/docs/guide.html")
+	assert.Equal(t, "<html>guide</html>", rr.Body.String())
+}
+
+func TestIsContentHashed(t *testing.T) {
+	assert.True(t, isContentHashed("app.3f2a1b9c.js"))
+	assert.True(t, isContentHashed("index-BkQ1x_9z.css"))
+	assert.False(t, isContentHashed("style.css"))
+	assert.False(t, isContentHashed("my-component.js"))
+	assert.False(t, isContentHashed("bootstrap.bundle.min.js"))
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip;q=0.5, br;q=0, deflate")
+	assert.True(t, acceptsEncoding(req, "gzip"))
+	assert.False(t, acceptsEncoding(req, "br"))
+	assert.True(t, acceptsEncoding(req, "deflate"))
+	assert.False(t, acceptsEncoding(req, "zstd"))
+}
@@ -0,0 +1,118 @@
+package prefab
+
+import (
+	"context"
+	"io"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type streamingKey struct{}
+
+// IsStreaming reports whether ctx belongs to a streaming method, for GRPC
+// interceptors which only apply to unary calls, since interceptors run for both.
+func IsStreaming(ctx context.Context) bool {
+	v, _ := ctx.Value(streamingKey{}).(bool)
+	return v
+}
+
+// streamInterceptor runs the unary interceptor chain for streaming methods, so
+// that streams get the same context, authentication and authorization as unary
+// calls.
+//
+// For server-streaming methods the chain runs once the handler has received
+// the request, so interceptors see the request message. Client and
+// bidirectional streams have no single request, so the chain runs as the
+// stream starts with an empty request, and interceptors that need fields of
+// the request, such as authz, deny the call. Interceptors see a nil response,
+// since messages are sent on the stream, and the error the stream ends with.
+// See IsStreaming.
+func streamInterceptor(chain grpc.UnaryServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		unaryInfo := &grpc.UnaryServerInfo{Server: srv, FullMethod: info.FullMethod}
+		ctx := context.WithValue(ss.Context(), streamingKey{}, true)
+		if info.IsClientStream {
+			_, err := chain(ctx, &emptypb.Empty{}, unaryInfo, func(ctx context.Context, _ any) (any, error) {
+				return nil, handler(srv, &interceptedStream{ServerStream: ss, ctx: ctx})
+			})
+			return err
+		}
+		s := &interceptedStream{ServerStream: ss, ctx: ctx, chain: chain, info: unaryInfo}
+		defer s.abandon()
+		return s.finish(handler(srv, s))
+	}
+}
+
+// interceptedStream is a server stream whose context comes from the interceptor
+// chain. For server-streaming methods, the chain runs in its own goroutine from
+// the first RecvMsg until the handler returns.
+type interceptedStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	chain grpc.UnaryServerInterceptor
+	info  *grpc.UnaryServerInfo
+
+	started  bool
+	returned bool       // The chain returned without calling the handler.
+	chainErr error      // The chain's result, if it returned early.
+	done     chan error // The handler's result, sent to the chain.
+	result   chan error // The chain's result.
+}
+
+// From grpc.ServerStream.
+func (s *interceptedStream) Context() context.Context {
+	return s.ctx
+}
+
+// From grpc.ServerStream.
+func (s *interceptedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil || s.chain == nil || s.started {
+		return err
+	}
+	s.started = true
+	done, result, ready := make(chan error, 1), make(chan error, 1), make(chan context.Context, 1)
+	s.done, s.result = done, result
+	go func() {
+		_, err := s.chain(s.ctx, m, s.info, func(ctx context.Context, _ any) (any, error) {
+			ready <- ctx
+			return nil, <-done
+		})
+		result <- err
+	}()
+	select {
+	case ctx := <-ready:
+		s.ctx = ctx
+		return nil
+	case err := <-result:
+		// An interceptor rejected or answered the call before it reached the
+		// handler, which shouldn't continue.
+		s.returned, s.chainErr = true, err
+		if err == nil {
+			return io.EOF
+		}
+		return err
+	}
+}
+
+// finish passes the handler's result to the chain, and returns the chain's.
+func (s *interceptedStream) finish(err error) error {
+	switch {
+	case !s.started:
+		return err
+	case s.returned:
+		return s.chainErr
+	}
+	s.done <- err
+	s.done = nil
+	return <-s.result
+}
+
+// abandon releases the chain if the handler panicked before finishing.
+func (s *interceptedStream) abandon() {
+	if s.started && !s.returned && s.done != nil {
+		s.done <- errors.NewC("prefab: stream handler failed", codes.Internal)
+	}
+}
@@ -0,0 +1,93 @@
+package prefab
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type streamKey struct{}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req proto.Message
+}
+
+func (s *fakeServerStream) Context() context.Context { return s.ctx }
+
+func (s *fakeServerStream) RecvMsg(m any) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func TestStreamInterceptor_ServerStream(t *testing.T) {
+	var seenErr error
+	var req any
+	chain := func(ctx context.Context, r any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		req = r
+		_, seenErr = handler(context.WithValue(ctx, streamKey{}, "intercepted"), r)
+		return nil, errors.NewC("chain failed", codes.Aborted)
+	}
+	ss := &fakeServerStream{ctx: t.Context(), req: wrapperspb.String("hello")}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream", IsServerStream: true}
+
+	err := streamInterceptor(chain)(nil, ss, info, func(_ any, stream grpc.ServerStream) error {
+		// The chain hasn't run before the request is received.
+		assert.Nil(t, stream.Context().Value(streamKey{}))
+		m := &wrapperspb.StringValue{}
+		if err := stream.RecvMsg(m); err != nil {
+			return err
+		}
+		assert.Equal(t, "intercepted", stream.Context().Value(streamKey{}))
+		assert.True(t, IsStreaming(stream.Context()))
+		return errors.NewC("stream failed", codes.Unavailable)
+	})
+	assert.Equal(t, "hello", req.(*wrapperspb.StringValue).GetValue())
+	assert.Equal(t, codes.Unavailable, errors.Code(seenErr))
+	assert.Equal(t, codes.Aborted, errors.Code(err), "the chain's result should be returned")
+}
+
+func TestStreamInterceptor_Rejected(t *testing.T) {
+	chain := func(ctx context.Context, r any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return nil, errors.NewC("denied", codes.PermissionDenied)
+	}
+	ss := &fakeServerStream{ctx: t.Context(), req: wrapperspb.String("hello")}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream", IsServerStream: true}
+
+	sent := false
+	err := streamInterceptor(chain)(nil, ss, info, func(_ any, stream grpc.ServerStream) error {
+		if err := stream.RecvMsg(&wrapperspb.StringValue{}); err != nil {
+			return err
+		}
+		sent = true
+		return nil
+	})
+	assert.Equal(t, codes.PermissionDenied, errors.Code(err))
+	assert.False(t, sent)
+}
+
+func TestStreamInterceptor_ClientStream(t *testing.T) {
+	var req any
+	chain := func(ctx context.Context, r any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		req = r
+		return handler(context.WithValue(ctx, streamKey{}, "intercepted"), r)
+	}
+	ss := &fakeServerStream{ctx: t.Context()}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Chat", IsClientStream: true, IsServerStream: true}
+
+	err := streamInterceptor(chain)(nil, ss, info, func(_ any, stream grpc.ServerStream) error {
+		assert.Equal(t, "intercepted", stream.Context().Value(streamKey{}))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &emptypb.Empty{}, req)
+}
@@ -0,0 +1,155 @@
+package prefab
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Default Retry-After sent when a stream limit is exceeded.
+const defaultStreamRetryAfter = 5 * time.Second
+
+// StreamLimits caps the number of long-lived streams a server keeps open, so
+// that clients which open many tabs or reconnect in a loop can't exhaust it.
+// Zero values are unlimited.
+type StreamLimits struct {
+	// Streams open across the server.
+	MaxOpen int
+
+	// Streams open for each caller, identified by subject, see
+	// logging.SubjectFromContext. Unauthenticated callers only count towards
+	// MaxOpen.
+	MaxPerIdentity int
+
+	// Sent as the Retry-After header when a limit is exceeded. Defaults to 5
+	// seconds.
+	RetryAfter time.Duration
+}
+
+// WithStreamLimits limits the number of SSE streams that can be open at once.
+// Requests over a limit are rejected with 429 Too Many Requests and a
+// Retry-After header, after the endpoint's guards have run. Current counts are
+// served by AdminService.GetStreamStats.
+//
+// Config keys: `server.streams.maxOpen`, `server.streams.maxPerIdentity`,
+// `server.streams.retryAfter`.
+func WithStreamLimits(limits StreamLimits) ServerOption {
+	return func(b *builder) {
+		b.streamLimits = limits
+	}
+}
+
+// streamTracker counts open streams and enforces StreamLimits.
+type streamTracker struct {
+	limits StreamLimits
+
+	mu         sync.Mutex
+	open       int
+	byIdentity map[string]int
+}
+
+func newStreamTracker(limits StreamLimits) *streamTracker {
+	if limits.RetryAfter <= 0 {
+		limits.RetryAfter = defaultStreamRetryAfter
+	}
+	return &streamTracker{limits: limits, byIdentity: map[string]int{}}
+}
+
+// acquire reserves a stream for subject, returning a function which releases
+// it. Fails with ResourceExhausted if a limit would be exceeded. A nil tracker
+// is unlimited.
+func (t *streamTracker) acquire(subject string) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.limits.MaxOpen > 0 && t.open >= t.limits.MaxOpen {
+		return nil, errors.NewC("prefab: too many open streams", codes.ResourceExhausted)
+	}
+	if subject != "" && t.limits.MaxPerIdentity > 0 && t.byIdentity[subject] >= t.limits.MaxPerIdentity {
+		return nil, errors.NewC("prefab: too many open streams for caller", codes.ResourceExhausted)
+	}
+	t.open++
+	if subject != "" {
+		t.byIdentity[subject]++
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.open--
+			if subject != "" {
+				if t.byIdentity[subject]--; t.byIdentity[subject] <= 0 {
+					delete(t.byIdentity, subject)
+				}
+			}
+		})
+	}, nil
+}
+
+// reject writes an error for a request which exceeded a limit.
+func (t *streamTracker) reject(w http.ResponseWriter, r *http.Request, err error) {
+	secs := int(math.Ceil(t.limits.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	WriteJSONError(w, r, err)
+}
+
+func (t *streamTracker) stats() *StreamStats {
+	out := &StreamStats{}
+	if t == nil {
+		return out
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out.Open = int32(t.open)                            //nolint:gosec // Bounded by connections.
+	out.MaxOpen = int32(t.limits.MaxOpen)               //nolint:gosec // Validated config.
+	out.MaxPerIdentity = int32(t.limits.MaxPerIdentity) //nolint:gosec // Validated config.
+	for subject, n := range t.byIdentity {
+		out.Identities = append(out.Identities, &IdentityStreams{Subject: subject, Open: int32(n)}) //nolint:gosec // Bounded by connections.
+	}
+	sort.Slice(out.Identities, func(i, j int) bool {
+		a, b := out.Identities[i], out.Identities[j]
+		if a.Open != b.Open {
+			return a.Open > b.Open
+		}
+		return a.Subject < b.Subject
+	})
+	return out
+}
+
+// StreamStats returns the number of open SSE streams, overall, for each caller,
+// and for each of the server's SSE endpoints.
+func (s *Server) StreamStats() *StreamStats {
+	out := s.streams.stats()
+	endpoints := map[string]bool{}
+	for _, path := range s.sseEndpoints {
+		endpoints[path] = true
+	}
+	for _, e := range SSEStreamStats() {
+		if !endpoints[e.Path] {
+			continue
+		}
+		out.Endpoints = append(out.Endpoints, &EndpointStreams{
+			Path:            e.Path,
+			Open:            e.Open,
+			Dropped:         e.Dropped,
+			SlowDisconnects: e.SlowDisconnects,
+			Rejected:        e.Rejected,
+		})
+	}
+	return out
+}
+
+func (a *adminService) GetStreamStats(context.Context, *GetStreamStatsRequest) (*StreamStats, error) {
+	return a.s.StreamStats(), nil
+}
@@ -0,0 +1,101 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestStreamTracker(t *testing.T) {
+	tr := newStreamTracker(StreamLimits{MaxOpen: 3, MaxPerIdentity: 2})
+
+	a1, err := tr.acquire("alice")
+	require.NoError(t, err)
+	_, err = tr.acquire("alice")
+	require.NoError(t, err)
+	_, err = tr.acquire("alice")
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err), "per-identity limit")
+
+	_, err = tr.acquire("")
+	require.NoError(t, err, "unauthenticated callers only count towards the total")
+	_, err = tr.acquire("bob")
+	assert.Equal(t, codes.ResourceExhausted, errors.Code(err), "total limit")
+
+	stats := tr.stats()
+	assert.Equal(t, int32(3), stats.GetOpen())
+	assert.Equal(t, int32(3), stats.GetMaxOpen())
+	assert.Equal(t, int32(2), stats.GetMaxPerIdentity())
+	require.Len(t, stats.GetIdentities(), 1)
+	assert.Equal(t, "alice", stats.GetIdentities()[0].GetSubject())
+	assert.Equal(t, int32(2), stats.GetIdentities()[0].GetOpen())
+
+	a1()
+	a1() // Releasing twice is a no-op.
+	assert.Equal(t, int32(2), tr.stats().GetOpen())
+	_, err = tr.acquire("bob")
+	require.NoError(t, err)
+
+	var unlimited *streamTracker
+	release, err := unlimited.acquire("alice")
+	require.NoError(t, err)
+	release()
+}
+
+func TestSSEStreamLimits(t *testing.T) {
+	pattern, err := parsePathPattern("/limited")
+	require.NoError(t, err)
+	o := newSSEOptions("/test/limited", nil)
+	rejected := o.stats.rejected.Load()
+	s := &Server{
+		streams:      newStreamTracker(StreamLimits{MaxPerIdentity: 1, RetryAfter: 1500 * time.Millisecond}),
+		sseEndpoints: []string{"/test/limited"},
+	}
+
+	started := make(chan struct{})
+	starter := func(ctx context.Context, _ map[string]string, _ grpc.ClientConnInterface) (ClientStream[*wrapperspb.StringValue], error) {
+		close(started)
+		return &blockingClientStream{ctx: ctx}, nil
+	}
+	h := createSSEHandler(pattern, starter, o, s)
+	request := func(ctx context.Context) *http.Request {
+		ctx = logging.With(ctx, logging.NewDevLogger())
+		ctx = logging.WithSubjectFunc(ctx, func(context.Context) string { return "alice" })
+		return httptest.NewRequestWithContext(ctx, http.MethodGet, "/limited", nil)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), request(ctx))
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, request(t.Context()))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, rejected+1, o.stats.rejected.Load())
+
+	stats, err := (&adminService{s: s}).GetStreamStats(t.Context(), &GetStreamStatsRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), stats.GetOpen())
+	assert.Equal(t, int32(1), stats.GetMaxPerIdentity())
+	require.Len(t, stats.GetEndpoints(), 1)
+	assert.Equal(t, "/test/limited", stats.GetEndpoints()[0].GetPath())
+	assert.Equal(t, int64(1), stats.GetEndpoints()[0].GetOpen())
+
+	cancel()
+	<-done
+	assert.Equal(t, int32(0), s.streams.stats().GetOpen(), "closed streams are released")
+}
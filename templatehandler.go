@@ -0,0 +1,83 @@
+package prefab
+
+import (
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+)
+
+// TemplateDataFunc returns the data used to render a template for a request.
+// Returning an error aborts rendering and the error's HTTP status is sent to the
+// client.
+type TemplateDataFunc func(req *http.Request) (any, error)
+
+// TemplateRenderer is implemented by plugins which can render named templates,
+// such as the templates plugin. WithTemplateHandler uses the registered
+// TemplateRenderer to render pages.
+type TemplateRenderer interface {
+	Plugin
+
+	// Render executes a template by name with the provided data.
+	Render(ctx context.Context, name string, data any) (string, error)
+}
+
+// WithTemplateHandler adds an HTTP handler which renders a server-side
+// template for requests matching the given path. The dataFunc, which may be
+// nil, is called for each request to produce the template's data.
+//
+// A CSRF token is issued for every request and is available to the renderer via
+// CSRFTokenFromContext. A plugin implementing TemplateRenderer must be
+// registered.
+//
+// Example:
+//
+//	prefab.WithPlugin(templates.Plugin()),
+//	prefab.WithTemplateHandler("/profile", "profile.tmpl", func(r *http.Request) (any, error) {
+//	    return loadProfile(r.Context())
+//	}),
+func WithTemplateHandler(path, template string, dataFunc TemplateDataFunc) ServerOption {
+	return func(b *builder) {
+		b.handlers = append(b.handlers, handler{
+			prefix: path,
+			httpHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serveTemplate(w, r, b.plugins, b.csrfSigningKey, template, dataFunc)
+			}),
+		})
+	}
+}
+
+func serveTemplate(w http.ResponseWriter, r *http.Request, plugins *Registry, csrfSigningKey []byte, template string, dataFunc TemplateDataFunc) {
+	renderer, ok := GetPlugin[TemplateRenderer](plugins)
+	if !ok {
+		logging.Errorw(r.Context(), "No template renderer registered", "template", template)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	r = r.WithContext(sendHTTPCSRFToken(w, r, csrfSigningKey))
+
+	var data any
+	if dataFunc != nil {
+		var err error
+		if data, err = dataFunc(r); err != nil {
+			logging.Errorw(r.Context(), "Template data error", "error", err, "template", template)
+			status := errors.HTTPStatusCode(err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+	}
+
+	out, err := renderer.Render(r.Context(), template, data)
+	if err != nil {
+		logging.Errorw(r.Context(), "Template rendering error", "error", err, "template", template)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, out)
+}
@@ -0,0 +1,96 @@
+package prefab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type fakeRenderer struct{}
+
+func (fakeRenderer) Name() string { return "fakerenderer" }
+
+func (fakeRenderer) Render(ctx context.Context, name string, data any) (string, error) {
+	if name == "broken" {
+		return "", errors.New("broken template")
+	}
+	return fmt.Sprintf("%s:%v:%t", name, data, CSRFTokenFromContext(ctx) != ""), nil
+}
+
+func TestServeTemplate(t *testing.T) {
+	key := []byte("signing-key")
+	plugins := &Registry{}
+	plugins.Register(fakeRenderer{})
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/page", nil)
+		return req.WithContext(logging.EnsureLogger(t.Context()))
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, newRequest(), plugins, key, "page.tmpl", func(*http.Request) (any, error) {
+			return "data", nil
+		})
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"))
+		assert.Equal(t, "page.tmpl:data:true", rr.Body.String())
+
+		cookies := rr.Result().Cookies()
+		require.Len(t, cookies, 1)
+		assert.Equal(t, csrfCookie, cookies[0].Name)
+		assert.NoError(t, verifyCSRFToken(cookies[0].Value, key))
+	})
+
+	t.Run("ReusesValidToken", func(t *testing.T) {
+		token := generateCSRFToken(key)
+		req := newRequest()
+		req.AddCookie(&http.Cookie{Name: csrfCookie, Value: token})
+
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, req, plugins, key, "page.tmpl", nil)
+
+		assert.Equal(t, "page.tmpl:<nil>:true", rr.Body.String())
+		assert.Equal(t, token, rr.Result().Cookies()[0].Value)
+	})
+
+	t.Run("ReplacesInvalidToken", func(t *testing.T) {
+		token := generateCSRFToken([]byte("other-key"))
+		req := newRequest()
+		req.AddCookie(&http.Cookie{Name: csrfCookie, Value: token})
+
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, req, plugins, key, "page.tmpl", nil)
+
+		assert.NotEqual(t, token, rr.Result().Cookies()[0].Value)
+	})
+
+	t.Run("DataError", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, newRequest(), plugins, key, "page.tmpl", func(*http.Request) (any, error) {
+			return nil, errors.NewC("not found", codes.NotFound)
+		})
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("RenderError", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, newRequest(), plugins, key, "broken", nil)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+
+	t.Run("NoRenderer", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		serveTemplate(rr, newRequest(), &Registry{}, key, "page.tmpl", nil)
+		assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	})
+}
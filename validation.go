@@ -3,9 +3,12 @@ package prefab
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"strings"
 	"time"
+
+	"github.com/dpup/prefab/serverutil"
 )
 
 // ConfigMustString returns the string value for the given key.
@@ -169,6 +172,16 @@ func ValidateConfig() []ValidationError {
 		}
 	}
 
+	// Validate server.admin.port if set, 0 disables the admin listener
+	if Config.Exists("server.admin.port") {
+		if err := ValidateIntRange(Config.Int("server.admin.port"), 0, 65535); err != nil {
+			errors = append(errors, ValidationError{
+				Key:     "server.admin.port",
+				Message: err.Error(),
+			})
+		}
+	}
+
 	// Validate server.maxMsgSizeBytes if set
 	if Config.Exists("server.maxMsgSizeBytes") {
 		size := Config.Int("server.maxMsgSizeBytes")
@@ -180,6 +193,57 @@ func ValidateConfig() []ValidationError {
 		}
 	}
 
+	// Validate gRPC sizes if set. Window sizes are int32, stream limits uint32.
+	for _, key := range []string{
+		"server.maxSendMsgSizeBytes",
+		"server.grpc.maxConcurrentStreams",
+		"server.grpc.initialWindowSize",
+		"server.grpc.initialConnWindowSize",
+	} {
+		if Config.Exists(key) {
+			if err := ValidateIntRange(Config.Int(key), 1, math.MaxInt32); err != nil {
+				errors = append(errors, ValidationError{
+					Key:     key,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	// Validate stream limits if set, 0 is unlimited
+	for _, key := range []string{"server.streams.maxOpen", "server.streams.maxPerIdentity"} {
+		if Config.Exists(key) {
+			if err := ValidateIntRange(Config.Int(key), 0, math.MaxInt32); err != nil {
+				errors = append(errors, ValidationError{
+					Key:     key,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
+	// Validate gRPC keepalive durations if set
+	for _, key := range []string{
+		"server.grpc.keepalive.time",
+		"server.grpc.keepalive.timeout",
+		"server.grpc.keepalive.minTime",
+		"server.grpc.maxConnectionIdle",
+		"server.grpc.maxConnectionAge",
+		"server.grpc.maxConnectionAgeGrace",
+		"server.requestTimeout",
+		"server.maxRequestTimeout",
+		"server.streams.retryAfter",
+	} {
+		if Config.Exists(key) {
+			if err := ValidateNonNegativeDuration(Config.Duration(key)); err != nil {
+				errors = append(errors, ValidationError{
+					Key:     key,
+					Message: err.Error(),
+				})
+			}
+		}
+	}
+
 	// Validate server.security.hstsExpiration if set
 	if Config.Exists("server.security.hstsExpiration") {
 		duration := Config.Duration("server.security.hstsExpiration")
@@ -204,6 +268,20 @@ func ValidateConfig() []ValidationError {
 		}
 	}
 
+	// Validate trusted proxies, which the PROXY protocol requires
+	if _, err := serverutil.ParseTrustedProxies(Config.Strings("server.proxy.trusted")); err != nil {
+		errors = append(errors, ValidationError{
+			Key:     "server.proxy.trusted",
+			Message: err.Error(),
+		})
+	}
+	if Config.Bool("server.proxy.protocol") && len(Config.Strings("server.proxy.trusted")) == 0 {
+		errors = append(errors, ValidationError{
+			Key:     "server.proxy.protocol",
+			Message: "requires server.proxy.trusted to be set",
+		})
+	}
+
 	// Validate auth.expiration if set
 	if Config.Exists("auth.expiration") {
 		duration := Config.Duration("auth.expiration")
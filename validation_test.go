@@ -388,6 +388,31 @@ func TestValidateConfig(t *testing.T) {
 		assert.Equal(t, "server.maxMsgSizeBytes", errors[0].Key)
 	})
 
+	t.Run("returns errors for invalid gRPC tuning", func(t *testing.T) {
+		originalConfig := Config
+		defer func() { Config = originalConfig }()
+
+		Config = koanf.New(".")
+		Config.Load(confmap.Provider(map[string]interface{}{
+			"server.maxSendMsgSizeBytes":        0,
+			"server.grpc.maxConcurrentStreams":  100,
+			"server.grpc.initialWindowSize":     int64(1) << 32,
+			"server.grpc.keepalive.time":        "-1s",
+			"server.grpc.maxConnectionAgeGrace": "30s",
+		}, "."), nil)
+
+		errors := ValidateConfig()
+		keys := make([]string, 0, len(errors))
+		for _, e := range errors {
+			keys = append(keys, e.Key)
+		}
+		assert.ElementsMatch(t, []string{
+			"server.maxSendMsgSizeBytes",
+			"server.grpc.initialWindowSize",
+			"server.grpc.keepalive.time",
+		}, keys)
+	})
+
 	t.Run("returns errors for invalid auth expiration", func(t *testing.T) {
 		originalConfig := Config
 		defer func() { Config = originalConfig }()
@@ -0,0 +1,421 @@
+package prefab
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dpup/prefab/errors"
+	"github.com/dpup/prefab/logging"
+	"github.com/dpup/prefab/serverutil"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// APIVersionOption configures a version of the API, see WithAPIVersion.
+type APIVersionOption func(*apiVersion)
+
+// VersionSunset schedules the removal of a version. Responses to requests for
+// the version carry Deprecation, Sunset, and Warning headers, and requests are
+// logged. With `server.enforceSunset`, requests after the date fail with 410
+// Gone.
+func VersionSunset(t time.Time) APIVersionOption {
+	return func(v *apiVersion) {
+		v.sunset = t
+	}
+}
+
+// VersionClientConfig adds a client config value which is served by the
+// metaservice to clients using the version, overriding values added with
+// WithClientConfigValue. See WithClientConfigValue for supported values.
+func VersionClientConfig(key string, value any) APIVersionOption {
+	v, err := clientConfigValue(key, value)
+	if err != nil {
+		panic(err)
+	}
+	return func(av *apiVersion) {
+		av.clientConfigs[key] = v
+	}
+}
+
+// WithAPIVersion declares a version of the API, e.g. "v2". Services are
+// registered for a version with Server.RegisterVersionedService, and their
+// GRPC Gateway routes include the version as a path segment, for example
+// "/api/v2/notes".
+//
+// Clients either use a versioned path, or an unversioned path such as
+// "/api/notes" which is routed to the version requested with the Accept
+// header's version parameter, e.g. `Accept: application/json; version=v2`.
+// Requests without a version use the default version, see
+// WithDefaultAPIVersion. The version used is returned in the Api-Version
+// response header, and is available to handlers with APIVersion.
+func WithAPIVersion(name string, opts ...APIVersionOption) ServerOption {
+	v := newAPIVersion(name)
+	for _, opt := range opts {
+		opt(v)
+	}
+	return func(b *builder) {
+		b.apiVersions = append(b.apiVersions, v)
+	}
+}
+
+// WithDefaultAPIVersion sets the version used for requests which don't ask for
+// one. If not set, the last version declared is used.
+//
+// Config key: `server.versions.default`.
+func WithDefaultAPIVersion(name string) ServerOption {
+	return func(b *builder) {
+		b.defaultAPIVersion = name
+	}
+}
+
+// RegisterVersionedService registers a service, as RegisterService, for a
+// version of the API. Versions which haven't been declared with WithAPIVersion
+// are added with no options.
+func (s *Server) RegisterVersionedService(
+	version string,
+	serviceDesc *grpc.ServiceDesc,
+	registerGateway GatewayHandlerFunc,
+	impl any,
+) error {
+	if err := s.versions.addService(version, serviceDesc.ServiceName); err != nil {
+		return err
+	}
+	return s.RegisterService(serviceDesc, registerGateway, impl)
+}
+
+// APIVersion returns the version of the API negotiated for a gateway or HTTP
+// request, or an empty string if no versions are declared.
+func APIVersion(ctx context.Context) string {
+	if v, ok := ctx.Value(apiVersionKey{}).(string); ok {
+		return v
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(apiVersionMetadata); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+type apiVersionKey struct{}
+
+const apiVersionMetadata = serverutil.MetadataHTTPPrefix + "api-version"
+
+// apiVersionMetadataAnnotator passes the negotiated version to GRPC services.
+func apiVersionMetadataAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	if v, ok := r.Context().Value(apiVersionKey{}).(string); ok {
+		return metadata.Pairs(apiVersionMetadata, v)
+	}
+	return nil
+}
+
+// apiVersion is a version of the API and the gateway routes of its services.
+type apiVersion struct {
+	name          string
+	sunset        time.Time
+	clientConfigs map[string]*structpb.Value
+	routes        []versionRoute
+}
+
+func newAPIVersion(name string) *apiVersion {
+	return &apiVersion{name: name, clientConfigs: map[string]*structpb.Value{}}
+}
+
+// versionRoute is a gateway route which includes the version as a segment.
+// Segments before and after the version are matched against request paths.
+type versionRoute struct {
+	method string
+	before []string
+	after  []string
+	verb   string
+}
+
+// apiVersions negotiates the version of gateway requests.
+type apiVersions struct {
+	defaultName   string
+	enforceSunset bool
+	now           func() time.Time
+
+	mu       sync.RWMutex
+	versions []*apiVersion
+}
+
+func newAPIVersions(versions []*apiVersion, defaultName string, enforceSunset bool) *apiVersions {
+	return &apiVersions{versions: versions, defaultName: defaultName, enforceSunset: enforceSunset, now: time.Now}
+}
+
+// find returns the version with the given name, or nil. Must be called with
+// the lock held.
+func (vs *apiVersions) find(name string) *apiVersion {
+	for _, v := range vs.versions {
+		if v.name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// defaultVersion returns the version used when a request doesn't ask for one.
+// Must be called with the lock held.
+func (vs *apiVersions) defaultVersion() *apiVersion {
+	if v := vs.find(vs.defaultName); v != nil {
+		return v
+	}
+	if len(vs.versions) == 0 {
+		return nil
+	}
+	return vs.versions[len(vs.versions)-1]
+}
+
+// addService records the gateway routes of a service for a version.
+func (vs *apiVersions) addService(version, serviceName string) error {
+	desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return errors.WrapPrefix(err, "prefab: versioned service "+serviceName+" isn't registered", 0)
+	}
+	sd, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return errors.Errorf("prefab: %s isn't a service", serviceName)
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+	v := vs.find(version)
+	if v == nil {
+		v = newAPIVersion(version)
+		vs.versions = append(vs.versions, v)
+	}
+	for i := range sd.Methods().Len() {
+		rule, _ := proto.GetExtension(sd.Methods().Get(i).Options(), annotations.E_Http).(*annotations.HttpRule)
+		for _, b := range httpBindings(rule) {
+			if route, ok := parseVersionRoute(b[0], b[1], version); ok {
+				v.routes = append(v.routes, route)
+			}
+		}
+	}
+	return nil
+}
+
+// httpBindings returns the method and path template of each binding of a
+// google.api.http rule.
+func httpBindings(rule *annotations.HttpRule) [][2]string {
+	if rule == nil {
+		return nil
+	}
+	var out [][2]string
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		out = append(out, [2]string{http.MethodGet, p.Get})
+	case *annotations.HttpRule_Put:
+		out = append(out, [2]string{http.MethodPut, p.Put})
+	case *annotations.HttpRule_Post:
+		out = append(out, [2]string{http.MethodPost, p.Post})
+	case *annotations.HttpRule_Delete:
+		out = append(out, [2]string{http.MethodDelete, p.Delete})
+	case *annotations.HttpRule_Patch:
+		out = append(out, [2]string{http.MethodPatch, p.Patch})
+	case *annotations.HttpRule_Custom:
+		out = append(out, [2]string{p.Custom.GetKind(), p.Custom.GetPath()})
+	}
+	for _, additional := range rule.GetAdditionalBindings() {
+		out = append(out, httpBindings(additional)...)
+	}
+	return out
+}
+
+// templateVariable matches variables in path templates, e.g. {id} or
+// {name=shelves/*}.
+var templateVariable = regexp.MustCompile(`\{[^}=]+(?:=([^}]*))?\}`)
+
+// parseVersionRoute splits a path template around the version segment.
+// Returns false if the template doesn't include the version.
+func parseVersionRoute(method, template, version string) (versionRoute, bool) {
+	template = templateVariable.ReplaceAllStringFunc(template, func(v string) string {
+		if m := templateVariable.FindStringSubmatch(v); m[1] != "" {
+			return m[1]
+		}
+		return "*"
+	})
+	route := versionRoute{method: method}
+	if i := strings.LastIndex(template, ":"); i > strings.LastIndex(template, "/") {
+		template, route.verb = template[:i], template[i+1:]
+	}
+	segs := strings.Split(strings.Trim(template, "/"), "/")
+	for i, seg := range segs {
+		if seg == version {
+			route.before, route.after = segs[:i], segs[i+1:]
+			return route, true
+		}
+	}
+	return route, false
+}
+
+// matchSegments reports whether path segments match template segments, where
+// "*" matches a segment and "**" matches the remaining segments.
+func matchSegments(template, segs []string) bool {
+	for i, t := range template {
+		if t == "**" {
+			return true
+		}
+		if i >= len(segs) || (t != "*" && t != segs[i]) {
+			return false
+		}
+	}
+	return len(template) == len(segs)
+}
+
+// match reports whether a request matches the route, either with the version
+// segment or, if versioned is false, without it.
+func (r versionRoute) match(method string, segs []string, version string, versioned bool) bool {
+	if r.method != method {
+		return false
+	}
+	if r.verb != "" {
+		last := len(segs) - 1
+		if last < 0 || !strings.HasSuffix(segs[last], ":"+r.verb) {
+			return false
+		}
+		segs = append(segs[:last:last], strings.TrimSuffix(segs[last], ":"+r.verb))
+	}
+	if !versioned {
+		return matchSegments(append(r.before[:len(r.before):len(r.before)], r.after...), segs)
+	}
+	if len(segs) <= len(r.before) || segs[len(r.before)] != version {
+		return false
+	}
+	return matchSegments(r.before, segs[:len(r.before)]) && matchSegments(r.after, segs[len(r.before)+1:])
+}
+
+// negotiate returns the version for a request and the path to route it to,
+// which includes the version if the request's path doesn't. Returns nil if no
+// versions are declared.
+func (vs *apiVersions) negotiate(r *http.Request) (*apiVersion, string, error) {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	if len(vs.versions) == 0 {
+		return nil, r.URL.Path, nil
+	}
+
+	segs := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	for _, v := range vs.versions {
+		for _, route := range v.routes {
+			if route.match(r.Method, segs, v.name, true) {
+				return v, r.URL.Path, nil
+			}
+		}
+	}
+
+	v := vs.defaultVersion()
+	if name := acceptVersion(r.Header.Values("Accept")); name != "" {
+		if v = vs.find(name); v == nil {
+			v = vs.find("v" + name)
+		}
+		if v == nil {
+			return nil, "", errors.NewC(fmt.Errorf("prefab: unsupported API version %q", name), codes.InvalidArgument).
+				WithHTTPStatusCode(http.StatusNotAcceptable).
+				WithUserPresentableMessage("API version %q isn't supported", name)
+		}
+	}
+	for _, route := range v.routes {
+		if !route.match(r.Method, segs, v.name, false) {
+			continue
+		}
+		n := len(route.before)
+		path := "/" + strings.Join(append(append(segs[:n:n], v.name), segs[n:]...), "/")
+		return v, path, nil
+	}
+	return v, r.URL.Path, nil
+}
+
+// acceptVersion returns the version parameter of the Accept header, e.g.
+// "v2" for `application/json; version=v2`.
+func acceptVersion(accept []string) string {
+	for _, header := range accept {
+		for _, mediaRange := range strings.Split(header, ",") {
+			_, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err == nil && params["version"] != "" {
+				return params["version"]
+			}
+		}
+	}
+	return ""
+}
+
+// describe returns the versions for the metaservice.
+func (vs *apiVersions) describe() []*ApiVersion {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	def := vs.defaultVersion()
+	out := make([]*ApiVersion, 0, len(vs.versions))
+	for _, v := range vs.versions {
+		av := &ApiVersion{Name: v.name, Default: v == def}
+		if !v.sunset.IsZero() {
+			av.Sunset = v.sunset.UTC().Format(time.DateOnly)
+		}
+		out = append(out, av)
+	}
+	return out
+}
+
+// clientConfigs returns the client config values of a version.
+func (vs *apiVersions) clientConfigs(name string) map[string]*structpb.Value {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	if v := vs.find(name); v != nil {
+		return v.clientConfigs
+	}
+	return nil
+}
+
+// middleware negotiates the version of gateway requests, routes unversioned
+// paths to the version, and warns clients about versions scheduled for
+// removal.
+func (vs *apiVersions) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v, path, err := vs.negotiate(r)
+		if err != nil {
+			WriteJSONError(w, r, err)
+			return
+		}
+		if v == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), apiVersionKey{}, v.name)
+		w.Header().Set("Api-Version", v.name)
+
+		if !v.sunset.IsZero() {
+			sunset := v.sunset.UTC().Format(http.TimeFormat)
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Sunset", sunset)
+			w.Header().Set("Warning", fmt.Sprintf(`299 - "API version %s will be removed after %s"`, v.name, sunset))
+			logging.Warnw(ctx, "prefab: API version scheduled for removal used",
+				"version", v.name, "sunset", sunset, "path", r.URL.Path, "userAgent", r.UserAgent())
+			if vs.enforceSunset && !vs.now().Before(v.sunset) {
+				WriteJSONError(w, r, errors.NewC(fmt.Errorf("prefab: API version %s was sunset", v.name), codes.Unimplemented).
+					WithHTTPStatusCode(http.StatusGone).
+					WithUserPresentableMessage("API version %s is no longer available", v.name))
+				return
+			}
+		}
+
+		r = r.WithContext(ctx)
+		if path != r.URL.Path {
+			u := *r.URL
+			u.Path, u.RawPath = path, ""
+			r.URL = &u
+		}
+		next.ServeHTTP(w, r)
+	})
+}
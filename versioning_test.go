@@ -0,0 +1,216 @@
+package prefab
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestParseVersionRoute(t *testing.T) {
+	r, ok := parseVersionRoute(http.MethodGet, "/api/v2/notes/{id}", "v2")
+	require.True(t, ok)
+	assert.Equal(t, versionRoute{method: http.MethodGet, before: []string{"api"}, after: []string{"notes", "*"}}, r)
+
+	r, ok = parseVersionRoute(http.MethodPost, "/api/v2/{name=shelves/*}:archive", "v2")
+	require.True(t, ok)
+	assert.Equal(t, versionRoute{method: http.MethodPost, before: []string{"api"}, after: []string{"shelves", "*"}, verb: "archive"}, r)
+
+	_, ok = parseVersionRoute(http.MethodGet, "/api/notes/{id}", "v2")
+	assert.False(t, ok, "routes without the version are ignored")
+}
+
+func TestAPIVersionNegotiation(t *testing.T) {
+	v1 := newAPIVersion("v1")
+	v2 := newAPIVersion("v2")
+	for _, tpl := range []string{"/api/v1/notes", "/api/v1/notes/{id=**}"} {
+		r, _ := parseVersionRoute(http.MethodGet, tpl, "v1")
+		v1.routes = append(v1.routes, r)
+	}
+	r, _ := parseVersionRoute(http.MethodGet, "/api/v2/notes", "v2")
+	v2.routes = append(v2.routes, r)
+	r, _ = parseVersionRoute(http.MethodPost, "/api/v2/notes/{id}:archive", "v2")
+	v2.routes = append(v2.routes, r)
+	vs := newAPIVersions([]*apiVersion{v1, v2}, "", false)
+
+	tests := []struct {
+		method, path, accept string
+		version, routed      string
+	}{
+		{http.MethodGet, "/api/notes", "", "v2", "/api/v2/notes"},
+		{http.MethodGet, "/api/v1/notes", "", "v1", "/api/v1/notes"},
+		{http.MethodGet, "/api/v1/notes", "application/json; version=v2", "v1", "/api/v1/notes"},
+		{http.MethodGet, "/api/notes", "application/json; version=v1", "v1", "/api/v1/notes"},
+		{http.MethodGet, "/api/notes/a/b", "text/html, application/json;version=1", "v1", "/api/v1/notes/a/b"},
+		{http.MethodPost, "/api/notes/abc:archive", "", "v2", "/api/v2/notes/abc:archive"},
+		{http.MethodGet, "/api/notes/abc", "", "v2", "/api/notes/abc"},
+		{http.MethodGet, "/api/meta/config", "", "v2", "/api/meta/config"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+		v, path, err := vs.negotiate(req)
+		require.NoError(t, err, tc.path)
+		assert.Equal(t, tc.version, v.name, "%s %s", tc.path, tc.accept)
+		assert.Equal(t, tc.routed, path, "%s %s", tc.path, tc.accept)
+	}
+
+	vs.defaultName = "v1"
+	v, path, err := vs.negotiate(httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "v1", v.name)
+	assert.Equal(t, "/api/v1/notes", path)
+
+	v, _, err = newAPIVersions(nil, "", false).negotiate(httptest.NewRequest(http.MethodGet, "/api/notes", nil))
+	require.NoError(t, err)
+	assert.Nil(t, v, "without versions there's nothing to negotiate")
+}
+
+func TestAPIVersionMiddleware(t *testing.T) {
+	v1 := newAPIVersion("v1")
+	v1.sunset = time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	r, _ := parseVersionRoute(http.MethodGet, "/api/v1/notes", "v1")
+	v1.routes = append(v1.routes, r)
+	vs := newAPIVersions([]*apiVersion{v1, newAPIVersion("v2")}, "", true)
+	vs.now = func() time.Time { return time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC) }
+
+	var gotPath, gotVersion string
+	h := vs.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotVersion = r.URL.Path, APIVersion(r.Context())
+	}))
+	serve := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req = req.WithContext(logging.EnsureLogger(t.Context()))
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/api/notes", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "v2", rr.Header().Get("Api-Version"))
+	assert.Equal(t, "v2", gotVersion)
+	assert.Equal(t, "/api/notes", gotPath)
+	assert.Empty(t, rr.Header().Get("Sunset"))
+
+	rr = serve("/api/notes", "application/json; version=v1")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "v1", rr.Header().Get("Api-Version"))
+	assert.Equal(t, "/api/v1/notes", gotPath)
+	assert.Equal(t, "true", rr.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 30 Jun 2025 00:00:00 GMT", rr.Header().Get("Sunset"))
+	assert.Equal(t, `299 - "API version v1 will be removed after Mon, 30 Jun 2025 00:00:00 GMT"`, rr.Header().Get("Warning"))
+
+	rr = serve("/api/notes", "application/json; version=v9")
+	assert.Equal(t, http.StatusNotAcceptable, rr.Code)
+	assert.Contains(t, rr.Body.String(), `API version \"v9\" isn't supported`)
+
+	vs.now = func() time.Time { return time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC) }
+	rr = serve("/api/v1/notes", "")
+	assert.Equal(t, http.StatusGone, rr.Code)
+	assert.Contains(t, rr.Body.String(), "API version v1 is no longer available")
+}
+
+func TestAPIVersionAddService(t *testing.T) {
+	opts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(opts, annotations.E_Http, &annotations.HttpRule{
+		Pattern: &annotations.HttpRule_Get{Get: "/api/v3/items/{id}"},
+		AdditionalBindings: []*annotations.HttpRule{
+			{Pattern: &annotations.HttpRule_Custom{Custom: &annotations.CustomHttpPattern{Kind: "HEAD", Path: "/api/v3/items/{id}"}}},
+		},
+	})
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("versioning_test.proto"),
+		Package:    proto.String("versioningtest"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"metaservice.proto", "google/api/annotations.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ItemService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("GetItem"),
+				InputType:  proto.String(".prefab.ClientConfigRequest"),
+				OutputType: proto.String(".prefab.ClientConfigResponse"),
+				Options:    opts,
+			}},
+		}},
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	if _, err := protoregistry.GlobalFiles.FindFileByPath("versioning_test.proto"); err != nil {
+		require.NoError(t, protoregistry.GlobalFiles.RegisterFile(fd))
+	}
+
+	vs := newAPIVersions(nil, "", false)
+	require.NoError(t, vs.addService("v3", "versioningtest.ItemService"))
+	require.Len(t, vs.versions, 1, "undeclared versions are added")
+	assert.Equal(t, []versionRoute{
+		{method: http.MethodGet, before: []string{"api"}, after: []string{"items", "*"}},
+		{method: "HEAD", before: []string{"api"}, after: []string{"items", "*"}},
+	}, vs.versions[0].routes)
+
+	assert.Error(t, vs.addService("v3", "versioningtest.MissingService"))
+}
+
+func TestAPIVersionClientConfig(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := New(
+		WithListener(ln),
+		WithClientConfigValue("features.search", false),
+		WithAPIVersion("v1",
+			VersionSunset(time.Date(2099, 1, 31, 0, 0, 0, 0, time.UTC)),
+			VersionClientConfig("features.search", true)),
+		WithAPIVersion("v2"),
+	)
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+	require.Eventually(t, func() bool { return s.Ready() }, time.Second, 10*time.Millisecond)
+
+	get := func(accept string) (*http.Response, map[string]any) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+ln.Addr().String()+"/api/meta/config", nil)
+		require.NoError(t, err)
+		req.Header.Set("Accept", accept)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(b, &body))
+		return resp, body
+	}
+
+	versions := []any{
+		map[string]any{"name": "v1", "default": false, "sunset": "2099-01-31"},
+		map[string]any{"name": "v2", "default": true, "sunset": ""},
+	}
+	resp, body := get("application/json")
+	assert.Equal(t, "v2", resp.Header.Get("Api-Version"))
+	assert.Equal(t, "v2", body["apiVersion"])
+	assert.Equal(t, versions, body["apiVersions"])
+	assert.Equal(t, map[string]any{"features": map[string]any{"search": false}}, body["values"])
+
+	resp, body = get("application/json; version=v1")
+	assert.Equal(t, "v1", resp.Header.Get("Api-Version"))
+	assert.NotEmpty(t, resp.Header.Get("Sunset"))
+	assert.Equal(t, "v1", body["apiVersion"])
+	assert.Equal(t, map[string]any{"features": map[string]any{"search": true}}, body["values"])
+
+	require.NoError(t, s.Shutdown())
+	require.NoError(t, <-started)
+}
@@ -0,0 +1,222 @@
+package prefab
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"github.com/knadh/koanf/v2"
+)
+
+// VirtualHostOption configures a virtual host, see WithVirtualHost.
+type VirtualHostOption func(*virtualHost)
+
+// virtualHost holds the handlers and configuration specific to a hostname.
+type virtualHost struct {
+	host            string
+	handlers        []handler
+	gateway         bool
+	certFile        string
+	keyFile         string
+	securityHeaders *SecurityHeaders
+}
+
+// WithVirtualHost serves handlers for a specific hostname, so that a single
+// server can serve, for example, api.example.com and admin.example.com. Routes
+// registered for the host take precedence over server-wide routes, and the
+// host's security headers and TLS certificate apply to them.
+//
+// Example:
+//
+//	prefab.WithVirtualHost("admin.example.com",
+//	    prefab.HostRoute("GET /", adminUI),
+//	    prefab.HostTLS("admin.crt", "admin.key"),
+//	    prefab.HostSecurityHeaders(&prefab.SecurityHeaders{XFramesOptions: prefab.XFramesOptionsDeny}),
+//	)
+//
+// Config key: `server.hosts`, a list of hosts with keys `host`, `gateway`,
+// `tls.certFile`, `tls.keyFile`, and `security`, which accepts the keys of
+// `server.security` and overrides them for the host.
+func WithVirtualHost(host string, opts ...VirtualHostOption) ServerOption {
+	return func(b *builder) {
+		vh := b.virtualHost(host)
+		for _, opt := range opts {
+			opt(vh)
+		}
+	}
+}
+
+// HostRoute registers an HTTP handler for a pattern on the virtual host, see
+// WithRoute. Patterns must not include a host.
+func HostRoute(pattern string, h http.Handler, mw ...Middleware) VirtualHostOption {
+	return func(vh *virtualHost) {
+		vh.handlers = append(vh.handlers, handler{prefix: pattern, httpHandler: h, middleware: mw})
+	}
+}
+
+// HostRouteFunc registers an HTTP handler function for a pattern on the
+// virtual host, see WithRoute.
+func HostRouteFunc(pattern string, h func(http.ResponseWriter, *http.Request), mw ...Middleware) VirtualHostOption {
+	return HostRoute(pattern, http.HandlerFunc(h), mw...)
+}
+
+// HostJSONRoute registers a JSON handler for a pattern on the virtual host, see
+// WithJSONRoute.
+func HostJSONRoute(pattern string, h JSONHandler, mw ...Middleware) VirtualHostOption {
+	return func(vh *virtualHost) {
+		vh.handlers = append(vh.handlers, handler{prefix: pattern, jsonHandler: h, middleware: mw})
+	}
+}
+
+// HostGateway serves the GRPC Gateway under /api/ on the virtual host. Once any
+// virtual host serves the gateway, it is no longer served on other hosts.
+func HostGateway() VirtualHostOption {
+	return func(vh *virtualHost) {
+		vh.gateway = true
+	}
+}
+
+// HostTLS sets the certificate presented to clients which request the host via
+// SNI. Other clients are presented the server's certificate, so WithTLS is
+// required.
+func HostTLS(certFile, keyFile string) VirtualHostOption {
+	return func(vh *virtualHost) {
+		vh.certFile = certFile
+		vh.keyFile = keyFile
+	}
+}
+
+// HostSecurityHeaders replaces the server's security headers for the host's
+// routes. CORS overrides still apply by path, see WithCORSOverride.
+func HostSecurityHeaders(headers *SecurityHeaders) VirtualHostOption {
+	return func(vh *virtualHost) {
+		vh.securityHeaders = headers
+	}
+}
+
+// hasGatewayHosts reports whether the gateway is restricted to virtual hosts.
+func (b *builder) hasGatewayHosts() bool {
+	for _, vh := range b.virtualHosts {
+		if vh.gateway {
+			return true
+		}
+	}
+	return false
+}
+
+// virtualHost returns the virtual host for a hostname, adding it if needed.
+func (b *builder) virtualHost(host string) *virtualHost {
+	host = strings.ToLower(host)
+	for _, vh := range b.virtualHosts {
+		if vh.host == host {
+			return vh
+		}
+	}
+	vh := &virtualHost{host: host}
+	b.virtualHosts = append(b.virtualHosts, vh)
+	return vh
+}
+
+// hostPattern adds a host to a ServeMux pattern, e.g. "GET /users" becomes
+// "GET api.example.com/users".
+func hostPattern(host, pattern string) string {
+	if method, path, ok := strings.Cut(pattern, " "); ok {
+		return method + " " + host + strings.TrimSpace(path)
+	}
+	return host + pattern
+}
+
+// hostCertificates loads the certificates of virtual hosts, returning a
+// GetCertificate function which selects one by SNI, falling back to the
+// server's certificate. Returns nil if no virtual host has a certificate.
+func (b *builder) hostCertificates() func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certs := map[string]*tls.Certificate{}
+	for _, vh := range b.virtualHosts {
+		if vh.certFile == "" {
+			continue
+		}
+		c, err := tls.LoadX509KeyPair(vh.certFile, vh.keyFile)
+		if err != nil {
+			panic(err)
+		}
+		certs[vh.host] = &c
+	}
+	if len(certs) == 0 {
+		return nil
+	}
+	if !b.isSecure() {
+		panic("prefab: virtual host certificates require a server certificate, see WithTLS")
+	}
+	def, err := tls.LoadX509KeyPair(b.certFile, b.keyFile)
+	if err != nil {
+		panic(err)
+	}
+	return func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if c, ok := certs[strings.ToLower(hello.ServerName)]; ok {
+			return c, nil
+		}
+		return &def, nil
+	}
+}
+
+// virtualHostsFromConfig reads virtual hosts from `server.hosts`.
+func virtualHostsFromConfig(b *builder) {
+	for _, c := range Config.Slices("server.hosts") {
+		vh := b.virtualHost(c.String("host"))
+		vh.gateway = c.Bool("gateway")
+		vh.certFile = c.String("tls.certFile")
+		vh.keyFile = c.String("tls.keyFile")
+		if s := c.Cut("security"); len(s.Keys()) > 0 {
+			vh.securityHeaders = overlaySecurityHeaders(b.securityHeaders, s)
+		}
+	}
+}
+
+// overlaySecurityHeaders returns a copy of base, with values present in c
+// replacing the base values. Keys match `server.security`.
+func overlaySecurityHeaders(base *SecurityHeaders, c *koanf.Koanf) *SecurityHeaders {
+	h := &SecurityHeaders{
+		XFramesOptions:        base.XFramesOptions,
+		HSTSExpiration:        base.HSTSExpiration,
+		HSTSIncludeSubdomains: base.HSTSIncludeSubdomains,
+		HSTSPreload:           base.HSTSPreload,
+		CORSOrigins:           base.CORSOrigins,
+		CORSOriginValidator:   base.CORSOriginValidator,
+		CORSAllowMethods:      base.CORSAllowMethods,
+		CORSAllowHeaders:      base.CORSAllowHeaders,
+		CORSExposeHeaders:     base.CORSExposeHeaders,
+		CORSAllowCredentials:  base.CORSAllowCredentials,
+		CORSMaxAge:            base.CORSMaxAge,
+	}
+	if c.Exists("xFramesOptions") {
+		h.XFramesOptions = XFramesOptions(c.String("xFramesOptions"))
+	}
+	if c.Exists("hstsExpiration") {
+		h.HSTSExpiration = c.Duration("hstsExpiration")
+	}
+	if c.Exists("hstsIncludeSubdomains") {
+		h.HSTSIncludeSubdomains = c.Bool("hstsIncludeSubdomains")
+	}
+	if c.Exists("hstsPreload") {
+		h.HSTSPreload = c.Bool("hstsPreload")
+	}
+	if c.Exists("corsOrigins") {
+		h.CORSOrigins = c.Strings("corsOrigins")
+	}
+	if c.Exists("corsAllowMethods") {
+		h.CORSAllowMethods = c.Strings("corsAllowMethods")
+	}
+	if c.Exists("corsAllowHeaders") {
+		h.CORSAllowHeaders = c.Strings("corsAllowHeaders")
+	}
+	if c.Exists("corsExposeHeaders") {
+		h.CORSExposeHeaders = c.Strings("corsExposeHeaders")
+	}
+	if c.Exists("corsAllowCredentials") {
+		h.CORSAllowCredentials = c.Bool("corsAllowCredentials")
+	}
+	if c.Exists("corsMaxAge") {
+		h.CORSMaxAge = c.Duration("corsMaxAge")
+	}
+	return h
+}
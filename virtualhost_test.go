@@ -0,0 +1,146 @@
+package prefab
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVirtualHost_Routes(t *testing.T) {
+	s := New(
+		WithRouteFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("default"))
+		}),
+		WithVirtualHost("Admin.example.com",
+			HostRouteFunc("GET /", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("admin"))
+			}),
+			HostSecurityHeaders(&SecurityHeaders{XFramesOptions: XFramesOptionsDeny}),
+		),
+		WithVirtualHost("admin.example.com",
+			HostJSONRoute("GET /users/{id}", func(r *http.Request) (any, error) {
+				return map[string]string{"id": r.PathValue("id")}, nil
+			}),
+		),
+	)
+
+	rec := serveRoute(s.httpMux, http.MethodGet, "http://example.com/")
+	assert.Equal(t, "default", rec.Body.String())
+	assert.Empty(t, rec.Header().Get("X-Frame-Options"))
+
+	rec = serveRoute(s.httpMux, http.MethodGet, "http://admin.example.com:8080/")
+	assert.Equal(t, "admin", rec.Body.String())
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+
+	rec = serveRoute(s.httpMux, http.MethodGet, "http://admin.example.com/users/7")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"id":"7"}`, rec.Body.String())
+
+	assert.Contains(t, s.Routes(), Route{Pattern: "GET /users/{id}", Method: "GET", Host: "admin.example.com"})
+	assert.Contains(t, s.Routes(), Route{Pattern: "/"})
+}
+
+func TestVirtualHost_Gateway(t *testing.T) {
+	// The GRPC server isn't running, so gateway requests are unavailable.
+	s := New()
+	assert.Equal(t, http.StatusServiceUnavailable, serveRoute(s.httpMux, http.MethodGet, "http://example.com/api/meta/config").Code)
+
+	s = New(WithVirtualHost("api.example.com", HostGateway()))
+	assert.Equal(t, http.StatusNotFound, serveRoute(s.httpMux, http.MethodGet, "http://example.com/api/meta/config").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serveRoute(s.httpMux, http.MethodGet, "http://api.example.com/api/meta/config").Code)
+}
+
+func TestVirtualHost_Certificates(t *testing.T) {
+	dir := t.TempDir()
+	defCert, defKey := writeTestCertificate(t, dir, "localhost")
+	adminCert, adminKey := writeTestCertificate(t, dir, "admin.example.com")
+
+	assert.Panics(t, func() {
+		New(WithVirtualHost("admin.example.com", HostTLS(adminCert, adminKey)))
+	}, "host certificates require a server certificate")
+
+	s := New(
+		WithTLS(defCert, defKey),
+		WithVirtualHost("admin.example.com", HostTLS(adminCert, adminKey)),
+	)
+	require.NotNil(t, s.hostCertificates)
+
+	c, err := s.hostCertificates(&tls.ClientHelloInfo{ServerName: "ADMIN.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "admin.example.com", c.Leaf.Subject.CommonName)
+
+	c, err = s.hostCertificates(&tls.ClientHelloInfo{ServerName: "other.example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", c.Leaf.Subject.CommonName)
+}
+
+func TestVirtualHost_Config(t *testing.T) {
+	originalConfig := Config
+	t.Cleanup(func() { Config = originalConfig })
+	Config = koanf.New(".")
+	require.NoError(t, Config.Load(confmap.Provider(map[string]any{
+		"server.hosts": []any{
+			map[string]any{
+				"host":    "api.example.com",
+				"gateway": true,
+				"security": map[string]any{
+					"xFramesOptions": "SAMEORIGIN",
+				},
+			},
+		},
+	}, "."), nil))
+
+	b := &builder{securityHeaders: &SecurityHeaders{XFramesOptions: XFramesOptionsDeny, HSTSExpiration: time.Hour}}
+	virtualHostsFromConfig(b)
+	require.Len(t, b.virtualHosts, 1)
+	vh := b.virtualHosts[0]
+	assert.Equal(t, "api.example.com", vh.host)
+	assert.True(t, vh.gateway)
+	assert.Equal(t, XFramesOptionsSameOrigin, vh.securityHeaders.XFramesOptions)
+	assert.Equal(t, time.Hour, vh.securityHeaders.HSTSExpiration, "unset keys are inherited")
+}
+
+func TestHostPattern(t *testing.T) {
+	assert.Equal(t, "GET api.example.com/users", hostPattern("api.example.com", "GET /users"))
+	assert.Equal(t, "api.example.com/static/", hostPattern("api.example.com", "/static/"))
+}
+
+// writeTestCertificate writes a self-signed certificate for host, returning the
+// paths of the certificate and key.
+func writeTestCertificate(t *testing.T, dir, host string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, host+".crt")
+	keyFile := filepath.Join(dir, host+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
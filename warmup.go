@@ -0,0 +1,50 @@
+package prefab
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dpup/prefab/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrNotReady is returned by the readiness endpoint until plugins have warmed
+// up, and once the server begins shutting down.
+var ErrNotReady = errors.NewC("server is not ready", codes.Unavailable)
+
+// WithBackgroundWarmup starts accepting traffic before plugins have warmed up,
+// instead of waiting for WarmupPlugin.Warmup to complete. The readiness
+// endpoint reports the server as unavailable until warmup succeeds, so load
+// balancers can hold traffic while liveness checks pass.
+//
+// Config key: `server.backgroundWarmup`.
+func WithBackgroundWarmup(enabled bool) ServerOption {
+	return func(b *builder) {
+		b.backgroundWarmup = enabled
+	}
+}
+
+// Ready returns whether the server has warmed up and is accepting traffic.
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// warmup runs plugin warmups and marks the server as ready on success.
+func (s *Server) warmup(ctx context.Context) error {
+	if err := s.plugins.Warmup(ctx); err != nil {
+		return err
+	}
+	s.ready.Store(true)
+	return nil
+}
+
+// readyHandler serves the readiness endpoint. Errors aren't logged, since
+// probes poll it frequently.
+func (s *Server) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.Ready() {
+		WriteJSONError(w, r, errors.Mark(ErrNotReady, 0))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"status":"ready"}`))
+}
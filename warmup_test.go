@@ -0,0 +1,107 @@
+package prefab
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dpup/prefab/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type warmupPlugin struct {
+	name    string
+	deps    []string
+	err     error
+	release chan struct{}
+	order   *[]string
+}
+
+func (p *warmupPlugin) Name() string   { return p.name }
+func (p *warmupPlugin) Deps() []string { return p.deps }
+
+func (p *warmupPlugin) Warmup(ctx context.Context) error {
+	if p.release != nil {
+		<-p.release
+	}
+	if p.order != nil {
+		*p.order = append(*p.order, p.name)
+	}
+	return p.err
+}
+
+func TestRegistryWarmup(t *testing.T) {
+	ctx := logging.With(t.Context(), logging.NewDevLogger())
+
+	var order []string
+	r := &Registry{}
+	r.Register(&warmupPlugin{name: "A", deps: []string{"B"}, order: &order})
+	r.Register(&warmupPlugin{name: "B", order: &order})
+	r.Register(&TestPlugin{name: "C"})
+	require.NoError(t, r.Init(ctx))
+
+	require.NoError(t, r.Warmup(ctx))
+	assert.Equal(t, []string{"B", "A"}, order, "warmup follows initialization order")
+
+	r = &Registry{}
+	r.Register(&warmupPlugin{name: "A", err: errors.New("boom")})
+	require.NoError(t, r.Init(ctx))
+	assert.ErrorContains(t, r.Warmup(ctx), "failed to warm up 'A'")
+}
+
+func TestWarmup_Readiness(t *testing.T) {
+	s := New(WithPlugin(&warmupPlugin{name: "A"}))
+	assert.False(t, s.Ready())
+	assert.Equal(t, http.StatusServiceUnavailable, serveStatus(s.httpMux, "/readyz"))
+
+	require.NoError(t, s.plugins.Init(s.baseContext))
+	require.NoError(t, s.warmup(s.baseContext))
+	assert.True(t, s.Ready())
+	assert.Equal(t, http.StatusOK, serveStatus(s.httpMux, "/readyz"))
+
+	s = New(WithPlugin(&warmupPlugin{name: "A", err: errors.New("boom")}))
+	require.NoError(t, s.plugins.Init(s.baseContext))
+	require.Error(t, s.warmup(s.baseContext))
+	assert.False(t, s.Ready())
+}
+
+func TestWarmup_Background(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	release := make(chan struct{})
+	s := New(
+		WithListener(ln),
+		WithBackgroundWarmup(true),
+		WithPlugin(&warmupPlugin{name: "A", release: release}),
+	)
+	started := make(chan error, 1)
+	go func() { started <- s.Start() }()
+
+	client := &http.Client{Transport: &http.Transport{}}
+	readyz := func() int {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodGet, "http://"+ln.Addr().String()+"/readyz", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Traffic is accepted while warming up, but the server isn't ready.
+	require.Eventually(t, func() bool { return readyz() == http.StatusServiceUnavailable }, time.Second, 10*time.Millisecond)
+
+	close(release)
+	require.Eventually(t, func() bool { return readyz() == http.StatusOK }, time.Second, 10*time.Millisecond)
+
+	client.CloseIdleConnections()
+	require.NoError(t, s.Shutdown())
+	assert.False(t, s.Ready())
+	require.NoError(t, <-started)
+}